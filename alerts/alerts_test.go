@@ -0,0 +1,241 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"hlf-easy/config"
+	"hlf-easy/node"
+	"hlf-easy/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	rulesYAML := `
+rules:
+  - name: peer-down
+    condition: node_down
+    kind: peer
+    for: 30s
+    actions:
+      - type: restart
+      - type: webhook
+        url: https://hooks.example.com/alerts
+  - name: cpu
+    condition: cpu_high
+    threshold: 90
+    for: 5m
+    actions:
+      - type: log
+`
+	alertsConfig, err := ParseRules([]byte(rulesYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alertsConfig.Rules) != 2 || alertsConfig.Rules[0].Actions[1].URL != "https://hooks.example.com/alerts" {
+		t.Fatalf("unexpected rules %+v", alertsConfig.Rules)
+	}
+	_, err = ParseRules([]byte(`{"rules": [{"name": "certs", "condition": "cert_expiring", "actions": [{"type": "log"}]}]}`))
+	if err != nil {
+		t.Fatalf("expected a JSON rules file to be valid, got %v", err)
+	}
+	_, err = ParseRules([]byte("rules:\n  - name: a\n    condition: node_down\n    actions: [{type: log}]\n  - name: a\n    condition: node_down\n    actions: [{type: log}]\n"))
+	if err == nil {
+		t.Fatal("expected the rules with the same name to be invalid")
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	log := []config.AlertAction{{Type: ActionLog}}
+	invalid := []config.AlertRule{
+		{Condition: ConditionNodeDown, Actions: log},
+		{Name: "unknown", Condition: "disk_full", Actions: log},
+		{Name: "no-threshold", Condition: ConditionCPUHigh, Actions: log},
+		{Name: "threshold", Condition: ConditionNodeDown, Threshold: 1, Actions: log},
+		{Name: "disk", Condition: ConditionDiskUsageHigh, Threshold: 120, Actions: log},
+		{Name: "kind", Condition: ConditionNodeDown, Kind: "ca", Actions: log},
+		{Name: "for", Condition: ConditionNodeDown, For: "a while", Actions: log},
+		{Name: "no-actions", Condition: ConditionNodeDown},
+		{Name: "action", Condition: ConditionNodeDown, Actions: []config.AlertAction{{Type: "email"}}},
+		{Name: "webhook", Condition: ConditionNodeDown, Actions: []config.AlertAction{{Type: ActionWebhook}}},
+		{Name: "restart-url", Condition: ConditionNodeDown, Actions: []config.AlertAction{{Type: ActionRestart, URL: "http://localhost"}}},
+	}
+	for _, r := range invalid {
+		if err := ValidateRule(r); err == nil {
+			t.Errorf("expected %+v to be invalid", r)
+		}
+	}
+}
+
+type fakeProcess struct {
+	mu       sync.Mutex
+	state    node.ProcessState
+	started  chan struct{}
+	restarts int
+}
+
+func (p *fakeProcess) Status() (*node.ProcessState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	state := p.state
+	return &state, nil
+}
+
+func (p *fakeProcess) Start() error {
+	p.mu.Lock()
+	p.state = node.ProcessState{PID: 2, Status: "Running"}
+	p.mu.Unlock()
+	p.started <- struct{}{}
+	return nil
+}
+
+func (p *fakeProcess) Restart() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.restarts++
+	return nil
+}
+
+func saveRules(t *testing.T, rules ...config.AlertRule) {
+	t.Helper()
+	err := utils.SaveAlertsConfig(&config.AlertsConfig{Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEngineNodeDown(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	events := make(chan map[string]interface{}, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+	}))
+	defer webhook.Close()
+	saveRules(t,
+		config.AlertRule{
+			Name:      "down",
+			Condition: ConditionNodeDown,
+			Kind:      "peer",
+			For:       "30s",
+			Actions:   []config.AlertAction{{Type: ActionWebhook, URL: webhook.URL}, {Type: ActionRestart}},
+		},
+		config.AlertRule{Name: "orderers", Condition: ConditionNodeDown, Kind: "orderer", Actions: []config.AlertAction{{Type: ActionLog}}},
+		config.AlertRule{Name: "other-peer", Condition: ConditionNodeDown, IDs: []string{"peer1"}, Actions: []config.AlertAction{{Type: ActionLog}}},
+	)
+	process := &fakeProcess{state: node.ProcessState{Status: "Stop"}, started: make(chan struct{}, 1)}
+	e := NewEngine(Node{Kind: "peer", ID: "peer0", Process: process})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	e.check(now)
+	alerts := e.Alerts()
+	if len(alerts) != 1 || alerts[0].Rule != "down" || alerts[0].Firing {
+		t.Fatalf("expected a pending alert of the down rule only, got %+v", alerts)
+	}
+	e.check(now.Add(31 * time.Second))
+	alerts = e.Alerts()
+	if len(alerts) != 1 || !alerts[0].Firing || alerts[0].FiredAt == nil {
+		t.Fatalf("expected the alert to fire after 30s, got %+v", alerts)
+	}
+	select {
+	case event := <-events:
+		if event["type"] != "alert_fired" || event["id"] != "peer0" {
+			t.Fatalf("unexpected event %v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the webhook to be called")
+	}
+	select {
+	case <-process.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the node that's down to be started")
+	}
+	if process.restarts != 0 {
+		t.Fatal("expected the node that's down to be started instead of restarted")
+	}
+	e.check(now.Add(45 * time.Second))
+	if alerts := e.Alerts(); len(alerts) != 0 {
+		t.Fatalf("expected the alert to be resolved, got %+v", alerts)
+	}
+}
+
+func TestEngineHeightStalled(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var mu sync.Mutex
+	height := 10
+	metrics := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "ledger_blockchain_height{channel=\"mychannel\"} %d\n", height)
+	}))
+	defer metrics.Close()
+	saveRules(t, config.AlertRule{Name: "stalled", Condition: ConditionHeightStalled, For: "1m", Actions: []config.AlertAction{{Type: ActionLog}}})
+	process := &fakeProcess{state: node.ProcessState{PID: 1, Status: "Running"}}
+	e := NewEngine(Node{
+		Kind:       "peer",
+		ID:         "peer0",
+		Process:    process,
+		Operations: node.OperationsEndpoint{Address: strings.TrimPrefix(metrics.URL, "http://")},
+	})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	e.check(now)
+	e.check(now.Add(50 * time.Second))
+	mu.Lock()
+	height = 11
+	mu.Unlock()
+	e.check(now.Add(70 * time.Second))
+	alerts := e.Alerts()
+	if len(alerts) != 1 || alerts[0].Firing || !alerts[0].Since.Equal(now.Add(70*time.Second)) {
+		t.Fatalf("expected a pending alert since the height changed, got %+v", alerts)
+	}
+	e.check(now.Add(131 * time.Second))
+	alerts = e.Alerts()
+	if len(alerts) != 1 || !alerts[0].Firing {
+		t.Fatalf("expected the alert to fire once the height didn't change for 1m, got %+v", alerts)
+	}
+}
+
+func TestEngineRemovedRule(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	saveRules(t, config.AlertRule{Name: "cpu", Condition: ConditionCPUHigh, Threshold: 80, Actions: []config.AlertAction{{Type: ActionLog}}})
+	process := &fakeProcess{state: node.ProcessState{PID: 1, Status: "Running", CPUInfo: node.CPUInfo{CPUPercent: 95}}}
+	e := NewEngine(Node{Kind: "orderer", ID: "orderer0", Process: process})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.check(now)
+	if alerts := e.Alerts(); len(alerts) != 1 || !alerts[0].Firing {
+		t.Fatalf("expected the cpu alert to fire without a duration, got %+v", alerts)
+	}
+	saveRules(t)
+	e.check(now.Add(time.Minute))
+	if alerts := e.Alerts(); len(alerts) != 0 {
+		t.Fatalf("expected the alerts of the removed rule to be dropped, got %+v", alerts)
+	}
+}
+
+func TestEngineDiskUsageHigh(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	saveRules(t,
+		config.AlertRule{Name: "disk", Condition: ConditionDiskUsageHigh, Actions: []config.AlertAction{{Type: ActionLog}}},
+		config.AlertRule{Name: "disk-95", Condition: ConditionDiskUsageHigh, Threshold: 95, Actions: []config.AlertAction{{Type: ActionLog}}},
+	)
+	process := &fakeProcess{state: node.ProcessState{PID: 1, Status: "Running", DiskUsage: &node.DiskUsage{UsedPercent: 92, Threshold: 90, FreeBytes: 8 << 30}}}
+	e := NewEngine(Node{Kind: "peer", ID: "peer0", Process: process})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.check(now)
+	alerts := e.Alerts()
+	if len(alerts) != 1 || alerts[0].Rule != "disk" || !alerts[0].Firing {
+		t.Fatalf("expected only the rule with the threshold of the peer to fire, got %+v", alerts)
+	}
+	// an orderer doesn't measure its ledger
+	e = NewEngine(Node{Kind: "orderer", ID: "orderer0", Process: &fakeProcess{state: node.ProcessState{PID: 1, Status: "Running"}}})
+	e.check(now)
+	if alerts := e.Alerts(); len(alerts) != 0 {
+		t.Fatalf("expected no alert without a disk usage, got %+v", alerts)
+	}
+}
@@ -0,0 +1,382 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/log"
+	"hlf-easy/node"
+	"hlf-easy/notify"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCheckInterval is how often the rules are evaluated
+const DefaultCheckInterval = 15 * time.Second
+
+// Process is the process of the node the rules are evaluated on
+type Process interface {
+	Status() (*node.ProcessState, error)
+	Start() error
+	Restart() error
+}
+
+// Node is the node the rules are evaluated on
+type Node struct {
+	Kind    string
+	ID      string
+	Dir     string
+	Process Process
+	// Operations is the endpoint the block heights are read from
+	Operations node.OperationsEndpoint
+}
+
+// Alert is a rule whose condition holds on the node
+type Alert struct {
+	Rule      string     `json:"rule"`
+	Condition string     `json:"condition"`
+	Kind      string     `json:"kind"`
+	ID        string     `json:"id"`
+	Message   string     `json:"message"`
+	Since     time.Time  `json:"since"`
+	Firing    bool       `json:"firing"`
+	FiredAt   *time.Time `json:"firedAt,omitempty"`
+}
+
+// ruleState is the state of a rule on the node
+type ruleState struct {
+	condition string
+	since     time.Time
+	message   string
+	fired     time.Time
+	firing    bool
+}
+
+// Engine evaluates the alert rules of the host on a node, the rules are
+// read again on every check so they can be changed while the node runs
+type Engine struct {
+	node  Node
+	mu    sync.Mutex
+	state map[string]*ruleState
+	// invalid are the invalid rules already logged
+	invalid map[string]string
+	// heights are the last block heights of the channels, heightsChanged
+	// is when they last changed
+	heights        map[string]uint64
+	heightsChanged time.Time
+}
+
+// NewEngine returns the engine of the alert rules of a node
+func NewEngine(n Node) *Engine {
+	return &Engine{
+		node:    n,
+		state:   map[string]*ruleState{},
+		invalid: map[string]string{},
+	}
+}
+
+// Alerts returns the alerts of the node, pending until they hold for the
+// duration of their rule
+func (e *Engine) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	alerts := []Alert{}
+	for name, state := range e.state {
+		if state.since.IsZero() {
+			continue
+		}
+		alert := Alert{
+			Rule:      name,
+			Condition: state.condition,
+			Kind:      e.node.Kind,
+			ID:        e.node.ID,
+			Message:   state.message,
+			Since:     state.since,
+			Firing:    state.firing,
+		}
+		if state.firing {
+			fired := state.fired
+			alert.FiredAt = &fired
+		}
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Rule < alerts[j].Rule
+	})
+	return alerts
+}
+
+// Run evaluates the rules every interval until the context is done
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		e.check(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check evaluates the rules that apply to the node and runs the actions of
+// the alerts that fire
+func (e *Engine) check(now time.Time) {
+	alertsConfig, err := utils.GetAlertsConfig()
+	if err != nil {
+		log.Warnf("Failed to read the alert rules: %v", err)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	evaluated := map[string]bool{}
+	// the status and the heights are read once per check
+	var state *node.ProcessState
+	var heightsErr error
+	heightsRead := false
+	for _, r := range alertsConfig.Rules {
+		if r.Disabled {
+			continue
+		}
+		compiled, err := compile(r)
+		if err != nil {
+			if e.invalid[r.Name] != err.Error() {
+				log.Warnf("Alert rule %s is invalid, it won't be evaluated: %v", r.Name, err)
+				e.invalid[r.Name] = err.Error()
+			}
+			continue
+		}
+		delete(e.invalid, r.Name)
+		if !compiled.applies(e.node.Kind, e.node.ID) {
+			continue
+		}
+		if state == nil {
+			state, err = e.node.Process.Status()
+			if err != nil {
+				log.Warnf("Failed to get the status of %s %s: %v", e.node.Kind, e.node.ID, err)
+				return
+			}
+		}
+		if compiled.Condition == ConditionHeightStalled && !heightsRead {
+			heightsErr = e.readHeights(now, state)
+			heightsRead = true
+		}
+		evaluated[r.Name] = true
+		var holds bool
+		var since time.Time
+		var message string
+		switch compiled.Condition {
+		case ConditionNodeDown:
+			holds = isDown(state)
+			message = fmt.Sprintf("%s %s is down", e.node.Kind, e.node.ID)
+		case ConditionCPUHigh:
+			holds = state.CPUInfo.CPUPercent > compiled.Threshold
+			message = fmt.Sprintf("%s %s uses %.1f%% CPU, over %.1f%%", e.node.Kind, e.node.ID, state.CPUInfo.CPUPercent, compiled.Threshold)
+		case ConditionHeightStalled:
+			if heightsErr != nil {
+				log.Debugf("Failed to read the block heights of %s %s: %v", e.node.Kind, e.node.ID, heightsErr)
+				continue
+			}
+			// a node that's down doesn't commit blocks, node_down covers it
+			holds = !isDown(state) && len(e.heights) > 0
+			since = e.heightsChanged
+			message = fmt.Sprintf("the block height of %s %s didn't change since %s", e.node.Kind, e.node.ID, e.heightsChanged.Format(time.RFC3339))
+		case ConditionCertExpiring:
+			threshold := compiled.Threshold
+			if threshold == 0 {
+				threshold = DefaultCertExpiringDays
+			}
+			holds, message, err = certExpiring(e.node, now, threshold)
+			if err != nil {
+				log.Warnf("Failed to read the certificates of %s %s: %v", e.node.Kind, e.node.ID, err)
+				continue
+			}
+		case ConditionDiskUsageHigh:
+			holds, message = diskUsageHigh(e.node, state.DiskUsage, compiled.Threshold)
+		}
+		e.evaluate(compiled, now, holds, since, message)
+	}
+	// forget the rules removed from the config
+	for name := range e.state {
+		if !evaluated[name] {
+			delete(e.state, name)
+		}
+	}
+}
+
+// evaluate updates the state of a rule and fires its alert once its
+// condition held for its duration
+func (e *Engine) evaluate(r *rule, now time.Time, holds bool, since time.Time, message string) {
+	state, ok := e.state[r.Name]
+	if !ok {
+		state = &ruleState{}
+		e.state[r.Name] = state
+	}
+	if !holds {
+		if state.firing {
+			log.Infof("Alert %s of %s %s resolved", r.Name, e.node.Kind, e.node.ID)
+		}
+		*state = ruleState{}
+		return
+	}
+	if state.since.IsZero() {
+		state.since = now
+	}
+	if !since.IsZero() {
+		state.since = since
+	}
+	state.condition = r.Condition
+	state.message = message
+	if state.firing || now.Sub(state.since) < r.duration {
+		return
+	}
+	state.firing = true
+	state.fired = now
+	e.fire(r, state)
+}
+
+// fire runs the actions of a rule, a failed action is logged and doesn't
+// prevent the others
+func (e *Engine) fire(r *rule, state *ruleState) {
+	for _, action := range r.Actions {
+		switch action.Type {
+		case ActionLog:
+			log.Warnf("Alert %s of %s %s fired: %s", r.Name, e.node.Kind, e.node.ID, state.message)
+		case ActionWebhook:
+			event := notify.NewEvent(notify.EventAlertFired, e.node.Kind, e.node.ID, fmt.Sprintf("Alert %s fired: %s", r.Name, state.message))
+			event.Details = map[string]string{
+				"rule":      r.Name,
+				"condition": r.Condition,
+				"since":     state.since.UTC().Format(time.RFC3339),
+			}
+			notifyConfig := config.NotifyConfig{
+				Webhooks: []config.WebhookConfig{{URL: action.URL, Format: action.Format}},
+			}
+			if err := notify.Send(notifyConfig, event); err != nil {
+				log.Warnf("Alert %s: %v", r.Name, err)
+			}
+		case ActionRestart:
+			// the node is restarted in the background, the status of the
+			// next check tells if it's back
+			go e.restart(r.Name)
+		}
+	}
+}
+
+// restart starts the node again, a node that's down can't be stopped first
+func (e *Engine) restart(ruleName string) {
+	state, err := e.node.Process.Status()
+	if err != nil {
+		log.Warnf("Alert %s: failed to get the status of %s %s: %v", ruleName, e.node.Kind, e.node.ID, err)
+		return
+	}
+	log.Infof("Alert %s: restarting %s %s", ruleName, e.node.Kind, e.node.ID)
+	if isDown(state) {
+		err = e.node.Process.Start()
+	} else {
+		err = e.node.Process.Restart()
+	}
+	if err != nil {
+		log.Errorf("Alert %s: failed to restart %s %s: %v", ruleName, e.node.Kind, e.node.ID, err)
+	}
+}
+
+// isDown tells if the process of the node isn't running
+func isDown(state *node.ProcessState) bool {
+	return state.PID == 0 || state.Status == "Stop"
+}
+
+// readHeights reads the block heights of the channels of the node and
+// records when they last changed
+func (e *Engine) readHeights(now time.Time, state *node.ProcessState) error {
+	if isDown(state) {
+		e.heights = nil
+		return nil
+	}
+	heights, err := node.GetBlockHeights(e.node.Operations)
+	if err != nil {
+		return err
+	}
+	if e.heightsChanged.IsZero() || !sameHeights(e.heights, heights) {
+		e.heightsChanged = now
+	}
+	e.heights = heights
+	return nil
+}
+
+func sameHeights(a map[string]uint64, b map[string]uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for channel, height := range a {
+		if b[channel] != height {
+			return false
+		}
+	}
+	return true
+}
+
+// diskUsageHigh tells if the filesystem of the ledger of a peer is used over
+// threshold percent, or over the threshold of the peer when it's 0
+func diskUsageHigh(n Node, usage *node.DiskUsage, threshold float64) (bool, string) {
+	// only the peers measure their ledger
+	if usage == nil || usage.Error != "" {
+		return false, ""
+	}
+	if threshold == 0 {
+		threshold = usage.Threshold
+	}
+	if usage.UsedPercent <= threshold {
+		return false, ""
+	}
+	return true, fmt.Sprintf("the filesystem of the ledger of %s %s is %.0f%% used, over %.0f%%, with %.1f GiB free", n.Kind, n.ID, usage.UsedPercent, threshold, float64(usage.FreeBytes)/(1<<30))
+}
+
+// certExpiring tells if the TLS or sign certificate of the node expires in
+// less than days
+func certExpiring(n Node, now time.Time, days float64) (bool, string, error) {
+	nodeConfigBytes, err := os.ReadFile(filepath.Join(n.Dir, "config.json"))
+	if err != nil {
+		return false, "", err
+	}
+	nodeConfig := struct {
+		TLSCert  []byte `json:"tlsCert"`
+		SignCert []byte `json:"signCert"`
+	}{}
+	err = json.Unmarshal(nodeConfigBytes, &nodeConfig)
+	if err != nil {
+		return false, "", err
+	}
+	expiring := []string{}
+	for _, c := range []struct {
+		usage string
+		pem   []byte
+	}{
+		{"tls", nodeConfig.TLSCert},
+		{"sign", nodeConfig.SignCert},
+	} {
+		if len(c.pem) == 0 {
+			continue
+		}
+		crt, err := utils.ParseX509Certificate(c.pem)
+		if err != nil {
+			return false, "", errors.Wrapf(err, "failed to parse the %s certificate", c.usage)
+		}
+		left := crt.NotAfter.Sub(now).Hours() / 24
+		if left < days {
+			expiring = append(expiring, fmt.Sprintf("the %s certificate expires in %.0f days", c.usage, left))
+		}
+	}
+	if len(expiring) == 0 {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("%s %s: %s", n.Kind, n.ID, strings.Join(expiring, ", ")), nil
+}
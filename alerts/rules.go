@@ -0,0 +1,156 @@
+package alerts
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/config"
+	"hlf-easy/notify"
+	"time"
+)
+
+// Conditions of the rules
+const (
+	ConditionNodeDown      = "node_down"
+	ConditionCPUHigh       = "cpu_high"
+	ConditionHeightStalled = "height_stalled"
+	ConditionCertExpiring  = "cert_expiring"
+	ConditionDiskUsageHigh = "disk_usage_high"
+)
+
+// Conditions are all the conditions of the rules
+var Conditions = []string{
+	ConditionNodeDown,
+	ConditionCPUHigh,
+	ConditionHeightStalled,
+	ConditionCertExpiring,
+	ConditionDiskUsageHigh,
+}
+
+// Types of the actions
+const (
+	ActionWebhook = "webhook"
+	ActionRestart = "restart"
+	ActionLog     = "log"
+)
+
+// DefaultCertExpiringDays is the threshold of the cert_expiring rules without
+// one
+const DefaultCertExpiringDays = 30
+
+// rule is a validated rule
+type rule struct {
+	config.AlertRule
+	duration time.Duration
+}
+
+// applies tells if the rule applies to a node
+func (r rule) applies(kind string, id string) bool {
+	if r.Kind != "" && r.Kind != kind {
+		return false
+	}
+	if len(r.IDs) == 0 {
+		return true
+	}
+	for _, ruleID := range r.IDs {
+		if ruleID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRule checks the condition, the duration, the threshold and the
+// actions of a rule
+func ValidateRule(r config.AlertRule) error {
+	_, err := compile(r)
+	return err
+}
+
+func compile(r config.AlertRule) (*rule, error) {
+	if r.Name == "" {
+		return nil, errors.New("rule name is required")
+	}
+	switch r.Condition {
+	case ConditionNodeDown, ConditionHeightStalled:
+		if r.Threshold != 0 {
+			return nil, errors.Errorf("rule %s: condition %s has no threshold", r.Name, r.Condition)
+		}
+	case ConditionCPUHigh:
+		if r.Threshold <= 0 {
+			return nil, errors.Errorf("rule %s: condition %s needs a CPU percent threshold", r.Name, r.Condition)
+		}
+	case ConditionCertExpiring:
+		if r.Threshold < 0 {
+			return nil, errors.Errorf("rule %s: the days left threshold can't be negative", r.Name)
+		}
+	case ConditionDiskUsageHigh:
+		if r.Threshold < 0 || r.Threshold > 100 {
+			return nil, errors.Errorf("rule %s: the disk usage threshold must be a percent", r.Name)
+		}
+	default:
+		return nil, errors.Errorf("rule %s: invalid condition %q, expected one of %v", r.Name, r.Condition, Conditions)
+	}
+	if r.Kind != "" && r.Kind != "peer" && r.Kind != "orderer" {
+		return nil, errors.Errorf("rule %s: invalid kind %q, expected peer or orderer", r.Name, r.Kind)
+	}
+	var duration time.Duration
+	if r.For != "" {
+		var err error
+		duration, err = time.ParseDuration(r.For)
+		if err != nil {
+			return nil, errors.Wrapf(err, "rule %s: invalid duration", r.Name)
+		}
+		if duration < 0 {
+			return nil, errors.Errorf("rule %s: the duration can't be negative", r.Name)
+		}
+	}
+	if len(r.Actions) == 0 {
+		return nil, errors.Errorf("rule %s: at least an action is required", r.Name)
+	}
+	for _, action := range r.Actions {
+		switch action.Type {
+		case ActionWebhook:
+			err := notify.ValidateWebhook(config.WebhookConfig{URL: action.URL, Format: action.Format})
+			if err != nil {
+				return nil, errors.Wrapf(err, "rule %s", r.Name)
+			}
+		case ActionRestart, ActionLog:
+			if action.URL != "" || action.Format != "" {
+				return nil, errors.Errorf("rule %s: only the webhook actions have a URL and a format", r.Name)
+			}
+		default:
+			return nil, errors.Errorf("rule %s: invalid action %q, expected %s, %s or %s", r.Name, action.Type, ActionWebhook, ActionRestart, ActionLog)
+		}
+	}
+	return &rule{AlertRule: r, duration: duration}, nil
+}
+
+// ValidateConfig checks the rules of a config and that their names are
+// unique
+func ValidateConfig(alertsConfig config.AlertsConfig) error {
+	names := map[string]bool{}
+	for _, r := range alertsConfig.Rules {
+		if err := ValidateRule(r); err != nil {
+			return err
+		}
+		if names[r.Name] {
+			return errors.Errorf("rule %s is defined twice", r.Name)
+		}
+		names[r.Name] = true
+	}
+	return nil
+}
+
+// ParseRules reads a rules file, in YAML or JSON
+func ParseRules(rulesBytes []byte) (*config.AlertsConfig, error) {
+	alertsConfig := &config.AlertsConfig{}
+	err := yaml.Unmarshal(rulesBytes, alertsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid rules file")
+	}
+	err = ValidateConfig(*alertsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return alertsConfig, nil
+}
@@ -0,0 +1,98 @@
+package anomaly
+
+import (
+	"hlf-easy/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateRule(t *testing.T) {
+	for _, r := range DefaultRules {
+		if err := ValidateRule(r); err != nil {
+			t.Fatalf("expected default rule %s to be valid, got %v", r.Name, err)
+		}
+	}
+	invalid := []config.AnomalyRule{
+		{Pattern: "panic", Severity: SeverityCritical},
+		{Name: "bad-pattern", Pattern: "(", Severity: SeverityCritical},
+		{Name: "bad-severity", Pattern: "panic", Severity: "fatal"},
+		{Name: "no-window", Pattern: "reconnect", Severity: SeverityWarning, Threshold: 3},
+		{Name: "bad-window", Pattern: "reconnect", Severity: SeverityWarning, Window: "soon"},
+	}
+	for _, r := range invalid {
+		if err := ValidateRule(r); err == nil {
+			t.Errorf("expected %+v to be invalid", r)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	override := config.AnomalyRule{Name: "panic", Pattern: "PANIC", Severity: SeverityInfo}
+	custom := config.AnomalyRule{Name: "endorsement", Pattern: "endorsement failure", Severity: SeverityWarning}
+	rules := Rules(config.AnomalyConfig{Rules: []config.AnomalyRule{override, custom}})
+	if len(rules) != len(DefaultRules)+1 {
+		t.Fatalf("expected the defaults with panic overridden plus the custom rule, got %d rules", len(rules))
+	}
+	for _, r := range rules {
+		if r.Name == "panic" && r.Severity != SeverityInfo {
+			t.Fatal("expected the panic rule to be overridden")
+		}
+	}
+	rules = Rules(config.AnomalyConfig{DisableDefaultRules: true, Rules: []config.AnomalyRule{custom}})
+	if len(rules) != 1 || rules[0].Name != "endorsement" {
+		t.Fatalf("expected only the custom rule, got %v", rules)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	var alerts []Alert
+	s, err := NewScanner(DefaultRules, func(alert Alert) {
+		alerts = append(alerts, alert)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	stderr := s.NewStream()
+	stdout := s.NewStream()
+	// lines split across writes and interleaved with another output
+	_, _ = stderr.Write([]byte("2024-01-01 INFO peer started\n2024-01-01 ERRO failed to ver"))
+	_, _ = stdout.Write([]byte("unrelated output\n"))
+	_, _ = stderr.Write([]byte("ify signature\n"))
+	if len(alerts) != 1 || alerts[0].Rule != "failed-to-verify" || alerts[0].Line != "2024-01-01 ERRO failed to verify signature" {
+		t.Fatalf("expected the failed verification alert, got %+v", alerts)
+	}
+
+	// 4 reconnects in the window don't raise an alert
+	for i := 0; i < 4; i++ {
+		_, _ = stderr.Write([]byte("WARN could not connect to peer0\n"))
+		now = now.Add(time.Minute)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("expected no reconnect alert below the threshold, got %+v", alerts)
+	}
+	// the first ones leave the window
+	now = now.Add(2 * time.Minute)
+	_, _ = stderr.Write([]byte("WARN could not connect to peer0\n"))
+	if len(alerts) != 1 {
+		t.Fatalf("expected the matches out of the window not to count, got %+v", alerts)
+	}
+	for i := 0; i < 4; i++ {
+		_, _ = stderr.Write([]byte("WARN could not connect to peer0\n"))
+	}
+	if len(alerts) != 2 || alerts[1].Rule != "repeated-reconnects" || alerts[1].Count != 5 {
+		t.Fatalf("expected the reconnect alert, got %+v", alerts)
+	}
+
+	_, _ = stderr.Write([]byte("panic: runtime error: invalid memory address"))
+	stderr.Flush()
+	if len(alerts) != 3 || alerts[2].Severity != SeverityCritical {
+		t.Fatalf("expected the panic alert on flush, got %+v", alerts)
+	}
+	if got := s.Alerts(); len(got) != 3 || !strings.HasPrefix(got[2].Line, "panic:") {
+		t.Fatalf("expected the scanner to keep the alerts, got %+v", got)
+	}
+}
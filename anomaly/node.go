@@ -0,0 +1,33 @@
+package anomaly
+
+import (
+	"fmt"
+	"hlf-easy/log"
+	"hlf-easy/notify"
+	"hlf-easy/utils"
+)
+
+// NewNodeScanner returns a scanner with the rules of the host that logs the
+// alerts of a node and notifies them to the webhooks
+func NewNodeScanner(kind string, id string) (*Scanner, error) {
+	anomalyConfig, err := utils.GetAnomalyConfig()
+	if err != nil {
+		return nil, err
+	}
+	return NewScanner(Rules(*anomalyConfig), func(alert Alert) {
+		log.Warnf("Anomaly %s (%s) in the logs of %s %s: %s", alert.Rule, alert.Severity, kind, id, alert.Line)
+		event := notify.NewEvent(
+			notify.EventLogAnomaly,
+			kind,
+			id,
+			fmt.Sprintf("Anomaly %s detected in the logs of %s %s", alert.Rule, kind, id),
+		)
+		event.Details = map[string]string{
+			"rule":     alert.Rule,
+			"severity": alert.Severity,
+			"count":    fmt.Sprint(alert.Count),
+			"line":     alert.Line,
+		}
+		go notify.Notify(event)
+	})
+}
@@ -0,0 +1,117 @@
+package anomaly
+
+import (
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"regexp"
+	"time"
+)
+
+// Severities of the rules
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// DefaultRules detect the problems of Fabric nodes that don't show in the
+// status of their process
+var DefaultRules = []config.AnomalyRule{
+	{
+		Name:     "panic",
+		Pattern:  `\bpanic:|\bPANI\b`,
+		Severity: SeverityCritical,
+	},
+	{
+		Name:     "failed-to-verify",
+		Pattern:  `(?i)failed to verify`,
+		Severity: SeverityWarning,
+	},
+	{
+		Name:      "repeated-reconnects",
+		Pattern:   `(?i)(reconnect|connection refused|failed to connect|could not connect)`,
+		Severity:  SeverityWarning,
+		Threshold: 5,
+		Window:    "5m",
+	},
+	{
+		Name:     "disk-full",
+		Pattern:  `(?i)no space left on device`,
+		Severity: SeverityCritical,
+	},
+}
+
+// rule is a compiled rule
+type rule struct {
+	config.AnomalyRule
+	regexp    *regexp.Regexp
+	window    time.Duration
+	threshold int
+	// matches are the times of the matches in the window
+	matches []time.Time
+}
+
+// ValidateRule checks the pattern, severity, threshold and window of a rule
+func ValidateRule(r config.AnomalyRule) error {
+	_, err := compile(r)
+	return err
+}
+
+func compile(r config.AnomalyRule) (*rule, error) {
+	if r.Name == "" {
+		return nil, errors.New("rule name is required")
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid pattern of rule %s", r.Name)
+	}
+	switch r.Severity {
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return nil, errors.Errorf("invalid severity %q of rule %s, expected %s, %s or %s", r.Severity, r.Name, SeverityInfo, SeverityWarning, SeverityCritical)
+	}
+	if r.Threshold < 0 {
+		return nil, errors.Errorf("threshold of rule %s can't be negative", r.Name)
+	}
+	threshold := r.Threshold
+	if threshold == 0 {
+		threshold = 1
+	}
+	var window time.Duration
+	if r.Window != "" {
+		window, err = time.ParseDuration(r.Window)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid window of rule %s", r.Name)
+		}
+		if window <= 0 {
+			return nil, errors.Errorf("window of rule %s must be positive", r.Name)
+		}
+	}
+	if threshold > 1 && window == 0 {
+		return nil, errors.Errorf("rule %s needs a window to count %d matches", r.Name, threshold)
+	}
+	return &rule{
+		AnomalyRule: r,
+		regexp:      re,
+		window:      window,
+		threshold:   threshold,
+	}, nil
+}
+
+// Rules returns the rules of a config, the default rules are replaced by the
+// configured rules of the same name
+func Rules(anomalyConfig config.AnomalyConfig) []config.AnomalyRule {
+	rules := []config.AnomalyRule{}
+	configured := map[string]bool{}
+	for _, r := range anomalyConfig.Rules {
+		configured[r.Name] = true
+	}
+	if !anomalyConfig.DisableDefaultRules {
+		for _, r := range DefaultRules {
+			if !configured[r.Name] {
+				rules = append(rules, r)
+			}
+		}
+	}
+	return append(rules, anomalyConfig.Rules...)
+}
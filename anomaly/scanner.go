@@ -0,0 +1,156 @@
+package anomaly
+
+import (
+	"bytes"
+	"hlf-easy/config"
+	"sync"
+	"time"
+)
+
+// maxAlerts is the number of alerts kept by a scanner
+const maxAlerts = 100
+
+// maxLineLength bounds the partial line buffered by a scanner
+const maxLineLength = 64 * 1024
+
+// Alert is raised when a rule matches the logs of a node threshold times
+// within its window
+type Alert struct {
+	Time     time.Time `json:"time"`
+	Rule     string    `json:"rule"`
+	Severity string    `json:"severity"`
+	// Count is the number of matches that raised the alert
+	Count int `json:"count"`
+	// Line is the last matching log line
+	Line string `json:"line"`
+}
+
+// Scanner matches the log lines written to it against the rules, it can be
+// used as the output of a node process
+type Scanner struct {
+	mu      sync.Mutex
+	rules   []*rule
+	alerts  []Alert
+	onAlert func(Alert)
+	now     func() time.Time
+	// stream buffers the partial line written to the scanner itself
+	stream *Stream
+}
+
+// NewScanner compiles the rules, onAlert is called for each alert
+func NewScanner(rules []config.AnomalyRule, onAlert func(Alert)) (*Scanner, error) {
+	s := &Scanner{
+		onAlert: onAlert,
+		now:     time.Now,
+	}
+	for _, r := range rules {
+		compiled, err := compile(r)
+		if err != nil {
+			return nil, err
+		}
+		s.rules = append(s.rules, compiled)
+	}
+	s.stream = s.NewStream()
+	return s, nil
+}
+
+// Stream is an output scanned by a scanner, each output needs its own stream
+// so their partial lines aren't mixed
+type Stream struct {
+	scanner *Scanner
+	partial []byte
+}
+
+// NewStream returns a writer for an output of the node, e.g. its stderr
+func (s *Scanner) NewStream() *Stream {
+	return &Stream{scanner: s}
+}
+
+// Write scans the complete lines of p, the last line is kept until it's
+// completed by the next write
+func (st *Stream) Write(p []byte) (int, error) {
+	s := st.scanner
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := append(st.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		s.scanLine(string(bytes.TrimRight(data[:i], "\r")))
+		data = data[i+1:]
+	}
+	if len(data) > maxLineLength {
+		s.scanLine(string(data))
+		data = nil
+	}
+	st.partial = append([]byte{}, data...)
+	return len(p), nil
+}
+
+// Flush scans the partial line, e.g. the last line of a file without a new
+// line
+func (st *Stream) Flush() {
+	s := st.scanner
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(st.partial) > 0 {
+		s.scanLine(string(st.partial))
+		st.partial = nil
+	}
+}
+
+// Write scans the lines of a single output
+func (s *Scanner) Write(p []byte) (int, error) {
+	return s.stream.Write(p)
+}
+
+// Flush scans the partial line of a single output
+func (s *Scanner) Flush() {
+	s.stream.Flush()
+}
+
+func (s *Scanner) scanLine(line string) {
+	now := s.now()
+	for _, r := range s.rules {
+		if !r.regexp.MatchString(line) {
+			continue
+		}
+		r.matches = append(r.matches, now)
+		if r.window > 0 {
+			// drop the matches out of the window
+			start := 0
+			for start < len(r.matches) && now.Sub(r.matches[start]) > r.window {
+				start++
+			}
+			r.matches = r.matches[start:]
+		}
+		if len(r.matches) < r.threshold {
+			continue
+		}
+		alert := Alert{
+			Time:     now,
+			Rule:     r.Name,
+			Severity: r.Severity,
+			Count:    len(r.matches),
+			Line:     line,
+		}
+		// the next alert of the rule needs threshold new matches
+		r.matches = nil
+		s.alerts = append(s.alerts, alert)
+		if len(s.alerts) > maxAlerts {
+			s.alerts = s.alerts[len(s.alerts)-maxAlerts:]
+		}
+		if s.onAlert != nil {
+			s.onAlert(alert)
+		}
+	}
+}
+
+// Alerts returns the latest alerts, the oldest first
+func (s *Scanner) Alerts() []Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Alert{}, s.alerts...)
+}
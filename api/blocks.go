@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"hlf-easy/auth"
+	"hlf-easy/explorer"
+	"net/http"
+	"strconv"
+)
+
+// parseBlockNumber parses the number of a block, newest for the last one
+func parseBlockNumber(value string) (int64, bool) {
+	if value == "newest" {
+		return explorer.Newest, true
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	return number, err == nil && number >= 0
+}
+
+// addBlockRoutes serves the blocks of the channels of a peer, they're read
+// with the admin identity managed for the peer
+func addBlockRoutes(r *gin.Engine, peerID string) {
+	ledgerOptions := func(c *gin.Context) explorer.Options {
+		return explorer.Options{PeerID: peerID, Channel: c.Param("channel")}
+	}
+	r.GET("/channels/:channel/height", auth.Allow(auth.ResourceChannels), func(c *gin.Context) {
+		height, err := explorer.GetHeight(ledgerOptions(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"channel": c.Param("channel"),
+			"height":  height,
+		})
+	})
+	r.GET("/channels/:channel/config", auth.Allow(auth.ResourceChannels), func(c *gin.Context) {
+		block, err := explorer.GetConfigBlock(ledgerOptions(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		config, err := explorer.ConfigFromBlock(block)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		var buf bytes.Buffer
+		if err := explorer.DecodeConfig(&buf, config); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", buf.Bytes())
+	})
+	r.GET("/channels/:channel/blocks/:number", auth.Allow(auth.ResourceChannels), func(c *gin.Context) {
+		number, ok := parseBlockNumber(c.Param("number"))
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid block number, expected a number or newest",
+			})
+			return
+		}
+		block, err := explorer.GetBlock(ledgerOptions(c), number)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		var buf bytes.Buffer
+		if err := explorer.DecodeBlock(&buf, block); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", buf.Bytes())
+	})
+	r.GET("/channels/:channel/blocks/:number/transactions", auth.Allow(auth.ResourceChannels), func(c *gin.Context) {
+		number, ok := parseBlockNumber(c.Param("number"))
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid block number, expected a number or newest",
+			})
+			return
+		}
+		block, err := explorer.GetBlock(ledgerOptions(c), number)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		transactions, err := explorer.Transactions(block)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"number":       block.Header.Number,
+			"transactions": transactions,
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang/protobuf/proto"
+	"hlf-easy/audit"
+	"hlf-easy/auth"
+	"hlf-easy/channel"
+	"hlf-easy/node"
+	"hlf-easy/utils"
+	"net/http"
+	"strconv"
+)
+
+// addConfigUpdateRoutes lets the other orgs of a channel collect the
+// signature of the org of the peer on a config update, it's signed with the
+// admin identity managed for the peer
+func addConfigUpdateRoutes(r *gin.Engine, peerID string, mspID string) {
+	r.POST("/config-updates/sign", auth.Allow(auth.ResourceIdentities), func(c *gin.Context) {
+		body := struct {
+			// Tx is the config update transaction in protobuf, base64 in JSON
+			Tx []byte `json:"tx"`
+		}{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		env, err := channel.ReadConfigUpdateTx(body.Tx)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		identityPath, err := node.ManagedAdminIdentity(peerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		crt, key, err := utils.ReadIdentity(identityPath)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		signed, err := channel.SignConfigUpdate(env, channel.Identity{MSPID: mspID, Cert: crt, Key: key})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		signers, err := channel.ConfigUpdateSigners(signed)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Set(audit.ContextKeyParams, map[string]string{"signatures": strconv.Itoa(len(signers))})
+		txBytes, err := proto.Marshal(signed)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"tx":      txBytes,
+			"signers": signers,
+		})
+	})
+}
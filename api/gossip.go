@@ -0,0 +1,29 @@
+package api
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"hlf-easy/auth"
+	"hlf-easy/discover"
+	"net/http"
+)
+
+// addGossipRoutes serves the gossip mesh of the peers of the org of a peer
+// on the host, their membership is queried with the admin identities managed
+// for them
+func addGossipRoutes(r *gin.Engine, mspID string) {
+	r.GET("/gossip/mesh", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), discover.DefaultTimeout)
+		defer cancel()
+		mesh, err := discover.GetMesh(ctx, mspID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"peers": mesh,
+		})
+	})
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"hlf-easy/node"
+	"net/http"
+	"time"
+)
+
+// getStatusHistory returns the status samples of a node, filtered with the
+// since query parameter as RFC 3339 or with last as a duration, e.g. 15m
+func getStatusHistory(history *node.StatusHistory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		since := time.Time{}
+		if value := c.Query("since"); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "invalid since, expected RFC 3339: " + err.Error(),
+				})
+				return
+			}
+			since = t
+		} else if value := c.Query("last"); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "invalid last, expected a duration: " + err.Error(),
+				})
+				return
+			}
+			since = time.Now().Add(-d)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"samples": history.Samples(since),
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"hlf-easy/resources"
+	"net/http"
+)
+
+// getHostUtilization returns the capacity of the host and the resources
+// reserved by all its nodes
+func getHostUtilization(c *gin.Context) {
+	utilization, err := resources.GetUtilization()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, utilization)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"hlf-easy/auth"
+	"hlf-easy/lock"
+	"hlf-easy/log"
+	"net/http"
+	"strings"
+)
+
+// lockNode holds the lock of the node while an action of the management API
+// runs. An action is refused with the holder of the lock when another
+// operation holds it, unless the request carries the token of the lock
+func lockNode(kind string, id string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		operation := kind + "." + strings.TrimPrefix(c.FullPath(), "/")
+		l, err := lock.Acquire(kind, id, operation, c.GetString(auth.ContextKeyActor), 0, c.GetHeader(lock.Header))
+		if err != nil {
+			var locked *lock.LockedError
+			if errors.As(err, &locked) {
+				holder := locked.Holder
+				holder.Token = ""
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+					"error":  err.Error(),
+					"holder": holder,
+				})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		// the lock is released even when the handler panics
+		defer func() {
+			if err := l.Release(); err != nil {
+				log.Warnf("Failed to release the lock of %s %s: %v", kind, id, err)
+			}
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"hlf-easy/audit"
+	"hlf-easy/auth"
+	"hlf-easy/node"
+	"net/http"
+)
+
+// addLogSpecRoutes serves the logging spec of a node. A spec changed through
+// the management API is persisted, so it's applied again when the node
+// restarts
+func addLogSpecRoutes(r *gin.Engine, kind string, id string, nodeDir string, operations node.OperationsEndpoint) {
+	r.GET("/logspec", auth.Allow(auth.ResourceLogSpec), func(c *gin.Context) {
+		persisted, err := node.GetLogSpec(nodeDir)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		// the active spec is unknown while the node is stopped
+		active, _ := node.GetOperationsLogSpec(operations)
+		c.JSON(http.StatusOK, gin.H{
+			"active":    active,
+			"persisted": persisted,
+			"start":     node.GetStartLogSpec(nodeDir),
+		})
+	})
+	r.PUT("/logspec", auth.Allow(auth.ResourceLogSpec), lockNode(kind, id), func(c *gin.Context) {
+		body := struct {
+			Spec string `json:"spec"`
+		}{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Set(audit.ContextKeyParams, map[string]string{"spec": body.Spec})
+		if err := node.ValidateLogSpec(body.Spec); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err := node.SaveLogSpec(nodeDir, body.Spec, c.GetString(auth.ContextKeyActor)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		applied := node.SetOperationsLogSpec(operations, body.Spec) == nil
+		persisted, err := node.GetLogSpec(nodeDir)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"persisted": persisted,
+			// a stopped node gets the spec on its next start
+			"applied": applied,
+		})
+	})
+	r.DELETE("/logspec", auth.Allow(auth.ResourceLogSpec), lockNode(kind, id), func(c *gin.Context) {
+		if err := node.ResetLogSpec(nodeDir); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		applied := node.SetOperationsLogSpec(operations, node.DefaultLogSpec) == nil
+		c.JSON(http.StatusOK, gin.H{
+			"start":   node.DefaultLogSpec,
+			"applied": applied,
+		})
+	})
+}
@@ -3,14 +3,19 @@ package api
 import (
 	"embed"
 	"encoding/json"
-	"fmt"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/static"
 	"github.com/gin-gonic/gin"
 	"github.com/hyperledger/fabric-lib-go/healthz"
 	"github.com/hyperledger/fabric/core/operations"
+	"hlf-easy/alerts"
+	"hlf-easy/anomaly"
+	"hlf-easy/audit"
+	"hlf-easy/auth"
 	"hlf-easy/config"
+	"hlf-easy/errdefs"
 	"hlf-easy/node"
+	"hlf-easy/tasks"
 	"hlf-easy/ui"
 	"io/ioutil"
 	"net/http"
@@ -19,12 +24,12 @@ import (
 )
 
 type OrdererClient struct {
-	OperationsAddress string
-	OrdererAddress    string
+	Operations     node.OperationsEndpoint
+	OrdererAddress string
 }
 
 func (pc *OrdererClient) GetVersionInfo() (*operations.VersionInfoHandler, error) {
-	resp, err := http.Get(pc.OperationsAddress + "/version")
+	resp, err := pc.Operations.Client().Get(pc.Operations.URL("/version"))
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +47,7 @@ func (pc *OrdererClient) GetVersionInfo() (*operations.VersionInfoHandler, error
 }
 
 func (pc *OrdererClient) GetHealthz() (*healthz.HealthStatus, error) {
-	resp, err := http.Get(pc.OperationsAddress + "/healthz")
+	resp, err := pc.Operations.Client().Get(pc.Operations.URL("/healthz"))
 	if err != nil {
 		return nil, err
 	}
@@ -74,6 +79,12 @@ func getHandlerFuncForOrdererFile(opts config.StartOrdererOpts, filename string)
 	}
 }
 
+// newOrdererOperationsEndpoint returns the operations endpoint of the
+// orderer, it's served over plain HTTP
+func newOrdererOperationsEndpoint(startOptions config.OrdererStartOptions) node.OperationsEndpoint {
+	return node.OperationsEndpoint{Address: startOptions.OperationsListenAddress}
+}
+
 func NewOrdererRouter(
 	node *node.OrdererNode,
 	cmdOrdererStdout *config.SaveOutputWriter,
@@ -81,35 +92,39 @@ func NewOrdererRouter(
 	startOptions config.OrdererStartOptions,
 	opts config.StartOrdererOpts,
 	views embed.FS,
+	history *node.StatusHistory,
+	scanner *anomaly.Scanner,
+	scheduler *tasks.Scheduler,
+	alerting *alerts.Engine,
 ) (*gin.Engine, error) {
 	r := gin.Default()
 	peerClient := &OrdererClient{
-		OperationsAddress: fmt.Sprintf("http://%s", startOptions.OperationsListenAddress),
-		OrdererAddress:    startOptions.ListenAddress,
+		Operations:     newOrdererOperationsEndpoint(startOptions),
+		OrdererAddress: startOptions.ListenAddress,
 	}
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true                                                   // Allow all origins
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"} // Specify what methods are allowed
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
 
 	r.Use(cors.New(config))
-	r.GET("/tls.crt", getHandlerFuncForOrdererFile(opts, "tls.crt"))
-	r.GET("/tlscacert.crt", getHandlerFuncForOrdererFile(opts, "tlscacerts/cacert.pem"))
-	r.GET("/cacert.crt", getHandlerFuncForOrdererFile(opts, "cacerts/cacert.pem"))
-	r.GET("/sign.crt", getHandlerFuncForOrdererFile(opts, "signcerts/cert.pem"))
-	r.GET("/core.yaml", getHandlerFuncForOrdererFile(opts, "core.yaml"))
-	r.POST("/restart", func(context *gin.Context) {
-		err := node.Stop()
-		if err != nil {
-			context.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-			})
-			return
-		}
-		err = node.Start()
+	authenticator, err := auth.NewAuthenticator(startOptions.Auth, auth.UIFiles)
+	if err != nil {
+		return nil, err
+	}
+	r.Use(audit.Middleware("orderer", startOptions.ID))
+	r.Use(authenticator.Middleware())
+	r.GET("/tls.crt", auth.Allow(auth.ResourceNodes), getHandlerFuncForOrdererFile(opts, "tls.crt"))
+	r.GET("/tlscacert.crt", auth.Allow(auth.ResourceNodes), getHandlerFuncForOrdererFile(opts, "tlscacerts/cacert.pem"))
+	r.GET("/cacert.crt", auth.Allow(auth.ResourceNodes), getHandlerFuncForOrdererFile(opts, "cacerts/cacert.pem"))
+	r.GET("/sign.crt", auth.Allow(auth.ResourceNodes), getHandlerFuncForOrdererFile(opts, "signcerts/cert.pem"))
+	r.GET("/core.yaml", auth.Allow(auth.ResourceNodes), getHandlerFuncForOrdererFile(opts, "core.yaml"))
+	r.POST("/restart", auth.Allow(auth.ResourceNodes), lockNode("orderer", startOptions.ID), func(context *gin.Context) {
+		err := node.Restart()
 		if err != nil {
-			context.JSON(http.StatusInternalServerError, gin.H{
+			context.JSON(errdefs.HTTPStatus(err), gin.H{
 				"error": err.Error(),
+				"code":  errdefs.Code(err),
 			})
 			return
 		}
@@ -117,11 +132,12 @@ func NewOrdererRouter(
 			"success": true,
 		})
 	})
-	r.POST("/stop", func(context *gin.Context) {
+	r.POST("/stop", auth.Allow(auth.ResourceNodes), lockNode("orderer", startOptions.ID), func(context *gin.Context) {
 		err := node.Stop()
 		if err != nil {
-			context.JSON(http.StatusInternalServerError, gin.H{
+			context.JSON(errdefs.HTTPStatus(err), gin.H{
 				"error": err.Error(),
+				"code":  errdefs.Code(err),
 			})
 			return
 		}
@@ -129,11 +145,12 @@ func NewOrdererRouter(
 			"success": true,
 		})
 	})
-	r.POST("/start", func(context *gin.Context) {
+	r.POST("/start", auth.Allow(auth.ResourceNodes), lockNode("orderer", startOptions.ID), func(context *gin.Context) {
 		err := node.Start()
 		if err != nil {
-			context.JSON(http.StatusInternalServerError, gin.H{
+			context.JSON(errdefs.HTTPStatus(err), gin.H{
 				"error": err.Error(),
+				"code":  errdefs.Code(err),
 			})
 			return
 		}
@@ -141,7 +158,7 @@ func NewOrdererRouter(
 			"success": true,
 		})
 	})
-	r.GET("/status", func(context *gin.Context) {
+	r.GET("/status", auth.Allow(auth.ResourceNodes), func(context *gin.Context) {
 		status, err := node.Status()
 		if err != nil {
 			context.JSON(http.StatusBadRequest, gin.H{
@@ -151,7 +168,21 @@ func NewOrdererRouter(
 		}
 		context.JSON(http.StatusOK, status)
 	})
-	r.GET("/config", func(c *gin.Context) {
+	r.GET("/status/history", auth.Allow(auth.ResourceNodes), getStatusHistory(history))
+	r.GET("/audit", auth.Allow(auth.ResourceAudit), audit.Handler)
+	addTaskRoutes(r, "orderer", startOptions.ID, scheduler)
+	addLogSpecRoutes(r, "orderer", startOptions.ID, opts.MSPConfigPath, peerClient.Operations)
+	r.GET("/anomalies", auth.Allow(auth.ResourceAlerts), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"alerts": scanner.Alerts(),
+		})
+	})
+	r.GET("/alerts", auth.Allow(auth.ResourceAlerts), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"alerts": alerting.Alerts(),
+		})
+	})
+	r.GET("/config", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
 		conf, err := node.GetConfig()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -181,7 +212,7 @@ func NewOrdererRouter(
 			"startOptions": startOptions,
 		})
 	})
-	r.GET("/logs", func(c *gin.Context) {
+	r.GET("/logs", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"stdout": string(cmdOrdererStdout.GetSavedOutput()),
 			"stderr": string(cmdOrdererStderr.GetSavedOutput()),
@@ -189,7 +220,7 @@ func NewOrdererRouter(
 	})
 	fileSystem := ui.NewFileSystemUI(views, "web")
 
-	r.GET("/healthz", func(c *gin.Context) {
+	r.GET("/healthz", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
 		version, err := peerClient.GetHealthz()
 		if err != nil {
 			// return generic error in this gin route
@@ -201,7 +232,9 @@ func NewOrdererRouter(
 		c.JSON(http.StatusOK, version)
 	})
 
-	r.GET("/version", func(c *gin.Context) {
+	r.GET("/host", auth.Allow(auth.ResourceHost), getHostUtilization)
+
+	r.GET("/version", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
 		version, err := peerClient.GetVersionInfo()
 		if err != nil {
 			// return generic error in this gin route
@@ -3,14 +3,19 @@ package api
 import (
 	"embed"
 	"encoding/json"
-	"fmt"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/static"
 	"github.com/gin-gonic/gin"
 	"github.com/hyperledger/fabric-lib-go/healthz"
 	"github.com/hyperledger/fabric/core/operations"
+	"hlf-easy/alerts"
+	"hlf-easy/anomaly"
+	"hlf-easy/audit"
+	"hlf-easy/auth"
 	"hlf-easy/config"
+	"hlf-easy/errdefs"
 	"hlf-easy/node"
+	"hlf-easy/tasks"
 	"hlf-easy/ui"
 	"io"
 	"io/ioutil"
@@ -20,12 +25,12 @@ import (
 )
 
 type PeerClient struct {
-	OperationsAddress string
-	PeerAddress       string
+	Operations  node.OperationsEndpoint
+	PeerAddress string
 }
 
 func (pc *PeerClient) GetVersionInfo() (*operations.VersionInfoHandler, error) {
-	resp, err := http.Get(pc.OperationsAddress + "/version")
+	resp, err := pc.Operations.Client().Get(pc.Operations.URL("/version"))
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +48,7 @@ func (pc *PeerClient) GetVersionInfo() (*operations.VersionInfoHandler, error) {
 }
 
 func (pc *PeerClient) GetHealthz() (*healthz.HealthStatus, error) {
-	resp, err := http.Get(pc.OperationsAddress + "/healthz")
+	resp, err := pc.Operations.Client().Get(pc.Operations.URL("/healthz"))
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +80,19 @@ func getHandlerFuncForFile(opts config.StartPeerOpts, filename string) func(c *g
 	}
 }
 
+// newPeerClient returns the client of the operations endpoint of the peer,
+// served over TLS when it's enabled in its init options
+func newPeerClient(startOptions config.PeerStartOptions, opts config.StartPeerOpts) (*PeerClient, error) {
+	operations, err := node.GetPeerOperationsEndpoint(opts.MSPConfigPath, startOptions.OperationsListenAddress)
+	if err != nil {
+		return nil, err
+	}
+	return &PeerClient{
+		Operations:  operations,
+		PeerAddress: startOptions.ListenAddress,
+	}, nil
+}
+
 func NewPeerRouter(
 	node *node.PeerNode,
 	cmdPeerStdout *config.SaveOutputWriter,
@@ -82,35 +100,39 @@ func NewPeerRouter(
 	startOptions config.PeerStartOptions,
 	opts config.StartPeerOpts,
 	views embed.FS,
+	history *node.StatusHistory,
+	scanner *anomaly.Scanner,
+	scheduler *tasks.Scheduler,
+	alerting *alerts.Engine,
 ) (*gin.Engine, error) {
 	r := gin.Default()
-	peerClient := &PeerClient{
-		OperationsAddress: fmt.Sprintf("http://%s", startOptions.OperationsListenAddress),
-		PeerAddress:       startOptions.ListenAddress,
+	peerClient, err := newPeerClient(startOptions, opts)
+	if err != nil {
+		return nil, err
 	}
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true                                                   // Allow all origins
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"} // Specify what methods are allowed
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type"}
+	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
 
 	r.Use(cors.New(config))
-	r.GET("/tls.crt", getHandlerFuncForFile(opts, "tls.crt"))
-	r.GET("/tlscacert.crt", getHandlerFuncForFile(opts, "tlscacerts/cacert.pem"))
-	r.GET("/cacert.crt", getHandlerFuncForFile(opts, "cacerts/cacert.pem"))
-	r.GET("/sign.crt", getHandlerFuncForFile(opts, "signcerts/cert.pem"))
-	r.GET("/core.yaml", getHandlerFuncForFile(opts, "core.yaml"))
-	r.POST("/restart", func(context *gin.Context) {
-		err := node.Stop()
-		if err != nil {
-			context.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-			})
-			return
-		}
-		err = node.Start()
+	authenticator, err := auth.NewAuthenticator(startOptions.Auth, auth.UIFiles)
+	if err != nil {
+		return nil, err
+	}
+	r.Use(audit.Middleware("peer", startOptions.ID))
+	r.Use(authenticator.Middleware())
+	r.GET("/tls.crt", auth.Allow(auth.ResourceNodes), getHandlerFuncForFile(opts, "tls.crt"))
+	r.GET("/tlscacert.crt", auth.Allow(auth.ResourceNodes), getHandlerFuncForFile(opts, "tlscacerts/cacert.pem"))
+	r.GET("/cacert.crt", auth.Allow(auth.ResourceNodes), getHandlerFuncForFile(opts, "cacerts/cacert.pem"))
+	r.GET("/sign.crt", auth.Allow(auth.ResourceNodes), getHandlerFuncForFile(opts, "signcerts/cert.pem"))
+	r.GET("/core.yaml", auth.Allow(auth.ResourceNodes), getHandlerFuncForFile(opts, "core.yaml"))
+	r.POST("/restart", auth.Allow(auth.ResourceNodes), lockNode("peer", startOptions.ID), func(context *gin.Context) {
+		err := node.Restart()
 		if err != nil {
-			context.JSON(http.StatusInternalServerError, gin.H{
+			context.JSON(errdefs.HTTPStatus(err), gin.H{
 				"error": err.Error(),
+				"code":  errdefs.Code(err),
 			})
 			return
 		}
@@ -118,11 +140,12 @@ func NewPeerRouter(
 			"success": true,
 		})
 	})
-	r.POST("/stop", func(context *gin.Context) {
+	r.POST("/stop", auth.Allow(auth.ResourceNodes), lockNode("peer", startOptions.ID), func(context *gin.Context) {
 		err := node.Stop()
 		if err != nil {
-			context.JSON(http.StatusInternalServerError, gin.H{
+			context.JSON(errdefs.HTTPStatus(err), gin.H{
 				"error": err.Error(),
+				"code":  errdefs.Code(err),
 			})
 			return
 		}
@@ -130,11 +153,12 @@ func NewPeerRouter(
 			"success": true,
 		})
 	})
-	r.POST("/start", func(context *gin.Context) {
+	r.POST("/start", auth.Allow(auth.ResourceNodes), lockNode("peer", startOptions.ID), func(context *gin.Context) {
 		err := node.Start()
 		if err != nil {
-			context.JSON(http.StatusInternalServerError, gin.H{
+			context.JSON(errdefs.HTTPStatus(err), gin.H{
 				"error": err.Error(),
+				"code":  errdefs.Code(err),
 			})
 			return
 		}
@@ -142,7 +166,7 @@ func NewPeerRouter(
 			"success": true,
 		})
 	})
-	r.GET("/status", func(context *gin.Context) {
+	r.GET("/status", auth.Allow(auth.ResourceNodes), func(context *gin.Context) {
 		status, err := node.Status()
 		if err != nil {
 			context.JSON(http.StatusBadRequest, gin.H{
@@ -152,7 +176,24 @@ func NewPeerRouter(
 		}
 		context.JSON(http.StatusOK, status)
 	})
-	r.GET("/config", func(c *gin.Context) {
+	r.GET("/status/history", auth.Allow(auth.ResourceNodes), getStatusHistory(history))
+	r.GET("/audit", auth.Allow(auth.ResourceAudit), audit.Handler)
+	addTaskRoutes(r, "peer", startOptions.ID, scheduler)
+	addLogSpecRoutes(r, "peer", startOptions.ID, opts.MSPConfigPath, peerClient.Operations)
+	addBlockRoutes(r, startOptions.ID)
+	addGossipRoutes(r, startOptions.MSPID)
+	addConfigUpdateRoutes(r, startOptions.ID, startOptions.MSPID)
+	r.GET("/anomalies", auth.Allow(auth.ResourceAlerts), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"alerts": scanner.Alerts(),
+		})
+	})
+	r.GET("/alerts", auth.Allow(auth.ResourceAlerts), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"alerts": alerting.Alerts(),
+		})
+	})
+	r.GET("/config", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
 		conf, err := node.GetConfig()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -182,7 +223,7 @@ func NewPeerRouter(
 			"startOptions": startOptions,
 		})
 	})
-	r.GET("/logs", func(c *gin.Context) {
+	r.GET("/logs", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"stdout": string(cmdPeerStdout.GetSavedOutput()),
 			"stderr": string(cmdPeerStderr.GetSavedOutput()),
@@ -190,7 +231,7 @@ func NewPeerRouter(
 	})
 	fileSystem := ui.NewFileSystemUI(views, "web")
 
-	r.GET("/healthz", func(c *gin.Context) {
+	r.GET("/healthz", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
 		version, err := peerClient.GetHealthz()
 		if err != nil {
 			// return generic error in this gin route
@@ -202,7 +243,9 @@ func NewPeerRouter(
 		c.JSON(http.StatusOK, version)
 	})
 
-	r.GET("/version", func(c *gin.Context) {
+	r.GET("/host", auth.Allow(auth.ResourceHost), getHostUtilization)
+
+	r.GET("/version", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
 		version, err := peerClient.GetVersionInfo()
 		if err != nil {
 			// return generic error in this gin route
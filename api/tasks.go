@@ -0,0 +1,76 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"hlf-easy/auth"
+	"hlf-easy/config"
+	"hlf-easy/tasks"
+	"net/http"
+)
+
+// addTaskRoutes serves the tasks of a node, their history and their manual
+// runs
+func addTaskRoutes(r *gin.Engine, kind string, id string, scheduler *tasks.Scheduler) {
+	r.GET("/tasks", auth.Allow(auth.ResourceTasks), func(c *gin.Context) {
+		statuses, err := scheduler.Tasks()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, statuses)
+	})
+	r.GET("/tasks/history", auth.Allow(auth.ResourceTasks), func(c *gin.Context) {
+		runs, err := tasks.GetHistory(kind, id, c.Query("task"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, runs)
+	})
+	r.PUT("/tasks/:name", auth.Allow(auth.ResourceTasks), func(c *gin.Context) {
+		task := config.TaskConfig{}
+		if err := c.ShouldBindJSON(&task); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		task.Name = c.Param("name")
+		if err := tasks.SaveTask(kind, id, task); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, task)
+	})
+	r.DELETE("/tasks/:name", auth.Allow(auth.ResourceTasks), func(c *gin.Context) {
+		if err := tasks.RemoveTask(kind, id, c.Param("name")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	})
+	r.POST("/tasks/:name/run", auth.Allow(auth.ResourceTasks), func(c *gin.Context) {
+		run, err := scheduler.RunTask(c.Request.Context(), c.Param("name"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		status := http.StatusOK
+		if run.Status == tasks.StatusFailed {
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, run)
+	})
+}
@@ -0,0 +1,218 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Results of the operations
+const (
+	ResultOK      = "ok"
+	ResultError   = "error"
+	ResultDenied  = "denied"
+	ResultStarted = "started"
+)
+
+// Entry is an operation that changed the state of the host
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Actor is who ran the operation, the user of the CLI or the caller of a
+	// management API, e.g. user:alice or token:ops
+	Actor string `json:"actor"`
+	// Operation is the command or the route, e.g. peer.init or peer.restart
+	Operation string            `json:"operation"`
+	Target    string            `json:"target,omitempty"`
+	Params    map[string]string `json:"params,omitempty"`
+	Result    string            `json:"result"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// mu serializes the writes of a process, the writes of different processes
+// are single appends of a line
+var mu sync.Mutex
+
+func getAuditLogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/audit.log"), nil
+}
+
+// Record appends an entry to the audit log of the host, $HOME/hlf-easy/audit.log
+func Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC()
+	entry.Params = RedactParams(entry.Params)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	auditLogPath, err := getAuditLogPath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(auditLogPath), 0755)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	f, err := os.OpenFile(auditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// secretParams are the parts of the names of the params that are never
+// written to the audit log
+var secretParams = []string{"secret", "password", "passphrase", "pin", "token"}
+
+// RedactParams hides the values of the secret params
+func RedactParams(params map[string]string) map[string]string {
+	if len(params) == 0 {
+		return nil
+	}
+	redacted := map[string]string{}
+	for name, value := range params {
+		for _, secret := range secretParams {
+			if strings.Contains(strings.ToLower(name), secret) {
+				value = "***"
+				break
+			}
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// LocalActor is the user running the CLI, with the user that ran sudo
+func LocalActor() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" && sudoUser != name {
+		return "user:" + name + " (sudo " + sudoUser + ")"
+	}
+	return "user:" + name
+}
+
+// Filter selects the entries of the audit log, the empty fields match all
+// the entries
+type Filter struct {
+	Since time.Time
+	Until time.Time
+	// Operation matches the operation and the ones it prefixes, e.g. peer
+	// matches peer.init and peer.restart
+	Operation string
+	Actor     string
+	Target    string
+	// Limit keeps the last entries
+	Limit int
+}
+
+// Match tells if an entry is selected by the filter
+func (f Filter) Match(entry Entry) bool {
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Time.After(f.Until) {
+		return false
+	}
+	if f.Operation != "" && entry.Operation != f.Operation && !strings.HasPrefix(entry.Operation, f.Operation+".") {
+		return false
+	}
+	if f.Actor != "" && entry.Actor != f.Actor {
+		return false
+	}
+	if f.Target != "" && entry.Target != f.Target {
+		return false
+	}
+	return true
+}
+
+// Query returns the entries of the audit log selected by the filter, oldest
+// first
+func Query(f Filter) ([]Entry, error) {
+	entries := []Entry{}
+	auditLogPath, err := getAuditLogPath()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(auditLogPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := Entry{}
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid entry at line %d of %s", lineNumber, auditLogPath)
+		}
+		if f.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[len(entries)-f.Limit:]
+	}
+	return entries, nil
+}
+
+// ParseFilter parses a filter of the CLI or the API, since is a RFC3339 time
+// or a duration before now, e.g. 24h
+func ParseFilter(operation string, actor string, target string, since string, limit string) (Filter, error) {
+	f := Filter{
+		Operation: operation,
+		Actor:     actor,
+		Target:    target,
+	}
+	if since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			f.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+			f.Since = t
+		} else {
+			return f, errors.Errorf("invalid since %q, expected a RFC3339 time or a duration", since)
+		}
+	}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return f, errors.Errorf("invalid limit %q", limit)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/auth"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndQuery(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	now := time.Now()
+	for _, entry := range []Entry{
+		{Time: now.Add(-2 * time.Hour), Actor: "user:alice", Operation: "peer.init", Target: "peer1", Result: ResultOK},
+		{Time: now.Add(-time.Hour), Actor: "token:ops", Operation: "peer.restart", Target: "peer1", Result: ResultOK},
+		{Time: now, Actor: "user:bob", Operation: "ca.enroll", Params: map[string]string{"enroll-secret": "pw", "ca-name": "ca"}, Result: ResultError, Error: "refused"},
+	} {
+		if err := Record(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, _ := os.UserHomeDir()
+	info, err := os.Stat(filepath.Join(home, "hlf-easy/audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the audit log to only be readable by the user, got %s", info.Mode())
+	}
+
+	entries, err := Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Operation != "peer.init" {
+		t.Fatalf("expected the 3 entries oldest first, got %+v", entries)
+	}
+	if entries[2].Params["enroll-secret"] != "***" || entries[2].Params["ca-name"] != "ca" {
+		t.Fatalf("expected the secret params to be redacted, got %v", entries[2].Params)
+	}
+	for _, tc := range []struct {
+		filter   Filter
+		expected int
+	}{
+		{Filter{Operation: "peer"}, 2},
+		{Filter{Operation: "pee"}, 0},
+		{Filter{Operation: "peer.init"}, 1},
+		{Filter{Actor: "token:ops"}, 1},
+		{Filter{Target: "peer1"}, 2},
+		{Filter{Since: now.Add(-90 * time.Minute)}, 2},
+		{Filter{Until: now.Add(-90 * time.Minute)}, 1},
+		{Filter{Limit: 1}, 1},
+	} {
+		entries, err := Query(tc.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != tc.expected {
+			t.Errorf("expected %d entries for %+v, got %d", tc.expected, tc.filter, len(entries))
+		}
+	}
+	entries, err = Query(Filter{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].Operation != "ca.enroll" {
+		t.Fatalf("expected the limit to keep the last entries, got %+v", entries)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter("peer", "", "", "24h", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Limit != 10 || time.Since(f.Since) < 23*time.Hour {
+		t.Fatalf("unexpected filter %+v", f)
+	}
+	f, err = ParseFilter("", "", "", "2024-01-02T15:04:05Z", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Since.Equal(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Fatalf("unexpected since %s", f.Since)
+	}
+	if _, err := ParseFilter("", "", "", "yesterday", ""); err == nil {
+		t.Fatal("expected an invalid since to be refused")
+	}
+	if _, err := ParseFilter("", "", "", "", "-1"); err == nil {
+		t.Fatal("expected an invalid limit to be refused")
+	}
+}
+
+func TestCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := &cobra.Command{Use: "hlf-easy"}
+	peer := &cobra.Command{Use: "peer"}
+	var id, secret string
+	var dryRun bool
+	initCmd := &cobra.Command{
+		Use:  "init",
+		RunE: func(cmd *cobra.Command, args []string) error { return errors.New("enroll failed") },
+	}
+	initCmd.Flags().StringVar(&id, "id", "", "")
+	initCmd.Flags().StringVar(&secret, "enroll-secret", "", "")
+	initCmd.Flags().BoolVar(&dryRun, "dry-run", false, "")
+	status := &cobra.Command{
+		Use:  "status",
+		RunE: func(cmd *cobra.Command, args []string) error { return nil },
+	}
+	peer.AddCommand(initCmd, status)
+	root.AddCommand(peer)
+	Commands(root, map[string]bool{"peer init": false})
+
+	root.SetArgs([]string{"peer", "init", "--id=peer1", "--enroll-secret=pw"})
+	root.SilenceErrors = true
+	root.SilenceUsage = true
+	if err := root.Execute(); err == nil {
+		t.Fatal("expected the error of the command")
+	}
+	root.SetArgs([]string{"peer", "status"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	root.SetArgs([]string{"peer", "init", "--id=peer2", "--dry-run"})
+	root.Execute()
+	entries, err := Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only peer init without --dry-run to be recorded, got %+v", entries)
+	}
+	entry := entries[0]
+	if entry.Operation != "peer.init" || entry.Target != "peer1" || entry.Result != ResultError || entry.Error != "enroll failed" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if entry.Params["id"] != "peer1" || entry.Params["enroll-secret"] != "***" || entry.Actor == "" {
+		t.Fatalf("unexpected params or actor %+v", entry)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware("peer", "peer1"))
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set(auth.ContextKeyActor, "token:ops")
+	})
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	r.GET("/status", ok)
+	r.POST("/restart", ok)
+	r.POST("/start", func(c *gin.Context) { c.String(http.StatusInternalServerError, "failed") })
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/status", nil),
+		httptest.NewRequest(http.MethodPost, "/restart", nil),
+		httptest.NewRequest(http.MethodPost, "/restart?force=true", nil),
+		httptest.NewRequest(http.MethodPost, "/start", nil),
+		httptest.NewRequest(http.MethodPost, "/unknown", nil),
+	} {
+		if req.Method == http.MethodPost && req.URL.RawQuery == "" {
+			req.Header.Set("Authorization", "Bearer x")
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	entries, err := Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected the 3 POST to known routes to be recorded, got %+v", entries)
+	}
+	if entries[0].Operation != "peer.restart" || entries[0].Actor != "token:ops" || entries[0].Target != "peer1" || entries[0].Result != ResultOK {
+		t.Fatalf("unexpected entry %+v", entries[0])
+	}
+	if entries[1].Result != ResultDenied || entries[1].Actor != "unauthenticated" || entries[1].Params["force"] != "true" {
+		t.Fatalf("expected the denied request to be recorded, got %+v", entries[1])
+	}
+	if entries[2].Operation != "peer.start" || entries[2].Result != ResultError {
+		t.Fatalf("expected the failed start to be recorded, got %+v", entries[2])
+	}
+}
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"hlf-easy/log"
+	"strings"
+)
+
+// targetFlags are the flags naming what a command operates on
+var targetFlags = []string{"id", "peer-id", "name", "channel"}
+
+// operationName is the path of a command without the root, e.g. peer.init
+func operationName(cmd *cobra.Command) string {
+	path := strings.Fields(cmd.CommandPath())
+	return strings.Join(path[1:], ".")
+}
+
+// newEntry returns the entry of a command with the flags it was run with
+func newEntry(cmd *cobra.Command, args []string) Entry {
+	entry := Entry{
+		Actor:     LocalActor(),
+		Operation: operationName(cmd),
+		Params:    map[string]string{},
+	}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		entry.Params[f.Name] = f.Value.String()
+	})
+	if len(args) > 0 {
+		entry.Params["args"] = strings.Join(args, " ")
+	}
+	for _, name := range targetFlags {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Value.String() != "" {
+			entry.Target = f.Value.String()
+			break
+		}
+	}
+	// e.g. peer upgrade <id>
+	if entry.Target == "" && len(args) == 1 {
+		entry.Target = args[0]
+	}
+	return entry
+}
+
+func record(entry Entry) {
+	if err := Record(entry); err != nil {
+		log.Warnf("Failed to write the audit log: %v", err)
+	}
+}
+
+// Command records the runs of a command in the audit log. The commands that
+// keep running, e.g. to serve a node, are recorded when they start
+func Command(cmd *cobra.Command, longRunning bool) {
+	runE := cmd.RunE
+	if runE == nil {
+		return
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// a dry run changes nothing and writes nothing, not even the audit log
+		if f := cmd.Flags().Lookup("dry-run"); f != nil && f.Value.String() == "true" {
+			return runE(cmd, args)
+		}
+		entry := newEntry(cmd, args)
+		if longRunning {
+			entry.Result = ResultStarted
+			record(entry)
+			return runE(cmd, args)
+		}
+		err := runE(cmd, args)
+		entry.Result = ResultOK
+		if err != nil {
+			entry.Result = ResultError
+			entry.Error = err.Error()
+		}
+		record(entry)
+		return err
+	}
+}
+
+// Commands records the runs of the commands of a tree by their path, e.g.
+// "peer init", the value tells if the command keeps running
+func Commands(root *cobra.Command, audited map[string]bool) {
+	for _, cmd := range root.Commands() {
+		path := strings.Join(strings.Fields(cmd.CommandPath())[1:], " ")
+		if longRunning, ok := audited[path]; ok {
+			Command(cmd, longRunning)
+		}
+		Commands(cmd, audited)
+	}
+}
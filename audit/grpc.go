@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"hlf-easy/auth"
+	"path"
+)
+
+// UnaryInterceptor records the gRPC calls that change the state of the host,
+// e.g. StartNode is recorded as daemon.StartNode with the string fields of
+// its request as params. It runs before the authentication so the denied
+// calls are recorded too
+func UnaryInterceptor(kind string, readOnly func(fullMethod string) bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if readOnly(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, actor := auth.RecordActor(ctx)
+		resp, err := handler(ctx, req)
+		entry := Entry{
+			Actor:     *actor,
+			Operation: kind + "." + path.Base(info.FullMethod),
+			Params:    map[string]string{},
+			Result:    ResultOK,
+		}
+		if entry.Actor == "" {
+			entry.Actor = "unauthenticated"
+		}
+		if p, ok := peer.FromContext(ctx); ok {
+			entry.Params["remoteAddr"] = p.Addr.String()
+		}
+		if m, ok := req.(proto.Message); ok {
+			m.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+				if fd.Kind() == protoreflect.StringKind && !fd.IsList() {
+					entry.Params[fd.JSONName()] = v.String()
+				}
+				return true
+			})
+		}
+		switch code := status.Code(err); code {
+		case codes.OK:
+		case codes.Unauthenticated, codes.PermissionDenied:
+			entry.Result = ResultDenied
+			entry.Error = code.String()
+		default:
+			entry.Result = ResultError
+			entry.Error = code.String()
+		}
+		record(entry)
+		return resp, err
+	}
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"github.com/gin-gonic/gin"
+	"hlf-easy/auth"
+	"net/http"
+	"strings"
+)
+
+// ContextKeyParams is the key of the gin context where the handlers put the
+// params of the request body to record, e.g. the spec of PUT /logspec
+const ContextKeyParams = "audit.params"
+
+// Middleware records the requests that change the state of a node, e.g.
+// POST /restart is recorded as peer.restart. It runs before the
+// authentication so the denied requests are recorded too
+func Middleware(kind string, id string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		method := c.Request.Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions || c.FullPath() == "" {
+			return
+		}
+		entry := Entry{
+			Actor:     c.GetString(auth.ContextKeyActor),
+			Operation: kind + "." + strings.ReplaceAll(strings.Trim(c.FullPath(), "/"), "/", "."),
+			Target:    id,
+			Params:    map[string]string{},
+			Result:    ResultOK,
+		}
+		if entry.Actor == "" {
+			entry.Actor = "unauthenticated"
+		}
+		entry.Params["remoteAddr"] = c.ClientIP()
+		for _, p := range c.Params {
+			entry.Params[p.Key] = p.Value
+		}
+		for name, values := range c.Request.URL.Query() {
+			entry.Params[name] = strings.Join(values, ",")
+		}
+		if params, ok := c.Get(ContextKeyParams); ok {
+			for name, value := range params.(map[string]string) {
+				entry.Params[name] = value
+			}
+		}
+		status := c.Writer.Status()
+		switch {
+		case status == http.StatusUnauthorized || status == http.StatusForbidden:
+			entry.Result = ResultDenied
+			entry.Error = http.StatusText(status)
+		case status >= http.StatusBadRequest:
+			entry.Result = ResultError
+			entry.Error = http.StatusText(status)
+		}
+		record(entry)
+	}
+}
+
+// Handler serves the entries of the audit log selected by the query params
+// operation, actor, target, since (RFC3339) and limit
+func Handler(c *gin.Context) {
+	f, err := ParseFilter(c.Query("operation"), c.Query("actor"), c.Query("target"), c.Query("since"), c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	entries, err := Query(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
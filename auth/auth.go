@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/log"
+	"hlf-easy/utils"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Modes of authentication of the management APIs
+const (
+	ModeToken = "token"
+	ModeMTLS  = "mtls"
+	ModeNone  = "none"
+)
+
+// CookieName is the cookie the token is read from when there's no
+// Authorization header, it's used by the web UIs
+const CookieName = "hlf-easy-token"
+
+// Authenticator authenticates and authorizes the requests of a management API
+type Authenticator struct {
+	opts config.APIAuthOptions
+	// public tells the requests that don't need authentication, e.g. the
+	// files of the web UI
+	public func(c *gin.Context) bool
+}
+
+// ValidateOptions checks the mode and the TLS files it needs
+func ValidateOptions(opts config.APIAuthOptions) error {
+	switch opts.Mode {
+	case ModeToken, ModeNone:
+	case ModeMTLS:
+		if opts.TLSCert == "" || opts.TLSKey == "" || opts.ClientCA == "" {
+			return errors.New("mtls needs --api-tls-cert, --api-tls-key and --api-client-ca")
+		}
+	default:
+		return errors.Errorf("invalid API auth mode %q, expected %s, %s or %s", opts.Mode, ModeToken, ModeMTLS, ModeNone)
+	}
+	if (opts.TLSCert == "") != (opts.TLSKey == "") {
+		return errors.New("--api-tls-cert and --api-tls-key must be set together")
+	}
+	return nil
+}
+
+// NewAuthenticator returns an authenticator, public tells the requests that
+// don't need authentication and can be nil
+func NewAuthenticator(opts config.APIAuthOptions, public func(c *gin.Context) bool) (*Authenticator, error) {
+	err := ValidateOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	switch opts.Mode {
+	case ModeNone:
+		log.Warnf("The management API is not authenticated, anyone who reaches it can operate the node")
+	case ModeToken:
+		apiTokens, err := utils.GetAPITokens()
+		if err != nil {
+			return nil, err
+		}
+		if len(apiTokens.Tokens) == 0 {
+			log.Warnf("There are no API tokens, create one with hlf-easy apitoken create")
+		}
+	}
+	if public == nil {
+		public = func(c *gin.Context) bool { return false }
+	}
+	return &Authenticator{opts: opts, public: public}, nil
+}
+
+// RequestToken returns the bearer token of a request, or its cookie when it
+// has no Authorization header
+func RequestToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if !strings.HasPrefix(header, "Bearer ") {
+			return ""
+		}
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	if cookie, err := r.Cookie(CookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// Identity is the caller of a management API
+type Identity struct {
+	// Name is the token or the client certificate of the caller, e.g.
+	// token:grafana or cert:ops
+	Name string
+	Role string
+}
+
+// Authenticate returns the identity of the caller of a request
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if fromSocket(r) {
+		identity := SocketIdentity
+		return &identity, nil
+	}
+	switch a.opts.Mode {
+	case ModeNone:
+		return &Identity{Name: "anonymous", Role: RoleAdmin}, nil
+	case ModeMTLS:
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			return nil, errors.New("a client certificate is required")
+		}
+		crt := r.TLS.VerifiedChains[0][0]
+		identity := &Identity{Name: "cert:" + crt.Subject.CommonName, Role: RoleViewer}
+		if a.opts.AdminOU != "" && utils.Contains(crt.Subject.OrganizationalUnit, a.opts.AdminOU) {
+			identity.Role = RoleAdmin
+		} else if utils.Contains(crt.Subject.OrganizationalUnit, a.opts.OperatorOU) {
+			identity.Role = RoleOperator
+		}
+		return identity, nil
+	}
+	token := RequestToken(r)
+	if token == "" {
+		return nil, errors.New("a bearer token is required")
+	}
+	apiTokens, err := utils.GetAPITokens()
+	if err != nil {
+		return nil, err
+	}
+	apiToken, err := VerifyToken(apiTokens, token)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{Name: "token:" + apiToken.Name, Role: apiToken.Role}, nil
+}
+
+// Keys of the caller in the gin context, its name and its role
+const (
+	ContextKeyActor = "actor"
+	ContextKeyRole  = "role"
+)
+
+// Middleware authenticates the requests, every route is then authorized on
+// its resource by Allow
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if a.public(c) {
+			c.Next()
+			return
+		}
+		identity, err := a.Authenticate(c.Request)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Set(ContextKeyActor, identity.Name)
+		c.Set(ContextKeyRole, identity.Role)
+		c.Next()
+	}
+}
+
+// UIFiles is the public func of the management APIs of the nodes, their web
+// UI is served by the requests that don't match any route
+func UIFiles(c *gin.Context) bool {
+	return c.Request.Method == http.MethodGet && c.FullPath() == ""
+}
+
+// ListenAndServe serves the API on its Unix socket when it's configured, and
+// on the address of the server when it's set
+func ListenAndServe(srv *http.Server, opts config.APIAuthOptions) error {
+	if opts.Socket == "" {
+		return listenAndServeTCP(srv, opts)
+	}
+	l, err := ListenSocket(opts.Socket)
+	if err != nil {
+		return err
+	}
+	srv.ConnContext = socketConnContext
+	if srv.Addr == "" {
+		return srv.Serve(l)
+	}
+	errs := make(chan error, 2)
+	go func() {
+		errs <- srv.Serve(l)
+	}()
+	go func() {
+		errs <- listenAndServeTCP(srv, opts)
+	}()
+	return <-errs
+}
+
+// listenAndServeTCP serves the API over TLS when it's configured, requiring
+// the client certificates with mtls
+func listenAndServeTCP(srv *http.Server, opts config.APIAuthOptions) error {
+	if opts.TLSCert == "" {
+		return srv.ListenAndServe()
+	}
+	if opts.Mode == ModeMTLS {
+		tlsConfig, err := mtlsConfig(opts)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = tlsConfig
+	}
+	return srv.ListenAndServeTLS(opts.TLSCert, opts.TLSKey)
+}
+
+// mtlsConfig returns the TLS config requiring the client certificates issued
+// by the client CA
+func mtlsConfig(opts config.APIAuthOptions) (*tls.Config, error) {
+	clientCABytes, err := os.ReadFile(opts.ClientCA)
+	if err != nil {
+		return nil, err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(clientCABytes) {
+		return nil, errors.Errorf("no certificate found in %s", opts.ClientCA)
+	}
+	return &tls.Config{
+		ClientCAs:  clientCAs,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
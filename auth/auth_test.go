@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/gin-gonic/gin"
+	"hlf-easy/config"
+	"hlf-easy/utils"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTokens(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	token, apiToken, err := CreateToken("ci", RoleOperator, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiTokens, err := utils.GetAPITokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apiTokens.Tokens) != 1 || apiTokens.Tokens[0].Hash == "" || apiTokens.Tokens[0].Hash == token {
+		t.Fatalf("expected only the hash of the token to be stored, got %+v", apiTokens.Tokens)
+	}
+	home, _ := os.UserHomeDir()
+	info, err := os.Stat(filepath.Join(home, "hlf-easy/apitokens.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the tokens to only be readable by the user, got %s", info.Mode())
+	}
+	verified, err := VerifyToken(apiTokens, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.ID != apiToken.ID || verified.Role != RoleOperator {
+		t.Fatalf("unexpected token %+v", verified)
+	}
+	for _, invalid := range []string{"", "hlfe", token + "x", "other_" + apiToken.ID + "_secret"} {
+		if _, err := VerifyToken(apiTokens, invalid); err == nil {
+			t.Errorf("expected %q to be refused", invalid)
+		}
+	}
+	if _, _, err := CreateToken("ci", "root", time.Now()); err == nil {
+		t.Fatal("expected an unknown role to be refused")
+	}
+	err = RevokeToken(apiToken.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiTokens, err = utils.GetAPITokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyToken(apiTokens, token); err == nil {
+		t.Fatal("expected a revoked token to be refused")
+	}
+	if err := RevokeToken(apiToken.ID); err == nil {
+		t.Fatal("expected revoking an unknown token to fail")
+	}
+}
+
+func newTestRouter(t *testing.T, opts config.APIAuthOptions) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	authenticator, err := NewAuthenticator(opts, UIFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(authenticator.Middleware())
+	r.GET("/status", Allow(ResourceNodes), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.POST("/restart", Allow(ResourceNodes), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.POST("/config-updates/sign", Allow(ResourceIdentities), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.NoRoute(func(c *gin.Context) { c.String(http.StatusOK, "index.html") })
+	return r
+}
+
+func serve(r *gin.Engine, req *http.Request) int {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestTokenMiddleware(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	reader, _, err := CreateToken("grafana", RoleReader, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator, _, err := CreateToken("ops", RoleOperator, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, _, err := CreateToken("root", RoleAdmin, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRouter(t, config.APIAuthOptions{Mode: ModeToken})
+	request := func(method string, path string, token string) *http.Request {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return req
+	}
+	for _, tc := range []struct {
+		req      *http.Request
+		expected int
+	}{
+		{request(http.MethodGet, "/status", ""), http.StatusUnauthorized},
+		{request(http.MethodGet, "/status", "hlfe_x_y"), http.StatusUnauthorized},
+		{request(http.MethodGet, "/status", reader), http.StatusOK},
+		{request(http.MethodPost, "/restart", reader), http.StatusForbidden},
+		{request(http.MethodPost, "/restart", operator), http.StatusOK},
+		{request(http.MethodPost, "/config-updates/sign", operator), http.StatusForbidden},
+		{request(http.MethodPost, "/config-updates/sign", admin), http.StatusOK},
+		{request(http.MethodGet, "/index.html", ""), http.StatusOK},
+		{request(http.MethodPost, "/index.html", ""), http.StatusUnauthorized},
+	} {
+		if code := serve(r, tc.req); code != tc.expected {
+			t.Errorf("expected %d for %s %s, got %d", tc.expected, tc.req.Method, tc.req.URL.Path, code)
+		}
+	}
+	req := request(http.MethodPost, "/restart", "")
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: operator})
+	if code := serve(r, req); code != http.StatusOK {
+		t.Errorf("expected the token of the cookie to be accepted, got %d", code)
+	}
+
+	r = newTestRouter(t, config.APIAuthOptions{Mode: ModeNone})
+	if code := serve(r, request(http.MethodPost, "/restart", "")); code != http.StatusOK {
+		t.Errorf("expected no authentication with mode none, got %d", code)
+	}
+}
+
+func TestMTLSIdentity(t *testing.T) {
+	a := &Authenticator{opts: config.APIAuthOptions{Mode: ModeMTLS, OperatorOU: "admin", AdminOU: "root"}}
+	request := func(ous ...string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		crt := &x509.Certificate{Subject: pkix.Name{CommonName: "client", OrganizationalUnit: ous}}
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{crt}}}
+		return req
+	}
+	identity, err := a.Authenticate(request("client", "admin"))
+	if err != nil || identity.Role != RoleOperator || identity.Name != "cert:client" {
+		t.Fatalf("expected cert:client with the operator role, got %+v %v", identity, err)
+	}
+	identity, err = a.Authenticate(request("client", "admin", "root"))
+	if err != nil || identity.Role != RoleAdmin {
+		t.Fatalf("expected the admin role, got %+v %v", identity, err)
+	}
+	identity, err = a.Authenticate(request("client"))
+	if err != nil || identity.Role != RoleViewer {
+		t.Fatalf("expected the viewer role, got %+v %v", identity, err)
+	}
+	if _, err := a.Authenticate(httptest.NewRequest(http.MethodGet, "/status", nil)); err == nil {
+		t.Fatal("expected a request without a client certificate to be refused")
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	for _, opts := range []config.APIAuthOptions{
+		{Mode: "basic"},
+		{Mode: ModeMTLS, TLSCert: "tls.crt", TLSKey: "tls.key"},
+		{Mode: ModeToken, TLSCert: "tls.crt"},
+	} {
+		if err := ValidateOptions(opts); err == nil {
+			t.Errorf("expected %+v to be invalid", opts)
+		}
+	}
+	for _, opts := range []config.APIAuthOptions{
+		{Mode: ModeToken},
+		{Mode: ModeNone},
+		{Mode: ModeMTLS, TLSCert: "tls.crt", TLSKey: "tls.key", ClientCA: "ca.crt"},
+	} {
+		if err := ValidateOptions(opts); err != nil {
+			t.Errorf("expected %+v to be valid: %v", opts, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"hlf-easy/config"
+	"net/http"
+)
+
+type actorContextKey struct{}
+
+// RecordActor returns a context where the gRPC interceptor of an
+// authenticator records the name of the caller, it's read by the audit
+// interceptor once the call returned
+func RecordActor(ctx context.Context) (context.Context, *string) {
+	actor := new(string)
+	return context.WithValue(ctx, actorContextKey{}, actor), actor
+}
+
+// grpcRequest returns the request a gRPC call is authenticated as, with the
+// token of its authorization metadata, its client certificate and whether it
+// was received on the Unix socket
+func grpcRequest(ctx context.Context) (*http.Request, error) {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr.Network() == "unix" {
+		ctx = context.WithValue(ctx, socketContextKey{}, true)
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			r.Header.Set("Authorization", values[0])
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &tlsInfo.State
+		}
+	}
+	return r, nil
+}
+
+// UnaryInterceptor authenticates the gRPC calls and authorizes them by their
+// role, permission returns the permission of a method and the unknown
+// methods are refused
+func (a *Authenticator) UnaryInterceptor(permission func(fullMethod string) (Permission, bool)) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		r, err := grpcRequest(ctx)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		identity, err := a.Authenticate(r)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if actor, ok := ctx.Value(actorContextKey{}).(*string); ok {
+			*actor = identity.Name
+		}
+		p, ok := permission(info.FullMethod)
+		if !ok {
+			return nil, status.Errorf(codes.PermissionDenied, "%s has no permission", info.FullMethod)
+		}
+		apiPolicies, err := GetPolicies()
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if !Allowed(apiPolicies, identity.Role, p) {
+			return nil, status.Error(codes.PermissionDenied, denied(identity.Role, p))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GRPCCredentials returns the TLS credentials of a gRPC API served on TCP,
+// the client certificates are required with mtls
+func GRPCCredentials(opts config.APIAuthOptions) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(opts.TLSCert, opts.TLSKey)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.Mode == ModeMTLS {
+		tlsConfig, err = mtlsConfig(opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	return credentials.NewTLS(tlsConfig), nil
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/config"
+	"hlf-easy/utils"
+	"net/http"
+	"strings"
+)
+
+// Actions of the roles on the resources, the GET routes read their resource
+// and the other routes write it
+const (
+	ActionRead  = "read"
+	ActionWrite = "write"
+)
+
+// Resources of the management APIs the roles grant actions on
+const (
+	// ResourceNodes are the status, the files and the logs of the nodes, and
+	// their processes
+	ResourceNodes = "nodes"
+	// ResourceLogSpec is the logging spec of the nodes
+	ResourceLogSpec = "logspec"
+	// ResourceTasks are the maintenance tasks of the nodes
+	ResourceTasks = "tasks"
+	// ResourceAudit is the audit log of the host
+	ResourceAudit = "audit"
+	// ResourceAlerts are the anomalies and the alerts of the nodes
+	ResourceAlerts = "alerts"
+	// ResourceChannels are the blocks and the configs of the channels, and
+	// the channels the nodes create and join
+	ResourceChannels = "channels"
+	// ResourceChaincodes are the chaincodes of the host and their
+	// transactions
+	ResourceChaincodes = "chaincodes"
+	// ResourceIdentities are the certificates issued by the CAs and the
+	// signatures of the admin identities managed for the nodes
+	ResourceIdentities = "identities"
+	// ResourceHost is the utilization of the host and the daemon
+	ResourceHost = "host"
+)
+
+var resources = []string{
+	ResourceNodes,
+	ResourceLogSpec,
+	ResourceTasks,
+	ResourceAudit,
+	ResourceAlerts,
+	ResourceChannels,
+	ResourceChaincodes,
+	ResourceIdentities,
+	ResourceHost,
+}
+
+// Permission is an action on a resource
+type Permission struct {
+	Resource string
+	Action   string
+}
+
+// methodPermission returns the permission needed for a request on a resource
+func methodPermission(resource string, method string) Permission {
+	if method == http.MethodGet || method == http.MethodHead {
+		return Permission{Resource: resource, Action: ActionRead}
+	}
+	return Permission{Resource: resource, Action: ActionWrite}
+}
+
+// DefaultPolicies are the roles of the host without a policies file:
+// viewers read everything, operators also operate the nodes, their
+// channels and chaincodes, and admins can also issue certificates and sign
+// with the admin identities of the nodes
+var DefaultPolicies = config.APIPolicies{
+	Roles: []config.APIRole{
+		{Name: RoleViewer, Rules: []config.APIRule{
+			{Resources: []string{"*"}, Actions: []string{ActionRead}},
+		}},
+		{Name: RoleReader, Rules: []config.APIRule{
+			{Resources: []string{"*"}, Actions: []string{ActionRead}},
+		}},
+		{Name: RoleOperator, Rules: []config.APIRule{
+			{Resources: []string{"*"}, Actions: []string{ActionRead}},
+			{Resources: []string{ResourceNodes, ResourceLogSpec, ResourceTasks, ResourceChannels, ResourceChaincodes, ResourceHost}, Actions: []string{ActionWrite}},
+		}},
+		{Name: RoleAdmin, Rules: []config.APIRule{
+			{Resources: []string{"*"}, Actions: []string{"*"}},
+		}},
+	},
+}
+
+// ValidatePolicies checks that the roles have a unique name and that their
+// rules name known resources and actions
+func ValidatePolicies(apiPolicies config.APIPolicies) error {
+	names := map[string]bool{}
+	for _, role := range apiPolicies.Roles {
+		if role.Name == "" {
+			return errors.New("a role has no name")
+		}
+		if names[role.Name] {
+			return errors.Errorf("role %s is defined twice", role.Name)
+		}
+		names[role.Name] = true
+		for _, rule := range role.Rules {
+			if len(rule.Resources) == 0 || len(rule.Actions) == 0 {
+				return errors.Errorf("a rule of role %s has no resources or no actions", role.Name)
+			}
+			for _, resource := range rule.Resources {
+				if resource != "*" && !utils.Contains(resources, resource) {
+					return errors.Errorf("invalid resource %q of role %s, expected * or one of %s", resource, role.Name, strings.Join(resources, ", "))
+				}
+			}
+			for _, action := range rule.Actions {
+				if action != "*" && action != ActionRead && action != ActionWrite {
+					return errors.Errorf("invalid action %q of role %s, expected *, %s or %s", action, role.Name, ActionRead, ActionWrite)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ParsePolicies reads a policies file, in YAML or JSON
+func ParsePolicies(policiesBytes []byte) (*config.APIPolicies, error) {
+	apiPolicies := &config.APIPolicies{}
+	err := yaml.Unmarshal(policiesBytes, apiPolicies)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid policies file")
+	}
+	err = ValidatePolicies(*apiPolicies)
+	if err != nil {
+		return nil, err
+	}
+	return apiPolicies, nil
+}
+
+// GetPolicies returns the roles of the host: the roles of the policies file,
+// and the default roles it doesn't redefine
+func GetPolicies() (*config.APIPolicies, error) {
+	apiPolicies, err := utils.GetAPIPolicies()
+	if err != nil {
+		return nil, err
+	}
+	roles := append([]config.APIRole{}, apiPolicies.Roles...)
+	for _, role := range DefaultPolicies.Roles {
+		if findRole(apiPolicies, role.Name) == nil {
+			roles = append(roles, role)
+		}
+	}
+	return &config.APIPolicies{Roles: roles}, nil
+}
+
+func findRole(apiPolicies *config.APIPolicies, name string) *config.APIRole {
+	for i := range apiPolicies.Roles {
+		if apiPolicies.Roles[i].Name == name {
+			return &apiPolicies.Roles[i]
+		}
+	}
+	return nil
+}
+
+// Allowed tells whether a role grants a permission, the unknown roles grant
+// nothing
+func Allowed(apiPolicies *config.APIPolicies, role string, p Permission) bool {
+	r := findRole(apiPolicies, role)
+	if r == nil {
+		return false
+	}
+	for _, rule := range r.Rules {
+		if (utils.Contains(rule.Resources, "*") || utils.Contains(rule.Resources, p.Resource)) &&
+			(utils.Contains(rule.Actions, "*") || utils.Contains(rule.Actions, p.Action)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRole checks that the role is one of the roles of the host
+func ValidateRole(role string) error {
+	apiPolicies, err := GetPolicies()
+	if err != nil {
+		return err
+	}
+	if findRole(apiPolicies, role) == nil {
+		names := []string{}
+		for _, r := range apiPolicies.Roles {
+			names = append(names, r.Name)
+		}
+		return errors.Errorf("invalid role %q, expected one of %s", role, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// denied is the error of a role that doesn't grant a permission
+func denied(role string, p Permission) string {
+	return "the " + role + " role can't " + p.Action + " " + p.Resource
+}
+
+// Allow authorizes the requests of a route on a resource by the role of
+// their caller, it follows the Middleware of an authenticator. The policies
+// are read on every request so they apply without a restart
+func Allow(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiPolicies, err := GetPolicies()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		role := c.GetString(ContextKeyRole)
+		p := methodPermission(resource, c.Request.Method)
+		if !Allowed(apiPolicies, role, p) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": denied(role, p),
+			})
+			return
+		}
+		c.Next()
+	}
+}
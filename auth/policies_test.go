@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"hlf-easy/config"
+	"hlf-easy/utils"
+	"testing"
+)
+
+func TestDefaultPolicies(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	apiPolicies, err := GetPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		role     string
+		p        Permission
+		expected bool
+	}{
+		{RoleViewer, Permission{ResourceNodes, ActionRead}, true},
+		{RoleViewer, Permission{ResourceNodes, ActionWrite}, false},
+		{RoleReader, Permission{ResourceAudit, ActionRead}, true},
+		{RoleOperator, Permission{ResourceNodes, ActionWrite}, true},
+		{RoleOperator, Permission{ResourceIdentities, ActionRead}, true},
+		{RoleOperator, Permission{ResourceIdentities, ActionWrite}, false},
+		{RoleAdmin, Permission{ResourceIdentities, ActionWrite}, true},
+		{"root", Permission{ResourceNodes, ActionRead}, false},
+	} {
+		if allowed := Allowed(apiPolicies, tc.role, tc.p); allowed != tc.expected {
+			t.Errorf("expected %t for %s on %+v, got %t", tc.expected, tc.role, tc.p, allowed)
+		}
+	}
+}
+
+func TestPoliciesFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	apiPolicies, err := ParsePolicies([]byte(`
+roles:
+  - name: operator
+    rules:
+      - resources: [nodes]
+        actions: [read, write]
+  - name: ci
+    rules:
+      - resources: [chaincodes, channels]
+        actions: ["*"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.SaveAPIPolicies(apiPolicies); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateRole("ci"); err != nil {
+		t.Fatal(err)
+	}
+	policies, err := GetPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the operator of the file replaces the default one, the other default
+	// roles are kept
+	if Allowed(policies, RoleOperator, Permission{ResourceTasks, ActionWrite}) || !Allowed(policies, RoleOperator, Permission{ResourceNodes, ActionWrite}) {
+		t.Error("expected the operator role of the file")
+	}
+	if !Allowed(policies, "ci", Permission{ResourceChaincodes, ActionWrite}) || Allowed(policies, "ci", Permission{ResourceNodes, ActionRead}) {
+		t.Error("expected the ci role to only grant the chaincodes and the channels")
+	}
+	if !Allowed(policies, RoleAdmin, Permission{ResourceHost, ActionWrite}) {
+		t.Error("expected the default admin role to be kept")
+	}
+
+	for _, invalid := range []config.APIPolicies{
+		{Roles: []config.APIRole{{Rules: []config.APIRule{{Resources: []string{"*"}, Actions: []string{"*"}}}}}},
+		{Roles: []config.APIRole{{Name: "ci"}, {Name: "ci"}}},
+		{Roles: []config.APIRole{{Name: "ci", Rules: []config.APIRule{{Resources: []string{"certs"}, Actions: []string{ActionRead}}}}}},
+		{Roles: []config.APIRole{{Name: "ci", Rules: []config.APIRule{{Resources: []string{ResourceNodes}, Actions: []string{"start"}}}}}},
+		{Roles: []config.APIRole{{Name: "ci", Rules: []config.APIRule{{Resources: []string{ResourceNodes}}}}}},
+	} {
+		if err := ValidatePolicies(invalid); err == nil {
+			t.Errorf("expected %+v to be invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SocketIdentity is the caller of the requests on the Unix socket of a
+// management API, only the owner of the socket can connect to it
+var SocketIdentity = Identity{Name: "socket", Role: RoleAdmin}
+
+type socketContextKey struct{}
+
+// socketConnContext marks the connections accepted on the Unix socket, their
+// requests are authorized by the permissions of the socket
+func socketConnContext(ctx context.Context, c net.Conn) context.Context {
+	if c.LocalAddr().Network() == "unix" {
+		return context.WithValue(ctx, socketContextKey{}, true)
+	}
+	return ctx
+}
+
+// fromSocket tells whether a request was received on the Unix socket
+func fromSocket(r *http.Request) bool {
+	onSocket, _ := r.Context().Value(socketContextKey{}).(bool)
+	return onSocket
+}
+
+// listenSocket listens on a Unix socket only its owner can connect to, its
+// directory is created private so no one else can connect before the socket
+// permissions are set. A socket left by a crashed process is removed
+func ListenSocket(socket string) (net.Listener, error) {
+	err := os.MkdirAll(filepath.Dir(socket), 0700)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Lstat(socket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, errors.Errorf("%s exists and is not a socket", socket)
+		}
+		if conn, err := net.Dial("unix", socket); err == nil {
+			conn.Close()
+			return nil, errors.Errorf("%s is in use by another process", socket)
+		}
+		err = os.Remove(socket)
+		if err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chmod(socket, 0600)
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// NewSocketClient returns an HTTP client that connects to a Unix socket
+// whatever the host of the URLs
+func NewSocketClient(socket string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				d := net.Dialer{}
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+}
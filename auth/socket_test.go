@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"hlf-easy/config"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSocket(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	socket := filepath.Join(t.TempDir(), "run", "api.sock")
+	opts := config.APIAuthOptions{Mode: ModeToken, Socket: socket}
+	srv := &http.Server{Handler: newTestRouter(t, opts)}
+	served := make(chan error, 1)
+	go func() {
+		served <- ListenAndServe(srv, opts)
+	}()
+	c := NewSocketClient(socket, time.Second)
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		resp, err = c.Post("http://unix/restart", "", nil)
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// the admin role is granted by the permissions of the socket
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the request on the socket to be authorized, got %s", resp.Status)
+	}
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the socket to only be usable by its owner, got %s", info.Mode())
+	}
+	dirInfo, err := os.Stat(filepath.Dir(socket))
+	if err != nil || dirInfo.Mode().Perm() != 0700 {
+		t.Errorf("expected the directory of the socket to be private: %v", err)
+	}
+	if _, err := ListenSocket(socket); err == nil {
+		t.Error("expected the socket of a running API not to be replaced")
+	}
+
+	err = srv.Shutdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != http.ErrServerClosed {
+		t.Fatalf("expected the server to be closed, got %v", err)
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("expected the socket to be removed: %v", err)
+	}
+}
+
+func TestListenStaleSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	// a socket left by a crashed process
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	l, err = ListenSocket(socket)
+	if err != nil {
+		t.Fatalf("expected the stale socket to be replaced: %v", err)
+	}
+	l.Close()
+
+	file := filepath.Join(t.TempDir(), "api.sock")
+	err = os.WriteFile(file, nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListenSocket(file); err == nil {
+		t.Error("expected a regular file not to be replaced")
+	}
+}
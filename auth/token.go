@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/utils"
+	"strings"
+	"time"
+)
+
+// Default roles of the APIs, viewers can only call the GET routes,
+// operators can also operate the nodes and admins can do anything. reader is
+// the former name of viewer, kept for the tokens created with it
+const (
+	RoleViewer   = "viewer"
+	RoleReader   = "reader"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// tokenPrefix makes the tokens easy to find by secret scanners
+const tokenPrefix = "hlfe"
+
+func randomString(size int) (string, error) {
+	b := make([]byte, size)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken creates and stores a token, the returned token is the only
+// copy of its secret
+func CreateToken(name string, role string, now time.Time) (string, *config.APIToken, error) {
+	err := ValidateRole(role)
+	if err != nil {
+		return "", nil, err
+	}
+	id, err := randomString(6)
+	if err != nil {
+		return "", nil, err
+	}
+	// the id is base64url, it can't contain the separator
+	id = strings.ReplaceAll(id, "_", "-")
+	secret, err := randomString(32)
+	if err != nil {
+		return "", nil, err
+	}
+	apiToken := config.APIToken{
+		ID:        id,
+		Name:      name,
+		Role:      role,
+		Hash:      hashSecret(secret),
+		CreatedAt: now.UTC(),
+	}
+	apiTokens, err := utils.GetAPITokens()
+	if err != nil {
+		return "", nil, err
+	}
+	apiTokens.Tokens = append(apiTokens.Tokens, apiToken)
+	err = utils.SaveAPITokens(apiTokens)
+	if err != nil {
+		return "", nil, err
+	}
+	return tokenPrefix + "_" + id + "_" + secret, &apiToken, nil
+}
+
+// RevokeToken removes a token by its id
+func RevokeToken(id string) error {
+	apiTokens, err := utils.GetAPITokens()
+	if err != nil {
+		return err
+	}
+	tokens := []config.APIToken{}
+	for _, t := range apiTokens.Tokens {
+		if t.ID != id {
+			tokens = append(tokens, t)
+		}
+	}
+	if len(tokens) == len(apiTokens.Tokens) {
+		return errors.Errorf("token %s not found", id)
+	}
+	apiTokens.Tokens = tokens
+	return utils.SaveAPITokens(apiTokens)
+}
+
+// VerifyToken returns the stored token matching a token
+func VerifyToken(apiTokens *config.APITokens, token string) (*config.APIToken, error) {
+	parts := strings.SplitN(token, "_", 3)
+	if len(parts) != 3 || parts[0] != tokenPrefix {
+		return nil, errors.New("malformed token")
+	}
+	hash := hashSecret(parts[2])
+	for _, t := range apiTokens.Tokens {
+		if t.ID == parts[1] && subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) == 1 {
+			return &t, nil
+		}
+	}
+	return nil, errors.New("invalid token")
+}
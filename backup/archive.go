@@ -0,0 +1,285 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeFormat is the time of a backup in its name
+const timeFormat = "20060102T150405Z"
+
+// skipped are the directories of a node that aren't backed up: its ledgers,
+// its process files, the logs of its chaincode builds and the blocks of a
+// clone
+var skipped = map[string]bool{
+	"data":             true,
+	"run":              true,
+	"chaincode-builds": true,
+	"clone":            true,
+}
+
+// Prefix is the start of the names of the backups of a node, so the backups
+// of several nodes can share a storage
+func Prefix(kind string, id string) string {
+	return fmt.Sprintf("%s-%s-", kind, id)
+}
+
+// Name returns the name of a backup of a node taken at t
+func Name(kind string, id string, t time.Time) string {
+	return Prefix(kind, id) + t.UTC().Format(timeFormat) + ".tar.gz"
+}
+
+// Time returns the time of a backup of a node from its name
+func Time(kind string, id string, name string) (time.Time, bool) {
+	prefix := Prefix(kind, id)
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".tar.gz") {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(timeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".tar.gz"))
+	return t, err == nil
+}
+
+// Parse returns the kind, the ID and the time of a backup from its name
+func Parse(name string) (string, string, time.Time, bool) {
+	kind, rest, ok := strings.Cut(strings.TrimSuffix(name, ".tar.gz"), "-")
+	i := strings.LastIndex(rest, "-")
+	if !ok || i <= 0 || !strings.HasSuffix(name, ".tar.gz") {
+		return "", "", time.Time{}, false
+	}
+	t, err := time.Parse(timeFormat, rest[i+1:])
+	return kind, rest[:i], t, err == nil
+}
+
+// Sorted returns the backups of a node, the newest first. The names that
+// aren't backups of the node are left out
+func Sorted(kind string, id string, names []string) []string {
+	backups := []string{}
+	times := map[string]time.Time{}
+	for _, name := range names {
+		if t, ok := Time(kind, id, name); ok {
+			backups = append(backups, name)
+			times[name] = t
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return times[backups[i]].After(times[backups[j]])
+	})
+	return backups
+}
+
+// Expired returns the backups of a node beyond the keep newest ones, and the
+// ones older than maxAge when it's set. The names that aren't backups of the
+// node are ignored
+func Expired(kind string, id string, names []string, keep int, maxAge time.Duration, now time.Time) []string {
+	expired := []string{}
+	for i, name := range Sorted(kind, id, names) {
+		t, _ := Time(kind, id, name)
+		if (keep > 0 && i >= keep) || (maxAge > 0 && now.Sub(t) > maxAge) {
+			expired = append(expired, name)
+		}
+	}
+	return expired
+}
+
+// latestSnapshots returns the directories of the last completed snapshot of
+// every channel of a peer by channel
+func latestSnapshots(peerDir string) (map[string]string, error) {
+	completedDir := filepath.Join(peerDir, "data/snapshots/completed")
+	channels, err := os.ReadDir(completedDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	snapshots := map[string]string{}
+	for _, channel := range channels {
+		if !channel.IsDir() {
+			continue
+		}
+		heights, err := os.ReadDir(filepath.Join(completedDir, channel.Name()))
+		if err != nil {
+			return nil, err
+		}
+		latest := int64(-1)
+		for _, height := range heights {
+			h, err := strconv.ParseInt(height.Name(), 10, 64)
+			if err == nil && height.IsDir() && h > latest {
+				latest = h
+			}
+		}
+		if latest >= 0 {
+			snapshots[channel.Name()] = filepath.Join(completedDir, channel.Name(), strconv.FormatInt(latest, 10))
+		}
+	}
+	return snapshots, nil
+}
+
+// addDir adds the regular files of a directory to the archive under a name,
+// the directories of skip are left out
+func addDir(tw *tar.Writer, dir string, name string, skip map[string]bool) (int, error) {
+	files := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && skip[rel] {
+			return filepath.SkipDir
+		}
+		// sockets and links aren't backed up
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(name, rel))
+		if err := tw.WriteHeader(header); err != nil || info.IsDir() {
+			return err
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		files++
+		return err
+	})
+	return files, err
+}
+
+// Write writes the backup of a node to w as a tar.gz: the directory of the
+// node under node/, with its crypto material and its config, and the last
+// snapshots of the channels of a peer under snapshots/<channel>/<height>/
+// when ledger is set. It returns the number of files
+func Write(w io.Writer, nodeDir string, ledger bool) (int, error) {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	files, err := addDir(tw, nodeDir, "node", skipped)
+	if err != nil {
+		return files, err
+	}
+	if ledger {
+		snapshots, err := latestSnapshots(nodeDir)
+		if err != nil {
+			return files, err
+		}
+		if len(snapshots) == 0 {
+			return files, errors.New("the peer has no completed snapshot to back up, schedule a snapshot task before the backup")
+		}
+		channels := []string{}
+		for channel := range snapshots {
+			channels = append(channels, channel)
+		}
+		sort.Strings(channels)
+		for _, channel := range channels {
+			dir := snapshots[channel]
+			added, err := addDir(tw, dir, filepath.Join("snapshots", channel, filepath.Base(dir)), nil)
+			files += added
+			if err != nil {
+				return files, err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return files, err
+	}
+	return files, gw.Close()
+}
+
+// Snapshot is the snapshot of a channel extracted from a backup
+type Snapshot struct {
+	Channel string `json:"channel"`
+	Height  uint64 `json:"height"`
+	Dir     string `json:"dir"`
+}
+
+// Extract extracts a backup written by Write: node/ to the directory of the
+// node and the snapshots to the completed snapshots of the peer, in
+// data/snapshots/completed/<channel>/<height>. It returns the number of files
+// and the snapshots
+func Extract(r io.Reader, nodeDir string) (int, []Snapshot, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "invalid backup")
+	}
+	tr := tar.NewReader(gr)
+	completedDir := filepath.Join(nodeDir, "data/snapshots/completed")
+	files := 0
+	snapshots := map[string]Snapshot{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return files, nil, errors.Wrap(err, "invalid backup")
+		}
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		// the names can't leave the directories they're extracted to
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return files, nil, errors.Errorf("invalid file %s of the backup", header.Name)
+		}
+		var path string
+		root, rel, _ := strings.Cut(name, string(filepath.Separator))
+		switch root {
+		case "node":
+			path = filepath.Join(nodeDir, rel)
+		case "snapshots":
+			parts := strings.SplitN(rel, string(filepath.Separator), 3)
+			if len(parts) < 2 {
+				continue
+			}
+			height, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				return files, nil, errors.Errorf("invalid snapshot %s of the backup", header.Name)
+			}
+			snapshots[parts[0]] = Snapshot{Channel: parts[0], Height: height, Dir: filepath.Join(completedDir, parts[0], parts[1])}
+			path = filepath.Join(completedDir, rel)
+		default:
+			return files, nil, errors.Errorf("invalid file %s of the backup", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return files, nil, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return files, nil, err
+			}
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return files, nil, err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return files, nil, err
+			}
+			files++
+		}
+	}
+	extracted := []Snapshot{}
+	for _, snapshot := range snapshots {
+		extracted = append(extracted, snapshot)
+	}
+	sort.Slice(extracted, func(i, j int) bool {
+		return extracted[i].Channel < extracted[j].Channel
+	})
+	return files, extracted, nil
+}
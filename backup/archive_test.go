@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpired(t *testing.T) {
+	now := time.Date(2024, 3, 10, 4, 0, 0, 0, time.UTC)
+	names := []string{
+		"peer-peer1-20240310T040000Z.tar.gz",
+		"peer-peer1-20240309T040000Z.tar.gz",
+		"peer-peer1-20240301T040000Z.tar.gz",
+		"peer-peer1-20240308T040000Z.tar.gz",
+		"peer-peer1-a-20240101T040000Z.tar.gz",
+		"peer-peer10-20240101T040000Z.tar.gz",
+		"notes.txt",
+	}
+	expired := Expired("peer", "peer1", names, 2, 0, now)
+	sort.Strings(expired)
+	if strings.Join(expired, ",") != "peer-peer1-20240301T040000Z.tar.gz,peer-peer1-20240308T040000Z.tar.gz" {
+		t.Fatalf("expected the 2 oldest backups to expire, got %v", expired)
+	}
+	expired = Expired("peer", "peer1", names, 0, 72*time.Hour, now)
+	if len(expired) != 1 || expired[0] != "peer-peer1-20240301T040000Z.tar.gz" {
+		t.Fatalf("expected the backup older than 72h to expire, got %v", expired)
+	}
+	kind, id, _, ok := Parse("peer-peer1-a-20240101T040000Z.tar.gz")
+	if !ok || kind != "peer" || id != "peer1-a" {
+		t.Fatalf("expected the backup of peer peer1-a, got %s %s", kind, id)
+	}
+	if _, _, _, ok := Parse("notes.txt"); ok {
+		t.Fatal("expected notes.txt not to be a backup")
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	peerDir := t.TempDir()
+	writeFiles(t, peerDir, map[string]string{
+		"init.json":            "{}",
+		"msp/keystore/priv_sk": "key",
+		"data/ledgersData/chains/chains/ch1/blockfile": "blocks",
+		"data/snapshots/completed/ch1/10/txids.data":   "old",
+		"data/snapshots/completed/ch1/25/txids.data":   "new",
+	})
+	var buf bytes.Buffer
+	files, err := Write(&buf, peerDir, true)
+	if err != nil || files != 3 {
+		t.Fatalf("expected 3 files to be backed up, got %d, %v", files, err)
+	}
+	backupDir := t.TempDir()
+	name := Name("peer", "peer1", time.Date(2024, 3, 10, 4, 0, 0, 0, time.UTC))
+	writeFiles(t, backupDir, map[string]string{
+		name:                                 buf.String(),
+		"peer-peer1-20240101T000000Z.tar.gz": "old",
+	})
+	storage, err := OpenStorage(backupDir, Credentials{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := Latest(context.Background(), storage, "peer", "peer1")
+	if err != nil || latest != name {
+		t.Fatalf("expected the newest backup %s, got %s, %v", name, latest, err)
+	}
+	restoredDir := filepath.Join(t.TempDir(), "peers/peer1")
+	files, snapshots, err := Restore(context.Background(), storage, latest, restoredDir)
+	if err != nil || files != 3 {
+		t.Fatalf("expected 3 files to be restored, got %d, %v", files, err)
+	}
+	snapshotDir := filepath.Join(restoredDir, "data/snapshots/completed/ch1/25")
+	if len(snapshots) != 1 || snapshots[0].Channel != "ch1" || snapshots[0].Height != 25 || snapshots[0].Dir != snapshotDir {
+		t.Fatalf("unexpected snapshots %v", snapshots)
+	}
+	key, err := os.ReadFile(filepath.Join(restoredDir, "msp/keystore/priv_sk"))
+	if err != nil || string(key) != "key" {
+		t.Fatalf("expected the key of the peer to be restored, got %q, %v", key, err)
+	}
+	if _, _, err := Restore(context.Background(), storage, latest, restoredDir); err == nil {
+		t.Fatal("expected an existing node to be refused")
+	}
+	if _, _, err := Restore(context.Background(), storage, "peer-peer1-20240101T000000Z.tar.gz", filepath.Join(t.TempDir(), "peer2")); err == nil {
+		t.Fatal("expected an invalid backup to fail")
+	}
+}
+
+func TestExtractOutside(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	content := []byte("evil")
+	if err := tw.WriteHeader(&tar.Header{Name: "node/../../evil", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gw.Close()
+	dir := filepath.Join(t.TempDir(), "a/b")
+	if _, _, err := Extract(&buf, dir); err == nil {
+		t.Fatal("expected a file outside of the node to be refused")
+	}
+}
@@ -0,0 +1,55 @@
+package backup
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Latest returns the newest backup of a node in a storage
+func Latest(ctx context.Context, s Storage, kind string, id string) (string, error) {
+	names, err := s.List(ctx, Prefix(kind, id))
+	if err != nil {
+		return "", err
+	}
+	backups := Sorted(kind, id, names)
+	if len(backups) == 0 {
+		return "", errors.Errorf("no backup of %s %s found", kind, id)
+	}
+	return backups[0], nil
+}
+
+// Restore downloads a backup of a storage and extracts it to the directory of
+// a node, the node can't exist on the host. It returns the number of files and
+// the snapshots of the channels of a peer
+func Restore(ctx context.Context, s Storage, name string, nodeDir string) (int, []Snapshot, error) {
+	if entries, err := os.ReadDir(nodeDir); err == nil && len(entries) > 0 {
+		return 0, nil, errors.Errorf("%s isn't empty, remove the node before restoring it", nodeDir)
+	}
+	if err := os.MkdirAll(filepath.Dir(nodeDir), 0755); err != nil {
+		return 0, nil, err
+	}
+	// the backup is downloaded before being extracted, so a failed download
+	// leaves nothing behind
+	f, err := os.CreateTemp(filepath.Dir(nodeDir), ".backup-*")
+	if err != nil {
+		return 0, nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if err := s.Get(ctx, name, f); err != nil {
+		return 0, nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, nil, err
+	}
+	files, snapshots, err := Extract(f, nodeDir)
+	if err != nil {
+		// a partial node can't be started
+		_ = os.RemoveAll(nodeDir)
+		return 0, nil, errors.Wrapf(err, "failed to restore %s", name)
+	}
+	return files, snapshots, nil
+}
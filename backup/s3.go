@@ -0,0 +1,199 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/sigv4"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// s3Bucket is a bucket of an S3-compatible storage addressed with a
+// path-style URL, https://s3.eu-west-1.amazonaws.com/<bucket>/<prefix>, its
+// requests are signed with AWS Signature Version 4. Google Cloud Storage
+// serves the same API with the HMAC keys of a service account
+type s3Bucket struct {
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+	// the keys of the signer default to AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY, or GS_ACCESS_KEY_ID and GS_SECRET_ACCESS_KEY for
+	// Google Cloud Storage
+	signer sigv4.Signer
+}
+
+func newS3Bucket(u *url.URL, creds Credentials) (*s3Bucket, error) {
+	if u.Host == "" {
+		return nil, errors.Errorf("invalid target %q, expected %s://<bucket>/<prefix>", u.String(), u.Scheme)
+	}
+	region := creds.Region
+	envPrefix := "AWS"
+	path := u.Path
+	endpoint := &url.URL{Scheme: u.Scheme, Host: u.Host}
+	switch u.Scheme {
+	case "s3":
+		if region == "" {
+			region = "us-east-1"
+		}
+		endpoint = &url.URL{Scheme: "https", Host: fmt.Sprintf("s3.%s.amazonaws.com", region)}
+		path = u.Host + u.Path
+	case "gs":
+		// the region of the HMAC requests of Google Cloud Storage is auto
+		region = "auto"
+		envPrefix = "GS"
+		endpoint = &url.URL{Scheme: "https", Host: "storage.googleapis.com"}
+		path = u.Host + u.Path
+	}
+	bucket, prefix, _ := strings.Cut(strings.Trim(path, "/"), "/")
+	if bucket == "" {
+		return nil, errors.Errorf("the target %q has no bucket", u.String())
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	accessKey := creds.AccessKey
+	if accessKey == "" {
+		accessKey = os.Getenv(envPrefix + "_ACCESS_KEY_ID")
+	}
+	secretKey := creds.SecretKey
+	if secretKey == "" {
+		secretKey = os.Getenv(envPrefix + "_SECRET_ACCESS_KEY")
+	}
+	if accessKey == "" || secretKey == "" {
+		return nil, errors.Errorf("the credentials of the bucket are missing, set the access and secret keys or %s_ACCESS_KEY_ID and %s_SECRET_ACCESS_KEY", envPrefix, envPrefix)
+	}
+	return &s3Bucket{
+		endpoint: endpoint,
+		bucket:   bucket,
+		prefix:   prefix,
+		signer:   sigv4.Signer{Region: region, Service: "s3", AccessKey: accessKey, SecretKey: secretKey},
+	}, nil
+}
+
+// Location returns the URL of an object of the prefix of the bucket
+func (b *s3Bucket) Location(name string) string {
+	return b.endpoint.String() + "/" + b.bucket + "/" + b.prefix + name
+}
+
+func (b *s3Bucket) send(req *http.Request, payloadHash string, expected int) (*http.Response, error) {
+	b.signer.Sign(req, payloadHash)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != expected {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, errors.Errorf("the bucket returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+func (b *s3Bucket) do(req *http.Request, payloadHash string, expected int) ([]byte, error) {
+	resp, err := b.send(req, payloadHash, expected)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Put uploads a file to an object of the prefix of the bucket
+func (b *s3Bucket) Put(ctx context.Context, name string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// the payload is signed with its SHA-256, the file is read again to be
+	// uploaded
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	payloadHash := hex.EncodeToString(hash.Sum(nil))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.Location(name), f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	_, err = b.do(req, payloadHash, http.StatusOK)
+	return errors.Wrapf(err, "failed to upload %s", name)
+}
+
+// Get downloads an object of the prefix of the bucket to w
+func (b *s3Bucket) Get(ctx context.Context, name string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.Location(name), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.send(req, sigv4.EmptyPayloadHash, http.StatusOK)
+	if err != nil {
+		return errors.Wrapf(err, "failed to download %s", name)
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return errors.Wrapf(err, "failed to download %s", name)
+}
+
+// List returns the names of the objects of the prefix of the bucket starting
+// with a name prefix
+func (b *s3Bucket) List(ctx context.Context, namePrefix string) ([]string, error) {
+	names := []string{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {b.prefix + namePrefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.endpoint.String()+"/"+b.bucket+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		body, err := b.do(req, sigv4.EmptyPayloadHash, http.StatusOK)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list the backups")
+		}
+		result := struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}{}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, errors.Wrap(err, "invalid list of the objects of the bucket")
+		}
+		for _, object := range result.Contents {
+			names = append(names, strings.TrimPrefix(object.Key, b.prefix))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Delete removes an object of the prefix of the bucket
+func (b *s3Bucket) Delete(ctx context.Context, name string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, b.Location(name), nil)
+	if err != nil {
+		return err
+	}
+	_, err = b.do(req, sigv4.EmptyPayloadHash, http.StatusNoContent)
+	return errors.Wrapf(err, "failed to remove %s", name)
+}
@@ -0,0 +1,143 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// execCommand runs the sftp client, it's replaced by the tests
+var execCommand = exec.CommandContext
+
+// sftpStorage keeps the backups in a directory of an SFTP server, with the
+// sftp client of OpenSSH in batch mode so the host must be in known_hosts
+// and the key must not need a passphrase
+type sftpStorage struct {
+	destination string
+	port        string
+	dir         string
+	sshKey      string
+}
+
+func newSFTPStorage(u *url.URL, sshKey string) (*sftpStorage, error) {
+	if u.Hostname() == "" {
+		return nil, errors.Errorf("invalid target %q, expected sftp://<user>@<host>:<port>/<path>", u.String())
+	}
+	if _, ok := u.User.Password(); ok {
+		return nil, errors.New("the SFTP target can't have a password, the server is reached with an SSH key")
+	}
+	if strings.ContainsAny(u.Path, "\"\n") {
+		return nil, errors.Errorf("invalid path %q of the SFTP target", u.Path)
+	}
+	destination := u.Hostname()
+	if u.User != nil {
+		destination = u.User.Username() + "@" + destination
+	}
+	dir := u.Path
+	if dir == "" {
+		dir = "."
+	}
+	return &sftpStorage{destination: destination, port: u.Port(), dir: dir, sshKey: sshKey}, nil
+}
+
+func (s *sftpStorage) Location(name string) string {
+	location := "sftp://" + s.destination
+	if s.port != "" {
+		location += ":" + s.port
+	}
+	return location + path.Join("/", s.dir, name)
+}
+
+func (s *sftpStorage) remotePath(name string) string {
+	return path.Join(s.dir, name)
+}
+
+// run runs the commands of a batch with the sftp client and returns its
+// output, the commands starting with - don't stop the batch when they fail
+func (s *sftpStorage) run(ctx context.Context, commands ...string) (string, error) {
+	args := []string{"-b", "-", "-o", "BatchMode=yes"}
+	if s.port != "" {
+		args = append(args, "-P", s.port)
+	}
+	if s.sshKey != "" {
+		args = append(args, "-i", s.sshKey)
+	}
+	args = append(args, s.destination)
+	cmd := execCommand(ctx, "sftp", args...)
+	cmd.Stdin = strings.NewReader(strings.Join(commands, "\n") + "\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Errorf("sftp failed on %s: %v: %s", s.destination, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func (s *sftpStorage) Put(ctx context.Context, name string, localPath string) error {
+	if strings.ContainsAny(localPath, "\"\n") {
+		return errors.Errorf("invalid path %q of the backup", localPath)
+	}
+	// the backup is renamed once it's uploaded, so a partial upload is never
+	// listed
+	dst := s.remotePath(name)
+	_, err := s.run(ctx,
+		fmt.Sprintf("-mkdir %q", s.dir),
+		fmt.Sprintf("put %q %q", localPath, dst+".part"),
+		fmt.Sprintf("-rm %q", dst),
+		fmt.Sprintf("rename %q %q", dst+".part", dst),
+	)
+	return errors.Wrapf(err, "failed to upload %s", name)
+}
+
+func (s *sftpStorage) Get(ctx context.Context, name string, w io.Writer) error {
+	f, err := os.CreateTemp("", "hlf-easy-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := s.run(ctx, fmt.Sprintf("get %q %q", s.remotePath(name), f.Name())); err != nil {
+		return errors.Wrapf(err, "failed to download %s", name)
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (s *sftpStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	// a missing directory has no backups
+	out, err := s.run(ctx, fmt.Sprintf("-ls -1 %q", s.dir))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the backups")
+	}
+	return parseSFTPList(out, prefix), nil
+}
+
+// parseSFTPList returns the names starting with a prefix of the output of
+// ls -1, without the commands echoed by the batch
+func parseSFTPList(out string, prefix string) []string {
+	names := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "sftp>") {
+			continue
+		}
+		name := path.Base(line)
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (s *sftpStorage) Delete(ctx context.Context, name string) error {
+	_, err := s.run(ctx, fmt.Sprintf("rm %q", s.remotePath(name)))
+	return errors.Wrapf(err, "failed to remove %s", name)
+}
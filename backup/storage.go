@@ -0,0 +1,144 @@
+package backup
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// httpClient sends the requests of the buckets, an upload is only limited by
+// its context
+var httpClient = &http.Client{}
+
+// Storage is where the backups are kept: a directory, a bucket or a directory
+// of an SFTP server
+type Storage interface {
+	// Put stores a local file under a name
+	Put(ctx context.Context, name string, path string) error
+	// Get writes the backup of a name to w
+	Get(ctx context.Context, name string, w io.Writer) error
+	// List returns the names of the backups starting with a prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete removes the backup of a name
+	Delete(ctx context.Context, name string) error
+	// Location returns where the backup of a name is stored
+	Location(name string) string
+}
+
+// Credentials are the credentials of a storage, the keys of a bucket default
+// to the environment of the process
+type Credentials struct {
+	Region    string
+	AccessKey string
+	SecretKey string
+	// SSHKey is the private key of an SFTP server, the keys of the SSH agent
+	// and of ~/.ssh are used when it's empty
+	SSHKey string
+}
+
+// OpenStorage returns the storage of a target: a directory, /path or
+// file:///path, an S3 bucket, s3://<bucket>/<prefix>, a bucket of an
+// S3-compatible storage with a path-style URL,
+// http(s)://<endpoint>/<bucket>/<prefix>, a Google Cloud Storage bucket with
+// HMAC keys, gs://<bucket>/<prefix>, or a directory of an SFTP server,
+// sftp://<user>@<host>:<port>/<path>
+func OpenStorage(target string, creds Credentials) (Storage, error) {
+	if !strings.Contains(target, "://") {
+		return newDirStorage(target)
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid target %q", target)
+	}
+	switch u.Scheme {
+	case "file":
+		return newDirStorage(u.Path)
+	case "s3", "gs", "http", "https":
+		return newS3Bucket(u, creds)
+	case "sftp":
+		return newSFTPStorage(u, creds.SSHKey)
+	}
+	return nil, errors.Errorf("unsupported target %q, expected a directory or an s3://, gs://, http(s):// or sftp:// URL", target)
+}
+
+// dirStorage keeps the backups in a directory
+type dirStorage struct {
+	dir string
+}
+
+func newDirStorage(dir string) (*dirStorage, error) {
+	if dir == "" {
+		return nil, errors.New("the directory of the backups is empty")
+	}
+	return &dirStorage{dir: dir}, nil
+}
+
+func (s *dirStorage) Location(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+func (s *dirStorage) Put(ctx context.Context, name string, path string) error {
+	dst := s.Location(name)
+	if path == dst {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	// the backup is renamed once it's complete, so a partial copy is never
+	// listed
+	f, err := os.OpenFile(dst+".part", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, src)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dst + ".part")
+		return errors.Wrapf(err, "failed to copy %s", name)
+	}
+	return os.Rename(dst+".part", dst)
+}
+
+func (s *dirStorage) Get(ctx context.Context, name string, w io.Writer) error {
+	f, err := os.Open(s.Location(name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (s *dirStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), prefix) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func (s *dirStorage) Delete(ctx context.Context, name string) error {
+	return os.Remove(s.Location(name))
+}
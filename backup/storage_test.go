@@ -0,0 +1,113 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenStorage(t *testing.T) {
+	creds := Credentials{AccessKey: "access", SecretKey: "secret"}
+	locations := map[string]string{
+		"/var/backups":                                     "/var/backups/b.tar.gz",
+		"file:///var/backups":                              "/var/backups/b.tar.gz",
+		"s3://hlf-backups/org1":                            "https://s3.us-east-1.amazonaws.com/hlf-backups/org1/b.tar.gz",
+		"gs://hlf-backups":                                 "https://storage.googleapis.com/hlf-backups/b.tar.gz",
+		"http://127.0.0.1:9000/hlf-backups/org1":           "http://127.0.0.1:9000/hlf-backups/org1/b.tar.gz",
+		"sftp://backup@vault.example.com:2222/srv/backups": "sftp://backup@vault.example.com:2222/srv/backups/b.tar.gz",
+	}
+	for target, expected := range locations {
+		storage, err := OpenStorage(target, creds)
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", target, err)
+		}
+		if location := storage.Location("b.tar.gz"); location != expected {
+			t.Errorf("expected %s for %s, got %s", expected, target, location)
+		}
+	}
+	bucket, err := OpenStorage("gs://hlf-backups", creds)
+	if err != nil || bucket.(*s3Bucket).signer.Region != "auto" {
+		t.Fatalf("expected the auto region of Google Cloud Storage, got %v", err)
+	}
+	for _, target := range []string{"ftp://example.com/backups", "https://s3.amazonaws.com", "sftp://backup:pw@vault.example.com/srv"} {
+		if _, err := OpenStorage(target, creds); err == nil {
+			t.Errorf("expected %s to be refused", target)
+		}
+	}
+	t.Setenv("GS_ACCESS_KEY_ID", "")
+	t.Setenv("GS_SECRET_ACCESS_KEY", "")
+	if _, err := OpenStorage("gs://hlf-backups", Credentials{}); err == nil {
+		t.Error("expected a bucket without credentials to be refused")
+	}
+}
+
+func TestDirStorage(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := OpenStorage(filepath.Join(dir, "backups"), Credentials{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	src := filepath.Join(dir, "src.tar.gz")
+	if err := os.WriteFile(src, []byte("backup"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(ctx, "peer-peer1-20240310T040000Z.tar.gz", src); err != nil {
+		t.Fatal(err)
+	}
+	names, err := storage.List(ctx, "peer-peer1-")
+	if err != nil || len(names) != 1 {
+		t.Fatalf("expected the backup to be listed, got %v, %v", names, err)
+	}
+	var buf bytes.Buffer
+	if err := storage.Get(ctx, names[0], &buf); err != nil || buf.String() != "backup" {
+		t.Fatalf("expected the content of the backup, got %q, %v", buf.String(), err)
+	}
+	if err := storage.Delete(ctx, names[0]); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := storage.List(ctx, ""); len(names) != 0 {
+		t.Fatalf("expected the backup to be removed, got %v", names)
+	}
+}
+
+func TestSFTPStorage(t *testing.T) {
+	// the fake sftp client writes its batch to a file and prints the output
+	batchFile := filepath.Join(t.TempDir(), "batch")
+	var args []string
+	output := ""
+	execCommand = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		args = append([]string{name}, arg...)
+		cmd := exec.CommandContext(ctx, "sh", "-c", `cat > "$BATCH"; printf '%s' "$OUTPUT"`)
+		cmd.Env = append(os.Environ(), "BATCH="+batchFile, "OUTPUT="+output)
+		return cmd
+	}
+	defer func() { execCommand = exec.CommandContext }()
+	storage, err := OpenStorage("sftp://backup@vault.example.com:2222/srv/backups", Credentials{SSHKey: "/keys/backup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(context.Background(), "peer-peer1-20240310T040000Z.tar.gz", "/tmp/b.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(args, " ") != "sftp -b - -o BatchMode=yes -P 2222 -i /keys/backup backup@vault.example.com" {
+		t.Fatalf("unexpected command %v", args)
+	}
+	batch, err := os.ReadFile(batchFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(batch), `put "/tmp/b.tar.gz" "/srv/backups/peer-peer1-20240310T040000Z.tar.gz.part"`) ||
+		!strings.Contains(string(batch), `rename "/srv/backups/peer-peer1-20240310T040000Z.tar.gz.part" "/srv/backups/peer-peer1-20240310T040000Z.tar.gz"`) {
+		t.Fatalf("unexpected batch %s", batch)
+	}
+	output = "sftp> -ls -1 \"/srv/backups\"\n/srv/backups/peer-peer1-20240310T040000Z.tar.gz\n/srv/backups/orderer-orderer1-20240310T040000Z.tar.gz\n"
+	names, err := storage.List(context.Background(), "peer-peer1-")
+	if err != nil || len(names) != 1 || names[0] != "peer-peer1-20240310T040000Z.tar.gz" {
+		t.Fatalf("expected the backups of peer1, got %v, %v", names, err)
+	}
+}
@@ -0,0 +1,224 @@
+package bench
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the benchmarks
+const (
+	DefaultTPS         = 100
+	DefaultDuration    = time.Minute
+	DefaultConcurrency = 200
+	// DefaultTxTimeout is how long a transaction has to be committed before
+	// it's counted as failed
+	DefaultTxTimeout = 30 * time.Second
+	// MaxTPS bounds the rate, far above what a peer commits
+	MaxTPS = 100000
+)
+
+// TxNumber is replaced in the arguments of the transactions by their number,
+// for the transactions to write different keys
+const TxNumber = "{n}"
+
+// maxErrors is the number of distinct errors kept in a report
+const maxErrors = 5
+
+// Options are the load of a benchmark
+type Options struct {
+	// TPS is the rate the transactions are sent at
+	TPS      int
+	Duration time.Duration
+	// Concurrency bounds the transactions waiting for their commit, a
+	// transaction due while they're all waiting is skipped
+	Concurrency int
+	TxTimeout   time.Duration
+	Function    string
+	// Args are the arguments of the function, TxNumber is replaced by the
+	// number of the transaction
+	Args []string
+}
+
+// Validate checks the load of a benchmark
+func (o Options) Validate() error {
+	if o.TPS < 1 || o.TPS > MaxTPS {
+		return errors.Errorf("the rate must be between 1 and %d transactions per second", MaxTPS)
+	}
+	if o.Duration <= 0 {
+		return errors.New("the duration must be positive")
+	}
+	if o.Concurrency < 1 {
+		return errors.New("the concurrency must be at least 1")
+	}
+	if o.TxTimeout <= 0 {
+		return errors.New("the timeout of the transactions must be positive")
+	}
+	if o.Function == "" {
+		return errors.New("the function is required")
+	}
+	return nil
+}
+
+// SubmitFunc submits a transaction and waits for its commit
+type SubmitFunc func(ctx context.Context, function string, args []string) error
+
+// Latencies are the percentiles of the latencies of the committed
+// transactions, from their submission to their commit
+type Latencies struct {
+	Min  time.Duration `json:"min"`
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+// Report is the outcome of a benchmark
+type Report struct {
+	TargetTPS int           `json:"targetTPS"`
+	Duration  time.Duration `json:"duration"`
+	// Sent transactions were submitted, Skipped ones were due while all the
+	// concurrent transactions were waiting for their commit
+	Sent      int `json:"sent"`
+	Skipped   int `json:"skipped"`
+	Committed int `json:"committed"`
+	Failed    int `json:"failed"`
+	// SendTPS is the rate the transactions were sent at, CommitTPS the rate
+	// they were committed at until the last one completed
+	SendTPS   float64   `json:"sendTPS"`
+	CommitTPS float64   `json:"commitTPS"`
+	Latencies Latencies `json:"latencies"`
+	// Errors are the first distinct errors of the failed transactions
+	Errors []string `json:"errors,omitempty"`
+}
+
+// txArgs returns the arguments of a transaction with its number
+func txArgs(args []string, n int) []string {
+	result := make([]string, len(args))
+	for i, arg := range args {
+		result[i] = strings.ReplaceAll(arg, TxNumber, strconv.Itoa(n))
+	}
+	return result
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// getLatencies returns the percentiles of latencies
+func getLatencies(latencies []time.Duration) Latencies {
+	if len(latencies) == 0 {
+		return Latencies{}
+	}
+	sorted := append([]time.Duration{}, latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, l := range sorted {
+		total += l
+	}
+	return Latencies{
+		Min:  sorted[0],
+		Mean: total / time.Duration(len(sorted)),
+		P50:  percentile(sorted, 50),
+		P90:  percentile(sorted, 90),
+		P95:  percentile(sorted, 95),
+		P99:  percentile(sorted, 99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// Run sends transactions at the rate of the options for their duration with
+// at most Concurrency of them waiting for their commit, and reports their
+// latencies and the commit throughput. The transactions in flight when the
+// duration ends are waited for, the context stops the benchmark early
+func Run(ctx context.Context, opts Options, submit SubmitFunc) (*Report, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	report := &Report{TargetTPS: opts.TPS}
+	var mu sync.Mutex
+	latencies := []time.Duration{}
+	seenErrors := map[string]bool{}
+	sem := make(chan struct{}, opts.Concurrency)
+	wg := sync.WaitGroup{}
+
+	start := time.Now()
+	lastDone := start
+	interval := time.Second / time.Duration(opts.TPS)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(opts.Duration)
+	defer deadline.Stop()
+	n := 0
+	send := func() {
+		select {
+		case sem <- struct{}{}:
+		default:
+			report.Skipped++
+			return
+		}
+		n++
+		report.Sent++
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			txCtx, cancel := context.WithTimeout(ctx, opts.TxTimeout)
+			defer cancel()
+			txStart := time.Now()
+			err := submit(txCtx, opts.Function, txArgs(opts.Args, n))
+			done := time.Now()
+			mu.Lock()
+			defer mu.Unlock()
+			if done.After(lastDone) {
+				lastDone = done
+			}
+			if err != nil {
+				report.Failed++
+				if msg := err.Error(); !seenErrors[msg] && len(report.Errors) < maxErrors {
+					seenErrors[msg] = true
+					report.Errors = append(report.Errors, msg)
+				}
+				return
+			}
+			report.Committed++
+			latencies = append(latencies, done.Sub(txStart))
+		}(n)
+	}
+	send()
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			send()
+		case <-deadline.C:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	sendDuration := time.Since(start)
+	wg.Wait()
+
+	report.Duration = sendDuration
+	report.SendTPS = float64(report.Sent) / sendDuration.Seconds()
+	if elapsed := lastDone.Sub(start); report.Committed > 0 && elapsed > 0 {
+		report.CommitTPS = float64(report.Committed) / elapsed.Seconds()
+	}
+	report.Latencies = getLatencies(latencies)
+	return report, ctx.Err()
+}
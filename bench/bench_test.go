@@ -0,0 +1,102 @@
+package bench
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	l := getLatencies(latencies)
+	expected := Latencies{
+		Min:  time.Millisecond,
+		Mean: 50500 * time.Microsecond,
+		P50:  50 * time.Millisecond,
+		P90:  90 * time.Millisecond,
+		P95:  95 * time.Millisecond,
+		P99:  99 * time.Millisecond,
+		Max:  100 * time.Millisecond,
+	}
+	if l != expected {
+		t.Fatalf("expected %+v, got %+v", expected, l)
+	}
+	if l := getLatencies(nil); l != (Latencies{}) {
+		t.Fatalf("expected no latencies, got %+v", l)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var mu sync.Mutex
+	keys := map[string]bool{}
+	opts := Options{
+		TPS:         100,
+		Duration:    500 * time.Millisecond,
+		Concurrency: 10,
+		TxTimeout:   time.Second,
+		Function:    "set",
+		Args:        []string{"key" + TxNumber, "10"},
+	}
+	report, err := Run(context.Background(), opts, func(ctx context.Context, function string, args []string) error {
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		if function != "set" || len(args) != 2 || keys[args[0]] {
+			return errors.Errorf("unexpected transaction %s %v", function, args)
+		}
+		keys[args[0]] = true
+		if args[0] == "key3" {
+			return errors.New("MVCC_READ_CONFLICT")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Sent < 25 || report.Sent > 60 {
+		t.Fatalf("expected about 50 transactions, got %d", report.Sent)
+	}
+	if report.Failed != 1 || report.Committed != report.Sent-1 || report.Skipped != 0 {
+		t.Fatalf("expected one failed transaction, got %+v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0] != "MVCC_READ_CONFLICT" {
+		t.Fatalf("expected the error of the failed transaction, got %v", report.Errors)
+	}
+	if report.Latencies.Min < 5*time.Millisecond || report.Latencies.P99 > report.Latencies.Max || report.CommitTPS <= 0 {
+		t.Fatalf("unexpected latencies %+v", report)
+	}
+
+	// the transactions outlast the duration, the ones due meanwhile are
+	// skipped and the ones in flight time out
+	opts.Concurrency = 1
+	opts.TxTimeout = 100 * time.Millisecond
+	opts.Duration = 300 * time.Millisecond
+	report, err = Run(context.Background(), opts, func(ctx context.Context, function string, args []string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Committed != 0 || report.Failed != report.Sent || report.Skipped == 0 {
+		t.Fatalf("expected the transactions to be skipped or to fail, got %+v", report)
+	}
+	if !strings.Contains(report.Errors[0], "deadline") {
+		t.Fatalf("expected the transactions to time out, got %v", report.Errors)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, opts, func(ctx context.Context, function string, args []string) error { return ctx.Err() }); err == nil {
+		t.Fatal("expected a canceled benchmark to fail")
+	}
+	if _, err := Run(context.Background(), Options{TPS: 0}, nil); err == nil {
+		t.Fatal("expected an error for invalid options")
+	}
+}
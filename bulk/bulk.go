@@ -0,0 +1,140 @@
+package bulk
+
+import (
+	"fmt"
+	"hlf-easy/dashboard"
+	"hlf-easy/node"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// DefaultParallelism is the number of nodes operated on at the same time
+const DefaultParallelism = 4
+
+// Actions of the bulk operations, status only reads the nodes
+const (
+	ActionStart  = "start"
+	ActionStop   = "stop"
+	ActionStatus = "status"
+)
+
+// Result is the outcome of an operation on a node
+type Result struct {
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	OK     bool   `json:"ok"`
+	// Status is the status of the process of the node after the operation
+	Status string  `json:"status,omitempty"`
+	PID    int     `json:"pid,omitempty"`
+	Uptime float64 `json:"uptime,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// ListNodeIDs returns the IDs of the nodes of a kind managed on the host
+func ListNodeIDs(kind string) ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(home, "hlf-easy", kind+"s"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	ids := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Run runs an operation on the nodes with at most parallelism of them at the
+// same time, the results are in the order of the IDs
+func Run(ids []string, parallelism int, operation func(id string) Result) []Result {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	results := make([]Result, len(ids))
+	sem := make(chan struct{}, parallelism)
+	wg := sync.WaitGroup{}
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = operation(id)
+		}(i, id)
+	}
+	wg.Wait()
+	return results
+}
+
+func setStatus(result *Result, status *node.ProcessState) {
+	if status == nil {
+		return
+	}
+	result.Status = status.Status
+	result.PID = status.PID
+	result.Uptime = status.Uptime
+}
+
+// Operate starts, stops or reads the status of a node through the management
+// API of its hlf-easy process. The nodes whose hlf-easy process is down can't
+// be started or stopped, they're reported as failed
+func Operate(kind string, id string, action string, token string) Result {
+	result := Result{Kind: kind, ID: id, Action: action}
+	if action != ActionStatus {
+		n, err := dashboard.GetNode(kind, id, token)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if !n.Running {
+			result.Status = "Stop"
+			result.Error = fmt.Sprintf("the hlf-easy process of %s %s is down, start it with hlf-easy %s start --id=%s", kind, id, kind, id)
+			return result
+		}
+		if err := dashboard.RunAction(kind, id, action, token); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+	n, err := dashboard.GetNode(kind, id, token)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if !n.Running {
+		// the node is stopped with its hlf-easy process
+		result.Status = "Stop"
+		result.OK = true
+		return result
+	}
+	setStatus(&result, n.Status)
+	if n.Error != "" {
+		result.Error = n.Error
+		return result
+	}
+	result.OK = true
+	return result
+}
+
+// Failed returns the number of failed results
+func Failed(results []Result) int {
+	failed := 0
+	for _, result := range results {
+		if !result.OK {
+			failed++
+		}
+	}
+	return failed
+}
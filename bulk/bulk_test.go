@@ -0,0 +1,105 @@
+package bulk
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	ids := []string{"peer0", "peer1", "peer2", "peer3", "peer4"}
+	mu := sync.Mutex{}
+	running, maxRunning := 0, 0
+	results := Run(ids, 2, func(id string) Result {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return Result{ID: id, OK: id != "peer3"}
+	})
+	if maxRunning != 2 {
+		t.Errorf("expected 2 operations at the same time, got %d", maxRunning)
+	}
+	for i, result := range results {
+		if result.ID != ids[i] {
+			t.Fatalf("expected the results in the order of the ids, got %v", results)
+		}
+	}
+	if Failed(results) != 1 {
+		t.Errorf("expected 1 failed result, got %d", Failed(results))
+	}
+}
+
+func TestOperate(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	stopped := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/stop":
+			stopped = true
+			w.Write([]byte("{}"))
+		case r.Method == http.MethodGet && r.URL.Path == "/status":
+			status := map[string]interface{}{"pid": 42, "status": "Running", "uptime": 60}
+			if stopped {
+				status = map[string]interface{}{"pid": 0, "status": "Stop"}
+			}
+			json.NewEncoder(w).Encode(status)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	for _, id := range []string{"peer0", "peer1"} {
+		err := os.MkdirAll(filepath.Join(home, "hlf-easy/peers", id), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	runConfig := fmt.Sprintf(`{"options":{"managementAddress":"%s"}}`, strings.TrimPrefix(srv.URL, "http://"))
+	err := os.WriteFile(filepath.Join(home, "hlf-easy/peers/peer0/run.json"), []byte(runConfig), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := ListNodeIDs("peer")
+	if err != nil || strings.Join(ids, ",") != "peer0,peer1" {
+		t.Fatalf("expected peer0 and peer1, got %v: %v", ids, err)
+	}
+	result := Operate("peer", "peer0", ActionStatus, "")
+	if !result.OK || result.Status != "Running" || result.PID != 42 {
+		t.Fatalf("unexpected status %+v", result)
+	}
+	result = Operate("peer", "peer1", ActionStatus, "")
+	if !result.OK || result.Status != "Stop" {
+		t.Fatalf("expected peer1 to be stopped with its hlf-easy process, got %+v", result)
+	}
+	result = Operate("peer", "peer0", ActionStop, "")
+	if !result.OK || result.Status != "Stop" || !stopped {
+		t.Fatalf("expected peer0 to be stopped, got %+v", result)
+	}
+	result = Operate("peer", "peer1", ActionStop, "")
+	if result.OK || !strings.Contains(result.Error, "is down") {
+		t.Fatalf("expected the stop of peer1 to fail, got %+v", result)
+	}
+	// a peer whose hlf-easy process is down is reported, not skipped
+	results := Run(ids, 2, func(id string) Result {
+		return Operate("peer", id, ActionStart, "")
+	})
+	if len(results) != 2 || results[1].OK || !strings.Contains(results[1].Error, "hlf-easy peer start --id=peer1") {
+		t.Fatalf("expected the start of peer1 to fail, got %+v", results)
+	}
+}
@@ -0,0 +1,510 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/chaincode"
+	"hlf-easy/fabric"
+	"hlf-easy/log"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// FormatVersion is the version of the layout of the bundles
+const FormatVersion = 1
+
+// names of the entries of a bundle, the manifest is the first one
+const (
+	manifestName = "manifest.json"
+	fabricDir    = "fabric"
+	buildersDir  = "builders"
+	imagesName   = "images.tar"
+)
+
+// dockerCommand is the docker CLI saving and loading the images
+var dockerCommand = "docker"
+
+// Manifest describes the artifacts of a bundle
+type Manifest struct {
+	FormatVersion int       `json:"formatVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	// OS and Arch of the fabric binaries, the bundle is imported on hosts of
+	// the same platform
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// Fabric are the versions of Fabric whose binaries are bundled
+	Fabric []string `json:"fabric"`
+	// Builders are the custom external builders of the host
+	Builders []string `json:"builders"`
+	// Images are the docker images of the chaincodes, saved with docker save
+	Images []string `json:"images"`
+}
+
+// CreateOptions select the artifacts of a bundle
+type CreateOptions struct {
+	// FabricVersions are downloaded unless they're already installed
+	FabricVersions []string
+	Builders       []string
+	Images         []string
+	// RegistryImages adds the images of the docker chaincodes of the registry
+	RegistryImages bool
+	// Platform is the <os>/<arch> of the hosts importing the bundle, the
+	// platform of the host when empty. The binaries of Fabric are downloaded
+	// for it and the variants of the images of the platform are pulled
+	Platform string
+}
+
+// RegistryImages returns the images of the docker chaincodes of the registry
+func RegistryImages() ([]string, error) {
+	definitions, err := chaincode.List()
+	if err != nil {
+		return nil, err
+	}
+	var images []string
+	for _, d := range definitions {
+		if d.Type == chaincode.TypeDocker && d.Image != "" {
+			images = append(images, d.Image)
+		}
+	}
+	return images, nil
+}
+
+// uniq returns the values without the empty and duplicated ones, in order
+func uniq(values []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+	return result
+}
+
+// Create writes a bundle with the binaries of the versions of Fabric, the
+// external builders and the docker images to a tar.gz, it runs on a host
+// with access to the internet and docker when images are bundled. The
+// sample chaincodes are part of hlf-easy and aren't bundled
+func Create(bundlePath string, opts CreateOptions) (*Manifest, error) {
+	var err error
+	goos, goarch := runtime.GOOS, runtime.GOARCH
+	if opts.Platform != "" {
+		goos, goarch, err = fabric.ParsePlatform(opts.Platform)
+		if err != nil {
+			return nil, err
+		}
+	}
+	crossPlatform := goos != runtime.GOOS || goarch != runtime.GOARCH
+	m := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		OS:            goos,
+		Arch:          goarch,
+		Fabric:        []string{},
+		Builders:      uniq(opts.Builders),
+		Images:        uniq(opts.Images),
+	}
+	if opts.RegistryImages {
+		images, err := RegistryImages()
+		if err != nil {
+			return nil, err
+		}
+		m.Images = uniq(append(m.Images, images...))
+	}
+	// the versions are given with or without their v prefix
+	var versions []string
+	for _, version := range opts.FabricVersions {
+		v, err := fabric.ParseVersion(version)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v.String())
+	}
+	var binDirs []string
+	// the binaries of another platform aren't installed, they're downloaded
+	// to a temporary directory
+	var downloadDir string
+	if crossPlatform && len(versions) > 0 {
+		downloadDir, err = os.MkdirTemp("", "hlf-easy-fabric-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(downloadDir)
+	}
+	for _, version := range uniq(versions) {
+		v, err := fabric.ParseVersion(version)
+		if err != nil {
+			return nil, err
+		}
+		var binDir string
+		if crossPlatform {
+			binDir = filepath.Join(downloadDir, v.String())
+			err = fabric.Download(v, goos, goarch, binDir)
+		} else {
+			binDir, err = fabric.Install(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.Fabric = append(m.Fabric, v.String())
+		binDirs = append(binDirs, binDir)
+	}
+	builders, err := chaincode.ListExternalBuilders()
+	if err != nil {
+		return nil, err
+	}
+	builderDirs := map[string]string{}
+	for _, b := range builders {
+		builderDirs[b.Name] = b.Path
+	}
+	for _, name := range m.Builders {
+		if _, ok := builderDirs[name]; !ok {
+			return nil, errors.Errorf("builder %s not found", name)
+		}
+	}
+	if len(m.Fabric)+len(m.Builders)+len(m.Images) == 0 {
+		return nil, errors.New("the bundle has no artifacts, add fabric versions, builders or images")
+	}
+
+	f, err := os.OpenFile(bundlePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	platform := ""
+	if crossPlatform {
+		platform = goos + "/" + goarch
+	}
+	err = writeBundle(f, m, platform, binDirs, builderDirs)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// a partial bundle can't be imported
+		_ = os.Remove(bundlePath)
+		return nil, errors.Wrap(err, "failed to create the bundle")
+	}
+	return m, nil
+}
+
+func writeBundle(w io.Writer, m *Manifest, platform string, binDirs []string, builderDirs map[string]string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	manifestBytes, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0644,
+		Size:    int64(len(manifestBytes)),
+		ModTime: m.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(manifestBytes)
+	if err != nil {
+		return err
+	}
+	for i, version := range m.Fabric {
+		err = writeDir(tw, binDirs[i], path.Join(fabricDir, version))
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range m.Builders {
+		err = writeDir(tw, builderDirs[name], path.Join(buildersDir, name))
+		if err != nil {
+			return err
+		}
+	}
+	if len(m.Images) > 0 {
+		err = writeImages(tw, m.Images, platform)
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// writeDir writes the regular files of a directory under a prefix
+func writeDir(tw *tar.Writer, dir string, prefix string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		name, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, filepath.ToSlash(name))
+		err = tw.WriteHeader(header)
+		if err != nil || info.IsDir() {
+			return err
+		}
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+}
+
+// writeImages saves the images with docker and writes the archive of docker
+// save to the bundle. For another platform the variants of the platform of
+// the multi-arch images are pulled first, docker saves the pulled ones
+func writeImages(tw *tar.Writer, images []string, platform string) error {
+	tmp, err := os.CreateTemp("", "hlf-easy-images-*.tar")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	if platform != "" {
+		for _, image := range images {
+			log.Infof("Pulling the docker image %s for %s", image, platform)
+			cmd := exec.Command(dockerCommand, "pull", "--platform", platform, image)
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				return errors.Wrapf(err, "failed to pull the docker image %s for %s: %s", image, platform, strings.TrimSpace(string(output)))
+			}
+		}
+	}
+	log.Infof("Saving the docker images %s", strings.Join(images, ", "))
+	cmd := exec.Command(dockerCommand, append([]string{"save", "-o", tmp.Name()}, images...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "failed to save the docker images: %s", strings.TrimSpace(string(output)))
+	}
+	info, err := os.Stat(tmp.Name())
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = imagesName
+	err = tw.WriteHeader(header)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(tmp.Name())
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(tw, src)
+	return err
+}
+
+// ReadManifest reads the manifest of a bundle
+func ReadManifest(bundlePath string) (*Manifest, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "the bundle isn't a tar.gz")
+	}
+	defer gz.Close()
+	return readManifest(tar.NewReader(gz))
+}
+
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the bundle")
+	}
+	if header.Name != manifestName {
+		return nil, errors.Errorf("the bundle has no %s", manifestName)
+	}
+	m := &Manifest{}
+	err = json.NewDecoder(tr).Decode(m)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", manifestName)
+	}
+	if m.FormatVersion != FormatVersion {
+		return nil, errors.Errorf("the bundle has the format %d, hlf-easy reads the format %d", m.FormatVersion, FormatVersion)
+	}
+	return m, nil
+}
+
+// ImportResult are the artifacts of a bundle installed on the host, the
+// versions of Fabric and the builders already on the host are skipped
+type ImportResult struct {
+	Manifest *Manifest
+	Fabric   []string
+	Builders []string
+	Images   []string
+}
+
+// Import installs the artifacts of a bundle on a host without access to the
+// internet: the binaries of Fabric next to the ones downloaded by hlf-easy,
+// the builders with the custom external builders of the host and the images
+// with docker load
+func Import(bundlePath string) (*ImportResult, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	// the bundle is extracted in the hlf-easy home so its artifacts are
+	// moved in place, an interrupted import doesn't leave partial ones
+	err = os.MkdirAll(filepath.Join(home, "hlf-easy"), 0755)
+	if err != nil {
+		return nil, err
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Join(home, "hlf-easy"), ".bundle-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	m, err := extract(bundlePath, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	result := &ImportResult{Manifest: m}
+	for _, version := range m.Fabric {
+		v, err := fabric.ParseVersion(version)
+		if err != nil {
+			return nil, err
+		}
+		binDir, err := fabric.GetBinDir(v)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(filepath.Join(binDir, fabric.BinaryFile("peer"))); err == nil {
+			log.Infof("Fabric %s is already installed", version)
+			continue
+		}
+		err = moveDir(filepath.Join(tmpDir, fabricDir, version), binDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to install fabric %s", version)
+		}
+		result.Fabric = append(result.Fabric, version)
+	}
+	externalBuildersDir, err := chaincode.GetExternalBuildersDir()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range m.Builders {
+		builderDir := filepath.Join(externalBuildersDir, name)
+		if _, err := os.Stat(builderDir); err == nil {
+			log.Infof("Builder %s is already on the host", name)
+			continue
+		}
+		err = moveDir(filepath.Join(tmpDir, buildersDir, name), builderDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to install builder %s", name)
+		}
+		result.Builders = append(result.Builders, name)
+	}
+	if len(m.Images) > 0 {
+		log.Infof("Loading the docker images %s", strings.Join(m.Images, ", "))
+		cmd := exec.Command(dockerCommand, "load", "-i", filepath.Join(tmpDir, imagesName))
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load the docker images: %s", strings.TrimSpace(string(output)))
+		}
+		result.Images = m.Images
+	}
+	return result, nil
+}
+
+// extract checks the manifest of a bundle against the host and extracts its
+// artifacts in a directory
+func extract(bundlePath string, dir string) (*Manifest, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "the bundle isn't a tar.gz")
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	m, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Fabric) > 0 && (m.OS != runtime.GOOS || m.Arch != runtime.GOARCH) {
+		return nil, errors.Errorf("the bundle has the fabric binaries of %s/%s, the host is %s/%s", m.OS, m.Arch, runtime.GOOS, runtime.GOARCH)
+	}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the bundle")
+		}
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, errors.Errorf("invalid path %s in the bundle", header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = extractFile(tr, target, os.FileMode(header.Mode).Perm())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func extractFile(r io.Reader, target string, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// moveDir moves an extracted directory to its place in the hlf-easy home
+func moveDir(src string, dst string) error {
+	if _, err := os.Stat(src); err != nil {
+		return errors.New("the bundle doesn't have it")
+	}
+	err := os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
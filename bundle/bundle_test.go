@@ -0,0 +1,226 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"hlf-easy/chaincode"
+	"hlf-easy/fabric"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// fakeDocker writes a docker CLI whose save writes the images to the
+// archive, whose load copies the archive to loaded and whose pull logs the
+// images to pulled
+func fakeDocker(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	script := `#!/bin/sh
+set -e
+case "$1" in
+save) out="$3"; shift 3; echo "$@" > "$out" ;;
+pull) shift; echo "$@" >> "` + filepath.Join(dir, "pulled") + `" ;;
+load) cp "$3" "` + filepath.Join(dir, "loaded") + `" ;;
+*) exit 1 ;;
+esac
+`
+	err := os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := dockerCommand
+	t.Cleanup(func() { dockerCommand = command })
+	dockerCommand = filepath.Join(dir, "docker")
+	return dir
+}
+
+func TestCreateImport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dockerDir := fakeDocker(t)
+	v, err := fabric.ParseVersion("2.5.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the installed version isn't downloaded again
+	binDir, err := fabric.GetBinDir(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(binDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"peer", "orderer"} {
+		err = os.WriteFile(filepath.Join(binDir, name), []byte(name+" binary"), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := chaincode.ScaffoldExternalBuilder("wasm", nil, false); err != nil {
+		t.Fatal(err)
+	}
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if _, err := Create(bundlePath, CreateOptions{Builders: []string{"rust"}}); err == nil {
+		t.Fatal("expected an error for an unknown builder")
+	}
+	if _, err := Create(bundlePath, CreateOptions{}); err == nil {
+		t.Fatal("expected an error for an empty bundle")
+	}
+	m, err := Create(bundlePath, CreateOptions{
+		FabricVersions: []string{"2.5.4", "v2.5.4"},
+		Builders:       []string{"wasm"},
+		Images:         []string{"asset:1.0", "asset:1.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Fabric, []string{"2.5.4"}) || !reflect.DeepEqual(m.Images, []string{"asset:1.0"}) {
+		t.Errorf("unexpected manifest %+v", m)
+	}
+	if _, err := Create(bundlePath, CreateOptions{FabricVersions: []string{"2.5.4"}}); err == nil {
+		t.Fatal("expected an error for an existing bundle")
+	}
+	read, err := ReadManifest(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Builders, []string{"wasm"}) {
+		t.Errorf("unexpected manifest %+v", read)
+	}
+
+	// the bundle is imported on a host without the artifacts
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	result, err := Import(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Fabric, []string{"2.5.4"}) || !reflect.DeepEqual(result.Builders, []string{"wasm"}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+	peerBytes, err := os.ReadFile(filepath.Join(home, "hlf-easy/bin/fabric-2.5.4/peer"))
+	if err != nil || string(peerBytes) != "peer binary" {
+		t.Errorf("expected the peer binary, got %q: %v", peerBytes, err)
+	}
+	builders, err := chaincode.ListExternalBuilders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(builders) != 1 || builders[0].Name != "wasm" {
+		t.Errorf("expected the builder wasm, got %+v", builders)
+	}
+	if info, err := os.Stat(filepath.Join(builders[0].Path, "bin", "detect")); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("expected an executable detect script: %v", err)
+	}
+	loaded, err := os.ReadFile(filepath.Join(dockerDir, "loaded"))
+	if err != nil || string(loaded) != "asset:1.0\n" {
+		t.Errorf("expected the images to be loaded, got %q: %v", loaded, err)
+	}
+	entries, err := os.ReadDir(filepath.Join(home, "hlf-easy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "bin" && entry.Name() != "builders" {
+			t.Errorf("unexpected %s left by the import", entry.Name())
+		}
+	}
+
+	// the artifacts already on the host are skipped
+	result, err = Import(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Fabric) != 0 || len(result.Builders) != 0 {
+		t.Errorf("expected the artifacts to be skipped, got %+v", result)
+	}
+}
+
+func TestCreatePlatform(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dockerDir := fakeDocker(t)
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if _, err := Create(bundlePath, CreateOptions{Images: []string{"asset:1.0"}, Platform: "arm64"}); err == nil {
+		t.Fatal("expected an error for an invalid platform")
+	}
+	platform := "linux/arm64"
+	if runtime.GOARCH == "arm64" {
+		platform = "linux/amd64"
+	}
+	m, err := Create(bundlePath, CreateOptions{Images: []string{"asset:1.0"}, Platform: platform})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.OS+"/"+m.Arch != platform {
+		t.Errorf("expected a bundle for %s, got %s/%s", platform, m.OS, m.Arch)
+	}
+	// the variant of the platform is pulled before it's saved
+	pulled, err := os.ReadFile(filepath.Join(dockerDir, "pulled"))
+	if err != nil || string(pulled) != "--platform "+platform+" asset:1.0\n" {
+		t.Errorf("expected the image to be pulled for %s, got %q: %v", platform, pulled, err)
+	}
+}
+
+func writeArchive(t *testing.T, m Manifest, files map[string]string) string {
+	t.Helper()
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	f, err := os.Create(bundlePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	manifestBytes, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []struct{ name, content string }{{manifestName, string(manifestBytes)}}
+	for name, content := range files {
+		entries = append(entries, struct{ name, content string }{name, content})
+	}
+	for _, e := range entries {
+		err = tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tw.Write([]byte(e.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bundlePath
+}
+
+func TestImportInvalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	valid := Manifest{FormatVersion: FormatVersion, OS: runtime.GOOS, Arch: runtime.GOARCH}
+	otherPlatform := valid
+	otherPlatform.OS = "plan9"
+	otherPlatform.Fabric = []string{"2.5.4"}
+	missingFabric := valid
+	missingFabric.Fabric = []string{"2.5.4"}
+	otherFormat := valid
+	otherFormat.FormatVersion = FormatVersion + 1
+	invalid := map[string]string{
+		"other platform": writeArchive(t, otherPlatform, nil),
+		"other format":   writeArchive(t, otherFormat, nil),
+		"path traversal": writeArchive(t, valid, map[string]string{"../escape": "escape"}),
+		"missing fabric": writeArchive(t, missingFabric, nil),
+	}
+	for name, bundlePath := range invalid {
+		if _, err := Import(bundlePath); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package certs
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/utils"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// MaxBatchIdentities bounds the identities issued by a batch enrollment
+const MaxBatchIdentities = 10000
+
+// Formats of the manifests of the batch enrollments
+const (
+	BatchFormatCSV  = "csv"
+	BatchFormatJSON = "json"
+)
+
+// BatchIdentity is an identity of a batch enrollment
+type BatchIdentity struct {
+	CommonName string `json:"commonName"`
+	// Type is the OU of the identity, client when empty
+	Type string `json:"type,omitempty"`
+	// OUs are added to the OU of the type
+	OUs []string `json:"ous,omitempty"`
+	// Affiliation is added as OUs like the Fabric CA does, org1.department1
+	// adds the OUs org1 and department1
+	Affiliation string `json:"affiliation,omitempty"`
+}
+
+// BatchEnrollOptions select the local CA and the identities of a batch
+// enrollment
+type BatchEnrollOptions struct {
+	CAName     string          `json:"-"`
+	Identities []BatchIdentity `json:"identities"`
+	// Parallelism is the number of identities issued at the same time, the
+	// number of CPUs when 0
+	Parallelism int `json:"parallelism,omitempty"`
+	// CertPolicy overrides the certificate policy of the CA
+	CertPolicy config.CertificatePolicy `json:"certPolicy,omitempty"`
+}
+
+// EnrolledIdentity is an identity issued by a batch enrollment, with its
+// certificate and its key PEM encoded
+type EnrolledIdentity struct {
+	CommonName string `json:"commonName"`
+	Cert       string `json:"cert"`
+	Key        string `json:"key"`
+}
+
+// ous returns the OUs of an identity added to the OU of its type
+func (i BatchIdentity) ous() []string {
+	ous := append([]string{}, i.OUs...)
+	if i.Affiliation != "" {
+		ous = append(ous, strings.Split(i.Affiliation, ".")...)
+	}
+	return ous
+}
+
+// Validate checks the CA and the identities of a batch enrollment, their
+// common names are unique
+func (o BatchEnrollOptions) Validate() error {
+	if o.CAName == "" {
+		return errors.New("the name of the CA is required")
+	}
+	if len(o.Identities) == 0 {
+		return errors.New("the batch has no identities")
+	}
+	if len(o.Identities) > MaxBatchIdentities {
+		return errors.Errorf("the batch has %d identities, at most %d are issued at once", len(o.Identities), MaxBatchIdentities)
+	}
+	if o.Parallelism < 0 {
+		return errors.New("the parallelism can't be negative")
+	}
+	seen := map[string]bool{}
+	for i, identity := range o.Identities {
+		if identity.CommonName == "" {
+			return errors.Errorf("identity %d has no common name", i+1)
+		}
+		// the common names name the files of the identities
+		if strings.ContainsAny(identity.CommonName, `/\`) || identity.CommonName == "." || identity.CommonName == ".." {
+			return errors.Errorf("invalid common name %q", identity.CommonName)
+		}
+		if seen[identity.CommonName] {
+			return errors.Errorf("the common name %s is repeated", identity.CommonName)
+		}
+		seen[identity.CommonName] = true
+		for _, ou := range identity.ous() {
+			if ou == "" {
+				return errors.Errorf("identity %s has an empty OU or affiliation", identity.CommonName)
+			}
+		}
+	}
+	return ValidateCertificatePolicy(o.CertPolicy)
+}
+
+// ParseBatchManifest parses the identities of a batch enrollment, a JSON
+// array of identities or a CSV with a header naming its columns among
+// commonName, type, ous and affiliation, the OUs separated by ;
+func ParseBatchManifest(r io.Reader, format string) ([]BatchIdentity, error) {
+	identities := []BatchIdentity{}
+	switch format {
+	case BatchFormatJSON:
+		err := json.NewDecoder(r).Decode(&identities)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid JSON manifest")
+		}
+		return identities, nil
+	case BatchFormatCSV:
+	default:
+		return nil, errors.Errorf("unknown manifest format %q, expected csv or json", format)
+	}
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid CSV manifest")
+	}
+	if len(records) == 0 {
+		return identities, nil
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		switch strings.TrimSpace(name) {
+		case "commonName", "type", "ous", "affiliation":
+			columns[strings.TrimSpace(name)] = i
+		default:
+			return nil, errors.Errorf("unknown column %q of the CSV manifest, expected commonName, type, ous or affiliation", name)
+		}
+	}
+	if _, ok := columns["commonName"]; !ok {
+		return nil, errors.New("the CSV manifest has no commonName column")
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	for _, record := range records[1:] {
+		identity := BatchIdentity{
+			CommonName:  field(record, "commonName"),
+			Type:        field(record, "type"),
+			Affiliation: field(record, "affiliation"),
+		}
+		if ous := field(record, "ous"); ous != "" {
+			for _, ou := range strings.Split(ous, ";") {
+				identity.OUs = append(identity.OUs, strings.TrimSpace(ou))
+			}
+		}
+		identities = append(identities, identity)
+	}
+	return identities, nil
+}
+
+// EnrollBatch issues the identities of a batch with a local CA, at most
+// Parallelism of them at the same time. The identities are returned in the
+// order of the batch, the issuance stops at the first error or when the
+// context is done
+func EnrollBatch(ctx context.Context, o BatchEnrollOptions) ([]EnrolledIdentity, error) {
+	err := o.Validate()
+	if err != nil {
+		return nil, err
+	}
+	caConfig, err := utils.GetCAConfig(o.CAName)
+	if err != nil {
+		return nil, err
+	}
+	parallelism := o.Parallelism
+	if parallelism == 0 {
+		parallelism = runtime.NumCPU()
+	}
+	// the sequential serial numbers are taken under the lock of the CA, the
+	// identities are issued one at a time then
+	if caConfig.CertPolicy.Merge(o.CertPolicy).SerialNumberPolicy == "sequential" {
+		parallelism = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	enrolled := make([]EnrolledIdentity, len(o.Identities))
+	var errOnce sync.Once
+	var batchErr error
+	sem := make(chan struct{}, parallelism)
+	wg := sync.WaitGroup{}
+	for i, identity := range o.Identities {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, identity BatchIdentity) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			identityType := identity.Type
+			if identityType == "" {
+				identityType = "client"
+			}
+			crtPem, keyPem, err := enrollWithCA(caConfig, EnrollOptions{
+				CAName:     o.CAName,
+				Type:       identityType,
+				CommonName: identity.CommonName,
+				CertPolicy: o.CertPolicy,
+			}, identity.ous())
+			if err != nil {
+				errOnce.Do(func() {
+					batchErr = errors.Wrapf(err, "failed to issue %s", identity.CommonName)
+					cancel()
+				})
+				return
+			}
+			enrolled[i] = EnrolledIdentity{CommonName: identity.CommonName, Cert: string(crtPem), Key: string(keyPem)}
+		}(i, identity)
+	}
+	wg.Wait()
+	if batchErr != nil {
+		return nil, batchErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return enrolled, nil
+}
@@ -0,0 +1,93 @@
+package certs
+
+import (
+	"context"
+	"fmt"
+	"hlf-easy/utils"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseBatchManifest(t *testing.T) {
+	csvManifest := "commonName,type,ous,affiliation\nuser1,,,org1.department1\nadmin1,admin,ops;audit,\n"
+	identities, err := ParseBatchManifest(strings.NewReader(csvManifest), BatchFormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []BatchIdentity{
+		{CommonName: "user1", Affiliation: "org1.department1"},
+		{CommonName: "admin1", Type: "admin", OUs: []string{"ops", "audit"}},
+	}
+	if !reflect.DeepEqual(identities, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, identities)
+	}
+	jsonManifest := `[{"commonName":"user1","affiliation":"org1.department1"},{"commonName":"admin1","type":"admin","ous":["ops","audit"]}]`
+	identities, err = ParseBatchManifest(strings.NewReader(jsonManifest), BatchFormatJSON)
+	if err != nil || !reflect.DeepEqual(identities, expected) {
+		t.Fatalf("expected %+v, got %+v: %v", expected, identities, err)
+	}
+	if _, err := ParseBatchManifest(strings.NewReader("name\nuser1\n"), BatchFormatCSV); err == nil {
+		t.Fatal("expected an error for an unknown column")
+	}
+
+	invalid := [][]BatchIdentity{
+		{},
+		{{CommonName: ""}},
+		{{CommonName: "../user1"}},
+		{{CommonName: "user1"}, {CommonName: "user1"}},
+		{{CommonName: "user1", Affiliation: "org1..department1"}},
+	}
+	for _, identities := range invalid {
+		if err := (BatchEnrollOptions{CAName: "ca0", Identities: identities}).Validate(); err == nil {
+			t.Errorf("expected an error for %+v", identities)
+		}
+	}
+}
+
+func TestEnrollBatch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, err := InitCA(InitCAOptions{Name: "ca0", Organization: "Org1", Hosts: []string{"localhost"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := BatchEnrollOptions{CAName: "ca0", Parallelism: 4}
+	for i := 0; i < 20; i++ {
+		opts.Identities = append(opts.Identities, BatchIdentity{CommonName: fmt.Sprintf("user%d", i), Affiliation: "org1.department1"})
+	}
+	enrolled, err := EnrollBatch(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enrolled) != len(opts.Identities) {
+		t.Fatalf("expected %d identities, got %d", len(opts.Identities), len(enrolled))
+	}
+	for i, identity := range enrolled {
+		crt, err := utils.ParseX509Certificate([]byte(identity.Cert))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if crt.Subject.CommonName != opts.Identities[i].CommonName || identity.CommonName != crt.Subject.CommonName {
+			t.Fatalf("expected the identities in the order of the batch, got %s for %s", crt.Subject.CommonName, opts.Identities[i].CommonName)
+		}
+		// the OUs are a DER set, sorted by their encoding
+		ous := append([]string{}, crt.Subject.OrganizationalUnit...)
+		sort.Strings(ous)
+		if !reflect.DeepEqual(ous, []string{"client", "department1", "org1"}) {
+			t.Fatalf("expected the OUs of a client and of its affiliation, got %v", crt.Subject.OrganizationalUnit)
+		}
+		if _, err := utils.ParseECDSAPrivateKey([]byte(identity.Key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := EnrollBatch(ctx, opts); err == nil {
+		t.Fatal("expected a canceled batch to fail")
+	}
+	if _, err := EnrollBatch(context.Background(), BatchEnrollOptions{CAName: "ca1", Identities: opts.Identities}); err == nil {
+		t.Fatal("expected an error for an unknown CA")
+	}
+}
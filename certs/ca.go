@@ -0,0 +1,344 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"hlf-easy/secrets"
+	"hlf-easy/utils"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// InitCAOptions are the subject and the hosts of a local CA
+type InitCAOptions struct {
+	Name               string
+	Organization       string
+	Country            string
+	Locality           string
+	OrganizationalUnit string
+	StreetAddress      string
+	Hosts              []string
+	// CertPolicy is the default policy of the certificates issued by the CA
+	CertPolicy config.CertificatePolicy
+	// Only creates the signing or the TLS CA of an existing local CA again,
+	// the other one is kept
+	Only string
+}
+
+// Validate checks the name, the hosts and the certificate policy of the CA
+func (o InitCAOptions) Validate() error {
+	if o.Name == "" {
+		return errors.Errorf("--name must be specified")
+	}
+	if o.Only != "" {
+		return ValidateCAKind(o.Only)
+	}
+	if len(o.Hosts) == 0 {
+		return errors.Errorf("--hosts must be specified")
+	}
+	return ValidateCertificatePolicy(o.CertPolicy)
+}
+
+// InitCA creates the signing and TLS CAs of a local CA with the TLS
+// certificate of its hosts, and writes them to cas/<name>/config.json
+func InitCA(o InitCAOptions) (*config.CAConfig, error) {
+	if o.Only != "" {
+		return initOneCA(o)
+	}
+	tlsCert, tlsPK, err := o.createDefaultTLSCert()
+	if err != nil {
+		return nil, err
+	}
+	caCert, caPK, err := o.createDefaultCA("ca")
+	if err != nil {
+		return nil, err
+	}
+	tlsCACert, tlsCAPK, err := o.createDefaultCA("tlsca")
+	if err != nil {
+		return nil, err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	dirPath := filepath.Join(
+		homeDir,
+		fmt.Sprintf("hlf-easy/cas/%s", o.Name),
+	)
+	err = os.MkdirAll(dirPath, 0755) // Creates the directory if it doesn't exist
+	if err != nil {
+		return nil, err
+	}
+	tlsKeyBytes, err := utils.EncodePrivateKey(tlsPK)
+	if err != nil {
+		return nil, err
+	}
+	caKeyBytes, err := utils.EncodePrivateKey(caPK)
+	if err != nil {
+		return nil, err
+	}
+	tlsCAKeyBytes, err := utils.EncodePrivateKey(tlsCAPK)
+	if err != nil {
+		return nil, err
+	}
+	caConfig := &config.CAConfig{
+		CaCert:    utils.EncodeX509Certificate(caCert),
+		CaKey:     caKeyBytes,
+		CaName:    o.Name,
+		TlsCACert: utils.EncodeX509Certificate(tlsCACert),
+		TlsCAKey:  tlsCAKeyBytes,
+		TlsCert:   utils.EncodeX509Certificate(tlsCert),
+		TlsKey:    tlsKeyBytes,
+
+		CertPolicy: o.CertPolicy,
+	}
+	err = writeCAConfig(o.Name, caConfig)
+	if err != nil {
+		return nil, err
+	}
+	return caConfig, nil
+}
+
+func caConfigPath(name string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, fmt.Sprintf("hlf-easy/cas/%s/config.json", name)), nil
+}
+
+// readCAConfig reads the cas/<name>/config.json of a local CA
+func readCAConfig(name string) (*config.CAConfig, error) {
+	filePath, err := caConfigPath(name)
+	if err != nil {
+		return nil, err
+	}
+	configBytes, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, errdefs.Errorf(errdefs.ErrCANotInitialized, "ca config file does not exist: %v", filePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	caConfig := &config.CAConfig{}
+	err = json.Unmarshal(configBytes, caConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", filePath)
+	}
+	err = utils.LoadCAKeys(name, caConfig)
+	if err != nil {
+		return nil, err
+	}
+	return caConfig, nil
+}
+
+// writeCAConfig writes the cas/<name>/config.json of a local CA, its keys are
+// written to its secrets manager instead when it has one
+func writeCAConfig(name string, caConfig *config.CAConfig) error {
+	filePath, err := caConfigPath(name)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secrets.DefaultTimeout)
+	defer cancel()
+	caConfig, err = utils.StoreCAKeys(ctx, name, caConfig)
+	if err != nil {
+		return err
+	}
+	configBytes, err := json.MarshalIndent(caConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	// the certificates renewed by a rotation are parsed again
+	defer utils.InvalidateConfig(filePath)
+	return os.WriteFile(filePath, configBytes, 0644)
+}
+
+// splitHosts splits the hosts of a certificate in IPs and DNS names
+func splitHosts(hosts []string) ([]net.IP, []string) {
+	var ips []net.IP
+	var dnsNames []string
+	for _, host := range hosts {
+		// check if it's ip address
+		ip := net.ParseIP(host)
+		if ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = append(dnsNames, host)
+		}
+	}
+	return ips, dnsNames
+}
+
+// newCAKey returns the key and the serial number of a certificate of the CA,
+// derived from its name and organization in the deterministic mode
+func (o InitCAOptions) newCAKey(commonName string) (*ecdsa.PrivateKey, *big.Int, error) {
+	if o.CertPolicy.Deterministic {
+		key := deterministicKey(elliptic.P256(), deterministicSeed(o.Name, o.Organization, commonName))
+		return key, deterministicSerialNumber(key), nil
+	}
+	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate serial number")
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, serialNumber, nil
+}
+
+func (o InitCAOptions) createDefaultTLSCert() (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	caPrivKey, serialNumber, err := o.newCAKey("tls")
+	if err != nil {
+		return nil, nil, err
+	}
+	ips, dnsNames := splitHosts(o.Hosts)
+	notBefore, notAfter := validityWindow(time.Now().AddDate(0, 0, -1), time.Now().AddDate(10, 0, 0), o.CertPolicy.Deterministic)
+
+	x509Cert := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization:       []string{o.Organization},
+			Country:            []string{o.Country},
+			Locality:           []string{o.Locality},
+			OrganizationalUnit: []string{o.OrganizationalUnit},
+			StreetAddress:      []string{o.StreetAddress},
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           ips,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		SubjectKeyId:          computeSKI(caPrivKey),
+	}
+
+	caBytes, err := x509.CreateCertificate(rand.Reader, x509Cert, x509Cert, &caPrivKey.PublicKey, issuerSigner(caPrivKey, o.CertPolicy.Deterministic))
+	if err != nil {
+		return nil, nil, err
+	}
+	crt, err := x509.ParseCertificate(caBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return crt, caPrivKey, nil
+}
+
+func (o InitCAOptions) createDefaultCA(commonName string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	caPrivKey, serialNumber, err := o.newCAKey(commonName)
+	if err != nil {
+		return nil, nil, err
+	}
+	notBefore, notAfter := validityWindow(time.Now().AddDate(0, 0, -1), time.Now().AddDate(10, 0, 0), o.CertPolicy.Deterministic)
+
+	signCA := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization:       []string{o.Organization},
+			Country:            []string{o.Country},
+			Locality:           []string{o.Locality},
+			OrganizationalUnit: []string{o.OrganizationalUnit},
+			StreetAddress:      []string{o.StreetAddress},
+			CommonName:         commonName,
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		SubjectKeyId:          computeSKI(caPrivKey),
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+	}
+	caBytes, err := x509.CreateCertificate(rand.Reader, signCA, signCA, &caPrivKey.PublicKey, issuerSigner(caPrivKey, o.CertPolicy.Deterministic))
+	if err != nil {
+		return nil, nil, err
+	}
+	crt, err := x509.ParseCertificate(caBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return crt, caPrivKey, nil
+}
+
+// EnrollOptions select the local CA and the subject of an identity
+type EnrollOptions struct {
+	CAName string
+	// Type is the OU of the identity, e.g. admin or client
+	Type       string
+	CommonName string
+	// Hosts are the DNS names and IPs of the certificate
+	Hosts []string
+	// TLS issues the certificate with the TLS CA
+	TLS bool
+	// CertPolicy overrides the certificate policy of the CA
+	CertPolicy config.CertificatePolicy
+}
+
+// Validate checks that the CA, the type and the common name are set
+func (o EnrollOptions) Validate() error {
+	if o.CAName == "" {
+		return errors.Errorf("--name is required")
+	}
+	if o.Type == "" {
+		return errors.Errorf("--type is required")
+	}
+	if o.CommonName == "" {
+		return errors.Errorf("--common-name is required")
+	}
+	return ValidateCertificatePolicy(o.CertPolicy)
+}
+
+// Enroll issues a certificate with a local CA and returns it with its key,
+// PEM encoded
+func Enroll(o EnrollOptions) ([]byte, []byte, error) {
+	caConfig, err := utils.GetCAConfig(o.CAName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enrollWithCA(caConfig, o, nil)
+}
+
+// enrollWithCA issues a certificate with the parsed config of a local CA,
+// ous are added to the OU of the type of the identity
+func enrollWithCA(caConfig *utils.CAConfig, o EnrollOptions, ous []string) ([]byte, []byte, error) {
+	ips, dnsNames := splitHosts(o.Hosts)
+	caCert, caKey := caConfig.CACert, caConfig.CAKey
+	if o.TLS {
+		caCert, caKey = caConfig.TLSCACert, caConfig.TLSCAKey
+	}
+	certOpts := GenerateCertificateOptions{
+		CommonName:       o.CommonName,
+		OrganizationUnit: append([]string{o.Type}, ous...),
+		IPAddresses:      ips,
+		DNSNames:         dnsNames,
+	}
+	err := ApplyCertificatePolicy(&certOpts, caConfig.CertPolicy.Merge(o.CertPolicy), o.CAName, o.TLS)
+	if err != nil {
+		return nil, nil, err
+	}
+	userCert, userKey, err := GenerateCertificate(certOpts, caCert, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := utils.EncodePrivateKey(userKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return utils.EncodeX509Certificate(userCert), keyPEM, nil
+}
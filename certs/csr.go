@@ -0,0 +1,35 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+)
+
+// GenerateCSR creates a new key pair and a PEM encoded certificate signing request
+// for it, so the certificate can be signed by a CA that hlf-easy doesn't manage
+func GenerateCSR(o GenerateCertificateOptions) ([]byte, *ecdsa.PrivateKey, error) {
+	priv, err := ecdsa.GenerateKey(o.curve(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			OrganizationalUnit: o.OrganizationUnit,
+			CommonName:         o.CommonName,
+		},
+		DNSNames:    o.DNSNames,
+		IPAddresses: o.IPAddresses,
+	}
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, template, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	csrPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE REQUEST",
+		Bytes: csrBytes,
+	})
+	return csrPEM, priv, nil
+}
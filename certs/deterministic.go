@@ -0,0 +1,167 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+)
+
+// DeterministicNotBefore and DeterministicNotAfter are the validity window of
+// every certificate issued in the deterministic mode of a certificate policy,
+// the certificates issued again are the same whenever they are
+var (
+	DeterministicNotBefore = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	DeterministicNotAfter  = time.Date(2099, 12, 31, 23, 59, 59, 0, time.UTC)
+)
+
+var one = big.NewInt(1)
+
+// deterministicSeed returns the seed of a key derived from labels, they're
+// length prefixed so no two lists of labels give the same seed
+func deterministicSeed(labels ...string) []byte {
+	h := sha256.New()
+	h.Write([]byte("hlf-easy deterministic"))
+	for _, label := range labels {
+		binary.Write(h, binary.BigEndian, uint32(len(label)))
+		h.Write([]byte(label))
+	}
+	return h.Sum(nil)
+}
+
+// certificateSeed returns the seed of the key of a certificate issued by a CA
+// in the deterministic mode, the key of the CA keeps it secret
+func certificateSeed(caKey *ecdsa.PrivateKey, o GenerateCertificateOptions) []byte {
+	return deterministicSeed(
+		caKey.D.String(),
+		o.curve().Params().Name,
+		o.CommonName,
+		fmt.Sprint(o.OrganizationUnit),
+		fmt.Sprint(o.DNSNames),
+		fmt.Sprint(o.IPAddresses),
+		fmt.Sprint(o.ExtKeyUsage),
+	)
+}
+
+// deterministicKey derives an ECDSA key on the curve from a seed, the scalar
+// is drawn with 64 more bits than the order so its bias is negligible
+func deterministicKey(curve elliptic.Curve, seed []byte) *ecdsa.PrivateKey {
+	params := curve.Params()
+	size := (params.BitSize+7)/8 + 8
+	var stream []byte
+	for i := byte(0); len(stream) < size; i++ {
+		mac := hmac.New(sha256.New, seed)
+		mac.Write([]byte{i})
+		stream = mac.Sum(stream)
+	}
+	d := new(big.Int).SetBytes(stream[:size])
+	d.Mod(d, new(big.Int).Sub(params.N, one))
+	d.Add(d, one)
+	x, y := curve.ScalarBaseMult(d.FillBytes(make([]byte, (params.BitSize+7)/8)))
+	return &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y}, D: d}
+}
+
+// deterministicSerialNumber returns the serial number of the random policy in
+// the deterministic mode, 128 bits of the hash of the key of the certificate
+func deterministicSerialNumber(key *ecdsa.PrivateKey) *big.Int {
+	h := sha256.Sum256(elliptic.Marshal(key.Curve, key.X, key.Y))
+	// the serial numbers are positive
+	h[0] &= 0x7f
+	return new(big.Int).SetBytes(h[:16])
+}
+
+// deterministicSigner signs the certificates with the nonces of RFC 6979
+// instead of random ones, so the same certificate has the same signature
+type deterministicSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s deterministicSigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+// Sign signs the digest with ECDSA and HMAC-SHA256 as the HMAC of RFC 6979,
+// the s of the signature is the low one
+func (s deterministicSigner) Sign(_ io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	params := s.key.Curve.Params()
+	n := params.N
+	qlen := n.BitLen()
+	rolen := (qlen + 7) / 8
+	bits2int := func(b []byte) *big.Int {
+		v := new(big.Int).SetBytes(b)
+		if len(b)*8 > qlen {
+			v.Rsh(v, uint(len(b)*8-qlen))
+		}
+		return v
+	}
+	e := bits2int(digest)
+	h1 := new(big.Int).Mod(e, n).FillBytes(make([]byte, rolen))
+	x := s.key.D.FillBytes(make([]byte, rolen))
+	hmacK := func(k []byte, data ...[]byte) []byte {
+		mac := hmac.New(sha256.New, k)
+		for _, b := range data {
+			mac.Write(b)
+		}
+		return mac.Sum(nil)
+	}
+	v := make([]byte, sha256.Size)
+	for i := range v {
+		v[i] = 0x01
+	}
+	k := make([]byte, sha256.Size)
+	k = hmacK(k, v, []byte{0x00}, x, h1)
+	v = hmacK(k, v)
+	k = hmacK(k, v, []byte{0x01}, x, h1)
+	v = hmacK(k, v)
+	for {
+		var t []byte
+		for len(t) < rolen {
+			v = hmacK(k, v)
+			t = append(t, v...)
+		}
+		nonce := bits2int(t[:rolen])
+		if nonce.Sign() > 0 && nonce.Cmp(n) < 0 {
+			rx, _ := s.key.Curve.ScalarBaseMult(nonce.FillBytes(make([]byte, rolen)))
+			r := new(big.Int).Mod(rx, n)
+			if r.Sign() != 0 {
+				sig := new(big.Int).Mul(r, s.key.D)
+				sig.Add(sig, e)
+				sig.Mul(sig, new(big.Int).ModInverse(nonce, n))
+				sig.Mod(sig, n)
+				if sig.Sign() != 0 {
+					if sig.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+						sig.Sub(n, sig)
+					}
+					return asn1.Marshal(struct{ R, S *big.Int }{r, sig})
+				}
+			}
+		}
+		k = hmacK(k, v, []byte{0x00})
+		v = hmacK(k, v)
+	}
+}
+
+// issuerSigner returns the signer of the certificates of a CA key, the
+// deterministic one in the deterministic mode
+func issuerSigner(key *ecdsa.PrivateKey, deterministic bool) crypto.Signer {
+	if deterministic {
+		return deterministicSigner{key: key}
+	}
+	return key
+}
+
+// validityWindow returns the validity window of a certificate issued now,
+// the fixed one in the deterministic mode
+func validityWindow(notBefore time.Time, notAfter time.Time, deterministic bool) (time.Time, time.Time) {
+	if deterministic {
+		return DeterministicNotBefore, DeterministicNotAfter
+	}
+	return notBefore, notAfter
+}
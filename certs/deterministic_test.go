@@ -0,0 +1,91 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"hlf-easy/config"
+	"math/big"
+	"testing"
+)
+
+func TestDeterministicInitCA(t *testing.T) {
+	o := InitCAOptions{Name: "ca-1", Organization: "Org1MSP", Hosts: []string{"localhost"}, CertPolicy: config.CertificatePolicy{Deterministic: true}}
+	var configs []*config.CAConfig
+	for i := 0; i < 2; i++ {
+		t.Setenv("HOME", t.TempDir())
+		caConfig, err := InitCA(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, caConfig)
+	}
+	if !bytes.Equal(configs[0].CaCert, configs[1].CaCert) || !bytes.Equal(configs[0].CaKey, configs[1].CaKey) || !bytes.Equal(configs[0].TlsCert, configs[1].TlsCert) {
+		t.Fatal("expected the same CA certificates and keys on every init")
+	}
+	if bytes.Equal(configs[0].CaKey, configs[0].TlsCAKey) {
+		t.Fatal("expected the signing and the TLS CAs to have different keys")
+	}
+	o.Name = "ca-2"
+	other, err := InitCA(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(configs[0].CaKey, other.CaKey) {
+		t.Fatal("expected another CA to have another key")
+	}
+}
+
+func TestDeterministicCertificate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	caCert, caKey, err := InitCAOptions{Name: "test-ca", CertPolicy: config.CertificatePolicy{Deterministic: true}}.createDefaultCA("ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := func(commonName string) ([]byte, *ecdsa.PrivateKey) {
+		o := GenerateCertificateOptions{CommonName: commonName, DNSNames: []string{"localhost"}}
+		err := ApplyCertificatePolicy(&o, config.CertificatePolicy{Deterministic: true, SerialNumberPolicy: "random"}, "test-ca", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crt, key, err := GenerateCertificate(o, caCert, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := crt.CheckSignatureFrom(caCert); err != nil {
+			t.Fatal(err)
+		}
+		if !crt.NotBefore.Equal(DeterministicNotBefore) || !crt.NotAfter.Equal(DeterministicNotAfter) || crt.SerialNumber.Cmp(big.NewInt(1)) == 0 {
+			t.Fatalf("expected the fixed validity window and a derived serial number, got %s to %s, %s", crt.NotBefore, crt.NotAfter, crt.SerialNumber)
+		}
+		return crt.Raw, key
+	}
+	first, firstKey := issue("peer1")
+	second, secondKey := issue("peer1")
+	if !bytes.Equal(first, second) || firstKey.D.Cmp(secondKey.D) != 0 {
+		t.Fatal("expected the same certificate and key when issued again")
+	}
+	if _, key := issue("peer2"); key.D.Cmp(firstKey.D) == 0 {
+		t.Fatal("expected another common name to have another key")
+	}
+}
+
+func TestDeterministicSigner(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384()} {
+		key := deterministicKey(curve, deterministicSeed("signer"))
+		digest := sha256.Sum256([]byte("certificate"))
+		signer := deterministicSigner{key: key}
+		first, err := signer.Sign(nil, digest[:], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, _ := signer.Sign(nil, digest[:], nil)
+		if !bytes.Equal(first, second) {
+			t.Fatalf("expected the same signature on %s", curve.Params().Name)
+		}
+		if !ecdsa.VerifyASN1(&key.PublicKey, digest[:], first) {
+			t.Fatalf("expected a valid signature on %s", curve.Params().Name)
+		}
+	}
+}
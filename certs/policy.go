@@ -0,0 +1,245 @@
+package certs
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/audit"
+	"hlf-easy/config"
+	"hlf-easy/lock"
+	"hlf-easy/log"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var keyUsages = map[string]x509.KeyUsage{
+	"digitalSignature":  x509.KeyUsageDigitalSignature,
+	"contentCommitment": x509.KeyUsageContentCommitment,
+	"keyEncipherment":   x509.KeyUsageKeyEncipherment,
+	"dataEncipherment":  x509.KeyUsageDataEncipherment,
+	"keyAgreement":      x509.KeyUsageKeyAgreement,
+	"certSign":          x509.KeyUsageCertSign,
+	"crlSign":           x509.KeyUsageCRLSign,
+}
+
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"any":             x509.ExtKeyUsageAny,
+	"serverAuth":      x509.ExtKeyUsageServerAuth,
+	"clientAuth":      x509.ExtKeyUsageClientAuth,
+	"codeSigning":     x509.ExtKeyUsageCodeSigning,
+	"emailProtection": x509.ExtKeyUsageEmailProtection,
+	"timeStamping":    x509.ExtKeyUsageTimeStamping,
+	"ocspSigning":     x509.ExtKeyUsageOCSPSigning,
+}
+
+// ValidateCertificatePolicy checks that all the fields of the policy can be parsed
+func ValidateCertificatePolicy(policy config.CertificatePolicy) error {
+	if policy.Validity != "" {
+		if _, err := parsePolicyValidity(policy); err != nil {
+			return err
+		}
+	}
+	o := &GenerateCertificateOptions{}
+	if err := applyPolicyUsages(o, policy); err != nil {
+		return err
+	}
+	if err := applyPolicySANs(o, policy); err != nil {
+		return err
+	}
+	switch policy.SerialNumberPolicy {
+	case "", "fixed", "random", "sequential":
+	default:
+		return errors.Errorf("unknown serial number policy %q", policy.SerialNumberPolicy)
+	}
+	if policy.Deterministic && policy.Validity != "" {
+		return errors.Errorf("the certificates of the deterministic mode are valid from %s to %s, the validity can't be set", DeterministicNotBefore.Format(time.RFC3339), DeterministicNotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func parsePolicyValidity(policy config.CertificatePolicy) (time.Duration, error) {
+	validity, err := time.ParseDuration(policy.Validity)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid certificate validity %q", policy.Validity)
+	}
+	if validity <= 0 {
+		return 0, errors.Errorf("certificate validity must be positive, got %q", policy.Validity)
+	}
+	return validity, nil
+}
+
+// ApplyCertificatePolicy sets the validity, usages and serial number policy in
+// the certificate options. TLS certificates also get the extra SANs of the
+// policy and are numbered apart from the certificates of the signing CA
+func ApplyCertificatePolicy(o *GenerateCertificateOptions, policy config.CertificatePolicy, caName string, tls bool) error {
+	if policy.Validity != "" {
+		validity, err := parsePolicyValidity(policy)
+		if err != nil {
+			return err
+		}
+		o.Validity = validity
+	}
+	if err := applyPolicyUsages(o, policy); err != nil {
+		return err
+	}
+	o.deterministic = policy.Deterministic
+	issuer := "ca"
+	if tls {
+		if err := applyPolicySANs(o, policy); err != nil {
+			return err
+		}
+		if len(o.ExtKeyUsage) > 0 && !hasExtKeyUsage(o.ExtKeyUsage, x509.ExtKeyUsageServerAuth) {
+			log.Warnf("Extended key usages %v of the TLS certificate %s don't include serverAuth, it can't be used by a TLS server", policy.ExtKeyUsages, o.CommonName)
+		}
+		issuer = "tlsca"
+	}
+	switch policy.SerialNumberPolicy {
+	case "", "fixed":
+	case "random":
+		o.randomSerial = true
+	case "sequential":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		o.serialCA = caName
+		o.serialFilePath = filepath.Join(home, fmt.Sprintf("hlf-easy/cas/%s/serial-%s", caName, issuer))
+	default:
+		return errors.Errorf("unknown serial number policy %q", policy.SerialNumberPolicy)
+	}
+	return nil
+}
+
+func hasExtKeyUsage(usages []x509.ExtKeyUsage, usage x509.ExtKeyUsage) bool {
+	for _, u := range usages {
+		if u == usage || u == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	return false
+}
+
+func applyPolicyUsages(o *GenerateCertificateOptions, policy config.CertificatePolicy) error {
+	for _, name := range policy.KeyUsages {
+		usage, ok := keyUsages[name]
+		if !ok {
+			return errors.Errorf("unknown key usage %q", name)
+		}
+		o.KeyUsage |= usage
+	}
+	for _, name := range policy.ExtKeyUsages {
+		usage, ok := extKeyUsages[name]
+		if !ok {
+			return errors.Errorf("unknown extended key usage %q", name)
+		}
+		o.ExtKeyUsage = append(o.ExtKeyUsage, usage)
+	}
+	return nil
+}
+
+// ApplyCertificatePolicySANs adds the extra SANs of the policy to the options,
+// for CSRs the rest of the policy is decided by the external CA
+func ApplyCertificatePolicySANs(o *GenerateCertificateOptions, policy config.CertificatePolicy) error {
+	return applyPolicySANs(o, policy)
+}
+
+func applyPolicySANs(o *GenerateCertificateOptions, policy config.CertificatePolicy) error {
+	for _, san := range policy.SANs {
+		sanType, value, found := strings.Cut(san, ":")
+		if !found {
+			return errors.Errorf("SAN %q must be prefixed by its type", san)
+		}
+		switch sanType {
+		case "dns":
+			o.DNSNames = append(o.DNSNames, value)
+		case "ip":
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return errors.Errorf("invalid IP address in SAN %q", san)
+			}
+			o.IPAddresses = append(o.IPAddresses, ip)
+		case "email":
+			o.EmailAddresses = append(o.EmailAddresses, value)
+		case "uri":
+			uri, err := url.Parse(value)
+			if err != nil {
+				return errors.Wrapf(err, "invalid URI in SAN %q", san)
+			}
+			o.URIs = append(o.URIs, uri)
+		default:
+			return errors.Errorf("unknown SAN type %q", sanType)
+		}
+	}
+	return nil
+}
+
+func randomSerialNumber() (*big.Int, error) {
+	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	return rand.Int(rand.Reader, serialNumberLimit)
+}
+
+// serialLockTimeout is how long the issuance of a certificate waits for the
+// other processes issuing certificates with the same CA
+const serialLockTimeout = 10 * time.Second
+
+// nextSerialNumber returns the serial number following the last one in the
+// serial file
+func nextSerialNumber(serialFilePath string) (*big.Int, error) {
+	serialBytes, err := os.ReadFile(serialFilePath)
+	if os.IsNotExist(err) {
+		return big.NewInt(1), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	last, ok := new(big.Int).SetString(strings.TrimSpace(string(serialBytes)), 10)
+	if !ok {
+		return nil, errors.Errorf("invalid serial file %s", serialFilePath)
+	}
+	return last.Add(last, big.NewInt(1)), nil
+}
+
+// PlanSerialNumber sets the serial number the sequential policy would give to
+// the certificate without reserving it. It returns the serial file updated
+// when the certificate is issued, empty for the other policies
+func PlanSerialNumber(o *GenerateCertificateOptions) (string, error) {
+	if o.SerialNumber != nil || o.serialFilePath == "" {
+		return "", nil
+	}
+	serialNumber, err := nextSerialNumber(o.serialFilePath)
+	if err != nil {
+		return "", err
+	}
+	o.SerialNumber = serialNumber
+	return o.serialFilePath, nil
+}
+
+// withSequentialSerialNumber calls issue with the serial number following the
+// last one in the serial file, the serial file is only updated if issue
+// succeeds. The CA is locked meanwhile so its serial numbers are never reused
+func withSequentialSerialNumber(caName string, serialFilePath string, issue func(serialNumber *big.Int) error) error {
+	l, err := lock.Acquire("ca", caName, "ca.serial."+filepath.Base(serialFilePath), audit.LocalActor(), serialLockTimeout, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := l.Release(); err != nil {
+			log.Warnf("Failed to release the lock of ca %s: %v", caName, err)
+		}
+	}()
+	serialNumber, err := nextSerialNumber(serialFilePath)
+	if err != nil {
+		return err
+	}
+	err = issue(serialNumber)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(serialFilePath, []byte(serialNumber.String()), 0644)
+}
@@ -0,0 +1,196 @@
+package certs
+
+import (
+	"crypto/x509"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValidateCertificatePolicy(t *testing.T) {
+	valid := []config.CertificatePolicy{
+		{},
+		{Validity: "720h", KeyUsages: []string{"digitalSignature"}, ExtKeyUsages: []string{"serverAuth"}},
+		{SANs: []string{"dns:peer.example.com", "ip:10.0.0.1", "email:ops@example.com", "uri:spiffe://example.com/peer"}},
+		{SerialNumberPolicy: "sequential"},
+		{SerialNumberPolicy: "random", Deterministic: true},
+	}
+	for _, policy := range valid {
+		if err := ValidateCertificatePolicy(policy); err != nil {
+			t.Errorf("expected %+v to be valid: %v", policy, err)
+		}
+	}
+	invalid := []config.CertificatePolicy{
+		{Validity: "1 year"},
+		{Validity: "0s"},
+		{Validity: "-24h"},
+		{KeyUsages: []string{"sign"}},
+		{ExtKeyUsages: []string{"server"}},
+		{SANs: []string{"peer.example.com"}},
+		{SANs: []string{"ip:not-an-ip"}},
+		{SANs: []string{"mail:ops@example.com"}},
+		{SerialNumberPolicy: "incremental"},
+		{Validity: "720h", Deterministic: true},
+	}
+	for _, policy := range invalid {
+		if err := ValidateCertificatePolicy(policy); err == nil {
+			t.Errorf("expected %+v to be rejected", policy)
+		}
+	}
+}
+
+func TestApplyCertificatePolicy(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	caCert, caKey := testca.NewCA(t, "ca")
+	policy := config.CertificatePolicy{
+		Validity:     "48h",
+		KeyUsages:    []string{"digitalSignature"},
+		ExtKeyUsages: []string{"serverAuth", "clientAuth"},
+		SANs:         []string{"dns:peer.example.com", "ip:10.0.0.1", "email:ops@example.com", "uri:spiffe://example.com/peer"},
+	}
+
+	t.Run("tls certificate", func(t *testing.T) {
+		o := GenerateCertificateOptions{CommonName: "peer", DNSNames: []string{"localhost"}}
+		err := ApplyCertificatePolicy(&o, policy, "ca", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crt, _, err := GenerateCertificate(o, caCert, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := crt.NotAfter.Sub(crt.NotBefore); d != 48*time.Hour {
+			t.Errorf("expected a validity of 48h, got %s", d)
+		}
+		if crt.KeyUsage != x509.KeyUsageDigitalSignature {
+			t.Errorf("unexpected key usage %v", crt.KeyUsage)
+		}
+		if len(crt.ExtKeyUsage) != 2 || crt.ExtKeyUsage[0] != x509.ExtKeyUsageServerAuth || crt.ExtKeyUsage[1] != x509.ExtKeyUsageClientAuth {
+			t.Errorf("unexpected extended key usages %v", crt.ExtKeyUsage)
+		}
+		if len(crt.DNSNames) != 2 || crt.DNSNames[0] != "localhost" || crt.DNSNames[1] != "peer.example.com" {
+			t.Errorf("unexpected DNS names %v", crt.DNSNames)
+		}
+		if len(crt.IPAddresses) != 1 || crt.IPAddresses[0].String() != "10.0.0.1" {
+			t.Errorf("unexpected IP addresses %v", crt.IPAddresses)
+		}
+		if len(crt.EmailAddresses) != 1 || crt.EmailAddresses[0] != "ops@example.com" {
+			t.Errorf("unexpected email addresses %v", crt.EmailAddresses)
+		}
+		if len(crt.URIs) != 1 || crt.URIs[0].String() != "spiffe://example.com/peer" {
+			t.Errorf("unexpected URIs %v", crt.URIs)
+		}
+	})
+
+	t.Run("signing certificate has no extra SANs", func(t *testing.T) {
+		o := GenerateCertificateOptions{CommonName: "peer"}
+		err := ApplyCertificatePolicy(&o, policy, "ca", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(o.DNSNames) != 0 || len(o.IPAddresses) != 0 || len(o.EmailAddresses) != 0 || len(o.URIs) != 0 {
+			t.Errorf("expected no SANs, got %+v", o)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		o := GenerateCertificateOptions{CommonName: "peer"}
+		err := ApplyCertificatePolicy(&o, config.CertificatePolicy{}, "ca", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crt, _, err := GenerateCertificate(o, caCert, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the usages and serial number the certificates have always had
+		expectedUsage := x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+		if crt.KeyUsage != expectedUsage {
+			t.Errorf("expected the default key usages %v, got %v", expectedUsage, crt.KeyUsage)
+		}
+		if crt.SerialNumber.Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("expected the fixed serial number 1, got %s", crt.SerialNumber)
+		}
+		if d := crt.NotAfter.Sub(crt.NotBefore); d != 365*24*time.Hour {
+			t.Errorf("expected a validity of one year, got %s", d)
+		}
+	})
+
+	t.Run("leaf key usages and random serial numbers", func(t *testing.T) {
+		o := GenerateCertificateOptions{CommonName: "peer"}
+		leafPolicy := config.CertificatePolicy{
+			KeyUsages:          []string{"digitalSignature", "keyEncipherment"},
+			SerialNumberPolicy: "random",
+		}
+		err := ApplyCertificatePolicy(&o, leafPolicy, "ca", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crt, _, err := GenerateCertificate(o, caCert, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if crt.KeyUsage&(x509.KeyUsageCertSign|x509.KeyUsageCRLSign) != 0 {
+			t.Errorf("expected a leaf certificate that can't sign certificates, got %v", crt.KeyUsage)
+		}
+		if crt.SerialNumber.Cmp(big.NewInt(1)) == 0 {
+			t.Errorf("expected a random serial number, got %s", crt.SerialNumber)
+		}
+	})
+}
+
+func TestSequentialSerialNumbers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	err := os.MkdirAll(filepath.Join(home, "hlf-easy/cas/ca"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, caKey := testca.NewCA(t, "ca")
+	policy := config.CertificatePolicy{SerialNumberPolicy: "sequential"}
+	issue := func(tls bool) *big.Int {
+		t.Helper()
+		o := GenerateCertificateOptions{CommonName: "peer"}
+		err := ApplyCertificatePolicy(&o, policy, "ca", tls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crt, _, err := GenerateCertificate(o, caCert, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crt.SerialNumber
+	}
+
+	for i := int64(1); i <= 3; i++ {
+		if serial := issue(false); serial.Cmp(big.NewInt(i)) != 0 {
+			t.Fatalf("expected serial number %d, got %s", i, serial)
+		}
+	}
+	// the TLS CA has its own counter
+	if serial := issue(true); serial.Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("expected serial number 1 for the TLS CA, got %s", serial)
+	}
+
+	// a failed issuance doesn't consume a serial number
+	o := GenerateCertificateOptions{CommonName: "peer"}
+	err = ApplyCertificatePolicy(&o, policy, "ca", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey := testca.NewCA(t, "ca")
+	_, _, err = GenerateCertificate(o, caCert, otherKey)
+	if err == nil {
+		t.Fatal("expected issuing with a key that doesn't match the CA to fail")
+	}
+	if serial := issue(false); serial.Cmp(big.NewInt(4)) != 0 {
+		t.Fatalf("expected serial number 4, got %s", serial)
+	}
+	if _, err := os.Stat(filepath.Join(home, "hlf-easy/locks/ca/ca.json")); !os.IsNotExist(err) {
+		t.Fatal("expected the lock of the CA to be released")
+	}
+}
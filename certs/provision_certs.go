@@ -1,6 +1,7 @@
 package certs
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/x509"
 	"hlf-easy/utils"
@@ -93,6 +94,9 @@ type EnrollUserRequest struct {
 	CN         string
 	Profile    string
 	Attributes []*api.AttributeRequest
+	// KeySize is the size of the ECDSA key, 256 or 384, the default of the
+	// Fabric CA client if 0
+	KeySize int
 }
 type ReenrollUserRequest struct {
 	EnrollID   string
@@ -123,6 +127,9 @@ type RegisterUserRequest struct {
 	Secret       string
 	Type         string
 	Attributes   []api.Attribute
+	// MaxEnrollments bounds how many times the user can be enrolled with the
+	// secret, unlimited when 0
+	MaxEnrollments int
 }
 
 func RegisterUser(params RegisterUserRequest) (string, error) {
@@ -147,10 +154,14 @@ func RegisterUser(params RegisterUserRequest) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	maxEnrollments := params.MaxEnrollments
+	if maxEnrollments == 0 {
+		maxEnrollments = -1
+	}
 	secret, err := enrollResponse.Identity.Register(&api.RegistrationRequest{
 		Name:           params.User,
 		Type:           params.Type,
-		MaxEnrollments: -1,
+		MaxEnrollments: maxEnrollments,
 		Affiliation:    "",
 		Attributes:     params.Attributes,
 		CAName:         params.Name,
@@ -213,6 +224,13 @@ func EnrollUser(params EnrollUserRequest) (*x509.Certificate, *ecdsa.PrivateKey,
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	csrInfo := &api.CSRInfo{
+		Hosts: params.Hosts,
+		CN:    params.CN,
+	}
+	if params.KeySize != 0 {
+		csrInfo.KeyRequest = &api.KeyRequest{Algo: "ecdsa", Size: params.KeySize}
+	}
 	enrollResponse, err := caClient.Enroll(&api.EnrollmentRequest{
 		Name:     params.User,
 		Secret:   params.Secret,
@@ -221,10 +239,7 @@ func EnrollUser(params EnrollUserRequest) (*x509.Certificate, *ecdsa.PrivateKey,
 		Profile:  params.Profile,
 		Label:    "",
 		Type:     "x509",
-		CSR: &api.CSRInfo{
-			Hosts: params.Hosts,
-			CN:    params.CN,
-		},
+		CSR:      csrInfo,
 	})
 	if err != nil {
 		return nil, nil, nil, err
@@ -248,6 +263,28 @@ func EnrollUser(params EnrollUserRequest) (*x509.Certificate, *ecdsa.PrivateKey,
 	return userCrt, userKey, rootCrt, nil
 }
 
+// EnrollUserContext is EnrollUser returning when the context is done, the
+// client of the Fabric CA takes no context so the enrollment is abandoned
+func EnrollUserContext(ctx context.Context, params EnrollUserRequest) (*x509.Certificate, *ecdsa.PrivateKey, *x509.Certificate, error) {
+	type result struct {
+		cert   *x509.Certificate
+		key    *ecdsa.PrivateKey
+		caCert *x509.Certificate
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		cert, key, caCert, err := EnrollUser(params)
+		done <- result{cert: cert, key: key, caCert: caCert, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.cert, r.key, r.caCert, r.err
+	case <-ctx.Done():
+		return nil, nil, nil, errors.Wrapf(ctx.Err(), "enrollment of %s with %s didn't complete", params.User, params.URL)
+	}
+}
+
 type GetUserRequest struct {
 	TLSCert      string
 	URL          string
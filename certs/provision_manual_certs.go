@@ -11,6 +11,7 @@ import (
 	"hlf-easy/utils"
 	"math/big"
 	"net"
+	"net/url"
 	"time"
 )
 
@@ -19,6 +20,27 @@ type GenerateCertificateOptions struct {
 	OrganizationUnit []string
 	IPAddresses      []net.IP
 	DNSNames         []string
+	EmailAddresses   []string
+	URIs             []*url.URL
+	// Validity of the certificate, one year if not set
+	Validity time.Duration
+	// KeyUsage and ExtKeyUsage replace the default usages if set. The default
+	// key usages include certSign and crlSign, as the certificates have
+	// always been issued, a policy with the key usages of a leaf certificate
+	// (digitalSignature, keyEncipherment) drops them
+	KeyUsage    x509.KeyUsage
+	ExtKeyUsage []x509.ExtKeyUsage
+	// Curve of the key of the certificate, P-256 if not set
+	Curve elliptic.Curve
+	// SerialNumber of the certificate, if not set it's the serial number set
+	// by the policy of ApplyCertificatePolicy: fixed (1, as the certificates
+	// have always been issued), random or the next one of the serial file
+	SerialNumber   *big.Int
+	randomSerial   bool
+	serialCA       string
+	serialFilePath string
+	// deterministic is set by the deterministic mode of the policy
+	deterministic bool
 }
 
 func GenerateCertificate(
@@ -26,38 +48,105 @@ func GenerateCertificate(
 	parsedCaCert *x509.Certificate,
 	parsedCaKey *ecdsa.PrivateKey,
 ) (*x509.Certificate, *ecdsa.PrivateKey, error) {
-	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var priv *ecdsa.PrivateKey
+	var err error
+	if o.deterministic {
+		priv = deterministicKey(o.curve(), certificateSeed(parsedCaKey, o))
+	} else {
+		priv, err = ecdsa.GenerateKey(o.curve(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	validity := o.Validity
+	if validity == 0 {
+		validity = time.Hour * 24 * 365
+	}
+	keyUsage := o.KeyUsage
+	if keyUsage == 0 {
+		keyUsage = x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+	extKeyUsage := o.ExtKeyUsage
+	if len(extKeyUsage) == 0 {
+		extKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+	}
+	if o.SerialNumber == nil && o.serialFilePath != "" {
+		var newCert *x509.Certificate
+		err = withSequentialSerialNumber(o.serialCA, o.serialFilePath, func(serialNumber *big.Int) error {
+			newCert, err = createCertificate(o, serialNumber, validity, keyUsage, extKeyUsage, priv, parsedCaCert, parsedCaKey)
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return newCert, priv, nil
+	}
+	serialNumber := o.SerialNumber
+	if serialNumber == nil && o.randomSerial && o.deterministic {
+		serialNumber = deterministicSerialNumber(priv)
+	}
+	if serialNumber == nil && o.randomSerial {
+		serialNumber, err = randomSerialNumber()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if serialNumber == nil {
+		serialNumber = big.NewInt(1)
+	}
+	newCert, err := createCertificate(o, serialNumber, validity, keyUsage, extKeyUsage, priv, parsedCaCert, parsedCaKey)
 	if err != nil {
 		return nil, nil, err
 	}
+	return newCert, priv, nil
+}
+
+// curve returns the curve of the key of the certificate
+func (o GenerateCertificateOptions) curve() elliptic.Curve {
+	if o.Curve == nil {
+		return elliptic.P256()
+	}
+	return o.Curve
+}
+
+func createCertificate(
+	o GenerateCertificateOptions,
+	serialNumber *big.Int,
+	validity time.Duration,
+	keyUsage x509.KeyUsage,
+	extKeyUsage []x509.ExtKeyUsage,
+	priv *ecdsa.PrivateKey,
+	parsedCaCert *x509.Certificate,
+	parsedCaKey *ecdsa.PrivateKey,
+) (*x509.Certificate, error) {
+	now := time.Now()
+	notBefore, notAfter := validityWindow(now, now.Add(validity), o.deterministic)
 	template := &x509.Certificate{
-		SerialNumber: big.NewInt(1),
+		SerialNumber: serialNumber,
 		SubjectKeyId: computeSKI(priv),
-		NotBefore:    time.Now(),
-		NotAfter:     time.Now().Add(time.Hour * 24 * 365),
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
 		Subject: pkix.Name{
 			OrganizationalUnit: o.OrganizationUnit,
 			CommonName:         o.CommonName,
 		},
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		KeyUsage:              keyUsage,
 		BasicConstraintsValid: true,
 		DNSNames:              o.DNSNames,
 		IPAddresses:           o.IPAddresses,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		EmailAddresses:        o.EmailAddresses,
+		URIs:                  o.URIs,
+		ExtKeyUsage:           extKeyUsage,
 	}
-	certBytes, err := x509.CreateCertificate(rand.Reader, template, parsedCaCert, priv.Public(), parsedCaKey)
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, parsedCaCert, priv.Public(), issuerSigner(parsedCaKey, o.deterministic))
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	certPEM := pem.EncodeToMemory(&pem.Block{
 		Type:  "CERTIFICATE",
 		Bytes: certBytes,
 	})
-	newCert, err := utils.ParseX509Certificate(certPEM)
-	if err != nil {
-		return nil, nil, err
-	}
-	return newCert, priv, nil
+	return utils.ParseX509Certificate(certPEM)
 }
 
 func computeSKI(privKey *ecdsa.PrivateKey) []byte {
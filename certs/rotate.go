@@ -0,0 +1,162 @@
+package certs
+
+import (
+	"crypto/x509"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/utils"
+)
+
+const (
+	// CAKindSign is the CA that issues the identities of the MSP
+	CAKindSign = "sign"
+	// CAKindTLS is the CA that issues the TLS certificates
+	CAKindTLS = "tls"
+)
+
+// ValidateCAKind checks the kind of a CA of a local CA is sign or tls
+func ValidateCAKind(kind string) error {
+	if kind != CAKindSign && kind != CAKindTLS {
+		return errors.Errorf("invalid CA %q, it must be %s or %s", kind, CAKindSign, CAKindTLS)
+	}
+	return nil
+}
+
+// caRoots returns the current root of the CA of a kind and the roots of its
+// rotation in progress
+func caRoots(caConfig *config.CAConfig, kind string) (*[]byte, *[]byte, *[][]byte) {
+	if kind == CAKindTLS {
+		return &caConfig.TlsCACert, &caConfig.TlsCAKey, &caConfig.PreviousTlsCACerts
+	}
+	return &caConfig.CaCert, &caConfig.CaKey, &caConfig.PreviousCaCerts
+}
+
+// TrustedRoots returns the roots of the CA of a kind trusted by its nodes, the
+// current root first and then the ones of the rotation in progress
+func TrustedRoots(caConfig *utils.CAConfig, kind string) []*x509.Certificate {
+	if kind == CAKindTLS {
+		return append([]*x509.Certificate{caConfig.TLSCACert}, caConfig.PreviousTLSCACerts...)
+	}
+	return append([]*x509.Certificate{caConfig.CACert}, caConfig.PreviousCACerts...)
+}
+
+// newRoot creates a root with the subject of the current one
+func newRoot(current *x509.Certificate) (*x509.Certificate, []byte, error) {
+	first := func(values []string) string {
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+	o := InitCAOptions{
+		Organization:       first(current.Subject.Organization),
+		Country:            first(current.Subject.Country),
+		Locality:           first(current.Subject.Locality),
+		OrganizationalUnit: first(current.Subject.OrganizationalUnit),
+		StreetAddress:      first(current.Subject.StreetAddress),
+	}
+	crt, key, err := o.createDefaultCA(current.Subject.CommonName)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyBytes, err := utils.EncodePrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return crt, keyBytes, nil
+}
+
+// initOneCA creates the signing or the TLS CA of an existing local CA again
+// with the subject of the options, the previous root isn't trusted anymore
+func initOneCA(o InitCAOptions) (*config.CAConfig, error) {
+	caConfig, err := readCAConfig(o.Name)
+	if err != nil {
+		return nil, err
+	}
+	certBytes, keyBytes, previous := caRoots(caConfig, o.Only)
+	if len(*previous) > 0 {
+		return nil, errors.Errorf("the %s CA of %s is being rotated, complete the rotation with hlf-easy ca rotate --complete first", o.Only, o.Name)
+	}
+	commonName := "ca"
+	if o.Only == CAKindTLS {
+		commonName = "tlsca"
+	}
+	crt, key, err := o.createDefaultCA(commonName)
+	if err != nil {
+		return nil, err
+	}
+	*keyBytes, err = utils.EncodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	*certBytes = utils.EncodeX509Certificate(crt)
+	err = writeCAConfig(o.Name, caConfig)
+	if err != nil {
+		return nil, err
+	}
+	return caConfig, nil
+}
+
+// RotateCA replaces the root of the signing or the TLS CA of a local CA with a
+// new one with the same subject. The replaced root is kept in the previous
+// roots, trusted with the new one until the rotation is completed, so the
+// certificates issued by both are valid while the nodes are issued again
+func RotateCA(name string, kind string) (*config.CAConfig, error) {
+	if err := ValidateCAKind(kind); err != nil {
+		return nil, err
+	}
+	caConfig, err := readCAConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	certBytes, keyBytes, previous := caRoots(caConfig, kind)
+	if len(*previous) > 0 {
+		return nil, errors.Errorf("the %s CA of %s is already being rotated, complete the rotation with hlf-easy ca rotate --complete first", kind, name)
+	}
+	current, err := utils.ParseX509Certificate(*certBytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the %s CA of %s", kind, name)
+	}
+	crt, key, err := newRoot(current)
+	if err != nil {
+		return nil, err
+	}
+	*previous = [][]byte{*certBytes}
+	*certBytes = utils.EncodeX509Certificate(crt)
+	*keyBytes = key
+	err = writeCAConfig(name, caConfig)
+	if err != nil {
+		return nil, err
+	}
+	return caConfig, nil
+}
+
+// CompleteCARotation stops trusting the roots replaced by the rotation of the
+// signing or the TLS CA of a local CA, it returns the roots dropped
+func CompleteCARotation(name string, kind string) ([]*x509.Certificate, error) {
+	if err := ValidateCAKind(kind); err != nil {
+		return nil, err
+	}
+	caConfig, err := readCAConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	_, _, previous := caRoots(caConfig, kind)
+	if len(*previous) == 0 {
+		return nil, errors.Errorf("the %s CA of %s is not being rotated", kind, name)
+	}
+	var dropped []*x509.Certificate
+	for _, pemBytes := range *previous {
+		crt, err := utils.ParseX509Certificate(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		dropped = append(dropped, crt)
+	}
+	*previous = nil
+	err = writeCAConfig(name, caConfig)
+	if err != nil {
+		return nil, err
+	}
+	return dropped, nil
+}
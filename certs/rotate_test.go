@@ -0,0 +1,83 @@
+package certs
+
+import (
+	"hlf-easy/utils"
+	"testing"
+)
+
+func TestRotateCA(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, err := InitCA(InitCAOptions{Name: "ca0", Organization: "Org1", Hosts: []string{"localhost"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := utils.GetCAConfig("ca0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = RotateCA("ca0", CAKindTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RotateCA("ca0", CAKindTLS); err == nil {
+		t.Fatal("expected an error when the TLS CA is already being rotated")
+	}
+	rotated, err := utils.GetCAConfig("ca0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the signing CA has its own lifecycle
+	if !rotated.CACert.Equal(before.CACert) || len(rotated.PreviousCACerts) != 0 {
+		t.Fatal("expected the signing CA to be kept")
+	}
+	if rotated.TLSCACert.Equal(before.TLSCACert) {
+		t.Fatal("expected a new TLS root")
+	}
+	if rotated.TLSCACert.Subject.String() != before.TLSCACert.Subject.String() {
+		t.Fatalf("expected the subject %s, got %s", before.TLSCACert.Subject, rotated.TLSCACert.Subject)
+	}
+	roots := TrustedRoots(rotated, CAKindTLS)
+	if len(roots) != 2 || !roots[0].Equal(rotated.TLSCACert) || !roots[1].Equal(before.TLSCACert) {
+		t.Fatal("expected the new and the previous TLS roots to be trusted, the new one first")
+	}
+	if !rotated.TLSCAKey.PublicKey.Equal(rotated.TLSCACert.PublicKey) {
+		t.Fatal("expected the key of the new TLS root")
+	}
+	if _, err := InitCA(InitCAOptions{Name: "ca0", Only: CAKindTLS}); err == nil {
+		t.Fatal("expected an error when the TLS CA is created again during its rotation")
+	}
+
+	dropped, err := CompleteCARotation("ca0", CAKindTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dropped) != 1 || !dropped[0].Equal(before.TLSCACert) {
+		t.Fatal("expected the previous TLS root to be dropped")
+	}
+	if _, err := CompleteCARotation("ca0", CAKindTLS); err == nil {
+		t.Fatal("expected an error when no rotation is in progress")
+	}
+	completed, err := utils.GetCAConfig("ca0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(TrustedRoots(completed, CAKindTLS)) != 1 {
+		t.Fatal("expected only the new TLS root to be trusted")
+	}
+
+	_, err = InitCA(InitCAOptions{Name: "ca0", Organization: "Org1", Only: CAKindSign})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recreated, err := utils.GetCAConfig("ca0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recreated.CACert.Equal(before.CACert) || len(recreated.PreviousCACerts) != 0 {
+		t.Fatal("expected a new signing CA without previous roots")
+	}
+	if !recreated.TLSCACert.Equal(completed.TLSCACert) {
+		t.Fatal("expected the TLS CA to be kept")
+	}
+}
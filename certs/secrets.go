@@ -0,0 +1,44 @@
+package certs
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"hlf-easy/secrets"
+)
+
+// MoveCAKeys moves the keys of a local CA to the secrets manager of a URL,
+// or back to its config.json when to is empty. They're removed from the
+// secrets manager they leave
+func MoveCAKeys(ctx context.Context, name string, to string) error {
+	caConfig, err := readCAConfig(name)
+	if err != nil {
+		return err
+	}
+	from := caConfig.Secrets
+	if from == to {
+		return errors.Errorf("the keys of CA %s are already there", name)
+	}
+	if to != "" {
+		if _, err := secrets.Open(to); err != nil {
+			return err
+		}
+	}
+	caConfig.Secrets = to
+	if err := writeCAConfig(name, caConfig); err != nil {
+		return err
+	}
+	if from == "" {
+		return nil
+	}
+	store, err := secrets.Open(from)
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{secrets.CAKey, secrets.TLSCAKey} {
+		err := store.Delete(ctx, secrets.KeyName("ca", name, key))
+		if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+			return errors.Wrapf(err, "failed to remove the keys of CA %s from %s", name, from)
+		}
+	}
+	return nil
+}
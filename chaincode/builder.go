@@ -0,0 +1,107 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"hlf-easy/plan"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// BuilderName is the name of the external builder of hlf-easy in the
+// core.yaml of the peers, it's tried before the ccaas_builder
+const BuilderName = "hlf-easy_ccaas"
+
+// GetBuilderDir is the directory of the external builder of a peer
+func GetBuilderDir(peerDir string) string {
+	return filepath.Join(peerDir, "builders", BuilderName)
+}
+
+// WriteBuilder writes the scripts of the external builder of a peer, they
+// run the chaincode builder commands of the hlf-easy executable
+func WriteBuilder(w plan.Writer, peerDir string, executable string) error {
+	binDir := filepath.Join(GetBuilderDir(peerDir), "bin")
+	err := w.MkdirAll(binDir, 0755)
+	if err != nil {
+		return err
+	}
+	for _, phase := range []string{"detect", "build", "release"} {
+		name, script := builderScript(runtime.GOOS, executable, phase, peerDir)
+		err = w.WriteFile(filepath.Join(binDir, name), []byte(script), 0755)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// builderScript returns the file name and the content of the script of a
+// phase of the builder. On Windows the peer runs bin\detect.cmd for
+// bin\detect since it tries the extensions of the executables
+func builderScript(goos string, executable string, phase string, peerDir string) (string, string) {
+	if goos == "windows" {
+		return phase + ".cmd", fmt.Sprintf("@\"%s\" chaincode builder %s --peer-dir \"%s\" %%*\r\n", executable, phase, peerDir)
+	}
+	return phase, fmt.Sprintf("#!/bin/sh\nexec %q chaincode builder %s --peer-dir %q \"$@\"\n", executable, phase, peerDir)
+}
+
+func readMetadata(dir string) (*metadata, error) {
+	metadataBytes, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		return nil, err
+	}
+	m := &metadata{}
+	err = json.Unmarshal(metadataBytes, m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Detect tells whether the builder of hlf-easy handles a package, that is a
+// chaincode-as-a-service package with a connection.json generated for the
+// peer from the service registry
+func Detect(peerDir string, metadataDir string) (bool, error) {
+	m, err := readMetadata(metadataDir)
+	if err != nil {
+		return false, err
+	}
+	if m.Type != "ccaas" {
+		return false, nil
+	}
+	_, err = os.Stat(GetPeerConnectionFile(peerDir, m.Label))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Build keeps the metadata of the package for the release, the connection is
+// only read when the chaincode is released
+func Build(metadataDir string, outputDir string) error {
+	metadataBytes, err := os.ReadFile(filepath.Join(metadataDir, "metadata.json"))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, "metadata.json"), metadataBytes, 0644)
+}
+
+// Release writes the connection.json of the peer where the peer reads the
+// address of the chaincode server
+func Release(peerDir string, buildDir string, releaseDir string) error {
+	m, err := readMetadata(buildDir)
+	if err != nil {
+		return err
+	}
+	connectionBytes, err := os.ReadFile(GetPeerConnectionFile(peerDir, m.Label))
+	if err != nil {
+		return err
+	}
+	serverDir := filepath.Join(releaseDir, "chaincode", "server")
+	err = os.MkdirAll(serverDir, 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(serverDir, "connection.json"), connectionBytes, 0600)
+}
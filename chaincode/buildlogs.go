@@ -0,0 +1,255 @@
+package chaincode
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses of the builds of the chaincode packages
+const (
+	BuildStatusBuilding  = "building"
+	BuildStatusSucceeded = "succeeded"
+	BuildStatusFailed    = "failed"
+)
+
+// maxBuildLogs is the number of builds whose logs are kept in a peer
+const maxBuildLogs = 50
+
+// buildLogIdle is the time without build output after which the next output
+// belongs to another build
+const buildLogIdle = time.Minute
+
+// maxBuildLineLength bounds the partial line buffered by a stream
+const maxBuildLineLength = 64 * 1024
+
+var (
+	// the start of a line of the Fabric log format, the other lines continue
+	// the entry of the previous one, e.g. the output of a docker build
+	logHeaderRegexp = regexp.MustCompile(`^(\x1b\[[0-9;]*m)?\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`)
+	// the loggers of the external builders, whose output is logged line by
+	// line, and of the docker builds
+	builderLoggerRegexp = regexp.MustCompile(`\[(chaincode\.externalbuilder[^\]]*|dockercontroller|chaincode\.platform[^\]]*)\]`)
+	buildFailedRegexp   = regexp.MustCompile(`(?i)could not build chaincode|external builder failed|error building image`)
+	buildEndRegexp      = regexp.MustCompile(`(?i)could not build chaincode|successfully installed chaincode`)
+	installedRegexp     = regexp.MustCompile(`(?i)successfully installed chaincode`)
+	packageIDRegexp     = regexp.MustCompile(`[A-Za-z0-9_.+-]+:[0-9a-f]{64}`)
+)
+
+// BuildLog is the build of a chaincode package by a peer, at install or when
+// the peer builds it again at launch
+type BuildLog struct {
+	ID string `json:"id"`
+	// PackageID is empty when the peer didn't log it
+	PackageID string    `json:"packageID,omitempty"`
+	Status    string    `json:"status"`
+	Started   time.Time `json:"started"`
+	Updated   time.Time `json:"updated"`
+}
+
+// GetBuildLogsDir is the directory of the build logs of a peer
+func GetBuildLogsDir(peerDir string) string {
+	return filepath.Join(peerDir, "chaincode-builds")
+}
+
+// BuildLogCollector stores the output of the external builders and of the
+// docker builds logged by a peer, a build per install, so a failed install
+// can be diagnosed without searching the logs of the peer
+type BuildLogCollector struct {
+	mu      sync.Mutex
+	dir     string
+	current *BuildLog
+	// capturing is set while the lines continue a captured entry
+	capturing bool
+	// finished is set when the peer logged the end of the current build
+	finished bool
+	now      func() time.Time
+}
+
+// NewBuildLogCollector returns a collector of the builds of a peer
+func NewBuildLogCollector(peerDir string) *BuildLogCollector {
+	return &BuildLogCollector{
+		dir: GetBuildLogsDir(peerDir),
+		now: time.Now,
+	}
+}
+
+// BuildLogStream is an output of the peer scanned by a collector, each output
+// needs its own stream so their partial lines aren't mixed
+type BuildLogStream struct {
+	collector *BuildLogCollector
+	partial   []byte
+}
+
+// NewStream returns a writer for an output of the peer
+func (c *BuildLogCollector) NewStream() *BuildLogStream {
+	return &BuildLogStream{collector: c}
+}
+
+// Write collects the complete lines of p, the last line is kept until it's
+// completed by the next write
+func (st *BuildLogStream) Write(p []byte) (int, error) {
+	c := st.collector
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data := append(st.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		c.collectLine(string(bytes.TrimRight(data[:i], "\r")))
+		data = data[i+1:]
+	}
+	if len(data) > maxBuildLineLength {
+		c.collectLine(string(data))
+		data = nil
+	}
+	st.partial = append([]byte{}, data...)
+	return len(p), nil
+}
+
+func (c *BuildLogCollector) collectLine(line string) {
+	if logHeaderRegexp.MatchString(line) {
+		c.capturing = builderLoggerRegexp.MatchString(line) || buildFailedRegexp.MatchString(line) ||
+			(installedRegexp.MatchString(line) && packageIDRegexp.MatchString(line))
+		if !c.capturing {
+			return
+		}
+		now := c.now()
+		if c.current == nil || c.finished || now.Sub(c.current.Updated) > buildLogIdle {
+			c.current = &BuildLog{
+				ID:      now.UTC().Format("20060102T150405.000000000"),
+				Status:  BuildStatusBuilding,
+				Started: now,
+			}
+			c.finished = false
+			c.prune()
+		}
+		c.current.Updated = now
+		if c.current.PackageID == "" {
+			c.current.PackageID = packageIDRegexp.FindString(line)
+		}
+		if buildFailedRegexp.MatchString(line) {
+			c.current.Status = BuildStatusFailed
+		} else if installedRegexp.MatchString(line) && c.current.Status == BuildStatusBuilding {
+			c.current.Status = BuildStatusSucceeded
+		}
+		// the next output belongs to another build
+		c.finished = buildEndRegexp.MatchString(line)
+	} else if !c.capturing || c.current == nil {
+		return
+	}
+	err := c.write(line)
+	if err != nil {
+		log.Warnf("Failed to store the chaincode build log: %v", err)
+	}
+}
+
+// write appends a line to the log of the current build and saves its status
+func (c *BuildLogCollector) write(line string) error {
+	err := os.MkdirAll(c.dir, 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(c.dir, c.current.ID+".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(line + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	buildBytes, err := json.Marshal(c.current)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.dir, c.current.ID+".json"), buildBytes, 0644)
+}
+
+// prune removes the oldest builds over maxBuildLogs, room is kept for the
+// current one
+func (c *BuildLogCollector) prune() {
+	builds, err := listBuildLogs(c.dir)
+	if err != nil {
+		return
+	}
+	for len(builds) >= maxBuildLogs {
+		oldest := builds[len(builds)-1]
+		_ = os.Remove(filepath.Join(c.dir, oldest.ID+".json"))
+		_ = os.Remove(filepath.Join(c.dir, oldest.ID+".log"))
+		builds = builds[:len(builds)-1]
+	}
+}
+
+func listBuildLogs(dir string) ([]BuildLog, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []BuildLog{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	builds := []BuildLog{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		buildBytes, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		build := BuildLog{}
+		err = json.Unmarshal(buildBytes, &build)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid build log %s", entry.Name())
+		}
+		builds = append(builds, build)
+	}
+	sort.Slice(builds, func(i, j int) bool {
+		return builds[i].Started.After(builds[j].Started)
+	})
+	return builds, nil
+}
+
+// ListBuildLogs returns the builds of a peer, the latest first
+func ListBuildLogs(peerDir string) ([]BuildLog, error) {
+	return listBuildLogs(GetBuildLogsDir(peerDir))
+}
+
+// FindBuildLog returns the build with the ID, or the latest build of the
+// package ID, or the latest build when both are empty
+func FindBuildLog(peerDir string, id string, packageID string) (*BuildLog, error) {
+	builds, err := ListBuildLogs(peerDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, build := range builds {
+		if (id == "" || build.ID == id) && (packageID == "" || build.PackageID == packageID) {
+			return &build, nil
+		}
+	}
+	switch {
+	case id != "":
+		return nil, errors.Errorf("build %s not found", id)
+	case packageID != "":
+		return nil, errors.Errorf("no build of package %s, the peer didn't log its ID or it wasn't built since its builds are captured", packageID)
+	}
+	return nil, errors.New("no chaincode build captured")
+}
+
+// ReadBuildLog returns the output of a build
+func ReadBuildLog(peerDir string, id string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(GetBuildLogsDir(peerDir), id+".log"))
+}
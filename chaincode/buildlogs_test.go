@@ -0,0 +1,102 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPackageID = "asset_1.0:0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+
+func TestBuildLogCollector(t *testing.T) {
+	peerDir := t.TempDir()
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	c := NewBuildLogCollector(peerDir)
+	c.now = func() time.Time {
+		return now
+	}
+	stderr := c.NewStream()
+	write := func(lines ...string) {
+		t.Helper()
+		if _, err := stderr.Write([]byte(strings.Join(lines, "\n") + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a failed docker build and its output
+	write(
+		"2024-01-02 15:04:05.000 UTC 0041 INFO [gossip.state] deliverPayloads -> Committed block [5]",
+		"2024-01-02 15:04:05.000 UTC 0042 ERRO [dockercontroller] buildImage -> Error building image: docker build failed",
+		"Build Output:",
+		"go: cannot find main module",
+		"2024-01-02 15:04:06.000 UTC 0043 INFO [gossip.state] deliverPayloads -> Committed block [6]",
+		"not a build line",
+		"2024-01-02 15:04:06.000 UTC 0044 WARN [endorser] ProcessProposal -> could not build chaincode: docker build failed",
+	)
+	// a successful install through an external builder, a minute later
+	now = now.Add(time.Minute)
+	write(
+		"2024-01-02 15:05:05.000 UTC 0045 INFO [chaincode.externalbuilder.hlf-easy_ccaas] waitForExit -> released command=release",
+		"2024-01-02 15:05:05.000 UTC 0046 INFO [lifecycle] InstallChaincode -> Successfully installed chaincode with package ID '"+testPackageID+"'",
+	)
+
+	builds, err := ListBuildLogs(peerDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(builds) != 2 {
+		t.Fatalf("expected 2 builds, got %+v", builds)
+	}
+	if builds[0].Status != BuildStatusSucceeded || builds[0].PackageID != testPackageID {
+		t.Errorf("expected the latest build to succeed with its package ID, got %+v", builds[0])
+	}
+	if builds[1].Status != BuildStatusFailed || builds[1].PackageID != "" {
+		t.Errorf("expected the first build to fail, got %+v", builds[1])
+	}
+	failedLog, err := ReadBuildLog(peerDir, builds[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(failedLog)), "\n")
+	if len(lines) != 4 || lines[2] != "go: cannot find main module" || strings.Contains(string(failedLog), "Committed block") {
+		t.Errorf("expected the docker build output only, got %q", failedLog)
+	}
+
+	build, err := FindBuildLog(peerDir, "", testPackageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if build.ID != builds[0].ID {
+		t.Errorf("expected the build of the package, got %+v", build)
+	}
+	if _, err := FindBuildLog(peerDir, "", "other_1.0:"+strings.Repeat("0", 64)); err == nil {
+		t.Error("expected an error for a package without builds")
+	}
+}
+
+func TestBuildLogCollectorPrune(t *testing.T) {
+	peerDir := t.TempDir()
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	c := NewBuildLogCollector(peerDir)
+	c.now = func() time.Time {
+		return now
+	}
+	stderr := c.NewStream()
+	for i := 0; i < maxBuildLogs+5; i++ {
+		now = now.Add(time.Second)
+		line := "2024-01-02 15:04:05.000 UTC 0046 INFO [lifecycle] InstallChaincode -> Successfully installed chaincode with package ID '" + testPackageID + "'\n"
+		if _, err := stderr.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	builds, err := ListBuildLogs(peerDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(builds) != maxBuildLogs {
+		t.Fatalf("expected %d builds to be kept, got %d", maxBuildLogs, len(builds))
+	}
+	if !builds[0].Started.Equal(now) {
+		t.Errorf("expected the latest builds to be kept, got %v", builds[0].Started)
+	}
+}
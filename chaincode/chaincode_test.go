@@ -0,0 +1,159 @@
+package chaincode
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func readTarGz(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	files := map[string][]byte{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = content
+	}
+}
+
+func dockerDefinition() Definition {
+	return Definition{
+		Name:    "asset",
+		Version: "1.0",
+		Type:    TypeDocker,
+		Address: "chaincode.example.com:9999",
+		Image:   "example/asset:1.0",
+		Limits: Limits{
+			ExecuteTimeout: "10s",
+			DialTimeout:    "5s",
+			MemoryMB:       512,
+			CPUs:           0.5,
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := dockerDefinition().Validate(); err != nil {
+		t.Fatal(err)
+	}
+	invalid := []func(d *Definition){
+		func(d *Definition) { d.Name = "../asset" },
+		func(d *Definition) { d.Type = "k8s" },
+		func(d *Definition) { d.Image = "" },
+		func(d *Definition) { d.Address = "" },
+		func(d *Definition) { d.Limits.ExecuteTimeout = "ten seconds" },
+		func(d *Definition) { d.Limits.DialTimeout = "-1s" },
+		func(d *Definition) { d.Limits.MemoryMB = -1 },
+		func(d *Definition) { d.Type = TypeCCaaS },
+	}
+	for i, mutate := range invalid {
+		d := dockerDefinition()
+		mutate(&d)
+		if err := d.Validate(); err == nil {
+			t.Errorf("expected definition %d to be rejected: %+v", i, d)
+		}
+	}
+}
+
+func TestPackage(t *testing.T) {
+	d := dockerDefinition()
+	pkg, packageID, err := Package(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(packageID, "asset_1.0:") {
+		t.Fatalf("unexpected package ID %s", packageID)
+	}
+	_, otherPackageID, err := Package(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherPackageID != packageID {
+		t.Fatal("expected the package to be reproducible")
+	}
+	files := readTarGz(t, pkg)
+	md := metadata{}
+	if err := json.Unmarshal(files["metadata.json"], &md); err != nil {
+		t.Fatal(err)
+	}
+	if md.Type != "ccaas" || md.Label != "asset_1.0" {
+		t.Fatalf("unexpected metadata %+v", md)
+	}
+	code := readTarGz(t, files["code.tar.gz"])
+	conn := connection{}
+	if err := json.Unmarshal(code["connection.json"], &conn); err != nil {
+		t.Fatal(err)
+	}
+	if conn.Address != d.Address || conn.DialTimeout != "5s" {
+		t.Fatalf("unexpected connection %+v", conn)
+	}
+
+	d.Limits.ExecuteTimeout = "1m"
+	if _, _, err := Package(d); err == nil {
+		t.Fatal("expected an execute timeout longer than the one of the peers to be rejected")
+	}
+}
+
+func TestDockerRunArgs(t *testing.T) {
+	args, err := DockerRunArgs(dockerDefinition(), "asset_1.0:abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(args, " ")
+	for _, expected := range []string{
+		"-p 9999:9999",
+		"CHAINCODE_ID=asset_1.0:abc",
+		"CHAINCODE_EXECUTE_TIMEOUT=10s",
+		"--memory 512m",
+		"--cpus 0.5",
+	} {
+		if !strings.Contains(joined, expected) {
+			t.Errorf("expected %q in %s", expected, joined)
+		}
+	}
+	if args[len(args)-1] != "example/asset:1.0" {
+		t.Fatalf("expected the image last, got %v", args)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := Save(dockerDefinition()); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Get("asset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Limits.MemoryMB != 512 {
+		t.Fatalf("expected the limits to be stored, got %+v", d.Limits)
+	}
+	definitions, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(definitions) != 1 {
+		t.Fatalf("expected 1 chaincode, got %d", len(definitions))
+	}
+	if _, err := Get("missing"); err == nil {
+		t.Fatal("expected a missing chaincode to return an error")
+	}
+}
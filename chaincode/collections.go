@@ -0,0 +1,207 @@
+package chaincode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-sdk-go/third_party/github.com/hyperledger/fabric/common/policydsl"
+	"github.com/pkg/errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// the names of the collections allowed by the peers, the ones starting with
+// _implicit_org_ are reserved for the implicit collections of the orgs
+var collectionNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+const implicitCollectionPrefix = "_implicit_org_"
+
+// ShortBlockToLive is the block to live below which the private data of a
+// collection are likely purged before the peers that missed them reconcile
+// them
+const ShortBlockToLive = 10
+
+// CollectionEndorsementPolicy overrides the endorsement policy of the
+// chaincode for the writes to a collection
+type CollectionEndorsementPolicy struct {
+	SignaturePolicy     string `json:"signaturePolicy,omitempty"`
+	ChannelConfigPolicy string `json:"channelConfigPolicy,omitempty"`
+}
+
+// Collection is a private data collection of a chaincode, in the format of
+// the collections_config.json of the peer CLI
+type Collection struct {
+	Name string `json:"name"`
+	// Policy is the signature policy of the member orgs of the collection,
+	// e.g. OR('Org1MSP.member','Org2MSP.member')
+	Policy            string `json:"policy"`
+	RequiredPeerCount int32  `json:"requiredPeerCount"`
+	MaxPeerCount      int32  `json:"maxPeerCount"`
+	// BlockToLive is the number of blocks the private data are kept, 0 keeps
+	// them forever
+	BlockToLive       uint64                       `json:"blockToLive"`
+	MemberOnlyRead    bool                         `json:"memberOnlyRead"`
+	MemberOnlyWrite   bool                         `json:"memberOnlyWrite"`
+	EndorsementPolicy *CollectionEndorsementPolicy `json:"endorsementPolicy,omitempty"`
+}
+
+// MemberPolicy returns the policy of a collection whose members are the
+// members of the orgs
+func MemberPolicy(mspIDs []string) string {
+	principals := []string{}
+	for _, mspID := range mspIDs {
+		principals = append(principals, fmt.Sprintf("'%s.member'", mspID))
+	}
+	return fmt.Sprintf("OR(%s)", strings.Join(principals, ","))
+}
+
+// ParseCollections parses a collections_config.json, the unknown fields are
+// refused so a misspelled field isn't silently ignored
+func ParseCollections(collectionsBytes []byte) ([]Collection, error) {
+	collections := []Collection{}
+	decoder := json.NewDecoder(bytes.NewReader(collectionsBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&collections); err != nil {
+		return nil, errors.Wrap(err, "invalid collections config")
+	}
+	return collections, nil
+}
+
+// policyMSPIDs parses a signature policy and returns the MSP IDs of its
+// principals
+func policyMSPIDs(policy string) ([]string, error) {
+	envelope, err := policydsl.FromString(policy)
+	if err != nil {
+		return nil, err
+	}
+	mspIDs := []string{}
+	for _, identity := range envelope.Identities {
+		if identity.PrincipalClassification != mb.MSPPrincipal_ROLE {
+			continue
+		}
+		role := &mb.MSPRole{}
+		if err := proto.Unmarshal(identity.Principal, role); err != nil {
+			return nil, err
+		}
+		mspIDs = append(mspIDs, role.MspIdentifier)
+	}
+	return mspIDs, nil
+}
+
+// checkPolicy parses a signature policy of a collection and checks that its
+// orgs are in the channel when the MSP IDs of the channel are known
+func checkPolicy(collection string, name string, policy string, channelMSPIDs map[string]bool) error {
+	mspIDs, err := policyMSPIDs(policy)
+	if err != nil {
+		return errors.Wrapf(err, "invalid %s %q of collection %s", name, policy, collection)
+	}
+	if len(channelMSPIDs) == 0 {
+		return nil
+	}
+	for _, mspID := range mspIDs {
+		if !channelMSPIDs[mspID] {
+			known := []string{}
+			for id := range channelMSPIDs {
+				known = append(known, id)
+			}
+			sort.Strings(known)
+			return errors.Errorf("the %s of collection %s refers to %s, which isn't an org of the channel: %s", name, collection, mspID, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// ValidateCollections checks the collections of a chaincode as the peers do
+// when the definition is approved, and the orgs of their policies against
+// the MSP IDs of the channel if they're given. It returns warnings about the
+// settings that are valid but likely mistakes
+func ValidateCollections(collections []Collection, mspIDs []string) ([]string, error) {
+	channelMSPIDs := map[string]bool{}
+	for _, mspID := range mspIDs {
+		channelMSPIDs[mspID] = true
+	}
+	warnings := []string{}
+	names := map[string]bool{}
+	for _, c := range collections {
+		if !collectionNameRegexp.MatchString(c.Name) || strings.HasPrefix(c.Name, implicitCollectionPrefix) {
+			return nil, errors.Errorf("invalid collection name %q", c.Name)
+		}
+		if names[c.Name] {
+			return nil, errors.Errorf("collection %s is defined twice", c.Name)
+		}
+		names[c.Name] = true
+		if c.Policy == "" {
+			return nil, errors.Errorf("collection %s has no policy", c.Name)
+		}
+		if err := checkPolicy(c.Name, "policy", c.Policy, channelMSPIDs); err != nil {
+			return nil, err
+		}
+		if c.RequiredPeerCount < 0 {
+			return nil, errors.Errorf("the required peer count of collection %s can't be negative", c.Name)
+		}
+		if c.MaxPeerCount < c.RequiredPeerCount {
+			return nil, errors.Errorf("the max peer count %d of collection %s is lower than its required peer count %d", c.MaxPeerCount, c.Name, c.RequiredPeerCount)
+		}
+		if c.EndorsementPolicy != nil {
+			if c.EndorsementPolicy.SignaturePolicy != "" && c.EndorsementPolicy.ChannelConfigPolicy != "" {
+				return nil, errors.Errorf("the endorsement policy of collection %s has both a signature policy and a channel config policy", c.Name)
+			}
+			if c.EndorsementPolicy.SignaturePolicy != "" {
+				if err := checkPolicy(c.Name, "endorsement policy", c.EndorsementPolicy.SignaturePolicy, channelMSPIDs); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if c.BlockToLive > 0 && c.BlockToLive < ShortBlockToLive {
+			warnings = append(warnings, fmt.Sprintf("the private data of collection %s are purged after %d blocks, the peers that miss them may not reconcile them in time", c.Name, c.BlockToLive))
+		}
+		if c.MaxPeerCount == 0 {
+			warnings = append(warnings, fmt.Sprintf("the private data of collection %s aren't disseminated at endorsement, the other peers of its orgs pull them when they commit", c.Name))
+		}
+	}
+	return warnings, nil
+}
+
+// CollectionConfigs converts the collections to the collections config of a
+// chaincode definition of the _lifecycle
+func CollectionConfigs(collections []Collection) ([]*pb.CollectionConfig, error) {
+	configs := []*pb.CollectionConfig{}
+	for _, c := range collections {
+		policy, err := policydsl.FromString(c.Policy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid policy %q of collection %s", c.Policy, c.Name)
+		}
+		staticConfig := &pb.StaticCollectionConfig{
+			Name: c.Name,
+			MemberOrgsPolicy: &pb.CollectionPolicyConfig{
+				Payload: &pb.CollectionPolicyConfig_SignaturePolicy{SignaturePolicy: policy},
+			},
+			RequiredPeerCount: c.RequiredPeerCount,
+			MaximumPeerCount:  c.MaxPeerCount,
+			BlockToLive:       c.BlockToLive,
+			MemberOnlyRead:    c.MemberOnlyRead,
+			MemberOnlyWrite:   c.MemberOnlyWrite,
+		}
+		if c.EndorsementPolicy != nil && c.EndorsementPolicy.SignaturePolicy != "" {
+			endorsementPolicy, err := policydsl.FromString(c.EndorsementPolicy.SignaturePolicy)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid endorsement policy %q of collection %s", c.EndorsementPolicy.SignaturePolicy, c.Name)
+			}
+			staticConfig.EndorsementPolicy = &pb.ApplicationPolicy{
+				Type: &pb.ApplicationPolicy_SignaturePolicy{SignaturePolicy: endorsementPolicy},
+			}
+		} else if c.EndorsementPolicy != nil && c.EndorsementPolicy.ChannelConfigPolicy != "" {
+			staticConfig.EndorsementPolicy = &pb.ApplicationPolicy{
+				Type: &pb.ApplicationPolicy_ChannelConfigPolicyReference{ChannelConfigPolicyReference: c.EndorsementPolicy.ChannelConfigPolicy},
+			}
+		}
+		configs = append(configs, &pb.CollectionConfig{
+			Payload: &pb.CollectionConfig_StaticCollectionConfig{StaticCollectionConfig: staticConfig},
+		})
+	}
+	return configs, nil
+}
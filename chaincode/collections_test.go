@@ -0,0 +1,104 @@
+package chaincode
+
+import (
+	"strings"
+	"testing"
+)
+
+func testCollection() Collection {
+	return Collection{
+		Name:              "private",
+		Policy:            MemberPolicy([]string{"Org1MSP", "Org2MSP"}),
+		RequiredPeerCount: 0,
+		MaxPeerCount:      3,
+		BlockToLive:       1000000,
+		MemberOnlyRead:    true,
+		MemberOnlyWrite:   true,
+	}
+}
+
+func TestParseCollections(t *testing.T) {
+	collections, err := ParseCollections([]byte(`[{"name":"private","policy":"OR('Org1MSP.member')","requiredPeerCount":1,"maxPeerCount":2,"blockToLive":0,"memberOnlyRead":true,"memberOnlyWrite":false,"endorsementPolicy":{"signaturePolicy":"AND('Org1MSP.peer')"}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collections) != 1 || collections[0].MaxPeerCount != 2 || collections[0].EndorsementPolicy.SignaturePolicy != "AND('Org1MSP.peer')" {
+		t.Errorf("unexpected collections %+v", collections)
+	}
+	if _, err := ParseCollections([]byte(`[{"name":"private","blockToLife":10}]`)); err == nil {
+		t.Error("expected an error for a misspelled field")
+	}
+}
+
+func TestValidateCollections(t *testing.T) {
+	channelMSPIDs := []string{"Org1MSP", "Org2MSP"}
+	warnings, err := ValidateCollections([]Collection{testCollection()}, channelMSPIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+
+	invalid := []func(c *Collection){
+		func(c *Collection) { c.Name = "private data" },
+		func(c *Collection) { c.Name = "_implicit_org_Org1MSP" },
+		func(c *Collection) { c.Policy = "" },
+		func(c *Collection) { c.Policy = "OR('Org1MSP.member'" },
+		func(c *Collection) { c.Policy = "OR('Org3MSP.member')" },
+		func(c *Collection) { c.RequiredPeerCount = 4 },
+		func(c *Collection) { c.RequiredPeerCount = -1 },
+		func(c *Collection) {
+			c.EndorsementPolicy = &CollectionEndorsementPolicy{SignaturePolicy: "OR('Org1MSP.peer')", ChannelConfigPolicy: "/Channel/Application/Endorsement"}
+		},
+		func(c *Collection) {
+			c.EndorsementPolicy = &CollectionEndorsementPolicy{SignaturePolicy: "OR('Org3MSP.peer')"}
+		},
+	}
+	for i, mutate := range invalid {
+		c := testCollection()
+		mutate(&c)
+		if _, err := ValidateCollections([]Collection{c}, channelMSPIDs); err == nil {
+			t.Errorf("expected collection %d to be rejected: %+v", i, c)
+		}
+	}
+	if _, err := ValidateCollections([]Collection{testCollection(), testCollection()}, nil); err == nil {
+		t.Error("expected an error for a collection defined twice")
+	}
+
+	// the orgs aren't checked without the MSP IDs of the channel
+	c := testCollection()
+	c.Policy = "OR('Org3MSP.member')"
+	c.BlockToLive = 2
+	c.MaxPeerCount = 0
+	warnings, err = ValidateCollections([]Collection{c}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "purged after 2 blocks") {
+		t.Errorf("expected warnings about the block to live and the dissemination, got %v", warnings)
+	}
+}
+
+func TestCollectionConfigs(t *testing.T) {
+	c := testCollection()
+	c.EndorsementPolicy = &CollectionEndorsementPolicy{ChannelConfigPolicy: "/Channel/Application/Endorsement"}
+	configs, err := CollectionConfigs([]Collection{c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	staticConfig := configs[0].GetStaticCollectionConfig()
+	if len(configs) != 1 || staticConfig.Name != "private" || staticConfig.MaximumPeerCount != 3 || !staticConfig.MemberOnlyRead {
+		t.Fatalf("unexpected collections config %v", configs)
+	}
+	if len(staticConfig.MemberOrgsPolicy.GetSignaturePolicy().Identities) != 2 {
+		t.Errorf("expected the 2 orgs in the policy of the collection, got %v", staticConfig.MemberOrgsPolicy)
+	}
+	if staticConfig.EndorsementPolicy.GetChannelConfigPolicyReference() != "/Channel/Application/Endorsement" {
+		t.Errorf("unexpected endorsement policy %v", staticConfig.EndorsementPolicy)
+	}
+	c.Policy = "OR('Org1MSP.member'"
+	if _, err := CollectionConfigs([]Collection{c}); err == nil {
+		t.Error("expected an error for an invalid policy")
+	}
+}
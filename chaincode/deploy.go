@@ -0,0 +1,156 @@
+package chaincode
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"net"
+	"strconv"
+	"time"
+)
+
+// PeerExecuteTimeout is the executetimeout of the core.yaml of the peers, an
+// invocation is aborted by the peer after it whatever the chaincode limits
+const PeerExecuteTimeout = 30 * time.Second
+
+// defaultDialTimeout is used when the definition doesn't set one
+const defaultDialTimeout = "10s"
+
+type connection struct {
+	Address            string `json:"address"`
+	DialTimeout        string `json:"dial_timeout"`
+	TLSRequired        bool   `json:"tls_required"`
+	ClientAuthRequired bool   `json:"client_auth_required,omitempty"`
+	ClientKey          string `json:"client_key,omitempty"`
+	ClientCert         string `json:"client_cert,omitempty"`
+	RootCert           string `json:"root_cert,omitempty"`
+}
+
+type metadata struct {
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+func writeTarGz(files map[string][]byte, order []string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for _, name := range order {
+		content := files[name]
+		err := tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(content)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		_, err = tw.Write(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := tw.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = gw.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CheckLimits checks that the limits of the chaincode can be honored by the peers
+func CheckLimits(d Definition) error {
+	executeTimeout, err := parseTimeout("execute timeout", d.Limits.ExecuteTimeout)
+	if err != nil {
+		return err
+	}
+	if executeTimeout > PeerExecuteTimeout {
+		return errors.Errorf("execute timeout %s of chaincode %s is longer than the execute timeout %s of the peers", executeTimeout, d.Name, PeerExecuteTimeout)
+	}
+	return nil
+}
+
+// Package builds the chaincode-as-a-service package of the chaincode, the
+// dial timeout of the definition is set in its connection.json
+func Package(d Definition) ([]byte, string, error) {
+	err := d.Validate()
+	if err != nil {
+		return nil, "", err
+	}
+	err = CheckLimits(d)
+	if err != nil {
+		return nil, "", err
+	}
+	dialTimeout := d.Limits.DialTimeout
+	if dialTimeout == "" {
+		dialTimeout = defaultDialTimeout
+	}
+	connectionBytes, err := json.Marshal(connection{
+		Address:     d.Address,
+		DialTimeout: dialTimeout,
+		TLSRequired: false,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	codeTarGz, err := writeTarGz(map[string][]byte{"connection.json": connectionBytes}, []string{"connection.json"})
+	if err != nil {
+		return nil, "", err
+	}
+	metadataBytes, err := json.Marshal(metadata{Type: "ccaas", Label: d.Label()})
+	if err != nil {
+		return nil, "", err
+	}
+	pkg, err := writeTarGz(map[string][]byte{
+		"metadata.json": metadataBytes,
+		"code.tar.gz":   codeTarGz,
+	}, []string{"metadata.json", "code.tar.gz"})
+	if err != nil {
+		return nil, "", err
+	}
+	return pkg, PackageID(d.Label(), pkg), nil
+}
+
+// PackageID is the ID the peer gives to an installed package
+func PackageID(label string, pkg []byte) string {
+	hash := sha256.Sum256(pkg)
+	return fmt.Sprintf("%s:%s", label, hex.EncodeToString(hash[:]))
+}
+
+// DockerRunArgs are the arguments of docker run to start the container of a
+// docker chaincode with its limits
+func DockerRunArgs(d Definition, packageID string) ([]string, error) {
+	if d.Type != TypeDocker {
+		return nil, errors.Errorf("chaincode %s is not a docker chaincode", d.Name)
+	}
+	_, port, err := net.SplitHostPort(d.Address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid address %s", d.Address)
+	}
+	args := []string{
+		"run", "-d",
+		"--name", d.Label(),
+		"--restart", "unless-stopped",
+		"-p", fmt.Sprintf("%s:%s", port, port),
+		"-e", fmt.Sprintf("CHAINCODE_SERVER_ADDRESS=0.0.0.0:%s", port),
+		"-e", fmt.Sprintf("CHAINCODE_ID=%s", packageID),
+	}
+	if d.Limits.ExecuteTimeout != "" {
+		args = append(args, "-e", fmt.Sprintf("CHAINCODE_EXECUTE_TIMEOUT=%s", d.Limits.ExecuteTimeout))
+	}
+	if d.Limits.MemoryMB > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%dm", d.Limits.MemoryMB))
+	}
+	if d.Limits.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(d.Limits.CPUs, 'f', -1, 64))
+	}
+	return append(args, d.Image), nil
+}
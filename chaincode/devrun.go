@@ -0,0 +1,159 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/log"
+	"hlf-easy/proc"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// DefaultDevRunInterval is how often the binary of a chaincode run in dev
+// mode is checked for a rebuild
+const DefaultDevRunInterval = time.Second
+
+// devStopTimeout is the time a chaincode has to exit on an interrupt before
+// it's killed
+const devStopTimeout = 5 * time.Second
+
+// DevRunOptions configures a chaincode process connected to a peer in dev
+// mode
+type DevRunOptions struct {
+	// ID is the name:version the chaincode is approved with, the peer routes
+	// the invocations of the chaincode to the process registered with it
+	ID string
+	// PeerAddress is the chaincode address of the peer
+	PeerAddress string
+	Binary      string
+	Args        []string
+	// LogLevel is the log level of the shim, info when empty
+	LogLevel string
+	// Watch restarts the chaincode when its binary is rebuilt
+	Watch    bool
+	Interval time.Duration
+}
+
+// DevRunEnv returns the environment of a chaincode run in dev mode, the one
+// of hlf-easy with the settings of the shim
+func DevRunEnv(opts DevRunOptions) []string {
+	logLevel := opts.LogLevel
+	if logLevel == "" {
+		logLevel = "info"
+	}
+	return append(os.Environ(),
+		fmt.Sprintf("CORE_CHAINCODE_ID_NAME=%s", opts.ID),
+		fmt.Sprintf("CORE_PEER_ADDRESS=%s", opts.PeerAddress),
+		fmt.Sprintf("CORE_CHAINCODE_LOGLEVEL=%s", logLevel),
+		"CORE_PEER_TLS_ENABLED=false",
+	)
+}
+
+// DevRunArgs returns the arguments of a chaincode run in dev mode, the shims
+// of all the languages read the address of the peer from --peer.address
+func DevRunArgs(opts DevRunOptions) []string {
+	return append([]string{"--peer.address", opts.PeerAddress}, opts.Args...)
+}
+
+// binaryStamp identifies a build of the binary
+type binaryStamp struct {
+	modTime int64
+	size    int64
+}
+
+func statBinary(binary string) (binaryStamp, error) {
+	info, err := os.Stat(binary)
+	if err != nil {
+		return binaryStamp{}, err
+	}
+	return binaryStamp{modTime: info.ModTime().UnixNano(), size: info.Size()}, nil
+}
+
+// RunDev runs a chaincode connected to a peer in dev mode until the context
+// is done. With Watch the chaincode is restarted when its binary changes and
+// stays the same for an interval, so a build still writing it isn't started,
+// and a chaincode that exits waits for the next rebuild
+func RunDev(ctx context.Context, opts DevRunOptions, stdout io.Writer, stderr io.Writer) error {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultDevRunInterval
+	}
+	stamp, err := statBinary(opts.Binary)
+	if err != nil {
+		return err
+	}
+	var tick <-chan time.Time
+	if opts.Watch {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		cmd := exec.Command(opts.Binary, DevRunArgs(opts)...)
+		cmd.Env = DevRunEnv(opts)
+		proc.NewProcessGroup(cmd)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		err = cmd.Start()
+		if err != nil {
+			return errors.Wrapf(err, "failed to start chaincode %s", opts.ID)
+		}
+		log.Infof("Chaincode %s started with pid %d, connected to %s", opts.ID, cmd.Process.Pid, opts.PeerAddress)
+		exited := make(chan error, 1)
+		go func() {
+			exited <- cmd.Wait()
+		}()
+		pending := stamp
+		rebuilt := false
+		for !rebuilt {
+			select {
+			case <-ctx.Done():
+				stopDevProcess(cmd, exited)
+				return nil
+			case err := <-exited:
+				exited = nil
+				if !opts.Watch {
+					if err != nil {
+						return errors.Wrapf(err, "chaincode %s exited", opts.ID)
+					}
+					return nil
+				}
+				log.Warnf("Chaincode %s exited: %v, it's started again when its binary is rebuilt", opts.ID, err)
+			case <-tick:
+				current, err := statBinary(opts.Binary)
+				if err != nil || current == stamp {
+					continue
+				}
+				if current != pending {
+					pending = current
+					continue
+				}
+				stamp = current
+				rebuilt = true
+			}
+		}
+		log.Infof("Binary of chaincode %s rebuilt, restarting it", opts.ID)
+		stopDevProcess(cmd, exited)
+	}
+}
+
+// stopDevProcess interrupts a chaincode and kills it if it doesn't exit in
+// time, exited is nil when it already exited
+func stopDevProcess(cmd *exec.Cmd, exited chan error) {
+	if exited == nil {
+		return
+	}
+	err := proc.Interrupt(cmd.Process)
+	if err != nil {
+		log.Warnf("Failed to interrupt the chaincode: %v", err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(devStopTimeout):
+		_ = cmd.Process.Kill()
+		<-exited
+	}
+}
@@ -0,0 +1,84 @@
+package chaincode
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunDev(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "chaincode")
+	out := filepath.Join(dir, "out")
+	writeBinary := func(build string) {
+		script := "#!/bin/sh\necho \"" + build + " $CORE_CHAINCODE_ID_NAME $CORE_PEER_TLS_ENABLED $1 $2\" >> " + out + "\nexec sleep 30\n"
+		err := os.WriteFile(binary, []byte(script), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitLines := func(n int) []string {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			outBytes, _ := os.ReadFile(out)
+			lines := strings.Split(strings.TrimSpace(string(outBytes)), "\n")
+			if len(outBytes) > 0 && len(lines) >= n {
+				return lines
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("expected the chaincode to be started %d times", n)
+		return nil
+	}
+	writeBinary("v1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- RunDev(ctx, DevRunOptions{
+			ID:          "mycc:1.0",
+			PeerAddress: "127.0.0.1:7052",
+			Binary:      binary,
+			Watch:       true,
+			Interval:    50 * time.Millisecond,
+		}, io.Discard, io.Discard)
+	}()
+	lines := waitLines(1)
+	if lines[0] != "v1 mycc:1.0 false --peer.address 127.0.0.1:7052" {
+		t.Fatalf("unexpected environment of the chaincode %q", lines[0])
+	}
+
+	// the rebuilt binary is started in place of the running one
+	writeBinary("v2-rebuilt")
+	lines = waitLines(2)
+	if !strings.HasPrefix(lines[1], "v2-rebuilt ") {
+		t.Fatalf("expected the rebuilt chaincode to be started, got %q", lines[1])
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the chaincode to be stopped")
+	}
+}
+
+func TestRunDevExit(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "chaincode")
+	err := os.WriteFile(binary, []byte("#!/bin/sh\nexit 2\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RunDev(context.Background(), DevRunOptions{ID: "mycc:1.0", Binary: binary}, io.Discard, io.Discard)
+	if err == nil {
+		t.Fatal("expected the exit of the chaincode to be reported without --watch")
+	}
+}
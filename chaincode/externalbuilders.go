@@ -0,0 +1,183 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ccaasBuilderName is the builder of Fabric in the core.yaml of the peers
+const ccaasBuilderName = "ccaas_builder"
+
+// ExternalBuilder is a custom external builder of the host, it's added to
+// the externalBuilders of the core.yaml of the peers after the builder of
+// hlf-easy
+type ExternalBuilder struct {
+	Name string `json:"name"`
+	// Path is the directory of the builder, its scripts are in bin
+	Path string `json:"path"`
+	// PropagateEnvironment are the environment variables of the peer passed
+	// to the scripts
+	PropagateEnvironment []string  `json:"propagateEnvironment,omitempty"`
+	CreatedAt            time.Time `json:"createdAt"`
+}
+
+// the scripts of a scaffolded builder, %[1]s is its name. They handle the
+// packages of type name, copy the source and the metadata to the build
+// output and release the couchdb indexes and the connection.json of the
+// package, the run script must be implemented
+var builderScripts = map[string]string{
+	"detect": `#!/bin/sh
+# detect CHAINCODE_SOURCE_DIR CHAINCODE_METADATA_DIR
+# exits 0 when the builder handles the package, here the packages of type %[1]s
+set -e
+grep -q '"type"[[:space:]]*:[[:space:]]*"%[1]s"' "$2/metadata.json"
+`,
+	"build": `#!/bin/sh
+# build CHAINCODE_SOURCE_DIR CHAINCODE_METADATA_DIR BUILD_OUTPUT_DIR
+# builds the chaincode into BUILD_OUTPUT_DIR, its output is logged by the peer
+set -e
+cp -R "$1"/. "$3"/
+cp "$2/metadata.json" "$3/metadata.json"
+`,
+	"release": `#!/bin/sh
+# release BUILD_OUTPUT_DIR RELEASE_OUTPUT_DIR
+# releases the couchdb indexes of the chaincode and the connection.json of a
+# chaincode server
+set -e
+if [ -d "$1/META-INF/statedb" ]; then
+  cp -R "$1/META-INF/statedb" "$2/statedb"
+fi
+if [ -f "$1/connection.json" ]; then
+  mkdir -p "$2/chaincode/server"
+  cp "$1/connection.json" "$2/chaincode/server/connection.json"
+fi
+`,
+	"run": `#!/bin/sh
+# run BUILD_OUTPUT_DIR RUN_METADATA_DIR
+# starts the chaincode, RUN_METADATA_DIR/chaincode.json has its chaincode_id,
+# its peer_address and its TLS material. The peer stops the chaincode by
+# killing this process
+echo "the run script of the external builder %[1]s isn't implemented" >&2
+exit 1
+`,
+}
+
+// GetExternalBuildersDir returns the directory of the custom external
+// builders of the host
+func GetExternalBuildersDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/builders"), nil
+}
+
+// ScaffoldExternalBuilder writes the scripts of a new external builder in
+// the hlf-easy home, without the run script the chaincodes it builds are
+// chaincode servers
+func ScaffoldExternalBuilder(name string, propagateEnvironment []string, withRun bool) (*ExternalBuilder, error) {
+	if !nameRegexp.MatchString(name) {
+		return nil, errors.Errorf("invalid builder name %q", name)
+	}
+	if name == BuilderName || name == ccaasBuilderName {
+		return nil, errors.Errorf("builder name %s is reserved", name)
+	}
+	buildersDir, err := GetExternalBuildersDir()
+	if err != nil {
+		return nil, err
+	}
+	builderDir := filepath.Join(buildersDir, name)
+	if _, err := os.Stat(builderDir); err == nil {
+		return nil, errors.Errorf("builder %s already exists in %s", name, builderDir)
+	}
+	binDir := filepath.Join(builderDir, "bin")
+	err = os.MkdirAll(binDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	for phase, script := range builderScripts {
+		if phase == "run" && !withRun {
+			continue
+		}
+		err = os.WriteFile(filepath.Join(binDir, phase), []byte(fmt.Sprintf(script, name)), 0755)
+		if err != nil {
+			return nil, err
+		}
+	}
+	b := &ExternalBuilder{
+		Name:                 name,
+		Path:                 builderDir,
+		PropagateEnvironment: propagateEnvironment,
+		CreatedAt:            time.Now(),
+	}
+	builderBytes, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(filepath.Join(builderDir, "builder.json"), builderBytes, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ListExternalBuilders returns the external builders of the host by name
+func ListExternalBuilders() ([]ExternalBuilder, error) {
+	buildersDir, err := GetExternalBuildersDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(buildersDir)
+	if os.IsNotExist(err) {
+		return []ExternalBuilder{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	builders := []ExternalBuilder{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		builderBytes, err := os.ReadFile(filepath.Join(buildersDir, entry.Name(), "builder.json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		b := ExternalBuilder{}
+		err = json.Unmarshal(builderBytes, &b)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid builder %s", entry.Name())
+		}
+		// the directory may have been moved with the home
+		b.Path = filepath.Join(buildersDir, entry.Name())
+		builders = append(builders, b)
+	}
+	sort.Slice(builders, func(i, j int) bool {
+		return builders[i].Name < builders[j].Name
+	})
+	return builders, nil
+}
+
+// RemoveExternalBuilder removes an external builder and its scripts
+func RemoveExternalBuilder(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return errors.Errorf("invalid builder name %q", name)
+	}
+	buildersDir, err := GetExternalBuildersDir()
+	if err != nil {
+		return err
+	}
+	builderDir := filepath.Join(buildersDir, name)
+	if _, err := os.Stat(filepath.Join(builderDir, "builder.json")); err != nil {
+		return errors.Errorf("builder %s not found", name)
+	}
+	return os.RemoveAll(builderDir)
+}
@@ -0,0 +1,65 @@
+package chaincode
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestScaffoldExternalBuilder(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	b, err := ScaffoldExternalBuilder("wasm", []string{"WASM_RUNTIME"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, phase := range []string{"detect", "build", "release", "run"} {
+		if _, err := os.Stat(filepath.Join(b.Path, "bin", phase)); err != nil {
+			t.Fatalf("expected the %s script: %v", phase, err)
+		}
+	}
+	if _, err := ScaffoldExternalBuilder("wasm", nil, true); err == nil {
+		t.Fatal("expected an error for an existing builder")
+	}
+	if _, err := ScaffoldExternalBuilder(BuilderName, nil, true); err == nil {
+		t.Fatal("expected an error for a reserved name")
+	}
+
+	// the detect script handles the packages of the type of the builder
+	metadataDir := t.TempDir()
+	detect := func(packageType string) error {
+		err := os.WriteFile(filepath.Join(metadataDir, "metadata.json"), []byte(`{"type": "`+packageType+`", "label": "asset_1.0"}`), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return exec.Command(filepath.Join(b.Path, "bin", "detect"), t.TempDir(), metadataDir).Run()
+	}
+	if err := detect("wasm"); err != nil {
+		t.Errorf("expected the builder to detect its packages: %v", err)
+	}
+	if err := detect("golang"); err == nil {
+		t.Error("expected the builder not to detect the golang packages")
+	}
+
+	server, err := ScaffoldExternalBuilder("server", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(server.Path, "bin", "run")); !os.IsNotExist(err) {
+		t.Error("expected no run script for a builder of chaincode servers")
+	}
+	builders, err := ListExternalBuilders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(builders) != 2 || builders[0].Name != "server" || builders[1].Name != "wasm" || builders[1].PropagateEnvironment[0] != "WASM_RUNTIME" {
+		t.Fatalf("unexpected builders %+v", builders)
+	}
+
+	if err := RemoveExternalBuilder("wasm"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveExternalBuilder("wasm"); err == nil {
+		t.Fatal("expected an error for a removed builder")
+	}
+}
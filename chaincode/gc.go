@@ -0,0 +1,290 @@
+package chaincode
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PeerNetworkID is the network ID of the peers started by hlf-easy, the
+// containers and images of the chaincodes the peers build with docker are
+// named <network ID>-<peer ID>-<package ID>
+const PeerNetworkID = "peer01-nid"
+
+// Kinds of the docker resources of the chaincodes
+const (
+	ResourceContainer = "container"
+	ResourceImage     = "image"
+)
+
+// DefaultGCTimeout bounds the listing and the removals of GC
+const DefaultGCTimeout = 5 * time.Minute
+
+// dockerTimeFormat is the time of the docker CLI templates
+const dockerTimeFormat = "2006-01-02 15:04:05 -0700 MST"
+
+// execDocker runs the docker CLI, it's replaced by the tests
+var execDocker = func(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, "docker", args...)
+}
+
+var (
+	// the characters the peers replace with - in the names of the docker
+	// resources
+	vmNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9-_.]`)
+	// the hash of a package ID, and of the name of an image after it
+	packageHashRegexp = regexp.MustCompile(`-[0-9a-f]{64}(-[0-9a-f]{64})?$`)
+)
+
+// DockerResource is a container or an image of a chaincode built by a peer
+type DockerResource struct {
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	PeerID string `json:"peerID"`
+	// Label is the label of the chaincode package, the image names are in
+	// lowercase
+	Label   string    `json:"label"`
+	Created time.Time `json:"created"`
+	Running bool      `json:"running,omitempty"`
+	// Image is the image of a container
+	Image string `json:"image,omitempty"`
+}
+
+// GCOptions selects the docker resources of the chaincodes to remove
+type GCOptions struct {
+	// PeerIDs and Names filter the peers and the chaincodes, all of them
+	// when empty
+	PeerIDs []string
+	Names   []string
+	// Keep is the number of newest images kept per chaincode and peer
+	Keep   int
+	DryRun bool
+}
+
+// labelName returns the name of the chaincode of a label, the labels of the
+// packages of hlf-easy are <name>_<version>
+func labelName(label string) string {
+	if i := strings.LastIndex(label, "_"); i > 0 {
+		return label[:i]
+	}
+	return label
+}
+
+// matchName tells if the label of a package is the one of a chaincode of
+// names, any chaincode when names is empty
+func matchName(label string, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, name := range names {
+		if strings.EqualFold(label, name) || strings.EqualFold(labelName(label), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// getPeerIDs returns the IDs of the peers of the host
+func getPeerIDs() ([]string, error) {
+	peersDir, err := getPeersDir()
+	if err != nil {
+		return nil, err
+	}
+	peerConfigFiles, err := filepath.Glob(filepath.Join(peersDir, "*", "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	peerIDs := []string{}
+	for _, peerConfigFile := range peerConfigFiles {
+		peerIDs = append(peerIDs, filepath.Base(filepath.Dir(peerConfigFile)))
+	}
+	return peerIDs, nil
+}
+
+// parseResourceName returns the peer and the label of the name of a docker
+// resource of a chaincode, the longest peer ID matching the name wins since
+// the IDs can contain -
+func parseResourceName(name string, peerIDs []string) (string, string, bool) {
+	peerID := ""
+	label := ""
+	for _, id := range peerIDs {
+		prefix := vmNameRegexp.ReplaceAllString(PeerNetworkID+"-"+id+"-", "-")
+		if len(id) <= len(peerID) || len(name) <= len(prefix) || !strings.EqualFold(name[:len(prefix)], prefix) {
+			continue
+		}
+		rest := name[len(prefix):]
+		hash := packageHashRegexp.FindStringIndex(rest)
+		if hash == nil || hash[0] == 0 {
+			continue
+		}
+		peerID = id
+		label = rest[:hash[0]]
+	}
+	return peerID, label, peerID != ""
+}
+
+// runDocker runs a docker command and returns its output
+func runDocker(ctx context.Context, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := execDocker(ctx, args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "docker %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// listDocker returns the tab separated fields of the lines of a docker ls
+// command
+func listDocker(ctx context.Context, args ...string) ([][]string, error) {
+	output, err := runDocker(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	rows := [][]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			rows = append(rows, strings.Split(line, "\t"))
+		}
+	}
+	return rows, scanner.Err()
+}
+
+// ListDockerResources returns the containers and the images of the chaincodes
+// built by the peers of the host, filtered by peer and chaincode
+func ListDockerResources(ctx context.Context, peerIDs []string, names []string) ([]DockerResource, error) {
+	hostPeerIDs, err := getPeerIDs()
+	if err != nil {
+		return nil, err
+	}
+	if len(peerIDs) > 0 {
+		exists := map[string]bool{}
+		for _, id := range hostPeerIDs {
+			exists[id] = true
+		}
+		for _, id := range peerIDs {
+			if !exists[id] {
+				return nil, errors.Errorf("peer %s does not exist", id)
+			}
+		}
+		hostPeerIDs = peerIDs
+	}
+	resources := []DockerResource{}
+	add := func(r DockerResource, created string) {
+		peerID, label, ok := parseResourceName(r.Name, hostPeerIDs)
+		if !ok || !matchName(label, names) {
+			return
+		}
+		r.PeerID = peerID
+		r.Label = label
+		r.Created, _ = time.Parse(dockerTimeFormat, created)
+		resources = append(resources, r)
+	}
+	containers, err := listDocker(ctx, "ps", "--all", "--no-trunc",
+		"--filter", "name="+PeerNetworkID+"-",
+		"--format", "{{.ID}}\t{{.Names}}\t{{.Image}}\t{{.State}}\t{{.CreatedAt}}")
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range containers {
+		if len(fields) < 5 {
+			continue
+		}
+		add(DockerResource{Kind: ResourceContainer, ID: fields[0], Name: fields[1], Image: fields[2], Running: fields[3] == "running"}, fields[4])
+	}
+	images, err := listDocker(ctx, "images", "--no-trunc",
+		"--filter", "reference="+PeerNetworkID+"-*",
+		"--format", "{{.ID}}\t{{.Repository}}\t{{.CreatedAt}}")
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range images {
+		if len(fields) < 3 {
+			continue
+		}
+		add(DockerResource{Kind: ResourceImage, ID: fields[0], Name: fields[1]}, fields[2])
+	}
+	return resources, nil
+}
+
+// Stale returns the resources left behind by the previous builds: the
+// containers that aren't running, the peers create a new one when they
+// launch a chaincode, and the images of a chaincode of a peer beyond the keep
+// newest ones that no running container uses
+func Stale(resources []DockerResource, keep int) []DockerResource {
+	inUse := map[string]bool{}
+	images := map[string][]DockerResource{}
+	for _, r := range resources {
+		if r.Kind == ResourceContainer && r.Running {
+			inUse[r.Image] = true
+		}
+		if r.Kind == ResourceImage {
+			key := r.PeerID + "/" + strings.ToLower(labelName(r.Label))
+			images[key] = append(images[key], r)
+		}
+	}
+	staleImages := map[string]bool{}
+	for _, chaincodeImages := range images {
+		sort.Slice(chaincodeImages, func(i, j int) bool {
+			return chaincodeImages[i].Created.After(chaincodeImages[j].Created)
+		})
+		for i, image := range chaincodeImages {
+			if i >= keep && !inUse[image.Name] {
+				staleImages[image.Name] = true
+			}
+		}
+	}
+	stale := []DockerResource{}
+	// the containers are removed before their images
+	for _, r := range resources {
+		if r.Kind == ResourceContainer && !r.Running {
+			stale = append(stale, r)
+		}
+	}
+	for _, r := range resources {
+		if r.Kind == ResourceImage && staleImages[r.Name] {
+			stale = append(stale, r)
+		}
+	}
+	return stale
+}
+
+// GC removes the stale containers and images of the chaincodes built by the
+// peers of the host and returns them, they're only listed on a dry run
+func GC(ctx context.Context, opts GCOptions) ([]DockerResource, error) {
+	if opts.Keep < 1 {
+		return nil, errors.New("at least the newest image of a chaincode is kept")
+	}
+	resources, err := ListDockerResources(ctx, opts.PeerIDs, opts.Names)
+	if err != nil {
+		return nil, err
+	}
+	stale := Stale(resources, opts.Keep)
+	if opts.DryRun {
+		return stale, nil
+	}
+	removed := []DockerResource{}
+	for _, r := range stale {
+		args := []string{"rm", r.ID}
+		if r.Kind == ResourceImage {
+			// an image is removed by its name, its ID can have other tags
+			args = []string{"rmi", r.Name}
+		}
+		_, err := runDocker(ctx, args...)
+		if err != nil {
+			return removed, errors.Wrapf(err, "failed to remove the %s %s", r.Kind, r.Name)
+		}
+		removed = append(removed, r)
+	}
+	return removed, nil
+}
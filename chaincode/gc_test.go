@@ -0,0 +1,118 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseResourceName(t *testing.T) {
+	hash := strings.Repeat("a", 64)
+	peerIDs := []string{"peer0", "peer0-org1"}
+	names := map[string][2]string{
+		"peer01-nid-peer0-asset_1.0-" + hash:                               {"peer0", "asset_1.0"},
+		"peer01-nid-peer0-org1-asset_1.0-" + hash:                          {"peer0-org1", "asset_1.0"},
+		"peer01-nid-peer0-org1-asset_1.0-" + hash + "-" + hash:             {"peer0-org1", "asset_1.0"},
+		"peer01-nid-peer0-my-cc_2-" + strings.Repeat("b", 64) + "-" + hash: {"peer0", "my-cc_2"},
+	}
+	for name, expected := range names {
+		peerID, label, ok := parseResourceName(name, peerIDs)
+		if !ok || peerID != expected[0] || label != expected[1] {
+			t.Errorf("expected %v for %s, got %s %s %t", expected, name, peerID, label, ok)
+		}
+	}
+	for _, name := range []string{"peer01-nid-peer1-asset_1.0-" + hash, "peer01-nid-peer0-asset_1.0", "dev-peer0-asset_1.0-" + hash} {
+		if _, _, ok := parseResourceName(name, peerIDs); ok {
+			t.Errorf("expected %s not to be a resource of the peers", name)
+		}
+	}
+}
+
+func TestGC(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writePeer(t, home, "peer0")
+	writePeer(t, home, "peer1")
+	hash := func(c string) string {
+		return strings.Repeat(c, 64)
+	}
+	image := func(peerID string, label string, c string) string {
+		return fmt.Sprintf("peer01-nid-%s-%s-%s-%s", peerID, label, hash(c), hash("f"))
+	}
+	containers := strings.Join([]string{
+		fmt.Sprintf("c1\tpeer01-nid-peer0-asset_1-%s\t%s\texited\t2024-01-01 10:00:00 +0000 UTC", hash("a"), image("peer0", "asset_1", "a")),
+		fmt.Sprintf("c2\tpeer01-nid-peer0-asset_2-%s\t%s\trunning\t2024-01-02 10:00:00 +0000 UTC", hash("b"), image("peer0", "asset_2", "b")),
+		fmt.Sprintf("c3\tpeer01-nid-peer1-asset_1-%s\t%s\texited\t2024-01-01 10:00:00 +0000 UTC", hash("a"), image("peer1", "asset_1", "a")),
+		fmt.Sprintf("c4\tpeer01-nid-peer9-asset_1-%s\t%s\texited\t2024-01-01 10:00:00 +0000 UTC", hash("a"), image("peer9", "asset_1", "a")),
+	}, "\n")
+	images := strings.Join([]string{
+		fmt.Sprintf("i1\t%s\t2024-01-01 09:00:00 +0000 UTC", image("peer0", "asset_1", "a")),
+		fmt.Sprintf("i2\t%s\t2024-01-02 09:00:00 +0000 UTC", image("peer0", "asset_2", "b")),
+		fmt.Sprintf("i3\t%s\t2024-01-03 09:00:00 +0000 UTC", image("peer0", "asset_3", "c")),
+		fmt.Sprintf("i4\t%s\t2024-01-01 09:00:00 +0000 UTC", image("peer0", "token_1", "d")),
+		fmt.Sprintf("i5\t%s\t2024-01-01 09:00:00 +0000 UTC", image("peer1", "asset_1", "a")),
+	}, "\n")
+	// the fake docker CLI prints the containers and the images and logs the
+	// removals
+	removedFile := filepath.Join(t.TempDir(), "removed")
+	defer func(execDockerCmd func(ctx context.Context, args ...string) *exec.Cmd) {
+		execDocker = execDockerCmd
+	}(execDocker)
+	execDocker = func(ctx context.Context, args ...string) *exec.Cmd {
+		output := ""
+		switch args[0] {
+		case "ps":
+			output = containers
+		case "images":
+			output = images
+		}
+		cmd := exec.CommandContext(ctx, "sh", "-c", `printf '%s\n' "$OUTPUT"; [ -z "$REMOVE" ] || echo "$REMOVE" >> "$REMOVED"`)
+		remove := ""
+		if args[0] == "rm" || args[0] == "rmi" {
+			remove = strings.Join(args, " ")
+		}
+		cmd.Env = append(os.Environ(), "OUTPUT="+output, "REMOVE="+remove, "REMOVED="+removedFile)
+		return cmd
+	}
+
+	ctx := context.Background()
+	stale, err := GC(ctx, GCOptions{PeerIDs: []string{"peer0"}, Names: []string{"asset"}, Keep: 1, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// asset_2 is kept for its running container and asset_3 as the newest
+	// image, token and peer1 are filtered out
+	ids := []string{}
+	for _, r := range stale {
+		ids = append(ids, r.ID)
+	}
+	if strings.Join(ids, " ") != "c1 i1" {
+		t.Fatalf("expected the exited container and the old image of asset, got %v", ids)
+	}
+	if _, err := os.Stat(removedFile); !os.IsNotExist(err) {
+		t.Fatal("expected a dry run to remove nothing")
+	}
+
+	removed, err := GC(ctx, GCOptions{Keep: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Fatalf("expected the exited containers and the old image of the peers to be removed, got %+v", removed)
+	}
+	removals, err := os.ReadFile(removedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := fmt.Sprintf("rm c1\nrm c3\nrmi %s\n", image("peer0", "asset_1", "a"))
+	if string(removals) != expected {
+		t.Fatalf("expected the containers to be removed before the images, got %s", removals)
+	}
+	if _, err := GC(ctx, GCOptions{PeerIDs: []string{"peer9"}, Keep: 1}); err == nil {
+		t.Fatal("expected a peer of another host to be refused")
+	}
+}
@@ -0,0 +1,293 @@
+package chaincode
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// the couchdb indexes in the code package of a chaincode, the indexes of a
+// collection are under collections/<name>
+var indexPathRegexp = regexp.MustCompile(`^META-INF/statedb/couchdb/(?:collections/([^/]+)/)?indexes/([^/]+)$`)
+
+// Index is a couchdb index of a chaincode package, the peers create it in the
+// database of the chaincode, or of its collection, once the chaincode is
+// committed
+type Index struct {
+	// Path of the index in the code package
+	Path string `json:"path"`
+	// Collection of the index, empty for the state of the chaincode
+	Collection string `json:"collection,omitempty"`
+	// DDoc and Name are the design document and the name of the index,
+	// CouchDB generates them when they're empty
+	DDoc   string   `json:"ddoc,omitempty"`
+	Name   string   `json:"name,omitempty"`
+	Fields []string `json:"fields"`
+	// Error is set when the index is invalid, the peers log it and skip the
+	// index
+	Error string `json:"error,omitempty"`
+}
+
+// ParseIndex validates the JSON of a couchdb index as the peers and CouchDB
+// do, the unknown fields are refused
+func ParseIndex(indexBytes []byte) (*Index, error) {
+	definition := map[string]json.RawMessage{}
+	if err := json.Unmarshal(indexBytes, &definition); err != nil {
+		return nil, errors.Wrap(err, "invalid JSON")
+	}
+	index := &Index{}
+	rawIndex, ok := definition["index"]
+	if !ok {
+		return nil, errors.New("the index field is required")
+	}
+	for key, value := range definition {
+		switch key {
+		case "index":
+		case "ddoc", "name":
+			s := ""
+			if err := json.Unmarshal(value, &s); err != nil || s == "" {
+				return nil, errors.Errorf("%s must be a non empty string", key)
+			}
+			if key == "ddoc" {
+				index.DDoc = s
+			} else {
+				index.Name = s
+			}
+		case "type":
+			s := ""
+			if err := json.Unmarshal(value, &s); err != nil || s != "json" {
+				return nil, errors.New("type must be json")
+			}
+		default:
+			return nil, errors.Errorf("unknown field %s", key)
+		}
+	}
+	fields, err := parseIndexFields(rawIndex)
+	if err != nil {
+		return nil, err
+	}
+	index.Fields = fields
+	return index, nil
+}
+
+// parseIndexFields returns the fields of an index, a field is its name or an
+// object with its name and the asc or desc sort direction
+func parseIndexFields(rawIndex json.RawMessage) ([]string, error) {
+	definition := map[string]json.RawMessage{}
+	if err := json.Unmarshal(rawIndex, &definition); err != nil {
+		return nil, errors.New("index must be an object")
+	}
+	for key := range definition {
+		if key != "fields" && key != "partial_filter_selector" {
+			return nil, errors.Errorf("unknown field index.%s", key)
+		}
+	}
+	rawFields := []json.RawMessage{}
+	if err := json.Unmarshal(definition["fields"], &rawFields); err != nil || len(rawFields) == 0 {
+		return nil, errors.New("index.fields must be a non empty array")
+	}
+	fields := []string{}
+	direction := ""
+	for _, rawField := range rawFields {
+		name := ""
+		if err := json.Unmarshal(rawField, &name); err == nil {
+			if name == "" {
+				return nil, errors.New("index.fields can't have an empty field")
+			}
+			fields = append(fields, name)
+			continue
+		}
+		sorted := map[string]string{}
+		if err := json.Unmarshal(rawField, &sorted); err != nil || len(sorted) != 1 {
+			return nil, errors.Errorf("invalid field %s, expected a name or {\"<name>\": \"asc\"}", rawField)
+		}
+		for name, fieldDirection := range sorted {
+			if fieldDirection != "asc" && fieldDirection != "desc" {
+				return nil, errors.Errorf("field %s must be sorted asc or desc", name)
+			}
+			// CouchDB refuses the indexes sorted in both directions
+			if direction != "" && fieldDirection != direction {
+				return nil, errors.New("the fields must be sorted in the same direction")
+			}
+			direction = fieldDirection
+			fields = append(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+// readTarGzFile returns the content of a file of a tar.gz archive
+func readTarGzFile(archive []byte, name string) ([]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.Errorf("%s not found", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// ReadIndexes returns the couchdb indexes of the code package of a chaincode
+// package, an invalid index is returned with its error
+func ReadIndexes(pkg []byte) ([]Index, error) {
+	code, err := readTarGzFile(pkg, "code.tar.gz")
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid chaincode package")
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(code))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid code package")
+	}
+	tr := tar.NewReader(gr)
+	indexes := []Index{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid code package")
+		}
+		path := strings.TrimPrefix(header.Name, "./")
+		match := indexPathRegexp.FindStringSubmatch(path)
+		if header.Typeflag != tar.TypeReg || match == nil {
+			continue
+		}
+		index := Index{Path: path, Collection: match[1]}
+		if !strings.HasSuffix(match[2], ".json") {
+			index.Error = "the indexes must be .json files"
+			indexes = append(indexes, index)
+			continue
+		}
+		indexBytes, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParseIndex(indexBytes)
+		if err != nil {
+			index.Error = err.Error()
+		} else {
+			index.DDoc, index.Name, index.Fields = parsed.DDoc, parsed.Name, parsed.Fields
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Path < indexes[j].Path
+	})
+	return indexes, nil
+}
+
+// couchDBIndex is an index listed by the _index endpoint of CouchDB
+type couchDBIndex struct {
+	DDoc string `json:"ddoc"`
+	Name string `json:"name"`
+	Def  struct {
+		Fields []map[string]string `json:"fields"`
+	} `json:"def"`
+}
+
+// listCouchDBIndexes returns the indexes of a database, none when the
+// database doesn't exist yet
+func listCouchDBIndexes(ctx context.Context, couchDBURL string, db string) ([]couchDBIndex, error) {
+	body, status, err := couchDBRequest(ctx, http.MethodGet, couchDBURL, url.PathEscape(db)+"/_index", nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, couchDBError(status, body)
+	}
+	list := struct {
+		Indexes []couchDBIndex `json:"indexes"`
+	}{}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, errors.Wrap(err, "invalid answer of CouchDB")
+	}
+	return list.Indexes, nil
+}
+
+// matches tells if an index of CouchDB is the index of the package, by its
+// name when it has one or by its fields
+func (index Index) matches(created couchDBIndex) bool {
+	if index.DDoc != "" && strings.TrimPrefix(created.DDoc, "_design/") != strings.TrimPrefix(index.DDoc, "_design/") {
+		return false
+	}
+	if index.Name != "" {
+		return created.Name == index.Name
+	}
+	if len(created.Def.Fields) != len(index.Fields) {
+		return false
+	}
+	for i, field := range created.Def.Fields {
+		if _, ok := field[index.Fields[i]]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// MissingIndexes returns the valid indexes of a chaincode not created in the
+// databases of a channel on CouchDB, the peers only log the indexes they fail
+// to create
+func MissingIndexes(ctx context.Context, couchDBURL string, channel string, chaincode string, indexes []Index) ([]Index, error) {
+	created := map[string][]couchDBIndex{}
+	missing := []Index{}
+	for _, index := range indexes {
+		if index.Error != "" {
+			continue
+		}
+		db := StateDBName(channel, chaincode, index.Collection)
+		if _, ok := created[db]; !ok {
+			dbIndexes, err := listCouchDBIndexes(ctx, couchDBURL, db)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list the indexes of database %s", db)
+			}
+			created[db] = dbIndexes
+		}
+		found := false
+		for _, c := range created[db] {
+			if index.matches(c) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, index)
+		}
+	}
+	return missing, nil
+}
+
+// DescribeIndex names an index by its collection and its name or its path
+func DescribeIndex(index Index) string {
+	name := index.Name
+	if name == "" {
+		name = index.Path
+	}
+	if index.Collection != "" {
+		return fmt.Sprintf("%s of collection %s", name, index.Collection)
+	}
+	return name
+}
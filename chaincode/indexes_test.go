@@ -0,0 +1,126 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseIndex(t *testing.T) {
+	index, err := ParseIndex([]byte(`{"index":{"fields":["docType","owner"]},"ddoc":"indexOwnerDoc","name":"indexOwner","type":"json"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.DDoc != "indexOwnerDoc" || index.Name != "indexOwner" || strings.Join(index.Fields, ",") != "docType,owner" {
+		t.Fatalf("unexpected index %+v", index)
+	}
+	index, err = ParseIndex([]byte(`{"index":{"fields":[{"size":"desc"},{"color":"desc"}]}}`))
+	if err != nil || strings.Join(index.Fields, ",") != "size,color" {
+		t.Fatalf("expected the sorted fields to be valid, got %+v, %v", index, err)
+	}
+	invalid := []string{
+		`{"index":`,
+		`{"ddoc":"indexOwnerDoc","name":"indexOwner"}`,
+		`{"index":{"fields":[]}}`,
+		`{"index":{"fields":["owner"],"selector":{}}}`,
+		`{"index":{"fields":["owner"]},"type":"text"}`,
+		`{"index":{"fields":["owner"]},"unique":true}`,
+		`{"index":{"fields":[{"owner":"up"}]}}`,
+		`{"index":{"fields":[{"size":"asc"},{"color":"desc"}]}}`,
+	}
+	for _, indexJSON := range invalid {
+		if _, err := ParseIndex([]byte(indexJSON)); err == nil {
+			t.Errorf("expected %s to be invalid", indexJSON)
+		}
+	}
+}
+
+func testIndexesPackage(t *testing.T) []byte {
+	t.Helper()
+	code := map[string][]byte{
+		"main.go": []byte("package main"),
+		"META-INF/statedb/couchdb/indexes/indexOwner.json":                     []byte(`{"index":{"fields":["docType","owner"]},"ddoc":"indexOwnerDoc","name":"indexOwner","type":"json"}`),
+		"META-INF/statedb/couchdb/indexes/indexSize.json":                      []byte(`{"index":{"fields":["size"]}}`),
+		"META-INF/statedb/couchdb/indexes/indexColor.txt":                      []byte(`{"index":{"fields":["color"]}}`),
+		"META-INF/statedb/couchdb/collections/private/indexes/indexPrice.json": []byte(`{"index":{"fields":["price"]},"name":"indexPrice","type":"text"}`),
+	}
+	order := []string{}
+	for name := range code {
+		order = append(order, name)
+	}
+	codeTarGz, err := writeTarGz(code, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := writeTarGz(map[string][]byte{
+		"metadata.json": []byte(`{"type":"golang","label":"asset_1.0"}`),
+		"code.tar.gz":   codeTarGz,
+	}, []string{"metadata.json", "code.tar.gz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+func TestReadIndexes(t *testing.T) {
+	indexes, err := ReadIndexes(testIndexesPackage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 4 {
+		t.Fatalf("expected 4 indexes, got %+v", indexes)
+	}
+	price, color, owner, size := indexes[0], indexes[1], indexes[2], indexes[3]
+	if price.Collection != "private" || price.Error == "" {
+		t.Fatalf("expected the text index of the collection to be invalid, got %+v", price)
+	}
+	if color.Error == "" {
+		t.Fatalf("expected an index without the .json extension to be invalid, got %+v", color)
+	}
+	if owner.Error != "" || owner.Name != "indexOwner" || owner.Collection != "" {
+		t.Fatalf("unexpected index %+v", owner)
+	}
+	if size.Error != "" || size.Name != "" || len(size.Fields) != 1 {
+		t.Fatalf("unexpected index %+v", size)
+	}
+	if _, err := ReadIndexes([]byte("not a package")); err == nil {
+		t.Fatal("expected an invalid package to fail")
+	}
+}
+
+func TestMissingIndexes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/mychannel_asset/_index":
+			fmt.Fprint(w, `{"indexes":[{"ddoc":null,"name":"_all_docs","type":"special","def":{"fields":[{"_id":"asc"}]}},`+
+				`{"ddoc":"_design/a5f4711fc9448864a13c81dc71e660b524d7410c","name":"a5f4711fc9448864a13c81dc71e660b524d7410c","type":"json","def":{"fields":[{"size":"asc"}]}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not_found","reason":"Database does not exist."}`)
+		}
+	}))
+	defer server.Close()
+	indexes, err := ReadIndexes(testIndexesPackage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	couchDBURL := strings.Replace(server.URL, "http://", "http://admin:secret@", 1)
+	missing, err := MissingIndexes(context.Background(), couchDBURL, "mychannel", "asset", indexes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 1 || missing[0].Name != "indexOwner" {
+		t.Fatalf("expected only indexOwner to be missing, got %+v", missing)
+	}
+	_, err = MissingIndexes(context.Background(), server.URL, "mychannel", "asset", indexes)
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Fatalf("expected the unauthorized request to fail without the credentials, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+const (
+	// TypeCCaaS is a chaincode run as a service by its operator
+	TypeCCaaS = "ccaas"
+	// TypeDocker is a chaincode run as a service in a docker container started by hlf-easy
+	TypeDocker = "docker"
+)
+
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.+-]*$`)
+
+// Limits tune how a chaincode runs, they're applied when it's deployed
+type Limits struct {
+	// ExecuteTimeout is passed to the chaincode server as
+	// CHAINCODE_EXECUTE_TIMEOUT, the server aborts the invocations that take
+	// longer. It must be lower than the executetimeout of the peer
+	ExecuteTimeout string `json:"executeTimeout,omitempty"`
+	// DialTimeout is the time the peer waits to connect to the chaincode server
+	DialTimeout string `json:"dialTimeout,omitempty"`
+	// MemoryMB and CPUs limit the container of docker chaincodes
+	MemoryMB int64   `json:"memoryMB,omitempty"`
+	CPUs     float64 `json:"cpus,omitempty"`
+}
+
+// Definition is a chaincode stored in the registry of the host
+type Definition struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Type    string `json:"type"`
+	// Address of the chaincode server, host:port
+	Address string `json:"address"`
+	// Image of docker chaincodes
+	Image  string `json:"image,omitempty"`
+	Limits Limits `json:"limits"`
+	// Collections are the private data collections of the chaincode, passed
+	// as the collections config of its definition
+	Collections []Collection `json:"collections,omitempty"`
+}
+
+// Label is the label of the chaincode package
+func (d Definition) Label() string {
+	return fmt.Sprintf("%s_%s", d.Name, d.Version)
+}
+
+func parseTimeout(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid %s %s", name, value)
+	}
+	if d <= 0 {
+		return 0, errors.Errorf("%s must be greater than 0", name)
+	}
+	return d, nil
+}
+
+// Validate checks the definition and its limits
+func (d Definition) Validate() error {
+	if !nameRegexp.MatchString(d.Name) {
+		return errors.Errorf("invalid chaincode name %q", d.Name)
+	}
+	if !nameRegexp.MatchString(d.Version) {
+		return errors.Errorf("invalid chaincode version %q", d.Version)
+	}
+	if d.Address == "" {
+		return errors.New("address of the chaincode server is required")
+	}
+	switch d.Type {
+	case TypeCCaaS:
+		if d.Limits.MemoryMB != 0 || d.Limits.CPUs != 0 {
+			return errors.New("memory and CPU limits only apply to docker chaincodes")
+		}
+	case TypeDocker:
+		if d.Image == "" {
+			return errors.New("image is required for docker chaincodes")
+		}
+	default:
+		return errors.Errorf("unknown chaincode type %q, valid types are %s and %s", d.Type, TypeCCaaS, TypeDocker)
+	}
+	if _, err := parseTimeout("execute timeout", d.Limits.ExecuteTimeout); err != nil {
+		return err
+	}
+	if _, err := parseTimeout("dial timeout", d.Limits.DialTimeout); err != nil {
+		return err
+	}
+	if d.Limits.MemoryMB < 0 || d.Limits.CPUs < 0 {
+		return errors.New("memory and CPU limits can't be negative")
+	}
+	if _, err := ValidateCollections(d.Collections, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func getRegistryDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/chaincodes"), nil
+}
+
+// Save stores the definition in the registry, replacing the existing one
+func Save(d Definition) error {
+	err := d.Validate()
+	if err != nil {
+		return err
+	}
+	registryDir, err := getRegistryDir()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(registryDir, 0755)
+	if err != nil {
+		return err
+	}
+	definitionBytes, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(registryDir, d.Name+".json"), definitionBytes, 0644)
+}
+
+// Get returns the definition of a chaincode of the registry
+func Get(name string) (*Definition, error) {
+	if !nameRegexp.MatchString(name) {
+		return nil, errors.Errorf("invalid chaincode name %q", name)
+	}
+	registryDir, err := getRegistryDir()
+	if err != nil {
+		return nil, err
+	}
+	definitionBytes, err := os.ReadFile(filepath.Join(registryDir, name+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Errorf("chaincode %s is not in the registry", name)
+		}
+		return nil, err
+	}
+	d := &Definition{}
+	err = json.Unmarshal(definitionBytes, d)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Remove removes the definition of a chaincode from the registry
+func Remove(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return errors.Errorf("invalid chaincode name %q", name)
+	}
+	registryDir, err := getRegistryDir()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(registryDir, name+".json"))
+	if os.IsNotExist(err) {
+		return errors.Errorf("chaincode %s is not in the registry", name)
+	}
+	return err
+}
+
+// List returns the definitions of all the chaincodes of the registry
+func List() ([]Definition, error) {
+	registryDir, err := getRegistryDir()
+	if err != nil {
+		return nil, err
+	}
+	definitionFiles, err := filepath.Glob(filepath.Join(registryDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	definitions := []Definition{}
+	for _, definitionFile := range definitionFiles {
+		definitionBytes, err := os.ReadFile(definitionFile)
+		if err != nil {
+			return nil, err
+		}
+		d := Definition{}
+		err = json.Unmarshal(definitionBytes, &d)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", definitionFile)
+		}
+		definitions = append(definitions, d)
+	}
+	return definitions, nil
+}
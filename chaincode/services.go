@@ -0,0 +1,249 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Service is a chaincode server run as a service, on this host or another
+// one, that the peers of the host connect to
+type Service struct {
+	// Name of the chaincode of the registry
+	Name string `json:"name"`
+	// Address of the chaincode server, host:port
+	Address string `json:"address"`
+	// RootCert is the PEM of the TLS CA of the chaincode server, TLS is
+	// disabled if empty
+	RootCert string `json:"rootCert,omitempty"`
+	// ClientAuth makes the peers connect with their TLS certificates
+	ClientAuth bool      `json:"clientAuth,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Validate checks the service
+func (s Service) Validate() error {
+	if !nameRegexp.MatchString(s.Name) {
+		return errors.Errorf("invalid chaincode name %q", s.Name)
+	}
+	if _, _, err := net.SplitHostPort(s.Address); err != nil {
+		return errors.Wrapf(err, "invalid address %s", s.Address)
+	}
+	if s.ClientAuth && s.RootCert == "" {
+		return errors.New("client authentication requires the TLS CA of the chaincode server")
+	}
+	return nil
+}
+
+func getServicesDir() (string, error) {
+	registryDir, err := getRegistryDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(registryDir, "services"), nil
+}
+
+func getPeersDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/peers"), nil
+}
+
+// GetPeerConnectionFile is the connection.json the builder of hlf-easy
+// releases for a chaincode package in a peer
+func GetPeerConnectionFile(peerDir string, label string) string {
+	return filepath.Join(peerDir, "chaincodes", label, "connection.json")
+}
+
+// SaveService stores the service in the registry, replacing the existing one
+func SaveService(s Service) error {
+	err := s.Validate()
+	if err != nil {
+		return err
+	}
+	servicesDir, err := getServicesDir()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(servicesDir, 0755)
+	if err != nil {
+		return err
+	}
+	serviceBytes, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(servicesDir, s.Name+".json"), serviceBytes, 0644)
+}
+
+// RemoveService removes the service from the registry
+func RemoveService(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return errors.Errorf("invalid chaincode name %q", name)
+	}
+	servicesDir, err := getServicesDir()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(servicesDir, name+".json"))
+	if os.IsNotExist(err) {
+		return errors.Errorf("chaincode service %s is not in the registry", name)
+	}
+	return err
+}
+
+// ListServices returns the services of the registry
+func ListServices() ([]Service, error) {
+	servicesDir, err := getServicesDir()
+	if err != nil {
+		return nil, err
+	}
+	serviceFiles, err := filepath.Glob(filepath.Join(servicesDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	services := []Service{}
+	for _, serviceFile := range serviceFiles {
+		serviceBytes, err := os.ReadFile(serviceFile)
+		if err != nil {
+			return nil, err
+		}
+		s := Service{}
+		err = json.Unmarshal(serviceBytes, &s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", serviceFile)
+		}
+		services = append(services, s)
+	}
+	return services, nil
+}
+
+// PeerConnection builds the connection.json of a service for a peer, the
+// peer authenticates with its TLS certificate when the service requires it
+func PeerConnection(d Definition, s Service, peerConfig config.PeerConfig) ([]byte, error) {
+	dialTimeout := d.Limits.DialTimeout
+	if dialTimeout == "" {
+		dialTimeout = defaultDialTimeout
+	}
+	c := connection{
+		Address:     s.Address,
+		DialTimeout: dialTimeout,
+		TLSRequired: s.RootCert != "",
+		RootCert:    s.RootCert,
+	}
+	if s.ClientAuth {
+		if len(peerConfig.TLSKey) == 0 || len(peerConfig.TLSCert) == 0 {
+			return nil, errors.Errorf("peer %s has no TLS certificate", peerConfig.PeerID)
+		}
+		c.ClientAuthRequired = true
+		c.ClientKey = string(peerConfig.TLSKey)
+		c.ClientCert = string(peerConfig.TLSCert)
+	}
+	return json.MarshalIndent(c, "", "  ")
+}
+
+// SyncResult is the connection.json written for a chaincode in a peer
+type SyncResult struct {
+	Peer    string `json:"peer"`
+	Label   string `json:"label"`
+	Address string `json:"address"`
+	Changed bool   `json:"changed"`
+}
+
+type registeredService struct {
+	definition Definition
+	service    Service
+}
+
+// SyncPeers writes the connection.json of the services of the registry in
+// all the peers of the host and removes the ones of the services that are no
+// longer registered. The peers connect to the new addresses the next time
+// they launch the chaincodes
+func SyncPeers() ([]SyncResult, error) {
+	services, err := ListServices()
+	if err != nil {
+		return nil, err
+	}
+	connections := map[string]registeredService{}
+	for _, s := range services {
+		d, err := Get(s.Name)
+		if err != nil {
+			return nil, err
+		}
+		connections[d.Label()] = registeredService{definition: *d, service: s}
+	}
+	labels := []string{}
+	for label := range connections {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	peersDir, err := getPeersDir()
+	if err != nil {
+		return nil, err
+	}
+	peerConfigFiles, err := filepath.Glob(filepath.Join(peersDir, "*", "config.json"))
+	if err != nil {
+		return nil, err
+	}
+	results := []SyncResult{}
+	for _, peerConfigFile := range peerConfigFiles {
+		peerDir := filepath.Dir(peerConfigFile)
+		peerConfigBytes, err := os.ReadFile(peerConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		peerConfig := config.PeerConfig{}
+		err = json.Unmarshal(peerConfigBytes, &peerConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", peerConfigFile)
+		}
+		for _, label := range labels {
+			c := connections[label]
+			connectionBytes, err := PeerConnection(c.definition, c.service, peerConfig)
+			if err != nil {
+				return nil, err
+			}
+			connectionFile := GetPeerConnectionFile(peerDir, label)
+			existing, _ := os.ReadFile(connectionFile)
+			changed := string(existing) != string(connectionBytes)
+			if changed {
+				err = os.MkdirAll(filepath.Dir(connectionFile), 0700)
+				if err != nil {
+					return nil, err
+				}
+				// the connection.json holds the TLS key of the peer
+				err = os.WriteFile(connectionFile, connectionBytes, 0600)
+				if err != nil {
+					return nil, err
+				}
+			}
+			results = append(results, SyncResult{
+				Peer:    filepath.Base(peerDir),
+				Label:   label,
+				Address: c.service.Address,
+				Changed: changed,
+			})
+		}
+		staleDirs, err := filepath.Glob(filepath.Join(peerDir, "chaincodes", "*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, staleDir := range staleDirs {
+			if _, ok := connections[filepath.Base(staleDir)]; ok {
+				continue
+			}
+			err = os.RemoveAll(staleDir)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,208 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"hlf-easy/plan"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePeer(t *testing.T, home string, id string) string {
+	t.Helper()
+	peerDir := filepath.Join(home, "hlf-easy/peers", id)
+	err := os.MkdirAll(peerDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerConfigBytes, err := json.Marshal(config.PeerConfig{
+		PeerID:  id,
+		TLSKey:  []byte("key of " + id),
+		TLSCert: []byte("cert of " + id),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(peerDir, "config.json"), peerConfigBytes, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return peerDir
+}
+
+func readConnection(t *testing.T, connectionFile string) connection {
+	t.Helper()
+	connectionBytes, err := os.ReadFile(connectionFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := connection{}
+	err = json.Unmarshal(connectionBytes, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestServiceValidate(t *testing.T) {
+	valid := Service{Name: "asset", Address: "cc.example.com:9999"}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	invalid := []Service{
+		{Name: "../asset", Address: "cc.example.com:9999"},
+		{Name: "asset", Address: "cc.example.com"},
+		{Name: "asset", Address: "cc.example.com:9999", ClientAuth: true},
+	}
+	for _, s := range invalid {
+		if err := s.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", s)
+		}
+	}
+}
+
+func TestSyncPeers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peer0Dir := writePeer(t, home, "peer0")
+	peer1Dir := writePeer(t, home, "peer1")
+	d := Definition{Name: "asset", Version: "1.0", Type: TypeCCaaS, Address: "localhost:9999", Limits: Limits{DialTimeout: "5s"}}
+	if err := Save(d); err != nil {
+		t.Fatal(err)
+	}
+	err := SaveService(Service{Name: "asset", Address: "cc1.example.com:9999", RootCert: "root cert", ClientAuth: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := SyncPeers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || !results[0].Changed || results[1].Peer != "peer1" || results[1].Label != "asset_1.0" {
+		t.Fatalf("expected the connection of asset_1.0 written in both peers, got %+v", results)
+	}
+	c := readConnection(t, GetPeerConnectionFile(peer1Dir, "asset_1.0"))
+	expected := connection{
+		Address:            "cc1.example.com:9999",
+		DialTimeout:        "5s",
+		TLSRequired:        true,
+		ClientAuthRequired: true,
+		ClientKey:          "key of peer1",
+		ClientCert:         "cert of peer1",
+		RootCert:           "root cert",
+	}
+	if c != expected {
+		t.Errorf("expected %+v, got %+v", expected, c)
+	}
+
+	// the service moves to another host
+	err = SaveService(Service{Name: "asset", Address: "cc2.example.com:9999"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = SyncPeers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !results[0].Changed {
+		t.Errorf("expected the connection of peer0 to change, got %+v", results)
+	}
+	c = readConnection(t, GetPeerConnectionFile(peer0Dir, "asset_1.0"))
+	if c.Address != "cc2.example.com:9999" || c.TLSRequired || c.ClientKey != "" {
+		t.Errorf("expected a plaintext connection to cc2, got %+v", c)
+	}
+	results, err = SyncPeers()
+	if err != nil || results[0].Changed {
+		t.Errorf("expected the connections to be unchanged, got %+v: %v", results, err)
+	}
+
+	// a new version of the chaincode has another label
+	d.Version = "2.0"
+	if err := Save(d); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SyncPeers(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(peer0Dir, "chaincodes/asset_1.0")); !os.IsNotExist(err) {
+		t.Errorf("expected the connection of asset_1.0 to be removed: %v", err)
+	}
+	if err := RemoveService("asset"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SyncPeers(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(peer0Dir, "chaincodes/asset_2.0")); !os.IsNotExist(err) {
+		t.Errorf("expected the connection of asset_2.0 to be removed: %v", err)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peerDir := writePeer(t, home, "peer0")
+	err := WriteBuilder(plan.Disk, peerDir, "/usr/local/bin/hlf-easy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := os.ReadFile(filepath.Join(GetBuilderDir(peerDir), "bin/release"))
+	if err != nil || !strings.Contains(string(script), "chaincode builder release --peer-dir") {
+		t.Fatalf("unexpected release script %s: %v", script, err)
+	}
+
+	metadataDir := t.TempDir()
+	metadataBytes, _ := json.Marshal(metadata{Type: "ccaas", Label: "asset_1.0"})
+	err = os.WriteFile(filepath.Join(metadataDir, "metadata.json"), metadataBytes, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// packages without a registered service are left to the ccaas_builder
+	detected, err := Detect(peerDir, metadataDir)
+	if err != nil || detected {
+		t.Fatalf("expected the package not to be detected: %v", err)
+	}
+	connectionFile := GetPeerConnectionFile(peerDir, "asset_1.0")
+	os.MkdirAll(filepath.Dir(connectionFile), 0700)
+	err = os.WriteFile(connectionFile, []byte(`{"address":"cc1.example.com:9999"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	detected, err = Detect(peerDir, metadataDir)
+	if err != nil || !detected {
+		t.Fatalf("expected the package to be detected: %v", err)
+	}
+
+	buildDir, releaseDir := t.TempDir(), t.TempDir()
+	if err := Build(metadataDir, buildDir); err != nil {
+		t.Fatal(err)
+	}
+	// the service moved between the build and the release
+	err = os.WriteFile(connectionFile, []byte(`{"address":"cc2.example.com:9999"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Release(peerDir, buildDir, releaseDir); err != nil {
+		t.Fatal(err)
+	}
+	c := readConnection(t, filepath.Join(releaseDir, "chaincode/server/connection.json"))
+	if c.Address != "cc2.example.com:9999" {
+		t.Errorf("expected the released connection to cc2, got %+v", c)
+	}
+}
+
+func TestBuilderScript(t *testing.T) {
+	name, script := builderScript("linux", "/usr/local/bin/hlf-easy", "detect", "/home/user/hlf-easy/peers/peer0")
+	if name != "detect" || !strings.HasPrefix(script, "#!/bin/sh\n") {
+		t.Errorf("unexpected script %s: %s", name, script)
+	}
+	// the peer runs detect.cmd on Windows, the paths may have spaces
+	name, script = builderScript("windows", `C:\Program Files\hlf-easy.exe`, "detect", `C:\Users\dev\hlf-easy\peers\peer0`)
+	expected := "@\"C:\\Program Files\\hlf-easy.exe\" chaincode builder detect --peer-dir \"C:\\Users\\dev\\hlf-easy\\peers\\peer0\" %*\r\n"
+	if name != "detect.cmd" || script != expected {
+		t.Errorf("unexpected script %s: %q", name, script)
+	}
+}
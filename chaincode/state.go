@@ -0,0 +1,217 @@
+package chaincode
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultStateQueryLimit is the number of keys a rich query returns without a
+// limit
+const DefaultStateQueryLimit = 25
+
+// StateDBName returns the CouchDB database of the state of a chaincode on a
+// channel, or of one of its collections. The upper case letters are escaped
+// as the peers do, the names long enough to be truncated by the peers aren't
+// supported
+func StateDBName(channel string, chaincode string, collection string) string {
+	namespace := chaincode
+	if collection != "" {
+		namespace += "$$p" + collection
+	}
+	escaped := strings.Builder{}
+	for _, r := range namespace {
+		if unicode.IsUpper(r) {
+			escaped.WriteRune('$')
+			escaped.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		escaped.WriteRune(r)
+	}
+	return strings.ToLower(channel) + "_" + escaped.String()
+}
+
+var couchDBClient = &http.Client{Timeout: 30 * time.Second}
+
+// couchDBRequest sends a request to CouchDB and returns the body and the
+// status of its answer. The credentials of the URL are sent in a header so
+// the errors don't have them
+func couchDBRequest(ctx context.Context, method string, couchDBURL string, path string, body []byte) ([]byte, int, error) {
+	u, err := url.Parse(strings.TrimSuffix(couchDBURL, "/") + "/" + path)
+	if err != nil {
+		return nil, 0, errors.New("invalid CouchDB URL")
+	}
+	user := u.User
+	u.User = nil
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reqBody)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+	resp, err := couchDBClient.Do(req)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "request to CouchDB failed")
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return respBody, resp.StatusCode, nil
+}
+
+func couchDBError(status int, body []byte) error {
+	return errors.Errorf("CouchDB returned %d %s: %s", status, http.StatusText(status), strings.TrimSpace(string(body)))
+}
+
+// StateEntry is a key of the state of a chaincode and its value
+type StateEntry struct {
+	Key string `json:"key"`
+	// Value is the value of the key when it's JSON
+	Value json.RawMessage `json:"value,omitempty"`
+	// Bytes is the value of the key when it isn't JSON, the peers store it
+	// as an attachment
+	Bytes []byte `json:"bytes,omitempty"`
+}
+
+// String returns the value of the entry, a binary value is encoded in base64
+func (e StateEntry) String() string {
+	if e.Value != nil {
+		return string(e.Value)
+	}
+	if json.Valid(e.Bytes) || isPrintable(e.Bytes) {
+		return string(e.Bytes)
+	}
+	return base64.StdEncoding.EncodeToString(e.Bytes)
+}
+
+func isPrintable(b []byte) bool {
+	for _, r := range string(b) {
+		if r == unicode.ReplacementChar || (!unicode.IsPrint(r) && !unicode.IsSpace(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// stateEntry converts a document of the state database to its key and value,
+// the fields the peers add to the document are removed
+func stateEntry(doc map[string]json.RawMessage) (StateEntry, error) {
+	entry := StateEntry{}
+	if err := json.Unmarshal(doc["_id"], &entry.Key); err != nil {
+		return entry, errors.Wrap(err, "invalid document")
+	}
+	attachments := map[string]struct {
+		Data []byte `json:"data"`
+	}{}
+	if rawAttachments, ok := doc["_attachments"]; ok {
+		if err := json.Unmarshal(rawAttachments, &attachments); err != nil {
+			return entry, errors.Wrapf(err, "invalid attachments of key %s", entry.Key)
+		}
+	}
+	for _, field := range []string{"_id", "_rev", "~version", "_attachments"} {
+		delete(doc, field)
+	}
+	if attachment, ok := attachments["valueBytes"]; ok {
+		entry.Bytes = attachment.Data
+		return entry, nil
+	}
+	value, err := json.Marshal(doc)
+	if err != nil {
+		return entry, err
+	}
+	entry.Value = value
+	return entry, nil
+}
+
+// GetState reads a key of the state of a chaincode, or of one of its
+// collections, from the CouchDB of a peer
+func GetState(ctx context.Context, couchDBURL string, channel string, chaincode string, collection string, key string) (*StateEntry, error) {
+	db := StateDBName(channel, chaincode, collection)
+	body, status, err := couchDBRequest(ctx, http.MethodGet, couchDBURL, url.PathEscape(db)+"/"+url.PathEscape(key)+"?attachments=true", nil)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, errors.Errorf("key %s isn't in database %s", key, db)
+	}
+	if status != http.StatusOK {
+		return nil, couchDBError(status, body)
+	}
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, errors.Wrap(err, "invalid answer of CouchDB")
+	}
+	entry, err := stateEntry(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// StateQuery returns the query of a rich query of the state, a selector or a
+// query with a selector. The limit is added when the query has none
+func StateQuery(query string, limit int) ([]byte, error) {
+	parsed := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(query), &parsed); err != nil {
+		return nil, errors.Wrap(err, "the query must be a JSON object")
+	}
+	if _, ok := parsed["selector"]; !ok {
+		parsed = map[string]json.RawMessage{"selector": json.RawMessage(query)}
+	}
+	if _, ok := parsed["limit"]; !ok && limit > 0 {
+		parsed["limit"] = json.RawMessage(strconv.Itoa(limit))
+	}
+	return json.Marshal(parsed)
+}
+
+// QueryState runs a rich query on the state of a chaincode, or of one of its
+// collections, in the CouchDB of a peer
+func QueryState(ctx context.Context, couchDBURL string, channel string, chaincode string, collection string, query []byte) ([]StateEntry, error) {
+	db := StateDBName(channel, chaincode, collection)
+	body, status, err := couchDBRequest(ctx, http.MethodPost, couchDBURL, url.PathEscape(db)+"/_find", query)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, errors.Errorf("database %s doesn't exist, the chaincode has no state on the peer", db)
+	}
+	if status != http.StatusOK {
+		return nil, couchDBError(status, body)
+	}
+	result := struct {
+		Docs []map[string]json.RawMessage `json:"docs"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, errors.Wrap(err, "invalid answer of CouchDB")
+	}
+	entries := []StateEntry{}
+	for _, doc := range result.Docs {
+		entry, err := stateEntry(doc)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
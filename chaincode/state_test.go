@@ -0,0 +1,108 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStateDBName(t *testing.T) {
+	if db := StateDBName("mychannel", "asset", ""); db != "mychannel_asset" {
+		t.Fatalf("unexpected database %s", db)
+	}
+	if db := StateDBName("mychannel", "assetTransfer", "Org1Private"); db != "mychannel_asset$transfer$$p$org1$private" {
+		t.Fatalf("unexpected database %s", db)
+	}
+}
+
+func TestStateQuery(t *testing.T) {
+	query, err := StateQuery(`{"docType":"asset","owner":"Tom"}`, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(query) != `{"limit":10,"selector":{"docType":"asset","owner":"Tom"}}` {
+		t.Fatalf("expected the selector to be wrapped in a query with the limit, got %s", query)
+	}
+	query, err = StateQuery(`{"selector":{"owner":"Tom"},"limit":2,"sort":[{"size":"asc"}]}`, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(query) != `{"limit":2,"selector":{"owner":"Tom"},"sort":[{"size":"asc"}]}` {
+		t.Fatalf("expected the query to keep its limit, got %s", query)
+	}
+	if _, err := StateQuery(`["owner"]`, 10); err == nil {
+		t.Fatal("expected a query that isn't an object to be invalid")
+	}
+}
+
+func newStateServer(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/mychannel_asset/asset1":
+			fmt.Fprint(w, `{"_id":"asset1","_rev":"1-a","~version":"CgMBAgA=","owner":"Tom","size":5}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/mychannel_asset$$pprivate/counter":
+			if r.URL.Query().Get("attachments") != "true" {
+				t.Errorf("expected the attachments to be requested")
+			}
+			fmt.Fprint(w, `{"_id":"counter","_rev":"1-b","~version":"CgMBAwA=","_attachments":{"valueBytes":{"content_type":"application/octet-stream","data":"NDI="}}}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/mychannel_asset/_find":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"limit":25,"selector":{"owner":"Tom"}}` {
+				t.Errorf("unexpected query %s", body)
+			}
+			fmt.Fprint(w, `{"docs":[{"_id":"asset1","_rev":"1-a","~version":"CgMBAgA=","owner":"Tom","size":5}],"bookmark":"nil"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"not_found","reason":"missing"}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestGetState(t *testing.T) {
+	couchDBURL := newStateServer(t)
+	entry, err := GetState(context.Background(), couchDBURL, "mychannel", "asset", "", "asset1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Key != "asset1" || entry.String() != `{"owner":"Tom","size":5}` {
+		t.Fatalf("expected the value without the fields of the peer, got %+v", entry)
+	}
+	entry, err = GetState(context.Background(), couchDBURL, "mychannel", "asset", "private", "counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Value != nil || entry.String() != "42" {
+		t.Fatalf("expected the value of the attachment, got %+v", entry)
+	}
+	if _, err := GetState(context.Background(), couchDBURL, "mychannel", "asset", "", "missing"); err == nil {
+		t.Fatal("expected a missing key to fail")
+	}
+	if s := (StateEntry{Bytes: []byte{0xff, 0x00}}).String(); s != "/wA=" {
+		t.Fatalf("expected a binary value in base64, got %s", s)
+	}
+}
+
+func TestQueryState(t *testing.T) {
+	couchDBURL := newStateServer(t)
+	query, err := StateQuery(`{"owner":"Tom"}`, DefaultStateQueryLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := QueryState(context.Background(), couchDBURL, "mychannel", "asset", "", query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Key != "asset1" || !strings.Contains(entries[0].String(), `"size":5`) {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if _, err := QueryState(context.Background(), couchDBURL, "other", "asset", "", query); err == nil {
+		t.Fatal("expected a query of a missing database to fail")
+	}
+}
@@ -0,0 +1,138 @@
+package channel
+
+import (
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	ob "github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/orderer/smartbft"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+)
+
+// ConsensusTypeBFT is the SmartBFT consensus of the ordering services from
+// Fabric 3.0
+const ConsensusTypeBFT = "BFT"
+
+// bftCapability is the channel and orderer capability required by SmartBFT
+const bftCapability = "V3_0"
+
+// DefaultSmartBFTOptions are the SmartBFT options of the sample configtx.yaml
+// of Fabric 3.0
+var DefaultSmartBFTOptions = &smartbft.Options{
+	RequestBatchMaxCount:      100,
+	RequestBatchMaxBytes:      10 * 1024 * 1024,
+	RequestBatchMaxInterval:   "50ms",
+	IncomingMessageBufferSize: 200,
+	RequestPoolSize:           100000,
+	RequestForwardTimeout:     "2s",
+	RequestComplainTimeout:    "20s",
+	RequestAutoRemoveTimeout:  "3m0s",
+	ViewChangeResendInterval:  "5s",
+	ViewChangeTimeout:         "20s",
+	LeaderHeartbeatTimeout:    "1m0s",
+	LeaderHeartbeatCount:      10,
+	CollectTimeout:            "1s",
+}
+
+// BFTQuorum returns how many of the n consenters of a BFT ordering service
+// must sign a block, it tolerates (n-1)/3 faulty consenters
+func BFTQuorum(n int) int {
+	f := (n - 1) / 3
+	// ceil((n+f+1)/2)
+	return (n + f + 2) / 2
+}
+
+// bftConsenters maps the orderers of the bundle to their consenter ID and
+// identity
+func bftConsenters(bundle *config.OrdererBundle) []*cb.Consenter {
+	var consenters []*cb.Consenter
+	for i, o := range bundle.Orderers {
+		consenters = append(consenters, &cb.Consenter{
+			Id:            o.ConsenterID(i),
+			Host:          o.Host,
+			Port:          uint32(o.Port),
+			MspId:         bundle.MSPID,
+			Identity:      []byte(o.Identity),
+			ClientTlsCert: []byte(o.TLSCert),
+			ServerTlsCert: []byte(o.TLSCert),
+		})
+	}
+	return consenters
+}
+
+// setBFTConsensus replaces the etcdraft consensus of the orderer group
+// generated by configtx with SmartBFT: the consensus type, the consenter
+// mapping and a block validation policy requiring the signatures of a quorum
+// of consenters, like configtxgen of Fabric 3.0 does
+func setBFTConsensus(c *cb.Config, bundle *config.OrdererBundle) error {
+	ordererGroup, ok := c.ChannelGroup.Groups["Orderer"]
+	if !ok {
+		return errors.New("the config has no orderer group")
+	}
+	consenters := bftConsenters(bundle)
+	metadata, err := proto.Marshal(DefaultSmartBFTOptions)
+	if err != nil {
+		return err
+	}
+	err = setConfigValue(ordererGroup, "ConsensusType", &ob.ConsensusType{
+		Type:     ConsensusTypeBFT,
+		Metadata: metadata,
+		State:    ob.ConsensusType_STATE_NORMAL,
+	})
+	if err != nil {
+		return err
+	}
+	err = setConfigValue(ordererGroup, "Orderers", &cb.Orderers{ConsenterMapping: consenters})
+	if err != nil {
+		return err
+	}
+
+	var rules []*cb.SignaturePolicy
+	var identities []*mb.MSPPrincipal
+	for i, consenter := range consenters {
+		principal, err := proto.Marshal(&mb.SerializedIdentity{Mspid: consenter.MspId, IdBytes: consenter.Identity})
+		if err != nil {
+			return err
+		}
+		identities = append(identities, &mb.MSPPrincipal{
+			PrincipalClassification: mb.MSPPrincipal_IDENTITY,
+			Principal:               principal,
+		})
+		rules = append(rules, &cb.SignaturePolicy{Type: &cb.SignaturePolicy_SignedBy{SignedBy: int32(i)}})
+	}
+	policy, err := proto.Marshal(&cb.SignaturePolicyEnvelope{
+		Rule: &cb.SignaturePolicy{Type: &cb.SignaturePolicy_NOutOf_{NOutOf: &cb.SignaturePolicy_NOutOf{
+			N:     int32(BFTQuorum(len(consenters))),
+			Rules: rules,
+		}}},
+		Identities: identities,
+	})
+	if err != nil {
+		return err
+	}
+	modPolicy := "Admins"
+	if existing, ok := ordererGroup.Policies["BlockValidation"]; ok {
+		modPolicy = existing.ModPolicy
+	}
+	ordererGroup.Policies["BlockValidation"] = &cb.ConfigPolicy{
+		ModPolicy: modPolicy,
+		Policy:    &cb.Policy{Type: int32(cb.Policy_SIGNATURE), Value: policy},
+	}
+	return nil
+}
+
+// setConfigValue sets a value of a config group, an existing value keeps its
+// mod policy
+func setConfigValue(group *cb.ConfigGroup, key string, value proto.Message) error {
+	valueBytes, err := proto.Marshal(value)
+	if err != nil {
+		return err
+	}
+	modPolicy := "Admins"
+	if existing, ok := group.Values[key]; ok {
+		modPolicy = existing.ModPolicy
+	}
+	group.Values[key] = &cb.ConfigValue{Value: valueBytes, ModPolicy: modPolicy}
+	return nil
+}
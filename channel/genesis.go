@@ -0,0 +1,396 @@
+package channel
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-config/configtx"
+	"github.com/hyperledger/fabric-config/configtx/membership"
+	"github.com/hyperledger/fabric-config/configtx/orderer"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/utils"
+	"strings"
+	"time"
+)
+
+// OrgOptions is an application organization of the channel
+type OrgOptions struct {
+	MSPID     string
+	CACert    *x509.Certificate
+	TLSCACert *x509.Certificate
+	// IntermediateCerts and TLSIntermediateCerts are set when the
+	// certificates of the org are issued by intermediate CAs
+	IntermediateCerts    []*x509.Certificate
+	TLSIntermediateCerts []*x509.Certificate
+	// ExtraCACerts and ExtraTLSCACerts are roots trusted besides CACert and
+	// TLSCACert while a CA of the org is being rotated, the NodeOUs of an org
+	// definition accept the identities issued by all of them
+	ExtraCACerts    []*x509.Certificate
+	ExtraTLSCACerts []*x509.Certificate
+	// Admins are admin certificates of the org, its admins are already
+	// identified by the admin OU
+	Admins      []*x509.Certificate
+	AnchorPeers []configtx.Address
+}
+
+// CreateOptions are the options to generate the genesis block of an
+// application channel ordered by the orderers of the bundle
+type CreateOptions struct {
+	ChannelID string
+	// Org is the organization that creates the channel
+	Org OrgOptions
+	// Orgs are the other organizations of the channel from its genesis, the
+	// orgs of a sandbox are all created on the host
+	Orgs   []OrgOptions
+	Bundle *config.OrdererBundle
+	// Capabilities of the channel, DefaultCapabilities when empty
+	Capabilities Capabilities
+}
+
+// Capabilities are the capabilities of the channel, orderer and application
+// groups of a channel, they must be supported by all its orderers and peers
+type Capabilities struct {
+	Channel     string
+	Orderer     string
+	Application string
+}
+
+// DefaultCapabilities are supported by the orderers and peers from Fabric 2.0
+var DefaultCapabilities = Capabilities{
+	Channel:     "V2_0",
+	Orderer:     "V2_0",
+	Application: "V2_0",
+}
+
+// supportedCapabilities are the capabilities of each group a genesis block
+// can be generated with
+var supportedCapabilities = map[string][]string{
+	"channel":     {"V2_0", bftCapability},
+	"orderer":     {"V2_0", bftCapability},
+	"application": {"V2_0", "V2_5"},
+}
+
+// withDefaults returns the capabilities with the empty ones set to the
+// default ones
+func (c Capabilities) withDefaults() Capabilities {
+	if c.Channel == "" {
+		c.Channel = DefaultCapabilities.Channel
+	}
+	if c.Orderer == "" {
+		c.Orderer = DefaultCapabilities.Orderer
+	}
+	if c.Application == "" {
+		c.Application = DefaultCapabilities.Application
+	}
+	return c
+}
+
+// Validate checks that the capabilities are supported
+func (c Capabilities) Validate() error {
+	c = c.withDefaults()
+	for group, capability := range map[string]string{"channel": c.Channel, "orderer": c.Orderer, "application": c.Application} {
+		if !utils.Contains(supportedCapabilities[group], capability) {
+			return errors.Errorf("unsupported %s capability %s, expected one of %s", group, capability, strings.Join(supportedCapabilities[group], ", "))
+		}
+	}
+	return nil
+}
+
+// ConsensusTypeEtcdRaft is the consensus type of the ordering services the
+// channels are created on by default
+const ConsensusTypeEtcdRaft = "etcdraft"
+
+// NewGenesisBlock generates the genesis block of an application channel, only
+// the org side is generated, the orderer side is taken as is from the bundle
+func NewGenesisBlock(opts CreateOptions) (*cb.Block, error) {
+	if opts.ChannelID == "" {
+		return nil, errors.New("channel ID is required")
+	}
+	if opts.Bundle == nil {
+		return nil, errors.New("orderer bundle is required")
+	}
+	bft := opts.Bundle.ConsensusType == ConsensusTypeBFT
+	if opts.Bundle.ConsensusType != "" && opts.Bundle.ConsensusType != ConsensusTypeEtcdRaft && !bft {
+		return nil, errors.Errorf("consensus type %s of the orderer bundle is not supported, expected %s or %s", opts.Bundle.ConsensusType, ConsensusTypeEtcdRaft, ConsensusTypeBFT)
+	}
+	if bft {
+		// SmartBFT is supported by the orderers from Fabric 3.0
+		if opts.Capabilities.Channel == "" {
+			opts.Capabilities.Channel = bftCapability
+		}
+		if opts.Capabilities.Orderer == "" {
+			opts.Capabilities.Orderer = bftCapability
+		}
+		if opts.Capabilities.Channel != bftCapability || opts.Capabilities.Orderer != bftCapability {
+			return nil, errors.Errorf("the %s consensus requires the %s channel and orderer capabilities", ConsensusTypeBFT, bftCapability)
+		}
+	}
+	if err := opts.Capabilities.Validate(); err != nil {
+		return nil, err
+	}
+	capabilities := opts.Capabilities.withDefaults()
+	ordererOrg, consenters, err := ordererOrganization(opts.Bundle)
+	if err != nil {
+		return nil, err
+	}
+	orgs := append([]OrgOptions{opts.Org}, opts.Orgs...)
+	appOrgs := []configtx.Organization{}
+	mspIDs := map[string]bool{}
+	for _, org := range orgs {
+		if mspIDs[org.MSPID] {
+			return nil, errors.Errorf("organization %s is listed twice", org.MSPID)
+		}
+		mspIDs[org.MSPID] = true
+		appOrgs = append(appOrgs, applicationOrganization(org))
+	}
+	channelConfig := configtx.Channel{
+		Orderer: configtx.Orderer{
+			OrdererType:  orderer.ConsensusTypeEtcdRaft,
+			BatchTimeout: 2 * time.Second,
+			BatchSize: orderer.BatchSize{
+				MaxMessageCount:   500,
+				AbsoluteMaxBytes:  10 * 1024 * 1024,
+				PreferredMaxBytes: 2 * 1024 * 1024,
+			},
+			EtcdRaft: orderer.EtcdRaft{
+				Consenters: consenters,
+				Options: orderer.EtcdRaftOptions{
+					TickInterval:         "500ms",
+					ElectionTick:         10,
+					HeartbeatTick:        1,
+					MaxInflightBlocks:    5,
+					SnapshotIntervalSize: 16 * 1024 * 1024,
+				},
+			},
+			Organizations: []configtx.Organization{ordererOrg},
+			Capabilities:  []string{capabilities.Orderer},
+			Policies: map[string]configtx.Policy{
+				configtx.ReadersPolicyKey:         implicitMetaPolicy("ANY Readers"),
+				configtx.WritersPolicyKey:         implicitMetaPolicy("ANY Writers"),
+				configtx.AdminsPolicyKey:          implicitMetaPolicy("MAJORITY Admins"),
+				configtx.BlockValidationPolicyKey: implicitMetaPolicy("ANY Writers"),
+			},
+			State: orderer.ConsensusStateNormal,
+		},
+		Application: configtx.Application{
+			Organizations: appOrgs,
+			Capabilities:  []string{capabilities.Application},
+			Policies: map[string]configtx.Policy{
+				configtx.ReadersPolicyKey:              implicitMetaPolicy("ANY Readers"),
+				configtx.WritersPolicyKey:              implicitMetaPolicy("ANY Writers"),
+				configtx.AdminsPolicyKey:               implicitMetaPolicy("MAJORITY Admins"),
+				configtx.EndorsementPolicyKey:          implicitMetaPolicy("MAJORITY Endorsement"),
+				configtx.LifecycleEndorsementPolicyKey: implicitMetaPolicy("MAJORITY Endorsement"),
+			},
+		},
+		Capabilities: []string{capabilities.Channel},
+		Policies: map[string]configtx.Policy{
+			configtx.ReadersPolicyKey: implicitMetaPolicy("ANY Readers"),
+			configtx.WritersPolicyKey: implicitMetaPolicy("ANY Writers"),
+			configtx.AdminsPolicyKey:  implicitMetaPolicy("MAJORITY Admins"),
+		},
+	}
+	block, err := configtx.NewApplicationChannelGenesisBlock(channelConfig, opts.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	// the anchor peers of the organizations are not added to the genesis block
+	for _, org := range orgs {
+		err = addGenesisAnchorPeers(block, org.MSPID, org.AnchorPeers)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// configtx only generates etcdraft orderer groups
+	if bft {
+		err = updateGenesisConfig(block, func(c *cb.Config) (*cb.Config, error) {
+			return c, setBFTConsensus(c, opts.Bundle)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return block, nil
+}
+
+// applicationOrganization returns the application group of an org, its
+// members are its admins, peers and clients
+func applicationOrganization(org OrgOptions) configtx.Organization {
+	msp := newOrgMSP(
+		org.MSPID,
+		append([]*x509.Certificate{org.CACert}, org.ExtraCACerts...),
+		append([]*x509.Certificate{org.TLSCACert}, org.ExtraTLSCACerts...),
+	)
+	msp.IntermediateCerts = org.IntermediateCerts
+	msp.TLSIntermediateCerts = org.TLSIntermediateCerts
+	msp.Admins = org.Admins
+	if len(org.IntermediateCerts) > 0 {
+		// the identities are issued by the first intermediate, like in the
+		// config.yaml of the nodes
+		msp.NodeOUs.ClientOUIdentifier.Certificate = org.IntermediateCerts[0]
+		msp.NodeOUs.PeerOUIdentifier.Certificate = org.IntermediateCerts[0]
+		msp.NodeOUs.AdminOUIdentifier.Certificate = org.IntermediateCerts[0]
+		msp.NodeOUs.OrdererOUIdentifier.Certificate = org.IntermediateCerts[0]
+	}
+	return configtx.Organization{
+		Name: org.MSPID,
+		MSP:  msp,
+		Policies: map[string]configtx.Policy{
+			configtx.ReadersPolicyKey:     signaturePolicy(fmt.Sprintf("OR('%[1]s.admin', '%[1]s.peer', '%[1]s.client')", org.MSPID)),
+			configtx.WritersPolicyKey:     signaturePolicy(fmt.Sprintf("OR('%[1]s.admin', '%[1]s.client')", org.MSPID)),
+			configtx.AdminsPolicyKey:      signaturePolicy(fmt.Sprintf("OR('%s.admin')", org.MSPID)),
+			configtx.EndorsementPolicyKey: signaturePolicy(fmt.Sprintf("OR('%s.peer')", org.MSPID)),
+		},
+		AnchorPeers: org.AnchorPeers,
+	}
+}
+
+// addGenesisAnchorPeers adds the anchor peers of an org to the config of a
+// genesis block
+func addGenesisAnchorPeers(block *cb.Block, mspID string, anchorPeers []configtx.Address) error {
+	if len(anchorPeers) == 0 {
+		return nil
+	}
+	return updateGenesisConfig(block, func(config *cb.Config) (*cb.Config, error) {
+		c := configtx.New(config)
+		for _, anchorPeer := range anchorPeers {
+			err := c.Application().Organization(mspID).AddAnchorPeer(anchorPeer)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to add anchor peer %s:%d", anchorPeer.Host, anchorPeer.Port)
+			}
+		}
+		return c.UpdatedConfig(), nil
+	})
+}
+
+// updateGenesisConfig replaces the config of a genesis block with the updated
+// one and recomputes its data hash
+func updateGenesisConfig(block *cb.Block, update func(*cb.Config) (*cb.Config, error)) error {
+	envelope := &cb.Envelope{}
+	err := proto.Unmarshal(block.Data.Data[0], envelope)
+	if err != nil {
+		return err
+	}
+	payload := &cb.Payload{}
+	err = proto.Unmarshal(envelope.Payload, payload)
+	if err != nil {
+		return err
+	}
+	configEnvelope := &cb.ConfigEnvelope{}
+	err = proto.Unmarshal(payload.Data, configEnvelope)
+	if err != nil {
+		return err
+	}
+	configEnvelope.Config, err = update(configEnvelope.Config)
+	if err != nil {
+		return err
+	}
+	payload.Data, err = proto.Marshal(configEnvelope)
+	if err != nil {
+		return err
+	}
+	envelope.Payload, err = proto.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	block.Data.Data[0], err = proto.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	dataHash := sha256.Sum256(bytes.Join(block.Data.Data, nil))
+	block.Header.DataHash = dataHash[:]
+	return nil
+}
+
+// ordererOrganization builds the orderer organization and the consenters
+// from the bundle provided by the ordering service operator
+func ordererOrganization(bundle *config.OrdererBundle) (configtx.Organization, []orderer.Consenter, error) {
+	caCerts, err := utils.ParsePEMCertificates(bundle.CACerts)
+	if err != nil {
+		return configtx.Organization{}, nil, errors.Wrap(err, "invalid orderer CA certificates")
+	}
+	tlsCACerts, err := utils.ParsePEMCertificates(bundle.TLSCACerts)
+	if err != nil {
+		return configtx.Organization{}, nil, errors.Wrap(err, "invalid orderer TLS CA certificates")
+	}
+	var consenters []orderer.Consenter
+	var endpoints []string
+	for _, o := range bundle.Orderers {
+		tlsCert, err := utils.ParseX509Certificate([]byte(o.TLSCert))
+		if err != nil {
+			return configtx.Organization{}, nil, errors.Wrapf(err, "invalid TLS certificate of orderer %s", o.Host)
+		}
+		consenters = append(consenters, orderer.Consenter{
+			Address: orderer.EtcdAddress{
+				Host: o.Host,
+				Port: o.Port,
+			},
+			ClientTLSCert: tlsCert,
+			ServerTLSCert: tlsCert,
+		})
+		endpoints = append(endpoints, fmt.Sprintf("%s:%d", o.Host, o.Port))
+	}
+	org := configtx.Organization{
+		Name: bundle.MSPID,
+		MSP:  newOrgMSP(bundle.MSPID, caCerts, tlsCACerts),
+		Policies: map[string]configtx.Policy{
+			configtx.ReadersPolicyKey: signaturePolicy(fmt.Sprintf("OR('%s.member')", bundle.MSPID)),
+			configtx.WritersPolicyKey: signaturePolicy(fmt.Sprintf("OR('%s.member')", bundle.MSPID)),
+			configtx.AdminsPolicyKey:  signaturePolicy(fmt.Sprintf("OR('%s.admin')", bundle.MSPID)),
+		},
+		OrdererEndpoints: endpoints,
+	}
+	return org, consenters, nil
+}
+
+// newOrgMSP returns an MSP with NodeOUs enabled, like the config.yaml
+// written in the MSP of the nodes
+func newOrgMSP(mspID string, caCerts []*x509.Certificate, tlsCACerts []*x509.Certificate) configtx.MSP {
+	ouCert := caCerts[0]
+	return configtx.MSP{
+		Name:         mspID,
+		RootCerts:    caCerts,
+		TLSRootCerts: tlsCACerts,
+		NodeOUs: membership.NodeOUs{
+			Enable: true,
+			ClientOUIdentifier: membership.OUIdentifier{
+				Certificate:                  ouCert,
+				OrganizationalUnitIdentifier: "client",
+			},
+			PeerOUIdentifier: membership.OUIdentifier{
+				Certificate:                  ouCert,
+				OrganizationalUnitIdentifier: "peer",
+			},
+			AdminOUIdentifier: membership.OUIdentifier{
+				Certificate:                  ouCert,
+				OrganizationalUnitIdentifier: "admin",
+			},
+			OrdererOUIdentifier: membership.OUIdentifier{
+				Certificate:                  ouCert,
+				OrganizationalUnitIdentifier: "orderer",
+			},
+		},
+		CryptoConfig: membership.CryptoConfig{
+			SignatureHashFamily:            "SHA2",
+			IdentityIdentifierHashFunction: "SHA256",
+		},
+	}
+}
+
+func signaturePolicy(rule string) configtx.Policy {
+	return configtx.Policy{
+		Type: configtx.SignaturePolicyType,
+		Rule: rule,
+	}
+}
+
+func implicitMetaPolicy(rule string) configtx.Policy {
+	return configtx.Policy{
+		Type: configtx.ImplicitMetaPolicyType,
+		Rule: rule,
+	}
+}
@@ -0,0 +1,297 @@
+package channel
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-config/configtx"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	ob "github.com/hyperledger/fabric-protos-go/orderer"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"hlf-easy/utils"
+	"testing"
+)
+
+func newTestCert(t *testing.T, cn string) *x509.Certificate {
+	t.Helper()
+	crt, _ := testca.NewCA(t, cn)
+	return crt
+}
+
+func pemString(crt *x509.Certificate) string {
+	return string(utils.EncodeX509Certificate(crt))
+}
+
+func configFromBlock(t *testing.T, block *cb.Block) *cb.Config {
+	t.Helper()
+	envelope := &cb.Envelope{}
+	if err := proto.Unmarshal(block.Data.Data[0], envelope); err != nil {
+		t.Fatal(err)
+	}
+	payload := &cb.Payload{}
+	if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+		t.Fatal(err)
+	}
+	configEnvelope := &cb.ConfigEnvelope{}
+	if err := proto.Unmarshal(payload.Data, configEnvelope); err != nil {
+		t.Fatal(err)
+	}
+	return configEnvelope.Config
+}
+
+func TestNewGenesisBlock(t *testing.T) {
+	bundle := &config.OrdererBundle{
+		MSPID:      "OrdererMSP",
+		CACerts:    []string{pemString(newTestCert(t, "orderer-ca"))},
+		TLSCACerts: []string{pemString(newTestCert(t, "orderer-tlsca"))},
+		Orderers: []config.BundleOrderer{
+			{Host: "orderer0.example.com", Port: 7050, TLSCert: pemString(newTestCert(t, "orderer0"))},
+			{Host: "orderer1.example.com", Port: 7050, TLSCert: pemString(newTestCert(t, "orderer1"))},
+		},
+	}
+	block, err := NewGenesisBlock(CreateOptions{
+		ChannelID: "demo",
+		Org: OrgOptions{
+			MSPID:       "Org1MSP",
+			CACert:      newTestCert(t, "ca"),
+			TLSCACert:   newTestCert(t, "tlsca"),
+			AnchorPeers: []configtx.Address{{Host: "peer0.org1.example.com", Port: 7051}},
+		},
+		Bundle: bundle,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataHash := sha256.Sum256(bytes.Join(block.Data.Data, nil))
+	if !bytes.Equal(block.Header.DataHash, dataHash[:]) {
+		t.Fatal("block data hash doesn't match the block data")
+	}
+	c := configtx.New(configFromBlock(t, block))
+
+	ordererConfig, err := c.Orderer().Configuration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ordererConfig.EtcdRaft.Consenters) != 2 {
+		t.Fatalf("expected 2 consenters, got %d", len(ordererConfig.EtcdRaft.Consenters))
+	}
+	if ordererConfig.EtcdRaft.Consenters[1].Address.Host != "orderer1.example.com" {
+		t.Fatalf("unexpected consenter %v", ordererConfig.EtcdRaft.Consenters[1].Address)
+	}
+	if len(ordererConfig.Organizations) != 1 || ordererConfig.Organizations[0].Name != "OrdererMSP" {
+		t.Fatalf("expected the orderer org of the bundle, got %v", ordererConfig.Organizations)
+	}
+	endpoints := ordererConfig.Organizations[0].OrdererEndpoints
+	if len(endpoints) != 2 || endpoints[0] != "orderer0.example.com:7050" {
+		t.Fatalf("unexpected orderer endpoints %v", endpoints)
+	}
+
+	anchorPeers, err := c.Application().Organization("Org1MSP").AnchorPeers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anchorPeers) != 1 || anchorPeers[0].Host != "peer0.org1.example.com" {
+		t.Fatalf("unexpected anchor peers %v", anchorPeers)
+	}
+}
+
+func TestNewGenesisBlockOrgs(t *testing.T) {
+	bundle := &config.OrdererBundle{
+		MSPID:      "OrdererMSP",
+		CACerts:    []string{pemString(newTestCert(t, "orderer-ca"))},
+		TLSCACerts: []string{pemString(newTestCert(t, "orderer-tlsca"))},
+		Orderers: []config.BundleOrderer{
+			{Host: "localhost", Port: 7050, TLSCert: pemString(newTestCert(t, "orderer0"))},
+		},
+	}
+	org := func(mspID string, port int) OrgOptions {
+		return OrgOptions{
+			MSPID:       mspID,
+			CACert:      newTestCert(t, mspID+"-ca"),
+			TLSCACert:   newTestCert(t, mspID+"-tlsca"),
+			AnchorPeers: []configtx.Address{{Host: "localhost", Port: port}},
+		}
+	}
+	opts := CreateOptions{
+		ChannelID: "sandbox",
+		Org:       org("Org1MSP", 7052),
+		Orgs:      []OrgOptions{org("Org2MSP", 7058)},
+		Bundle:    bundle,
+	}
+	block, err := NewGenesisBlock(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := configtx.New(configFromBlock(t, block))
+	for mspID, port := range map[string]int{"Org1MSP": 7052, "Org2MSP": 7058} {
+		anchorPeers, err := c.Application().Organization(mspID).AnchorPeers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(anchorPeers) != 1 || anchorPeers[0].Port != port {
+			t.Fatalf("unexpected anchor peers %v of %s", anchorPeers, mspID)
+		}
+	}
+	opts.Orgs = append(opts.Orgs, org("Org1MSP", 7064))
+	if _, err := NewGenesisBlock(opts); err == nil {
+		t.Fatal("expected an error with an org listed twice")
+	}
+}
+
+func TestNewGenesisBlockRequiresBundle(t *testing.T) {
+	_, err := NewGenesisBlock(CreateOptions{ChannelID: "demo"})
+	if err == nil {
+		t.Fatal("expected an error without an orderer bundle")
+	}
+}
+
+func TestNewGenesisBlockCapabilities(t *testing.T) {
+	bundle := &config.OrdererBundle{
+		MSPID:      "OrdererMSP",
+		CACerts:    []string{pemString(newTestCert(t, "orderer-ca"))},
+		TLSCACerts: []string{pemString(newTestCert(t, "orderer-tlsca"))},
+		Orderers: []config.BundleOrderer{
+			{Host: "orderer0.example.com", Port: 7050, TLSCert: pemString(newTestCert(t, "orderer0"))},
+		},
+	}
+	opts := CreateOptions{
+		ChannelID: "demo",
+		Org: OrgOptions{
+			MSPID:     "Org1MSP",
+			CACert:    newTestCert(t, "ca"),
+			TLSCACert: newTestCert(t, "tlsca"),
+		},
+		Bundle:       bundle,
+		Capabilities: Capabilities{Application: "V2_5"},
+	}
+	block, err := NewGenesisBlock(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := configtx.New(configFromBlock(t, block))
+	applicationCapabilities, err := c.Application().Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applicationCapabilities) != 1 || applicationCapabilities[0] != "V2_5" {
+		t.Errorf("expected the V2_5 application capability, got %v", applicationCapabilities)
+	}
+	channelCapabilities, err := c.Channel().Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channelCapabilities) != 1 || channelCapabilities[0] != "V2_0" {
+		t.Errorf("expected the default channel capability, got %v", channelCapabilities)
+	}
+
+	opts.Capabilities = Capabilities{Channel: "V1_4_3"}
+	if _, err := NewGenesisBlock(opts); err == nil {
+		t.Error("expected an unsupported capability to be refused")
+	}
+	opts.Capabilities = Capabilities{}
+	bundle.ConsensusType = "kafka"
+	if _, err := NewGenesisBlock(opts); err == nil {
+		t.Error("expected an unsupported consensus type to be refused")
+	}
+}
+
+func TestNewGenesisBlockBFT(t *testing.T) {
+	bundle := &config.OrdererBundle{
+		MSPID:         "OrdererMSP",
+		CACerts:       []string{pemString(newTestCert(t, "orderer-ca"))},
+		TLSCACerts:    []string{pemString(newTestCert(t, "orderer-tlsca"))},
+		ConsensusType: ConsensusTypeBFT,
+	}
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("orderer%d", i)
+		bundle.Orderers = append(bundle.Orderers, config.BundleOrderer{
+			Host:     name + ".example.com",
+			Port:     7050,
+			TLSCert:  pemString(newTestCert(t, name+"-tls")),
+			Identity: pemString(newTestCert(t, name)),
+		})
+	}
+	bundle.Orderers[3].ID = 7
+	opts := CreateOptions{
+		ChannelID: "demo",
+		Org: OrgOptions{
+			MSPID:       "Org1MSP",
+			CACert:      newTestCert(t, "ca"),
+			TLSCACert:   newTestCert(t, "tlsca"),
+			AnchorPeers: []configtx.Address{{Host: "peer0.org1.example.com", Port: 7051}},
+		},
+		Bundle: bundle,
+	}
+	block, err := NewGenesisBlock(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataHash := sha256.Sum256(bytes.Join(block.Data.Data, nil))
+	if !bytes.Equal(block.Header.DataHash, dataHash[:]) {
+		t.Fatal("block data hash doesn't match the block data")
+	}
+	channelConfig := configFromBlock(t, block)
+	ordererGroup := channelConfig.ChannelGroup.Groups["Orderer"]
+
+	consensusType := &ob.ConsensusType{}
+	if err := proto.Unmarshal(ordererGroup.Values["ConsensusType"].Value, consensusType); err != nil {
+		t.Fatal(err)
+	}
+	if consensusType.Type != ConsensusTypeBFT {
+		t.Fatalf("expected the BFT consensus type, got %s", consensusType.Type)
+	}
+	orderers := &cb.Orderers{}
+	if err := proto.Unmarshal(ordererGroup.Values["Orderers"].Value, orderers); err != nil {
+		t.Fatal(err)
+	}
+	if len(orderers.ConsenterMapping) != 4 {
+		t.Fatalf("expected 4 consenters, got %d", len(orderers.ConsenterMapping))
+	}
+	consenter := orderers.ConsenterMapping[3]
+	if consenter.Id != 7 || consenter.Host != "orderer3.example.com" || consenter.MspId != "OrdererMSP" || string(consenter.Identity) != bundle.Orderers[3].Identity {
+		t.Errorf("unexpected consenter %+v", consenter)
+	}
+	if orderers.ConsenterMapping[0].Id != 1 {
+		t.Errorf("expected the first consenter to have the ID 1, got %d", orderers.ConsenterMapping[0].Id)
+	}
+
+	policy := &cb.SignaturePolicyEnvelope{}
+	if err := proto.Unmarshal(ordererGroup.Policies["BlockValidation"].Policy.Value, policy); err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.Identities) != 4 || policy.Rule.GetNOutOf().GetN() != 3 {
+		t.Errorf("expected a quorum of 3 out of the 4 consenters, got %+v", policy.Rule)
+	}
+
+	c := configtx.New(channelConfig)
+	channelCapabilities, err := c.Channel().Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channelCapabilities) != 1 || channelCapabilities[0] != "V3_0" {
+		t.Errorf("expected the V3_0 channel capability, got %v", channelCapabilities)
+	}
+	anchorPeers, err := c.Application().Organization("Org1MSP").AnchorPeers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anchorPeers) != 1 {
+		t.Errorf("expected the anchor peer to be kept, got %v", anchorPeers)
+	}
+
+	opts.Capabilities = Capabilities{Orderer: "V2_0"}
+	if _, err := NewGenesisBlock(opts); err == nil {
+		t.Error("expected the V2_0 orderer capability to be refused with BFT")
+	}
+}
+
+func TestBFTQuorum(t *testing.T) {
+	for n, quorum := range map[int]int{1: 1, 4: 3, 5: 4, 7: 5, 10: 7} {
+		if BFTQuorum(n) != quorum {
+			t.Errorf("expected a quorum of %d for %d consenters, got %d", quorum, n, BFTQuorum(n))
+		}
+	}
+}
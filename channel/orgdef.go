@@ -0,0 +1,206 @@
+package channel
+
+import (
+	"bytes"
+	"crypto/x509"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-config/configtx"
+	"github.com/hyperledger/fabric-config/protolator"
+	"github.com/hyperledger/fabric-config/protolator/protoext/peerext"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+	"hlf-easy/utils"
+	"io"
+)
+
+// NewOrgDefinition returns the config group of an application org, with its
+// MSP and its policies, as it's added to the Application group of a channel
+// by a config update
+func NewOrgDefinition(org OrgOptions) (*cb.ConfigGroup, error) {
+	if org.MSPID == "" {
+		return nil, errors.New("the MSP ID of the org is required")
+	}
+	if org.CACert == nil || org.TLSCACert == nil {
+		return nil, errors.Errorf("the CA and TLS CA certificates of %s are required", org.MSPID)
+	}
+	// configtx only builds the org group in the Application group of a config
+	c := configtx.New(&cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			Groups: map[string]*cb.ConfigGroup{
+				configtx.ApplicationGroupKey: {},
+			},
+		},
+	})
+	// the copy of the config made by configtx drops the empty maps
+	c.UpdatedConfig().ChannelGroup.Groups[configtx.ApplicationGroupKey].Groups = map[string]*cb.ConfigGroup{}
+	err := c.Application().SetOrganization(applicationOrganization(org))
+	if err != nil {
+		return nil, err
+	}
+	group := c.UpdatedConfig().ChannelGroup.Groups[configtx.ApplicationGroupKey].Groups[org.MSPID]
+	if len(org.ExtraCACerts) > 0 && len(org.IntermediateCerts) == 0 {
+		err = unpinNodeOUs(group)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return group, nil
+}
+
+// WriteOrgDefinition writes the config group of an org as JSON, like
+// configtxgen -printOrg does, so it can be added to the config of a channel
+// with configtxlator. The group is read as an application org so its MSP is
+// decoded
+func WriteOrgDefinition(w io.Writer, group *cb.ConfigGroup) error {
+	return protolator.DeepMarshalJSON(w, &peerext.DynamicApplicationOrgGroup{ConfigGroup: group})
+}
+
+// unpinNodeOUs clears the certificate of the NodeOUs of the MSP of an org
+// group, so the identities issued by every root of the MSP get their OU.
+// configtx always writes the certificate
+func unpinNodeOUs(group *cb.ConfigGroup) error {
+	value := group.Values[configtx.MSPKey]
+	mspConfig := &mb.MSPConfig{}
+	if err := proto.Unmarshal(value.Value, mspConfig); err != nil {
+		return err
+	}
+	fabricConfig := &mb.FabricMSPConfig{}
+	if err := proto.Unmarshal(mspConfig.Config, fabricConfig); err != nil {
+		return err
+	}
+	nodeOUs := fabricConfig.FabricNodeOus
+	for _, identifier := range []*mb.FabricOUIdentifier{nodeOUs.ClientOuIdentifier, nodeOUs.PeerOuIdentifier, nodeOUs.AdminOuIdentifier, nodeOUs.OrdererOuIdentifier} {
+		if identifier != nil {
+			identifier.Certificate = nil
+		}
+	}
+	var err error
+	mspConfig.Config, err = proto.Marshal(fabricConfig)
+	if err != nil {
+		return err
+	}
+	value.Value, err = proto.Marshal(mspConfig)
+	return err
+}
+
+// SetOrgRoots returns a copy of the config of a channel where the MSP of an
+// application org trusts the added roots and not the removed ones, the
+// signing roots or the TLS roots. The rest of the MSP, its policies and its
+// anchor peers are kept. The NodeOUs pointing to a root accept the identities
+// of every root while the MSP has several. It's false when the MSP doesn't
+// change
+func SetOrgRoots(config *cb.Config, mspID string, tls bool, add []*x509.Certificate, remove []*x509.Certificate) (*cb.Config, bool, error) {
+	application := config.GetChannelGroup().GetGroups()[configtx.ApplicationGroupKey]
+	org, ok := application.GetGroups()[mspID]
+	if !ok {
+		return nil, false, errors.Errorf("%s is not an org of the channel", mspID)
+	}
+	value, ok := org.GetValues()[configtx.MSPKey]
+	if !ok {
+		return nil, false, errors.Errorf("%s has no MSP in the channel", mspID)
+	}
+	mspConfig := &mb.MSPConfig{}
+	if err := proto.Unmarshal(value.Value, mspConfig); err != nil {
+		return nil, false, errors.Wrapf(err, "invalid MSP of %s", mspID)
+	}
+	fabricConfig := &mb.FabricMSPConfig{}
+	if err := proto.Unmarshal(mspConfig.Config, fabricConfig); err != nil {
+		return nil, false, errors.Wrapf(err, "invalid MSP of %s", mspID)
+	}
+	roots := &fabricConfig.RootCerts
+	if tls {
+		roots = &fabricConfig.TlsRootCerts
+	}
+	previous := *roots
+	updated, err := setRoots(previous, add, remove)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "invalid root of %s", mspID)
+	}
+	if len(updated) == 0 {
+		return nil, false, errors.Errorf("the MSP of %s would have no root", mspID)
+	}
+	*roots = updated
+	changed := len(updated) != len(previous)
+	for i := 0; !changed && i < len(updated); i++ {
+		changed = !bytes.Equal(updated[i], previous[i])
+	}
+	if !tls && fabricConfig.FabricNodeOus != nil {
+		nodeOUs := fabricConfig.FabricNodeOus
+		for _, identifier := range []*mb.FabricOUIdentifier{nodeOUs.ClientOuIdentifier, nodeOUs.PeerOuIdentifier, nodeOUs.AdminOuIdentifier, nodeOUs.OrdererOuIdentifier} {
+			if identifier == nil {
+				continue
+			}
+			// the NodeOUs pointing to an intermediate are kept
+			if len(identifier.Certificate) > 0 && !containsPEM(previous, identifier.Certificate) {
+				continue
+			}
+			var certificate []byte
+			if len(updated) == 1 {
+				certificate = updated[0]
+			}
+			if !bytes.Equal(identifier.Certificate, certificate) {
+				identifier.Certificate = certificate
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return config, false, nil
+	}
+	mspConfig.Config, err = proto.Marshal(fabricConfig)
+	if err != nil {
+		return nil, false, err
+	}
+	valueBytes, err := proto.Marshal(mspConfig)
+	if err != nil {
+		return nil, false, err
+	}
+	updatedConfig := proto.Clone(config).(*cb.Config)
+	updatedConfig.ChannelGroup.Groups[configtx.ApplicationGroupKey].Groups[mspID].Values[configtx.MSPKey].Value = valueBytes
+	return updatedConfig, true, nil
+}
+
+// setRoots removes and adds roots to the PEM roots of an MSP
+func setRoots(roots [][]byte, add []*x509.Certificate, remove []*x509.Certificate) ([][]byte, error) {
+	updated := [][]byte{}
+	for _, root := range roots {
+		crt, err := utils.ParseX509Certificate(root)
+		if err != nil {
+			return nil, err
+		}
+		if !containsCert(remove, crt) {
+			updated = append(updated, root)
+		}
+	}
+	for _, crt := range add {
+		rootPEM := utils.EncodeX509Certificate(crt)
+		if !containsPEM(updated, rootPEM) {
+			updated = append(updated, rootPEM)
+		}
+	}
+	return updated, nil
+}
+
+func containsCert(crts []*x509.Certificate, crt *x509.Certificate) bool {
+	for _, c := range crts {
+		if c.Equal(crt) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsPEM compares the certificates of PEMs, not their encoding
+func containsPEM(pems [][]byte, pemBytes []byte) bool {
+	crt, err := utils.ParseX509Certificate(pemBytes)
+	if err != nil {
+		return false
+	}
+	for _, p := range pems {
+		if c, err := utils.ParseX509Certificate(p); err == nil && c.Equal(crt) {
+			return true
+		}
+	}
+	return false
+}
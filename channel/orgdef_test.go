@@ -0,0 +1,148 @@
+package channel
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-config/configtx"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"hlf-easy/utils"
+	"testing"
+)
+
+func TestNewOrgDefinition(t *testing.T) {
+	group, err := NewOrgDefinition(OrgOptions{
+		MSPID:     "Org1MSP",
+		CACert:    newTestCert(t, "org1-ca"),
+		TLSCACert: newTestCert(t, "org1-tlsca"),
+		Admins:    []*x509.Certificate{newTestCert(t, "org1-admin")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	err = WriteOrgDefinition(&buf, group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var def struct {
+		ModPolicy string                     `json:"mod_policy"`
+		Policies  map[string]json.RawMessage `json:"policies"`
+		Values    struct {
+			MSP struct {
+				Value struct {
+					Config struct {
+						Name          string   `json:"name"`
+						RootCerts     []string `json:"root_certs"`
+						TLSRootCerts  []string `json:"tls_root_certs"`
+						Admins        []string `json:"admins"`
+						FabricNodeOUs struct {
+							Enable bool `json:"enable"`
+						} `json:"fabric_node_ous"`
+					} `json:"config"`
+				} `json:"value"`
+			} `json:"MSP"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &def); err != nil {
+		t.Fatal(err)
+	}
+	msp := def.Values.MSP.Value.Config
+	if msp.Name != "Org1MSP" || len(msp.RootCerts) != 1 || len(msp.TLSRootCerts) != 1 || len(msp.Admins) != 1 {
+		t.Fatalf("unexpected MSP %+v", msp)
+	}
+	if !msp.FabricNodeOUs.Enable {
+		t.Fatal("expected the NodeOUs to be enabled")
+	}
+	if def.ModPolicy != "Admins" {
+		t.Fatalf("expected the Admins mod policy, got %q", def.ModPolicy)
+	}
+	for _, policy := range []string{"Readers", "Writers", "Admins", "Endorsement"} {
+		if _, ok := def.Policies[policy]; !ok {
+			t.Errorf("expected the %s policy", policy)
+		}
+	}
+
+	if _, err := NewOrgDefinition(OrgOptions{MSPID: "Org1MSP"}); err == nil {
+		t.Fatal("expected an error without the CAs")
+	}
+}
+
+func orgMSP(t *testing.T, config *cb.Config, mspID string) *mb.FabricMSPConfig {
+	t.Helper()
+	value := config.ChannelGroup.Groups[configtx.ApplicationGroupKey].Groups[mspID].Values[configtx.MSPKey]
+	mspConfig := &mb.MSPConfig{}
+	if err := proto.Unmarshal(value.Value, mspConfig); err != nil {
+		t.Fatal(err)
+	}
+	fabricConfig := &mb.FabricMSPConfig{}
+	if err := proto.Unmarshal(mspConfig.Config, fabricConfig); err != nil {
+		t.Fatal(err)
+	}
+	return fabricConfig
+}
+
+func TestSetOrgRoots(t *testing.T) {
+	caCert := newTestCert(t, "org1-ca")
+	group, err := NewOrgDefinition(OrgOptions{
+		MSPID:     "Org1MSP",
+		CACert:    caCert,
+		TLSCACert: newTestCert(t, "org1-tlsca"),
+		Admins:    []*x509.Certificate{newTestCert(t, "org1-admin")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			Groups: map[string]*cb.ConfigGroup{
+				configtx.ApplicationGroupKey: {
+					Groups: map[string]*cb.ConfigGroup{"Org1MSP": group},
+				},
+			},
+		},
+	}
+	newCACert := newTestCert(t, "org1-ca")
+
+	updated, changed, err := SetOrgRoots(original, "Org1MSP", false, []*x509.Certificate{newCACert}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("expected the MSP to change")
+	}
+	msp := orgMSP(t, updated, "Org1MSP")
+	if len(msp.RootCerts) != 2 || len(msp.Admins) != 1 {
+		t.Fatalf("expected both roots and the admin to be kept, got %d roots and %d admins", len(msp.RootCerts), len(msp.Admins))
+	}
+	// the identities of both roots are accepted by the NodeOUs
+	if len(msp.FabricNodeOus.PeerOuIdentifier.Certificate) != 0 || len(msp.FabricNodeOus.AdminOuIdentifier.Certificate) != 0 {
+		t.Fatal("expected the NodeOUs not to point to a root")
+	}
+	if len(orgMSP(t, original, "Org1MSP").RootCerts) != 1 {
+		t.Fatal("expected the original config to be kept")
+	}
+	if _, changed, err := SetOrgRoots(updated, "Org1MSP", false, []*x509.Certificate{newCACert}, nil); err != nil || changed {
+		t.Fatalf("expected no change when the root is already trusted, got %t, %v", changed, err)
+	}
+
+	retired, changed, err := SetOrgRoots(updated, "Org1MSP", false, nil, []*x509.Certificate{caCert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msp = orgMSP(t, retired, "Org1MSP")
+	if !changed || len(msp.RootCerts) != 1 || !bytes.Equal(msp.RootCerts[0], utils.EncodeX509Certificate(newCACert)) {
+		t.Fatal("expected only the new root to be trusted")
+	}
+	if !bytes.Equal(msp.FabricNodeOus.PeerOuIdentifier.Certificate, msp.RootCerts[0]) {
+		t.Fatal("expected the NodeOUs to point to the new root")
+	}
+	if _, _, err := SetOrgRoots(retired, "Org1MSP", false, nil, []*x509.Certificate{newCACert}); err == nil {
+		t.Fatal("expected an error when no root would be left")
+	}
+	if _, _, err := SetOrgRoots(retired, "Org2MSP", true, []*x509.Certificate{newCACert}, nil); err == nil {
+		t.Fatal("expected an error for an org that isn't in the channel")
+	}
+}
@@ -0,0 +1,107 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultJoinTimeout is how long the orderers of a bundle, or a peer, have
+// to join a channel
+const DefaultJoinTimeout = 2 * time.Minute
+
+// JoinOrderer submits the genesis block to the channel participation API of
+// an orderer, the TLS config must hold the admin client certificate accepted
+// by the orderer. The request is canceled when the context is done
+func JoinOrderer(ctx context.Context, adminURL string, block *cb.Block, tlsConfig *tls.Config) error {
+	blockBytes, err := proto.Marshal(block)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("config-block", "config.block")
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(blockBytes)
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/participation/v1/channels", strings.TrimSuffix(adminURL, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return errors.Errorf("orderer %s rejected the channel with status %d: %s", adminURL, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// Results of joining an orderer to a channel
+const (
+	JoinResultJoined  = "joined"
+	JoinResultFailed  = "failed"
+	JoinResultSkipped = "skipped"
+)
+
+// JoinResult is the result of joining an orderer of a bundle to a channel
+type JoinResult struct {
+	Orderer  string `json:"orderer"`
+	AdminURL string `json:"adminURL,omitempty"`
+	Result   string `json:"result"`
+	Error    string `json:"error,omitempty"`
+}
+
+// JoinOrderers submits the genesis block to all the orderers with an admin
+// URL. An orderer rejecting the block doesn't stop the others from being
+// joined, the result of each orderer is returned so the operator knows which
+// ones joined the channel. The orderers left when the context is done fail
+// with its error
+func JoinOrderers(ctx context.Context, orderers []config.BundleOrderer, block *cb.Block, tlsConfig *tls.Config) []JoinResult {
+	var results []JoinResult
+	for _, orderer := range orderers {
+		result := JoinResult{
+			Orderer:  fmt.Sprintf("%s:%d", orderer.Host, orderer.Port),
+			AdminURL: orderer.AdminURL,
+			Result:   JoinResultJoined,
+		}
+		if orderer.AdminURL == "" {
+			result.Result = JoinResultSkipped
+			result.Error = "no admin URL in the bundle, it must be joined by the ordering service operator"
+		} else if err := JoinOrderer(ctx, orderer.AdminURL, block, tlsConfig); err != nil {
+			result.Result = JoinResultFailed
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,50 @@
+package channel
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"hlf-easy/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJoinOrderers(t *testing.T) {
+	accepting := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/participation/v1/channels" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer accepting.Close()
+	rejecting := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "channel already exists", http.StatusMethodNotAllowed)
+	}))
+	defer rejecting.Close()
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(accepting.Certificate())
+	rootCAs.AddCert(rejecting.Certificate())
+
+	block := &cb.Block{Header: &cb.BlockHeader{}, Data: &cb.BlockData{}}
+	results := JoinOrderers(context.Background(), []config.BundleOrderer{
+		{Host: "orderer0.example.com", Port: 7050, AdminURL: rejecting.URL},
+		{Host: "orderer1.example.com", Port: 7050, AdminURL: accepting.URL},
+		{Host: "orderer2.example.com", Port: 7050},
+	}, block, &tls.Config{RootCAs: rootCAs})
+	if len(results) != 3 {
+		t.Fatalf("expected a result for every orderer, got %v", results)
+	}
+	// the orderers after a rejecting one are still joined
+	expected := []string{JoinResultFailed, JoinResultJoined, JoinResultSkipped}
+	for i, r := range results {
+		if r.Result != expected[i] {
+			t.Errorf("expected orderer %s to be %s, got %+v", r.Orderer, expected[i], r)
+		}
+	}
+	if results[0].Error == "" || results[0].Orderer != "orderer0.example.com:7050" {
+		t.Errorf("expected the error of the rejecting orderer, got %+v", results[0])
+	}
+}
@@ -0,0 +1,386 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-config/configtx"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	ob "github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"hlf-easy/ordering"
+	"hlf-easy/utils"
+	"math/big"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultSubmitTimeout is how long an orderer has to accept a config update
+const DefaultSubmitTimeout = 30 * time.Second
+
+// Identity signs the config updates of a channel for an org
+type Identity struct {
+	MSPID string
+	Cert  *x509.Certificate
+	Key   *ecdsa.PrivateKey
+}
+
+// Signer is an org that signed a config update
+type Signer struct {
+	MSPID      string `json:"mspID"`
+	CommonName string `json:"commonName"`
+}
+
+// NewConfigUpdateTx returns the unsigned config update transaction from the
+// original config of a channel to the updated one, like configtxlator
+// compute_update does. It's passed around the orgs whose signatures are
+// required by the modification policies
+func NewConfigUpdateTx(channelID string, original *cb.Config, updated *cb.Config) (*cb.Envelope, error) {
+	c := configtx.New(original)
+	// configtx computes the update from the original config to its updated
+	// copy, which is replaced by the modified config
+	u := c.UpdatedConfig()
+	u.Reset()
+	proto.Merge(u, updated)
+	configUpdate, err := c.ComputeMarshaledUpdate(channelID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute the config update")
+	}
+	return newConfigUpdateTx(channelID, &cb.ConfigUpdateEnvelope{ConfigUpdate: configUpdate}, nil)
+}
+
+// ReadConfigUpdateTx parses a config update transaction, as written by
+// NewConfigUpdateTx or by peer channel signconfigtx
+func ReadConfigUpdateTx(txBytes []byte) (*cb.Envelope, error) {
+	env := &cb.Envelope{}
+	if err := proto.Unmarshal(txBytes, env); err != nil {
+		return nil, errors.Wrap(err, "invalid config update transaction")
+	}
+	if _, _, err := openConfigUpdateTx(env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// SignConfigUpdate adds the signature of an org to a config update
+// transaction, like peer channel signconfigtx does. An identity can only sign
+// it once
+func SignConfigUpdate(env *cb.Envelope, id Identity) (*cb.Envelope, error) {
+	channelID, configUpdateEnv, err := openConfigUpdateTx(env)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := serializeIdentity(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, signature := range configUpdateEnv.Signatures {
+		header := &cb.SignatureHeader{}
+		if err := proto.Unmarshal(signature.SignatureHeader, header); err != nil {
+			return nil, errors.Wrap(err, "invalid signature header")
+		}
+		if bytes.Equal(header.Creator, creator) {
+			return nil, errors.Errorf("the config update is already signed by %s of %s", id.Cert.Subject.CommonName, id.MSPID)
+		}
+	}
+	signatureHeader, err := newSignatureHeader(creator)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := sign(id.Key, append(append([]byte{}, signatureHeader...), configUpdateEnv.ConfigUpdate...))
+	if err != nil {
+		return nil, err
+	}
+	configUpdateEnv.Signatures = append(configUpdateEnv.Signatures, &cb.ConfigSignature{
+		SignatureHeader: signatureHeader,
+		Signature:       signature,
+	})
+	return newConfigUpdateTx(channelID, configUpdateEnv, nil)
+}
+
+// ConfigUpdateSigners returns the orgs that signed a config update
+// transaction, in the order they signed it
+func ConfigUpdateSigners(env *cb.Envelope) ([]Signer, error) {
+	_, configUpdateEnv, err := openConfigUpdateTx(env)
+	if err != nil {
+		return nil, err
+	}
+	signers := []Signer{}
+	for _, signature := range configUpdateEnv.Signatures {
+		header := &cb.SignatureHeader{}
+		if err := proto.Unmarshal(signature.SignatureHeader, header); err != nil {
+			return nil, errors.Wrap(err, "invalid signature header")
+		}
+		creator := &mb.SerializedIdentity{}
+		if err := proto.Unmarshal(header.Creator, creator); err != nil {
+			return nil, errors.Wrap(err, "invalid signer")
+		}
+		signer := Signer{MSPID: creator.Mspid}
+		if crt, err := utils.ParseX509Certificate(creator.IdBytes); err == nil {
+			signer.CommonName = crt.Subject.CommonName
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
+
+// SubmitConfigUpdate signs the config update transaction with the identity of
+// the submitter and broadcasts it to an orderer, the TLS config must trust the
+// orderer and hold the client certificate when it requires one
+func SubmitConfigUpdate(ctx context.Context, ordererAddress string, tlsConfig *tls.Config, env *cb.Envelope, id Identity) error {
+	channelID, configUpdateEnv, err := openConfigUpdateTx(env)
+	if err != nil {
+		return err
+	}
+	signed, err := newConfigUpdateTx(channelID, configUpdateEnv, &id)
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.DialContext(ctx, ordererAddress, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), grpc.WithBlock())
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to orderer %s", ordererAddress)
+	}
+	defer conn.Close()
+	stream, err := ob.NewAtomicBroadcastClient(conn).Broadcast(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to broadcast to orderer %s", ordererAddress)
+	}
+	if err := stream.Send(signed); err != nil {
+		return errors.Wrapf(err, "failed to send the config update to orderer %s", ordererAddress)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the response of orderer %s", ordererAddress)
+	}
+	_ = stream.CloseSend()
+	if resp.Status != cb.Status_SUCCESS {
+		return errors.Errorf("orderer %s rejected the config update: %s %s", ordererAddress, resp.Status, resp.Info)
+	}
+	return nil
+}
+
+// OrdererEndpoints returns the endpoints of the orderer orgs of the config of
+// a channel with the TLS roots of their MSP, a config update is submitted to
+// one of them
+func OrdererEndpoints(config *cb.Config) ([]ordering.Endpoint, error) {
+	c := configtx.New(config)
+	ordererGroup := config.GetChannelGroup().GetGroups()[configtx.OrdererGroupKey]
+	names := make([]string, 0, len(ordererGroup.GetGroups()))
+	for name := range ordererGroup.GetGroups() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	endpoints := []ordering.Endpoint{}
+	for _, name := range names {
+		org, err := c.Orderer().Organization(name).Configuration()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the orderer org %s", name)
+		}
+		tlsCACerts := []string{}
+		for _, crt := range append(org.MSP.TLSRootCerts, org.MSP.TLSIntermediateCerts...) {
+			tlsCACerts = append(tlsCACerts, string(utils.EncodeX509Certificate(crt)))
+		}
+		for _, address := range org.OrdererEndpoints {
+			endpoints = append(endpoints, ordering.Endpoint{Address: address, TLSCACerts: tlsCACerts})
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, errors.New("the channel has no orderer endpoint")
+	}
+	return endpoints, nil
+}
+
+// AnchorPeer is an anchor peer of an org of a channel with the TLS roots of
+// the MSP of the org
+type AnchorPeer struct {
+	MSPID      string
+	Address    string
+	TLSCACerts []string
+}
+
+// AnchorPeers returns the anchor peers of the application orgs of the config
+// of a channel, the peers of the other orgs gossip with them
+func AnchorPeers(config *cb.Config) ([]AnchorPeer, error) {
+	c := configtx.New(config)
+	applicationGroup := config.GetChannelGroup().GetGroups()[configtx.ApplicationGroupKey]
+	names := make([]string, 0, len(applicationGroup.GetGroups()))
+	for name := range applicationGroup.GetGroups() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	anchorPeers := []AnchorPeer{}
+	for _, name := range names {
+		org := c.Application().Organization(name)
+		addresses, err := org.AnchorPeers()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the anchor peers of %s", name)
+		}
+		if len(addresses) == 0 {
+			continue
+		}
+		msp, err := org.MSP().Configuration()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the MSP of %s", name)
+		}
+		tlsCACerts := []string{}
+		for _, crt := range append(msp.TLSRootCerts, msp.TLSIntermediateCerts...) {
+			tlsCACerts = append(tlsCACerts, string(utils.EncodeX509Certificate(crt)))
+		}
+		for _, address := range addresses {
+			anchorPeers = append(anchorPeers, AnchorPeer{
+				MSPID:      msp.Name,
+				Address:    net.JoinHostPort(address.Host, strconv.Itoa(address.Port)),
+				TLSCACerts: tlsCACerts,
+			})
+		}
+	}
+	return anchorPeers, nil
+}
+
+// openConfigUpdateTx returns the channel and the config update envelope of a
+// config update transaction
+func openConfigUpdateTx(env *cb.Envelope) (string, *cb.ConfigUpdateEnvelope, error) {
+	payload := &cb.Payload{}
+	if err := proto.Unmarshal(env.Payload, payload); err != nil {
+		return "", nil, errors.Wrap(err, "invalid config update transaction")
+	}
+	chdr := &cb.ChannelHeader{}
+	if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), chdr); err != nil {
+		return "", nil, errors.Wrap(err, "invalid config update transaction")
+	}
+	if chdr.Type != int32(cb.HeaderType_CONFIG_UPDATE) {
+		return "", nil, errors.Errorf("the transaction is a %s, not a config update", cb.HeaderType(chdr.Type))
+	}
+	configUpdateEnv := &cb.ConfigUpdateEnvelope{}
+	if err := proto.Unmarshal(payload.Data, configUpdateEnv); err != nil {
+		return "", nil, errors.Wrap(err, "invalid config update")
+	}
+	return chdr.ChannelId, configUpdateEnv, nil
+}
+
+// newConfigUpdateTx wraps a config update envelope in a transaction, signed
+// by the identity when it's set
+func newConfigUpdateTx(channelID string, configUpdateEnv *cb.ConfigUpdateEnvelope, id *Identity) (*cb.Envelope, error) {
+	data, err := proto.Marshal(configUpdateEnv)
+	if err != nil {
+		return nil, err
+	}
+	chdr, err := proto.Marshal(&cb.ChannelHeader{
+		Type:      int32(cb.HeaderType_CONFIG_UPDATE),
+		ChannelId: channelID,
+		Timestamp: timestamppb.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var signatureHeader []byte
+	if id != nil {
+		creator, err := serializeIdentity(*id)
+		if err != nil {
+			return nil, err
+		}
+		signatureHeader, err = newSignatureHeader(creator)
+		if err != nil {
+			return nil, err
+		}
+	}
+	payload, err := proto.Marshal(&cb.Payload{
+		Header: &cb.Header{ChannelHeader: chdr, SignatureHeader: signatureHeader},
+		Data:   data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	env := &cb.Envelope{Payload: payload}
+	if id != nil {
+		env.Signature, err = sign(id.Key, payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+func serializeIdentity(id Identity) ([]byte, error) {
+	return proto.Marshal(&mb.SerializedIdentity{Mspid: id.MSPID, IdBytes: utils.EncodeX509Certificate(id.Cert)})
+}
+
+func newSignatureHeader(creator []byte) ([]byte, error) {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&cb.SignatureHeader{Creator: creator, Nonce: nonce})
+}
+
+// sign signs the SHA-256 digest of the message with a low S, Fabric rejects
+// the signatures with a high S
+func sign(key *ecdsa.PrivateKey, message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	halfOrder := new(big.Int).Rsh(key.Curve.Params().N, 1)
+	if s.Cmp(halfOrder) > 0 {
+		s.Sub(key.Curve.Params().N, s)
+	}
+	return asn1.Marshal(struct{ R, S *big.Int }{r, s})
+}
+
+// Consenter is a consenter of the ordering service of a channel, the orderers
+// of the cluster connect to each other on its address
+type Consenter struct {
+	Address string
+	// ServerTLSCert is the TLS certificate in PEM the consenter presents
+	ServerTLSCert []byte
+}
+
+// Consenters returns the consenters of the etcdraft or BFT ordering service
+// of the config of a channel
+func Consenters(config *cb.Config) ([]Consenter, error) {
+	ordererGroup := config.GetChannelGroup().GetGroups()[configtx.OrdererGroupKey]
+	consensusType := &ob.ConsensusType{}
+	if err := proto.Unmarshal(ordererGroup.GetValues()["ConsensusType"].GetValue(), consensusType); err != nil {
+		return nil, errors.Wrap(err, "invalid consensus type")
+	}
+	consenters := []Consenter{}
+	switch consensusType.Type {
+	case ConsensusTypeEtcdRaft:
+		metadata := &etcdraft.ConfigMetadata{}
+		if err := proto.Unmarshal(consensusType.Metadata, metadata); err != nil {
+			return nil, errors.Wrap(err, "invalid etcdraft metadata")
+		}
+		for _, c := range metadata.Consenters {
+			consenters = append(consenters, Consenter{
+				Address:       net.JoinHostPort(c.Host, strconv.Itoa(int(c.Port))),
+				ServerTLSCert: c.ServerTlsCert,
+			})
+		}
+	case ConsensusTypeBFT:
+		orderers := &cb.Orderers{}
+		if err := proto.Unmarshal(ordererGroup.GetValues()["Orderers"].GetValue(), orderers); err != nil {
+			return nil, errors.Wrap(err, "invalid consenter mapping")
+		}
+		for _, c := range orderers.ConsenterMapping {
+			consenters = append(consenters, Consenter{
+				Address:       net.JoinHostPort(c.Host, strconv.Itoa(int(c.Port))),
+				ServerTLSCert: c.ServerTlsCert,
+			})
+		}
+	}
+	return consenters, nil
+}
@@ -0,0 +1,145 @@
+package channel
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-config/configtx"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"hlf-easy/internal/testca"
+	"reflect"
+	"testing"
+)
+
+func testOrgGroup(t *testing.T, mspID string) *cb.ConfigGroup {
+	t.Helper()
+	group, err := NewOrgDefinition(OrgOptions{
+		MSPID:     mspID,
+		CACert:    newTestCert(t, mspID+"-ca"),
+		TLSCACert: newTestCert(t, mspID+"-tlsca"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return group
+}
+
+func TestConfigUpdateSignatures(t *testing.T) {
+	original := &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			Groups: map[string]*cb.ConfigGroup{
+				configtx.ApplicationGroupKey: {
+					Groups:    map[string]*cb.ConfigGroup{"Org1MSP": testOrgGroup(t, "Org1MSP")},
+					Values:    map[string]*cb.ConfigValue{},
+					Policies:  map[string]*cb.ConfigPolicy{},
+					ModPolicy: "Admins",
+				},
+			},
+			Values:   map[string]*cb.ConfigValue{},
+			Policies: map[string]*cb.ConfigPolicy{},
+		},
+	}
+	updated := proto.Clone(original).(*cb.Config)
+	updated.ChannelGroup.Groups[configtx.ApplicationGroupKey].Groups["Org2MSP"] = testOrgGroup(t, "Org2MSP")
+
+	env, err := NewConfigUpdateTx("mychannel", original, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txBytes, err := proto.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err = ReadConfigUpdateTx(txBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers, err := ConfigUpdateSigners(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signers) != 0 {
+		t.Fatalf("expected no signers, got %v", signers)
+	}
+
+	org1Cert, org1Key := testca.Issue(t, testca.Cert{CommonName: "admin1", OUs: []string{"admin"}}, nil, nil)
+	org2Cert, org2Key := testca.Issue(t, testca.Cert{CommonName: "admin2", OUs: []string{"admin"}}, nil, nil)
+	org1 := Identity{MSPID: "Org1MSP", Cert: org1Cert, Key: org1Key}
+	env, err = SignConfigUpdate(env, org1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SignConfigUpdate(env, org1); err == nil {
+		t.Fatal("expected an error when an identity signs twice")
+	}
+	env, err = SignConfigUpdate(env, Identity{MSPID: "Org2MSP", Cert: org2Cert, Key: org2Key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers, err = ConfigUpdateSigners(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Signer{{MSPID: "Org1MSP", CommonName: "admin1"}, {MSPID: "Org2MSP", CommonName: "admin2"}}
+	if !reflect.DeepEqual(signers, expected) {
+		t.Fatalf("expected the signers %v, got %v", expected, signers)
+	}
+
+	// the signatures cover the signature header and the config update
+	channelID, configUpdateEnv, err := openConfigUpdateTx(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if channelID != "mychannel" {
+		t.Fatalf("expected channel mychannel, got %s", channelID)
+	}
+	for i, key := range []*ecdsa.PrivateKey{org1Key, org2Key} {
+		signature := configUpdateEnv.Signatures[i]
+		digest := sha256.Sum256(append(append([]byte{}, signature.SignatureHeader...), configUpdateEnv.ConfigUpdate...))
+		if !ecdsa.VerifyASN1(&key.PublicKey, digest[:], signature.Signature) {
+			t.Fatalf("invalid signature %d", i)
+		}
+	}
+	configUpdate := &cb.ConfigUpdate{}
+	if err := proto.Unmarshal(configUpdateEnv.ConfigUpdate, configUpdate); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := configUpdate.WriteSet.Groups[configtx.ApplicationGroupKey].Groups["Org2MSP"]; !ok {
+		t.Fatal("expected the update to add Org2MSP")
+	}
+}
+
+func TestAnchorPeers(t *testing.T) {
+	config := &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			Groups: map[string]*cb.ConfigGroup{
+				configtx.ApplicationGroupKey: {
+					Groups: map[string]*cb.ConfigGroup{
+						"Org1MSP": testOrgGroup(t, "Org1MSP"),
+						"Org2MSP": testOrgGroup(t, "Org2MSP"),
+					},
+					Values:    map[string]*cb.ConfigValue{},
+					Policies:  map[string]*cb.ConfigPolicy{},
+					ModPolicy: "Admins",
+				},
+			},
+			Values:   map[string]*cb.ConfigValue{},
+			Policies: map[string]*cb.ConfigPolicy{},
+		},
+	}
+	c := configtx.New(config)
+	err := c.Application().Organization("Org2MSP").AddAnchorPeer(configtx.Address{Host: "peer0.org2.example.com", Port: 7051})
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchorPeers, err := AnchorPeers(c.UpdatedConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anchorPeers) != 1 || anchorPeers[0].MSPID != "Org2MSP" || anchorPeers[0].Address != "peer0.org2.example.com:7051" {
+		t.Fatalf("expected the anchor peer of Org2MSP, got %+v", anchorPeers)
+	}
+	if len(anchorPeers[0].TLSCACerts) != 1 {
+		t.Fatalf("expected the TLS root of Org2MSP, got %d", len(anchorPeers[0].TLSCACerts))
+	}
+}
@@ -0,0 +1,374 @@
+package cluster
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/channel"
+	"hlf-easy/config"
+	"hlf-easy/log"
+	"hlf-easy/node"
+	"hlf-easy/proc"
+	"hlf-easy/utils"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DefaultStartTimeout is how long an orderer has to become healthy before
+// the next one of the cluster is started
+const DefaultStartTimeout = 60 * time.Second
+
+// Member is an orderer of the consenter set, every member listens on its own
+// ports so the cluster can run in a single host
+type Member struct {
+	ID string `json:"id"`
+	// Host and Port are the endpoint of the orderer for the other consenters,
+	// the peers and the clients, the TLS certificate is issued for the host
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	// AdminPort serves the channel participation API
+	AdminPort int `json:"adminPort"`
+	// OperationsPort serves /healthz and the metrics on 127.0.0.1
+	OperationsPort int `json:"operationsPort"`
+}
+
+// Endpoint returns the host and port of the orderer
+func (m Member) Endpoint() string {
+	return net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+}
+
+// Spec is a cluster of orderers of the host, stored in
+// $HOME/hlf-easy/clusters/<name>.json
+type Spec struct {
+	Name string `json:"name"`
+	// MSPID of the orderer organization
+	MSPID string `json:"mspID"`
+	// CAName is the local CA that issues the certificates of the orderers
+	CAName string `json:"caName"`
+	// ConsensusType of the channels of the cluster, etcdraft when empty or BFT
+	ConsensusType string   `json:"consensusType,omitempty"`
+	Members       []Member `json:"members"`
+}
+
+// bft returns whether the consenters of the cluster run SmartBFT
+func (s Spec) bft() bool {
+	return s.ConsensusType == channel.ConsensusTypeBFT
+}
+
+// Validate checks that the members are unique and don't share ports
+func (s Spec) Validate() error {
+	if s.Name == "" {
+		return errors.New("the name of the cluster is required")
+	}
+	if s.MSPID == "" {
+		return errors.New("the MSP ID of the cluster is required")
+	}
+	if s.CAName == "" {
+		return errors.New("the CA of the cluster is required")
+	}
+	if s.ConsensusType != "" && s.ConsensusType != channel.ConsensusTypeEtcdRaft && !s.bft() {
+		return errors.Errorf("unknown consensus type %s, expected %s or %s", s.ConsensusType, channel.ConsensusTypeEtcdRaft, channel.ConsensusTypeBFT)
+	}
+	if len(s.Members) == 0 {
+		return errors.New("the cluster needs at least one orderer")
+	}
+	ids := map[string]bool{}
+	ports := map[int]string{}
+	for _, m := range s.Members {
+		if m.ID == "" || m.Host == "" {
+			return errors.Errorf("the orderer %q needs an ID and a host", m.Endpoint())
+		}
+		if ids[m.ID] {
+			return errors.Errorf("the orderer %s is listed twice", m.ID)
+		}
+		ids[m.ID] = true
+		for _, port := range []int{m.Port, m.AdminPort, m.OperationsPort} {
+			if port <= 0 || port > 65535 {
+				return errors.Errorf("invalid port %d of orderer %s", port, m.ID)
+			}
+			// the orderers of the cluster run in the same host
+			if other, ok := ports[port]; ok {
+				return errors.Errorf("the port %d of orderer %s is already used by orderer %s", port, m.ID, other)
+			}
+			ports[port] = m.ID
+		}
+	}
+	return nil
+}
+
+// FaultTolerance returns how many consenters can be down while the cluster
+// still has a quorum, a BFT cluster tolerates as many malicious consenters
+func (s Spec) FaultTolerance() int {
+	if s.bft() {
+		return (len(s.Members) - 1) / 3
+	}
+	return (len(s.Members) - 1) / 2
+}
+
+func getClustersDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/clusters"), nil
+}
+
+// Get returns the spec of a cluster initialized with Init
+func Get(name string) (*Spec, error) {
+	clustersDir, err := getClustersDir()
+	if err != nil {
+		return nil, err
+	}
+	specBytes, err := os.ReadFile(filepath.Join(clustersDir, name+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Errorf("cluster %s does not exist, initialize it with orderer cluster init", name)
+		}
+		return nil, err
+	}
+	spec := &Spec{}
+	err = json.Unmarshal(specBytes, spec)
+	if err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func saveSpec(spec Spec) error {
+	clustersDir, err := getClustersDir()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(clustersDir, 0755)
+	if err != nil {
+		return err
+	}
+	specBytes, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(clustersDir, spec.Name+".json"), specBytes, 0644)
+}
+
+// enrollOrderer is replaced in the tests
+var enrollOrderer = node.EnrollOrdererCertificates
+
+// Init enrolls the orderers of the cluster with the local CA, their TLS
+// certificates are issued for the host of the member so the consenters can
+// authenticate each other, and saves the spec of the cluster. The context
+// bounds the wait for the locks of the orderers
+func Init(ctx context.Context, spec Spec) error {
+	err := spec.Validate()
+	if err != nil {
+		return err
+	}
+	// the orderers beyond 2f+1 for Raft and 3f+1 for BFT don't tolerate more failures
+	if spec.bft() && (len(spec.Members)-1)%3 != 0 {
+		log.Warnf("The BFT cluster %s has %d orderers, it tolerates as many failures as %d orderers", spec.Name, len(spec.Members), 3*spec.FaultTolerance()+1)
+	} else if !spec.bft() && len(spec.Members)%2 == 0 {
+		log.Warnf("The cluster %s has %d orderers, it tolerates as many failures as %d orderers", spec.Name, len(spec.Members), len(spec.Members)-1)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	for _, m := range spec.Members {
+		err = enrollOrderer(ctx, config.OrdererInitOptions{
+			ID:     m.ID,
+			Local:  true,
+			CAName: spec.CAName,
+			Hosts:  []string{m.Host},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to enroll orderer %s", m.ID)
+		}
+		// an orderer enrolled before keeps its certificates, they must be
+		// valid for the host of the consenter
+		tlsCert, err := readTLSCert(filepath.Join(home, "hlf-easy/orderers", m.ID))
+		if err != nil {
+			return err
+		}
+		if err := tlsCert.VerifyHostname(m.Host); err != nil {
+			return errors.Wrapf(err, "the TLS certificate of the existing orderer %s isn't valid for the consenter host", m.ID)
+		}
+	}
+	return saveSpec(spec)
+}
+
+// Bundle returns the orderer bundle of the cluster, the consenters of the
+// channels created with it are the members of the cluster. The consenters of
+// a BFT cluster are mapped to the signing certificate of the orderer and their
+// position in the cluster as ID
+func Bundle(spec Spec) (*config.OrdererBundle, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	caConfig, err := utils.GetCAConfig(spec.CAName)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &config.OrdererBundle{
+		MSPID:         spec.MSPID,
+		CACerts:       []string{string(utils.EncodeX509Certificate(caConfig.CACert))},
+		TLSCACerts:    []string{string(utils.EncodeX509Certificate(caConfig.TLSCACert))},
+		ConsensusType: channel.ConsensusTypeEtcdRaft,
+	}
+	if spec.bft() {
+		bundle.ConsensusType = channel.ConsensusTypeBFT
+	}
+	for i, m := range spec.Members {
+		ordererDir := filepath.Join(home, "hlf-easy/orderers", m.ID)
+		tlsCert, err := readTLSCert(ordererDir)
+		if err != nil {
+			return nil, err
+		}
+		orderer := config.BundleOrderer{
+			Host:     m.Host,
+			Port:     m.Port,
+			AdminURL: fmt.Sprintf("https://%s", net.JoinHostPort(m.Host, strconv.Itoa(m.AdminPort))),
+			TLSCert:  string(utils.EncodeX509Certificate(tlsCert)),
+		}
+		if spec.bft() {
+			identity, err := os.ReadFile(filepath.Join(ordererDir, "signcerts/cert.pem"))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read the identity of orderer %s", m.ID)
+			}
+			orderer.ID = uint32(i + 1)
+			orderer.Identity = string(identity)
+		}
+		bundle.Orderers = append(bundle.Orderers, orderer)
+	}
+	return bundle, utils.ValidateOrdererBundle(bundle)
+}
+
+func readTLSCert(ordererDir string) (*x509.Certificate, error) {
+	tlsCertBytes, err := os.ReadFile(filepath.Join(ordererDir, "tls.crt"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the TLS certificate of orderer %s", filepath.Base(ordererDir))
+	}
+	return utils.ParseX509Certificate(tlsCertBytes)
+}
+
+// StartResult is the result of starting a member of the cluster
+type StartResult struct {
+	ID     string `json:"id"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Results of starting a member
+const (
+	StartResultStarted = "started"
+	StartResultRunning = "running"
+	StartResultFailed  = "failed"
+)
+
+// StartArgs are the arguments of the hlf-easy command that starts a member
+func StartArgs(spec Spec, m Member) []string {
+	return []string{"orderer", "start",
+		"--id", m.ID,
+		"--msp-id", spec.MSPID,
+		"--listen-address", net.JoinHostPort("0.0.0.0", strconv.Itoa(m.Port)),
+		"--admin-listen-address", net.JoinHostPort("0.0.0.0", strconv.Itoa(m.AdminPort)),
+		"--operations-listen-address", net.JoinHostPort("127.0.0.1", strconv.Itoa(m.OperationsPort)),
+		"--external-endpoint", m.Endpoint(),
+	}
+}
+
+// startOrderer starts the hlf-easy process of a member in the background, its
+// output is written to hlf-easy.log in the directory of the orderer. It's
+// replaced in the tests
+var startOrderer = func(spec Spec, m Member, ordererDir string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(filepath.Join(ordererDir, "hlf-easy.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	cmd := exec.Command(executable, StartArgs(spec, m)...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	proc.NewProcessGroup(cmd)
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// waitHealthy waits for the operations service of a member to be healthy.
+// It's replaced in the tests
+var waitHealthy = func(ctx context.Context, m Member) error {
+	return errors.Wrapf(WaitHealthy(ctx, m.OperationsPort), "orderer %s isn't healthy", m.ID)
+}
+
+// WaitHealthy polls the /healthz of the operations service listening on a
+// port of 127.0.0.1 until it answers 200 or the context is done, the error
+// of the context is returned with the one of the last poll
+func WaitHealthy(ctx context.Context, operationsPort int) error {
+	url := fmt.Sprintf("http://%s/healthz", net.JoinHostPort("127.0.0.1", strconv.Itoa(operationsPort)))
+	client := &http.Client{Timeout: 5 * time.Second}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = errors.Errorf("status %d", resp.StatusCode)
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "%v", err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Start starts the orderers of the cluster one at a time, each one must be
+// healthy before the next one is started so a quorum forms as soon as a
+// majority is up. The running orderers are skipped, the start stops at the
+// first orderer that doesn't become healthy within the timeout or when the
+// context is done
+func Start(ctx context.Context, spec Spec, timeout time.Duration) ([]StartResult, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	var results []StartResult
+	for _, m := range spec.Members {
+		ordererDir := filepath.Join(home, "hlf-easy/orderers", m.ID)
+		if _, err := os.Stat(filepath.Join(ordererDir, "run.json")); err == nil {
+			results = append(results, StartResult{ID: m.ID, Result: StartResultRunning})
+			continue
+		}
+		err = ctx.Err()
+		if err == nil {
+			err = startOrderer(spec, m, ordererDir)
+		}
+		if err == nil {
+			healthyCtx, cancel := context.WithTimeout(ctx, timeout)
+			err = waitHealthy(healthyCtx, m)
+			cancel()
+		}
+		if err != nil {
+			results = append(results, StartResult{ID: m.ID, Result: StartResultFailed, Error: err.Error()})
+			return results, errors.Wrapf(err, "failed to start orderer %s, see %s", m.ID, filepath.Join(ordererDir, "hlf-easy.log"))
+		}
+		results = append(results, StartResult{ID: m.ID, Result: StartResultStarted})
+	}
+	return results, nil
+}
@@ -0,0 +1,234 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/channel"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testSpec() Spec {
+	return Spec{
+		Name:   "raft",
+		MSPID:  "OrdererMSP",
+		CAName: "orderer-ca",
+		Members: []Member{
+			{ID: "orderer0", Host: "orderer0.example.com", Port: 7050, AdminPort: 7053, OperationsPort: 9443},
+			{ID: "orderer1", Host: "orderer1.example.com", Port: 8050, AdminPort: 8053, OperationsPort: 9444},
+			{ID: "orderer2", Host: "orderer2.example.com", Port: 9050, AdminPort: 9053, OperationsPort: 9445},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	spec := testSpec()
+	if err := spec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if spec.FaultTolerance() != 1 {
+		t.Errorf("expected 3 orderers to tolerate 1 failure, got %d", spec.FaultTolerance())
+	}
+	spec.Members[2].AdminPort = 8053
+	if err := spec.Validate(); err == nil {
+		t.Error("expected an error for a port used by two orderers")
+	}
+	spec = testSpec()
+	spec.Members[2].ID = "orderer0"
+	if err := spec.Validate(); err == nil {
+		t.Error("expected an error for an orderer listed twice")
+	}
+}
+
+// writeTestCA writes the local CA orderer-ca and returns a function issuing
+// the TLS certificates of the orderers with it
+func writeTestCA(t *testing.T, home string) func(ctx context.Context, opts config.OrdererInitOptions) error {
+	t.Helper()
+	caCert, caKey := testca.NewCA(t, "ca")
+	tlsCACert, tlsCAKey := testca.NewCA(t, "tlsca")
+	caKeyBytes, err := utils.EncodePrivateKey(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsCAKeyBytes, err := utils.EncodePrivateKey(tlsCAKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caConfigBytes, err := json.Marshal(config.CAConfig{
+		CaCert:    utils.EncodeX509Certificate(caCert),
+		CaKey:     caKeyBytes,
+		CaName:    "orderer-ca",
+		TlsCACert: utils.EncodeX509Certificate(tlsCACert),
+		TlsCAKey:  tlsCAKeyBytes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caDir := filepath.Join(home, "hlf-easy/cas/orderer-ca")
+	if err := os.MkdirAll(caDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(caDir, "config.json"), caConfigBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return func(ctx context.Context, opts config.OrdererInitOptions) error {
+		ordererDir := filepath.Join(home, "hlf-easy/orderers", opts.ID)
+		if _, err := os.Stat(filepath.Join(ordererDir, "tls.crt")); err == nil {
+			return nil
+		}
+		tlsCert, _ := testca.Issue(t, testca.Cert{CommonName: "orderer", DNSNames: opts.Hosts}, tlsCACert, tlsCAKey)
+		signCert, _ := testca.Issue(t, testca.Cert{CommonName: opts.ID, OUs: []string{"orderer"}}, caCert, caKey)
+		if err := os.MkdirAll(filepath.Join(ordererDir, "signcerts"), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(ordererDir, "signcerts/cert.pem"), utils.EncodeX509Certificate(signCert), 0644); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(ordererDir, "tls.crt"), utils.EncodeX509Certificate(tlsCert), 0644)
+	}
+}
+
+func TestInitAndBundle(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	defer func(original func(context.Context, config.OrdererInitOptions) error) { enrollOrderer = original }(enrollOrderer)
+	enrollOrderer = writeTestCA(t, home)
+
+	spec := testSpec()
+	err := Init(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Get("raft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*saved, spec) {
+		t.Fatalf("expected the saved spec %+v, got %+v", spec, *saved)
+	}
+	bundle, err := Bundle(*saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Orderers) != 3 || bundle.Orderers[1].Host != "orderer1.example.com" || bundle.Orderers[1].Port != 8050 {
+		t.Fatalf("expected the members as consenters, got %+v", bundle.Orderers)
+	}
+	if bundle.Orderers[1].AdminURL != "https://orderer1.example.com:8053" {
+		t.Errorf("expected the admin URL of orderer1, got %s", bundle.Orderers[1].AdminURL)
+	}
+
+	// an existing orderer with a certificate for another host can't join
+	spec.Members[0].Host = "other.example.com"
+	if err := Init(context.Background(), spec); err == nil {
+		t.Fatal("expected an error for an orderer enrolled for another host")
+	}
+}
+
+func TestBundleBFT(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	defer func(original func(context.Context, config.OrdererInitOptions) error) { enrollOrderer = original }(enrollOrderer)
+	enrollOrderer = writeTestCA(t, home)
+
+	spec := testSpec()
+	spec.ConsensusType = channel.ConsensusTypeBFT
+	spec.Members = append(spec.Members, Member{ID: "orderer3", Host: "orderer3.example.com", Port: 10050, AdminPort: 10053, OperationsPort: 9446})
+	if spec.FaultTolerance() != 1 {
+		t.Errorf("expected 4 BFT orderers to tolerate 1 failure, got %d", spec.FaultTolerance())
+	}
+	if err := Init(context.Background(), spec); err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := Bundle(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.ConsensusType != channel.ConsensusTypeBFT {
+		t.Fatalf("expected a BFT bundle, got %s", bundle.ConsensusType)
+	}
+	for i, orderer := range bundle.Orderers {
+		identity, err := utils.ParseX509Certificate([]byte(orderer.Identity))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if orderer.ID != uint32(i+1) || identity.Subject.CommonName != spec.Members[i].ID {
+			t.Errorf("expected orderer %s to be consenter %d, got %d with the identity of %s", spec.Members[i].ID, i+1, orderer.ID, identity.Subject.CommonName)
+		}
+	}
+
+	spec.ConsensusType = "kafka"
+	if err := spec.Validate(); err == nil {
+		t.Error("expected an error for an unknown consensus type")
+	}
+}
+
+func TestStart(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	spec := testSpec()
+	runningDir := filepath.Join(home, "hlf-easy/orderers/orderer0")
+	if err := os.MkdirAll(runningDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(runningDir, "run.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(originalStart func(Spec, Member, string) error, originalWait func(context.Context, Member) error) {
+		startOrderer, waitHealthy = originalStart, originalWait
+	}(startOrderer, waitHealthy)
+	var events []string
+	startOrderer = func(spec Spec, m Member, ordererDir string) error {
+		events = append(events, "start "+m.ID)
+		return nil
+	}
+	waitHealthy = func(ctx context.Context, m Member) error {
+		if m.ID == "orderer2" {
+			return errors.New("connection refused")
+		}
+		events = append(events, "healthy "+m.ID)
+		return nil
+	}
+
+	results, err := Start(context.Background(), spec, time.Second)
+	if err == nil {
+		t.Fatal("expected an error for the orderer that isn't healthy")
+	}
+	expected := []string{"start orderer1", "healthy orderer1", "start orderer2"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+	statuses := []string{}
+	for _, r := range results {
+		statuses = append(statuses, r.Result)
+	}
+	if !reflect.DeepEqual(statuses, []string{StartResultRunning, StartResultStarted, StartResultFailed}) {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestStartCanceled(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer func(originalStart func(Spec, Member, string) error) {
+		startOrderer = originalStart
+	}(startOrderer)
+	startOrderer = func(spec Spec, m Member, ordererDir string) error {
+		t.Errorf("expected orderer %s not to be started", m.ID)
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := Start(ctx, testSpec(), time.Second)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the start to be canceled, got %v", err)
+	}
+	if len(results) != 1 || results[0].Result != StartResultFailed {
+		t.Errorf("expected the first orderer to fail, got %+v", results)
+	}
+}
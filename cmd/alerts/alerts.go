@@ -0,0 +1,19 @@
+package alerts
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewAlertsCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alerts",
+		Short: "Configure the rules that raise alerts on the health of the nodes",
+	}
+	cmd.AddCommand(
+		newApplyCommand(out, errOut),
+		newValidateCommand(out, errOut),
+		newListRulesCommand(out, errOut),
+	)
+	return cmd
+}
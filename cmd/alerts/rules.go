@@ -0,0 +1,156 @@
+package alerts
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/alerts"
+	"hlf-easy/config"
+	"hlf-easy/output"
+	"hlf-easy/utils"
+	"io"
+	"os"
+	"strings"
+)
+
+func readRulesFile(file string) (*config.AlertsConfig, error) {
+	rulesBytes, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return alerts.ParseRules(rulesBytes)
+}
+
+type applyCmd struct {
+	file string
+}
+
+func (c *applyCmd) validate() error {
+	if c.file == "" {
+		return errors.New("--file is required")
+	}
+	return nil
+}
+
+func (c *applyCmd) run(out io.Writer, errOut io.Writer) error {
+	alertsConfig, err := readRulesFile(c.file)
+	if err != nil {
+		return err
+	}
+	// the rules of the file replace the configured ones
+	err = utils.SaveAlertsConfig(alertsConfig)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d rules applied, the running nodes evaluate them on their next check\n", len(alertsConfig.Rules))
+	return nil
+}
+
+func newApplyCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &applyCmd{}
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Replace the alert rules of the host with the rules of a file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.file, "file", "", "Rules file, in YAML or JSON")
+	return cmd
+}
+
+type validateCmd struct {
+	file string
+}
+
+func (c *validateCmd) validate() error {
+	if c.file == "" {
+		return errors.New("--file is required")
+	}
+	return nil
+}
+
+func (c *validateCmd) run(out io.Writer, errOut io.Writer) error {
+	alertsConfig, err := readRulesFile(c.file)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d rules are valid\n", len(alertsConfig.Rules))
+	return nil
+}
+
+func newValidateCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &validateCmd{}
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check a rules file without applying it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.file, "file", "", "Rules file, in YAML or JSON")
+	return cmd
+}
+
+type listRulesCmd struct {
+}
+
+func (c *listRulesCmd) validate() error {
+	return nil
+}
+
+func (c *listRulesCmd) run(out io.Writer, errOut io.Writer) error {
+	alertsConfig, err := utils.GetAlertsConfig()
+	if err != nil {
+		return err
+	}
+	rules := alertsConfig.Rules
+	return output.Print(out, rules, func(w io.Writer) error {
+		for _, r := range rules {
+			nodes := r.Kind
+			if nodes == "" {
+				nodes = "all"
+			}
+			if len(r.IDs) > 0 {
+				nodes = fmt.Sprintf("%s %s", nodes, strings.Join(r.IDs, ","))
+			}
+			duration := r.For
+			if duration == "" {
+				duration = "-"
+			}
+			actions := []string{}
+			for _, action := range r.Actions {
+				actions = append(actions, action.Type)
+			}
+			state := "enabled"
+			if r.Disabled {
+				state = "disabled"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%g\t%s\t%s\t%s\t%s\n", r.Name, r.Condition, r.Threshold, duration, nodes, strings.Join(actions, ","), state)
+		}
+		return nil
+	})
+}
+
+func newListRulesCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &listRulesCmd{}
+	cmd := &cobra.Command{
+		Use:   "list-rules",
+		Short: "List the alert rules of the host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,20 @@
+package anomaly
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewAnomalyCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "anomaly",
+		Short: "Configure the rules that detect anomalies in the logs of the nodes",
+	}
+	cmd.AddCommand(
+		newAddRuleCommand(out, errOut),
+		newRemoveRuleCommand(out, errOut),
+		newListRulesCommand(out, errOut),
+		newScanCommand(out, errOut),
+	)
+	return cmd
+}
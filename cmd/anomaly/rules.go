@@ -0,0 +1,172 @@
+package anomaly
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/anomaly"
+	"hlf-easy/config"
+	"hlf-easy/output"
+	"hlf-easy/utils"
+	"io"
+)
+
+type addRuleCmd struct {
+	rule config.AnomalyRule
+}
+
+func (c *addRuleCmd) validate() error {
+	return anomaly.ValidateRule(c.rule)
+}
+
+func (c *addRuleCmd) run(out io.Writer, errOut io.Writer) error {
+	anomalyConfig, err := utils.GetAnomalyConfig()
+	if err != nil {
+		return err
+	}
+	// adding a rule again replaces it, a default rule is overridden by name
+	rules := []config.AnomalyRule{}
+	for _, r := range anomalyConfig.Rules {
+		if r.Name != c.rule.Name {
+			rules = append(rules, r)
+		}
+	}
+	anomalyConfig.Rules = append(rules, c.rule)
+	err = utils.SaveAnomalyConfig(anomalyConfig)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Rule %s added, restart the nodes to apply it\n", c.rule.Name)
+	return nil
+}
+
+func newAddRuleCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &addRuleCmd{}
+	cmd := &cobra.Command{
+		Use:   "add-rule",
+		Short: "Add or replace a rule, a default rule is replaced by a rule with its name",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.rule.Name, "name", "", "Name of the rule")
+	f.StringVar(&c.rule.Pattern, "pattern", "", "Regular expression matched against each log line")
+	f.StringVar(&c.rule.Severity, "severity", anomaly.SeverityWarning, "Severity of the alerts: info, warning or critical")
+	f.IntVar(&c.rule.Threshold, "threshold", 1, "Number of matches within the window that raise an alert")
+	f.StringVar(&c.rule.Window, "window", "", "Window in which the matches are counted, e.g. 5m")
+	return cmd
+}
+
+type removeRuleCmd struct {
+	name string
+}
+
+func (c *removeRuleCmd) validate() error {
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	return nil
+}
+
+func (c *removeRuleCmd) run(out io.Writer, errOut io.Writer) error {
+	anomalyConfig, err := utils.GetAnomalyConfig()
+	if err != nil {
+		return err
+	}
+	rules := []config.AnomalyRule{}
+	for _, r := range anomalyConfig.Rules {
+		if r.Name != c.name {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == len(anomalyConfig.Rules) {
+		return errors.Errorf("rule %s not found, default rules can only be disabled all at once", c.name)
+	}
+	anomalyConfig.Rules = rules
+	err = utils.SaveAnomalyConfig(anomalyConfig)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Rule %s removed\n", c.name)
+	return nil
+}
+
+func newRemoveRuleCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &removeRuleCmd{}
+	cmd := &cobra.Command{
+		Use:   "remove-rule",
+		Short: "Remove a configured rule",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.name, "name", "", "Name of the rule")
+	return cmd
+}
+
+type listRulesCmd struct {
+	disableDefaults bool
+	enableDefaults  bool
+}
+
+func (c *listRulesCmd) validate() error {
+	if c.disableDefaults && c.enableDefaults {
+		return errors.New("--disable-defaults can't be used with --enable-defaults")
+	}
+	return nil
+}
+
+func (c *listRulesCmd) run(out io.Writer, errOut io.Writer) error {
+	anomalyConfig, err := utils.GetAnomalyConfig()
+	if err != nil {
+		return err
+	}
+	if c.disableDefaults || c.enableDefaults {
+		anomalyConfig.DisableDefaultRules = c.disableDefaults
+		err = utils.SaveAnomalyConfig(anomalyConfig)
+		if err != nil {
+			return err
+		}
+	}
+	rules := anomaly.Rules(*anomalyConfig)
+	return output.Print(out, rules, func(w io.Writer) error {
+		for _, r := range rules {
+			threshold := r.Threshold
+			if threshold == 0 {
+				threshold = 1
+			}
+			window := r.Window
+			if window == "" {
+				window = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d in %s\t%s\n", r.Name, r.Severity, threshold, window, r.Pattern)
+		}
+		return nil
+	})
+}
+
+func newListRulesCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &listRulesCmd{}
+	cmd := &cobra.Command{
+		Use:   "list-rules",
+		Short: "List the rules applied to the logs, the default ones included",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVar(&c.disableDefaults, "disable-defaults", false, "Stop applying the default rules")
+	f.BoolVar(&c.enableDefaults, "enable-defaults", false, "Apply the default rules again")
+	return cmd
+}
@@ -0,0 +1,72 @@
+package anomaly
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/anomaly"
+	"hlf-easy/output"
+	"hlf-easy/utils"
+	"io"
+	"os"
+)
+
+type scanCmd struct {
+	file string
+}
+
+func (c *scanCmd) validate() error {
+	if c.file == "" {
+		return errors.New("--file is required")
+	}
+	return nil
+}
+
+func (c *scanCmd) run(out io.Writer, errOut io.Writer) error {
+	anomalyConfig, err := utils.GetAnomalyConfig()
+	if err != nil {
+		return err
+	}
+	// the alerts are streamed in the table format
+	alerts := []anomaly.Alert{}
+	scanner, err := anomaly.NewScanner(anomaly.Rules(*anomalyConfig), func(alert anomaly.Alert) {
+		if output.Format == output.Table {
+			fmt.Fprintf(out, "%s\t%s\t%d\t%s\n", alert.Severity, alert.Rule, alert.Count, alert.Line)
+			return
+		}
+		alerts = append(alerts, alert)
+	})
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(c.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(scanner, f)
+	if err != nil {
+		return err
+	}
+	scanner.Flush()
+	return output.Print(out, alerts, func(w io.Writer) error {
+		return nil
+	})
+}
+
+func newScanCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &scanCmd{}
+	cmd := &cobra.Command{
+		Use:   "scan",
+		Short: "Scan a log file with the rules and print the alerts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.file, "file", "", "Log file to scan")
+	return cmd
+}
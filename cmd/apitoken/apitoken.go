@@ -0,0 +1,21 @@
+package apitoken
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewAPITokenCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apitoken",
+		Short: "Manage the tokens of the management APIs of the host",
+	}
+	cmd.AddCommand(
+		newCreateCommand(out, errOut),
+		newListCommand(out, errOut),
+		newRevokeCommand(out, errOut),
+		newApplyPoliciesCommand(out, errOut),
+		newListRolesCommand(out, errOut),
+	)
+	return cmd
+}
@@ -0,0 +1,117 @@
+package apitoken
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/auth"
+	"hlf-easy/output"
+	"hlf-easy/utils"
+	"io"
+	"os"
+	"strings"
+)
+
+type applyPoliciesCmd struct {
+	file string
+}
+
+func (c *applyPoliciesCmd) validate() error {
+	if c.file == "" {
+		return errors.New("--file is required")
+	}
+	return nil
+}
+
+func (c *applyPoliciesCmd) run(out io.Writer, errOut io.Writer) error {
+	policiesBytes, err := os.ReadFile(c.file)
+	if err != nil {
+		return err
+	}
+	apiPolicies, err := auth.ParsePolicies(policiesBytes)
+	if err != nil {
+		return err
+	}
+	// the roles of the file replace the configured ones
+	err = utils.SaveAPIPolicies(apiPolicies)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d roles applied, the management APIs use them on their next request\n", len(apiPolicies.Roles))
+	return nil
+}
+
+func newApplyPoliciesCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &applyPoliciesCmd{}
+	cmd := &cobra.Command{
+		Use:   "apply-policies",
+		Short: "Replace the roles of the management APIs with the roles of a policies file",
+		Long: `Replace the roles of the management APIs with the roles of a policies file.
+A role grants actions, read or write, on resources of the APIs: nodes,
+logspec, tasks, audit, alerts, channels, chaincodes, identities and host,
+* matches all of them. The GET routes read their resource and the other
+routes write it.
+
+The default roles, viewer, operator and admin, are kept unless the file
+defines a role of the same name.`,
+		Example: `  # policies.yaml
+  roles:
+    - name: deployer
+      rules:
+        - resources: [chaincodes, channels]
+          actions: ["*"]
+        - resources: ["*"]
+          actions: [read]
+
+  hlf-easy apitoken apply-policies --file=policies.yaml
+  hlf-easy apitoken create --name=ci --role=deployer`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.file, "file", "", "Policies file, in YAML or JSON")
+	return cmd
+}
+
+type listRolesCmd struct{}
+
+func (c *listRolesCmd) validate() error {
+	return nil
+}
+
+func (c *listRolesCmd) run(out io.Writer, errOut io.Writer) error {
+	apiPolicies, err := auth.GetPolicies()
+	if err != nil {
+		return err
+	}
+	roles := apiPolicies.Roles
+	return output.Print(out, roles, func(w io.Writer) error {
+		for _, role := range roles {
+			rules := []string{}
+			for _, rule := range role.Rules {
+				rules = append(rules, fmt.Sprintf("%s:%s", strings.Join(rule.Resources, ","), strings.Join(rule.Actions, ",")))
+			}
+			fmt.Fprintf(w, "%s\t%s\n", role.Name, strings.Join(rules, " "))
+		}
+		return nil
+	})
+}
+
+func newListRolesCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &listRolesCmd{}
+	cmd := &cobra.Command{
+		Use:   "list-roles",
+		Short: "List the roles of the management APIs with the actions they grant",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,134 @@
+package apitoken
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/auth"
+	"hlf-easy/output"
+	"hlf-easy/utils"
+	"io"
+	"time"
+)
+
+type createCmd struct {
+	name string
+	role string
+}
+
+func (c *createCmd) validate() error {
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	return auth.ValidateRole(c.role)
+}
+
+func (c *createCmd) run(out io.Writer, errOut io.Writer) error {
+	token, apiToken, err := auth.CreateToken(c.name, c.role, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(errOut, "Token %s created with the %s role, it won't be shown again\n", apiToken.ID, apiToken.Role)
+	fmt.Fprintln(out, token)
+	return nil
+}
+
+func newCreateCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &createCmd{}
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a token, it's sent as a bearer token to the management APIs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.name, "name", "", "Name of the token, e.g. who or what uses it")
+	f.StringVar(&c.role, "role", auth.RoleViewer, "Role of the token: viewer, operator, admin or a role of the policies file")
+	return cmd
+}
+
+type listCmd struct{}
+
+func (c *listCmd) validate() error {
+	return nil
+}
+
+func (c *listCmd) run(out io.Writer, errOut io.Writer) error {
+	apiTokens, err := utils.GetAPITokens()
+	if err != nil {
+		return err
+	}
+	// the hashes of the tokens aren't listed
+	type token struct {
+		ID        string    `json:"id"`
+		Name      string    `json:"name"`
+		Role      string    `json:"role"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+	tokens := []token{}
+	for _, t := range apiTokens.Tokens {
+		tokens = append(tokens, token{ID: t.ID, Name: t.Name, Role: t.Role, CreatedAt: t.CreatedAt})
+	}
+	return output.Print(out, tokens, func(w io.Writer) error {
+		for _, t := range tokens {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Role, t.CreatedAt.Format(time.RFC3339))
+		}
+		return nil
+	})
+}
+
+func newListCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &listCmd{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the tokens with their name and role",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
+
+type revokeCmd struct {
+	id string
+}
+
+func (c *revokeCmd) validate() error {
+	if c.id == "" {
+		return errors.New("--id is required")
+	}
+	return nil
+}
+
+func (c *revokeCmd) run(out io.Writer, errOut io.Writer) error {
+	err := auth.RevokeToken(c.id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Token revoked")
+	return nil
+}
+
+func newRevokeCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &revokeCmd{}
+	cmd := &cobra.Command{
+		Use:   "revoke",
+		Short: "Revoke a token, the management APIs refuse it right away",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.id, "id", "", "ID of the token")
+	return cmd
+}
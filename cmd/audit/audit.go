@@ -0,0 +1,17 @@
+package audit
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewAuditCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Query the audit log of the operations that changed the host",
+	}
+	cmd.AddCommand(
+		newListCommand(out, errOut),
+	)
+	return cmd
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"hlf-easy/audit"
+	"hlf-easy/output"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type listCmd struct {
+	operation string
+	actor     string
+	target    string
+	since     string
+	limit     int
+}
+
+func (c *listCmd) validate() error {
+	return nil
+}
+
+func formatParams(params map[string]string) string {
+	names := []string{}
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := []string{}
+	for _, name := range names {
+		parts = append(parts, name+"="+params[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+func (c *listCmd) run(out io.Writer, errOut io.Writer) error {
+	f, err := audit.ParseFilter(c.operation, c.actor, c.target, c.since, strconv.Itoa(c.limit))
+	if err != nil {
+		return err
+	}
+	entries, err := audit.Query(f)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, entries, func(w io.Writer) error {
+		for _, entry := range entries {
+			result := entry.Result
+			if entry.Error != "" {
+				result += ": " + entry.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", entry.Time.Format(time.RFC3339), entry.Actor, entry.Operation, entry.Target, result, formatParams(entry.Params))
+		}
+		return nil
+	})
+}
+
+func newListCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &listCmd{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the operations of the audit log, oldest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.operation, "operation", "", "Operation, or prefix of the operations, e.g. peer or peer.init")
+	f.StringVar(&c.actor, "actor", "", "Actor of the operations, e.g. user:alice or token:ops")
+	f.StringVar(&c.target, "target", "", "Node, chaincode or channel the operations were run on")
+	f.StringVar(&c.since, "since", "", "RFC3339 time or duration before now, e.g. 24h")
+	f.IntVar(&c.limit, "limit", 100, "Maximum number of operations, the last ones are kept, 0 for all")
+	return cmd
+}
@@ -0,0 +1,38 @@
+package backup
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"hlf-easy/backup"
+	"io"
+)
+
+func NewBackupCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "List and restore the backups of the nodes taken by the backup tasks",
+	}
+	cmd.AddCommand(
+		newListCommand(out, errOut),
+		newRestoreCommand(out, errOut),
+	)
+	return cmd
+}
+
+// storageFlags select the storage of the backups
+type storageFlags struct {
+	target string
+	creds  backup.Credentials
+}
+
+func (s *storageFlags) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&s.target, "target", "", "Storage of the backups: a directory, s3://<bucket>/<prefix>, gs://<bucket>/<prefix>, http(s)://<endpoint>/<bucket>/<prefix> or sftp://<user>@<host>:<port>/<path>")
+	f.StringVar(&s.creds.Region, "region", "", "Region of an S3 bucket, us-east-1 by default")
+	f.StringVar(&s.creds.AccessKey, "access-key", "", "Access key of the bucket, AWS_ACCESS_KEY_ID or GS_ACCESS_KEY_ID by default")
+	f.StringVar(&s.creds.SecretKey, "secret-key", "", "Secret key of the bucket, AWS_SECRET_ACCESS_KEY or GS_SECRET_ACCESS_KEY by default")
+	f.StringVar(&s.creds.SSHKey, "ssh-key", "", "Private key of the SFTP server, the keys of the SSH agent and of ~/.ssh by default")
+}
+
+func (s *storageFlags) open() (backup.Storage, error) {
+	return backup.OpenStorage(s.target, s.creds)
+}
@@ -0,0 +1,100 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/backup"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"sort"
+	"time"
+)
+
+type listCmd struct {
+	storage storageFlags
+	kind    string
+	id      string
+	timeout time.Duration
+}
+
+// backupInfo is a backup of the storage
+type backupInfo struct {
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"`
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Location string    `json:"location"`
+}
+
+func (c *listCmd) validate() error {
+	if c.storage.target == "" {
+		return errors.New("--target is required")
+	}
+	if c.kind != "" && c.kind != "peer" && c.kind != "orderer" {
+		return errors.Errorf("invalid kind %s, expected peer or orderer", c.kind)
+	}
+	return nil
+}
+
+func (c *listCmd) run(out io.Writer) error {
+	storage, err := c.storage.open()
+	if err != nil {
+		return err
+	}
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	prefix := ""
+	if c.kind != "" {
+		prefix = c.kind + "-"
+		if c.id != "" {
+			prefix = backup.Prefix(c.kind, c.id)
+		}
+	}
+	names, err := storage.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	backups := []backupInfo{}
+	for _, name := range names {
+		kind, id, t, ok := backup.Parse(name)
+		if !ok || (c.id != "" && id != c.id) {
+			continue
+		}
+		backups = append(backups, backupInfo{Name: name, Kind: kind, ID: id, Time: t, Location: storage.Location(name)})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return output.Print(out, backups, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "NODE\tTIME\tNAME")
+		for _, b := range backups {
+			fmt.Fprintf(w, "%s %s\t%s\t%s\n", b.Kind, b.ID, b.Time.Local().Format(time.RFC3339), b.Name)
+		}
+		return w.Flush()
+	})
+}
+
+func newListCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &listCmd{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the backups of a storage, the newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	c.storage.addFlags(f)
+	f.StringVar(&c.kind, "kind", "", "Kind of the nodes of the backups: peer or orderer, all by default")
+	f.StringVar(&c.id, "id", "", "ID of the node of the backups, all by default")
+	f.DurationVar(&c.timeout, "timeout", time.Minute, "How long to wait for the storage, 0 waits without a limit")
+	return cmd
+}
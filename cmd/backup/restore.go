@@ -0,0 +1,136 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/backup"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"hlf-easy/utils"
+	"io"
+	"time"
+)
+
+type restoreCmd struct {
+	storage storageFlags
+	kind    string
+	id      string
+	name    string
+	timeout time.Duration
+}
+
+// restored is a node restored from a backup
+type restored struct {
+	Name      string            `json:"name"`
+	Dir       string            `json:"dir"`
+	Files     int               `json:"files"`
+	Snapshots []backup.Snapshot `json:"snapshots"`
+}
+
+func (c *restoreCmd) validate() error {
+	if c.storage.target == "" {
+		return errors.New("--target is required")
+	}
+	if c.kind != "peer" && c.kind != "orderer" {
+		return errors.Errorf("invalid kind %s, expected peer or orderer", c.kind)
+	}
+	if c.id == "" {
+		return errors.New("--id is required")
+	}
+	if c.name != "" {
+		if _, ok := backup.Time(c.kind, c.id, c.name); !ok {
+			return errors.Errorf("%s isn't a backup of %s %s", c.name, c.kind, c.id)
+		}
+	}
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	return nil
+}
+
+func (c *restoreCmd) run(out io.Writer) error {
+	storage, err := c.storage.open()
+	if err != nil {
+		return err
+	}
+	nodeDir, err := utils.GetNodeDir(c.kind, c.id)
+	if err != nil {
+		return err
+	}
+	// an interrupt or --timeout stops the download, the partial node is
+	// removed
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	name := c.name
+	if name == "" {
+		name, err = backup.Latest(ctx, storage, c.kind, c.id)
+		if err != nil {
+			return err
+		}
+	}
+	files, snapshots, err := backup.Restore(ctx, storage, name, nodeDir)
+	if err != nil {
+		return err
+	}
+	// the peer joins its channels from the snapshots once it's started
+	if c.kind == "peer" && len(snapshots) > 0 {
+		joins := []node.CloneJoin{}
+		for _, snapshot := range snapshots {
+			joins = append(joins, node.CloneJoin{Channel: snapshot.Channel, Snapshot: snapshot.Dir, Height: snapshot.Height})
+		}
+		if err := node.RestorePeerJoins(c.id, joins); err != nil {
+			return err
+		}
+	}
+	result := restored{Name: name, Dir: nodeDir, Files: files, Snapshots: snapshots}
+	return output.Print(out, result, func(out io.Writer) error {
+		fmt.Fprintf(out, "Restored %d files of %s %s from %s to %s\n", files, c.kind, c.id, storage.Location(name), nodeDir)
+		if len(snapshots) > 0 {
+			w := output.NewTabWriter(out)
+			fmt.Fprintln(w, "CHANNEL\tHEIGHT")
+			for _, snapshot := range snapshots {
+				fmt.Fprintf(w, "%s\t%d\n", snapshot.Channel, snapshot.Height)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			_, err := fmt.Fprintf(out, "Start it with peer start --id=%s, it joins the channels from the snapshots once it's ready\n", c.id)
+			return err
+		}
+		_, err := fmt.Fprintf(out, "Start it with %s start --id=%s and join it to its channels again\n", c.kind, c.id)
+		return err
+	})
+}
+
+func newRestoreCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &restoreCmd{}
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore a node of the host from a backup, the newest one by default",
+		Long: `Restore a node of the host from a backup taken by a backup task, after a
+disk failure or on a new host with the same home directory. The node can't
+exist on the host: its crypto material, its config and its tasks are
+extracted to its directory.
+
+The snapshots of a backup taken with ledger=true are extracted to the
+completed snapshots of the peer, and the peer joins its channels from them
+once it's started so it doesn't replay their blocks.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	c.storage.addFlags(f)
+	f.StringVar(&c.kind, "kind", "peer", "Kind of the node: peer or orderer")
+	f.StringVar(&c.id, "id", "", "ID of the node")
+	f.StringVar(&c.name, "name", "", "Name of the backup, the newest backup of the node by default")
+	f.DurationVar(&c.timeout, "timeout", 0, "How long to wait for the download of the backup, 0 waits without a limit")
+	return cmd
+}
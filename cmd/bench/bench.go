@@ -0,0 +1,118 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/bench"
+	"hlf-easy/contract"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"time"
+)
+
+type benchCmd struct {
+	contractOpts contract.Options
+	opts         bench.Options
+	args         string
+}
+
+func (c *benchCmd) validate() error {
+	if c.contractOpts.PeerID == "" {
+		return errors.New("--peer-id is required")
+	}
+	if c.contractOpts.Channel == "" {
+		return errors.New("--channel is required")
+	}
+	if c.contractOpts.Chaincode == "" {
+		return errors.New("--chaincode is required")
+	}
+	if c.args == "" {
+		return errors.New("--args is required")
+	}
+	var err error
+	c.opts.Function, c.opts.Args, err = contract.ParseArgs(c.args)
+	if err != nil {
+		return err
+	}
+	return c.opts.Validate()
+}
+
+func (c *benchCmd) run(out io.Writer, errOut io.Writer) error {
+	session, err := contract.Open(c.contractOpts)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	log.Debugf("Sending %d transactions per second to chaincode %s on channel %s for %s", c.opts.TPS, c.contractOpts.Chaincode, c.contractOpts.Channel, c.opts.Duration)
+	report, err := bench.Run(ctx, c.opts, func(ctx context.Context, function string, args []string) error {
+		_, err := session.Submit(ctx, function, args)
+		return err
+	})
+	if report == nil {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintln(errOut, "The benchmark was interrupted, the report covers the transactions sent until then")
+	}
+	return output.Print(out, report, func(w io.Writer) error {
+		tw := output.NewTabWriter(w)
+		fmt.Fprintf(tw, "Duration\t%s\n", report.Duration.Round(time.Millisecond))
+		fmt.Fprintf(tw, "Sent\t%d (%.1f tx/s, target %d tx/s)\n", report.Sent, report.SendTPS, report.TargetTPS)
+		fmt.Fprintf(tw, "Skipped\t%d\n", report.Skipped)
+		fmt.Fprintf(tw, "Committed\t%d (%.1f tx/s)\n", report.Committed, report.CommitTPS)
+		fmt.Fprintf(tw, "Failed\t%d\n", report.Failed)
+		l := report.Latencies
+		fmt.Fprintf(tw, "Latency\tmin %s, mean %s, p50 %s, p90 %s, p95 %s, p99 %s, max %s\n",
+			l.Min.Round(time.Millisecond), l.Mean.Round(time.Millisecond), l.P50.Round(time.Millisecond), l.P90.Round(time.Millisecond),
+			l.P95.Round(time.Millisecond), l.P99.Round(time.Millisecond), l.Max.Round(time.Millisecond))
+		for _, msg := range report.Errors {
+			fmt.Fprintf(tw, "Error\t%s\n", msg)
+		}
+		return tw.Flush()
+	})
+}
+
+func NewBenchCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &benchCmd{}
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Drive transactions to a chaincode at a rate and report their latencies",
+		Long: `Submit transactions to a chaincode through the gateway of a peer at --tps
+for --duration, and report the latency percentiles of the committed ones,
+from their submission to their commit, and the commit throughput.
+
+At most --concurrency transactions wait for their commit at the same time,
+a transaction due while they're all waiting is skipped and counted in the
+report, a rate the network can't sustain shows as skipped transactions and
+a commit throughput under the target. {n} in --args is replaced by the
+number of the transaction, for the transactions to write different keys
+instead of conflicting. The transactions are signed by the admin identity
+hlf-easy manages for the peer or by --identity.`,
+		Example: `  hlf-easy bench --peer-id=peer0 --channel=mychannel --chaincode=basic --args='["CreateAsset","asset{n}","blue","5","tom","100"]' --tps=200 --duration=2m`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.contractOpts.PeerID, "peer-id", "", "ID of the peer whose gateway sends the transactions")
+	f.StringVar(&c.contractOpts.Channel, "channel", "", "Channel of the chaincode")
+	f.StringVar(&c.contractOpts.Chaincode, "chaincode", "", "Name of the chaincode")
+	f.StringVar(&c.args, "args", "", `Function and arguments of the transactions, {n} replaced by their number, ["set","key{n}","10"]`)
+	f.StringVar(&c.contractOpts.Identity, "identity", "", "Identity file signing the transactions, an admin identity issued by the local CA of the peer if empty")
+	f.StringVar(&c.contractOpts.Endpoint, "endpoint", "", "Endpoint of the peer, its external endpoint if empty")
+	f.IntVar(&c.opts.TPS, "tps", bench.DefaultTPS, "Transactions sent per second")
+	f.DurationVar(&c.opts.Duration, "duration", bench.DefaultDuration, "How long the transactions are sent")
+	f.IntVar(&c.opts.Concurrency, "concurrency", bench.DefaultConcurrency, "Maximum number of transactions waiting for their commit")
+	f.DurationVar(&c.opts.TxTimeout, "tx-timeout", bench.DefaultTxTimeout, "How long a transaction has to be committed before it's counted as failed")
+	return cmd
+}
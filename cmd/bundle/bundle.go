@@ -0,0 +1,18 @@
+package bundle
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewBundleCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Bundle the fabric binaries, builders and images to run networks on hosts without internet",
+	}
+	cmd.AddCommand(
+		newBundleCreateCommand(out, errOut),
+		newBundleImportCommand(out, errOut),
+	)
+	return cmd
+}
@@ -0,0 +1,85 @@
+package bundle
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/bundle"
+	"io"
+	"strings"
+)
+
+type createCmd struct {
+	output         string
+	fabricVersions []string
+	builders       []string
+	images         []string
+	registryImages bool
+	platform       string
+}
+
+func (c *createCmd) validate() error {
+	if c.output == "" {
+		return errors.New("--output is required")
+	}
+	return nil
+}
+
+func (c *createCmd) run(out io.Writer) error {
+	m, err := bundle.Create(c.output, bundle.CreateOptions{
+		FabricVersions: c.fabricVersions,
+		Builders:       c.builders,
+		Images:         c.images,
+		RegistryImages: c.registryImages,
+		Platform:       c.platform,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Bundle %s created for %s/%s\n", c.output, m.OS, m.Arch)
+	printManifest(out, m.Fabric, m.Builders, m.Images)
+	return nil
+}
+
+// printManifest prints the artifacts of a bundle
+func printManifest(out io.Writer, fabric []string, builders []string, images []string) {
+	for _, a := range []struct {
+		name   string
+		values []string
+	}{{"Fabric", fabric}, {"Builders", builders}, {"Images", images}} {
+		if len(a.values) > 0 {
+			fmt.Fprintf(out, "%s: %s\n", a.name, strings.Join(a.values, ", "))
+		}
+	}
+}
+
+func newBundleCreateCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &createCmd{}
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a bundle of the artifacts needed to bootstrap a network offline",
+		Long: `Create a tar.gz with the binaries of versions of Fabric, custom external
+builders of the host and docker images of chaincodes. It runs on a host with
+access to the internet, the versions of Fabric that aren't installed are
+downloaded and the images are saved with docker. The sample chaincodes are
+part of hlf-easy and need no bundle. With --platform the bundle is created for
+hosts of another platform, like linux/arm64 from an amd64 host: the binaries
+of Fabric of the platform are downloaded, the arm64 ones are published from
+Fabric 2.5, and the variants of the platform of the images are pulled before
+they're saved.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVarP(&c.output, "output", "o", "", "Path of the bundle to create")
+	f.StringArrayVar(&c.fabricVersions, "fabric-version", []string{}, "Version of Fabric whose binaries are bundled, can be repeated")
+	f.StringArrayVar(&c.builders, "builder", []string{}, "Custom external builder of the host to bundle, can be repeated")
+	f.StringArrayVar(&c.images, "image", []string{}, "Docker image to bundle, can be repeated")
+	f.BoolVar(&c.registryImages, "registry-images", false, "Bundle the images of the docker chaincodes of the registry")
+	f.StringVar(&c.platform, "platform", "", "Platform <os>/<arch> of the hosts importing the bundle, like linux/arm64, the platform of the host when empty")
+	return cmd
+}
@@ -0,0 +1,57 @@
+package bundle
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/bundle"
+	"io"
+)
+
+type importCmd struct {
+	file string
+}
+
+func (c *importCmd) validate() error {
+	if c.file == "" {
+		return errors.New("--file is required")
+	}
+	return nil
+}
+
+func (c *importCmd) run(out io.Writer) error {
+	result, err := bundle.Import(c.file)
+	if err != nil {
+		return err
+	}
+	m := result.Manifest
+	fmt.Fprintf(out, "Bundle %s created on %s imported\n", c.file, m.CreatedAt.Format("2006-01-02 15:04:05"))
+	printManifest(out, result.Fabric, result.Builders, result.Images)
+	if skipped := len(m.Fabric) + len(m.Builders) - len(result.Fabric) - len(result.Builders); skipped > 0 {
+		fmt.Fprintf(out, "%d artifacts already on the host were skipped\n", skipped)
+	}
+	return nil
+}
+
+func newBundleImportCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &importCmd{}
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Install the artifacts of a bundle on a host without internet",
+		Long: `Install the artifacts of a bundle created with bundle create: the binaries of
+Fabric are installed next to the ones hlf-easy downloads, so peer upgrade and
+the nodes started with a version of Fabric use them, the builders are added
+to the custom external builders of the host and the images are loaded with
+docker. The versions of Fabric and the builders already on the host are
+skipped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVarP(&c.file, "file", "f", "", "Path of the bundle")
+	return cmd
+}
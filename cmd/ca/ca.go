@@ -14,6 +14,13 @@ func NewCACmd(out io.Writer, errOut io.Writer) *cobra.Command {
 		newCAStartCommand(),
 		newCAInspectCommand(out, errOut),
 		newCAEnrollCommand(out, errOut),
+		newCAEnrollBatchCommand(out),
+		newCACeremonyCommand(out, errOut),
+		newCAUnsealCommand(out, errOut),
+		newCARehearseRolloverCommand(out, errOut),
+		newCARotateCommand(out),
+		newCAExportCommand(out),
+		newCARotationCommand(out),
 	)
 	return cmd
 }
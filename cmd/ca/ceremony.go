@@ -0,0 +1,349 @@
+package ca
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/certs"
+	"hlf-easy/escrow"
+	"hlf-easy/utils"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ceremonyLogEntry is appended to the ceremonies.log of the CA for every
+// admin key ceremony, whether it succeeded or was aborted
+type ceremonyLogEntry struct {
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	CAName      string    `json:"caName"`
+	CommonName  string    `json:"commonName"`
+	Operators   []string  `json:"operators"`
+	Sealer      string    `json:"sealer,omitempty"`
+	Result      string    `json:"result"`
+	Reason      string    `json:"reason,omitempty"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	SealedKey   string    `json:"sealedKey,omitempty"`
+}
+
+type ceremonyCmd struct {
+	Name       string
+	CommonName string
+	Operators  int
+	Sealer     string
+	Output     string
+}
+
+func (c *ceremonyCmd) validate() error {
+	if c.Name == "" {
+		return errors.Errorf("--name is required")
+	}
+	if c.CommonName == "" {
+		return errors.Errorf("--common-name is required")
+	}
+	if err := validateCeremonyCommonName(c.CommonName); err != nil {
+		return err
+	}
+	if c.Operators < 2 {
+		return errors.Errorf("--operators must be at least 2")
+	}
+	if !utils.Contains(escrow.Sealers(), c.Sealer) {
+		return errors.Errorf("--sealer must be one of %s", strings.Join(escrow.Sealers(), ", "))
+	}
+	return nil
+}
+
+func getEscrowDir(caName string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fmt.Sprintf("hlf-easy/cas/%s/escrow", caName)), nil
+}
+
+func appendCeremonyLog(entry ceremonyLogEntry) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	logFilePath := filepath.Join(home, fmt.Sprintf("hlf-easy/cas/%s/ceremonies.log", entry.CAName))
+	f, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(entryBytes, '\n'))
+	return err
+}
+
+// commonNameRegexp restricts the common names of ceremony identities to
+// names that can be used as file names in the escrow dir of the CA
+var commonNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]*$`)
+
+func validateCeremonyCommonName(commonName string) error {
+	if !commonNameRegexp.MatchString(commonName) {
+		return errors.Errorf("invalid common name %q, only letters, digits, '.', '_', '@' and '-' are allowed", commonName)
+	}
+	return nil
+}
+
+// readLine reads a line one byte at a time, so nothing is buffered past the
+// line and the passphrases can still be read from the same input
+func readLine(in io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := in.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(line) > 0 {
+				break
+			}
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
+
+// readSecret reads a passphrase without echo when the input is a terminal
+func readSecret(in io.Reader, errOut io.Writer) (string, error) {
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		secret, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(errOut)
+		if err != nil {
+			return "", err
+		}
+		return string(secret), nil
+	}
+	return readLine(in)
+}
+
+// collectShares asks every operator for their name, passphrase share and
+// confirmation, it fails if any of the operators doesn't confirm. If
+// expectedOperators is set, only those operators can take part
+func collectShares(in io.Reader, errOut io.Writer, operators int, expectedOperators []string, prompt string) ([]string, []escrow.Share, error) {
+	var names []string
+	var shares []escrow.Share
+	for i := 1; i <= operators; i++ {
+		fmt.Fprintf(errOut, "Operator %d/%d name: ", i, operators)
+		name, err := readLine(in)
+		if err != nil {
+			return names, nil, err
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return names, nil, errors.Errorf("operator %d didn't provide a name", i)
+		}
+		if utils.Contains(names, name) {
+			return names, nil, errors.Errorf("operator %s already took part in the ceremony", name)
+		}
+		if expectedOperators != nil && !utils.Contains(expectedOperators, name) {
+			return names, nil, errors.Errorf("operator %s didn't take part in the key ceremony", name)
+		}
+		names = append(names, name)
+		fmt.Fprintf(errOut, "Operator %s passphrase share: ", name)
+		passphrase, err := readSecret(in, errOut)
+		if err != nil {
+			return names, nil, err
+		}
+		if passphrase == "" {
+			return names, nil, errors.Errorf("operator %s provided an empty passphrase share", name)
+		}
+		shares = append(shares, escrow.Share{Operator: name, Passphrase: []byte(passphrase)})
+		fmt.Fprintf(errOut, "Operator %s, %s [yes/no]: ", name, prompt)
+		confirmation, err := readLine(in)
+		if err != nil {
+			return names, nil, err
+		}
+		if strings.TrimSpace(confirmation) != "yes" {
+			return names, nil, errors.Errorf("operator %s didn't confirm", name)
+		}
+	}
+	return names, shares, nil
+}
+
+// writeNewFile writes the file only if it doesn't exist yet
+func writeNewFile(name string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (c *ceremonyCmd) run(in io.Reader, out io.Writer, errOut io.Writer) error {
+	caConfig, err := utils.GetCAConfig(c.Name)
+	if err != nil {
+		return err
+	}
+	escrowDir, err := getEscrowDir(c.Name)
+	if err != nil {
+		return err
+	}
+	sealedKeyPath := filepath.Join(escrowDir, fmt.Sprintf("%s.json", c.CommonName))
+	if _, err := os.Stat(sealedKeyPath); err == nil {
+		return errors.Errorf("admin identity %s of CA %s already exists", c.CommonName, c.Name)
+	}
+	idBytes := make([]byte, 8)
+	_, err = rand.Read(idBytes)
+	if err != nil {
+		return err
+	}
+	entry := ceremonyLogEntry{
+		ID:         hex.EncodeToString(idBytes),
+		Time:       time.Now().UTC(),
+		CAName:     c.Name,
+		CommonName: c.CommonName,
+		Sealer:     c.Sealer,
+	}
+	fmt.Fprintf(errOut, "Starting key ceremony %s for admin %s of CA %s with %d operators\n", entry.ID, c.CommonName, c.Name, c.Operators)
+	names, shares, err := collectShares(
+		in,
+		errOut,
+		c.Operators,
+		nil,
+		fmt.Sprintf("confirm the generation of the admin identity %s", c.CommonName),
+	)
+	entry.Operators = names
+	if err != nil {
+		entry.Result = "aborted"
+		entry.Reason = err.Error()
+		if logErr := appendCeremonyLog(entry); logErr != nil {
+			log.Warnf("Failed to write ceremony log: %v", logErr)
+		}
+		return errors.Wrapf(err, "key ceremony %s aborted", entry.ID)
+	}
+
+	crtPem, fingerprint, err := c.generateSealedIdentity(caConfig, escrowDir, sealedKeyPath, shares)
+	if err != nil {
+		entry.Result = "failed"
+		entry.Reason = err.Error()
+		if logErr := appendCeremonyLog(entry); logErr != nil {
+			log.Warnf("Failed to write ceremony log: %v", logErr)
+		}
+		return errors.Wrapf(err, "key ceremony %s failed", entry.ID)
+	}
+	entry.Result = "completed"
+	entry.Fingerprint = fingerprint
+	entry.SealedKey = sealedKeyPath
+	err = appendCeremonyLog(entry)
+	if err != nil {
+		return err
+	}
+	// only the certificate leaves the ceremony, the key stays sealed
+	certYaml, err := yaml.Marshal(map[string]interface{}{
+		"cert": map[string]interface{}{
+			"pem": string(crtPem),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if c.Output != "" {
+		return os.WriteFile(c.Output, certYaml, 0644)
+	}
+	_, err = out.Write(certYaml)
+	return err
+}
+
+// generateSealedIdentity generates the admin identity and seals its key with
+// the shares, it returns the certificate and its fingerprint
+func (c *ceremonyCmd) generateSealedIdentity(caConfig *utils.CAConfig, escrowDir string, sealedKeyPath string, shares []escrow.Share) ([]byte, string, error) {
+	certOpts := certs.GenerateCertificateOptions{
+		CommonName:       c.CommonName,
+		OrganizationUnit: []string{"admin"},
+		IPAddresses:      []net.IP{},
+		DNSNames:         []string{},
+	}
+	err := certs.ApplyCertificatePolicy(&certOpts, caConfig.CertPolicy, c.Name, false)
+	if err != nil {
+		return nil, "", err
+	}
+	adminCert, adminKey, err := certs.GenerateCertificate(
+		certOpts,
+		caConfig.CACert,
+		caConfig.CAKey,
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	keyPem, err := utils.EncodePrivateKey(adminKey)
+	if err != nil {
+		return nil, "", err
+	}
+	sealedKey, err := escrow.Seal(c.Sealer, keyPem, shares)
+	if err != nil {
+		return nil, "", err
+	}
+	err = os.MkdirAll(escrowDir, 0700)
+	if err != nil {
+		return nil, "", err
+	}
+	// a concurrent ceremony for the same admin must not overwrite the key
+	err = writeNewFile(sealedKeyPath, sealedKey, 0600)
+	if err != nil {
+		return nil, "", err
+	}
+	crtPem := utils.EncodeX509Certificate(adminCert)
+	err = writeNewFile(filepath.Join(escrowDir, fmt.Sprintf("%s.crt", c.CommonName)), crtPem, 0644)
+	if err != nil {
+		return nil, "", err
+	}
+	fingerprint := sha256.Sum256(adminCert.Raw)
+	return crtPem, hex.EncodeToString(fingerprint[:]), nil
+}
+
+func newCACeremonyCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &ceremonyCmd{}
+	cmd := &cobra.Command{
+		Use:   "ceremony",
+		Short: "Generate an org admin identity confirmed by multiple operators",
+		Long: `Generate an org admin identity in a key ceremony, every operator must confirm
+the generation and provide a passphrase share. The private key is sealed with
+all the shares right after its generation and the ceremony is logged in the
+ceremonies.log file of the CA.
+
+With --sealer=vault-transit the sealed key is also wrapped with a key of the
+transit engine of HashiCorp Vault, VAULT_ADDR and VAULT_TOKEN configure the
+server and HLF_EASY_VAULT_TRANSIT_KEY the key (hlf-easy by default), so
+unsealing it needs the shares and access to the KMS.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(cmd.InOrStdin(), out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.Name, "name", "", "Name of the CA")
+	f.StringVar(&c.CommonName, "common-name", "", "Common name of the admin")
+	f.IntVar(&c.Operators, "operators", 2, "Number of operators that must take part in the ceremony")
+	f.StringVar(&c.Sealer, "sealer", escrow.DefaultSealer, "Sealer of the key: passphrase or vault-transit")
+	f.StringVarP(&c.Output, "output", "o", "", "Output file for the admin certificate")
+	return cmd
+}
@@ -0,0 +1,86 @@
+package ca
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCollectShares(t *testing.T) {
+	t.Run("all operators confirm", func(t *testing.T) {
+		in := strings.NewReader("alice\nalice passphrase\nyes\nbob\r\nbob passphrase\r\nyes\r\n")
+		errOut := &bytes.Buffer{}
+		names, shares, err := collectShares(in, errOut, 2, nil, "confirm")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 2 || names[0] != "alice" || names[1] != "bob" {
+			t.Fatalf("unexpected operators %v", names)
+		}
+		if len(shares) != 2 || string(shares[0].Passphrase) != "alice passphrase" || string(shares[1].Passphrase) != "bob passphrase" {
+			t.Fatalf("unexpected shares %v", shares)
+		}
+		if shares[1].Operator != "bob" {
+			t.Fatalf("expected share of bob, got %s", shares[1].Operator)
+		}
+		if !strings.Contains(errOut.String(), "Operator 2/2 name") {
+			t.Fatalf("expected prompts on the error output, got %q", errOut.String())
+		}
+	})
+
+	t.Run("operator doesn't confirm", func(t *testing.T) {
+		in := strings.NewReader("alice\nalice passphrase\nyes\nbob\nbob passphrase\nno\n")
+		names, _, err := collectShares(in, &bytes.Buffer{}, 2, nil, "confirm")
+		if err == nil {
+			t.Fatal("expected an error when an operator doesn't confirm")
+		}
+		if len(names) != 2 {
+			t.Fatalf("expected the names collected so far to be returned, got %v", names)
+		}
+	})
+
+	t.Run("duplicated operator", func(t *testing.T) {
+		in := strings.NewReader("alice\nalice passphrase\nyes\nalice\nother passphrase\nyes\n")
+		_, _, err := collectShares(in, &bytes.Buffer{}, 2, nil, "confirm")
+		if err == nil {
+			t.Fatal("expected an error when an operator takes part twice")
+		}
+	})
+
+	t.Run("empty passphrase", func(t *testing.T) {
+		in := strings.NewReader("alice\n\nyes\n")
+		_, _, err := collectShares(in, &bytes.Buffer{}, 2, nil, "confirm")
+		if err == nil {
+			t.Fatal("expected an error on an empty passphrase")
+		}
+	})
+
+	t.Run("unexpected operator", func(t *testing.T) {
+		in := strings.NewReader("alice\nalice passphrase\nyes\nmallory\nmallory passphrase\nyes\n")
+		_, _, err := collectShares(in, &bytes.Buffer{}, 2, []string{"alice", "bob"}, "confirm")
+		if err == nil {
+			t.Fatal("expected an error when an operator didn't take part in the ceremony")
+		}
+	})
+
+	t.Run("input ends early", func(t *testing.T) {
+		in := strings.NewReader("alice\nalice passphrase\nyes\n")
+		_, _, err := collectShares(in, &bytes.Buffer{}, 2, nil, "confirm")
+		if err == nil {
+			t.Fatal("expected an error when the input ends before all operators confirm")
+		}
+	})
+}
+
+func TestValidateCeremonyCommonName(t *testing.T) {
+	for _, commonName := range []string{"admin", "org1-admin", "admin@org1.example.com"} {
+		if err := validateCeremonyCommonName(commonName); err != nil {
+			t.Errorf("expected %q to be valid: %v", commonName, err)
+		}
+	}
+	for _, commonName := range []string{"", ".", "..", "../../x", "a/b", ".hidden", "admin\n"} {
+		if err := validateCeremonyCommonName(commonName); err == nil {
+			t.Errorf("expected %q to be rejected", commonName)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package ca
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/certs"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type enrollBatchCmd struct {
+	opts      certs.BatchEnrollOptions
+	manifest  string
+	format    string
+	outputDir string
+	timeout   time.Duration
+}
+
+// batchIdentityFile is an identity written by enroll-batch
+type batchIdentityFile struct {
+	CommonName string `json:"commonName"`
+	File       string `json:"file"`
+}
+
+func (c *enrollBatchCmd) validate() error {
+	if c.opts.CAName == "" {
+		return errors.New("--name is required")
+	}
+	if c.manifest == "" {
+		return errors.New("--manifest is required")
+	}
+	if c.outputDir == "" {
+		return errors.New("--output-dir is required")
+	}
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	return nil
+}
+
+// manifestFormat returns the format of the manifest, given by --format or
+// by the extension of its file
+func (c *enrollBatchCmd) manifestFormat() string {
+	if c.format != "" {
+		return c.format
+	}
+	if strings.EqualFold(filepath.Ext(c.manifest), ".json") {
+		return certs.BatchFormatJSON
+	}
+	return certs.BatchFormatCSV
+}
+
+func (c *enrollBatchCmd) run(out io.Writer) error {
+	f, err := os.Open(c.manifest)
+	if err != nil {
+		return err
+	}
+	c.opts.Identities, err = certs.ParseBatchManifest(f, c.manifestFormat())
+	f.Close()
+	if err != nil {
+		return err
+	}
+	err = c.opts.Validate()
+	if err != nil {
+		return err
+	}
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	enrolled, err := certs.EnrollBatch(ctx, c.opts)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(c.outputDir, 0700)
+	if err != nil {
+		return err
+	}
+	files := []batchIdentityFile{}
+	for _, identity := range enrolled {
+		identityYaml, err := marshalIdentity([]byte(identity.Cert), []byte(identity.Key))
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(c.outputDir, identity.CommonName+".yaml")
+		// the files hold the keys of the identities
+		err = os.WriteFile(file, identityYaml, 0600)
+		if err != nil {
+			return err
+		}
+		files = append(files, batchIdentityFile{CommonName: identity.CommonName, File: file})
+	}
+	return output.Print(out, files, func(w io.Writer) error {
+		fmt.Fprintf(w, "%d identities issued by %s written to %s\n", len(files), c.opts.CAName, c.outputDir)
+		return nil
+	})
+}
+
+func newCAEnrollBatchCommand(out io.Writer) *cobra.Command {
+	c := &enrollBatchCmd{}
+	cmd := &cobra.Command{
+		Use:   "enroll-batch",
+		Short: "Issue many identities with a local CA from a CSV or JSON manifest",
+		Long: `Issue many identities with a local CA in one call, for load tests needing
+hundreds of clients. The manifest is a CSV with a header naming its columns
+among commonName, type, ous and affiliation, the OUs separated by ;, or a
+JSON array of {"commonName", "type", "ous", "affiliation"}. The type is the
+first OU of an identity, client when empty, and the affiliation is added as
+OUs like the Fabric CA does. The identities are issued in parallel and
+written to <output-dir>/<common name>.yaml, the identity files of ca enroll.`,
+		Example: `  hlf-easy ca enroll-batch --name=ca-1 --manifest=clients.csv --output-dir=clients
+  hlf-easy ca enroll-batch --name=ca-1 --manifest=clients.json --output-dir=clients --parallelism=8`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.opts.CAName, "name", "", "Name of the CA")
+	f.StringVar(&c.manifest, "manifest", "", "CSV or JSON manifest of the identities to issue")
+	f.StringVar(&c.format, "format", "", "Format of the manifest, csv or json, from the extension of its file if empty")
+	f.StringVar(&c.outputDir, "output-dir", "", "Directory the identity files are written to")
+	f.IntVar(&c.opts.Parallelism, "parallelism", 0, "Number of identities issued at the same time, the number of CPUs when 0")
+	f.DurationVar(&c.timeout, "timeout", 0, "How long the issuance of the identities has to complete, 0 waits without a limit")
+	c.opts.CertPolicy.AddFlags(f)
+	return cmd
+}
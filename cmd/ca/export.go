@@ -0,0 +1,68 @@
+package ca
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/certs"
+	"hlf-easy/utils"
+	"io"
+	"os"
+)
+
+type exportCmd struct {
+	Name   string
+	Kind   string
+	Output string
+}
+
+func (c *exportCmd) validate() error {
+	if c.Name == "" {
+		return errors.Errorf("--name is required")
+	}
+	return certs.ValidateCAKind(c.Kind)
+}
+
+func (c *exportCmd) run(out io.Writer) error {
+	caConfig, err := utils.GetCAConfig(c.Name)
+	if err != nil {
+		return err
+	}
+	roots := certs.TrustedRoots(caConfig, c.Kind)
+	var bundle []byte
+	for _, root := range roots {
+		bundle = append(bundle, utils.EncodeX509Certificate(root)...)
+	}
+	if c.Output == "" {
+		_, err = out.Write(bundle)
+		return err
+	}
+	err = os.WriteFile(c.Output, bundle, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d %s root(s) of %s written to %s\n", len(roots), c.Kind, c.Name, c.Output)
+	return nil
+}
+
+func newCAExportCommand(out io.Writer) *cobra.Command {
+	c := &exportCmd{}
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the roots of the signing or the TLS CA of a local CA to trust them on other hosts",
+		Long: `Export the roots of the signing or the TLS CA of a local CA in PEM, the current
+root first and then the previous one while the CA is being rotated, so the
+other hosts trust the certificates issued by both.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.Name, "name", "", "Name of the CA")
+	f.StringVar(&c.Kind, "ca", certs.CAKindTLS, "CA to export, sign or tls")
+	f.StringVarP(&c.Output, "output", "o", "", "File to write the roots to, they're printed if empty")
+	return cmd
+}
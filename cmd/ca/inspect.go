@@ -1,10 +1,11 @@
 package ca
 
 import (
-	"bytes"
+	"crypto/x509"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
+	"hlf-easy/output"
 	"hlf-easy/utils"
 	"io"
 )
@@ -24,24 +25,31 @@ func (c *inspectCmd) run(out io.Writer, errOut io.Writer) error {
 	if err != nil {
 		return err
 	}
-	var buf bytes.Buffer
 	dataToExport := map[string]interface{}{
 		"tlsCACert": string(utils.EncodeX509Certificate(caConfig.TLSCACert)),
 		"caCert":    string(utils.EncodeX509Certificate(caConfig.CACert)),
 	}
-	encoder := yaml.NewEncoder(&buf)
-	encoder.SetIndent(2)
-	err = encoder.Encode(dataToExport)
-	if err != nil {
-		return err
+	// the roots still trusted while a CA is being rotated
+	if len(caConfig.PreviousTLSCACerts) > 0 {
+		dataToExport["previousTLSCACerts"] = encodeCerts(caConfig.PreviousTLSCACerts)
 	}
-	// write to out
-	_, err = io.Copy(out, &buf)
-	if err != nil {
-		return err
+	if len(caConfig.PreviousCACerts) > 0 {
+		dataToExport["previousCACerts"] = encodeCerts(caConfig.PreviousCACerts)
 	}
-	return nil
+	return output.Print(out, dataToExport, func(w io.Writer) error {
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		return encoder.Encode(dataToExport)
+	})
+}
+func encodeCerts(crts []*x509.Certificate) []string {
+	pems := make([]string, 0, len(crts))
+	for _, crt := range crts {
+		pems = append(pems, string(utils.EncodeX509Certificate(crt)))
+	}
+	return pems
 }
+
 func newCAInspectCommand(out io.Writer, errOut io.Writer) *cobra.Command {
 	c := &inspectCmd{}
 	cmd := &cobra.Command{
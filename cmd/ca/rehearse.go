@@ -0,0 +1,82 @@
+package ca
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/output"
+	"hlf-easy/rollover"
+	"io"
+	"strings"
+	"time"
+)
+
+type rehearseCmd struct {
+	restartDuration time.Duration
+	stagingDir      string
+}
+
+func (c *rehearseCmd) validate() error {
+	if c.restartDuration <= 0 {
+		return errors.New("--restart-duration must be positive")
+	}
+	return nil
+}
+
+func printRehearsal(out io.Writer, report *rollover.Report) error {
+	fmt.Fprintf(out, "Decision: %s\n", strings.ToUpper(report.Decision))
+	for _, reason := range report.Reasons {
+		fmt.Fprintf(out, "  - %s\n", reason)
+	}
+	fmt.Fprintf(out, "Estimated downtime: %s of rolling restarts\n\n", time.Duration(report.Downtime)*time.Second)
+	for _, ca := range report.CAs {
+		fmt.Fprintf(out, "TLS CA %s (%s)\n  staging CA %s\n  nodes: %s\n", ca.Subject, ca.Fingerprint[:16], ca.StagingFingerprint[:16], strings.Join(ca.Nodes, ", "))
+	}
+	fmt.Fprintln(out)
+	for _, n := range report.Nodes {
+		fmt.Fprintf(out, "%s/%s\trunning=%t\tchannels=%d\trestarts=%d\n", n.Kind, n.ID, n.Running, n.Channels, n.Restarts)
+		for _, c := range n.Checks {
+			fmt.Fprintf(out, "  %-4s %-14s %s\n", c.Status, c.Name, c.Message)
+		}
+	}
+	return nil
+}
+
+func (c *rehearseCmd) run(out io.Writer, errOut io.Writer) error {
+	report, err := rollover.Rehearse(rollover.Options{
+		Now:             time.Now(),
+		RestartDuration: c.restartDuration,
+		StagingDir:      c.stagingDir,
+	})
+	if err != nil {
+		return err
+	}
+	err = output.Print(out, report, func(w io.Writer) error {
+		return printRehearsal(w, report)
+	})
+	if err != nil {
+		return err
+	}
+	if report.Decision != rollover.DecisionGo {
+		return errors.New("the TLS CA rollover rehearsal is a no-go")
+	}
+	return nil
+}
+
+func newCARehearseRolloverCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &rehearseCmd{}
+	cmd := &cobra.Command{
+		Use:   "rehearse-rollover",
+		Short: "Rehearse a TLS CA rollover of the peers and orderers of the host without changing them",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.DurationVar(&c.restartDuration, "restart-duration", rollover.DefaultRestartDuration, "Estimated time a node takes to restart")
+	f.StringVar(&c.stagingDir, "staging-dir", "", "Directory to write the staging CAs and the reissued certificates to, outside ~/hlf-easy")
+	return cmd
+}
@@ -0,0 +1,148 @@
+package ca
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/certs"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"hlf-easy/utils"
+	"io"
+)
+
+type rotateCmd struct {
+	Name     string
+	Kind     string
+	Complete bool
+}
+
+func (c *rotateCmd) validate() error {
+	if c.Name == "" {
+		return errors.Errorf("--name is required")
+	}
+	return certs.ValidateCAKind(c.Kind)
+}
+
+func printTrustNodes(out io.Writer, nodes []node.CATrustNode) error {
+	w := output.NewTabWriter(out)
+	fmt.Fprintln(w, "KIND\tID\tRUNNING\tISSUED BY NEW ROOT")
+	for _, n := range nodes {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%t\n", n.Kind, n.ID, n.Running, n.Issued)
+	}
+	return w.Flush()
+}
+
+// start replaces the root of the CA, the nodes that trust the current one
+// trust both until the rotation is completed
+func (c *rotateCmd) start(out io.Writer) error {
+	caConfig, err := utils.GetCAConfig(c.Name)
+	if err != nil {
+		return err
+	}
+	nodes, err := node.CATrustingNodes(c.Kind, certs.TrustedRoots(caConfig, c.Kind))
+	if err != nil {
+		return err
+	}
+	_, err = certs.RotateCA(c.Name, c.Kind)
+	if err != nil {
+		return err
+	}
+	caConfig, err = utils.GetCAConfig(c.Name)
+	if err != nil {
+		return err
+	}
+	err = node.SyncCATrust(nodes, c.Kind, certs.TrustedRoots(caConfig, c.Kind))
+	if err != nil {
+		return err
+	}
+	return output.Print(out, nodes, func(out io.Writer) error {
+		fmt.Fprintf(out, "New %s root of %s created, the nodes trust the new and the previous root\n", c.Kind, c.Name)
+		if len(nodes) == 0 {
+			return nil
+		}
+		if err := printTrustNodes(out, nodes); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "\nRestart the running nodes, update the MSP of the org in its channels with hlf-easy org export-msp-def")
+		fmt.Fprintln(out, "and hlf-easy channel update, issue the certificates of the nodes again, e.g. with hlf-easy peer rehost,")
+		fmt.Fprintf(out, "then complete the rotation with hlf-easy ca rotate --name %s --ca %s --complete\n", c.Name, c.Kind)
+		return nil
+	})
+}
+
+// complete stops trusting the previous roots, once every node has its
+// certificate issued by the new root
+func (c *rotateCmd) complete(out io.Writer) error {
+	caConfig, err := utils.GetCAConfig(c.Name)
+	if err != nil {
+		return err
+	}
+	roots := certs.TrustedRoots(caConfig, c.Kind)
+	nodes, err := node.CATrustingNodes(c.Kind, roots)
+	if err != nil {
+		return err
+	}
+	var pending []node.CATrustNode
+	for _, n := range nodes {
+		if !n.Issued {
+			pending = append(pending, n)
+		}
+	}
+	if len(pending) > 0 {
+		return errors.Errorf("the %s certificates of %s are not issued by the new root yet, they would stop being trusted", c.Kind, node.NodeIDs(pending))
+	}
+	_, err = certs.CompleteCARotation(c.Name, c.Kind)
+	if err != nil {
+		return err
+	}
+	err = node.SyncCATrust(nodes, c.Kind, roots[:1])
+	if err != nil {
+		return err
+	}
+	return output.Print(out, nodes, func(out io.Writer) error {
+		fmt.Fprintf(out, "Rotation of the %s CA of %s completed, the previous root isn't trusted anymore\n", c.Kind, c.Name)
+		if len(nodes) == 0 {
+			return nil
+		}
+		if err := printTrustNodes(out, nodes); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "\nRestart the running nodes and remove the previous root from the MSP of the org in its channels")
+		return nil
+	})
+}
+
+func (c *rotateCmd) run(out io.Writer) error {
+	if c.Complete {
+		return c.complete(out)
+	}
+	return c.start(out)
+}
+
+func newCARotateCommand(out io.Writer) *cobra.Command {
+	c := &rotateCmd{}
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate the signing or the TLS CA of a local CA without breaking the nodes that trust it",
+		Long: `Rotate the signing or the TLS CA of a local CA, the other one is kept.
+
+A new root with the subject of the current one issues the certificates from
+then on, and the previous root is still trusted: the peers and orderers of the
+host that trust it get both roots, in tlscacerts/cacert.pem for the TLS CA and
+in cacerts for the signing CA, so the nodes issued by either root keep talking
+to each other while they're issued again. --complete drops the previous root
+once every node of the host has its certificate issued by the new one.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.Name, "name", "", "Name of the CA")
+	f.StringVar(&c.Kind, "ca", certs.CAKindTLS, "CA to rotate, sign or tls")
+	f.BoolVar(&c.Complete, "complete", false, "Complete the rotation in progress, the previous root isn't trusted anymore")
+	return cmd
+}
@@ -0,0 +1,461 @@
+package ca
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/certs"
+	"hlf-easy/channel"
+	"hlf-easy/explorer"
+	"hlf-easy/node"
+	"hlf-easy/ordering"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"hlf-easy/rollover"
+	"hlf-easy/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func newCARotationCommand(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotation",
+		Short: "Rotate the root of a local CA step by step, up to the MSP of the org in its channels",
+		Long: `Rotate the signing or the TLS root of a local CA in steps, the state of the
+rotation is kept in the directory of the CA between them:
+
+  start      creates the new root, the nodes of the host trust both roots
+  propagate  adds the new root to the MSP of the org in each of its channels
+  reissue    issues the certificates of the peers by the new root
+  retire     removes the previous root from the channels and the nodes
+
+The new root is added to the channels before the certificates are issued by
+it, so the other orgs trust the peers of the org once they present them. The
+config updates are signed with the admin identity managed for a peer of the
+org, so the MSP of the org must be modifiable by its own admins.`,
+	}
+	cmd.AddCommand(
+		newRotationStartCommand(out),
+		newRotationStatusCommand(out),
+		newRotationPropagateCommand(out),
+		newRotationReissueCommand(out),
+		newRotationRetireCommand(out),
+	)
+	return cmd
+}
+
+// printRotation prints the state of a rotation and a hint on its next step
+func printRotation(out io.Writer, r *rollover.Rotation, hint string) error {
+	return output.Print(out, r, func(out io.Writer) error {
+		next := r.Next()
+		if next == "" {
+			next = "none"
+		}
+		fmt.Fprintf(out, "Rotation of the %s root of %s for %s: %s, next step %s\n", r.Kind, r.CA, r.MSPID, r.Step, next)
+		if len(r.Nodes) > 0 {
+			w := output.NewTabWriter(out)
+			fmt.Fprintln(w, "\nKIND\tID\tREISSUED")
+			for _, n := range r.Nodes {
+				fmt.Fprintf(w, "%s\t%s\t%t\n", n.Kind, n.ID, n.Reissued)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		if len(r.Channels) > 0 {
+			w := output.NewTabWriter(out)
+			fmt.Fprintln(w, "\nCHANNEL\tPEER\tSTEP")
+			for _, c := range r.Channels {
+				step := c.Step
+				if step == "" {
+					step = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, c.Peer, step)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		if hint != "" {
+			fmt.Fprintln(out, "\n"+hint)
+		}
+		return nil
+	})
+}
+
+// updateChannelRoots adds and removes roots of the MSP of the org in a
+// channel, the config update is signed by the admin identity managed for the
+// peer and submitted to the closest healthy orderer of the channel. It's
+// false when the MSP already has the roots
+func updateChannelRoots(r *rollover.Rotation, c *rollover.RotationChannel, add []*x509.Certificate, remove []*x509.Certificate) (bool, error) {
+	block, err := explorer.GetConfigBlock(explorer.Options{PeerID: c.Peer, Channel: c.Name})
+	if err != nil {
+		return false, err
+	}
+	original, err := explorer.ConfigFromBlock(block)
+	if err != nil {
+		return false, err
+	}
+	updated, changed, err := channel.SetOrgRoots(original, r.MSPID, r.Kind == certs.CAKindTLS, add, remove)
+	if err != nil || !changed {
+		return false, err
+	}
+	env, err := channel.NewConfigUpdateTx(c.Name, original, updated)
+	if err != nil {
+		return false, err
+	}
+	identityPath, err := node.ManagedAdminIdentity(c.Peer)
+	if err != nil {
+		return false, err
+	}
+	crt, key, err := utils.ReadIdentity(identityPath)
+	if err != nil {
+		return false, err
+	}
+	id := channel.Identity{MSPID: r.MSPID, Cert: crt, Key: key}
+	env, err = channel.SignConfigUpdate(env, id)
+	if err != nil {
+		return false, err
+	}
+	endpoints, err := channel.OrdererEndpoints(original)
+	if err != nil {
+		return false, err
+	}
+	ranked, err := ordering.Select(context.Background(), endpoints)
+	if err != nil {
+		return false, err
+	}
+	rootCAs := x509.NewCertPool()
+	for _, endpoint := range endpoints {
+		if endpoint.Address != ranked[0].Address {
+			continue
+		}
+		for _, pem := range endpoint.TLSCACerts {
+			rootCAs.AppendCertsFromPEM([]byte(pem))
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false, err
+	}
+	// the orderers that require a client certificate get the one of the peer
+	peerDir := filepath.Join(home, "hlf-easy/peers", c.Peer)
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(peerDir, "tls.crt"), filepath.Join(peerDir, "tls.key"))
+	if err != nil {
+		return false, err
+	}
+	tlsConfig := &tls.Config{RootCAs: rootCAs, Certificates: []tls.Certificate{clientCert}, MinVersion: tls.VersionTLS12}
+	ctx, stop := proc.TimeoutContext(context.Background(), channel.DefaultSubmitTimeout)
+	defer stop()
+	err = channel.SubmitConfigUpdate(ctx, ranked[0].Address, tlsConfig, env, id)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to update the MSP of %s in channel %s", r.MSPID, c.Name)
+	}
+	return true, nil
+}
+
+// updateChannels applies a step to the channels it isn't applied to yet, the
+// state is saved after each channel so a failed step resumes where it
+// stopped
+func updateChannels(out io.Writer, r *rollover.Rotation, step string, add []*x509.Certificate, remove []*x509.Certificate) error {
+	for _, c := range r.PendingChannels(step) {
+		updated, err := updateChannelRoots(r, c, add, remove)
+		if err != nil {
+			return err
+		}
+		c.Step = step
+		if err := r.Save(); err != nil {
+			return err
+		}
+		if updated {
+			fmt.Fprintf(out, "MSP of %s updated in channel %s\n", r.MSPID, c.Name)
+		} else {
+			fmt.Fprintf(out, "MSP of %s already up to date in channel %s\n", r.MSPID, c.Name)
+		}
+	}
+	return nil
+}
+
+type rotationStartCmd struct {
+	Name  string
+	Kind  string
+	MSPID string
+}
+
+func (c *rotationStartCmd) validate() error {
+	if c.Name == "" {
+		return errors.Errorf("--name is required")
+	}
+	if c.MSPID == "" {
+		return errors.Errorf("--msp-id is required")
+	}
+	return certs.ValidateCAKind(c.Kind)
+}
+
+func (c *rotationStartCmd) run(out io.Writer) error {
+	caConfig, err := utils.GetCAConfig(c.Name)
+	if err != nil {
+		return err
+	}
+	previous := certs.TrustedRoots(caConfig, c.Kind)
+	if len(previous) > 1 {
+		return errors.Errorf("the %s CA of %s is already being rotated, complete it with hlf-easy ca rotate --complete", c.Kind, c.Name)
+	}
+	nodes, err := node.CATrustingNodes(c.Kind, previous)
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if n.Kind != "peer" {
+			return errors.Errorf("%s/%s trusts the CA, only the CAs of the peers of an org are rotated step by step, use hlf-easy ca rotate", n.Kind, n.ID)
+		}
+		mspID, err := node.GetPeerMSPID(n.ID)
+		if err != nil {
+			return err
+		}
+		if mspID != c.MSPID {
+			return errors.Errorf("peer %s of %s trusts the CA, only the CAs of a single org are rotated step by step, use hlf-easy ca rotate", n.ID, mspID)
+		}
+	}
+	now := time.Now()
+	r, err := rollover.NewRotation(c.Name, c.Kind, c.MSPID, now)
+	if err != nil {
+		return err
+	}
+	for _, root := range previous {
+		r.PreviousRoots = append(r.PreviousRoots, utils.EncodeX509Certificate(root))
+	}
+	for _, n := range nodes {
+		r.Nodes = append(r.Nodes, rollover.RotationNode{Kind: n.Kind, ID: n.ID})
+	}
+	_, err = certs.RotateCA(c.Name, c.Kind)
+	if err != nil {
+		return err
+	}
+	caConfig, err = utils.GetCAConfig(c.Name)
+	if err != nil {
+		return err
+	}
+	err = node.SyncCATrust(nodes, c.Kind, certs.TrustedRoots(caConfig, c.Kind))
+	if err != nil {
+		return err
+	}
+	err = r.Advance(rollover.StepIssued, now)
+	if err != nil {
+		return err
+	}
+	err = r.Save()
+	if err != nil {
+		return err
+	}
+	return printRotation(out, r, fmt.Sprintf("Restart the running peers, then add the new root to the channels with hlf-easy ca rotation propagate --name %s", c.Name))
+}
+
+func newRotationStartCommand(out io.Writer) *cobra.Command {
+	c := &rotationStartCmd{}
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Create the new root of a local CA, the peers of the org trust both roots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.Name, "name", "", "Name of the CA")
+	f.StringVar(&c.Kind, "ca", certs.CAKindTLS, "CA to rotate, sign or tls")
+	f.StringVar(&c.MSPID, "msp-id", "", "MSP ID of the org whose peers are issued by the CA")
+	return cmd
+}
+
+func newRotationStatusCommand(out io.Writer) *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the steps done of the rotation of the root of a local CA",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				return errors.Errorf("--name is required")
+			}
+			r, err := rollover.GetRotation(name)
+			if err != nil {
+				return err
+			}
+			return printRotation(out, r, "")
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "Name of the CA")
+	return cmd
+}
+
+// getRotation reads the rotation of a CA, it fails when a step isn't the
+// next one
+func getRotation(name string, step string) (*rollover.Rotation, error) {
+	if name == "" {
+		return nil, errors.Errorf("--name is required")
+	}
+	r, err := rollover.GetRotation(name)
+	if err != nil {
+		return nil, err
+	}
+	return r, r.Check(step)
+}
+
+func newRotationPropagateCommand(out io.Writer) *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:   "propagate",
+		Short: "Add the new root to the MSP of the org in each of its channels",
+		Long: `Add the new root to the MSP of the org in each channel joined by its peers on
+this host. The config of the channel is read from a peer of the org, and the
+config update is signed by its managed admin identity and submitted to the
+closest healthy orderer of the channel.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := getRotation(name, rollover.StepPropagated)
+			if err != nil {
+				return err
+			}
+			caConfig, err := utils.GetCAConfig(r.CA)
+			if err != nil {
+				return err
+			}
+			root := certs.TrustedRoots(caConfig, r.Kind)[0]
+			err = updateChannels(out, r, rollover.StepPropagated, []*x509.Certificate{root}, nil)
+			if err != nil {
+				return err
+			}
+			err = r.Advance(rollover.StepPropagated, time.Now())
+			if err != nil {
+				return err
+			}
+			err = r.Save()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "The channels trust the new root, issue the certificates of the peers with hlf-easy ca rotation reissue --name %s\n", r.CA)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "Name of the CA")
+	return cmd
+}
+
+func newRotationReissueCommand(out io.Writer) *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:   "reissue",
+		Short: "Issue the certificates of the peers of the org by the new root",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := getRotation(name, rollover.StepReissued)
+			if err != nil {
+				return err
+			}
+			for i := range r.Nodes {
+				n := &r.Nodes[i]
+				if n.Reissued {
+					continue
+				}
+				err = node.ReissuePeerCertificates(n.ID, r.Kind)
+				if err != nil {
+					return err
+				}
+				n.Reissued = true
+				if err := r.Save(); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "%s certificate of %s/%s issued by the new root\n", r.Kind, n.Kind, n.ID)
+			}
+			err = r.Advance(rollover.StepReissued, time.Now())
+			if err != nil {
+				return err
+			}
+			err = r.Save()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Restart the running peers, then retire the previous root with hlf-easy ca rotation retire --name %s\n", r.CA)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "Name of the CA")
+	return cmd
+}
+
+func newRotationRetireCommand(out io.Writer) *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:   "retire",
+		Short: "Remove the previous root from the channels and the nodes",
+		Long: `Remove the previous root from the MSP of the org in each of its channels, then
+complete the rotation of the CA: the nodes of the host stop trusting the
+previous root. It fails while a node still has a certificate of the previous
+root.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := getRotation(name, rollover.StepRetired)
+			if err != nil {
+				return err
+			}
+			var previous []*x509.Certificate
+			for _, root := range r.PreviousRoots {
+				crt, err := utils.ParseX509Certificate(root)
+				if err != nil {
+					return err
+				}
+				previous = append(previous, crt)
+			}
+			caConfig, err := utils.GetCAConfig(r.CA)
+			if err != nil {
+				return err
+			}
+			roots := certs.TrustedRoots(caConfig, r.Kind)
+			nodes, err := node.CATrustingNodes(r.Kind, roots)
+			if err != nil {
+				return err
+			}
+			var pending []node.CATrustNode
+			for _, n := range nodes {
+				if !n.Issued {
+					pending = append(pending, n)
+				}
+			}
+			if len(pending) > 0 {
+				return errors.Errorf("the %s certificates of %s are not issued by the new root yet, they would stop being trusted", r.Kind, node.NodeIDs(pending))
+			}
+			err = updateChannels(out, r, rollover.StepRetired, nil, previous)
+			if err != nil {
+				return err
+			}
+			// the rotation of the CA may already be completed by a retire
+			// that failed afterwards
+			if len(roots) > 1 {
+				_, err = certs.CompleteCARotation(r.CA, r.Kind)
+				if err != nil {
+					return err
+				}
+			}
+			err = node.SyncCATrust(nodes, r.Kind, roots[:1])
+			if err != nil {
+				return err
+			}
+			err = r.Advance(rollover.StepRetired, time.Now())
+			if err != nil {
+				return err
+			}
+			err = r.Save()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Rotation of the %s root of %s retired, restart the running peers\n", r.Kind, r.CA)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "Name of the CA")
+	return cmd
+}
@@ -0,0 +1,98 @@
+package ca
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/escrow"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type unsealCmd struct {
+	Name       string
+	CommonName string
+	Output     string
+}
+
+func (c *unsealCmd) validate() error {
+	if c.Name == "" {
+		return errors.Errorf("--name is required")
+	}
+	if c.CommonName == "" {
+		return errors.Errorf("--common-name is required")
+	}
+	return validateCeremonyCommonName(c.CommonName)
+}
+
+func (c *unsealCmd) run(in io.Reader, out io.Writer, errOut io.Writer) error {
+	escrowDir, err := getEscrowDir(c.Name)
+	if err != nil {
+		return err
+	}
+	sealedKey, err := os.ReadFile(filepath.Join(escrowDir, fmt.Sprintf("%s.json", c.CommonName)))
+	if err != nil {
+		return err
+	}
+	crtPem, err := os.ReadFile(filepath.Join(escrowDir, fmt.Sprintf("%s.crt", c.CommonName)))
+	if err != nil {
+		return err
+	}
+	sealed, err := escrow.ParseSealedKey(sealedKey)
+	if err != nil {
+		return err
+	}
+	// every operator that took part in the ceremony must provide its share
+	_, shares, err := collectShares(
+		in,
+		errOut,
+		len(sealed.Operators),
+		sealed.Operators,
+		fmt.Sprintf("confirm unsealing the key of the admin identity %s", c.CommonName),
+	)
+	if err != nil {
+		return err
+	}
+	keyPem, err := escrow.Unseal(sealedKey, shares)
+	if err != nil {
+		return err
+	}
+	userYaml, err := yaml.Marshal(map[string]interface{}{
+		"key": map[string]interface{}{
+			"pem": string(keyPem),
+		},
+		"cert": map[string]interface{}{
+			"pem": string(crtPem),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if c.Output != "" {
+		return os.WriteFile(c.Output, userYaml, 0600)
+	}
+	_, err = io.Copy(out, bytes.NewReader(userYaml))
+	return err
+}
+
+func newCAUnsealCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &unsealCmd{}
+	cmd := &cobra.Command{
+		Use:   "unseal",
+		Short: "Unseal an admin identity generated in a key ceremony",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(cmd.InOrStdin(), out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.Name, "name", "", "Name of the CA")
+	f.StringVar(&c.CommonName, "common-name", "", "Common name of the admin")
+	f.StringVarP(&c.Output, "output", "o", "", "Output file for the admin identity")
+	return cmd
+}
@@ -0,0 +1,65 @@
+package chaincode
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/chaincode"
+	"io"
+)
+
+// the builder commands are run by the peers through the scripts of their
+// external builder, with the arguments of the external builder phases
+
+func newChaincodeBuilderDetectCommand(peerDir *string) *cobra.Command {
+	return &cobra.Command{
+		Use:  "detect CHAINCODE_SOURCE_DIR CHAINCODE_METADATA_DIR",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			detected, err := chaincode.Detect(*peerDir, args[1])
+			if err != nil {
+				return err
+			}
+			if !detected {
+				return errors.New("the package isn't a registered chaincode service")
+			}
+			return nil
+		},
+	}
+}
+
+func newChaincodeBuilderBuildCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:  "build CHAINCODE_SOURCE_DIR CHAINCODE_METADATA_DIR BUILD_OUTPUT_DIR",
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return chaincode.Build(args[1], args[2])
+		},
+	}
+}
+
+func newChaincodeBuilderReleaseCommand(peerDir *string) *cobra.Command {
+	return &cobra.Command{
+		Use:  "release BUILD_OUTPUT_DIR RELEASE_OUTPUT_DIR",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return chaincode.Release(*peerDir, args[0], args[1])
+		},
+	}
+}
+
+func newChaincodeBuilderCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	var peerDir string
+	cmd := &cobra.Command{
+		Use:    "builder",
+		Short:  "External builder of the chaincode services run by the peers",
+		Hidden: true,
+	}
+	cmd.PersistentFlags().StringVar(&peerDir, "peer-dir", "", "Directory of the peer")
+	cmd.MarkPersistentFlagRequired("peer-dir")
+	cmd.AddCommand(
+		newChaincodeBuilderDetectCommand(&peerDir),
+		newChaincodeBuilderBuildCommand(),
+		newChaincodeBuilderReleaseCommand(&peerDir),
+	)
+	return cmd
+}
@@ -0,0 +1,91 @@
+package chaincode
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/chaincode"
+	"hlf-easy/output"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type buildLogsCmd struct {
+	peerID    string
+	build     string
+	packageID string
+	last      bool
+}
+
+func (c *buildLogsCmd) validate() error {
+	if c.peerID == "" {
+		return errors.New("--peer-id is required")
+	}
+	if c.last && c.build != "" {
+		return errors.New("--last can't be used with --build")
+	}
+	return nil
+}
+
+func (c *buildLogsCmd) run(out io.Writer, errOut io.Writer) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers", c.peerID)
+	if _, err := os.Stat(peerDir); err != nil {
+		return errors.Errorf("peer %s not found", c.peerID)
+	}
+	if c.build == "" && c.packageID == "" && !c.last {
+		builds, err := chaincode.ListBuildLogs(peerDir)
+		if err != nil {
+			return err
+		}
+		return output.Print(out, builds, func(w io.Writer) error {
+			tw := output.NewTabWriter(w)
+			fmt.Fprintln(tw, "BUILD\tSTATUS\tPACKAGE ID\tSTARTED")
+			for _, build := range builds {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", build.ID, build.Status, build.PackageID, build.Started.Format(time.RFC3339))
+			}
+			return tw.Flush()
+		})
+	}
+	build, err := chaincode.FindBuildLog(peerDir, c.build, c.packageID)
+	if err != nil {
+		return err
+	}
+	buildLog, err := chaincode.ReadBuildLog(peerDir, build.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(errOut, "Build %s of package %s %s\n", build.ID, build.PackageID, build.Status)
+	_, err = out.Write(buildLog)
+	return err
+}
+
+func newChaincodeBuildLogsCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &buildLogsCmd{}
+	cmd := &cobra.Command{
+		Use:   "build-logs",
+		Short: "List the chaincode builds of a peer or print the output of one",
+		Long: `List the chaincode builds of a peer or print the output of one. The output of
+the external builders and of the docker builds logged by the peer is stored
+per build in its directory while it runs through hlf-easy, with the package ID
+when the peer logged it, so a failed install can be diagnosed without searching
+the logs of the peer.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.peerID, "peer-id", "", "ID of the peer")
+	f.StringVar(&c.build, "build", "", "ID of the build to print")
+	f.StringVar(&c.packageID, "package-id", "", "Print the latest build of the package ID")
+	f.BoolVar(&c.last, "last", false, "Print the latest build")
+	return cmd
+}
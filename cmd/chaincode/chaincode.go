@@ -0,0 +1,32 @@
+package chaincode
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewChaincodeCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "chaincode",
+		Short: "Manage the chaincodes of the registry and deploy them with their limits",
+	}
+	cmd.AddCommand(
+		newChaincodeRegisterCommand(out, errOut),
+		newChaincodeListCommand(out, errOut),
+		newChaincodePackageCommand(out, errOut),
+		newChaincodeRunCommand(out, errOut),
+		newChaincodeDevRunCommand(out, errOut),
+		newChaincodeBuildLogsCommand(out, errOut),
+		newChaincodeGCCommand(out, errOut),
+		newChaincodeExternalBuilderCommand(out, errOut),
+		newChaincodeServiceCommand(out, errOut),
+		newChaincodeBuilderCommand(out, errOut),
+		newChaincodeInvokeCommand(out, errOut),
+		newChaincodeQueryCommand(out, errOut),
+		newChaincodeCollectionCommand(out, errOut),
+		newChaincodeIndexesCommand(out, errOut),
+		newChaincodeDeploySampleCommand(out, errOut),
+		newChaincodeServeSampleCommand(),
+	)
+	return cmd
+}
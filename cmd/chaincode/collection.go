@@ -0,0 +1,340 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/chaincode"
+	"hlf-easy/explorer"
+	"io"
+	"os"
+)
+
+func newChaincodeCollectionCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "collection",
+		Short: "Author and validate the private data collections of the chaincodes of the registry",
+		Long: `Author and validate the private data collections of the chaincodes of the
+registry. The collections are checked as the peers check them when the
+definition of the chaincode is approved, and the orgs of their policies are
+checked against --msp-id or the orgs of the channel read through --peer-id and
+--channel. export writes the collections_config.json passed to the approval and
+the commit of the definition.`,
+	}
+	cmd.AddCommand(
+		newCollectionAddCommand(out),
+		newCollectionRemoveCommand(out),
+		newCollectionValidateCommand(out),
+		newCollectionExportCommand(out),
+	)
+	return cmd
+}
+
+// channelOrgs selects the orgs the policies of the collections are checked
+// against
+type channelOrgs struct {
+	mspIDs []string
+	opts   explorer.Options
+}
+
+func (o *channelOrgs) addFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringArrayVar(&o.mspIDs, "msp-id", []string{}, "MSP ID of an org of the channel, the policies may only refer to these orgs")
+	f.StringVar(&o.opts.PeerID, "peer-id", "", "ID of a peer of the channel to read its orgs from, with --channel")
+	f.StringVar(&o.opts.Channel, "channel", "", "Channel of the chaincode to read its orgs from, with --peer-id")
+	f.StringVar(&o.opts.Identity, "identity", "", "Identity file of a member of the channel, an admin identity issued by the local CA of the peer if empty")
+}
+
+// get returns the MSP IDs of the flags or of the channel, none when the
+// channel isn't given
+func (o *channelOrgs) get() ([]string, error) {
+	if len(o.mspIDs) > 0 || o.opts.PeerID == "" || o.opts.Channel == "" {
+		return o.mspIDs, nil
+	}
+	block, err := explorer.GetConfigBlock(o.opts)
+	if err != nil {
+		return nil, err
+	}
+	config, err := explorer.ConfigFromBlock(block)
+	if err != nil {
+		return nil, err
+	}
+	return explorer.ApplicationMSPIDs(config)
+}
+
+// validateCollections validates the collections against the orgs and logs
+// the warnings
+func validateCollections(collections []chaincode.Collection, orgs *channelOrgs) error {
+	mspIDs, err := orgs.get()
+	if err != nil {
+		return err
+	}
+	warnings, err := chaincode.ValidateCollections(collections, mspIDs)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		log.Warn(warning)
+	}
+	return nil
+}
+
+type collectionAddCmd struct {
+	chaincodeName     string
+	collection        chaincode.Collection
+	members           []string
+	endorsementPolicy string
+	orgs              channelOrgs
+}
+
+func (c *collectionAddCmd) validate() error {
+	if c.chaincodeName == "" {
+		return errors.New("--chaincode is required")
+	}
+	if c.collection.Name == "" {
+		return errors.New("--collection is required")
+	}
+	if c.collection.Policy == "" {
+		if len(c.members) == 0 {
+			return errors.New("--member or --policy is required")
+		}
+		c.collection.Policy = chaincode.MemberPolicy(c.members)
+	} else if len(c.members) > 0 {
+		return errors.New("--member can't be used with --policy")
+	}
+	if c.endorsementPolicy != "" {
+		c.collection.EndorsementPolicy = &chaincode.CollectionEndorsementPolicy{SignaturePolicy: c.endorsementPolicy}
+	}
+	return nil
+}
+
+func (c *collectionAddCmd) run(out io.Writer) error {
+	definition, err := chaincode.Get(c.chaincodeName)
+	if err != nil {
+		return err
+	}
+	collections := []chaincode.Collection{}
+	replaced := false
+	for _, existing := range definition.Collections {
+		if existing.Name == c.collection.Name {
+			existing = c.collection
+			replaced = true
+		}
+		collections = append(collections, existing)
+	}
+	if !replaced {
+		collections = append(collections, c.collection)
+	}
+	err = validateCollections(collections, &c.orgs)
+	if err != nil {
+		return err
+	}
+	definition.Collections = collections
+	err = chaincode.Save(*definition)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Collection %s of chaincode %s saved with the policy %s\n", c.collection.Name, c.chaincodeName, c.collection.Policy)
+	return nil
+}
+
+func newCollectionAddCommand(out io.Writer) *cobra.Command {
+	c := &collectionAddCmd{}
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "Add or replace a collection of a chaincode of the registry",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.chaincodeName, "chaincode", "", "Name of the chaincode")
+	f.StringVar(&c.collection.Name, "collection", "", "Name of the collection")
+	f.StringArrayVar(&c.members, "member", []string{}, "MSP ID of an org whose members are members of the collection")
+	f.StringVar(&c.collection.Policy, "policy", "", "Signature policy of the members of the collection, e.g. OR('Org1MSP.member','Org2MSP.member'), instead of --member")
+	f.Int32Var(&c.collection.RequiredPeerCount, "required-peer-count", 0, "Number of peers the private data must be disseminated to at endorsement")
+	f.Int32Var(&c.collection.MaxPeerCount, "max-peer-count", 3, "Maximum number of peers the private data are disseminated to at endorsement")
+	f.Uint64Var(&c.collection.BlockToLive, "block-to-live", 0, "Number of blocks the private data are kept, forever if 0")
+	f.BoolVar(&c.collection.MemberOnlyRead, "member-only-read", true, "Only the members of the collection can read its private data")
+	f.BoolVar(&c.collection.MemberOnlyWrite, "member-only-write", true, "Only the members of the collection can write its private data")
+	f.StringVar(&c.endorsementPolicy, "endorsement-policy", "", "Signature policy endorsing the writes to the collection instead of the policy of the chaincode")
+	c.orgs.addFlags(cmd)
+	return cmd
+}
+
+type collectionRemoveCmd struct {
+	chaincodeName string
+	name          string
+}
+
+func (c *collectionRemoveCmd) validate() error {
+	if c.chaincodeName == "" {
+		return errors.New("--chaincode is required")
+	}
+	if c.name == "" {
+		return errors.New("--collection is required")
+	}
+	return nil
+}
+
+func (c *collectionRemoveCmd) run(out io.Writer) error {
+	definition, err := chaincode.Get(c.chaincodeName)
+	if err != nil {
+		return err
+	}
+	collections := []chaincode.Collection{}
+	for _, existing := range definition.Collections {
+		if existing.Name != c.name {
+			collections = append(collections, existing)
+		}
+	}
+	if len(collections) == len(definition.Collections) {
+		return errors.Errorf("chaincode %s has no collection %s", c.chaincodeName, c.name)
+	}
+	definition.Collections = collections
+	err = chaincode.Save(*definition)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Collection %s of chaincode %s removed\n", c.name, c.chaincodeName)
+	return nil
+}
+
+func newCollectionRemoveCommand(out io.Writer) *cobra.Command {
+	c := &collectionRemoveCmd{}
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a collection of a chaincode of the registry",
+		Long: `Remove a collection of a chaincode of the registry. A collection of a
+committed definition can't be removed from the channel, only from the next
+definitions of hlf-easy.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.chaincodeName, "chaincode", "", "Name of the chaincode")
+	f.StringVar(&c.name, "collection", "", "Name of the collection")
+	return cmd
+}
+
+type collectionValidateCmd struct {
+	chaincodeName string
+	file          string
+	orgs          channelOrgs
+}
+
+func (c *collectionValidateCmd) validate() error {
+	if (c.chaincodeName == "") == (c.file == "") {
+		return errors.New("either --chaincode or --file is required")
+	}
+	return nil
+}
+
+func (c *collectionValidateCmd) run(out io.Writer) error {
+	var collections []chaincode.Collection
+	if c.file != "" {
+		collectionsBytes, err := os.ReadFile(c.file)
+		if err != nil {
+			return err
+		}
+		collections, err = chaincode.ParseCollections(collectionsBytes)
+		if err != nil {
+			return err
+		}
+	} else {
+		definition, err := chaincode.Get(c.chaincodeName)
+		if err != nil {
+			return err
+		}
+		collections = definition.Collections
+	}
+	err := validateCollections(collections, &c.orgs)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%d collections are valid\n", len(collections))
+	return nil
+}
+
+func newCollectionValidateCommand(out io.Writer) *cobra.Command {
+	c := &collectionValidateCmd{}
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate a collections_config.json or the collections of a chaincode of the registry",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.chaincodeName, "chaincode", "", "Name of the chaincode of the registry")
+	f.StringVar(&c.file, "file", "", "collections_config.json to validate")
+	c.orgs.addFlags(cmd)
+	return cmd
+}
+
+type collectionExportCmd struct {
+	chaincodeName string
+	outputPath    string
+}
+
+func (c *collectionExportCmd) validate() error {
+	if c.chaincodeName == "" {
+		return errors.New("--chaincode is required")
+	}
+	return nil
+}
+
+func (c *collectionExportCmd) run(out io.Writer) error {
+	definition, err := chaincode.Get(c.chaincodeName)
+	if err != nil {
+		return err
+	}
+	if len(definition.Collections) == 0 {
+		return errors.Errorf("chaincode %s has no collections", c.chaincodeName)
+	}
+	collectionsBytes, err := json.MarshalIndent(definition.Collections, "", "  ")
+	if err != nil {
+		return err
+	}
+	collectionsBytes = append(collectionsBytes, '\n')
+	if c.outputPath == "" {
+		_, err = out.Write(collectionsBytes)
+		return err
+	}
+	err = os.WriteFile(c.outputPath, collectionsBytes, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Collections of chaincode %s written to %s\n", c.chaincodeName, c.outputPath)
+	return nil
+}
+
+func newCollectionExportCommand(out io.Writer) *cobra.Command {
+	c := &collectionExportCmd{}
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the collections_config.json of a chaincode of the registry",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.chaincodeName, "chaincode", "", "Name of the chaincode")
+	f.StringVarP(&c.outputPath, "output", "o", "", "File to write the collections config to, it's printed if empty")
+	return cmd
+}
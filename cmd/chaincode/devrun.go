@@ -0,0 +1,83 @@
+package chaincode
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/chaincode"
+	"hlf-easy/node"
+	"hlf-easy/proc"
+	"io"
+	"time"
+)
+
+type devRunCmd struct {
+	peerID   string
+	name     string
+	version  string
+	binary   string
+	args     []string
+	logLevel string
+	watch    bool
+	interval time.Duration
+}
+
+func (c *devRunCmd) validate() error {
+	if c.peerID == "" {
+		return errors.New("--peer-id is required")
+	}
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	if c.binary == "" {
+		return errors.New("--binary is required")
+	}
+	return nil
+}
+
+func (c *devRunCmd) run(out io.Writer, errOut io.Writer) error {
+	address, err := node.GetDevPeerChaincodeAddress(c.peerID)
+	if err != nil {
+		return err
+	}
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	return chaincode.RunDev(ctx, chaincode.DevRunOptions{
+		ID:          c.name + ":" + c.version,
+		PeerAddress: address,
+		Binary:      c.binary,
+		Args:        c.args,
+		LogLevel:    c.logLevel,
+		Watch:       c.watch,
+		Interval:    c.interval,
+	}, out, errOut)
+}
+
+func newChaincodeDevRunCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &devRunCmd{}
+	cmd := &cobra.Command{
+		Use:   "dev-run [-- args]",
+		Short: "Run a chaincode binary connected to a peer started with --dev-mode",
+		Long: `Run a chaincode binary connected to a peer started with peer start --dev-mode.
+The chaincode registers with the peer as name:version, the package ID its
+definition is approved with, and connects to the chaincode address of the peer
+without TLS. The arguments after -- are passed to the binary. With --watch the
+chaincode is restarted when its binary is rebuilt.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.args = args
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.peerID, "peer-id", "", "ID of the peer running in dev mode")
+	f.StringVar(&c.name, "name", "", "Name of the chaincode")
+	f.StringVar(&c.version, "version", "1.0", "Version of the chaincode, the package ID is name:version")
+	f.StringVar(&c.binary, "binary", "", "Chaincode binary")
+	f.StringVar(&c.logLevel, "log-level", "info", "Log level of the chaincode shim")
+	f.BoolVar(&c.watch, "watch", false, "Restart the chaincode when its binary is rebuilt")
+	f.DurationVar(&c.interval, "interval", chaincode.DefaultDevRunInterval, "Interval the binary is checked for a rebuild with --watch")
+	return cmd
+}
@@ -0,0 +1,165 @@
+package chaincode
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/chaincode"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"io"
+	"strings"
+)
+
+func newChaincodeExternalBuilderCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "external-builder",
+		Short: "Scaffold the custom external builders of the peers of the host",
+		Long: `Scaffold the custom external builders of the peers of the host, for the
+chaincode runtimes the builders of Fabric don't support. A builder is written in
+the builders directory of the hlf-easy home with the detect, build, release and
+run scripts to complete, and it's added to the externalBuilders of the core.yaml
+of the peers after the builder of hlf-easy. The peers must be restarted to use
+it.`,
+	}
+	cmd.AddCommand(
+		newExternalBuilderScaffoldCommand(out),
+		newExternalBuilderListCommand(out),
+		newExternalBuilderRemoveCommand(out),
+	)
+	return cmd
+}
+
+// updatePeerBuilders renders the core.yaml of the peers with the external
+// builders of the host
+func updatePeerBuilders() error {
+	updated, err := node.UpdatePeerExternalBuilders()
+	if err != nil {
+		return err
+	}
+	if len(updated) > 0 {
+		log.Warnf("Restart the peers %s to use their external builders", strings.Join(updated, ", "))
+	}
+	return nil
+}
+
+type externalBuilderScaffoldCmd struct {
+	name                 string
+	propagateEnvironment []string
+	withRun              bool
+}
+
+func (c *externalBuilderScaffoldCmd) validate() error {
+	if c.name == "" {
+		return errors.New("--builder is required")
+	}
+	return nil
+}
+
+func (c *externalBuilderScaffoldCmd) run(out io.Writer) error {
+	b, err := chaincode.ScaffoldExternalBuilder(c.name, c.propagateEnvironment, c.withRun)
+	if err != nil {
+		return err
+	}
+	err = updatePeerBuilders()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "External builder %s written to %s, complete its scripts in %s/bin\n", b.Name, b.Path, b.Path)
+	return nil
+}
+
+func newExternalBuilderScaffoldCommand(out io.Writer) *cobra.Command {
+	c := &externalBuilderScaffoldCmd{}
+	cmd := &cobra.Command{
+		Use:   "scaffold",
+		Short: "Write a new external builder and add it to the peers",
+		Long: `Write a new external builder and add it to the peers. Its detect script handles
+the packages whose metadata.json has the name of the builder as type, its build
+script copies the source and its release script releases the couchdb indexes
+and the connection.json of the package. The run script launching the chaincode
+must be implemented, without --run the builder builds chaincode servers the peer
+connects to.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.name, "builder", "", "Name of the builder, the type of the packages it detects")
+	f.StringArrayVar(&c.propagateEnvironment, "propagate-env", []string{}, "Environment variable of the peer passed to the scripts of the builder")
+	f.BoolVar(&c.withRun, "run", true, "Write a run script, the peer launches the chaincodes through it")
+	return cmd
+}
+
+type externalBuilderListCmd struct{}
+
+func (c *externalBuilderListCmd) run(out io.Writer) error {
+	builders, err := chaincode.ListExternalBuilders()
+	if err != nil {
+		return err
+	}
+	return output.Print(out, builders, func(w io.Writer) error {
+		for _, b := range builders {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", b.Name, b.Path, strings.Join(b.PropagateEnvironment, ","))
+		}
+		return nil
+	})
+}
+
+func newExternalBuilderListCommand(out io.Writer) *cobra.Command {
+	c := &externalBuilderListCmd{}
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the external builders of the host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.run(out)
+		},
+	}
+}
+
+type externalBuilderRemoveCmd struct {
+	name string
+}
+
+func (c *externalBuilderRemoveCmd) validate() error {
+	if c.name == "" {
+		return errors.New("--builder is required")
+	}
+	return nil
+}
+
+func (c *externalBuilderRemoveCmd) run(out io.Writer) error {
+	err := chaincode.RemoveExternalBuilder(c.name)
+	if err != nil {
+		return err
+	}
+	err = updatePeerBuilders()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "External builder %s removed\n", c.name)
+	return nil
+}
+
+func newExternalBuilderRemoveCommand(out io.Writer) *cobra.Command {
+	c := &externalBuilderRemoveCmd{}
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove an external builder and remove it from the peers",
+		Long: `Remove an external builder and remove it from the peers. The chaincodes it
+built must be installed again with another builder.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.name, "builder", "", "Name of the builder")
+	return cmd
+}
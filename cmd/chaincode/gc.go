@@ -0,0 +1,78 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/chaincode"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"time"
+)
+
+type gcCmd struct {
+	opts    chaincode.GCOptions
+	timeout time.Duration
+}
+
+func (c *gcCmd) validate() error {
+	if c.opts.Keep < 1 {
+		return errors.New("--keep must be at least 1")
+	}
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	return nil
+}
+
+func (c *gcCmd) run(out io.Writer, errOut io.Writer) error {
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	resources, err := chaincode.GC(ctx, c.opts)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, resources, func(w io.Writer) error {
+		for _, r := range resources {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Kind, r.PeerID, r.Label, r.Name, r.Created.Format(time.RFC3339))
+		}
+		action := "Removed"
+		if c.opts.DryRun {
+			action = "Would remove"
+		}
+		fmt.Fprintf(w, "%s %d containers and images\n", action, len(resources))
+		return nil
+	})
+}
+
+func newChaincodeGCCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &gcCmd{}
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove the stale containers and images of the chaincodes built by the peers of the host",
+		Long: `Remove the stale containers and images of the chaincodes built by the peers of
+the host with docker, named after the network ID, the peer and the package of
+the chaincode. The containers that aren't running are removed, the peers
+create a new container when they launch a chaincode, and so are the images of
+a chaincode of a peer older than the --keep newest ones, unless a running
+container uses them. The chaincodes run by chaincode run and the images of the
+other peers of the docker host are left alone.`,
+		Example: `  hlf-easy chaincode gc --dry-run
+  hlf-easy chaincode gc --peer-id=peer0 --name=asset --keep=2`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringSliceVar(&c.opts.PeerIDs, "peer-id", []string{}, "Peers whose chaincodes are cleaned up, all the peers of the host when empty")
+	f.StringSliceVar(&c.opts.Names, "name", []string{}, "Chaincodes to clean up, by name or package label, all of them when empty")
+	f.IntVar(&c.opts.Keep, "keep", 1, "Number of newest images kept per chaincode and peer")
+	f.BoolVar(&c.opts.DryRun, "dry-run", false, "List the stale containers and images without removing them")
+	f.DurationVar(&c.timeout, "timeout", chaincode.DefaultGCTimeout, "How long the listing and the removals have to complete, 0 waits without a limit")
+	return cmd
+}
@@ -0,0 +1,123 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/contract"
+	"hlf-easy/proc"
+	"io"
+	"time"
+)
+
+type transactionCmd struct {
+	opts      contract.Options
+	args      string
+	transient string
+	submit    bool
+	timeout   time.Duration
+}
+
+func (c *transactionCmd) validate() error {
+	if c.opts.PeerID == "" {
+		return errors.New("--peer-id is required")
+	}
+	if c.opts.Channel == "" {
+		return errors.New("--channel is required")
+	}
+	if c.opts.Chaincode == "" {
+		return errors.New("--name is required")
+	}
+	if c.args == "" {
+		return errors.New("--args is required")
+	}
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	var err error
+	c.opts.Function, c.opts.Args, err = contract.ParseArgs(c.args)
+	if err != nil {
+		return err
+	}
+	if c.transient != "" {
+		c.opts.Transient, err = contract.ParseTransient(c.transient)
+	}
+	return err
+}
+
+func (c *transactionCmd) run(out io.Writer, errOut io.Writer) error {
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	var result []byte
+	var err error
+	if c.submit {
+		result, err = contract.Invoke(ctx, c.opts)
+	} else {
+		result, err = contract.Query(ctx, c.opts)
+	}
+	if err != nil {
+		return err
+	}
+	if c.submit {
+		fmt.Fprintf(errOut, "Transaction %s of chaincode %s committed on channel %s\n", c.opts.Function, c.opts.Chaincode, c.opts.Channel)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	_, err = fmt.Fprintln(out, string(result))
+	return err
+}
+
+func addTransactionFlags(cmd *cobra.Command, c *transactionCmd) {
+	f := cmd.Flags()
+	f.StringVar(&c.opts.PeerID, "peer-id", "", "ID of the peer whose gateway sends the transaction")
+	f.StringVar(&c.opts.Channel, "channel", "", "Channel of the chaincode")
+	f.StringVar(&c.opts.Chaincode, "name", "", "Name of the chaincode")
+	f.StringVar(&c.args, "args", "", `Function and arguments, ["set","a","10"] or {"Args":["set","a","10"]}`)
+	f.StringVar(&c.transient, "transient", "", `Transient data passed to the chaincode without being written to the ledger, {"key":"value"}`)
+	f.StringVar(&c.opts.Identity, "identity", "", "Identity file signing the transaction, an admin identity issued by the local CA of the peer if empty")
+	f.StringVar(&c.opts.Endpoint, "endpoint", "", "Endpoint of the peer, its external endpoint if empty")
+	f.DurationVar(&c.timeout, "timeout", contract.DefaultTimeout, "How long the transaction has to complete, 0 waits without a limit")
+}
+
+const transactionLong = `
+The transaction is sent to the peer, the other endorsers and the orderers of
+the channel are discovered through it. Without --identity the transaction is
+signed by an admin of the org of the peer that hlf-easy issues with the local
+CA of the peer and keeps in gateway-admin.yaml in its directory, a peer
+enrolled with a Fabric CA needs --identity.`
+
+func newChaincodeInvokeCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &transactionCmd{submit: true}
+	cmd := &cobra.Command{
+		Use:   "invoke",
+		Short: "Submit a transaction to a chaincode and wait for its commit",
+		Long:  "Submit a transaction calling a function of a chaincode and wait for its commit.\n" + transactionLong,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	addTransactionFlags(cmd, c)
+	return cmd
+}
+
+func newChaincodeQueryCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &transactionCmd{}
+	cmd := &cobra.Command{
+		Use:   "query",
+		Short: "Evaluate a function of a chaincode without submitting a transaction",
+		Long:  "Evaluate a function of a chaincode on the peer and print its result, nothing\nis written to the ledger.\n" + transactionLong,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	addTransactionFlags(cmd, c)
+	return cmd
+}
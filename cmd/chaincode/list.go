@@ -0,0 +1,42 @@
+package chaincode
+
+import (
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/chaincode"
+	"hlf-easy/output"
+	"io"
+)
+
+type listCmd struct{}
+
+func (c *listCmd) validate() error {
+	return nil
+}
+
+func (c *listCmd) run(out io.Writer, errOut io.Writer) error {
+	definitions, err := chaincode.List()
+	if err != nil {
+		return err
+	}
+	return output.Print(out, definitions, func(w io.Writer) error {
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		return encoder.Encode(definitions)
+	})
+}
+
+func newChaincodeListCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &listCmd{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the chaincodes of the registry and their limits",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
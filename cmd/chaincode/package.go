@@ -0,0 +1,61 @@
+package chaincode
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/chaincode"
+	"io"
+	"os"
+)
+
+type packageCmd struct {
+	name   string
+	output string
+}
+
+func (c *packageCmd) validate() error {
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	if c.output == "" {
+		return errors.New("--output is required")
+	}
+	return nil
+}
+
+func (c *packageCmd) run(out io.Writer, errOut io.Writer) error {
+	definition, err := chaincode.Get(c.name)
+	if err != nil {
+		return err
+	}
+	pkg, packageID, err := chaincode.Package(*definition)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(c.output, pkg, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(errOut, "Package written to %s, install it in the peers with peer lifecycle chaincode install\n", c.output)
+	fmt.Fprintln(out, packageID)
+	return nil
+}
+
+func newChaincodePackageCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &packageCmd{}
+	cmd := &cobra.Command{
+		Use:   "package",
+		Short: "Build the package of a chaincode of the registry with its limits and print its package ID",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.name, "name", "", "Name of the chaincode")
+	f.StringVarP(&c.output, "output", "o", "", "Output file for the package")
+	return cmd
+}
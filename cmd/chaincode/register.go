@@ -0,0 +1,83 @@
+package chaincode
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"hlf-easy/chaincode"
+	"io"
+	"os"
+)
+
+type registerCmd struct {
+	definition        chaincode.Definition
+	collectionsConfig string
+}
+
+func (c *registerCmd) validate() error {
+	if c.collectionsConfig != "" {
+		collectionsBytes, err := os.ReadFile(c.collectionsConfig)
+		if err != nil {
+			return err
+		}
+		c.definition.Collections, err = chaincode.ParseCollections(collectionsBytes)
+		if err != nil {
+			return err
+		}
+	}
+	err := c.definition.Validate()
+	if err != nil {
+		return err
+	}
+	return chaincode.CheckLimits(c.definition)
+}
+
+func (c *registerCmd) run(out io.Writer, errOut io.Writer) error {
+	// a new version keeps the collections of the chaincode
+	if c.collectionsConfig == "" {
+		if existing, err := chaincode.Get(c.definition.Name); err == nil {
+			c.definition.Collections = existing.Collections
+		}
+	}
+	err := chaincode.Save(c.definition)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Chaincode %s registered\n", c.definition.Label())
+	// the connection.json of the peers follow the label of the chaincode
+	services, err := chaincode.ListServices()
+	if err != nil {
+		return err
+	}
+	for _, s := range services {
+		if s.Name == c.definition.Name {
+			return syncPeers(out)
+		}
+	}
+	return nil
+}
+
+func newChaincodeRegisterCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &registerCmd{}
+	cmd := &cobra.Command{
+		Use:   "register",
+		Short: "Store the definition of a chaincode and its limits in the registry",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.definition.Name, "name", "", "Name of the chaincode")
+	f.StringVar(&c.definition.Version, "version", "1.0", "Version of the chaincode")
+	f.StringVar(&c.definition.Type, "type", chaincode.TypeCCaaS, "Type of the chaincode: ccaas or docker")
+	f.StringVar(&c.definition.Address, "address", "", "Address of the chaincode server, host:port")
+	f.StringVar(&c.definition.Image, "image", "", "Image of a docker chaincode")
+	f.StringVar(&c.definition.Limits.ExecuteTimeout, "execute-timeout", "", "Timeout of the invocations enforced by the chaincode server")
+	f.StringVar(&c.definition.Limits.DialTimeout, "dial-timeout", "", "Time the peer waits to connect to the chaincode server")
+	f.Int64Var(&c.definition.Limits.MemoryMB, "memory-mb", 0, "Memory limit in megabytes of the container of a docker chaincode")
+	f.Float64Var(&c.definition.Limits.CPUs, "cpus", 0, "CPU limit of the container of a docker chaincode")
+	f.StringVar(&c.collectionsConfig, "collections-config", "", "collections_config.json of the private data collections of the chaincode, the existing collections are kept if empty")
+	return cmd
+}
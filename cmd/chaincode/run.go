@@ -0,0 +1,59 @@
+package chaincode
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/chaincode"
+	"io"
+	"os/exec"
+)
+
+type runCmd struct {
+	name string
+}
+
+func (c *runCmd) validate() error {
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	return nil
+}
+
+func (c *runCmd) run(out io.Writer, errOut io.Writer) error {
+	definition, err := chaincode.Get(c.name)
+	if err != nil {
+		return err
+	}
+	// the package ID is derived from the package, the same one installed in the peers
+	_, packageID, err := chaincode.Package(*definition)
+	if err != nil {
+		return err
+	}
+	args, err := chaincode.DockerRunArgs(*definition, packageID)
+	if err != nil {
+		return err
+	}
+	log.Infof("Running docker %v", args)
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	return cmd.Run()
+}
+
+func newChaincodeRunCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &runCmd{}
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Start the container of a docker chaincode of the registry with its limits",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.name, "name", "", "Name of the chaincode")
+	return cmd
+}
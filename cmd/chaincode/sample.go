@@ -0,0 +1,159 @@
+package chaincode
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"hlf-easy/samples"
+	"io"
+	"strings"
+	"time"
+)
+
+type deploySampleCmd struct {
+	opts    samples.DeployOptions
+	list    bool
+	timeout time.Duration
+}
+
+func (c *deploySampleCmd) validate() error {
+	if c.list {
+		return nil
+	}
+	if c.opts.Sample == "" {
+		return errors.New("--sample is required")
+	}
+	if c.opts.Channel == "" {
+		return errors.New("--channel is required")
+	}
+	if len(c.opts.PeerIDs) == 0 {
+		return errors.New("--peer-id is required")
+	}
+	if c.opts.OrdererBundle == "" {
+		return errors.New("--orderer-bundle is required")
+	}
+	if c.opts.Address == "" {
+		return errors.New("--address is required")
+	}
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	return c.opts.Validate()
+}
+
+func (c *deploySampleCmd) run(out io.Writer, errOut io.Writer) error {
+	if c.list {
+		list := samples.List()
+		return output.Print(out, list, func(out io.Writer) error {
+			for _, s := range list {
+				fmt.Fprintf(out, "%s: %s\n", s.Name, s.Description)
+				for _, function := range s.Functions {
+					fmt.Fprintf(out, "  %s\n", function)
+				}
+			}
+			return nil
+		})
+	}
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	deployment, err := samples.Deploy(ctx, c.opts)
+	if deployment != nil {
+		fmt.Fprintf(errOut, "Chaincode %s is served on %s by process %d, its log is %s\n", deployment.Definition.Name, deployment.Definition.Address, deployment.PID, deployment.LogPath)
+	}
+	if err != nil {
+		return err
+	}
+	return output.Print(out, deployment, func(out io.Writer) error {
+		fmt.Fprintf(out, "Sample %s deployed as chaincode %s on channel %s with package ID %s\n", c.opts.Sample, deployment.Definition.Name, c.opts.Channel, deployment.PackageID)
+		return nil
+	})
+}
+
+func newChaincodeDeploySampleCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &deploySampleCmd{}
+	cmd := &cobra.Command{
+		Use:   "deploy-sample",
+		Short: "Deploy a sample chaincode bundled in hlf-easy on a channel",
+		Long: `Deploy a sample chaincode bundled in hlf-easy on a channel of the peers of the
+host, for demos and tests without network access: nothing is downloaded or
+built, the chaincode is served by a background hlf-easy process as a
+chaincode-as-a-service.
+
+The sample is registered under --name, the name of the sample by default,
+installed in every peer, approved for the org of every peer and committed with
+sequence 1, so it's deployed once on a channel. The orgs of the peers are the
+members of the private data collections of the sample. The process serving the
+chaincode keeps running after the command, it's printed with its log file.
+
+The samples are listed with --list.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVar(&c.list, "list", false, "List the samples and their functions")
+	f.StringVar(&c.opts.Sample, "sample", "", fmt.Sprintf("Sample to deploy: %s", strings.Join(samples.Names(), ", ")))
+	f.StringVar(&c.opts.Name, "name", "", "Name of the chaincode, the name of the sample if empty")
+	f.StringVar(&c.opts.Version, "version", samples.DefaultVersion, "Version of the chaincode")
+	f.StringVar(&c.opts.Channel, "channel", "", "Channel to deploy the chaincode on")
+	f.StringSliceVar(&c.opts.PeerIDs, "peer-id", []string{}, "Peers of the host enrolled with a local CA to install the chaincode in")
+	f.StringVar(&c.opts.OrdererBundle, "orderer-bundle", "", "Orderer bundle of the ordering service of the channel")
+	f.StringVar(&c.opts.Address, "address", "", "Address the chaincode server listens on, host:port reachable by the peers")
+	f.DurationVar(&c.timeout, "timeout", samples.DefaultDeployTimeout, "How long the install, approvals and commit have to complete, 0 waits without a limit")
+	return cmd
+}
+
+type serveSampleCmd struct {
+	sample    string
+	address   string
+	packageID string
+}
+
+func (c *serveSampleCmd) validate() error {
+	if c.address == "" {
+		return errors.New("--address is required")
+	}
+	if c.packageID == "" {
+		return errors.New("--package-id is required")
+	}
+	_, err := samples.Get(c.sample)
+	return err
+}
+
+func (c *serveSampleCmd) run() error {
+	s, err := samples.Get(c.sample)
+	if err != nil {
+		return err
+	}
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	return samples.Serve(ctx, c.address, c.packageID, s.Chaincode)
+}
+
+// newChaincodeServeSampleCommand serves a sample chaincode, it's started in
+// the background by deploy-sample and sandbox up
+func newChaincodeServeSampleCommand() *cobra.Command {
+	c := &serveSampleCmd{}
+	cmd := &cobra.Command{
+		Use:    "serve-sample",
+		Short:  "Serve a sample chaincode bundled in hlf-easy",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run()
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.sample, "sample", "", "Sample to serve")
+	f.StringVar(&c.address, "address", "", "Address the chaincode server listens on")
+	f.StringVar(&c.packageID, "package-id", "", "Package ID of the chaincode installed in the peers")
+	return cmd
+}
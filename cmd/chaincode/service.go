@@ -0,0 +1,215 @@
+package chaincode
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/chaincode"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"hlf-easy/utils"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// syncPeers writes the connection.json of the services in the peers, the
+// peers created before the service registry get its builder first
+func syncPeers(out io.Writer) error {
+	updated, err := node.InstallPeerBuilders()
+	if err != nil {
+		return err
+	}
+	if len(updated) > 0 {
+		log.Warnf("Restart the peers %s to use the builder of the chaincode services", strings.Join(updated, ", "))
+	}
+	results, err := chaincode.SyncPeers()
+	if err != nil {
+		return err
+	}
+	return output.Print(out, results, func(w io.Writer) error {
+		for _, r := range results {
+			state := "unchanged"
+			if r.Changed {
+				state = "updated"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Peer, r.Label, r.Address, state)
+		}
+		return nil
+	})
+}
+
+type serviceSetCmd struct {
+	service  chaincode.Service
+	rootCert string
+}
+
+func (c *serviceSetCmd) validate() error {
+	if c.service.Name == "" {
+		return errors.New("--name is required")
+	}
+	if c.service.Address == "" {
+		return errors.New("--address is required")
+	}
+	return nil
+}
+
+func (c *serviceSetCmd) run(out io.Writer, errOut io.Writer) error {
+	// the connection.json of the peers are generated from the definition
+	if _, err := chaincode.Get(c.service.Name); err != nil {
+		return err
+	}
+	if c.rootCert != "" {
+		rootCertBytes, err := os.ReadFile(c.rootCert)
+		if err != nil {
+			return err
+		}
+		if _, err := utils.ParseX509CertificateChain(rootCertBytes); err != nil {
+			return errors.Wrapf(err, "invalid TLS CA %s", c.rootCert)
+		}
+		c.service.RootCert = string(rootCertBytes)
+	}
+	c.service.UpdatedAt = time.Now()
+	err := chaincode.SaveService(c.service)
+	if err != nil {
+		return err
+	}
+	return syncPeers(out)
+}
+
+func newChaincodeServiceSetCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &serviceSetCmd{}
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: "Register the address and TLS CA of a chaincode server, or move it, and update the peers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.service.Name, "name", "", "Name of the chaincode of the registry")
+	f.StringVar(&c.service.Address, "address", "", "Address of the chaincode server, host:port")
+	f.StringVar(&c.rootCert, "root-cert", "", "PEM file with the TLS CA of the chaincode server, TLS is disabled if empty")
+	f.BoolVar(&c.service.ClientAuth, "client-auth", false, "The peers authenticate to the chaincode server with their TLS certificates")
+	return cmd
+}
+
+type serviceRemoveCmd struct {
+	name string
+}
+
+func (c *serviceRemoveCmd) validate() error {
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	return nil
+}
+
+func (c *serviceRemoveCmd) run(out io.Writer, errOut io.Writer) error {
+	err := chaincode.RemoveService(c.name)
+	if err != nil {
+		return err
+	}
+	return syncPeers(out)
+}
+
+func newChaincodeServiceRemoveCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &serviceRemoveCmd{}
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a chaincode server from the registry and its connection.json from the peers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.name, "name", "", "Name of the chaincode")
+	return cmd
+}
+
+type serviceListCmd struct{}
+
+func (c *serviceListCmd) validate() error {
+	return nil
+}
+
+func (c *serviceListCmd) run(out io.Writer, errOut io.Writer) error {
+	services, err := chaincode.ListServices()
+	if err != nil {
+		return err
+	}
+	return output.Print(out, services, func(w io.Writer) error {
+		for _, s := range services {
+			security := "plaintext"
+			if s.ClientAuth {
+				security = "mutual-tls"
+			} else if s.RootCert != "" {
+				security = "tls"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, s.Address, security, s.UpdatedAt.Format(time.RFC3339))
+		}
+		return nil
+	})
+}
+
+func newChaincodeServiceListCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &serviceListCmd{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the chaincode servers of the registry",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
+
+type serviceSyncCmd struct{}
+
+func (c *serviceSyncCmd) validate() error {
+	return nil
+}
+
+func (c *serviceSyncCmd) run(out io.Writer, errOut io.Writer) error {
+	return syncPeers(out)
+}
+
+func newChaincodeServiceSyncCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &serviceSyncCmd{}
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Generate again the connection.json of the chaincode servers in all the peers of the host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
+
+func newChaincodeServiceCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "Track the chaincode servers the peers of the host connect to",
+	}
+	cmd.AddCommand(
+		newChaincodeServiceSetCommand(out, errOut),
+		newChaincodeServiceRemoveCommand(out, errOut),
+		newChaincodeServiceListCommand(out, errOut),
+		newChaincodeServiceSyncCommand(out, errOut),
+	)
+	return cmd
+}
@@ -0,0 +1,259 @@
+package channel
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/explorer"
+	"hlf-easy/output"
+	"io"
+	"os"
+	"time"
+)
+
+func newChannelBlockCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "block",
+		Short: "Inspect the blocks and transactions of a channel",
+		Long: `Inspect the blocks and transactions of a channel through a peer, without the
+peer CLI and configtxlator. The blocks are read with --identity, a member of
+the channel, or with an admin of the org of the peer that hlf-easy issues with
+the local CA of the peer.`,
+	}
+	cmd.AddCommand(
+		newBlockHeightCommand(out),
+		newBlockFetchCommand(out),
+		newBlockDecodeCommand(out),
+		newBlockTransactionsCommand(out),
+	)
+	return cmd
+}
+
+// addLedgerFlags adds the flags selecting the peer and the channel
+func addLedgerFlags(cmd *cobra.Command, opts *explorer.Options) {
+	f := cmd.Flags()
+	f.StringVar(&opts.PeerID, "peer-id", "", "ID of the peer the blocks are read from")
+	f.StringVar(&opts.Channel, "channel", "", "Name of the channel")
+	f.StringVar(&opts.Identity, "identity", "", "Identity file of a member of the channel, an admin identity issued by the local CA of the peer if empty")
+	f.StringVar(&opts.Endpoint, "endpoint", "", "Endpoint of the peer, its external endpoint if empty")
+}
+
+func validateLedgerOptions(opts explorer.Options) error {
+	if opts.PeerID == "" {
+		return errors.New("--peer-id is required")
+	}
+	if opts.Channel == "" {
+		return errors.New("--channel is required")
+	}
+	return nil
+}
+
+type blockHeightCmd struct {
+	opts explorer.Options
+}
+
+func (c *blockHeightCmd) validate() error {
+	return validateLedgerOptions(c.opts)
+}
+
+func (c *blockHeightCmd) run(out io.Writer) error {
+	height, err := explorer.GetHeight(c.opts)
+	if err != nil {
+		return err
+	}
+	result := map[string]interface{}{"channel": c.opts.Channel, "height": height}
+	return output.Print(out, result, func(out io.Writer) error {
+		_, err := fmt.Fprintf(out, "Channel %s has %d blocks on peer %s\n", c.opts.Channel, height, c.opts.PeerID)
+		return err
+	})
+}
+
+func newBlockHeightCommand(out io.Writer) *cobra.Command {
+	c := &blockHeightCmd{}
+	cmd := &cobra.Command{
+		Use:   "height",
+		Short: "Print the number of blocks of a channel on a peer",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	addLedgerFlags(cmd, &c.opts)
+	return cmd
+}
+
+type blockFetchCmd struct {
+	opts       explorer.Options
+	number     int64
+	outputPath string
+}
+
+func (c *blockFetchCmd) validate() error {
+	if c.outputPath == "" {
+		return errors.New("--output is required")
+	}
+	return validateLedgerOptions(c.opts)
+}
+
+func (c *blockFetchCmd) run(out io.Writer) error {
+	block, err := explorer.GetBlock(c.opts, c.number)
+	if err != nil {
+		return err
+	}
+	blockBytes, err := proto.Marshal(block)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(c.outputPath, blockBytes, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Block %d of channel %s written to %s\n", block.Header.Number, c.opts.Channel, c.outputPath)
+	return nil
+}
+
+func newBlockFetchCommand(out io.Writer) *cobra.Command {
+	c := &blockFetchCmd{}
+	cmd := &cobra.Command{
+		Use:   "fetch",
+		Short: "Write a block of a channel in protobuf, like peer channel fetch",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	addLedgerFlags(cmd, &c.opts)
+	f := cmd.Flags()
+	f.Int64Var(&c.number, "number", explorer.Newest, "Number of the block, the last one if -1")
+	f.StringVarP(&c.outputPath, "output", "o", "", "File to write the block to")
+	return cmd
+}
+
+type blockDecodeCmd struct {
+	opts       explorer.Options
+	number     int64
+	file       string
+	outputPath string
+}
+
+func (c *blockDecodeCmd) validate() error {
+	if c.file != "" {
+		return nil
+	}
+	return validateLedgerOptions(c.opts)
+}
+
+// getBlock reads the block from the file, or from the peer without it
+func getBlock(file string, opts explorer.Options, number int64) (*cb.Block, error) {
+	if file == "" {
+		return explorer.GetBlock(opts, number)
+	}
+	blockBytes, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return explorer.UnmarshalBlock(blockBytes)
+}
+
+func (c *blockDecodeCmd) run(out io.Writer) error {
+	block, err := getBlock(c.file, c.opts, c.number)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = explorer.DecodeBlock(&buf, block)
+	if err != nil {
+		return err
+	}
+	if c.outputPath == "" {
+		_, err = out.Write(buf.Bytes())
+		return err
+	}
+	err = os.WriteFile(c.outputPath, buf.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Block %d decoded to %s\n", block.Header.Number, c.outputPath)
+	return nil
+}
+
+func newBlockDecodeCommand(out io.Writer) *cobra.Command {
+	c := &blockDecodeCmd{}
+	cmd := &cobra.Command{
+		Use:   "decode",
+		Short: "Decode a block to JSON, like configtxlator proto_decode",
+		Long: `Decode a block to JSON with its transactions and config decoded, like
+configtxlator proto_decode --type=common.Block. The block is read from --file,
+a block in protobuf, or from the peer.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	addLedgerFlags(cmd, &c.opts)
+	f := cmd.Flags()
+	f.Int64Var(&c.number, "number", explorer.Newest, "Number of the block, the last one if -1")
+	f.StringVar(&c.file, "file", "", "Block in protobuf to decode instead of reading it from the peer")
+	f.StringVarP(&c.outputPath, "output", "o", "", "File to write the JSON to, it's printed if empty")
+	return cmd
+}
+
+type blockTransactionsCmd struct {
+	opts   explorer.Options
+	number int64
+	file   string
+}
+
+func (c *blockTransactionsCmd) validate() error {
+	if c.file != "" {
+		return nil
+	}
+	return validateLedgerOptions(c.opts)
+}
+
+func (c *blockTransactionsCmd) run(out io.Writer) error {
+	block, err := getBlock(c.file, c.opts, c.number)
+	if err != nil {
+		return err
+	}
+	transactions, err := explorer.Transactions(block)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, transactions, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "TX ID\tTYPE\tCHAINCODE\tCREATOR\tTIMESTAMP\tVALIDATION")
+		for _, tx := range transactions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", tx.TxID, tx.Type, tx.Chaincode, tx.Creator, tx.Timestamp.Format(time.RFC3339), tx.ValidationCode)
+		}
+		return w.Flush()
+	})
+}
+
+func newBlockTransactionsCommand(out io.Writer) *cobra.Command {
+	c := &blockTransactionsCmd{}
+	cmd := &cobra.Command{
+		Use:   "transactions",
+		Short: "List the transactions of a block with their validation code",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	addLedgerFlags(cmd, &c.opts)
+	f := cmd.Flags()
+	f.Int64Var(&c.number, "number", explorer.Newest, "Number of the block, the last one if -1")
+	f.StringVar(&c.file, "file", "", "Block in protobuf to read instead of reading it from the peer")
+	return cmd
+}
@@ -0,0 +1,19 @@
+package channel
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewChannelCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use: "channel",
+	}
+	cmd.AddCommand(
+		newChannelCreateCommand(out, errOut),
+		newChannelBlockCommand(out, errOut),
+		newChannelConfigCommand(out, errOut),
+		newChannelUpdateCommand(out, errOut),
+	)
+	return cmd
+}
@@ -0,0 +1,225 @@
+package channel
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/explorer"
+	"hlf-easy/output"
+	"io"
+	"os"
+)
+
+func newChannelConfigCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Fetch, decode and diff the config of a channel",
+		Long: `Fetch, decode and diff the config of a channel through a peer, instead of
+fetching the config block with the peer CLI and decoding it with configtxlator.`,
+	}
+	cmd.AddCommand(
+		newConfigFetchCommand(out),
+		newConfigDecodeCommand(out),
+		newConfigDiffCommand(out),
+	)
+	return cmd
+}
+
+type configFetchCmd struct {
+	opts       explorer.Options
+	outputPath string
+}
+
+func (c *configFetchCmd) validate() error {
+	if c.outputPath == "" {
+		return errors.New("--output is required")
+	}
+	return validateLedgerOptions(c.opts)
+}
+
+func (c *configFetchCmd) run(out io.Writer) error {
+	block, err := explorer.GetConfigBlock(c.opts)
+	if err != nil {
+		return err
+	}
+	blockBytes, err := proto.Marshal(block)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(c.outputPath, blockBytes, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Config block %d of channel %s written to %s\n", block.Header.Number, c.opts.Channel, c.outputPath)
+	return nil
+}
+
+func newConfigFetchCommand(out io.Writer) *cobra.Command {
+	c := &configFetchCmd{}
+	cmd := &cobra.Command{
+		Use:   "fetch",
+		Short: "Write the last config block of a channel in protobuf",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	addLedgerFlags(cmd, &c.opts)
+	cmd.Flags().StringVarP(&c.outputPath, "output", "o", "", "File to write the config block to")
+	return cmd
+}
+
+// getConfigBlock reads the config block from the file, or the block of the
+// number from the peer, or the last config block of the channel without both
+func getConfigBlock(file string, opts explorer.Options, number int64) (*cb.Block, error) {
+	if file != "" || number != explorer.Newest {
+		return getBlock(file, opts, number)
+	}
+	return explorer.GetConfigBlock(opts)
+}
+
+type configDecodeCmd struct {
+	opts       explorer.Options
+	file       string
+	number     int64
+	outputPath string
+}
+
+func (c *configDecodeCmd) validate() error {
+	if c.file != "" {
+		return nil
+	}
+	return validateLedgerOptions(c.opts)
+}
+
+func (c *configDecodeCmd) run(out io.Writer) error {
+	block, err := getConfigBlock(c.file, c.opts, c.number)
+	if err != nil {
+		return err
+	}
+	config, err := explorer.ConfigFromBlock(block)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = explorer.DecodeConfig(&buf, config)
+	if err != nil {
+		return err
+	}
+	if c.outputPath == "" {
+		_, err = out.Write(buf.Bytes())
+		return err
+	}
+	err = os.WriteFile(c.outputPath, buf.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Config of block %d decoded to %s\n", block.Header.Number, c.outputPath)
+	return nil
+}
+
+func newConfigDecodeCommand(out io.Writer) *cobra.Command {
+	c := &configDecodeCmd{}
+	cmd := &cobra.Command{
+		Use:   "decode",
+		Short: "Decode the config of a channel to JSON",
+		Long: `Decode the config of a config block to JSON, the config that's updated with
+configtxlator compute_update. The config block is read from --file, a config
+block in protobuf, or from the peer: the block --number or the last config
+block of the channel.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	addLedgerFlags(cmd, &c.opts)
+	f := cmd.Flags()
+	f.StringVar(&c.file, "file", "", "Config block in protobuf to decode instead of reading it from the peer")
+	f.Int64Var(&c.number, "number", explorer.Newest, "Number of the config block, the last config block if -1")
+	f.StringVarP(&c.outputPath, "output", "o", "", "File to write the JSON to, it's printed if empty")
+	return cmd
+}
+
+type configDiffCmd struct {
+	opts       explorer.Options
+	fromFile   string
+	fromNumber int64
+	toFile     string
+	toNumber   int64
+}
+
+func (c *configDiffCmd) validate() error {
+	if c.fromFile != "" && c.toFile != "" {
+		return nil
+	}
+	return validateLedgerOptions(c.opts)
+}
+
+func (c *configDiffCmd) run(out io.Writer) error {
+	toBlock, err := getConfigBlock(c.toFile, c.opts, c.toNumber)
+	if err != nil {
+		return err
+	}
+	var fromBlock *cb.Block
+	if c.fromFile == "" && c.fromNumber == explorer.Newest {
+		fromBlock, err = explorer.GetPreviousConfigBlock(c.opts, toBlock)
+	} else {
+		fromBlock, err = getBlock(c.fromFile, c.opts, c.fromNumber)
+	}
+	if err != nil {
+		return err
+	}
+	fromConfig, err := explorer.ConfigFromBlock(fromBlock)
+	if err != nil {
+		return err
+	}
+	toConfig, err := explorer.ConfigFromBlock(toBlock)
+	if err != nil {
+		return err
+	}
+	changes, err := explorer.DiffConfigs(fromConfig, toConfig)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, changes, func(out io.Writer) error {
+		fmt.Fprintf(out, "Config block %d (sequence %d) to config block %d (sequence %d)\n", fromBlock.Header.Number, fromConfig.Sequence, toBlock.Header.Number, toConfig.Sequence)
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "PATH\tCHANGE\tFROM\tTO")
+		for _, change := range changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Path, change.Kind, change.From, change.To)
+		}
+		return w.Flush()
+	})
+}
+
+func newConfigDiffCommand(out io.Writer) *cobra.Command {
+	c := &configDiffCmd{}
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show the changes between two config revisions of a channel",
+		Long: `Show the values of the decoded config that were added, removed or changed
+between two config blocks. The config blocks are read from files or from the
+peer by number, by default the last config block of the channel is compared
+with the config block it replaced.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	addLedgerFlags(cmd, &c.opts)
+	f := cmd.Flags()
+	f.StringVar(&c.fromFile, "from-file", "", "Config block in protobuf of the old revision")
+	f.Int64Var(&c.fromNumber, "from-number", explorer.Newest, "Number of the config block of the old revision, the one replaced by the new revision if -1")
+	f.StringVar(&c.toFile, "to-file", "", "Config block in protobuf of the new revision")
+	f.Int64Var(&c.toNumber, "to-number", explorer.Newest, "Number of the config block of the new revision, the last config block if -1")
+	return cmd
+}
@@ -0,0 +1,223 @@
+package channel
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-config/configtx"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/channel"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"hlf-easy/utils"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type createCmd struct {
+	ChannelName  string
+	MSPID        string
+	CAName       string
+	Bundle       string
+	AnchorPeers  []string
+	Output       string
+	Submit       bool
+	AdminTLSCert string
+	AdminTLSKey  string
+	Capabilities channel.Capabilities
+	Timeout      time.Duration
+}
+
+func (c *createCmd) validate() error {
+	if c.ChannelName == "" {
+		return errors.Errorf("--channel is required")
+	}
+	if c.MSPID == "" {
+		return errors.Errorf("--msp-id is required")
+	}
+	if c.CAName == "" {
+		return errors.Errorf("--ca-name is required")
+	}
+	if c.Bundle == "" {
+		return errors.Errorf("--orderer-bundle is required")
+	}
+	if c.Output == "" && !c.Submit {
+		return errors.Errorf("--output or --submit is required")
+	}
+	if c.Submit && (c.AdminTLSCert == "" || c.AdminTLSKey == "") {
+		return errors.Errorf("--admin-tls-cert and --admin-tls-key are required to submit the channel")
+	}
+	if c.Timeout < 0 {
+		return errors.Errorf("--timeout can't be negative")
+	}
+	return c.Capabilities.Validate()
+}
+
+func parseAnchorPeers(anchorPeers []string) ([]configtx.Address, error) {
+	var addresses []configtx.Address
+	for _, anchorPeer := range anchorPeers {
+		host, portString, err := net.SplitHostPort(anchorPeer)
+		if err != nil {
+			return nil, err
+		}
+		if host == "" {
+			return nil, errors.Errorf("host cannot be empty")
+		}
+		port, err := strconv.Atoi(portString)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, configtx.Address{
+			Host: host,
+			Port: port,
+		})
+	}
+	return addresses, nil
+}
+
+func (c *createCmd) run(out io.Writer, errOut io.Writer) error {
+	bundle, err := utils.ReadOrdererBundle(c.Bundle)
+	if err != nil {
+		return err
+	}
+	caConfig, err := utils.GetCAConfig(c.CAName)
+	if err != nil {
+		return err
+	}
+	anchorPeers, err := parseAnchorPeers(c.AnchorPeers)
+	if err != nil {
+		return err
+	}
+	block, err := channel.NewGenesisBlock(channel.CreateOptions{
+		ChannelID: c.ChannelName,
+		Org: channel.OrgOptions{
+			MSPID:       c.MSPID,
+			CACert:      caConfig.CACert,
+			TLSCACert:   caConfig.TLSCACert,
+			AnchorPeers: anchorPeers,
+		},
+		Bundle:       bundle,
+		Capabilities: c.Capabilities,
+	})
+	if err != nil {
+		return err
+	}
+	if c.Output != "" {
+		blockBytes, err := proto.Marshal(block)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(c.Output, blockBytes, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Genesis block of channel %s written to %s\n", c.ChannelName, c.Output)
+	}
+	if !c.Submit {
+		return nil
+	}
+	clientCert, err := tls.LoadX509KeyPair(c.AdminTLSCert, c.AdminTLSKey)
+	if err != nil {
+		return err
+	}
+	tlsCACerts, err := utils.ParsePEMCertificates(bundle.TLSCACerts)
+	if err != nil {
+		return err
+	}
+	rootCAs := x509.NewCertPool()
+	for _, tlsCACert := range tlsCACerts {
+		rootCAs.AddCert(tlsCACert)
+	}
+	tlsConfig := &tls.Config{
+		RootCAs:      rootCAs,
+		Certificates: []tls.Certificate{clientCert},
+	}
+	ctx, stop := proc.TimeoutContext(context.Background(), c.Timeout)
+	defer stop()
+	results := channel.JoinOrderers(ctx, bundle.Orderers, block, tlsConfig)
+	w := output.NewTabWriter(out)
+	fmt.Fprintf(w, "ORDERER\tADMIN URL\tRESULT\tERROR\n")
+	var joined, failed []string
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Orderer, r.AdminURL, r.Result, r.Error)
+		switch r.Result {
+		case channel.JoinResultJoined:
+			joined = append(joined, r.Orderer)
+		case channel.JoinResultFailed:
+			failed = append(failed, r.Orderer)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		// the orderers that joined keep the channel, the failed ones can be
+		// joined again with the same genesis block
+		if err := ctx.Err(); err != nil {
+			return errors.Wrapf(err, "orderers %s didn't join channel %s within --timeout, joined: %s", strings.Join(failed, ", "), c.ChannelName, joinedList(joined))
+		}
+		return errors.Errorf("orderers %s failed to join channel %s, joined: %s", strings.Join(failed, ", "), c.ChannelName, joinedList(joined))
+	}
+	if len(joined) == 0 {
+		return errors.Errorf("no orderer of the bundle has an admin URL to submit the channel to")
+	}
+	return nil
+}
+
+func joinedList(joined []string) string {
+	if len(joined) == 0 {
+		return "none"
+	}
+	return strings.Join(joined, ", ")
+}
+
+func newChannelCreateCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &createCmd{}
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an application channel on an ordering service operated by a third party",
+		Long: `Create an application channel on an ordering service operated by a third party.
+The consenters and TLS CAs of the ordering service are taken from the orderer
+bundle provided by its operator, only the org side of the channel is generated.
+The genesis block can be written to a file for the operator or submitted to the
+channel participation API of the orderers listed in the bundle. Every orderer
+is submitted the block and the result of each one is printed, the failed ones
+can be joined again with the same block. The orderers left when --timeout runs
+out fail.
+
+The ordering service must use etcdraft or BFT, the consensus type of the
+bundle. The channel, orderer and application capabilities are V2_0 unless set
+with --channel-capability, --orderer-capability and --application-capability,
+they must be supported by all the orderers and peers of the channel. BFT
+requires Fabric 3.0 orderers, the channel and orderer capabilities are V3_0
+and every orderer of the bundle needs its signing certificate as identity.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.ChannelName, "channel", "", "Name of the channel to create")
+	f.StringVar(&c.MSPID, "msp-id", "", "MSP ID of the organization creating the channel")
+	f.StringVar(&c.CAName, "ca-name", "", "Name of the CA of the organization")
+	f.StringVar(&c.Bundle, "orderer-bundle", "", "Orderer bundle provided by the ordering service operator")
+	f.StringArrayVar(&c.AnchorPeers, "anchor-peers", []string{}, "Anchor peers of the organization, the format is <host>:<port>")
+	f.StringVarP(&c.Output, "output", "o", "", "Output file for the genesis block")
+	f.BoolVar(&c.Submit, "submit", false, "Submit the genesis block to the admin URLs of the orderers in the bundle")
+	f.StringVar(&c.AdminTLSCert, "admin-tls-cert", "", "TLS client certificate accepted by the channel participation API of the orderers")
+	f.StringVar(&c.AdminTLSKey, "admin-tls-key", "", "TLS client key accepted by the channel participation API of the orderers")
+	f.DurationVar(&c.Timeout, "timeout", channel.DefaultJoinTimeout, "How long the orderers have to join the channel, 0 waits without a limit")
+	f.StringVar(&c.Capabilities.Channel, "channel-capability", "", "Capability of the channel group: V2_0 or V3_0, V2_0 by default and V3_0 with a BFT ordering service")
+	f.StringVar(&c.Capabilities.Orderer, "orderer-capability", "", "Capability of the orderer group: V2_0 or V3_0, V2_0 by default and V3_0 with a BFT ordering service")
+	f.StringVar(&c.Capabilities.Application, "application-capability", channel.DefaultCapabilities.Application, "Capability of the application group: V2_0 or V2_5")
+	return cmd
+}
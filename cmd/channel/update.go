@@ -0,0 +1,363 @@
+package channel
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/channel"
+	"hlf-easy/dashboard"
+	"hlf-easy/explorer"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"hlf-easy/utils"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func newChannelUpdateCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Create a config update of a channel, collect the signatures of its orgs and submit it",
+		Long: `Create a config update of a channel, collect the signatures of the orgs
+required by its modification policies and submit it to an orderer, instead of
+passing the update around with configtxlator and peer channel signconfigtx.
+
+The update is a file signed in turn by each org: with an identity of the org,
+or through the management API of a peer of the org, on this host or on the
+host of the org, which signs it with the admin identity managed for the peer.`,
+	}
+	cmd.AddCommand(
+		newUpdateCreateCommand(out),
+		newUpdateSignCommand(out),
+		newUpdateSubmitCommand(out),
+	)
+	return cmd
+}
+
+// readConfigUpdateTx reads a config update transaction file
+func readConfigUpdateTx(path string) (*cb.Envelope, error) {
+	txBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return channel.ReadConfigUpdateTx(txBytes)
+}
+
+func writeConfigUpdateTx(path string, env *cb.Envelope) error {
+	txBytes, err := proto.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, txBytes, 0644)
+}
+
+func printSigners(out io.Writer, signers []channel.Signer) error {
+	return output.Print(out, signers, func(out io.Writer) error {
+		if len(signers) == 0 {
+			fmt.Fprintln(out, "Not signed yet")
+			return nil
+		}
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "MSP ID\tSIGNER")
+		for _, signer := range signers {
+			fmt.Fprintf(w, "%s\t%s\n", signer.MSPID, signer.CommonName)
+		}
+		return w.Flush()
+	})
+}
+
+// readSigningIdentity reads the identity file of an org
+func readSigningIdentity(identityPath string, mspID string) (channel.Identity, error) {
+	crt, key, err := utils.ReadIdentity(identityPath)
+	if err != nil {
+		return channel.Identity{}, err
+	}
+	return channel.Identity{MSPID: mspID, Cert: crt, Key: key}, nil
+}
+
+type updateCreateCmd struct {
+	opts       explorer.Options
+	file       string
+	modified   string
+	outputPath string
+}
+
+func (c *updateCreateCmd) validate() error {
+	if c.modified == "" {
+		return errors.New("--modified is required")
+	}
+	if c.outputPath == "" {
+		return errors.New("--output is required")
+	}
+	if c.file != "" {
+		if c.opts.Channel == "" {
+			return errors.New("--channel is required")
+		}
+		return nil
+	}
+	return validateLedgerOptions(c.opts)
+}
+
+func (c *updateCreateCmd) run(out io.Writer) error {
+	block, err := getConfigBlock(c.file, c.opts, explorer.Newest)
+	if err != nil {
+		return err
+	}
+	original, err := explorer.ConfigFromBlock(block)
+	if err != nil {
+		return err
+	}
+	modifiedFile, err := os.Open(c.modified)
+	if err != nil {
+		return err
+	}
+	defer modifiedFile.Close()
+	modified, err := explorer.EncodeConfig(modifiedFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", c.modified)
+	}
+	env, err := channel.NewConfigUpdateTx(c.opts.Channel, original, modified)
+	if err != nil {
+		return err
+	}
+	err = writeConfigUpdateTx(c.outputPath, env)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Config update of channel %s written to %s, sign it with hlf-easy channel update sign\n", c.opts.Channel, c.outputPath)
+	return nil
+}
+
+func newUpdateCreateCommand(out io.Writer) *cobra.Command {
+	c := &updateCreateCmd{}
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create the config update from the config of a channel to a modified one",
+		Long: `Create the config update from the config of a channel to a modified one, like
+configtxlator compute_update does. The modified config is the JSON written by
+channel config decode, edited. The config block is read from --file, a config
+block in protobuf, or the last config block of the channel is read from the
+peer.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	addLedgerFlags(cmd, &c.opts)
+	f := cmd.Flags()
+	f.StringVar(&c.file, "file", "", "Config block in protobuf the config was decoded from, instead of reading it from the peer")
+	f.StringVar(&c.modified, "modified", "", "Modified config of the channel in JSON")
+	f.StringVarP(&c.outputPath, "output", "o", "", "File to write the config update to")
+	return cmd
+}
+
+type updateSignCmd struct {
+	identity   string
+	mspID      string
+	target     dashboard.SignTarget
+	token      string
+	outputPath string
+}
+
+func (c *updateSignCmd) validate() error {
+	targets := 0
+	for _, set := range []bool{c.identity != "", c.target.PeerID != "", c.target.Address != ""} {
+		if set {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return errors.New("one of --identity, --peer-id or --remote is required")
+	}
+	if c.identity != "" && c.mspID == "" {
+		return errors.New("--msp-id is required with --identity")
+	}
+	return nil
+}
+
+func (c *updateSignCmd) run(out io.Writer, txPath string) error {
+	env, err := readConfigUpdateTx(txPath)
+	if err != nil {
+		return err
+	}
+	var signers []channel.Signer
+	if c.identity != "" {
+		id, err := readSigningIdentity(c.identity, c.mspID)
+		if err != nil {
+			return err
+		}
+		env, err = channel.SignConfigUpdate(env, id)
+		if err != nil {
+			return err
+		}
+		signers, err = channel.ConfigUpdateSigners(env)
+		if err != nil {
+			return err
+		}
+	} else {
+		txBytes, err := proto.Marshal(env)
+		if err != nil {
+			return err
+		}
+		txBytes, signers, err = dashboard.SignConfigUpdate(c.target, txBytes, c.token)
+		if err != nil {
+			return err
+		}
+		// the transaction signed by the peer is checked before it's written
+		env, err = channel.ReadConfigUpdateTx(txBytes)
+		if err != nil {
+			return errors.Wrapf(err, "%s returned an invalid config update", c.target)
+		}
+	}
+	outputPath := c.outputPath
+	if outputPath == "" {
+		outputPath = txPath
+	}
+	err = writeConfigUpdateTx(outputPath, env)
+	if err != nil {
+		return err
+	}
+	return printSigners(out, signers)
+}
+
+func newUpdateSignCommand(out io.Writer) *cobra.Command {
+	c := &updateSignCmd{}
+	cmd := &cobra.Command{
+		Use:   "sign <file>",
+		Short: "Add the signature of an org to a config update",
+		Long: `Add the signature of an org to a config update, like peer channel signconfigtx
+does, and print the orgs that signed it. It's signed with --identity, or
+through the management API of a peer of the org: --peer-id for a peer of this
+host, --remote for the management API of a peer on another host, which signs it
+with the admin identity managed for the peer. The file is signed in place
+unless --output is set.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, args[0])
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.identity, "identity", "", "Identity file of an admin of the org signing the update")
+	f.StringVar(&c.mspID, "msp-id", "", "MSP ID of the org of --identity")
+	f.StringVar(&c.target.PeerID, "peer-id", "", "Peer of this host whose org signs the update")
+	f.StringVar(&c.target.Address, "remote", "", "Management API of a peer on another host whose org signs the update, the format is <host>:<port>")
+	f.StringVar(&c.target.TLSCACert, "remote-tls-ca", "", "CA certificate of the TLS certificate of --remote, it's reached over plain HTTP if empty")
+	f.StringVar(&c.token, "token", "", "API token of the management API of the peer")
+	f.StringVarP(&c.outputPath, "output", "o", "", "File to write the signed update to, the update is signed in place if empty")
+	return cmd
+}
+
+type updateSubmitCmd struct {
+	identity       string
+	mspID          string
+	ordererAddress string
+	ordererTLSCA   string
+	tlsCert        string
+	tlsKey         string
+	timeout        time.Duration
+}
+
+func (c *updateSubmitCmd) validate() error {
+	if c.identity == "" {
+		return errors.New("--identity is required")
+	}
+	if c.mspID == "" {
+		return errors.New("--msp-id is required")
+	}
+	if c.ordererAddress == "" {
+		return errors.New("--orderer-address is required")
+	}
+	if c.ordererTLSCA == "" {
+		return errors.New("--orderer-tls-ca is required")
+	}
+	if (c.tlsCert == "") != (c.tlsKey == "") {
+		return errors.New("--tls-cert and --tls-key must be set together")
+	}
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	return nil
+}
+
+func (c *updateSubmitCmd) run(out io.Writer, txPath string) error {
+	env, err := readConfigUpdateTx(txPath)
+	if err != nil {
+		return err
+	}
+	signers, err := channel.ConfigUpdateSigners(env)
+	if err != nil {
+		return err
+	}
+	id, err := readSigningIdentity(c.identity, c.mspID)
+	if err != nil {
+		return err
+	}
+	caBytes, err := os.ReadFile(c.ordererTLSCA)
+	if err != nil {
+		return err
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caBytes) {
+		return errors.Errorf("no certificate found in %s", c.ordererTLSCA)
+	}
+	tlsConfig := &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	if c.tlsCert != "" {
+		clientCert, err := tls.LoadX509KeyPair(c.tlsCert, c.tlsKey)
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	err = channel.SubmitConfigUpdate(ctx, c.ordererAddress, tlsConfig, env, id)
+	if err != nil {
+		return err
+	}
+	mspIDs := make([]string, 0, len(signers))
+	for _, signer := range signers {
+		mspIDs = append(mspIDs, signer.MSPID)
+	}
+	fmt.Fprintf(out, "Config update submitted to %s, signed by %s and submitted by %s\n", c.ordererAddress, strings.Join(mspIDs, ", "), c.mspID)
+	return nil
+}
+
+func newUpdateSubmitCommand(out io.Writer) *cobra.Command {
+	c := &updateSubmitCmd{}
+	cmd := &cobra.Command{
+		Use:   "submit <file>",
+		Short: "Submit a signed config update to an orderer",
+		Long: `Submit a config update to an orderer once it's signed by the orgs required by
+the modification policies, like peer channel update does. The transaction is
+signed by --identity, a member of an org of the channel, and the orderer checks
+the signatures of the update against the policies of the channel.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, args[0])
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.identity, "identity", "", "Identity file of the member of an org of the channel submitting the update")
+	f.StringVar(&c.mspID, "msp-id", "", "MSP ID of the org of --identity")
+	f.StringVar(&c.ordererAddress, "orderer-address", "", "Address of the orderer, the format is <host>:<port>")
+	f.StringVar(&c.ordererTLSCA, "orderer-tls-ca", "", "CA certificate of the TLS certificate of the orderer")
+	f.StringVar(&c.tlsCert, "tls-cert", "", "TLS client certificate, when the orderer requires one")
+	f.StringVar(&c.tlsKey, "tls-key", "", "Key of the TLS client certificate")
+	f.DurationVar(&c.timeout, "timeout", channel.DefaultSubmitTimeout, "How long the orderer has to accept the update, 0 waits without a limit")
+	return cmd
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"hlf-easy/bulk"
+	"hlf-easy/chaincode"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"hlf-easy/utils"
+	"strings"
+)
+
+// completeNodeIDs completes the IDs of the nodes of a kind of the host
+func completeNodeIDs(kind string) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		ids, err := bulk.ListNodeIDs(kind)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return ids, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeTaskNodeIDs completes the IDs of the nodes of the --kind of the
+// tasks commands
+func completeTaskNodeIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	kind, _ := cmd.Flags().GetString("kind")
+	return completeNodeIDs(kind)(cmd, args, toComplete)
+}
+
+// completeHostNodeIDs completes the IDs of the peers and orderers of the host
+func completeHostNodeIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	ids := []string{}
+	for _, kind := range []string{"peer", "orderer"} {
+		kindIDs, err := bulk.ListNodeIDs(kind)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		ids = append(ids, kindIDs...)
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeChaincodes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	definitions, err := chaincode.List()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := []string{}
+	for _, d := range definitions {
+		names = append(names, d.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeExternalBuilders(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	builders, err := chaincode.ListExternalBuilders()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := []string{}
+	for _, b := range builders {
+		names = append(names, b.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeTaskNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	kind, _ := cmd.Flags().GetString("kind")
+	id, _ := cmd.Flags().GetString("id")
+	nodeTasks, err := utils.GetNodeTasks(kind, id)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := []string{}
+	for _, task := range nodeTasks.Tasks {
+		names = append(names, task.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeValues(values ...string) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp)
+}
+
+// flagCompletions are the completions of the flags by the top level command
+// and the name of the flag, the top level command tells what the flag refers to
+var flagCompletions = map[string]map[string]func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective){
+	"peer": {
+		"id":               completeNodeIDs("peer"),
+		"ca-name":          completeNodeIDs("ca"),
+		"metrics-provider": completeValues(node.MetricsPrometheus, node.MetricsStatsd, node.MetricsDisabled),
+	},
+	"orderer": {
+		"id":      completeNodeIDs("orderer"),
+		"ca-name": completeNodeIDs("ca"),
+	},
+	"ca": {
+		"name": completeNodeIDs("ca"),
+	},
+	"chaincode": {
+		"name":      completeChaincodes,
+		"type":      completeValues(chaincode.TypeCCaaS, chaincode.TypeDocker),
+		"peer-id":   completeNodeIDs("peer"),
+		"chaincode": completeChaincodes,
+		"builder":   completeExternalBuilders,
+	},
+	"channel": {
+		"peer-id": completeNodeIDs("peer"),
+	},
+	"gateway": {
+		"peer-id": completeNodeIDs("peer"),
+	},
+	"doctor": {
+		"node": completeHostNodeIDs,
+	},
+	"listen": {
+		"peer-id":   completeNodeIDs("peer"),
+		"chaincode": completeChaincodes,
+	},
+	"discover": {
+		"peer-id":   completeNodeIDs("peer"),
+		"chaincode": completeChaincodes,
+	},
+	"tasks": {
+		"kind": completeValues("peer", "orderer"),
+		"id":   completeTaskNodeIDs,
+		"name": completeTaskNames,
+	},
+}
+
+// newValueFlags are the flags whose value is a new ID or name, they're not
+// completed with the existing ones
+var newValueFlags = map[string]bool{
+	"peer init --id":                                true,
+	"peer import --id":                              true,
+	"orderer init --id":                             true,
+	"ca init --name":                                true,
+	"chaincode register --name":                     true,
+	"chaincode external-builder scaffold --builder": true,
+	"tasks add --name":                              true,
+}
+
+// registerCompletions registers the completions of the values of the flags
+// and of the IDs of the nodes passed as arguments
+func registerCompletions(root *cobra.Command) {
+	root.RegisterFlagCompletionFunc("output", completeValues(output.Table, output.JSON, output.YAML))
+	for _, top := range root.Commands() {
+		completions, ok := flagCompletions[top.Name()]
+		if !ok {
+			continue
+		}
+		var walk func(cmd *cobra.Command)
+		walk = func(cmd *cobra.Command) {
+			for name, complete := range completions {
+				if cmd.LocalNonPersistentFlags().Lookup(name) == nil {
+					continue
+				}
+				if newValueFlags[strings.TrimPrefix(cmd.CommandPath(), root.Name()+" ")+" --"+name] {
+					continue
+				}
+				cmd.RegisterFlagCompletionFunc(name, complete)
+			}
+			if cmd.ValidArgsFunction == nil && cmd.Args != nil && cmd.Use == cmd.Name()+" <id>" {
+				cmd.ValidArgsFunction = completions["id"]
+			}
+			for _, child := range cmd.Commands() {
+				walk(child)
+			}
+		}
+		walk(top)
+	}
+}
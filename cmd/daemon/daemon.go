@@ -0,0 +1,340 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"hlf-easy/auth"
+	"hlf-easy/config"
+	"hlf-easy/daemon"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"hlf-easy/rpc"
+	"hlf-easy/utils"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+func NewDaemonCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Supervise the nodes of the host with a background process controlled on a Unix socket",
+	}
+	cmd.AddCommand(
+		newRunCommand(out, errOut),
+		newStartCommand(out, errOut),
+		newStopCommand(out, errOut),
+		newStatusCommand(out, errOut),
+		newAddCommand(out, errOut),
+		newRemoveCommand(out, errOut),
+		newNodeCommand(out, errOut),
+	)
+	return cmd
+}
+
+// socketFlag selects the socket of the daemon
+type socketFlag struct {
+	socket string
+}
+
+func (s *socketFlag) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&s.socket, "socket", "", "Unix socket of the daemon, daemon/daemon.sock in $HOME/hlf-easy by default")
+}
+
+func (s *socketFlag) validate() error {
+	if s.socket != "" {
+		return nil
+	}
+	socket, err := daemon.DefaultSocket()
+	if err != nil {
+		return err
+	}
+	s.socket = socket
+	return nil
+}
+
+func (s *socketFlag) client() *daemon.Client {
+	return daemon.NewClient(s.socket)
+}
+
+type runCmd struct {
+	socketFlag
+	listenAddress string
+	grpcAddress   string
+	authOpts      config.APIAuthOptions
+}
+
+func (c *runCmd) validate() error {
+	if err := c.socketFlag.validate(); err != nil {
+		return err
+	}
+	if c.listenAddress == "" && c.grpcAddress == "" {
+		return nil
+	}
+	return auth.ValidateOptions(c.authOpts)
+}
+
+// args returns the flags of the run command started in the background
+func (c *runCmd) args() []string {
+	args := []string{"daemon", "run", "--socket", c.socket}
+	if c.listenAddress == "" && c.grpcAddress == "" {
+		return args
+	}
+	args = append(args, "--api-auth", c.authOpts.Mode, "--api-operator-ou", c.authOpts.OperatorOU)
+	for _, flag := range [][2]string{
+		{"--listen-address", c.listenAddress},
+		{"--grpc-address", c.grpcAddress},
+		{"--api-tls-cert", c.authOpts.TLSCert},
+		{"--api-tls-key", c.authOpts.TLSKey},
+		{"--api-client-ca", c.authOpts.ClientCA},
+		{"--api-admin-ou", c.authOpts.AdminOU},
+	} {
+		if flag[1] != "" {
+			args = append(args, flag[0], flag[1])
+		}
+	}
+	return args
+}
+
+func (c *runCmd) run(out io.Writer, errOut io.Writer) error {
+	if c.client().Ping() {
+		return errors.Errorf("the daemon is already running on %s", c.socket)
+	}
+	d, err := daemon.New()
+	if err != nil {
+		return err
+	}
+	c.authOpts.Socket = c.socket
+	g, err := daemon.NewRouter(d, c.authOpts)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:    c.listenAddress,
+		Handler: g,
+	}
+	grpcSrv, listeners, err := c.grpcServer(d)
+	if err != nil {
+		return err
+	}
+	// the daemon and its nodes keep running when the terminal is closed
+	signal.Ignore(syscall.SIGHUP)
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	errs := make(chan error, 1+len(listeners))
+	go func() {
+		errs <- auth.ListenAndServe(srv, c.authOpts)
+	}()
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			errs <- grpcSrv.Serve(l)
+		}(l)
+	}
+	d.Start()
+	fmt.Fprintf(out, "Daemon listening on %s, its gRPC API on %s\n", c.socket, listeners[0].Addr())
+	select {
+	case <-ctx.Done():
+	case <-d.Done():
+	case err = <-errs:
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+	}
+	stop()
+	if shutdownErr := d.Shutdown(); shutdownErr != nil {
+		log.Warnf("Failed to stop the nodes: %v", shutdownErr)
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(shutdownCtx)
+	grpcSrv.Stop()
+	return err
+}
+
+// grpcServer returns the gRPC API of the daemon with its listeners, the Unix
+// socket next to the one of the control API and the gRPC address when it's
+// set, with TLS when the API has a certificate
+func (c *runCmd) grpcServer(d *daemon.Daemon) (*grpc.Server, []net.Listener, error) {
+	opts := []grpc.ServerOption{}
+	if c.grpcAddress != "" && c.authOpts.TLSCert != "" {
+		creds, err := auth.GRPCCredentials(c.authOpts)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	grpcSrv, err := rpc.NewGRPCServer(rpc.NewServer(d), c.authOpts, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	socket := filepath.Join(filepath.Dir(c.socket), "grpc.sock")
+	l, err := auth.ListenSocket(socket)
+	if err != nil {
+		return nil, nil, err
+	}
+	listeners := []net.Listener{l}
+	if c.grpcAddress != "" {
+		l, err = net.Listen("tcp", c.grpcAddress)
+		if err != nil {
+			listeners[0].Close()
+			return nil, nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return grpcSrv, listeners, nil
+}
+
+func newRunCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &runCmd{}
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run the daemon in the foreground, the nodes added to it are started and restarted when they crash",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	c.addFlags(f)
+	f.StringVar(&c.listenAddress, "listen-address", "", "TCP address the control API is also served on, e.g. 127.0.0.1:7070")
+	f.StringVar(&c.grpcAddress, "grpc-address", "", "TCP address the gRPC API is also served on, e.g. 127.0.0.1:7071")
+	c.authOpts.AddFlags(f)
+	return cmd
+}
+
+type startCmd struct {
+	runCmd
+	timeout time.Duration
+}
+
+func (c *startCmd) run(out io.Writer, errOut io.Writer) error {
+	client := c.client()
+	if client.Ping() {
+		fmt.Fprintf(out, "The daemon is already running on %s\n", c.socket)
+		return nil
+	}
+	dir, err := daemon.GetDir()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	logPath := filepath.Join(dir, "daemon.log")
+	pid, err := utils.Spawn(c.args(), logPath)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(c.timeout)
+	for !client.Ping() {
+		if !proc.Running(pid) || time.Now().After(deadline) {
+			return errors.Errorf("the daemon didn't start, see %s", logPath)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	fmt.Fprintf(out, "Daemon started with PID %d on %s, its log is %s\n", pid, c.socket, logPath)
+	return nil
+}
+
+func newStartCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &startCmd{}
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start the daemon in the background, it keeps running when the terminal is closed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	c.addFlags(f)
+	f.StringVar(&c.listenAddress, "listen-address", "", "TCP address the control API is also served on, e.g. 127.0.0.1:7070")
+	f.StringVar(&c.grpcAddress, "grpc-address", "", "TCP address the gRPC API is also served on, e.g. 127.0.0.1:7071")
+	c.authOpts.AddFlags(f)
+	f.DurationVar(&c.timeout, "timeout", 10*time.Second, "How long to wait for the daemon to answer on its socket")
+	return cmd
+}
+
+type stopCmd struct {
+	socketFlag
+}
+
+func (c *stopCmd) run(out io.Writer, errOut io.Writer) error {
+	err := c.client().Shutdown()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Daemon stopped with its nodes")
+	return nil
+}
+
+func newStopCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &stopCmd{}
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the nodes supervised by the daemon and the daemon",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	c.addFlags(cmd.Flags())
+	return cmd
+}
+
+type statusCmd struct {
+	socketFlag
+}
+
+func (c *statusCmd) run(out io.Writer, errOut io.Writer) error {
+	nodes, err := c.client().Nodes()
+	if err != nil {
+		return err
+	}
+	return output.Print(out, nodes, func(w io.Writer) error {
+		tw := output.NewTabWriter(w)
+		fmt.Fprintln(tw, "KIND\tID\tSTATE\tPID\tUPTIME\tRESTARTS")
+		for _, n := range nodes {
+			pid, uptime := "-", "-"
+			if n.Process != nil && n.Process.PID != 0 {
+				pid = fmt.Sprint(n.Process.PID)
+				uptime = (time.Duration(n.Process.Uptime) * time.Second).String()
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\n", n.Kind, n.ID, n.State, pid, uptime, n.Restarts)
+		}
+		return tw.Flush()
+	})
+}
+
+func newStatusCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &statusCmd{}
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the nodes supervised by the daemon with the state of their hlf-easy process",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	c.addFlags(cmd.Flags())
+	return cmd
+}
@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"hlf-easy/daemon"
+	"io"
+)
+
+// nodeFlags select a node supervised by the daemon
+type nodeFlags struct {
+	socketFlag
+	kind string
+	id   string
+}
+
+func (n *nodeFlags) addFlags(f *pflag.FlagSet) {
+	n.socketFlag.addFlags(f)
+	f.StringVar(&n.kind, "kind", "peer", "Kind of the node: peer or orderer")
+	f.StringVar(&n.id, "id", "", "ID of the node")
+}
+
+func (n *nodeFlags) validate() error {
+	if n.kind != "peer" && n.kind != "orderer" {
+		return errors.Errorf("invalid kind %s, expected peer or orderer", n.kind)
+	}
+	if n.id == "" {
+		return errors.New("--id is required")
+	}
+	return n.socketFlag.validate()
+}
+
+type addCmd struct {
+	nodeFlags
+	args []string
+}
+
+func (c *addCmd) validate() error {
+	if err := c.nodeFlags.validate(); err != nil {
+		return err
+	}
+	return c.spec().Validate()
+}
+
+func (c *addCmd) spec() daemon.NodeSpec {
+	return daemon.NodeSpec{Kind: c.kind, ID: c.id, Args: c.args}
+}
+
+func (c *addCmd) run(out io.Writer, errOut io.Writer) error {
+	err := c.client().Add(c.spec())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s %s added to the daemon and started, its output is appended to hlf-easy.log in its directory\n", c.kind, c.id)
+	return nil
+}
+
+func newAddCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &addCmd{}
+	cmd := &cobra.Command{
+		Use:   "add [-- start flags]",
+		Short: "Start a node with the daemon, it's restarted when it crashes and when the daemon starts",
+		Example: `  hlf-easy daemon add --kind peer --id peer0 -- --msp-id Org1MSP --external-endpoint peer0.org1.example.com:7051
+  hlf-easy daemon add --kind orderer --id orderer0 -- --msp-id OrdererMSP`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the flags after -- are passed to the start command of the node
+			c.args = args
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	c.addFlags(cmd.Flags())
+	return cmd
+}
+
+type removeCmd struct {
+	nodeFlags
+}
+
+func (c *removeCmd) run(out io.Writer, errOut io.Writer) error {
+	err := c.client().Remove(c.kind, c.id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s %s stopped and removed from the daemon\n", c.kind, c.id)
+	return nil
+}
+
+func newRemoveCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &removeCmd{}
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Stop a node and remove it from the daemon",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	c.addFlags(cmd.Flags())
+	return cmd
+}
+
+// nodeAction is an action of the daemon on the process of a node
+type nodeAction struct {
+	name  string
+	short string
+	// done is printed once the action succeeded
+	done string
+}
+
+var nodeActions = []nodeAction{
+	{name: "start", short: "Start the hlf-easy process of a node supervised by the daemon", done: "started"},
+	{name: "stop", short: "Stop the hlf-easy process of a node, the daemon doesn't restart it until it's started", done: "stopped"},
+	{name: "restart", short: "Restart the hlf-easy process of a node supervised by the daemon", done: "restarted"},
+}
+
+func newNodeCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "node",
+		Short: "Start, stop or restart a node supervised by the daemon",
+	}
+	for _, action := range nodeActions {
+		cmd.AddCommand(newNodeActionCommand(out, errOut, action))
+	}
+	return cmd
+}
+
+type nodeActionCmd struct {
+	nodeFlags
+	action nodeAction
+}
+
+func (c *nodeActionCmd) run(out io.Writer, errOut io.Writer) error {
+	err := c.client().Action(c.kind, c.id, c.action.name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s %s %s\n", c.kind, c.id, c.action.done)
+	return nil
+}
+
+func newNodeActionCommand(out io.Writer, errOut io.Writer, action nodeAction) *cobra.Command {
+	c := &nodeActionCmd{action: action}
+	cmd := &cobra.Command{
+		Use:   action.name,
+		Short: action.short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	c.addFlags(cmd.Flags())
+	return cmd
+}
@@ -0,0 +1,78 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/auth"
+	"hlf-easy/config"
+	"hlf-easy/dashboard"
+	"hlf-easy/proc"
+	"io"
+	"net/http"
+	"time"
+)
+
+type dashboardCmd struct {
+	address  string
+	authOpts config.APIAuthOptions
+}
+
+func (c *dashboardCmd) validate() error {
+	if c.address == "" {
+		return errors.New("--address is required")
+	}
+	return auth.ValidateOptions(c.authOpts)
+}
+
+func (c *dashboardCmd) run(out io.Writer, errOut io.Writer) error {
+	g, err := dashboard.NewRouter(c.authOpts)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:    c.address,
+		Handler: g,
+	}
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	errs := make(chan error, 1)
+	go func() {
+		if err := auth.ListenAndServe(srv, c.authOpts); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+	scheme := "http"
+	if c.authOpts.TLSCert != "" {
+		scheme = "https"
+	}
+	fmt.Fprintf(out, "Dashboard listening on %s://%s\n", scheme, c.address)
+	select {
+	case <-ctx.Done():
+	case err := <-errs:
+		return errors.Wrap(err, "failed to serve the dashboard")
+	}
+	stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+func NewDashboardCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &dashboardCmd{}
+	cmd := &cobra.Command{
+		Use:   "dashboard",
+		Short: "Serve a web dashboard with the nodes, channels and chaincodes of the host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.address, "address", "127.0.0.1:8080", "Listen address of the dashboard")
+	c.authOpts.AddFlags(f)
+	return cmd
+}
@@ -0,0 +1,53 @@
+package discover
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"hlf-easy/discover"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"strings"
+)
+
+type discoverConfigCmd struct {
+	discoverCmd
+}
+
+func (c *discoverConfigCmd) run(out io.Writer) error {
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	config, err := discover.GetConfig(ctx, c.opts)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, config, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "MSP ID\tORDERERS")
+		for _, mspID := range config.MSPIDs {
+			orderers := strings.Join(config.Orderers[mspID], ",")
+			if orderers == "" {
+				orderers = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", mspID, orderers)
+		}
+		return w.Flush()
+	})
+}
+
+func newDiscoverConfigCommand(out io.Writer) *cobra.Command {
+	c := &discoverConfigCmd{}
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Print the orgs and the orderers of a channel served by the discovery service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	addDiscoverFlags(cmd, &c.discoverCmd)
+	return cmd
+}
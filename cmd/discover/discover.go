@@ -0,0 +1,70 @@
+package discover
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/discover"
+	"io"
+	"time"
+)
+
+func NewDiscoverCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "discover",
+		Short: "Query the discovery service of a channel like the SDKs do",
+		Long: `Query the discovery service of a channel through a peer, as the SDKs and the
+gateway do before endorsing, to verify which peers are known through gossip,
+the endorsers of a chaincode and the config of the channel. The queries are
+signed with --identity, a member of the channel, or with an admin of the org
+of the peer that hlf-easy issues with the local CA of the peer.`,
+	}
+	cmd.AddCommand(
+		newDiscoverPeersCommand(out, errOut),
+		newDiscoverConfigCommand(out),
+		newDiscoverEndorsersCommand(out),
+	)
+	return cmd
+}
+
+// discoverCmd is the peer, the channel and the timeout of a query
+type discoverCmd struct {
+	opts    discover.Options
+	timeout time.Duration
+}
+
+// addDiscoverFlags adds the flags selecting the peer and the channel
+func addDiscoverFlags(cmd *cobra.Command, c *discoverCmd) {
+	f := cmd.Flags()
+	f.StringVar(&c.opts.PeerID, "peer-id", "", "ID of the peer whose discovery service is queried")
+	f.StringVar(&c.opts.Channel, "channel", "", "Name of the channel")
+	f.StringVar(&c.opts.Identity, "identity", "", "Identity file of a member of the channel, an admin identity issued by the local CA of the peer if empty")
+	f.StringVar(&c.opts.Endpoint, "endpoint", "", "Endpoint of the peer, its external endpoint if empty")
+	f.DurationVar(&c.timeout, "timeout", discover.DefaultTimeout, "How long the discovery service has to answer, 0 waits without a limit")
+}
+
+// addInterestFlags adds the flags selecting the chaincode and its collections
+func addInterestFlags(cmd *cobra.Command, interest *discover.Interest, usage string) {
+	f := cmd.Flags()
+	f.StringVar(&interest.Chaincode, "chaincode", "", usage)
+	f.StringSliceVar(&interest.Collections, "collection", nil, "Private data collections of the chaincode the transactions read or write")
+}
+
+func (c *discoverCmd) validate() error {
+	if c.opts.PeerID == "" {
+		return errors.New("--peer-id is required")
+	}
+	if c.opts.Channel == "" {
+		return errors.New("--channel is required")
+	}
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	return nil
+}
+
+func validateInterest(interest discover.Interest) error {
+	if interest.Chaincode == "" && len(interest.Collections) > 0 {
+		return errors.New("--collection is only used with --chaincode")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package discover
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/discover"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+)
+
+type discoverEndorsersCmd struct {
+	discoverCmd
+	interest discover.Interest
+}
+
+func (c *discoverEndorsersCmd) validate() error {
+	if err := c.discoverCmd.validate(); err != nil {
+		return err
+	}
+	if c.interest.Chaincode == "" {
+		return errors.New("--chaincode is required")
+	}
+	return nil
+}
+
+func (c *discoverEndorsersCmd) run(out io.Writer) error {
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	endorsers, err := discover.GetEndorsers(ctx, c.opts, c.interest)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, endorsers, func(out io.Writer) error {
+		return printPeers(out, endorsers)
+	})
+}
+
+func newDiscoverEndorsersCommand(out io.Writer) *cobra.Command {
+	c := &discoverEndorsersCmd{}
+	cmd := &cobra.Command{
+		Use:   "endorsers",
+		Short: "Print peers whose endorsements satisfy the endorsement policy of a chaincode",
+		Long: `Print a set of peers whose endorsements satisfy the endorsement policy of a
+chaincode, and of the collections of --collection, as the SDKs select them.
+The command fails when the peers known to the discovery service can't
+satisfy the policy: the chaincode isn't installed on enough orgs, or the
+peers of an org aren't known through its anchor peers.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	addDiscoverFlags(cmd, &c.discoverCmd)
+	addInterestFlags(cmd, &c.interest, "Chaincode whose endorsers are selected")
+	return cmd
+}
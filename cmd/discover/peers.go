@@ -0,0 +1,77 @@
+package discover
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"hlf-easy/discover"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"strings"
+)
+
+// printPeers prints the peers in a table, the chaincodes installed on them
+// with their version
+func printPeers(out io.Writer, peers []discover.Peer) error {
+	w := output.NewTabWriter(out)
+	fmt.Fprintln(w, "MSP ID\tENDPOINT\tHEIGHT\tCHAINCODES")
+	for _, p := range peers {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", p.MSPID, p.Endpoint, p.LedgerHeight, strings.Join(p.Chaincodes, ","))
+	}
+	return w.Flush()
+}
+
+type discoverPeersCmd struct {
+	discoverCmd
+	interest discover.Interest
+}
+
+func (c *discoverPeersCmd) validate() error {
+	if err := c.discoverCmd.validate(); err != nil {
+		return err
+	}
+	return validateInterest(c.interest)
+}
+
+func (c *discoverPeersCmd) run(out io.Writer, errOut io.Writer) error {
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	peers, err := discover.GetPeers(ctx, c.opts, c.interest)
+	if err != nil {
+		return err
+	}
+	err = output.Print(out, peers, func(out io.Writer) error {
+		return printPeers(out, peers.Peers)
+	})
+	if err != nil {
+		return err
+	}
+	for _, mspID := range peers.MissingOrgs {
+		fmt.Fprintf(errOut, "No peer of org %s is known to peer %s, check the anchor peers of %s are set and reachable\n", mspID, c.opts.PeerID, mspID)
+	}
+	return nil
+}
+
+func newDiscoverPeersCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &discoverPeersCmd{}
+	cmd := &cobra.Command{
+		Use:   "peers",
+		Short: "List the peers of a channel known to the discovery service",
+		Long: `List the peers of a channel known to the discovery service of a peer, with
+their ledger height and the chaincodes installed on them. The peers of the
+other orgs are only known through their anchor peers, the orgs of the channel
+without a known peer are reported.
+
+With --chaincode only the peers the chaincode is installed on are listed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	addDiscoverFlags(cmd, &c.discoverCmd)
+	addInterestFlags(cmd, &c.interest, "Chaincode the listed peers have installed")
+	return cmd
+}
@@ -0,0 +1,37 @@
+package doctor
+
+import (
+	"github.com/spf13/cobra"
+	"hlf-easy/doctor"
+	"io"
+	"time"
+)
+
+func NewDoctorCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &envCmd{}
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the environment of the host and the connectivity of its nodes",
+		Long: `Check the host runs the nodes: the peer and orderer binaries and their
+versions, the binaries of the fabric versions the peers are pinned to, docker
+for the docker chaincodes, the open files limit, the clock against an NTP
+server, as the nodes reject the requests signed outside their 15m
+authentication time window, the free space of the data directory and the
+validity of the certificates of the CAs and nodes.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.opts.NTPServer, "ntp-server", doctor.DefaultNTPServer, "NTP server the clock is compared to, empty to skip the check offline")
+	f.IntVar(&c.opts.ExpiryWarningDays, "expiry-warning-days", 30, "Flag the certificates that expire within these days")
+	f.DurationVar(&c.opts.Timeout, "timeout", 5*time.Second, "Time the NTP server and the docker daemon have to answer")
+	cmd.AddCommand(
+		newDoctorNetworkCommand(out, errOut),
+	)
+	return cmd
+}
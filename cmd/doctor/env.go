@@ -0,0 +1,61 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/doctor"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+)
+
+type envCmd struct {
+	opts doctor.EnvOptions
+}
+
+// envReport is the result of the checks of the environment of the host
+type envReport struct {
+	Healthy bool         `json:"healthy"`
+	Checks  []node.Check `json:"checks"`
+}
+
+func (c *envCmd) validate() error {
+	if c.opts.Timeout <= 0 {
+		return errors.New("--timeout must be positive")
+	}
+	if c.opts.ExpiryWarningDays < 0 {
+		return errors.New("--expiry-warning-days can't be negative")
+	}
+	return nil
+}
+
+func (c *envCmd) run(out io.Writer) error {
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	checks := doctor.CheckEnv(ctx, c.opts)
+	result := envReport{Healthy: true, Checks: checks}
+	failed := 0
+	for _, check := range checks {
+		if check.Status == node.CheckFail {
+			result.Healthy = false
+			failed++
+		}
+	}
+	err := output.Print(out, result, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "STATUS\tCHECK\tMESSAGE")
+		for _, check := range result.Checks {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", check.Status, check.Name, check.Message)
+		}
+		return w.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Healthy {
+		return errors.Errorf("the host failed %d checks, fix them before starting the nodes", failed)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/channel"
+	"hlf-easy/doctor"
+	"hlf-easy/errdefs"
+	"hlf-easy/fabric"
+	"hlf-easy/node"
+	"hlf-easy/ordering"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type networkCmd struct {
+	nodeID  string
+	timeout time.Duration
+}
+
+// networkReport is the result of the checks of the links of the nodes
+type networkReport struct {
+	Healthy bool            `json:"healthy"`
+	Links   []doctor.Result `json:"links"`
+}
+
+func (c *networkCmd) validate() error {
+	if c.timeout <= 0 {
+		return errors.New("--timeout must be positive")
+	}
+	return nil
+}
+
+// peerLinks returns the links a peer of the host opens: to the peers of its
+// gossip bootstrap, and to the orderers and the anchor peers of its channels
+func peerLinks(peerDir string) ([]doctor.Link, error) {
+	id := filepath.Base(peerDir)
+	// an imported peer has no init.json and no gossip bootstrap
+	externalEndpoint, bootstrap, err := node.GetPeerEndpoints(peerDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	tlsCACert, err := os.ReadFile(filepath.Join(peerDir, "tlscacerts/cacert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	links := []doctor.Link{}
+	for _, address := range bootstrap {
+		links = append(links, doctor.Link{From: id, To: "gossip bootstrap", Address: address, TLSCACerts: []string{string(tlsCACert)}})
+	}
+	configs, err := fabric.ChannelConfigs(fabric.GetChainsDir(peerDir))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		endpoints, err := channel.OrdererEndpoints(configs[name])
+		if err != nil {
+			log.Warnf("The orderers of channel %s of peer %s aren't checked: %v", name, id, err)
+		}
+		for _, endpoint := range endpoints {
+			links = append(links, doctor.Link{
+				From:       id,
+				To:         "orderer",
+				Channels:   []string{name},
+				Address:    ordering.EndpointAddress(endpoint.Address),
+				TLSCACerts: endpoint.TLSCACerts,
+			})
+		}
+		anchorPeers, err := channel.AnchorPeers(configs[name])
+		if err != nil {
+			log.Warnf("The anchor peers of channel %s of peer %s aren't checked: %v", name, id, err)
+		}
+		for _, anchorPeer := range anchorPeers {
+			if anchorPeer.Address == externalEndpoint {
+				continue
+			}
+			links = append(links, doctor.Link{
+				From:       id,
+				To:         "anchor peer " + anchorPeer.MSPID,
+				Channels:   []string{name},
+				Address:    anchorPeer.Address,
+				TLSCACerts: anchorPeer.TLSCACerts,
+			})
+		}
+	}
+	return links, nil
+}
+
+// ordererLinks returns the links an orderer of the host opens to the other
+// consenters of its channels
+func ordererLinks(ordererDir string) ([]doctor.Link, error) {
+	id := filepath.Base(ordererDir)
+	tlsCert, err := os.ReadFile(filepath.Join(ordererDir, "tls.crt"))
+	if err != nil {
+		return nil, err
+	}
+	configs, err := fabric.ChannelConfigs(fabric.GetOrdererChainsDir(ordererDir))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	links := []doctor.Link{}
+	for _, name := range names {
+		consenters, err := channel.Consenters(configs[name])
+		if err != nil {
+			log.Warnf("The consenters of channel %s of orderer %s aren't checked: %v", name, id, err)
+			continue
+		}
+		// the consenters are verified with the TLS roots of the orderer orgs
+		tlsCACerts := []string{}
+		endpoints, _ := channel.OrdererEndpoints(configs[name])
+		for _, endpoint := range endpoints {
+			tlsCACerts = append(tlsCACerts, endpoint.TLSCACerts...)
+		}
+		for _, consenter := range consenters {
+			if bytes.Equal(bytes.TrimSpace(consenter.ServerTLSCert), bytes.TrimSpace(tlsCert)) {
+				continue
+			}
+			links = append(links, doctor.Link{
+				From:       id,
+				To:         "consenter",
+				Channels:   []string{name},
+				Address:    consenter.Address,
+				TLSCACerts: tlsCACerts,
+			})
+		}
+	}
+	return links, nil
+}
+
+// networkLinks returns the links of the nodes of the host, or of one node
+func networkLinks(nodeID string) ([]doctor.Link, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	links := []doctor.Link{}
+	found := false
+	for _, kind := range []string{node.KindPeer, node.KindOrderer} {
+		nodeDirs, err := filepath.Glob(filepath.Join(home, fmt.Sprintf("hlf-easy/%ss/*", kind)))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(nodeDirs)
+		for _, nodeDir := range nodeDirs {
+			if info, err := os.Stat(nodeDir); err != nil || !info.IsDir() {
+				continue
+			}
+			if nodeID != "" && filepath.Base(nodeDir) != nodeID {
+				continue
+			}
+			found = true
+			var nodeLinks []doctor.Link
+			if kind == node.KindPeer {
+				nodeLinks, err = peerLinks(nodeDir)
+			} else {
+				nodeLinks, err = ordererLinks(nodeDir)
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read the links of %s %s", kind, filepath.Base(nodeDir))
+			}
+			links = append(links, nodeLinks...)
+		}
+	}
+	if nodeID != "" && !found {
+		return nil, errdefs.Errorf(errdefs.ErrNodeNotFound, "node %s does not exist", nodeID)
+	}
+	return doctor.Merge(links), nil
+}
+
+func (c *networkCmd) run(out io.Writer) error {
+	links, err := networkLinks(c.nodeID)
+	if err != nil {
+		return err
+	}
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	results := doctor.CheckLinks(ctx, links, c.timeout)
+	broken := doctor.Broken(results)
+	report := networkReport{Healthy: len(broken) == 0, Links: results}
+	err = output.Print(out, report, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "STATUS\tFROM\tTO\tADDRESS\tCHANNELS\tDETAIL")
+		for _, r := range results {
+			detail := r.Hint
+			if r.Status == doctor.StatusOK {
+				detail = r.RTT.Round(time.Millisecond).String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Status, r.From, r.To, r.Address, strings.Join(r.Channels, ","), detail)
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(w, "no link is configured on the nodes of the host")
+		}
+		return w.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	if len(broken) > 0 {
+		return errors.Errorf("%d of %d links are broken: %s", len(broken), len(results), doctor.Describe(broken))
+	}
+	return nil
+}
+
+func newDoctorNetworkCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &networkCmd{}
+	cmd := &cobra.Command{
+		Use:   "network",
+		Short: "Check the nodes of the host reach the peers and orderers they connect to",
+		Long: `Check the links the nodes of the host open before debugging gossip or block
+delivery: a peer connects to its gossip bootstrap, and to the orderers and the
+anchor peers of its channels, an orderer to the other consenters of its
+channels. The endpoints are read from init.json and from the last config
+block in the ledger of the node, so the nodes don't need to run.
+
+Each link is dialed and completes a TLS handshake verified with the TLS CA the
+channel config expects. The status tells where it breaks: unresolved, blocked
+when the dial isn't answered, refused, unreachable or tls-failed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.nodeID, "node", "", "Check only the links of the peer or orderer with this ID")
+	f.DurationVar(&c.timeout, "timeout", doctor.DefaultTimeout, "Time each link has to complete the dial and the TLS handshake")
+	return cmd
+}
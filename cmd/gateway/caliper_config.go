@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"io"
+	"strings"
+)
+
+type caliperConfigCmd struct {
+	opts node.CaliperConfigOptions
+}
+
+func (c *caliperConfigCmd) validate() error {
+	if c.opts.Channel == "" {
+		return errors.New("--channel is required")
+	}
+	if len(c.opts.Chaincodes) == 0 {
+		return errors.New("--chaincode is required")
+	}
+	if c.opts.OutputDir == "" {
+		return errors.New("--output-dir is required")
+	}
+	return nil
+}
+
+func (c *caliperConfigCmd) run(out io.Writer) error {
+	caliperConfig, err := node.WriteCaliperConfig(c.opts)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, caliperConfig, func(w io.Writer) error {
+		tw := output.NewTabWriter(w)
+		fmt.Fprintln(tw, "MSP ID\tPEERS\tIDENTITIES\tCONNECTION PROFILE")
+		for _, org := range caliperConfig.Orgs {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", org.MSPID, strings.Join(org.Peers, ","), strings.Join(org.Identities, ","), org.ConnectionProfile)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, "Caliper network config written to %s\n", caliperConfig.NetworkConfig)
+		return err
+	})
+}
+
+func newCaliperConfigCommand(out io.Writer) *cobra.Command {
+	c := &caliperConfigCmd{}
+	cmd := &cobra.Command{
+		Use:   "caliper-config",
+		Short: "Write the Hyperledger Caliper network config of the peers of the host",
+		Long: `Write the network config of the Fabric connector of Hyperledger Caliper for
+the peers of the host, to run the Caliper benchmarks against them without
+writing connection profiles by hand:
+
+  networkconfig.yaml        the channel and chaincodes under test and the
+                            orgs of the peers with their identities, passed
+                            to caliper launch manager with
+                            --caliper-networkconfig
+  connection-<MSP ID>.yaml  the connection profile of each org, with the
+                            endpoints and the TLS roots of its peers
+
+The workers discover the other endorsers and the orderers of the channel
+through the peers. Each --identity is used by the org of its MSP, an org
+without one uses the admin identity hlf-easy manages for its first peer, the
+workloads select them with invokerIdentity by their common name. The network
+config has the private keys of the identities, it's only readable by its
+owner.`,
+		Example: `  hlf-easy gateway caliper-config --channel=mychannel --chaincode=asset --output-dir=caliper
+  hlf-easy gateway caliper-config --peer-id=peer1 --channel=mychannel --chaincode=asset --identity=clients/user1.yaml --output-dir=caliper
+  npx caliper launch manager --caliper-workspace . --caliper-networkconfig caliper/networkconfig.yaml --caliper-benchconfig benchmark.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringSliceVar(&c.opts.PeerIDs, "peer-id", nil, "Peers the workers connect to, all the peers of the host if empty")
+	f.StringVar(&c.opts.Channel, "channel", "", "Channel under test")
+	f.StringSliceVar(&c.opts.Chaincodes, "chaincode", nil, "Chaincodes under test")
+	f.StringSliceVar(&c.opts.Identities, "identity", nil, "Identity files, as written by ca enroll, the workers submit the transactions with")
+	f.StringVar(&c.opts.OutputDir, "output-dir", "", "Directory the network config and the connection profiles are written to")
+	return cmd
+}
@@ -0,0 +1,80 @@
+package gateway
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/node"
+	"io"
+	"os"
+)
+
+type clientConfigCmd struct {
+	opts       node.GatewayClientConfigOptions
+	outputPath string
+}
+
+func (c *clientConfigCmd) validate() error {
+	if c.opts.PeerID == "" {
+		return errors.New("--peer-id is required")
+	}
+	if c.opts.Identity == "" {
+		return errors.New("--identity is required")
+	}
+	return nil
+}
+
+func (c *clientConfigCmd) run(out io.Writer) error {
+	clientConfig, err := node.NewGatewayClientConfig(c.opts)
+	if err != nil {
+		return err
+	}
+	clientConfigYaml, err := yaml.Marshal(clientConfig)
+	if err != nil {
+		return err
+	}
+	if c.outputPath == "" {
+		_, err = out.Write(clientConfigYaml)
+		return err
+	}
+	// the config has the private key of the identity
+	err = os.WriteFile(c.outputPath, clientConfigYaml, 0600)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Gateway client config of peer %s written to %s\n", c.opts.PeerID, c.outputPath)
+	return nil
+}
+
+func newClientConfigCommand(out io.Writer) *cobra.Command {
+	c := &clientConfigCmd{}
+	cmd := &cobra.Command{
+		Use:   "client-config",
+		Short: "Write the endpoint, TLS roots and identity a fabric-gateway client needs to connect to a peer",
+		Long: `Write the endpoint, TLS roots and identity a client of the fabric-gateway SDKs
+needs to connect to the gateway of a peer:
+
+  mspID         MSP ID of the identity
+  endpoint      external endpoint of the peer
+  serverName    host to verify the TLS certificate of the peer with, set when
+                the endpoint is an IP or a host the certificate isn't valid for
+  tlsRootCerts  TLS CA and intermediates of the peer
+  identity      certificate and private key of the identity
+
+The identity must belong to the MSP of the peer. The config has its private key,
+the file is only readable by its owner.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.opts.PeerID, "peer-id", "", "ID of the peer whose gateway the client connects to")
+	f.StringVar(&c.opts.Identity, "identity", "", "Identity file, as written by ca enroll, the client signs the transactions with")
+	f.StringVar(&c.opts.Endpoint, "endpoint", "", "Endpoint the client connects to, the external endpoint of the peer if empty")
+	f.StringVarP(&c.outputPath, "output", "o", "", "File to write the client config to, printed if empty")
+	return cmd
+}
@@ -0,0 +1,18 @@
+package gateway
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewGatewayCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gateway",
+		Short: "Connect the clients of the fabric-gateway SDKs to the peers",
+	}
+	cmd.AddCommand(
+		newClientConfigCommand(out),
+		newCaliperConfigCommand(out),
+	)
+	return cmd
+}
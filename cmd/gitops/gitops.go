@@ -0,0 +1,18 @@
+package gitops
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewGitOpsCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gitops",
+		Short: "Reconcile the host with the network.yaml and release.yaml of a Git repository",
+	}
+	cmd.AddCommand(
+		newGitOpsSyncCommand(out, errOut),
+		newGitOpsStatusCommand(out, errOut),
+	)
+	return cmd
+}
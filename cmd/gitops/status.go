@@ -0,0 +1,52 @@
+package gitops
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"hlf-easy/gitops"
+	"hlf-easy/output"
+	"io"
+	"time"
+)
+
+type statusCmd struct{}
+
+func (c *statusCmd) validate() error {
+	return nil
+}
+
+func (c *statusCmd) run(out io.Writer, errOut io.Writer) error {
+	state, err := gitops.GetState()
+	if err != nil {
+		return err
+	}
+	return output.Print(out, state, func(w io.Writer) error {
+		fmt.Fprintf(w, "Repository: %s@%s (%s)\n", state.Repo, state.Branch, state.Path)
+		fmt.Fprintf(w, "Commit: %s\n", state.Commit)
+		fmt.Fprintf(w, "Status: %s at %s\n", state.Status, state.SyncedAt.Format(time.RFC3339))
+		if state.Error != "" {
+			fmt.Fprintf(w, "Error: %s\n", state.Error)
+		}
+		if state.Result != nil {
+			for _, drift := range state.Result.Drift {
+				fmt.Fprintf(w, "Drift: %s\n", drift)
+			}
+		}
+		return nil
+	})
+}
+
+func newGitOpsStatusCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &statusCmd{}
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the last synced commit, its status and the drift of the host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
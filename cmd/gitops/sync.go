@@ -0,0 +1,98 @@
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/gitops"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"time"
+)
+
+type syncCmd struct {
+	opts     gitops.Options
+	interval time.Duration
+	once     bool
+	dryRun   bool
+}
+
+func (c *syncCmd) validate() error {
+	if c.opts.Repo == "" {
+		return errors.New("--repo is required")
+	}
+	if c.opts.Branch == "" {
+		return errors.New("--branch is required")
+	}
+	if !c.once && !c.dryRun && c.interval <= 0 {
+		return errors.New("--interval must be positive")
+	}
+	return nil
+}
+
+func (c *syncCmd) run(out io.Writer, errOut io.Writer) error {
+	if c.dryRun {
+		state, err := gitops.PlanSync(c.opts)
+		if err != nil {
+			return err
+		}
+		return output.Print(out, state, func(out io.Writer) error {
+			fmt.Fprintf(out, "Syncing commit %s would:\n", state.Commit)
+			for _, action := range state.Result.Actions {
+				fmt.Fprintf(out, "  %s\n", action)
+			}
+			if len(state.Result.Actions) == 0 {
+				fmt.Fprintln(out, "  change nothing")
+			}
+			for _, drift := range state.Result.Drift {
+				fmt.Fprintf(errOut, "  drift: %s\n", drift)
+			}
+			fmt.Fprintln(out, "Nothing was changed on the host")
+			return nil
+		})
+	}
+	if c.once {
+		state, err := gitops.Sync(c.opts)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Synced commit %s\n", state.Commit)
+		for _, action := range state.Result.Actions {
+			fmt.Fprintf(out, "  %s\n", action)
+		}
+		for _, drift := range state.Result.Drift {
+			fmt.Fprintf(errOut, "  drift: %s\n", drift)
+		}
+		return nil
+	}
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	log.Infof("Syncing %s@%s every %s", c.opts.Repo, c.opts.Branch, c.interval)
+	gitops.Run(ctx, c.opts, c.interval)
+	return nil
+}
+
+func newGitOpsSyncCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &syncCmd{}
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Watch a Git repository and reconcile the host on each commit",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.opts.Repo, "repo", "", "URL or path of the Git repository")
+	f.StringVar(&c.opts.Branch, "branch", "main", "Branch to follow")
+	f.StringVar(&c.opts.Path, "path", ".", "Directory of the network.yaml and release.yaml in the repository")
+	f.DurationVar(&c.interval, "interval", time.Minute, "Interval between two fetches of the repository")
+	f.BoolVar(&c.once, "once", false, "Sync the last commit and exit")
+	f.BoolVar(&c.dryRun, "dry-run", false, "Print the actions and drift of the last commit without changing the host and exit")
+	return cmd
+}
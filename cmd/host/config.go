@@ -0,0 +1,48 @@
+package host
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"hlf-easy/config"
+	"hlf-easy/output"
+	"hlf-easy/resources"
+	"hlf-easy/utils"
+	"io"
+)
+
+type configCmd struct {
+	hostConfig config.HostConfig
+}
+
+func (c *configCmd) validate() error {
+	return resources.ValidateHostConfig(c.hostConfig)
+}
+
+func (c *configCmd) run(out io.Writer, errOut io.Writer) error {
+	err := utils.SaveHostConfig(&c.hostConfig)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, c.hostConfig, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, "Host config saved")
+		return err
+	})
+}
+
+func newHostConfigCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &configCmd{}
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Configure how much of the host capacity can be reserved by the nodes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.Float64Var(&c.hostConfig.OvercommitThreshold, "overcommit-threshold", 1, "Fraction of the host CPUs and memory that can be reserved by the nodes, e.g. 0.8")
+	f.StringVar(&c.hostConfig.OvercommitPolicy, "overcommit-policy", resources.OvercommitPolicyWarn, "What to do when a node would overcommit the host: warn or refuse")
+	return cmd
+}
@@ -0,0 +1,19 @@
+package host
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewHostCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "host",
+		Short: "Manage the capacity of the host shared by the nodes",
+	}
+	cmd.AddCommand(
+		newHostUsageCommand(out, errOut),
+		newHostConfigCommand(out, errOut),
+		newHostServiceCommand(out, errOut),
+	)
+	return cmd
+}
@@ -0,0 +1,110 @@
+package host
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/proc"
+	"io"
+	"os"
+	"strings"
+)
+
+func newHostServiceCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "Run hlf-easy commands as Windows services",
+		Long: `Run hlf-easy commands as Windows services, like the start of the nodes of the
+host so they run without a session and are started on boot. The services
+restart the command when it fails and stop it gracefully. On the other hosts
+the commands are run with their service manager, like systemd.`,
+	}
+	cmd.AddCommand(
+		newHostServiceInstallCommand(out),
+		newHostServiceRemoveCommand(out),
+	)
+	return cmd
+}
+
+type serviceInstallCmd struct {
+	opts proc.ServiceOptions
+}
+
+func (c *serviceInstallCmd) validate() error {
+	return c.opts.Validate()
+}
+
+func (c *serviceInstallCmd) run(out io.Writer) error {
+	// the password isn't a flag so it's not in the audit log
+	c.opts.Password = os.Getenv("HLF_EASY_SERVICE_PASSWORD")
+	if c.opts.Description == "" {
+		c.opts.Description = "hlf-easy " + strings.Join(c.opts.Args, " ")
+	}
+	err := proc.InstallService(c.opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Service %s installed, start it with sc start %s\n", c.opts.Name, c.opts.Name)
+	return nil
+}
+
+func newHostServiceInstallCommand(out io.Writer) *cobra.Command {
+	c := &serviceInstallCmd{}
+	cmd := &cobra.Command{
+		Use:   "install --name <name> -- <command>",
+		Short: "Install a Windows service running an hlf-easy command",
+		Long: `Install a Windows service running the hlf-easy command given after --, it's
+started on boot. The service runs as LocalSystem unless --account is set, the
+nodes it starts are in the hlf-easy directory of the profile of the account.
+The password of the account is read from HLF_EASY_SERVICE_PASSWORD.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.opts.Args = args
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.opts.Name, "name", "", "Name of the service")
+	f.StringVar(&c.opts.Description, "description", "", "Description of the service, the command by default")
+	f.StringVar(&c.opts.Account, "account", "", "Account the service runs as, e.g. .\\fabric, its password is read from HLF_EASY_SERVICE_PASSWORD")
+	return cmd
+}
+
+type serviceRemoveCmd struct {
+	name string
+}
+
+func (c *serviceRemoveCmd) validate() error {
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	return nil
+}
+
+func (c *serviceRemoveCmd) run(out io.Writer) error {
+	err := proc.RemoveService(c.name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Service %s removed\n", c.name)
+	return nil
+}
+
+func newHostServiceRemoveCommand(out io.Writer) *cobra.Command {
+	c := &serviceRemoveCmd{}
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Stop and remove a Windows service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.name, "name", "", "Name of the service")
+	return cmd
+}
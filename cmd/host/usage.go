@@ -0,0 +1,42 @@
+package host
+
+import (
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/output"
+	"hlf-easy/resources"
+	"io"
+)
+
+type usageCmd struct{}
+
+func (c *usageCmd) validate() error {
+	return nil
+}
+
+func (c *usageCmd) run(out io.Writer, errOut io.Writer) error {
+	utilization, err := resources.GetUtilization()
+	if err != nil {
+		return err
+	}
+	return output.Print(out, utilization, func(w io.Writer) error {
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		return encoder.Encode(utilization)
+	})
+}
+
+func newHostUsageCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &usageCmd{}
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show the capacity of the host and the resources reserved by its nodes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,81 @@
+package listen
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/contract"
+	"hlf-easy/proc"
+	"io"
+	"regexp"
+)
+
+type listenCmd struct {
+	opts contract.ListenOptions
+}
+
+func (c *listenCmd) validate() error {
+	if c.opts.PeerID == "" {
+		return errors.New("--peer-id is required")
+	}
+	if c.opts.Channel == "" {
+		return errors.New("--channel is required")
+	}
+	if c.opts.EventFilter != "" {
+		if c.opts.Chaincode == "" {
+			return errors.New("--event-filter is only used with --chaincode")
+		}
+		if _, err := regexp.Compile(c.opts.EventFilter); err != nil {
+			return errors.Wrap(err, "invalid --event-filter")
+		}
+	}
+	if c.opts.FromBlock < -1 {
+		return errors.New("--from-block must be a block number or -1")
+	}
+	return nil
+}
+
+func (c *listenCmd) run(out io.Writer) error {
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	log.Debugf("Listening to the events of channel %s on peer %s", c.opts.Channel, c.opts.PeerID)
+	encoder := json.NewEncoder(out)
+	return contract.Listen(ctx, c.opts, func(event contract.Event) error {
+		return encoder.Encode(event)
+	})
+}
+
+func NewListenCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &listenCmd{}
+	cmd := &cobra.Command{
+		Use:   "listen",
+		Short: "Stream the blocks and the chaincode events of a channel as JSON lines",
+		Long: `Stream the blocks committed on a channel, with their transactions and
+validation codes, and the events set by the transactions of --chaincode, as
+one JSON object per line until interrupted, to pipe them to jq or debug an
+event-driven application.
+
+The events are received from a peer of the host through its gateway, signed
+by the admin identity hlf-easy manages for the peer or by --identity. Only
+the next blocks are received unless --from-block replays the channel from a
+block.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.opts.PeerID, "peer-id", "", "ID of the peer the events are received from")
+	f.StringVar(&c.opts.Channel, "channel", "", "Channel to listen to")
+	f.StringVar(&c.opts.Chaincode, "chaincode", "", "Chaincode whose events are streamed with the blocks")
+	f.StringVar(&c.opts.EventFilter, "event-filter", "", "Regular expression the names of the chaincode events match, all of them if empty")
+	f.StringVar(&c.opts.Identity, "identity", "", "Identity file receiving the events, an admin identity issued by the local CA of the peer if empty")
+	f.StringVar(&c.opts.Endpoint, "endpoint", "", "Endpoint of the peer, its external endpoint if empty")
+	f.Int64Var(&c.opts.FromBlock, "from-block", -1, "Block the events are replayed from, only the next blocks if -1")
+	return cmd
+}
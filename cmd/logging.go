@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	hlflog "hlf-easy/log"
+	"strings"
+	"time"
+)
+
+// nodeFlags are the flags naming the node a command operates on
+var nodeFlags = []string{"id", "peer-id"}
+
+// commandFields returns the fields of the logs of a command, its operation
+// and the node it operates on
+func commandFields(cmd *cobra.Command) logrus.Fields {
+	fields := logrus.Fields{
+		hlflog.FieldOp: strings.Join(strings.Fields(cmd.CommandPath())[1:], "."),
+	}
+	for _, name := range nodeFlags {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Value.String() != "" && f.Value.String() != "[]" {
+			fields[hlflog.FieldNodeID] = strings.Trim(f.Value.String(), "[]")
+			break
+		}
+	}
+	return fields
+}
+
+// timeCommands logs the duration of the commands of a tree once they return,
+// the commands that keep running log it when they're stopped
+func timeCommands(root *cobra.Command) {
+	for _, cmd := range root.Commands() {
+		if runE := cmd.RunE; runE != nil {
+			cmd.RunE = func(cmd *cobra.Command, args []string) error {
+				start := time.Now()
+				err := runE(cmd, args)
+				entry := logrus.WithField(hlflog.FieldDuration, time.Since(start).Seconds())
+				if err != nil {
+					entry.WithError(err).Debugf("Command %s failed", cmd.CommandPath())
+				} else {
+					entry.Debugf("Command %s completed", cmd.CommandPath())
+				}
+				return err
+			}
+		}
+		timeCommands(cmd)
+	}
+}
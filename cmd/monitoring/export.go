@@ -0,0 +1,77 @@
+package monitoring
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/monitoring"
+	"hlf-easy/output"
+	"io"
+)
+
+type exportCmd struct {
+	opts monitoring.ExportOptions
+}
+
+func (c *exportCmd) validate() error {
+	if c.opts.OutputDir == "" {
+		return errors.New("--output-dir is required")
+	}
+	return nil
+}
+
+func (c *exportCmd) run(out io.Writer, errOut io.Writer) error {
+	exported, err := monitoring.Export(c.opts)
+	if err != nil {
+		return err
+	}
+	for _, skipped := range exported.Skipped {
+		fmt.Fprintf(errOut, "Skipped %s\n", skipped)
+	}
+	return output.Print(out, exported, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "KIND\tID\tMSP ID\tTARGET\tTLS")
+		for _, t := range exported.Targets {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", t.Kind, t.ID, t.MSPID, t.Address, t.TLS)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		for _, file := range exported.Files {
+			fmt.Fprintf(out, "Written %s\n", file)
+		}
+		return nil
+	})
+}
+
+func newExportCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &exportCmd{}
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the Prometheus scrape config of the nodes of the host and a Grafana dashboard",
+		Long: `Export the Prometheus scrape config of the operations endpoints of the peers and
+orderers of the host and a Grafana dashboard of their Fabric metrics:
+
+  <output-dir>/prometheus.yml          scrape config of the nodes
+  <output-dir>/federation.yml          with --federate-address, the config of a
+                                       central Prometheus federating the host
+  <output-dir>/grafana-dashboard.json  dashboard to import in Grafana
+
+The metrics are labeled with the kind, the ID and the MSP ID of the nodes. The
+stopped peers are scraped on the address of their init options, the stopped
+orderers and the peers without Prometheus metrics are skipped. Export the
+config again when the nodes change.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.opts.OutputDir, "output-dir", "", "Directory to write the monitoring config to")
+	f.StringVar(&c.opts.Host, "host", "127.0.0.1", "Address Prometheus reaches the nodes listening on all the interfaces on")
+	f.StringVar(&c.opts.ScrapeInterval, "scrape-interval", monitoring.DefaultScrapeInterval, "How often Prometheus scrapes the nodes")
+	f.StringVar(&c.opts.FederateAddress, "federate-address", "", "Address of the Prometheus of the host, writes the config of a central Prometheus federating it")
+	return cmd
+}
@@ -0,0 +1,17 @@
+package monitoring
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewMonitoringCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "monitoring",
+		Short: "Monitor the nodes of the host with Prometheus and Grafana",
+	}
+	cmd.AddCommand(
+		newExportCommand(out, errOut),
+	)
+	return cmd
+}
@@ -0,0 +1,20 @@
+package notify
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewNotifyCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "notify",
+		Short: "Configure the webhooks notified of the events of the nodes of the host",
+	}
+	cmd.AddCommand(
+		newAddWebhookCommand(out, errOut),
+		newRemoveWebhookCommand(out, errOut),
+		newListWebhooksCommand(out, errOut),
+		newTestCommand(out, errOut),
+	)
+	return cmd
+}
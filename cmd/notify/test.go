@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/notify"
+	"hlf-easy/utils"
+	"io"
+)
+
+type testCmd struct{}
+
+func (c *testCmd) validate() error {
+	return nil
+}
+
+func (c *testCmd) run(out io.Writer, errOut io.Writer) error {
+	notifyConfig, err := utils.GetNotifyConfig()
+	if err != nil {
+		return err
+	}
+	if len(notifyConfig.Webhooks) == 0 {
+		return errors.New("no webhook configured, run notify add-webhook first")
+	}
+	// the test event is posted to all the webhooks regardless of their events
+	for i := range notifyConfig.Webhooks {
+		notifyConfig.Webhooks[i].Events = nil
+	}
+	event := notify.NewEvent("test", "", "", "Test notification from hlf-easy")
+	err = notify.Send(*notifyConfig, event)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Test event posted to %d webhooks\n", len(notifyConfig.Webhooks))
+	return nil
+}
+
+func newTestCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &testCmd{}
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Post a test event to all the webhooks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
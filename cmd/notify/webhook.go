@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/config"
+	"hlf-easy/notify"
+	"hlf-easy/output"
+	"hlf-easy/utils"
+	"io"
+	"strings"
+)
+
+type addWebhookCmd struct {
+	webhook config.WebhookConfig
+}
+
+func (c *addWebhookCmd) validate() error {
+	if c.webhook.URL == "" {
+		return errors.New("--url is required")
+	}
+	return notify.ValidateWebhook(c.webhook)
+}
+
+func (c *addWebhookCmd) run(out io.Writer, errOut io.Writer) error {
+	notifyConfig, err := utils.GetNotifyConfig()
+	if err != nil {
+		return err
+	}
+	// adding a webhook again replaces its format and events
+	webhooks := []config.WebhookConfig{}
+	for _, webhook := range notifyConfig.Webhooks {
+		if webhook.URL != c.webhook.URL {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	notifyConfig.Webhooks = append(webhooks, c.webhook)
+	err = utils.SaveNotifyConfig(notifyConfig)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Webhook added")
+	return nil
+}
+
+func newAddWebhookCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &addWebhookCmd{}
+	cmd := &cobra.Command{
+		Use:   "add-webhook",
+		Short: "Post the events of the nodes to a webhook",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.webhook.URL, "url", "", "URL of the webhook")
+	f.StringVar(&c.webhook.Format, "format", notify.FormatJSON, "Format of the payload: json or slack")
+	f.StringSliceVar(&c.webhook.Events, "events", []string{}, fmt.Sprintf("Events posted to the webhook, all if empty: %s", strings.Join(notify.Events, ", ")))
+	return cmd
+}
+
+type removeWebhookCmd struct {
+	url string
+}
+
+func (c *removeWebhookCmd) validate() error {
+	if c.url == "" {
+		return errors.New("--url is required")
+	}
+	return nil
+}
+
+func (c *removeWebhookCmd) run(out io.Writer, errOut io.Writer) error {
+	notifyConfig, err := utils.GetNotifyConfig()
+	if err != nil {
+		return err
+	}
+	webhooks := []config.WebhookConfig{}
+	for _, webhook := range notifyConfig.Webhooks {
+		if webhook.URL != c.url {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	if len(webhooks) == len(notifyConfig.Webhooks) {
+		return errors.Errorf("webhook %s not found", c.url)
+	}
+	notifyConfig.Webhooks = webhooks
+	err = utils.SaveNotifyConfig(notifyConfig)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Webhook removed")
+	return nil
+}
+
+func newRemoveWebhookCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &removeWebhookCmd{}
+	cmd := &cobra.Command{
+		Use:   "remove-webhook",
+		Short: "Stop posting the events to a webhook",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.url, "url", "", "URL of the webhook")
+	return cmd
+}
+
+type listWebhooksCmd struct{}
+
+func (c *listWebhooksCmd) validate() error {
+	return nil
+}
+
+func (c *listWebhooksCmd) run(out io.Writer, errOut io.Writer) error {
+	notifyConfig, err := utils.GetNotifyConfig()
+	if err != nil {
+		return err
+	}
+	webhooks := notifyConfig.Webhooks
+	for i := range webhooks {
+		if webhooks[i].Format == "" {
+			webhooks[i].Format = notify.FormatJSON
+		}
+	}
+	return output.Print(out, webhooks, func(w io.Writer) error {
+		for _, webhook := range webhooks {
+			events := "all"
+			if len(webhook.Events) > 0 {
+				events = strings.Join(webhook.Events, ",")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", webhook.URL, webhook.Format, events)
+		}
+		return nil
+	})
+}
+
+func newListWebhooksCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &listWebhooksCmd{}
+	cmd := &cobra.Command{
+		Use:   "list-webhooks",
+		Short: "List the webhooks with their format and events",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,279 @@
+package orderer
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/channel"
+	"hlf-easy/cluster"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func newOrdererClusterCommand(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cluster",
+		Short: "Bootstrap a Raft or BFT cluster of orderers on the host",
+	}
+	cmd.AddCommand(
+		newClusterInitCommand(out),
+		newClusterStartCommand(out),
+		newClusterBundleCommand(out),
+	)
+	return cmd
+}
+
+type clusterInitCmd struct {
+	spec     cluster.Spec
+	orderers []string
+	timeout  time.Duration
+}
+
+// parseMember parses <id>=<host>:<port>[,admin=<port>][,operations=<port>],
+// the admin port defaults to the port + 3 and the operations port to 9443 +
+// the index of the orderer
+func parseMember(value string, index int) (cluster.Member, error) {
+	m := cluster.Member{}
+	parts := strings.Split(value, ",")
+	id, endpoint, ok := strings.Cut(parts[0], "=")
+	if !ok {
+		return m, errors.Errorf("invalid orderer %q, expected <id>=<host>:<port>", value)
+	}
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return m, errors.Wrapf(err, "invalid endpoint of orderer %s", id)
+	}
+	m.ID = id
+	m.Host = host
+	m.Port, err = strconv.Atoi(port)
+	if err != nil {
+		return m, errors.Errorf("invalid port %q of orderer %s", port, id)
+	}
+	m.AdminPort = m.Port + 3
+	m.OperationsPort = 9443 + index
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(part, "=")
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return m, errors.Errorf("invalid %s port %q of orderer %s", key, value, id)
+		}
+		switch key {
+		case "admin":
+			m.AdminPort = port
+		case "operations":
+			m.OperationsPort = port
+		default:
+			return m, errors.Errorf("unknown option %q of orderer %s, expected admin or operations", key, id)
+		}
+	}
+	return m, nil
+}
+
+func (c *clusterInitCmd) validate() error {
+	if c.spec.Name == "" {
+		return errors.New("--name is required")
+	}
+	if c.spec.MSPID == "" {
+		return errors.New("--msp-id is required")
+	}
+	if c.spec.CAName == "" {
+		return errors.New("--ca-name is required")
+	}
+	if len(c.orderers) == 0 {
+		return errors.New("at least one --orderer is required")
+	}
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	c.spec.Members = nil
+	for i, value := range c.orderers {
+		m, err := parseMember(value, i)
+		if err != nil {
+			return err
+		}
+		c.spec.Members = append(c.spec.Members, m)
+	}
+	return c.spec.Validate()
+}
+
+func (c *clusterInitCmd) run(out io.Writer) error {
+	// an interrupt or --timeout stops waiting for the locks of the orderers
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	err := cluster.Init(ctx, c.spec)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, c.spec, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "ID\tENDPOINT\tADMIN PORT\tOPERATIONS PORT")
+		for _, m := range c.spec.Members {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", m.ID, m.Endpoint(), m.AdminPort, m.OperationsPort)
+		}
+		err := w.Flush()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Cluster %s initialized, it tolerates %d failed orderers, start it with orderer cluster start --name=%s\n", c.spec.Name, c.spec.FaultTolerance(), c.spec.Name)
+		return nil
+	})
+}
+
+func newClusterInitCommand(out io.Writer) *cobra.Command {
+	c := &clusterInitCmd{}
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Enroll the orderers of a cluster with a local CA",
+		Long: `Enroll the orderers of a cluster with a local CA, the TLS certificate of every
+orderer is issued for its host so the consenters can authenticate each other.
+The orderers run on the host and need their own ports:
+
+  --orderer <id>=<host>:<port>[,admin=<port>][,operations=<port>]
+
+The admin port defaults to the port + 3 and the operations port to 9443, 9444...
+in the order of the orderers. Orderers initialized before are reused if their
+TLS certificate is valid for the host.
+
+With --consensus=BFT the channels of the cluster use SmartBFT, the orderers
+must be Fabric 3.0 orderers and the consenters are mapped to the signing
+certificate of their orderer. A BFT cluster needs 3f+1 orderers to tolerate f
+failures.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.spec.Name, "name", "", "Name of the cluster")
+	f.StringVar(&c.spec.MSPID, "msp-id", "", "MSP ID of the orderer organization")
+	f.StringVar(&c.spec.CAName, "ca-name", "", "Name of the local CA that issues the certificates of the orderers")
+	f.StringVar(&c.spec.ConsensusType, "consensus", channel.ConsensusTypeEtcdRaft, "Consensus type of the channels of the cluster: etcdraft or BFT")
+	f.StringArrayVar(&c.orderers, "orderer", []string{}, "Orderer of the cluster, <id>=<host>:<port>[,admin=<port>][,operations=<port>]")
+	f.DurationVar(&c.timeout, "timeout", node.DefaultInitTimeout, "How long to wait for the locks of the orderers, 0 waits without a limit")
+	return cmd
+}
+
+type clusterStartCmd struct {
+	name    string
+	timeout time.Duration
+}
+
+func (c *clusterStartCmd) validate() error {
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	return nil
+}
+
+func (c *clusterStartCmd) run(out io.Writer) error {
+	spec, err := cluster.Get(c.name)
+	if err != nil {
+		return err
+	}
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	results, startErr := cluster.Start(ctx, *spec, c.timeout)
+	err = output.Print(out, results, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "ID\tRESULT\tERROR")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.ID, r.Result, r.Error)
+		}
+		return w.Flush()
+	})
+	if startErr != nil {
+		return startErr
+	}
+	return err
+}
+
+func newClusterStartCommand(out io.Writer) *cobra.Command {
+	c := &clusterStartCmd{}
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start the orderers of a cluster one at a time",
+		Long: `Start the orderers of a cluster in the background one at a time, every
+orderer must answer /healthz on its operations port before the next one is
+started. The running orderers are skipped, the output of the others is written
+to hlf-easy.log in their directory.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.name, "name", "", "Name of the cluster")
+	f.DurationVar(&c.timeout, "timeout", cluster.DefaultStartTimeout, "How long every orderer has to become healthy")
+	return cmd
+}
+
+type clusterBundleCmd struct {
+	name       string
+	outputPath string
+}
+
+func (c *clusterBundleCmd) validate() error {
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	return nil
+}
+
+func (c *clusterBundleCmd) run(out io.Writer) error {
+	spec, err := cluster.Get(c.name)
+	if err != nil {
+		return err
+	}
+	bundle, err := cluster.Bundle(*spec)
+	if err != nil {
+		return err
+	}
+	bundleYaml, err := yaml.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+	if c.outputPath == "" {
+		_, err = out.Write(bundleYaml)
+		return err
+	}
+	err = os.WriteFile(c.outputPath, bundleYaml, 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Orderer bundle of cluster %s written to %s\n", c.name, c.outputPath)
+	return nil
+}
+
+func newClusterBundleCommand(out io.Writer) *cobra.Command {
+	c := &clusterBundleCmd{}
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Write the orderer bundle of a cluster",
+		Long: `Write the orderer bundle of a cluster, the members of the cluster are the
+consenters of the channels created with channel create --orderer-bundle and
+--submit joins them to the channels through their admin port.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.name, "name", "", "Name of the cluster")
+	f.StringVarP(&c.outputPath, "output", "o", "", "File to write the bundle to, it's printed if empty")
+	return cmd
+}
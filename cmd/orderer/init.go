@@ -1,51 +1,56 @@
 package orderer
 
 import (
+	"context"
 	"fmt"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"hlf-easy/config"
 	"hlf-easy/node"
+	"hlf-easy/output"
+	"hlf-easy/plan"
+	"hlf-easy/proc"
+	"io"
+	"time"
 )
 
 type ordererInitCmd struct {
 	ordererOpts config.OrdererInitOptions
+	dryRun      bool
+	timeout     time.Duration
 }
 
 func (c ordererInitCmd) validate() error {
-	if c.ordererOpts.ID == "" {
-		return fmt.Errorf("--id is required")
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
 	}
-	if c.ordererOpts.Local {
-		if c.ordererOpts.CAName == "" {
-			return fmt.Errorf("--ca-name is required")
-		}
-	} else {
-		// validate that the options are not empty
-		if c.ordererOpts.CAUrl == "" {
-			return fmt.Errorf("--ca-url is required")
-		}
-		if !c.ordererOpts.CAInsecure && c.ordererOpts.CACert == "" {
-			return fmt.Errorf("--ca-cert is required")
-		}
-		if c.ordererOpts.EnrollID == "" {
-			return fmt.Errorf("--enroll-id is required")
-		}
-		if c.ordererOpts.EnrollSecret == "" {
-			return fmt.Errorf("--enroll-secret is required")
-		}
-	}
-	return nil
+	return node.ValidateOrdererInitOptions(c.ordererOpts)
 }
 
-func (c ordererInitCmd) run() error {
-	err := node.EnrollOrdererCertificates(c.ordererOpts)
+func (c ordererInitCmd) run(out io.Writer) error {
+	if c.dryRun {
+		p := plan.New()
+		err := node.PlanOrdererInit(p, c.ordererOpts)
+		if err != nil {
+			return err
+		}
+		return output.Print(out, p.Changes, func(out io.Writer) error {
+			err := p.Print(out)
+			fmt.Fprintf(out, "Nothing was written and no process is started, the orderer is started by orderer start --id=%s\n", c.ordererOpts.ID)
+			return err
+		})
+	}
+	// an interrupt or --timeout stops waiting for the lock of the node
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	err := node.EnrollOrdererCertificates(ctx, c.ordererOpts)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func newOrdererInitCommand() *cobra.Command {
+func newOrdererInitCommand(out io.Writer) *cobra.Command {
 	c := ordererInitCmd{
 		ordererOpts: config.OrdererInitOptions{},
 	}
@@ -55,19 +60,28 @@ func newOrdererInitCommand() *cobra.Command {
 			if err := c.validate(); err != nil {
 				return err
 			}
-			return c.run()
+			return c.run(out)
 		},
 	}
 	f := cmd.Flags()
 	f.BoolVar(&c.ordererOpts.Local, "local", false, "Local provisioning")
 	f.StringVar(&c.ordererOpts.CAName, "ca-name", "", "Name of the CA")
 	f.StringSliceVar(&c.ordererOpts.Hosts, "hosts", []string{}, "Hosts")
+	f.StringVar(&c.ordererOpts.ExternalEndpoint, "external-endpoint", "", "Endpoint the orderer is advertised on behind a reverse proxy or a load balancer, added to its TLS SANs and the default of orderer start")
 	f.StringVar(&c.ordererOpts.ID, "id", "", "ID of the orderer")
 	f.StringVar(&c.ordererOpts.CAUrl, "ca-url", "", "URL of the CA")
 	f.BoolVar(&c.ordererOpts.CAInsecure, "ca-insecure", false, "CA certificate is not verified")
 	f.StringVar(&c.ordererOpts.CACert, "ca-cert", "", "Path to the CA tls certificate")
 	f.StringVar(&c.ordererOpts.EnrollID, "enroll-id", "", "Enroll ID")
 	f.StringVar(&c.ordererOpts.EnrollSecret, "enroll-secret", "", "Enroll secret")
+	f.BoolVar(&c.dryRun, "dry-run", false, "Print the directories, files and certificates that would be written without writing them")
+	f.DurationVar(&c.timeout, "timeout", node.DefaultInitTimeout, "How long to wait for the lock of the orderer, 0 waits without a limit")
+	c.ordererOpts.CertPolicy.AddFlags(f)
+	c.ordererOpts.CertPolicy.AddSANFlags(f)
+	c.ordererOpts.Resources.AddFlags(f)
+	c.ordererOpts.Limits.AddFlags(f)
+	c.ordererOpts.Hooks.AddFlags(f)
+	c.ordererOpts.TLSPolicy.AddFlags(f)
 
 	return cmd
 }
@@ -11,7 +11,9 @@ func NewOrdererCmd(out io.Writer, errOut io.Writer, views embed.FS) *cobra.Comma
 		Use: "orderer",
 	}
 	cmd.AddCommand(
+		newOrdererInitCommand(out),
 		newOrdererStartCommand(views),
+		newOrdererClusterCommand(out),
 	)
 	return cmd
 }
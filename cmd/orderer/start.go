@@ -8,26 +8,34 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"hlf-easy/alerts"
+	"hlf-easy/anomaly"
 	"hlf-easy/api"
+	"hlf-easy/auth"
 	"hlf-easy/config"
 	"hlf-easy/node"
+	"hlf-easy/proc"
+	"hlf-easy/tasks"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
-	"syscall"
 	"time"
 )
 
-func StartOrdererNodeCommand(stdout *config.SaveOutputWriter, stderr *config.SaveOutputWriter, opts config.StartOrdererOpts) (*exec.Cmd, error) {
+func StartOrdererNodeCommand(opts config.StartOrdererOpts) (*exec.Cmd, error) {
 	// Define the command and arguments
 	cmd := exec.Command("orderer")
 	host, port, err := net.SplitHostPort(opts.ListenAddress)
 	if err != nil {
 		return nil, err
 	}
+	logSpec := opts.LogSpec
+	if logSpec == "" {
+		logSpec = node.DefaultLogSpec
+	}
 	// Set environment variables specifically for this command
 	cmd.Env = []string{
 		fmt.Sprintf("FABRIC_CFG_PATH=%s", opts.ConfigOrdererPath),
@@ -66,7 +74,7 @@ func StartOrdererNodeCommand(stdout *config.SaveOutputWriter, stderr *config.Sav
 		fmt.Sprintf("ORDERER_GENERAL_BOOTSTRAPMETHOD=%s", "none"),
 		fmt.Sprintf("ORDERER_GENERAL_GENESISPROFILE=%s", "initial"),
 		fmt.Sprintf("ORDERER_GENERAL_LEDGERTYPE=%s", "file"),
-		fmt.Sprintf("FABRIC_LOGGING_SPEC=%s", "info"),
+		fmt.Sprintf("FABRIC_LOGGING_SPEC=%s", logSpec),
 		fmt.Sprintf("ORDERER_GENERAL_MAXWINDOWSIZE=%s", "1000"),
 		fmt.Sprintf("ORDERER_GENERAL_ORDERERTYPE=%s", "etcdraft"),
 		fmt.Sprintf("ORDERER_GENERAL_TLS_CLIENTAUTHREQUIRED=%s", "false"),
@@ -74,13 +82,17 @@ func StartOrdererNodeCommand(stdout *config.SaveOutputWriter, stderr *config.Sav
 		fmt.Sprintf("ORDERER_METRICS_PROVIDER=%s", "prometheus"),
 		fmt.Sprintf("ORDERER_OPERATIONS_TLS_ENABLED=%s", "false"),
 	}
+	if opts.KeystorePath != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("ORDERER_GENERAL_BCCSP_SW_FILEKEYSTORE_KEYSTORE=%s", opts.KeystorePath))
+	}
 	log.Infof("Envs: %v", cmd.Env)
 	// Set the Stdout and Stderr to os.Stdout and os.Stderr
 	// so that we can see the command output
 	//cmd.Stdout = os.Stdout
 	//cmd.Stderr = os.Stderr
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
+	cmd.Env = proc.Env(cmd.Env)
+	// the node is stopped with an interrupt of its own process group
+	proc.NewProcessGroup(cmd)
 
 	return cmd, nil
 }
@@ -93,7 +105,7 @@ func (c ordererCmd) validate() error {
 	if c.ordererOpts.ID == "" {
 		return fmt.Errorf("--id is required")
 	}
-	return nil
+	return auth.ValidateOptions(c.ordererOpts.Auth)
 }
 
 func (c ordererCmd) run(views embed.FS) error {
@@ -115,7 +127,29 @@ func (c ordererCmd) run(views embed.FS) error {
 	if err != nil {
 		return err
 	}
+	// the limits of the orderer are the ones in init.json
+	ordererInitOpts := config.OrdererInitOptions{}
+	ordererInitOptsBytes, err := os.ReadFile(filepath.Join(ordererConfigDir, "init.json"))
+	if err == nil {
+		err = json.Unmarshal(ordererInitOptsBytes, &ordererInitOpts)
+		if err != nil {
+			return err
+		}
+	}
 
+	// the endpoint the orderer was initialized with is advertised by default
+	if c.ordererOpts.ExternalEndpoint == "" {
+		c.ordererOpts.ExternalEndpoint = ordererInitOpts.ExternalEndpoint
+	}
+	// without a management address the API is only served on the socket
+	if c.ordererOpts.ManagementAddress == "" && c.ordererOpts.Auth.Socket == "" {
+		c.ordererOpts.Auth.Socket = filepath.Join(ordererConfigDir, "run", "api.sock")
+	}
+	// a node managed by another hlf-easy process isn't started again
+	err = node.CheckManager(node.KindOrderer, c.ordererOpts.ID)
+	if err != nil {
+		return err
+	}
 	// save run.json config in order to indicate that the orderer is running
 	runConfig := config.OrdererRunConfig{
 		OrdererID: c.ordererOpts.ID,
@@ -144,8 +178,13 @@ func (c ordererCmd) run(views embed.FS) error {
 			}
 		}
 	}()
-	stdOut := &config.SaveOutputWriter{}
-	stdErr := &config.SaveOutputWriter{}
+	// the logs are scanned to detect the problems that don't show in the status
+	scanner, err := anomaly.NewNodeScanner("orderer", c.ordererOpts.ID)
+	if err != nil {
+		return err
+	}
+	stdOut := &config.SaveOutputWriter{Tee: scanner.NewStream()}
+	stdErr := &config.SaveOutputWriter{Tee: scanner.NewStream()}
 	startOrdererOpts := config.StartOrdererOpts{
 		ID:                      c.ordererOpts.ID,
 		ListenAddress:           c.ordererOpts.ListenAddress,
@@ -155,32 +194,98 @@ func (c ordererCmd) run(views embed.FS) error {
 		MSPConfigPath:           ordererConfigDir,
 		ConfigOrdererPath:       ordererConfigDir,
 	}
+	// the signing key kept in a secrets manager is only on the disk while
+	// hlf-easy runs the orderer
+	keystorePath, removeKeystore, err := node.FetchSignKey("orderer", ordererID)
+	if err != nil {
+		return err
+	}
+	defer removeKeystore()
+	startOrdererOpts.KeystorePath = keystorePath
+	// the output of the orderer process is written to files so it outlives
+	// hlf-easy, it's followed into the writers
+	output := node.NewOutput(ordererConfigDir)
 	cmdGetter := func() (*exec.Cmd, error) {
-		cmd, err := StartOrdererNodeCommand(
-			stdOut,
-			stdErr,
-			startOrdererOpts)
+		// the logging spec changed at runtime is applied again on restart
+		opts := startOrdererOpts
+		opts.LogSpec = node.GetStartLogSpec(ordererConfigDir)
+		// a port bound by another node or process fails the start with its
+		// owner instead of the orderer exiting on a bind error
+		err := node.CheckPorts(node.KindOrderer, c.ordererOpts.ID, node.OrdererListenAddresses(c.ordererOpts))
+		if err != nil {
+			return nil, err
+		}
+		cmd, err := StartOrdererNodeCommand(opts)
 		if err != nil {
 			log.Warnf("Failed to start orderer node: %v", err)
 			return nil, err
 		}
+		err = output.Redirect(cmd)
+		if err != nil {
+			return nil, err
+		}
 		return cmd, nil
 	}
 
 	ordererNode := node.NewOrdererNode(
 		c.ordererOpts.ID,
 		c.ordererOpts.MSPID,
+		ordererInitOpts.Limits,
 		cmdGetter,
 	)
-	go func() {
-		if err := ordererNode.Start(); err != nil {
-			log.Fatalf("Failed to start orderer node: %v", err)
-		}
-		log.Infof("Orderer node command finished")
-	}()
+	ordererNode.SetHooks(ordererInitOpts.Hooks)
+	// the registry stops the nodes of the process on shutdown
+	manager := node.NewManager()
+	if err := manager.Register(ordererNode); err != nil {
+		return err
+	}
+	// the orderer left running by an hlf-easy process that exited is attached
+	attached, err := ordererNode.Attach()
+	if err != nil {
+		return err
+	}
+	// the failures of the node and of the API end the command once the nodes
+	// are stopped
+	errs := make(chan error, 2)
+	if !attached {
+		go func() {
+			if err := ordererNode.Start(); err != nil {
+				errs <- errors.Wrapf(err, "failed to start orderer node")
+				return
+			}
+			log.Infof("Orderer node command finished")
+		}()
+	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	ctx, stop := proc.NotifyContext(context.Background())
 	defer stop()
+	go output.Follow(ctx, stdOut, stdErr, node.DefaultFollowInterval)
+
+	// sample the status of the node to serve its recent history
+	history := node.NewStatusHistory(node.DefaultHistorySize)
+	go node.SampleStatus(ctx, ordererNode, history, node.DefaultHistoryInterval)
+	go node.WatchLogSpec(ctx, ordererNode, "orderer", ordererID, ordererConfigDir, node.OperationsEndpoint{Address: c.ordererOpts.OperationsListenAddress}, node.DefaultLogSpecInterval)
+
+	// run the maintenance tasks of the node on their schedule
+	scheduler := tasks.NewScheduler(tasks.Node{
+		Kind:    "orderer",
+		ID:      c.ordererOpts.ID,
+		Dir:     ordererConfigDir,
+		MSPID:   c.ordererOpts.MSPID,
+		Address: c.ordererOpts.ExternalEndpoint,
+		Logs:    []*config.SaveOutputWriter{stdOut, stdErr},
+	})
+	go scheduler.Run(ctx, tasks.DefaultCheckInterval)
+
+	// evaluate the alert rules of the host on the node
+	alerting := alerts.NewEngine(alerts.Node{
+		Kind:       "orderer",
+		ID:         c.ordererOpts.ID,
+		Dir:        ordererConfigDir,
+		Process:    ordererNode,
+		Operations: node.OperationsEndpoint{Address: c.ordererOpts.OperationsListenAddress},
+	})
+	go alerting.Run(ctx, alerts.DefaultCheckInterval)
 
 	g, err := api.NewOrdererRouter(
 		ordererNode,
@@ -189,6 +294,10 @@ func (c ordererCmd) run(views embed.FS) error {
 		c.ordererOpts,
 		startOrdererOpts,
 		views,
+		history,
+		scanner,
+		scheduler,
+		alerting,
 	)
 	if err != nil {
 		return err
@@ -201,17 +310,26 @@ func (c ordererCmd) run(views embed.FS) error {
 
 	go func() {
 		// start the admin API server + UI
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("listen: %s\n", err)
+		if err := auth.ListenAndServe(srv, c.ordererOpts.Auth); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- errors.Wrapf(err, "failed to serve the management API")
 		}
 	}()
 
 	// Listen for the interrupt signal.
-	<-ctx.Done()
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case runErr = <-errs:
+	}
 
 	// Restore default behavior on the interrupt signal and notify user of shutdown.
 	stop()
 	log.Infof("shutting down gracefully, press Ctrl+C again to force")
+	// the Fabric process doesn't get the signals sent to hlf-easy alone, nor
+	// the Ctrl+C of the console on Windows
+	if err := manager.StopAll(); err != nil {
+		log.Warnf("Orderer node not stopped: %v", err)
+	}
 
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
@@ -223,7 +341,7 @@ func (c ordererCmd) run(views embed.FS) error {
 
 	log.Infof("Server exiting")
 
-	return nil
+	return runErr
 }
 
 // NewOrdererCommand creates a new 'orderer' Cobra command
@@ -254,6 +372,8 @@ applications, and the 'orderer' command is a part of this application.`,
 	f.StringVar(&c.ordererOpts.OperationsListenAddress, "operations-listen-address", "0.0.0.0:9443", "Operations listen address of the orderer")
 	f.StringVar(&c.ordererOpts.ExternalEndpoint, "external-endpoint", "", "External endpoint of the orderer")
 	f.StringVar(&c.ordererOpts.MSPID, "msp-id", "", "MSP ID of the orderer")
-	f.StringVar(&c.ordererOpts.ManagementAddress, "mgmt-address", "", "Management address of the orderer")
+	f.StringVar(&c.ordererOpts.ManagementAddress, "mgmt-address", "", "Management address of the orderer, the API is only served on its Unix socket if empty")
+	f.StringVar(&c.ordererOpts.Auth.Socket, "mgmt-socket", "", "Unix socket of the management API, only its owner can use it without a token, run/api.sock in the directory of the orderer by default without --mgmt-address")
+	c.ordererOpts.Auth.AddFlags(f)
 	return cmd
 }
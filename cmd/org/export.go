@@ -0,0 +1,70 @@
+package org
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"io"
+	"strings"
+)
+
+type exportCmd struct {
+	opts node.ExportOrgOptions
+}
+
+func (c *exportCmd) validate() error {
+	if c.opts.MSPID == "" {
+		return errors.New("--msp-id is required")
+	}
+	if c.opts.Domain == "" {
+		return errors.New("--domain is required")
+	}
+	if c.opts.OutputDir == "" {
+		return errors.New("--output-dir is required")
+	}
+	return nil
+}
+
+func (c *exportCmd) run(out io.Writer) error {
+	exported, err := node.ExportOrg(c.opts)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, exported, func(out io.Writer) error {
+		fmt.Fprintf(out, "Org %s exported to %s\n", exported.MSPID, exported.Dir)
+		fmt.Fprintf(out, "Peers: %s\n", strings.Join(exported.Peers, ", "))
+		if len(exported.Users) > 0 {
+			fmt.Fprintf(out, "Users: %s\n", strings.Join(exported.Users, ", "))
+		}
+		return nil
+	})
+}
+
+func newExportCommand(out io.Writer) *cobra.Command {
+	c := &exportCmd{}
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the MSP of the org, its peers and identities in the crypto-config layout of cryptogen",
+		Long: `Export the MSP of the org, the MSP and TLS material of the peers of the host
+with the MSP ID and the identities in the crypto-config layout of cryptogen:
+
+  <output-dir>/peerOrganizations/<domain>/{msp,ca,tlsca,peers,users}
+
+The peers and users are named after the domain like cryptogen does. The keys of
+the CAs aren't exported, and the users have no TLS client certificates.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.opts.MSPID, "msp-id", "", "MSP ID of the org")
+	f.StringVar(&c.opts.Domain, "domain", "", "Domain of the org, for example org1.example.com")
+	f.StringVar(&c.opts.OutputDir, "output-dir", "", "crypto-config directory to export the org to")
+	f.StringSliceVar(&c.opts.Identities, "identity", []string{}, "Identity file, as written by ca enroll, to export as a user of the org")
+	return cmd
+}
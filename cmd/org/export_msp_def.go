@@ -0,0 +1,109 @@
+package org
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"hlf-easy/channel"
+	"hlf-easy/node"
+	"hlf-easy/utils"
+	"io"
+	"os"
+)
+
+type exportMSPDefCmd struct {
+	mspID      string
+	adminCerts []string
+	output     string
+}
+
+func (c *exportMSPDefCmd) validate() error {
+	if c.mspID == "" {
+		return errors.New("--msp-id is required")
+	}
+	return nil
+}
+
+func (c *exportMSPDefCmd) run(out io.Writer) error {
+	orgMSP, err := node.GetOrgMSP(c.mspID)
+	if err != nil {
+		return err
+	}
+	var admins []*x509.Certificate
+	for _, adminCert := range c.adminCerts {
+		adminCertBytes, err := os.ReadFile(adminCert)
+		if err != nil {
+			return err
+		}
+		crt, err := utils.ParseX509Certificate(adminCertBytes)
+		if err != nil {
+			return errors.Wrapf(err, "invalid admin certificate %s", adminCert)
+		}
+		admins = append(admins, crt)
+	}
+	group, err := channel.NewOrgDefinition(channel.OrgOptions{
+		MSPID:                orgMSP.MSPID,
+		CACert:               orgMSP.CACert,
+		TLSCACert:            orgMSP.TLSCACert,
+		IntermediateCerts:    orgMSP.IntermediateCerts,
+		TLSIntermediateCerts: orgMSP.TLSIntermediateCerts,
+		ExtraCACerts:         orgMSP.ExtraCACerts,
+		ExtraTLSCACerts:      orgMSP.ExtraTLSCACerts,
+		Admins:               admins,
+	})
+	if err != nil {
+		return err
+	}
+	if c.output == "" {
+		return channel.WriteOrgDefinition(out, group)
+	}
+	var buf bytes.Buffer
+	err = channel.WriteOrgDefinition(&buf, group)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(c.output, buf.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "MSP definition of %s written to %s\n", c.mspID, c.output)
+	return nil
+}
+
+func newExportMSPDefCommand(out io.Writer) *cobra.Command {
+	c := &exportMSPDefCmd{}
+	cmd := &cobra.Command{
+		Use:   "export-msp-def",
+		Short: "Print the MSP definition of the org as JSON, to add it to a channel with a config update",
+		Long: `Print the MSP definition of the org as JSON, like configtxgen -printOrg does:
+its root and intermediate certificates, its TLS root certificates, its NodeOUs,
+its admin certificates and its Readers, Writers, Admins and Endorsement
+policies. It's sent to the other members of the consortium so they add the org
+to the Application group of a channel:
+
+  jq -s '.[0] * {"channel_group":{"groups":{"Application":{"groups":{"Org1MSP":.[1]}}}}}' config.json Org1MSP.json
+
+The CAs are read from the peers of the host with the MSP ID.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.mspID, "msp-id", "", "MSP ID of the org")
+	f.StringSliceVar(&c.adminCerts, "admin-cert", []string{}, "Admin certificate of the org added to its MSP, its admins are already identified by the admin OU")
+	f.StringVarP(&c.output, "output", "o", "", "Output file for the MSP definition, printed if empty")
+	// --mspid is the name configtxgen users know the flag by
+	f.SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "mspid" {
+			name = "msp-id"
+		}
+		return pflag.NormalizedName(name)
+	})
+	return cmd
+}
@@ -0,0 +1,210 @@
+package org
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/certs"
+	"hlf-easy/config"
+	"hlf-easy/invite"
+	"hlf-easy/utils"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+type invitePeerOptions struct {
+	Identity  string
+	MSPID     string
+	PeerID    string
+	CAURL     string
+	CAName    string
+	TLSCAName string
+	CATLSCert string
+	// RegistrarID and RegistrarSecret register the peer in the CA, otherwise
+	// EnrollID and EnrollSecret must be of an identity already registered
+	RegistrarID     string
+	RegistrarSecret string
+	EnrollID        string
+	EnrollSecret    string
+	GossipBootstrap []string
+	ExternalPort    int
+	ExpiresIn       time.Duration
+	Output          string
+	CertPolicy      config.CertificatePolicy
+}
+
+type invitePeerCmd struct {
+	opts invitePeerOptions
+}
+
+func (c *invitePeerCmd) validate() error {
+	if c.opts.Identity == "" {
+		return errors.New("--identity is required")
+	}
+	if c.opts.MSPID == "" {
+		return errors.New("--msp-id is required")
+	}
+	if c.opts.PeerID == "" {
+		return errors.New("--peer-id is required")
+	}
+	if c.opts.CAURL == "" {
+		return errors.New("--ca-url is required")
+	}
+	if c.opts.CATLSCert == "" {
+		return errors.New("--ca-tls-cert is required")
+	}
+	if c.opts.RegistrarID == "" && c.opts.EnrollSecret == "" {
+		return errors.New("either --registrar-id or --enroll-secret is required")
+	}
+	if c.opts.RegistrarID != "" && c.opts.RegistrarSecret == "" {
+		return errors.New("--registrar-secret is required")
+	}
+	if c.opts.ExternalPort <= 0 || c.opts.ExternalPort > 65535 {
+		return errors.Errorf("invalid --external-port %d", c.opts.ExternalPort)
+	}
+	if c.opts.ExpiresIn <= 0 {
+		return errors.New("--expires-in must be greater than 0")
+	}
+	for _, endpoint := range c.opts.GossipBootstrap {
+		if _, _, err := net.SplitHostPort(endpoint); err != nil {
+			return errors.Wrapf(err, "invalid gossip bootstrap endpoint %s", endpoint)
+		}
+	}
+	return certs.ValidateCertificatePolicy(c.opts.CertPolicy)
+}
+
+func (c *invitePeerCmd) run(out io.Writer, errOut io.Writer) error {
+	signerCert, signerKey, err := utils.ReadIdentity(c.opts.Identity)
+	if err != nil {
+		return err
+	}
+	caTLSCertBytes, err := os.ReadFile(c.opts.CATLSCert)
+	if err != nil {
+		return err
+	}
+	enrollID := c.opts.EnrollID
+	if enrollID == "" {
+		enrollID = c.opts.PeerID
+	}
+	enrollSecret := c.opts.EnrollSecret
+	if c.opts.RegistrarID != "" {
+		enrollSecret, err = c.register(string(caTLSCertBytes), enrollID)
+		if err != nil {
+			return err
+		}
+	}
+	token, err := invite.Sign(invite.Invite{
+		MSPID:           c.opts.MSPID,
+		PeerID:          c.opts.PeerID,
+		CAURL:           c.opts.CAURL,
+		CAName:          c.opts.CAName,
+		TLSCAName:       c.opts.TLSCAName,
+		CATLSCert:       string(caTLSCertBytes),
+		EnrollID:        enrollID,
+		EnrollSecret:    enrollSecret,
+		CertPolicy:      c.opts.CertPolicy,
+		GossipBootstrap: c.opts.GossipBootstrap,
+		ExternalPort:    c.opts.ExternalPort,
+		ExpiresAt:       time.Now().Add(c.opts.ExpiresIn).UTC(),
+	}, signerCert, signerKey)
+	if err != nil {
+		return err
+	}
+	caTLSCert, err := utils.ParseX509Certificate(caTLSCertBytes)
+	if err != nil {
+		return err
+	}
+	// the fingerprint is shared out of band so the peer can pin the CA
+	fmt.Fprintf(errOut, "CA TLS certificate fingerprint: %s\n", invite.Fingerprint(caTLSCert))
+	if c.opts.Output != "" {
+		// the token contains the enroll secret
+		return os.WriteFile(c.opts.Output, []byte(token), 0600)
+	}
+	fmt.Fprintln(out, token)
+	return nil
+}
+
+// register registers the peer in the CA and in the TLS CA, the secret in the
+// invite can only be used for the enrollments of the sign and TLS
+// certificates of the peer, so a leaked invite can't enroll other peers once
+// it's been used
+func (c *invitePeerCmd) register(caTLSCert string, enrollID string) (string, error) {
+	tlsCAName := c.opts.TLSCAName
+	if tlsCAName == "" {
+		tlsCAName = c.opts.CAName
+	}
+	caNames := []string{c.opts.CAName}
+	if tlsCAName != c.opts.CAName {
+		caNames = append(caNames, tlsCAName)
+	}
+	secret := c.opts.EnrollSecret
+	for _, caName := range caNames {
+		// a single CA enrolls both certificates
+		maxEnrollments := 1
+		if len(caNames) == 1 {
+			maxEnrollments = 2
+		}
+		registeredSecret, err := certs.RegisterUser(certs.RegisterUserRequest{
+			TLSCert:        caTLSCert,
+			URL:            c.opts.CAURL,
+			Name:           caName,
+			MSPID:          c.opts.MSPID,
+			EnrollID:       c.opts.RegistrarID,
+			EnrollSecret:   c.opts.RegistrarSecret,
+			User:           enrollID,
+			Secret:         secret,
+			Type:           "peer",
+			MaxEnrollments: maxEnrollments,
+		})
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to register peer %s in CA %s", enrollID, caName)
+		}
+		// the TLS CA is registered with the secret generated by the CA
+		secret = registeredSecret
+		log.Infof("Registered peer %s in CA %s of %s", enrollID, caName, c.opts.CAURL)
+	}
+	return secret, nil
+}
+
+func newInvitePeerCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &invitePeerCmd{}
+	cmd := &cobra.Command{
+		Use:   "invite-peer",
+		Short: "Create a signed invite for a peer on another machine to join the org",
+		Long: `Create a signed invite for a peer on another machine to join the org, the
+invite has the CA to enroll with, the certificate policy and the gossip
+bootstrap endpoints. With --registrar-id the peer is registered in the CA and
+in the TLS CA with a secret that can only enroll its sign and TLS certificates.
+On the other machine run:
+
+  hlf-easy peer init --invite <token> --invite-fingerprint <fingerprint> --hosts <host>`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.opts.Identity, "identity", "", "Admin identity of the org that signs the invite")
+	f.StringVar(&c.opts.MSPID, "msp-id", "", "MSP ID of the org")
+	f.StringVar(&c.opts.PeerID, "peer-id", "", "ID of the invited peer")
+	f.StringVar(&c.opts.CAURL, "ca-url", "", "URL of the Fabric CA the peer enrolls with")
+	f.StringVar(&c.opts.CAName, "ca-name", "", "Name of the Fabric CA the peer enrolls with")
+	f.StringVar(&c.opts.TLSCAName, "tls-ca-name", "", "Name of the Fabric CA that issues the TLS certificate of the peer, defaults to --ca-name")
+	f.StringVar(&c.opts.CATLSCert, "ca-tls-cert", "", "Path to the TLS certificate of the Fabric CA")
+	f.StringVar(&c.opts.RegistrarID, "registrar-id", "", "Registrar used to register the peer in the CA")
+	f.StringVar(&c.opts.RegistrarSecret, "registrar-secret", "", "Secret of the registrar")
+	f.StringVar(&c.opts.EnrollID, "enroll-id", "", "Enroll ID of the peer, defaults to --peer-id")
+	f.StringVar(&c.opts.EnrollSecret, "enroll-secret", "", "Enroll secret of the peer, generated by the CA when registering if empty")
+	f.StringSliceVar(&c.opts.GossipBootstrap, "gossip-bootstrap", []string{}, "Endpoints of the peers of the org, the format is <host>:<port>")
+	f.IntVar(&c.opts.ExternalPort, "external-port", 7051, "Port of the external endpoint of the peer")
+	f.DurationVar(&c.opts.ExpiresIn, "expires-in", 24*time.Hour, "How long the invite is valid")
+	f.StringVarP(&c.opts.Output, "output", "o", "", "Output file for the invite, printed if empty")
+	c.opts.CertPolicy.AddFlags(f)
+	c.opts.CertPolicy.AddSANFlags(f)
+	return cmd
+}
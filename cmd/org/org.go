@@ -0,0 +1,19 @@
+package org
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewOrgCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "org",
+		Short: "Manage the organization and invite nodes to it",
+	}
+	cmd.AddCommand(
+		newInvitePeerCommand(out, errOut),
+		newExportCommand(out),
+		newExportMSPDefCommand(out),
+	)
+	return cmd
+}
@@ -2,6 +2,7 @@ package anchorpeers
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/Masterminds/sprig/v3"
 	"github.com/cloudflare/cfssl/log"
@@ -16,11 +17,13 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
+	"hlf-easy/ordering"
 	"hlf-easy/utils"
 	"net"
 	"os"
 	"strconv"
 	"text/template"
+	"time"
 )
 
 const tmplGoConfig = `
@@ -232,7 +235,7 @@ type anchorPeersSetOptions struct {
 	Identity       string
 	PeerID         string
 	AnchorPeers    []string
-	OrdererURL     string
+	OrdererURLs    []string
 	OrdererTLSCert string
 }
 type anchorPeersSetCmd struct {
@@ -247,7 +250,7 @@ func (c *anchorPeersSetCmd) validate() error {
 	if c.peerOpts.PeerID == "" {
 		return errors.Errorf("--peer-id is required")
 	}
-	if c.peerOpts.OrdererURL == "" {
+	if len(c.peerOpts.OrdererURLs) == 0 {
 		return errors.Errorf("--orderer-url is required")
 	}
 	if c.peerOpts.OrdererTLSCert == "" {
@@ -268,13 +271,37 @@ type Pem struct {
 	Pem string
 }
 
+// selectOrderer returns the URL of the closest healthy orderer of the flags
+func (c *anchorPeersSetCmd) selectOrderer(tlsCACert string) (string, error) {
+	if len(c.peerOpts.OrdererURLs) == 1 {
+		return c.peerOpts.OrdererURLs[0], nil
+	}
+	endpoints := []ordering.Endpoint{}
+	urls := map[string]string{}
+	for _, url := range c.peerOpts.OrdererURLs {
+		address := ordering.EndpointAddress(url)
+		endpoints = append(endpoints, ordering.Endpoint{Address: address, TLSCACerts: []string{tlsCACert}})
+		urls[address] = url
+	}
+	ranked, err := ordering.Select(context.Background(), endpoints)
+	if err != nil {
+		return "", err
+	}
+	log.Infof("Using orderer %s, RTT %s", ranked[0].Address, ranked[0].RTT)
+	return urls[ranked[0].Address], nil
+}
+
 func (c *anchorPeersSetCmd) run() error {
 	ordererTLSCertBytes, err := os.ReadFile(c.peerOpts.OrdererTLSCert)
 	if err != nil {
 		return err
 	}
+	ordererURL, err := c.selectOrderer(string(ordererTLSCertBytes))
+	if err != nil {
+		return err
+	}
 	orderer := &Orderer{
-		URL:       c.peerOpts.OrdererURL,
+		URL:       ordererURL,
 		Name:      "orderer",
 		TLSCACert: string(ordererTLSCertBytes),
 	}
@@ -294,6 +321,15 @@ func (c *anchorPeersSetCmd) run() error {
 		TLSCACert: string(tlsCertBytes),
 	}
 	mspID := runConfig.Options.MSPID
+	// refuse identities of other orgs before Fabric does it with ACCESS_DENIED
+	identityCert, _, err := utils.ReadIdentity(c.peerOpts.Identity)
+	if err != nil {
+		return err
+	}
+	err = utils.VerifyMSPAdmin(identityCert, mspID, peerConfig.CaCert, peerConfig.IntermediateCerts, time.Now())
+	if err != nil {
+		return err
+	}
 	identityBytes, err := os.ReadFile(c.peerOpts.Identity)
 	if err != nil {
 		return err
@@ -445,7 +481,7 @@ func newAnchorPeersSetCommand() *cobra.Command {
 	}
 	f := cmd.Flags()
 	f.StringVar(&c.peerOpts.PeerID, "id", "", "ID of the peer to join")
-	f.StringVar(&c.peerOpts.OrdererURL, "orderer-url", "", "URL of the orderer to join")
+	f.StringSliceVar(&c.peerOpts.OrdererURLs, "orderer-url", []string{}, "URLs of the orderers, the closest healthy one is used")
 	f.StringVar(&c.peerOpts.OrdererTLSCert, "orderer-tls-cert", "", "TLS certificate of the orderer to join")
 	f.StringVar(&c.peerOpts.ChannelName, "channel", "", "Name of the channel to join")
 	f.StringVar(&c.peerOpts.Identity, "identity", "", "Identity to use to join the channel")
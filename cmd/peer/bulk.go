@@ -0,0 +1,86 @@
+package peer
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"hlf-easy/bulk"
+	"hlf-easy/output"
+	"io"
+	"time"
+)
+
+// bulkFlags are the flags of the commands that operate on one or all the
+// peers through their management APIs
+type bulkFlags struct {
+	id       string
+	all      bool
+	parallel int
+	token    string
+}
+
+func (b *bulkFlags) addFlags(f *pflag.FlagSet) {
+	f.BoolVar(&b.all, "all", false, "Operate on all the peers of the host")
+	f.IntVar(&b.parallel, "parallel", bulk.DefaultParallelism, "Number of peers operated on at the same time with --all")
+	f.StringVar(&b.token, "token", "", "API token of the management APIs of the peers")
+}
+
+func (b *bulkFlags) validate() error {
+	if b.all == (b.id != "") {
+		return errors.New("either --id or --all is required")
+	}
+	if b.parallel < 1 {
+		return errors.New("--parallel must be at least 1")
+	}
+	return nil
+}
+
+func printResults(out io.Writer, results []bulk.Result) error {
+	return output.Print(out, results, func(out io.Writer) error {
+		return printResultsTable(out, results)
+	})
+}
+
+func printResultsTable(out io.Writer, results []bulk.Result) error {
+	w := output.NewTabWriter(out)
+	fmt.Fprintln(w, "ID\tACTION\tRESULT\tSTATUS\tPID\tUPTIME\tERROR")
+	for _, r := range results {
+		result := "ok"
+		if !r.OK {
+			result = "failed"
+		}
+		pid := ""
+		if r.PID != 0 {
+			pid = fmt.Sprint(r.PID)
+		}
+		uptime := ""
+		if r.Uptime > 0 {
+			uptime = (time.Duration(r.Uptime) * time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.Action, result, r.Status, pid, uptime, r.Error)
+	}
+	return w.Flush()
+}
+
+// run runs the action on the peers and fails when it failed on one of them
+func (b *bulkFlags) run(out io.Writer, action string) error {
+	ids := []string{b.id}
+	if b.all {
+		var err error
+		ids, err = bulk.ListNodeIDs("peer")
+		if err != nil {
+			return err
+		}
+	}
+	results := bulk.Run(ids, b.parallel, func(id string) bulk.Result {
+		return bulk.Operate("peer", id, action, b.token)
+	})
+	err := printResults(out, results)
+	if err != nil {
+		return err
+	}
+	if failed := bulk.Failed(results); failed > 0 {
+		return errors.Errorf("%s failed on %d of %d peers", action, failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/channel"
+	"hlf-easy/contract"
+	"hlf-easy/node"
+	"hlf-easy/notify"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"time"
+)
+
+type peerCloneCmd struct {
+	opts    node.ClonePeerOptions
+	timeout time.Duration
+}
+
+func (c *peerCloneCmd) validate() error {
+	if c.opts.SourceID == c.opts.ID {
+		return errors.New("the new peer needs an ID of its own")
+	}
+	if c.opts.ExternalPort < 0 {
+		return errors.New("--external-port can't be negative")
+	}
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	return nil
+}
+
+func (c *peerCloneCmd) run(out io.Writer) error {
+	// an interrupt or --timeout stops waiting for the lock of the new peer
+	// and for the Fabric CA
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	clone, err := node.ClonePeer(ctx, c.opts)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, clone, func(out io.Writer) error {
+		fmt.Fprintf(out, "Peer %s cloned from peer %s\n", c.opts.ID, c.opts.SourceID)
+		if len(clone.Joins) == 0 {
+			fmt.Fprintf(out, "Peer %s joined no channel\n", c.opts.SourceID)
+		} else {
+			w := output.NewTabWriter(out)
+			fmt.Fprintln(w, "CHANNEL\tFROM")
+			for _, join := range clone.Joins {
+				from := "genesis block"
+				if join.Snapshot != "" {
+					from = fmt.Sprintf("snapshot at height %d", join.Height)
+				}
+				fmt.Fprintf(w, "%s\t%s\n", join.Channel, from)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(out, "Start it with peer start --id=%s on ports of its own, it joins the channels once it's ready\n", c.opts.ID)
+		return err
+	})
+}
+
+// joinClonedChannels joins a cloned peer, once it's ready, to the channels of
+// its source it didn't join yet. The channels that fail are joined on its
+// next start
+func joinClonedChannels(peerID string) {
+	clone, err := node.GetPeerClone(peerID)
+	if err != nil {
+		log.Warnf("Failed to read the channels peer %s was cloned with: %v", peerID, err)
+		return
+	}
+	if clone == nil {
+		return
+	}
+	ctx, stop := context.WithTimeout(context.Background(), channel.DefaultJoinTimeout)
+	defer stop()
+	opts := contract.Options{PeerID: peerID, Identity: clone.Identity}
+	joined := 0
+	for _, join := range clone.Joins {
+		if join.Snapshot != "" {
+			log.Infof("Joining channel %s from the snapshot of peer %s at height %d", join.Channel, clone.Source, join.Height)
+			err = contract.JoinChannelBySnapshot(ctx, opts, join.Snapshot)
+		} else {
+			log.Infof("Joining channel %s with its genesis block", join.Channel)
+			var genesisBlock *common.Block
+			genesisBlock, err = node.ReadCloneGenesisBlock(join.GenesisBlock)
+			if err == nil {
+				err = contract.JoinChannel(ctx, opts, genesisBlock)
+			}
+		}
+		if err != nil {
+			log.Warnf("Peer %s didn't join channel %s, it's tried again on its next start: %v", peerID, join.Channel, err)
+			continue
+		}
+		joined++
+		log.Infof("Channel joined: %v", join.Channel)
+		event := notify.NewEvent(notify.EventChannelJoined, "peer", peerID, fmt.Sprintf("Peer %s joined channel %s", peerID, join.Channel))
+		event.Details = map[string]string{"channel": join.Channel, "clonedFrom": clone.Source}
+		notify.Notify(event)
+	}
+	if joined == len(clone.Joins) {
+		if err := node.CompletePeerClone(peerID); err != nil {
+			log.Warnf("Failed to complete the clone of peer %s: %v", peerID, err)
+		}
+	}
+}
+
+func newPeerCloneCommand(out io.Writer) *cobra.Command {
+	c := &peerCloneCmd{}
+	cmd := &cobra.Command{
+		Use:   "clone <src-id> <new-id>",
+		Short: "Initialize a new peer with the settings of a peer of the host, it joins the channels of the source once it's started",
+		Long: `Initialize a new peer with the settings of a peer of the host to scale an org
+horizontally. The certificates of the new peer are enrolled with the local CA
+or the Fabric CA of the source, with --enroll-id and --enroll-secret for a
+Fabric CA, and its settings are copied with the endpoints of its own.
+
+The channels of the source are joined by the new peer once it's started and
+ready, with their genesis blocks read from the ledger of the source, or with
+--from-snapshot from the last completed snapshots of the source so the new
+peer doesn't replay the blocks.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.opts.SourceID = args[0]
+			c.opts.ID = args[1]
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringSliceVar(&c.opts.Hosts, "hosts", nil, "Hosts of the new peer, the hosts of the source by default")
+	f.IntVar(&c.opts.ExternalPort, "external-port", 0, "Port of the external endpoint of the new peer, the first host is used as its address")
+	f.StringVar(&c.opts.ExternalEndpoint, "external-endpoint", "", "External endpoint of the new peer, built with the first host and --external-port by default")
+	f.StringVar(&c.opts.OperationsListenAddress, "operations-listen-address", "", "Address the operations endpoint of the new peer binds to, required when the source sets one")
+	f.StringVar(&c.opts.EnrollID, "enroll-id", "", "Enroll ID of the new peer with the Fabric CA of the source")
+	f.StringVar(&c.opts.EnrollSecret, "enroll-secret", "", "Enroll secret of the new peer with the Fabric CA of the source")
+	f.BoolVar(&c.opts.FromSnapshot, "from-snapshot", false, "Join the channels from the last completed snapshots of the source instead of their genesis blocks")
+	f.StringVar(&c.opts.Identity, "identity", "", "Identity file of an admin of the org joining the channels, the admin identity managed for the new peer if empty")
+	f.DurationVar(&c.timeout, "timeout", node.DefaultInitTimeout, "How long to wait for the lock of the new peer and the Fabric CA, 0 waits without a limit")
+	c.opts.Env.AddFlags(f)
+	return cmd
+}
@@ -0,0 +1,18 @@
+package csr
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewCSRCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "csr",
+		Short: "Enroll a peer against an external CA using CSRs",
+	}
+	cmd.AddCommand(
+		newCSRGenerateCommand(out, errOut),
+		newCSRImportCommand(),
+	)
+	return cmd
+}
@@ -0,0 +1,91 @@
+package csr
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/certs"
+	"hlf-easy/config"
+	"hlf-easy/node"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type csrGenerateCmd struct {
+	peerOpts config.PeerInitOptions
+	Output   string
+	Force    bool
+}
+
+func (c *csrGenerateCmd) validate() error {
+	if c.peerOpts.ID == "" {
+		return errors.Errorf("--id is required")
+	}
+	if len(c.peerOpts.Hosts) == 0 {
+		return errors.Errorf("--hosts is required")
+	}
+	if err := node.ValidateTLSPolicy(c.peerOpts.TLSPolicy); err != nil {
+		return err
+	}
+	return certs.ValidateCertificatePolicy(c.peerOpts.CertPolicy)
+}
+
+func (c *csrGenerateCmd) run(out io.Writer) error {
+	csrs, err := node.GeneratePeerCSRs(c.peerOpts, c.Force)
+	if err != nil {
+		return err
+	}
+	if c.Output != "" {
+		// export the CSRs to a directory so they can be handed to the CA operators
+		err = os.MkdirAll(c.Output, 0755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(c.Output, c.peerOpts.ID+"-tls.csr"), csrs.TLSCSR, 0644)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(c.Output, c.peerOpts.ID+"-sign.csr"), csrs.SignCSR, 0644)
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(map[string]interface{}{
+		"tlsCSR":  string(csrs.TLSCSR),
+		"signCSR": string(csrs.SignCSR),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, &buf)
+	return err
+}
+
+func newCSRGenerateCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &csrGenerateCmd{}
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate the keys and the TLS/sign CSRs of a peer",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.peerOpts.ID, "id", "", "ID of the peer")
+	f.StringSliceVar(&c.peerOpts.Hosts, "hosts", []string{}, "Hosts to include in the TLS CSR")
+	f.StringVarP(&c.Output, "output", "o", "", "Directory to export the CSRs to, if empty they are printed")
+	c.peerOpts.CertPolicy.AddSANFlags(f)
+	c.peerOpts.Resources.AddFlags(f)
+	c.peerOpts.TLSPolicy.AddFlags(f)
+	f.BoolVar(&c.Force, "force", false, "Overwrite an existing peer or pending CSRs")
+	return cmd
+}
@@ -0,0 +1,92 @@
+package csr
+
+import (
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/node"
+	"os"
+)
+
+type csrImportOptions struct {
+	ID         string
+	TLSCert    string
+	SignCert   string
+	CAChain    string
+	TLSCAChain string
+}
+
+type csrImportCmd struct {
+	opts csrImportOptions
+}
+
+func (c *csrImportCmd) validate() error {
+	if c.opts.ID == "" {
+		return errors.Errorf("--id is required")
+	}
+	if c.opts.TLSCert == "" {
+		return errors.Errorf("--tls-cert is required")
+	}
+	if c.opts.SignCert == "" {
+		return errors.Errorf("--sign-cert is required")
+	}
+	if c.opts.CAChain == "" {
+		return errors.Errorf("--ca-chain is required")
+	}
+	if c.opts.TLSCAChain == "" {
+		return errors.Errorf("--tls-ca-chain is required")
+	}
+	return nil
+}
+
+func (c *csrImportCmd) run() error {
+	tlsCert, err := os.ReadFile(c.opts.TLSCert)
+	if err != nil {
+		return err
+	}
+	signCert, err := os.ReadFile(c.opts.SignCert)
+	if err != nil {
+		return err
+	}
+	caChain, err := os.ReadFile(c.opts.CAChain)
+	if err != nil {
+		return err
+	}
+	tlsCAChain, err := os.ReadFile(c.opts.TLSCAChain)
+	if err != nil {
+		return err
+	}
+	err = node.ImportPeerCertificates(node.ImportPeerCertificatesOptions{
+		ID:         c.opts.ID,
+		TLSCert:    tlsCert,
+		SignCert:   signCert,
+		CAChain:    caChain,
+		TLSCAChain: tlsCAChain,
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("Certificates imported for peer %s", c.opts.ID)
+	return nil
+}
+
+func newCSRImportCommand() *cobra.Command {
+	c := &csrImportCmd{}
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import the certificates signed by the external CA into the peer MSP",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run()
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.opts.ID, "id", "", "ID of the peer")
+	f.StringVar(&c.opts.TLSCert, "tls-cert", "", "Path to the signed TLS certificate")
+	f.StringVar(&c.opts.SignCert, "sign-cert", "", "Path to the signed sign certificate")
+	f.StringVar(&c.opts.CAChain, "ca-chain", "", "Path to the CA chain of the sign certificate, root last")
+	f.StringVar(&c.opts.TLSCAChain, "tls-ca-chain", "", "Path to the CA chain of the TLS certificate, root last")
+	return cmd
+}
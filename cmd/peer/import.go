@@ -0,0 +1,81 @@
+package peer
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/limits"
+	"hlf-easy/node"
+)
+
+type peerImportCmd struct {
+	opts node.ImportPeerMSPOptions
+}
+
+func (c *peerImportCmd) validate() error {
+	if c.opts.InitOptions.ID == "" {
+		return fmt.Errorf("--id is required")
+	}
+	if c.opts.MSPDir == "" {
+		return fmt.Errorf("--msp-dir is required")
+	}
+	if err := limits.Validate(c.opts.InitOptions.Limits); err != nil {
+		return err
+	}
+	if err := node.ValidateGossipState(c.opts.InitOptions.GossipState); err != nil {
+		return err
+	}
+	if err := node.ValidateGateway(c.opts.InitOptions.Gateway); err != nil {
+		return err
+	}
+	if err := node.ValidateTLSPolicy(c.opts.InitOptions.TLSPolicy); err != nil {
+		return err
+	}
+	return node.ValidateOperations(c.opts.InitOptions.Operations)
+}
+
+func (c *peerImportCmd) run() error {
+	err := node.ImportPeerMSP(c.opts)
+	if err != nil {
+		return err
+	}
+	log.Infof("Peer %s imported, check it with hlf-easy peer validate %s and start it with hlf-easy peer start --id=%s", c.opts.InitOptions.ID, c.opts.InitOptions.ID, c.opts.InitOptions.ID)
+	return nil
+}
+
+func newPeerImportCommand() *cobra.Command {
+	c := &peerImportCmd{}
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import the MSP of a peer created by cryptogen or fabric-ca-client",
+		Long: `Import the MSP and TLS material of an existing peer so it's managed by
+hlf-easy. The MSP directory has the signcerts, keystore and cacerts of the peer.
+The TLS directory is the tls directory of cryptogen, with server.crt, server.key
+and ca.crt, or the MSP enrolled with the tls profile of fabric-ca-client, it
+defaults to the tls directory next to the MSP directory. The certificates must
+match their keys and chain to the CAs, and the sign certificate must have the
+peer OU of the NodeOUs. The ledger of the peer isn't imported.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run()
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.opts.InitOptions.ID, "id", "", "ID of the peer")
+	f.StringVar(&c.opts.MSPDir, "msp-dir", "", "MSP directory of the peer")
+	f.StringVar(&c.opts.TLSDir, "tls-dir", "", "TLS directory of the peer, defaults to the tls directory next to --msp-dir")
+	f.StringSliceVar(&c.opts.InitOptions.Hosts, "hosts", []string{}, "Hosts of the peer, they must be in the TLS certificate, defaults to its SANs")
+	f.StringVar(&c.opts.InitOptions.MSPID, "msp-id", "", "MSP ID of the peer, the gossip of the peers of the same MSP ID is wired automatically")
+	f.IntVar(&c.opts.InitOptions.ExternalPort, "external-port", 7051, "Port of the external endpoint of the peer, the first host is used as its address")
+	f.BoolVar(&c.opts.Force, "force", false, "Overwrite an existing peer")
+	c.opts.InitOptions.Resources.AddFlags(f)
+	c.opts.InitOptions.Limits.AddFlags(f)
+	c.opts.InitOptions.GossipState.AddFlags(f)
+	c.opts.InitOptions.Gateway.AddFlags(f)
+	c.opts.InitOptions.Operations.AddFlags(f)
+	c.opts.InitOptions.VM.AddFlags(f)
+	c.opts.InitOptions.TLSPolicy.AddFlags(f)
+	return cmd
+}
@@ -1,51 +1,230 @@
 package peer
 
 import (
+	"context"
 	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"hlf-easy/certs"
 	"hlf-easy/config"
+	"hlf-easy/invite"
 	"hlf-easy/node"
+	"hlf-easy/output"
+	"hlf-easy/plan"
+	"hlf-easy/proc"
+	"hlf-easy/publicip"
+	"hlf-easy/utils"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type peerInitCmd struct {
 	peerOpts config.PeerInitOptions
+	// invite is the token, or the path to the file with the token, created by org invite-peer
+	invite            string
+	inviteFingerprint string
+	dryRun            bool
+	timeout           time.Duration
+	// detectPublicIP adds the public IP of the host, asked to publicIPSource,
+	// as the first host of the peer
+	detectPublicIP bool
+	publicIPSource string
 }
 
 func (c peerInitCmd) validate() error {
-	if c.peerOpts.ID == "" {
-		return fmt.Errorf("--id is required")
+	if c.timeout < 0 {
+		return fmt.Errorf("--timeout can't be negative")
+	}
+	if c.detectPublicIP {
+		if err := publicip.ValidateSource(c.publicIPSource); err != nil {
+			return err
+		}
+	}
+	if c.invite == "" {
+		return node.ValidatePeerInitOptions(c.peerOpts)
+	}
+	if err := node.ValidatePeerSettings(c.peerOpts); err != nil {
+		return err
 	}
 	if c.peerOpts.Local {
-		if c.peerOpts.CAName == "" {
-			return fmt.Errorf("--ca-name is required")
+		return fmt.Errorf("--invite can't be used with --local")
+	}
+	if c.inviteFingerprint == "" {
+		return fmt.Errorf("--invite-fingerprint is required with --invite, it's the fingerprint printed by org invite-peer")
+	}
+	return certs.ValidateCertificatePolicy(c.peerOpts.CertPolicy)
+}
+
+func (c peerInitCmd) run(out io.Writer) error {
+	var w plan.Writer = plan.Disk
+	p := plan.New()
+	if c.dryRun {
+		w = p
+	}
+	if c.detectPublicIP {
+		err := c.applyPublicIP()
+		if err != nil {
+			return err
 		}
-	} else {
-		// validate that the options are not empty
-		if c.peerOpts.CAUrl == "" {
-			return fmt.Errorf("--ca-url is required")
+	}
+	if c.invite != "" {
+		err := c.applyInvite(w)
+		if err != nil {
+			return err
 		}
-		if !c.peerOpts.CAInsecure && c.peerOpts.CACert == "" {
-			return fmt.Errorf("--ca-cert is required")
+	}
+	// in the net chaincode mode the peer builds and runs its chaincodes with
+	// the docker daemon, it must answer before the peer is written and the
+	// chaincode images of its fabric version must exist for its architecture
+	if c.peerOpts.VM.Endpoint != "" && !c.peerOpts.DevMode {
+		ctx, cancel := context.WithTimeout(context.Background(), node.DefaultVMCheckTimeout)
+		err := node.CheckDocker(ctx, c.peerOpts.VM)
+		if err == nil {
+			err = node.CheckDockerArch(ctx, c.peerOpts)
 		}
-		if c.peerOpts.EnrollID == "" {
-			return fmt.Errorf("--enroll-id is required")
+		cancel()
+		if err != nil {
+			return err
 		}
-		if c.peerOpts.EnrollSecret == "" {
-			return fmt.Errorf("--enroll-secret is required")
+	}
+	if c.dryRun {
+		err := node.PlanPeerInit(p, c.peerOpts)
+		if err != nil {
+			return err
 		}
+		return output.Print(out, p.Changes, func(out io.Writer) error {
+			err := p.Print(out)
+			fmt.Fprintf(out, "Nothing was written and no process is started, the peer is started by peer start --id=%s\n", c.peerOpts.ID)
+			return err
+		})
 	}
+	// an interrupt or --timeout stops waiting for the lock of the node and
+	// for the Fabric CA
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	err := node.EnrollPeerCertificates(ctx, c.peerOpts)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// applyPublicIP detects the public IP of the host and makes it the first host
+// of the peer, its external endpoint is built with it unless it's set and it's
+// in the SANs of the TLS certificate
+func (c *peerInitCmd) applyPublicIP() error {
+	ctx, cancel := context.WithTimeout(context.Background(), publicip.DefaultTimeout)
+	defer cancel()
+	ip, err := publicip.Detect(ctx, c.publicIPSource)
+	if err != nil {
+		return errors.Wrap(err, "failed to detect the public IP, set it with --hosts")
+	}
+	hosts := []string{ip.String()}
+	for _, host := range c.peerOpts.Hosts {
+		if host != ip.String() {
+			hosts = append(hosts, host)
+		}
+	}
+	c.peerOpts.Hosts = hosts
+	log.Infof("Detected the public IP %s with %s", ip, c.publicIPSource)
 	return nil
 }
 
-func (c peerInitCmd) run() error {
-	err := node.EnrollPeerCertificates(c.peerOpts)
+// applyInvite verifies the invite and fills the init options of the peer with it
+func (c *peerInitCmd) applyInvite(w plan.Writer) error {
+	token := c.invite
+	if tokenBytes, err := os.ReadFile(c.invite); err == nil {
+		token = string(tokenBytes)
+	}
+	inv, signerCert, err := invite.Parse(token, time.Now())
+	if err != nil {
+		return err
+	}
+	caTLSCert, err := utils.ParseX509Certificate([]byte(inv.CATLSCert))
+	if err != nil {
+		return errors.Wrap(err, "invalid CA TLS certificate in the invite")
+	}
+	// the CA is pinned before it's trusted to tell who the admins are
+	err = invite.VerifyCAFingerprint(caTLSCert, c.inviteFingerprint)
+	if err != nil {
+		return err
+	}
+	caInfo, err := certs.GetCAInfo(certs.GetCAInfoRequest{
+		TLSCert: inv.CATLSCert,
+		URL:     inv.CAURL,
+		Name:    inv.CAName,
+		MSPID:   inv.MSPID,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the info of CA %s", inv.CAURL)
+	}
+	caCert, err := utils.ParseX509Certificate(caInfo.CAChain)
+	if err != nil {
+		return err
+	}
+	err = invite.VerifySigner(signerCert, caCert)
+	if err != nil {
+		return err
+	}
+
+	if c.peerOpts.ID == "" {
+		c.peerOpts.ID = inv.PeerID
+	}
+	if c.peerOpts.ID == "" {
+		return fmt.Errorf("--id is required, the invite doesn't set the ID of the peer")
+	}
+	if len(c.peerOpts.Hosts) == 0 {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return errors.Wrap(err, "--hosts is required, failed to get the hostname")
+		}
+		c.peerOpts.Hosts = []string{hostname}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	peerDir := filepath.Join(home, "hlf-easy", "peers", c.peerOpts.ID)
+	err = w.MkdirAll(peerDir, 0755)
 	if err != nil {
 		return err
 	}
+	caTLSCertPath := filepath.Join(peerDir, "ca-tls.crt")
+	err = w.WriteFile(caTLSCertPath, utils.EncodeX509Certificate(caTLSCert), 0644)
+	if err != nil {
+		return err
+	}
+	c.peerOpts.CAUrl = inv.CAURL
+	c.peerOpts.CAName = inv.CAName
+	c.peerOpts.TLSCAName = inv.TLSCAName
+	c.peerOpts.CACert = caTLSCertPath
+	c.peerOpts.EnrollID = inv.EnrollID
+	c.peerOpts.EnrollSecret = inv.EnrollSecret
+	c.peerOpts.MSPID = inv.MSPID
+	c.peerOpts.CertPolicy = inv.CertPolicy.Merge(c.peerOpts.CertPolicy)
+	c.peerOpts.ExternalPort = inv.ExternalPort
+	// a wildcard host can't be advertised, the first other host is used
+	for _, host := range c.peerOpts.Hosts {
+		if c.peerOpts.ExternalEndpoint == "" && !strings.HasPrefix(host, "*.") {
+			c.peerOpts.ExternalEndpoint = net.JoinHostPort(host, strconv.Itoa(inv.ExternalPort))
+		}
+	}
+	c.peerOpts.GossipBootstrap = nil
+	for _, endpoint := range inv.GossipBootstrap {
+		if endpoint != c.peerOpts.ExternalEndpoint {
+			c.peerOpts.GossipBootstrap = append(c.peerOpts.GossipBootstrap, endpoint)
+		}
+	}
 	return nil
 }
 
-func newPeerInitCommand() *cobra.Command {
+func newPeerInitCommand(out io.Writer) *cobra.Command {
 	c := peerInitCmd{
 		peerOpts: config.PeerInitOptions{},
 	}
@@ -55,7 +234,7 @@ func newPeerInitCommand() *cobra.Command {
 			if err := c.validate(); err != nil {
 				return err
 			}
-			return c.run()
+			return c.run(out)
 		},
 	}
 	f := cmd.Flags()
@@ -68,6 +247,27 @@ func newPeerInitCommand() *cobra.Command {
 	f.StringVar(&c.peerOpts.CACert, "ca-cert", "", "Path to the CA tls certificate")
 	f.StringVar(&c.peerOpts.EnrollID, "enroll-id", "", "Enroll ID")
 	f.StringVar(&c.peerOpts.EnrollSecret, "enroll-secret", "", "Enroll secret")
+	f.StringVar(&c.peerOpts.MSPID, "msp-id", "", "MSP ID of the peer, the gossip of the peers of the same MSP ID is wired automatically")
+	f.IntVar(&c.peerOpts.ExternalPort, "external-port", 7051, "Port of the external endpoint of the peer, the first host is used as its address")
+	f.StringVar(&c.peerOpts.ExternalEndpoint, "external-endpoint", "", "Endpoint the peer is advertised on behind a reverse proxy or a load balancer, added to its TLS SANs, the first host and --external-port if empty")
+	f.StringVar(&c.peerOpts.TLSCAName, "tls-ca-name", "", "Name of the CA that issues the TLS certificate, defaults to --ca-name")
+	f.StringVar(&c.invite, "invite", "", "Invite token, or path to a file with it, created with org invite-peer")
+	f.StringVar(&c.inviteFingerprint, "invite-fingerprint", "", "SHA-256 fingerprint of the CA TLS certificate of the invite, required with --invite")
+	f.BoolVar(&c.dryRun, "dry-run", false, "Print the directories, files and certificates that would be written without writing them")
+	f.BoolVar(&c.detectPublicIP, "detect-public-ip", false, "Detect the public IP of the host, for a cloud VM behind a NAT, and use it as the first host and the address of the external endpoint")
+	f.StringVar(&c.publicIPSource, "public-ip-source", publicip.DefaultSource, "HTTP echo service URL or STUN server as stun:host:port the public IP is detected with")
+	f.DurationVar(&c.timeout, "timeout", node.DefaultInitTimeout, "How long to wait for the lock of the peer and the Fabric CA, 0 waits without a limit")
+	c.peerOpts.CertPolicy.AddFlags(f)
+	c.peerOpts.CertPolicy.AddSANFlags(f)
+	c.peerOpts.Resources.AddFlags(f)
+	c.peerOpts.Limits.AddFlags(f)
+	c.peerOpts.Hooks.AddFlags(f)
+	c.peerOpts.GossipState.AddFlags(f)
+	c.peerOpts.Gateway.AddFlags(f)
+	c.peerOpts.Operations.AddFlags(f)
+	c.peerOpts.VM.AddFlags(f)
+	c.peerOpts.TLSPolicy.AddFlags(f)
+	c.peerOpts.Env.AddFlags(f)
 
 	return cmd
 }
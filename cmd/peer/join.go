@@ -2,6 +2,7 @@ package peer
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/Masterminds/sprig/v3"
 	"github.com/cloudflare/cfssl/log"
@@ -11,9 +12,17 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
+	"hlf-easy/channel"
+	"hlf-easy/node"
+	"hlf-easy/notify"
+	"hlf-easy/ordering"
+	"hlf-easy/proc"
 	"hlf-easy/utils"
 	"os"
+	"path/filepath"
+	"strings"
 	"text/template"
+	"time"
 )
 
 const tmplGoConfig = `
@@ -224,11 +233,13 @@ type peerJoinOptions struct {
 	ChannelName    string
 	Identity       string
 	PeerID         string
-	OrdererURL     string
+	OrdererURLs    []string
 	OrdererTLSCert string
+	OrdererBundle  string
 }
 type peerJoinCmd struct {
 	peerOpts peerJoinOptions
+	timeout  time.Duration
 }
 
 func (c *peerJoinCmd) validate() error {
@@ -239,7 +250,16 @@ func (c *peerJoinCmd) validate() error {
 	if c.peerOpts.PeerID == "" {
 		return errors.Errorf("--peer-id is required")
 	}
-	if c.peerOpts.OrdererURL == "" {
+	if c.timeout < 0 {
+		return errors.Errorf("--timeout can't be negative")
+	}
+	if c.peerOpts.OrdererBundle != "" {
+		if len(c.peerOpts.OrdererURLs) > 0 || c.peerOpts.OrdererTLSCert != "" {
+			return errors.Errorf("--orderer-bundle can't be used with --orderer-url or --orderer-tls-cert")
+		}
+		return nil
+	}
+	if len(c.peerOpts.OrdererURLs) == 0 {
 		return errors.Errorf("--orderer-url is required")
 	}
 	if c.peerOpts.OrdererTLSCert == "" {
@@ -248,6 +268,63 @@ func (c *peerJoinCmd) validate() error {
 	return nil
 }
 
+// getOrderers returns the orderers to join the channel from, either the ones
+// in the flags or the ones of the orderer bundle, with their TLS root
+// certificates
+func (c *peerJoinCmd) getOrderers() ([]*Orderer, []string, error) {
+	var urls []string
+	var tlsCACerts []string
+	if c.peerOpts.OrdererBundle != "" {
+		bundle, err := utils.ReadOrdererBundle(c.peerOpts.OrdererBundle)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, orderer := range bundle.Orderers {
+			urls = append(urls, utils.OrdererBundleURL(orderer))
+		}
+		tlsCACerts = bundle.TLSCACerts
+	} else {
+		ordererTLSCertBytes, err := os.ReadFile(c.peerOpts.OrdererTLSCert)
+		if err != nil {
+			return nil, nil, err
+		}
+		urls = c.peerOpts.OrdererURLs
+		tlsCACerts = []string{string(ordererTLSCertBytes)}
+	}
+	orderers := []*Orderer{}
+	for _, url := range urls {
+		orderers = append(orderers, &Orderer{
+			URL:       url,
+			Name:      "orderer",
+			TLSCACert: strings.Join(tlsCACerts, "\n"),
+		})
+	}
+	return orderers, tlsCACerts, nil
+}
+
+// rankOrderers probes the orderers and returns them in the failover order,
+// the closest healthy orderer first
+func rankOrderers(ctx context.Context, orderers []*Orderer, tlsCACerts []string) ([]*Orderer, []ordering.Probe) {
+	endpoints := []ordering.Endpoint{}
+	byAddress := map[string]*Orderer{}
+	for _, orderer := range orderers {
+		address := ordering.EndpointAddress(orderer.URL)
+		endpoints = append(endpoints, ordering.Endpoint{Address: address, TLSCACerts: tlsCACerts})
+		byAddress[address] = orderer
+	}
+	ranked := ordering.Rank(ordering.ProbeEndpoints(ctx, endpoints, ordering.DefaultAttempts, ordering.DefaultTimeout))
+	rankedOrderers := []*Orderer{}
+	for _, p := range ranked {
+		if p.Healthy {
+			log.Infof("Orderer %s is healthy, RTT %s", p.Address, p.RTT)
+		} else {
+			log.Warningf("Orderer %s is unreachable: %s", p.Address, p.Error)
+		}
+		rankedOrderers = append(rankedOrderers, byAddress[p.Address])
+	}
+	return rankedOrderers, ranked
+}
+
 type identity struct {
 	Cert Pem `json:"cert"`
 	Key  Pem `json:"key"`
@@ -257,15 +334,10 @@ type Pem struct {
 }
 
 func (c *peerJoinCmd) run() error {
-	ordererTLSCertBytes, err := os.ReadFile(c.peerOpts.OrdererTLSCert)
+	orderers, tlsCACerts, err := c.getOrderers()
 	if err != nil {
 		return err
 	}
-	orderer := &Orderer{
-		URL:       c.peerOpts.OrdererURL,
-		Name:      "orderer",
-		TLSCACert: string(ordererTLSCertBytes),
-	}
 	runConfig, err := utils.GetPeerRunConfig(c.peerOpts.PeerID)
 	if err != nil {
 		return errors.Wrapf(err, "failed to get run config for peer %s, is the peer running?", c.peerOpts.PeerID)
@@ -282,6 +354,15 @@ func (c *peerJoinCmd) run() error {
 		TLSCACert: string(tlsCertBytes),
 	}
 	mspID := runConfig.Options.MSPID
+	// refuse identities of other orgs before Fabric does it with ACCESS_DENIED
+	identityCert, _, err := utils.ReadIdentity(c.peerOpts.Identity)
+	if err != nil {
+		return err
+	}
+	err = utils.VerifyMSPAdmin(identityCert, mspID, peerConfig.CaCert, peerConfig.IntermediateCerts, time.Now())
+	if err != nil {
+		return err
+	}
 	identityBytes, err := os.ReadFile(c.peerOpts.Identity)
 	if err != nil {
 		return err
@@ -299,6 +380,53 @@ func (c *peerJoinCmd) run() error {
 			Key:  id.Key.Pem,
 		},
 	}
+	// the genesis block is fetched from the closest healthy orderer, the
+	// next ones are tried when it fails until --timeout runs out
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	// a peer that is still booting isn't joined until it's ready
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	readyCheck, err := node.PeerReadyCheck(filepath.Join(home, "hlf-easy/peers", c.peerOpts.PeerID), runConfig.Options)
+	if err != nil {
+		return err
+	}
+	err = readyCheck.Wait(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "peer %s isn't ready", c.peerOpts.PeerID)
+	}
+	rankedOrderers, ranked := rankOrderers(ctx, orderers, tlsCACerts)
+	for _, orderer := range rankedOrderers {
+		err = c.joinChannel(ctx, peer, users, orderer, mspID, username)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return errors.Wrapf(ctx.Err(), "peer %s didn't join channel %s: %v", c.peerOpts.PeerID, c.peerOpts.ChannelName, err)
+		}
+		log.Warningf("Failed to join channel %s with orderer %s: %v", c.peerOpts.ChannelName, orderer.URL, err)
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("Channel joined: %v", c.peerOpts.ChannelName)
+	changed, err := node.UpdatePeerOrdererOverrides(c.peerOpts.PeerID, ranked, tlsCACerts)
+	if err != nil {
+		return errors.Wrap(err, "failed to write the orderer overrides of the peer")
+	}
+	if changed {
+		log.Infof("The unreachable orderers are replaced with the closest one in the core.yaml of %s, restart the peer to apply it", c.peerOpts.PeerID)
+	}
+	event := notify.NewEvent(notify.EventChannelJoined, "peer", c.peerOpts.PeerID, fmt.Sprintf("Peer %s joined channel %s", c.peerOpts.PeerID, c.peerOpts.ChannelName))
+	event.Details = map[string]string{"channel": c.peerOpts.ChannelName, "mspID": mspID}
+	notify.Notify(event)
+	return nil
+}
+
+// joinChannel joins the peer to the channel with the genesis block of an orderer
+func (c *peerJoinCmd) joinChannel(ctx context.Context, peer *Peer, users []OrgUser, orderer *Orderer, mspID, username string) error {
 	nc, err := GenerateNetworkConfigForFollower(
 		peer,
 		users,
@@ -314,6 +442,7 @@ func (c *peerJoinCmd) run() error {
 	if err != nil {
 		return err
 	}
+	defer sdk.Close()
 	sdkContext := sdk.Context(
 		fabsdk.WithUser(username),
 		fabsdk.WithOrg(mspID),
@@ -322,12 +451,7 @@ func (c *peerJoinCmd) run() error {
 	if err != nil {
 		return err
 	}
-	err = resClient.JoinChannel(c.peerOpts.ChannelName)
-	if err != nil {
-		return err
-	}
-	log.Infof("Channel joined: %v", c.peerOpts.ChannelName)
-	return nil
+	return resClient.JoinChannel(c.peerOpts.ChannelName, resmgmt.WithParentContext(ctx))
 }
 
 func newPeerJoinCommand() *cobra.Command {
@@ -345,9 +469,11 @@ func newPeerJoinCommand() *cobra.Command {
 	}
 	f := cmd.Flags()
 	f.StringVar(&c.peerOpts.PeerID, "id", "", "ID of the peer to join")
-	f.StringVar(&c.peerOpts.OrdererURL, "orderer-url", "", "URL of the orderer to join")
+	f.StringSliceVar(&c.peerOpts.OrdererURLs, "orderer-url", []string{}, "URLs of the orderers to join, the closest healthy one is used")
 	f.StringVar(&c.peerOpts.OrdererTLSCert, "orderer-tls-cert", "", "TLS certificate of the orderer to join")
+	f.StringVar(&c.peerOpts.OrdererBundle, "orderer-bundle", "", "Orderer bundle of an ordering service operated by a third party, replaces --orderer-url and --orderer-tls-cert")
 	f.StringVar(&c.peerOpts.ChannelName, "channel", "", "Name of the channel to join")
 	f.StringVar(&c.peerOpts.Identity, "identity", "", "Identity to use to join the channel")
+	f.DurationVar(&c.timeout, "timeout", channel.DefaultJoinTimeout, "How long the peer has to join the channel, 0 waits without a limit")
 	return cmd
 }
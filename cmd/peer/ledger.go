@@ -0,0 +1,317 @@
+package peer
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"hlf-easy/audit"
+	"hlf-easy/dashboard"
+	"hlf-easy/lock"
+	"hlf-easy/node"
+	"hlf-easy/utils"
+	"hlf-easy/wizard"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ledgerFlags are the flags of the commands run on the ledger of a stopped
+// peer
+type ledgerFlags struct {
+	peerID string
+	token  string
+	wait   time.Duration
+}
+
+func (l *ledgerFlags) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&l.peerID, "id", "", "ID of the peer, it can also be passed as an argument")
+	f.StringVar(&l.token, "token", "", "API token of the management API of the peer")
+	f.DurationVar(&l.wait, "wait", 15*time.Second, "Time the peer must keep running after it's started again")
+}
+
+func (l *ledgerFlags) validate() error {
+	if l.peerID == "" {
+		return errors.New("the id of the peer is required")
+	}
+	return nil
+}
+
+func (l *ledgerFlags) getPeerDir() (string, error) {
+	peerDir, err := utils.GetNodeDir("peer", l.peerID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(peerDir, "init.json")); err != nil {
+		return "", errors.Errorf("peer %s not found", l.peerID)
+	}
+	return peerDir, nil
+}
+
+// runStopped runs an operation on a peer while it's stopped. A running peer
+// is stopped through its management API and started again after the
+// operation, even when it failed so it isn't left stopped. It returns
+// whether the peer was started again
+func (l *ledgerFlags) runStopped(operation string, run func(peerDir string) error) (bool, error) {
+	peerDir, err := l.getPeerDir()
+	if err != nil {
+		return false, err
+	}
+	// the lock is held through the stop and start of the peer by its
+	// management API
+	lk, err := lock.Acquire(dashboard.KindPeer, l.peerID, "peer."+operation, audit.LocalActor(), lock.Wait, "")
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := lk.Release(); err != nil {
+			log.Warnf("Failed to release the lock of peer %s: %v", l.peerID, err)
+		}
+	}()
+
+	n, err := dashboard.GetNode(dashboard.KindPeer, l.peerID, l.token)
+	if err != nil {
+		return false, err
+	}
+	if n.Running && n.Error != "" {
+		return false, errors.Errorf("failed to get the status of peer %s: %s", l.peerID, n.Error)
+	}
+	running := isRunning(n)
+	if running {
+		log.Infof("Stopping peer %s", l.peerID)
+		err = dashboard.RunLockedAction(dashboard.KindPeer, l.peerID, "stop", l.token, lk)
+		if err != nil {
+			return false, err
+		}
+	}
+	runErr := run(peerDir)
+	if !running {
+		return false, runErr
+	}
+
+	log.Infof("Starting peer %s", l.peerID)
+	err = dashboard.RunLockedAction(dashboard.KindPeer, l.peerID, "start", l.token, lk)
+	if err == nil {
+		err = waitRunning(l.peerID, l.token, l.wait)
+	}
+	if runErr != nil {
+		if err != nil {
+			return false, errors.Wrapf(runErr, "peer %s failed to start again: %v", l.peerID, err)
+		}
+		return true, errors.Wrapf(runErr, "peer %s is started again", l.peerID)
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "peer %s failed to start after %s", l.peerID, operation)
+	}
+	return true, nil
+}
+
+type peerRebuildDBsCmd struct {
+	ledgerFlags
+}
+
+func (c *peerRebuildDBsCmd) run(out io.Writer) error {
+	restarted, err := c.runStopped("rebuild-dbs", func(peerDir string) error {
+		log.Infof("Dropping the databases of peer %s", c.peerID)
+		return node.RebuildPeerDBs(peerDir)
+	})
+	if err != nil {
+		return err
+	}
+	if restarted {
+		fmt.Fprintf(out, "Peer %s restarted, its databases are rebuilt from its blocks\n", c.peerID)
+	} else {
+		fmt.Fprintf(out, "The databases of peer %s are rebuilt from its blocks on its next start\n", c.peerID)
+	}
+	return nil
+}
+
+func newPeerRebuildDBsCommand(out io.Writer) *cobra.Command {
+	c := &peerRebuildDBsCmd{}
+	cmd := &cobra.Command{
+		Use:   "rebuild-dbs <id>",
+		Short: "Drop the state and history databases of a peer so they're rebuilt from its blocks, it's restarted when it's running",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				c.peerID = args[0]
+			}
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	c.addFlags(cmd.Flags())
+	return cmd
+}
+
+// backupFlags are the flags of the commands that back up the ledger of the
+// peer before they change it
+type backupFlags struct {
+	yes       bool
+	backupDir string
+}
+
+func (b *backupFlags) addFlags(f *pflag.FlagSet) {
+	f.BoolVarP(&b.yes, "yes", "y", false, "Run without confirmation")
+	f.StringVar(&b.backupDir, "backup-dir", "", "Directory the ledger is backed up to before it's changed, the backups directory of the peer by default")
+}
+
+// confirm asks to go on with an operation that discards blocks of the peer
+func (b *backupFlags) confirm(in io.Reader, out io.Writer, message string) error {
+	if b.yes {
+		return nil
+	}
+	fmt.Fprintln(out, message)
+	ok, err := wizard.NewPrompter(in, out).Confirm("Continue", false)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("aborted, the peer wasn't changed")
+	}
+	return nil
+}
+
+// backup archives the ledger of the stopped peer
+func (b *backupFlags) backup(out io.Writer, peerDir string) error {
+	backupDir := b.backupDir
+	if backupDir == "" {
+		backupDir = filepath.Join(peerDir, "backups")
+	}
+	backupPath, err := node.BackupPeerLedger(peerDir, backupDir, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Ledger backed up to %s\n", backupPath)
+	return nil
+}
+
+type peerResetCmd struct {
+	ledgerFlags
+	backupFlags
+}
+
+func (c *peerResetCmd) run(in io.Reader, out io.Writer) error {
+	if _, err := c.getPeerDir(); err != nil {
+		return err
+	}
+	err := c.confirm(in, out, fmt.Sprintf("The channels of peer %s are reset to their genesis block and their blocks are pulled again from the orderers, the peer doesn't endorse until it has caught up.", c.peerID))
+	if err != nil {
+		return err
+	}
+	restarted, err := c.runStopped("reset", func(peerDir string) error {
+		if err := c.backup(out, peerDir); err != nil {
+			return err
+		}
+		log.Infof("Resetting the channels of peer %s", c.peerID)
+		return node.ResetPeer(peerDir)
+	})
+	if err != nil {
+		return err
+	}
+	if restarted {
+		fmt.Fprintf(out, "Peer %s reset and restarted\n", c.peerID)
+	} else {
+		fmt.Fprintf(out, "Peer %s reset, it pulls the blocks of its channels on its next start\n", c.peerID)
+	}
+	return nil
+}
+
+func newPeerResetCommand(out io.Writer) *cobra.Command {
+	c := &peerResetCmd{}
+	cmd := &cobra.Command{
+		Use:   "reset <id>",
+		Short: "Reset the channels of a peer to their genesis block after backing up its ledger, it's restarted when it's running",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				c.peerID = args[0]
+			}
+			if err := c.ledgerFlags.validate(); err != nil {
+				return err
+			}
+			return c.run(cmd.InOrStdin(), out)
+		},
+	}
+	c.ledgerFlags.addFlags(cmd.Flags())
+	c.backupFlags.addFlags(cmd.Flags())
+	return cmd
+}
+
+type peerRollbackCmd struct {
+	ledgerFlags
+	backupFlags
+	channel     string
+	blockNumber int64
+}
+
+func (c *peerRollbackCmd) validate() error {
+	if err := c.ledgerFlags.validate(); err != nil {
+		return err
+	}
+	if c.channel == "" {
+		return errors.New("--channel is required")
+	}
+	if c.blockNumber < 0 {
+		return errors.New("--block-number is required")
+	}
+	return nil
+}
+
+func (c *peerRollbackCmd) run(in io.Reader, out io.Writer) error {
+	peerDir, err := c.getPeerDir()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(node.GetPeerLedgerDir(peerDir), "chains", "chains", c.channel)); err != nil {
+		return errors.Errorf("peer %s hasn't joined channel %s", c.peerID, c.channel)
+	}
+	err = c.confirm(in, out, fmt.Sprintf("Channel %s of peer %s is rolled back to block %d and the next blocks are pulled again from the orderers.", c.channel, c.peerID, c.blockNumber))
+	if err != nil {
+		return err
+	}
+	restarted, err := c.runStopped("rollback", func(peerDir string) error {
+		if err := c.backup(out, peerDir); err != nil {
+			return err
+		}
+		log.Infof("Rolling back channel %s of peer %s to block %d", c.channel, c.peerID, c.blockNumber)
+		return node.RollbackPeer(peerDir, c.channel, uint64(c.blockNumber))
+	})
+	if err != nil {
+		return err
+	}
+	if restarted {
+		fmt.Fprintf(out, "Channel %s of peer %s rolled back to block %d, the peer is restarted\n", c.channel, c.peerID, c.blockNumber)
+	} else {
+		fmt.Fprintf(out, "Channel %s of peer %s rolled back to block %d\n", c.channel, c.peerID, c.blockNumber)
+	}
+	return nil
+}
+
+func newPeerRollbackCommand(out io.Writer) *cobra.Command {
+	c := &peerRollbackCmd{}
+	cmd := &cobra.Command{
+		Use:   "rollback <id>",
+		Short: "Roll a channel of a peer back to a block after backing up its ledger, it's restarted when it's running",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				c.peerID = args[0]
+			}
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(cmd.InOrStdin(), out)
+		},
+	}
+	c.ledgerFlags.addFlags(cmd.Flags())
+	c.backupFlags.addFlags(cmd.Flags())
+	f := cmd.Flags()
+	f.StringVar(&c.channel, "channel", "", "Channel to roll back")
+	f.Int64Var(&c.blockNumber, "block-number", -1, "Number of the block the channel is rolled back to")
+	return cmd
+}
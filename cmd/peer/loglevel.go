@@ -0,0 +1,58 @@
+package peer
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/dashboard"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"io"
+)
+
+type peerLogLevelCmd struct {
+	peerID string
+	spec   string
+	token  string
+}
+
+func (c *peerLogLevelCmd) validate() error {
+	if c.peerID == "" {
+		return errors.New("the id of the peer is required")
+	}
+	return node.ValidateLogSpec(c.spec)
+}
+
+func (c *peerLogLevelCmd) run(out io.Writer) error {
+	update, err := dashboard.SetLogSpec(dashboard.KindPeer, c.peerID, c.spec, c.token)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, update, func(out io.Writer) error {
+		if !update.Applied {
+			_, err := fmt.Fprintf(out, "Logging spec %s of peer %s persisted, it's applied when the peer starts\n", c.spec, c.peerID)
+			return err
+		}
+		_, err := fmt.Fprintf(out, "Logging spec of peer %s set to %s\n", c.peerID, c.spec)
+		return err
+	})
+}
+
+func newPeerLogLevelCommand(out io.Writer) *cobra.Command {
+	c := &peerLogLevelCmd{}
+	cmd := &cobra.Command{
+		Use:   "loglevel <id> <spec>",
+		Short: "Change the logging spec of a running peer without a restart, e.g. gossip=debug:info",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.peerID, c.spec = args[0], args[1]
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.token, "token", "", "API token of the management API of the peer")
+	return cmd
+}
@@ -0,0 +1,123 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/discover"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"strings"
+	"time"
+)
+
+type gossipMeshCmd struct {
+	mspID   string
+	timeout time.Duration
+}
+
+func (c *gossipMeshCmd) validate() error {
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	return nil
+}
+
+// meshNames returns the IDs of the peers of the host with the endpoints and
+// the endpoints of the other peers
+func meshNames(mesh []discover.MeshPeer, endpoints []string) string {
+	ids := map[string]string{}
+	for _, p := range mesh {
+		ids[p.Endpoint] = p.ID
+	}
+	names := []string{}
+	for _, endpoint := range endpoints {
+		if id, ok := ids[endpoint]; ok {
+			endpoint = id
+		}
+		names = append(names, endpoint)
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ",")
+}
+
+func formatLeaders(channels []node.ChannelGossip) string {
+	leaders := []string{}
+	for _, channel := range channels {
+		leaders = append(leaders, fmt.Sprintf("%s:%s", channel.Channel, channel.Leader))
+	}
+	if len(leaders) == 0 {
+		return "-"
+	}
+	return strings.Join(leaders, ",")
+}
+
+func (c *gossipMeshCmd) run(out io.Writer, errOut io.Writer) error {
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	mesh, err := discover.GetMesh(ctx, c.mspID)
+	if err != nil {
+		return err
+	}
+	err = output.Print(out, mesh, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "ID\tMSP ID\tENDPOINT\tSEES\tSEEN BY\tLEADER\tSTATUS")
+		for _, p := range mesh {
+			status := "ok"
+			switch {
+			case p.Error != "":
+				status = "unreachable"
+			case p.Isolated:
+				status = "ISOLATED"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", p.ID, p.MSPID, p.Endpoint, meshNames(mesh, p.Sees), meshNames(mesh, p.SeenBy), formatLeaders(p.Channels), status)
+		}
+		return w.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range mesh {
+		if p.Error != "" {
+			fmt.Fprintf(errOut, "Peer %s: %s\n", p.ID, p.Error)
+		}
+		if p.Isolated {
+			fmt.Fprintf(errOut, "Peer %s is isolated from the peers of %s, check its gossip bootstrap and external endpoint\n", p.ID, p.MSPID)
+		}
+	}
+	return nil
+}
+
+func newPeerGossipMeshCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &gossipMeshCmd{}
+	cmd := &cobra.Command{
+		Use:   "gossip-mesh",
+		Short: "Show which peers of the host know each other through gossip",
+		Long: `Query the gossip membership of every peer of the host through its discovery
+service, with the admin identity hlf-easy manages for the peer, and show the
+peers each peer knows, the peers of the host that know it and its leader role
+on its channels. A peer is isolated when it and the other running peers of
+its org don't know each other, it then misses the private data and the
+blocks the other peers disseminate.
+
+The leader roles are read from the metrics of the peers exposing Prometheus
+metrics: static for the org leaders hlf-easy starts by default, elected or
+follower with CORE_PEER_GOSSIP_USELEADERELECTION=true.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.mspID, "msp-id", "", "MSP ID of the org whose peers are shown, all the peers of the host if empty")
+	f.DurationVar(&c.timeout, "timeout", discover.DefaultTimeout, "How long the peers have to answer, 0 waits without a limit")
+	return cmd
+}
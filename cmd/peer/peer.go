@@ -4,6 +4,7 @@ import (
 	"embed"
 	"github.com/spf13/cobra"
 	"hlf-easy/cmd/peer/anchorpeers"
+	"hlf-easy/cmd/peer/csr"
 	"io"
 )
 
@@ -12,10 +13,25 @@ func NewPeerCmd(out io.Writer, errOut io.Writer, views embed.FS) *cobra.Command
 		Use: "peer",
 	}
 	cmd.AddCommand(
-		newPeerInitCommand(),
-		newPeerStartCommand(views),
+		newPeerInitCommand(out),
+		newPeerImportCommand(),
+		newPeerStartCommand(out, views),
+		newPeerStopCommand(out),
+		newPeerStatusCommand(out),
 		newPeerJoinCommand(),
+		newPeerRemoveCommand(out),
+		newPeerUpgradeCommand(out),
+		newPeerLogLevelCommand(out),
+		newPeerRehostCommand(out),
+		newPeerCloneCommand(out),
+		newPeerRebuildDBsCommand(out),
+		newPeerResetCommand(out),
+		newPeerRollbackCommand(out),
+		newPeerValidateCommand(out),
+		newPeerQueryStateCommand(out),
+		newPeerGossipMeshCommand(out, errOut),
 		anchorpeers.NewAnchorPeersCmd(out, errOut),
+		csr.NewCSRCmd(out, errOut),
 	)
 	return cmd
 }
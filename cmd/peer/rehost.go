@@ -0,0 +1,84 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/node"
+	"hlf-easy/proc"
+	"io"
+	"time"
+)
+
+type peerRehostCmd struct {
+	ledgerFlags
+	hosts            []string
+	externalEndpoint string
+	timeout          time.Duration
+}
+
+func (c *peerRehostCmd) validate() error {
+	if err := c.ledgerFlags.validate(); err != nil {
+		return err
+	}
+	if len(c.hosts) == 0 {
+		return errors.New("--host is required")
+	}
+	if c.timeout < 0 {
+		return fmt.Errorf("--timeout can't be negative")
+	}
+	return nil
+}
+
+func (c *peerRehostCmd) run(out io.Writer) error {
+	var previous, endpoint string
+	restarted, err := c.runStopped("rehost", func(peerDir string) error {
+		// an interrupt or --timeout stops the enrollment with the Fabric CA
+		ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+		defer stop()
+		log.Infof("Issuing the TLS certificate of peer %s for %v", c.peerID, c.hosts)
+		var err error
+		previous, endpoint, err = node.RehostPeer(ctx, peerDir, node.RehostPeerOptions{
+			Hosts:            c.hosts,
+			ExternalEndpoint: c.externalEndpoint,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Peer %s moved from %s to %s\n", c.peerID, previous, endpoint)
+	if restarted {
+		fmt.Fprintf(out, "Peer %s restarted on its new endpoint\n", c.peerID)
+	} else {
+		fmt.Fprintf(out, "The new endpoint of peer %s is used on its next start\n", c.peerID)
+	}
+	fmt.Fprintf(out, "Update the anchor peers of its channels with peer anchorpeers set when it's an anchor peer\n")
+	return nil
+}
+
+func newPeerRehostCommand(out io.Writer) *cobra.Command {
+	c := &peerRehostCmd{}
+	cmd := &cobra.Command{
+		Use:   "rehost <id>",
+		Short: "Move a peer to new hosts: its TLS certificate is issued again for them and its endpoint is updated, it's restarted when it's running",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				c.peerID = args[0]
+			}
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	c.addFlags(f)
+	f.StringSliceVar(&c.hosts, "host", nil, "New hosts of the peer, the first one is the host of its external endpoint, can be repeated")
+	f.StringVar(&c.externalEndpoint, "external-endpoint", "", "New external endpoint of the peer, the first host with the port of its current endpoint by default")
+	f.DurationVar(&c.timeout, "timeout", node.DefaultInitTimeout, "How long to wait for the Fabric CA, 0 waits without a limit")
+	return cmd
+}
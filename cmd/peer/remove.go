@@ -0,0 +1,45 @@
+package peer
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"hlf-easy/node"
+	"io"
+)
+
+type peerRemoveCmd struct {
+	peerID string
+}
+
+func (c *peerRemoveCmd) validate() error {
+	if c.peerID == "" {
+		return fmt.Errorf("--id is required")
+	}
+	return nil
+}
+
+func (c *peerRemoveCmd) run(out io.Writer) error {
+	err := node.RemovePeer(c.peerID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Peer %s removed\n", c.peerID)
+	return nil
+}
+
+func newPeerRemoveCommand(out io.Writer) *cobra.Command {
+	c := &peerRemoveCmd{}
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a stopped peer and its crypto material from the host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.peerID, "id", "", "ID of the peer to remove")
+	return cmd
+}
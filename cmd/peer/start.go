@@ -8,21 +8,53 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"hlf-easy/alerts"
+	"hlf-easy/anomaly"
 	"hlf-easy/api"
+	"hlf-easy/auth"
+	"hlf-easy/bulk"
+	"hlf-easy/chaincode"
 	"hlf-easy/config"
+	"hlf-easy/monitoring"
 	"hlf-easy/node"
+	"hlf-easy/proc"
+	"hlf-easy/tasks"
+	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
-	"syscall"
+	"strings"
 	"time"
 )
 
-func StartPeerNodeCommand(stdout *config.SaveOutputWriter, stderr *config.SaveOutputWriter, opts config.StartPeerOpts) (*exec.Cmd, error) {
+func StartPeerNodeCommand(opts config.StartPeerOpts) (*exec.Cmd, error) {
 	// Define the command and arguments
-	cmd := exec.Command("peer", "node", "start")
+	binary := opts.Binary
+	if binary == "" {
+		binary = "peer"
+	}
+	args := []string{"node", "start"}
+	tlsEnabled := "true"
+	if opts.DevMode {
+		// the chaincodes of the developers connect to a dev peer without TLS
+		args = append(args, "--peer-chaincodedev=true")
+		tlsEnabled = "false"
+	}
+	cmd := exec.Command(binary, args...)
+	logSpec := opts.LogSpec
+	if logSpec == "" {
+		logSpec = node.DefaultLogSpec
+	}
+	gossipBootstrap := opts.ExternalEndpoint
+	if len(opts.GossipBootstrap) > 0 {
+		gossipBootstrap = strings.Join(opts.GossipBootstrap, " ")
+	}
+	metricsProvider := opts.Operations.MetricsProvider
+	if metricsProvider == "" {
+		metricsProvider = node.MetricsPrometheus
+	}
 	// Set environment variables specifically for this command
 	cmd.Env = []string{
 
@@ -46,21 +78,21 @@ func StartPeerNodeCommand(stdout *config.SaveOutputWriter, stderr *config.SaveOu
 
 		fmt.Sprintf("CORE_OPERATIONS_LISTENADDRESS=%s", opts.OperationsListenAddress),
 
-		"CORE_PEER_NETWORKID=peer01-nid",
+		fmt.Sprintf("CORE_PEER_NETWORKID=%s", chaincode.PeerNetworkID),
 		fmt.Sprintf("CORE_PEER_LOCALMSPID=%s", opts.MSPID),
 
 		fmt.Sprintf("CORE_PEER_ID=%s", opts.ID),
 
-		"CORE_OPERATIONS_TLS_ENABLED=false",
-		"CORE_OPERATIONS_TLS_CLIENTAUTHREQUIRED=false",
+		fmt.Sprintf("CORE_OPERATIONS_TLS_ENABLED=%t", opts.Operations.TLS),
+		fmt.Sprintf("CORE_OPERATIONS_TLS_CLIENTAUTHREQUIRED=%t", opts.Operations.ClientAuthRequired),
 
 		"CORE_PEER_GOSSIP_ORGLEADER=true",
-		fmt.Sprintf("CORE_PEER_GOSSIP_BOOTSTRAP=%s", opts.ExternalEndpoint),
+		fmt.Sprintf("CORE_PEER_GOSSIP_BOOTSTRAP=%s", gossipBootstrap),
 		"CORE_PEER_PROFILE_ENABLED=true",
 		"CORE_PEER_ADDRESSAUTODETECT=false",
 		"CORE_LOGGING_GOSSIP=info",
 
-		"FABRIC_LOGGING_SPEC=info",
+		fmt.Sprintf("FABRIC_LOGGING_SPEC=%s", logSpec),
 		"CORE_LOGGING_LEDGER=info",
 		"CORE_LOGGING_MSP=info",
 		"CORE_PEER_COMMITTER_ENABLED=true",
@@ -68,36 +100,68 @@ func StartPeerNodeCommand(stdout *config.SaveOutputWriter, stderr *config.SaveOu
 		"CORE_PEER_GOSSIP_USELEADERELECTION=false",
 
 		"CORE_PEER_DISCOVERY_PERIOD=60s",
-		"CORE_METRICS_PROVIDER=prometheus",
+		fmt.Sprintf("CORE_METRICS_PROVIDER=%s", metricsProvider),
 		"CORE_LOGGING_CAUTHDSL=info",
 		"CORE_LOGGING_POLICIES=info",
 
 		"CORE_LEDGER_STATE_STATEDATABASE=goleveldb",
 
-		"CORE_PEER_TLS_ENABLED=true",
+		fmt.Sprintf("CORE_PEER_TLS_ENABLED=%s", tlsEnabled),
 		"CORE_LOGGING_GRPC=info",
 		"CORE_LOGGING_PEER=info",
 	}
+	if metricsProvider == node.MetricsStatsd {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("CORE_METRICS_STATSD_ADDRESS=%s", opts.Operations.StatsdAddress))
+	}
+	if opts.KeystorePath != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("CORE_PEER_BCCSP_SW_FILEKEYSTORE_KEYSTORE=%s", opts.KeystorePath))
+	}
+	// the extra variables of the init options override the ones above
+	cmd.Env = node.WithEnv(cmd.Env, opts.Env)
 	log.Infof("Envs: %v", cmd.Env)
 	// Set the Stdout and Stderr to os.Stdout and os.Stderr
 	// so that we can see the command output
 	//cmd.Stdout = os.Stdout
 	//cmd.Stderr = os.Stderr
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
+	cmd.Env = proc.Env(cmd.Env)
+	// the node is stopped with an interrupt of its own process group
+	proc.NewProcessGroup(cmd)
 
 	return cmd, nil
 }
 
 type peerCmd struct {
 	peerOpts config.PeerStartOptions
+	// bulk starts the peers of the host through their hlf-easy process, the
+	// ones whose process is down are reported as failed
+	bulk bulkFlags
 }
 
 func (c peerCmd) validate() error {
+	if c.bulk.all {
+		if c.peerOpts.DevMode {
+			return fmt.Errorf("--dev-mode can't be used with --all")
+		}
+		c.bulk.id = c.peerOpts.ID
+		return c.bulk.validate()
+	}
 	if c.peerOpts.ID == "" {
 		return fmt.Errorf("--id is required")
 	}
-	return nil
+	if c.peerOpts.DiskUsageThreshold < 0 || c.peerOpts.DiskUsageThreshold > 100 {
+		return fmt.Errorf("--disk-usage-threshold must be a percent")
+	}
+	return auth.ValidateOptions(c.peerOpts.Auth)
+}
+
+// writeRunConfig writes the run.json of a peer, it tells the peer is running
+// and how to reach it
+func writeRunConfig(runConfigFilePath string, runConfig config.PeerRunConfig) error {
+	runConfigBytes, err := json.Marshal(runConfig)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(runConfigFilePath, runConfigBytes, 0644)
 }
 
 func (c peerCmd) run(views embed.FS) error {
@@ -119,18 +183,59 @@ func (c peerCmd) run(views embed.FS) error {
 	if err != nil {
 		return err
 	}
+	// the MSP ID and external endpoint of the peer default to the ones in init.json
+	peerInitOpts := config.PeerInitOptions{}
+	peerInitOptsBytes, err := os.ReadFile(filepath.Join(peerConfigDir, "init.json"))
+	if err == nil {
+		err = json.Unmarshal(peerInitOptsBytes, &peerInitOpts)
+		if err != nil {
+			return err
+		}
+	}
+	if c.peerOpts.MSPID == "" {
+		c.peerOpts.MSPID = peerInitOpts.MSPID
+	}
+	// --external-endpoint overrides the endpoint of init.json on every start
+	externalEndpointSet := c.peerOpts.ExternalEndpoint != ""
+	if !externalEndpointSet {
+		c.peerOpts.ExternalEndpoint = peerInitOpts.ExternalEndpoint
+	}
+	gossipBootstrap, err := node.GetPeerGossipBootstrap(peerInitOpts)
+	if err != nil {
+		return err
+	}
+	// the operations endpoint is bound to the address of its init options
+	// unless --operations-listen-address overrides it
+	operations := node.GetPeerOperations(peerInitOpts)
+	if c.peerOpts.OperationsListenAddress == "" {
+		c.peerOpts.OperationsListenAddress = operations.ListenAddress
+	}
+	operations.ListenAddress = c.peerOpts.OperationsListenAddress
+	// the chaincode mode of core.yaml follows --dev-mode
+	_, err = node.SetPeerDevMode(peerID, c.peerOpts.DevMode)
+	if err != nil {
+		return err
+	}
+	if c.peerOpts.DevMode {
+		log.Warnf("Peer %s runs in chaincode dev mode without TLS, start its chaincodes with chaincode dev-run", peerID)
+	}
 
+	// without a management address the API is only served on the socket
+	if c.peerOpts.ManagementAddress == "" && c.peerOpts.Auth.Socket == "" {
+		c.peerOpts.Auth.Socket = filepath.Join(peerConfigDir, "run", "api.sock")
+	}
+	// a node managed by another hlf-easy process isn't started again
+	err = node.CheckManager(node.KindPeer, c.peerOpts.ID)
+	if err != nil {
+		return err
+	}
 	// save run.json config in order to indicate that the peer is running
 	runConfig := config.PeerRunConfig{
 		PeerID:  c.peerOpts.ID,
 		Options: c.peerOpts,
 	}
-	runConfigBytes, err := json.Marshal(runConfig)
-	if err != nil {
-		return err
-	}
 	runConfigFilePath := filepath.Join(peerConfigDir, "run.json")
-	err = os.WriteFile(runConfigFilePath, runConfigBytes, 0644)
+	err = writeRunConfig(runConfigFilePath, runConfig)
 	if err != nil {
 		return err
 	}
@@ -148,8 +253,15 @@ func (c peerCmd) run(views embed.FS) error {
 			}
 		}
 	}()
-	stdOut := &config.SaveOutputWriter{}
-	stdErr := &config.SaveOutputWriter{}
+	// the logs are scanned to detect the problems that don't show in the status
+	scanner, err := anomaly.NewNodeScanner("peer", c.peerOpts.ID)
+	if err != nil {
+		return err
+	}
+	// the output of the chaincode builds is stored per build
+	buildLogs := chaincode.NewBuildLogCollector(peerConfigDir)
+	stdOut := &config.SaveOutputWriter{Tee: io.MultiWriter(scanner.NewStream(), buildLogs.NewStream())}
+	stdErr := &config.SaveOutputWriter{Tee: io.MultiWriter(scanner.NewStream(), buildLogs.NewStream())}
 	startPeerOpts := config.StartPeerOpts{
 		ID:                      c.peerOpts.ID,
 		ListenAddress:           c.peerOpts.ListenAddress,
@@ -158,35 +270,156 @@ func (c peerCmd) run(views embed.FS) error {
 		OperationsListenAddress: c.peerOpts.OperationsListenAddress,
 		ExternalEndpoint:        c.peerOpts.ExternalEndpoint,
 		MSPID:                   c.peerOpts.MSPID,
+		GossipBootstrap:         gossipBootstrap,
 		MSPConfigPath:           peerConfigDir,
 		ConfigPeerPath:          peerConfigDir,
+		DevMode:                 c.peerOpts.DevMode,
+		Operations:              operations,
+		Env:                     peerInitOpts.Env,
 	}
+	// the signing key kept in a secrets manager is only on the disk while
+	// hlf-easy runs the peer
+	keystorePath, removeKeystore, err := node.FetchSignKey("peer", peerID)
+	if err != nil {
+		return err
+	}
+	defer removeKeystore()
+	startPeerOpts.KeystorePath = keystorePath
+	// the output of the peer process is written to files so it outlives
+	// hlf-easy, it's followed into the writers
+	output := node.NewOutput(peerConfigDir)
 	cmdGetter := func() (*exec.Cmd, error) {
-		cmd, err := StartPeerNodeCommand(
-			stdOut,
-			stdErr,
-			startPeerOpts)
+		// the binary is read on every start so an upgrade applies on restart
+		opts := startPeerOpts
+		binary, err := node.GetPeerBinary(peerConfigDir)
+		if err != nil {
+			log.Warnf("Failed to get the binary of peer node: %v", err)
+			return nil, err
+		}
+		opts.Binary = binary
+		// the logging spec changed at runtime is applied again on restart
+		opts.LogSpec = node.GetStartLogSpec(peerConfigDir)
+		// the endpoints are read again so a rehost of the peer, or of the
+		// other peers of its org, applies on restart
+		externalEndpoint, bootstrap, err := node.GetPeerEndpoints(peerConfigDir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			opts.GossipBootstrap = bootstrap
+			if !externalEndpointSet && externalEndpoint != runConfig.Options.ExternalEndpoint {
+				opts.ExternalEndpoint = externalEndpoint
+				runConfig.Options.ExternalEndpoint = externalEndpoint
+				if err := writeRunConfig(runConfigFilePath, runConfig); err != nil {
+					return nil, err
+				}
+			}
+		}
+		// a port bound by another node or process fails the start with its
+		// owner instead of the peer exiting on a bind error
+		err = node.CheckPorts(node.KindPeer, c.peerOpts.ID, node.PeerListenAddresses(c.peerOpts))
+		if err != nil {
+			return nil, err
+		}
+		cmd, err := StartPeerNodeCommand(opts)
 		if err != nil {
 			log.Warnf("Failed to start peer node: %v", err)
 			return nil, err
 		}
+		err = output.Redirect(cmd)
+		if err != nil {
+			return nil, err
+		}
 		return cmd, nil
 	}
 
 	peerNode := node.NewPeerNode(
 		c.peerOpts.ID,
 		c.peerOpts.MSPID,
+		peerInitOpts.Limits,
 		cmdGetter,
 	)
+	peerNode.SetHooks(peerInitOpts.Hooks)
+	// the start fails when the peer doesn't pass its health checks in time,
+	// so the channels aren't joined while it's booting
+	readyCheck, err := node.PeerReadyCheck(peerConfigDir, c.peerOpts)
+	if err != nil {
+		return err
+	}
+	peerNode.SetReadyCheck(readyCheck, c.peerOpts.ReadyTimeout)
+	// the registry stops the nodes of the process on shutdown
+	manager := node.NewManager()
+	if err := manager.Register(peerNode); err != nil {
+		return err
+	}
+	// the heights of the channels are compared with the other peers of the
+	// org to catch a broken gossip or delivery
+	lagMonitor := monitoring.NewLagMonitor(c.peerOpts.ID, c.peerOpts.MSPID, c.peerOpts.HeightLagThreshold)
+	peerNode.SetHeightLag(lagMonitor.HeightLag)
+	// the ledger is measured to follow its growth before its filesystem is
+	// full
+	diskMonitor := monitoring.NewDiskMonitor(c.peerOpts.ID, node.GetPeerDataDir(peerConfigDir), c.peerOpts.DiskUsageThreshold)
+	peerNode.SetDiskUsage(diskMonitor.DiskUsage)
+	// the Fabric counters of the peer are scraped from its metrics, so its
+	// status tells how fast it commits and how many endorsements fail
+	operationsEndpoint, err := node.GetPeerOperationsEndpoint(peerConfigDir, c.peerOpts.OperationsListenAddress)
+	if err != nil {
+		return err
+	}
+	metricsMonitor := monitoring.NewMetricsMonitor(c.peerOpts.ID, operationsEndpoint)
+	peerNode.SetMetrics(metricsMonitor.Metrics)
+	// the peer left running by an hlf-easy process that exited is attached
+	attached, err := peerNode.Attach()
+	if err != nil {
+		return err
+	}
+	// the failures of the node and of the API end the command once the nodes
+	// are stopped
+	errs := make(chan error, 2)
 	go func() {
-		if err := peerNode.Start(); err != nil {
-			log.Fatalf("Failed to start peer node: %v", err)
+		if !attached {
+			if err := peerNode.Start(); err != nil {
+				errs <- errors.Wrapf(err, "failed to start peer node")
+				return
+			}
+			log.Infof("Peer node is ready")
 		}
-		log.Infof("Peer node command finished")
+		// a cloned peer joins the channels of its source once it's ready
+		joinClonedChannels(peerID)
 	}()
 
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	ctx, stop := proc.NotifyContext(context.Background())
 	defer stop()
+	go output.Follow(ctx, stdOut, stdErr, node.DefaultFollowInterval)
+
+	// sample the status of the node to serve its recent history
+	history := node.NewStatusHistory(node.DefaultHistorySize)
+	go node.SampleStatus(ctx, peerNode, history, node.DefaultHistoryInterval)
+	go lagMonitor.Run(ctx, node.DefaultHeightLagInterval)
+	go diskMonitor.Run(ctx, node.DefaultDiskUsageInterval)
+	go metricsMonitor.Run(ctx, node.DefaultMetricsInterval)
+	go node.WatchLogSpec(ctx, peerNode, "peer", peerID, peerConfigDir, operationsEndpoint, node.DefaultLogSpecInterval)
+
+	// run the maintenance tasks of the node on their schedule
+	scheduler := tasks.NewScheduler(tasks.Node{
+		Kind:    "peer",
+		ID:      c.peerOpts.ID,
+		Dir:     peerConfigDir,
+		MSPID:   c.peerOpts.MSPID,
+		Address: c.peerOpts.ExternalEndpoint,
+		Logs:    []*config.SaveOutputWriter{stdOut, stdErr},
+	})
+	go scheduler.Run(ctx, tasks.DefaultCheckInterval)
+
+	// evaluate the alert rules of the host on the node
+	alerting := alerts.NewEngine(alerts.Node{
+		Kind:       "peer",
+		ID:         c.peerOpts.ID,
+		Dir:        peerConfigDir,
+		Process:    peerNode,
+		Operations: operationsEndpoint,
+	})
+	go alerting.Run(ctx, alerts.DefaultCheckInterval)
 
 	g, err := api.NewPeerRouter(
 		peerNode,
@@ -195,6 +428,10 @@ func (c peerCmd) run(views embed.FS) error {
 		c.peerOpts,
 		startPeerOpts,
 		views,
+		history,
+		scanner,
+		scheduler,
+		alerting,
 	)
 	if err != nil {
 		return err
@@ -207,17 +444,26 @@ func (c peerCmd) run(views embed.FS) error {
 
 	go func() {
 		// start the admin API server + UI
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("listen: %s\n", err)
+		if err := auth.ListenAndServe(srv, c.peerOpts.Auth); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- errors.Wrapf(err, "failed to serve the management API")
 		}
 	}()
 
 	// Listen for the interrupt signal.
-	<-ctx.Done()
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case runErr = <-errs:
+	}
 
 	// Restore default behavior on the interrupt signal and notify user of shutdown.
 	stop()
 	log.Infof("shutting down gracefully, press Ctrl+C again to force")
+	// the Fabric process doesn't get the signals sent to hlf-easy alone, nor
+	// the Ctrl+C of the console on Windows
+	if err := manager.StopAll(); err != nil {
+		log.Warnf("Peer node not stopped: %v", err)
+	}
 
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
@@ -229,11 +475,11 @@ func (c peerCmd) run(views embed.FS) error {
 
 	log.Infof("Server exiting")
 
-	return nil
+	return runErr
 }
 
 // NewPeerCommand creates a new 'peer' Cobra command
-func newPeerStartCommand(views embed.FS) *cobra.Command {
+func newPeerStartCommand(out io.Writer, views embed.FS) *cobra.Command {
 	c := peerCmd{
 		peerOpts: config.PeerStartOptions{},
 	}
@@ -250,6 +496,9 @@ applications, and the 'peer' command is a part of this application.`,
 			if err := c.validate(); err != nil {
 				return err
 			}
+			if c.bulk.all {
+				return c.bulk.run(out, bulk.ActionStart)
+			}
 			return c.run(views)
 		},
 	}
@@ -258,9 +507,18 @@ applications, and the 'peer' command is a part of this application.`,
 	f.StringVar(&c.peerOpts.ListenAddress, "listen-address", "0.0.0.0:7051", "Listen address of the peer")
 	f.StringVar(&c.peerOpts.ChaincodeAddress, "chaincode-address", "0.0.0.0:7052", "Chaincode address of the peer")
 	f.StringVar(&c.peerOpts.EventsAddress, "events-address", "0.0.0.0:7053", "Events address of the peer")
-	f.StringVar(&c.peerOpts.OperationsListenAddress, "operations-listen-address", "0.0.0.0:9443", "Operations listen address of the peer")
+	f.StringVar(&c.peerOpts.OperationsListenAddress, "operations-listen-address", "", "Operations listen address of the peer, the one of its init options when empty")
 	f.StringVar(&c.peerOpts.ExternalEndpoint, "external-endpoint", "", "External endpoint of the peer")
 	f.StringVar(&c.peerOpts.MSPID, "msp-id", "", "MSP ID of the peer")
-	f.StringVar(&c.peerOpts.ManagementAddress, "mgmt-address", "", "Management address of the peer")
+	f.StringVar(&c.peerOpts.ManagementAddress, "mgmt-address", "", "Management address of the peer, the API is only served on its Unix socket if empty")
+	f.StringVar(&c.peerOpts.Auth.Socket, "mgmt-socket", "", "Unix socket of the management API, only its owner can use it without a token, run/api.sock in the directory of the peer by default without --mgmt-address")
+	c.peerOpts.Auth.AddFlags(f)
+	f.BoolVar(&c.peerOpts.DevMode, "dev-mode", false, "Start the peer in chaincode dev mode without TLS, its chaincodes are started with chaincode dev-run")
+	f.DurationVar(&c.peerOpts.ReadyTimeout, "ready-timeout", node.DefaultReadyTimeout, "How long the peer has to answer its health checks on its operations and gRPC ports once started, 0 doesn't wait")
+	f.Uint64Var(&c.peerOpts.HeightLagThreshold, "height-lag-threshold", node.DefaultHeightLagThreshold, "Number of blocks the peer can be behind the other peers of its org on the host before its status flags it as lagging")
+	f.Float64Var(&c.peerOpts.DiskUsageThreshold, "disk-usage-threshold", node.DefaultDiskUsageThreshold, "Used percent of the filesystem of the ledger of the peer above which a warning is logged and its status flags it")
+	f.BoolVar(&c.bulk.all, "all", false, "Start the stopped peers of the host through their hlf-easy process, the ones whose process is down are reported as failed")
+	f.IntVar(&c.bulk.parallel, "parallel", bulk.DefaultParallelism, "Number of peers started at the same time with --all")
+	f.StringVar(&c.bulk.token, "token", "", "API token of the management APIs of the peers with --all")
 	return cmd
 }
@@ -0,0 +1,153 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/chaincode"
+	"hlf-easy/contract"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"io"
+	"time"
+)
+
+type queryStateCmd struct {
+	id         string
+	channel    string
+	name       string
+	collection string
+	key        string
+	selector   string
+	limit      int
+	function   string
+	identity   string
+	timeout    time.Duration
+}
+
+func (c *queryStateCmd) validate() error {
+	if c.channel == "" {
+		return errors.New("--channel is required")
+	}
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	if (c.key == "") == (c.selector == "") {
+		return errors.New("either --key or --selector is required")
+	}
+	if c.function != "" && c.collection != "" {
+		return errors.New("--collection can't be used with --function, the function reads the collections of the chaincode")
+	}
+	if c.function == "" && c.identity != "" {
+		return errors.New("--identity is only used with --function")
+	}
+	if c.limit <= 0 {
+		return errors.New("--limit must be positive")
+	}
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	return nil
+}
+
+// queryFunction evaluates the read function of the chaincode with the key or
+// the selector as its argument, it works whatever the state database
+func (c *queryStateCmd) queryFunction(ctx context.Context, out io.Writer) error {
+	arg := c.key
+	if c.selector != "" {
+		arg = c.selector
+	}
+	result, err := contract.Query(ctx, contract.Options{
+		PeerID:    c.id,
+		Identity:  c.identity,
+		Channel:   c.channel,
+		Chaincode: c.name,
+		Function:  c.function,
+		Args:      []string{arg},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(result))
+	return err
+}
+
+func (c *queryStateCmd) run(out io.Writer) error {
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	if c.function != "" {
+		return c.queryFunction(ctx, out)
+	}
+	couchDBURL, ok, err := node.GetPeerCouchDBURL(c.id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.Errorf("the state of peer %s is in goleveldb, it's only read by the peer, pass --function with a read function of chaincode %s", c.id, c.name)
+	}
+	if c.key != "" {
+		entry, err := chaincode.GetState(ctx, couchDBURL, c.channel, c.name, c.collection, c.key)
+		if err != nil {
+			return err
+		}
+		return output.Print(out, entry, func(out io.Writer) error {
+			_, err := fmt.Fprintln(out, entry.String())
+			return err
+		})
+	}
+	query, err := chaincode.StateQuery(c.selector, c.limit)
+	if err != nil {
+		return err
+	}
+	entries, err := chaincode.QueryState(ctx, couchDBURL, c.channel, c.name, c.collection, query)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, entries, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "KEY\tVALUE")
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s\t%s\n", entry.Key, entry.String())
+		}
+		return w.Flush()
+	})
+}
+
+func newPeerQueryStateCommand(out io.Writer) *cobra.Command {
+	c := &queryStateCmd{}
+	cmd := &cobra.Command{
+		Use:   "query-state <id>",
+		Short: "Read a key or run a rich query on the world state of a chaincode on a peer",
+		Long: `Read a key or run a CouchDB selector query on the world state of a chaincode
+on a peer, to inspect it without writing a client application. Nothing is
+written to the ledger.
+
+The state of a peer whose state database is CouchDB, set with
+CORE_LEDGER_STATE_STATEDATABASE=CouchDB in --env on peer init, is read from its
+CouchDB, with the private data of --collection. A goleveldb state is only read
+by the peer: --function evaluates a read function of the chaincode with the key
+or the selector as its argument, signed by the admin identity hlf-easy manages
+for the peer or by --identity.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.id = args[0]
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.channel, "channel", "", "Channel of the chaincode")
+	f.StringVar(&c.name, "name", "", "Name of the chaincode")
+	f.StringVar(&c.collection, "collection", "", "Private data collection of the chaincode to read instead of its state")
+	f.StringVar(&c.key, "key", "", "Key to read")
+	f.StringVar(&c.selector, "selector", "", `CouchDB selector, {"owner":"Tom"}, or query with a selector, {"selector":{"owner":"Tom"},"sort":[{"size":"desc"}]}`)
+	f.IntVar(&c.limit, "limit", chaincode.DefaultStateQueryLimit, "Number of keys returned by a selector without a limit")
+	f.StringVar(&c.function, "function", "", "Read function of the chaincode evaluated with the key or the selector instead of reading CouchDB")
+	f.StringVar(&c.identity, "identity", "", "Identity file evaluating --function, an admin identity issued by the local CA of the peer if empty")
+	f.DurationVar(&c.timeout, "timeout", contract.DefaultTimeout, "How long the read has to complete, 0 waits without a limit")
+	return cmd
+}
@@ -0,0 +1,43 @@
+package peer
+
+import (
+	"github.com/spf13/cobra"
+	"hlf-easy/bulk"
+	"io"
+)
+
+func newPeerStopCommand(out io.Writer) *cobra.Command {
+	c := &bulkFlags{}
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop a peer, or all the peers of the host, through their management APIs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, bulk.ActionStop)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.id, "id", "", "ID of the peer")
+	c.addFlags(f)
+	return cmd
+}
+
+func newPeerStatusCommand(out io.Writer) *cobra.Command {
+	c := &bulkFlags{}
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of a peer, or of all the peers of the host",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, bulk.ActionStatus)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.id, "id", "", "ID of the peer")
+	c.addFlags(f)
+	return cmd
+}
@@ -0,0 +1,203 @@
+package peer
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/audit"
+	"hlf-easy/dashboard"
+	"hlf-easy/fabric"
+	"hlf-easy/lock"
+	"hlf-easy/node"
+	"hlf-easy/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type peerUpgradeCmd struct {
+	peerID        string
+	fabricVersion string
+	token         string
+	wait          time.Duration
+	dryRun        bool
+}
+
+func (c *peerUpgradeCmd) validate() error {
+	if c.peerID == "" {
+		return errors.New("the id of the peer is required")
+	}
+	if c.fabricVersion == "" {
+		return errors.New("--fabric-version is required")
+	}
+	_, err := fabric.ParseVersion(c.fabricVersion)
+	return err
+}
+
+// isRunning returns true when the process of the peer is running, the peer
+// is stopped when its hlf-easy process isn't running
+func isRunning(n *dashboard.Node) bool {
+	return n.Running && n.Status != nil && n.Status.Status != "Stop"
+}
+
+// waitRunning checks that the peer keeps running for a while after it's
+// started
+func waitRunning(peerID string, token string, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+	for {
+		time.Sleep(time.Second)
+		n, err := dashboard.GetNode(dashboard.KindPeer, peerID, token)
+		if err != nil {
+			return err
+		}
+		if n.Error != "" {
+			return errors.New(n.Error)
+		}
+		if !isRunning(n) {
+			return errors.Errorf("peer %s stopped after it started", peerID)
+		}
+		if time.Now().After(deadline) {
+			return nil
+		}
+	}
+}
+
+func (c *peerUpgradeCmd) run(out io.Writer) error {
+	peerDir, err := utils.GetNodeDir("peer", c.peerID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(peerDir, "init.json")); err != nil {
+		return errors.Errorf("peer %s not found", c.peerID)
+	}
+	to, err := fabric.ParseVersion(c.fabricVersion)
+	if err != nil {
+		return err
+	}
+	from, err := node.GetPeerFabricVersion(peerDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the fabric version of the peer")
+	}
+	capabilities, err := fabric.ChannelCapabilities(peerDir)
+	if err != nil {
+		return err
+	}
+	plan, err := fabric.CheckUpgrade(from, to, capabilities)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Peer %s can be upgraded from fabric %s to %s, %d channels checked\n", c.peerID, from, to, len(capabilities))
+	if plan.UpgradeDBs {
+		fmt.Fprintln(out, "The state and history databases are rebuilt by the new version, the upgrade can't be rolled back")
+	}
+	if c.dryRun {
+		return nil
+	}
+	// the lock is held through the stop and start of the peer by its
+	// management API, the other operations on the peer wait for the upgrade
+	l, err := lock.Acquire(dashboard.KindPeer, c.peerID, "peer.upgrade", audit.LocalActor(), lock.Wait, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := l.Release(); err != nil {
+			log.Warnf("Failed to release the lock of peer %s: %v", c.peerID, err)
+		}
+	}()
+
+	binDir, err := fabric.Install(to)
+	if err != nil {
+		return err
+	}
+	binary := filepath.Join(binDir, fabric.BinaryFile("peer"))
+	installed, err := fabric.BinaryVersion(binary)
+	if err != nil {
+		return err
+	}
+	if installed.Compare(to) != 0 {
+		return errors.Errorf("the binary %s is fabric %s instead of %s", binary, installed, to)
+	}
+
+	n, err := dashboard.GetNode(dashboard.KindPeer, c.peerID, c.token)
+	if err != nil {
+		return err
+	}
+	if n.Running && n.Error != "" {
+		return errors.Errorf("failed to get the status of peer %s: %s", c.peerID, n.Error)
+	}
+	running := isRunning(n)
+	if running {
+		log.Infof("Stopping peer %s", c.peerID)
+		err = dashboard.RunLockedAction(dashboard.KindPeer, c.peerID, "stop", c.token, l)
+		if err != nil {
+			return err
+		}
+	}
+	if plan.UpgradeDBs {
+		log.Infof("Upgrading the databases of peer %s", c.peerID)
+		err = node.UpgradePeerDBs(peerDir, binary)
+		if err != nil {
+			return err
+		}
+	}
+	previous, err := node.SetPeerFabricVersion(peerDir, to.String())
+	if err != nil {
+		return err
+	}
+	if !running {
+		fmt.Fprintf(out, "Peer %s runs fabric %s on its next start\n", c.peerID, to)
+		return nil
+	}
+
+	log.Infof("Starting peer %s with fabric %s", c.peerID, to)
+	err = dashboard.RunLockedAction(dashboard.KindPeer, c.peerID, "start", c.token, l)
+	if err == nil {
+		err = waitRunning(c.peerID, c.token, c.wait)
+	}
+	if err == nil {
+		fmt.Fprintf(out, "Peer %s upgraded to fabric %s\n", c.peerID, to)
+		return nil
+	}
+	if plan.UpgradeDBs {
+		return errors.Wrapf(err, "peer %s failed to start with fabric %s", c.peerID, to)
+	}
+	// the previous binary is restored, the ledger isn't changed by a failed
+	// start of a minor or patch version
+	log.Warnf("Peer %s failed to start with fabric %s, rolling back: %v", c.peerID, to, err)
+	_, rollbackErr := node.SetPeerFabricVersion(peerDir, previous)
+	if rollbackErr == nil {
+		_ = dashboard.RunLockedAction(dashboard.KindPeer, c.peerID, "stop", c.token, l)
+		rollbackErr = dashboard.RunLockedAction(dashboard.KindPeer, c.peerID, "start", c.token, l)
+	}
+	if rollbackErr != nil {
+		return errors.Wrapf(err, "peer %s failed to start with fabric %s and the rollback failed: %v", c.peerID, to, rollbackErr)
+	}
+	return errors.Wrapf(err, "peer %s failed to start with fabric %s, it's started again with fabric %s", c.peerID, to, from)
+}
+
+func newPeerUpgradeCommand(out io.Writer) *cobra.Command {
+	c := &peerUpgradeCmd{}
+	cmd := &cobra.Command{
+		Use:   "upgrade <id>",
+		Short: "Upgrade the fabric version of a peer, it's restarted when it's running",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				c.peerID = args[0]
+			}
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.peerID, "id", "", "ID of the peer to upgrade, it can also be passed as an argument")
+	f.StringVar(&c.fabricVersion, "fabric-version", "", "Fabric version to upgrade to, e.g. 2.5.4")
+	f.StringVar(&c.token, "token", "", "API token of the management API of the peer")
+	f.DurationVar(&c.wait, "wait", 15*time.Second, "Time the peer must keep running after the upgrade before it's rolled back")
+	f.BoolVar(&c.dryRun, "dry-run", false, "Only check the compatibility of the peer with the fabric version")
+	return cmd
+}
@@ -0,0 +1,76 @@
+package peer
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"io"
+)
+
+type validateCmd struct {
+	id                      string
+	listenAddress           string
+	chaincodeAddress        string
+	eventsAddress           string
+	operationsListenAddress string
+}
+
+// validation is the result of the validation of a peer
+type validation struct {
+	ID     string       `json:"id"`
+	Valid  bool         `json:"valid"`
+	Checks []node.Check `json:"checks"`
+}
+
+func (c *validateCmd) run(out io.Writer) error {
+	checks, err := node.ValidatePeer(c.id, []string{c.listenAddress, c.chaincodeAddress, c.eventsAddress, c.operationsListenAddress})
+	if err != nil {
+		return err
+	}
+	result := validation{ID: c.id, Valid: true, Checks: checks}
+	for _, check := range checks {
+		if check.Status == node.CheckFail {
+			result.Valid = false
+		}
+	}
+	err = output.Print(out, result, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "STATUS\tCHECK\tMESSAGE")
+		for _, check := range result.Checks {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", check.Status, check.Name, check.Message)
+		}
+		return w.Flush()
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Valid {
+		return errors.Errorf("peer %s failed the validation, fix the failed checks before starting it", c.id)
+	}
+	return nil
+}
+
+func newPeerValidateCommand(out io.Writer) *cobra.Command {
+	c := &validateCmd{}
+	cmd := &cobra.Command{
+		Use:   "validate <id>",
+		Short: "Check the MSP, certificates, core.yaml and ports of a peer before starting it",
+		Long: `Check the directory of a peer before starting it: the MSP layout, that the
+certificates match their keys and chain to the CAs of the MSP, the NodeOUs of
+config.yaml, that core.yaml parses and that the addresses the peer listens on
+are free. Pass the addresses given to peer start when they aren't the defaults.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.id = args[0]
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.listenAddress, "listen-address", "0.0.0.0:7051", "Listen address of the peer")
+	f.StringVar(&c.chaincodeAddress, "chaincode-address", "0.0.0.0:7052", "Chaincode address of the peer")
+	f.StringVar(&c.eventsAddress, "events-address", "0.0.0.0:7053", "Events address of the peer")
+	f.StringVar(&c.operationsListenAddress, "operations-listen-address", "0.0.0.0:9443", "Operations listen address of the peer")
+	return cmd
+}
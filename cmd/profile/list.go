@@ -0,0 +1,111 @@
+package profile
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"hlf-easy/output"
+	"hlf-easy/profile"
+	"io"
+)
+
+// profileInfo is a profile with its home directory, the default profile has
+// an empty name
+type profileInfo struct {
+	Name    string `json:"name"`
+	Home    string `json:"home"`
+	Current bool   `json:"current"`
+}
+
+func getProfileInfo(name string) (profileInfo, error) {
+	home, err := profile.Dir(name)
+	if err != nil {
+		return profileInfo{}, err
+	}
+	return profileInfo{Name: name, Home: home, Current: name == profile.Current()}, nil
+}
+
+// displayName is the name of a profile in the tables
+func displayName(name string) string {
+	if name == "" {
+		return "(default)"
+	}
+	return name
+}
+
+type listCmd struct{}
+
+func (c *listCmd) validate() error {
+	return nil
+}
+
+func (c *listCmd) run(out io.Writer, errOut io.Writer) error {
+	names, err := profile.List()
+	if err != nil {
+		return err
+	}
+	profiles := []profileInfo{}
+	for _, name := range append([]string{""}, names...) {
+		info, err := getProfileInfo(name)
+		if err != nil {
+			return err
+		}
+		profiles = append(profiles, info)
+	}
+	return output.Print(out, profiles, func(w io.Writer) error {
+		for _, info := range profiles {
+			current := ""
+			if info.Current {
+				current = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", current, displayName(info.Name), info.Home)
+		}
+		return nil
+	})
+}
+
+func newListCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &listCmd{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the profiles with their home directory, the current one marked with *",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
+
+type currentCmd struct{}
+
+func (c *currentCmd) validate() error {
+	return nil
+}
+
+func (c *currentCmd) run(out io.Writer, errOut io.Writer) error {
+	info, err := getProfileInfo(profile.Current())
+	if err != nil {
+		return err
+	}
+	return output.Print(out, info, func(w io.Writer) error {
+		fmt.Fprintf(w, "%s\t%s\n", displayName(info.Name), info.Home)
+		return nil
+	})
+}
+
+func newCurrentCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &currentCmd{}
+	cmd := &cobra.Command{
+		Use:   "current",
+		Short: "Show the profile of the commands, selected with --profile or HLF_EASY_PROFILE",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,27 @@
+package profile
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewProfileCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage several organizations from one host, each in its own profile",
+		Long: `Manage several organizations from one host, each in its own profile.
+A profile has its own hlf-easy directory, under ~/hlf-easy-profiles/<name>,
+so its own nodes, CAs, API tokens and daemon. The profile of a command is
+selected with --profile or the HLF_EASY_PROFILE environment variable, the
+default profile being ~/hlf-easy. A profile is created on its first use.`,
+		Example: `  hlf-easy --profile=org1 ca init --name=org1-ca
+  export HLF_EASY_PROFILE=org2
+  hlf-easy ca init --name=org2-ca`,
+	}
+	cmd.AddCommand(
+		newListCommand(out, errOut),
+		newCurrentCommand(out, errOut),
+		newRemoveCommand(out, errOut),
+	)
+	return cmd
+}
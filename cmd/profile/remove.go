@@ -0,0 +1,46 @@
+package profile
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/profile"
+	"io"
+)
+
+type removeCmd struct {
+	name string
+}
+
+func (c *removeCmd) validate() error {
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	return profile.Validate(c.name)
+}
+
+func (c *removeCmd) run(out io.Writer, errOut io.Writer) error {
+	err := profile.Remove(c.name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Profile %s removed\n", c.name)
+	return nil
+}
+
+func newRemoveCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &removeCmd{}
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a profile with its nodes, CAs and crypto material, its nodes must be stopped",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.name, "name", "", "Name of the profile")
+	return cmd
+}
@@ -0,0 +1,58 @@
+package report
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"hlf-easy/config"
+	"hlf-easy/output"
+	"hlf-easy/report"
+	"hlf-easy/utils"
+	"io"
+	"path/filepath"
+)
+
+type configCmd struct {
+	reportConfig config.ReportConfig
+}
+
+func (c *configCmd) validate() error {
+	return report.ValidateConfig(c.reportConfig)
+}
+
+func (c *configCmd) run(out io.Writer, errOut io.Writer) error {
+	// the scheduler may run from another directory
+	identity, err := filepath.Abs(c.reportConfig.Identity)
+	if err != nil {
+		return err
+	}
+	c.reportConfig.Identity = identity
+	err = utils.SaveReportConfig(&c.reportConfig)
+	if err != nil {
+		return err
+	}
+	return output.Print(out, c.reportConfig, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, "Report config saved")
+		return err
+	})
+}
+
+func newReportConfigCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &configCmd{}
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Configure the interval, formats, destination and signer of the reports",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.reportConfig.Interval, "interval", "24h", "Interval between two reports")
+	f.StringSliceVar(&c.reportConfig.Formats, "formats", []string{report.FormatJSON, report.FormatPDF}, "Formats of the reports: json and/or pdf")
+	f.StringVar(&c.reportConfig.Destination, "destination", "", "Directory or http(s) URL the reports are delivered to")
+	f.StringVar(&c.reportConfig.Identity, "identity", "", "Identity that signs the reports")
+	f.IntVar(&c.reportConfig.ExpiryWarningDays, "expiry-warning-days", 30, "Flag the certificates that expire within these days")
+	return cmd
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"hlf-easy/report"
+	"hlf-easy/utils"
+	"io"
+	"time"
+)
+
+type generateCmd struct {
+	destination string
+}
+
+func (c *generateCmd) validate() error {
+	return nil
+}
+
+func (c *generateCmd) run(out io.Writer, errOut io.Writer) error {
+	reportConfig, err := utils.GetReportConfig()
+	if err != nil {
+		return err
+	}
+	if c.destination != "" {
+		reportConfig.Destination = c.destination
+	}
+	files, err := report.GenerateAndDeliver(*reportConfig, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Fprintf(out, "Delivered %s to %s\n", file.Name, reportConfig.Destination)
+	}
+	return nil
+}
+
+func newReportGenerateCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &generateCmd{}
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate and deliver a compliance report now",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.destination, "destination", "", "Overrides the configured destination")
+	return cmd
+}
@@ -0,0 +1,20 @@
+package report
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewReportCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Generate signed compliance reports of the CAs and nodes of the host",
+	}
+	cmd.AddCommand(
+		newReportConfigCommand(out, errOut),
+		newReportGenerateCommand(out, errOut),
+		newReportScheduleCommand(out, errOut),
+		newReportVerifyCommand(out, errOut),
+	)
+	return cmd
+}
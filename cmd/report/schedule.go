@@ -0,0 +1,47 @@
+package report
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/proc"
+	"hlf-easy/report"
+	"hlf-easy/utils"
+	"io"
+)
+
+type scheduleCmd struct{}
+
+func (c *scheduleCmd) validate() error {
+	return nil
+}
+
+func (c *scheduleCmd) run(out io.Writer, errOut io.Writer) error {
+	reportConfig, err := utils.GetReportConfig()
+	if err != nil {
+		return err
+	}
+	err = report.ValidateConfig(*reportConfig)
+	if err != nil {
+		return err
+	}
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	log.Infof("Delivering compliance reports every %s to %s", reportConfig.Interval, reportConfig.Destination)
+	return report.Schedule(ctx, *reportConfig)
+}
+
+func newReportScheduleCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &scheduleCmd{}
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Generate and deliver a compliance report every configured interval",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	return cmd
+}
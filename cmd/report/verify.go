@@ -0,0 +1,126 @@
+package report
+
+import (
+	"crypto/x509"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/output"
+	"hlf-easy/report"
+	"hlf-easy/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type verifyCmd struct {
+	path      string
+	signature string
+	caCert    string
+}
+
+// verification is the result of the verification of a report
+type verification struct {
+	Report      string    `json:"report"`
+	Signer      string    `json:"signer"`
+	Issuer      string    `json:"issuer"`
+	GeneratedAt time.Time `json:"generatedAt,omitempty"`
+	Host        string    `json:"host,omitempty"`
+	Trusted     bool      `json:"trusted"`
+}
+
+func (c *verifyCmd) validate() error {
+	if c.path == "" {
+		return fmt.Errorf("the report to verify is required")
+	}
+	if c.signature != "" && filepath.Ext(c.path) == ".json" {
+		return fmt.Errorf("--signature is only used with PDF reports, the JSON reports embed their signature")
+	}
+	return nil
+}
+
+func (c *verifyCmd) run(out io.Writer) error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return err
+	}
+	result := verification{Report: c.path}
+	var crt *x509.Certificate
+	// the signer must have been valid when the report was generated, the
+	// generation time of a PDF report isn't signed apart from the document
+	signedAt := time.Now()
+	if filepath.Ext(c.path) == ".json" {
+		var r *report.Report
+		r, crt, err = report.VerifySignedJSON(data)
+		if err != nil {
+			return err
+		}
+		result.GeneratedAt = r.GeneratedAt
+		result.Host = r.Host
+		signedAt = r.GeneratedAt
+	} else {
+		signaturePath := c.signature
+		if signaturePath == "" {
+			signaturePath = c.path + ".sig"
+		}
+		signatureBytes, err := os.ReadFile(signaturePath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read the signature of %s, set it with --signature", c.path)
+		}
+		crt, err = report.VerifyDetached(data, signatureBytes)
+		if err != nil {
+			return err
+		}
+	}
+	result.Signer = crt.Subject.CommonName
+	result.Issuer = crt.Issuer.CommonName
+	if c.caCert != "" {
+		caBytes, err := os.ReadFile(c.caCert)
+		if err != nil {
+			return err
+		}
+		caCerts, err := utils.ParseX509CertificateChain(caBytes)
+		if err != nil {
+			return err
+		}
+		err = report.VerifySigner(crt, caCerts, signedAt)
+		if err != nil {
+			return err
+		}
+		result.Trusted = true
+	}
+	return output.Print(out, result, func(out io.Writer) error {
+		fmt.Fprintf(out, "Signature of %s is valid, signed by %q issued by %q\n", result.Report, result.Signer, result.Issuer)
+		if result.Host != "" {
+			fmt.Fprintf(out, "Report of %s generated at %s\n", result.Host, result.GeneratedAt.Format(time.RFC3339))
+		}
+		if !result.Trusted {
+			fmt.Fprintln(out, "The signer isn't checked against a CA, set --ca-cert to verify that it's an identity of the org")
+		}
+		return nil
+	})
+}
+
+func newReportVerifyCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &verifyCmd{}
+	cmd := &cobra.Command{
+		Use:   "verify <report>",
+		Short: "Verify the signature of a JSON or PDF compliance report",
+		Long: `Verify the signature of a compliance report. The JSON reports embed their
+signature, the signature of a PDF report is read from the .pdf.sig file next to it.
+With --ca-cert the certificate of the signer must also chain to the CA.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.path = args[0]
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.signature, "signature", "", "Signature file of a PDF report, defaults to the report path with .sig")
+	f.StringVar(&c.caCert, "ca-cert", "", "PEM file with the CA certificates the signer must chain to")
+	return cmd
+}
@@ -4,28 +4,189 @@ import (
 	"embed"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	auditlog "hlf-easy/audit"
+	"hlf-easy/cmd/alerts"
+	"hlf-easy/cmd/anomaly"
+	"hlf-easy/cmd/apitoken"
+	"hlf-easy/cmd/audit"
+	"hlf-easy/cmd/backup"
+	"hlf-easy/cmd/bench"
+	"hlf-easy/cmd/bundle"
 	"hlf-easy/cmd/ca"
+	"hlf-easy/cmd/chaincode"
+	"hlf-easy/cmd/channel"
+	"hlf-easy/cmd/daemon"
+	"hlf-easy/cmd/dashboard"
+	"hlf-easy/cmd/discover"
+	"hlf-easy/cmd/doctor"
+	"hlf-easy/cmd/gateway"
+	"hlf-easy/cmd/gitops"
+	"hlf-easy/cmd/host"
+	"hlf-easy/cmd/listen"
+	"hlf-easy/cmd/monitoring"
+	"hlf-easy/cmd/notify"
 	"hlf-easy/cmd/orderer"
+	"hlf-easy/cmd/org"
 	"hlf-easy/cmd/peer"
+	cmdprofile "hlf-easy/cmd/profile"
+	"hlf-easy/cmd/report"
+	"hlf-easy/cmd/sandbox"
+	"hlf-easy/cmd/secrets"
+	"hlf-easy/cmd/tasks"
+	"hlf-easy/cmd/wizard"
+	hlflog "hlf-easy/log"
+	"hlf-easy/output"
+	"hlf-easy/profile"
+	"os"
 )
 
 const (
 	hlfEasyDesc = ``
 )
 
+// auditedCommands are the commands that change the state of the host, they're
+// recorded in the audit log. The commands that keep running are recorded when
+// they start
+var auditedCommands = map[string]bool{
+	"ca init":                             false,
+	"ca enroll":                           false,
+	"ca enroll-batch":                     false,
+	"ca ceremony":                         false,
+	"ca unseal":                           false,
+	"ca start":                            true,
+	"peer init":                           false,
+	"peer start":                          true,
+	"peer stop":                           false,
+	"peer remove":                         false,
+	"peer join":                           false,
+	"peer upgrade":                        false,
+	"peer rebuild-dbs":                    false,
+	"peer reset":                          false,
+	"peer rollback":                       false,
+	"peer anchorpeers set":                false,
+	"peer csr generate":                   false,
+	"peer csr import":                     false,
+	"peer import":                         false,
+	"orderer init":                        false,
+	"orderer start":                       true,
+	"orderer cluster init":                false,
+	"orderer cluster start":               false,
+	"channel create":                      false,
+	"chaincode register":                  false,
+	"chaincode run":                       false,
+	"chaincode gc":                        false,
+	"chaincode invoke":                    false,
+	"chaincode deploy-sample":             false,
+	"chaincode collection add":            false,
+	"chaincode collection remove":         false,
+	"chaincode service set":               false,
+	"chaincode service remove":            false,
+	"chaincode external-builder scaffold": false,
+	"chaincode external-builder remove":   false,
+	"bench":                               false,
+	"host config":                         false,
+	"host service install":                false,
+	"host service remove":                 false,
+	"org invite-peer":                     false,
+	"org export":                          false,
+	"monitoring export":                   false,
+	"report config":                       false,
+	"notify add-webhook":                  false,
+	"notify remove-webhook":               false,
+	"gitops sync":                         true,
+	"anomaly add-rule":                    false,
+	"anomaly remove-rule":                 false,
+	"alerts apply":                        false,
+	"apitoken create":                     false,
+	"apitoken revoke":                     false,
+	"apitoken apply-policies":             false,
+	"tasks add":                           false,
+	"tasks remove":                        false,
+	"tasks run":                           false,
+	"backup restore":                      false,
+	"secrets move":                        false,
+	"profile remove":                      false,
+	"sandbox up":                          false,
+	"sandbox down":                        false,
+	"bundle import":                       false,
+	"daemon run":                          true,
+	"daemon start":                        false,
+	"daemon stop":                         false,
+	"daemon add":                          false,
+	"daemon remove":                       false,
+	"daemon node start":                   false,
+	"daemon node stop":                    false,
+	"daemon node restart":                 false,
+}
+
 // NewCmdHLFEasy creates a new root command for hlf-easy
 func NewCmdHLFEasy(views embed.FS) *cobra.Command {
+	var profileName string
 	cmd := &cobra.Command{
 		Use:          "hlf-easy",
 		Short:        "CLI to easily run Hyperledger Fabric on baremetal",
 		Long:         hlfEasyDesc,
 		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// the profile is activated first, every path of the command is
+			// in its home directory
+			if err := profile.Activate(profileName); err != nil {
+				return err
+			}
+			if err := hlflog.Configure(hlflog.Format); err != nil {
+				return err
+			}
+			hlflog.SetFields(commandFields(cmd))
+			return output.Validate(output.Format)
+		},
 	}
+	output.AddFlag(cmd.PersistentFlags())
+	hlflog.AddFlag(cmd.PersistentFlags())
+	cmd.PersistentFlags().StringVar(&profileName, "profile", os.Getenv(profile.EnvProfile), "Profile of the organization to manage, with its own hlf-easy directory, the default profile when empty")
 	logrus.SetLevel(logrus.DebugLevel)
+	// execute runs an hlf-easy command for the commands built on the others
+	execute := func(args []string) error {
+		// the command run keeps the log format, the fields of this one are
+		// restored once it returns
+		format := hlflog.Format
+		defer hlflog.SetFields(hlflog.GetFields())
+		root := NewCmdHLFEasy(views)
+		root.SetArgs(append(args, "--log-format", format, "--profile", profileName))
+		return root.Execute()
+	}
 	cmd.AddCommand(
 		ca.NewCACmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
 		peer.NewPeerCmd(cmd.OutOrStdout(), cmd.ErrOrStderr(), views),
 		orderer.NewOrdererCmd(cmd.OutOrStdout(), cmd.ErrOrStderr(), views),
+		channel.NewChannelCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		gateway.NewGatewayCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		host.NewHostCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		org.NewOrgCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		report.NewReportCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		chaincode.NewChaincodeCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		notify.NewNotifyCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		gitops.NewGitOpsCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		dashboard.NewDashboardCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		monitoring.NewMonitoringCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		anomaly.NewAnomalyCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		alerts.NewAlertsCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		apitoken.NewAPITokenCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		audit.NewAuditCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		tasks.NewTasksCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		backup.NewBackupCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		secrets.NewSecretsCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		wizard.NewInitCmd(cmd.OutOrStdout(), cmd.ErrOrStderr(), execute),
+		sandbox.NewSandboxCmd(cmd.OutOrStdout(), cmd.ErrOrStderr(), execute),
+		bundle.NewBundleCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		bench.NewBenchCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		daemon.NewDaemonCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		doctor.NewDoctorCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		listen.NewListenCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		discover.NewDiscoverCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
+		cmdprofile.NewProfileCmd(cmd.OutOrStdout(), cmd.ErrOrStderr()),
 	)
+	timeCommands(cmd)
+	auditlog.Commands(cmd, auditedCommands)
+	registerCompletions(cmd)
 	return cmd
 }
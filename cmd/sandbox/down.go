@@ -0,0 +1,61 @@
+package sandbox
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"hlf-easy/sandbox"
+	"io"
+)
+
+type downCmd struct {
+	remove bool
+}
+
+func (c *downCmd) validate() error {
+	return nil
+}
+
+func (c *downCmd) run(out io.Writer) error {
+	n, err := sandbox.Load()
+	if err != nil {
+		return err
+	}
+	err = n.Stop()
+	if saveErr := sandbox.Save(n); err == nil {
+		err = saveErr
+	}
+	if err != nil {
+		return err
+	}
+	if !c.remove {
+		fmt.Fprintln(out, "Sandbox stopped, remove it with sandbox down --remove")
+		return nil
+	}
+	err = n.Remove()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Sandbox removed")
+	return nil
+}
+
+func newSandboxDownCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &downCmd{}
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "Stop the nodes and the chaincode server of the sandbox",
+		Long: `Stop the nodes and the chaincode server of the sandbox, the peers and the
+orderer are stopped through their management API before their hlf-easy process
+is terminated. With --remove the nodes, the CAs, the cluster and the chaincode
+of the sandbox are deleted from the host.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVar(&c.remove, "remove", false, "Delete the nodes, CAs, cluster and chaincode of the sandbox once stopped")
+	return cmd
+}
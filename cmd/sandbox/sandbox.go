@@ -0,0 +1,18 @@
+package sandbox
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewSandboxCmd(out io.Writer, errOut io.Writer, execute func(args []string) error) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sandbox",
+		Short: "Run a network with several orgs on localhost to develop and test chaincodes",
+	}
+	cmd.AddCommand(
+		newSandboxUpCommand(out, errOut, execute),
+		newSandboxDownCommand(out, errOut),
+	)
+	return cmd
+}
@@ -0,0 +1,187 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"hlf-easy/samples"
+	"hlf-easy/sandbox"
+	"io"
+	"strings"
+	"time"
+)
+
+// joinAttempts is how many times a peer tries to join the channel, the
+// orderer serves the genesis block once the channel has a leader
+const joinAttempts = 10
+
+type upCmd struct {
+	spec    sandbox.Spec
+	timeout time.Duration
+	// execute runs an hlf-easy command, the CAs and the nodes are created
+	// with the commands a user would type so they're audited as usual
+	execute func(args []string) error
+}
+
+func (c *upCmd) validate() error {
+	if c.timeout <= 0 {
+		return errors.New("--timeout must be positive")
+	}
+	return c.spec.Validate()
+}
+
+func (c *upCmd) executeAll(commands [][]string) error {
+	for _, args := range commands {
+		err := c.execute(args)
+		if err != nil {
+			return errors.Wrapf(err, "hlf-easy %s failed", strings.Join(args, " "))
+		}
+	}
+	return nil
+}
+
+func (c *upCmd) join(ctx context.Context, commands [][]string) error {
+	for _, args := range commands {
+		var err error
+		for i := 0; i < joinAttempts; i++ {
+			err = c.execute(args)
+			if err == nil {
+				break
+			}
+			log.Warnf("Failed to join the channel, retrying: %v", err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(2 * time.Second):
+			}
+		}
+		if err != nil {
+			return errors.Wrapf(err, "hlf-easy %s failed", strings.Join(args, " "))
+		}
+	}
+	return nil
+}
+
+func (c *upCmd) up(ctx context.Context, n *sandbox.Network) error {
+	err := c.executeAll(n.InitCommands())
+	if err != nil {
+		return err
+	}
+	err = n.StartOrderer(ctx, c.timeout)
+	if err != nil {
+		return err
+	}
+	err = n.CreateChannel(ctx)
+	if err != nil {
+		return err
+	}
+	err = n.StartPeers(ctx, c.timeout)
+	if err != nil {
+		return err
+	}
+	joinCommands, err := n.JoinCommands()
+	if err != nil {
+		return err
+	}
+	err = c.join(ctx, joinCommands)
+	if err != nil {
+		return err
+	}
+	return n.DeployChaincode(ctx)
+}
+
+func (c *upCmd) run(out io.Writer) error {
+	if _, err := sandbox.Load(); err == nil {
+		return errors.New("the sandbox already exists, remove it with sandbox down --remove")
+	}
+	n, err := sandbox.New(c.spec)
+	if err != nil {
+		return err
+	}
+	err = n.CheckPorts()
+	if err != nil {
+		return err
+	}
+	err = sandbox.Save(n)
+	if err != nil {
+		return err
+	}
+	// an interrupt stops bringing the sandbox up, the processes started so
+	// far are recorded
+	ctx, stop := proc.NotifyContext(context.Background())
+	defer stop()
+	upErr := c.up(ctx, n)
+	// the processes started so far are recorded so sandbox down stops them
+	err = sandbox.Save(n)
+	if upErr != nil {
+		return errors.Wrap(upErr, "failed to bring the sandbox up, stop and remove it with sandbox down --remove")
+	}
+	if err != nil {
+		return err
+	}
+	return output.Print(out, n, func(out io.Writer) error {
+		w := output.NewTabWriter(out)
+		fmt.Fprintln(w, "NODE\tMSP ID\tENDPOINT\tOPERATIONS")
+		for _, m := range n.Cluster.Members {
+			fmt.Fprintf(w, "%s\t%s\t%s\t127.0.0.1:%d\n", m.ID, n.Cluster.MSPID, m.Endpoint(), m.OperationsPort)
+		}
+		for _, org := range n.Orgs {
+			for _, p := range org.Peers {
+				fmt.Fprintf(w, "%s\t%s\t%s\t127.0.0.1:%d\n", p.ID, org.MSPID, p.Endpoint(), p.OperationsPort)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		sample, err := samples.Get(n.Spec.Sample)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "\nChaincode %s, the %s sample, is committed on channel %s and served on %s. Its functions are:\n", n.Chaincode.Name, sample.Name, n.Spec.Channel, n.Chaincode.Address)
+		for _, function := range sample.Functions {
+			fmt.Fprintf(out, "  %s\n", function)
+		}
+		fmt.Fprintf(out, "Try it with:\n  hlf-easy chaincode query --peer-id %s --channel %s --name %s --args '[\"GetAllAssets\"]'\n", n.Orgs[0].Peers[0].ID, n.Spec.Channel, n.Chaincode.Name)
+		return nil
+	})
+}
+
+func newSandboxUpCommand(out io.Writer, errOut io.Writer, execute func(args []string) error) *cobra.Command {
+	c := &upCmd{execute: execute}
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Create and start a network with several orgs on localhost",
+		Long: `Create and start a network on localhost: a local CA per org and for the
+orderer, an orderer, the peers of every org, a channel with all the orgs and a
+sample chaincode committed on it, asset-transfer-basic unless set with --sample.
+The samples are bundled in hlf-easy and served by it, nothing is downloaded.
+
+Every node listens on its own ports, consecutive from --base-port, and its
+operations service on consecutive ports of 127.0.0.1 from --operations-port.
+The ports are checked to be free before anything is created. The nodes, CAs
+and chaincode are prefixed with sandbox so they don't collide with the other
+nodes of the host, the layout is written to $HOME/hlf-easy/sandbox/network.json.
+
+The nodes and the chaincode server run in the background, stop them with
+sandbox down and remove everything with sandbox down --remove.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.IntVar(&c.spec.Orgs, "orgs", sandbox.DefaultOrgs, "Number of application orgs")
+	f.IntVar(&c.spec.PeersPerOrg, "peers-per-org", sandbox.DefaultPeersPerOrg, "Number of peers of every org")
+	f.StringVar(&c.spec.Channel, "channel", sandbox.DefaultChannel, "Name of the channel of the orgs")
+	f.StringVar(&c.spec.Sample, "sample", sandbox.DefaultSample, fmt.Sprintf("Sample chaincode deployed on the channel: %s", strings.Join(samples.Names(), ", ")))
+	f.IntVar(&c.spec.BasePort, "base-port", sandbox.DefaultBasePort, "First port of the nodes and the chaincode server")
+	f.IntVar(&c.spec.OperationsPort, "operations-port", sandbox.DefaultOperationsPort, "First operations port of the nodes")
+	f.DurationVar(&c.timeout, "timeout", sandbox.DefaultStartTimeout, "Time every node has to become healthy")
+	return cmd
+}
@@ -0,0 +1,119 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/certs"
+	"hlf-easy/node"
+	"hlf-easy/output"
+	"hlf-easy/proc"
+	"hlf-easy/secrets"
+	"io"
+	"time"
+)
+
+type moveCmd struct {
+	kind string
+	id   string
+	to   string
+	// toSet tells an empty --to, moving the keys back, from a missing one
+	toSet   bool
+	timeout time.Duration
+}
+
+// moved is a private key moved to or from a secrets manager
+type moved struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	To   string `json:"to"`
+}
+
+func (c *moveCmd) validate() error {
+	if c.kind != "ca" && c.kind != "peer" && c.kind != "orderer" {
+		return errors.Errorf("invalid kind %s, expected ca, peer or orderer", c.kind)
+	}
+	if c.id == "" {
+		return errors.New("--id is required")
+	}
+	if !c.toSet {
+		return errors.New("--to is required, an empty --to moves the keys back to ~/hlf-easy")
+	}
+	if c.to != "" {
+		if _, err := secrets.Open(c.to); err != nil {
+			return err
+		}
+	}
+	if c.timeout < 0 {
+		return errors.New("--timeout can't be negative")
+	}
+	return nil
+}
+
+func (c *moveCmd) run(out io.Writer) error {
+	ctx, stop := proc.TimeoutContext(context.Background(), c.timeout)
+	defer stop()
+	var err error
+	if c.kind == "ca" {
+		err = certs.MoveCAKeys(ctx, c.id, c.to)
+	} else {
+		err = node.MoveSignKey(ctx, c.kind, c.id, c.to)
+	}
+	if err != nil {
+		return err
+	}
+	return output.Print(out, moved{Kind: c.kind, ID: c.id, To: c.to}, func(out io.Writer) error {
+		keys := "signing key"
+		if c.kind == "ca" {
+			keys = "keys"
+		}
+		if c.to == "" {
+			_, err := fmt.Fprintf(out, "Moved the %s of %s %s back to its directory\n", keys, c.kind, c.id)
+			return err
+		}
+		_, err := fmt.Fprintf(out, "Moved the %s of %s %s to %s, restart it to use them from there\n", keys, c.kind, c.id, c.to)
+		return err
+	})
+}
+
+func newMoveCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &moveCmd{}
+	cmd := &cobra.Command{
+		Use:   "move",
+		Short: "Move the private keys of a CA or of a node to a secrets manager, or back to ~/hlf-easy",
+		Long: `Move the private keys of a local CA, its CA and TLS CA keys, or the signing
+key of a peer or an orderer to a secrets manager, and remove them from
+~/hlf-easy. They're fetched when the CA enrolls an identity and when the node
+is started, the key of a node is written to a keystore of $XDG_RUNTIME_DIR
+while hlf-easy runs it.
+
+The secrets manager is one of:
+  vault://<mount>/<prefix>    the KV version 2 engine of HashiCorp Vault,
+                              with VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE
+  awssm://<region>/<prefix>   AWS Secrets Manager, with AWS_ACCESS_KEY_ID,
+                              AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+  azurekv://<vault>/<prefix>  Azure Key Vault, with AZURE_TENANT_ID,
+                              AZURE_CLIENT_ID and AZURE_CLIENT_SECRET
+
+An empty --to moves the keys back to ~/hlf-easy. The keys are removed from
+the secrets manager they leave. The TLS keys stay in ~/hlf-easy.`,
+		Example: `  hlf-easy secrets move --kind=ca --id=org1-ca --to=vault://secret/hlf-easy
+  hlf-easy secrets move --kind=peer --id=peer0 --to=awssm://eu-west-1/hlf-easy
+  hlf-easy secrets move --kind=peer --id=peer0 --to=`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c.toSet = cmd.Flags().Changed("to")
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out)
+		},
+	}
+	f := cmd.Flags()
+	f.StringVar(&c.kind, "kind", "peer", "Kind of the owner of the keys: ca, peer or orderer")
+	f.StringVar(&c.id, "id", "", "Name of the CA or ID of the node")
+	f.StringVar(&c.to, "to", "", "URL of the secrets manager, empty to move the keys back to ~/hlf-easy")
+	f.DurationVar(&c.timeout, "timeout", time.Minute, "How long to wait for the secrets managers")
+	return cmd
+}
@@ -0,0 +1,17 @@
+package secrets
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func NewSecretsCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Keep the private keys of the CAs and of the nodes in a secrets manager",
+	}
+	cmd.AddCommand(
+		newMoveCommand(out, errOut),
+	)
+	return cmd
+}
@@ -0,0 +1,282 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/config"
+	"hlf-easy/output"
+	"hlf-easy/tasks"
+	"hlf-easy/utils"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+type addCmd struct {
+	node nodeFlags
+	task config.TaskConfig
+}
+
+func (c *addCmd) validate() error {
+	if err := c.node.validate(); err != nil {
+		return err
+	}
+	if c.task.Name == "" {
+		return errors.New("--name is required")
+	}
+	return tasks.ValidateTask(c.node.kind, c.task)
+}
+
+func (c *addCmd) run(out io.Writer, errOut io.Writer) error {
+	// adding a task again replaces it
+	err := tasks.SaveTask(c.node.kind, c.node.id, c.task)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Task %s added to %s %s, it's scheduled while the node runs\n", c.task.Name, c.node.kind, c.node.id)
+	return nil
+}
+
+func newAddCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &addCmd{}
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "Add a task to a node, or replace the task with its name",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	c.node.addFlags(f)
+	f.StringVar(&c.task.Name, "name", "", "Name of the task")
+	f.StringVar(&c.task.Type, "type", "", fmt.Sprintf("Type of the task: %s", strings.Join(tasks.Types(), ", ")))
+	f.StringVar(&c.task.Schedule, "schedule", "", "Cron expression, e.g. \"0 3 * * *\", or @every <duration>, @hourly, @daily or @weekly")
+	f.StringToStringVar(&c.task.Params, "param", map[string]string{}, "Params of the task, e.g. --param=msp-dir=/etc/admin/msp")
+	f.BoolVar(&c.task.Disabled, "disabled", false, "Add the task without scheduling it")
+	return cmd
+}
+
+type removeCmd struct {
+	node nodeFlags
+	name string
+}
+
+func (c *removeCmd) validate() error {
+	if err := c.node.validate(); err != nil {
+		return err
+	}
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	return nil
+}
+
+func (c *removeCmd) run(out io.Writer, errOut io.Writer) error {
+	err := tasks.RemoveTask(c.node.kind, c.node.id, c.name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Task %s removed from %s %s\n", c.name, c.node.kind, c.node.id)
+	return nil
+}
+
+func newRemoveCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &removeCmd{}
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a task of a node",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	c.node.addFlags(f)
+	f.StringVar(&c.name, "name", "", "Name of the task")
+	return cmd
+}
+
+type listCmd struct {
+	node nodeFlags
+}
+
+func (c *listCmd) validate() error {
+	return c.node.validate()
+}
+
+func formatParams(params map[string]string) string {
+	names := []string{}
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := []string{}
+	for _, name := range names {
+		parts = append(parts, name+"="+params[name])
+	}
+	return strings.Join(parts, ",")
+}
+
+func (c *listCmd) run(out io.Writer, errOut io.Writer) error {
+	nodeTasks, err := utils.GetNodeTasks(c.node.kind, c.node.id)
+	if err != nil {
+		return err
+	}
+	statuses := []tasks.TaskStatus{}
+	for _, task := range nodeTasks.Tasks {
+		status := tasks.TaskStatus{TaskConfig: task}
+		if err := tasks.ValidateTask(c.node.kind, task); err != nil {
+			status.Error = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return output.Print(out, statuses, func(w io.Writer) error {
+		for _, task := range statuses {
+			state := "enabled"
+			if task.Disabled {
+				state = "disabled"
+			}
+			if task.Error != "" {
+				state = "invalid: " + task.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", task.Name, task.Type, task.Schedule, formatParams(task.Params), state)
+		}
+		return nil
+	})
+}
+
+func newListCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &listCmd{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the tasks of a node with their schedule and params",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	c.node.addFlags(cmd.Flags())
+	return cmd
+}
+
+type historyCmd struct {
+	node  nodeFlags
+	name  string
+	limit int
+}
+
+func (c *historyCmd) validate() error {
+	return c.node.validate()
+}
+
+func (c *historyCmd) run(out io.Writer, errOut io.Writer) error {
+	runs, err := tasks.GetHistory(c.node.kind, c.node.id, c.name)
+	if err != nil {
+		return err
+	}
+	if c.limit > 0 && len(runs) > c.limit {
+		runs = runs[:c.limit]
+	}
+	return output.Print(out, runs, func(w io.Writer) error {
+		for _, run := range runs {
+			result := run.Status
+			if run.Error != "" {
+				result += ": " + run.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%.1fs\t%s\n", run.StartedAt.Format(time.RFC3339), run.Task, run.Trigger, run.Duration, result)
+		}
+		return nil
+	})
+}
+
+func newHistoryCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &historyCmd{}
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the last runs of the tasks of a node, the last first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	c.node.addFlags(f)
+	f.StringVar(&c.name, "name", "", "Name of the task, all the tasks if empty")
+	f.IntVar(&c.limit, "limit", 20, "Maximum number of runs, 0 for all")
+	return cmd
+}
+
+type runCmd struct {
+	node nodeFlags
+	name string
+}
+
+func (c *runCmd) validate() error {
+	if err := c.node.validate(); err != nil {
+		return err
+	}
+	if c.name == "" {
+		return errors.New("--name is required")
+	}
+	return nil
+}
+
+func (c *runCmd) run(out io.Writer, errOut io.Writer) error {
+	n := tasks.Node{Kind: c.node.kind, ID: c.node.id}
+	nodeDir, err := utils.GetNodeDir(c.node.kind, c.node.id)
+	if err != nil {
+		return err
+	}
+	n.Dir = nodeDir
+	if c.node.kind == "peer" {
+		// the snapshots are requested to the endpoint the peer was started with
+		runConfig, err := utils.GetPeerRunConfig(c.node.id)
+		if err == nil {
+			n.MSPID = runConfig.Options.MSPID
+			n.Address = runConfig.Options.ExternalEndpoint
+		}
+	}
+	// the logs saved by the process of the node are only pruned by its
+	// scheduler or through its management API
+	run, err := tasks.NewScheduler(n).RunTask(context.Background(), c.name)
+	if err != nil {
+		return err
+	}
+	if run.Output != "" {
+		fmt.Fprintln(out, run.Output)
+	}
+	if run.Status == tasks.StatusFailed {
+		return errors.Errorf("task %s failed: %s", c.name, run.Error)
+	}
+	return nil
+}
+
+func newRunCommand(out io.Writer, errOut io.Writer) *cobra.Command {
+	c := &runCmd{}
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run a task of a node now, the run is recorded in its history",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	c.node.addFlags(f)
+	f.StringVar(&c.name, "name", "", "Name of the task")
+	return cmd
+}
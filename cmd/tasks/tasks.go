@@ -0,0 +1,53 @@
+package tasks
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"hlf-easy/utils"
+	"io"
+	"os"
+)
+
+func NewTasksCmd(out io.Writer, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tasks",
+		Short: "Manage the maintenance tasks run on a schedule by the nodes",
+	}
+	cmd.AddCommand(
+		newAddCommand(out, errOut),
+		newRemoveCommand(out, errOut),
+		newListCommand(out, errOut),
+		newHistoryCommand(out, errOut),
+		newRunCommand(out, errOut),
+	)
+	return cmd
+}
+
+// nodeFlags select the node of the tasks
+type nodeFlags struct {
+	kind string
+	id   string
+}
+
+func (n *nodeFlags) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&n.kind, "kind", "peer", "Kind of the node: peer or orderer")
+	f.StringVar(&n.id, "id", "", "ID of the node")
+}
+
+func (n *nodeFlags) validate() error {
+	if n.kind != "peer" && n.kind != "orderer" {
+		return errors.Errorf("invalid kind %s, expected peer or orderer", n.kind)
+	}
+	if n.id == "" {
+		return errors.New("--id is required")
+	}
+	nodeDir, err := utils.GetNodeDir(n.kind, n.id)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(nodeDir); err != nil {
+		return errors.Errorf("%s %s not found", n.kind, n.id)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package wizard
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"hlf-easy/wizard"
+	"io"
+	"os"
+	"strings"
+)
+
+type initCmd struct {
+	yes bool
+	// execute runs an hlf-easy command, the wizard runs the commands a user
+	// would type so they're audited as usual
+	execute func(args []string) error
+}
+
+func (c *initCmd) validate() error {
+	return nil
+}
+
+func (c *initCmd) run(in io.Reader, out io.Writer, errOut io.Writer) error {
+	h, err := wizard.LoadHost()
+	if err != nil {
+		return err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	p := wizard.NewPrompter(in, out)
+	answers, err := wizard.Ask(p, h, hostname)
+	if err != nil {
+		return err
+	}
+	commands := answers.Commands()
+	fmt.Fprintln(out, "\nThe organization is set up with:")
+	for _, args := range commands {
+		fmt.Fprintf(out, "  hlf-easy %s\n", strings.Join(args, " "))
+	}
+	if !c.yes {
+		ok, err := p.Confirm("Run them", true)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("aborted, nothing was written")
+		}
+	}
+	for _, args := range commands {
+		err = c.execute(args)
+		if err != nil {
+			return errors.Wrapf(err, "hlf-easy %s failed", strings.Join(args, " "))
+		}
+	}
+	fmt.Fprintf(out, "Peer %s of %s is ready, start it with hlf-easy peer start --id=%s --listen-address=0.0.0.0:%d\n", answers.PeerID, answers.MSPID, answers.PeerID, answers.Port)
+	return nil
+}
+
+func NewInitCmd(out io.Writer, errOut io.Writer, execute func(args []string) error) *cobra.Command {
+	c := &initCmd{execute: execute}
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Set up an organization, its CA and its first peer interactively",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			return c.run(cmd.InOrStdin(), out, errOut)
+		},
+	}
+	f := cmd.Flags()
+	f.BoolVarP(&c.yes, "yes", "y", false, "Run the commands without confirmation once the answers are validated")
+	return cmd
+}
@@ -0,0 +1,41 @@
+package config
+
+// AlertsConfig configures the rules that raise alerts on the health of the
+// nodes, it's stored in $HOME/hlf-easy/alerts.json and written from a rules
+// file
+type AlertsConfig struct {
+	Rules []AlertRule `json:"rules" yaml:"rules"`
+}
+
+// AlertRule raises an alert when its condition holds on a node for its
+// duration, and runs its actions when the alert fires
+type AlertRule struct {
+	Name string `json:"name" yaml:"name"`
+	// Condition is node_down, cpu_high, height_stalled, cert_expiring or
+	// disk_usage_high
+	Condition string `json:"condition" yaml:"condition"`
+	// Kind restricts the rule to the peers or the orderers, it applies to
+	// both when empty
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// IDs restricts the rule to some nodes, it applies to all the nodes of
+	// the kind when empty
+	IDs []string `json:"ids,omitempty" yaml:"ids,omitempty"`
+	// For is how long the condition must hold before the alert fires, e.g.
+	// 30s, it fires on the first check when empty
+	For string `json:"for,omitempty" yaml:"for,omitempty"`
+	// Threshold is the CPU percent of cpu_high, the days left of
+	// cert_expiring and the used percent of disk_usage_high
+	Threshold float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	// Actions run when the alert fires
+	Actions  []AlertAction `json:"actions" yaml:"actions"`
+	Disabled bool          `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+}
+
+// AlertAction is run when an alert fires
+type AlertAction struct {
+	// Type is webhook, restart or log
+	Type string `json:"type" yaml:"type"`
+	// URL and Format of the webhook, the format is json or slack
+	URL    string `json:"url,omitempty" yaml:"url,omitempty"`
+	Format string `json:"format,omitempty" yaml:"format,omitempty"`
+}
@@ -0,0 +1,23 @@
+package config
+
+// AnomalyConfig configures the rules that detect anomalies in the logs of the
+// nodes, it's stored in $HOME/hlf-easy/anomaly.json
+type AnomalyConfig struct {
+	// DisableDefaultRules only uses the rules of the config
+	DisableDefaultRules bool          `json:"disableDefaultRules,omitempty"`
+	Rules               []AnomalyRule `json:"rules"`
+}
+
+// AnomalyRule raises an alert when a log line matches its pattern threshold
+// times within its window
+type AnomalyRule struct {
+	Name string `json:"name"`
+	// Pattern is a regular expression matched against each log line
+	Pattern string `json:"pattern"`
+	// Severity is info, warning or critical
+	Severity string `json:"severity"`
+	// Threshold is the number of matches that raise an alert, 1 if not set
+	Threshold int `json:"threshold,omitempty"`
+	// Window in which the matches are counted, e.g. 5m
+	Window string `json:"window,omitempty"`
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"github.com/spf13/pflag"
+	"time"
+)
+
+// APIAuthOptions configure the authentication of a management API
+type APIAuthOptions struct {
+	// Mode is token (default), mtls or none
+	Mode string `json:"mode"`
+	// TLSCert and TLSKey serve the API over TLS, required with mtls
+	TLSCert string `json:"tlsCert,omitempty"`
+	TLSKey  string `json:"tlsKey,omitempty"`
+	// ClientCA verifies the client certificates with mtls
+	ClientCA string `json:"clientCA,omitempty"`
+	// OperatorOU is the OU of the client certificates with the operator role
+	// with mtls, the other client certificates are viewers
+	OperatorOU string `json:"operatorOU,omitempty"`
+	// AdminOU is the OU of the client certificates with the admin role with
+	// mtls, no client certificate has it when empty
+	AdminOU string `json:"adminOU,omitempty"`
+	// Socket is the Unix socket the API is also served on, only its owner can
+	// connect and its requests have the admin role without a token
+	Socket string `json:"socket,omitempty"`
+}
+
+// AddFlags registers the flags to configure the authentication of the API
+func (o *APIAuthOptions) AddFlags(f *pflag.FlagSet) {
+	f.StringVar(&o.Mode, "api-auth", "token", "Authentication of the management API: token, mtls or none")
+	f.StringVar(&o.TLSCert, "api-tls-cert", "", "TLS certificate of the management API, it's served over plain HTTP if empty")
+	f.StringVar(&o.TLSKey, "api-tls-key", "", "TLS key of the management API")
+	f.StringVar(&o.ClientCA, "api-client-ca", "", "CA of the client certificates of the management API with mtls")
+	f.StringVar(&o.OperatorOU, "api-operator-ou", "admin", "OU of the client certificates with the operator role with mtls")
+	f.StringVar(&o.AdminOU, "api-admin-ou", "", "OU of the client certificates with the admin role with mtls")
+}
+
+// APIToken is a token of the management APIs of the host, only its hash is
+// stored
+type APIToken struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// APITokens are stored in $HOME/hlf-easy/apitokens.json
+type APITokens struct {
+	Tokens []APIToken `json:"tokens"`
+}
+
+// APIPolicies are the roles of the tokens and of the client certificates of
+// the management APIs, they're stored in $HOME/hlf-easy/apipolicies.json
+type APIPolicies struct {
+	Roles []APIRole `json:"roles" yaml:"roles"`
+}
+
+// APIRole grants the actions of its rules on their resources
+type APIRole struct {
+	Name  string    `json:"name" yaml:"name"`
+	Rules []APIRule `json:"rules" yaml:"rules"`
+}
+
+// APIRule grants actions, read or write, on resources of the APIs, * matches
+// all of them
+type APIRule struct {
+	Resources []string `json:"resources" yaml:"resources"`
+	Actions   []string `json:"actions" yaml:"actions"`
+}
@@ -0,0 +1,43 @@
+package config
+
+// OrdererBundle describes an ordering service operated by a third party, it's
+// handed over by the ordering service operator to the orgs that create or
+// join application channels on it
+type OrdererBundle struct {
+	// MSPID of the orderer organization
+	MSPID string `json:"mspID" yaml:"mspID"`
+	// CACerts are the PEM encoded root certificates of the orderer organization
+	CACerts []string `json:"caCerts" yaml:"caCerts"`
+	// TLSCACerts are the PEM encoded TLS root certificates of the orderer organization
+	TLSCACerts []string        `json:"tlsCACerts" yaml:"tlsCACerts"`
+	Orderers   []BundleOrderer `json:"orderers" yaml:"orderers"`
+	// ConsensusType of the ordering service, etcdraft when empty or BFT
+	ConsensusType string `json:"consensusType,omitempty" yaml:"consensusType,omitempty"`
+}
+
+// BundleOrderer is a consenter of the ordering service
+type BundleOrderer struct {
+	// Host and Port of the orderer endpoint used by peers and clients
+	Host string `json:"host" yaml:"host"`
+	Port int    `json:"port" yaml:"port"`
+	// AdminURL is the channel participation API of the orderer, e.g.
+	// https://orderer0.example.com:7053, it's optional
+	AdminURL string `json:"adminURL,omitempty" yaml:"adminURL,omitempty"`
+	// TLSCert is the PEM encoded TLS certificate of the consenter
+	TLSCert string `json:"tlsCert" yaml:"tlsCert"`
+	// ID of the consenter in a BFT ordering service, the position of the
+	// orderer in the bundle starting from 1 when empty
+	ID uint32 `json:"id,omitempty" yaml:"id,omitempty"`
+	// Identity is the PEM encoded signing certificate of the consenter, the
+	// blocks of a BFT ordering service are signed with it
+	Identity string `json:"identity,omitempty" yaml:"identity,omitempty"`
+}
+
+// ConsenterID returns the ID of the orderer at the index of the bundle in a
+// BFT consenter set
+func (o BundleOrderer) ConsenterID(index int) uint32 {
+	if o.ID != 0 {
+		return o.ID
+	}
+	return uint32(index + 1)
+}
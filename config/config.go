@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type CAConfig struct {
 	CaCert    []byte `json:"caCert"`
 	CaKey     []byte `json:"caKey"`
@@ -8,6 +10,15 @@ type CAConfig struct {
 	TlsCAKey  []byte `json:"tlsCAKey"`
 	TlsCert   []byte `json:"tlsCert"`
 	TlsKey    []byte `json:"tlsKey"`
+	// CertPolicy is the default policy of the certificates issued by the CA
+	CertPolicy CertificatePolicy `json:"certPolicy"`
+	// PreviousCaCerts and PreviousTlsCACerts are the roots replaced by a
+	// rotation of the signing or TLS CA, trusted until it's completed
+	PreviousCaCerts    [][]byte `json:"previousCaCerts,omitempty"`
+	PreviousTlsCACerts [][]byte `json:"previousTlsCACerts,omitempty"`
+	// Secrets is the URL of the secrets manager holding CaKey and TlsCAKey,
+	// they're empty in the config.json of the CA then
+	Secrets string `json:"secrets,omitempty"`
 }
 type PeerRunConfig struct {
 	PeerID  string           `json:"peerID"`
@@ -22,6 +33,9 @@ type PeerConfig struct {
 	SignKey  []byte `json:"signKey"`
 	SignCert []byte `json:"signCert"`
 	PeerID   string `json:"peerID"`
+	// Secrets is the URL of the secrets manager holding SignKey, it's empty
+	// and the keystore has no key then
+	Secrets string `json:"secrets,omitempty"`
 }
 type OrdererConfig struct {
 	TlsCACert []byte `json:"tlsCACert"`
@@ -32,6 +46,9 @@ type OrdererConfig struct {
 	SignKey  []byte `json:"signKey"`
 	SignCert []byte `json:"signCert"`
 	PeerID   string `json:"peerID"`
+	// Secrets is the URL of the secrets manager holding SignKey, it's empty
+	// and the keystore has no key then
+	Secrets string `json:"secrets,omitempty"`
 }
 
 type OrdererRunConfig struct {
@@ -50,6 +67,20 @@ type OrdererInitOptions struct {
 	CAName string `json:"caName"`
 
 	Hosts []string `json:"hosts"`
+	// ExternalEndpoint is the endpoint the orderer is advertised on, behind a
+	// reverse proxy or a load balancer, the default of orderer start. Its
+	// host is added to the SANs of the TLS certificate
+	ExternalEndpoint string `json:"externalEndpoint,omitempty"`
+	// CertPolicy overrides the certificate policy of the CA for this node
+	CertPolicy CertificatePolicy `json:"certPolicy"`
+	// Resources reserved for the node on the host
+	Resources NodeResources `json:"resources"`
+	// Limits of the orderer process, applied when it's started
+	Limits NodeLimits `json:"limits"`
+	// Hooks run on the start, stop and crash of the orderer process
+	Hooks NodeHooks `json:"hooks,omitempty"`
+	// TLSPolicy constrains the TLS of the listeners of the orderer
+	TLSPolicy TLSPolicyOptions `json:"tlsPolicy"`
 }
 type PeerInitOptions struct {
 	CAUrl        string `json:"caUrl"`
@@ -61,8 +92,52 @@ type PeerInitOptions struct {
 
 	Local  bool   `json:"local"`
 	CAName string `json:"caName"`
+	// TLSCAName is the Fabric CA that issues the TLS certificate, CAName is used when empty
+	TLSCAName string `json:"tlsCAName,omitempty"`
+	// ExternalCA is set when the certificates are signed by an external CA from CSRs
+	ExternalCA bool `json:"externalCA"`
+	// MSPID and ExternalEndpoint are the defaults of peer start, the gossip
+	// bootstrap of the peers with the same MSPID in the host is wired automatically.
+	// An ExternalEndpoint behind a reverse proxy or a load balancer is added to
+	// the SANs of the TLS certificate when the hosts don't cover it
+	MSPID            string `json:"mspID,omitempty"`
+	ExternalEndpoint string `json:"externalEndpoint,omitempty"`
+	// ExternalPort is used with the first host when ExternalEndpoint is empty
+	ExternalPort int `json:"externalPort,omitempty"`
+	// GossipBootstrap are endpoints of peers of the org in other hosts
+	GossipBootstrap []string `json:"gossipBootstrap,omitempty"`
+	// GossipState configures the state transfer of the peer
+	GossipState GossipStateOptions `json:"gossipState"`
+	// Gateway configures the Fabric Gateway service of the peer
+	Gateway GatewayOptions `json:"gateway"`
+	// Operations configures the operations endpoint and the metrics of the peer
+	Operations OperationsOptions `json:"operations"`
+	// VM configures the docker daemon the peer builds its chaincodes with
+	VM VMOptions `json:"vm"`
+	// TLSPolicy constrains the TLS of the listeners of the peer
+	TLSPolicy TLSPolicyOptions `json:"tlsPolicy"`
 
 	Hosts []string `json:"hosts"`
+	// CertPolicy overrides the certificate policy of the CA for this node
+	CertPolicy CertificatePolicy `json:"certPolicy"`
+	// Resources reserved for the node on the host
+	Resources NodeResources `json:"resources"`
+	// Limits of the peer process, applied when it's started
+	Limits NodeLimits `json:"limits"`
+	// OrdererOverrides are written in the deliveryclient of the peer, they're
+	// set when the peer joins a channel with unreachable orderers
+	OrdererOverrides []OrdererOverride `json:"ordererOverrides,omitempty"`
+	// FabricVersion is the version of the peer binary managed by hlf-easy, the
+	// peer in the PATH is used when it's empty
+	FabricVersion string `json:"fabricVersion,omitempty"`
+	// DevMode runs the chaincodes of the peer as processes started by their
+	// developers, set by peer start --dev-mode
+	DevMode bool `json:"devMode,omitempty"`
+	// Env are extra environment variables of the peer process, they override
+	// the ones set by hlf-easy
+	Env EnvVars `json:"env,omitempty"`
+	// Hooks run on the start, stop and crash of the peer process
+	Hooks NodeHooks `json:"hooks,omitempty"`
 }
 type StartPeerOpts struct {
 	ID string
@@ -74,10 +149,28 @@ type StartPeerOpts struct {
 
 	ExternalEndpoint string
 	MSPID            string
+	// GossipBootstrap are the endpoints of the peers of the org, the external
+	// endpoint of the peer is used when empty
+	GossipBootstrap []string
 
 	MSPConfigPath string
+	// KeystorePath is the keystore of the signing key fetched from a secrets
+	// manager, the keystore of MSPConfigPath when empty
+	KeystorePath string
 
 	ConfigPeerPath string
+	// Binary is the path of the peer binary, the one in the PATH when empty
+	Binary string
+	// LogSpec is the Fabric logging spec, info when empty
+	LogSpec string
+	// DevMode starts the peer in chaincode dev mode, without TLS since the
+	// chaincodes of the developers connect without it
+	DevMode bool
+	// Operations configures the operations endpoint and the metrics, it's
+	// bound to OperationsListenAddress
+	Operations OperationsOptions
+	// Env are extra environment variables of the peer process
+	Env EnvVars
 }
 
 type StartOrdererOpts struct {
@@ -91,8 +184,13 @@ type StartOrdererOpts struct {
 	MSPID            string
 
 	MSPConfigPath string
+	// KeystorePath is the keystore of the signing key fetched from a secrets
+	// manager, the keystore of the MSP directory when empty
+	KeystorePath string
 
 	ConfigOrdererPath string
+	// LogSpec is the Fabric logging spec, info when empty
+	LogSpec string
 }
 type PeerStartOptions struct {
 	ID                      string `json:"id"`
@@ -103,6 +201,19 @@ type PeerStartOptions struct {
 	ExternalEndpoint        string `json:"externalEndpoint"`
 	MSPID                   string `json:"mspID"`
 	ManagementAddress       string `json:"managementAddress"`
+	// Auth configures the authentication of the management API
+	Auth APIAuthOptions `json:"auth"`
+	// DevMode starts the peer in chaincode dev mode
+	DevMode bool `json:"devMode,omitempty"`
+	// HeightLagThreshold is the number of blocks the peer can be behind the
+	// other peers of its org on the host before it's flagged as lagging
+	HeightLagThreshold uint64 `json:"heightLagThreshold,omitempty"`
+	// DiskUsageThreshold is the used percent of the filesystem of the ledger
+	// above which the peer is flagged as running out of disk
+	DiskUsageThreshold float64 `json:"diskUsageThreshold,omitempty"`
+	// ReadyTimeout is how long the started peer has to answer its health
+	// checks, the start fails when it doesn't
+	ReadyTimeout time.Duration `json:"readyTimeout,omitempty"`
 }
 
 type OrdererStartOptions struct {
@@ -113,4 +224,6 @@ type OrdererStartOptions struct {
 	ExternalEndpoint        string `json:"externalEndpoint"`
 	MSPID                   string `json:"mspID"`
 	ManagementAddress       string `json:"managementAddress"`
+	// Auth configures the authentication of the management API
+	Auth APIAuthOptions `json:"auth"`
 }
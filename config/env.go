@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"sort"
+	"strings"
+)
+
+// EnvVars are extra environment variables of the process of a node, e.g.
+// CORE_* overrides or proxy settings, by name
+type EnvVars map[string]string
+
+// AddFlags registers the flag to set the extra environment variables of a
+// node, repeated once per variable
+func (e *EnvVars) AddFlags(f *pflag.FlagSet) {
+	f.Var(&envValue{vars: e}, "env", "Extra environment variable of the node process as NAME=VALUE, e.g. CORE_PEER_KEEPALIVE_MININTERVAL=30s, can be repeated")
+}
+
+// envValue parses a NAME=VALUE flag, the values can have commas, e.g.
+// NO_PROXY=localhost,10.0.0.1
+type envValue struct {
+	vars *EnvVars
+}
+
+func (v *envValue) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid environment variable %q, expected NAME=VALUE", s)
+	}
+	if *v.vars == nil {
+		*v.vars = EnvVars{}
+	}
+	(*v.vars)[name] = value
+	return nil
+}
+
+func (v *envValue) String() string {
+	if v.vars == nil {
+		return "[]"
+	}
+	names := make([]string, 0, len(*v.vars))
+	for name := range *v.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	vars := make([]string, 0, len(names))
+	for _, name := range names {
+		vars = append(vars, name+"="+(*v.vars)[name])
+	}
+	return "[" + strings.Join(vars, ",") + "]"
+}
+
+func (v *envValue) Type() string {
+	return "stringArray"
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"github.com/spf13/pflag"
+	"testing"
+)
+
+func TestEnvFlag(t *testing.T) {
+	var env EnvVars
+	f := pflag.NewFlagSet("init", pflag.ContinueOnError)
+	env.AddFlags(f)
+	err := f.Parse([]string{"--env", "NO_PROXY=localhost,10.0.0.1", "--env=CORE_PEER_KEEPALIVE_MININTERVAL=30s", "--env", "EMPTY="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := EnvVars{"NO_PROXY": "localhost,10.0.0.1", "CORE_PEER_KEEPALIVE_MININTERVAL": "30s", "EMPTY": ""}
+	if len(env) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, env)
+	}
+	for name, value := range expected {
+		if env[name] != value {
+			t.Errorf("expected %s to be %q, got %q", name, value, env[name])
+		}
+	}
+	if s := f.Lookup("env").Value.String(); s != "[CORE_PEER_KEEPALIVE_MININTERVAL=30s,EMPTY=,NO_PROXY=localhost,10.0.0.1]" {
+		t.Errorf("unexpected value of the flag %s", s)
+	}
+	if err := f.Parse([]string{"--env", "NO_PROXY"}); err == nil {
+		t.Error("expected a variable without a value to be refused")
+	}
+}
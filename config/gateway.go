@@ -0,0 +1,25 @@
+package config
+
+import "github.com/spf13/pflag"
+
+// GatewayOptions configure the Fabric Gateway service of a peer, used by the
+// clients of the fabric-gateway SDKs. The settings of Fabric are used for the
+// empty values
+type GatewayOptions struct {
+	// Disabled turns off the gateway, it's enabled by default like in Fabric
+	Disabled bool `json:"disabled,omitempty"`
+	// EndorsementTimeout is the time to wait for the endorsements of the other peers
+	EndorsementTimeout string `json:"endorsementTimeout,omitempty"`
+	// BroadcastTimeout is the time to wait for the orderers to accept a transaction
+	BroadcastTimeout string `json:"broadcastTimeout,omitempty"`
+	// DialTimeout is the time to wait for a connection to the other nodes
+	DialTimeout string `json:"dialTimeout,omitempty"`
+}
+
+// AddFlags registers the flags to configure the gateway of a peer
+func (o *GatewayOptions) AddFlags(f *pflag.FlagSet) {
+	f.BoolVar(&o.Disabled, "gateway-disabled", false, "Disable the Fabric Gateway service of the peer")
+	f.StringVar(&o.EndorsementTimeout, "gateway-endorsement-timeout", "", "Time the gateway waits for the endorsements of the other peers, 30s if empty")
+	f.StringVar(&o.BroadcastTimeout, "gateway-broadcast-timeout", "", "Time the gateway waits for the orderers to accept a transaction, 30s if empty")
+	f.StringVar(&o.DialTimeout, "gateway-dial-timeout", "", "Time the gateway waits for a connection to the other nodes, 2m if empty")
+}
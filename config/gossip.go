@@ -0,0 +1,30 @@
+package config
+
+import "github.com/spf13/pflag"
+
+// GossipStateOptions configure the state transfer of a peer, used by lagging
+// peers to catch up with the blocks of the other peers of the org. The
+// settings of Fabric are used for the empty values
+type GossipStateOptions struct {
+	Enabled bool `json:"enabled"`
+	// CheckInterval is the interval to check whether the peer is lagging behind
+	CheckInterval string `json:"checkInterval,omitempty"`
+	// ResponseTimeout is the time to wait for a state transfer response
+	ResponseTimeout string `json:"responseTimeout,omitempty"`
+	// BatchSize is the number of blocks requested in a state transfer
+	BatchSize int `json:"batchSize,omitempty"`
+	// BlockBufferSize is the size of the buffer that reorders the blocks
+	BlockBufferSize int `json:"blockBufferSize,omitempty"`
+	// MaxRetries of a state transfer request
+	MaxRetries int `json:"maxRetries,omitempty"`
+}
+
+// AddFlags registers the flags to configure the state transfer of a peer
+func (o *GossipStateOptions) AddFlags(f *pflag.FlagSet) {
+	f.BoolVar(&o.Enabled, "gossip-state-enabled", false, "Enable the gossip state transfer so the peer catches up with the blocks of the peers of its org")
+	f.StringVar(&o.CheckInterval, "gossip-state-check-interval", "", "Interval to check whether the peer is lagging behind, 10s if empty")
+	f.StringVar(&o.ResponseTimeout, "gossip-state-response-timeout", "", "Time to wait for a state transfer response, 3s if empty")
+	f.IntVar(&o.BatchSize, "gossip-state-batch-size", 0, "Number of blocks requested in a state transfer, 10 if 0")
+	f.IntVar(&o.BlockBufferSize, "gossip-state-block-buffer-size", 0, "Size of the buffer that reorders the blocks, 20 if 0")
+	f.IntVar(&o.MaxRetries, "gossip-state-max-retries", 0, "Maximum retries of a state transfer request, 3 if 0")
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"strings"
+)
+
+// Hook is a script or a webhook run on an event of the lifecycle of a node,
+// e.g. to mount a volume before the node starts
+type Hook struct {
+	// Event is pre-start, post-start, pre-stop or post-crash
+	Event string `json:"event"`
+	// Command is the path of the script run with the event in its environment
+	Command string `json:"command,omitempty"`
+	// URL is the webhook the event is posted to
+	URL string `json:"url,omitempty"`
+}
+
+// NodeHooks are the hooks of a node, run in order for each event
+type NodeHooks []Hook
+
+// AddFlags registers the flag to set the hooks of a node, repeated once per
+// hook
+func (h *NodeHooks) AddFlags(f *pflag.FlagSet) {
+	f.Var(&hooksValue{hooks: h}, "hook", "Hook run on an event of the node as EVENT=SCRIPT or EVENT=URL, the events are pre-start, post-start, pre-stop and post-crash, can be repeated")
+}
+
+// hooksValue parses an EVENT=SCRIPT or EVENT=URL flag, the http and https
+// targets are webhooks
+type hooksValue struct {
+	hooks *NodeHooks
+}
+
+func (v *hooksValue) Set(s string) error {
+	event, target, ok := strings.Cut(s, "=")
+	if !ok || event == "" || target == "" {
+		return fmt.Errorf("invalid hook %q, expected EVENT=SCRIPT or EVENT=URL", s)
+	}
+	hook := Hook{Event: event, Command: target}
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		hook = Hook{Event: event, URL: target}
+	}
+	*v.hooks = append(*v.hooks, hook)
+	return nil
+}
+
+func (v *hooksValue) String() string {
+	if v.hooks == nil {
+		return "[]"
+	}
+	hooks := make([]string, 0, len(*v.hooks))
+	for _, hook := range *v.hooks {
+		target := hook.Command
+		if hook.URL != "" {
+			target = hook.URL
+		}
+		hooks = append(hooks, hook.Event+"="+target)
+	}
+	return "[" + strings.Join(hooks, ",") + "]"
+}
+
+func (v *hooksValue) Type() string {
+	return "stringArray"
+}
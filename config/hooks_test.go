@@ -0,0 +1,31 @@
+package config
+
+import (
+	"github.com/spf13/pflag"
+	"testing"
+)
+
+func TestHooksFlag(t *testing.T) {
+	var hooks NodeHooks
+	f := pflag.NewFlagSet("init", pflag.ContinueOnError)
+	hooks.AddFlags(f)
+	err := f.Parse([]string{"--hook", "pre-start=/usr/local/bin/mount-ledger.sh", "--hook=post-crash=https://hooks.example.com/crash?token=a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := NodeHooks{
+		{Event: "pre-start", Command: "/usr/local/bin/mount-ledger.sh"},
+		{Event: "post-crash", URL: "https://hooks.example.com/crash?token=a=b"},
+	}
+	if len(hooks) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, hooks)
+	}
+	for i := range expected {
+		if hooks[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], hooks[i])
+		}
+	}
+	if err := f.Parse([]string{"--hook", "pre-start"}); err == nil {
+		t.Error("expected a hook without a target to be refused")
+	}
+}
@@ -1,15 +1,64 @@
 package config
 
-import "os"
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
 
 type SaveOutputWriter struct {
+	mu          sync.Mutex
 	savedOutput []byte
+	// Tee receives the output too, e.g. to scan it
+	Tee io.Writer
 }
 
 func (so *SaveOutputWriter) GetSavedOutput() []byte {
+	so.mu.Lock()
+	defer so.mu.Unlock()
 	return so.savedOutput
 }
 func (so *SaveOutputWriter) Write(p []byte) (n int, err error) {
+	so.mu.Lock()
 	so.savedOutput = append(so.savedOutput, p...)
+	so.mu.Unlock()
+	if so.Tee != nil {
+		_, _ = so.Tee.Write(p)
+	}
 	return os.Stdout.Write(p)
 }
+
+// Prune keeps the last maxBytes of the saved output, from the start of a
+// line, and returns the number of pruned bytes
+func (so *SaveOutputWriter) Prune(maxBytes int) int {
+	so.mu.Lock()
+	defer so.mu.Unlock()
+	if len(so.savedOutput) <= maxBytes {
+		return 0
+	}
+	start := len(so.savedOutput) - maxBytes
+	kept := so.savedOutput[start:]
+	if so.savedOutput[start-1] != '\n' {
+		for i, b := range kept {
+			if b == '\n' {
+				kept = kept[i+1:]
+				break
+			}
+		}
+	}
+	pruned := len(so.savedOutput) - len(kept)
+	// copy so the pruned output can be released
+	so.savedOutput = append([]byte(nil), kept...)
+	return pruned
+}
+
+// LogSpec is the Fabric logging spec of a node, it's applied on every start
+// of the node so the levels changed at runtime survive its restarts
+type LogSpec struct {
+	Spec      string    `json:"spec"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// UpdatedBy is the actor that changed the spec, or the operations
+	// endpoint when it was changed there directly
+	UpdatedBy string `json:"updatedBy"`
+}
@@ -0,0 +1,21 @@
+package config
+
+import "testing"
+
+func TestSaveOutputWriterPrune(t *testing.T) {
+	so := &SaveOutputWriter{}
+	so.Write([]byte("first line\nsecond line\nthird\n"))
+	if pruned := so.Prune(100); pruned != 0 {
+		t.Fatalf("expected nothing to be pruned under the limit, got %d", pruned)
+	}
+	// the last 10 bytes start in the middle of the second line
+	pruned := so.Prune(10)
+	if string(so.GetSavedOutput()) != "third\n" || pruned != 23 {
+		t.Fatalf("expected the output to be pruned to the start of a line, got %q and %d", so.GetSavedOutput(), pruned)
+	}
+	so.Write([]byte("fourth\n"))
+	so.Prune(7)
+	if string(so.GetSavedOutput()) != "fourth\n" {
+		t.Fatalf("expected a line starting at the limit to be kept, got %q", so.GetSavedOutput())
+	}
+}
@@ -0,0 +1,16 @@
+package config
+
+// NotifyConfig configures the webhooks notified of the events of the nodes of
+// the host, it's stored in $HOME/hlf-easy/notify.json
+type NotifyConfig struct {
+	Webhooks []WebhookConfig `json:"webhooks"`
+}
+
+// WebhookConfig is a webhook the events are posted to
+type WebhookConfig struct {
+	URL string `json:"url"`
+	// Format of the payload, json (default) or slack
+	Format string `json:"format,omitempty"`
+	// Events posted to the webhook, all of them if empty
+	Events []string `json:"events,omitempty"`
+}
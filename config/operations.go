@@ -0,0 +1,30 @@
+package config
+
+import "github.com/spf13/pflag"
+
+// OperationsOptions configure the operations endpoint of a peer, serving its
+// health, logging spec and metrics, and its metrics provider. The defaults of
+// hlf-easy are used for the empty values
+type OperationsOptions struct {
+	// ListenAddress is the address the operations endpoint binds to
+	ListenAddress string `json:"listenAddress,omitempty"`
+	// MetricsProvider is prometheus, statsd or disabled
+	MetricsProvider string `json:"metricsProvider,omitempty"`
+	// StatsdAddress is the statsd server the metrics are pushed to
+	StatsdAddress string `json:"statsdAddress,omitempty"`
+	// TLS serves the operations endpoint over TLS, with a certificate of its
+	// own when the peer is enrolled by the local CA
+	TLS bool `json:"tls,omitempty"`
+	// ClientAuthRequired requires a client certificate of the TLS CA of the
+	// peer, e.g. from Prometheus
+	ClientAuthRequired bool `json:"clientAuthRequired,omitempty"`
+}
+
+// AddFlags registers the flags to configure the operations endpoint of a peer
+func (o *OperationsOptions) AddFlags(f *pflag.FlagSet) {
+	f.StringVar(&o.ListenAddress, "operations-listen-address", "", "Address the operations endpoint binds to, 0.0.0.0:9443 if empty")
+	f.StringVar(&o.MetricsProvider, "metrics-provider", "", "Metrics provider of the peer, prometheus, statsd or disabled, prometheus if empty")
+	f.StringVar(&o.StatsdAddress, "statsd-address", "", "Address of the statsd server with --metrics-provider=statsd")
+	f.BoolVar(&o.TLS, "operations-tls", false, "Serve the operations endpoint over TLS, with a certificate of its own issued by the local CA")
+	f.BoolVar(&o.ClientAuthRequired, "operations-client-auth", false, "Require a client certificate of the TLS CA of the peer on the operations endpoint")
+}
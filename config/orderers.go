@@ -0,0 +1,10 @@
+package config
+
+// OrdererOverride replaces an orderer endpoint of the channel configs in the
+// deliveryclient of a peer, e.g. with a closer or reachable orderer
+type OrdererOverride struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// CACertsFile has the TLS root certificates of the orderer it's replaced with
+	CACertsFile string `json:"caCertsFile"`
+}
@@ -0,0 +1,65 @@
+package config
+
+import "github.com/spf13/pflag"
+
+// CertificatePolicy configures the certificates issued for a node, it can be set
+// per CA as a default for all the certificates it issues and overridden per node
+type CertificatePolicy struct {
+	// Validity is a duration such as 8760h, one year if empty
+	Validity string `json:"validity,omitempty"`
+	// KeyUsages replaces the default key usages, digitalSignature,
+	// keyEncipherment, certSign and crlSign
+	KeyUsages []string `json:"keyUsages,omitempty"`
+	// ExtKeyUsages replaces the default extended key usages, e.g. clientAuth, serverAuth
+	ExtKeyUsages []string `json:"extKeyUsages,omitempty"`
+	// SANs are extra subject alternative names added to the TLS certificates,
+	// prefixed by their type: dns:, ip:, email: or uri:. They are only taken
+	// from the policy of the node, never from the policy of the CA
+	SANs []string `json:"sans,omitempty"`
+	// SerialNumberPolicy is fixed (default, every certificate has the serial
+	// number 1), random or sequential. The serial numbers identify the
+	// revoked certificates, random or sequential must be used to revoke them
+	SerialNumberPolicy string `json:"serialNumberPolicy,omitempty"`
+	// Deterministic derives the keys of the certificates from the key of the
+	// CA and fixes their validity window and signatures, for reproducible test
+	// fixtures. Anyone with the name of a CA can derive its keys, it's never
+	// meant for a real network
+	Deterministic bool `json:"deterministic,omitempty"`
+}
+
+// Merge returns the policy with the fields set in override replacing its own,
+// the SANs are always the ones of override
+func (p CertificatePolicy) Merge(override CertificatePolicy) CertificatePolicy {
+	merged := p
+	if override.Validity != "" {
+		merged.Validity = override.Validity
+	}
+	if len(override.KeyUsages) > 0 {
+		merged.KeyUsages = override.KeyUsages
+	}
+	if len(override.ExtKeyUsages) > 0 {
+		merged.ExtKeyUsages = override.ExtKeyUsages
+	}
+	merged.SANs = override.SANs
+	if override.SerialNumberPolicy != "" {
+		merged.SerialNumberPolicy = override.SerialNumberPolicy
+	}
+	if override.Deterministic {
+		merged.Deterministic = true
+	}
+	return merged
+}
+
+// AddFlags registers the flags to configure the policy, except the SANs
+func (p *CertificatePolicy) AddFlags(f *pflag.FlagSet) {
+	f.StringVar(&p.Validity, "cert-validity", "", "Validity of the issued certificates, e.g. 8760h")
+	f.StringSliceVar(&p.KeyUsages, "cert-key-usages", []string{}, "Key usages of the issued certificates")
+	f.StringSliceVar(&p.ExtKeyUsages, "cert-ext-key-usages", []string{}, "Extended key usages of the issued certificates")
+	f.StringVar(&p.SerialNumberPolicy, "cert-serial-number-policy", "", "Serial number policy of the issued certificates: fixed, random or sequential")
+	f.BoolVar(&p.Deterministic, "deterministic", false, "Derive the keys of the issued certificates from the CA and fix their validity window, for reproducible test fixtures only")
+}
+
+// AddSANFlags registers the flag to set the extra SANs of a node
+func (p *CertificatePolicy) AddSANFlags(f *pflag.FlagSet) {
+	f.StringSliceVar(&p.SANs, "cert-sans", []string{}, "Extra SANs for the TLS certificates, e.g. dns:peer.example.com, email:ops@example.com")
+}
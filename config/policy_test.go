@@ -0,0 +1,51 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCertificatePolicyMerge(t *testing.T) {
+	caPolicy := CertificatePolicy{
+		Validity:           "8760h",
+		KeyUsages:          []string{"digitalSignature"},
+		ExtKeyUsages:       []string{"clientAuth", "serverAuth"},
+		SANs:               []string{"dns:ca.example.com"},
+		SerialNumberPolicy: "sequential",
+	}
+
+	t.Run("empty override keeps the CA policy without SANs", func(t *testing.T) {
+		merged := caPolicy.Merge(CertificatePolicy{})
+		expected := caPolicy
+		expected.SANs = nil
+		if !reflect.DeepEqual(merged, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, merged)
+		}
+	})
+
+	t.Run("override replaces the fields it sets", func(t *testing.T) {
+		override := CertificatePolicy{
+			Validity:     "720h",
+			ExtKeyUsages: []string{"serverAuth"},
+			SANs:         []string{"dns:peer.example.com"},
+		}
+		merged := caPolicy.Merge(override)
+		expected := CertificatePolicy{
+			Validity:           "720h",
+			KeyUsages:          []string{"digitalSignature"},
+			ExtKeyUsages:       []string{"serverAuth"},
+			SANs:               []string{"dns:peer.example.com"},
+			SerialNumberPolicy: "sequential",
+		}
+		if !reflect.DeepEqual(merged, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, merged)
+		}
+	})
+
+	t.Run("merge doesn't modify the CA policy", func(t *testing.T) {
+		caPolicy.Merge(CertificatePolicy{SANs: []string{"dns:peer.example.com"}})
+		if !reflect.DeepEqual(caPolicy.SANs, []string{"dns:ca.example.com"}) {
+			t.Fatalf("CA policy was modified: %+v", caPolicy)
+		}
+	})
+}
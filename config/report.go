@@ -0,0 +1,16 @@
+package config
+
+// ReportConfig configures the compliance reports of the host, it's stored in
+// $HOME/hlf-easy/report.json
+type ReportConfig struct {
+	// Interval between two reports, e.g. 24h
+	Interval string `json:"interval"`
+	// Formats of the report, json and/or pdf
+	Formats []string `json:"formats"`
+	// Destination is a directory or an http(s) URL the reports are posted to
+	Destination string `json:"destination"`
+	// Identity is the path to the identity that signs the reports
+	Identity string `json:"identity"`
+	// ExpiryWarningDays flags the certificates that expire within these days
+	ExpiryWarningDays int `json:"expiryWarningDays"`
+}
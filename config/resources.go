@@ -0,0 +1,45 @@
+package config
+
+import "github.com/spf13/pflag"
+
+// NodeResources are the resources reserved for a node on its host
+type NodeResources struct {
+	// CPUs reserved for the node, e.g. 0.5 or 2
+	CPUs float64 `json:"cpus,omitempty"`
+	// MemoryMB reserved for the node in megabytes
+	MemoryMB int64 `json:"memoryMB,omitempty"`
+}
+
+// AddFlags registers the flags to declare the resources of a node
+func (r *NodeResources) AddFlags(f *pflag.FlagSet) {
+	f.Float64Var(&r.CPUs, "cpus", 0, "CPUs reserved for the node on the host")
+	f.Int64Var(&r.MemoryMB, "memory-mb", 0, "Memory in megabytes reserved for the node on the host")
+}
+
+// HostConfig configures how the nodes of a host share its capacity, it's
+// stored in $HOME/hlf-easy/host.json
+type HostConfig struct {
+	// OvercommitThreshold is the fraction of the host CPUs and memory that can
+	// be reserved by the nodes, 1 if not set
+	OvercommitThreshold float64 `json:"overcommitThreshold,omitempty"`
+	// OvercommitPolicy is either warn (default) or refuse
+	OvercommitPolicy string `json:"overcommitPolicy,omitempty"`
+}
+
+// NodeLimits are the hard limits of the process of a node, enforced with
+// cgroup v2 on Linux and Job Objects on Windows
+type NodeLimits struct {
+	// CPUs the process can use, e.g. 0.5 or 2
+	CPUs float64 `json:"cpus,omitempty"`
+	// MemoryMB the process can use in megabytes
+	MemoryMB int64 `json:"memoryMB,omitempty"`
+	// Nice is the scheduling priority of the process, from -20 to 19
+	Nice int `json:"nice,omitempty"`
+}
+
+// AddFlags registers the flags to limit the process of a node
+func (l *NodeLimits) AddFlags(f *pflag.FlagSet) {
+	f.Float64Var(&l.CPUs, "limit-cpus", 0, "CPUs the node process can use, not limited if 0")
+	f.Int64Var(&l.MemoryMB, "limit-memory-mb", 0, "Memory in megabytes the node process can use, not limited if 0")
+	f.IntVar(&l.Nice, "nice", 0, "Scheduling priority of the node process, from -20 to 19")
+}
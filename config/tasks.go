@@ -0,0 +1,20 @@
+package config
+
+// TaskConfig is a maintenance task run on a schedule by the hlf-easy process
+// of a node
+type TaskConfig struct {
+	Name string `json:"name"`
+	// Type is snapshot, couchdb-compact, log-prune, cert-check or backup
+	Type string `json:"type"`
+	// Schedule is a cron expression, e.g. "0 3 * * *", or @every <duration>,
+	// @hourly, @daily or @weekly
+	Schedule string `json:"schedule"`
+	// Params of the task, they depend on its type
+	Params   map[string]string `json:"params,omitempty"`
+	Disabled bool              `json:"disabled,omitempty"`
+}
+
+// NodeTasks are the tasks of a node, stored in the tasks.json of its directory
+type NodeTasks struct {
+	Tasks []TaskConfig `json:"tasks"`
+}
@@ -0,0 +1,20 @@
+package config
+
+import "github.com/spf13/pflag"
+
+// TLSPolicyOptions constrain the TLS of the listeners of a node, for
+// deployments with a policy on the TLS versions and curves. The defaults of
+// Fabric are used for the empty values
+type TLSPolicyOptions struct {
+	// MinVersion is the lowest TLS version the node must accept, checked
+	// against the versions the Fabric listeners negotiate
+	MinVersion string `json:"minVersion,omitempty"`
+	// Curve of the keys of the TLS certificates of the node, P-256 or P-384
+	Curve string `json:"curve,omitempty"`
+}
+
+// AddFlags registers the flags to configure the TLS policy of a node
+func (o *TLSPolicyOptions) AddFlags(f *pflag.FlagSet) {
+	f.StringVar(&o.MinVersion, "tls-min-version", "", "Lowest TLS version the listeners of the node must accept, 1.2, the only version Fabric negotiates, if empty")
+	f.StringVar(&o.Curve, "tls-curve", "", "Curve of the keys of the TLS certificates of the node, P-256 or P-384, P-256 if empty")
+}
@@ -0,0 +1,30 @@
+package config
+
+import "github.com/spf13/pflag"
+
+// VMOptions configure the docker daemon a peer builds and runs its chaincodes
+// with, the chaincodes no external builder detects. The docker builds are
+// disabled when the endpoint is empty
+type VMOptions struct {
+	// Endpoint of the docker daemon, unix:///var/run/docker.sock, a local or
+	// remote tcp://, http:// or https:// address
+	Endpoint string `json:"endpoint,omitempty"`
+	// TLS authenticates the peer to a remote docker daemon with a client
+	// certificate, the daemon is verified with the CA certificate
+	TLS       bool   `json:"tls,omitempty"`
+	TLSCACert string `json:"tlsCACert,omitempty"`
+	TLSCert   string `json:"tlsCert,omitempty"`
+	TLSKey    string `json:"tlsKey,omitempty"`
+	// AttachStdout logs the output of the chaincode containers in the peer
+	AttachStdout bool `json:"attachStdout,omitempty"`
+}
+
+// AddFlags registers the flags to configure the docker daemon of a peer
+func (o *VMOptions) AddFlags(f *pflag.FlagSet) {
+	f.StringVar(&o.Endpoint, "vm-endpoint", "", "Endpoint of the docker daemon the peer builds its chaincodes with, e.g. unix:///var/run/docker.sock or tcp://docker.example.com:2376, the docker builds are disabled if empty")
+	f.BoolVar(&o.TLS, "vm-tls", false, "Connect to the docker daemon over TLS with a client certificate")
+	f.StringVar(&o.TLSCACert, "vm-tls-ca-cert", "", "CA certificate of the docker daemon, with --vm-tls")
+	f.StringVar(&o.TLSCert, "vm-tls-cert", "", "Client certificate of the peer for the docker daemon, with --vm-tls")
+	f.StringVar(&o.TLSKey, "vm-tls-key", "", "Client key of the peer for the docker daemon, with --vm-tls")
+	f.BoolVar(&o.AttachStdout, "vm-attach-stdout", false, "Log the output of the chaincode containers in the peer, for debugging")
+}
@@ -0,0 +1,299 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/node"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is how long a transaction has to be committed, or a query
+// to be evaluated
+const DefaultTimeout = 2 * time.Minute
+
+// Options select the peer, the identity and the chaincode function of a
+// transaction
+type Options struct {
+	PeerID string
+	// Identity is the identity file signing the transaction, the admin
+	// identity managed for the peer is used when empty
+	Identity string
+	// Endpoint overrides the external endpoint of the peer
+	Endpoint  string
+	Channel   string
+	Chaincode string
+	Function  string
+	Args      []string
+	// Transient data of the proposal, passed to the chaincode without being
+	// written to the ledger
+	Transient map[string][]byte
+}
+
+// ParseArgs parses the arguments of a chaincode function, either a JSON array
+// whose first element is the function, ["set","a","10"], or the object of the
+// peer CLI, {"Args":["set","a","10"]} or {"function":"set","Args":["a","10"]}
+func ParseArgs(value string) (string, []string, error) {
+	value = strings.TrimSpace(value)
+	var args []string
+	if strings.HasPrefix(value, "{") {
+		peerArgs := struct {
+			Function string   `json:"function"`
+			Args     []string `json:"Args"`
+		}{}
+		if err := json.Unmarshal([]byte(value), &peerArgs); err != nil {
+			return "", nil, errors.Wrapf(err, "invalid arguments %s", value)
+		}
+		if peerArgs.Function != "" {
+			return peerArgs.Function, peerArgs.Args, nil
+		}
+		args = peerArgs.Args
+	} else if err := json.Unmarshal([]byte(value), &args); err != nil {
+		return "", nil, errors.Wrapf(err, "invalid arguments %s, expected a JSON array of strings", value)
+	}
+	if len(args) == 0 || args[0] == "" {
+		return "", nil, errors.Errorf("no function in the arguments %s", value)
+	}
+	return args[0], args[1:], nil
+}
+
+// ParseTransient parses the transient data of a proposal, a JSON object of
+// strings, {"asset_properties":"{\"ID\":\"asset1\"}"}
+func ParseTransient(value string) (map[string][]byte, error) {
+	values := map[string]string{}
+	if err := json.Unmarshal([]byte(value), &values); err != nil {
+		return nil, errors.Wrapf(err, "invalid transient data %s, expected a JSON object of strings", value)
+	}
+	transient := map[string][]byte{}
+	for key, v := range values {
+		transient[key] = []byte(v)
+	}
+	return transient, nil
+}
+
+// ProfileUser is the user of the connection profile signing with the identity
+// of the client config
+const ProfileUser = "user"
+
+// networkConfig returns the connection profile of the SDK with the peer as
+// the only peer of the channel, the other endorsers and the orderers are
+// discovered through it. Without a channel the profile only has the peer
+func networkConfig(clientConfig *node.GatewayClientConfig, peerID string, channel string) ([]byte, error) {
+	grpcOptions := map[string]interface{}{
+		"allow-insecure": false,
+	}
+	if clientConfig.ServerName != "" {
+		grpcOptions["ssl-target-name-override"] = clientConfig.ServerName
+		grpcOptions["hostnameOverride"] = clientConfig.ServerName
+	}
+	profile := map[string]interface{}{
+		"version": "1.0.0",
+		"client": map[string]interface{}{
+			"organization": clientConfig.MSPID,
+		},
+		"organizations": map[string]interface{}{
+			clientConfig.MSPID: map[string]interface{}{
+				"mspid":      clientConfig.MSPID,
+				"cryptoPath": "/tmp/cryptopath",
+				"peers":      []string{peerID},
+				"users": map[string]interface{}{
+					ProfileUser: map[string]interface{}{
+						"cert": map[string]interface{}{"pem": clientConfig.Identity.Cert},
+						"key":  map[string]interface{}{"pem": clientConfig.Identity.Key},
+					},
+				},
+			},
+		},
+		"peers": map[string]interface{}{
+			peerID: map[string]interface{}{
+				"url":         "grpcs://" + clientConfig.Endpoint,
+				"grpcOptions": grpcOptions,
+				"tlsCACerts": map[string]interface{}{
+					"pem": clientConfig.TLSRootCerts,
+				},
+			},
+		},
+	}
+	if channel != "" {
+		profile["channels"] = map[string]interface{}{
+			channel: map[string]interface{}{
+				"peers": map[string]interface{}{
+					peerID: map[string]interface{}{
+						"endorsingPeer":  true,
+						"chaincodeQuery": true,
+						"ledgerQuery":    true,
+						"eventSource":    true,
+					},
+				},
+			},
+		}
+	}
+	return yaml.Marshal(profile)
+}
+
+// Profile returns the client config of the peer with the identity of the
+// options, the admin identity managed for the peer when empty, and the
+// connection profile of the SDK for the channel of the options
+func Profile(opts Options) (*node.GatewayClientConfig, []byte, error) {
+	identity := opts.Identity
+	if identity == "" {
+		var err error
+		identity, err = node.ManagedAdminIdentity(opts.PeerID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	clientConfig, err := node.NewGatewayClientConfig(node.GatewayClientConfigOptions{
+		PeerID:   opts.PeerID,
+		Identity: identity,
+		Endpoint: opts.Endpoint,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	networkConfigBytes, err := networkConfig(clientConfig, opts.PeerID, opts.Channel)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientConfig, networkConfigBytes, nil
+}
+
+// connect connects to the peer with the identity of the options and returns
+// the gateway, to close, and the channel of the options
+func connect(opts Options, gatewayOpts ...gateway.Option) (*gateway.Gateway, *gateway.Network, error) {
+	clientConfig, networkConfigBytes, err := Profile(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	wallet := gateway.NewInMemoryWallet()
+	err = wallet.Put(ProfileUser, gateway.NewX509Identity(clientConfig.MSPID, clientConfig.Identity.Cert, clientConfig.Identity.Key))
+	if err != nil {
+		return nil, nil, err
+	}
+	gw, err := gateway.Connect(
+		gateway.WithConfig(config.FromRaw(networkConfigBytes, "yaml")),
+		gateway.WithIdentity(wallet, ProfileUser),
+		gatewayOpts...,
+	)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to connect to peer %s", opts.PeerID)
+	}
+	network, err := gw.GetNetwork(opts.Channel)
+	if err != nil {
+		gw.Close()
+		return nil, nil, errors.Wrapf(err, "failed to get channel %s", opts.Channel)
+	}
+	return gw, network, nil
+}
+
+// transact connects to the peer with the identity of the options and calls
+// the function of the chaincode with it
+func transact(ctx context.Context, opts Options, call func(contract *gateway.Contract) ([]byte, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	gw, network, err := connect(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer gw.Close()
+	return withContext(ctx, opts.Chaincode, opts.Function, func() ([]byte, error) {
+		return call(network.GetContract(opts.Chaincode))
+	})
+}
+
+// withContext calls a function of a chaincode through the gateway of the SDK,
+// which takes no context, the call is abandoned when the context is done
+func withContext(ctx context.Context, chaincode string, function string, call func() ([]byte, error)) ([]byte, error) {
+	type result struct {
+		payload []byte
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		payload, err := call()
+		done <- result{payload: payload, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.payload, r.err
+	case <-ctx.Done():
+		return nil, errors.Wrapf(ctx.Err(), "%s of chaincode %s didn't complete", function, chaincode)
+	}
+}
+
+// submit submits a transaction calling a function of a chaincode and waits
+// for its commit
+func submit(contract *gateway.Contract, chaincode string, function string, args []string, transient map[string][]byte) ([]byte, error) {
+	tx, err := contract.CreateTransaction(function, gateway.WithTransient(transient))
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.Submit(args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to invoke %s of chaincode %s", function, chaincode)
+	}
+	return result, nil
+}
+
+// Invoke submits a transaction calling a function of a chaincode and waits
+// for its commit, it returns the result of the function
+func Invoke(ctx context.Context, opts Options) ([]byte, error) {
+	return transact(ctx, opts, func(contract *gateway.Contract) ([]byte, error) {
+		return submit(contract, opts.Chaincode, opts.Function, opts.Args, opts.Transient)
+	})
+}
+
+// Query evaluates a function of a chaincode on the peer without submitting a
+// transaction
+func Query(ctx context.Context, opts Options) ([]byte, error) {
+	return transact(ctx, opts, func(contract *gateway.Contract) ([]byte, error) {
+		tx, err := contract.CreateTransaction(opts.Function, gateway.WithTransient(opts.Transient))
+		if err != nil {
+			return nil, err
+		}
+		result, err := tx.Evaluate(opts.Args...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to query %s of chaincode %s", opts.Function, opts.Chaincode)
+		}
+		return result, nil
+	})
+}
+
+// Session keeps the connection to the peer for many transactions, the ones
+// of a benchmark, instead of connecting for each of them
+type Session struct {
+	opts     Options
+	gw       *gateway.Gateway
+	contract *gateway.Contract
+}
+
+// Open connects to the peer with the identity of the options for the
+// chaincode of the options, the session is closed by Close
+func Open(opts Options) (*Session, error) {
+	gw, network, err := connect(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{opts: opts, gw: gw, contract: network.GetContract(opts.Chaincode)}, nil
+}
+
+// Submit submits a transaction calling a function of the chaincode of the
+// session and waits for its commit, it's safe for concurrent use
+func (s *Session) Submit(ctx context.Context, function string, args []string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return withContext(ctx, s.opts.Chaincode, function, func() ([]byte, error) {
+		return submit(s.contract, s.opts.Chaincode, function, args, s.opts.Transient)
+	})
+}
+
+// Close closes the connection of the session
+func (s *Session) Close() {
+	s.gw.Close()
+}
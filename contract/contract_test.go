@@ -0,0 +1,90 @@
+package contract
+
+import (
+	"gopkg.in/yaml.v3"
+	"hlf-easy/node"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	for _, value := range []string{`["set","a","10"]`, `{"Args":["set","a","10"]}`, `{"function":"set","Args":["a","10"]}`} {
+		fn, args, err := ParseArgs(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fn != "set" || !reflect.DeepEqual(args, []string{"a", "10"}) {
+			t.Errorf("expected set [a 10] for %s, got %s %v", value, fn, args)
+		}
+	}
+	for _, value := range []string{`[]`, `{"Args":[]}`, `set a 10`, `[1,2]`} {
+		if _, _, err := ParseArgs(value); err == nil {
+			t.Errorf("expected an error for %s", value)
+		}
+	}
+}
+
+func TestParseTransient(t *testing.T) {
+	transient, err := ParseTransient(`{"asset_properties":"{\"ID\":\"asset1\"}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(transient["asset_properties"]) != `{"ID":"asset1"}` {
+		t.Errorf("unexpected transient data %v", transient)
+	}
+	if _, err := ParseTransient(`{"asset_properties":{"ID":"asset1"}}`); err == nil {
+		t.Error("expected an error for a value that isn't a string")
+	}
+}
+
+func TestNetworkConfig(t *testing.T) {
+	clientConfig := &node.GatewayClientConfig{
+		MSPID:        "Org1MSP",
+		Endpoint:     "10.0.0.1:7051",
+		ServerName:   "peer0.org1.example.com",
+		TLSRootCerts: "tls roots\n",
+	}
+	networkConfigBytes, err := networkConfig(clientConfig, "peer0", "mychannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := struct {
+		Client struct {
+			Organization string `yaml:"organization"`
+		} `yaml:"client"`
+		Peers map[string]struct {
+			URL         string                 `yaml:"url"`
+			GRPCOptions map[string]interface{} `yaml:"grpcOptions"`
+			TLSCACerts  struct {
+				Pem string `yaml:"pem"`
+			} `yaml:"tlsCACerts"`
+		} `yaml:"peers"`
+		Channels map[string]struct {
+			Peers map[string]map[string]bool `yaml:"peers"`
+		} `yaml:"channels"`
+	}{}
+	if err := yaml.Unmarshal(networkConfigBytes, &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.Client.Organization != "Org1MSP" {
+		t.Errorf("expected the client of Org1MSP, got %q", profile.Client.Organization)
+	}
+	peer := profile.Peers["peer0"]
+	if peer.URL != "grpcs://10.0.0.1:7051" || peer.GRPCOptions["ssl-target-name-override"] != "peer0.org1.example.com" || peer.TLSCACerts.Pem != "tls roots\n" {
+		t.Errorf("unexpected peer %+v", peer)
+	}
+	if !profile.Channels["mychannel"].Peers["peer0"]["endorsingPeer"] {
+		t.Error("expected the peer to endorse the transactions of the channel")
+	}
+	networkConfigBytes, err = networkConfig(clientConfig, "peer0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile.Channels = nil
+	if err := yaml.Unmarshal(networkConfigBytes, &profile); err != nil {
+		t.Fatal(err)
+	}
+	if len(profile.Channels) != 0 || profile.Peers["peer0"].URL != "grpcs://10.0.0.1:7051" {
+		t.Errorf("expected a profile with the peer and without channels, got %+v", profile)
+	}
+}
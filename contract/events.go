@@ -0,0 +1,148 @@
+package contract
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+	"github.com/pkg/errors"
+	"unicode/utf8"
+)
+
+// Types of the events
+const (
+	EventBlock     = "block"
+	EventChaincode = "chaincode"
+)
+
+// Event is a block committed on a channel or an event set by a chaincode in a
+// transaction of the block
+type Event struct {
+	Type        string `json:"type"`
+	Channel     string `json:"channel"`
+	BlockNumber uint64 `json:"blockNumber"`
+	// Transactions of a block event
+	Transactions []TransactionEvent `json:"transactions,omitempty"`
+	// TxID, Chaincode, Name and Payload of a chaincode event
+	TxID      string `json:"txId,omitempty"`
+	Chaincode string `json:"chaincode,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Payload is the payload of the chaincode event as JSON, or as a string
+	// when it isn't JSON, encoded in base64 when it isn't text
+	Payload         json.RawMessage `json:"payload,omitempty"`
+	PayloadEncoding string          `json:"payloadEncoding,omitempty"`
+}
+
+// TransactionEvent is a transaction of a block and whether the peer
+// validated it
+type TransactionEvent struct {
+	TxID           string `json:"txId"`
+	Type           string `json:"type"`
+	ValidationCode string `json:"validationCode"`
+}
+
+// ListenOptions select the peer, the identity and the channel the events are
+// received from, the events of the chaincode of the options are included
+// when it's set
+type ListenOptions struct {
+	Options
+	// EventFilter is a regular expression the names of the chaincode events
+	// match, all the events when empty
+	EventFilter string
+	// FromBlock is the block the events are replayed from, only the next
+	// blocks are received when negative
+	FromBlock int64
+}
+
+func blockEvent(channel string, block *peer.FilteredBlock) Event {
+	event := Event{Type: EventBlock, Channel: channel, BlockNumber: block.Number, Transactions: []TransactionEvent{}}
+	for _, tx := range block.FilteredTransactions {
+		event.Transactions = append(event.Transactions, TransactionEvent{
+			TxID:           tx.Txid,
+			Type:           tx.Type.String(),
+			ValidationCode: tx.TxValidationCode.String(),
+		})
+	}
+	return event
+}
+
+func chaincodeEvent(channel string, ccEvent *fab.CCEvent) Event {
+	event := Event{
+		Type:        EventChaincode,
+		Channel:     channel,
+		BlockNumber: ccEvent.BlockNumber,
+		TxID:        ccEvent.TxID,
+		Chaincode:   ccEvent.ChaincodeID,
+		Name:        ccEvent.EventName,
+	}
+	switch {
+	case len(ccEvent.Payload) == 0:
+	case json.Valid(ccEvent.Payload):
+		event.Payload = ccEvent.Payload
+	case utf8.Valid(ccEvent.Payload):
+		event.Payload, _ = json.Marshal(string(ccEvent.Payload))
+	default:
+		event.Payload, _ = json.Marshal(base64.StdEncoding.EncodeToString(ccEvent.Payload))
+		event.PayloadEncoding = "base64"
+	}
+	return event
+}
+
+// Listen receives the blocks committed on the channel, and the events of the
+// chaincode of the options, and passes them to handle until the context is
+// done or handle fails
+func Listen(ctx context.Context, opts ListenOptions, handle func(Event) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var gatewayOpts []gateway.Option
+	if opts.FromBlock >= 0 {
+		gatewayOpts = append(gatewayOpts, gateway.WithBlockNum(uint64(opts.FromBlock)))
+	}
+	gw, network, err := connect(opts.Options, gatewayOpts...)
+	if err != nil {
+		return err
+	}
+	defer gw.Close()
+	blockReg, blocks, err := network.RegisterFilteredBlockEvent()
+	if err != nil {
+		return errors.Wrapf(err, "failed to register for the blocks of channel %s", opts.Channel)
+	}
+	defer network.Unregister(blockReg)
+	var ccEvents <-chan *fab.CCEvent
+	if opts.Chaincode != "" {
+		filter := opts.EventFilter
+		if filter == "" {
+			filter = ".*"
+		}
+		cc := network.GetContract(opts.Chaincode)
+		ccReg, events, err := cc.RegisterEvent(filter)
+		if err != nil {
+			return errors.Wrapf(err, "failed to register for the events of chaincode %s", opts.Chaincode)
+		}
+		defer cc.Unregister(ccReg)
+		ccEvents = events
+	}
+	for {
+		var event Event
+		select {
+		case <-ctx.Done():
+			return nil
+		case block, ok := <-blocks:
+			if !ok {
+				return errors.Errorf("peer %s closed the event stream of channel %s", opts.PeerID, opts.Channel)
+			}
+			event = blockEvent(opts.Channel, block.FilteredBlock)
+		case ccEvent, ok := <-ccEvents:
+			if !ok {
+				return errors.Errorf("peer %s closed the event stream of chaincode %s", opts.PeerID, opts.Chaincode)
+			}
+			event = chaincodeEvent(opts.Channel, ccEvent)
+		}
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+}
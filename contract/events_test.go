@@ -0,0 +1,45 @@
+package contract
+
+import (
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	"testing"
+)
+
+func TestBlockEvent(t *testing.T) {
+	event := blockEvent("mychannel", &peer.FilteredBlock{
+		Number: 5,
+		FilteredTransactions: []*peer.FilteredTransaction{
+			{Txid: "tx1", Type: common.HeaderType_ENDORSER_TRANSACTION, TxValidationCode: peer.TxValidationCode_VALID},
+			{Txid: "tx2", Type: common.HeaderType_ENDORSER_TRANSACTION, TxValidationCode: peer.TxValidationCode_MVCC_READ_CONFLICT},
+		},
+	})
+	if event.Type != EventBlock || event.Channel != "mychannel" || event.BlockNumber != 5 || len(event.Transactions) != 2 {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	tx := event.Transactions[1]
+	if tx.TxID != "tx2" || tx.Type != "ENDORSER_TRANSACTION" || tx.ValidationCode != "MVCC_READ_CONFLICT" {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+}
+
+func TestChaincodeEvent(t *testing.T) {
+	for payload, expected := range map[string]string{
+		`{"ID":"asset1"}`: `{"ID":"asset1"}`,
+		"asset1":          `"asset1"`,
+		"\xff\x00":        `"/wA="`,
+		"":                "",
+	} {
+		event := chaincodeEvent("mychannel", &fab.CCEvent{TxID: "tx1", ChaincodeID: "asset", EventName: "CreateAsset", Payload: []byte(payload), BlockNumber: 5})
+		if event.Type != EventChaincode || event.TxID != "tx1" || event.Chaincode != "asset" || event.Name != "CreateAsset" || event.BlockNumber != 5 {
+			t.Fatalf("unexpected event %+v", event)
+		}
+		if string(event.Payload) != expected {
+			t.Errorf("expected the payload %q to be %s, got %s", payload, expected, event.Payload)
+		}
+		if (event.PayloadEncoding == "base64") != (payload == "\xff\x00") {
+			t.Errorf("unexpected encoding %q of payload %q", event.PayloadEncoding, payload)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package contract
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-sdk-go/pkg/common/providers/fab"
+	contextImpl "github.com/hyperledger/fabric-sdk-go/pkg/context"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fab/txn"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+	"github.com/pkg/errors"
+	"net/http"
+)
+
+// functions of the configuration system chaincode joining a peer to a channel
+const (
+	csccJoinChain           = "JoinChain"
+	csccJoinChainBySnapshot = "JoinChainBySnapshot"
+)
+
+// JoinChannel joins the peer of the options to the channel of the genesis
+// block, the peer then pulls the blocks of the channel from its orderers
+func JoinChannel(ctx context.Context, opts Options, genesisBlock *common.Block) error {
+	blockBytes, err := proto.Marshal(genesisBlock)
+	if err != nil {
+		return err
+	}
+	return invokeCSCC(ctx, opts, csccJoinChain, blockBytes)
+}
+
+// JoinChannelBySnapshot joins the peer of the options to the channel of a
+// snapshot, the ledger of the peer is built from the snapshot directory so it
+// must be readable by the peer. The peer builds it after answering
+func JoinChannelBySnapshot(ctx context.Context, opts Options, snapshotDir string) error {
+	return invokeCSCC(ctx, opts, csccJoinChainBySnapshot, []byte(snapshotDir))
+}
+
+// invokeCSCC sends a proposal of the configuration system chaincode to the
+// peer of the options, signed with the identity of the options
+func invokeCSCC(ctx context.Context, opts Options, fcn string, arg []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	clientConfig, profile, err := Profile(Options{PeerID: opts.PeerID, Identity: opts.Identity, Endpoint: opts.Endpoint})
+	if err != nil {
+		return err
+	}
+	sdk, err := fabsdk.New(config.FromRaw(profile, "yaml"))
+	if err != nil {
+		return err
+	}
+	defer sdk.Close()
+	clientCtx, err := sdk.Context(
+		fabsdk.WithUser(ProfileUser),
+		fabsdk.WithOrg(clientConfig.MSPID),
+	)()
+	if err != nil {
+		return err
+	}
+	peerConfig, ok := clientCtx.EndpointConfig().PeerConfig(opts.PeerID)
+	if !ok {
+		return errors.Errorf("peer %s not found in the connection profile", opts.PeerID)
+	}
+	target, err := clientCtx.InfraProvider().CreatePeerFromConfig(&fab.NetworkPeer{PeerConfig: *peerConfig, MSPID: clientConfig.MSPID})
+	if err != nil {
+		return err
+	}
+	txh, err := txn.NewHeader(clientCtx, fab.SystemChannel)
+	if err != nil {
+		return err
+	}
+	proposal, err := txn.CreateChaincodeInvokeProposal(txh, fab.ChaincodeInvokeRequest{
+		ChaincodeID: "cscc",
+		Fcn:         fcn,
+		Args:        [][]byte{arg},
+	})
+	if err != nil {
+		return err
+	}
+	reqCtx, cancel := contextImpl.NewRequest(clientCtx, contextImpl.WithParent(ctx))
+	defer cancel()
+	responses, err := txn.SendProposal(reqCtx, proposal, []fab.ProposalProcessor{target})
+	if err != nil {
+		return errors.Wrapf(err, "peer %s refused %s", opts.PeerID, fcn)
+	}
+	if len(responses) == 0 {
+		return errors.Errorf("peer %s didn't answer %s", opts.PeerID, fcn)
+	}
+	if resp := responses[0].ProposalResponse.GetResponse(); resp.GetStatus() != http.StatusOK {
+		return errors.Errorf("peer %s refused %s: %s", opts.PeerID, fcn, resp.GetMessage())
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/resmgmt"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/chaincode"
+	"hlf-easy/node"
+	"hlf-easy/utils"
+	"strings"
+)
+
+// Definition is the definition of a chaincode approved by the orgs of a
+// channel and committed through the _lifecycle
+type Definition struct {
+	Name      string
+	Version   string
+	Sequence  int64
+	PackageID string
+	// Collections are the private data collections of the chaincode
+	Collections []chaincode.Collection
+}
+
+// LifecycleOptions select the peers and the ordering service of the
+// _lifecycle operations of a channel
+type LifecycleOptions struct {
+	Channel string
+	// PeerIDs are peers of the host enrolled with a local CA, the admin
+	// identity managed for the first one signs the operations. A definition
+	// is committed through one peer of every org whose approval it needs
+	PeerIDs []string
+	// OrdererBundle is the orderer bundle file of the ordering service of
+	// the channel
+	OrdererBundle string
+}
+
+// lifecycleProfile returns the connection profile of the SDK with the peers
+// and the admin identity managed for the first one, and the orderers of the
+// bundle when it's set
+func lifecycleProfile(opts LifecycleOptions) (string, []byte, error) {
+	if len(opts.PeerIDs) == 0 {
+		return "", nil, errors.New("at least one peer is required")
+	}
+	organizations := map[string]interface{}{}
+	peers := map[string]interface{}{}
+	channelPeers := map[string]interface{}{}
+	mspID := ""
+	for i, peerID := range opts.PeerIDs {
+		identity, err := node.ManagedAdminIdentity(peerID)
+		if err != nil {
+			return "", nil, err
+		}
+		clientConfig, err := node.NewGatewayClientConfig(node.GatewayClientConfigOptions{
+			PeerID:   peerID,
+			Identity: identity,
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		org, ok := organizations[clientConfig.MSPID].(map[string]interface{})
+		if !ok {
+			org = map[string]interface{}{
+				"mspid":      clientConfig.MSPID,
+				"cryptoPath": "/tmp/cryptopath",
+				"peers":      []string{},
+			}
+			organizations[clientConfig.MSPID] = org
+		}
+		org["peers"] = append(org["peers"].([]string), peerID)
+		if i == 0 {
+			mspID = clientConfig.MSPID
+			org["users"] = map[string]interface{}{
+				ProfileUser: map[string]interface{}{
+					"cert": map[string]interface{}{"pem": clientConfig.Identity.Cert},
+					"key":  map[string]interface{}{"pem": clientConfig.Identity.Key},
+				},
+			}
+		}
+		grpcOptions := map[string]interface{}{
+			"allow-insecure": false,
+		}
+		if clientConfig.ServerName != "" {
+			grpcOptions["ssl-target-name-override"] = clientConfig.ServerName
+			grpcOptions["hostnameOverride"] = clientConfig.ServerName
+		}
+		peers[peerID] = map[string]interface{}{
+			"url":         "grpcs://" + clientConfig.Endpoint,
+			"grpcOptions": grpcOptions,
+			"tlsCACerts": map[string]interface{}{
+				"pem": clientConfig.TLSRootCerts,
+			},
+		}
+		channelPeers[peerID] = map[string]interface{}{
+			"endorsingPeer":  true,
+			"chaincodeQuery": true,
+			"ledgerQuery":    true,
+			"eventSource":    i == 0,
+		}
+	}
+	profile := map[string]interface{}{
+		"version": "1.0.0",
+		"client": map[string]interface{}{
+			"organization": mspID,
+		},
+		"organizations": organizations,
+		"peers":         peers,
+	}
+	if opts.Channel != "" {
+		channel := map[string]interface{}{
+			"peers": channelPeers,
+		}
+		profile["channels"] = map[string]interface{}{
+			opts.Channel: channel,
+		}
+		if opts.OrdererBundle != "" {
+			bundle, err := utils.ReadOrdererBundle(opts.OrdererBundle)
+			if err != nil {
+				return "", nil, err
+			}
+			orderers := map[string]interface{}{}
+			names := []string{}
+			for _, orderer := range bundle.Orderers {
+				name := fmt.Sprintf("%s:%d", orderer.Host, orderer.Port)
+				orderers[name] = map[string]interface{}{
+					"url": utils.OrdererBundleURL(orderer),
+					"grpcOptions": map[string]interface{}{
+						"allow-insecure": false,
+					},
+					"tlsCACerts": map[string]interface{}{
+						"pem": strings.Join(bundle.TLSCACerts, "\n"),
+					},
+				}
+				names = append(names, name)
+			}
+			profile["orderers"] = orderers
+			channel["orderers"] = names
+		}
+	}
+	profileBytes, err := yaml.Marshal(profile)
+	if err != nil {
+		return "", nil, err
+	}
+	return mspID, profileBytes, nil
+}
+
+// withResourceClient calls f with a resource management client of the admin
+// of the first peer
+func withResourceClient(ctx context.Context, opts LifecycleOptions, f func(client *resmgmt.Client) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	mspID, profile, err := lifecycleProfile(opts)
+	if err != nil {
+		return err
+	}
+	sdk, err := fabsdk.New(config.FromRaw(profile, "yaml"))
+	if err != nil {
+		return err
+	}
+	defer sdk.Close()
+	client, err := resmgmt.New(sdk.Context(
+		fabsdk.WithUser(ProfileUser),
+		fabsdk.WithOrg(mspID),
+	))
+	if err != nil {
+		return err
+	}
+	return f(client)
+}
+
+// Install installs a chaincode package on a peer with the admin identity
+// managed for it, it returns the package ID of the chaincode. The lifecycle
+// operations are canceled when their context is done
+func Install(ctx context.Context, peerID string, label string, pkg []byte) (string, error) {
+	packageID := ""
+	err := withResourceClient(ctx, LifecycleOptions{PeerIDs: []string{peerID}}, func(client *resmgmt.Client) error {
+		responses, err := client.LifecycleInstallCC(
+			resmgmt.LifecycleInstallCCRequest{Label: label, Package: pkg},
+			resmgmt.WithTargetEndpoints(peerID),
+			resmgmt.WithParentContext(ctx),
+		)
+		if err != nil {
+			return errors.Wrapf(err, "failed to install chaincode %s on peer %s", label, peerID)
+		}
+		if len(responses) == 0 {
+			return errors.Errorf("peer %s didn't answer the install of chaincode %s", peerID, label)
+		}
+		packageID = responses[0].PackageID
+		return nil
+	})
+	return packageID, err
+}
+
+// Approve approves a chaincode definition for the org of the first peer
+func Approve(ctx context.Context, opts LifecycleOptions, d Definition) error {
+	collections, err := chaincode.CollectionConfigs(d.Collections)
+	if err != nil {
+		return err
+	}
+	return withResourceClient(ctx, opts, func(client *resmgmt.Client) error {
+		_, err := client.LifecycleApproveCC(opts.Channel, resmgmt.LifecycleApproveCCRequest{
+			Name:             d.Name,
+			Version:          d.Version,
+			PackageID:        d.PackageID,
+			Sequence:         d.Sequence,
+			CollectionConfig: collections,
+		}, resmgmt.WithTargetEndpoints(opts.PeerIDs[0]), resmgmt.WithParentContext(ctx))
+		if err != nil {
+			return errors.Wrapf(err, "failed to approve chaincode %s on channel %s through peer %s", d.Name, opts.Channel, opts.PeerIDs[0])
+		}
+		return nil
+	})
+}
+
+// Commit commits a chaincode definition approved by the orgs of the peers,
+// every peer endorses the commit
+func Commit(ctx context.Context, opts LifecycleOptions, d Definition) error {
+	collections, err := chaincode.CollectionConfigs(d.Collections)
+	if err != nil {
+		return err
+	}
+	return withResourceClient(ctx, opts, func(client *resmgmt.Client) error {
+		_, err := client.LifecycleCommitCC(opts.Channel, resmgmt.LifecycleCommitCCRequest{
+			Name:             d.Name,
+			Version:          d.Version,
+			Sequence:         d.Sequence,
+			CollectionConfig: collections,
+		}, resmgmt.WithTargetEndpoints(opts.PeerIDs...), resmgmt.WithParentContext(ctx))
+		if err != nil {
+			return errors.Wrapf(err, "failed to commit chaincode %s on channel %s", d.Name, opts.Channel)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/auth"
+	"hlf-easy/certs"
+	"hlf-easy/errdefs"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// clientTimeout covers a node killed after StopTimeout and started again
+const clientTimeout = 2*StopTimeout + 30*time.Second
+
+// Client calls the control API of the daemon on its Unix socket
+type Client struct {
+	socket string
+	client *http.Client
+}
+
+// NewClient returns a client of the daemon listening on a socket
+func NewClient(socket string) *Client {
+	return &Client{socket: socket, client: auth.NewSocketClient(socket, clientTimeout)}
+}
+
+func (c *Client) do(method string, path string, body interface{}, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(bodyBytes)
+	}
+	req, err := http.NewRequest(method, "http://unix"+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if _, statErr := os.Stat(c.socket); os.IsNotExist(statErr) {
+			return errors.Errorf("the daemon isn't running on %s, start it with hlf-easy daemon start", c.socket)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// the daemon returns the code of the kind of the failure
+		apiErr := struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}{}
+		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Error != "" {
+			return errdefs.FromCode(apiErr.Code, apiErr.Error)
+		}
+		return errors.Errorf("daemon returned %s: %s", resp.Status, respBody)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, result)
+}
+
+// Nodes returns the nodes supervised by the daemon
+func (c *Client) Nodes() ([]NodeStatus, error) {
+	nodes := []NodeStatus{}
+	err := c.do(http.MethodGet, "/nodes", nil, &nodes)
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Add supervises a node with the daemon and starts it
+func (c *Client) Add(spec NodeSpec) error {
+	return c.do(http.MethodPost, "/nodes", spec, nil)
+}
+
+// Remove stops a node and removes it from the daemon
+func (c *Client) Remove(kind string, id string) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/nodes/%s/%s", kind, id), nil, nil)
+}
+
+// Action starts, stops or restarts a node supervised by the daemon
+func (c *Client) Action(kind string, id string, action string) error {
+	return c.do(http.MethodPost, fmt.Sprintf("/nodes/%s/%s/%s", kind, id, action), nil, nil)
+}
+
+// EnrollBatch issues the identities of a batch with a local CA of the host
+func (c *Client) EnrollBatch(opts certs.BatchEnrollOptions) ([]certs.EnrolledIdentity, error) {
+	enrolled := []certs.EnrolledIdentity{}
+	err := c.do(http.MethodPost, fmt.Sprintf("/cas/%s/identities", opts.CAName), opts, &enrolled)
+	if err != nil {
+		return nil, err
+	}
+	return enrolled, nil
+}
+
+// Shutdown stops the nodes and the daemon
+func (c *Client) Shutdown() error {
+	return c.do(http.MethodPost, "/shutdown", nil, nil)
+}
+
+// Ping tells whether the daemon answers on its socket
+func (c *Client) Ping() bool {
+	return c.do(http.MethodGet, "/nodes", nil, nil) == nil
+}
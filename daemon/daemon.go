@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/errdefs"
+	"hlf-easy/node"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NodeSpec is a node supervised by the daemon, it's run by an hlf-easy
+// <kind> start process with the args
+type NodeSpec struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	// Args are the flags of the start command, e.g. --msp-id=Org1MSP
+	Args []string `json:"args,omitempty"`
+}
+
+// Validate checks the kind and id of the node and that it's initialized
+func (s NodeSpec) Validate() error {
+	if s.Kind != node.KindPeer && s.Kind != node.KindOrderer {
+		return errors.Errorf("invalid kind %s, expected peer or orderer", s.Kind)
+	}
+	if s.ID == "" || s.ID == "." || s.ID == ".." || filepath.Base(s.ID) != s.ID {
+		return errors.Errorf("invalid %s id %q", s.Kind, s.ID)
+	}
+	for _, arg := range s.Args {
+		if arg == "--id" || strings.HasPrefix(arg, "--id=") || arg == "--all" || strings.HasPrefix(arg, "--all=") {
+			return errors.Errorf("%s can't be set in the args of the node", strings.SplitN(arg, "=", 2)[0])
+		}
+	}
+	nodeDir, err := utils.GetNodeDir(s.Kind, s.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(nodeDir); err != nil {
+		return errdefs.Errorf(errdefs.ErrNodeNotFound, "%s %s not found, initialize it with hlf-easy %s init", s.Kind, s.ID, s.Kind)
+	}
+	return nil
+}
+
+// Command returns the args of the hlf-easy process running the node
+func (s NodeSpec) Command() []string {
+	return append([]string{s.Kind, "start", "--id", s.ID}, s.Args...)
+}
+
+// GetDir returns the directory of the daemon, $HOME/hlf-easy/daemon
+func GetDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy", "daemon"), nil
+}
+
+// DefaultSocket returns the Unix socket the daemon is controlled on
+func DefaultSocket() (string, error) {
+	dir, err := GetDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "daemon.sock"), nil
+}
+
+func getSpecsPath() (string, error) {
+	dir, err := GetDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "nodes.json"), nil
+}
+
+// LoadSpecs reads the nodes supervised by the daemon, they're started again
+// when the daemon restarts
+func LoadSpecs() ([]NodeSpec, error) {
+	specsPath, err := getSpecsPath()
+	if err != nil {
+		return nil, err
+	}
+	specsBytes, err := os.ReadFile(specsPath)
+	if os.IsNotExist(err) {
+		return []NodeSpec{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	specs := []NodeSpec{}
+	err = json.Unmarshal(specsBytes, &specs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", specsPath)
+	}
+	return specs, nil
+}
+
+// SaveSpecs writes the nodes supervised by the daemon
+func SaveSpecs(specs []NodeSpec) error {
+	specsPath, err := getSpecsPath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(specsPath), 0700)
+	if err != nil {
+		return err
+	}
+	specsBytes, err := json.MarshalIndent(specs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(specsPath, specsBytes, 0600)
+}
@@ -0,0 +1,149 @@
+package daemon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"hlf-easy/auth"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"hlf-easy/node"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNodeSpecValidate(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	err := os.MkdirAll(filepath.Join(home, "hlf-easy", "peers", "peer0"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := NodeSpec{Kind: node.KindPeer, ID: "peer0", Args: []string{"--msp-id", "Org1MSP"}}
+	if err := spec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"peer", "start", "--id", "peer0", "--msp-id", "Org1MSP"}
+	if !reflect.DeepEqual(spec.Command(), expected) {
+		t.Errorf("expected %v, got %v", expected, spec.Command())
+	}
+	invalid := map[string]NodeSpec{
+		"kind":      {Kind: "ca", ID: "ca0"},
+		"empty id":  {Kind: node.KindPeer},
+		"path id":   {Kind: node.KindPeer, ID: "../peer0"},
+		"id arg":    {Kind: node.KindPeer, ID: "peer0", Args: []string{"--id=peer1"}},
+		"all arg":   {Kind: node.KindPeer, ID: "peer0", Args: []string{"--all"}},
+		"not found": {Kind: node.KindOrderer, ID: "orderer0"},
+	}
+	for name, spec := range invalid {
+		if err := spec.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := invalid["not found"].Validate(); !errors.Is(err, errdefs.ErrNodeNotFound) {
+		t.Errorf("expected the orderer not to be found, got %v", err)
+	}
+}
+
+func TestDaemon(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, nodeDir := range []string{"peers/peer0", "orderers/orderer0"} {
+		err := os.MkdirAll(filepath.Join(home, "hlf-easy", nodeDir), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.newProcess = func(spec NodeSpec) *Process {
+		p := NewProcess(spec)
+		p.command = func() (*exec.Cmd, error) {
+			return exec.Command("sleep", "30"), nil
+		}
+		return p
+	}
+	socket := filepath.Join(t.TempDir(), "daemon.sock")
+	opts := config.APIAuthOptions{Mode: "token", Socket: socket}
+	r, err := NewRouter(d, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: r}
+	go func() {
+		_ = auth.ListenAndServe(srv, opts)
+	}()
+	defer srv.Shutdown(context.Background())
+	c := NewClient(socket)
+	for i := 0; !c.Ping(); i++ {
+		if i == 50 {
+			t.Fatal("expected the daemon to answer on its socket")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	for _, spec := range []NodeSpec{{Kind: node.KindPeer, ID: "peer0"}, {Kind: node.KindOrderer, ID: "orderer0"}} {
+		if err := c.Add(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Add(NodeSpec{Kind: node.KindPeer, ID: "peer0"}); !errors.Is(err, errdefs.ErrNodeAlreadyRunning) {
+		t.Errorf("expected the peer not to be added twice, got %v", err)
+	}
+	if err := c.Action(node.KindPeer, "peer1", "restart"); !errors.Is(err, errdefs.ErrNodeNotFound) {
+		t.Errorf("expected the peer not to be found, got %v", err)
+	}
+	err = c.Action(node.KindPeer, "peer0", "restart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Action(node.KindOrderer, "orderer0", "stop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Action(node.KindOrderer, "orderer0", "stop"); !errors.Is(err, errdefs.ErrNodeNotRunning) {
+		t.Errorf("expected the stopped orderer not to be stopped again, got %v", err)
+	}
+	nodes, err := c.Nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[0].ID != "orderer0" || nodes[0].State != node.StateStopped || nodes[1].State != node.StateRunning || nodes[1].Process.PID == 0 {
+		t.Fatalf("expected a stopped orderer and a running peer, got %+v", nodes)
+	}
+
+	// the nodes are supervised again when the daemon restarts
+	specs, err := LoadSpecs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 {
+		t.Errorf("expected the nodes to be saved, got %+v", specs)
+	}
+	err = c.Remove(node.KindOrderer, "orderer0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if specs, _ := LoadSpecs(); len(specs) != 1 || specs[0].ID != "peer0" {
+		t.Errorf("expected the orderer to be removed, got %+v", specs)
+	}
+
+	err = c.Shutdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-d.Done():
+	default:
+		t.Error("expected the daemon to be done")
+	}
+	if state := d.Nodes()[0].State; state != node.StateStopped {
+		t.Errorf("expected the peer to be stopped by the shutdown, got %s", state)
+	}
+}
@@ -0,0 +1,353 @@
+package daemon
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/process"
+	"hlf-easy/errdefs"
+	"hlf-easy/log"
+	"hlf-easy/node"
+	"hlf-easy/notify"
+	"hlf-easy/proc"
+	"hlf-easy/utils"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The hlf-easy process of a node that exits without being stopped is
+// restarted after a backoff doubled on every crash, it's reset once the
+// process ran for stableRun
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	stableRun  = time.Minute
+	// StopTimeout is how long the process of a node has to exit once it's
+	// interrupted, it's killed after
+	StopTimeout = 30 * time.Second
+)
+
+// Process supervises the hlf-easy process running a node, it's safe for
+// concurrent use
+type Process struct {
+	spec NodeSpec
+	// command returns the command of the process, the tests replace it
+	command func() (*exec.Cmd, error)
+	// lifecycle serializes Start, Stop and Restart
+	lifecycle sync.Mutex
+	// mu guards the fields below
+	mu    sync.Mutex
+	state node.State
+	pid   int
+	// child is false for a process attached after the daemon restarted, it
+	// can't be waited
+	child     bool
+	startedAt time.Time
+	exited    chan struct{}
+	restarts  int
+	backoff   time.Duration
+	// restart is the pending restart of a crashed process
+	restart *time.Timer
+}
+
+// NewProcess returns the supervisor of a node, its process isn't started
+func NewProcess(spec NodeSpec) *Process {
+	p := &Process{spec: spec, state: node.StateStopped}
+	p.command = p.hlfEasyCommand
+	return p
+}
+
+// hlfEasyCommand runs the start command of the node with the hlf-easy binary
+// of the daemon, its output is appended to hlf-easy.log in the directory of
+// the node
+func (p *Process) hlfEasyCommand() (*exec.Cmd, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	nodeDir, err := utils.GetNodeDir(p.spec.Kind, p.spec.ID)
+	if err != nil {
+		return nil, err
+	}
+	logFile, err := os.OpenFile(filepath.Join(nodeDir, "hlf-easy.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(executable, p.spec.Command()...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	proc.NewProcessGroup(cmd)
+	return cmd, nil
+}
+
+func (p *Process) GetID() string {
+	return p.spec.ID
+}
+
+func (p *Process) GetKind() string {
+	return p.spec.Kind
+}
+
+// State returns the lifecycle state of the process
+func (p *Process) State() node.State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// Restarts returns how many times the process was restarted after a crash
+func (p *Process) Restarts() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.restarts
+}
+
+// Start starts the process of the node
+func (p *Process) Start() error {
+	p.lifecycle.Lock()
+	defer p.lifecycle.Unlock()
+	return p.start()
+}
+
+func (p *Process) start() error {
+	p.mu.Lock()
+	p.cancelRestart()
+	if err := node.Transition(&p.state, node.StateStarting); err != nil {
+		p.mu.Unlock()
+		return errdefs.Errorf(errdefs.ErrNodeAlreadyRunning, "%s %s is already started", p.spec.Kind, p.spec.ID)
+	}
+	p.mu.Unlock()
+	cmd, err := p.command()
+	if err == nil {
+		err = cmd.Start()
+		closeOutput(cmd)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		_ = node.Transition(&p.state, node.StateFailed)
+		return err
+	}
+	p.run(cmd.Process.Pid, true)
+	go p.wait(func() error {
+		return cmd.Wait()
+	}, p.exited)
+	return nil
+}
+
+// fields are the fields of the logs of the node of the process
+func (p *Process) fields() log.Fields {
+	return log.Fields{log.FieldNodeID: p.spec.ID, "kind": p.spec.Kind}
+}
+
+// logger returns the logger of the node of the process, its logs carry the
+// ID of the node
+func (p *Process) logger() log.Logger {
+	return log.WithFields(p.fields())
+}
+
+// run records a started process, mu must be held
+func (p *Process) run(pid int, child bool) {
+	p.pid = pid
+	p.child = child
+	p.startedAt = time.Now()
+	p.exited = make(chan struct{})
+	_ = node.Transition(&p.state, node.StateRunning)
+}
+
+// attach supervises the hlf-easy process of the node left running by a
+// daemon that exited, it's false when there's none
+func (p *Process) attach() bool {
+	r, err := node.LoadProcessRecord(p.spec.Kind, p.spec.ID)
+	if err != nil {
+		p.logger().Warnf("Failed to read the process of %s %s: %v", p.spec.Kind, p.spec.ID, err)
+		return false
+	}
+	if r == nil || !node.ProcessRunning(r.ManagerPID, r.ManagerCreateTime) {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := node.Transition(&p.state, node.StateStarting); err != nil {
+		return false
+	}
+	p.run(r.ManagerPID, false)
+	go p.wait(func() error {
+		return node.WaitProcess(r.ManagerPID, r.ManagerCreateTime)
+	}, p.exited)
+	return true
+}
+
+// wait waits for the process to exit, an exit that wasn't requested by Stop
+// is a crash and the process is restarted after the backoff
+func (p *Process) wait(done func() error, exited chan struct{}) {
+	err := done()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	close(exited)
+	if p.state == node.StateStopping {
+		return
+	}
+	_ = node.Transition(&p.state, node.StateFailed)
+	p.pid = 0
+	if time.Since(p.startedAt) >= stableRun || p.backoff == 0 {
+		p.backoff = minBackoff
+	} else {
+		p.increaseBackoff()
+	}
+	fields := p.fields()
+	fields[log.FieldDuration] = time.Since(p.startedAt).Seconds()
+	log.WithFields(fields).Warnf("%s %s exited unexpectedly, restarting it in %s: %v", p.spec.Kind, p.spec.ID, p.backoff, err)
+	event := notify.NewEvent(notify.EventNodeCrashed, p.spec.Kind, p.spec.ID, fmt.Sprintf("The hlf-easy process of %s %s exited", p.spec.Kind, p.spec.ID))
+	if err != nil {
+		event.Details = map[string]string{"exit": err.Error()}
+	}
+	go notify.Notify(event)
+	p.scheduleRestart()
+}
+
+// scheduleRestart starts the process again after the backoff, mu must be
+// held. A restart that fails is scheduled again
+func (p *Process) scheduleRestart() {
+	var t *time.Timer
+	t = time.AfterFunc(p.backoff, func() {
+		p.lifecycle.Lock()
+		defer p.lifecycle.Unlock()
+		p.mu.Lock()
+		// the restart was cancelled or replaced meanwhile
+		if p.restart != t {
+			p.mu.Unlock()
+			return
+		}
+		p.restart = nil
+		p.restarts++
+		p.mu.Unlock()
+		if err := p.start(); err != nil {
+			p.logger().Warnf("Failed to restart %s %s: %v", p.spec.Kind, p.spec.ID, err)
+			p.mu.Lock()
+			if p.state == node.StateFailed {
+				p.increaseBackoff()
+				p.scheduleRestart()
+			}
+			p.mu.Unlock()
+		}
+	})
+	p.restart = t
+}
+
+// increaseBackoff doubles the backoff up to maxBackoff, mu must be held
+func (p *Process) increaseBackoff() {
+	p.backoff *= 2
+	if p.backoff > maxBackoff {
+		p.backoff = maxBackoff
+	}
+}
+
+// cancelRestart cancels the pending restart, mu must be held
+func (p *Process) cancelRestart() {
+	if p.restart != nil {
+		p.restart.Stop()
+		p.restart = nil
+	}
+}
+
+// Stop stops the process and waits for it to exit, it's killed after
+// StopTimeout. A crashed process waiting to be restarted is only stopped
+// from being restarted
+func (p *Process) Stop() error {
+	p.lifecycle.Lock()
+	defer p.lifecycle.Unlock()
+	return p.stop()
+}
+
+func (p *Process) stop() error {
+	p.mu.Lock()
+	p.cancelRestart()
+	if p.state == node.StateFailed {
+		_ = node.Transition(&p.state, node.StateStopped)
+		p.mu.Unlock()
+		return nil
+	}
+	if err := node.Transition(&p.state, node.StateStopping); err != nil {
+		p.mu.Unlock()
+		return errdefs.Errorf(errdefs.ErrNodeNotRunning, "%s %s is already stopped", p.spec.Kind, p.spec.ID)
+	}
+	pid, child, exited := p.pid, p.child, p.exited
+	p.mu.Unlock()
+	osProcess, err := os.FindProcess(pid)
+	if err == nil {
+		if child {
+			err = proc.Interrupt(osProcess)
+		} else {
+			// an attached process isn't a child of the daemon, it's
+			// terminated by its PID
+			err = proc.Terminate(pid)
+		}
+	}
+	if err != nil {
+		p.logger().Warnf("Failed to stop %s %s: %v", p.spec.Kind, p.spec.ID, err)
+		p.mu.Lock()
+		_ = node.Transition(&p.state, node.StateRunning)
+		p.mu.Unlock()
+		return err
+	}
+	select {
+	case <-exited:
+	case <-time.After(StopTimeout):
+		p.logger().Warnf("%s %s didn't exit in %s, killing it", p.spec.Kind, p.spec.ID, StopTimeout)
+		_ = osProcess.Kill()
+		<-exited
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pid = 0
+	p.backoff = 0
+	_ = node.Transition(&p.state, node.StateStopped)
+	return nil
+}
+
+// Restart stops the process when it's running and starts it again
+func (p *Process) Restart() error {
+	p.lifecycle.Lock()
+	defer p.lifecycle.Unlock()
+	err := p.stop()
+	if err != nil && !errors.Is(err, errdefs.ErrNodeNotRunning) {
+		return err
+	}
+	return p.start()
+}
+
+// Status returns the PID, status and uptime of the hlf-easy process of the
+// node, the status of the node itself is served by its management API
+func (p *Process) Status() (*node.ProcessState, error) {
+	p.mu.Lock()
+	pid, state, startedAt := p.pid, p.state, p.startedAt
+	p.mu.Unlock()
+	if pid == 0 {
+		return &node.ProcessState{Status: "Stop", State: state}, nil
+	}
+	status := "Unknown"
+	if osProcess, err := process.NewProcess(int32(pid)); err == nil {
+		if s, err := osProcess.Status(); err == nil {
+			if name, ok := node.StatusMap[s]; ok {
+				status = name
+			}
+		}
+	}
+	return &node.ProcessState{
+		PID:    pid,
+		Status: status,
+		Uptime: time.Since(startedAt).Seconds(),
+		State:  state,
+	}, nil
+}
+
+// closeOutput closes the log file of a started process, the process has its
+// own descriptor
+func closeOutput(cmd *exec.Cmd) {
+	if f, ok := cmd.Stdout.(*os.File); ok && f != os.Stdout && f != os.Stderr {
+		_ = f.Close()
+	}
+}
@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"hlf-easy/node"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitState waits for a process to reach a state
+func waitState(t *testing.T, p *Process, state node.State, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for p.State() != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s %s to be %s, got %s", p.GetKind(), p.GetID(), state, p.State())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestProcessRestart(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var starts int32
+	p := NewProcess(NodeSpec{Kind: node.KindPeer, ID: "peer0"})
+	p.command = func() (*exec.Cmd, error) {
+		// the first process crashes, the restarted one keeps running
+		if atomic.AddInt32(&starts, 1) == 1 {
+			return exec.Command("sh", "-c", "exit 3"), nil
+		}
+		return exec.Command("sleep", "30"), nil
+	}
+	err := p.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, p, node.StateFailed, 5*time.Second)
+	waitState(t, p, node.StateRunning, minBackoff+5*time.Second)
+	if p.Restarts() != 1 || atomic.LoadInt32(&starts) != 2 {
+		t.Errorf("expected the crashed process to be restarted once, got %d restarts", p.Restarts())
+	}
+	status, err := p.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.PID == 0 || status.State != node.StateRunning {
+		t.Errorf("expected the status of the running process, got %+v", status)
+	}
+	err = p.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.State() != node.StateStopped {
+		t.Errorf("expected the process to be stopped, got %s", p.State())
+	}
+	if err := p.Stop(); err == nil {
+		t.Error("expected an error stopping a stopped process")
+	}
+}
+
+func TestProcessStopFailed(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	var starts int32
+	p := NewProcess(NodeSpec{Kind: node.KindOrderer, ID: "orderer0"})
+	p.command = func() (*exec.Cmd, error) {
+		atomic.AddInt32(&starts, 1)
+		return exec.Command("sh", "-c", "exit 3"), nil
+	}
+	err := p.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, p, node.StateFailed, 5*time.Second)
+	// stopping a crashed process cancels its restart
+	err = p.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(minBackoff + 500*time.Millisecond)
+	if n := atomic.LoadInt32(&starts); p.State() != node.StateStopped || n != 1 {
+		t.Errorf("expected the stopped process not to be restarted, got %s after %d starts", p.State(), n)
+	}
+}
@@ -0,0 +1,314 @@
+package daemon
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"hlf-easy/audit"
+	"hlf-easy/auth"
+	"hlf-easy/certs"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"hlf-easy/node"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NodeStatus is a node supervised by the daemon with its hlf-easy process
+type NodeStatus struct {
+	NodeSpec
+	State    node.State         `json:"state"`
+	Restarts int                `json:"restarts"`
+	Process  *node.ProcessState `json:"process,omitempty"`
+}
+
+// Daemon supervises the hlf-easy processes of the nodes of the host, they
+// keep running when the terminal they were started from is closed
+type Daemon struct {
+	manager *node.Manager
+	// newProcess returns the supervisor of an added node, the tests replace
+	// it
+	newProcess func(spec NodeSpec) *Process
+	// mu guards the specs and nodes.json
+	mu    sync.Mutex
+	specs []NodeSpec
+	// done is closed when the daemon is asked to shut down
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// New returns a daemon supervising the nodes of nodes.json, they're started
+// by Start
+func New() (*Daemon, error) {
+	specs, err := LoadSpecs()
+	if err != nil {
+		return nil, err
+	}
+	d := &Daemon{manager: node.NewManager(), newProcess: NewProcess, specs: specs, done: make(chan struct{})}
+	for _, spec := range specs {
+		err = d.manager.Register(d.newProcess(spec))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// Start supervises the processes of the nodes, the ones left running by a
+// daemon that exited are attached instead of started again
+func (d *Daemon) Start() {
+	for _, info := range d.manager.List() {
+		n, err := d.manager.Get(info.Kind, info.ID)
+		if err != nil {
+			continue
+		}
+		p := n.(*Process)
+		if p.attach() {
+			p.logger().Infof("Attached to the running process of %s %s", info.Kind, info.ID)
+			continue
+		}
+		if err := p.Start(); err != nil {
+			p.logger().Warnf("Failed to start %s %s: %v", info.Kind, info.ID, err)
+		}
+	}
+}
+
+// Add supervises a node and starts it, it's started again when the daemon
+// restarts
+func (d *Daemon) Add(spec NodeSpec) error {
+	err := spec.Validate()
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err = node.CheckManager(spec.Kind, spec.ID)
+	if err != nil {
+		return err
+	}
+	p := d.newProcess(spec)
+	err = d.manager.Register(p)
+	if err != nil {
+		return errdefs.Errorf(errdefs.ErrNodeAlreadyRunning, "%s %s is already supervised by the daemon", spec.Kind, spec.ID)
+	}
+	err = SaveSpecs(append(d.specs, spec))
+	if err != nil {
+		_ = d.manager.Unregister(spec.Kind, spec.ID)
+		return err
+	}
+	d.specs = append(d.specs, spec)
+	return p.Start()
+}
+
+// Remove stops a node and stops supervising it
+func (d *Daemon) Remove(kind string, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n, err := d.manager.Get(kind, id)
+	if err != nil {
+		return err
+	}
+	err = n.Stop()
+	if err != nil && !errors.Is(err, errdefs.ErrNodeNotRunning) {
+		return err
+	}
+	err = d.manager.Unregister(kind, id)
+	if err != nil {
+		return err
+	}
+	specs := []NodeSpec{}
+	for _, spec := range d.specs {
+		if spec.Kind != kind || spec.ID != id {
+			specs = append(specs, spec)
+		}
+	}
+	d.specs = specs
+	return SaveSpecs(specs)
+}
+
+// Nodes returns the nodes supervised by the daemon sorted by kind and id
+func (d *Daemon) Nodes() []NodeStatus {
+	d.mu.Lock()
+	specs := map[string]NodeSpec{}
+	for _, spec := range d.specs {
+		specs[spec.Kind+"/"+spec.ID] = spec
+	}
+	d.mu.Unlock()
+	nodes := []NodeStatus{}
+	for _, info := range d.manager.List() {
+		n, err := d.manager.Get(info.Kind, info.ID)
+		if err != nil {
+			continue
+		}
+		nodes = append(nodes, status(specs[info.Kind+"/"+info.ID], n.(*Process)))
+	}
+	return nodes
+}
+
+// Node returns a node supervised by the daemon
+func (d *Daemon) Node(kind string, id string) (NodeStatus, error) {
+	n, err := d.manager.Get(kind, id)
+	if err != nil {
+		return NodeStatus{}, err
+	}
+	spec := NodeSpec{Kind: kind, ID: id}
+	d.mu.Lock()
+	for _, s := range d.specs {
+		if s.Kind == kind && s.ID == id {
+			spec = s
+		}
+	}
+	d.mu.Unlock()
+	return status(spec, n.(*Process)), nil
+}
+
+// status returns the status of the process of a node
+func status(spec NodeSpec, p *Process) NodeStatus {
+	s := NodeStatus{NodeSpec: spec, State: p.State(), Restarts: p.Restarts()}
+	var err error
+	if s.Process, err = p.Status(); err != nil {
+		p.logger().Warnf("Failed to get the process of %s %s: %v", spec.Kind, spec.ID, err)
+	}
+	return s
+}
+
+// Action starts, stops or restarts a node supervised by the daemon
+func (d *Daemon) Action(kind string, id string, action string) error {
+	n, err := d.manager.Get(kind, id)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case "start":
+		return n.Start()
+	case "stop":
+		return n.Stop()
+	case "restart":
+		return n.Restart()
+	}
+	return errors.Errorf("unknown action %s", action)
+}
+
+// Shutdown stops the nodes, the crashed ones aren't restarted anymore
+func (d *Daemon) Shutdown() error {
+	for _, info := range d.manager.List() {
+		if info.State != node.StateFailed {
+			continue
+		}
+		if n, err := d.manager.Get(info.Kind, info.ID); err == nil {
+			_ = n.Stop()
+		}
+	}
+	return d.manager.StopAll()
+}
+
+// Done is closed when the daemon is asked to shut down through its API
+func (d *Daemon) Done() <-chan struct{} {
+	return d.done
+}
+
+// writeError returns an error with the code of its kind, the client maps it
+// back to the kind
+func writeError(c *gin.Context, err error) {
+	c.JSON(errdefs.HTTPStatus(err), gin.H{
+		"error": err.Error(),
+		"code":  errdefs.Code(err),
+	})
+}
+
+// NewRouter returns the control API of the daemon, the CLI calls it on the
+// Unix socket of the daemon
+func NewRouter(d *Daemon, authOpts config.APIAuthOptions) (*gin.Engine, error) {
+	authenticator, err := auth.NewAuthenticator(authOpts, nil)
+	if err != nil {
+		return nil, err
+	}
+	r := gin.Default()
+	r.Use(audit.Middleware("daemon", ""), authenticator.Middleware())
+	r.GET("/nodes", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
+		c.JSON(http.StatusOK, d.Nodes())
+	})
+	r.POST("/nodes", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
+		spec := NodeSpec{}
+		if err := c.ShouldBindJSON(&spec); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Set(audit.ContextKeyParams, map[string]string{"kind": spec.Kind, "id": spec.ID})
+		if err := d.Add(spec); err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	})
+	r.DELETE("/nodes/:kind/:id", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
+		if err := d.Remove(c.Param("kind"), c.Param("id")); err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	})
+	r.POST("/nodes/:kind/:id/:action", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
+		if err := d.Action(c.Param("kind"), c.Param("id"), c.Param("action")); err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	})
+	// the identities of a batch are given as the JSON of the options or as a
+	// CSV manifest with the parallelism in the query
+	r.POST("/cas/:name/identities", auth.Allow(auth.ResourceIdentities), func(c *gin.Context) {
+		opts := certs.BatchEnrollOptions{}
+		var err error
+		if strings.HasPrefix(c.ContentType(), "text/csv") {
+			opts.Identities, err = certs.ParseBatchManifest(c.Request.Body, certs.BatchFormatCSV)
+			if err == nil && c.Query("parallelism") != "" {
+				opts.Parallelism, err = strconv.Atoi(c.Query("parallelism"))
+			}
+		} else {
+			err = c.ShouldBindJSON(&opts)
+		}
+		opts.CAName = c.Param("name")
+		if err == nil {
+			err = opts.Validate()
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Set(audit.ContextKeyParams, map[string]string{"ca": opts.CAName, "identities": strconv.Itoa(len(opts.Identities))})
+		enrolled, err := certs.EnrollBatch(c.Request.Context(), opts)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, enrolled)
+	})
+	r.POST("/shutdown", auth.Allow(auth.ResourceHost), func(c *gin.Context) {
+		// the nodes are stopped before answering so the client returns once
+		// they exited
+		err := d.Shutdown()
+		d.doneOnce.Do(func() {
+			close(d.done)
+		})
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	})
+	return r, nil
+}
@@ -0,0 +1,71 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hlf-easy/channel"
+	"hlf-easy/errdefs"
+	"io"
+	"net/http"
+)
+
+// SignTarget is the management API of the peer whose org signs a config
+// update, a peer of the host or the peer of an org on another host
+type SignTarget struct {
+	// PeerID is a peer of the host
+	PeerID string
+	// Address is the management API of a peer on another host, served over
+	// TLS with a certificate issued by TLSCACert when it's set
+	Address   string
+	TLSCACert string
+}
+
+func (t SignTarget) String() string {
+	if t.PeerID != "" {
+		return "peer " + t.PeerID
+	}
+	return t.Address
+}
+
+// SignConfigUpdate gets the signature of the org of a peer on a config update
+// transaction through its management API, it's signed with the admin
+// identity managed for the peer. It returns the signed transaction and its
+// signers
+func SignConfigUpdate(target SignTarget, tx []byte, token string) ([]byte, []channel.Signer, error) {
+	n := &Node{Kind: KindPeer, ManagementAddress: target.Address, tlsCert: target.TLSCACert}
+	if target.PeerID != "" {
+		var err error
+		n, err = GetNode(KindPeer, target.PeerID, token)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !n.Running || (n.ManagementAddress == "" && n.ManagementSocket == "") {
+			return nil, nil, errdefs.Errorf(errdefs.ErrNodeNotRunning, "peer %s is not running, start it with hlf-easy peer start", target.PeerID)
+		}
+	}
+	txBytes, err := json.Marshal(map[string][]byte{"tx": tx})
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := doRequest(n, http.MethodPost, "/config-updates/sign", bytes.NewReader(txBytes), token, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, apiError(body, fmt.Sprintf("%s failed to sign the config update", target))
+	}
+	signed := struct {
+		Tx      []byte           `json:"tx"`
+		Signers []channel.Signer `json:"signers"`
+	}{}
+	if err := json.Unmarshal(body, &signed); err != nil {
+		return nil, nil, err
+	}
+	return signed.Tx, signed.Signers, nil
+}
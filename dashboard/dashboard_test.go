@@ -0,0 +1,242 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManagementURL(t *testing.T) {
+	for address, expected := range map[string]string{
+		"0.0.0.0:7055":   "http://127.0.0.1:7055",
+		":7055":          "http://127.0.0.1:7055",
+		"10.0.0.1:7055":  "http://10.0.0.1:7055",
+		"[::]:7055":      "http://127.0.0.1:7055",
+		"localhost:7065": "http://localhost:7065",
+	} {
+		u, err := managementURL(address, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u != expected {
+			t.Errorf("expected %s for %s, got %s", expected, address, u)
+		}
+	}
+	u, err := managementURL("0.0.0.0:7055", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u != "https://127.0.0.1:7055" {
+		t.Errorf("expected https for a management API served over TLS, got %s", u)
+	}
+}
+
+func TestNodes(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	var actions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"a bearer token is required"}`))
+			return
+		}
+		if r.Method == http.MethodPost {
+			actions = append(actions, r.URL.Path)
+			w.Write([]byte(`{"success":true}`))
+			return
+		}
+		w.Write([]byte(`{"pid":42,"status":"Running","memory":{"rss":1048576},"cpu":{"percent":1.5},"uptime":90}`))
+	}))
+	defer srv.Close()
+
+	peerDir := filepath.Join(home, "hlf-easy/peers/peer1")
+	err := os.MkdirAll(filepath.Join(peerDir, "data/ledgersData/chains/chains/demo"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runConfig, err := json.Marshal(map[string]interface{}{
+		"peerID": "peer1",
+		"options": map[string]string{
+			"mspID":             "Org1MSP",
+			"managementAddress": strings.TrimPrefix(srv.URL, "http://"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(peerDir, "run.json"), runConfig, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(filepath.Join(home, "hlf-easy/orderers/orderer1"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := ListNodes("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %d", len(nodes))
+	}
+	orderer, peer := nodes[0], nodes[1]
+	if orderer.Kind != KindOrderer || orderer.Running {
+		t.Fatalf("expected orderer1 not to be running, got %+v", orderer)
+	}
+	if !peer.Running || peer.MSPID != "Org1MSP" || peer.Status == nil || peer.Status.PID != 42 || peer.Status.Uptime != 90 {
+		t.Fatalf("unexpected peer %+v", peer)
+	}
+	if strings.Join(peer.Channels, ",") != "demo" {
+		t.Fatalf("expected peer1 to be in channel demo, got %v", peer.Channels)
+	}
+
+	err = RunAction(KindPeer, "peer1", "restart", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(actions, ",") != "/restart" {
+		t.Fatalf("expected the restart to be posted, got %v", actions)
+	}
+	if err := RunAction(KindPeer, "peer1", "stop", ""); err == nil {
+		t.Fatal("expected the action to be refused without the token of the caller")
+	}
+	if err := RunAction(KindOrderer, "orderer1", "start", "secret"); err == nil {
+		t.Fatal("expected an orderer that isn't running not to be started")
+	}
+	if err := RunAction(KindPeer, "peer1", "delete", "secret"); err == nil {
+		t.Fatal("expected an unknown action to be refused")
+	}
+	if err := RunAction("ca", "peer1", "stop", "secret"); err == nil {
+		t.Fatal("expected an unknown kind to be refused")
+	}
+}
+
+func TestSocketNode(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			actions = append(actions, r.URL.Path)
+			w.Write([]byte(`{"success":true}`))
+			return
+		}
+		w.Write([]byte(`{"pid":42,"status":"Running","uptime":90}`))
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	peerDir := filepath.Join(home, "hlf-easy/peers/peer1")
+	err = os.MkdirAll(peerDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the node has no management address, its API is only on the socket
+	runConfig, err := json.Marshal(map[string]interface{}{
+		"peerID": "peer1",
+		"options": map[string]interface{}{
+			"auth": map[string]string{"mode": "token", "socket": socket},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(peerDir, "run.json"), runConfig, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer, err := GetNode(KindPeer, "peer1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peer.Error != "" || peer.Status == nil || peer.Status.PID != 42 || peer.ManagementSocket != socket {
+		t.Fatalf("expected the status of peer1 from its socket, got %+v", peer)
+	}
+	err = RunAction(KindPeer, "peer1", "stop", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(actions, ",") != "/stop" {
+		t.Fatalf("expected the stop to be posted on the socket, got %v", actions)
+	}
+}
+
+func TestSetLogSpec(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	var specs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/logspec" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body := struct {
+			Spec string `json:"spec"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if body.Spec == "msp=debug" {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"peer peer1 is locked by user:alice"}`))
+			return
+		}
+		specs = append(specs, body.Spec)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"persisted": map[string]string{"spec": body.Spec, "updatedBy": "user:alice"},
+			"applied":   true,
+		})
+	}))
+	defer srv.Close()
+
+	peerDir := filepath.Join(home, "hlf-easy/peers/peer1")
+	if err := os.MkdirAll(peerDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runConfig, err := json.Marshal(map[string]interface{}{
+		"peerID":  "peer1",
+		"options": map[string]string{"managementAddress": strings.TrimPrefix(srv.URL, "http://")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(peerDir, "run.json"), runConfig, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(home, "hlf-easy/peers/peer2"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	update, err := SetLogSpec(KindPeer, "peer1", "gossip=debug:info", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !update.Applied || update.Persisted == nil || update.Persisted.Spec != "gossip=debug:info" {
+		t.Fatalf("expected the spec to be applied and persisted, got %+v", update)
+	}
+	if strings.Join(specs, ",") != "gossip=debug:info" {
+		t.Fatalf("expected the spec to be put, got %v", specs)
+	}
+	if _, err := SetLogSpec(KindPeer, "peer1", "gossip=loud", ""); err == nil {
+		t.Fatal("expected an invalid spec to be refused")
+	}
+	if _, err := SetLogSpec(KindPeer, "peer1", "msp=debug", ""); err == nil || !strings.Contains(err.Error(), "locked by user:alice") {
+		t.Fatalf("expected the error of the management API, got %v", err)
+	}
+	if _, err := SetLogSpec(KindPeer, "peer2", "info", ""); err == nil {
+		t.Fatal("expected a peer that isn't running to be refused")
+	}
+}
@@ -0,0 +1,56 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"hlf-easy/node"
+	"io"
+	"net/http"
+)
+
+// LogSpecUpdate is the result of a change of the logging spec of a node
+type LogSpecUpdate struct {
+	Persisted *config.LogSpec `json:"persisted"`
+	// Applied is false when the node is stopped, it gets the spec on its
+	// next start
+	Applied bool `json:"applied"`
+}
+
+// SetLogSpec changes the logging spec of a node through its management API,
+// which applies it to the running node without a restart and persists it
+func SetLogSpec(kind string, id string, spec string, token string) (*LogSpecUpdate, error) {
+	if err := node.ValidateLogSpec(spec); err != nil {
+		return nil, err
+	}
+	n, err := GetNode(kind, id, token)
+	if err != nil {
+		return nil, err
+	}
+	if !n.Running || (n.ManagementAddress == "" && n.ManagementSocket == "") {
+		return nil, errdefs.Errorf(errdefs.ErrNodeNotRunning, "%s %s is not running, start it with hlf-easy %s start", kind, id, kind)
+	}
+	specBytes, err := json.Marshal(map[string]string{"spec": spec})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := doRequest(n, http.MethodPut, "/logspec", bytes.NewReader(specBytes), token, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError(body, fmt.Sprintf("failed to set the logging spec of %s %s", kind, id))
+	}
+	update := &LogSpecUpdate{}
+	if err := json.Unmarshal(body, update); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
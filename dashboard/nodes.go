@@ -0,0 +1,330 @@
+package dashboard
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/auth"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"hlf-easy/lock"
+	"hlf-easy/node"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Node is a peer or orderer of the host as shown in the dashboard
+type Node struct {
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	MSPID string `json:"mspID,omitempty"`
+	// Running is true when the hlf-easy process of the node is running, it
+	// serves the management API used to start and stop the node
+	Running           bool               `json:"running"`
+	ManagementAddress string             `json:"managementAddress,omitempty"`
+	ManagementSocket  string             `json:"managementSocket,omitempty"`
+	ExternalEndpoint  string             `json:"externalEndpoint,omitempty"`
+	Status            *node.ProcessState `json:"status,omitempty"`
+	Channels          []string           `json:"channels"`
+	Error             string             `json:"error,omitempty"`
+	// tlsCert is the certificate the management API is served with, it's
+	// served over plain HTTP when empty
+	tlsCert string
+}
+
+// Kinds of the nodes, the name of their directory in $HOME/hlf-easy is the
+// plural of the kind
+const (
+	KindPeer    = "peer"
+	KindOrderer = "orderer"
+)
+
+// runConfig has the fields shared by the run.json of peers and orderers
+type runConfig struct {
+	Options struct {
+		MSPID             string                `json:"mspID"`
+		ExternalEndpoint  string                `json:"externalEndpoint"`
+		ManagementAddress string                `json:"managementAddress"`
+		Auth              config.APIAuthOptions `json:"auth"`
+	} `json:"options"`
+}
+
+var client = &http.Client{Timeout: 5 * time.Second}
+
+func getNodesDir(kind string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy", kind+"s"), nil
+}
+
+// channelsDir is the directory with a subdirectory per channel in the ledger
+// of a node
+func channelsDir(kind string, nodeDir string) string {
+	if kind == KindPeer {
+		return filepath.Join(nodeDir, "data/ledgersData/chains/chains")
+	}
+	return filepath.Join(nodeDir, "data/chains")
+}
+
+func listChannels(kind string, nodeDir string) []string {
+	channels := []string{}
+	entries, err := os.ReadDir(channelsDir(kind, nodeDir))
+	if err != nil {
+		return channels
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			channels = append(channels, entry.Name())
+		}
+	}
+	return channels
+}
+
+// managementURL returns the URL to reach a management API from the host, the
+// unspecified addresses it listens on are reached through the loopback
+func managementURL(address string, secure bool) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid management address %s", address)
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	scheme := "http"
+	if secure {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, port)), nil
+}
+
+// doRequest calls the management API of a node with the token of the caller
+// of the dashboard, the certificate of the node is trusted when it's served
+// over TLS. The lock token lets the holder of the lock of the node run its
+// actions, the body is sent as JSON
+func doRequest(n *Node, method string, path string, body io.Reader, token string, lockToken string) (*http.Response, error) {
+	if n.ManagementSocket != "" {
+		return doSocketRequest(n.ManagementSocket, method, path, body, token, lockToken)
+	}
+	baseURL, err := managementURL(n.ManagementAddress, n.tlsCert != "")
+	if err != nil {
+		return nil, err
+	}
+	c := client
+	if n.tlsCert != "" {
+		certBytes, err := os.ReadFile(n.tlsCert)
+		if err != nil {
+			return nil, err
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(certBytes) {
+			return nil, errors.Errorf("no certificate found in %s", n.tlsCert)
+		}
+		c = &http.Client{
+			Timeout: client.Timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12},
+			},
+		}
+	}
+	req, err := newRequest(method, baseURL+path, body, token, lockToken)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// doSocketRequest calls the management API of a node on its Unix socket, the
+// token is sent anyway in case the API is reached through a proxy
+func doSocketRequest(socket string, method string, path string, body io.Reader, token string, lockToken string) (*http.Response, error) {
+	req, err := newRequest(method, "http://unix"+path, body, token, lockToken)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewSocketClient(socket, client.Timeout).Do(req)
+}
+
+func newRequest(method string, url string, body io.Reader, token string, lockToken string) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if lockToken != "" {
+		req.Header.Set(lock.Header, lockToken)
+	}
+	return req, nil
+}
+
+// apiError returns the error of a failed call of the management API, with
+// the kind of the failure when the API returned its code
+func apiError(body []byte, msg string) error {
+	apiErr := struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}{}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+		return errdefs.FromCode(apiErr.Code, fmt.Sprintf("%s: %s", msg, apiErr.Error))
+	}
+	return errors.Errorf("%s: %s", msg, body)
+}
+
+func getStatus(n *Node, token string) (*node.ProcessState, error) {
+	resp, err := doRequest(n, http.MethodGet, "/status", nil, token, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("management API returned %s: %s", resp.Status, body)
+	}
+	status := &node.ProcessState{}
+	err = json.Unmarshal(body, status)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// GetNode reads the node from its directory and gets its status from its
+// management API when it's running
+func GetNode(kind string, id string, token string) (*Node, error) {
+	nodesDir, err := getNodesDir(kind)
+	if err != nil {
+		return nil, err
+	}
+	if id == "" || id == "." || id == ".." || filepath.Base(id) != id {
+		return nil, errors.Errorf("invalid %s id %q", kind, id)
+	}
+	nodeDir := filepath.Join(nodesDir, id)
+	if _, err := os.Stat(nodeDir); err != nil {
+		return nil, errdefs.Errorf(errdefs.ErrNodeNotFound, "%s %s not found", kind, id)
+	}
+	n := &Node{
+		Kind:     kind,
+		ID:       id,
+		Channels: listChannels(kind, nodeDir),
+	}
+	runConfigBytes, err := os.ReadFile(filepath.Join(nodeDir, "run.json"))
+	if err != nil {
+		return n, nil
+	}
+	rc := runConfig{}
+	err = json.Unmarshal(runConfigBytes, &rc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the run.json of %s %s", kind, id)
+	}
+	n.Running = true
+	n.MSPID = rc.Options.MSPID
+	n.ExternalEndpoint = rc.Options.ExternalEndpoint
+	n.ManagementAddress = rc.Options.ManagementAddress
+	n.ManagementSocket = rc.Options.Auth.Socket
+	n.tlsCert = rc.Options.Auth.TLSCert
+	if n.ManagementAddress == "" && n.ManagementSocket == "" {
+		n.Error = "the node has no management address"
+		return n, nil
+	}
+	n.Status, err = getStatus(n, token)
+	if err != nil {
+		n.Error = err.Error()
+	}
+	return n, nil
+}
+
+// ListNodes returns the peers and orderers of the host, their status is read
+// with the API token of the caller
+func ListNodes(token string) ([]Node, error) {
+	nodes := []Node{}
+	for _, kind := range []string{KindPeer, KindOrderer} {
+		nodesDir, err := getNodesDir(kind)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := os.ReadDir(nodesDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			n, err := GetNode(kind, entry.Name(), token)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, *n)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Kind != nodes[j].Kind {
+			return nodes[i].Kind < nodes[j].Kind
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+	return nodes, nil
+}
+
+// Actions of the management API of the nodes
+var Actions = []string{"start", "stop", "restart"}
+
+// RunAction starts, stops or restarts a node through its management API with
+// the API token of the caller
+func RunAction(kind string, id string, action string, token string) error {
+	return RunLockedAction(kind, id, action, token, nil)
+}
+
+// RunLockedAction runs an action of a node whose lock is held by the caller,
+// the action is refused when another operation holds the lock
+func RunLockedAction(kind string, id string, action string, token string, l *lock.Lock) error {
+	validAction := false
+	for _, a := range Actions {
+		validAction = validAction || a == action
+	}
+	if !validAction {
+		return errors.Errorf("unknown action %s", action)
+	}
+	if kind != KindPeer && kind != KindOrderer {
+		return errors.Errorf("unknown node kind %s", kind)
+	}
+	n, err := GetNode(kind, id, token)
+	if err != nil {
+		return err
+	}
+	if !n.Running || (n.ManagementAddress == "" && n.ManagementSocket == "") {
+		return errdefs.Errorf(errdefs.ErrNodeNotRunning, "%s %s is not running, start it with hlf-easy %s start", kind, id, kind)
+	}
+	lockToken := ""
+	if l != nil {
+		lockToken = l.Holder.Token
+	}
+	resp, err := doRequest(n, http.MethodPost, "/"+action, nil, token, lockToken)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return apiError(body, fmt.Sprintf("failed to %s %s %s", action, kind, id))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package dashboard
+
+import (
+	"embed"
+	"github.com/gin-gonic/gin"
+	"hlf-easy/audit"
+	"hlf-easy/auth"
+	"hlf-easy/chaincode"
+	"hlf-easy/config"
+	"hlf-easy/resources"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+//go:embed static
+var static embed.FS
+
+// uiFiles tells the requests of the web UI, they don't need authentication
+func uiFiles(c *gin.Context) bool {
+	path := c.Request.URL.Path
+	return c.Request.Method == http.MethodGet && (path == "/" || strings.HasPrefix(path, "/ui/"))
+}
+
+// NewRouter returns the dashboard and its API, the nodes are managed through
+// their own management API with the token of the caller
+func NewRouter(authOpts config.APIAuthOptions) (*gin.Engine, error) {
+	staticFS, err := fs.Sub(static, "static")
+	if err != nil {
+		return nil, err
+	}
+	authenticator, err := auth.NewAuthenticator(authOpts, uiFiles)
+	if err != nil {
+		return nil, err
+	}
+	r := gin.Default()
+	r.Use(authenticator.Middleware())
+	api := r.Group("/api")
+	api.GET("/nodes", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
+		nodes, err := ListNodes(auth.RequestToken(c.Request))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, nodes)
+	})
+	api.POST("/nodes/:kind/:id/:action", auth.Allow(auth.ResourceNodes), func(c *gin.Context) {
+		err := RunAction(c.Param("kind"), c.Param("id"), c.Param("action"), auth.RequestToken(c.Request))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+		})
+	})
+	api.GET("/audit", auth.Allow(auth.ResourceAudit), audit.Handler)
+	api.GET("/chaincodes", auth.Allow(auth.ResourceChaincodes), func(c *gin.Context) {
+		definitions, err := chaincode.List()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, definitions)
+	})
+	api.GET("/host", auth.Allow(auth.ResourceHost), func(c *gin.Context) {
+		utilization, err := resources.GetUtilization()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, utilization)
+	})
+	r.StaticFS("/ui", http.FS(staticFS))
+	r.GET("/", func(c *gin.Context) {
+		c.Redirect(http.StatusFound, "/ui/")
+	})
+	return r, nil
+}
@@ -0,0 +1,174 @@
+'use strict';
+
+const refreshInterval = 5000;
+
+function text(value) {
+  const span = document.createElement('span');
+  span.textContent = value === undefined || value === null ? '' : String(value);
+  return span.innerHTML;
+}
+
+function formatBytes(bytes) {
+  if (!bytes) {
+    return '-';
+  }
+  return (bytes / 1024 / 1024).toFixed(0) + ' MB';
+}
+
+function formatUptime(seconds) {
+  if (!seconds) {
+    return '-';
+  }
+  const hours = Math.floor(seconds / 3600);
+  const minutes = Math.floor((seconds % 3600) / 60);
+  return hours + 'h ' + minutes + 'm';
+}
+
+// request asks for an API token when the dashboard refuses the request, the
+// token is kept in the cookie read by the dashboard
+async function request(path, options) {
+  const cookie = document.cookie;
+  let resp = await fetch(path, options);
+  if (resp.status === 401) {
+    // the parallel requests only prompt once
+    if (document.cookie === cookie) {
+      const token = window.prompt('API token (hlf-easy apitoken create)');
+      if (!token) {
+        return resp;
+      }
+      document.cookie = `hlf-easy-token=${encodeURIComponent(token.trim())}; path=/; SameSite=Strict`;
+    }
+    resp = await fetch(path, options);
+  }
+  return resp;
+}
+
+async function getJSON(path) {
+  const resp = await request(path);
+  const body = await resp.json();
+  if (!resp.ok) {
+    throw new Error(body.error || resp.statusText);
+  }
+  return body;
+}
+
+function renderHost(host) {
+  const cards = [
+    ['CPUs', host.capacity.cpus],
+    ['Memory', host.capacity.memoryMB + ' MB'],
+    ['Reserved CPU', host.reservedCPUPercent.toFixed(0) + '%'],
+    ['Reserved memory', host.reservedMemoryPercent.toFixed(0) + '%'],
+    ['Used memory', host.usedMemoryPercent.toFixed(0) + '%'],
+  ];
+  document.getElementById('host').innerHTML = cards
+    .map(([label, value]) => `<div class="card">${text(label)}<strong>${text(value)}</strong></div>`)
+    .join('');
+}
+
+// formatLag shows the channels of a peer behind the other peers of its org
+function formatLag(heightLag) {
+  if (!heightLag) {
+    return '-';
+  }
+  if (heightLag.error) {
+    return 'unknown';
+  }
+  const lagging = heightLag.channels.filter((channel) => channel.lag > 0);
+  if (lagging.length === 0) {
+    return 'in sync';
+  }
+  return lagging.map((channel) => `${channel.channel}: ${channel.lag} blocks`).join(', ');
+}
+
+// formatDisk shows the size of the ledger of a peer and the usage of its
+// filesystem
+function formatDisk(diskUsage) {
+  if (!diskUsage) {
+    return '-';
+  }
+  if (diskUsage.error) {
+    return 'unknown';
+  }
+  return `${formatBytes(diskUsage.ledgerBytes)}, disk ${diskUsage.usedPercent.toFixed(0)}%`;
+}
+
+function renderNodes(nodes) {
+  document.getElementById('nodes').innerHTML = nodes.map((node) => {
+    const status = node.status || {};
+    const running = node.running && status.pid > 0;
+    let state = node.running ? (status.status || 'Unknown') : 'Not running';
+    if (node.error) {
+      state += ` (${node.error})`;
+    }
+    const buttons = node.running ? ['start', 'stop', 'restart']
+      .map((action) => `<button data-kind="${text(node.kind)}" data-id="${text(node.id)}" data-action="${action}">${action}</button>`)
+      .join('') : '';
+    const lagging = running && status.heightLag && status.heightLag.lagging;
+    const diskHigh = running && status.diskUsage && status.diskUsage.high;
+    return `<tr class="${running ? (lagging || diskHigh ? 'lagging' : '') : 'stopped'}">
+      <td>${text(node.kind)}/${text(node.id)}</td>
+      <td>${text(node.mspID)}</td>
+      <td>${text(state)}</td>
+      <td>${running ? status.cpu.percent.toFixed(1) + '%' : '-'}</td>
+      <td>${running ? formatBytes(status.memory.rss) : '-'}</td>
+      <td>${running ? formatUptime(status.uptime) : '-'}</td>
+      <td>${text(node.channels.join(', '))}</td>
+      <td>${running ? text(formatLag(status.heightLag)) : '-'}</td>
+      <td>${running ? text(formatDisk(status.diskUsage)) : '-'}</td>
+      <td>${buttons}</td>
+    </tr>`;
+  }).join('');
+}
+
+function renderChaincodes(chaincodes) {
+  document.getElementById('chaincodes').innerHTML = chaincodes.map((cc) => `<tr>
+      <td>${text(cc.name)}</td>
+      <td>${text(cc.version)}</td>
+      <td>${text(cc.type)}</td>
+      <td>${text(cc.address)}</td>
+      <td>${text(cc.limits.executeTimeout || '-')}</td>
+    </tr>`).join('');
+}
+
+async function refresh() {
+  const errorElement = document.getElementById('error');
+  try {
+    const [host, nodes, chaincodes] = await Promise.all([
+      getJSON('/api/host'),
+      getJSON('/api/nodes'),
+      getJSON('/api/chaincodes'),
+    ]);
+    renderHost(host);
+    renderNodes(nodes);
+    renderChaincodes(chaincodes);
+    errorElement.textContent = '';
+    document.getElementById('updated').textContent = 'Updated ' + new Date().toLocaleTimeString();
+  } catch (err) {
+    errorElement.textContent = err.message;
+  }
+}
+
+document.getElementById('nodes').addEventListener('click', async (event) => {
+  const button = event.target.closest('button');
+  if (!button) {
+    return;
+  }
+  const { kind, id, action } = button.dataset;
+  if (action !== 'start' && !window.confirm(`${action} ${kind} ${id}?`)) {
+    return;
+  }
+  button.disabled = true;
+  try {
+    const resp = await request(`/api/nodes/${encodeURIComponent(kind)}/${encodeURIComponent(id)}/${action}`, { method: 'POST' });
+    const body = await resp.json();
+    if (!resp.ok) {
+      throw new Error(body.error || resp.statusText);
+    }
+  } catch (err) {
+    document.getElementById('error').textContent = err.message;
+  }
+  await refresh();
+});
+
+refresh();
+setInterval(refresh, refreshInterval);
@@ -0,0 +1,261 @@
+package discover
+
+import (
+	"context"
+	"fmt"
+	"github.com/hyperledger/fabric-protos-go/gossip"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fab/discovery"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+	"github.com/pkg/errors"
+	"hlf-easy/contract"
+	"sort"
+	"time"
+)
+
+// DefaultTimeout is how long the discovery service has to answer
+const DefaultTimeout = 30 * time.Second
+
+// Options select the peer and the channel the discovery service is queried
+// on
+type Options struct {
+	PeerID string
+	// Identity is the identity file of a member of the channel, the admin
+	// identity managed for the peer is used when empty
+	Identity string
+	// Endpoint overrides the external endpoint of the peer
+	Endpoint string
+	Channel  string
+}
+
+// Peer is a peer of the channel known to the discovery service through
+// gossip
+type Peer struct {
+	MSPID        string `json:"mspId"`
+	Endpoint     string `json:"endpoint"`
+	LedgerHeight uint64 `json:"ledgerHeight,omitempty"`
+	// Chaincodes installed on the peer, with their version
+	Chaincodes []string `json:"chaincodes,omitempty"`
+}
+
+// Peers are the peers of the channel known to the discovery service
+type Peers struct {
+	Peers []Peer `json:"peers"`
+	// MissingOrgs are the orgs of the channel with no peer known, their
+	// anchor peers are missing or unreachable when they have peers
+	MissingOrgs []string `json:"missingOrgs,omitempty"`
+}
+
+// Config is the config of the channel served by the discovery service
+type Config struct {
+	MSPIDs []string `json:"mspIds"`
+	// Orderers are the endpoints of the orderers of the orgs
+	Orderers map[string][]string `json:"orderers"`
+}
+
+// Interest is the chaincode, and its collections, of the peers and
+// endorsers queried
+type Interest struct {
+	Chaincode   string
+	Collections []string
+}
+
+func (i Interest) calls() []*peer.ChaincodeCall {
+	if i.Chaincode == "" {
+		return nil
+	}
+	return []*peer.ChaincodeCall{{Name: i.Chaincode, CollectionNames: i.Collections}}
+}
+
+// newPeer returns the peer of the alive and state info messages of gossip, the
+// state info is only known for the peers that joined the channel
+func newPeer(mspID string, alive *gossip.GossipMessage, stateInfo *gossip.GossipMessage) Peer {
+	p := Peer{MSPID: mspID}
+	if membership := alive.GetAliveMsg().GetMembership(); membership != nil {
+		p.Endpoint = membership.Endpoint
+	}
+	if properties := stateInfo.GetStateInfo().GetProperties(); properties != nil {
+		p.LedgerHeight = properties.LedgerHeight
+		for _, cc := range properties.Chaincodes {
+			p.Chaincodes = append(p.Chaincodes, fmt.Sprintf("%s:%s", cc.Name, cc.Version))
+		}
+	}
+	return p
+}
+
+func sortPeers(peers []Peer) {
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].MSPID != peers[j].MSPID {
+			return peers[i].MSPID < peers[j].MSPID
+		}
+		return peers[i].Endpoint < peers[j].Endpoint
+	})
+}
+
+// missingOrgs returns the orgs of the config without a peer, the orgs with
+// only orderers aren't expected to have peers
+func missingOrgs(config *Config, peers []Peer) []string {
+	found := map[string]bool{}
+	for _, p := range peers {
+		found[p.MSPID] = true
+	}
+	missing := []string{}
+	for _, mspID := range config.MSPIDs {
+		if _, orderer := config.Orderers[mspID]; !found[mspID] && !orderer {
+			missing = append(missing, mspID)
+		}
+	}
+	return missing
+}
+
+// query sends the request, whose queries are of the channel of the options,
+// to the discovery service of the peer. The request is canceled when the
+// context is done
+func query(ctx context.Context, opts Options, req *discovery.Request) (discovery.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	clientConfig, profile, err := contract.Profile(contract.Options{
+		PeerID:   opts.PeerID,
+		Identity: opts.Identity,
+		Endpoint: opts.Endpoint,
+		Channel:  opts.Channel,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sdk, err := fabsdk.New(config.FromRaw(profile, "yaml"))
+	if err != nil {
+		return nil, err
+	}
+	defer sdk.Close()
+	clientCtx, err := sdk.Context(
+		fabsdk.WithUser(contract.ProfileUser),
+		fabsdk.WithOrg(clientConfig.MSPID),
+	)()
+	if err != nil {
+		return nil, err
+	}
+	target, ok := clientCtx.EndpointConfig().PeerConfig(opts.PeerID)
+	if !ok {
+		return nil, errors.Errorf("peer %s not found in the connection profile", opts.PeerID)
+	}
+	client, err := discovery.New(clientCtx)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := client.Send(ctx, req, *target)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-responses:
+		if resp == nil {
+			return nil, errors.Errorf("peer %s didn't answer the discovery request", opts.PeerID)
+		}
+		if err := resp.Error(); err != nil {
+			return nil, errors.Wrapf(err, "failed to query the discovery service of peer %s", opts.PeerID)
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return nil, errors.Wrapf(ctx.Err(), "the discovery service of peer %s didn't answer", opts.PeerID)
+	}
+}
+
+func newConfig(resp discovery.Response, channel string) (*Config, error) {
+	result, err := resp.ForChannel(channel).Config()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to discover the config of channel %s", channel)
+	}
+	config := &Config{MSPIDs: []string{}, Orderers: map[string][]string{}}
+	for mspID := range result.Msps {
+		config.MSPIDs = append(config.MSPIDs, mspID)
+	}
+	sort.Strings(config.MSPIDs)
+	for mspID, endpoints := range result.Orderers {
+		for _, endpoint := range endpoints.Endpoint {
+			config.Orderers[mspID] = append(config.Orderers[mspID], fmt.Sprintf("%s:%d", endpoint.Host, endpoint.Port))
+		}
+	}
+	return config, nil
+}
+
+// GetConfig returns the orgs and the orderers of the channel as the
+// discovery service serves them to the SDKs
+func GetConfig(ctx context.Context, opts Options) (*Config, error) {
+	resp, err := query(ctx, opts, discovery.NewRequest().OfChannel(opts.Channel).AddConfigQuery())
+	if err != nil {
+		return nil, err
+	}
+	return newConfig(resp, opts.Channel)
+}
+
+// GetPeers returns the peers of the channel known to the discovery service,
+// only the ones with the chaincode of the interest installed when it's set.
+// The orgs without peers are returned when the interest has no chaincode
+func GetPeers(ctx context.Context, opts Options, interest Interest) (*Peers, error) {
+	req := discovery.NewRequest().OfChannel(opts.Channel).AddPeersQuery(interest.calls()...)
+	if interest.Chaincode == "" {
+		req = req.AddConfigQuery()
+	}
+	resp, err := query(ctx, opts, req)
+	if err != nil {
+		return nil, err
+	}
+	discovered, err := resp.ForChannel(opts.Channel).Peers(interest.calls()...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to discover the peers of channel %s", opts.Channel)
+	}
+	peers := &Peers{Peers: []Peer{}}
+	for _, p := range discovered {
+		var alive, stateInfo *gossip.GossipMessage
+		if p.AliveMessage != nil {
+			alive = p.AliveMessage.GossipMessage
+		}
+		if p.StateInfoMessage != nil {
+			stateInfo = p.StateInfoMessage.GossipMessage
+		}
+		peers.Peers = append(peers.Peers, newPeer(p.MSPID, alive, stateInfo))
+	}
+	sortPeers(peers.Peers)
+	if interest.Chaincode == "" {
+		config, err := newConfig(resp, opts.Channel)
+		if err != nil {
+			return nil, err
+		}
+		peers.MissingOrgs = missingOrgs(config, peers.Peers)
+	}
+	return peers, nil
+}
+
+// GetEndorsers returns a set of peers whose endorsements satisfy the
+// endorsement policy of the chaincode of the interest, and of its
+// collections, as the SDKs select them
+func GetEndorsers(ctx context.Context, opts Options, interest Interest) ([]Peer, error) {
+	req, err := discovery.NewRequest().OfChannel(opts.Channel).AddEndorsersQuery(discovery.CcInterests(interest.calls())...)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := query(ctx, opts, req)
+	if err != nil {
+		return nil, err
+	}
+	endorsers, err := resp.ForChannel(opts.Channel).Endorsers(interest.calls(), discovery.NewIndifferentFilter())
+	if err != nil {
+		return nil, errors.Wrapf(err, "the peers known to peer %s can't satisfy the endorsement policy of chaincode %s", opts.PeerID, interest.Chaincode)
+	}
+	peers := []Peer{}
+	for _, p := range endorsers {
+		var alive, stateInfo *gossip.GossipMessage
+		if p.AliveMessage != nil {
+			alive = p.AliveMessage.GossipMessage
+		}
+		if p.StateInfoMessage != nil {
+			stateInfo = p.StateInfoMessage.GossipMessage
+		}
+		peers = append(peers, newPeer(p.MSPID, alive, stateInfo))
+	}
+	sortPeers(peers)
+	return peers, nil
+}
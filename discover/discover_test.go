@@ -0,0 +1,36 @@
+package discover
+
+import (
+	"github.com/hyperledger/fabric-protos-go/gossip"
+	"reflect"
+	"testing"
+)
+
+func TestNewPeer(t *testing.T) {
+	alive := &gossip.GossipMessage{Content: &gossip.GossipMessage_AliveMsg{AliveMsg: &gossip.AliveMessage{
+		Membership: &gossip.Member{Endpoint: "peer1.org1.example.com:7051"},
+	}}}
+	stateInfo := &gossip.GossipMessage{Content: &gossip.GossipMessage_StateInfo{StateInfo: &gossip.StateInfo{
+		Properties: &gossip.Properties{LedgerHeight: 12, Chaincodes: []*gossip.Chaincode{{Name: "asset", Version: "1.0"}}},
+	}}}
+	p := newPeer("Org1MSP", alive, stateInfo)
+	expected := Peer{MSPID: "Org1MSP", Endpoint: "peer1.org1.example.com:7051", LedgerHeight: 12, Chaincodes: []string{"asset:1.0"}}
+	if !reflect.DeepEqual(p, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, p)
+	}
+	p = newPeer("Org1MSP", alive, nil)
+	if p.Endpoint != expected.Endpoint || p.LedgerHeight != 0 || p.Chaincodes != nil {
+		t.Fatalf("expected a peer without state info, got %+v", p)
+	}
+}
+
+func TestMissingOrgs(t *testing.T) {
+	config := &Config{
+		MSPIDs:   []string{"Org1MSP", "Org2MSP", "OrdererMSP"},
+		Orderers: map[string][]string{"OrdererMSP": {"orderer0.example.com:7050"}},
+	}
+	peers := []Peer{{MSPID: "Org1MSP", Endpoint: "peer1.org1.example.com:7051"}}
+	if missing := missingOrgs(config, peers); !reflect.DeepEqual(missing, []string{"Org2MSP"}) {
+		t.Fatalf("expected Org2MSP to be missing, got %v", missing)
+	}
+}
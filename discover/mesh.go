@@ -0,0 +1,151 @@
+package discover
+
+import (
+	"context"
+	"github.com/hyperledger/fabric-protos-go/gossip"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fab/discovery"
+	"github.com/pkg/errors"
+	"hlf-easy/log"
+	"hlf-easy/monitoring"
+	"hlf-easy/node"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// MeshPeer is a peer of the host, the peers it knows through gossip and the
+// peers of the host that know it
+type MeshPeer struct {
+	ID       string `json:"id"`
+	MSPID    string `json:"mspId"`
+	Endpoint string `json:"endpoint"`
+	// Sees are the endpoints of the peers the peer knows, of every org
+	Sees []string `json:"sees"`
+	// SeenBy are the IDs of the peers of the host that know the peer
+	SeenBy []string `json:"seenBy"`
+	// Channels is the gossip of the channels of the peer, read from its
+	// metrics when it exposes Prometheus metrics
+	Channels []node.ChannelGossip `json:"channels,omitempty"`
+	// Isolated is set when the peer and the other running peers of its org
+	// on the host don't know each other
+	Isolated bool `json:"isolated"`
+	// Error is set when the membership of the peer couldn't be queried,
+	// usually because it isn't running
+	Error string `json:"error,omitempty"`
+}
+
+// GetLocalPeers returns the peers the peer knows through gossip, of every
+// channel, with the admin identity managed for the peer
+func GetLocalPeers(ctx context.Context, peerID string) ([]Peer, error) {
+	resp, err := query(ctx, Options{PeerID: peerID}, discovery.NewRequest().AddLocalPeersQuery())
+	if err != nil {
+		return nil, err
+	}
+	discovered, err := resp.ForLocal().Peers()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to discover the membership of peer %s", peerID)
+	}
+	peers := []Peer{}
+	for _, p := range discovered {
+		var alive *gossip.GossipMessage
+		if p.AliveMessage != nil {
+			alive = p.AliveMessage.GossipMessage
+		}
+		peers = append(peers, newPeer(p.MSPID, alive, nil))
+	}
+	sortPeers(peers)
+	return peers, nil
+}
+
+// computeMesh sets which peers of the host know each peer and flags the
+// isolated ones
+func computeMesh(mesh []MeshPeer) {
+	ids := map[string]string{}
+	for _, p := range mesh {
+		ids[p.Endpoint] = p.ID
+	}
+	seenBy := map[string][]string{}
+	sees := map[string]map[string]bool{}
+	for _, p := range mesh {
+		sees[p.ID] = map[string]bool{}
+		for _, endpoint := range p.Sees {
+			if id, ok := ids[endpoint]; ok && id != p.ID {
+				seenBy[id] = append(seenBy[id], p.ID)
+				sees[p.ID][id] = true
+			}
+		}
+	}
+	for i := range mesh {
+		p := &mesh[i]
+		p.SeenBy = seenBy[p.ID]
+		if p.SeenBy == nil {
+			p.SeenBy = []string{}
+		}
+		sort.Strings(p.SeenBy)
+		if p.Error != "" {
+			continue
+		}
+		orgPeers, connected := 0, false
+		for _, other := range mesh {
+			if other.ID == p.ID || other.MSPID != p.MSPID || other.Error != "" {
+				continue
+			}
+			orgPeers++
+			connected = connected || sees[p.ID][other.ID] || sees[other.ID][p.ID]
+		}
+		p.Isolated = orgPeers > 0 && !connected
+	}
+}
+
+// GetMesh queries the gossip membership of the peers of the host, only the
+// ones of the org when mspID is set, and the gossip of their channels
+func GetMesh(ctx context.Context, mspID string) ([]MeshPeer, error) {
+	peers, err := node.ListPeerEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	targets, _, err := monitoring.ListTargets("127.0.0.1")
+	if err != nil {
+		return nil, err
+	}
+	addresses := map[string]string{}
+	for _, target := range targets {
+		if target.Kind == monitoring.KindPeer {
+			addresses[target.ID] = target.Address
+		}
+	}
+	mesh := []MeshPeer{}
+	for _, p := range peers {
+		if mspID != "" && p.MSPID != mspID {
+			continue
+		}
+		meshPeer := MeshPeer{ID: p.ID, MSPID: p.MSPID, Endpoint: p.Endpoint, Sees: []string{}}
+		known, err := GetLocalPeers(ctx, p.ID)
+		if err != nil {
+			meshPeer.Error = err.Error()
+			mesh = append(mesh, meshPeer)
+			continue
+		}
+		for _, k := range known {
+			if k.Endpoint != p.Endpoint {
+				meshPeer.Sees = append(meshPeer.Sees, k.Endpoint)
+			}
+		}
+		if address, ok := addresses[p.ID]; ok {
+			endpoint, err := node.GetPeerOperationsEndpoint(filepath.Join(home, "hlf-easy", "peers", p.ID), address)
+			if err == nil {
+				meshPeer.Channels, err = node.GetGossipMetrics(endpoint)
+			}
+			if err != nil {
+				log.Debugf("Failed to read the gossip metrics of peer %s: %v", p.ID, err)
+			}
+		}
+		mesh = append(mesh, meshPeer)
+	}
+	computeMesh(mesh)
+	return mesh, nil
+}
@@ -0,0 +1,29 @@
+package discover
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeMesh(t *testing.T) {
+	mesh := []MeshPeer{
+		{ID: "peer0", MSPID: "Org1MSP", Endpoint: "peer0.org1:7051", Sees: []string{"peer1.org1:7051", "peer0.org2:7051"}},
+		{ID: "peer1", MSPID: "Org1MSP", Endpoint: "peer1.org1:7051", Sees: []string{"peer0.org1:7051"}},
+		{ID: "peer2", MSPID: "Org1MSP", Endpoint: "peer2.org1:7051", Sees: []string{"peer0.org2:7051"}},
+		{ID: "peer3", MSPID: "Org1MSP", Endpoint: "peer3.org1:7051", Sees: []string{}, Error: "connection refused"},
+		{ID: "org2peer0", MSPID: "Org2MSP", Endpoint: "peer0.org2:7051", Sees: []string{}},
+	}
+	computeMesh(mesh)
+	if !reflect.DeepEqual(mesh[0].SeenBy, []string{"peer1"}) || !reflect.DeepEqual(mesh[4].SeenBy, []string{"peer0", "peer2"}) {
+		t.Fatalf("unexpected seen by %v and %v", mesh[0].SeenBy, mesh[4].SeenBy)
+	}
+	isolated := []bool{}
+	for _, p := range mesh {
+		isolated = append(isolated, p.Isolated)
+	}
+	// peer2 only knows a peer of another org, the stopped peer3 and the only
+	// peer of Org2MSP aren't isolated
+	if !reflect.DeepEqual(isolated, []bool{false, false, true, false, false}) {
+		t.Fatalf("unexpected isolated peers %v", isolated)
+	}
+}
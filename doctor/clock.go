@@ -0,0 +1,69 @@
+package doctor
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"net"
+	"time"
+)
+
+// DefaultNTPServer is the NTP server the clock of the host is compared to
+const DefaultNTPServer = "pool.ntp.org"
+
+// ntpEpochOffset is the number of seconds between the NTP epoch, 1900, and
+// the Unix epoch
+const ntpEpochOffset = 2208988800
+
+// ntpTime decodes an NTP timestamp, seconds and fraction of a second since
+// 1900
+func ntpTime(b []byte) time.Time {
+	seconds := binary.BigEndian.Uint32(b[0:4])
+	fraction := binary.BigEndian.Uint32(b[4:8])
+	nanoseconds := (uint64(fraction) * 1e9) >> 32
+	return time.Unix(int64(seconds)-ntpEpochOffset, int64(nanoseconds))
+}
+
+// ClockOffset asks an NTP server for its time with SNTP, the offset is
+// positive when the clock of the host is behind the one of the server
+func ClockOffset(ctx context.Context, server string) (time.Duration, error) {
+	address := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		address = net.JoinHostPort(server, "123")
+	}
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to reach NTP server %s", server)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return 0, err
+		}
+	}
+	request := make([]byte, 48)
+	// no leap indicator, version 3, client mode
+	request[0] = 0x1b
+	sent := time.Now()
+	if _, err := conn.Write(request); err != nil {
+		return 0, errors.Wrapf(err, "failed to query NTP server %s", server)
+	}
+	response := make([]byte, 48)
+	n, err := conn.Read(response)
+	received := time.Now()
+	if err != nil {
+		return 0, errors.Wrapf(err, "NTP server %s didn't answer", server)
+	}
+	if n < 48 || response[0]&0x7 != 4 {
+		return 0, errors.Errorf("invalid answer of NTP server %s", server)
+	}
+	if response[1] == 0 {
+		return 0, errors.Errorf("NTP server %s refused to answer", server)
+	}
+	// the time the server received the request and sent the answer, the
+	// network delay is assumed symmetric
+	serverReceived := ntpTime(response[32:40])
+	serverSent := ntpTime(response[40:48])
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
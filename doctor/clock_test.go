@@ -0,0 +1,59 @@
+package doctor
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// putNTPTime encodes an NTP timestamp
+func putNTPTime(b []byte, t time.Time) {
+	binary.BigEndian.PutUint32(b[0:4], uint32(t.Unix()+ntpEpochOffset))
+	binary.BigEndian.PutUint32(b[4:8], uint32((uint64(t.Nanosecond())<<32)/1e9))
+}
+
+// serveNTP answers the SNTP requests with a clock off by the skew
+func serveNTP(t *testing.T, skew time.Duration, stratum byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		request := make([]byte, 48)
+		for {
+			_, addr, err := conn.ReadFrom(request)
+			if err != nil {
+				return
+			}
+			response := make([]byte, 48)
+			// version 3, server mode
+			response[0] = 0x1c
+			response[1] = stratum
+			putNTPTime(response[32:40], time.Now().Add(skew))
+			putNTPTime(response[40:48], time.Now().Add(skew))
+			conn.WriteTo(response, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestClockOffset(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	offset, err := ClockOffset(ctx, serveNTP(t, 20*time.Minute, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset < 20*time.Minute-time.Second || offset > 20*time.Minute+time.Second {
+		t.Fatalf("expected the host to be 20m behind, got %s", offset)
+	}
+
+	_, err = ClockOffset(ctx, serveNTP(t, 0, 0))
+	if err == nil {
+		t.Fatal("expected the kiss of death of the server to fail")
+	}
+}
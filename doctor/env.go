@@ -0,0 +1,281 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"github.com/shirou/gopsutil/disk"
+	"hlf-easy/chaincode"
+	"hlf-easy/fabric"
+	"hlf-easy/node"
+	"hlf-easy/report"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Thresholds of the environment checks
+const (
+	// AuthenticationTimeWindow is the authentication.timewindow of the peers
+	// and orderers, the requests signed by a client whose clock is further
+	// off are rejected
+	AuthenticationTimeWindow = 15 * time.Minute
+	// MaxClockOffset is the offset from the NTP server above which the clock
+	// is reported as drifting
+	MaxClockOffset = time.Minute
+	// MinOpenFiles is the open files limit the nodes need under load
+	MinOpenFiles = 65536
+	// MinFreeDisk is the free space of the data directory below which the
+	// nodes can't write their ledger
+	MinFreeDisk = 1 << 30
+	// MaxDiskUsage is the usage of the data directory in percent above which
+	// it's reported as filling up
+	MaxDiskUsage = 90
+)
+
+// EnvOptions are the options of the environment checks
+type EnvOptions struct {
+	// NTPServer is the server the clock is compared to, the clock isn't
+	// checked when it's empty
+	NTPServer         string
+	ExpiryWarningDays int
+	Timeout           time.Duration
+}
+
+// CheckEnv checks the host runs the nodes: the Fabric binaries, docker for
+// the docker chaincodes, the open files limit, the clock, the free space of
+// the data directory and the validity of the certificates
+func CheckEnv(ctx context.Context, opts EnvOptions) []node.Check {
+	checks := []node.Check{
+		checkBinary("peer"),
+		checkBinary("orderer"),
+	}
+	checks = append(checks, checkPeerBinaries()...)
+	checks = append(checks, checkDocker(ctx, opts.Timeout))
+	limit, err := openFilesLimit()
+	if err != nil {
+		checks = append(checks, node.Check{Name: "open files", Status: node.CheckWarn, Message: fmt.Sprintf("failed to read the open files limit: %v", err)})
+	} else {
+		checks = append(checks, openFilesCheck(limit))
+	}
+	checks = append(checks, checkClock(ctx, opts.NTPServer, opts.Timeout))
+	checks = append(checks, checkDisk())
+	checks = append(checks, checkCertificates(time.Now(), opts.ExpiryWarningDays))
+	return checks
+}
+
+// checkBinary checks a binary of Fabric is in the PATH and reports its
+// version, the nodes without a fabric version run it
+func checkBinary(name string) node.Check {
+	check := node.Check{Name: "binary " + name}
+	path, err := exec.LookPath(fabric.BinaryFile(name))
+	if err != nil {
+		check.Status = node.CheckWarn
+		check.Message = fmt.Sprintf("%s isn't in the PATH, only the nodes with a fabric version can start", name)
+		return check
+	}
+	version, err := fabric.BinaryVersion(path)
+	if err != nil {
+		check.Status = node.CheckFail
+		check.Message = err.Error()
+		return check
+	}
+	check.Status = node.CheckPass
+	check.Message = fmt.Sprintf("%s %s at %s", name, version, path)
+	return check
+}
+
+// checkPeerBinaries checks the binaries of the fabric versions the peers of
+// the host are pinned to are installed
+func checkPeerBinaries() []node.Check {
+	checks := []node.Check{}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return checks
+	}
+	peerDirs, err := filepath.Glob(filepath.Join(home, "hlf-easy/peers/*"))
+	if err != nil {
+		return checks
+	}
+	sort.Strings(peerDirs)
+	for _, peerDir := range peerDirs {
+		binary, err := node.GetPeerBinary(peerDir)
+		if err != nil || binary == "peer" {
+			continue
+		}
+		check := node.Check{Name: "binary of peer " + filepath.Base(peerDir), Status: node.CheckPass}
+		if version, err := fabric.BinaryVersion(binary); err != nil {
+			check.Status = node.CheckFail
+			check.Message = fmt.Sprintf("%s can't run, install its fabric version again with hlf-easy peer upgrade or bundle import: %v", binary, err)
+		} else {
+			check.Message = fmt.Sprintf("peer %s at %s", version, binary)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkDocker checks the docker daemon answers, it's only required by the
+// docker chaincodes
+func checkDocker(ctx context.Context, timeout time.Duration) node.Check {
+	check := node.Check{Name: "docker"}
+	dockerChaincodes := []string{}
+	if definitions, err := chaincode.List(); err == nil {
+		for _, d := range definitions {
+			if d.Type == chaincode.TypeDocker {
+				dockerChaincodes = append(dockerChaincodes, d.Name)
+			}
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "docker", "version", "--format", "{{.Server.Version}}").Output()
+	if err == nil {
+		check.Status = node.CheckPass
+		check.Message = "docker " + strings.TrimSpace(string(output))
+		return check
+	}
+	if len(dockerChaincodes) > 0 {
+		check.Status = node.CheckFail
+		check.Message = fmt.Sprintf("the docker daemon isn't available, the docker chaincodes %s can't run: %v", strings.Join(dockerChaincodes, ", "), err)
+		return check
+	}
+	check.Status = node.CheckWarn
+	check.Message = "the docker daemon isn't available, only the ccaas chaincodes can run"
+	return check
+}
+
+// openFilesCheck checks the open files limit of the nodes
+func openFilesCheck(limit uint64) node.Check {
+	check := node.Check{Name: "open files", Status: node.CheckPass}
+	if limit == math.MaxUint64 {
+		check.Message = "the open files are unlimited"
+		return check
+	}
+	check.Message = fmt.Sprintf("the open files limit is %d", limit)
+	if limit < MinOpenFiles {
+		check.Status = node.CheckWarn
+		check.Message = fmt.Sprintf("the open files limit is %d, raise it to %d with nofile in /etc/security/limits.conf or LimitNOFILE of the service", limit, MinOpenFiles)
+	}
+	return check
+}
+
+// clockCheck checks the offset of the clock from an NTP server, the peers
+// and orderers reject the requests signed out of their time window
+func clockCheck(server string, offset time.Duration) node.Check {
+	check := node.Check{Name: "clock", Status: node.CheckPass}
+	direction := "behind"
+	abs := offset
+	if offset < 0 {
+		direction = "ahead of"
+		abs = -offset
+	}
+	check.Message = fmt.Sprintf("the clock is %s %s %s", abs.Round(time.Millisecond), direction, server)
+	switch {
+	case abs >= AuthenticationTimeWindow:
+		check.Status = node.CheckFail
+		check.Message += fmt.Sprintf(", the nodes reject the requests signed outside their %s time window, sync it with NTP", AuthenticationTimeWindow)
+	case abs >= MaxClockOffset:
+		check.Status = node.CheckWarn
+		check.Message += ", sync it with NTP before it leaves the time window of the nodes"
+	}
+	return check
+}
+
+// checkClock compares the clock to the one of an NTP server
+func checkClock(ctx context.Context, server string, timeout time.Duration) node.Check {
+	if server == "" {
+		return node.Check{Name: "clock", Status: node.CheckWarn, Message: "not checked without an NTP server"}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	offset, err := ClockOffset(ctx, server)
+	if err != nil {
+		return node.Check{Name: "clock", Status: node.CheckWarn, Message: fmt.Sprintf("%v, check the clock is synced with timedatectl", err)}
+	}
+	return clockCheck(server, offset)
+}
+
+// diskCheck checks the free space of the data directory
+func diskCheck(dir string, usage *disk.UsageStat) node.Check {
+	check := node.Check{Name: "disk", Status: node.CheckPass}
+	check.Message = fmt.Sprintf("%.1f GiB free in %s, %.0f%% used", float64(usage.Free)/(1<<30), dir, usage.UsedPercent)
+	switch {
+	case usage.Free < MinFreeDisk:
+		check.Status = node.CheckFail
+		check.Message += ", the nodes can't write their ledger"
+	case usage.UsedPercent >= MaxDiskUsage:
+		check.Status = node.CheckWarn
+		check.Message += ", free some space or move the ledgers before it's full"
+	}
+	return check
+}
+
+// checkDisk checks the free space of the data directory of hlf-easy, or of the
+// home directory when there's no node yet
+func checkDisk() node.Check {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return node.Check{Name: "disk", Status: node.CheckWarn, Message: err.Error()}
+	}
+	dir := filepath.Join(home, "hlf-easy")
+	if _, err := os.Stat(dir); err != nil {
+		dir = home
+	}
+	usage, err := disk.Usage(dir)
+	if err != nil {
+		return node.Check{Name: "disk", Status: node.CheckWarn, Message: fmt.Sprintf("failed to read the usage of %s: %v", dir, err)}
+	}
+	return diskCheck(dir, usage)
+}
+
+// certificatesCheck checks the certificates of the inventory of the host are
+// valid: none is expired, expiring or not valid yet
+func certificatesCheck(r *report.Report, now time.Time) node.Check {
+	check := node.Check{Name: "certificates", Status: node.CheckPass}
+	expired, expiring, notYetValid := []string{}, []string{}, []string{}
+	for _, crt := range r.Certificates {
+		name := crt.Owner + " " + crt.Usage
+		switch {
+		case crt.Status == report.StatusExpired:
+			expired = append(expired, name)
+		case now.Before(crt.NotBefore):
+			notYetValid = append(notYetValid, name)
+		case crt.Status == report.StatusExpiring:
+			expiring = append(expiring, name)
+		}
+	}
+	messages := []string{}
+	if len(expired) > 0 {
+		check.Status = node.CheckFail
+		messages = append(messages, "expired: "+strings.Join(expired, ", "))
+	}
+	if len(notYetValid) > 0 {
+		check.Status = node.CheckFail
+		messages = append(messages, "not valid yet, check the clock: "+strings.Join(notYetValid, ", "))
+	}
+	if len(expiring) > 0 {
+		if check.Status == node.CheckPass {
+			check.Status = node.CheckWarn
+		}
+		messages = append(messages, "expiring soon: "+strings.Join(expiring, ", "))
+	}
+	if len(messages) == 0 {
+		check.Message = fmt.Sprintf("the %d certificates of the host are valid", len(r.Certificates))
+		return check
+	}
+	check.Message = strings.Join(messages, "; ")
+	return check
+}
+
+// checkCertificates checks the certificates of the CAs and nodes of the host
+func checkCertificates(now time.Time, warningDays int) node.Check {
+	r, err := report.Generate(now, warningDays)
+	if err != nil {
+		return node.Check{Name: "certificates", Status: node.CheckFail, Message: fmt.Sprintf("failed to read the certificates of the host: %v", err)}
+	}
+	return certificatesCheck(r, now)
+}
@@ -0,0 +1,63 @@
+package doctor
+
+import (
+	"github.com/shirou/gopsutil/disk"
+	"hlf-easy/node"
+	"hlf-easy/report"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClockCheck(t *testing.T) {
+	for offset, status := range map[time.Duration]string{
+		200 * time.Millisecond: node.CheckPass,
+		-2 * time.Minute:       node.CheckWarn,
+		20 * time.Minute:       node.CheckFail,
+	} {
+		check := clockCheck("pool.ntp.org", offset)
+		if check.Status != status {
+			t.Errorf("expected an offset of %s to %s, got %s: %s", offset, status, check.Status, check.Message)
+		}
+	}
+	if check := clockCheck("pool.ntp.org", -2*time.Minute); !strings.Contains(check.Message, "2m0s ahead of pool.ntp.org") {
+		t.Errorf("unexpected message %q", check.Message)
+	}
+}
+
+func TestOpenFilesCheck(t *testing.T) {
+	if check := openFilesCheck(1024); check.Status != node.CheckWarn {
+		t.Errorf("expected a low limit to warn, got %+v", check)
+	}
+	if check := openFilesCheck(1048576); check.Status != node.CheckPass {
+		t.Errorf("expected a high limit to pass, got %+v", check)
+	}
+}
+
+func TestDiskCheck(t *testing.T) {
+	if check := diskCheck("/data", &disk.UsageStat{Free: 100 << 20, UsedPercent: 99}); check.Status != node.CheckFail {
+		t.Errorf("expected a full disk to fail, got %+v", check)
+	}
+	if check := diskCheck("/data", &disk.UsageStat{Free: 10 << 30, UsedPercent: 95}); check.Status != node.CheckWarn {
+		t.Errorf("expected a disk filling up to warn, got %+v", check)
+	}
+	if check := diskCheck("/data", &disk.UsageStat{Free: 100 << 30, UsedPercent: 20}); check.Status != node.CheckPass {
+		t.Errorf("expected a free disk to pass, got %+v", check)
+	}
+}
+
+func TestCertificatesCheck(t *testing.T) {
+	now := time.Now()
+	r := &report.Report{Certificates: []report.Certificate{
+		{Owner: "peer/peer0", Usage: "tls", NotBefore: now.Add(-time.Hour), Status: report.StatusExpiring},
+	}}
+	if check := certificatesCheck(r, now); check.Status != node.CheckWarn || !strings.Contains(check.Message, "peer/peer0 tls") {
+		t.Errorf("expected the expiring certificate to warn, got %+v", check)
+	}
+	r.Certificates = append(r.Certificates,
+		report.Certificate{Owner: "peer/peer1", Usage: "sign", NotBefore: now.Add(time.Hour), Status: report.StatusValid},
+	)
+	if check := certificatesCheck(r, now); check.Status != node.CheckFail || !strings.Contains(check.Message, "not valid yet") {
+		t.Errorf("expected the certificate not valid yet to fail, got %+v", check)
+	}
+}
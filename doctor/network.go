@@ -0,0 +1,206 @@
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/pkg/errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultTimeout is how long a link has to answer the dial and the TLS
+// handshake
+const DefaultTimeout = 5 * time.Second
+
+// Statuses of a link, only StatusOK is healthy
+const (
+	StatusOK = "ok"
+	// StatusUnresolved is a host the DNS doesn't resolve
+	StatusUnresolved = "unresolved"
+	// StatusBlocked is a dial without an answer, a firewall dropping the
+	// packets on the way
+	StatusBlocked = "blocked"
+	// StatusRefused is a dial refused, nothing listens on the port or a
+	// firewall rejects it
+	StatusRefused = "refused"
+	// StatusUnreachable is a host without a route from this one
+	StatusUnreachable = "unreachable"
+	// StatusTLSFailed is an endpoint reached whose TLS certificate isn't the
+	// expected one
+	StatusTLSFailed = "tls-failed"
+)
+
+// Link is a connection a node of the host opens to an endpoint
+type Link struct {
+	// From is the node opening the connection
+	From string `json:"from"`
+	// To tells what the endpoint is, e.g. orderer OrdererMSP
+	To string `json:"to"`
+	// Channels are the channels the link is configured in, empty for the
+	// links of the node itself like its gossip bootstrap
+	Channels []string `json:"channels,omitempty"`
+	Address  string   `json:"address"`
+	// TLSCACerts are the TLS roots in PEM the certificate of the endpoint
+	// must chain to
+	TLSCACerts []string `json:"-"`
+}
+
+// Result is the check of a link
+type Result struct {
+	Link
+	Status string `json:"status"`
+	// RTT is the time to dial the endpoint and complete the TLS handshake
+	RTT   time.Duration `json:"rtt,omitempty"`
+	Error string        `json:"error,omitempty"`
+	Hint  string        `json:"hint,omitempty"`
+}
+
+// Merge merges the links of a node to the same address, the ones of the
+// channels of a peer sharing their orderers, keeping the order they're found
+func Merge(links []Link) []Link {
+	merged := []Link{}
+	index := map[string]int{}
+	for _, link := range links {
+		key := link.From + "/" + link.Address
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			link.Channels = append([]string(nil), link.Channels...)
+			merged = append(merged, link)
+			continue
+		}
+		for _, ch := range link.Channels {
+			if !contains(merged[i].Channels, ch) {
+				merged[i].Channels = append(merged[i].Channels, ch)
+			}
+		}
+		sort.Strings(merged[i].Channels)
+		for _, crt := range link.TLSCACerts {
+			if !contains(merged[i].TLSCACerts, crt) {
+				merged[i].TLSCACerts = append(merged[i].TLSCACerts, crt)
+			}
+		}
+	}
+	return merged
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// dialStatus classifies the error of a dial with the hint to fix it
+func dialStatus(err error) (string, string) {
+	dnsErr := &net.DNSError{}
+	switch {
+	case errors.As(err, &dnsErr):
+		return StatusUnresolved, "the host isn't resolved from this host, check the DNS or /etc/hosts"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return StatusRefused, "nothing listens on the port or a firewall rejects it, check the node is running and the port is open"
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return StatusUnreachable, "there's no route to the host, check the network and the address"
+	}
+	return StatusBlocked, "the dial isn't answered, a firewall likely drops the packets to the port"
+}
+
+// tlsHint tells why the TLS handshake with an endpoint failed
+func tlsHint(err error) string {
+	unknownAuthority := x509.UnknownAuthorityError{}
+	hostname := x509.HostnameError{}
+	invalid := x509.CertificateInvalidError{}
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return "the certificate isn't issued by the TLS CA of the channel config, the endpoint is another node or its TLS CA changed"
+	case errors.As(err, &hostname):
+		return "the certificate isn't valid for the host of the address, reissue it with the host in its SANs"
+	case errors.As(err, &invalid):
+		return "the certificate is expired or not valid yet, renew it"
+	}
+	return "the endpoint doesn't complete a TLS handshake, a proxy or another service may hold the port"
+}
+
+// CheckLink dials the endpoint of the link and completes a TLS handshake
+// verified with its TLS roots, the status tells where the link breaks
+func CheckLink(ctx context.Context, link Link, timeout time.Duration) Result {
+	r := Result{Link: link}
+	host, _, err := net.SplitHostPort(link.Address)
+	if err != nil {
+		r.Status, r.Error = StatusUnresolved, err.Error()
+		r.Hint = "the address isn't a host:port"
+		return r
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", link.Address)
+	if err != nil {
+		r.Status, r.Hint = dialStatus(err)
+		r.Error = err.Error()
+		return r
+	}
+	defer conn.Close()
+	rootCAs := x509.NewCertPool()
+	for _, pem := range link.TLSCACerts {
+		rootCAs.AppendCertsFromPEM([]byte(pem))
+	}
+	tlsConn := tls.Client(conn, &tls.Config{
+		RootCAs:    rootCAs,
+		ServerName: host,
+		NextProtos: []string{"h2"},
+		MinVersion: tls.VersionTLS12,
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		r.Status, r.Error, r.Hint = StatusTLSFailed, err.Error(), tlsHint(err)
+		return r
+	}
+	r.Status = StatusOK
+	r.RTT = time.Since(start)
+	return r
+}
+
+// CheckLinks checks the links concurrently, the results are in the order of
+// the links
+func CheckLinks(ctx context.Context, links []Link, timeout time.Duration) []Result {
+	results := make([]Result, len(links))
+	wg := sync.WaitGroup{}
+	for i, link := range links {
+		wg.Add(1)
+		go func(i int, link Link) {
+			defer wg.Done()
+			results[i] = CheckLink(ctx, link, timeout)
+		}(i, link)
+	}
+	wg.Wait()
+	return results
+}
+
+// Broken returns the results of the links that aren't ok
+func Broken(results []Result) []Result {
+	broken := []Result{}
+	for _, r := range results {
+		if r.Status != StatusOK {
+			broken = append(broken, r)
+		}
+	}
+	return broken
+}
+
+// Describe returns the links of the results, e.g. peer0 -> orderer
+// OrdererMSP (orderer0.example.com:7050)
+func Describe(results []Result) string {
+	descriptions := []string{}
+	for _, r := range results {
+		descriptions = append(descriptions, r.From+" -> "+r.To+" ("+r.Address+")")
+	}
+	return strings.Join(descriptions, ", ")
+}
@@ -0,0 +1,77 @@
+package doctor
+
+import (
+	"context"
+	"encoding/pem"
+	"hlf-easy/internal/testca"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func unusedAddress(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+	return address
+}
+
+func TestCheckLinks(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+	// the TLS CA of another org doesn't verify the certificate of the server
+	other, _ := testca.NewCA(t, "tlsca.org2.example.com")
+	otherCACert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: other.Raw}))
+	address := strings.TrimPrefix(srv.URL, "https://")
+
+	results := CheckLinks(context.Background(), []Link{
+		{From: "peer0", To: "orderer", Address: address, TLSCACerts: []string{caCert}},
+		{From: "peer0", To: "anchor peer Org2MSP", Address: address, TLSCACerts: []string{otherCACert}},
+		{From: "peer0", To: "gossip bootstrap", Address: unusedAddress(t), TLSCACerts: []string{caCert}},
+		{From: "peer0", To: "orderer", Address: "orderer0.invalid:7050", TLSCACerts: []string{caCert}},
+	}, time.Second)
+	expected := []string{StatusOK, StatusTLSFailed, StatusRefused, StatusUnresolved}
+	for i, r := range results {
+		if r.Status != expected[i] {
+			t.Errorf("expected link %d to be %s, got %s: %s", i, expected[i], r.Status, r.Error)
+		}
+	}
+	if results[0].RTT <= 0 || results[0].Error != "" {
+		t.Errorf("expected the healthy link to have an RTT, got %+v", results[0])
+	}
+	if !strings.Contains(results[1].Hint, "TLS CA") {
+		t.Errorf("expected the hint to tell the certificate isn't issued by the TLS CA, got %q", results[1].Hint)
+	}
+	if broken := Broken(results); len(broken) != 3 || !strings.Contains(Describe(broken), "peer0 -> gossip bootstrap") {
+		t.Errorf("expected the 3 broken links, got %v", broken)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	links := Merge([]Link{
+		{From: "peer0", To: "orderer", Channels: []string{"ch2"}, Address: "orderer0:7050", TLSCACerts: []string{"ca1"}},
+		{From: "peer0", To: "gossip bootstrap", Address: "peer1:7051"},
+		{From: "peer0", To: "orderer", Channels: []string{"ch1"}, Address: "orderer0:7050", TLSCACerts: []string{"ca1", "ca2"}},
+		{From: "peer1", To: "orderer", Channels: []string{"ch1"}, Address: "orderer0:7050"},
+	})
+	if len(links) != 3 {
+		t.Fatalf("expected 3 links, got %+v", links)
+	}
+	if !reflect.DeepEqual(links[0].Channels, []string{"ch1", "ch2"}) || !reflect.DeepEqual(links[0].TLSCACerts, []string{"ca1", "ca2"}) {
+		t.Errorf("expected the links of the channels to be merged, got %+v", links[0])
+	}
+	if links[1].To != "gossip bootstrap" || links[2].From != "peer1" {
+		t.Errorf("expected the order of the links to be kept, got %+v", links)
+	}
+}
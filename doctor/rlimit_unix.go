@@ -0,0 +1,15 @@
+//go:build !windows
+
+package doctor
+
+import "syscall"
+
+// openFilesLimit returns the hard limit of the open files of the processes
+// started by hlf-easy, the Go binaries of Fabric raise their soft limit to it
+func openFilesLimit() (uint64, error) {
+	rlimit := syscall.Rlimit{}
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0, err
+	}
+	return uint64(rlimit.Max), nil
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package doctor
+
+import "math"
+
+// openFilesLimit returns no limit, Windows doesn't limit the open files of a
+// process
+func openFilesLimit() (uint64, error) {
+	return math.MaxUint64, nil
+}
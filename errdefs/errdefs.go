@@ -0,0 +1,128 @@
+package errdefs
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"net/http"
+)
+
+// Kinds of the failures of hlf-easy, an error of a kind matches it with
+// errors.Is so API consumers and the CLI map it to a status and an exit code
+var (
+	ErrNodeNotFound       = errors.New("node not found")
+	ErrNodeAlreadyRunning = errors.New("node already running")
+	ErrNodeNotRunning     = errors.New("node not running")
+	ErrCANotInitialized   = errors.New("ca not initialized")
+	ErrCertExpired        = errors.New("certificate expired")
+	ErrPortInUse          = errors.New("port in use")
+	// ErrTimeout is the error of the context of an operation that ran out of
+	// time, it's context.DeadlineExceeded so the operations don't wrap it
+	ErrTimeout = context.DeadlineExceeded
+)
+
+// kind is a kind of failure with the code carried by the management API, the
+// exit code of the CLI, the HTTP status and the gRPC code
+type kind struct {
+	err      error
+	code     string
+	exitCode int
+	status   int
+	grpcCode codes.Code
+}
+
+var kinds = []kind{
+	{ErrNodeNotFound, "NodeNotFound", 3, http.StatusNotFound, codes.NotFound},
+	{ErrNodeAlreadyRunning, "NodeAlreadyRunning", 4, http.StatusConflict, codes.AlreadyExists},
+	{ErrNodeNotRunning, "NodeNotRunning", 5, http.StatusConflict, codes.FailedPrecondition},
+	{ErrCANotInitialized, "CANotInitialized", 6, http.StatusPreconditionFailed, codes.FailedPrecondition},
+	{ErrCertExpired, "CertExpired", 7, http.StatusPreconditionFailed, codes.FailedPrecondition},
+	{ErrTimeout, "Timeout", 8, http.StatusGatewayTimeout, codes.DeadlineExceeded},
+	{ErrPortInUse, "PortInUse", 9, http.StatusConflict, codes.FailedPrecondition},
+}
+
+// ExitCodeFailure is the exit code of the failures without a kind
+const ExitCodeFailure = 1
+
+// kindError is an error of a kind with its own message
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// Errorf returns an error of a kind with a formatted message
+func Errorf(kind error, format string, args ...interface{}) error {
+	return errors.WithStack(&kindError{kind: kind, msg: fmt.Sprintf(format, args...)})
+}
+
+func find(err error) (kind, bool) {
+	for _, k := range kinds {
+		if errors.Is(err, k.err) {
+			return k, true
+		}
+	}
+	return kind{}, false
+}
+
+// ExitCode returns the exit code of the CLI for an error
+func ExitCode(err error) int {
+	if k, ok := find(err); ok {
+		return k.exitCode
+	}
+	return ExitCodeFailure
+}
+
+// HTTPStatus returns the status of the management API for an error
+func HTTPStatus(err error) int {
+	if k, ok := find(err); ok {
+		return k.status
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode returns the code of the gRPC API for an error
+func GRPCCode(err error) codes.Code {
+	if k, ok := find(err); ok {
+		return k.grpcCode
+	}
+	return codes.Unknown
+}
+
+// Code returns the code of the kind of an error, empty when it has none
+func Code(err error) string {
+	if k, ok := find(err); ok {
+		return k.code
+	}
+	return ""
+}
+
+// FromCode returns an error of the kind of a code returned by the management
+// API, the message is returned as is for an unknown code
+func FromCode(code string, msg string) error {
+	for _, k := range kinds {
+		if k.code == code {
+			return Errorf(k.err, "%s", msg)
+		}
+	}
+	return errors.New(msg)
+}
+
+// FromExitCode returns an error of the kind of an exit code of the CLI, the
+// message is returned as is for the failures without a kind
+func FromExitCode(exitCode int, msg string) error {
+	for _, k := range kinds {
+		if k.exitCode == exitCode {
+			return Errorf(k.err, "%s", msg)
+		}
+	}
+	return errors.New(msg)
+}
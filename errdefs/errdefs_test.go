@@ -0,0 +1,63 @@
+package errdefs
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestKinds(t *testing.T) {
+	err := Errorf(ErrNodeNotFound, "peer %s does not exist", "peer0")
+	if err.Error() != "peer peer0 does not exist" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	wrapped := errors.Wrap(err, "failed to join the channel")
+	if !errors.Is(wrapped, ErrNodeNotFound) || errors.Is(wrapped, ErrNodeNotRunning) {
+		t.Errorf("expected a node not found error, got %v", wrapped)
+	}
+	if ExitCode(wrapped) != 3 || HTTPStatus(wrapped) != http.StatusNotFound || Code(wrapped) != "NodeNotFound" || GRPCCode(wrapped) != codes.NotFound {
+		t.Errorf("unexpected mapping %d %d %s %s", ExitCode(wrapped), HTTPStatus(wrapped), Code(wrapped), GRPCCode(wrapped))
+	}
+	other := errors.New("failed")
+	if ExitCode(other) != ExitCodeFailure || HTTPStatus(other) != http.StatusInternalServerError || Code(other) != "" || GRPCCode(other) != codes.Unknown {
+		t.Errorf("unexpected mapping %d %d %s %s", ExitCode(other), HTTPStatus(other), Code(other), GRPCCode(other))
+	}
+}
+
+func TestFromCode(t *testing.T) {
+	err := FromCode(Code(Errorf(ErrNodeAlreadyRunning, "started")), "peer node is already started")
+	if !errors.Is(err, ErrNodeAlreadyRunning) || err.Error() != "peer node is already started" {
+		t.Errorf("expected a node already running error, got %v", err)
+	}
+	err = FromCode("", "failed")
+	if Code(err) != "" || err.Error() != "failed" {
+		t.Errorf("expected an error without a kind, got %v", err)
+	}
+}
+
+func TestFromExitCode(t *testing.T) {
+	err := FromExitCode(ExitCode(Errorf(ErrCANotInitialized, "not initialized")), "ca ca0 is not initialized")
+	if !errors.Is(err, ErrCANotInitialized) || err.Error() != "ca ca0 is not initialized" {
+		t.Errorf("expected a ca not initialized error, got %v", err)
+	}
+	err = FromExitCode(ExitCodeFailure, "failed")
+	if ExitCode(err) != ExitCodeFailure || err.Error() != "failed" {
+		t.Errorf("expected an error without a kind, got %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	err := errors.Wrap(ctx.Err(), "orderer0 didn't join channel mychannel")
+	if ExitCode(err) != 8 || HTTPStatus(err) != http.StatusGatewayTimeout || GRPCCode(err) != codes.DeadlineExceeded {
+		t.Errorf("unexpected mapping %d %d %s", ExitCode(err), HTTPStatus(err), GRPCCode(err))
+	}
+	if Code(context.Canceled) != "" {
+		t.Error("expected a canceled operation not to time out")
+	}
+}
@@ -0,0 +1,153 @@
+package escrow
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"sort"
+)
+
+const sealedKeyVersion = 2
+
+// DefaultSealer is the sealer used when none is specified, it derives the
+// encryption key from the passphrase shares of the operators
+const DefaultSealer = "passphrase"
+
+// Share is the secret an operator provides to seal or unseal a key
+type Share struct {
+	Operator   string
+	Passphrase []byte
+}
+
+// SealedKey is a private key sealed with the shares of the operators that
+// took part in its generation, all of them are needed to unseal it
+type SealedKey struct {
+	Version   int      `json:"version"`
+	Sealer    string   `json:"sealer"`
+	Operators []string `json:"operators"`
+	Salt      []byte   `json:"salt,omitempty"`
+	Nonce     []byte   `json:"nonce,omitempty"`
+	// KMSKey is the key of the KMS that wrapped the ciphertext, if any
+	KMSKey     string `json:"kmsKey,omitempty"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Sealer encrypts and decrypts keys with the operator shares, the shares are
+// always sorted by operator name before being passed to the sealer
+type Sealer interface {
+	Seal(key []byte, shares []Share) (*SealedKey, error)
+	Unseal(sealed *SealedKey, shares []Share) ([]byte, error)
+}
+
+var sealers = map[string]Sealer{
+	DefaultSealer: passphraseSealer{},
+}
+
+// Register makes a sealer available by name, e.g. one backed by another KMS
+func Register(name string, sealer Sealer) {
+	sealers[name] = sealer
+}
+
+func getSealer(name string) (Sealer, error) {
+	sealer, ok := sealers[name]
+	if !ok {
+		return nil, errors.Errorf("unknown sealer %q", name)
+	}
+	return sealer, nil
+}
+
+// Sealers returns the names of the available sealers
+func Sealers() []string {
+	var names []string
+	for name := range sealers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortShares returns a copy of the shares sorted by operator name, so the
+// order in which the operators provide them doesn't matter
+func sortShares(shares []Share) ([]Share, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("at least one share is required")
+	}
+	sorted := make([]Share, len(shares))
+	copy(sorted, shares)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Operator < sorted[j].Operator
+	})
+	for i, share := range sorted {
+		if share.Operator == "" {
+			return nil, errors.New("every share must have an operator")
+		}
+		if len(share.Passphrase) == 0 {
+			return nil, errors.Errorf("operator %s provided an empty share", share.Operator)
+		}
+		if i > 0 && sorted[i-1].Operator == share.Operator {
+			return nil, errors.Errorf("operator %s provided more than one share", share.Operator)
+		}
+	}
+	return sorted, nil
+}
+
+// Seal seals the key with the shares using the given sealer
+func Seal(sealerName string, key []byte, shares []Share) ([]byte, error) {
+	sealer, err := getSealer(sealerName)
+	if err != nil {
+		return nil, err
+	}
+	sorted, err := sortShares(shares)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealer.Seal(key, sorted)
+	if err != nil {
+		return nil, err
+	}
+	sealed.Version = sealedKeyVersion
+	sealed.Sealer = sealerName
+	sealed.Operators = nil
+	for _, share := range sorted {
+		sealed.Operators = append(sealed.Operators, share.Operator)
+	}
+	return json.MarshalIndent(sealed, "", "  ")
+}
+
+// ParseSealedKey parses a key sealed with Seal
+func ParseSealedKey(sealedBytes []byte) (*SealedKey, error) {
+	sealed := &SealedKey{}
+	err := json.Unmarshal(sealedBytes, sealed)
+	if err != nil {
+		return nil, err
+	}
+	if sealed.Version != sealedKeyVersion {
+		return nil, errors.Errorf("unsupported sealed key version %d", sealed.Version)
+	}
+	return sealed, nil
+}
+
+// Unseal decrypts a key sealed with Seal, a share of every operator that
+// sealed the key is required
+func Unseal(sealedBytes []byte, shares []Share) ([]byte, error) {
+	sealed, err := ParseSealedKey(sealedBytes)
+	if err != nil {
+		return nil, err
+	}
+	sealer, err := getSealer(sealed.Sealer)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Operators) != len(shares) {
+		return nil, errors.Errorf("key was sealed with %d shares, got %d", len(sealed.Operators), len(shares))
+	}
+	sorted, err := sortShares(shares)
+	if err != nil {
+		return nil, err
+	}
+	for i, share := range sorted {
+		if sealed.Operators[i] != share.Operator {
+			return nil, errors.Errorf("operator %s didn't take part in sealing the key", share.Operator)
+		}
+	}
+	return sealer.Unseal(sealed, sorted)
+}
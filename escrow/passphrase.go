@@ -0,0 +1,75 @@
+package escrow
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+	"io"
+)
+
+// passphraseSealer encrypts keys with AES-GCM using a key derived from the
+// passphrases of all the operators
+type passphraseSealer struct{}
+
+// deriveKey derives the key encryption key from the sorted shares, every
+// passphrase is bound to the name of its operator
+func (passphraseSealer) deriveKey(shares []Share, salt []byte) ([]byte, error) {
+	h := sha256.New()
+	for _, share := range shares {
+		operatorHash := sha256.Sum256([]byte(share.Operator))
+		passphraseHash := sha256.Sum256(share.Passphrase)
+		h.Write(operatorHash[:])
+		h.Write(passphraseHash[:])
+	}
+	return scrypt.Key(h.Sum(nil), salt, 1<<15, 8, 1, 32)
+}
+
+func (s passphraseSealer) gcm(shares []Share, salt []byte) (cipher.AEAD, error) {
+	kek, err := s.deriveKey(shares, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s passphraseSealer) Seal(key []byte, shares []Share) (*SealedKey, error) {
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	gcm, err := s.gcm(shares, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return &SealedKey{
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, key, nil),
+	}, nil
+}
+
+func (s passphraseSealer) Unseal(sealed *SealedKey, shares []Share) ([]byte, error) {
+	gcm, err := s.gcm(shares, sealed.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid sealed key nonce")
+	}
+	key, err := gcm.Open(nil, sealed.Nonce, sealed.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("failed to unseal key, the passphrase shares are wrong")
+	}
+	return key, nil
+}
@@ -0,0 +1,144 @@
+package escrow
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// VaultTransitSealer is the name of the sealer backed by the transit secrets
+// engine of HashiCorp Vault
+const VaultTransitSealer = "vault-transit"
+
+// DefaultVaultTransitKey is the transit key used when HLF_EASY_VAULT_TRANSIT_KEY
+// is not set
+const DefaultVaultTransitKey = "hlf-easy"
+
+// vaultTransitSealer seals the keys with the passphrase shares and wraps the
+// result with a key of the Vault transit engine, so unsealing a key needs
+// both the shares of the operators and access to the KMS. The Vault server
+// is configured with VAULT_ADDR and VAULT_TOKEN, and the transit key with
+// HLF_EASY_VAULT_TRANSIT_KEY and HLF_EASY_VAULT_TRANSIT_MOUNT
+type vaultTransitSealer struct {
+	client *http.Client
+}
+
+type vaultConfig struct {
+	address string
+	token   string
+	mount   string
+}
+
+func getVaultConfig() (*vaultConfig, error) {
+	conf := &vaultConfig{
+		address: strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"),
+		token:   os.Getenv("VAULT_TOKEN"),
+		mount:   os.Getenv("HLF_EASY_VAULT_TRANSIT_MOUNT"),
+	}
+	if conf.address == "" || conf.token == "" {
+		return nil, errors.New("VAULT_ADDR and VAULT_TOKEN are required by the vault-transit sealer")
+	}
+	if conf.mount == "" {
+		conf.mount = "transit"
+	}
+	return conf, nil
+}
+
+// call posts a request to the transit engine and decodes the data of its
+// response
+func (s vaultTransitSealer) call(conf *vaultConfig, operation string, key string, body interface{}, data interface{}) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", conf.address, conf.mount, operation, key)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", conf.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to call the transit engine of %s", conf.address)
+	}
+	defer resp.Body.Close()
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("transit %s with key %s returned %s: %s", operation, key, resp.Status, respBytes)
+	}
+	respBody := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(respBytes, &respBody); err != nil {
+		return err
+	}
+	return json.Unmarshal(respBody.Data, data)
+}
+
+func (s vaultTransitSealer) Seal(key []byte, shares []Share) (*SealedKey, error) {
+	conf, err := getVaultConfig()
+	if err != nil {
+		return nil, err
+	}
+	transitKey := os.Getenv("HLF_EASY_VAULT_TRANSIT_KEY")
+	if transitKey == "" {
+		transitKey = DefaultVaultTransitKey
+	}
+	sealed, err := passphraseSealer{}.Seal(key, shares)
+	if err != nil {
+		return nil, err
+	}
+	data := struct {
+		Ciphertext string `json:"ciphertext"`
+	}{}
+	err = s.call(conf, "encrypt", transitKey, map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(sealed.Ciphertext),
+	}, &data)
+	if err != nil {
+		return nil, err
+	}
+	sealed.KMSKey = transitKey
+	sealed.Ciphertext = []byte(data.Ciphertext)
+	return sealed, nil
+}
+
+func (s vaultTransitSealer) Unseal(sealed *SealedKey, shares []Share) ([]byte, error) {
+	conf, err := getVaultConfig()
+	if err != nil {
+		return nil, err
+	}
+	if sealed.KMSKey == "" {
+		return nil, errors.New("the sealed key has no transit key")
+	}
+	data := struct {
+		Plaintext string `json:"plaintext"`
+	}{}
+	err = s.call(conf, "decrypt", sealed.KMSKey, map[string]string{
+		"ciphertext": string(sealed.Ciphertext),
+	}, &data)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(data.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	unwrapped := *sealed
+	unwrapped.Ciphertext = ciphertext
+	return passphraseSealer{}.Unseal(&unwrapped, shares)
+}
+
+func init() {
+	Register(VaultTransitSealer, vaultTransitSealer{client: &http.Client{Timeout: 30 * time.Second}})
+}
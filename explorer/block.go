@@ -0,0 +1,104 @@
+package explorer
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-config/protolator"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/pkg/errors"
+	"io"
+	"time"
+)
+
+// Transaction is the summary of a transaction of a block
+type Transaction struct {
+	TxID      string    `json:"txID"`
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	// Creator is the MSP ID of the identity that signed the transaction
+	Creator   string `json:"creator"`
+	Chaincode string `json:"chaincode,omitempty"`
+	// ValidationCode is set by the peers when they commit the block, it's
+	// empty for the blocks read from an orderer
+	ValidationCode string `json:"validationCode,omitempty"`
+}
+
+// DecodeBlock writes a block as JSON with its nested messages decoded, like
+// configtxlator proto_decode does
+func DecodeBlock(w io.Writer, block *cb.Block) error {
+	return protolator.DeepMarshalJSON(w, block)
+}
+
+// UnmarshalBlock parses a block in protobuf, as fetched by the peer CLI
+func UnmarshalBlock(blockBytes []byte) (*cb.Block, error) {
+	block := &cb.Block{}
+	if err := proto.Unmarshal(blockBytes, block); err != nil {
+		return nil, errors.Wrap(err, "invalid block")
+	}
+	if block.Header == nil || block.Data == nil {
+		return nil, errors.New("invalid block, it has no header or no data")
+	}
+	return block, nil
+}
+
+// Transactions returns the transactions of a block with their validation code
+func Transactions(block *cb.Block) ([]Transaction, error) {
+	var validationCodes []byte
+	if metadata := block.GetMetadata().GetMetadata(); len(metadata) > int(cb.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		validationCodes = metadata[cb.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+	transactions := []Transaction{}
+	for i, data := range block.GetData().GetData() {
+		tx, err := parseTransaction(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid transaction %d of block %d", i, block.GetHeader().GetNumber())
+		}
+		if i < len(validationCodes) {
+			tx.ValidationCode = pb.TxValidationCode(validationCodes[i]).String()
+		}
+		transactions = append(transactions, tx)
+	}
+	return transactions, nil
+}
+
+func parseTransaction(data []byte) (Transaction, error) {
+	tx := Transaction{}
+	env := &cb.Envelope{}
+	if err := proto.Unmarshal(data, env); err != nil {
+		return tx, err
+	}
+	payload := &cb.Payload{}
+	if err := proto.Unmarshal(env.Payload, payload); err != nil {
+		return tx, err
+	}
+	if payload.Header == nil {
+		return tx, errors.New("the payload has no header")
+	}
+	chdr := &cb.ChannelHeader{}
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, chdr); err != nil {
+		return tx, err
+	}
+	tx.TxID = chdr.TxId
+	tx.Type = cb.HeaderType(chdr.Type).String()
+	if chdr.Timestamp != nil {
+		tx.Timestamp = chdr.Timestamp.AsTime()
+	}
+	shdr := &cb.SignatureHeader{}
+	if err := proto.Unmarshal(payload.Header.SignatureHeader, shdr); err != nil {
+		return tx, err
+	}
+	creator := &mb.SerializedIdentity{}
+	if err := proto.Unmarshal(shdr.Creator, creator); err != nil {
+		return tx, err
+	}
+	tx.Creator = creator.Mspid
+	if chdr.Type == int32(cb.HeaderType_ENDORSER_TRANSACTION) {
+		extension := &pb.ChaincodeHeaderExtension{}
+		if err := proto.Unmarshal(chdr.Extension, extension); err != nil {
+			return tx, err
+		}
+		tx.Chaincode = extension.GetChaincodeId().GetName()
+	}
+	return tx, nil
+}
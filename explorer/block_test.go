@@ -0,0 +1,112 @@
+package explorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"testing"
+	"time"
+)
+
+func marshal(t *testing.T, msg proto.Message) []byte {
+	t.Helper()
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func testEnvelope(t *testing.T, txID string, headerType cb.HeaderType, chaincode string, created time.Time) []byte {
+	t.Helper()
+	chdr := &cb.ChannelHeader{
+		Type:      int32(headerType),
+		ChannelId: "mychannel",
+		TxId:      txID,
+		Timestamp: &timestamp.Timestamp{Seconds: created.Unix()},
+	}
+	if chaincode != "" {
+		chdr.Extension = marshal(t, &pb.ChaincodeHeaderExtension{ChaincodeId: &pb.ChaincodeID{Name: chaincode}})
+	}
+	payload := &cb.Payload{Header: &cb.Header{
+		ChannelHeader:   marshal(t, chdr),
+		SignatureHeader: marshal(t, &cb.SignatureHeader{Creator: marshal(t, &mb.SerializedIdentity{Mspid: "Org1MSP"})}),
+	}}
+	return marshal(t, &cb.Envelope{Payload: marshal(t, payload)})
+}
+
+func testBlock(t *testing.T) *cb.Block {
+	t.Helper()
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	block := &cb.Block{
+		Header: &cb.BlockHeader{Number: 5},
+		Data: &cb.BlockData{Data: [][]byte{
+			testEnvelope(t, "tx1", cb.HeaderType_ENDORSER_TRANSACTION, "asset", created),
+			testEnvelope(t, "tx2", cb.HeaderType_ENDORSER_TRANSACTION, "asset", created),
+		}},
+		Metadata: &cb.BlockMetadata{Metadata: [][]byte{{}, {}, {}}},
+	}
+	block.Metadata.Metadata[cb.BlockMetadataIndex_TRANSACTIONS_FILTER] = []byte{
+		byte(pb.TxValidationCode_VALID),
+		byte(pb.TxValidationCode_MVCC_READ_CONFLICT),
+	}
+	return block
+}
+
+func TestTransactions(t *testing.T) {
+	block, err := UnmarshalBlock(marshal(t, testBlock(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions, err := Transactions(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(transactions))
+	}
+	tx := transactions[0]
+	if tx.TxID != "tx1" || tx.Type != "ENDORSER_TRANSACTION" || tx.Creator != "Org1MSP" || tx.Chaincode != "asset" || tx.ValidationCode != "VALID" {
+		t.Errorf("unexpected transaction %+v", tx)
+	}
+	if !tx.Timestamp.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected timestamp %s", tx.Timestamp)
+	}
+	if transactions[1].ValidationCode != "MVCC_READ_CONFLICT" {
+		t.Errorf("expected the validation code of the peer, got %s", transactions[1].ValidationCode)
+	}
+
+	if _, err := UnmarshalBlock([]byte("not a block")); err == nil {
+		t.Error("expected an error for an invalid block")
+	}
+}
+
+func TestDecodeBlock(t *testing.T) {
+	var buf bytes.Buffer
+	if err := DecodeBlock(&buf, testBlock(t)); err != nil {
+		t.Fatal(err)
+	}
+	decoded := struct {
+		Data struct {
+			Data []struct {
+				Payload struct {
+					Header struct {
+						ChannelHeader struct {
+							TxID string `json:"tx_id"`
+						} `json:"channel_header"`
+					} `json:"header"`
+				} `json:"payload"`
+			} `json:"data"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Data.Data) != 2 || decoded.Data.Data[1].Payload.Header.ChannelHeader.TxID != "tx2" {
+		t.Errorf("expected the nested channel headers to be decoded, got %s", buf.String())
+	}
+}
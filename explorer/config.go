@@ -0,0 +1,253 @@
+package explorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-config/protolator"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+)
+
+// Kinds of the changes between two configs
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// ConfigChange is a value of the config that differs between two revisions,
+// its path is the one of the decoded config, e.g.
+// channel_group/groups/Application/groups/Org1MSP/values/AnchorPeers
+type ConfigChange struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// ConfigFromBlock returns the config of a config block
+func ConfigFromBlock(block *cb.Block) (*cb.Config, error) {
+	if len(block.GetData().GetData()) != 1 {
+		return nil, errors.Errorf("block %d isn't a config block, it has %d transactions", block.GetHeader().GetNumber(), len(block.GetData().GetData()))
+	}
+	env := &cb.Envelope{}
+	if err := proto.Unmarshal(block.Data.Data[0], env); err != nil {
+		return nil, err
+	}
+	payload := &cb.Payload{}
+	if err := proto.Unmarshal(env.Payload, payload); err != nil {
+		return nil, err
+	}
+	chdr := &cb.ChannelHeader{}
+	if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), chdr); err != nil {
+		return nil, err
+	}
+	if chdr.Type != int32(cb.HeaderType_CONFIG) {
+		return nil, errors.Errorf("block %d isn't a config block, its transaction is a %s", block.GetHeader().GetNumber(), cb.HeaderType(chdr.Type))
+	}
+	configEnv := &cb.ConfigEnvelope{}
+	if err := proto.Unmarshal(payload.Data, configEnv); err != nil {
+		return nil, err
+	}
+	if configEnv.Config == nil {
+		return nil, errors.Errorf("config block %d has no config", block.GetHeader().GetNumber())
+	}
+	return configEnv.Config, nil
+}
+
+// ApplicationMSPIDs returns the MSP IDs of the application orgs of a channel
+func ApplicationMSPIDs(config *cb.Config) ([]string, error) {
+	application, ok := config.GetChannelGroup().GetGroups()["Application"]
+	if !ok {
+		return nil, errors.New("the config has no application group")
+	}
+	mspIDs := []string{}
+	for name, org := range application.Groups {
+		value, ok := org.Values["MSP"]
+		if !ok {
+			return nil, errors.Errorf("org %s of the channel has no MSP", name)
+		}
+		mspConfig := &mb.MSPConfig{}
+		if err := proto.Unmarshal(value.Value, mspConfig); err != nil {
+			return nil, err
+		}
+		fabricMSPConfig := &mb.FabricMSPConfig{}
+		if err := proto.Unmarshal(mspConfig.Config, fabricMSPConfig); err != nil {
+			return nil, err
+		}
+		mspIDs = append(mspIDs, fabricMSPConfig.Name)
+	}
+	sort.Strings(mspIDs)
+	return mspIDs, nil
+}
+
+// LastConfigIndex returns the number of the last config block when a block
+// was committed, the block itself for a config block
+func LastConfigIndex(block *cb.Block) (uint64, error) {
+	metadata := block.GetMetadata().GetMetadata()
+	if len(metadata) > int(cb.BlockMetadataIndex_SIGNATURES) && len(metadata[cb.BlockMetadataIndex_SIGNATURES]) > 0 {
+		signatures := &cb.Metadata{}
+		if err := proto.Unmarshal(metadata[cb.BlockMetadataIndex_SIGNATURES], signatures); err != nil {
+			return 0, err
+		}
+		ordererMetadata := &cb.OrdererBlockMetadata{}
+		if err := proto.Unmarshal(signatures.Value, ordererMetadata); err != nil {
+			return 0, err
+		}
+		if ordererMetadata.LastConfig != nil {
+			return ordererMetadata.LastConfig.Index, nil
+		}
+	}
+	// the orderers before Fabric 2.0 wrote it in its own metadata
+	if len(metadata) > int(cb.BlockMetadataIndex_LAST_CONFIG) && len(metadata[cb.BlockMetadataIndex_LAST_CONFIG]) > 0 {
+		lastConfigMetadata := &cb.Metadata{}
+		if err := proto.Unmarshal(metadata[cb.BlockMetadataIndex_LAST_CONFIG], lastConfigMetadata); err != nil {
+			return 0, err
+		}
+		lastConfig := &cb.LastConfig{}
+		if err := proto.Unmarshal(lastConfigMetadata.Value, lastConfig); err != nil {
+			return 0, err
+		}
+		return lastConfig.Index, nil
+	}
+	return 0, errors.Errorf("block %d has no last config index", block.GetHeader().GetNumber())
+}
+
+// GetConfigBlock returns the last config block of the channel from the peer
+func GetConfigBlock(opts Options) (*cb.Block, error) {
+	var block *cb.Block
+	err := withLedger(opts, func(client *ledger.Client) error {
+		var err error
+		block, err = client.QueryConfigBlock(ledger.WithTargetEndpoints(opts.PeerID))
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the config block of channel %s", opts.Channel)
+		}
+		return nil
+	})
+	return block, err
+}
+
+// GetPreviousConfigBlock returns the config block before a config block of
+// the channel, the config revision it replaced
+func GetPreviousConfigBlock(opts Options, configBlock *cb.Block) (*cb.Block, error) {
+	number := configBlock.GetHeader().GetNumber()
+	if number == 0 {
+		return nil, errors.Errorf("channel %s has no config before the genesis block", opts.Channel)
+	}
+	previous, err := GetBlock(opts, int64(number)-1)
+	if err != nil {
+		return nil, err
+	}
+	lastConfig, err := LastConfigIndex(previous)
+	if err != nil {
+		return nil, err
+	}
+	return GetBlock(opts, int64(lastConfig))
+}
+
+// DecodeConfig writes a config as JSON, like configtxlator proto_decode
+// --type=common.Config does
+func DecodeConfig(w io.Writer, config *cb.Config) error {
+	return protolator.DeepMarshalJSON(w, config)
+}
+
+// EncodeConfig reads a config from JSON, like configtxlator proto_encode
+// --type=common.Config does, e.g. a decoded config that was modified
+func EncodeConfig(r io.Reader) (*cb.Config, error) {
+	config := &cb.Config{}
+	if err := protolator.DeepUnmarshalJSON(r, config); err != nil {
+		return nil, errors.Wrap(err, "invalid config")
+	}
+	return config, nil
+}
+
+// DiffConfigs returns the values that differ between two configs
+func DiffConfigs(from *cb.Config, to *cb.Config) ([]ConfigChange, error) {
+	var fromJSON, toJSON bytes.Buffer
+	if err := DecodeConfig(&fromJSON, from); err != nil {
+		return nil, err
+	}
+	if err := DecodeConfig(&toJSON, to); err != nil {
+		return nil, err
+	}
+	return diffJSON(fromJSON.Bytes(), toJSON.Bytes())
+}
+
+// diffJSON compares the leaves of two JSON documents by their path
+func diffJSON(from []byte, to []byte) ([]ConfigChange, error) {
+	var fromValue, toValue interface{}
+	if err := json.Unmarshal(from, &fromValue); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(to, &toValue); err != nil {
+		return nil, err
+	}
+	fromLeaves := map[string]string{}
+	toLeaves := map[string]string{}
+	if err := flattenJSON("", fromValue, fromLeaves); err != nil {
+		return nil, err
+	}
+	if err := flattenJSON("", toValue, toLeaves); err != nil {
+		return nil, err
+	}
+	changes := []ConfigChange{}
+	for path, fromLeaf := range fromLeaves {
+		toLeaf, ok := toLeaves[path]
+		if !ok {
+			changes = append(changes, ConfigChange{Path: path, Kind: ChangeRemoved, From: fromLeaf})
+		} else if toLeaf != fromLeaf {
+			changes = append(changes, ConfigChange{Path: path, Kind: ChangeChanged, From: fromLeaf, To: toLeaf})
+		}
+	}
+	for path, toLeaf := range toLeaves {
+		if _, ok := fromLeaves[path]; !ok {
+			changes = append(changes, ConfigChange{Path: path, Kind: ChangeAdded, To: toLeaf})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// flattenJSON collects the leaves of a JSON value by their path, the keys of
+// the objects are separated by / and the items of the arrays are indexed
+func flattenJSON(path string, value interface{}, leaves map[string]string) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			leaves[path] = "{}"
+		}
+		for key, item := range v {
+			itemPath := key
+			if path != "" {
+				itemPath = path + "/" + key
+			}
+			if err := flattenJSON(itemPath, item, leaves); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			leaves[path] = "[]"
+		}
+		for i, item := range v {
+			if err := flattenJSON(fmt.Sprintf("%s[%d]", path, i), item, leaves); err != nil {
+				return err
+			}
+		}
+	default:
+		leaf, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		leaves[path] = string(leaf)
+	}
+	return nil
+}
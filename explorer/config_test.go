@@ -0,0 +1,110 @@
+package explorer
+
+import (
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	mb "github.com/hyperledger/fabric-protos-go/msp"
+	"reflect"
+	"testing"
+)
+
+func testConfig(t *testing.T, sequence uint64, capability string) *cb.Config {
+	t.Helper()
+	return &cb.Config{
+		Sequence: sequence,
+		ChannelGroup: &cb.ConfigGroup{
+			Values: map[string]*cb.ConfigValue{
+				"Capabilities": {
+					ModPolicy: "Admins",
+					Value:     marshal(t, &cb.Capabilities{Capabilities: map[string]*cb.Capability{capability: {}}}),
+				},
+			},
+		},
+	}
+}
+
+func testConfigBlock(t *testing.T, number uint64, config *cb.Config) *cb.Block {
+	t.Helper()
+	payload := &cb.Payload{
+		Header: &cb.Header{ChannelHeader: marshal(t, &cb.ChannelHeader{Type: int32(cb.HeaderType_CONFIG), ChannelId: "mychannel"})},
+		Data:   marshal(t, &cb.ConfigEnvelope{Config: config}),
+	}
+	signatures := &cb.Metadata{Value: marshal(t, &cb.OrdererBlockMetadata{LastConfig: &cb.LastConfig{Index: number}})}
+	return &cb.Block{
+		Header:   &cb.BlockHeader{Number: number},
+		Data:     &cb.BlockData{Data: [][]byte{marshal(t, &cb.Envelope{Payload: marshal(t, payload)})}},
+		Metadata: &cb.BlockMetadata{Metadata: [][]byte{marshal(t, signatures), {}, {}}},
+	}
+}
+
+func TestConfigFromBlock(t *testing.T) {
+	block := testConfigBlock(t, 3, testConfig(t, 2, "V2_0"))
+	config, err := ConfigFromBlock(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Sequence != 2 {
+		t.Errorf("expected the config of sequence 2, got %d", config.Sequence)
+	}
+	lastConfig, err := LastConfigIndex(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastConfig != 3 {
+		t.Errorf("expected the config block to be its last config, got %d", lastConfig)
+	}
+	if _, err := ConfigFromBlock(testBlock(t)); err == nil {
+		t.Error("expected an error for a block of transactions")
+	}
+}
+
+func TestApplicationMSPIDs(t *testing.T) {
+	config := testConfig(t, 1, "V2_0")
+	config.ChannelGroup.Groups = map[string]*cb.ConfigGroup{"Application": {Groups: map[string]*cb.ConfigGroup{}}}
+	for org, mspID := range map[string]string{"Org2": "Org2MSP", "Org1": "Org1MSP"} {
+		mspConfig := &mb.MSPConfig{Config: marshal(t, &mb.FabricMSPConfig{Name: mspID})}
+		config.ChannelGroup.Groups["Application"].Groups[org] = &cb.ConfigGroup{
+			Values: map[string]*cb.ConfigValue{"MSP": {Value: marshal(t, mspConfig)}},
+		}
+	}
+	mspIDs, err := ApplicationMSPIDs(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mspIDs, []string{"Org1MSP", "Org2MSP"}) {
+		t.Errorf("expected the MSP IDs of the orgs, got %v", mspIDs)
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	changes, err := DiffConfigs(testConfig(t, 1, "V2_0"), testConfig(t, 2, "V3_0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ConfigChange{
+		{Path: "channel_group/values/Capabilities/value/capabilities/V2_0", Kind: ChangeRemoved, From: "{}"},
+		{Path: "channel_group/values/Capabilities/value/capabilities/V3_0", Kind: ChangeAdded, To: "{}"},
+		{Path: "sequence", Kind: ChangeChanged, From: `"1"`, To: `"2"`},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, changes)
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	changes, err := diffJSON(
+		[]byte(`{"a":{"b":1,"c":[1,2]},"d":"x"}`),
+		[]byte(`{"a":{"b":2,"c":[1]},"e":[]}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ConfigChange{
+		{Path: "a/b", Kind: ChangeChanged, From: "1", To: "2"},
+		{Path: "a/c[1]", Kind: ChangeRemoved, From: "2"},
+		{Path: "d", Kind: ChangeRemoved, From: `"x"`},
+		{Path: "e", Kind: ChangeAdded, To: "[]"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, changes)
+	}
+}
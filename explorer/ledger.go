@@ -0,0 +1,90 @@
+package explorer
+
+import (
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-sdk-go/pkg/client/ledger"
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/fabsdk"
+	"github.com/pkg/errors"
+	"hlf-easy/contract"
+)
+
+// Newest is the number of the last block of the channel
+const Newest = -1
+
+// Options select the peer and the channel the blocks are read from
+type Options struct {
+	PeerID string
+	// Identity is the identity file of a member of the channel, the admin
+	// identity managed for the peer is used when empty
+	Identity string
+	// Endpoint overrides the external endpoint of the peer
+	Endpoint string
+	Channel  string
+}
+
+// withLedger calls f with a ledger client of the channel targeting the peer
+func withLedger(opts Options, f func(client *ledger.Client) error) error {
+	clientConfig, profile, err := contract.Profile(contract.Options{
+		PeerID:   opts.PeerID,
+		Identity: opts.Identity,
+		Endpoint: opts.Endpoint,
+		Channel:  opts.Channel,
+	})
+	if err != nil {
+		return err
+	}
+	sdk, err := fabsdk.New(config.FromRaw(profile, "yaml"))
+	if err != nil {
+		return err
+	}
+	defer sdk.Close()
+	client, err := ledger.New(sdk.ChannelContext(
+		opts.Channel,
+		fabsdk.WithUser(contract.ProfileUser),
+		fabsdk.WithOrg(clientConfig.MSPID),
+	))
+	if err != nil {
+		return errors.Wrapf(err, "failed to read channel %s", opts.Channel)
+	}
+	return f(client)
+}
+
+// GetHeight returns the number of blocks of the channel on the peer
+func GetHeight(opts Options) (uint64, error) {
+	var height uint64
+	err := withLedger(opts, func(client *ledger.Client) error {
+		info, err := client.QueryInfo(ledger.WithTargetEndpoints(opts.PeerID))
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the height of channel %s", opts.Channel)
+		}
+		height = info.BCI.Height
+		return nil
+	})
+	return height, err
+}
+
+// GetBlock returns a block of the channel from the peer, the last one with
+// Newest
+func GetBlock(opts Options, number int64) (*cb.Block, error) {
+	var block *cb.Block
+	err := withLedger(opts, func(client *ledger.Client) error {
+		if number == Newest {
+			info, err := client.QueryInfo(ledger.WithTargetEndpoints(opts.PeerID))
+			if err != nil {
+				return errors.Wrapf(err, "failed to get the height of channel %s", opts.Channel)
+			}
+			number = int64(info.BCI.Height) - 1
+		}
+		if number < 0 {
+			return errors.Errorf("invalid block number %d", number)
+		}
+		var err error
+		block, err = client.QueryBlock(uint64(number), ledger.WithTargetEndpoints(opts.PeerID))
+		if err != nil {
+			return errors.Wrapf(err, "failed to get block %d of channel %s", number, opts.Channel)
+		}
+		return nil
+	})
+	return block, err
+}
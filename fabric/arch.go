@@ -0,0 +1,71 @@
+package fabric
+
+import (
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// MultiArchVersion is the first version of Fabric whose binaries and docker
+// images, such as fabric-ccenv and fabric-baseos, are published for arm64
+var MultiArchVersion = Version{Major: 2, Minor: 5}
+
+// NormalizeArch returns the GOARCH name of an architecture, the kernels and
+// some docker engines report aarch64 and x86_64
+func NormalizeArch(arch string) string {
+	switch strings.ToLower(arch) {
+	case "aarch64", "arm64v8":
+		return "arm64"
+	case "x86_64", "x86-64":
+		return "amd64"
+	}
+	return strings.ToLower(arch)
+}
+
+// ReleaseArch returns the architecture of the release archive of a version
+// of Fabric for a platform. The arm64 binaries are published from 2.5, the
+// older versions run on Apple Silicon with the amd64 binaries through
+// Rosetta 2
+func ReleaseArch(version Version, goos string, goarch string) (string, error) {
+	switch NormalizeArch(goarch) {
+	case "amd64":
+		return "amd64", nil
+	case "arm64":
+		if version.Compare(MultiArchVersion) >= 0 {
+			return "arm64", nil
+		}
+		if goos == "darwin" {
+			return "amd64", nil
+		}
+		return "", errors.Errorf("fabric %s has no %s/arm64 binaries, use %s or later", version, goos, MultiArchVersion)
+	}
+	return "", errors.Errorf("fabric has no binaries for %s/%s, only amd64 and arm64 are supported", goos, goarch)
+}
+
+// CheckImageArch checks the docker images of a version of Fabric, the
+// builder and runtime images of the chaincodes, run on a docker daemon of an
+// architecture. Docker pulls the variant of the architecture of the daemon
+// from the multi-arch images
+func CheckImageArch(version Version, arch string) error {
+	switch NormalizeArch(arch) {
+	case "amd64":
+		return nil
+	case "arm64":
+		if version.Compare(MultiArchVersion) >= 0 {
+			return nil
+		}
+		return errors.Errorf("the chaincode images of fabric %s, fabric-ccenv and fabric-baseos, aren't published for arm64, use %s or later", version, MultiArchVersion)
+	}
+	return errors.Errorf("the chaincode images of fabric aren't published for %s, only amd64 and arm64 are supported", arch)
+}
+
+// ParsePlatform parses a docker platform like linux/arm64 and returns its OS
+// and its architecture
+func ParsePlatform(platform string) (string, string, error) {
+	goos, goarch, ok := strings.Cut(platform, "/")
+	// a variant, like linux/arm64/v8, is ignored
+	goarch, _, _ = strings.Cut(goarch, "/")
+	if !ok || goos == "" || goarch == "" {
+		return "", "", errors.Errorf("invalid platform %q, expected <os>/<arch> like linux/arm64", platform)
+	}
+	return strings.ToLower(goos), NormalizeArch(goarch), nil
+}
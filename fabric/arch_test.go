@@ -0,0 +1,58 @@
+package fabric
+
+import (
+	"testing"
+)
+
+func TestReleaseArch(t *testing.T) {
+	v24 := Version{Major: 2, Minor: 4, Patch: 9}
+	v25 := Version{Major: 2, Minor: 5, Patch: 4}
+	tests := []struct {
+		version  Version
+		goos     string
+		goarch   string
+		expected string
+	}{
+		{v24, "linux", "amd64", "amd64"},
+		{v25, "linux", "arm64", "arm64"},
+		{v25, "darwin", "aarch64", "arm64"},
+		// the older versions run through Rosetta 2 on Apple Silicon
+		{v24, "darwin", "arm64", "amd64"},
+	}
+	for _, test := range tests {
+		arch, err := ReleaseArch(test.version, test.goos, test.goarch)
+		if err != nil || arch != test.expected {
+			t.Errorf("expected %s for %s %s/%s, got %s: %v", test.expected, test.version, test.goos, test.goarch, arch, err)
+		}
+	}
+	if _, err := ReleaseArch(v24, "linux", "arm64"); err == nil {
+		t.Error("expected an error for the linux/arm64 binaries of fabric 2.4")
+	}
+	if _, err := ReleaseArch(v25, "linux", "s390x"); err == nil {
+		t.Error("expected an error for an unsupported architecture")
+	}
+}
+
+func TestCheckImageArch(t *testing.T) {
+	if err := CheckImageArch(Version{Major: 2, Minor: 2}, "x86_64"); err != nil {
+		t.Error(err)
+	}
+	if err := CheckImageArch(Version{Major: 3, Minor: 0}, "aarch64"); err != nil {
+		t.Error(err)
+	}
+	if err := CheckImageArch(Version{Major: 2, Minor: 4, Patch: 9}, "arm64"); err == nil {
+		t.Error("expected an error for the arm64 images of fabric 2.4")
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	goos, goarch, err := ParsePlatform("linux/arm64/v8")
+	if err != nil || goos != "linux" || goarch != "arm64" {
+		t.Errorf("expected linux/arm64, got %s/%s: %v", goos, goarch, err)
+	}
+	for _, platform := range []string{"", "linux", "linux/", "/amd64"} {
+		if _, _, err := ParsePlatform(platform); err == nil {
+			t.Errorf("expected an error for %q", platform)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package fabric
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/log"
+	"hlf-easy/profile"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ReleaseURL is the URL of the release archives of Fabric, formatted with
+// the version, the OS and the architecture
+var ReleaseURL = "https://github.com/hyperledger/fabric/releases/download/v%[1]s/hyperledger-fabric-%[2]s-%[3]s-%[1]s.tar.gz"
+
+var client = &http.Client{Timeout: 10 * time.Minute}
+
+// GetBinDir returns the directory of the binaries of a version of Fabric
+// managed by hlf-easy
+func GetBinDir(version Version) (string, error) {
+	// the binaries are shared by the profiles
+	home, err := profile.UserHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/bin", "fabric-"+version.String()), nil
+}
+
+// Binary returns the path of the peer or orderer binary of a version, the
+// one in the PATH is used when the version isn't set
+func Binary(name string, version string) (string, error) {
+	if version == "" {
+		return name, nil
+	}
+	v, err := ParseVersion(version)
+	if err != nil {
+		return "", err
+	}
+	binDir, err := GetBinDir(v)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(binDir, BinaryFile(name)), nil
+}
+
+// BinaryFile returns the file name of a binary of Fabric on the platform
+func BinaryFile(name string) string {
+	if runtime.GOOS == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
+
+// Install downloads the binaries of a version of Fabric unless they're
+// already installed, and returns their directory
+func Install(version Version) (string, error) {
+	binDir, err := GetBinDir(version)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(binDir, BinaryFile("peer"))); err == nil {
+		return binDir, nil
+	}
+	err = Download(version, runtime.GOOS, runtime.GOARCH, binDir)
+	if err != nil {
+		return "", err
+	}
+	return binDir, nil
+}
+
+// Download downloads the binaries of a version of Fabric for a platform to
+// binDir, which mustn't exist
+func Download(version Version, goos string, goarch string, binDir string) error {
+	arch, err := ReleaseArch(version, goos, goarch)
+	if err != nil {
+		return err
+	}
+	if arch != NormalizeArch(goarch) {
+		log.Warnf("Fabric %s has no %s/%s binaries, the %s/%s ones are run through Rosetta 2", version, goos, goarch, goos, arch)
+	}
+	url := fmt.Sprintf(ReleaseURL, version.String(), goos, arch)
+	log.Infof("Downloading Fabric %s from %s", version, url)
+	resp, err := client.Get(url)
+	if err != nil {
+		return errors.Wrapf(err, "failed to download fabric %s, import it with bundle import on hosts without internet", version)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("failed to download fabric %s: %s", version, resp.Status)
+	}
+	// the archive is extracted next to the binaries so a failed download
+	// doesn't leave a partial version
+	err = os.MkdirAll(filepath.Dir(binDir), 0755)
+	if err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(binDir), ".fabric-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	err = extractBinaries(resp.Body, tmpDir)
+	if err != nil {
+		return errors.Wrapf(err, "failed to extract fabric %s", version)
+	}
+	peer := "peer"
+	if goos == "windows" {
+		peer = "peer.exe"
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, peer)); err != nil {
+		return errors.Errorf("the archive of fabric %s has no peer binary", version)
+	}
+	err = os.Chmod(tmpDir, 0755)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, binDir)
+}
+
+// extractBinaries extracts the files of the bin directory of a release
+// archive
+func extractBinaries(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(header.Name)), "./")
+		if header.Typeflag != tar.TypeReg || filepath.Dir(name) != "bin" {
+			continue
+		}
+		f, err := os.OpenFile(filepath.Join(dir, filepath.Base(name)), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package fabric
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func releaseArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tw.Write([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestInstall(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	archive := releaseArchive(t, map[string]string{
+		"bin/peer":              "peer binary",
+		"./bin/orderer":         "orderer binary",
+		"config/core.yaml":      "core",
+		"bin/../../escape/peer": "escape",
+	})
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if !strings.HasSuffix(r.URL.Path, "/v2.5.4/fabric-2.5.4.tar.gz") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(archive)
+	}))
+	defer srv.Close()
+	defer func(url string) { ReleaseURL = url }(ReleaseURL)
+	ReleaseURL = srv.URL + "/v%[1]s/fabric-%[1]s.tar.gz"
+
+	binDir, err := Install(mustParse(t, "2.5.4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binDir != filepath.Join(home, "hlf-easy/bin/fabric-2.5.4") {
+		t.Fatalf("unexpected bin dir %s", binDir)
+	}
+	entries, err := os.ReadDir(binDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "orderer,peer" {
+		t.Fatalf("expected only the binaries, got %v", names)
+	}
+	if _, err := os.Stat(filepath.Join(home, "escape")); err == nil {
+		t.Fatal("expected the files outside bin to be skipped")
+	}
+
+	// installed versions aren't downloaded again
+	_, err = Install(mustParse(t, "2.5.4"))
+	if err != nil || requests != 1 {
+		t.Fatalf("expected a single download, got %d: %v", requests, err)
+	}
+	_, err = Install(mustParse(t, "2.5.5"))
+	if err == nil {
+		t.Fatal("expected an error for a missing release")
+	}
+	binary, err := Binary("peer", "2.5.4")
+	if err != nil || binary != filepath.Join(binDir, "peer") {
+		t.Fatalf("unexpected binary %s: %v", binary, err)
+	}
+	binary, err = Binary("peer", "")
+	if err != nil || binary != "peer" {
+		t.Fatalf("expected the peer in the PATH, got %s: %v", binary, err)
+	}
+}
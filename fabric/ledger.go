@@ -0,0 +1,178 @@
+package fabric
+
+import (
+	"bufio"
+	"encoding/binary"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-config/configtx"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// GetChainsDir returns the directory with the block files of the channels
+// joined by a peer
+func GetChainsDir(peerDir string) string {
+	return filepath.Join(peerDir, "data/ledgersData/chains/chains")
+}
+
+// GetOrdererChainsDir returns the directory with the block files of the
+// channels joined by an orderer
+func GetOrdererChainsDir(ordererDir string) string {
+	return filepath.Join(ordererDir, "data/chains")
+}
+
+// ChannelCapabilities returns the channel and application capabilities of the
+// last config block of the channels in the ledger of a peer
+func ChannelCapabilities(peerDir string) (map[string][]string, error) {
+	capabilities := map[string][]string{}
+	chainsDir := GetChainsDir(peerDir)
+	entries, err := os.ReadDir(chainsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return capabilities, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		block, err := lastConfigBlock(filepath.Join(chainsDir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the ledger of channel %s", entry.Name())
+		}
+		if block == nil {
+			continue
+		}
+		channelCapabilities, err := configCapabilities(block)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the config of channel %s", entry.Name())
+		}
+		capabilities[entry.Name()] = channelCapabilities
+	}
+	return capabilities, nil
+}
+
+// lastConfigBlock scans the block files of a channel, each block is
+// prefixed with its length as a varint
+func lastConfigBlock(channelDir string) (*cb.Block, error) {
+	files, err := filepath.Glob(filepath.Join(channelDir, "blockfile_*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var last *cb.Block
+	for _, file := range files {
+		err = func() error {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r := bufio.NewReader(f)
+			for {
+				length, err := binary.ReadUvarint(r)
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				blockBytes := make([]byte, length)
+				_, err = io.ReadFull(r, blockBytes)
+				if err == io.ErrUnexpectedEOF {
+					// the peer was stopped while appending the block
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				block, err := protoutil.UnmarshalBlock(blockBytes)
+				if err != nil {
+					return err
+				}
+				if protoutil.IsConfigBlock(block) {
+					last = block
+				}
+			}
+		}()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return last, nil
+}
+
+// ChannelConfigs returns the config of the last config block of the channels
+// in a chains directory, it's read from the block files so the node doesn't
+// need to run
+func ChannelConfigs(chainsDir string) (map[string]*cb.Config, error) {
+	configs := map[string]*cb.Config{}
+	entries, err := os.ReadDir(chainsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return configs, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		block, err := lastConfigBlock(filepath.Join(chainsDir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the ledger of channel %s", entry.Name())
+		}
+		if block == nil {
+			continue
+		}
+		config, err := blockConfig(block)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the config of channel %s", entry.Name())
+		}
+		configs[entry.Name()] = config
+	}
+	return configs, nil
+}
+
+func blockConfig(block *cb.Block) (*cb.Config, error) {
+	envelope, err := protoutil.ExtractEnvelope(block, 0)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := protoutil.UnmarshalPayload(envelope.Payload)
+	if err != nil {
+		return nil, err
+	}
+	configEnvelope := &cb.ConfigEnvelope{}
+	err = proto.Unmarshal(payload.Data, configEnvelope)
+	if err != nil {
+		return nil, err
+	}
+	return configEnvelope.Config, nil
+}
+
+func configCapabilities(block *cb.Block) ([]string, error) {
+	config, err := blockConfig(block)
+	if err != nil {
+		return nil, err
+	}
+	c := configtx.New(config)
+	capabilities, err := c.Channel().Capabilities()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := config.ChannelGroup.Groups[configtx.ApplicationGroupKey]; ok {
+		applicationCapabilities, err := c.Application().Capabilities()
+		if err != nil {
+			return nil, err
+		}
+		capabilities = append(capabilities, applicationCapabilities...)
+	}
+	return capabilities, nil
+}
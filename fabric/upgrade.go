@@ -0,0 +1,64 @@
+package fabric
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+)
+
+// lastMinors are the last minor versions of the major versions of Fabric, a
+// peer must run them before it's upgraded to the next major version
+var lastMinors = map[int]int{1: 4, 2: 5}
+
+// Plan is an upgrade of a peer that passed the compatibility checks
+type Plan struct {
+	From Version `json:"from"`
+	To   Version `json:"to"`
+	// UpgradeDBs is true when the state and history databases are rebuilt by
+	// the new version, i.e. from 1.4 to 2.x
+	UpgradeDBs bool `json:"upgradeDBs"`
+}
+
+// CheckUpgrade checks that a peer can be upgraded from a version to another
+// one: the ledger can't be downgraded, the major versions can only be
+// upgraded from their last minor version and the new version must support the
+// capabilities of the channels the peer joined
+func CheckUpgrade(from Version, to Version, capabilities map[string][]string) (*Plan, error) {
+	switch {
+	case to.Compare(from) == 0:
+		return nil, errors.Errorf("the peer already runs fabric %s", to)
+	case to.Compare(from) < 0:
+		return nil, errors.Errorf("fabric %s can't be downgraded to %s, the ledger of the peer isn't compatible with older versions", from, to)
+	case to.Major > from.Major+1:
+		return nil, errors.Errorf("fabric %s can't be upgraded to %s, upgrade to %d.x first", from, to, from.Major+1)
+	}
+	plan := &Plan{From: from, To: to}
+	if to.Major > from.Major {
+		lastMinor, ok := lastMinors[from.Major]
+		if !ok {
+			return nil, errors.Errorf("the upgrade from fabric %d.x to %d.x isn't supported", from.Major, to.Major)
+		}
+		if from.Minor < lastMinor {
+			return nil, errors.Errorf("fabric %s can't be upgraded to %s, upgrade to %d.%d first", from, to, from.Major, lastMinor)
+		}
+		plan.UpgradeDBs = from.Major == 1
+	}
+	unsupported := []string{}
+	for channel, channelCapabilities := range capabilities {
+		for _, capability := range channelCapabilities {
+			v, err := CapabilityVersion(capability)
+			if err != nil {
+				return nil, errors.Wrapf(err, "channel %s", channel)
+			}
+			if v.Compare(to) > 0 {
+				unsupported = append(unsupported, fmt.Sprintf("%s of channel %s", capability, channel))
+			}
+		}
+	}
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return nil, errors.Errorf("fabric %s doesn't support the capabilities %s", to, strings.Join(unsupported, ", "))
+	}
+	return plan, nil
+}
@@ -0,0 +1,150 @@
+package fabric
+
+import (
+	"encoding/binary"
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mustParse(t *testing.T, s string) Version {
+	t.Helper()
+	v, err := ParseVersion(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParseVersion(t *testing.T) {
+	for s, expected := range map[string]string{"2.5.4": "2.5.4", "v2.2.15": "2.2.15", "3.0": "3.0.0"} {
+		if v := mustParse(t, s); v.String() != expected {
+			t.Errorf("expected %s for %s, got %s", expected, s, v)
+		}
+	}
+	for _, s := range []string{"", "2", "2.5.x", "2.5.4.1", "latest"} {
+		if _, err := ParseVersion(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+	if mustParse(t, "2.5.4").Compare(mustParse(t, "2.10.0")) != -1 {
+		t.Error("expected 2.5.4 to be older than 2.10.0")
+	}
+	v, err := CapabilityVersion("V1_4_3")
+	if err != nil || v.String() != "1.4.3" {
+		t.Errorf("expected V1_4_3 to be 1.4.3, got %s %v", v, err)
+	}
+}
+
+func TestCheckUpgrade(t *testing.T) {
+	capabilities := map[string][]string{"demo": {"V2_0", "V2_0"}}
+	plan, err := CheckUpgrade(mustParse(t, "2.2.15"), mustParse(t, "2.5.4"), capabilities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.UpgradeDBs {
+		t.Error("expected a minor upgrade to keep the databases")
+	}
+	plan, err = CheckUpgrade(mustParse(t, "1.4.12"), mustParse(t, "2.2.0"), map[string][]string{"demo": {"V1_4_3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.UpgradeDBs {
+		t.Error("expected the upgrade from 1.4 to rebuild the databases")
+	}
+
+	invalid := []struct {
+		from, to     string
+		capabilities map[string][]string
+		message      string
+	}{
+		{"2.5.4", "2.5.4", nil, "already runs"},
+		{"2.5.4", "2.2.0", nil, "can't be downgraded"},
+		{"1.4.12", "3.0.0", nil, "upgrade to 2.x first"},
+		{"2.2.0", "3.0.0", nil, "upgrade to 2.5 first"},
+		{"2.2.0", "2.4.9", map[string][]string{"demo": {"V2_5"}, "other": {"V2_0"}}, "V2_5 of channel demo"},
+	}
+	for _, tc := range invalid {
+		_, err := CheckUpgrade(mustParse(t, tc.from), mustParse(t, tc.to), tc.capabilities)
+		if err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Errorf("expected the upgrade from %s to %s to fail with %q, got %v", tc.from, tc.to, tc.message, err)
+		}
+	}
+}
+
+func marshal(t *testing.T, m proto.Message) []byte {
+	t.Helper()
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func capabilitiesValue(t *testing.T, names ...string) map[string]*cb.ConfigValue {
+	capabilities := &cb.Capabilities{Capabilities: map[string]*cb.Capability{}}
+	for _, name := range names {
+		capabilities.Capabilities[name] = &cb.Capability{}
+	}
+	return map[string]*cb.ConfigValue{"Capabilities": {Value: marshal(t, capabilities)}}
+}
+
+func testBlock(t *testing.T, headerType cb.HeaderType, channelCapability string, applicationCapability string) []byte {
+	config := &cb.Config{ChannelGroup: &cb.ConfigGroup{
+		Values: capabilitiesValue(t, channelCapability),
+		Groups: map[string]*cb.ConfigGroup{"Application": {Values: capabilitiesValue(t, applicationCapability)}},
+	}}
+	payload := &cb.Payload{
+		Header: &cb.Header{ChannelHeader: marshal(t, &cb.ChannelHeader{Type: int32(headerType), ChannelId: "demo"})},
+		Data:   marshal(t, &cb.ConfigEnvelope{Config: config}),
+	}
+	envelope := &cb.Envelope{Payload: marshal(t, payload)}
+	return marshal(t, &cb.Block{Data: &cb.BlockData{Data: [][]byte{marshal(t, envelope)}}})
+}
+
+func TestChannelCapabilities(t *testing.T) {
+	peerDir := t.TempDir()
+	channelDir := filepath.Join(GetChainsDir(peerDir), "demo")
+	err := os.MkdirAll(channelDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blocks []byte
+	for _, block := range [][]byte{
+		testBlock(t, cb.HeaderType_CONFIG, "V2_0", "V2_0"),
+		testBlock(t, cb.HeaderType_CONFIG, "V2_0", "V2_5"),
+		testBlock(t, cb.HeaderType_ENDORSER_TRANSACTION, "V3_0", "V3_0"),
+	} {
+		length := make([]byte, binary.MaxVarintLen64)
+		n := binary.PutUvarint(length, uint64(len(block)))
+		blocks = append(blocks, length[:n]...)
+		blocks = append(blocks, block...)
+	}
+	// the last block is cut, as when the peer is stopped while writing it
+	err = os.WriteFile(filepath.Join(channelDir, "blockfile_000000"), blocks[:len(blocks)-3], 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	capabilities, err := ChannelCapabilities(peerDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(capabilities["demo"], ",") != "V2_0,V2_5" {
+		t.Fatalf("expected the capabilities of the last config block, got %v", capabilities)
+	}
+	configs, err := ChannelConfigs(GetChainsDir(peerDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := configs["demo"].GetChannelGroup().GetGroups()["Application"]; !ok || len(configs) != 1 {
+		t.Fatalf("expected the config of channel demo, got %v", configs)
+	}
+
+	capabilities, err = ChannelCapabilities(t.TempDir())
+	if err != nil || len(capabilities) != 0 {
+		t.Fatalf("expected no channels for a peer without ledger, got %v %v", capabilities, err)
+	}
+}
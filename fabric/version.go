@@ -0,0 +1,82 @@
+package fabric
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/pkg/errors"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Version is a release of Hyperledger Fabric, e.g. 2.5.4
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parses a version like 2.5.4 or v2.5.4, the patch defaults
+// to 0
+func ParseVersion(s string) (Version, error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, errors.Errorf("invalid fabric version %q, expected e.g. 2.5.4", s)
+	}
+	numbers := []int{0, 0, 0}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, errors.Errorf("invalid fabric version %q, expected e.g. 2.5.4", s)
+		}
+		numbers[i] = n
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 when v is older, the same or newer than o
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// CapabilityVersion returns the first version supporting a capability of a
+// channel, e.g. 2.5.0 for V2_5 or 1.4.3 for V1_4_3
+func CapabilityVersion(capability string) (Version, error) {
+	if !strings.HasPrefix(capability, "V") {
+		return Version{}, errors.Errorf("invalid capability %q", capability)
+	}
+	v, err := ParseVersion(strings.ReplaceAll(capability[1:], "_", "."))
+	if err != nil {
+		return Version{}, errors.Errorf("invalid capability %q", capability)
+	}
+	return v, nil
+}
+
+// BinaryVersion returns the version reported by a peer or orderer binary
+func BinaryVersion(binary string) (Version, error) {
+	output, err := exec.Command(binary, "version").Output()
+	if err != nil {
+		return Version{}, errors.Wrapf(err, "failed to get the version of %s", binary)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "Version:") {
+			return ParseVersion(strings.TrimPrefix(line, "Version:"))
+		}
+	}
+	return Version{}, errors.Errorf("no version in the output of %s version", binary)
+}
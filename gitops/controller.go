@@ -0,0 +1,213 @@
+package gitops
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/audit"
+	"hlf-easy/log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	StatusSynced = "synced"
+	StatusFailed = "failed"
+	// StatusPlanned is the status of a dry run, its state is not saved
+	StatusPlanned = "planned"
+)
+
+// Options of the GitOps controller
+type Options struct {
+	// Repo is the URL or path of the Git repository
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+	// Path of the directory with the specs in the repository
+	Path string `json:"path"`
+}
+
+// State is the last sync of the controller, stored in
+// $HOME/hlf-easy/gitops/state.json
+type State struct {
+	Options
+	Commit   string    `json:"commit"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	SyncedAt time.Time `json:"syncedAt"`
+	Result   *Result   `json:"result,omitempty"`
+}
+
+func getGitOpsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/gitops"), nil
+}
+
+// GetState returns the state of the last sync
+func GetState() (*State, error) {
+	gitOpsDir, err := getGitOpsDir()
+	if err != nil {
+		return nil, err
+	}
+	stateBytes, err := os.ReadFile(filepath.Join(gitOpsDir, "state.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("the host has not been synced, run gitops sync first")
+		}
+		return nil, err
+	}
+	state := &State{}
+	err = json.Unmarshal(stateBytes, state)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func saveState(state *State) error {
+	gitOpsDir, err := getGitOpsDir()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(gitOpsDir, 0755)
+	if err != nil {
+		return err
+	}
+	stateBytes, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(gitOpsDir, "state.json"), stateBytes, 0644)
+}
+
+// Sync fetches the branch and reconciles the host with the specs of its last
+// commit. A commit is only reconciled again if its previous sync failed
+func Sync(opts Options) (*State, error) {
+	gitOpsDir, err := getGitOpsDir()
+	if err != nil {
+		return nil, err
+	}
+	repoDir := filepath.Join(gitOpsDir, "repo")
+	commit, err := fetch(opts.Repo, opts.Branch, repoDir)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := GetState()
+	if err == nil && previous.Options == opts && previous.Commit == commit && previous.Status == StatusSynced {
+		return previous, nil
+	}
+	state := &State{
+		Options:  opts,
+		Commit:   commit,
+		SyncedAt: time.Now().UTC(),
+	}
+	result, err := syncCommit(repoDir, commit, opts.Path)
+	state.Result = result
+	state.Status = StatusSynced
+	if err != nil {
+		state.Status = StatusFailed
+		state.Error = err.Error()
+	}
+	entry := audit.Entry{
+		Actor:     "gitops",
+		Operation: "gitops.reconcile",
+		Target:    commit,
+		Params:    map[string]string{"repo": opts.Repo, "branch": opts.Branch, "path": opts.Path},
+		Result:    audit.ResultOK,
+	}
+	if err != nil {
+		entry.Result = audit.ResultError
+		entry.Error = err.Error()
+	}
+	if auditErr := audit.Record(entry); auditErr != nil {
+		log.Warnf("Failed to write the audit log: %v", auditErr)
+	}
+	saveErr := saveState(state)
+	if saveErr != nil {
+		return nil, saveErr
+	}
+	return state, err
+}
+
+// PlanSync fetches the branch and returns the actions and drift of the
+// reconciliation of its last commit without changing the host, the state of
+// the last sync is left untouched
+func PlanSync(opts Options) (*State, error) {
+	gitOpsDir, err := getGitOpsDir()
+	if err != nil {
+		return nil, err
+	}
+	repoDir := filepath.Join(gitOpsDir, "repo")
+	commit, err := fetch(opts.Repo, opts.Branch, repoDir)
+	if err != nil {
+		return nil, err
+	}
+	err = checkout(repoDir, commit)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := LoadSpec(filepath.Join(repoDir, opts.Path))
+	if err != nil {
+		return nil, err
+	}
+	result, err := Plan(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &State{
+		Options: opts,
+		Commit:  commit,
+		Status:  StatusPlanned,
+		Result:  result,
+	}, nil
+}
+
+func syncCommit(repoDir string, commit string, path string) (*Result, error) {
+	err := checkout(repoDir, commit)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := LoadSpec(filepath.Join(repoDir, path))
+	if err != nil {
+		return nil, err
+	}
+	return Reconcile(spec)
+}
+
+// Run syncs the host every interval until the context is done, a failed sync
+// is logged and retried at the next interval
+func Run(ctx context.Context, opts Options, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// the result of a commit is only logged when it's reconciled
+	lastSyncedAt := time.Time{}
+	for {
+		state, err := Sync(opts)
+		if err != nil {
+			log.Errorf("Failed to sync %s: %v", opts.Repo, err)
+		} else if state.SyncedAt != lastSyncedAt {
+			lastSyncedAt = state.SyncedAt
+			for _, action := range state.Result.Actions {
+				log.Infof("%s: %s", shortCommit(state.Commit), action)
+			}
+			for _, drift := range state.Result.Drift {
+				log.Warnf("%s: %s", shortCommit(state.Commit), drift)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
@@ -0,0 +1,50 @@
+package gitops
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// git runs a git command in a directory and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return "", errors.Errorf("git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// fetch clones the repository in dir, or fetches it when it's already cloned,
+// and returns the last commit of the branch
+func fetch(repo string, branch string, dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		err = os.MkdirAll(filepath.Dir(dir), 0755)
+		if err != nil {
+			return "", err
+		}
+		_, err = git(filepath.Dir(dir), "clone", "--no-checkout", repo, filepath.Base(dir))
+		if err != nil {
+			return "", err
+		}
+	}
+	_, err := git(dir, "fetch", "--prune", repo, "+refs/heads/"+branch+":refs/remotes/origin/"+branch)
+	if err != nil {
+		return "", err
+	}
+	return git(dir, "rev-parse", "refs/remotes/origin/"+branch)
+}
+
+// checkout makes the working tree match a commit
+func checkout(dir string, commit string) error {
+	_, err := git(dir, "checkout", "--force", "--detach", commit)
+	return err
+}
@@ -0,0 +1,197 @@
+package gitops
+
+import (
+	"encoding/json"
+	"hlf-easy/chaincode"
+	"hlf-easy/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testNetwork = `
+host:
+  overcommitThreshold: 0.8
+  overcommitPolicy: refuse
+peers:
+  - id: peer0
+    mspID: Org1MSP
+    local: true
+    caName: ca-1
+    hosts: [peer0.org1.example.com]
+    externalPort: 7051
+`
+
+const testRelease = `
+chaincodes:
+  - name: asset
+    version: "1.0"
+    type: ccaas
+    address: asset.example.com:9999
+    limits:
+      executeTimeout: 20s
+`
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %s", args, output)
+	}
+}
+
+func commitFiles(t *testing.T, repo string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "-q", "-m", "update")
+}
+
+func TestLoadSpec(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, NetworkFile), []byte(testNetwork), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := LoadSpec(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Network.Host.OvercommitPolicy != "refuse" || spec.Network.Host.OvercommitThreshold != 0.8 {
+		t.Fatalf("unexpected host %+v", spec.Network.Host)
+	}
+	if len(spec.Network.Peers) != 1 || spec.Network.Peers[0].MSPID != "Org1MSP" || !spec.Network.Peers[0].Local {
+		t.Fatalf("unexpected peers %+v", spec.Network.Peers)
+	}
+	if len(spec.Release.Chaincodes) != 0 {
+		t.Fatal("expected no chaincodes without release.yaml")
+	}
+}
+
+func TestSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	var enrolled []string
+	enrollPeer = func(peerInitOpts config.PeerInitOptions) (bool, error) {
+		enrolled = append(enrolled, peerInitOpts.ID)
+		peerDir := filepath.Join(home, "hlf-easy/peers", peerInitOpts.ID)
+		err := os.MkdirAll(peerDir, 0755)
+		if err != nil {
+			return false, err
+		}
+		initBytes, err := json.Marshal(peerInitOpts)
+		if err != nil {
+			return false, err
+		}
+		return true, os.WriteFile(filepath.Join(peerDir, "init.json"), initBytes, 0644)
+	}
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q", "-b", "main")
+	commitFiles(t, repo, map[string]string{NetworkFile: testNetwork, ReleaseFile: testRelease})
+
+	opts := Options{Repo: repo, Branch: "main", Path: "."}
+	state, err := Sync(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != StatusSynced || len(state.Result.Actions) != 3 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	if strings.Join(enrolled, ",") != "peer0" {
+		t.Fatalf("expected peer0 to be initialized, got %v", enrolled)
+	}
+	d, err := chaincode.Get("asset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Limits.ExecuteTimeout != "20s" {
+		t.Fatalf("unexpected chaincode %+v", d)
+	}
+
+	// the same commit is not reconciled again
+	again, err := Sync(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.SyncedAt.Equal(state.SyncedAt) {
+		t.Fatal("expected the synced commit not to be reconciled again")
+	}
+
+	// peers are not re-enrolled, their changes are reported as drift
+	commitFiles(t, repo, map[string]string{
+		NetworkFile: strings.Replace(testNetwork, "externalPort: 7051", "externalPort: 7051\n    limits:\n      cpus: 2", 1),
+		ReleaseFile: strings.Replace(testRelease, `"1.0"`, `"1.1"`, 1),
+	})
+	state, err = Sync(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enrolled) != 1 {
+		t.Fatal("expected peer0 not to be enrolled again")
+	}
+	if len(state.Result.Actions) != 1 || !strings.Contains(state.Result.Actions[0], "asset to asset_1.1") {
+		t.Fatalf("expected the chaincode to be updated, got %v", state.Result.Actions)
+	}
+	if len(state.Result.Drift) != 1 || !strings.Contains(state.Result.Drift[0], "peer peer0: limits") {
+		t.Fatalf("expected the limits of peer0 to drift, got %v", state.Result.Drift)
+	}
+
+	// an invalid spec is refused before applying anything
+	commitFiles(t, repo, map[string]string{ReleaseFile: strings.Replace(testRelease, "type: ccaas", "type: lambda", 1)})
+	_, err = Sync(opts)
+	if err == nil {
+		t.Fatal("expected the invalid release to fail")
+	}
+	saved, err := GetState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != StatusFailed || !strings.Contains(saved.Error, "lambda") {
+		t.Fatalf("expected the failed sync to be saved, got %+v", saved)
+	}
+}
+
+func TestPlanSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	enrolled := false
+	enrollPeer = func(peerInitOpts config.PeerInitOptions) (bool, error) {
+		enrolled = true
+		return true, nil
+	}
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q", "-b", "main")
+	commitFiles(t, repo, map[string]string{NetworkFile: testNetwork, ReleaseFile: testRelease})
+
+	state, err := PlanSync(Options{Repo: repo, Branch: "main", Path: "."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != StatusPlanned || len(state.Result.Actions) != 3 {
+		t.Fatalf("unexpected plan %+v", state)
+	}
+	if enrolled {
+		t.Fatal("expected the plan not to initialize peer0")
+	}
+	if _, err := chaincode.Get("asset"); err == nil {
+		t.Fatal("expected the plan not to register the chaincode")
+	}
+	if _, err := GetState(); err == nil {
+		t.Fatal("expected the plan not to be saved as the last sync")
+	}
+}
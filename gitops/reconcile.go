@@ -0,0 +1,278 @@
+package gitops
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/chaincode"
+	"hlf-easy/config"
+	"hlf-easy/lock"
+	"hlf-easy/log"
+	"hlf-easy/node"
+	"hlf-easy/notify"
+	"hlf-easy/resources"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+// Result of a reconciliation, Actions are the changes applied to the host and
+// Drift the differences that must be fixed by an operator
+type Result struct {
+	Actions []string `json:"actions"`
+	Drift   []string `json:"drift"`
+}
+
+// enrollPeer is replaced in tests, enrolling needs a running CA. It returns
+// false when the peer was initialized by another operation meanwhile
+var enrollPeer = node.InitMissingPeer
+
+// Reconcile applies the spec to the host. The host, notify config and
+// chaincodes are updated to match the spec, and the missing peers are
+// initialized. Peers are never re-enrolled or removed, since it would replace
+// their certificates or delete their ledger, a peer that differs from the
+// spec is reported as drift instead
+func Reconcile(spec *Spec) (*Result, error) {
+	return reconcile(spec, true)
+}
+
+// Plan returns the actions and drift of the reconciliation of the spec
+// without changing the host
+func Plan(spec *Spec) (*Result, error) {
+	return reconcile(spec, false)
+}
+
+// reconcile compares the host with the spec, the actions are only applied
+// when apply is set
+func reconcile(spec *Spec, apply bool) (*Result, error) {
+	result := &Result{Actions: []string{}, Drift: []string{}}
+	err := validate(spec)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Network.Host != nil {
+		err = reconcileHost(*spec.Network.Host, apply, result)
+		if err != nil {
+			return result, err
+		}
+	}
+	if spec.Network.Notify != nil {
+		err = reconcileNotify(*spec.Network.Notify, apply, result)
+		if err != nil {
+			return result, err
+		}
+	}
+	err = reconcileChaincodes(spec.Release.Chaincodes, apply, result)
+	if err != nil {
+		return result, err
+	}
+	err = reconcilePeers(spec.Network.Peers, apply, result)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// validate checks the whole spec before applying anything
+func validate(spec *Spec) error {
+	if spec.Network.Host != nil {
+		err := resources.ValidateHostConfig(*spec.Network.Host)
+		if err != nil {
+			return errors.Wrap(err, "invalid host")
+		}
+	}
+	if spec.Network.Notify != nil {
+		for _, webhook := range spec.Network.Notify.Webhooks {
+			err := notify.ValidateWebhook(webhook)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	names := map[string]bool{}
+	for _, d := range spec.Release.Chaincodes {
+		err := d.Validate()
+		if err != nil {
+			return err
+		}
+		if names[d.Name] {
+			return errors.Errorf("chaincode %s is released twice", d.Name)
+		}
+		names[d.Name] = true
+	}
+	ids := map[string]bool{}
+	for _, peer := range spec.Network.Peers {
+		if peer.ID == "" {
+			return errors.New("peer without id")
+		}
+		if ids[peer.ID] {
+			return errors.Errorf("peer %s is declared twice", peer.ID)
+		}
+		ids[peer.ID] = true
+	}
+	return nil
+}
+
+func reconcileHost(hostConfig config.HostConfig, apply bool, result *Result) error {
+	current, err := utils.GetHostConfig()
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(*current, hostConfig) {
+		return nil
+	}
+	if apply {
+		err = utils.SaveHostConfig(&hostConfig)
+		if err != nil {
+			return err
+		}
+	}
+	result.Actions = append(result.Actions, "updated the host config")
+	return nil
+}
+
+func reconcileNotify(notifyConfig config.NotifyConfig, apply bool, result *Result) error {
+	current, err := utils.GetNotifyConfig()
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(current.Webhooks, notifyConfig.Webhooks) {
+		return nil
+	}
+	if apply {
+		err = utils.SaveNotifyConfig(&notifyConfig)
+		if err != nil {
+			return err
+		}
+	}
+	result.Actions = append(result.Actions, "updated the notify webhooks")
+	return nil
+}
+
+func reconcileChaincodes(definitions []chaincode.Definition, apply bool, result *Result) error {
+	registered, err := chaincode.List()
+	if err != nil {
+		return err
+	}
+	current := map[string]chaincode.Definition{}
+	for _, d := range registered {
+		current[d.Name] = d
+	}
+	released := map[string]bool{}
+	for _, d := range definitions {
+		released[d.Name] = true
+		existing, ok := current[d.Name]
+		if ok && reflect.DeepEqual(existing, d) {
+			continue
+		}
+		if apply {
+			err = chaincode.Save(d)
+			if err != nil {
+				return err
+			}
+		}
+		if ok {
+			result.Actions = append(result.Actions, fmt.Sprintf("updated chaincode %s to %s", d.Name, d.Label()))
+		} else {
+			result.Actions = append(result.Actions, fmt.Sprintf("registered chaincode %s", d.Label()))
+		}
+	}
+	for _, d := range registered {
+		if !released[d.Name] {
+			result.Drift = append(result.Drift, fmt.Sprintf("chaincode %s is registered but not in %s", d.Name, ReleaseFile))
+		}
+	}
+	return nil
+}
+
+func reconcilePeers(peers []config.PeerInitOptions, apply bool, result *Result) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	declared := map[string]bool{}
+	for _, peer := range peers {
+		declared[peer.ID] = true
+		err = reconcilePeer(home, peer, apply, result)
+		if err != nil {
+			return err
+		}
+	}
+	initFiles, err := filepath.Glob(filepath.Join(home, "hlf-easy/peers/*/init.json"))
+	if err != nil {
+		return err
+	}
+	for _, initFile := range initFiles {
+		id := filepath.Base(filepath.Dir(initFile))
+		if !declared[id] {
+			result.Drift = append(result.Drift, fmt.Sprintf("peer %s is not in %s", id, NetworkFile))
+		}
+	}
+	return nil
+}
+
+// reconcilePeer initializes a missing peer or reports the drift of an
+// existing one, its init.json is read holding the lock of the peer so it's
+// not read while an operation rewrites it
+func reconcilePeer(home string, peer config.PeerInitOptions, apply bool, result *Result) error {
+	initPath := filepath.Join(home, "hlf-easy/peers", peer.ID, "init.json")
+	if _, err := os.Stat(initPath); os.IsNotExist(err) {
+		initialized := true
+		if apply {
+			initialized, err = enrollPeer(peer)
+			if err != nil {
+				return errors.Wrapf(err, "failed to init peer %s", peer.ID)
+			}
+		}
+		if initialized {
+			result.Actions = append(result.Actions, fmt.Sprintf("initialized peer %s", peer.ID))
+			return nil
+		}
+	}
+	l, err := lock.Acquire("peer", peer.ID, "gitops.reconcile", "gitops", lock.Wait, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := l.Release(); err != nil {
+			log.Warnf("Failed to release the lock of peer %s: %v", peer.ID, err)
+		}
+	}()
+	initBytes, err := os.ReadFile(initPath)
+	if err != nil {
+		return err
+	}
+	existing := config.PeerInitOptions{}
+	err = json.Unmarshal(initBytes, &existing)
+	if err != nil {
+		return err
+	}
+	for _, field := range peerDrift(existing, peer) {
+		result.Drift = append(result.Drift, fmt.Sprintf("peer %s: %s differs from %s", peer.ID, field, NetworkFile))
+	}
+	return nil
+}
+
+// peerDrift returns the settings of an initialized peer that differ from the
+// spec, the settings left empty in the spec are defaulted by peer init
+func peerDrift(existing config.PeerInitOptions, desired config.PeerInitOptions) []string {
+	var fields []string
+	check := func(field string, equal bool, empty bool) {
+		if !empty && !equal {
+			fields = append(fields, field)
+		}
+	}
+	check("mspID", existing.MSPID == desired.MSPID, desired.MSPID == "")
+	check("caName", existing.CAName == desired.CAName, desired.CAName == "")
+	check("hosts", reflect.DeepEqual(existing.Hosts, desired.Hosts), len(desired.Hosts) == 0)
+	check("externalEndpoint", existing.ExternalEndpoint == desired.ExternalEndpoint, desired.ExternalEndpoint == "")
+	check("gossipBootstrap", reflect.DeepEqual(existing.GossipBootstrap, desired.GossipBootstrap), len(desired.GossipBootstrap) == 0)
+	check("gossipState", existing.GossipState == desired.GossipState, false)
+	check("gateway", existing.Gateway == desired.Gateway, false)
+	check("resources", existing.Resources == desired.Resources, false)
+	check("limits", existing.Limits == desired.Limits, false)
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,77 @@
+package gitops
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/chaincode"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+)
+
+const (
+	NetworkFile = "network.yaml"
+	ReleaseFile = "release.yaml"
+)
+
+// NetworkSpec is the network.yaml of the repository, the desired config of
+// the host and its peers. The keys are the JSON keys of the config files
+type NetworkSpec struct {
+	Host   *config.HostConfig       `json:"host,omitempty"`
+	Notify *config.NotifyConfig     `json:"notify,omitempty"`
+	Peers  []config.PeerInitOptions `json:"peers,omitempty"`
+}
+
+// ReleaseSpec is the release.yaml of the repository, the chaincodes released
+// to the host
+type ReleaseSpec struct {
+	Chaincodes []chaincode.Definition `json:"chaincodes,omitempty"`
+}
+
+// Spec is the desired state of the host in a commit of the repository
+type Spec struct {
+	Network NetworkSpec
+	Release ReleaseSpec
+}
+
+// unmarshalYAML decodes YAML with the JSON tags of the config types, so the
+// keys of the specs are the same as the ones of the files in $HOME/hlf-easy
+func unmarshalYAML(data []byte, v interface{}) error {
+	var value interface{}
+	err := yaml.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return nil
+	}
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonBytes, v)
+}
+
+// LoadSpec reads the network.yaml and release.yaml of a directory, both are
+// optional
+func LoadSpec(dir string) (*Spec, error) {
+	spec := &Spec{}
+	for file, v := range map[string]interface{}{
+		NetworkFile: &spec.Network,
+		ReleaseFile: &spec.Release,
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		err = unmarshalYAML(data, v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", file)
+		}
+	}
+	return spec, nil
+}
@@ -0,0 +1,321 @@
+// Package hlfeasytest runs a throwaway network of a single org for the
+// integration tests of the applications of Fabric, from their TestMain:
+//
+//	var network *hlfeasytest.Network
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(hlfeasytest.Run(m, hlfeasytest.Options{}, func(n *hlfeasytest.Network) {
+//			network = n
+//		}))
+//	}
+//
+// The network is the sandbox of hlf-easy with one org and one peer, created
+// by the hlf-easy binary in a temporary home directory on random free ports,
+// and removed with that directory once the tests are done. The peer and
+// orderer binaries of Fabric are the ones in the PATH
+package hlfeasytest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/contract"
+	"hlf-easy/node"
+	"hlf-easy/profile"
+	"hlf-easy/sandbox"
+	"math/rand"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// EnvBinary is the variable with the path of the hlf-easy binary, it's looked
+// up in the PATH when empty
+const EnvBinary = "HLF_EASY_BINARY"
+
+// Defaults of a test network
+const (
+	DefaultChannel = "test"
+	// DefaultTimeout is how long the network has to be up, with its
+	// chaincode committed
+	DefaultTimeout = 5 * time.Minute
+)
+
+// the base ports are picked in this range, above the default ports of the
+// nodes so they don't collide with a network of the host
+const (
+	minPort       = 20000
+	maxPort       = 60000
+	portAttempts  = 20
+	operationsGap = 100
+)
+
+// ErrNoBinary is returned when hlf-easy isn't installed, Run skips the tests
+// then
+var ErrNoBinary = errors.New("hlf-easy isn't in the PATH, set " + EnvBinary + " to its path")
+
+// Options of a test network
+type Options struct {
+	Channel string
+	// Sample is the sample chaincode committed on the channel, basic by
+	// default
+	Sample string
+	// Binary is the path of hlf-easy, EnvBinary or the PATH by default
+	Binary  string
+	Timeout time.Duration
+}
+
+// Network is a running test network
+type Network struct {
+	// Dir is the temporary home directory of the network, with the
+	// directories and the logs of its nodes
+	Dir       string
+	Channel   string
+	Chaincode string
+	PeerID    string
+	MSPID     string
+	// Endpoint is the external endpoint of the peer
+	Endpoint string
+	// Identity is the file of the admin identity managed for the peer, the
+	// transactions are signed with it
+	Identity string
+
+	binary string
+	// env is the environment of the test process before the network
+	env map[string]*string
+}
+
+// binaryPath returns the path of hlf-easy
+func binaryPath(opts Options) (string, error) {
+	if opts.Binary != "" {
+		return opts.Binary, nil
+	}
+	if binary := os.Getenv(EnvBinary); binary != "" {
+		return binary, nil
+	}
+	binary, err := exec.LookPath("hlf-easy")
+	if err != nil {
+		return "", ErrNoBinary
+	}
+	return binary, nil
+}
+
+// freeSpec returns the spec of a sandbox of one org and one peer on random
+// ports that are free on the host
+func freeSpec(channel string, sample string) (sandbox.Spec, error) {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i < portAttempts; i++ {
+		basePort := minPort + r.Intn(maxPort-minPort)
+		spec := sandbox.Spec{
+			Orgs:           1,
+			PeersPerOrg:    1,
+			Channel:        channel,
+			Sample:         sample,
+			BasePort:       basePort,
+			OperationsPort: basePort + operationsGap,
+		}
+		n, err := sandbox.New(spec)
+		if err != nil {
+			return sandbox.Spec{}, err
+		}
+		if n.CheckPorts() == nil {
+			return spec, nil
+		}
+	}
+	return sandbox.Spec{}, errors.Errorf("no free ports from %d to %d after %d attempts", minPort, maxPort, portAttempts)
+}
+
+// isolatedVars are the variables that select the home directory of hlf-easy,
+// they're replaced while the network runs
+func isolatedVars() []string {
+	return []string{profile.HomeEnv(), profile.EnvUserHome, profile.EnvProfile}
+}
+
+// isolate makes dir the home directory of the test process, of hlf-easy and
+// of the nodes it starts, the previous environment is returned to restore it
+func isolate(dir string) (map[string]*string, error) {
+	env := map[string]*string{}
+	for _, key := range isolatedVars() {
+		if value, ok := os.LookupEnv(key); ok {
+			env[key] = &value
+		} else {
+			env[key] = nil
+		}
+		if err := os.Unsetenv(key); err != nil {
+			return env, err
+		}
+	}
+	return env, os.Setenv(profile.HomeEnv(), dir)
+}
+
+// restore sets back the environment the network replaced
+func restore(env map[string]*string) error {
+	for key, value := range env {
+		var err error
+		if value == nil {
+			err = os.Unsetenv(key)
+		} else {
+			err = os.Setenv(key, *value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hlfEasy runs an hlf-easy command in the home directory of the network
+func (n *Network) hlfEasy(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, n.binary, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "hlf-easy %s failed: %s", strings.Join(args, " "), strings.TrimSpace(out.String()))
+	}
+	return nil
+}
+
+// Start creates and starts a test network, it's closed with Close. The
+// network changes the home directory of the test process until it's closed,
+// so one network runs at a time
+func Start(opts Options) (*Network, error) {
+	if opts.Channel == "" {
+		opts.Channel = DefaultChannel
+	}
+	if opts.Sample == "" {
+		opts.Sample = sandbox.DefaultSample
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	binary, err := binaryPath(opts)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := freeSpec(opts.Channel, opts.Sample)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "hlfeasytest-")
+	if err != nil {
+		return nil, err
+	}
+	n := &Network{Dir: dir, Channel: spec.Channel, binary: binary}
+	n.env, err = isolate(dir)
+	if err != nil {
+		restore(n.env)
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	err = n.hlfEasy(ctx, "sandbox", "up",
+		"--orgs", "1",
+		"--peers-per-org", "1",
+		"--channel", spec.Channel,
+		"--sample", spec.Sample,
+		"--base-port", strconv.Itoa(spec.BasePort),
+		"--operations-port", strconv.Itoa(spec.OperationsPort),
+	)
+	if err == nil {
+		err = n.load()
+	}
+	if err != nil {
+		// the directory is kept with the logs of the nodes
+		n.stop()
+		restore(n.env)
+		return nil, errors.Wrapf(err, "failed to start the test network, see the logs of its nodes in %s", dir)
+	}
+	return n, nil
+}
+
+// load reads the layout of the sandbox
+func (n *Network) load() error {
+	sb, err := sandbox.Load()
+	if err != nil {
+		return err
+	}
+	org := sb.Orgs[0]
+	peer := org.Peers[0]
+	identity, err := node.ManagedAdminIdentity(peer.ID)
+	if err != nil {
+		return err
+	}
+	n.Chaincode = sb.Chaincode.Name
+	n.PeerID = peer.ID
+	n.MSPID = org.MSPID
+	n.Endpoint = peer.Endpoint()
+	n.Identity = identity
+	return nil
+}
+
+// stop stops the nodes and the chaincode server of the network
+func (n *Network) stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	return n.hlfEasy(ctx, "sandbox", "down")
+}
+
+// Close stops the network, restores the home directory of the test process
+// and removes the directory of the network
+func (n *Network) Close() error {
+	err := n.stop()
+	if restoreErr := restore(n.env); err == nil {
+		err = restoreErr
+	}
+	if removeErr := os.RemoveAll(n.Dir); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// Options returns the options of a transaction of the chaincode of the
+// network, signed by the admin of the peer
+func (n *Network) Options(function string, args ...string) contract.Options {
+	return contract.Options{
+		PeerID:    n.PeerID,
+		Channel:   n.Channel,
+		Chaincode: n.Chaincode,
+		Function:  function,
+		Args:      args,
+	}
+}
+
+// Submit submits a transaction of the chaincode and waits for its commit
+func (n *Network) Submit(ctx context.Context, function string, args ...string) ([]byte, error) {
+	return contract.Invoke(ctx, n.Options(function, args...))
+}
+
+// Evaluate queries the chaincode on the peer
+func (n *Network) Evaluate(ctx context.Context, function string, args ...string) ([]byte, error) {
+	return contract.Query(ctx, n.Options(function, args...))
+}
+
+// Run is the body of a TestMain: it starts a network, passes it to setup,
+// runs the tests and closes the network. Without hlf-easy the tests are
+// skipped, they fail when the network doesn't start
+func Run(m *testing.M, opts Options, setup func(n *Network)) int {
+	n, err := Start(opts)
+	if errors.Is(err, ErrNoBinary) {
+		fmt.Fprintf(os.Stderr, "hlfeasytest: %v, skipping the tests\n", err)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hlfeasytest: %v\n", err)
+		return 1
+	}
+	setup(n)
+	code := m.Run()
+	if err := n.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "hlfeasytest: failed to close the test network: %v\n", err)
+		if code == 0 {
+			code = 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,62 @@
+package hlfeasytest
+
+import (
+	"hlf-easy/profile"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFreeSpec(t *testing.T) {
+	spec, err := freeSpec(DefaultChannel, "basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.BasePort < minPort || spec.BasePort >= maxPort || spec.Orgs != 1 || spec.PeersPerOrg != 1 {
+		t.Fatalf("expected one org and one peer on ports from %d, got %+v", minPort, spec)
+	}
+	if err := spec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsolate(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(profile.HomeEnv(), home)
+	t.Setenv(profile.EnvProfile, "org1")
+	dir := t.TempDir()
+	env, err := isolate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.UserHomeDir(); got != dir {
+		t.Fatalf("expected the home directory %s, got %s", dir, got)
+	}
+	if _, ok := os.LookupEnv(profile.EnvProfile); ok {
+		t.Fatal("expected the profile to be unset")
+	}
+	if err := restore(env); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.UserHomeDir(); got != home || os.Getenv(profile.EnvProfile) != "org1" {
+		t.Fatalf("expected the environment to be restored, got home %s", got)
+	}
+}
+
+func TestStartWithoutBinary(t *testing.T) {
+	t.Setenv(EnvBinary, "")
+	t.Setenv("PATH", t.TempDir())
+	if _, err := Start(Options{}); err != ErrNoBinary {
+		t.Fatalf("expected ErrNoBinary, got %v", err)
+	}
+	// the directory of a network that failed to start is kept
+	t.Setenv("TMPDIR", t.TempDir())
+	home, _ := os.UserHomeDir()
+	missing := filepath.Join(t.TempDir(), "hlf-easy")
+	if _, err := Start(Options{Binary: missing, Timeout: 1}); err == nil || err == ErrNoBinary {
+		t.Fatalf("expected hlf-easy %s to fail, got %v", missing, err)
+	}
+	if got, _ := os.UserHomeDir(); got != home {
+		t.Fatalf("expected the home directory %s to be restored, got %s", home, got)
+	}
+}
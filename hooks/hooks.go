@@ -0,0 +1,121 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/log"
+	"hlf-easy/notify"
+	"hlf-easy/utils"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Events of the lifecycle of a node the hooks run on
+const (
+	PreStart  = "pre-start"
+	PostStart = "post-start"
+	PreStop   = "pre-stop"
+	PostCrash = "post-crash"
+)
+
+// Events are all the events hooks can run on
+var Events = []string{PreStart, PostStart, PreStop, PostCrash}
+
+// DefaultTimeout is how long a hook can run before it's killed
+const DefaultTimeout = time.Minute
+
+// maxOutput is the size of the output of a failed script kept in its error
+const maxOutput = 4096
+
+// Validate checks the events and the targets of the hooks of a node
+func Validate(hooks config.NodeHooks) error {
+	for _, hook := range hooks {
+		if !utils.Contains(Events, hook.Event) {
+			return errors.Errorf("unknown hook event %s, expected one of %v", hook.Event, Events)
+		}
+		if (hook.Command == "") == (hook.URL == "") {
+			return errors.Errorf("the %s hook must have either a script or a URL", hook.Event)
+		}
+		if hook.URL != "" {
+			u, err := url.Parse(hook.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return errors.Errorf("invalid URL of the %s hook, it must be http or https", hook.Event)
+			}
+		}
+	}
+	return nil
+}
+
+// Run runs the hooks of an event of a node in order, it stops at the first
+// hook that fails. The details are passed to the scripts as HLF_EASY_*
+// variables and posted to the webhooks
+func Run(hooks config.NodeHooks, event string, kind string, id string, details map[string]string) error {
+	for _, hook := range hooks {
+		if hook.Event != event {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+		var err error
+		if hook.URL != "" {
+			err = post(hook, kind, id, details)
+		} else {
+			err = runScript(ctx, hook, kind, id, details)
+		}
+		cancel()
+		if err != nil {
+			return errors.Wrapf(err, "%s hook of %s %s failed", event, kind, id)
+		}
+	}
+	return nil
+}
+
+// RunLogged runs the hooks of an event that can't stop the operation of the
+// node, their failure is logged
+func RunLogged(hooks config.NodeHooks, event string, kind string, id string, details map[string]string) {
+	if err := Run(hooks, event, kind, id, details); err != nil {
+		log.Warnf("%v", err)
+	}
+}
+
+// runScript runs a script with the event and the node in its environment
+func runScript(ctx context.Context, hook config.Hook, kind string, id string, details map[string]string) error {
+	cmd := exec.CommandContext(ctx, hook.Command)
+	cmd.Env = append(os.Environ(),
+		"HLF_EASY_EVENT="+hook.Event,
+		"HLF_EASY_NODE_KIND="+kind,
+		"HLF_EASY_NODE_ID="+id,
+	)
+	keys := make([]string, 0, len(details))
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		cmd.Env = append(cmd.Env, "HLF_EASY_"+strings.ToUpper(key)+"="+details[key])
+	}
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return errors.Errorf("script %s didn't exit within %s", hook.Command, DefaultTimeout)
+	}
+	if err != nil {
+		if len(out) > maxOutput {
+			out = out[len(out)-maxOutput:]
+		}
+		return errors.Wrapf(err, "script %s: %s", hook.Command, strings.TrimSpace(string(out)))
+	}
+	log.Debugf("Hook %s of %s %s ran %s", hook.Event, kind, id, hook.Command)
+	return nil
+}
+
+// post posts the event to a webhook as a notification of the node
+func post(hook config.Hook, kind string, id string, details map[string]string) error {
+	event := notify.NewEvent(hook.Event, kind, id, fmt.Sprintf("Hook %s of %s %s", hook.Event, kind, id))
+	event.Details = details
+	return notify.Send(config.NotifyConfig{Webhooks: []config.WebhookConfig{{URL: hook.URL}}}, event)
+}
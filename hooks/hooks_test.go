@@ -0,0 +1,97 @@
+package hooks
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := config.NodeHooks{
+		{Event: PreStart, Command: "/usr/local/bin/mount-ledger.sh"},
+		{Event: PostCrash, URL: "https://hooks.example.com/crash"},
+	}
+	if err := Validate(valid); err != nil {
+		t.Fatal(err)
+	}
+	invalid := []config.Hook{
+		{Event: "post-stop", Command: "/bin/true"},
+		{Event: PreStart},
+		{Event: PreStart, Command: "/bin/true", URL: "https://hooks.example.com"},
+		{Event: PreStop, URL: "ftp://hooks.example.com"},
+	}
+	for _, hook := range invalid {
+		if err := Validate(config.NodeHooks{hook}); err == nil {
+			t.Errorf("expected %+v to be invalid", hook)
+		}
+	}
+}
+
+func writeScript(t *testing.T, content string) string {
+	script := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"+content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func TestRunScript(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	script := writeScript(t, `echo "$HLF_EASY_EVENT $HLF_EASY_NODE_KIND $HLF_EASY_NODE_ID $HLF_EASY_EXIT" >> `+out+"\n")
+	nodeHooks := config.NodeHooks{
+		{Event: PreStart, Command: script},
+		{Event: PostCrash, Command: script},
+	}
+	if err := Run(nodeHooks, PostCrash, "peer", "peer0", map[string]string{"exit": "exit status 3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(nodeHooks, PreStop, "peer", "peer0", nil); err != nil {
+		t.Fatal(err)
+	}
+	outBytes, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(outBytes) != "post-crash peer peer0 exit status 3\n" {
+		t.Errorf("expected only the post-crash hook to run, got %q", outBytes)
+	}
+
+	failing := writeScript(t, "echo volume not mounted\nexit 1\n")
+	err = Run(config.NodeHooks{{Event: PreStart, Command: failing}, {Event: PreStart, Command: script}}, PreStart, "orderer", "orderer0", nil)
+	if err == nil || !strings.Contains(err.Error(), "volume not mounted") {
+		t.Fatalf("expected the output of the failed script in the error, got %v", err)
+	}
+	outBytes, _ = os.ReadFile(out)
+	if strings.Contains(string(outBytes), "pre-start") {
+		t.Error("expected the hooks after a failed one not to run")
+	}
+}
+
+func TestRunWebhook(t *testing.T) {
+	var event map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/refuse" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	err := Run(config.NodeHooks{{Event: PostStart, URL: srv.URL + "/started"}}, PostStart, "peer", "peer0", map[string]string{"pid": "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event["type"] != PostStart || event["id"] != "peer0" || event["details"].(map[string]interface{})["pid"] != "42" {
+		t.Errorf("unexpected event %v", event)
+	}
+	if err := Run(config.NodeHooks{{Event: PreStart, URL: srv.URL + "/refuse"}}, PreStart, "peer", "peer0", nil); err == nil {
+		t.Error("expected the refused webhook to fail the hook")
+	}
+}
@@ -0,0 +1,74 @@
+package testca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// Cert describes a test certificate, the zero value is a leaf certificate
+// valid for an hour
+type Cert struct {
+	CommonName string
+	OUs        []string
+	DNSNames   []string
+	// NotAfter is one hour from now when not set
+	NotAfter time.Time
+	// CA certificates can sign certificates and CRLs, the self-signed ones are
+	// always CAs
+	CA bool
+}
+
+// Issue issues the certificate with a new P-256 key, signed by the parent or
+// self-signed when the parent is nil
+func Issue(t testing.TB, c Cert, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notAfter := c.NotAfter
+	if notAfter.IsZero() {
+		notAfter = time.Now().Add(time.Hour)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: c.CommonName, OrganizationalUnit: c.OUs},
+		DNSNames:     c.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if c.CA || parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crt, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crt, key
+}
+
+// NewCA returns a self-signed CA valid for an hour
+func NewCA(t testing.TB, commonName string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	return Issue(t, Cert{CommonName: commonName}, nil, nil)
+}
@@ -0,0 +1,136 @@
+package invite
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/utils"
+	"strings"
+	"time"
+)
+
+// Invite holds everything a peer on another machine needs to join the org,
+// it's signed by an admin of the org
+type Invite struct {
+	MSPID  string `json:"mspID"`
+	PeerID string `json:"peerID"`
+	// CAURL, CAName and CATLSCert are the Fabric CA the peer enrolls with
+	CAURL  string `json:"caURL"`
+	CAName string `json:"caName"`
+	// TLSCAName is the Fabric CA that issues the TLS certificate, CAName when empty
+	TLSCAName    string `json:"tlsCAName,omitempty"`
+	CATLSCert    string `json:"caTLSCert"`
+	EnrollID     string `json:"enrollID"`
+	EnrollSecret string `json:"enrollSecret"`
+	// CertPolicy of the peer certificates, only the SANs apply to a Fabric CA
+	CertPolicy config.CertificatePolicy `json:"certPolicy"`
+	// GossipBootstrap are the endpoints of the peers of the org
+	GossipBootstrap []string `json:"gossipBootstrap"`
+	// ExternalPort of the peer, the external endpoint is built with the first host of the peer
+	ExternalPort int       `json:"externalPort"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	// SignerCert is the PEM encoded certificate of the admin that signed the invite
+	SignerCert string `json:"signerCert"`
+}
+
+// Sign returns the token of the invite signed with the key of the admin
+func Sign(inv Invite, signerCert *x509.Certificate, signerKey *ecdsa.PrivateKey) (string, error) {
+	inv.SignerCert = string(utils.EncodeX509Certificate(signerCert))
+	payload, err := json.Marshal(inv)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, signerKey, digest[:])
+	if err != nil {
+		return "", err
+	}
+	encoding := base64.RawURLEncoding
+	return encoding.EncodeToString(payload) + "." + encoding.EncodeToString(signature), nil
+}
+
+// Parse verifies the signature and expiration of the token and returns the
+// invite with the certificate of the admin that signed it
+func Parse(token string, now time.Time) (*Invite, *x509.Certificate, error) {
+	payloadPart, signaturePart, found := strings.Cut(strings.TrimSpace(token), ".")
+	if !found {
+		return nil, nil, errors.New("invalid invite token")
+	}
+	encoding := base64.RawURLEncoding
+	payload, err := encoding.DecodeString(payloadPart)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid invite token payload")
+	}
+	signature, err := encoding.DecodeString(signaturePart)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid invite token signature")
+	}
+	inv := &Invite{}
+	err = json.Unmarshal(payload, inv)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid invite token payload")
+	}
+	signerCert, err := utils.ParseX509Certificate([]byte(inv.SignerCert))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid invite signer certificate")
+	}
+	pub, ok := signerCert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, nil, errors.New("invite signer certificate doesn't have an ECDSA key")
+	}
+	digest := sha256.Sum256(payload)
+	if !ecdsa.VerifyASN1(pub, digest[:], signature) {
+		return nil, nil, errors.New("invite token signature is not valid")
+	}
+	if !inv.ExpiresAt.IsZero() && now.After(inv.ExpiresAt) {
+		return nil, nil, errors.Errorf("invite expired at %s", inv.ExpiresAt.Format(time.RFC3339))
+	}
+	return inv, signerCert, nil
+}
+
+// Fingerprint returns the hex SHA-256 fingerprint of a certificate, used to
+// pin the CA of an invite out of band
+func Fingerprint(crt *x509.Certificate) string {
+	fingerprint := sha256.Sum256(crt.Raw)
+	return hex.EncodeToString(fingerprint[:])
+}
+
+// VerifyCAFingerprint checks the CA TLS certificate of an invite against the
+// fingerprint shared out of band. Everything else in the invite is trusted
+// through the CA, the signer included, so an invite whose CA isn't pinned is
+// refused: a forged invite could name the CA of an attacker
+func VerifyCAFingerprint(caTLSCert *x509.Certificate, fingerprint string) error {
+	if fingerprint == "" {
+		return errors.New("the fingerprint of the CA TLS certificate of the invite is required")
+	}
+	expected := strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))
+	if Fingerprint(caTLSCert) != expected {
+		return errors.Errorf("CA TLS certificate of the invite doesn't match fingerprint %s", fingerprint)
+	}
+	return nil
+}
+
+// VerifySigner checks that the invite was signed by an admin issued by the CA
+// the peer enrolled with, the CA must have been reached through its pinned
+// TLS certificate
+func VerifySigner(signerCert *x509.Certificate, caCert *x509.Certificate) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	_, err := signerCert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return errors.Wrap(err, "invite is not signed by an identity of the org CA")
+	}
+	if !utils.Contains(signerCert.Subject.OrganizationalUnit, "admin") {
+		return errors.New("invite is not signed by an admin of the org")
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package invite
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"hlf-easy/internal/testca"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCert issues a certificate whose common name is its OU
+func newTestCert(t *testing.T, ou string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	return testca.Issue(t, testca.Cert{CommonName: ou, OUs: []string{ou}}, parent, parentKey)
+}
+
+func TestSignParse(t *testing.T) {
+	caCert, caKey := newTestCert(t, "ca", nil, nil)
+	adminCert, adminKey := newTestCert(t, "admin", caCert, caKey)
+	now := time.Now()
+	inv := Invite{
+		MSPID:           "Org1MSP",
+		PeerID:          "peer1",
+		CAURL:           "https://ca.org1.example.com:7054",
+		EnrollID:        "peer1",
+		EnrollSecret:    "secret",
+		GossipBootstrap: []string{"peer0.org1.example.com:7051"},
+		ExternalPort:    7051,
+		ExpiresAt:       now.Add(time.Hour),
+	}
+	token, err := Sign(inv, adminCert, adminKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		parsed, signerCert, err := Parse(token, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.PeerID != "peer1" || parsed.EnrollSecret != "secret" || len(parsed.GossipBootstrap) != 1 {
+			t.Fatalf("unexpected invite %+v", parsed)
+		}
+		if err := VerifySigner(signerCert, caCert); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, _, err := Parse(token, now.Add(2*time.Hour))
+		if err == nil {
+			t.Fatal("expected an expired invite to be rejected")
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		payload, signature, _ := strings.Cut(token, ".")
+		other, err := Sign(Invite{PeerID: "peer2", ExpiresAt: now.Add(time.Hour)}, adminCert, adminKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherPayload, _, _ := strings.Cut(other, ".")
+		_, _, err = Parse(otherPayload+"."+signature, now)
+		if err == nil {
+			t.Fatal("expected a tampered invite to be rejected")
+		}
+		_, _, err = Parse(payload, now)
+		if err == nil {
+			t.Fatal("expected an invite without signature to be rejected")
+		}
+	})
+
+	t.Run("signer of another CA", func(t *testing.T) {
+		otherCACert, _ := newTestCert(t, "ca", nil, nil)
+		_, signerCert, err := Parse(token, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifySigner(signerCert, otherCACert); err == nil {
+			t.Fatal("expected a signer of another CA to be rejected")
+		}
+	})
+
+	t.Run("signer is not an admin", func(t *testing.T) {
+		clientCert, clientKey := newTestCert(t, "client", caCert, caKey)
+		clientToken, err := Sign(inv, clientCert, clientKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, signerCert, err := Parse(clientToken, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifySigner(signerCert, caCert); err == nil {
+			t.Fatal("expected a signer that isn't an admin to be rejected")
+		}
+	})
+}
+
+func TestVerifyCAFingerprint(t *testing.T) {
+	caTLSCert, _ := newTestCert(t, "tlsca", nil, nil)
+	fingerprint := Fingerprint(caTLSCert)
+	if err := VerifyCAFingerprint(caTLSCert, fingerprint); err != nil {
+		t.Fatal(err)
+	}
+	// the fingerprint can be copied with colons and in upper case
+	var pairs []string
+	for i := 0; i < len(fingerprint); i += 2 {
+		pairs = append(pairs, strings.ToUpper(fingerprint[i:i+2]))
+	}
+	if err := VerifyCAFingerprint(caTLSCert, strings.Join(pairs, ":")); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyCAFingerprint(caTLSCert, ""); err == nil {
+		t.Fatal("expected an invite without a pinned CA to be refused")
+	}
+	attackerCert, _ := newTestCert(t, "tlsca", nil, nil)
+	if err := VerifyCAFingerprint(attackerCert, fingerprint); err == nil {
+		t.Fatal("expected the CA of another invite to be refused")
+	}
+}
@@ -0,0 +1,60 @@
+package limits
+
+import (
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+)
+
+// Validate checks the limits of a node
+func Validate(l config.NodeLimits) error {
+	if l.CPUs < 0 {
+		return errors.New("CPU limit can't be negative")
+	}
+	if l.MemoryMB < 0 {
+		return errors.New("memory limit can't be negative")
+	}
+	if l.Nice < -20 || l.Nice > 19 {
+		return errors.Errorf("nice %d must be between -20 and 19", l.Nice)
+	}
+	return nil
+}
+
+// Enabled returns whether any limit is set
+func Enabled(l config.NodeLimits) bool {
+	return l.CPUs > 0 || l.MemoryMB > 0 || l.Nice != 0
+}
+
+// Usage is the usage of a process compared to its limits
+type Usage struct {
+	config.NodeLimits
+	// CPUPercent and MemoryPercent are the usage over the limit, 0 when the
+	// resource is not limited
+	CPUPercent    float64 `json:"cpuPercent"`
+	MemoryPercent float64 `json:"memoryPercent"`
+}
+
+// GetUsage compares the CPU percent, where 100 is one CPU, and the resident
+// memory of a process with its limits
+func GetUsage(l config.NodeLimits, cpuPercent float64, rssBytes uint64) *Usage {
+	usage := &Usage{NodeLimits: l}
+	if l.CPUs > 0 {
+		usage.CPUPercent = cpuPercent / l.CPUs
+	}
+	if l.MemoryMB > 0 {
+		usage.MemoryPercent = float64(rssBytes) / float64(l.MemoryMB*1024*1024) * 100
+	}
+	return usage
+}
+
+// Apply limits a running process, the returned release function removes the
+// resources created to enforce the limits once the process exits
+func Apply(name string, pid int, l config.NodeLimits) (func() error, error) {
+	if !Enabled(l) {
+		return func() error { return nil }, nil
+	}
+	err := Validate(l)
+	if err != nil {
+		return nil, err
+	}
+	return apply(name, pid, l)
+}
@@ -0,0 +1,83 @@
+//go:build linux
+
+package limits
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// cgroupRoot is the cgroup v2 hierarchy the node cgroups are created in
+var cgroupRoot = "/sys/fs/cgroup"
+
+// cpuPeriod is the period of cpu.max in microseconds
+const cpuPeriod = 100000
+
+func apply(name string, pid int, l config.NodeLimits) (func() error, error) {
+	release := func() error { return nil }
+	if l.CPUs > 0 || l.MemoryMB > 0 {
+		var err error
+		release, err = applyCgroup(name, pid, l)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if l.Nice != 0 {
+		err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, l.Nice)
+		if err != nil {
+			_ = release()
+			return nil, errors.Wrapf(err, "failed to set nice %d", l.Nice)
+		}
+	}
+	return release, nil
+}
+
+func applyCgroup(name string, pid int, l config.NodeLimits) (func() error, error) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return nil, errors.New("cgroup v2 is not mounted in " + cgroupRoot)
+	}
+	parent := filepath.Join(cgroupRoot, "hlf-easy")
+	err := os.MkdirAll(parent, 0755)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create cgroup %s, limits need write access to the cgroup hierarchy", parent)
+	}
+	// the controllers must be enabled in the parent to be used by the node cgroups
+	err = os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+cpu +memory"), 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to enable the cpu and memory controllers in %s", parent)
+	}
+	cgroupDir := filepath.Join(parent, name)
+	err = os.MkdirAll(cgroupDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	release := func() error {
+		return os.Remove(cgroupDir)
+	}
+	if l.CPUs > 0 {
+		quota := int64(l.CPUs * cpuPeriod)
+		err = os.WriteFile(filepath.Join(cgroupDir, "cpu.max"), []byte(fmt.Sprintf("%d %d", quota, cpuPeriod)), 0644)
+		if err != nil {
+			_ = release()
+			return nil, errors.Wrap(err, "failed to set the CPU limit")
+		}
+	}
+	if l.MemoryMB > 0 {
+		err = os.WriteFile(filepath.Join(cgroupDir, "memory.max"), []byte(strconv.FormatInt(l.MemoryMB*1024*1024, 10)), 0644)
+		if err != nil {
+			_ = release()
+			return nil, errors.Wrap(err, "failed to set the memory limit")
+		}
+	}
+	err = os.WriteFile(filepath.Join(cgroupDir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644)
+	if err != nil {
+		_ = release()
+		return nil, errors.Wrapf(err, "failed to move process %d to cgroup %s", pid, cgroupDir)
+	}
+	return release, nil
+}
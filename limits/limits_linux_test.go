@@ -0,0 +1,66 @@
+package limits
+
+import (
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyCgroup(t *testing.T) {
+	root := t.TempDir()
+	err := os.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu memory"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldRoot := cgroupRoot
+	cgroupRoot = root
+	defer func() { cgroupRoot = oldRoot }()
+
+	release, err := applyCgroup("peer-test", 1234, config.NodeLimits{CPUs: 1.5, MemoryMB: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cgroupDir := filepath.Join(root, "hlf-easy", "peer-test")
+	expected := map[string]string{
+		"cpu.max":      "150000 100000",
+		"memory.max":   "268435456",
+		"cgroup.procs": "1234",
+	}
+	for file, value := range expected {
+		content, err := os.ReadFile(filepath.Join(cgroupDir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != value {
+			t.Errorf("expected %s to be %q, got %q", file, value, content)
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(root, "hlf-easy", "cgroup.subtree_control"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "+cpu +memory" {
+		t.Errorf("expected the cpu and memory controllers to be enabled, got %q", content)
+	}
+	// the kernel removes the interface files of a cgroup, emulate it
+	for file := range expected {
+		_ = os.Remove(filepath.Join(cgroupDir, file))
+	}
+	if err := release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cgroupDir); !os.IsNotExist(err) {
+		t.Errorf("expected cgroup %s to be removed", cgroupDir)
+	}
+}
+
+func TestApplyCgroupWithoutCgroupV2(t *testing.T) {
+	oldRoot := cgroupRoot
+	cgroupRoot = t.TempDir()
+	defer func() { cgroupRoot = oldRoot }()
+	_, err := applyCgroup("peer-test", 1234, config.NodeLimits{CPUs: 1})
+	if err == nil {
+		t.Fatal("expected an error without cgroup v2")
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !windows
+
+package limits
+
+import (
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"syscall"
+)
+
+func apply(name string, pid int, l config.NodeLimits) (func() error, error) {
+	if l.CPUs > 0 || l.MemoryMB > 0 {
+		return nil, errors.New("CPU and memory limits are only supported on Linux and Windows")
+	}
+	err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, l.Nice)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to set nice %d", l.Nice)
+	}
+	return func() error { return nil }, nil
+}
@@ -0,0 +1,54 @@
+package limits
+
+import (
+	"hlf-easy/config"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := []config.NodeLimits{
+		{},
+		{CPUs: 0.5, MemoryMB: 512, Nice: 10},
+		{Nice: -20},
+	}
+	for _, l := range valid {
+		if err := Validate(l); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", l, err)
+		}
+	}
+	invalid := []config.NodeLimits{
+		{CPUs: -1},
+		{MemoryMB: -1},
+		{Nice: 20},
+		{Nice: -21},
+	}
+	for _, l := range invalid {
+		if err := Validate(l); err == nil {
+			t.Errorf("expected %+v to be invalid", l)
+		}
+	}
+}
+
+func TestGetUsage(t *testing.T) {
+	usage := GetUsage(config.NodeLimits{CPUs: 2, MemoryMB: 100}, 50, 25*1024*1024)
+	if usage.CPUPercent != 25 {
+		t.Errorf("expected 25%% of the CPU limit, got %v", usage.CPUPercent)
+	}
+	if usage.MemoryPercent != 25 {
+		t.Errorf("expected 25%% of the memory limit, got %v", usage.MemoryPercent)
+	}
+	usage = GetUsage(config.NodeLimits{Nice: 5}, 50, 25*1024*1024)
+	if usage.CPUPercent != 0 || usage.MemoryPercent != 0 {
+		t.Errorf("expected no usage without CPU and memory limits, got %+v", usage)
+	}
+}
+
+func TestApplyWithoutLimits(t *testing.T) {
+	release, err := Apply("peer-test", 0, config.NodeLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := release(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,96 @@
+//go:build windows
+
+package limits
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+	"hlf-easy/config"
+	"runtime"
+	"unsafe"
+)
+
+const (
+	jobObjectCPURateControlEnable  = 0x1
+	jobObjectCPURateControlHardCap = 0x4
+)
+
+// jobObjectCPURateControlInformation is JOBOBJECT_CPU_RATE_CONTROL_INFORMATION
+// with the CpuRate member of its union
+type jobObjectCPURateControlInformation struct {
+	ControlFlags uint32
+	CPURate      uint32
+}
+
+func apply(name string, pid int, l config.NodeLimits) (func() error, error) {
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE|windows.PROCESS_SET_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open process %d", pid)
+	}
+	defer windows.CloseHandle(process)
+	job, err := windows.CreateJobObject(nil, windows.StringToUTF16Ptr("hlf-easy-"+name))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create job object")
+	}
+	release := func() error {
+		return windows.CloseHandle(job)
+	}
+	if l.MemoryMB > 0 {
+		info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{}
+		info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_PROCESS_MEMORY
+		info.ProcessMemoryLimit = uintptr(l.MemoryMB * 1024 * 1024)
+		_, err = windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation, uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)))
+		if err != nil {
+			_ = release()
+			return nil, errors.Wrap(err, "failed to set the memory limit")
+		}
+	}
+	if l.CPUs > 0 {
+		// the rate is the share of all the CPUs of the host in 1/10000
+		rate := uint32(l.CPUs / float64(runtime.NumCPU()) * 10000)
+		if rate < 1 {
+			rate = 1
+		}
+		if rate > 10000 {
+			rate = 10000
+		}
+		info := jobObjectCPURateControlInformation{
+			ControlFlags: jobObjectCPURateControlEnable | jobObjectCPURateControlHardCap,
+			CPURate:      rate,
+		}
+		_, err = windows.SetInformationJobObject(job, windows.JobObjectCpuRateControlInformation, uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)))
+		if err != nil {
+			_ = release()
+			return nil, errors.Wrap(err, "failed to set the CPU limit")
+		}
+	}
+	err = windows.AssignProcessToJobObject(job, process)
+	if err != nil {
+		_ = release()
+		return nil, errors.Wrapf(err, "failed to assign process %d to the job object", pid)
+	}
+	if l.Nice != 0 {
+		err = windows.SetPriorityClass(process, priorityClass(l.Nice))
+		if err != nil {
+			_ = release()
+			return nil, errors.Wrap(err, "failed to set the priority class")
+		}
+	}
+	return release, nil
+}
+
+// priorityClass maps a nice value to the closest Windows priority class
+func priorityClass(nice int) uint32 {
+	switch {
+	case nice >= 15:
+		return windows.IDLE_PRIORITY_CLASS
+	case nice > 0:
+		return windows.BELOW_NORMAL_PRIORITY_CLASS
+	case nice <= -15:
+		return windows.HIGH_PRIORITY_CLASS
+	case nice < 0:
+		return windows.ABOVE_NORMAL_PRIORITY_CLASS
+	default:
+		return windows.NORMAL_PRIORITY_CLASS
+	}
+}
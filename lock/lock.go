@@ -0,0 +1,249 @@
+package lock
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/process"
+	"hlf-easy/log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Holder is who holds the lock of a node and for what operation
+type Holder struct {
+	PID       int       `json:"pid"`
+	Actor     string    `json:"actor"`
+	Operation string    `json:"operation"`
+	Since     time.Time `json:"since"`
+	// Token lets the holder run nested operations on the node, e.g. an
+	// upgrade stopping the peer through its management API
+	Token string `json:"token,omitempty"`
+}
+
+func (h Holder) String() string {
+	return fmt.Sprintf("%s (pid %d) for %s since %s", h.Actor, h.PID, h.Operation, h.Since.Format(time.RFC3339))
+}
+
+// LockedError is returned when the lock of a node is held by another operation
+type LockedError struct {
+	Kind   string
+	ID     string
+	Holder Holder
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s %s is locked by %s", e.Kind, e.ID, e.Holder)
+}
+
+// Lock is the held lock of a node
+type Lock struct {
+	Kind   string
+	ID     string
+	Holder Holder
+	path   string
+	// nested locks are released by the lock they're nested in
+	nested bool
+}
+
+// Header carries the token of the lock held by the caller of a management
+// API, so the holder can run the actions of the node
+const Header = "X-Hlf-Easy-Lock"
+
+// Wait is how long the CLI commands queue for the lock of a node
+var Wait = 30 * time.Second
+
+// pollInterval is how often a queued operation checks the lock
+var pollInterval = 200 * time.Millisecond
+
+// staleWrite is how long a lock can stay unparsable, a holder that takes
+// longer to write it crashed while writing it
+var staleWrite = 10 * time.Second
+
+func getLockPath(kind string, id string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if id == "" || id == "." || id == ".." || filepath.Base(id) != id {
+		return "", errors.Errorf("invalid %s id %q", kind, id)
+	}
+	return filepath.Join(home, "hlf-easy/locks", kind, id+".json"), nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// readHolder returns the holder of the lock, nil when the lock is free. The
+// lock of a process that no longer runs, or that stayed unparsable longer than
+// staleWrite, is removed
+func readHolder(lockPath string) (*Holder, error) {
+	holderBytes, err := os.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	h := &Holder{}
+	if err := json.Unmarshal(holderBytes, h); err != nil {
+		info, err := os.Stat(lockPath)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if time.Since(info.ModTime()) < staleWrite {
+			// the holder is still writing the lock
+			return &Holder{Operation: "unknown", Since: info.ModTime()}, nil
+		}
+		log.Warnf("Removing the lock %s, it's unparsable since %s", lockPath, info.ModTime().Format(time.RFC3339))
+		return nil, removeStale(lockPath, holderBytes)
+	}
+	if alive, err := process.PidExists(int32(h.PID)); err == nil && !alive {
+		log.Warnf("Removing the lock %s of %s, its process is gone", lockPath, h)
+		return nil, removeStale(lockPath, holderBytes)
+	}
+	return h, nil
+}
+
+// removeStale removes a stale lock only if it still has the stale content.
+// The lock is renamed first so another process can't acquire it between the
+// check and the removal, a lock acquired since it was read is put back
+func removeStale(lockPath string, stale []byte) error {
+	suffix, err := newToken()
+	if err != nil {
+		return err
+	}
+	stalePath := lockPath + ".stale-" + suffix
+	err = os.Rename(lockPath, stalePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	current, err := os.ReadFile(stalePath)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(current, stale) {
+		return os.Remove(stalePath)
+	}
+	// a link doesn't replace a lock acquired meanwhile
+	err = os.Link(stalePath, lockPath)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+	if os.IsExist(err) {
+		log.Warnf("The lock %s was acquired while it was put back, its previous holder lost it", lockPath)
+	}
+	return os.Remove(stalePath)
+}
+
+// Get returns the holder of the lock of a node, nil when it's free
+func Get(kind string, id string) (*Holder, error) {
+	lockPath, err := getLockPath(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	return readHolder(lockPath)
+}
+
+// Acquire takes the lock of a node for an operation. A lock held by another
+// operation is waited for up to wait, the operation is queued and reports who
+// holds the lock. A lock held with the token is nested in the held one
+func Acquire(kind string, id string, operation string, actor string, wait time.Duration, token string) (*Lock, error) {
+	return AcquireContext(context.Background(), kind, id, operation, actor, wait, token)
+}
+
+// AcquireContext is Acquire with a queued operation stopping to wait when
+// the context is done
+func AcquireContext(ctx context.Context, kind string, id string, operation string, actor string, wait time.Duration, token string) (*Lock, error) {
+	lockPath, err := getLockPath(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0700); err != nil {
+		return nil, err
+	}
+	newHolder := Holder{PID: os.Getpid(), Actor: actor, Operation: operation}
+	newHolder.Token, err = newToken()
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(wait)
+	queued := false
+	for {
+		newHolder.Since = time.Now()
+		holderBytes, err := json.Marshal(newHolder)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_, err = f.Write(holderBytes)
+			closeErr := f.Close()
+			if err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(lockPath)
+				return nil, err
+			}
+			return &Lock{Kind: kind, ID: id, Holder: newHolder, path: lockPath}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		h, err := readHolder(lockPath)
+		if err != nil {
+			return nil, err
+		}
+		if h == nil {
+			continue
+		}
+		if token != "" && h.Token == token {
+			return &Lock{Kind: kind, ID: id, Holder: *h, path: lockPath, nested: true}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, &LockedError{Kind: kind, ID: id, Holder: *h}
+		}
+		if !queued {
+			log.Infof("%s of %s %s is queued, the node is locked by %s", operation, kind, id, h)
+			queued = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "%s of %s %s stopped waiting for the lock held by %s", operation, kind, id, h)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Release frees the lock, a nested lock is kept until the lock it's nested
+// in is released
+func (l *Lock) Release() error {
+	if l.nested {
+		return nil
+	}
+	h, err := readHolder(l.path)
+	if err != nil {
+		return err
+	}
+	if h == nil || h.Token != l.Holder.Token {
+		return errors.Errorf("the lock of %s %s was taken over", l.Kind, l.ID)
+	}
+	return os.Remove(l.path)
+}
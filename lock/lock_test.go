@@ -0,0 +1,176 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	l, err := Acquire("peer", "peer1", "peer.upgrade", "user:alice", 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Acquire("peer", "peer1", "peer.init", "user:bob", 0, "")
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("expected the node to be locked, got %v", err)
+	}
+	if locked.Holder.Actor != "user:alice" || locked.Holder.Operation != "peer.upgrade" {
+		t.Errorf("unexpected holder %+v", locked.Holder)
+	}
+	if !strings.Contains(err.Error(), "peer peer1 is locked by user:alice") || !strings.Contains(err.Error(), "for peer.upgrade") {
+		t.Errorf("expected the error to tell who holds the lock and for what, got %v", err)
+	}
+
+	// the other nodes aren't locked
+	other, err := Acquire("peer", "peer2", "peer.init", "user:bob", 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the holder runs nested operations with the token of the lock
+	nested, err := Acquire("peer", "peer1", "peer.stop", "token:ops", 0, l.Holder.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nested.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if h, err := Get("peer", "peer1"); err != nil || h == nil || h.Operation != "peer.upgrade" {
+		t.Fatalf("expected the nested lock to keep the lock held, got %+v %v", h, err)
+	}
+
+	// a queued operation gets the lock once it's released
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		l.Release()
+	}()
+	queued, err := Acquire("peer", "peer1", "peer.init", "user:bob", 5*time.Second, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued.Holder.Operation != "peer.init" {
+		t.Errorf("unexpected holder %+v", queued.Holder)
+	}
+	if err := queued.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if h, err := Get("peer", "peer1"); err != nil || h != nil {
+		t.Fatalf("expected the lock to be free, got %+v %v", h, err)
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	lockPath := filepath.Join(home, "hlf-easy/locks/orderer/orderer1.json")
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	// no process has the max pid of linux
+	holderBytes, err := json.Marshal(Holder{PID: 4194304, Actor: "user:alice", Operation: "orderer.init", Since: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockPath, holderBytes, 0600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Acquire("orderer", "orderer1", "orderer.init", "user:bob", 0, "")
+	if err != nil {
+		t.Fatalf("expected the lock of a gone process to be taken, got %v", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnparsableLock(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	lockPath := filepath.Join(home, "hlf-easy/locks/peer/peer1.json")
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	// a holder that crashed while writing the lock
+	if err := os.WriteFile(lockPath, []byte(`{"pid":`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Acquire("peer", "peer1", "peer.init", "user:bob", 0, "")
+	var locked *LockedError
+	if !errors.As(err, &locked) || locked.Holder.Operation != "unknown" {
+		t.Fatalf("expected a lock being written to be held, got %v", err)
+	}
+	old := time.Now().Add(-2 * staleWrite)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Acquire("peer", "peer1", "peer.init", "user:bob", 0, "")
+	if err != nil {
+		t.Fatalf("expected an unparsable lock older than %s to be taken, got %v", staleWrite, err)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoveStaleKeepsNewLock(t *testing.T) {
+	home := t.TempDir()
+	lockPath := filepath.Join(home, "peer1.json")
+	if err := os.WriteFile(lockPath, []byte(`{"pid":2}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// the lock was acquired again since the stale one was read
+	if err := removeStale(lockPath, []byte(`{"pid":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	current, err := os.ReadFile(lockPath)
+	if err != nil || string(current) != `{"pid":2}` {
+		t.Fatalf("expected the new lock to be kept, got %s %v", current, err)
+	}
+	if err := removeStale(lockPath, []byte(`{"pid":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the stale lock to be removed, got %v", err)
+	}
+	entries, err := os.ReadDir(home)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected no renamed lock to be left, got %v %v", entries, err)
+	}
+}
+
+func TestInvalidID(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := Acquire("peer", "../peer1", "peer.init", "user:bob", 0, ""); err == nil {
+		t.Fatal("expected an invalid id to be refused")
+	}
+}
+
+func TestAcquireContext(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	l, err := Acquire("peer", "peer1", "peer.upgrade", "user:alice", 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Release()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = AcquireContext(ctx, "peer", "peer1", "peer.init", "user:bob", time.Minute, "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the queued operation to stop with its context, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("expected the queued operation to stop when its context is done, it waited %s", time.Since(start))
+	}
+}
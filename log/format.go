@@ -0,0 +1,92 @@
+package log
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"sync"
+)
+
+// Formats of the logs of hlf-easy
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Fields shared by the logs of the commands, so the log pipelines index the
+// logs of hlf-easy alongside the ones of the nodes
+const (
+	// FieldNodeID is the ID of the peer or orderer the log is about
+	FieldNodeID = "node_id"
+	// FieldOp is the operation, the path of the command, e.g. peer.start
+	FieldOp = "op"
+	// FieldDuration is the duration of the operation in seconds
+	FieldDuration = "duration"
+)
+
+// Format is the format set with the global --log-format flag
+var Format = FormatText
+
+// AddFlag adds the --log-format flag
+func AddFlag(f *pflag.FlagSet) {
+	f.StringVar(&Format, "log-format", FormatText, "Format of the logs of hlf-easy: text or json")
+}
+
+// Configure sets the formatter of the standard logger of logrus for a format,
+// the JSON logs have the time, level and msg keys of Loki and ELK
+func Configure(format string) error {
+	switch format {
+	case FormatText:
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	case FormatJSON:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return errors.Errorf("invalid log format %s, expected text or json", format)
+	}
+	return nil
+}
+
+var (
+	fieldsMu    sync.RWMutex
+	fields      = logrus.Fields{}
+	installHook sync.Once
+)
+
+// fieldsHook adds the fields set with SetFields to the entries that don't
+// set them
+type fieldsHook struct{}
+
+func (fieldsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (fieldsHook) Fire(entry *logrus.Entry) error {
+	for k, v := range GetFields() {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+	return nil
+}
+
+// SetFields replaces the fields added to all the logs of the standard logger
+// of logrus, e.g. the op and node_id of the command being run
+func SetFields(f logrus.Fields) {
+	installHook.Do(func() {
+		logrus.AddHook(fieldsHook{})
+	})
+	copied := logrus.Fields{}
+	for k, v := range f {
+		copied[k] = v
+	}
+	fieldsMu.Lock()
+	defer fieldsMu.Unlock()
+	fields = copied
+}
+
+// GetFields returns the fields added to all the logs
+func GetFields() logrus.Fields {
+	fieldsMu.RLock()
+	defer fieldsMu.RUnlock()
+	return fields
+}
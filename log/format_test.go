@@ -0,0 +1,54 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"os"
+	"testing"
+)
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	defer logrus.SetOutput(os.Stderr)
+	if err := Configure(FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	defer Configure(FormatText)
+	SetFields(logrus.Fields{FieldOp: "peer.start", FieldNodeID: "peer0"})
+	defer SetFields(nil)
+
+	logrus.WithField(FieldDuration, 1.5).Infof("Peer %s started", "peer0")
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a JSON log, got %q: %v", buf.String(), err)
+	}
+	expected := map[string]interface{}{
+		"level":       "info",
+		"msg":         "Peer peer0 started",
+		FieldOp:       "peer.start",
+		FieldNodeID:   "peer0",
+		FieldDuration: 1.5,
+	}
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Errorf("expected %s to be %v, got %v", k, v, entry[k])
+		}
+	}
+	if _, ok := entry["time"]; !ok {
+		t.Error("expected the time of the log")
+	}
+
+	buf.Reset()
+	logrus.WithField(FieldNodeID, "peer1").Info("Peer peer1 started")
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry[FieldNodeID] != "peer1" {
+		t.Errorf("expected the field of the entry to be kept, got %v", entry[FieldNodeID])
+	}
+	if err := Configure("xml"); err == nil {
+		t.Error("expected the format to be refused")
+	}
+}
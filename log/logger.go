@@ -0,0 +1,76 @@
+package log
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"sync"
+)
+
+// Logger receives the logs of the packages used by the Go API, the programs
+// embedding hlf-easy plug their own logger with SetLogger
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+var (
+	mu     sync.RWMutex
+	logger Logger = logrus.StandardLogger()
+)
+
+// SetLogger replaces the logger, nil restores the standard logger of logrus
+func SetLogger(l Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	if l == nil {
+		l = logrus.StandardLogger()
+	}
+	logger = l
+}
+
+// GetLogger returns the current logger
+func GetLogger() Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return logger
+}
+
+// Fields are the structured fields of a log, e.g. FieldNodeID
+type Fields map[string]interface{}
+
+// WithFields returns the logger adding fields to its logs. A logger set with
+// SetLogger that doesn't take fields like logrus does logs without them
+func WithFields(fields Fields) Logger {
+	l := GetLogger()
+	if fl, ok := l.(logrus.FieldLogger); ok {
+		return fl.WithFields(logrus.Fields(fields))
+	}
+	return l
+}
+
+// Debugf logs a message at the debug level
+func Debugf(format string, args ...interface{}) {
+	GetLogger().Debugf(format, args...)
+}
+
+// Info logs the operands at the info level, formatted like fmt.Sprint
+func Info(args ...interface{}) {
+	GetLogger().Infof("%s", fmt.Sprint(args...))
+}
+
+// Infof logs a message at the info level
+func Infof(format string, args ...interface{}) {
+	GetLogger().Infof(format, args...)
+}
+
+// Warnf logs a message at the warning level
+func Warnf(format string, args ...interface{}) {
+	GetLogger().Warnf(format, args...)
+}
+
+// Errorf logs a message at the error level
+func Errorf(format string, args ...interface{}) {
+	GetLogger().Errorf(format, args...)
+}
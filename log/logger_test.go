@@ -0,0 +1,60 @@
+package log
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"testing"
+)
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (r *recordingLogger) record(level string, format string, args ...interface{}) {
+	r.lines = append(r.lines, level+" "+fmt.Sprintf(format, args...))
+}
+
+func (r *recordingLogger) Debugf(format string, args ...interface{}) {
+	r.record("debug", format, args...)
+}
+func (r *recordingLogger) Infof(format string, args ...interface{}) {
+	r.record("info", format, args...)
+}
+func (r *recordingLogger) Warnf(format string, args ...interface{}) {
+	r.record("warn", format, args...)
+}
+func (r *recordingLogger) Errorf(format string, args ...interface{}) {
+	r.record("error", format, args...)
+}
+
+func TestSetLogger(t *testing.T) {
+	r := &recordingLogger{}
+	SetLogger(r)
+	defer SetLogger(nil)
+	Info("peer0 ", "started")
+	Warnf("peer %s not stopped", "peer0")
+	Errorf("lock of %s lost", "peer0")
+	expected := []string{"info peer0 started", "warn peer peer0 not stopped", "error lock of peer0 lost"}
+	if fmt.Sprint(r.lines) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, r.lines)
+	}
+	SetLogger(nil)
+	if GetLogger() != logrus.StandardLogger() {
+		t.Error("expected nil to restore the standard logger")
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	r := &recordingLogger{}
+	SetLogger(r)
+	defer SetLogger(nil)
+	WithFields(Fields{FieldNodeID: "peer0"}).Warnf("peer %s not stopped", "peer0")
+	if fmt.Sprint(r.lines) != "[warn peer peer0 not stopped]" {
+		t.Errorf("expected the logger without fields to log, got %v", r.lines)
+	}
+	SetLogger(nil)
+	entry, ok := WithFields(Fields{FieldNodeID: "peer0"}).(*logrus.Entry)
+	if !ok || entry.Data[FieldNodeID] != "peer0" {
+		t.Errorf("expected a logrus entry with the node ID, got %v", entry)
+	}
+}
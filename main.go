@@ -4,6 +4,7 @@ import (
 	"embed"
 	"github.com/sirupsen/logrus"
 	"hlf-easy/cmd"
+	"hlf-easy/errdefs"
 
 	"os"
 )
@@ -25,6 +26,6 @@ func main() {
 	// set global log level
 	logrus.SetLevel(ll)
 	if err := cmd.NewCmdHLFEasy(views).Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(errdefs.ExitCode(err))
 	}
 }
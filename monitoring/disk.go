@@ -0,0 +1,95 @@
+package monitoring
+
+import (
+	"context"
+	"github.com/shirou/gopsutil/disk"
+	"hlf-easy/log"
+	"hlf-easy/node"
+	"sync"
+	"time"
+)
+
+// DiskMonitor measures the ledger of a peer and the filesystem hosting it, a
+// full filesystem stops the peer from committing blocks
+type DiskMonitor struct {
+	peerID    string
+	dataDir   string
+	threshold float64
+	mu        sync.Mutex
+	usage     *node.DiskUsage
+	// last is the last successful check, a transient error doesn't reset
+	// the growth
+	last *node.DiskUsage
+}
+
+// NewDiskMonitor returns the monitor of the disk usage of a peer, dataDir is
+// its fileSystemPath
+func NewDiskMonitor(peerID string, dataDir string, threshold float64) *DiskMonitor {
+	return &DiskMonitor{
+		peerID:    peerID,
+		dataDir:   dataDir,
+		threshold: threshold,
+	}
+}
+
+// DiskUsage returns the last disk usage of the peer, nil before the first
+// check
+func (m *DiskMonitor) DiskUsage() *node.DiskUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.usage == nil {
+		return nil
+	}
+	usage := *m.usage
+	return &usage
+}
+
+// Run measures the disk usage every interval until the context is done
+func (m *DiskMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.check(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check measures the ledger and the filesystem, the growth is computed
+// against the last successful check
+func (m *DiskMonitor) check(now time.Time) {
+	usage := &node.DiskUsage{Path: m.dataDir, Threshold: m.threshold, CheckedAt: now}
+	ledgerBytes, err := node.DirSize(m.dataDir)
+	if err == nil {
+		usage.LedgerBytes = ledgerBytes
+		var fsUsage *disk.UsageStat
+		fsUsage, err = disk.Usage(m.dataDir)
+		if err == nil {
+			usage.TotalBytes = fsUsage.Total
+			usage.FreeBytes = fsUsage.Free
+			usage.UsedPercent = fsUsage.UsedPercent
+			usage.High = fsUsage.UsedPercent > m.threshold
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		usage.Error = err.Error()
+		m.usage = usage
+		return
+	}
+	if m.last != nil {
+		usage.GrowthPerHour = node.LedgerGrowth(m.last.LedgerBytes, usage.LedgerBytes, now.Sub(m.last.CheckedAt))
+	}
+	wasHigh := m.last != nil && m.last.High
+	if usage.High && !wasHigh {
+		log.Warnf("The filesystem of the ledger of peer %s is %.0f%% used, over %.0f%%, with %.1f GiB free, free some space before the peer can't commit blocks", m.peerID, usage.UsedPercent, m.threshold, float64(usage.FreeBytes)/(1<<30))
+	} else if !usage.High && wasHigh {
+		log.Infof("The filesystem of the ledger of peer %s is back to %.0f%% used", m.peerID, usage.UsedPercent)
+	}
+	m.usage = usage
+	m.last = usage
+}
@@ -0,0 +1,63 @@
+package monitoring
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskMonitor(t *testing.T) {
+	dataDir := t.TempDir()
+	blockFile := filepath.Join(dataDir, "ledgersData/chains/chains/mychannel/blockfile_000000")
+	if err := os.MkdirAll(filepath.Dir(blockFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(blockFile, make([]byte, 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewDiskMonitor("peer0", dataDir, 100)
+	if m.DiskUsage() != nil {
+		t.Fatal("expected no disk usage before the first check")
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.check(now)
+	usage := m.DiskUsage()
+	if usage.Error != "" || usage.LedgerBytes != 1000 || usage.TotalBytes == 0 || usage.High {
+		t.Fatalf("expected the ledger to be measured under the threshold, got %+v", usage)
+	}
+	if usage.GrowthPerHour != 0 {
+		t.Fatalf("expected no growth on the first check, got %v", usage.GrowthPerHour)
+	}
+
+	if err := os.WriteFile(blockFile, make([]byte, 3000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.check(now.Add(30 * time.Minute))
+	if usage := m.DiskUsage(); usage.LedgerBytes != 3000 || usage.GrowthPerHour != 4000 {
+		t.Fatalf("expected the ledger to grow by 4000 bytes per hour, got %+v", usage)
+	}
+
+	// a failed check is reported and doesn't reset the growth
+	if err := os.Rename(dataDir, dataDir+".moved"); err != nil {
+		t.Fatal(err)
+	}
+	m.check(now.Add(time.Hour))
+	if usage := m.DiskUsage(); usage.Error == "" {
+		t.Fatalf("expected an error for a missing data directory, got %+v", usage)
+	}
+	if err := os.Rename(dataDir+".moved", dataDir); err != nil {
+		t.Fatal(err)
+	}
+	m.check(now.Add(90 * time.Minute))
+	if usage := m.DiskUsage(); usage.Error != "" || usage.GrowthPerHour != 0 {
+		t.Fatalf("expected the growth against the last successful check, got %+v", usage)
+	}
+
+	m = NewDiskMonitor("peer0", dataDir, 0)
+	m.check(now)
+	if usage := m.DiskUsage(); !usage.High {
+		t.Fatalf("expected the filesystem to be over a threshold of 0, got %+v", usage)
+	}
+}
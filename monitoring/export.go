@@ -0,0 +1,96 @@
+package monitoring
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files written by Export
+const (
+	PrometheusFile = "prometheus.yml"
+	FederationFile = "federation.yml"
+	DashboardFile  = "grafana-dashboard.json"
+)
+
+// ExportOptions configures the monitoring config of the host
+type ExportOptions struct {
+	// OutputDir is the directory the files are written to
+	OutputDir string
+	// Host is the address Prometheus reaches the nodes listening on all the
+	// interfaces on
+	Host string
+	// ScrapeInterval is how often Prometheus scrapes the nodes, 15s when empty
+	ScrapeInterval string
+	// FederateAddress is the address of the Prometheus of the host, a
+	// federation config for a central Prometheus is written when it's set
+	FederateAddress string
+}
+
+// Exported lists what was exported
+type Exported struct {
+	Dir     string   `json:"dir"`
+	Files   []string `json:"files"`
+	Targets []Target `json:"targets"`
+	// Skipped are the nodes that aren't scraped and why
+	Skipped []string `json:"skipped"`
+}
+
+// Export writes the Prometheus config scraping the operations endpoints of
+// the nodes of the host and the Grafana dashboard of their metrics
+func Export(opts ExportOptions) (*Exported, error) {
+	if opts.ScrapeInterval != "" {
+		if _, err := time.ParseDuration(opts.ScrapeInterval); err != nil {
+			return nil, errors.Wrapf(err, "invalid scrape interval %q", opts.ScrapeInterval)
+		}
+	}
+	if opts.Host == "" {
+		opts.Host = "127.0.0.1"
+	}
+	targets, skipped, err := ListTargets(opts.Host)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(opts.OutputDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	exported := &Exported{Dir: opts.OutputDir, Files: []string{}, Targets: targets, Skipped: skipped}
+	write := func(name string, content []byte) error {
+		path := filepath.Join(opts.OutputDir, name)
+		err := os.WriteFile(path, content, 0644)
+		if err != nil {
+			return err
+		}
+		exported.Files = append(exported.Files, path)
+		return nil
+	}
+	prometheusConfig, err := PrometheusConfig(targets, opts.ScrapeInterval)
+	if err != nil {
+		return nil, err
+	}
+	err = write(PrometheusFile, prometheusConfig)
+	if err != nil {
+		return nil, err
+	}
+	if opts.FederateAddress != "" {
+		federationConfig, err := FederationConfig(opts.FederateAddress, opts.ScrapeInterval)
+		if err != nil {
+			return nil, err
+		}
+		err = write(FederationFile, federationConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dashboard, err := GrafanaDashboard()
+	if err != nil {
+		return nil, err
+	}
+	err = write(DashboardFile, dashboard)
+	if err != nil {
+		return nil, err
+	}
+	return exported, nil
+}
@@ -0,0 +1,140 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestNode(t *testing.T, home string, kind string, id string, files map[string]interface{}) string {
+	t.Helper()
+	nodeDir := filepath.Join(home, "hlf-easy", kind+"s", id)
+	err := os.MkdirAll(nodeDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		contentBytes, err := json.Marshal(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(nodeDir, name), contentBytes, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return nodeDir
+}
+
+func TestExport(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeTestNode(t, home, KindPeer, "peer0", map[string]interface{}{
+		"init.json": config.PeerInitOptions{ID: "peer0", MSPID: "Org1MSP"},
+	})
+	peer1Dir := writeTestNode(t, home, KindPeer, "peer1", map[string]interface{}{
+		"init.json": config.PeerInitOptions{ID: "peer1", MSPID: "Org1MSP", Hosts: []string{"peer1.org1.example.com"}, Operations: config.OperationsOptions{TLS: true, ClientAuthRequired: true}},
+		"run.json":  config.PeerRunConfig{PeerID: "peer1", Options: config.PeerStartOptions{OperationsListenAddress: "10.0.0.1:9544"}},
+	})
+	writeTestNode(t, home, KindPeer, "peer2", map[string]interface{}{
+		"init.json": config.PeerInitOptions{ID: "peer2", Operations: config.OperationsOptions{MetricsProvider: "disabled"}},
+	})
+	writeTestNode(t, home, KindOrderer, "orderer0", map[string]interface{}{
+		"run.json": map[string]interface{}{"options": config.OrdererStartOptions{MSPID: "OrdererMSP", OperationsListenAddress: "0.0.0.0:9445"}},
+	})
+	writeTestNode(t, home, KindOrderer, "orderer1", nil)
+
+	outputDir := filepath.Join(t.TempDir(), "monitoring")
+	exported, err := Export(ExportOptions{OutputDir: outputDir, Host: "192.168.1.10", FederateAddress: "192.168.1.10:9090"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Targets) != 3 || len(exported.Skipped) != 2 {
+		t.Fatalf("expected 3 targets and 2 skipped nodes, got %+v and %v", exported.Targets, exported.Skipped)
+	}
+	if !strings.HasPrefix(exported.Skipped[0], "peer peer2") || !strings.HasPrefix(exported.Skipped[1], "orderer orderer1") {
+		t.Fatalf("unexpected skipped nodes %v", exported.Skipped)
+	}
+
+	prometheusBytes, err := os.ReadFile(filepath.Join(outputDir, PrometheusFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := prometheusConfig{}
+	err = yaml.Unmarshal(prometheusBytes, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := map[string]scrapeConfig{}
+	for _, job := range c.ScrapeConfigs {
+		jobs[job.JobName] = job
+	}
+	peers := jobs["hlf-easy-peers"]
+	if len(peers.StaticConfigs) != 1 || peers.StaticConfigs[0].Targets[0] != "192.168.1.10:9443" || peers.StaticConfigs[0].Labels["node_id"] != "peer0" {
+		t.Fatalf("expected peer0 on the host in the peers job, got %+v", peers)
+	}
+	peer1 := jobs["hlf-easy-peer-peer1"]
+	if peer1.Scheme != "https" || peer1.StaticConfigs[0].Targets[0] != "10.0.0.1:9544" {
+		t.Fatalf("expected peer1 on its running address over TLS, got %+v", peer1)
+	}
+	expectedTLS := scrapeTLSConfig{
+		CAFile:     filepath.Join(peer1Dir, "tlscacerts", "cacert.pem"),
+		CertFile:   filepath.Join(peer1Dir, "tls.crt"),
+		KeyFile:    filepath.Join(peer1Dir, "tls.key"),
+		ServerName: "peer1.org1.example.com",
+	}
+	if peer1.TLSConfig == nil || *peer1.TLSConfig != expectedTLS {
+		t.Fatalf("unexpected TLS config of peer1 %+v", peer1.TLSConfig)
+	}
+	orderers := jobs["hlf-easy-orderers"]
+	if len(orderers.StaticConfigs) != 1 || orderers.StaticConfigs[0].Targets[0] != "192.168.1.10:9445" || orderers.StaticConfigs[0].Labels["msp_id"] != "OrdererMSP" {
+		t.Fatalf("expected orderer0 in the orderers job, got %+v", orderers)
+	}
+
+	federationBytes, err := os.ReadFile(filepath.Join(outputDir, FederationFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(federationBytes), "metrics_path: /federate") || !strings.Contains(string(federationBytes), "192.168.1.10:9090") {
+		t.Fatalf("unexpected federation config:\n%s", federationBytes)
+	}
+
+	dashboardBytes, err := os.ReadFile(filepath.Join(outputDir, DashboardFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dashboard := struct {
+		UID    string `json:"uid"`
+		Panels []struct {
+			Type    string `json:"type"`
+			Targets []struct {
+				Expr string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}{}
+	err = json.Unmarshal(dashboardBytes, &dashboard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dashboard.UID != DashboardUID || len(dashboard.Panels) == 0 {
+		t.Fatalf("unexpected dashboard %s", dashboard.UID)
+	}
+	for _, panel := range dashboard.Panels {
+		for _, target := range panel.Targets {
+			if !strings.Contains(target.Expr, `node_id=~"$node_id"`) {
+				t.Errorf("expected the expression %s to select the nodes of the variables", target.Expr)
+			}
+		}
+	}
+}
+
+func TestExportInvalidScrapeInterval(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := Export(ExportOptions{OutputDir: t.TempDir(), ScrapeInterval: "often"}); err == nil {
+		t.Fatal("expected an error for an invalid scrape interval")
+	}
+}
@@ -0,0 +1,153 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DashboardUID is the UID of the Grafana dashboard, importing it again
+// replaces the previous one
+const DashboardUID = "hlf-easy-fabric"
+
+// dashboardPanel is a time series of the dashboard, %s in the expressions is
+// the selector of the nodes chosen in the variables of the dashboard
+type dashboardPanel struct {
+	title  string
+	expr   string
+	legend string
+	unit   string
+}
+
+// dashboardRow groups the panels of a kind of node
+type dashboardRow struct {
+	title  string
+	panels []dashboardPanel
+}
+
+// the panels of the dashboard, the metrics are the ones of the operations
+// endpoint of Fabric
+var dashboardRows = []dashboardRow{
+	{
+		title: "Nodes",
+		panels: []dashboardPanel{
+			{"Block height", `max by (node_id, channel) (ledger_blockchain_height%s)`, "{{node_id}} {{channel}}", "short"},
+			{"gRPC requests", `sum by (node_id) (rate(grpc_server_completed_requests%s[5m]))`, "{{node_id}}", "reqps"},
+			{"Resident memory", `process_resident_memory_bytes%s`, "{{node_id}}", "bytes"},
+			{"Goroutines", `go_goroutines%s`, "{{node_id}}", "short"},
+		},
+	},
+	{
+		title: "Peers",
+		panels: []dashboardPanel{
+			{"Committed transactions", `sum by (node_id, channel, validation_code) (rate(ledger_transaction_count%s[5m]))`, "{{node_id}} {{channel}} {{validation_code}}", "ops"},
+			{"Block processing time p95", `histogram_quantile(0.95, sum by (node_id, channel, le) (rate(ledger_block_processing_time_bucket%s[5m])))`, "{{node_id}} {{channel}}", "s"},
+			{"Endorsed proposals", `sum by (node_id) (rate(endorser_successful_proposals%s[5m]))`, "{{node_id}}", "ops"},
+			{"Endorsement duration p95", `histogram_quantile(0.95, sum by (node_id, chaincode, le) (rate(endorser_proposal_duration_bucket%s[5m])))`, "{{node_id}} {{chaincode}}", "s"},
+			{"Gossip peers known", `max by (node_id, channel) (gossip_membership_total_peers_known%s)`, "{{node_id}} {{channel}}", "short"},
+			{"Chaincode launch duration p95", `histogram_quantile(0.95, sum by (node_id, chaincode, le) (rate(chaincode_launch_duration_bucket%s[5m])))`, "{{node_id}} {{chaincode}}", "s"},
+		},
+	},
+	{
+		title: "Orderers",
+		panels: []dashboardPanel{
+			{"Broadcast transactions", `sum by (node_id, channel, status) (rate(broadcast_processed_count%s[5m]))`, "{{node_id}} {{channel}} {{status}}", "ops"},
+			{"Raft leader", `max by (node_id, channel) (consensus_etcdraft_is_leader%s)`, "{{node_id}} {{channel}}", "short"},
+			{"Raft cluster size", `max by (node_id, channel) (consensus_etcdraft_cluster_size%s)`, "{{node_id}} {{channel}}", "short"},
+			{"Raft leader changes", `sum by (node_id, channel) (increase(consensus_etcdraft_leader_changes%s[1h]))`, "{{node_id}} {{channel}}", "short"},
+		},
+	},
+}
+
+// dashboardSelector selects the nodes chosen in the variables of the
+// dashboard
+const dashboardSelector = `{msp_id=~"$msp_id", node_id=~"$node_id"}`
+
+// dashboardDatasource is the Prometheus datasource chosen in the variables of
+// the dashboard
+var dashboardDatasource = map[string]string{"type": "prometheus", "uid": "${datasource}"}
+
+func dashboardVariable(name string, label string, query string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":       name,
+		"label":      label,
+		"type":       "query",
+		"datasource": dashboardDatasource,
+		"query":      query,
+		"refresh":    2,
+		"multi":      true,
+		"includeAll": true,
+		"allValue":   ".*",
+		"current":    map[string]interface{}{"text": "All", "value": "$__all"},
+		"sort":       1,
+	}
+}
+
+// GrafanaDashboard returns the Grafana dashboard of the metrics of the peers
+// and orderers scraped with PrometheusConfig, it's imported in Grafana with
+// its Prometheus datasource
+func GrafanaDashboard() ([]byte, error) {
+	panels := []interface{}{}
+	id := 1
+	y := 0
+	for _, row := range dashboardRows {
+		panels = append(panels, map[string]interface{}{
+			"id":        id,
+			"type":      "row",
+			"title":     row.title,
+			"collapsed": false,
+			"gridPos":   map[string]int{"h": 1, "w": 24, "x": 0, "y": y},
+			"panels":    []interface{}{},
+		})
+		id++
+		y++
+		// two panels of half the width per line
+		for i, p := range row.panels {
+			panels = append(panels, map[string]interface{}{
+				"id":         id,
+				"type":       "timeseries",
+				"title":      p.title,
+				"datasource": dashboardDatasource,
+				"gridPos":    map[string]int{"h": 8, "w": 12, "x": (i % 2) * 12, "y": y + (i/2)*8},
+				"fieldConfig": map[string]interface{}{
+					"defaults":  map[string]interface{}{"unit": p.unit},
+					"overrides": []interface{}{},
+				},
+				"targets": []interface{}{
+					map[string]interface{}{
+						"datasource":   dashboardDatasource,
+						"expr":         fmt.Sprintf(p.expr, dashboardSelector),
+						"legendFormat": p.legend,
+						"refId":        "A",
+					},
+				},
+			})
+			id++
+		}
+		y += (len(row.panels) + 1) / 2 * 8
+	}
+	dashboard := map[string]interface{}{
+		"uid":           DashboardUID,
+		"title":         "Hyperledger Fabric (hlf-easy)",
+		"tags":          []string{"hyperledger-fabric", JobPrefix},
+		"timezone":      "browser",
+		"schemaVersion": 36,
+		"editable":      true,
+		"refresh":       "30s",
+		"time":          map[string]string{"from": "now-6h", "to": "now"},
+		"templating": map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{
+					"name":    "datasource",
+					"label":   "Datasource",
+					"type":    "datasource",
+					"query":   "prometheus",
+					"current": map[string]interface{}{},
+				},
+				dashboardVariable("msp_id", "MSP ID", "label_values(ledger_blockchain_height, msp_id)"),
+				dashboardVariable("node_id", "Node", `label_values(ledger_blockchain_height{msp_id=~"$msp_id"}, node_id)`),
+			},
+		},
+		"panels": panels,
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
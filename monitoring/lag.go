@@ -0,0 +1,131 @@
+package monitoring
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"hlf-easy/log"
+	"hlf-easy/node"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LagMonitor compares the block heights of a peer with the heights of the
+// other peers of its org on the host, a peer behind the others usually has a
+// broken gossip or delivery of the blocks
+type LagMonitor struct {
+	peerID    string
+	mspID     string
+	threshold uint64
+	mu        sync.Mutex
+	lag       *node.HeightLag
+}
+
+// NewLagMonitor returns the monitor of the height lag of a peer
+func NewLagMonitor(peerID string, mspID string, threshold uint64) *LagMonitor {
+	return &LagMonitor{
+		peerID:    peerID,
+		mspID:     mspID,
+		threshold: threshold,
+	}
+}
+
+// HeightLag returns the last lag of the peer, nil before the first check
+func (m *LagMonitor) HeightLag() *node.HeightLag {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lag == nil {
+		return nil
+	}
+	lag := *m.lag
+	return &lag
+}
+
+// Run compares the heights every interval until the context is done
+func (m *LagMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.check(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check reads the heights of the peers of the org from their operations
+// endpoint, the peers that can't be reached are left out
+func (m *LagMonitor) check(now time.Time) {
+	lag := &node.HeightLag{Channels: []node.ChannelLag{}, Threshold: m.threshold, CheckedAt: now}
+	heights, others, err := m.readHeights()
+	if err != nil {
+		lag.Error = err.Error()
+	} else {
+		lag.Channels = node.ComputeHeightLag(heights, others, m.threshold)
+		for _, channel := range lag.Channels {
+			lag.Lagging = lag.Lagging || channel.Lagging
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wasLagging := m.lag != nil && m.lag.Lagging
+	if lag.Lagging && !wasLagging {
+		for _, channel := range lag.Channels {
+			if channel.Lagging {
+				log.Warnf("Peer %s is %d blocks behind peer %s on channel %s", m.peerID, channel.Lag, channel.MaxPeer, channel.Channel)
+			}
+		}
+	} else if !lag.Lagging && wasLagging && lag.Error == "" {
+		log.Infof("Peer %s caught up with the peers of %s", m.peerID, m.mspID)
+	}
+	m.lag = lag
+}
+
+// readHeights returns the heights of the peer and the ones of the other
+// peers of its org keyed by their ID
+func (m *LagMonitor) readHeights() (map[string]uint64, map[string]map[string]uint64, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	targets, _, err := ListTargets("127.0.0.1")
+	if err != nil {
+		return nil, nil, err
+	}
+	var heights map[string]uint64
+	others := map[string]map[string]uint64{}
+	for _, target := range targets {
+		if target.Kind != KindPeer || (target.ID != m.peerID && target.MSPID != m.mspID) {
+			continue
+		}
+		targetHeights, err := getTargetHeights(home, target)
+		if target.ID == m.peerID {
+			if err != nil {
+				return nil, nil, err
+			}
+			heights = targetHeights
+			continue
+		}
+		if err != nil {
+			// the stopped peers of the org are left out
+			log.Debugf("Failed to read the block heights of peer %s: %v", target.ID, err)
+			continue
+		}
+		others[target.ID] = targetHeights
+	}
+	if heights == nil {
+		return nil, nil, errors.Errorf("peer %s doesn't serve its block heights, its metrics provider must be %s", m.peerID, node.MetricsPrometheus)
+	}
+	return heights, others, nil
+}
+
+func getTargetHeights(home string, target Target) (map[string]uint64, error) {
+	endpoint, err := node.GetPeerOperationsEndpoint(filepath.Join(home, "hlf-easy", "peers", target.ID), target.Address)
+	if err != nil {
+		return nil, err
+	}
+	return node.GetBlockHeights(endpoint)
+}
@@ -0,0 +1,76 @@
+package monitoring
+
+import (
+	"fmt"
+	"hlf-easy/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMetricsServer(t *testing.T, height int) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "ledger_blockchain_height{channel=\"mychannel\"} %d\n", height)
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestLagMonitor(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peers := map[string]struct {
+		mspID  string
+		height int
+	}{
+		"peer0": {"Org1MSP", 10},
+		"peer1": {"Org1MSP", 30},
+		"peer2": {"Org2MSP", 50},
+	}
+	for id, p := range peers {
+		writeTestNode(t, home, KindPeer, id, map[string]interface{}{
+			"init.json": config.PeerInitOptions{ID: id, MSPID: p.mspID},
+			"run.json":  config.PeerRunConfig{PeerID: id, Options: config.PeerStartOptions{MSPID: p.mspID, OperationsListenAddress: newMetricsServer(t, p.height)}},
+		})
+	}
+	// a stopped peer of the org is left out
+	writeTestNode(t, home, KindPeer, "peer3", map[string]interface{}{
+		"init.json": config.PeerInitOptions{ID: "peer3", MSPID: "Org1MSP", Operations: config.OperationsOptions{ListenAddress: "127.0.0.1:1"}},
+	})
+
+	m := NewLagMonitor("peer0", "Org1MSP", 10)
+	if m.HeightLag() != nil {
+		t.Fatal("expected no lag before the first check")
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.check(now)
+	lag := m.HeightLag()
+	if lag.Error != "" || !lag.Lagging || len(lag.Channels) != 1 {
+		t.Fatalf("expected peer0 to be lagging, got %+v", lag)
+	}
+	if lag.Channels[0].MaxPeer != "peer1" || lag.Channels[0].Lag != 20 {
+		t.Fatalf("expected peer0 to be 20 blocks behind peer1 of its org, got %+v", lag.Channels[0])
+	}
+
+	m = NewLagMonitor("peer1", "Org1MSP", 10)
+	m.check(now)
+	if lag := m.HeightLag(); lag.Lagging || lag.Channels[0].Lag != 0 {
+		t.Fatalf("expected peer1 to be the highest peer of its org, got %+v", lag)
+	}
+}
+
+func TestLagMonitorWithoutMetrics(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeTestNode(t, home, KindPeer, "peer0", map[string]interface{}{
+		"init.json": config.PeerInitOptions{ID: "peer0", Operations: config.OperationsOptions{MetricsProvider: "disabled"}},
+	})
+	m := NewLagMonitor("peer0", "Org1MSP", 10)
+	m.check(time.Now())
+	if lag := m.HeightLag(); lag.Error == "" || lag.Lagging {
+		t.Fatalf("expected an error for a peer without metrics, got %+v", lag)
+	}
+}
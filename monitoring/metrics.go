@@ -0,0 +1,73 @@
+package monitoring
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"hlf-easy/node"
+	"sync"
+	"time"
+)
+
+// MetricsMonitor scrapes the Fabric counters of a peer from its operations
+// endpoint, the commit rates are computed between two scrapes
+type MetricsMonitor struct {
+	peerID   string
+	endpoint node.OperationsEndpoint
+	mu       sync.Mutex
+	metrics  *node.PeerMetrics
+	// last is the last successful scrape, a transient error doesn't reset
+	// the rates
+	last *node.PeerMetrics
+}
+
+// NewMetricsMonitor returns the monitor of the Fabric counters of a peer
+func NewMetricsMonitor(peerID string, endpoint node.OperationsEndpoint) *MetricsMonitor {
+	return &MetricsMonitor{
+		peerID:   peerID,
+		endpoint: endpoint,
+	}
+}
+
+// Metrics returns the last counters of the peer, nil before the first scrape
+func (m *MetricsMonitor) Metrics() *node.PeerMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.metrics == nil {
+		return nil
+	}
+	metrics := *m.metrics
+	return &metrics
+}
+
+// Run scrapes the metrics every interval until the context is done
+func (m *MetricsMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.check(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check scrapes the metrics of the peer, a peer without the prometheus
+// provider reports an error
+func (m *MetricsMonitor) check(now time.Time) {
+	metrics, err := node.GetPeerMetrics(m.endpoint)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		err = errors.Wrapf(err, "failed to read the metrics of peer %s, its metrics provider must be %s", m.peerID, node.MetricsPrometheus)
+		m.metrics = &node.PeerMetrics{Channels: []node.ChannelMetrics{}, ScrapedAt: now, Error: err.Error()}
+		return
+	}
+	metrics.ScrapedAt = now
+	if m.last != nil {
+		node.ComputeCommitRates(m.last, metrics)
+	}
+	m.metrics = metrics
+	m.last = metrics
+}
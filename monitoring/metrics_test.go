@@ -0,0 +1,49 @@
+package monitoring
+
+import (
+	"fmt"
+	"hlf-easy/node"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMetricsMonitor(t *testing.T) {
+	var height int64 = 10
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := atomic.LoadInt64(&height)
+		fmt.Fprintf(w, "ledger_blockchain_height{channel=\"mychannel\"} %d\n", h)
+		fmt.Fprintf(w, "ledger_transaction_count{channel=\"mychannel\",validation_code=\"VALID\"} %d\n", h*10)
+		fmt.Fprintln(w, "endorser_endorsement_failures{channel=\"mychannel\"} 1")
+	}))
+	defer server.Close()
+
+	m := NewMetricsMonitor("peer0", node.OperationsEndpoint{Address: strings.TrimPrefix(server.URL, "http://")})
+	if m.Metrics() != nil {
+		t.Fatal("expected no metrics before the first scrape")
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.check(now)
+	metrics := m.Metrics()
+	if metrics.Error != "" || len(metrics.Channels) != 1 || metrics.Channels[0].Height != 10 || metrics.EndorsementFailures != 1 {
+		t.Fatalf("expected the counters of the peer, got %+v", metrics)
+	}
+	if metrics.Channels[0].BlockCommitRate != 0 {
+		t.Fatalf("expected no rate on the first scrape, got %+v", metrics.Channels[0])
+	}
+
+	atomic.StoreInt64(&height, 40)
+	m.check(now.Add(30 * time.Second))
+	if c := m.Metrics().Channels[0]; c.BlockCommitRate != 1 || c.TxCommitRate != 10 {
+		t.Fatalf("expected 1 block and 10 transactions per second, got %+v", c)
+	}
+
+	server.Close()
+	m.check(now.Add(time.Minute))
+	if metrics := m.Metrics(); metrics.Error == "" {
+		t.Fatalf("expected an error for a peer that can't be scraped, got %+v", metrics)
+	}
+}
@@ -0,0 +1,136 @@
+package monitoring
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+)
+
+// JobPrefix prefixes the Prometheus jobs of the nodes of the host
+const JobPrefix = "hlf-easy"
+
+// DefaultScrapeInterval is how often Prometheus scrapes the nodes
+const DefaultScrapeInterval = "15s"
+
+type prometheusConfig struct {
+	Global        prometheusGlobal `yaml:"global"`
+	ScrapeConfigs []scrapeConfig   `yaml:"scrape_configs"`
+}
+
+type prometheusGlobal struct {
+	ScrapeInterval string `yaml:"scrape_interval"`
+}
+
+type scrapeConfig struct {
+	JobName       string              `yaml:"job_name"`
+	Scheme        string              `yaml:"scheme,omitempty"`
+	MetricsPath   string              `yaml:"metrics_path,omitempty"`
+	HonorLabels   bool                `yaml:"honor_labels,omitempty"`
+	Params        map[string][]string `yaml:"params,omitempty"`
+	TLSConfig     *scrapeTLSConfig    `yaml:"tls_config,omitempty"`
+	StaticConfigs []staticConfig      `yaml:"static_configs"`
+}
+
+type scrapeTLSConfig struct {
+	CAFile     string `yaml:"ca_file"`
+	CertFile   string `yaml:"cert_file,omitempty"`
+	KeyFile    string `yaml:"key_file,omitempty"`
+	ServerName string `yaml:"server_name,omitempty"`
+}
+
+type staticConfig struct {
+	Targets []string          `yaml:"targets"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
+}
+
+// targetLabels are the labels of the metrics of a node, the dashboard
+// selects the nodes with them
+func targetLabels(t Target) map[string]string {
+	labels := map[string]string{
+		"kind":    t.Kind,
+		"node_id": t.ID,
+	}
+	if t.MSPID != "" {
+		labels["msp_id"] = t.MSPID
+	}
+	return labels
+}
+
+// PrometheusConfig returns the Prometheus config scraping the targets. The
+// plain HTTP endpoints share a job per kind, every TLS endpoint has its own
+// job with its CA and client certificate
+func PrometheusConfig(targets []Target, scrapeInterval string) ([]byte, error) {
+	c := newPrometheusConfig(scrapeInterval)
+	plainJobs := map[string]int{}
+	for _, t := range targets {
+		static := staticConfig{Targets: []string{t.Address}, Labels: targetLabels(t)}
+		if t.TLS {
+			c.ScrapeConfigs = append(c.ScrapeConfigs, scrapeConfig{
+				JobName: fmt.Sprintf("%s-%s-%s", JobPrefix, t.Kind, t.ID),
+				Scheme:  "https",
+				TLSConfig: &scrapeTLSConfig{
+					CAFile:     t.CAFile,
+					CertFile:   t.CertFile,
+					KeyFile:    t.KeyFile,
+					ServerName: t.ServerName,
+				},
+				StaticConfigs: []staticConfig{static},
+			})
+			continue
+		}
+		i, ok := plainJobs[t.Kind]
+		if !ok {
+			i = len(c.ScrapeConfigs)
+			plainJobs[t.Kind] = i
+			c.ScrapeConfigs = append(c.ScrapeConfigs, scrapeConfig{
+				JobName: fmt.Sprintf("%s-%ss", JobPrefix, t.Kind),
+			})
+		}
+		c.ScrapeConfigs[i].StaticConfigs = append(c.ScrapeConfigs[i].StaticConfigs, static)
+	}
+	return marshalConfig(c)
+}
+
+// FederationConfig returns the Prometheus config of a central Prometheus
+// federating the jobs of the host from the Prometheus of the host on the
+// address
+func FederationConfig(address string, scrapeInterval string) ([]byte, error) {
+	c := newPrometheusConfig(scrapeInterval)
+	c.ScrapeConfigs = append(c.ScrapeConfigs, scrapeConfig{
+		JobName:     JobPrefix + "-federate",
+		MetricsPath: "/federate",
+		// the labels of the nodes are kept as they were scraped
+		HonorLabels: true,
+		Params: map[string][]string{
+			"match[]": {fmt.Sprintf(`{job=~"%s-.*"}`, JobPrefix)},
+		},
+		StaticConfigs: []staticConfig{{Targets: []string{address}}},
+	})
+	return marshalConfig(c)
+}
+
+func newPrometheusConfig(scrapeInterval string) prometheusConfig {
+	if scrapeInterval == "" {
+		scrapeInterval = DefaultScrapeInterval
+	}
+	return prometheusConfig{
+		Global:        prometheusGlobal{ScrapeInterval: scrapeInterval},
+		ScrapeConfigs: []scrapeConfig{},
+	}
+}
+
+// marshalConfig writes the config with the indentation of the Prometheus docs
+func marshalConfig(c prometheusConfig) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err := encoder.Encode(c)
+	if err != nil {
+		return nil, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,186 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"hlf-easy/bulk"
+	"hlf-easy/config"
+	"hlf-easy/node"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Kinds of the nodes scraped by Prometheus
+const (
+	KindPeer    = "peer"
+	KindOrderer = "orderer"
+)
+
+// Target is the operations endpoint of a node of the host as scraped by
+// Prometheus
+type Target struct {
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	MSPID string `json:"mspID,omitempty"`
+	// Address is the host and port Prometheus scrapes
+	Address string `json:"address"`
+	// TLS is set when the endpoint is served over TLS, the certificate is
+	// verified against CAFile for ServerName
+	TLS        bool   `json:"tls"`
+	CAFile     string `json:"caFile,omitempty"`
+	ServerName string `json:"serverName,omitempty"`
+	// CertFile and KeyFile are the client certificate of an endpoint that
+	// requires one
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+}
+
+// runOptions has the fields of the run.json of peers and orderers used to
+// find their operations endpoint
+type runOptions struct {
+	Options struct {
+		MSPID                   string `json:"mspID"`
+		OperationsListenAddress string `json:"operationsListenAddress"`
+	} `json:"options"`
+}
+
+func readRunOptions(nodeDir string) (*runOptions, error) {
+	runBytes, err := os.ReadFile(filepath.Join(nodeDir, "run.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	run := &runOptions{}
+	err = json.Unmarshal(runBytes, run)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// scrapeAddress returns the address Prometheus reaches an operations
+// endpoint on, the unspecified addresses it listens on are reached through
+// the host
+func scrapeAddress(listenAddress string, host string) (string, error) {
+	h, port, err := net.SplitHostPort(listenAddress)
+	if err != nil {
+		return "", err
+	}
+	if h == "" || h == "0.0.0.0" || h == "::" {
+		h = host
+	}
+	return net.JoinHostPort(h, port), nil
+}
+
+// ListTargets returns the operations endpoints of the peers and orderers of
+// the host. The address of a running node is the one it was started with,
+// the one of the init options of a stopped peer otherwise. The nodes that
+// can't be scraped are returned as skipped with the reason, the orderers are
+// only known while they're running and the peers may not expose Prometheus
+// metrics
+func ListTargets(host string) ([]Target, []string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	targets := []Target{}
+	skipped := []string{}
+	peerIDs, err := bulk.ListNodeIDs(KindPeer)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, id := range peerIDs {
+		peerDir := filepath.Join(home, "hlf-easy", "peers", id)
+		target, reason, err := getPeerTarget(peerDir, id, host)
+		if err != nil {
+			return nil, nil, err
+		}
+		if target == nil {
+			skipped = append(skipped, fmt.Sprintf("peer %s: %s", id, reason))
+			continue
+		}
+		targets = append(targets, *target)
+	}
+	ordererIDs, err := bulk.ListNodeIDs(KindOrderer)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, id := range ordererIDs {
+		run, err := readRunOptions(filepath.Join(home, "hlf-easy", "orderers", id))
+		if err != nil {
+			return nil, nil, err
+		}
+		if run == nil || run.Options.OperationsListenAddress == "" {
+			skipped = append(skipped, fmt.Sprintf("orderer %s: not running, its operations address is unknown", id))
+			continue
+		}
+		address, err := scrapeAddress(run.Options.OperationsListenAddress, host)
+		if err != nil {
+			return nil, nil, err
+		}
+		targets = append(targets, Target{
+			Kind:    KindOrderer,
+			ID:      id,
+			MSPID:   run.Options.MSPID,
+			Address: address,
+		})
+	}
+	return targets, skipped, nil
+}
+
+// getPeerTarget returns the target of a peer, or the reason it isn't
+// scraped
+func getPeerTarget(peerDir string, id string, host string) (*Target, string, error) {
+	peerInitOpts := config.PeerInitOptions{}
+	initBytes, err := os.ReadFile(filepath.Join(peerDir, "init.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, "", err
+	}
+	if err == nil {
+		err = json.Unmarshal(initBytes, &peerInitOpts)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	operations := node.GetPeerOperations(peerInitOpts)
+	if operations.MetricsProvider != node.MetricsPrometheus {
+		return nil, fmt.Sprintf("its metrics provider is %s", operations.MetricsProvider), nil
+	}
+	mspID := peerInitOpts.MSPID
+	run, err := readRunOptions(peerDir)
+	if err != nil {
+		return nil, "", err
+	}
+	if run != nil && run.Options.OperationsListenAddress != "" {
+		operations.ListenAddress = run.Options.OperationsListenAddress
+		if run.Options.MSPID != "" {
+			mspID = run.Options.MSPID
+		}
+	}
+	address, err := scrapeAddress(operations.ListenAddress, host)
+	if err != nil {
+		return nil, "", err
+	}
+	target := &Target{
+		Kind:    KindPeer,
+		ID:      id,
+		MSPID:   mspID,
+		Address: address,
+		TLS:     operations.TLS,
+	}
+	if operations.TLS {
+		target.CAFile = filepath.Join(peerDir, "tlscacerts", "cacert.pem")
+		// the certificate of the endpoint has the hosts of the peer
+		if len(peerInitOpts.Hosts) > 0 {
+			target.ServerName = peerInitOpts.Hosts[0]
+		}
+	}
+	if operations.ClientAuthRequired {
+		target.CertFile = filepath.Join(peerDir, "tls.crt")
+		target.KeyFile = filepath.Join(peerDir, "tls.key")
+	}
+	return target, "", nil
+}
@@ -0,0 +1,80 @@
+package node
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"hlf-easy/chaincode"
+	"hlf-easy/plan"
+	"os"
+	"path/filepath"
+)
+
+// InstallPeerBuilders renders again the core.yaml of the peers of the host
+// created before the chaincode service registry, so they use its external
+// builder. It returns the IDs of the peers that must be restarted
+func InstallPeerBuilders() ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	peers, err := getPeersInitOptions()
+	if err != nil {
+		return nil, err
+	}
+	var updated []string
+	for _, peer := range peers {
+		peerDir := filepath.Join(home, "hlf-easy/peers", peer.ID)
+		_, err = os.Stat(chaincode.GetBuilderDir(peerDir))
+		if err == nil {
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		err = renderPeerCoreYaml(plan.Disk, peerDir, peer, gossipBootstrap(peer, peers))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to render core.yaml of peer %s", peer.ID)
+		}
+		updated = append(updated, peer.ID)
+	}
+	return updated, nil
+}
+
+// UpdatePeerExternalBuilders renders again the core.yaml of the peers of the
+// host with the custom external builders. It returns the IDs of the peers
+// whose core.yaml changed, they must be restarted
+func UpdatePeerExternalBuilders() ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	peers, err := getPeersInitOptions()
+	if err != nil {
+		return nil, err
+	}
+	var updated []string
+	for _, peer := range peers {
+		peerDir := filepath.Join(home, "hlf-easy/peers", peer.ID)
+		changed := false
+		err = withLock("peer", peer.ID, "peer.builders.update", func() error {
+			before, _ := os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+			err := renderPeerCoreYaml(plan.Disk, peerDir, peer, gossipBootstrap(peer, peers))
+			if err != nil {
+				return err
+			}
+			after, err := os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+			if err != nil {
+				return err
+			}
+			changed = !bytes.Equal(before, after)
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to render core.yaml of peer %s", peer.ID)
+		}
+		if changed {
+			updated = append(updated, peer.ID)
+		}
+	}
+	return updated, nil
+}
@@ -0,0 +1,52 @@
+package node
+
+import (
+	"hlf-easy/chaincode"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUpdatePeerExternalBuilders(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peerDir := writeTestPeer(t, home, config.PeerInitOptions{ID: "peer0", MSPID: "Org1MSP"})
+	updated, err := UpdatePeerExternalBuilders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(updated, []string{"peer0"}) {
+		t.Fatalf("expected the peer without core.yaml to be updated, got %v", updated)
+	}
+
+	_, err = chaincode.ScaffoldExternalBuilder("wasm", []string{"WASM_RUNTIME"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err = UpdatePeerExternalBuilders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(updated, []string{"peer0"}) {
+		t.Fatalf("expected the peer to be updated, got %v", updated)
+	}
+	coreYaml, err := os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "    - name: wasm\n      path: " + filepath.Join(home, "hlf-easy/builders/wasm") + "\n      propagateEnvironment:\n      - WASM_RUNTIME\n    - name: ccaas_builder\n"
+	if !strings.Contains(string(coreYaml), expected) {
+		t.Fatalf("expected the builder before the ccaas_builder in core.yaml")
+	}
+
+	updated, err = UpdatePeerExternalBuilders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 0 {
+		t.Fatalf("expected no peer to be updated, got %v", updated)
+	}
+}
@@ -0,0 +1,288 @@
+package node
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/errdefs"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CaliperNetworkConfig is the file name of the Caliper network config
+const CaliperNetworkConfig = "networkconfig.yaml"
+
+// CaliperConfigOptions select the peers, the channel and the identities of a
+// Hyperledger Caliper network config
+type CaliperConfigOptions struct {
+	// PeerIDs are the peers of the host the workers connect to, all of them
+	// when empty
+	PeerIDs    []string
+	Channel    string
+	Chaincodes []string
+	// Identities are identity files, as written by ca enroll, each one is
+	// used by the org of its MSP. An org without one uses the admin identity
+	// managed for its first peer
+	Identities []string
+	// OutputDir is the directory the network config and the connection
+	// profiles of the orgs are written to
+	OutputDir string
+}
+
+// Validate checks the options of a Caliper network config
+func (o CaliperConfigOptions) Validate() error {
+	if o.Channel == "" {
+		return errors.New("the channel is required")
+	}
+	if len(o.Chaincodes) == 0 {
+		return errors.New("at least one chaincode is required")
+	}
+	if o.OutputDir == "" {
+		return errors.New("the output directory is required")
+	}
+	return nil
+}
+
+// CaliperOrg is an org of a Caliper network config
+type CaliperOrg struct {
+	MSPID      string   `json:"mspID"`
+	Peers      []string `json:"peers"`
+	Identities []string `json:"identities"`
+	// ConnectionProfile is the file of the connection profile of the org
+	ConnectionProfile string `json:"connectionProfile"`
+}
+
+// CaliperConfig is a Caliper network config written by WriteCaliperConfig
+type CaliperConfig struct {
+	NetworkConfig string       `json:"networkConfig"`
+	Orgs          []CaliperOrg `json:"orgs"`
+}
+
+type caliperPEM struct {
+	PEM string `yaml:"pem"`
+}
+
+type caliperCertificate struct {
+	Name             string     `yaml:"name"`
+	ClientPrivateKey caliperPEM `yaml:"clientPrivateKey"`
+	ClientSignedCert caliperPEM `yaml:"clientSignedCert"`
+}
+
+type caliperConnectionProfile struct {
+	Path     string `yaml:"path"`
+	Discover bool   `yaml:"discover"`
+}
+
+type caliperOrganization struct {
+	MSPID      string `yaml:"mspid"`
+	Identities struct {
+		Certificates []caliperCertificate `yaml:"certificates"`
+	} `yaml:"identities"`
+	ConnectionProfile caliperConnectionProfile `yaml:"connectionProfile"`
+}
+
+type caliperContract struct {
+	ID string `yaml:"id"`
+}
+
+type caliperChannel struct {
+	ChannelName string            `yaml:"channelName"`
+	Contracts   []caliperContract `yaml:"contracts"`
+}
+
+// caliperNetwork is the network config of the Fabric connector of Caliper
+type caliperNetwork struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	Caliper struct {
+		Blockchain string `yaml:"blockchain"`
+	} `yaml:"caliper"`
+	Channels      []caliperChannel      `yaml:"channels"`
+	Organizations []caliperOrganization `yaml:"organizations"`
+}
+
+// caliperOrgPeers returns the peers of the options by MSP ID, all the peers
+// of the host when the options have none
+func caliperOrgPeers(peerIDs []string) (map[string][]string, error) {
+	orgPeers := map[string][]string{}
+	if len(peerIDs) == 0 {
+		peers, err := ListPeerEndpoints()
+		if err != nil {
+			return nil, err
+		}
+		if len(peers) == 0 {
+			return nil, errdefs.Errorf(errdefs.ErrNodeNotFound, "the host has no peers")
+		}
+		for _, peer := range peers {
+			peerIDs = append(peerIDs, peer.ID)
+		}
+	}
+	for _, peerID := range peerIDs {
+		mspID, err := GetPeerMSPID(peerID)
+		if err != nil {
+			return nil, err
+		}
+		if mspID == "" {
+			return nil, errors.Errorf("peer %s has no MSP ID, initialize it with --msp-id", peerID)
+		}
+		orgPeers[mspID] = append(orgPeers[mspID], peerID)
+	}
+	return orgPeers, nil
+}
+
+// WriteCaliperConfig writes the network config of the Fabric connector of
+// Hyperledger Caliper for peers of the host, and the connection profile of
+// each of their orgs it points to. The workers discover the other endorsers
+// and the orderers of the channel through the peers. The network config has
+// the private keys of the identities, it's only readable by its owner
+func WriteCaliperConfig(opts CaliperConfigOptions) (*CaliperConfig, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	orgPeers, err := caliperOrgPeers(opts.PeerIDs)
+	if err != nil {
+		return nil, err
+	}
+	mspIDs := []string{}
+	for mspID := range orgPeers {
+		mspIDs = append(mspIDs, mspID)
+	}
+	sort.Strings(mspIDs)
+
+	// the identities are used by the org whose first peer accepts them
+	orgIdentities := map[string][]string{}
+	for _, identity := range opts.Identities {
+		found := false
+		for _, mspID := range mspIDs {
+			_, err := NewGatewayClientConfig(GatewayClientConfigOptions{PeerID: orgPeers[mspID][0], Identity: identity})
+			if err == nil {
+				orgIdentities[mspID] = append(orgIdentities[mspID], identity)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("identity %s doesn't belong to the MSP of any of the peers", identity)
+		}
+	}
+
+	outputDir, err := filepath.Abs(opts.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	network := caliperNetwork{Name: "hlf-easy", Version: "2.0.0"}
+	network.Caliper.Blockchain = "fabric"
+	channel := caliperChannel{ChannelName: opts.Channel}
+	for _, chaincode := range opts.Chaincodes {
+		channel.Contracts = append(channel.Contracts, caliperContract{ID: chaincode})
+	}
+	network.Channels = []caliperChannel{channel}
+	result := &CaliperConfig{NetworkConfig: filepath.Join(outputDir, CaliperNetworkConfig)}
+	for _, mspID := range mspIDs {
+		identities := orgIdentities[mspID]
+		if len(identities) == 0 {
+			identity, err := ManagedAdminIdentity(orgPeers[mspID][0])
+			if err != nil {
+				return nil, errors.Wrapf(err, "no identity of %s", mspID)
+			}
+			identities = []string{identity}
+		}
+		org := caliperOrganization{MSPID: mspID}
+		names := []string{}
+		seen := map[string]bool{}
+		for _, identity := range identities {
+			crt, _, err := utils.ReadIdentity(identity)
+			if err != nil {
+				return nil, err
+			}
+			clientConfig, err := NewGatewayClientConfig(GatewayClientConfigOptions{PeerID: orgPeers[mspID][0], Identity: identity})
+			if err != nil {
+				return nil, err
+			}
+			// the workloads select the identities by name with invokerIdentity
+			name := crt.Subject.CommonName
+			for i := 2; seen[name]; i++ {
+				name = fmt.Sprintf("%s-%d", crt.Subject.CommonName, i)
+			}
+			seen[name] = true
+			names = append(names, name)
+			org.Identities.Certificates = append(org.Identities.Certificates, caliperCertificate{
+				Name:             name,
+				ClientPrivateKey: caliperPEM{PEM: clientConfig.Identity.Key},
+				ClientSignedCert: caliperPEM{PEM: clientConfig.Identity.Cert},
+			})
+		}
+
+		profile, err := caliperProfile(mspID, orgPeers[mspID], identities[0])
+		if err != nil {
+			return nil, err
+		}
+		profilePath := filepath.Join(outputDir, fmt.Sprintf("connection-%s.yaml", mspID))
+		err = os.WriteFile(profilePath, profile, 0644)
+		if err != nil {
+			return nil, err
+		}
+		org.ConnectionProfile = caliperConnectionProfile{Path: profilePath, Discover: true}
+		network.Organizations = append(network.Organizations, org)
+		result.Orgs = append(result.Orgs, CaliperOrg{
+			MSPID:             mspID,
+			Peers:             orgPeers[mspID],
+			Identities:        names,
+			ConnectionProfile: profilePath,
+		})
+	}
+	networkYaml, err := yaml.Marshal(network)
+	if err != nil {
+		return nil, err
+	}
+	// the network config has the private keys of the identities
+	err = os.WriteFile(result.NetworkConfig, networkYaml, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// caliperProfile returns the connection profile of an org with its peers,
+// their endpoints and TLS roots are the ones of their gateway client config
+func caliperProfile(mspID string, peerIDs []string, identity string) ([]byte, error) {
+	peers := map[string]interface{}{}
+	for _, peerID := range peerIDs {
+		clientConfig, err := NewGatewayClientConfig(GatewayClientConfigOptions{PeerID: peerID, Identity: identity})
+		if err != nil {
+			return nil, err
+		}
+		grpcOptions := map[string]interface{}{}
+		if clientConfig.ServerName != "" {
+			grpcOptions["ssl-target-name-override"] = clientConfig.ServerName
+			grpcOptions["hostnameOverride"] = clientConfig.ServerName
+		}
+		peers[peerID] = map[string]interface{}{
+			"url":         "grpcs://" + clientConfig.Endpoint,
+			"grpcOptions": grpcOptions,
+			"tlsCACerts": map[string]interface{}{
+				"pem": clientConfig.TLSRootCerts,
+			},
+		}
+	}
+	return yaml.Marshal(map[string]interface{}{
+		"name":    "hlf-easy-" + mspID,
+		"version": "1.0.0",
+		"client": map[string]interface{}{
+			"organization": mspID,
+		},
+		"organizations": map[string]interface{}{
+			mspID: map[string]interface{}{
+				"mspid": mspID,
+				"peers": peerIDs,
+			},
+		},
+		"peers": peers,
+	})
+}
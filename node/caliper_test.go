@@ -0,0 +1,89 @@
+package node
+
+import (
+	"gopkg.in/yaml.v3"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteCaliperConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer0", Hosts: []string{"peer0.org1.example.com"}, MSPID: "Org1MSP"})
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer1", Hosts: []string{"peer1.org1.example.com"}, ExternalPort: 8051, MSPID: "Org1MSP"})
+	caConfig, err := utils.GetCAConfig("org1-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, clientKey := testca.Issue(t, testca.Cert{CommonName: "app", OUs: []string{"client"}}, caConfig.CACert, caConfig.CAKey)
+	outputDir := filepath.Join(t.TempDir(), "caliper")
+
+	result, err := WriteCaliperConfig(CaliperConfigOptions{
+		Channel:    "mychannel",
+		Chaincodes: []string{"asset"},
+		Identities: []string{writeTestIdentity(t, clientCert, clientKey), writeTestIdentity(t, clientCert, clientKey)},
+		OutputDir:  outputDir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Orgs) != 1 || len(result.Orgs[0].Peers) != 2 {
+		t.Fatalf("expected the two peers of Org1MSP, got %+v", result.Orgs)
+	}
+	if names := result.Orgs[0].Identities; len(names) != 2 || names[0] != "app" || names[1] != "app-2" {
+		t.Fatalf("expected the identities named after their common names, got %v", names)
+	}
+	networkYaml, err := os.ReadFile(filepath.Join(outputDir, CaliperNetworkConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	network := caliperNetwork{}
+	if err := yaml.Unmarshal(networkYaml, &network); err != nil {
+		t.Fatal(err)
+	}
+	if network.Caliper.Blockchain != "fabric" || network.Channels[0].ChannelName != "mychannel" || network.Channels[0].Contracts[0].ID != "asset" {
+		t.Fatalf("unexpected network config %+v", network)
+	}
+	org := network.Organizations[0]
+	if org.MSPID != "Org1MSP" || !org.ConnectionProfile.Discover || !filepath.IsAbs(org.ConnectionProfile.Path) {
+		t.Fatalf("unexpected org %+v", org)
+	}
+	if _, err := utils.ParseECDSAPrivateKey([]byte(org.Identities.Certificates[0].ClientPrivateKey.PEM)); err != nil {
+		t.Fatalf("expected the key of the identity: %v", err)
+	}
+	profileYaml, err := os.ReadFile(org.ConnectionProfile.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := struct {
+		Peers map[string]struct {
+			URL string `yaml:"url"`
+		} `yaml:"peers"`
+	}{}
+	if err := yaml.Unmarshal(profileYaml, &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.Peers["peer0"].URL != "grpcs://peer0.org1.example.com:7051" || profile.Peers["peer1"].URL != "grpcs://peer1.org1.example.com:8051" {
+		t.Fatalf("expected the endpoints of the peers, got %+v", profile.Peers)
+	}
+
+	// without identities the org uses the admin identity managed for its peer
+	result, err = WriteCaliperConfig(CaliperConfigOptions{PeerIDs: []string{"peer1"}, Channel: "mychannel", Chaincodes: []string{"asset"}, OutputDir: outputDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := result.Orgs[0].Identities; len(names) != 1 || names[0] != "admin-peer1" {
+		t.Fatalf("expected the managed admin identity, got %v", names)
+	}
+
+	otherCA, otherCAKey := testca.NewCA(t, "other-ca")
+	otherCert, otherKey := testca.Issue(t, testca.Cert{CommonName: "app", OUs: []string{"client"}}, otherCA, otherCAKey)
+	_, err = WriteCaliperConfig(CaliperConfigOptions{Channel: "mychannel", Chaincodes: []string{"asset"}, Identities: []string{writeTestIdentity(t, otherCert, otherKey)}, OutputDir: outputDir})
+	if err == nil {
+		t.Fatal("expected an error for an identity of another MSP")
+	}
+}
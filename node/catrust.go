@@ -0,0 +1,280 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/certs"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"hlf-easy/plan"
+	"hlf-easy/secrets"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CATrustNode is a peer or an orderer of the host that trusts a root of a
+// local CA
+type CATrustNode struct {
+	Kind    string `json:"kind"`
+	ID      string `json:"id"`
+	Running bool   `json:"running"`
+	// Issued is set when the TLS or the sign certificate of the node is
+	// issued by the current root
+	Issued bool `json:"issued"`
+
+	dir string
+}
+
+// extraRootFile is the file of a root trusted besides cacerts/cacert.pem in
+// the cacerts of a node, the MSP reads a single certificate from each file
+func extraRootFile(crt *x509.Certificate) string {
+	sum := sha256.Sum256(crt.Raw)
+	return fmt.Sprintf("cacert-%s.pem", hex.EncodeToString(sum[:8]))
+}
+
+// writeExtraRoots writes the signing roots trusted besides cacerts/cacert.pem
+// in their own file
+func writeExtraRoots(w plan.Writer, caCertsDir string, roots []*x509.Certificate) error {
+	for _, root := range roots {
+		err := w.WriteFile(filepath.Join(caCertsDir, extraRootFile(root)), utils.EncodeX509Certificate(root), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodeRoots returns the TLS or signing roots trusted by a node
+func nodeRoots(nodeDir string, kind string) ([]*x509.Certificate, error) {
+	if kind == certs.CAKindTLS {
+		contents, err := os.ReadFile(filepath.Join(nodeDir, "tlscacerts", "cacert.pem"))
+		if err != nil {
+			return nil, err
+		}
+		return utils.ParseX509CertificateChain(contents)
+	}
+	return readCertsDir(filepath.Join(nodeDir, "cacerts"))
+}
+
+// nodeCert returns the TLS or sign certificate of a node
+func nodeCert(nodeDir string, kind string) (*x509.Certificate, error) {
+	path := filepath.Join(nodeDir, "signcerts", "cert.pem")
+	if kind == certs.CAKindTLS {
+		path = filepath.Join(nodeDir, "tls.crt")
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return utils.ParseX509Certificate(contents)
+}
+
+func containsCert(crts []*x509.Certificate, crt *x509.Certificate) bool {
+	for _, c := range crts {
+		if bytes.Equal(c.Raw, crt.Raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// CATrustingNodes returns the peers and the orderers of the host that trust
+// one of the TLS or signing roots of a local CA, the current root first
+func CATrustingNodes(kind string, roots []*x509.Certificate) ([]CATrustNode, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []CATrustNode{}
+	for _, nodeKind := range []string{"peer", "orderer"} {
+		nodeDirs, err := filepath.Glob(filepath.Join(home, fmt.Sprintf("hlf-easy/%ss/*", nodeKind)))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(nodeDirs)
+		for _, nodeDir := range nodeDirs {
+			trusted, err := nodeRoots(nodeDir, kind)
+			if err != nil {
+				// not enrolled yet, e.g. waiting for the CSR to be signed
+				continue
+			}
+			trusts := false
+			for _, root := range roots {
+				if containsCert(trusted, root) {
+					trusts = true
+					break
+				}
+			}
+			if !trusts {
+				continue
+			}
+			n := CATrustNode{Kind: nodeKind, ID: filepath.Base(nodeDir), dir: nodeDir}
+			if _, err := os.Stat(filepath.Join(nodeDir, "run.json")); err == nil {
+				n.Running = true
+			}
+			if crt, err := nodeCert(nodeDir, kind); err == nil {
+				n.Issued = crt.CheckSignatureFrom(roots[0]) == nil
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes, nil
+}
+
+// SyncCATrust makes the nodes trust the roots of a local CA, the current root
+// first: the TLS roots are bundled in tlscacerts/cacert.pem, read by every TLS
+// root setting of the nodes, and the signing roots other than the one of
+// cacerts/cacert.pem, which the NodeOUs point to, get their own file in
+// cacerts. The roots not trusted anymore are removed. The nodes use them on
+// their next start
+func SyncCATrust(nodes []CATrustNode, kind string, roots []*x509.Certificate) error {
+	for _, n := range nodes {
+		if kind == certs.CAKindTLS {
+			err := os.WriteFile(filepath.Join(n.dir, "tlscacerts", "cacert.pem"), encodeX509Certificates(roots), 0644)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		caCertsDir := filepath.Join(n.dir, "cacerts")
+		caCert, err := os.ReadFile(filepath.Join(caCertsDir, "cacert.pem"))
+		if err != nil {
+			return err
+		}
+		files, err := filepath.Glob(filepath.Join(caCertsDir, "cacert-*.pem"))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := os.Remove(file); err != nil {
+				return err
+			}
+		}
+		var extraRoots []*x509.Certificate
+		for _, root := range roots {
+			if !bytes.Equal(bytes.TrimSpace(utils.EncodeX509Certificate(root)), bytes.TrimSpace(caCert)) {
+				extraRoots = append(extraRoots, root)
+			}
+		}
+		err = writeExtraRoots(plan.Disk, caCertsDir, extraRoots)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NodeIDs returns the nodes as <kind>/<id>
+func NodeIDs(nodes []CATrustNode) string {
+	ids := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		ids = append(ids, n.Kind+"/"+n.ID)
+	}
+	return strings.Join(ids, ", ")
+}
+
+// ReissuePeerCertificates issues again the TLS or the sign certificate of a
+// peer by the current root of its local CA, once the root is rotated. The
+// peer uses it on its next start
+func ReissuePeerCertificates(peerID string, kind string) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers", peerID)
+	peerInitOpts, err := readPeerInitOptions(peerDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errdefs.Errorf(errdefs.ErrNodeNotFound, "peer %s does not exist", peerID)
+		}
+		return err
+	}
+	if !peerInitOpts.Local || peerInitOpts.CAName == "" {
+		return errors.Errorf("peer %s isn't enrolled with a local CA, enroll it again with its CA", peerInitOpts.ID)
+	}
+	caConfig, err := utils.GetCAConfig(peerInitOpts.CAName)
+	if err != nil {
+		return err
+	}
+	m := peerMaterial{}
+	if kind == certs.CAKindTLS {
+		err = issueLocalPeerTLS(plan.Disk, caConfig, peerInitOpts, &m)
+		if err != nil {
+			return err
+		}
+		return writePeerTLS(peerDir, m)
+	}
+	err = issueLocalPeerSign(plan.Disk, caConfig, peerInitOpts, &m)
+	if err != nil {
+		return err
+	}
+	return writePeerSign(peerDir, caConfig, m)
+}
+
+// writePeerSign replaces the sign certificate and key of a peer, in its
+// config.json too, and the root of its cacerts, which the NodeOUs point to.
+// The admin identity managed for the peer is issued again by the new root on
+// its next use
+func writePeerSign(peerDir string, caConfig *utils.CAConfig, m peerMaterial) error {
+	peerConfigPath := filepath.Join(peerDir, "config.json")
+	peerConfigBytes, err := os.ReadFile(peerConfigPath)
+	if err != nil {
+		return err
+	}
+	peerConfig := config.PeerConfig{}
+	err = json.Unmarshal(peerConfigBytes, &peerConfig)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s", peerConfigPath)
+	}
+	peerConfig.SignCert = utils.EncodeX509Certificate(m.SignCert)
+	peerConfig.SignKey = m.SignKey
+	peerConfig.CaCert = utils.EncodeX509Certificate(caConfig.CACert)
+	// a key kept in a secrets manager is replaced there and stays off the disk
+	if peerConfig.Secrets != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), secrets.DefaultTimeout)
+		err = storeSignKey(ctx, peerConfig.Secrets, "peer", filepath.Base(peerDir), m.SignKey)
+		cancel()
+		if err != nil {
+			return err
+		}
+		peerConfig.SignKey = nil
+	}
+	peerConfigBytes, err = json.MarshalIndent(peerConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		peerConfigPath: peerConfigBytes,
+		filepath.Join(peerDir, "signcerts", "cert.pem"): utils.EncodeX509Certificate(m.SignCert),
+		filepath.Join(peerDir, "cacerts", "cacert.pem"): utils.EncodeX509Certificate(caConfig.CACert),
+	}
+	if peerConfig.Secrets == "" {
+		files[filepath.Join(peerDir, "keystore", "key.pem")] = m.SignKey
+	}
+	defer utils.InvalidateConfig(peerConfigPath)
+	for path, content := range files {
+		err = os.WriteFile(path, content, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	nodes := []CATrustNode{{Kind: "peer", ID: filepath.Base(peerDir), dir: peerDir}}
+	err = SyncCATrust(nodes, certs.CAKindSign, certs.TrustedRoots(caConfig, certs.CAKindSign))
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(peerDir, "gateway-admin.yaml"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package node
+
+import (
+	"hlf-easy/certs"
+	"hlf-easy/config"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncCATrust(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer0", MSPID: "Org1MSP", Hosts: []string{"localhost"}})
+	caConfig, err := utils.GetCAConfig("org1-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousTLSCA := caConfig.TLSCACert
+	nodes, err := CATrustingNodes(certs.CAKindTLS, certs.TrustedRoots(caConfig, certs.CAKindTLS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].ID != "peer0" || !nodes[0].Issued {
+		t.Fatalf("expected peer0 to trust the TLS CA, got %+v", nodes)
+	}
+
+	_, err = certs.RotateCA("org1-ca", certs.CAKindTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caConfig, err = utils.GetCAConfig("org1-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := certs.TrustedRoots(caConfig, certs.CAKindTLS)
+	err = SyncCATrust(nodes, certs.CAKindTLS, roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a peer initialized during the rotation trusts both roots too
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer1", MSPID: "Org1MSP", Hosts: []string{"localhost"}})
+	for _, peerID := range []string{"peer0", "peer1"} {
+		trusted, err := nodeRoots(filepath.Join(home, "hlf-easy/peers", peerID), certs.CAKindTLS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trusted) != 2 || !trusted[0].Equal(caConfig.TLSCACert) || !trusted[1].Equal(previousTLSCA) {
+			t.Fatalf("expected %s to trust the new and the previous TLS roots", peerID)
+		}
+	}
+	nodes, err = CATrustingNodes(certs.CAKindTLS, roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[0].Issued || !nodes[1].Issued {
+		t.Fatalf("expected only peer1 to be issued by the new root, got %+v", nodes)
+	}
+
+	_, err = certs.RotateCA("org1-ca", certs.CAKindSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caConfig, err = utils.GetCAConfig("org1-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signRoots := certs.TrustedRoots(caConfig, certs.CAKindSign)
+	signNodes, err := CATrustingNodes(certs.CAKindSign, signRoots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SyncCATrust(signNodes, certs.CAKindSign, signRoots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the NodeOUs keep pointing to the root that issued the peer
+	if _, err := os.Stat(filepath.Join(home, "hlf-easy/peers/peer0/cacerts", extraRootFile(caConfig.CACert))); err != nil {
+		t.Fatal(err)
+	}
+	orgMSP, err := GetOrgMSP("Org1MSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !orgMSP.CACert.Equal(caConfig.PreviousCACerts[0]) || len(orgMSP.ExtraCACerts) != 1 || !orgMSP.ExtraCACerts[0].Equal(caConfig.CACert) {
+		t.Fatal("expected the MSP of the org to trust the new signing root besides the one of its peers")
+	}
+	if len(orgMSP.ExtraTLSCACerts) != 1 || !orgMSP.ExtraTLSCACerts[0].Equal(previousTLSCA) {
+		t.Fatal("expected the MSP of the org to trust the previous TLS root")
+	}
+}
@@ -0,0 +1,359 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// ClonePeerOptions are the options of a new peer cloned from a peer of the
+// host, the settings of the source are kept unless they're overridden
+type ClonePeerOptions struct {
+	SourceID string
+	ID       string
+	// Hosts replace the hosts of the source, the first one is the host of
+	// the external endpoint of the clone
+	Hosts            []string
+	ExternalPort     int
+	ExternalEndpoint string
+	// OperationsListenAddress is required when the source binds its
+	// operations endpoint to an address of its init options
+	OperationsListenAddress string
+	// EnrollID and EnrollSecret enroll the clone with the Fabric CA of the
+	// source, they're unused with a local CA
+	EnrollID     string
+	EnrollSecret string
+	// Env overrides the extra environment variables of the source
+	Env config.EnvVars
+	// FromSnapshot joins the clone to the channels with the last completed
+	// snapshots of the source instead of their genesis blocks
+	FromSnapshot bool
+	// Identity is the identity file of an admin of the org joining the clone
+	// to the channels, the admin identity managed for the clone when empty
+	Identity string
+}
+
+// CloneJoin is a channel of the source a clone joins once it's ready, with
+// the genesis block of the channel or a snapshot of the source
+type CloneJoin struct {
+	Channel      string `json:"channel"`
+	GenesisBlock string `json:"genesisBlock,omitempty"`
+	Snapshot     string `json:"snapshot,omitempty"`
+	// Height is the height of the ledger of the snapshot
+	Height uint64 `json:"height,omitempty"`
+}
+
+// PeerClone is the source of a cloned peer and the channels it still has to
+// join, written to clone.json in the directory of the clone until they're
+// all joined
+type PeerClone struct {
+	Source   string      `json:"source"`
+	Identity string      `json:"identity,omitempty"`
+	Joins    []CloneJoin `json:"joins"`
+}
+
+// clonePeerInitOptions returns the init options of the clone of a peer, the
+// endpoints and the state database of the clone can't be the ones of the
+// source
+func clonePeerInitOptions(source config.PeerInitOptions, opts ClonePeerOptions) (config.PeerInitOptions, error) {
+	if source.ExternalCA {
+		return config.PeerInitOptions{}, errors.Errorf("peer %s is enrolled by an external CA, initialize the new peer with peer csr and join its channels", source.ID)
+	}
+	clone := source
+	clone.ID = opts.ID
+	if len(opts.Hosts) > 0 {
+		clone.Hosts = opts.Hosts
+	}
+	if opts.ExternalPort != 0 {
+		clone.ExternalPort = opts.ExternalPort
+	}
+	clone.ExternalEndpoint = opts.ExternalEndpoint
+	if clone.ExternalEndpoint == "" && len(opts.Hosts) == 0 && opts.ExternalPort == 0 {
+		clone.ExternalEndpoint = source.ExternalEndpoint
+	}
+	if endpoint := peerExternalEndpoint(clone); endpoint == peerExternalEndpoint(source) {
+		return config.PeerInitOptions{}, errors.Errorf("the new peer would have the external endpoint %s of peer %s, set --external-port or --hosts", endpoint, source.ID)
+	}
+	if !source.Local {
+		clone.EnrollID = opts.EnrollID
+		clone.EnrollSecret = opts.EnrollSecret
+	}
+	if opts.OperationsListenAddress != "" {
+		clone.Operations.ListenAddress = opts.OperationsListenAddress
+	} else if source.Operations.ListenAddress != "" {
+		return config.PeerInitOptions{}, errors.Errorf("--operations-listen-address is required, peer %s binds its operations endpoint to %s", source.ID, source.Operations.ListenAddress)
+	}
+	clone.Env = config.EnvVars{}
+	for name, value := range source.Env {
+		clone.Env[name] = value
+	}
+	for name, value := range opts.Env {
+		clone.Env[name] = value
+	}
+	// the databases of CouchDB are named after the channels and the
+	// chaincodes, two peers can't share them
+	if sourceURL, ok := peerCouchDBURL(source.Env); ok {
+		if cloneURL, ok := peerCouchDBURL(clone.Env); ok && cloneURL == sourceURL {
+			return config.PeerInitOptions{}, errors.Errorf("peer %s stores its state in CouchDB, set the CouchDB of the new peer with --env CORE_LEDGER_STATE_COUCHDBCONFIG_COUCHDBADDRESS", source.ID)
+		}
+	}
+	return clone, nil
+}
+
+// getPeerChannels returns the channels a peer joined, the directories of its
+// block store
+func getPeerChannels(peerDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(GetPeerDataDir(peerDir), "ledgersData/chains/chains"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	channels := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			channels = append(channels, entry.Name())
+		}
+	}
+	sort.Strings(channels)
+	return channels, nil
+}
+
+// readGenesisBlock reads the genesis block of a channel from the first block
+// file of the block store of a peer. The blocks are stored with the length of
+// their serialization, their header, their data and their metadata
+func readGenesisBlock(peerDir string, channel string) (*common.Block, error) {
+	blockFile := filepath.Join(GetPeerDataDir(peerDir), "ledgersData/chains/chains", channel, "blockfile_000000")
+	fileBytes, err := os.ReadFile(blockFile)
+	if err != nil {
+		return nil, err
+	}
+	length, n := proto.DecodeVarint(fileBytes)
+	if n == 0 || uint64(len(fileBytes)-n) < length {
+		return nil, errors.Errorf("%s has no complete block", blockFile)
+	}
+	buf := proto.NewBuffer(fileBytes[n : n+int(length)])
+	block := &common.Block{Header: &common.BlockHeader{}, Data: &common.BlockData{}, Metadata: &common.BlockMetadata{}}
+	if block.Header.Number, err = buf.DecodeVarint(); err != nil {
+		return nil, errors.Wrapf(err, "invalid block in %s", blockFile)
+	}
+	if block.Header.Number != 0 {
+		return nil, errors.Errorf("the block store of channel %s starts at block %d, the ledger was bootstrapped from a snapshot, clone it from a snapshot", channel, block.Header.Number)
+	}
+	if block.Header.DataHash, err = buf.DecodeRawBytes(true); err != nil {
+		return nil, errors.Wrapf(err, "invalid block in %s", blockFile)
+	}
+	if block.Header.PreviousHash, err = buf.DecodeRawBytes(true); err != nil {
+		return nil, errors.Wrapf(err, "invalid block in %s", blockFile)
+	}
+	for _, items := range []*[][]byte{&block.Data.Data, &block.Metadata.Metadata} {
+		count, err := buf.DecodeVarint()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid block in %s", blockFile)
+		}
+		for i := uint64(0); i < count; i++ {
+			item, err := buf.DecodeRawBytes(true)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid block in %s", blockFile)
+			}
+			*items = append(*items, item)
+		}
+	}
+	return block, nil
+}
+
+// latestSnapshot returns the directory of the completed snapshot of the
+// channel with the highest height, and its height
+func latestSnapshot(peerDir string, channel string) (string, uint64, error) {
+	channelDir := filepath.Join(GetPeerDataDir(peerDir), "snapshots/completed", channel)
+	entries, err := os.ReadDir(channelDir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", 0, err
+	}
+	latest, found := uint64(0), false
+	for _, entry := range entries {
+		height, err := strconv.ParseUint(entry.Name(), 10, 64)
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		if !found || height > latest {
+			latest, found = height, true
+		}
+	}
+	if !found {
+		return "", 0, errors.Errorf("no completed snapshot of channel %s, request one with a snapshot task", channel)
+	}
+	return filepath.Join(channelDir, strconv.FormatUint(latest, 10)), latest, nil
+}
+
+// ClonePeer initializes a new peer with the settings of a peer of the host,
+// its certificates are enrolled with the CA of the source. The channels of the
+// source, with their genesis blocks or the last snapshots of the source, are
+// written to clone.json so the clone joins them once it's started
+func ClonePeer(ctx context.Context, opts ClonePeerOptions) (*PeerClone, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	sourceDir := filepath.Join(home, "hlf-easy/peers", opts.SourceID)
+	cloneDir := filepath.Join(home, "hlf-easy/peers", opts.ID)
+	source, err := readPeerInitOptions(sourceDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errdefs.Errorf(errdefs.ErrNodeNotFound, "peer %s does not exist", opts.SourceID)
+		}
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(cloneDir, "init.json")); err == nil {
+		return nil, errors.Errorf("peer %s already exists", opts.ID)
+	}
+	peerInitOpts, err := clonePeerInitOptions(source, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidatePeerInitOptions(peerInitOpts); err != nil {
+		return nil, err
+	}
+	channels, err := getPeerChannels(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+	// the ledgers of the source are read before the clone is enrolled so a
+	// channel that can't be cloned leaves nothing behind
+	clone := &PeerClone{Source: opts.SourceID, Identity: opts.Identity, Joins: []CloneJoin{}}
+	blocks := map[string]*common.Block{}
+	for _, channel := range channels {
+		join := CloneJoin{Channel: channel}
+		if opts.FromSnapshot {
+			join.Snapshot, join.Height, err = latestSnapshot(sourceDir, channel)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "peer %s", opts.SourceID)
+			}
+		} else {
+			blocks[channel], err = readGenesisBlock(sourceDir, channel)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read the genesis block of channel %s", channel)
+			}
+			join.GenesisBlock = filepath.Join(cloneDir, "clone", channel+".block")
+		}
+		clone.Joins = append(clone.Joins, join)
+	}
+	err = EnrollPeerCertificates(ctx, peerInitOpts)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) > 0 {
+		if err := os.MkdirAll(filepath.Join(cloneDir, "clone"), 0755); err != nil {
+			return nil, err
+		}
+	}
+	for _, join := range clone.Joins {
+		if join.GenesisBlock == "" {
+			continue
+		}
+		blockBytes, err := proto.Marshal(blocks[join.Channel])
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(join.GenesisBlock, blockBytes, 0644); err != nil {
+			return nil, err
+		}
+	}
+	if err := writePeerClone(cloneDir, clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+func writePeerClone(peerDir string, clone *PeerClone) error {
+	cloneBytes, err := json.MarshalIndent(clone, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(peerDir, "clone.json"), cloneBytes, 0644)
+}
+
+// RestorePeerJoins records the snapshots a restored peer joins its channels
+// from once it's started, as the channels of a clone of itself
+func RestorePeerJoins(peerID string, joins []CloneJoin) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	return writePeerClone(filepath.Join(home, "hlf-easy/peers", peerID), &PeerClone{Source: peerID, Joins: joins})
+}
+
+// GetPeerClone returns the channels a cloned peer still has to join, nil
+// when the peer isn't a clone or joined them all. The channels the peer
+// joined since it was cloned are left out
+func GetPeerClone(peerID string) (*PeerClone, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers", peerID)
+	cloneBytes, err := os.ReadFile(filepath.Join(peerDir, "clone.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	clone := &PeerClone{}
+	if err := json.Unmarshal(cloneBytes, clone); err != nil {
+		return nil, errors.Wrapf(err, "invalid clone.json of peer %s", peerID)
+	}
+	joined, err := getPeerChannels(peerDir)
+	if err != nil {
+		return nil, err
+	}
+	isJoined := map[string]bool{}
+	for _, channel := range joined {
+		isJoined[channel] = true
+	}
+	pending := []CloneJoin{}
+	for _, join := range clone.Joins {
+		if !isJoined[join.Channel] {
+			pending = append(pending, join)
+		}
+	}
+	clone.Joins = pending
+	return clone, nil
+}
+
+// ReadCloneGenesisBlock reads a genesis block written by ClonePeer
+func ReadCloneGenesisBlock(path string) (*common.Block, error) {
+	blockBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block := &common.Block{}
+	if err := proto.Unmarshal(blockBytes, block); err != nil {
+		return nil, errors.Wrapf(err, "invalid genesis block %s", path)
+	}
+	return block, nil
+}
+
+// CompletePeerClone removes the clone.json of a peer, and the genesis blocks
+// of its channels, once it joined all the channels of its source
+func CompletePeerClone(peerID string) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers", peerID)
+	if err := os.RemoveAll(filepath.Join(peerDir, "clone")); err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(peerDir, "clone.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove the clone.json of peer %s", peerID)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package node
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClonePeerInitOptions(t *testing.T) {
+	source := config.PeerInitOptions{
+		ID:           "peer0",
+		Local:        true,
+		CAName:       "org1-ca",
+		MSPID:        "Org1MSP",
+		Hosts:        []string{"localhost"},
+		ExternalPort: 7051,
+		Env:          config.EnvVars{"CORE_PEER_KEEPALIVE_MININTERVAL": "30s"},
+	}
+	clone, err := clonePeerInitOptions(source, ClonePeerOptions{ID: "peer1", ExternalPort: 8051, Env: config.EnvVars{"HTTPS_PROXY": "proxy:3128"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.ID != "peer1" || clone.MSPID != "Org1MSP" || clone.CAName != "org1-ca" || peerExternalEndpoint(clone) != "localhost:8051" {
+		t.Fatalf("unexpected clone %+v", clone)
+	}
+	if clone.Env["CORE_PEER_KEEPALIVE_MININTERVAL"] != "30s" || clone.Env["HTTPS_PROXY"] != "proxy:3128" || len(source.Env) != 1 {
+		t.Fatalf("expected the env of the source to be copied and overridden, got %v and %v", clone.Env, source.Env)
+	}
+	if _, err := clonePeerInitOptions(source, ClonePeerOptions{ID: "peer1"}); err == nil {
+		t.Fatal("expected the endpoint of the source to be refused")
+	}
+	source.Operations.ListenAddress = "127.0.0.1:9443"
+	if _, err := clonePeerInitOptions(source, ClonePeerOptions{ID: "peer1", ExternalPort: 8051}); err == nil {
+		t.Fatal("expected the operations address of the source to be refused")
+	}
+	source.Operations.ListenAddress = ""
+	source.Env = config.EnvVars{"CORE_LEDGER_STATE_STATEDATABASE": "CouchDB"}
+	if _, err := clonePeerInitOptions(source, ClonePeerOptions{ID: "peer1", ExternalPort: 8051}); err == nil {
+		t.Fatal("expected the CouchDB of the source to be refused")
+	}
+	_, err = clonePeerInitOptions(source, ClonePeerOptions{ID: "peer1", ExternalPort: 8051, Env: config.EnvVars{"CORE_LEDGER_STATE_COUCHDBCONFIG_COUCHDBADDRESS": "127.0.0.1:6984"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.ExternalCA = true
+	if _, err := clonePeerInitOptions(source, ClonePeerOptions{ID: "peer1", ExternalPort: 8051}); err == nil {
+		t.Fatal("expected a peer enrolled by an external CA to be refused")
+	}
+}
+
+// writeBlockFile writes the block as the block store of the peer serializes it
+func writeBlockFile(t *testing.T, peerDir string, channel string, block *common.Block) {
+	t.Helper()
+	buf := proto.NewBuffer(nil)
+	buf.EncodeVarint(block.Header.Number)
+	buf.EncodeRawBytes(block.Header.DataHash)
+	buf.EncodeRawBytes(block.Header.PreviousHash)
+	buf.EncodeVarint(uint64(len(block.Data.Data)))
+	for _, data := range block.Data.Data {
+		buf.EncodeRawBytes(data)
+	}
+	buf.EncodeVarint(uint64(len(block.Metadata.Metadata)))
+	for _, metadata := range block.Metadata.Metadata {
+		buf.EncodeRawBytes(metadata)
+	}
+	blockBytes := append(proto.EncodeVarint(uint64(len(buf.Bytes()))), buf.Bytes()...)
+	chainDir := filepath.Join(GetPeerDataDir(peerDir), "ledgersData/chains/chains", channel)
+	if err := os.MkdirAll(chainDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(chainDir, "blockfile_000000"), append(blockBytes, 0x0a, 0x0b), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadGenesisBlock(t *testing.T) {
+	peerDir := t.TempDir()
+	genesis := &common.Block{
+		Header:   &common.BlockHeader{Number: 0, DataHash: []byte("hash")},
+		Data:     &common.BlockData{Data: [][]byte{[]byte("config envelope")}},
+		Metadata: &common.BlockMetadata{Metadata: [][]byte{{}, []byte("last config"), {}, {}, {}}},
+	}
+	writeBlockFile(t, peerDir, "mychannel", genesis)
+	block, err := readGenesisBlock(peerDir, "mychannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(block, genesis) {
+		t.Fatalf("expected %v, got %v", genesis, block)
+	}
+	channels, err := getPeerChannels(peerDir)
+	if err != nil || len(channels) != 1 || channels[0] != "mychannel" {
+		t.Fatalf("expected mychannel, got %v, %v", channels, err)
+	}
+	genesis.Header.Number = 42
+	writeBlockFile(t, peerDir, "snapshotted", genesis)
+	if _, err := readGenesisBlock(peerDir, "snapshotted"); err == nil || !strings.Contains(err.Error(), "snapshot") {
+		t.Fatalf("expected a ledger bootstrapped from a snapshot to be refused, got %v", err)
+	}
+}
+
+func TestLatestSnapshot(t *testing.T) {
+	peerDir := t.TempDir()
+	if _, _, err := latestSnapshot(peerDir, "mychannel"); err == nil {
+		t.Fatal("expected a channel without snapshots to fail")
+	}
+	for _, name := range []string{"9", "120", "35", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(GetPeerDataDir(peerDir), "snapshots/completed/mychannel", name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir, height, err := latestSnapshot(peerDir, "mychannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != 120 || filepath.Base(dir) != "120" {
+		t.Fatalf("expected the snapshot at height 120, got %s at %d", dir, height)
+	}
+}
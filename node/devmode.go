@@ -0,0 +1,86 @@
+package node
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/plan"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// SetPeerDevMode renders the core.yaml of a peer with the chaincode mode dev
+// or net, it returns true when the mode changed
+func SetPeerDevMode(peerID string, enabled bool) (bool, error) {
+	changed := false
+	err := withLock("peer", peerID, "peer.devmode", func() error {
+		var err error
+		changed, err = setPeerDevMode(peerID, enabled)
+		return err
+	})
+	return changed, err
+}
+
+func setPeerDevMode(peerID string, enabled bool) (bool, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false, err
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers", peerID)
+	peerInitOpts, err := readPeerInitOptions(peerDir)
+	if err != nil {
+		return false, err
+	}
+	if peerInitOpts.DevMode == enabled {
+		return false, nil
+	}
+	peerInitOpts.DevMode = enabled
+	initBytes, err := json.Marshal(peerInitOpts)
+	if err != nil {
+		return false, err
+	}
+	err = os.WriteFile(filepath.Join(peerDir, "init.json"), initBytes, 0644)
+	if err != nil {
+		return false, err
+	}
+	bootstrap, err := GetPeerGossipBootstrap(peerInitOpts)
+	if err != nil {
+		return false, err
+	}
+	return true, renderPeerCoreYaml(plan.Disk, peerDir, peerInitOpts, bootstrap)
+}
+
+// GetDevPeerChaincodeAddress returns the address the chaincodes run by their
+// developers connect to, the peer must be running in dev mode
+func GetDevPeerChaincodeAddress(peerID string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers", peerID)
+	runConfigBytes, err := os.ReadFile(filepath.Join(peerDir, "run.json"))
+	if os.IsNotExist(err) {
+		return "", errors.Errorf("peer %s isn't running, start it with peer start --dev-mode", peerID)
+	}
+	if err != nil {
+		return "", err
+	}
+	runConfig := config.PeerRunConfig{}
+	err = json.Unmarshal(runConfigBytes, &runConfig)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse the run.json of peer %s", peerID)
+	}
+	if !runConfig.Options.DevMode {
+		return "", errors.Errorf("peer %s isn't running in dev mode, restart it with peer start --dev-mode", peerID)
+	}
+	host, port, err := net.SplitHostPort(runConfig.Options.ChaincodeAddress)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid chaincode address of peer %s", peerID)
+	}
+	// the chaincodes run in the host of the peer
+	if host == "" || net.ParseIP(host).IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,83 @@
+package node
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetPeerDevMode(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peerDir := writeTestPeer(t, home, config.PeerInitOptions{ID: "peer0", MSPID: "Org1MSP"})
+
+	changed, err := SetPeerDevMode("peer0", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("expected the mode of the peer to change")
+	}
+	coreYaml, err := os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(coreYaml), "  mode: dev\n") {
+		t.Fatal("expected the chaincode mode dev")
+	}
+	changed, err = SetPeerDevMode("peer0", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Fatal("expected the mode of the peer not to change")
+	}
+
+	_, err = SetPeerDevMode("peer0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreYaml, err = os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(coreYaml), "  mode: net\n") {
+		t.Fatal("expected the chaincode mode net")
+	}
+}
+
+func TestGetDevPeerChaincodeAddress(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peerDir := writeTestPeer(t, home, config.PeerInitOptions{ID: "peer0"})
+	if _, err := GetDevPeerChaincodeAddress("peer0"); err == nil {
+		t.Fatal("expected an error for a stopped peer")
+	}
+
+	writeRunConfig := func(opts config.PeerStartOptions) {
+		runBytes, err := json.Marshal(config.PeerRunConfig{PeerID: "peer0", Options: opts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(peerDir, "run.json"), runBytes, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeRunConfig(config.PeerStartOptions{ID: "peer0", ChaincodeAddress: "0.0.0.0:7052"})
+	if _, err := GetDevPeerChaincodeAddress("peer0"); err == nil {
+		t.Fatal("expected an error for a peer in net mode")
+	}
+
+	writeRunConfig(config.PeerStartOptions{ID: "peer0", ChaincodeAddress: "0.0.0.0:7052", DevMode: true})
+	address, err := GetDevPeerChaincodeAddress("peer0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != "127.0.0.1:7052" {
+		t.Fatalf("expected the chaincode address of the host, got %s", address)
+	}
+}
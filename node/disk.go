@@ -0,0 +1,86 @@
+package node
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultDiskUsageThreshold is the usage in percent of the filesystem of the
+// ledger above which a peer is flagged as running out of disk
+const DefaultDiskUsageThreshold = 90
+
+// DefaultDiskUsageInterval is how often the ledger of a peer is measured,
+// walking a large ledger isn't free
+const DefaultDiskUsageInterval = 5 * time.Minute
+
+// DiskUsage is the size of the ledger of a peer and the usage of the
+// filesystem hosting it
+type DiskUsage struct {
+	// Path is the fileSystemPath of the peer, its data directory
+	Path string `json:"path"`
+	// LedgerBytes is the size of the data directory of the peer
+	LedgerBytes uint64 `json:"ledgerBytes"`
+	// GrowthPerHour is how many bytes the ledger grew by per hour since the
+	// previous check, 0 on the first check
+	GrowthPerHour float64 `json:"growthPerHour"`
+	// TotalBytes, FreeBytes and UsedPercent are the ones of the filesystem
+	TotalBytes  uint64  `json:"totalBytes"`
+	FreeBytes   uint64  `json:"freeBytes"`
+	UsedPercent float64 `json:"usedPercent"`
+	Threshold   float64 `json:"threshold"`
+	// High is true when the filesystem is used over the threshold
+	High      bool      `json:"high"`
+	CheckedAt time.Time `json:"checkedAt"`
+	// Error is set when the ledger or the filesystem couldn't be measured
+	Error string `json:"error,omitempty"`
+}
+
+// GetPeerDataDir returns the fileSystemPath of a peer, the directory of its
+// ledgers and snapshots
+func GetPeerDataDir(peerDir string) string {
+	return filepath.Join(peerDir, "data")
+}
+
+// DirSize returns the size of the regular files of a directory, the files
+// removed while it's walked are skipped
+func DirSize(dir string) (uint64, error) {
+	var size uint64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path != dir {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		size += uint64(info.Size())
+		return nil
+	})
+	return size, err
+}
+
+// LedgerGrowth returns the growth of the ledger in bytes per hour between two
+// measures, a ledger pruned or reset doesn't grow
+func LedgerGrowth(previous uint64, current uint64, elapsed time.Duration) float64 {
+	if elapsed <= 0 || current <= previous {
+		return 0
+	}
+	return float64(current-previous) / elapsed.Hours()
+}
+
+// SetDiskUsage sets the source of the disk usage reported in the status of
+// the peer, it's set before the peer starts
+func (n *PeerNode) SetDiskUsage(diskUsage func() *DiskUsage) {
+	n.diskUsage = diskUsage
+}
@@ -0,0 +1,48 @@
+package node
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]int{
+		"ledgersData/chains/chains/mychannel/blockfile_000000": 100,
+		"ledgersData/stateLeveldb/000001.log":                  20,
+		"snapshots/completed/mychannel/1/txids.data":           3,
+	}
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	size, err := DirSize(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 123 {
+		t.Fatalf("expected 123 bytes, got %d", size)
+	}
+	if _, err := DirSize(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+}
+
+func TestLedgerGrowth(t *testing.T) {
+	if growth := LedgerGrowth(1000, 3000, 30*time.Minute); growth != 4000 {
+		t.Fatalf("expected 4000 bytes per hour, got %v", growth)
+	}
+	if growth := LedgerGrowth(3000, 1000, time.Hour); growth != 0 {
+		t.Fatalf("expected a pruned ledger not to grow, got %v", growth)
+	}
+	if growth := LedgerGrowth(1000, 3000, 0); growth != 0 {
+		t.Fatalf("expected no growth without elapsed time, got %v", growth)
+	}
+}
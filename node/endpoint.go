@@ -0,0 +1,107 @@
+package node
+
+import (
+	"github.com/pkg/errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// isWildcardHost returns true for a wildcard DNS name, *.example.com
+func isWildcardHost(host string) bool {
+	return strings.HasPrefix(host, "*.")
+}
+
+// validateHost checks a host of the TLS certificate of a node, an IP or a DNS
+// name, a wildcard only as its whole leftmost label of a name of at least
+// three labels like *.peers.example.com
+func validateHost(host string) error {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	name := host
+	if isWildcardHost(host) {
+		name = strings.TrimPrefix(host, "*.")
+		if strings.Count(name, ".") < 1 {
+			return errors.Errorf("invalid wildcard host %q, the wildcard must be followed by at least two labels like *.peers.example.com", host)
+		}
+	}
+	if name == "" || strings.Contains(name, "*") {
+		return errors.Errorf("invalid host %q, a wildcard is only allowed as the leftmost label like *.example.com", host)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || strings.ContainsAny(label, " /:") {
+			return errors.Errorf("invalid host %q", host)
+		}
+	}
+	return nil
+}
+
+// ValidateEndpoints checks the hosts of the TLS certificate of a node and its
+// external endpoint, the endpoint it's advertised on when it's behind a
+// reverse proxy or a load balancer. Without an external endpoint one of the
+// hosts must not be a wildcard to build it
+func ValidateEndpoints(hosts []string, externalEndpoint string) error {
+	for _, host := range hosts {
+		if err := validateHost(host); err != nil {
+			return err
+		}
+	}
+	if externalEndpoint == "" {
+		if len(hosts) > 0 && endpointHost(hosts) == "" {
+			return errors.New("the hosts are wildcards, the external endpoint is required")
+		}
+		return nil
+	}
+	host, port, err := net.SplitHostPort(externalEndpoint)
+	if err != nil {
+		return errors.Wrapf(err, "invalid external endpoint %q", externalEndpoint)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return errors.Errorf("invalid port of the external endpoint %q", externalEndpoint)
+	}
+	if isWildcardHost(host) {
+		return errors.Errorf("the external endpoint %q can't be a wildcard", externalEndpoint)
+	}
+	return validateHost(host)
+}
+
+// endpointHost returns the host the external endpoint of a node is built with
+// when it has none, its first host that isn't a wildcard
+func endpointHost(hosts []string) string {
+	for _, host := range hosts {
+		if !isWildcardHost(host) {
+			return host
+		}
+	}
+	return ""
+}
+
+// hostCovered returns true when a host is one of the hosts of a TLS
+// certificate, or matches one of its wildcards on a single label
+func hostCovered(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+		if isWildcardHost(h) && net.ParseIP(host) == nil {
+			label, rest, found := strings.Cut(host, ".")
+			if found && label != "" && strings.EqualFold(rest, strings.TrimPrefix(h, "*.")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// certificateHosts returns the hosts of the TLS certificate of a node, its
+// hosts and the host of its external endpoint when they don't cover it, the
+// clients connecting through a proxy verify the certificate with that host
+func certificateHosts(hosts []string, externalEndpoint string) []string {
+	result := append([]string{}, hosts...)
+	host, _, err := net.SplitHostPort(externalEndpoint)
+	if err != nil || host == "" || hostCovered(hosts, host) {
+		return result
+	}
+	return append(result, host)
+}
@@ -0,0 +1,61 @@
+package node
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateEndpoints(t *testing.T) {
+	valid := []struct {
+		hosts    []string
+		endpoint string
+	}{
+		{[]string{"localhost", "127.0.0.1"}, ""},
+		{[]string{"*.peers.example.com", "localhost"}, ""},
+		{[]string{"*.peers.example.com"}, "peer1.peers.example.com:443"},
+		{[]string{"localhost"}, "lb.example.com:7051"},
+	}
+	for _, tc := range valid {
+		if err := ValidateEndpoints(tc.hosts, tc.endpoint); err != nil {
+			t.Errorf("unexpected error for %v %q: %v", tc.hosts, tc.endpoint, err)
+		}
+	}
+	invalid := []struct {
+		hosts    []string
+		endpoint string
+	}{
+		{[]string{"*.peers.example.com"}, ""},
+		{[]string{"*.com"}, "peer1.com:443"},
+		{[]string{"peer*.example.com"}, "peer1.example.com:443"},
+		{[]string{"*.*.example.com"}, "peer1.example.com:443"},
+		{[]string{"localhost"}, "lb.example.com"},
+		{[]string{"localhost"}, "lb.example.com:0"},
+		{[]string{"localhost"}, "*.example.com:443"},
+	}
+	for _, tc := range invalid {
+		if err := ValidateEndpoints(tc.hosts, tc.endpoint); err == nil {
+			t.Errorf("expected an error for %v %q", tc.hosts, tc.endpoint)
+		}
+	}
+}
+
+func TestCertificateHosts(t *testing.T) {
+	hosts := []string{"*.peers.example.com", "localhost"}
+	if got := certificateHosts(hosts, "peer1.peers.example.com:443"); !reflect.DeepEqual(got, hosts) {
+		t.Fatalf("expected the wildcard to cover the endpoint, got %v", got)
+	}
+	expected := []string{"*.peers.example.com", "localhost", "a.b.peers.example.com"}
+	if got := certificateHosts(hosts, "a.b.peers.example.com:443"); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, the wildcard covers a single label, got %v", expected, got)
+	}
+	expected = []string{"*.peers.example.com", "localhost", "lb.example.com"}
+	if got := certificateHosts(hosts, "lb.example.com:7051"); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	if got := certificateHosts(hosts, ""); !reflect.DeepEqual(got, hosts) {
+		t.Fatalf("expected the hosts without an endpoint, got %v", got)
+	}
+	if host := endpointHost(hosts); host != "localhost" {
+		t.Fatalf("expected the first host that isn't a wildcard, got %q", host)
+	}
+}
@@ -0,0 +1,87 @@
+package node
+
+import (
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateEnv checks the names of the extra environment variables of a node
+func ValidateEnv(env config.EnvVars) error {
+	for name := range env {
+		if !envNameRegexp.MatchString(name) {
+			return errors.Errorf("invalid environment variable name %q", name)
+		}
+	}
+	return nil
+}
+
+// WithEnv adds the extra environment variables to the environment of a node
+// process, they replace the variables of hlf-easy with the same name
+func WithEnv(env []string, extra config.EnvVars) []string {
+	if len(extra) == 0 {
+		return env
+	}
+	withEnv := make([]string, 0, len(env)+len(extra))
+	for _, v := range env {
+		name, _, _ := strings.Cut(v, "=")
+		if _, ok := extra[name]; !ok {
+			withEnv = append(withEnv, v)
+		}
+	}
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		withEnv = append(withEnv, name+"="+extra[name])
+	}
+	return withEnv
+}
+
+// defaultCouchDBAddress is the couchDBAddress of the core.yaml of the peers
+const defaultCouchDBAddress = "127.0.0.1:5984"
+
+// GetPeerCouchDBURL returns the URL of the CouchDB of a peer of the host with
+// its credentials, false when its state database is goleveldb
+func GetPeerCouchDBURL(peerID string) (string, bool, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false, err
+	}
+	peerInitOpts, err := readPeerInitOptions(filepath.Join(home, "hlf-easy/peers", peerID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, errdefs.Errorf(errdefs.ErrNodeNotFound, "peer %s does not exist", peerID)
+		}
+		return "", false, err
+	}
+	couchDBURL, ok := peerCouchDBURL(peerInitOpts.Env)
+	return couchDBURL, ok, nil
+}
+
+// peerCouchDBURL reads the CouchDB of a peer from its extra environment
+// variables, the state database is set with CORE_LEDGER_STATE_STATEDATABASE
+func peerCouchDBURL(env config.EnvVars) (string, bool) {
+	if !strings.EqualFold(env["CORE_LEDGER_STATE_STATEDATABASE"], "CouchDB") {
+		return "", false
+	}
+	address := env["CORE_LEDGER_STATE_COUCHDBCONFIG_COUCHDBADDRESS"]
+	if address == "" {
+		address = defaultCouchDBAddress
+	}
+	u := url.URL{Scheme: "http", Host: address}
+	if username := env["CORE_LEDGER_STATE_COUCHDBCONFIG_USERNAME"]; username != "" {
+		u.User = url.UserPassword(username, env["CORE_LEDGER_STATE_COUCHDBCONFIG_PASSWORD"])
+	}
+	return u.String(), true
+}
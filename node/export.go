@@ -0,0 +1,355 @@
+package node
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/secrets"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExportOrgOptions selects the org to export in the crypto-config layout of
+// cryptogen
+type ExportOrgOptions struct {
+	MSPID string
+	// Domain names the org directory, its peers and users like cryptogen does
+	Domain string
+	// OutputDir is the crypto-config directory, the org is written in its
+	// peerOrganizations directory
+	OutputDir string
+	// Identities are identity files, as written by ca enroll, exported as
+	// users of the org
+	Identities []string
+}
+
+// ExportedOrg lists what was exported
+type ExportedOrg struct {
+	MSPID string   `json:"mspID"`
+	Dir   string   `json:"dir"`
+	Peers []string `json:"peers"`
+	Users []string `json:"users"`
+}
+
+// orgCAs are the CAs of the MSP of an org, read from the MSP of one of its peers
+type orgCAs struct {
+	caCert               []byte
+	intermediateCerts    [][]byte
+	tlsCACert            []byte
+	tlsIntermediateCerts [][]byte
+}
+
+// ExportOrg writes the MSP of the org, the MSP and TLS material of its peers
+// and the identities in the crypto-config layout of cryptogen, so the tools
+// and samples of Fabric can use them. The keys of the CAs aren't exported
+func ExportOrg(opts ExportOrgOptions) (*ExportedOrg, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	peers, err := getPeersInitOptions()
+	if err != nil {
+		return nil, err
+	}
+	orgDir := filepath.Join(opts.OutputDir, "peerOrganizations", opts.Domain)
+	if _, err := os.Stat(orgDir); err == nil {
+		return nil, errors.Errorf("%s already exists, export the org to another directory", orgDir)
+	}
+	exported := &ExportedOrg{MSPID: opts.MSPID, Dir: orgDir}
+	var cas *orgCAs
+	for _, peer := range peers {
+		if peer.MSPID != opts.MSPID {
+			continue
+		}
+		peerDir := filepath.Join(home, "hlf-easy/peers", peer.ID)
+		peerCAs, err := readOrgCAs(peerDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the MSP of peer %s", peer.ID)
+		}
+		if cas == nil {
+			cas = peerCAs
+			err = writeExportedMSP(filepath.Join(orgDir, "msp"), opts.Domain, cas)
+			if err != nil {
+				return nil, err
+			}
+			err = writeExportedFile(filepath.Join(orgDir, "ca", fmt.Sprintf("ca.%s-cert.pem", opts.Domain)), cas.caCert, 0644)
+			if err != nil {
+				return nil, err
+			}
+			err = writeExportedFile(filepath.Join(orgDir, "tlsca", fmt.Sprintf("tlsca.%s-cert.pem", opts.Domain)), cas.tlsCACert, 0644)
+			if err != nil {
+				return nil, err
+			}
+		} else if !bytes.Equal(cas.caCert, peerCAs.caCert) || !bytes.Equal(cas.tlsCACert, peerCAs.tlsCACert) {
+			return nil, errors.Errorf("peer %s of %s has other CAs than the peer %s", peer.ID, opts.MSPID, exported.Peers[0])
+		}
+		name := exportedName(peer.ID, ".", opts.Domain)
+		err = exportPeer(peerDir, filepath.Join(orgDir, "peers", name), name, opts.Domain, cas)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to export peer %s", peer.ID)
+		}
+		exported.Peers = append(exported.Peers, peer.ID)
+	}
+	if cas == nil {
+		return nil, errors.Errorf("no peer of the host has the MSP ID %s", opts.MSPID)
+	}
+	for _, identityPath := range opts.Identities {
+		crt, key, err := utils.ReadIdentity(identityPath)
+		if err != nil {
+			return nil, err
+		}
+		keyBytes, err := utils.EncodePrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		name := exportedName(crt.Subject.CommonName, "@", opts.Domain)
+		userMSPDir := filepath.Join(orgDir, "users", name, "msp")
+		err = writeExportedMSP(userMSPDir, opts.Domain, cas)
+		if err != nil {
+			return nil, err
+		}
+		err = writeExportedFile(filepath.Join(userMSPDir, "signcerts", name+"-cert.pem"), utils.EncodeX509Certificate(crt), 0644)
+		if err != nil {
+			return nil, err
+		}
+		err = writeExportedFile(filepath.Join(userMSPDir, "keystore", "priv_sk"), keyBytes, 0600)
+		if err != nil {
+			return nil, err
+		}
+		exported.Users = append(exported.Users, name)
+	}
+	return exported, nil
+}
+
+// exportedName appends the domain to the name like cryptogen does, unless the
+// name already ends with it
+func exportedName(name string, separator string, domain string) string {
+	if strings.HasSuffix(name, separator+domain) {
+		return name
+	}
+	return name + separator + domain
+}
+
+func readOrgCAs(peerDir string) (*orgCAs, error) {
+	caCert, err := os.ReadFile(filepath.Join(peerDir, "cacerts", "cacert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	tlsCACert, err := os.ReadFile(filepath.Join(peerDir, "tlscacerts", "cacert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	cas := &orgCAs{caCert: caCert, tlsCACert: tlsCACert}
+	cas.intermediateCerts, err = readPEMFiles(filepath.Join(peerDir, "intermediatecerts"))
+	if err != nil {
+		return nil, err
+	}
+	cas.tlsIntermediateCerts, err = readPEMFiles(filepath.Join(peerDir, "tlsintermediatecerts"))
+	if err != nil {
+		return nil, err
+	}
+	return cas, nil
+}
+
+// readPEMFiles reads the intermediatecert-N.pem files of a directory in order
+func readPEMFiles(dir string) ([][]byte, error) {
+	var files [][]byte
+	for i := 0; ; i++ {
+		contents, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("intermediatecert-%d.pem", i)))
+		if os.IsNotExist(err) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, contents)
+	}
+}
+
+// writeExportedMSP writes the CAs and the NodeOUs of an MSP with the file
+// names of cryptogen
+func writeExportedMSP(mspDir string, domain string, cas *orgCAs) error {
+	caCertName := fmt.Sprintf("ca.%s-cert.pem", domain)
+	err := writeExportedFile(filepath.Join(mspDir, "cacerts", caCertName), cas.caCert, 0644)
+	if err != nil {
+		return err
+	}
+	err = writeExportedFile(filepath.Join(mspDir, "tlscacerts", fmt.Sprintf("tlsca.%s-cert.pem", domain)), cas.tlsCACert, 0644)
+	if err != nil {
+		return err
+	}
+	ouCertificate := "cacerts/" + caCertName
+	for i, intermediate := range cas.intermediateCerts {
+		err = writeExportedFile(filepath.Join(mspDir, "intermediatecerts", fmt.Sprintf("intermediatecert-%d.pem", i)), intermediate, 0644)
+		if err != nil {
+			return err
+		}
+		// the identities are issued by the first intermediate
+		ouCertificate = "intermediatecerts/intermediatecert-0.pem"
+	}
+	for i, intermediate := range cas.tlsIntermediateCerts {
+		err = writeExportedFile(filepath.Join(mspDir, "tlsintermediatecerts", fmt.Sprintf("intermediatecert-%d.pem", i)), intermediate, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	configYaml := fmt.Sprintf(`NodeOUs:
+  Enable: true
+  ClientOUIdentifier:
+    Certificate: %[1]s
+    OrganizationalUnitIdentifier: client
+  PeerOUIdentifier:
+    Certificate: %[1]s
+    OrganizationalUnitIdentifier: peer
+  AdminOUIdentifier:
+    Certificate: %[1]s
+    OrganizationalUnitIdentifier: admin
+  OrdererOUIdentifier:
+    Certificate: %[1]s
+    OrganizationalUnitIdentifier: orderer
+`, ouCertificate)
+	return writeExportedFile(filepath.Join(mspDir, "config.yaml"), []byte(configYaml), 0644)
+}
+
+// exportPeer writes the msp and tls directories of a peer, the TLS certificate
+// keeps its intermediates
+func exportPeer(peerDir string, exportDir string, name string, domain string, cas *orgCAs) error {
+	mspDir := filepath.Join(exportDir, "msp")
+	err := writeExportedMSP(mspDir, domain, cas)
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		src  string
+		dst  string
+		perm os.FileMode
+	}{
+		{"signcerts/cert.pem", filepath.Join(mspDir, "signcerts", name+"-cert.pem"), 0644},
+		{"keystore/key.pem", filepath.Join(mspDir, "keystore", "priv_sk"), 0600},
+		{"tls.crt", filepath.Join(exportDir, "tls", "server.crt"), 0644},
+		{"tls.key", filepath.Join(exportDir, "tls", "server.key"), 0600},
+		{"tlscacerts/cacert.pem", filepath.Join(exportDir, "tls", "ca.crt"), 0644},
+	}
+	// the signing key kept in a secrets manager is exported from it
+	secretsURL, err := getNodeSecrets(peerDir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		var contents []byte
+		if file.src == "keystore/key.pem" && secretsURL != "" {
+			contents, err = secrets.Fetch(secretsURL, secrets.KeyName("peer", filepath.Base(peerDir), secrets.SignKey))
+		} else {
+			contents, err = os.ReadFile(filepath.Join(peerDir, file.src))
+		}
+		if err != nil {
+			return err
+		}
+		err = writeExportedFile(file.dst, contents, file.perm)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeExportedFile(path string, contents []byte, perm os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, perm)
+}
+
+// OrgMSP are the CAs of the MSP of an org of the host
+type OrgMSP struct {
+	MSPID                string
+	CACert               *x509.Certificate
+	TLSCACert            *x509.Certificate
+	IntermediateCerts    []*x509.Certificate
+	TLSIntermediateCerts []*x509.Certificate
+	// ExtraCACerts and ExtraTLSCACerts are the other roots trusted by the
+	// peers while a CA of the org is being rotated
+	ExtraCACerts    []*x509.Certificate
+	ExtraTLSCACerts []*x509.Certificate
+}
+
+// GetOrgMSP reads the CAs of the MSP of an org from its peers of the host,
+// they must all have the same CAs
+func GetOrgMSP(mspID string) (*OrgMSP, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	peers, err := getPeersInitOptions()
+	if err != nil {
+		return nil, err
+	}
+	var cas *orgCAs
+	first := ""
+	for _, peer := range peers {
+		if peer.MSPID != mspID {
+			continue
+		}
+		peerCAs, err := readOrgCAs(filepath.Join(home, "hlf-easy/peers", peer.ID))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read the MSP of peer %s", peer.ID)
+		}
+		if cas == nil {
+			cas = peerCAs
+			first = peer.ID
+		} else if !bytes.Equal(cas.caCert, peerCAs.caCert) || !bytes.Equal(cas.tlsCACert, peerCAs.tlsCACert) {
+			return nil, errors.Errorf("peer %s of %s has other CAs than the peer %s", peer.ID, mspID, first)
+		}
+	}
+	if cas == nil {
+		return nil, errors.Errorf("no peer of the host has the MSP ID %s", mspID)
+	}
+	orgMSP := &OrgMSP{MSPID: mspID}
+	orgMSP.CACert, err = utils.ParseX509Certificate(cas.caCert)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid CA certificate")
+	}
+	// the TLS roots of a rotation are bundled, the current one first
+	tlsCACerts, err := utils.ParseX509CertificateChain(cas.tlsCACert)
+	if err != nil || len(tlsCACerts) == 0 {
+		return nil, errors.New("invalid TLS CA certificate")
+	}
+	orgMSP.TLSCACert, orgMSP.ExtraTLSCACerts = tlsCACerts[0], tlsCACerts[1:]
+	orgMSP.ExtraCACerts, err = readCertsDir(filepath.Join(home, "hlf-easy/peers", first, "cacerts"))
+	if err != nil {
+		return nil, err
+	}
+	// readCertsDir reads cacerts/cacert.pem too
+	extraCACerts := orgMSP.ExtraCACerts[:0]
+	for _, crt := range orgMSP.ExtraCACerts {
+		if !bytes.Equal(crt.Raw, orgMSP.CACert.Raw) {
+			extraCACerts = append(extraCACerts, crt)
+		}
+	}
+	orgMSP.ExtraCACerts = extraCACerts
+	orgMSP.IntermediateCerts, err = parsePEMFiles(cas.intermediateCerts)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid intermediate certificate")
+	}
+	orgMSP.TLSIntermediateCerts, err = parsePEMFiles(cas.tlsIntermediateCerts)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid TLS intermediate certificate")
+	}
+	return orgMSP, nil
+}
+
+func parsePEMFiles(files [][]byte) ([]*x509.Certificate, error) {
+	var crts []*x509.Certificate
+	for _, contents := range files {
+		crt, err := utils.ParseX509Certificate(contents)
+		if err != nil {
+			return nil, err
+		}
+		crts = append(crts, crt)
+	}
+	return crts, nil
+}
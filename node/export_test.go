@@ -0,0 +1,82 @@
+package node
+
+import (
+	"fmt"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportOrg(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer0", Hosts: []string{"peer0.org1.example.com"}, MSPID: "Org1MSP"})
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer1", Hosts: []string{"peer1.org1.example.com"}, MSPID: "Org1MSP"})
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer2", Hosts: []string{"peer2.org2.example.com"}, MSPID: "Org2MSP"})
+
+	adminCert, adminKey := testca.Issue(t, testca.Cert{CommonName: "Admin", OUs: []string{"admin"}}, nil, nil)
+	identityPath := filepath.Join(t.TempDir(), "admin.yaml")
+	identity := fmt.Sprintf("cert:\n  pem: |\n%s\nkey:\n  pem: |\n%s\n", indentPEM(utils.EncodeX509Certificate(adminCert)), indentPEM(encodeTestKey(t, adminKey)))
+	if err := os.WriteFile(identityPath, []byte(identity), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := t.TempDir()
+	opts := ExportOrgOptions{MSPID: "Org1MSP", Domain: "org1.example.com", OutputDir: outputDir, Identities: []string{identityPath}}
+	exported, err := ExportOrg(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exported.Peers, []string{"peer0", "peer1"}) {
+		t.Errorf("expected the peers of Org1MSP, got %v", exported.Peers)
+	}
+	if !reflect.DeepEqual(exported.Users, []string{"Admin@org1.example.com"}) {
+		t.Errorf("expected the admin user, got %v", exported.Users)
+	}
+	orgDir := filepath.Join(outputDir, "peerOrganizations/org1.example.com")
+	for _, file := range []string{
+		"msp/cacerts/ca.org1.example.com-cert.pem",
+		"msp/tlscacerts/tlsca.org1.example.com-cert.pem",
+		"msp/config.yaml",
+		"ca/ca.org1.example.com-cert.pem",
+		"tlsca/tlsca.org1.example.com-cert.pem",
+		"peers/peer0.org1.example.com/msp/signcerts/peer0.org1.example.com-cert.pem",
+		"peers/peer0.org1.example.com/msp/keystore/priv_sk",
+		"peers/peer0.org1.example.com/tls/server.crt",
+		"peers/peer0.org1.example.com/tls/server.key",
+		"peers/peer0.org1.example.com/tls/ca.crt",
+		"users/Admin@org1.example.com/msp/signcerts/Admin@org1.example.com-cert.pem",
+		"users/Admin@org1.example.com/msp/keystore/priv_sk",
+	} {
+		if _, err := os.Stat(filepath.Join(orgDir, file)); err != nil {
+			t.Errorf("expected %s to be exported: %v", file, err)
+		}
+	}
+
+	// the exported peer can be imported back
+	err = ImportPeerMSP(ImportPeerMSPOptions{
+		InitOptions: config.PeerInitOptions{ID: "peer3", MSPID: "Org1MSP"},
+		MSPDir:      filepath.Join(orgDir, "peers/peer0.org1.example.com/msp"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ExportOrg(opts); err == nil {
+		t.Fatal("expected an error exporting over an existing org")
+	}
+	opts.MSPID = "Org3MSP"
+	opts.OutputDir = t.TempDir()
+	if _, err := ExportOrg(opts); err == nil {
+		t.Fatal("expected an error for an org without peers")
+	}
+}
+
+func indentPEM(pemBytes []byte) string {
+	return "    " + strings.ReplaceAll(strings.TrimSpace(string(pemBytes)), "\n", "\n    ")
+}
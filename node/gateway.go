@@ -0,0 +1,248 @@
+package node
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/certs"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"hlf-easy/utils"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ValidateGateway checks the gateway settings of a peer
+func ValidateGateway(opts config.GatewayOptions) error {
+	for name, value := range map[string]string{
+		"endorsement timeout": opts.EndorsementTimeout,
+		"broadcast timeout":   opts.BroadcastTimeout,
+		"dial timeout":        opts.DialTimeout,
+	} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return errors.Errorf("invalid gateway %s %q, expected a positive duration like 30s", name, value)
+		}
+	}
+	return nil
+}
+
+// gatewayWithDefaults fills the empty gateway settings with the ones of Fabric
+func gatewayWithDefaults(opts config.GatewayOptions) config.GatewayOptions {
+	if opts.EndorsementTimeout == "" {
+		opts.EndorsementTimeout = "30s"
+	}
+	if opts.BroadcastTimeout == "" {
+		opts.BroadcastTimeout = "30s"
+	}
+	if opts.DialTimeout == "" {
+		opts.DialTimeout = "2m"
+	}
+	return opts
+}
+
+// GatewayClientConfigOptions select the peer and the identity of a gateway
+// client
+type GatewayClientConfigOptions struct {
+	PeerID string
+	// Identity is the identity file, as written by ca enroll, the client
+	// signs the transactions with
+	Identity string
+	// Endpoint overrides the external endpoint of the peer
+	Endpoint string
+}
+
+// GatewayIdentity is the PEM encoded certificate and key of a gateway client
+type GatewayIdentity struct {
+	Cert string `json:"cert" yaml:"cert"`
+	Key  string `json:"key" yaml:"key"`
+}
+
+// GatewayClientConfig is what a client of the fabric-gateway SDKs needs to
+// connect to the gateway of a peer
+type GatewayClientConfig struct {
+	MSPID    string `json:"mspID" yaml:"mspID"`
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	// ServerName overrides the host of the endpoint to verify the TLS
+	// certificate of the peer, it's empty when the endpoint host is valid
+	ServerName string `json:"serverName,omitempty" yaml:"serverName,omitempty"`
+	// TLSRootCerts are the PEM encoded TLS CA and intermediates of the peer
+	TLSRootCerts string          `json:"tlsRootCerts" yaml:"tlsRootCerts"`
+	Identity     GatewayIdentity `json:"identity" yaml:"identity"`
+}
+
+// NewGatewayClientConfig returns the endpoint, TLS roots and identity a
+// fabric-gateway client needs to connect to the gateway of a peer. The
+// identity must belong to the MSP of the peer
+func NewGatewayClientConfig(opts GatewayClientConfigOptions) (*GatewayClientConfig, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers", opts.PeerID)
+	peerInitOpts, err := readPeerInitOptions(peerDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errdefs.Errorf(errdefs.ErrNodeNotFound, "peer %s does not exist", opts.PeerID)
+		}
+		return nil, err
+	}
+	if peerInitOpts.Gateway.Disabled {
+		return nil, errors.Errorf("the gateway of peer %s is disabled", opts.PeerID)
+	}
+	if peerInitOpts.MSPID == "" {
+		return nil, errors.Errorf("peer %s has no MSP ID, initialize it with --msp-id", opts.PeerID)
+	}
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = peerExternalEndpoint(peerInitOpts)
+	}
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid endpoint %q of peer %s", endpoint, opts.PeerID)
+	}
+
+	tlsCertBytes, err := os.ReadFile(filepath.Join(peerDir, "tls.crt"))
+	if err != nil {
+		return nil, err
+	}
+	tlsChain, err := utils.ParseX509CertificateChain(tlsCertBytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid TLS certificate of peer %s", opts.PeerID)
+	}
+	serverName := ""
+	if err := tlsChain[0].VerifyHostname(host); err != nil {
+		// the clients connecting through an IP or a proxy verify one of the
+		// hosts of the certificate
+		serverName = endpointHost(tlsChain[0].DNSNames)
+		if serverName == "" {
+			return nil, errors.Wrapf(err, "the TLS certificate of peer %s isn't valid for the endpoint %s", opts.PeerID, endpoint)
+		}
+	}
+	tlsRootCerts, err := os.ReadFile(filepath.Join(peerDir, "tlscacerts/cacert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	tlsIntermediates, err := readPEMFiles(filepath.Join(peerDir, "tlsintermediatecerts"))
+	if err != nil {
+		return nil, err
+	}
+	roots := []string{strings.TrimSpace(string(tlsRootCerts))}
+	for _, intermediate := range tlsIntermediates {
+		roots = append(roots, strings.TrimSpace(string(intermediate)))
+	}
+
+	crt, key, err := utils.ReadIdentity(opts.Identity)
+	if err != nil {
+		return nil, err
+	}
+	peerConfig, err := utils.GetPeerConfig(opts.PeerID)
+	if err != nil {
+		return nil, err
+	}
+	err = utils.VerifyMSPMember(crt, peerInitOpts.MSPID, peerConfig.CaCert, peerConfig.IntermediateCerts, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	keyBytes, err := utils.EncodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &GatewayClientConfig{
+		MSPID:        peerInitOpts.MSPID,
+		Endpoint:     endpoint,
+		ServerName:   serverName,
+		TLSRootCerts: strings.Join(roots, "\n") + "\n",
+		Identity: GatewayIdentity{
+			Cert: string(utils.EncodeX509Certificate(crt)),
+			Key:  string(keyBytes),
+		},
+	}, nil
+}
+
+// managedIdentityRenewal is how long before it expires the managed admin
+// identity of a peer is issued again
+const managedIdentityRenewal = 24 * time.Hour
+
+// GetPeerMSPID returns the MSP ID a peer of the host was initialized with
+func GetPeerMSPID(peerID string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	peerInitOpts, err := readPeerInitOptions(filepath.Join(home, "hlf-easy/peers", peerID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", errdefs.Errorf(errdefs.ErrNodeNotFound, "peer %s does not exist", peerID)
+		}
+		return "", err
+	}
+	return peerInitOpts.MSPID, nil
+}
+
+// ManagedAdminIdentity returns the identity file of the admin hlf-easy manages
+// for a peer enrolled with a local CA. It's issued by the CA of the peer on
+// first use, and again when it's about to expire, and written to
+// gateway-admin.yaml in the directory of the peer
+func ManagedAdminIdentity(peerID string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers", peerID)
+	peerInitOpts, err := readPeerInitOptions(peerDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", errdefs.Errorf(errdefs.ErrNodeNotFound, "peer %s does not exist", peerID)
+		}
+		return "", err
+	}
+	if !peerInitOpts.Local || peerInitOpts.CAName == "" {
+		return "", errors.Errorf("peer %s isn't enrolled with a local CA, an identity of its org is required", peerID)
+	}
+	identityPath := filepath.Join(peerDir, "gateway-admin.yaml")
+	if crt, _, err := utils.ReadIdentity(identityPath); err == nil && time.Until(crt.NotAfter) > managedIdentityRenewal {
+		return identityPath, nil
+	}
+	caConfig, err := utils.GetCAConfig(peerInitOpts.CAName)
+	if err != nil {
+		return "", err
+	}
+	certOpts := certs.GenerateCertificateOptions{
+		CommonName:       "admin-" + peerID,
+		OrganizationUnit: []string{"admin"},
+	}
+	err = certs.ApplyCertificatePolicy(&certOpts, caConfig.CertPolicy, caConfig.Name, false)
+	if err != nil {
+		return "", err
+	}
+	crt, key, err := certs.GenerateCertificate(certOpts, caConfig.CACert, caConfig.CAKey)
+	if err != nil {
+		return "", err
+	}
+	keyBytes, err := utils.EncodePrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	// the same format as ca enroll
+	identityYaml, err := yaml.Marshal(map[string]interface{}{
+		"key": map[string]interface{}{
+			"pem": string(keyBytes),
+		},
+		"cert": map[string]interface{}{
+			"pem": string(utils.EncodeX509Certificate(crt)),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	err = os.WriteFile(identityPath, identityYaml, 0600)
+	if err != nil {
+		return "", err
+	}
+	return identityPath, nil
+}
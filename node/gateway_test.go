@@ -0,0 +1,154 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"fmt"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"hlf-easy/plan"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderGateway(t *testing.T) {
+	peerDir := t.TempDir()
+	err := renderPeerCoreYaml(plan.Disk, peerDir, config.PeerInitOptions{ID: "peer0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreYaml, err := os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"for this Peer.\n    enabled: true\n", "endorsementTimeout: 30s\n", "broadcastTimeout: 30s\n", "dialTimeout: 2m\n"} {
+		if !strings.Contains(string(coreYaml), expected) {
+			t.Fatalf("expected the default gateway setting %q", expected)
+		}
+	}
+
+	gateway := config.GatewayOptions{Disabled: true, EndorsementTimeout: "1m"}
+	err = renderPeerCoreYaml(plan.Disk, peerDir, config.PeerInitOptions{ID: "peer0", Gateway: gateway}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreYaml, err = os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"for this Peer.\n    enabled: false\n", "endorsementTimeout: 1m\n", "dialTimeout: 2m\n"} {
+		if !strings.Contains(string(coreYaml), expected) {
+			t.Fatalf("expected the gateway setting %q", expected)
+		}
+	}
+}
+
+func TestValidateGateway(t *testing.T) {
+	if err := ValidateGateway(config.GatewayOptions{EndorsementTimeout: "10s", DialTimeout: "1m"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range []config.GatewayOptions{{BroadcastTimeout: "10"}, {DialTimeout: "-1s"}} {
+		if err := ValidateGateway(opts); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
+
+func writeTestIdentity(t *testing.T, crt *x509.Certificate, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	identityPath := filepath.Join(t.TempDir(), "identity.yaml")
+	identity := fmt.Sprintf("cert:\n  pem: |\n%s\nkey:\n  pem: |\n%s\n", indentPEM(utils.EncodeX509Certificate(crt)), indentPEM(encodeTestKey(t, key)))
+	if err := os.WriteFile(identityPath, []byte(identity), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return identityPath
+}
+
+func TestNewGatewayClientConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer0", Hosts: []string{"peer0.org1.example.com"}, ExternalPort: 7051, MSPID: "Org1MSP"})
+	caConfig, err := utils.GetCAConfig("org1-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, clientKey := testca.Issue(t, testca.Cert{CommonName: "app", OUs: []string{"client"}}, caConfig.CACert, caConfig.CAKey)
+	identity := writeTestIdentity(t, clientCert, clientKey)
+
+	clientConfig, err := NewGatewayClientConfig(GatewayClientConfigOptions{PeerID: "peer0", Identity: identity})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientConfig.MSPID != "Org1MSP" || clientConfig.Endpoint != "peer0.org1.example.com:7051" || clientConfig.ServerName != "" {
+		t.Errorf("unexpected client config %+v", clientConfig)
+	}
+	roots, err := utils.ParseX509CertificateChain([]byte(clientConfig.TLSRootCerts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !roots[0].Equal(caConfig.TLSCACert) {
+		t.Errorf("expected the TLS CA of the peer as root, got %s", roots[0].Subject.CommonName)
+	}
+	if _, err := utils.ParseECDSAPrivateKey([]byte(clientConfig.Identity.Key)); err != nil {
+		t.Errorf("expected the key of the identity: %v", err)
+	}
+
+	// the clients connecting through an IP verify the host of the certificate
+	clientConfig, err = NewGatewayClientConfig(GatewayClientConfigOptions{PeerID: "peer0", Identity: identity, Endpoint: "10.0.0.1:7051"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientConfig.ServerName != "peer0.org1.example.com" {
+		t.Errorf("expected the host of the TLS certificate as server name, got %q", clientConfig.ServerName)
+	}
+
+	otherCA, otherCAKey := testca.NewCA(t, "other-ca")
+	otherCert, otherKey := testca.Issue(t, testca.Cert{CommonName: "app", OUs: []string{"client"}}, otherCA, otherCAKey)
+	_, err = NewGatewayClientConfig(GatewayClientConfigOptions{PeerID: "peer0", Identity: writeTestIdentity(t, otherCert, otherKey)})
+	if err == nil {
+		t.Error("expected an error for an identity of another MSP")
+	}
+}
+
+func TestManagedAdminIdentity(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer0", Hosts: []string{"localhost"}, MSPID: "Org1MSP"})
+
+	identity, err := ManagedAdminIdentity("peer0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	crt, _, err := utils.ReadIdentity(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerConfig, err := utils.GetPeerConfig("peer0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.VerifyMSPAdmin(crt, "Org1MSP", peerConfig.CaCert, peerConfig.IntermediateCerts, time.Now()); err != nil {
+		t.Fatalf("expected an admin of the MSP of the peer: %v", err)
+	}
+
+	// the identity is issued once
+	reused, err := ManagedAdminIdentity("peer0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reusedCrt, _, err := utils.ReadIdentity(reused)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reusedCrt.Equal(crt) {
+		t.Error("expected the managed identity to be reused")
+	}
+
+	if _, err := ManagedAdminIdentity("peer1"); err == nil {
+		t.Error("expected an error for a peer that does not exist")
+	}
+}
@@ -0,0 +1,243 @@
+package node
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"hlf-easy/log"
+	"hlf-easy/plan"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultGossipBootstrap is used when a peer has no other peers to bootstrap from
+const defaultGossipBootstrap = "127.0.0.1:7051"
+
+// peerExternalEndpoint returns the external endpoint of the peer, built with
+// its first host that isn't a wildcard when it isn't set explicitly
+func peerExternalEndpoint(peerInitOpts config.PeerInitOptions) string {
+	if peerInitOpts.ExternalEndpoint != "" {
+		return peerInitOpts.ExternalEndpoint
+	}
+	host := endpointHost(peerInitOpts.Hosts)
+	if host == "" {
+		return ""
+	}
+	port := peerInitOpts.ExternalPort
+	if port == 0 {
+		port = 7051
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// getPeersInitOptions returns the init options of all the peers of the host
+func getPeersInitOptions() ([]config.PeerInitOptions, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	initFiles, err := filepath.Glob(filepath.Join(home, "hlf-easy/peers/*/init.json"))
+	if err != nil {
+		return nil, err
+	}
+	var peers []config.PeerInitOptions
+	for _, initFile := range initFiles {
+		initBytes, err := os.ReadFile(initFile)
+		if err != nil {
+			return nil, err
+		}
+		peerInitOpts := config.PeerInitOptions{}
+		err = json.Unmarshal(initBytes, &peerInitOpts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", initFile)
+		}
+		peers = append(peers, peerInitOpts)
+	}
+	return peers, nil
+}
+
+// gossipBootstrap returns the gossip bootstrap of a peer, the endpoints of the
+// other peers of its org in the host plus the ones it was configured with
+func gossipBootstrap(peerInitOpts config.PeerInitOptions, peers []config.PeerInitOptions) []string {
+	self := peerExternalEndpoint(peerInitOpts)
+	seen := map[string]bool{}
+	var endpoints []string
+	add := func(endpoint string) {
+		if endpoint == "" || endpoint == self || seen[endpoint] {
+			return
+		}
+		seen[endpoint] = true
+		endpoints = append(endpoints, endpoint)
+	}
+	for _, endpoint := range peerInitOpts.GossipBootstrap {
+		add(endpoint)
+	}
+	if peerInitOpts.MSPID == "" {
+		return endpoints
+	}
+	var orgEndpoints []string
+	for _, peer := range peers {
+		if peer.ID == peerInitOpts.ID || peer.MSPID != peerInitOpts.MSPID {
+			continue
+		}
+		orgEndpoints = append(orgEndpoints, peerExternalEndpoint(peer))
+	}
+	sort.Strings(orgEndpoints)
+	for _, endpoint := range orgEndpoints {
+		add(endpoint)
+	}
+	return endpoints
+}
+
+// GetPeerGossipBootstrap returns the gossip bootstrap of a peer of the host
+func GetPeerGossipBootstrap(peerInitOpts config.PeerInitOptions) ([]string, error) {
+	peers, err := getPeersInitOptions()
+	if err != nil {
+		return nil, err
+	}
+	return gossipBootstrap(peerInitOpts, peers), nil
+}
+
+// WireOrgGossip renders again the core.yaml of all the peers of the org in the
+// host, so their gossip bootstrap has every other peer of the org
+func WireOrgGossip(mspID string) error {
+	return wireOrgGossip(plan.Disk, mspID, nil)
+}
+
+// wireOrgGossip renders the core.yaml of the peers of the org, the init options
+// of the peer being initialized replace the ones read from its init.json as a
+// dry run doesn't write them
+func wireOrgGossip(w plan.Writer, mspID string, initialized *config.PeerInitOptions) error {
+	if mspID == "" {
+		return nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	peers, err := getPeersInitOptions()
+	if err != nil {
+		return err
+	}
+	if initialized != nil {
+		found := false
+		for i := range peers {
+			if peers[i].ID == initialized.ID {
+				peers[i] = *initialized
+				found = true
+			}
+		}
+		if !found {
+			peers = append(peers, *initialized)
+		}
+	}
+	// the bootstrap is exported to the peer process when it starts, so the
+	// running peers only use the new one once they are restarted
+	var running []string
+	for _, peer := range peers {
+		if peer.MSPID != mspID {
+			continue
+		}
+		peerDir := filepath.Join(home, "hlf-easy/peers", peer.ID)
+		err = renderPeerCoreYaml(w, peerDir, peer, gossipBootstrap(peer, peers))
+		if err != nil {
+			return errors.Wrapf(err, "failed to render core.yaml of peer %s", peer.ID)
+		}
+		if w != plan.Disk {
+			continue
+		}
+		log.Infof("Gossip of peer %s wired to the peers of %s", peer.ID, mspID)
+		if _, err := os.Stat(filepath.Join(peerDir, "run.json")); err == nil {
+			running = append(running, peer.ID)
+		}
+	}
+	if len(running) > 0 {
+		log.Warnf("Restart the peers %s to use the new gossip bootstrap", strings.Join(running, ", "))
+	}
+	return nil
+}
+
+// RemovePeer deletes the files of a peer that isn't running and wires again
+// the gossip of the other peers of its org
+func RemovePeer(peerID string) error {
+	return withLock("peer", peerID, "peer.remove", func() error {
+		return removePeer(peerID)
+	})
+}
+
+func removePeer(peerID string) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers", peerID)
+	if _, err := os.Stat(peerDir); os.IsNotExist(err) {
+		return errdefs.Errorf(errdefs.ErrNodeNotFound, "peer %s does not exist", peerID)
+	}
+	if _, err := os.Stat(filepath.Join(peerDir, "run.json")); err == nil {
+		return errors.Errorf("peer %s is running, stop it before removing it", peerID)
+	}
+	peerInitOpts := config.PeerInitOptions{}
+	initBytes, err := os.ReadFile(filepath.Join(peerDir, "init.json"))
+	if err == nil {
+		err = json.Unmarshal(initBytes, &peerInitOpts)
+		if err != nil {
+			return err
+		}
+	}
+	err = os.RemoveAll(peerDir)
+	if err != nil {
+		return err
+	}
+	return WireOrgGossip(peerInitOpts.MSPID)
+}
+
+// ValidateGossipState checks the state transfer settings of a peer
+func ValidateGossipState(opts config.GossipStateOptions) error {
+	for name, value := range map[string]string{
+		"check interval":   opts.CheckInterval,
+		"response timeout": opts.ResponseTimeout,
+	} {
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid gossip state %s", name)
+		}
+		if d <= 0 {
+			return errors.Errorf("gossip state %s must be positive", name)
+		}
+	}
+	if opts.BatchSize < 0 || opts.BlockBufferSize < 0 || opts.MaxRetries < 0 {
+		return errors.New("gossip state batch size, block buffer size and max retries can't be negative")
+	}
+	return nil
+}
+
+// gossipStateWithDefaults fills the empty state transfer settings with the
+// ones of Fabric
+func gossipStateWithDefaults(opts config.GossipStateOptions) config.GossipStateOptions {
+	if opts.CheckInterval == "" {
+		opts.CheckInterval = "10s"
+	}
+	if opts.ResponseTimeout == "" {
+		opts.ResponseTimeout = "3s"
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = 10
+	}
+	if opts.BlockBufferSize == 0 {
+		opts.BlockBufferSize = 20
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = 3
+	}
+	return opts
+}
@@ -0,0 +1,151 @@
+package node
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"hlf-easy/plan"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGossipBootstrap(t *testing.T) {
+	peers := []config.PeerInitOptions{
+		{ID: "peer0", MSPID: "Org1MSP", Hosts: []string{"peer0.org1.example.com"}, ExternalPort: 7051},
+		{ID: "peer1", MSPID: "Org1MSP", Hosts: []string{"peer1.org1.example.com"}, ExternalPort: 7061},
+		{ID: "peer2", MSPID: "Org1MSP", ExternalEndpoint: "10.0.0.2:7051"},
+		{ID: "other", MSPID: "Org2MSP", Hosts: []string{"peer0.org2.example.com"}},
+	}
+	self := config.PeerInitOptions{
+		ID:              "peer1",
+		MSPID:           "Org1MSP",
+		Hosts:           []string{"peer1.org1.example.com"},
+		ExternalPort:    7061,
+		GossipBootstrap: []string{"remote.org1.example.com:7051", "10.0.0.2:7051", "peer1.org1.example.com:7061"},
+	}
+	bootstrap := gossipBootstrap(self, peers)
+	expected := []string{"remote.org1.example.com:7051", "10.0.0.2:7051", "peer0.org1.example.com:7051"}
+	if strings.Join(bootstrap, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected bootstrap %v, got %v", expected, bootstrap)
+	}
+
+	self.MSPID = ""
+	bootstrap = gossipBootstrap(self, peers)
+	if len(bootstrap) != 2 {
+		t.Fatalf("expected only the configured endpoints without MSP ID, got %v", bootstrap)
+	}
+}
+
+func writeTestPeer(t *testing.T, home string, peerInitOpts config.PeerInitOptions) string {
+	t.Helper()
+	peerDir := filepath.Join(home, "hlf-easy/peers", peerInitOpts.ID)
+	err := os.MkdirAll(peerDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initBytes, err := json.Marshal(peerInitOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(peerDir, "init.json"), initBytes, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return peerDir
+}
+
+func TestWireOrgGossip(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peer0Dir := writeTestPeer(t, home, config.PeerInitOptions{ID: "peer0", MSPID: "Org1MSP", ExternalEndpoint: "peer0.example.com:7051"})
+	peer1Dir := writeTestPeer(t, home, config.PeerInitOptions{ID: "peer1", MSPID: "Org1MSP", ExternalEndpoint: "peer1.example.com:7061"})
+
+	err := WireOrgGossip("Org1MSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreYaml, err := os.ReadFile(filepath.Join(peer0Dir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(coreYaml), "bootstrap: peer1.example.com:7061\n") {
+		t.Fatal("expected peer0 to bootstrap from peer1")
+	}
+	if !strings.Contains(string(coreYaml), "externalEndpoint: peer0.example.com:7051\n") {
+		t.Fatal("expected the external endpoint of peer0")
+	}
+
+	err = RemovePeer("peer0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreYaml, err = os.ReadFile(filepath.Join(peer1Dir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(coreYaml), "bootstrap: "+defaultGossipBootstrap+"\n") {
+		t.Fatal("expected peer1 to stop bootstrapping from the removed peer")
+	}
+}
+
+func TestRemoveRunningPeer(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peerDir := writeTestPeer(t, home, config.PeerInitOptions{ID: "peer0"})
+	err := os.WriteFile(filepath.Join(peerDir, "run.json"), []byte("{}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RemovePeer("peer0"); err == nil {
+		t.Fatal("expected a running peer not to be removed")
+	}
+}
+
+func TestRenderGossipState(t *testing.T) {
+	peerDir := t.TempDir()
+	err := renderPeerCoreYaml(plan.Disk, peerDir, config.PeerInitOptions{ID: "peer0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreYaml, err := os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"enabled: false\n      # checkInterval", "checkInterval: 10s\n", "batchSize: 10\n", "maxRetries: 3\n"} {
+		if !strings.Contains(string(coreYaml), expected) {
+			t.Fatalf("expected the default state transfer setting %q", expected)
+		}
+	}
+
+	gossipState := config.GossipStateOptions{Enabled: true, CheckInterval: "5s", BatchSize: 50, MaxRetries: 5}
+	err = renderPeerCoreYaml(plan.Disk, peerDir, config.PeerInitOptions{ID: "peer0", GossipState: gossipState}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreYaml, err = os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"enabled: true\n      # checkInterval", "checkInterval: 5s\n", "responseTimeout: 3s\n", "batchSize: 50\n", "blockBufferSize: 20\n", "maxRetries: 5\n"} {
+		if !strings.Contains(string(coreYaml), expected) {
+			t.Fatalf("expected the state transfer setting %q", expected)
+		}
+	}
+}
+
+func TestValidateGossipState(t *testing.T) {
+	if err := ValidateGossipState(config.GossipStateOptions{CheckInterval: "5s", BatchSize: 20}); err != nil {
+		t.Fatal(err)
+	}
+	invalid := []config.GossipStateOptions{
+		{CheckInterval: "five seconds"},
+		{ResponseTimeout: "-1s"},
+		{BatchSize: -1},
+	}
+	for _, opts := range invalid {
+		if err := ValidateGossipState(opts); err == nil {
+			t.Errorf("expected %+v to be invalid", opts)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package node
+
+import (
+	"context"
+	"hlf-easy/log"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHistorySize keeps an hour of samples with the default interval
+	DefaultHistorySize     = 360
+	DefaultHistoryInterval = 10 * time.Second
+)
+
+// StatusSample is the status and resource usage of a node at a point in time
+type StatusSample struct {
+	Time       time.Time `json:"time"`
+	Status     string    `json:"status"`
+	CPUPercent float64   `json:"cpuPercent"`
+	MemoryRSS  uint64    `json:"memoryRSS"`
+	// Uptime of the node process in seconds
+	Uptime float64 `json:"uptime"`
+	// LedgerBytes is the size of the ledger of a peer, it's tracked to
+	// follow its growth
+	LedgerBytes uint64 `json:"ledgerBytes,omitempty"`
+	// DiskUsedPercent is the usage of the filesystem of the ledger of a peer
+	DiskUsedPercent float64 `json:"diskUsedPercent,omitempty"`
+}
+
+// StatusHistory is a ring buffer with the latest status samples of a node
+type StatusHistory struct {
+	mu      sync.Mutex
+	samples []StatusSample
+	next    int
+	full    bool
+}
+
+// NewStatusHistory creates a history that keeps the latest size samples
+func NewStatusHistory(size int) *StatusHistory {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &StatusHistory{
+		samples: make([]StatusSample, size),
+	}
+}
+
+// Add records a sample, replacing the oldest one when the history is full
+func (h *StatusHistory) Add(sample StatusSample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples[h.next] = sample
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Samples returns the samples taken after since, the oldest first
+func (h *StatusHistory) Samples(since time.Time) []StatusSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ordered := h.samples[:h.next]
+	if h.full {
+		ordered = append(append([]StatusSample{}, h.samples[h.next:]...), h.samples[:h.next]...)
+	}
+	samples := []StatusSample{}
+	for _, sample := range ordered {
+		if sample.Time.After(since) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// StatusNode is a node whose status can be sampled
+type StatusNode interface {
+	Status() (*ProcessState, error)
+}
+
+// newStatusSample converts the status of a node to a sample
+func newStatusSample(now time.Time, state *ProcessState) StatusSample {
+	sample := StatusSample{
+		Time:       now,
+		Status:     state.Status,
+		CPUPercent: state.CPUInfo.CPUPercent,
+		Uptime:     state.Uptime,
+	}
+	if state.MemoryInfo != nil {
+		sample.MemoryRSS = state.MemoryInfo.RSS
+	}
+	if state.DiskUsage != nil && state.DiskUsage.Error == "" {
+		sample.LedgerBytes = state.DiskUsage.LedgerBytes
+		sample.DiskUsedPercent = state.DiskUsage.UsedPercent
+	}
+	return sample
+}
+
+// SampleStatus records the status of the node in the history every interval
+// until the context is done
+func SampleStatus(ctx context.Context, n StatusNode, history *StatusHistory, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			state, err := n.Status()
+			if err != nil {
+				log.Warnf("Failed to sample node status: %v", err)
+				continue
+			}
+			history.Add(newStatusSample(now, state))
+		}
+	}
+}
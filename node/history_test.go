@@ -0,0 +1,68 @@
+package node
+
+import (
+	"context"
+	"github.com/shirou/gopsutil/process"
+	"testing"
+	"time"
+)
+
+func TestStatusHistory(t *testing.T) {
+	history := NewStatusHistory(3)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if samples := history.Samples(time.Time{}); len(samples) != 0 {
+		t.Fatalf("expected an empty history, got %v", samples)
+	}
+	for i := 0; i < 5; i++ {
+		history.Add(StatusSample{Time: start.Add(time.Duration(i) * time.Second), CPUPercent: float64(i)})
+	}
+	samples := history.Samples(time.Time{})
+	if len(samples) != 3 {
+		t.Fatalf("expected the latest 3 samples, got %d", len(samples))
+	}
+	for i, sample := range samples {
+		if sample.CPUPercent != float64(i+2) {
+			t.Fatalf("expected sample %d to be the sample %d, got %v", i, i+2, sample.CPUPercent)
+		}
+	}
+	samples = history.Samples(start.Add(3 * time.Second))
+	if len(samples) != 1 || samples[0].CPUPercent != 4 {
+		t.Fatalf("expected the samples after the 4th second, got %v", samples)
+	}
+}
+
+type fakeStatusNode struct{}
+
+func (fakeStatusNode) Status() (*ProcessState, error) {
+	return &ProcessState{
+		PID:        1,
+		Status:     "Running",
+		MemoryInfo: &process.MemoryInfoStat{RSS: 1024},
+		CPUInfo:    CPUInfo{CPUPercent: 12.5},
+		Uptime:     60,
+	}, nil
+}
+
+func TestSampleStatus(t *testing.T) {
+	history := NewStatusHistory(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		SampleStatus(ctx, fakeStatusNode{}, history, time.Millisecond)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(history.Samples(time.Time{})) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	samples := history.Samples(time.Time{})
+	if len(samples) < 2 {
+		t.Fatalf("expected at least 2 samples, got %d", len(samples))
+	}
+	sample := samples[0]
+	if sample.Status != "Running" || sample.MemoryRSS != 1024 || sample.CPUPercent != 12.5 || sample.Uptime != 60 {
+		t.Fatalf("unexpected sample %+v", sample)
+	}
+}
@@ -0,0 +1,15 @@
+package node
+
+import "hlf-easy/config"
+
+// SetHooks sets the hooks run on the start, stop and crash of the peer
+// process, they're set before the peer starts
+func (n *PeerNode) SetHooks(nodeHooks config.NodeHooks) {
+	n.hooks = nodeHooks
+}
+
+// SetHooks sets the hooks run on the start, stop and crash of the orderer
+// process, they're set before the orderer starts
+func (n *OrdererNode) SetHooks(nodeHooks config.NodeHooks) {
+	n.hooks = nodeHooks
+}
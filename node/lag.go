@@ -0,0 +1,127 @@
+package node
+
+import (
+	"bufio"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHeightLagThreshold is the number of blocks a peer can be behind
+// the other peers of its org before it's flagged as lagging
+const DefaultHeightLagThreshold = 10
+
+// DefaultHeightLagInterval is how often the heights of the peers are compared
+const DefaultHeightLagInterval = 30 * time.Second
+
+// ChannelLag is the height of a channel on a peer against the highest peer
+// of its org on the host
+type ChannelLag struct {
+	Channel   string `json:"channel"`
+	Height    uint64 `json:"height"`
+	MaxHeight uint64 `json:"maxHeight"`
+	// MaxPeer is the peer with the highest height, empty when it's this peer
+	MaxPeer string `json:"maxPeer,omitempty"`
+	Lag     uint64 `json:"lag"`
+	Lagging bool   `json:"lagging"`
+}
+
+// HeightLag is the lag of the channels of a peer
+type HeightLag struct {
+	Channels  []ChannelLag `json:"channels"`
+	Threshold uint64       `json:"threshold"`
+	// Lagging is true when a channel is behind by more than the threshold
+	Lagging   bool      `json:"lagging"`
+	CheckedAt time.Time `json:"checkedAt"`
+	// Error is set when the heights of the peer couldn't be read
+	Error string `json:"error,omitempty"`
+}
+
+// blockHeightPattern matches the block height of a channel in the metrics of
+// a node
+var blockHeightPattern = regexp.MustCompile(`^ledger_blockchain_height\{(?:.*,)?channel="([^"]+)"(?:,.*)?\} ([0-9.e+]+)$`)
+
+// ParseBlockHeights reads the block heights of the channels from the
+// Prometheus metrics of a node
+func ParseBlockHeights(r io.Reader) (map[string]uint64, error) {
+	heights := map[string]uint64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		match := blockHeightPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		height, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid block height of channel %s", match[1])
+		}
+		heights[match[1]] = uint64(height)
+	}
+	return heights, scanner.Err()
+}
+
+// GetBlockHeights reads the block heights of the channels of a node from the
+// metrics of its operations endpoint, they're only served by the prometheus
+// provider
+func GetBlockHeights(endpoint OperationsEndpoint) (map[string]uint64, error) {
+	var heights map[string]uint64
+	err := readMetrics(endpoint, func(r io.Reader) error {
+		var err error
+		heights, err = ParseBlockHeights(r)
+		return err
+	})
+	return heights, err
+}
+
+// readMetrics passes the Prometheus metrics of the operations endpoint of a
+// node to parse
+func readMetrics(endpoint OperationsEndpoint, parse func(r io.Reader) error) error {
+	resp, err := endpoint.Client().Get(endpoint.URL("/metrics"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("the metrics endpoint returned %s", resp.Status)
+	}
+	return parse(resp.Body)
+}
+
+// ComputeHeightLag compares the heights of a peer with the heights of the
+// other peers of its org, keyed by their ID
+func ComputeHeightLag(heights map[string]uint64, others map[string]map[string]uint64, threshold uint64) []ChannelLag {
+	peerIDs := make([]string, 0, len(others))
+	for peerID := range others {
+		peerIDs = append(peerIDs, peerID)
+	}
+	sort.Strings(peerIDs)
+	channels := []ChannelLag{}
+	for channel, height := range heights {
+		lag := ChannelLag{Channel: channel, Height: height, MaxHeight: height}
+		for _, peerID := range peerIDs {
+			if peerHeight, ok := others[peerID][channel]; ok && peerHeight > lag.MaxHeight {
+				lag.MaxHeight = peerHeight
+				lag.MaxPeer = peerID
+			}
+		}
+		lag.Lag = lag.MaxHeight - lag.Height
+		lag.Lagging = lag.Lag > threshold
+		channels = append(channels, lag)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].Channel < channels[j].Channel
+	})
+	return channels
+}
+
+// SetHeightLag sets the source of the height lag reported in the status of
+// the peer, it's set before the peer starts
+func (n *PeerNode) SetHeightLag(heightLag func() *HeightLag) {
+	n.heightLag = heightLag
+}
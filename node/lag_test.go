@@ -0,0 +1,45 @@
+package node
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBlockHeights(t *testing.T) {
+	metrics := `# HELP ledger_blockchain_height Height of the chain in blocks.
+# TYPE ledger_blockchain_height gauge
+ledger_blockchain_height{channel="mychannel"} 12
+ledger_blockchain_height{channel="other"} 3
+ledger_transaction_count{channel="mychannel"} 40
+`
+	heights, err := ParseBlockHeights(strings.NewReader(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heights) != 2 || heights["mychannel"] != 12 || heights["other"] != 3 {
+		t.Fatalf("unexpected heights %v", heights)
+	}
+}
+
+func TestComputeHeightLag(t *testing.T) {
+	heights := map[string]uint64{"mychannel": 100, "other": 8}
+	others := map[string]map[string]uint64{
+		"peer1": {"mychannel": 120, "other": 8},
+		"peer2": {"mychannel": 105},
+	}
+	channels := ComputeHeightLag(heights, others, 10)
+	if len(channels) != 2 {
+		t.Fatalf("expected the lag of 2 channels, got %+v", channels)
+	}
+	expected := ChannelLag{Channel: "mychannel", Height: 100, MaxHeight: 120, MaxPeer: "peer1", Lag: 20, Lagging: true}
+	if channels[0] != expected {
+		t.Fatalf("expected %+v, got %+v", expected, channels[0])
+	}
+	if channels[1].Channel != "other" || channels[1].Lag != 0 || channels[1].MaxPeer != "" || channels[1].Lagging {
+		t.Fatalf("expected the peer to be in sync on other, got %+v", channels[1])
+	}
+	channels = ComputeHeightLag(heights, others, 20)
+	if channels[0].Lagging {
+		t.Fatal("expected a lag of the threshold not to be flagged")
+	}
+}
@@ -0,0 +1,122 @@
+package node
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// RebuildPeerDBs drops the state, history and config history databases of
+// all the channels of a stopped peer, they're rebuilt from its blocks on its
+// next start
+func RebuildPeerDBs(peerDir string) error {
+	binary, err := GetPeerBinary(peerDir)
+	if err != nil {
+		return err
+	}
+	return runPeerNodeCommand(peerDir, binary, "rebuild-dbs")
+}
+
+// ResetPeer resets the channels of a stopped peer to their genesis block, the
+// blocks are pulled again from the orderers on its next start
+func ResetPeer(peerDir string) error {
+	binary, err := GetPeerBinary(peerDir)
+	if err != nil {
+		return err
+	}
+	return runPeerNodeCommand(peerDir, binary, "reset")
+}
+
+// RollbackPeer rolls a channel of a stopped peer back to a block, the next
+// blocks are pulled again from the orderers on its next start
+func RollbackPeer(peerDir string, channel string, blockNumber uint64) error {
+	binary, err := GetPeerBinary(peerDir)
+	if err != nil {
+		return err
+	}
+	return runPeerNodeCommand(peerDir, binary, "rollback", "--channelID", channel, "--blockNumber", strconv.FormatUint(blockNumber, 10))
+}
+
+// GetPeerLedgerDir returns the directory of the ledgers of the channels of a
+// peer
+func GetPeerLedgerDir(peerDir string) string {
+	return filepath.Join(peerDir, "data", "ledgersData")
+}
+
+// BackupPeerLedger archives the ledger directory of a stopped peer in a
+// ledger-<time>.tar.gz of the backup directory, it's restored by extracting
+// it in the data directory of the peer
+func BackupPeerLedger(peerDir string, backupDir string, now time.Time) (string, error) {
+	ledgerDir := GetPeerLedgerDir(peerDir)
+	if _, err := os.Stat(ledgerDir); err != nil {
+		return "", errors.Wrap(err, "the peer has no ledger to back up")
+	}
+	err := os.MkdirAll(backupDir, 0755)
+	if err != nil {
+		return "", err
+	}
+	backupPath := filepath.Join(backupDir, fmt.Sprintf("ledger-%s.tar.gz", now.UTC().Format("20060102T150405Z")))
+	f, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	err = writeLedgerArchive(f, ledgerDir)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// a partial archive can't be restored
+		_ = os.Remove(backupPath)
+		return "", errors.Wrap(err, "failed to back up the ledger")
+	}
+	return backupPath, nil
+}
+
+// writeLedgerArchive writes the ledger directory to w as a tar.gz whose
+// paths start with ledgersData
+func writeLedgerArchive(w io.Writer, ledgerDir string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	root := filepath.Dir(ledgerDir)
+	err := filepath.Walk(ledgerDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		err = tw.WriteHeader(header)
+		if err != nil || info.IsDir() {
+			return err
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
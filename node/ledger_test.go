@@ -0,0 +1,128 @@
+package node
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"hlf-easy/config"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFakePeerBinary puts a peer in the PATH that runs the script
+func writeFakePeerBinary(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake peer binary is a shell script")
+	}
+	binDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(binDir, "peer"), []byte("#!/bin/sh\n"+script), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRebuildPeerDBs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peerDir := writeTestPeer(t, home, config.PeerInitOptions{ID: "peer0"})
+	writeFakePeerBinary(t, `echo "$@" > "$FABRIC_CFG_PATH/args"`)
+	err := RebuildPeerDBs(peerDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := os.ReadFile(filepath.Join(peerDir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(args)) != "node rebuild-dbs" {
+		t.Fatalf("expected peer node rebuild-dbs to run in the directory of the peer, got %q", args)
+	}
+
+	writeFakePeerBinary(t, "echo 'the peer is running'\nexit 1\n")
+	err = RebuildPeerDBs(peerDir)
+	if err == nil || !strings.Contains(err.Error(), "the peer is running") {
+		t.Fatalf("expected the output of the failed command in the error, got %v", err)
+	}
+}
+
+func TestRollbackPeer(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peerDir := writeTestPeer(t, home, config.PeerInitOptions{ID: "peer0"})
+	writeFakePeerBinary(t, `echo "$@" > "$FABRIC_CFG_PATH/args"`)
+	err := RollbackPeer(peerDir, "mychannel", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := os.ReadFile(filepath.Join(peerDir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(args)) != "node rollback --channelID mychannel --blockNumber 42" {
+		t.Fatalf("unexpected arguments %q", args)
+	}
+}
+
+func TestBackupPeerLedger(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peerDir := writeTestPeer(t, home, config.PeerInitOptions{ID: "peer0"})
+	backupDir := filepath.Join(peerDir, "backups")
+	if _, err := BackupPeerLedger(peerDir, backupDir, time.Now()); err == nil {
+		t.Fatal("expected an error for a peer without a ledger")
+	}
+	blockFile := filepath.Join(GetPeerLedgerDir(peerDir), "chains", "chains", "mychannel", "blockfile_000000")
+	err := os.MkdirAll(filepath.Dir(blockFile), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(blockFile, []byte("blocks"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	backupPath, err := BackupPeerLedger(peerDir, backupDir, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backupPath != filepath.Join(backupDir, "ledger-20240102T030405Z.tar.gz") {
+		t.Fatalf("unexpected backup path %s", backupPath)
+	}
+	f, err := os.Open(backupPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(content)
+	}
+	if files["ledgersData/chains/chains/mychannel/blockfile_000000"] != "blocks" {
+		t.Fatalf("expected the block file in the backup, got %v", files)
+	}
+	if _, err := BackupPeerLedger(peerDir, backupDir, now); err == nil {
+		t.Fatal("expected an existing backup not to be overwritten")
+	}
+}
@@ -0,0 +1,34 @@
+package node
+
+import (
+	"context"
+	"hlf-easy/audit"
+	"hlf-easy/lock"
+	"hlf-easy/log"
+	"time"
+)
+
+// DefaultInitTimeout is how long the init of a node waits for the lock of
+// the node and for the Fabric CA enrolling it
+const DefaultInitTimeout = 5 * time.Minute
+
+// withLock runs an operation of the CLI on a node holding its lock, the
+// operation is queued while another one holds it
+func withLock(kind string, id string, operation string, run func() error) error {
+	return withLockContext(context.Background(), kind, id, operation, run)
+}
+
+// withLockContext is withLock with the queued operation stopping to wait
+// when the context is done
+func withLockContext(ctx context.Context, kind string, id string, operation string, run func() error) error {
+	l, err := lock.AcquireContext(ctx, kind, id, operation, audit.LocalActor(), lock.Wait, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := l.Release(); err != nil {
+			log.Warnf("Failed to release the lock of %s %s: %v", kind, id, err)
+		}
+	}()
+	return run()
+}
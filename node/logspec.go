@@ -0,0 +1,228 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/audit"
+	"hlf-easy/config"
+	"hlf-easy/lock"
+	"hlf-easy/log"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultLogSpec is the logging spec of the nodes without a persisted one
+const DefaultLogSpec = "info"
+
+// LogSpecUpdatedByOperations is the actor of the specs changed directly on
+// the operations endpoint of the node
+const LogSpecUpdatedByOperations = "operations endpoint"
+
+var (
+	logLevels      = map[string]bool{"debug": true, "info": true, "warn": true, "warning": true, "error": true, "dpanic": true, "panic": true, "fatal": true}
+	loggerRegexp   = regexp.MustCompile(`^[A-Za-z0-9_#-]+(\.[A-Za-z0-9_#-]+)*\.?$`)
+	operationsHTTP = &http.Client{Timeout: 5 * time.Second}
+)
+
+// ValidateLogSpec checks a Fabric logging spec, e.g. gossip,msp=debug:info
+func ValidateLogSpec(spec string) error {
+	if spec == "" {
+		return errors.New("the logging spec is empty")
+	}
+	for _, field := range strings.Split(spec, ":") {
+		loggers, level := "", field
+		if i := strings.Index(field, "="); i >= 0 {
+			loggers, level = field[:i], field[i+1:]
+			for _, logger := range strings.Split(loggers, ",") {
+				if !loggerRegexp.MatchString(logger) {
+					return errors.Errorf("invalid logger %q in the logging spec %q", logger, spec)
+				}
+			}
+		}
+		if !logLevels[strings.ToLower(level)] {
+			return errors.Errorf("invalid level %q in the logging spec %q", level, spec)
+		}
+	}
+	return nil
+}
+
+func getLogSpecPath(nodeDir string) string {
+	return filepath.Join(nodeDir, "logspec.json")
+}
+
+// GetLogSpec returns the logging spec persisted for the node, nil when the
+// node uses the default one
+func GetLogSpec(nodeDir string) (*config.LogSpec, error) {
+	specBytes, err := os.ReadFile(getLogSpecPath(nodeDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	spec := &config.LogSpec{}
+	if err := json.Unmarshal(specBytes, spec); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", getLogSpecPath(nodeDir))
+	}
+	return spec, nil
+}
+
+// GetStartLogSpec returns the logging spec the node is started with
+func GetStartLogSpec(nodeDir string) string {
+	spec, err := GetLogSpec(nodeDir)
+	if err != nil {
+		log.Warnf("Failed to read the logging spec of %s, using %s: %v", nodeDir, DefaultLogSpec, err)
+		return DefaultLogSpec
+	}
+	if spec == nil {
+		return DefaultLogSpec
+	}
+	return spec.Spec
+}
+
+// SaveLogSpec persists the logging spec of the node
+func SaveLogSpec(nodeDir string, spec string, updatedBy string) error {
+	if err := ValidateLogSpec(spec); err != nil {
+		return err
+	}
+	specBytes, err := json.MarshalIndent(config.LogSpec{
+		Spec:      spec,
+		UpdatedAt: time.Now().UTC(),
+		UpdatedBy: updatedBy,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(getLogSpecPath(nodeDir), specBytes, 0644)
+}
+
+// ResetLogSpec removes the persisted logging spec, the node starts with the
+// default one
+func ResetLogSpec(nodeDir string) error {
+	err := os.Remove(getLogSpecPath(nodeDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+type logSpecBody struct {
+	Spec string `json:"spec"`
+}
+
+// GetOperationsLogSpec returns the logging spec active in the node, read from
+// its operations endpoint
+func GetOperationsLogSpec(operations OperationsEndpoint) (string, error) {
+	resp, err := operations.Client().Get(operations.URL("/logspec"))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("operations endpoint returned %s: %s", resp.Status, body)
+	}
+	spec := logSpecBody{}
+	if err := json.Unmarshal(body, &spec); err != nil {
+		return "", err
+	}
+	return spec.Spec, nil
+}
+
+// SetOperationsLogSpec changes the logging spec active in the node through
+// its operations endpoint
+func SetOperationsLogSpec(operations OperationsEndpoint, spec string) error {
+	specBytes, err := json.Marshal(logSpecBody{Spec: spec})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, operations.URL("/logspec"), bytes.NewReader(specBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := operations.Client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return errors.Errorf("operations endpoint returned %s: %s", resp.Status, body)
+	}
+	return nil
+}
+
+// DefaultLogSpecInterval is how often the logging spec of a running node is
+// read from its operations endpoint
+const DefaultLogSpecInterval = 10 * time.Second
+
+// WatchLogSpec persists the changes of the logging spec made directly on the
+// operations endpoint of the node, so they're applied again when it restarts.
+// The endpoint is only read while the node is running
+func WatchLogSpec(ctx context.Context, n StatusNode, kind string, id string, nodeDir string, operations OperationsEndpoint, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		state, err := n.Status()
+		if err != nil || state.PID == 0 || state.Status == "Stop" {
+			continue
+		}
+		syncLogSpec(kind, id, nodeDir, operations)
+	}
+}
+
+// syncLogSpec persists the active logging spec of the node when it differs
+// from the persisted one. The node is skipped while another operation holds
+// its lock, it's synced again on the next tick
+func syncLogSpec(kind string, id string, nodeDir string, operations OperationsEndpoint) {
+	active, err := GetOperationsLogSpec(operations)
+	if err != nil || active == "" {
+		return
+	}
+	if active == GetStartLogSpec(nodeDir) {
+		return
+	}
+	l, err := lock.Acquire(kind, id, kind+".logspec.sync", LogSpecUpdatedByOperations, 0, "")
+	if err != nil {
+		log.Debugf("Logging spec of %s %s not synced: %v", kind, id, err)
+		return
+	}
+	defer func() {
+		if err := l.Release(); err != nil {
+			log.Warnf("Failed to release the lock of %s %s: %v", kind, id, err)
+		}
+	}()
+	entry := audit.Entry{
+		Actor:     LogSpecUpdatedByOperations,
+		Operation: kind + ".logspec.sync",
+		Target:    id,
+		Params:    map[string]string{"spec": active},
+		Result:    audit.ResultOK,
+	}
+	if err := SaveLogSpec(nodeDir, active, LogSpecUpdatedByOperations); err != nil {
+		log.Warnf("Failed to persist the logging spec %s of %s: %v", active, nodeDir, err)
+		entry.Result = audit.ResultError
+		entry.Error = err.Error()
+	} else {
+		log.Infof("Logging spec %s of %s persisted, it's applied again on restart", active, nodeDir)
+	}
+	if err := audit.Record(entry); err != nil {
+		log.Warnf("Failed to write the audit log: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateLogSpec(t *testing.T) {
+	valid := []string{"info", "DEBUG", "gossip,msp=debug:info", "gossip.privdata=warn:error", "grpc=error:chaincode=debug:info"}
+	for _, spec := range valid {
+		if err := ValidateLogSpec(spec); err != nil {
+			t.Errorf("expected %q to be valid, got %v", spec, err)
+		}
+	}
+	invalid := []string{"", "verbose", "gossip=", "gossip msp=debug", "gossip=debug:loud", "=debug"}
+	for _, spec := range invalid {
+		if err := ValidateLogSpec(spec); err == nil {
+			t.Errorf("expected %q to be invalid", spec)
+		}
+	}
+}
+
+func TestLogSpecSurvivesRestart(t *testing.T) {
+	nodeDir := t.TempDir()
+	if spec := GetStartLogSpec(nodeDir); spec != DefaultLogSpec {
+		t.Fatalf("expected the default spec without a persisted one, got %s", spec)
+	}
+	if err := SaveLogSpec(nodeDir, "nope", "user:alice"); err == nil {
+		t.Fatal("expected an invalid spec to be refused")
+	}
+	if err := SaveLogSpec(nodeDir, "gossip=debug:info", "user:alice"); err != nil {
+		t.Fatal(err)
+	}
+	// every start of the node reads the persisted spec again
+	for i := 0; i < 2; i++ {
+		if spec := GetStartLogSpec(nodeDir); spec != "gossip=debug:info" {
+			t.Fatalf("expected the persisted spec on start %d, got %s", i, spec)
+		}
+	}
+	persisted, err := GetLogSpec(nodeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if persisted.UpdatedBy != "user:alice" || persisted.UpdatedAt.IsZero() {
+		t.Errorf("unexpected persisted spec %+v", persisted)
+	}
+	if err := ResetLogSpec(nodeDir); err != nil {
+		t.Fatal(err)
+	}
+	if spec := GetStartLogSpec(nodeDir); spec != DefaultLogSpec {
+		t.Fatalf("expected the default spec after a reset, got %s", spec)
+	}
+	if err := ResetLogSpec(nodeDir); err != nil {
+		t.Fatalf("expected resetting a default spec to succeed, got %v", err)
+	}
+}
+
+// fakeOperations is the /logspec route of the operations endpoint of a node
+type fakeOperations struct {
+	mu   sync.Mutex
+	spec string
+}
+
+func (o *fakeOperations) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(logSpecBody{Spec: o.spec})
+	case http.MethodPut:
+		body := logSpecBody{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		o.spec = body.Spec
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestWatchLogSpec(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	nodeDir := t.TempDir()
+	operations := &fakeOperations{spec: DefaultLogSpec}
+	server := httptest.NewServer(operations)
+	defer server.Close()
+	operationsEndpoint := OperationsEndpoint{Address: strings.TrimPrefix(server.URL, "http://")}
+
+	if err := SetOperationsLogSpec(operationsEndpoint, "msp=debug:info"); err != nil {
+		t.Fatal(err)
+	}
+	if spec, err := GetOperationsLogSpec(operationsEndpoint); err != nil || spec != "msp=debug:info" {
+		t.Fatalf("expected the spec set on the operations endpoint, got %s, %v", spec, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		WatchLogSpec(ctx, fakeStatusNode{}, "peer", "peer1", nodeDir, operationsEndpoint, time.Millisecond)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for GetStartLogSpec(nodeDir) != "msp=debug:info" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	persisted, err := GetLogSpec(nodeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if persisted == nil || persisted.Spec != "msp=debug:info" {
+		t.Fatalf("expected the spec of the operations endpoint to be persisted, got %+v", persisted)
+	}
+	if persisted.UpdatedBy != LogSpecUpdatedByOperations {
+		t.Errorf("expected the spec to be updated by %s, got %s", LogSpecUpdatedByOperations, persisted.UpdatedBy)
+	}
+}
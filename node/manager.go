@@ -0,0 +1,133 @@
+package node
+
+import (
+	"github.com/pkg/errors"
+	"hlf-easy/errdefs"
+	"hlf-easy/log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kinds of the nodes managed by hlf-easy
+const (
+	KindPeer    = "peer"
+	KindOrderer = "orderer"
+)
+
+// ManagedNode is a node whose process is supervised by hlf-easy
+type ManagedNode interface {
+	GetID() string
+	GetKind() string
+	State() State
+	Start() error
+	Stop() error
+	Restart() error
+	Status() (*ProcessState, error)
+}
+
+// ManagedNodeInfo is a node of the registry with its state
+type ManagedNodeInfo struct {
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	State State  `json:"state"`
+}
+
+// Manager is the registry of the nodes managed by hlf-easy, it's safe for
+// concurrent use
+type Manager struct {
+	mu    sync.RWMutex
+	nodes map[string]ManagedNode
+}
+
+// NewManager returns an empty registry
+func NewManager() *Manager {
+	return &Manager{nodes: map[string]ManagedNode{}}
+}
+
+func managerKey(kind string, id string) string {
+	return kind + "/" + id
+}
+
+// Register adds a node to the registry, a kind can't have two nodes with the
+// same id
+func (m *Manager) Register(n ManagedNode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := managerKey(n.GetKind(), n.GetID())
+	if _, ok := m.nodes[key]; ok {
+		return errors.Errorf("%s %s is already registered", n.GetKind(), n.GetID())
+	}
+	m.nodes[key] = n
+	return nil
+}
+
+// Get returns a node of the registry
+func (m *Manager) Get(kind string, id string) (ManagedNode, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n, ok := m.nodes[managerKey(kind, id)]
+	if !ok {
+		return nil, errdefs.Errorf(errdefs.ErrNodeNotFound, "%s %s is not registered", kind, id)
+	}
+	return n, nil
+}
+
+// Unregister removes a stopped node from the registry
+func (m *Manager) Unregister(kind string, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := managerKey(kind, id)
+	n, ok := m.nodes[key]
+	if !ok {
+		return errdefs.Errorf(errdefs.ErrNodeNotFound, "%s %s is not registered", kind, id)
+	}
+	if state := n.State(); state != StateStopped && state != StateFailed {
+		return errdefs.Errorf(errdefs.ErrNodeAlreadyRunning, "%s %s is %s, stop it first", kind, id, strings.ToLower(string(state)))
+	}
+	delete(m.nodes, key)
+	return nil
+}
+
+// List returns the nodes of the registry sorted by kind and id
+func (m *Manager) List() []ManagedNodeInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	infos := []ManagedNodeInfo{}
+	for _, n := range m.nodes {
+		infos = append(infos, ManagedNodeInfo{Kind: n.GetKind(), ID: n.GetID(), State: n.State()})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Kind != infos[j].Kind {
+			return infos[i].Kind < infos[j].Kind
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// StopAll stops the running nodes of the registry, the nodes that fail to stop
+// are returned in the error
+func (m *Manager) StopAll() error {
+	m.mu.RLock()
+	var nodes []ManagedNode
+	for _, n := range m.nodes {
+		nodes = append(nodes, n)
+	}
+	m.mu.RUnlock()
+	var failed []string
+	for _, n := range nodes {
+		if n.State() != StateRunning {
+			continue
+		}
+		if err := n.Stop(); err != nil {
+			log.Warnf("Failed to stop %s %s: %v", n.GetKind(), n.GetID(), err)
+			failed = append(failed, managerKey(n.GetKind(), n.GetID()))
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return errors.Errorf("failed to stop %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
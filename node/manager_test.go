@@ -0,0 +1,83 @@
+package node
+
+import (
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"os/exec"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestManager(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	newCmd := func() (*exec.Cmd, error) {
+		return exec.Command("sleep", "30"), nil
+	}
+	m := NewManager()
+	peer := NewPeerNode("peer0", "Org1MSP", config.NodeLimits{}, newCmd)
+	orderer := NewOrdererNode("orderer0", "OrdererMSP", config.NodeLimits{}, newCmd)
+	// the nodes are registered concurrently
+	var wg sync.WaitGroup
+	for _, n := range []ManagedNode{peer, orderer} {
+		wg.Add(1)
+		go func(n ManagedNode) {
+			defer wg.Done()
+			if err := m.Register(n); err != nil {
+				t.Error(err)
+			}
+		}(n)
+	}
+	wg.Wait()
+	if err := m.Register(NewPeerNode("peer0", "Org1MSP", config.NodeLimits{}, newCmd)); err == nil {
+		t.Fatal("expected an error for a registered peer")
+	}
+	if _, err := m.Get(KindPeer, "peer1"); !errors.Is(err, errdefs.ErrNodeNotFound) {
+		t.Fatalf("expected the peer not to be found, got %v", err)
+	}
+	n, err := m.Get(KindPeer, "peer0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ManagedNodeInfo{
+		{Kind: KindOrderer, ID: "orderer0", State: StateStopped},
+		{Kind: KindPeer, ID: "peer0", State: StateRunning},
+	}
+	if !reflect.DeepEqual(m.List(), expected) {
+		t.Errorf("expected %+v, got %+v", expected, m.List())
+	}
+	if err := m.Unregister(KindPeer, "peer0"); !errors.Is(err, errdefs.ErrNodeAlreadyRunning) {
+		t.Fatalf("expected a running peer not to be unregistered, got %v", err)
+	}
+	err = m.StopAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peer.State() != StateStopped {
+		t.Errorf("expected the peer to be stopped, got %s", peer.State())
+	}
+	err = m.Unregister(KindPeer, "peer0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.List()) != 1 {
+		t.Errorf("expected the orderer alone, got %+v", m.List())
+	}
+}
+
+func TestTransition(t *testing.T) {
+	state := StateStopped
+	for _, to := range []State{StateStarting, StateRunning, StateStopping, StateStopped} {
+		if err := Transition(&state, to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Transition(&state, StateRunning); err == nil || state != StateStopped {
+		t.Errorf("expected a stopped node not to run without starting, got %s", state)
+	}
+}
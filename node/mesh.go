@@ -0,0 +1,113 @@
+package node
+
+import (
+	"bufio"
+	"github.com/pkg/errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Leader roles of a peer on a channel
+const (
+	// LeaderStatic is a peer configured as a leader of its org, hlf-easy
+	// starts the peers with CORE_PEER_GOSSIP_ORGLEADER=true
+	LeaderStatic = "static"
+	// LeaderElected and LeaderFollower are the roles of the peers started
+	// with CORE_PEER_GOSSIP_USELEADERELECTION=true
+	LeaderElected  = "elected"
+	LeaderFollower = "follower"
+)
+
+// ChannelGossip is the gossip of a peer on a channel as its metrics report
+// it
+type ChannelGossip struct {
+	Channel string `json:"channel"`
+	// PeersKnown is the number of peers of the channel the peer knows
+	PeersKnown int `json:"peersKnown"`
+	// Leader is the role of the peer pulling the blocks from the orderers
+	// for its org
+	Leader string `json:"leader"`
+}
+
+// gossipMetricPattern matches the gossip metrics of a channel in the metrics
+// of a peer
+var gossipMetricPattern = regexp.MustCompile(`^(gossip_membership_total_peers_known|gossip_leader_election_leader)\{(?:.*,)?channel="([^"]+)"(?:,.*)?\} ([0-9.e+]+)$`)
+
+// ParseGossipMetrics reads the gossip of the channels from the Prometheus
+// metrics of a peer, the peers without leader election are static leaders
+func ParseGossipMetrics(r io.Reader) ([]ChannelGossip, error) {
+	channels := map[string]*ChannelGossip{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		match := gossipMetricPattern.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		value, err := strconv.ParseFloat(match[3], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s of channel %s", match[1], match[2])
+		}
+		channel, ok := channels[match[2]]
+		if !ok {
+			channel = &ChannelGossip{Channel: match[2], Leader: LeaderStatic}
+			channels[match[2]] = channel
+		}
+		if match[1] == "gossip_membership_total_peers_known" {
+			channel.PeersKnown = int(value)
+		} else if value == 1 {
+			channel.Leader = LeaderElected
+		} else {
+			channel.Leader = LeaderFollower
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	gossip := []ChannelGossip{}
+	for _, channel := range channels {
+		gossip = append(gossip, *channel)
+	}
+	sort.Slice(gossip, func(i, j int) bool {
+		return gossip[i].Channel < gossip[j].Channel
+	})
+	return gossip, nil
+}
+
+// GetGossipMetrics reads the gossip of the channels of a peer from the
+// metrics of its operations endpoint
+func GetGossipMetrics(endpoint OperationsEndpoint) ([]ChannelGossip, error) {
+	var gossip []ChannelGossip
+	err := readMetrics(endpoint, func(r io.Reader) error {
+		var err error
+		gossip, err = ParseGossipMetrics(r)
+		return err
+	})
+	return gossip, err
+}
+
+// PeerEndpoint is a peer of the host and the endpoint the other peers gossip
+// with it on
+type PeerEndpoint struct {
+	ID       string `json:"id"`
+	MSPID    string `json:"mspId"`
+	Endpoint string `json:"endpoint"`
+}
+
+// ListPeerEndpoints returns the external endpoints of the peers of the host
+func ListPeerEndpoints() ([]PeerEndpoint, error) {
+	peers, err := getPeersInitOptions()
+	if err != nil {
+		return nil, err
+	}
+	endpoints := []PeerEndpoint{}
+	for _, peer := range peers {
+		endpoints = append(endpoints, PeerEndpoint{ID: peer.ID, MSPID: peer.MSPID, Endpoint: peerExternalEndpoint(peer)})
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].ID < endpoints[j].ID
+	})
+	return endpoints, nil
+}
@@ -0,0 +1,28 @@
+package node
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGossipMetrics(t *testing.T) {
+	metrics := `# HELP gossip_membership_total_peers_known Total known peers
+# TYPE gossip_membership_total_peers_known gauge
+gossip_membership_total_peers_known{channel="mychannel"} 3
+gossip_membership_total_peers_known{channel="other"} 0
+gossip_leader_election_leader{channel="other"} 0
+ledger_blockchain_height{channel="mychannel"} 12
+`
+	gossip, err := ParseGossipMetrics(strings.NewReader(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ChannelGossip{
+		{Channel: "mychannel", PeersKnown: 3, Leader: LeaderStatic},
+		{Channel: "other", PeersKnown: 0, Leader: LeaderFollower},
+	}
+	if !reflect.DeepEqual(gossip, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, gossip)
+	}
+}
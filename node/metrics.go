@@ -0,0 +1,155 @@
+package node
+
+import (
+	"bufio"
+	"github.com/pkg/errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMetricsInterval is how often the Fabric metrics of a peer are
+// scraped for its status
+const DefaultMetricsInterval = 30 * time.Second
+
+// ChannelMetrics are the Fabric counters of a channel of a peer
+type ChannelMetrics struct {
+	Channel string `json:"channel"`
+	Height  uint64 `json:"height"`
+	// ValidTransactions and InvalidTransactions are the transactions of the
+	// blocks committed since the peer started, by their validation code
+	ValidTransactions   uint64 `json:"validTransactions"`
+	InvalidTransactions uint64 `json:"invalidTransactions"`
+	// BlockCommitRate and TxCommitRate are the blocks and the valid
+	// transactions committed per second since the previous scrape, 0 on the
+	// first one
+	BlockCommitRate float64 `json:"blockCommitRate"`
+	TxCommitRate    float64 `json:"txCommitRate"`
+}
+
+// PeerMetrics are the Fabric counters of a peer, scraped from the Prometheus
+// metrics of its operations endpoint
+type PeerMetrics struct {
+	Channels []ChannelMetrics `json:"channels"`
+	// ProposalsReceived, SuccessfulProposals and EndorsementFailures count
+	// the proposals of the endorser since the peer started
+	ProposalsReceived   uint64    `json:"proposalsReceived"`
+	SuccessfulProposals uint64    `json:"successfulProposals"`
+	EndorsementFailures uint64    `json:"endorsementFailures"`
+	ScrapedAt           time.Time `json:"scrapedAt"`
+	// Error is set when the metrics of the peer couldn't be read
+	Error string `json:"error,omitempty"`
+}
+
+// metricPattern matches a sample of the Prometheus text format, its name, its
+// labels and its value
+var metricPattern = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(?:\{(.*)\})?\s+(\S+)`)
+
+// labelPattern matches a label of a sample
+var labelPattern = regexp.MustCompile(`([a-zA-Z_][a-zA-Z0-9_]*)="((?:[^"\\]|\\.)*)"`)
+
+// ParsePeerMetrics reads the block heights, the committed transactions and the
+// endorsements from the Prometheus metrics of a peer
+func ParsePeerMetrics(r io.Reader) (*PeerMetrics, error) {
+	metrics := &PeerMetrics{Channels: []ChannelMetrics{}}
+	channels := map[string]*ChannelMetrics{}
+	channel := func(name string) *ChannelMetrics {
+		if c, ok := channels[name]; ok {
+			return c
+		}
+		c := &ChannelMetrics{Channel: name}
+		channels[name] = c
+		return c
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		match := metricPattern.FindStringSubmatch(line)
+		if match == nil || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := match[1]
+		switch name {
+		case "ledger_blockchain_height", "ledger_transaction_count", "endorser_proposals_received", "endorser_successful_proposals", "endorser_endorsement_failures":
+		default:
+			continue
+		}
+		value, err := strconv.ParseFloat(match[3], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value of metric %s", name)
+		}
+		labels := map[string]string{}
+		for _, label := range labelPattern.FindAllStringSubmatch(match[2], -1) {
+			labels[label[1]] = label[2]
+		}
+		switch name {
+		case "ledger_blockchain_height":
+			channel(labels["channel"]).Height = uint64(value)
+		case "ledger_transaction_count":
+			if labels["validation_code"] == "VALID" {
+				channel(labels["channel"]).ValidTransactions += uint64(value)
+			} else {
+				channel(labels["channel"]).InvalidTransactions += uint64(value)
+			}
+		case "endorser_proposals_received":
+			metrics.ProposalsReceived += uint64(value)
+		case "endorser_successful_proposals":
+			metrics.SuccessfulProposals += uint64(value)
+		case "endorser_endorsement_failures":
+			metrics.EndorsementFailures += uint64(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, c := range channels {
+		metrics.Channels = append(metrics.Channels, *c)
+	}
+	sort.Slice(metrics.Channels, func(i, j int) bool {
+		return metrics.Channels[i].Channel < metrics.Channels[j].Channel
+	})
+	return metrics, nil
+}
+
+// GetPeerMetrics reads the Fabric counters of a peer from the metrics of its
+// operations endpoint, they're only served by the prometheus provider
+func GetPeerMetrics(endpoint OperationsEndpoint) (*PeerMetrics, error) {
+	var metrics *PeerMetrics
+	err := readMetrics(endpoint, func(r io.Reader) error {
+		var err error
+		metrics, err = ParsePeerMetrics(r)
+		return err
+	})
+	return metrics, err
+}
+
+// ComputeCommitRates sets the commit rates of the channels against the
+// previous scrape, a channel whose counters went back, as they do when the
+// peer restarts, has no rate
+func ComputeCommitRates(previous *PeerMetrics, current *PeerMetrics) {
+	elapsed := current.ScrapedAt.Sub(previous.ScrapedAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	before := map[string]ChannelMetrics{}
+	for _, c := range previous.Channels {
+		before[c.Channel] = c
+	}
+	for i, c := range current.Channels {
+		p, ok := before[c.Channel]
+		if !ok || c.Height < p.Height || c.ValidTransactions < p.ValidTransactions {
+			continue
+		}
+		current.Channels[i].BlockCommitRate = float64(c.Height-p.Height) / elapsed
+		current.Channels[i].TxCommitRate = float64(c.ValidTransactions-p.ValidTransactions) / elapsed
+	}
+}
+
+// SetMetrics sets the source of the Fabric counters reported in the status of
+// the peer, it's set before the peer starts
+func (n *PeerNode) SetMetrics(metrics func() *PeerMetrics) {
+	n.metrics = metrics
+}
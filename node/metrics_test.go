@@ -0,0 +1,58 @@
+package node
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPeerMetrics = `# HELP ledger_blockchain_height Height of the chain in blocks.
+# TYPE ledger_blockchain_height gauge
+ledger_blockchain_height{channel="mychannel"} 12
+ledger_blockchain_height{channel="other"} 3
+# TYPE ledger_transaction_count counter
+ledger_transaction_count{chaincode="basic:1.0",channel="mychannel",transaction_type="ENDORSER_TRANSACTION",validation_code="VALID"} 40
+ledger_transaction_count{chaincode="basic:1.0",channel="mychannel",transaction_type="ENDORSER_TRANSACTION",validation_code="MVCC_READ_CONFLICT"} 2
+ledger_transaction_count{chaincode="_lifecycle:1.0",channel="mychannel",transaction_type="ENDORSER_TRANSACTION",validation_code="VALID"} 4
+endorser_proposals_received 50
+endorser_successful_proposals 47
+endorser_endorsement_failures{chaincode="basic:1.0",chaincodeerror="false",channel="mychannel"} 2
+endorser_endorsement_failures{chaincode="basic:1.0",chaincodeerror="true",channel="mychannel"} 1
+`
+
+func TestParsePeerMetrics(t *testing.T) {
+	metrics, err := ParsePeerMetrics(strings.NewReader(testPeerMetrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics.Channels) != 2 || metrics.Channels[0].Channel != "mychannel" || metrics.Channels[1].Height != 3 {
+		t.Fatalf("expected the channels sorted with their heights, got %+v", metrics.Channels)
+	}
+	if c := metrics.Channels[0]; c.Height != 12 || c.ValidTransactions != 44 || c.InvalidTransactions != 2 {
+		t.Fatalf("expected 44 valid and 2 invalid transactions at height 12, got %+v", c)
+	}
+	if metrics.ProposalsReceived != 50 || metrics.SuccessfulProposals != 47 || metrics.EndorsementFailures != 3 {
+		t.Fatalf("expected the endorsements of all the chaincodes, got %+v", metrics)
+	}
+}
+
+func TestComputeCommitRates(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	previous := &PeerMetrics{ScrapedAt: now, Channels: []ChannelMetrics{
+		{Channel: "mychannel", Height: 10, ValidTransactions: 100},
+		{Channel: "other", Height: 50, ValidTransactions: 10},
+	}}
+	current := &PeerMetrics{ScrapedAt: now.Add(10 * time.Second), Channels: []ChannelMetrics{
+		{Channel: "mychannel", Height: 30, ValidTransactions: 600},
+		{Channel: "new", Height: 1},
+		// the counters of the transactions restart with the peer
+		{Channel: "other", Height: 50},
+	}}
+	ComputeCommitRates(previous, current)
+	if c := current.Channels[0]; c.BlockCommitRate != 2 || c.TxCommitRate != 50 {
+		t.Fatalf("expected 2 blocks and 50 transactions per second, got %+v", c)
+	}
+	if current.Channels[1].BlockCommitRate != 0 || current.Channels[2].TxCommitRate != 0 {
+		t.Fatalf("expected no rate for a new channel and counters that went back, got %+v", current.Channels)
+	}
+}
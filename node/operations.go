@@ -0,0 +1,170 @@
+package node
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultOperationsListenAddress is the address of the operations endpoint
+// of the peers without one
+const DefaultOperationsListenAddress = "0.0.0.0:9443"
+
+// Metrics providers of the peers
+const (
+	MetricsPrometheus = "prometheus"
+	MetricsStatsd     = "statsd"
+	MetricsDisabled   = "disabled"
+)
+
+// ValidateOperations checks the operations settings of a peer
+func ValidateOperations(opts config.OperationsOptions) error {
+	if opts.ListenAddress != "" {
+		if _, _, err := net.SplitHostPort(opts.ListenAddress); err != nil {
+			return errors.Wrapf(err, "invalid operations listen address %q", opts.ListenAddress)
+		}
+	}
+	switch opts.MetricsProvider {
+	case "", MetricsPrometheus, MetricsDisabled:
+		if opts.StatsdAddress != "" {
+			return errors.New("the statsd address requires the statsd metrics provider")
+		}
+	case MetricsStatsd:
+		if _, _, err := net.SplitHostPort(opts.StatsdAddress); err != nil {
+			return errors.Errorf("invalid statsd address %q, the statsd metrics provider requires one", opts.StatsdAddress)
+		}
+	default:
+		return errors.Errorf("invalid metrics provider %q, expected %s, %s or %s", opts.MetricsProvider, MetricsPrometheus, MetricsStatsd, MetricsDisabled)
+	}
+	if opts.ClientAuthRequired && !opts.TLS {
+		return errors.New("the client authentication of the operations endpoint requires its TLS")
+	}
+	return nil
+}
+
+// operationsWithDefaults fills the empty operations settings with the ones
+// of hlf-easy, the peers expose Prometheus metrics by default
+func operationsWithDefaults(opts config.OperationsOptions) config.OperationsOptions {
+	if opts.ListenAddress == "" {
+		opts.ListenAddress = DefaultOperationsListenAddress
+	}
+	if opts.MetricsProvider == "" {
+		opts.MetricsProvider = MetricsPrometheus
+	}
+	return opts
+}
+
+// GetPeerOperations returns the operations settings of a peer with their
+// defaults
+func GetPeerOperations(peerInitOpts config.PeerInitOptions) config.OperationsOptions {
+	return operationsWithDefaults(peerInitOpts.Operations)
+}
+
+// GetOperationsTLSFiles returns the certificate and the key the operations
+// endpoint of a peer is served with, the local CA issues its own ones, the
+// peers enrolled with a Fabric CA serve their TLS certificate
+func GetOperationsTLSFiles(peerDir string, peerInitOpts config.PeerInitOptions) (string, string) {
+	if peerInitOpts.Local {
+		return filepath.Join(peerDir, "operations", "tls.crt"), filepath.Join(peerDir, "operations", "tls.key")
+	}
+	return filepath.Join(peerDir, "tls.crt"), filepath.Join(peerDir, "tls.key")
+}
+
+// OperationsEndpoint is the operations endpoint of a node and how hlf-easy
+// connects to it
+type OperationsEndpoint struct {
+	Address string
+	// TLS is the client config of an endpoint served over TLS
+	TLS *tls.Config
+}
+
+// URL returns the URL of a path of the endpoint
+func (e OperationsEndpoint) URL(path string) string {
+	scheme := "http"
+	if e.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, e.Address, path)
+}
+
+// Client returns the HTTP client of the endpoint
+func (e OperationsEndpoint) Client() *http.Client {
+	if e.TLS == nil {
+		return operationsHTTP
+	}
+	return &http.Client{
+		Timeout:   operationsHTTP.Timeout,
+		Transport: &http.Transport{TLSClientConfig: e.TLS},
+	}
+}
+
+// GetPeerOperationsEndpoint returns the operations endpoint of a peer bound
+// to the address, with the client TLS config of its settings
+func GetPeerOperationsEndpoint(peerDir string, address string) (OperationsEndpoint, error) {
+	endpoint := OperationsEndpoint{Address: address}
+	peerInitOpts, err := readPeerInitOptions(peerDir)
+	if err != nil {
+		return endpoint, err
+	}
+	if !peerInitOpts.Operations.TLS {
+		return endpoint, nil
+	}
+	caBytes, err := os.ReadFile(filepath.Join(peerDir, "tlscacerts", "cacert.pem"))
+	if err != nil {
+		return endpoint, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caBytes) {
+		return endpoint, errors.Errorf("no TLS CA certificate in %s", filepath.Join(peerDir, "tlscacerts", "cacert.pem"))
+	}
+	endpoint.TLS = &tls.Config{
+		// the endpoint is reached through its listen address, e.g. 0.0.0.0,
+		// so its certificate is verified against the TLS CA without its host
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyOperationsCertificate(rawCerts, roots)
+		},
+	}
+	if peerInitOpts.Operations.ClientAuthRequired {
+		// the TLS certificate of the peer is issued by the TLS CA the endpoint
+		// trusts
+		cert, err := tls.LoadX509KeyPair(filepath.Join(peerDir, "tls.crt"), filepath.Join(peerDir, "tls.key"))
+		if err != nil {
+			return endpoint, err
+		}
+		endpoint.TLS.Certificates = []tls.Certificate{cert}
+	}
+	return endpoint, nil
+}
+
+func verifyOperationsCertificate(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("the operations endpoint presented no certificate")
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, cert)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	return err
+}
@@ -0,0 +1,134 @@
+package node
+
+import (
+	"crypto/tls"
+	"hlf-easy/config"
+	"hlf-easy/plan"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateOperations(t *testing.T) {
+	valid := []config.OperationsOptions{
+		{},
+		{ListenAddress: "127.0.0.1:9443", MetricsProvider: MetricsDisabled},
+		{MetricsProvider: MetricsStatsd, StatsdAddress: "127.0.0.1:8125"},
+		{TLS: true, ClientAuthRequired: true},
+	}
+	for _, opts := range valid {
+		if err := ValidateOperations(opts); err != nil {
+			t.Errorf("expected %+v to be valid: %v", opts, err)
+		}
+	}
+	invalid := []config.OperationsOptions{
+		{ListenAddress: "9443"},
+		{MetricsProvider: "graphite"},
+		{MetricsProvider: MetricsStatsd},
+		{StatsdAddress: "127.0.0.1:8125"},
+		{ClientAuthRequired: true},
+	}
+	for _, opts := range invalid {
+		if err := ValidateOperations(opts); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
+
+func TestRenderOperations(t *testing.T) {
+	peerDir := t.TempDir()
+	err := renderPeerCoreYaml(plan.Disk, peerDir, config.PeerInitOptions{ID: "peer0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreYaml, err := os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"listenAddress: 0.0.0.0:9443\n", "# TLS enabled\n    enabled: false\n", "clientRootCAs:\n      files: []\n", "provider: prometheus\n"} {
+		if !strings.Contains(string(coreYaml), expected) {
+			t.Fatalf("expected the default operations setting %q", expected)
+		}
+	}
+
+	operations := config.OperationsOptions{ListenAddress: "10.0.0.1:9543", MetricsProvider: MetricsStatsd, StatsdAddress: "statsd:8125", TLS: true, ClientAuthRequired: true}
+	err = renderPeerCoreYaml(plan.Disk, peerDir, config.PeerInitOptions{ID: "peer0", Local: true, Operations: operations}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreYaml, err = os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"listenAddress: 10.0.0.1:9543\n",
+		"# TLS enabled\n    enabled: true\n",
+		"file: " + filepath.Join(peerDir, "operations", "tls.crt") + "\n",
+		"file: " + filepath.Join(peerDir, "operations", "tls.key") + "\n",
+		"clientAuthRequired: true\n",
+		"files:\n      - " + filepath.Join(peerDir, "tlscacerts", "cacert.pem") + "\n",
+		"provider: statsd\n",
+		"address: statsd:8125\n",
+	} {
+		if !strings.Contains(string(coreYaml), expected) {
+			t.Fatalf("expected the operations setting %q", expected)
+		}
+	}
+}
+
+func TestGetPeerOperationsEndpoint(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	initTestPeer(t, home, config.PeerInitOptions{
+		ID:         "peer0",
+		Hosts:      []string{"peer0.org1.example.com"},
+		MSPID:      "Org1MSP",
+		Operations: config.OperationsOptions{TLS: true, ClientAuthRequired: true},
+	})
+	peerDir := filepath.Join(home, "hlf-easy/peers/peer0")
+
+	// the operations endpoint serves the certificate issued by the local CA
+	// and requires a client certificate of the TLS CA
+	certFile, keyFile := GetOperationsTLSFiles(peerDir, config.PeerInitOptions{Local: true})
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"status":"OK"}`))
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	endpoint, err := GetPeerOperationsEndpoint(peerDir, strings.TrimPrefix(server.URL, "https://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.URL("/healthz") != server.URL+"/healthz" {
+		t.Fatalf("unexpected URL %s", endpoint.URL("/healthz"))
+	}
+	resp, err := endpoint.Client().Get(endpoint.URL("/healthz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the client certificate to be presented, got %s", resp.Status)
+	}
+
+	// a certificate of another CA isn't trusted
+	untrusted := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer untrusted.Close()
+	endpoint.Address = strings.TrimPrefix(untrusted.URL, "https://")
+	if _, err := endpoint.Client().Get(endpoint.URL("/healthz")); err == nil {
+		t.Fatal("expected an error for a certificate of another CA")
+	}
+}
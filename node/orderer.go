@@ -1,20 +1,33 @@
 package node
 
 import (
+	"bytes"
+	"context"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"github.com/Masterminds/sprig/v3"
 	"github.com/pkg/errors"
 	"github.com/shirou/gopsutil/process"
-	log "github.com/sirupsen/logrus"
 	"hlf-easy/certs"
 	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"hlf-easy/hooks"
+	"hlf-easy/limits"
+	"hlf-easy/log"
+	"hlf-easy/notify"
+	"hlf-easy/plan"
+	"hlf-easy/proc"
+	"hlf-easy/resources"
 	"hlf-easy/utils"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"text/template"
+	"time"
 )
 
 type OrdererNode struct {
@@ -23,6 +36,22 @@ type OrdererNode struct {
 	cmd       *exec.Cmd
 	p         *process.Process
 	mspID     string
+	limits    config.NodeLimits
+	// releaseLimits removes the resources used to enforce the limits
+	releaseLimits func() error
+	// hooks run on the start, stop and crash of the process
+	hooks config.NodeHooks
+	// exited is closed when the process exits, the state tells an exit
+	// requested by Stop from a crash
+	exited chan struct{}
+	state  State
+	// mu guards the process and the state, lifecycle serializes Start, Stop
+	// and Restart so the state moves through Starting and Stopping
+	mu        sync.Mutex
+	lifecycle sync.Mutex
+	// statusMu serializes the queries of the process, it's not safe for
+	// concurrent use
+	statusMu sync.Mutex
 }
 
 type OrdererConfig struct {
@@ -36,7 +65,7 @@ type OrdererConfig struct {
 func (n *OrdererNode) GetConfig() (*OrdererConfig, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	ordererDir := filepath.Join(home, fmt.Sprintf("hlf-easy/orderers/%s", n.id))
 	tlsCertBytes, err := os.ReadFile(filepath.Join(ordererDir, "tls.crt"))
@@ -72,56 +101,247 @@ func (n *OrdererNode) GetMSPID() string {
 	return n.mspID
 }
 
+// GetKind returns the kind of the node in the registry
+func (n *OrdererNode) GetKind() string {
+	return KindOrderer
+}
+
+// Attach re-attaches to the orderer process left running by an hlf-easy process
+// that exited, it returns false when there is none
+func (n *OrdererNode) Attach() (bool, error) {
+	n.lifecycle.Lock()
+	defer n.lifecycle.Unlock()
+	p, err := findRecordedProcess(KindOrderer, n.id)
+	if err != nil || p == nil {
+		return false, err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := Transition(&n.state, StateStarting); err != nil {
+		return false, errdefs.Errorf(errdefs.ErrNodeAlreadyRunning, "orderer node is already started")
+	}
+	// the limits are applied again, an attached orderer isn't killed when they
+	// can't be
+	release, err := limits.Apply(fmt.Sprintf("orderer-%s", n.id), int(p.Pid), n.limits)
+	if err != nil {
+		log.Warnf("Failed to limit orderer node: %v", err)
+	}
+	n.p = p
+	n.releaseLimits = release
+	n.exited = make(chan struct{})
+	_ = Transition(&n.state, StateRunning)
+	if err := saveProcessRecord(KindOrderer, n.id, int(p.Pid)); err != nil {
+		log.Warnf("Failed to record orderer node process: %v", err)
+	}
+	createTime, _ := p.CreateTime()
+	go n.wait(func() error { return WaitProcess(int(p.Pid), createTime) }, n.exited)
+	log.Infof("Attached to orderer node process %d", p.Pid)
+	return true, nil
+}
+
+// State returns the lifecycle state of the orderer process
+func (n *OrdererNode) State() State {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.state
+}
+
+// Start starts the orderer process and watches it to notify when it crashes
 func (n *OrdererNode) Start() error {
-	if n.cmd != nil {
-		log.Info("Orderer node is already started")
-		return errors.New("orderer node is already started")
+	n.lifecycle.Lock()
+	defer n.lifecycle.Unlock()
+	err := n.start()
+	if err != nil {
+		return err
 	}
-	cmd, err := n.cmdGetter()
+	go notify.Notify(notify.NewEvent(notify.EventNodeStarted, "orderer", n.id, fmt.Sprintf("Orderer %s started", n.id)))
+	return nil
+}
+
+// Restart stops and starts the orderer process
+func (n *OrdererNode) Restart() error {
+	n.lifecycle.Lock()
+	defer n.lifecycle.Unlock()
+	err := n.stop()
 	if err != nil {
-		log.Warnf("Failed to get orderer node command: %v", err)
 		return err
 	}
-	n.cmd = cmd
-	if err := n.cmd.Start(); err != nil {
-		log.Warnf("Failed to start orderer node: %v", err)
+	err = n.start()
+	if err != nil {
 		return err
 	}
+	go notify.Notify(notify.NewEvent(notify.EventNodeRestarted, "orderer", n.id, fmt.Sprintf("Orderer %s restarted", n.id)))
+	return nil
+}
 
-	p, err := process.NewProcess(int32(n.cmd.Process.Pid))
+func (n *OrdererNode) start() error {
+	n.mu.Lock()
+	if err := Transition(&n.state, StateStarting); err != nil {
+		n.mu.Unlock()
+		log.Info("Orderer node is already started")
+		return errdefs.Errorf(errdefs.ErrNodeAlreadyRunning, "orderer node is already started")
+	}
+	n.mu.Unlock()
+	cmd, p, release, err := n.startProcess()
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	if err != nil {
-		log.Warnf("Failed to get orderer node process: %v", err)
+		_ = Transition(&n.state, StateFailed)
 		return err
 	}
+	n.cmd = cmd
 	n.p = p
+	n.releaseLimits = release
+	n.exited = make(chan struct{})
+	_ = Transition(&n.state, StateRunning)
+	if err := saveProcessRecord(KindOrderer, n.id, cmd.Process.Pid); err != nil {
+		log.Warnf("Failed to record orderer node process: %v", err)
+	}
+	// the exit is handled once the orderer is running
+	go n.wait(cmd.Wait, n.exited)
+	go hooks.RunLogged(n.hooks, hooks.PostStart, KindOrderer, n.id, map[string]string{"pid": strconv.Itoa(cmd.Process.Pid)})
 	return nil
 }
 
+// startProcess starts the orderer process within its limits, a process that
+// can't be limited or watched is killed
+func (n *OrdererNode) startProcess() (*exec.Cmd, *process.Process, func() error, error) {
+	// a failed pre-start hook, e.g. a volume that can't be mounted, keeps the
+	// orderer from starting
+	if err := hooks.Run(n.hooks, hooks.PreStart, KindOrderer, n.id, nil); err != nil {
+		log.Warnf("%v", err)
+		return nil, nil, nil, err
+	}
+	cmd, err := n.cmdGetter()
+	if err != nil {
+		log.Warnf("Failed to get orderer node command: %v", err)
+		return nil, nil, nil, err
+	}
+	err = cmd.Start()
+	closeOutput(cmd)
+	if err != nil {
+		log.Warnf("Failed to start orderer node: %v", err)
+		return nil, nil, nil, err
+	}
+
+	// an orderer that can't be limited is killed so it can't starve the host
+	release, err := limits.Apply(fmt.Sprintf("orderer-%s", n.id), cmd.Process.Pid, n.limits)
+	if err != nil {
+		log.Warnf("Failed to limit orderer node: %v", err)
+		_ = cmd.Process.Kill()
+		_, _ = cmd.Process.Wait()
+		return nil, nil, nil, errors.Wrap(err, "failed to limit orderer node")
+	}
+
+	p, err := process.NewProcess(int32(cmd.Process.Pid))
+	if err != nil {
+		log.Warnf("Failed to get orderer node process: %v", err)
+		_ = cmd.Process.Kill()
+		_, _ = cmd.Process.Wait()
+		if release != nil {
+			_ = release()
+		}
+		return nil, nil, nil, err
+	}
+	return cmd, p, release, nil
+}
+
+// wait waits for the orderer process to exit, an exit that wasn't requested by
+// Stop is notified as a crash
+func (n *OrdererNode) wait(done func() error, exited chan struct{}) {
+	err := done()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	close(exited)
+	if n.state == StateStopping {
+		return
+	}
+	log.Warnf("Orderer node exited unexpectedly: %v", err)
+	n.cmd = nil
+	n.p = nil
+	n.release()
+	if err := removeProcessRecord(KindOrderer, n.id); err != nil {
+		log.Warnf("Failed to remove orderer node process record: %v", err)
+	}
+	_ = Transition(&n.state, StateFailed)
+	event := notify.NewEvent(notify.EventNodeCrashed, "orderer", n.id, fmt.Sprintf("Orderer %s crashed", n.id))
+	if err != nil {
+		event.Details = map[string]string{"exit": err.Error()}
+	}
+	go notify.Notify(event)
+	go hooks.RunLogged(n.hooks, hooks.PostCrash, KindOrderer, n.id, event.Details)
+}
+
+// release removes the resources used to enforce the limits
+func (n *OrdererNode) release() {
+	if n.releaseLimits == nil {
+		return
+	}
+	err := n.releaseLimits()
+	if err != nil {
+		log.Warnf("Failed to release orderer node limits: %v", err)
+	}
+	n.releaseLimits = nil
+}
+
+// Stop stops the orderer process and waits for it to exit
 func (n *OrdererNode) Stop() error {
-	if n.cmd == nil || n.cmd.Process == nil {
+	n.lifecycle.Lock()
+	defer n.lifecycle.Unlock()
+	return n.stop()
+}
+
+func (n *OrdererNode) stop() error {
+	n.mu.Lock()
+	if err := Transition(&n.state, StateStopping); err != nil {
+		n.mu.Unlock()
 		log.Info("Orderer node is already stopped")
-		return errors.New("orderer node is already stopped")
+		return errdefs.Errorf(errdefs.ErrNodeNotRunning, "orderer node is already stopped")
 	}
-	err := n.cmd.Process.Signal(os.Interrupt)
-	if err != nil {
-		log.Warnf("Failed to stop orderer node: %v", err)
-		return err
+	cmd, p := n.cmd, n.p
+	exited := n.exited
+	n.mu.Unlock()
+	// a failed pre-stop hook doesn't keep the orderer running
+	hooks.RunLogged(n.hooks, hooks.PreStop, KindOrderer, n.id, nil)
+	var err error
+	if cmd != nil {
+		err = proc.Interrupt(cmd.Process)
+	} else {
+		// an attached process isn't a child of hlf-easy, it's terminated
+		// by its PID
+		err = proc.Terminate(int(p.Pid))
 	}
-	state, err := n.cmd.Process.Wait()
 	if err != nil {
 		log.Warnf("Failed to stop orderer node: %v", err)
+		n.mu.Lock()
+		_ = Transition(&n.state, StateRunning)
+		n.mu.Unlock()
 		return err
 	}
-	_ = state
+	<-exited
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	n.cmd = nil
+	n.p = nil
+	n.release()
+	if err := removeProcessRecord(KindOrderer, n.id); err != nil {
+		log.Warnf("Failed to remove orderer node process record: %v", err)
+	}
+	_ = Transition(&n.state, StateStopped)
 	return nil
 }
 
 func (n *OrdererNode) Status() (*ProcessState, error) {
-	if n.cmd == nil || n.cmd.Process == nil {
+	n.statusMu.Lock()
+	defer n.statusMu.Unlock()
+	n.mu.Lock()
+	p, state := n.p, n.state
+	n.mu.Unlock()
+	if p == nil {
 		return &ProcessState{
 			PID:    0,
 			Status: "Stop",
+			State:  state,
 			MemoryInfo: &process.MemoryInfoStat{
 				RSS:    0,
 				VMS:    0,
@@ -137,7 +357,7 @@ func (n *OrdererNode) Status() (*ProcessState, error) {
 		}, nil
 	}
 
-	status, err := n.p.Status()
+	status, err := p.Status()
 	if err != nil {
 		log.Warnf("Failed to get orderer node status: %v", err)
 		return nil, err
@@ -146,23 +366,33 @@ func (n *OrdererNode) Status() (*ProcessState, error) {
 	if !ok {
 		statusStr = "Unknown"
 	}
-	memoryInfo, err := n.p.MemoryInfo()
+	memoryInfo, err := p.MemoryInfo()
 	if err != nil {
 		log.Warnf("Failed to get orderer node memory info: %v", err)
 		return nil, err
 	}
-	cpuPercent, err := n.p.CPUPercent()
+	cpuPercent, err := p.CPUPercent()
 	if err != nil {
 		log.Warnf("Failed to get orderer node cpu percent: %v", err)
 		return nil, err
 	}
+	createTime, err := p.CreateTime()
+	if err != nil {
+		log.Warnf("Failed to get orderer node create time: %v", err)
+		return nil, err
+	}
 	ps := &ProcessState{
-		PID:        int(n.p.Pid),
+		PID:        int(p.Pid),
 		Status:     statusStr,
 		MemoryInfo: memoryInfo,
 		CPUInfo: CPUInfo{
 			CPUPercent: cpuPercent,
 		},
+		Uptime: time.Since(time.UnixMilli(createTime)).Seconds(),
+		State:  state,
+	}
+	if limits.Enabled(n.limits) {
+		ps.Limits = limits.GetUsage(n.limits, cpuPercent, memoryInfo.RSS)
 	}
 	return ps, nil
 }
@@ -170,12 +400,15 @@ func (n *OrdererNode) Status() (*ProcessState, error) {
 func NewOrdererNode(
 	id string,
 	mspID string,
+	nodeLimits config.NodeLimits,
 	cmdGetter func() (*exec.Cmd, error),
 ) *OrdererNode {
 	return &OrdererNode{
 		id:        id,
 		mspID:     mspID,
+		limits:    nodeLimits,
 		cmdGetter: cmdGetter,
+		state:     StateStopped,
 	}
 }
 
@@ -613,23 +846,75 @@ Consensus:
 `
 )
 
+// ValidateOrdererInitOptions checks the options of an orderer enrolled by a
+// local CA or a Fabric CA before EnrollOrdererCertificates
+func ValidateOrdererInitOptions(ordererInitOptions config.OrdererInitOptions) error {
+	if ordererInitOptions.ID == "" {
+		return fmt.Errorf("--id is required")
+	}
+	if ordererInitOptions.Local {
+		if ordererInitOptions.CAName == "" {
+			return fmt.Errorf("--ca-name is required")
+		}
+	} else {
+		// validate that the options are not empty
+		if ordererInitOptions.CAUrl == "" {
+			return fmt.Errorf("--ca-url is required")
+		}
+		if !ordererInitOptions.CAInsecure && ordererInitOptions.CACert == "" {
+			return fmt.Errorf("--ca-cert is required")
+		}
+		if ordererInitOptions.EnrollID == "" {
+			return fmt.Errorf("--enroll-id is required")
+		}
+		if ordererInitOptions.EnrollSecret == "" {
+			return fmt.Errorf("--enroll-secret is required")
+		}
+	}
+	if err := limits.Validate(ordererInitOptions.Limits); err != nil {
+		return err
+	}
+	if err := hooks.Validate(ordererInitOptions.Hooks); err != nil {
+		return err
+	}
+	if err := ValidateTLSPolicy(ordererInitOptions.TLSPolicy); err != nil {
+		return err
+	}
+	if err := ValidateEndpoints(ordererInitOptions.Hosts, ordererInitOptions.ExternalEndpoint); err != nil {
+		return err
+	}
+	return certs.ValidateCertificatePolicy(ordererInitOptions.CertPolicy)
+}
+
+// EnrollOrdererCertificates enrolls the certificates of an orderer and
+// writes its configuration, it stops waiting for the lock of the orderer when
+// the context is done
 func EnrollOrdererCertificates(
+	ctx context.Context,
 	ordererInitOptions config.OrdererInitOptions,
-	//caConfig *utils.CAConfig,
-	//ordererId string,
-	//hosts []string,
-	//ordererDir string,
 ) error {
+	return withLockContext(ctx, "orderer", ordererInitOptions.ID, "orderer.init", func() error {
+		return enrollOrdererCertificates(plan.Disk, ordererInitOptions)
+	})
+}
+
+// PlanOrdererInit records in the plan the directories and files
+// EnrollOrdererCertificates would write
+func PlanOrdererInit(p *plan.Plan, ordererInitOptions config.OrdererInitOptions) error {
+	return enrollOrdererCertificates(p, ordererInitOptions)
+}
+
+func enrollOrdererCertificates(w plan.Writer, ordererInitOptions config.OrdererInitOptions) error {
 	ordererID := ordererInitOptions.ID
 	home, err := os.UserHomeDir()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	ordererDir := filepath.Join(home, fmt.Sprintf("hlf-easy/orderers/%s", ordererID))
-	err = os.MkdirAll(ordererDir, 0755)
+	err = w.MkdirAll(ordererDir, 0755)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	// check if output exists, if it does, return non error
 	if !ordererInitOptions.Local {
@@ -642,9 +927,13 @@ func EnrollOrdererCertificates(
 	if _, err := os.Stat(filepath.Join(ordererDir, "orderer.yaml")); err == nil {
 		return nil
 	}
+	err = resources.CheckReservation("orderer", ordererID, ordererInitOptions.Resources, ordererInitOptions.Limits)
+	if err != nil {
+		return err
+	}
 	var ips []net.IP
 	var dnsNames []string
-	for _, host := range ordererInitOptions.Hosts {
+	for _, host := range certificateHosts(ordererInitOptions.Hosts, ordererInitOptions.ExternalEndpoint) {
 		// check if it's ip address
 		ip := net.ParseIP(host)
 		if ip != nil {
@@ -653,14 +942,22 @@ func EnrollOrdererCertificates(
 			dnsNames = append(dnsNames, host)
 		}
 	}
+	certPolicy := caConfig.CertPolicy.Merge(ordererInitOptions.CertPolicy)
 	// create orderer tls cert
-	tlsCert, tlsKey, err := certs.GenerateCertificate(
-		certs.GenerateCertificateOptions{
-			CommonName:       "orderer",
-			OrganizationUnit: []string{"orderer"},
-			IPAddresses:      ips,
-			DNSNames:         dnsNames,
-		},
+	tlsCertOpts := certs.GenerateCertificateOptions{
+		CommonName:       "orderer",
+		OrganizationUnit: []string{"orderer"},
+		IPAddresses:      ips,
+		DNSNames:         dnsNames,
+		Curve:            tlsCurve(ordererInitOptions.TLSPolicy),
+	}
+	err = certs.ApplyCertificatePolicy(&tlsCertOpts, certPolicy, caConfig.Name, true)
+	if err != nil {
+		return err
+	}
+	tlsCert, tlsKey, err := issueCertificate(
+		w,
+		tlsCertOpts,
 		caConfig.TLSCACert,
 		caConfig.TLSCAKey,
 	)
@@ -669,13 +966,19 @@ func EnrollOrdererCertificates(
 	}
 
 	// create orderer cert
-	ordererCert, ordererKey, err := certs.GenerateCertificate(
-		certs.GenerateCertificateOptions{
-			CommonName:       "orderer",
-			OrganizationUnit: []string{"orderer"},
-			IPAddresses:      []net.IP{},
-			DNSNames:         []string{},
-		},
+	signCertOpts := certs.GenerateCertificateOptions{
+		CommonName:       "orderer",
+		OrganizationUnit: []string{"orderer"},
+		IPAddresses:      []net.IP{},
+		DNSNames:         []string{},
+	}
+	err = certs.ApplyCertificatePolicy(&signCertOpts, certPolicy, caConfig.Name, false)
+	if err != nil {
+		return err
+	}
+	ordererCert, ordererKey, err := issueCertificate(
+		w,
+		signCertOpts,
 		caConfig.CACert,
 		caConfig.CAKey,
 	)
@@ -705,55 +1008,61 @@ func EnrollOrdererCertificates(
 		return err
 	}
 	ordererConfigFilePath := filepath.Join(ordererDir, "config.json")
-	err = os.WriteFile(ordererConfigFilePath, ordererConfigBytes, 0644)
+	err = w.WriteFile(ordererConfigFilePath, ordererConfigBytes, 0644)
+	utils.InvalidateConfig(ordererConfigFilePath)
 	if err != nil {
 		return err
 	}
 
 	// keystore key pem
 	keyStoreDir := filepath.Join(ordererDir, "keystore")
-	err = os.MkdirAll(keyStoreDir, 0755)
+	err = w.MkdirAll(keyStoreDir, 0755)
 	if err != nil {
 		return err
 	}
 	signKeyFilePath := filepath.Join(keyStoreDir, "key.pem")
-	err = os.WriteFile(signKeyFilePath, signKeyBytes, 0644)
+	err = w.WriteFile(signKeyFilePath, signKeyBytes, 0644)
 	if err != nil {
 		return err
 	}
 
 	// tlscacerts pem
 	tlsCACertsDir := filepath.Join(ordererDir, "tlscacerts")
-	err = os.MkdirAll(tlsCACertsDir, 0755)
+	err = w.MkdirAll(tlsCACertsDir, 0755)
 	if err != nil {
 		return err
 	}
 	tlsCACertFilePath := filepath.Join(tlsCACertsDir, "cacert.pem")
-	err = os.WriteFile(tlsCACertFilePath, utils.EncodeX509Certificate(caConfig.TLSCACert), 0644)
+	tlsCACerts := append([]*x509.Certificate{caConfig.TLSCACert}, caConfig.PreviousTLSCACerts...)
+	err = w.WriteFile(tlsCACertFilePath, encodeX509Certificates(tlsCACerts), 0644)
 	if err != nil {
 		return err
 	}
 
 	// cacerts pem
 	cACertsDir := filepath.Join(ordererDir, "cacerts")
-	err = os.MkdirAll(cACertsDir, 0755)
+	err = w.MkdirAll(cACertsDir, 0755)
 	if err != nil {
 		return err
 	}
 	caCertFilePath := filepath.Join(cACertsDir, "cacert.pem")
-	err = os.WriteFile(caCertFilePath, utils.EncodeX509Certificate(caConfig.CACert), 0644)
+	err = w.WriteFile(caCertFilePath, utils.EncodeX509Certificate(caConfig.CACert), 0644)
+	if err != nil {
+		return err
+	}
+	err = writeExtraRoots(w, cACertsDir, caConfig.PreviousCACerts)
 	if err != nil {
 		return err
 	}
 
 	// signcerts pem
 	signCertsDir := filepath.Join(ordererDir, "signcerts")
-	err = os.MkdirAll(signCertsDir, 0755)
+	err = w.MkdirAll(signCertsDir, 0755)
 	if err != nil {
 		return err
 	}
 	signCertFilePath := filepath.Join(signCertsDir, "cert.pem")
-	err = os.WriteFile(signCertFilePath, utils.EncodeX509Certificate(ordererCert), 0644)
+	err = w.WriteFile(signCertFilePath, utils.EncodeX509Certificate(ordererCert), 0644)
 	if err != nil {
 		return err
 	}
@@ -775,20 +1084,20 @@ func EnrollOrdererCertificates(
     Certificate: cacerts/cacert.pem
     OrganizationalUnitIdentifier: orderer
 `
-	err = os.WriteFile(configFilePath, []byte(configYamlContent), 0644)
+	err = w.WriteFile(configFilePath, []byte(configYamlContent), 0644)
 	if err != nil {
 		return err
 	}
 	// write tls.key
 	tlsKeyFilePath := filepath.Join(ordererDir, "tls.key")
-	err = os.WriteFile(tlsKeyFilePath, tlsKeyBytes, 0644)
+	err = w.WriteFile(tlsKeyFilePath, tlsKeyBytes, 0644)
 	if err != nil {
 		return err
 	}
 
 	// write tls.crt
 	tlsCertFilePath := filepath.Join(ordererDir, "tls.crt")
-	err = os.WriteFile(tlsCertFilePath, utils.EncodeX509Certificate(tlsCert), 0644)
+	err = w.WriteFile(tlsCertFilePath, utils.EncodeX509Certificate(tlsCert), 0644)
 	if err != nil {
 		return err
 	}
@@ -798,13 +1107,8 @@ func EnrollOrdererCertificates(
 	if err != nil {
 		return err
 	}
-	coreYamlFilePath := filepath.Join(ordererDir, "orderer.yaml")
-	coreYamlFile, err := os.Create(coreYamlFilePath)
-	if err != nil {
-		return err
-	}
-	defer coreYamlFile.Close()
-	err = tmpl.Execute(coreYamlFile, struct {
+	var ordererYaml bytes.Buffer
+	err = tmpl.Execute(&ordererYaml, struct {
 		FileSystemPath string
 	}{
 		FileSystemPath: filepath.Join(ordererDir, "data"),
@@ -812,5 +1116,14 @@ func EnrollOrdererCertificates(
 	if err != nil {
 		return err
 	}
-	return nil
+	err = w.WriteFile(filepath.Join(ordererDir, "orderer.yaml"), ordererYaml.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	// save the init options, they hold the resources reserved for the orderer
+	ordererInitOptsBytes, err := json.MarshalIndent(ordererInitOptions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return w.WriteFile(filepath.Join(ordererDir, "init.json"), ordererInitOptsBytes, 0644)
 }
@@ -0,0 +1,81 @@
+package node
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"hlf-easy/ordering"
+	"hlf-easy/plan"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// UpdatePeerOrdererOverrides writes in the deliveryclient of a peer the
+// overrides of the unreachable orderers of a channel, they're replaced with
+// the closest healthy orderer. It returns true when the core.yaml changed,
+// the peer must be restarted to apply it
+func UpdatePeerOrdererOverrides(peerID string, ranked []ordering.Probe, tlsCACerts []string) (bool, error) {
+	changed := false
+	err := withLock("peer", peerID, "peer.orderers.override", func() error {
+		var err error
+		changed, err = updatePeerOrdererOverrides(peerID, ranked, tlsCACerts)
+		return err
+	})
+	return changed, err
+}
+
+func updatePeerOrdererOverrides(peerID string, ranked []ordering.Probe, tlsCACerts []string) (bool, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false, err
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers", peerID)
+	initBytes, err := os.ReadFile(filepath.Join(peerDir, "init.json"))
+	if err != nil {
+		return false, err
+	}
+	peerInitOpts := config.PeerInitOptions{}
+	err = json.Unmarshal(initBytes, &peerInitOpts)
+	if err != nil {
+		return false, err
+	}
+	caCertsFile := ""
+	if len(ranked) > 0 && ranked[0].Healthy {
+		// the orderers of different channels can have different TLS CAs
+		caCertsDir := filepath.Join(peerDir, "orderer-tlscacerts")
+		caCertsFile = filepath.Join(caCertsDir, strings.ReplaceAll(ranked[0].Address, ":", "_")+".pem")
+	}
+	overrides := ordering.MergeOverrides(peerInitOpts.OrdererOverrides, ranked, caCertsFile)
+	if reflect.DeepEqual(overrides, peerInitOpts.OrdererOverrides) || (len(overrides) == 0 && len(peerInitOpts.OrdererOverrides) == 0) {
+		return false, nil
+	}
+	for _, override := range overrides {
+		if override.CACertsFile != caCertsFile {
+			continue
+		}
+		err = os.MkdirAll(filepath.Dir(caCertsFile), 0755)
+		if err != nil {
+			return false, err
+		}
+		err = os.WriteFile(caCertsFile, []byte(strings.Join(tlsCACerts, "\n")), 0644)
+		if err != nil {
+			return false, err
+		}
+		break
+	}
+	peerInitOpts.OrdererOverrides = overrides
+	initBytes, err = json.Marshal(peerInitOpts)
+	if err != nil {
+		return false, err
+	}
+	err = os.WriteFile(filepath.Join(peerDir, "init.json"), initBytes, 0644)
+	if err != nil {
+		return false, err
+	}
+	bootstrap, err := GetPeerGossipBootstrap(peerInitOpts)
+	if err != nil {
+		return false, err
+	}
+	return true, renderPeerCoreYaml(plan.Disk, peerDir, peerInitOpts, bootstrap)
+}
@@ -0,0 +1,67 @@
+package node
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"hlf-easy/ordering"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdatePeerOrdererOverrides(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peerDir := writeTestPeer(t, home, config.PeerInitOptions{ID: "peer0", MSPID: "Org1MSP", ExternalEndpoint: "peer0.example.com:7051"})
+	ranked := []ordering.Probe{
+		{Address: "orderer1.example.com:7050", Healthy: true, RTT: time.Millisecond},
+		{Address: "orderer0.example.com:7050", Error: "connection refused"},
+	}
+	changed, err := UpdatePeerOrdererOverrides("peer0", ranked, []string{"CA1", "CA2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("expected the overrides to change")
+	}
+	caCertsFile := filepath.Join(peerDir, "orderer-tlscacerts/orderer1.example.com_7050.pem")
+	caBytes, err := os.ReadFile(caCertsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(caBytes) != "CA1\nCA2" {
+		t.Errorf("unexpected TLS root certificates %q", caBytes)
+	}
+	initBytes, err := os.ReadFile(filepath.Join(peerDir, "init.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerInitOpts := config.PeerInitOptions{}
+	err = json.Unmarshal(initBytes, &peerInitOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := config.OrdererOverride{From: "orderer0.example.com:7050", To: "orderer1.example.com:7050", CACertsFile: caCertsFile}
+	if len(peerInitOpts.OrdererOverrides) != 1 || peerInitOpts.OrdererOverrides[0] != expected {
+		t.Fatalf("expected %v, got %v", expected, peerInitOpts.OrdererOverrides)
+	}
+	coreYaml, err := os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"from: orderer0.example.com:7050", "to: orderer1.example.com:7050", "caCertsFile: " + caCertsFile} {
+		if !strings.Contains(string(coreYaml), s) {
+			t.Errorf("expected core.yaml to contain %q", s)
+		}
+	}
+
+	changed, err = UpdatePeerOrdererOverrides("peer0", ranked, []string{"CA1", "CA2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Fatal("expected the overrides to be unchanged")
+	}
+}
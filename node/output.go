@@ -0,0 +1,134 @@
+package node
+
+import (
+	"context"
+	"hlf-easy/log"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The process of a node writes its output to files of its run directory
+// instead of pipes of hlf-easy, so it keeps running when hlf-easy exits
+const (
+	StdoutFile = "stdout.log"
+	StderrFile = "stderr.log"
+	// MaxOutputSize is the size an output file is truncated at once it's
+	// followed
+	MaxOutputSize          = 64 * 1024 * 1024
+	DefaultFollowInterval  = 500 * time.Millisecond
+	followOutputBufferSize = 64 * 1024
+)
+
+// Output is the output of the process of a node, written to files and
+// followed by hlf-easy
+type Output struct {
+	paths [2]string
+	// mu guards the offsets the files are followed from
+	mu      sync.Mutex
+	offsets [2]int64
+}
+
+// NewOutput returns the output of the process of a node, it's followed from
+// the end of the files of a process that is still running
+func NewOutput(nodeDir string) *Output {
+	runDir := filepath.Join(nodeDir, "run")
+	o := &Output{paths: [2]string{filepath.Join(runDir, StdoutFile), filepath.Join(runDir, StderrFile)}}
+	for i, outputPath := range o.paths {
+		if info, err := os.Stat(outputPath); err == nil {
+			o.offsets[i] = info.Size()
+		}
+	}
+	return o
+}
+
+// Redirect writes the output of a process to the files, truncated so they're
+// followed from the start
+func (o *Output) Redirect(cmd *exec.Cmd) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	err := os.MkdirAll(filepath.Dir(o.paths[0]), 0755)
+	if err != nil {
+		return err
+	}
+	var files []*os.File
+	for _, outputPath := range o.paths {
+		// the process appends so it writes at the start once truncated
+		f, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0644)
+		if err != nil {
+			for _, f := range files {
+				_ = f.Close()
+			}
+			return err
+		}
+		files = append(files, f)
+	}
+	o.offsets = [2]int64{}
+	cmd.Stdout = files[0]
+	cmd.Stderr = files[1]
+	return nil
+}
+
+// closeOutput closes the output files of a started process, the process has
+// its own descriptors
+func closeOutput(cmd *exec.Cmd) {
+	for _, w := range []io.Writer{cmd.Stdout, cmd.Stderr} {
+		if f, ok := w.(*os.File); ok && f != os.Stdout && f != os.Stderr {
+			_ = f.Close()
+		}
+	}
+}
+
+// Follow copies what the process writes to its files to stdout and stderr
+// until the context is done
+func (o *Output) Follow(ctx context.Context, stdout io.Writer, stderr io.Writer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	buf := make([]byte, followOutputBufferSize)
+	for {
+		for i, w := range []io.Writer{stdout, stderr} {
+			o.follow(i, w, buf)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// follow copies what was written to a file since the last time, the file is
+// truncated once it's over MaxOutputSize, losing what the process writes
+// between the copy and the truncation
+func (o *Output) follow(i int, w io.Writer, buf []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	f, err := os.Open(o.paths[i])
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() < o.offsets[i] {
+		o.offsets[i] = 0
+	}
+	for {
+		n, err := f.ReadAt(buf, o.offsets[i])
+		if n > 0 {
+			_, _ = w.Write(buf[:n])
+			o.offsets[i] += int64(n)
+		}
+		if err != nil || n == 0 {
+			break
+		}
+	}
+	if o.offsets[i] >= MaxOutputSize {
+		if err := os.Truncate(o.paths[i], 0); err != nil {
+			log.Warnf("Failed to truncate %s: %v", o.paths[i], err)
+			return
+		}
+		o.offsets[i] = 0
+	}
+}
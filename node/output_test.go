@@ -0,0 +1,49 @@
+package node
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+)
+
+func TestOutput(t *testing.T) {
+	nodeDir := t.TempDir()
+	o := NewOutput(nodeDir)
+	var stdout, stderr bytes.Buffer
+	run := func(script string) {
+		t.Helper()
+		cmd := exec.Command("sh", "-c", script)
+		err := o.Redirect(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = cmd.Start()
+		closeOutput(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = cmd.Wait()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 4)
+		o.follow(0, &stdout, buf)
+		o.follow(1, &stderr, buf)
+	}
+	run("echo started; echo failed >&2")
+	if stdout.String() != "started\n" || stderr.String() != "failed\n" {
+		t.Fatalf("unexpected output %q %q", stdout.String(), stderr.String())
+	}
+	// the files of a restarted process are followed from the start
+	run("echo again")
+	if stdout.String() != "started\nagain\n" {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+	// the output of a process that is still running is followed from the end
+	stdout.Reset()
+	o = NewOutput(nodeDir)
+	o.follow(0, &stdout, make([]byte, 4))
+	if stdout.Len() != 0 {
+		t.Errorf("expected no output, got %q", stdout.String())
+	}
+}
@@ -1,20 +1,35 @@
 package node
 
 import (
+	"bytes"
+	"context"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"github.com/Masterminds/sprig/v3"
 	"github.com/pkg/errors"
 	"github.com/shirou/gopsutil/process"
-	log "github.com/sirupsen/logrus"
 	"hlf-easy/certs"
+	"hlf-easy/chaincode"
 	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"hlf-easy/hooks"
+	"hlf-easy/limits"
+	"hlf-easy/log"
+	"hlf-easy/notify"
+	"hlf-easy/plan"
+	"hlf-easy/proc"
+	"hlf-easy/resources"
 	"hlf-easy/utils"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"text/template"
+	"time"
 )
 
 type PeerNode struct {
@@ -23,6 +38,34 @@ type PeerNode struct {
 	cmd       *exec.Cmd
 	p         *process.Process
 	mspID     string
+	limits    config.NodeLimits
+	// releaseLimits removes the resources used to enforce the limits
+	releaseLimits func() error
+	// hooks run on the start, stop and crash of the process
+	hooks config.NodeHooks
+	// exited is closed when the process exits, the state tells an exit
+	// requested by Stop from a crash
+	exited chan struct{}
+	state  State
+	// mu guards the process and the state, lifecycle serializes Start, Stop
+	// and Restart so the state moves through Starting and Stopping
+	mu        sync.Mutex
+	lifecycle sync.Mutex
+	// statusMu serializes the queries of the process, it's not safe for
+	// concurrent use
+	statusMu sync.Mutex
+	// heightLag returns the lag of the channels of the peer behind the
+	// other peers of its org
+	heightLag func() *HeightLag
+	// diskUsage returns the size of the ledger of the peer and the usage of
+	// its filesystem
+	diskUsage func() *DiskUsage
+	// metrics returns the Fabric counters of the peer scraped from its
+	// operations endpoint
+	metrics func() *PeerMetrics
+	// readyCheck is passed by the peer within readyTimeout once started
+	readyCheck   *ReadyCheck
+	readyTimeout time.Duration
 }
 type PeerConfig struct {
 	TLSCert  string `json:"tlsCert"`
@@ -35,7 +78,7 @@ type PeerConfig struct {
 func (n *PeerNode) GetConfig() (*PeerConfig, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	peerDir := filepath.Join(home, fmt.Sprintf("hlf-easy/peers/%s", n.id))
 	tlsCertBytes, err := os.ReadFile(filepath.Join(peerDir, "tls.crt"))
@@ -71,47 +114,242 @@ func (n *PeerNode) GetMSPID() string {
 	return n.mspID
 }
 
+// GetKind returns the kind of the node in the registry
+func (n *PeerNode) GetKind() string {
+	return KindPeer
+}
+
+// Attach re-attaches to the peer process left running by an hlf-easy process
+// that exited, it returns false when there is none
+func (n *PeerNode) Attach() (bool, error) {
+	n.lifecycle.Lock()
+	defer n.lifecycle.Unlock()
+	p, err := findRecordedProcess(KindPeer, n.id)
+	if err != nil || p == nil {
+		return false, err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := Transition(&n.state, StateStarting); err != nil {
+		return false, errdefs.Errorf(errdefs.ErrNodeAlreadyRunning, "peer node is already started")
+	}
+	// the limits are applied again, an attached peer isn't killed when they
+	// can't be
+	release, err := limits.Apply(fmt.Sprintf("peer-%s", n.id), int(p.Pid), n.limits)
+	if err != nil {
+		log.Warnf("Failed to limit peer node: %v", err)
+	}
+	n.p = p
+	n.releaseLimits = release
+	n.exited = make(chan struct{})
+	_ = Transition(&n.state, StateRunning)
+	if err := saveProcessRecord(KindPeer, n.id, int(p.Pid)); err != nil {
+		log.Warnf("Failed to record peer node process: %v", err)
+	}
+	createTime, _ := p.CreateTime()
+	go n.wait(func() error { return WaitProcess(int(p.Pid), createTime) }, n.exited)
+	log.Infof("Attached to peer node process %d", p.Pid)
+	return true, nil
+}
+
+// State returns the lifecycle state of the peer process
+func (n *PeerNode) State() State {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.state
+}
+
+// Start starts the peer process and watches it to notify when it crashes,
+// it returns once the peer is ready when it has a ready check
 func (n *PeerNode) Start() error {
-	if n.cmd != nil {
-		log.Info("Peer node is already started")
-		return errors.New("peer node is already started")
+	n.lifecycle.Lock()
+	defer n.lifecycle.Unlock()
+	err := n.start()
+	if err != nil {
+		return err
 	}
-	cmd, err := n.cmdGetter()
+	err = n.waitReady()
 	if err != nil {
-		log.Warnf("Failed to get peer node command: %v", err)
 		return err
 	}
-	n.cmd = cmd
-	if err := n.cmd.Start(); err != nil {
-		log.Warnf("Failed to start peer node: %v", err)
+	go notify.Notify(notify.NewEvent(notify.EventNodeStarted, "peer", n.id, fmt.Sprintf("Peer %s started", n.id)))
+	return nil
+}
+
+// Restart stops and starts the peer process
+func (n *PeerNode) Restart() error {
+	n.lifecycle.Lock()
+	defer n.lifecycle.Unlock()
+	err := n.stop()
+	if err != nil {
+		return err
+	}
+	err = n.start()
+	if err != nil {
+		return err
+	}
+	err = n.waitReady()
+	if err != nil {
 		return err
 	}
+	go notify.Notify(notify.NewEvent(notify.EventNodeRestarted, "peer", n.id, fmt.Sprintf("Peer %s restarted", n.id)))
+	return nil
+}
 
-	p, err := process.NewProcess(int32(n.cmd.Process.Pid))
+func (n *PeerNode) start() error {
+	n.mu.Lock()
+	if err := Transition(&n.state, StateStarting); err != nil {
+		n.mu.Unlock()
+		log.Info("Peer node is already started")
+		return errdefs.Errorf(errdefs.ErrNodeAlreadyRunning, "peer node is already started")
+	}
+	n.mu.Unlock()
+	cmd, p, release, err := n.startProcess()
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	if err != nil {
-		log.Warnf("Failed to get peer node process: %v", err)
+		_ = Transition(&n.state, StateFailed)
 		return err
 	}
+	n.cmd = cmd
 	n.p = p
+	n.releaseLimits = release
+	n.exited = make(chan struct{})
+	_ = Transition(&n.state, StateRunning)
+	if err := saveProcessRecord(KindPeer, n.id, cmd.Process.Pid); err != nil {
+		log.Warnf("Failed to record peer node process: %v", err)
+	}
+	// the exit is handled once the peer is running
+	go n.wait(cmd.Wait, n.exited)
+	go hooks.RunLogged(n.hooks, hooks.PostStart, KindPeer, n.id, map[string]string{"pid": strconv.Itoa(cmd.Process.Pid)})
 	return nil
 }
+
+// startProcess starts the peer process within its limits, a process that
+// can't be limited or watched is killed
+func (n *PeerNode) startProcess() (*exec.Cmd, *process.Process, func() error, error) {
+	// a failed pre-start hook, e.g. a volume that can't be mounted, keeps the
+	// peer from starting
+	if err := hooks.Run(n.hooks, hooks.PreStart, KindPeer, n.id, nil); err != nil {
+		log.Warnf("%v", err)
+		return nil, nil, nil, err
+	}
+	cmd, err := n.cmdGetter()
+	if err != nil {
+		log.Warnf("Failed to get peer node command: %v", err)
+		return nil, nil, nil, err
+	}
+	err = cmd.Start()
+	closeOutput(cmd)
+	if err != nil {
+		log.Warnf("Failed to start peer node: %v", err)
+		return nil, nil, nil, err
+	}
+
+	// a peer that can't be limited is killed so it can't starve the host
+	release, err := limits.Apply(fmt.Sprintf("peer-%s", n.id), cmd.Process.Pid, n.limits)
+	if err != nil {
+		log.Warnf("Failed to limit peer node: %v", err)
+		_ = cmd.Process.Kill()
+		_, _ = cmd.Process.Wait()
+		return nil, nil, nil, errors.Wrap(err, "failed to limit peer node")
+	}
+
+	p, err := process.NewProcess(int32(cmd.Process.Pid))
+	if err != nil {
+		log.Warnf("Failed to get peer node process: %v", err)
+		_ = cmd.Process.Kill()
+		_, _ = cmd.Process.Wait()
+		if release != nil {
+			_ = release()
+		}
+		return nil, nil, nil, err
+	}
+	return cmd, p, release, nil
+}
+
+// wait waits for the peer process to exit, an exit that wasn't requested by
+// Stop is notified as a crash
+func (n *PeerNode) wait(done func() error, exited chan struct{}) {
+	err := done()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	close(exited)
+	if n.state == StateStopping {
+		return
+	}
+	log.Warnf("Peer node exited unexpectedly: %v", err)
+	n.cmd = nil
+	n.p = nil
+	n.release()
+	if err := removeProcessRecord(KindPeer, n.id); err != nil {
+		log.Warnf("Failed to remove peer node process record: %v", err)
+	}
+	_ = Transition(&n.state, StateFailed)
+	event := notify.NewEvent(notify.EventNodeCrashed, "peer", n.id, fmt.Sprintf("Peer %s crashed", n.id))
+	if err != nil {
+		event.Details = map[string]string{"exit": err.Error()}
+	}
+	go notify.Notify(event)
+	go hooks.RunLogged(n.hooks, hooks.PostCrash, KindPeer, n.id, event.Details)
+}
+
+// release removes the resources used to enforce the limits
+func (n *PeerNode) release() {
+	if n.releaseLimits == nil {
+		return
+	}
+	err := n.releaseLimits()
+	if err != nil {
+		log.Warnf("Failed to release peer node limits: %v", err)
+	}
+	n.releaseLimits = nil
+}
+
+// Stop stops the peer process and waits for it to exit
 func (n *PeerNode) Stop() error {
-	if n.cmd == nil || n.cmd.Process == nil {
+	n.lifecycle.Lock()
+	defer n.lifecycle.Unlock()
+	return n.stop()
+}
+
+func (n *PeerNode) stop() error {
+	n.mu.Lock()
+	if err := Transition(&n.state, StateStopping); err != nil {
+		n.mu.Unlock()
 		log.Info("Peer node is already stopped")
-		return errors.New("peer node is already stopped")
+		return errdefs.Errorf(errdefs.ErrNodeNotRunning, "peer node is already stopped")
 	}
-	err := n.cmd.Process.Signal(os.Interrupt)
-	if err != nil {
-		log.Warnf("Failed to stop peer node: %v", err)
-		return err
+	cmd, p := n.cmd, n.p
+	exited := n.exited
+	n.mu.Unlock()
+	// a failed pre-stop hook doesn't keep the peer running
+	hooks.RunLogged(n.hooks, hooks.PreStop, KindPeer, n.id, nil)
+	var err error
+	if cmd != nil {
+		err = proc.Interrupt(cmd.Process)
+	} else {
+		// an attached process isn't a child of hlf-easy, it's terminated
+		// by its PID
+		err = proc.Terminate(int(p.Pid))
 	}
-	state, err := n.cmd.Process.Wait()
 	if err != nil {
 		log.Warnf("Failed to stop peer node: %v", err)
+		n.mu.Lock()
+		_ = Transition(&n.state, StateRunning)
+		n.mu.Unlock()
 		return err
 	}
-	_ = state
+	<-exited
+	n.mu.Lock()
+	defer n.mu.Unlock()
 	n.cmd = nil
+	n.p = nil
+	n.release()
+	if err := removeProcessRecord(KindPeer, n.id); err != nil {
+		log.Warnf("Failed to remove peer node process record: %v", err)
+	}
+	_ = Transition(&n.state, StateStopped)
 	return nil
 }
 
@@ -130,16 +368,37 @@ type ProcessState struct {
 	Status     string                  `json:"status"`
 	MemoryInfo *process.MemoryInfoStat `json:"memory"`
 	CPUInfo    CPUInfo                 `json:"cpu"`
+	// Uptime of the process in seconds
+	Uptime float64 `json:"uptime"`
+	// Limits are the limits of the process and its usage over them
+	Limits *limits.Usage `json:"limits,omitempty"`
+	// HeightLag is how far the channels of a peer are behind the other
+	// peers of its org on the host
+	HeightLag *HeightLag `json:"heightLag,omitempty"`
+	// DiskUsage is the size of the ledger of a peer and the usage of the
+	// filesystem hosting it
+	DiskUsage *DiskUsage `json:"diskUsage,omitempty"`
+	// Metrics are the Fabric counters of a peer, its block heights, committed
+	// transactions and endorsements, with its commit rates
+	Metrics *PeerMetrics `json:"metrics,omitempty"`
+	// State is the lifecycle state of the node
+	State State `json:"state"`
 }
 type CPUInfo struct {
 	CPUPercent float64 `json:"percent"`
 }
 
 func (n *PeerNode) Status() (*ProcessState, error) {
-	if n.cmd == nil || n.cmd.Process == nil {
+	n.statusMu.Lock()
+	defer n.statusMu.Unlock()
+	n.mu.Lock()
+	p, state := n.p, n.state
+	n.mu.Unlock()
+	if p == nil {
 		return &ProcessState{
 			PID:    0,
 			Status: "Stop",
+			State:  state,
 			MemoryInfo: &process.MemoryInfoStat{
 				RSS:    0,
 				VMS:    0,
@@ -155,7 +414,7 @@ func (n *PeerNode) Status() (*ProcessState, error) {
 		}, nil
 	}
 
-	status, err := n.p.Status()
+	status, err := p.Status()
 	if err != nil {
 		log.Warnf("Failed to get peer node status: %v", err)
 		return nil, err
@@ -164,23 +423,42 @@ func (n *PeerNode) Status() (*ProcessState, error) {
 	if !ok {
 		statusStr = "Unknown"
 	}
-	memoryInfo, err := n.p.MemoryInfo()
+	memoryInfo, err := p.MemoryInfo()
 	if err != nil {
 		log.Warnf("Failed to get peer node memory info: %v", err)
 		return nil, err
 	}
-	cpuPercent, err := n.p.CPUPercent()
+	cpuPercent, err := p.CPUPercent()
 	if err != nil {
 		log.Warnf("Failed to get peer node cpu percent: %v", err)
 		return nil, err
 	}
+	createTime, err := p.CreateTime()
+	if err != nil {
+		log.Warnf("Failed to get peer node create time: %v", err)
+		return nil, err
+	}
 	ps := &ProcessState{
-		PID:        int(n.p.Pid),
+		PID:        int(p.Pid),
 		Status:     statusStr,
 		MemoryInfo: memoryInfo,
 		CPUInfo: CPUInfo{
 			CPUPercent: cpuPercent,
 		},
+		Uptime: time.Since(time.UnixMilli(createTime)).Seconds(),
+		State:  state,
+	}
+	if limits.Enabled(n.limits) {
+		ps.Limits = limits.GetUsage(n.limits, cpuPercent, memoryInfo.RSS)
+	}
+	if n.heightLag != nil {
+		ps.HeightLag = n.heightLag()
+	}
+	if n.diskUsage != nil {
+		ps.DiskUsage = n.diskUsage()
+	}
+	if n.metrics != nil {
+		ps.Metrics = n.metrics()
 	}
 	return ps, nil
 }
@@ -188,12 +466,15 @@ func (n *PeerNode) Status() (*ProcessState, error) {
 func NewPeerNode(
 	id string,
 	mspID string,
+	nodeLimits config.NodeLimits,
 	cmdGetter func() (*exec.Cmd, error),
 ) *PeerNode {
 	return &PeerNode{
 		id:        id,
 		mspID:     mspID,
+		limits:    nodeLimits,
 		cmdGetter: cmdGetter,
+		state:     StateStopped,
 	}
 }
 
@@ -287,7 +568,7 @@ peer:
     # Important: The endpoints here have to be endpoints of peers in the same
     # organization, because the peer would refuse connecting to these endpoints
     # unless they are in the same organization as the peer.
-    bootstrap: 127.0.0.1:7051
+    bootstrap: {{ .GossipBootstrap }}
 
     # NOTE: orgLeader and useLeaderElection parameters are mutual exclusive.
     # Setting both to true would result in the termination of the peer
@@ -368,7 +649,7 @@ peer:
     msgExpirationFactor: 20
     # This is an endpoint that is published to peers outside of the organization.
     # If this isn't set, the peer will not be known to other organizations.
-    externalEndpoint:
+    externalEndpoint: {{ .ExternalEndpoint }}
     # Leader election service configuration
     election:
       # Longest time peer waits for stable membership during leader election startup (unit: second)
@@ -432,23 +713,23 @@ peer:
       # default value is true, i.e. state transfer is active
       # and takes care to sync up missing blocks allowing
       # lagging peer to catch up to speed with rest network
-      enabled: false
+      enabled: {{ .GossipState.Enabled }}
       # checkInterval interval to check whether peer is lagging behind enough to
       # request blocks via state transfer from another peer.
-      checkInterval: 10s
+      checkInterval: {{ .GossipState.CheckInterval }}
       # responseTimeout amount of time to wait for state transfer response from
       # other peers
-      responseTimeout: 3s
+      responseTimeout: {{ .GossipState.ResponseTimeout }}
       # batchSize the number of blocks to request via state transfer from another peer
-      batchSize: 10
+      batchSize: {{ .GossipState.BatchSize }}
       # blockBufferSize reflects the size of the re-ordering buffer
       # which captures blocks and takes care to deliver them in order
       # down to the ledger layer. The actual buffer size is bounded between
       # 0 and 2*blockBufferSize, each channel maintains its own buffer
-      blockBufferSize: 20
+      blockBufferSize: {{ .GossipState.BlockBufferSize }}
       # maxRetries maximum number of re-tries to ask
       # for single state transfer request
-      maxRetries: 3
+      maxRetries: {{ .GossipState.MaxRetries }}
 
   # TLS Settings
   tls:
@@ -551,12 +832,11 @@ peer:
     # A list of orderer endpoint addresses which should be overridden
     # when found in channel configurations.
     addressOverrides:
-    #  - from:
-    #    to:
-    #    caCertsFile:
-    #  - from:
-    #    to:
-    #    caCertsFile:
+{{- range $override := .OrdererOverrides }}
+      - from: {{ $override.From }}
+        to: {{ $override.To }}
+        caCertsFile: {{ $override.CACertsFile }}
+{{- end }}
 
   # Type for the local MSP - by default it's of type bccsp
   localMspType: bccsp
@@ -641,6 +921,20 @@ peer:
     # When this is false, it means that only peer admins can perform non channel scoped queries.
     orgMembersAllowedAccess: false
 
+  # Settings for the Fabric Gateway
+  gateway:
+    # Whether the gateway is enabled for this Peer.
+    enabled: {{ not .Gateway.Disabled }}
+    # endorsementTimeout is the duration the gateway waits for a response
+    # from other endorsing peers before returning a timeout error to the client.
+    endorsementTimeout: {{ .Gateway.EndorsementTimeout }}
+    # broadcastTimeout is the duration the gateway waits for a response
+    # from ordering nodes before returning a timeout error to the client.
+    broadcastTimeout: {{ .Gateway.BroadcastTimeout }}
+    # dialTimeout is the duration the gateway waits for a connection
+    # to other network nodes.
+    dialTimeout: {{ .Gateway.DialTimeout }}
+
   # Limits is used to configure some internal resource limits.
   limits:
     # Concurrency limits the number of concurrently running requests to a service on each peer.
@@ -664,12 +958,12 @@ vm:
   # unix:///var/run/docker.sock
   # http://localhost:2375
   # https://localhost:2376
-  endpoint: ""
+  endpoint: "{{ .VM.Endpoint }}"
 
   # settings for docker vms
   docker:
     tls:
-      enabled: false
+      enabled: {{ .VM.TLS }}
       ca:
         file: docker/ca.crt
       cert:
@@ -679,7 +973,7 @@ vm:
 
     # Enables/disables the standard out/err from chaincode containers for
     # debugging purposes
-    attachStdout: false
+    attachStdout: {{ .VM.AttachStdout }}
 
     # Parameters on creating docker container.
     # Container may be efficiently created using ipam & dns-server for cluster
@@ -741,6 +1035,18 @@ chaincode:
   # chaincode. The external builder detection processing will iterate over the
   # builders in the order specified below.
   externalBuilders:
+    - name: {{ .BuilderName }}
+      path: {{ .BuilderPath }}
+{{- range .ExternalBuilders }}
+    - name: {{ .Name }}
+      path: {{ .Path }}
+{{- if .PropagateEnvironment }}
+      propagateEnvironment:
+{{- range .PropagateEnvironment }}
+      - {{ . }}
+{{- end }}
+{{- end }}
+{{- end }}
     - name: ccaas_builder
       path: /opt/hyperledger/ccaas_builder
       propagateEnvironment:
@@ -767,7 +1073,7 @@ chaincode:
   # In dev mode, user runs the chaincode after starting peer from
   # command line on local machine.
   # In net mode, peer will run chaincode in a docker container.
-  mode: net
+  mode: {{ if .DevMode }}dev{{ else }}net{{ end }}
 
   # keepalive in seconds. In situations where the communication goes through a
   # proxy that does not support keep-alive, this parameter will maintain connection
@@ -880,29 +1186,34 @@ ledger:
 ###############################################################################
 operations:
   # host and port for the operations server
-  listenAddress: 127.0.0.1:9443
+  listenAddress: {{ .Operations.ListenAddress }}
 
   # TLS configuration for the operations endpoint
   tls:
     # TLS enabled
-    enabled: false
+    enabled: {{ .Operations.TLS }}
 
     # path to PEM encoded server certificate for the operations server
     cert:
-      file:
+      file:{{ if .Operations.TLS }} {{ .OperationsCertFile }}{{ end }}
 
     # path to PEM encoded server key for the operations server
     key:
-      file:
+      file:{{ if .Operations.TLS }} {{ .OperationsKeyFile }}{{ end }}
 
     # most operations service endpoints require client authentication when TLS
     # is enabled. clientAuthRequired requires client certificate authentication
     # at the TLS layer to access all resources.
-    clientAuthRequired: false
+    clientAuthRequired: {{ .Operations.ClientAuthRequired }}
 
     # paths to PEM encoded ca certificates to trust for client authentication
     clientRootCAs:
+{{- if .Operations.ClientAuthRequired }}
+      files:
+      - {{ .OperationsClientRootCA }}
+{{- else }}
       files: []
+{{- end }}
 
 ###############################################################################
 #
@@ -911,7 +1222,7 @@ operations:
 ###############################################################################
 metrics:
   # metrics provider is one of statsd, prometheus, or disabled
-  provider: disabled
+  provider: {{ .Operations.MetricsProvider }}
 
   # statsd configuration
   statsd:
@@ -919,7 +1230,7 @@ metrics:
     network: udp
 
     # statsd server address
-    address: 127.0.0.1:8125
+    address: {{ if .Operations.StatsdAddress }}{{ .Operations.StatsdAddress }}{{ else }}127.0.0.1:8125{{ end }}
 
     # the interval at which locally cached counters and gauges are pushed
     # to statsd; timings are pushed immediately
@@ -930,31 +1241,201 @@ metrics:
 `
 )
 
+// ValidatePeerSettings checks the settings of a peer applied when it's
+// started: its limits, environment, hooks, gossip state, gateway, docker
+// daemon and operations endpoint
+func ValidatePeerSettings(peerInitOpts config.PeerInitOptions) error {
+	if err := limits.Validate(peerInitOpts.Limits); err != nil {
+		return err
+	}
+	if err := ValidateEnv(peerInitOpts.Env); err != nil {
+		return err
+	}
+	if err := hooks.Validate(peerInitOpts.Hooks); err != nil {
+		return err
+	}
+	if err := ValidateGossipState(peerInitOpts.GossipState); err != nil {
+		return err
+	}
+	if err := ValidateGateway(peerInitOpts.Gateway); err != nil {
+		return err
+	}
+	if err := ValidateVM(peerInitOpts.VM); err != nil {
+		return err
+	}
+	if err := ValidateTLSPolicy(peerInitOpts.TLSPolicy); err != nil {
+		return err
+	}
+	return ValidateOperations(peerInitOpts.Operations)
+}
+
+// ValidatePeerInitOptions checks the options of a peer enrolled by a local CA
+// or a Fabric CA before EnrollPeerCertificates
+func ValidatePeerInitOptions(peerInitOpts config.PeerInitOptions) error {
+	if err := ValidatePeerSettings(peerInitOpts); err != nil {
+		return err
+	}
+	if peerInitOpts.ID == "" {
+		return fmt.Errorf("--id is required")
+	}
+	if err := ValidateEndpoints(peerInitOpts.Hosts, peerInitOpts.ExternalEndpoint); err != nil {
+		return err
+	}
+	if peerInitOpts.Local {
+		if peerInitOpts.CAName == "" {
+			return fmt.Errorf("--ca-name is required")
+		}
+	} else {
+		// validate that the options are not empty
+		if peerInitOpts.CAUrl == "" {
+			return fmt.Errorf("--ca-url is required")
+		}
+		if !peerInitOpts.CAInsecure && peerInitOpts.CACert == "" {
+			return fmt.Errorf("--ca-cert is required")
+		}
+		if peerInitOpts.EnrollID == "" {
+			return fmt.Errorf("--enroll-id is required")
+		}
+		if peerInitOpts.EnrollSecret == "" {
+			return fmt.Errorf("--enroll-secret is required")
+		}
+	}
+	return certs.ValidateCertificatePolicy(peerInitOpts.CertPolicy)
+}
+
+// EnrollPeerCertificates enrolls the certificates of a peer and writes its
+// configuration, it stops waiting for the lock of the peer or for the Fabric
+// CA when the context is done
 func EnrollPeerCertificates(
+	ctx context.Context,
 	peerInitOpts config.PeerInitOptions,
 ) error {
+	return withLockContext(ctx, "peer", peerInitOpts.ID, "peer.init", func() error {
+		return enrollPeerCertificates(ctx, plan.Disk, peerInitOpts)
+	})
+}
+
+// InitMissingPeer enrolls a peer unless it's already initialized, the check
+// and the enrollment hold the lock of the peer so a concurrent init isn't
+// enrolled again. It returns true when the peer was initialized
+func InitMissingPeer(peerInitOpts config.PeerInitOptions) (bool, error) {
+	initialized := false
+	err := withLock("peer", peerInitOpts.ID, "peer.init", func() error {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stat(filepath.Join(home, "hlf-easy/peers", peerInitOpts.ID, "init.json"))
+		if err == nil {
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		initialized = true
+		return enrollPeerCertificates(context.Background(), plan.Disk, peerInitOpts)
+	})
+	return initialized, err
+}
+
+// PlanPeerInit records in the plan the directories and files
+// EnrollPeerCertificates would write, without enrolling with a Fabric CA
+func PlanPeerInit(p *plan.Plan, peerInitOpts config.PeerInitOptions) error {
+	return enrollPeerCertificates(context.Background(), p, peerInitOpts)
+}
+
+// enrollPeerCertificates writes the material of the peer, the enrollment
+// with a Fabric CA is abandoned when the context is done
+func enrollPeerCertificates(ctx context.Context, w plan.Writer, peerInitOpts config.PeerInitOptions) error {
 	peerID := peerInitOpts.ID
 	home, err := os.UserHomeDir()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	peerDir := filepath.Join(home, fmt.Sprintf("hlf-easy/peers/%s", peerID))
-	err = os.MkdirAll(peerDir, 0755)
+	err = w.MkdirAll(peerDir, 0755)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	// peers enrolled by an external CA must be renewed through new CSRs
+	existingInitOptsBytes, err := os.ReadFile(filepath.Join(peerDir, "init.json"))
+	if err == nil {
+		existingInitOpts := config.PeerInitOptions{}
+		err = json.Unmarshal(existingInitOptsBytes, &existingInitOpts)
+		if err != nil {
+			return err
+		}
+		if existingInitOpts.ExternalCA {
+			return errors.Errorf("peer %s is enrolled by an external CA, use peer csr generate --force to re-enroll it", peerID)
+		}
+		// the state of the peer isn't reset by a re-enrollment
+		peerInitOpts.OrdererOverrides = existingInitOpts.OrdererOverrides
+		peerInitOpts.FabricVersion = existingInitOpts.FabricVersion
+	}
+	err = resources.CheckReservation("peer", peerID, peerInitOpts.Resources, peerInitOpts.Limits)
+	if err != nil {
+		return err
 	}
 	if !peerInitOpts.Local {
-		return errors.Errorf("not local provisioning is not implemented")
+		return enrollPeerWithFabricCA(ctx, w, peerDir, peerInitOpts)
 	}
 	// init the certs
 	caConfig, err := utils.GetCAConfig(peerInitOpts.CAName)
 	if err != nil {
 		return err
 	}
+	m := peerMaterial{
+		CACert:             caConfig.CACert,
+		TLSCACert:          caConfig.TLSCACert,
+		PreviousCACerts:    caConfig.PreviousCACerts,
+		PreviousTLSCACerts: caConfig.PreviousTLSCACerts,
+	}
+	err = issueLocalPeerTLS(w, caConfig, peerInitOpts, &m)
+	if err != nil {
+		return err
+	}
+	err = issueLocalPeerSign(w, caConfig, peerInitOpts, &m)
+	if err != nil {
+		return err
+	}
+	return writePeerMaterial(w, peerDir, peerInitOpts, m)
+}
+
+// issueLocalPeerSign issues by the local CA the sign certificate of a peer
+func issueLocalPeerSign(w plan.Writer, caConfig *utils.CAConfig, peerInitOpts config.PeerInitOptions, m *peerMaterial) error {
+	certPolicy := caConfig.CertPolicy.Merge(peerInitOpts.CertPolicy)
+	// create peer cert
+	signCertOpts := certs.GenerateCertificateOptions{
+		CommonName:       "peer",
+		OrganizationUnit: []string{"peer"},
+		IPAddresses:      []net.IP{},
+		DNSNames:         []string{},
+	}
+	err := certs.ApplyCertificatePolicy(&signCertOpts, certPolicy, caConfig.Name, false)
+	if err != nil {
+		return err
+	}
+	peerCert, peerKey, err := issueCertificate(
+		w,
+		signCertOpts,
+		caConfig.CACert,
+		caConfig.CAKey,
+	)
+	if err != nil {
+		return err
+	}
+	m.SignCert = peerCert
+	m.SignKey, err = utils.EncodePrivateKey(peerKey)
+	return err
+}
+
+// issueLocalPeerTLS issues by the local CA the TLS certificate of a peer for
+// its hosts, and the one of its operations endpoint when it's served over TLS
+func issueLocalPeerTLS(w plan.Writer, caConfig *utils.CAConfig, peerInitOpts config.PeerInitOptions, m *peerMaterial) error {
 	var ips []net.IP
 	var dnsNames []string
-	for _, host := range peerInitOpts.Hosts {
+	for _, host := range certificateHosts(peerInitOpts.Hosts, peerInitOpts.ExternalEndpoint) {
 		// check if it's ip address
 		ip := net.ParseIP(host)
 		if ip != nil {
@@ -963,173 +1444,434 @@ func EnrollPeerCertificates(
 			dnsNames = append(dnsNames, host)
 		}
 	}
+	certPolicy := caConfig.CertPolicy.Merge(peerInitOpts.CertPolicy)
 	// create peer tls cert
-	tlsCert, tlsKey, err := certs.GenerateCertificate(
-		certs.GenerateCertificateOptions{
-			CommonName:       "peer",
-			OrganizationUnit: []string{"peer"},
-			IPAddresses:      ips,
-			DNSNames:         dnsNames,
-		},
+	tlsCertOpts := certs.GenerateCertificateOptions{
+		CommonName:       "peer",
+		OrganizationUnit: []string{"peer"},
+		IPAddresses:      ips,
+		DNSNames:         dnsNames,
+		Curve:            tlsCurve(peerInitOpts.TLSPolicy),
+	}
+	err := certs.ApplyCertificatePolicy(&tlsCertOpts, certPolicy, caConfig.Name, true)
+	if err != nil {
+		return err
+	}
+	tlsCert, tlsKey, err := issueCertificate(
+		w,
+		tlsCertOpts,
 		caConfig.TLSCACert,
 		caConfig.TLSCAKey,
 	)
 	if err != nil {
 		return err
 	}
-
-	// create peer cert
-	peerCert, peerKey, err := certs.GenerateCertificate(
-		certs.GenerateCertificateOptions{
-			CommonName:       "peer",
-			OrganizationUnit: []string{"peer"},
-			IPAddresses:      []net.IP{},
-			DNSNames:         []string{},
-		},
-		caConfig.CACert,
-		caConfig.CAKey,
+	m.TLSCert = tlsCert
+	m.TLSKey, err = utils.EncodePrivateKey(tlsKey)
+	if err != nil {
+		return err
+	}
+	if !peerInitOpts.Operations.TLS {
+		return nil
+	}
+	// the operations endpoint is reached locally too, e.g. by Prometheus on
+	// the host
+	operationsCertOpts := certs.GenerateCertificateOptions{
+		CommonName:       "peer-operations",
+		OrganizationUnit: []string{"peer"},
+		IPAddresses:      append(append([]net.IP{}, ips...), net.ParseIP("127.0.0.1")),
+		DNSNames:         append(append([]string{}, dnsNames...), "localhost"),
+		Curve:            tlsCurve(peerInitOpts.TLSPolicy),
+	}
+	err = certs.ApplyCertificatePolicy(&operationsCertOpts, certPolicy, caConfig.Name, true)
+	if err != nil {
+		return err
+	}
+	operationsCert, operationsKey, err := issueCertificate(
+		w,
+		operationsCertOpts,
+		caConfig.TLSCACert,
+		caConfig.TLSCAKey,
 	)
 	if err != nil {
 		return err
 	}
-	tlsKeyBytes, err := utils.EncodePrivateKey(tlsKey)
+	m.OperationsTLSCert = operationsCert
+	m.OperationsTLSKey, err = utils.EncodePrivateKey(operationsKey)
+	return err
+}
+
+// enrollPeerWithFabricCA enrolls the TLS and sign certificates of the peer
+// with a Fabric CA, only the SANs of the certificate policy apply
+func enrollPeerWithFabricCA(ctx context.Context, w plan.Writer, peerDir string, peerInitOpts config.PeerInitOptions) error {
+	tlsHosts, err := peerTLSHosts(peerInitOpts)
+	if err != nil {
+		return err
+	}
+	if p, ok := w.(*plan.Plan); ok {
+		return planEnrolledPeerMaterial(p, peerDir, peerInitOpts, tlsHosts)
+	}
+	caTLSCert, err := os.ReadFile(peerInitOpts.CACert)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read CA TLS certificate %s", peerInitOpts.CACert)
+	}
+	tlsCert, tlsKey, tlsCACert, err := enrollPeerTLS(ctx, peerInitOpts, caTLSCert, tlsHosts)
 	if err != nil {
 		return err
 	}
-	signKeyBytes, err := utils.EncodePrivateKey(peerKey)
+	signCert, signKey, caCert, err := certs.EnrollUserContext(ctx, certs.EnrollUserRequest{
+		TLSCert: string(caTLSCert),
+		URL:     peerInitOpts.CAUrl,
+		Name:    peerInitOpts.CAName,
+		MSPID:   peerInitOpts.MSPID,
+		User:    peerInitOpts.EnrollID,
+		Secret:  peerInitOpts.EnrollSecret,
+		CN:      peerInitOpts.EnrollID,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to enroll the sign certificate")
+	}
+	signKeyBytes, err := utils.EncodePrivateKey(signKey)
 	if err != nil {
 		return err
 	}
+	return writePeerMaterial(w, peerDir, peerInitOpts, peerMaterial{
+		TLSCert:   tlsCert,
+		TLSKey:    tlsKey,
+		SignCert:  signCert,
+		SignKey:   signKeyBytes,
+		CACert:    caCert,
+		TLSCACert: tlsCACert,
+	})
+}
 
+// peerTLSHosts returns the hosts of the TLS certificate of a peer enrolled by
+// a Fabric CA, its hosts and the SANs of its certificate policy
+func peerTLSHosts(peerInitOpts config.PeerInitOptions) ([]string, error) {
+	tlsCertOpts := certs.GenerateCertificateOptions{}
+	err := certs.ApplyCertificatePolicySANs(&tlsCertOpts, peerInitOpts.CertPolicy)
+	if err != nil {
+		return nil, err
+	}
+	hosts := certificateHosts(peerInitOpts.Hosts, peerInitOpts.ExternalEndpoint)
+	hosts = append(hosts, tlsCertOpts.DNSNames...)
+	for _, ip := range tlsCertOpts.IPAddresses {
+		hosts = append(hosts, ip.String())
+	}
+	return hosts, nil
+}
+
+// enrollPeerTLS enrolls the TLS certificate of a peer with the TLS CA of its
+// Fabric CA, it returns the certificate, its encoded key and the TLS CA
+func enrollPeerTLS(ctx context.Context, peerInitOpts config.PeerInitOptions, caTLSCert []byte, tlsHosts []string) (*x509.Certificate, []byte, *x509.Certificate, error) {
+	tlsCAName := peerInitOpts.TLSCAName
+	if tlsCAName == "" {
+		tlsCAName = peerInitOpts.CAName
+	}
+	tlsCert, tlsKey, tlsCACert, err := certs.EnrollUserContext(ctx, certs.EnrollUserRequest{
+		TLSCert: string(caTLSCert),
+		URL:     peerInitOpts.CAUrl,
+		Name:    tlsCAName,
+		MSPID:   peerInitOpts.MSPID,
+		User:    peerInitOpts.EnrollID,
+		Secret:  peerInitOpts.EnrollSecret,
+		Hosts:   tlsHosts,
+		CN:      peerInitOpts.EnrollID,
+		Profile: "tls",
+		KeySize: tlsKeySize(peerInitOpts.TLSPolicy),
+	})
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to enroll the TLS certificate")
+	}
+	tlsKeyBytes, err := utils.EncodePrivateKey(tlsKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return tlsCert, tlsKeyBytes, tlsCACert, nil
+}
+
+// peerMaterial holds the crypto material needed to lay out the MSP of a peer
+type peerMaterial struct {
+	TLSCert *x509.Certificate
+	TLSKey  []byte
+
+	SignCert *x509.Certificate
+	SignKey  []byte
+
+	CACert    *x509.Certificate
+	TLSCACert *x509.Certificate
+	// PreviousCACerts and PreviousTLSCACerts are the roots of a rotation in
+	// progress of the local CA, trusted besides CACert and TLSCACert
+	PreviousCACerts    []*x509.Certificate
+	PreviousTLSCACerts []*x509.Certificate
+
+	// IntermediateCerts and TLSIntermediateCerts are only set when the
+	// certificates were issued by an intermediate CA
+	IntermediateCerts    []*x509.Certificate
+	TLSIntermediateCerts []*x509.Certificate
+
+	// OperationsTLSCert and OperationsTLSKey are only set when the local CA
+	// issued the certificate of the operations endpoint
+	OperationsTLSCert *x509.Certificate
+	OperationsTLSKey  []byte
+}
+
+func encodeX509Certificates(crts []*x509.Certificate) []byte {
+	var pemBytes []byte
+	for _, crt := range crts {
+		pemBytes = append(pemBytes, utils.EncodeX509Certificate(crt)...)
+	}
+	return pemBytes
+}
+
+// writeIntermediateCerts writes every intermediate in its own file, the issuing CA first
+func writeIntermediateCerts(w plan.Writer, dir string, crts []*x509.Certificate) error {
+	err := w.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	for i, crt := range crts {
+		filePath := filepath.Join(dir, fmt.Sprintf("intermediatecert-%d.pem", i))
+		err = w.WriteFile(filePath, utils.EncodeX509Certificate(crt), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePeerMaterial writes the MSP, TLS material, core.yaml and init.json of a peer
+func writePeerMaterial(w plan.Writer, peerDir string, peerInitOpts config.PeerInitOptions, m peerMaterial) error {
+	tlsKeyBytes := m.TLSKey
+	signKeyBytes := m.SignKey
 	peerConfig := config.PeerConfig{
 		TLSKey:    tlsKeyBytes,
-		TLSCert:   utils.EncodeX509Certificate(tlsCert),
+		TLSCert:   utils.EncodeX509Certificate(m.TLSCert),
 		SignKey:   signKeyBytes,
-		SignCert:  utils.EncodeX509Certificate(peerCert),
+		SignCert:  utils.EncodeX509Certificate(m.SignCert),
 		PeerID:    peerInitOpts.ID,
-		TlsCACert: utils.EncodeX509Certificate(caConfig.TLSCACert),
-		CaCert:    utils.EncodeX509Certificate(caConfig.CACert),
+		TlsCACert: utils.EncodeX509Certificate(m.TLSCACert),
+		CaCert:    utils.EncodeX509Certificate(m.CACert),
 	}
 	peerConfigBytes, err := json.MarshalIndent(peerConfig, "", "  ")
 	if err != nil {
 		return err
 	}
 	peerConfigFilePath := filepath.Join(peerDir, "config.json")
-	err = os.WriteFile(peerConfigFilePath, peerConfigBytes, 0644)
+	err = w.WriteFile(peerConfigFilePath, peerConfigBytes, 0644)
+	utils.InvalidateConfig(peerConfigFilePath)
 	if err != nil {
 		return err
 	}
 
 	// keystore key pem
 	keyStoreDir := filepath.Join(peerDir, "keystore")
-	err = os.MkdirAll(keyStoreDir, 0755)
+	err = w.MkdirAll(keyStoreDir, 0755)
 	if err != nil {
 		return err
 	}
 	signKeyFilePath := filepath.Join(keyStoreDir, "key.pem")
-	err = os.WriteFile(signKeyFilePath, signKeyBytes, 0644)
+	err = w.WriteFile(signKeyFilePath, signKeyBytes, 0644)
 	if err != nil {
 		return err
 	}
 
 	// tlscacerts pem
 	tlsCACertsDir := filepath.Join(peerDir, "tlscacerts")
-	err = os.MkdirAll(tlsCACertsDir, 0755)
+	err = w.MkdirAll(tlsCACertsDir, 0755)
 	if err != nil {
 		return err
 	}
 	tlsCACertFilePath := filepath.Join(tlsCACertsDir, "cacert.pem")
-	err = os.WriteFile(tlsCACertFilePath, utils.EncodeX509Certificate(caConfig.TLSCACert), 0644)
+	tlsCACerts := append([]*x509.Certificate{m.TLSCACert}, m.PreviousTLSCACerts...)
+	err = w.WriteFile(tlsCACertFilePath, encodeX509Certificates(tlsCACerts), 0644)
 	if err != nil {
 		return err
 	}
 
 	// cacerts pem
 	cACertsDir := filepath.Join(peerDir, "cacerts")
-	err = os.MkdirAll(cACertsDir, 0755)
+	err = w.MkdirAll(cACertsDir, 0755)
 	if err != nil {
 		return err
 	}
 	caCertFilePath := filepath.Join(cACertsDir, "cacert.pem")
-	err = os.WriteFile(caCertFilePath, utils.EncodeX509Certificate(caConfig.CACert), 0644)
+	err = w.WriteFile(caCertFilePath, utils.EncodeX509Certificate(m.CACert), 0644)
+	if err != nil {
+		return err
+	}
+	err = writeExtraRoots(w, cACertsDir, m.PreviousCACerts)
 	if err != nil {
 		return err
 	}
 
 	// signcerts pem
 	signCertsDir := filepath.Join(peerDir, "signcerts")
-	err = os.MkdirAll(signCertsDir, 0755)
+	err = w.MkdirAll(signCertsDir, 0755)
 	if err != nil {
 		return err
 	}
 	signCertFilePath := filepath.Join(signCertsDir, "cert.pem")
-	err = os.WriteFile(signCertFilePath, utils.EncodeX509Certificate(peerCert), 0644)
+	err = w.WriteFile(signCertFilePath, utils.EncodeX509Certificate(m.SignCert), 0644)
 	if err != nil {
 		return err
 	}
 
+	// intermediatecerts and tlsintermediatecerts pem, one file per certificate
+	ouCertificate := "cacerts/cacert.pem"
+	if len(m.IntermediateCerts) > 0 {
+		err = writeIntermediateCerts(w, filepath.Join(peerDir, "intermediatecerts"), m.IntermediateCerts)
+		if err != nil {
+			return err
+		}
+		// the NodeOUs must point to the CA that issues the identities of the org
+		ouCertificate = "intermediatecerts/intermediatecert-0.pem"
+	}
+	if len(m.TLSIntermediateCerts) > 0 {
+		err = writeIntermediateCerts(w, filepath.Join(peerDir, "tlsintermediatecerts"), m.TLSIntermediateCerts)
+		if err != nil {
+			return err
+		}
+	}
+
 	// config.yaml
 	configFilePath := filepath.Join(peerDir, "config.yaml")
-	configYamlContent := `NodeOUs:
+	configYamlContent := fmt.Sprintf(`NodeOUs:
   Enable: true
   ClientOUIdentifier:
-    Certificate: cacerts/cacert.pem
+    Certificate: %[1]s
     OrganizationalUnitIdentifier: client
   PeerOUIdentifier:
-    Certificate: cacerts/cacert.pem
+    Certificate: %[1]s
     OrganizationalUnitIdentifier: peer
   AdminOUIdentifier:
-    Certificate: cacerts/cacert.pem
+    Certificate: %[1]s
     OrganizationalUnitIdentifier: admin
   OrdererOUIdentifier:
-    Certificate: cacerts/cacert.pem
+    Certificate: %[1]s
     OrganizationalUnitIdentifier: orderer
-`
-	err = os.WriteFile(configFilePath, []byte(configYamlContent), 0644)
+`, ouCertificate)
+	err = w.WriteFile(configFilePath, []byte(configYamlContent), 0644)
 	if err != nil {
 		return err
 	}
 	// write tls.key
 	tlsKeyFilePath := filepath.Join(peerDir, "tls.key")
-	err = os.WriteFile(tlsKeyFilePath, tlsKeyBytes, 0644)
+	err = w.WriteFile(tlsKeyFilePath, tlsKeyBytes, 0644)
 	if err != nil {
 		return err
 	}
 
-	// write tls.crt
+	// write tls.crt, followed by the intermediates so the peer serves the full chain
 	tlsCertFilePath := filepath.Join(peerDir, "tls.crt")
-	err = os.WriteFile(tlsCertFilePath, utils.EncodeX509Certificate(tlsCert), 0644)
+	tlsChain := append([]*x509.Certificate{m.TLSCert}, m.TLSIntermediateCerts...)
+	err = w.WriteFile(tlsCertFilePath, encodeX509Certificates(tlsChain), 0644)
+	if err != nil {
+		return err
+	}
+
+	// operations/tls.crt and operations/tls.key
+	if m.OperationsTLSCert != nil {
+		operationsDir := filepath.Join(peerDir, "operations")
+		err = w.MkdirAll(operationsDir, 0755)
+		if err != nil {
+			return err
+		}
+		err = w.WriteFile(filepath.Join(operationsDir, "tls.key"), m.OperationsTLSKey, 0644)
+		if err != nil {
+			return err
+		}
+		err = w.WriteFile(filepath.Join(operationsDir, "tls.crt"), utils.EncodeX509Certificate(m.OperationsTLSCert), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	// docker/ca.crt, docker/tls.crt and docker/tls.key
+	err = writeVMTLS(w, peerDir, peerInitOpts.VM)
+	if err != nil {
+		return err
+	}
+
+	peerInitOpts.ExternalEndpoint = peerExternalEndpoint(peerInitOpts)
+	peerInitOptsBytes, err := json.Marshal(peerInitOpts)
+	if err != nil {
+		return err
+	}
+	initJsonPath := filepath.Join(peerDir, "init.json")
+	err = w.WriteFile(initJsonPath, peerInitOptsBytes, 0644)
 	if err != nil {
 		return err
 	}
+	if peerInitOpts.MSPID != "" {
+		// the other peers of the org bootstrap from this peer too
+		return wireOrgGossip(w, peerInitOpts.MSPID, &peerInitOpts)
+	}
+	return renderPeerCoreYaml(w, peerDir, peerInitOpts, gossipBootstrap(peerInitOpts, nil))
+}
 
-	// write core.yaml based in the template
+// renderPeerCoreYaml writes the core.yaml of a peer based in the template
+func renderPeerCoreYaml(w plan.Writer, peerDir string, peerInitOpts config.PeerInitOptions, bootstrap []string) error {
 	tmpl, err := template.New("core.yaml").Funcs(sprig.HermeticTxtFuncMap()).Parse(coreYamlTemplate)
 	if err != nil {
 		return err
 	}
-	coreYamlFilePath := filepath.Join(peerDir, "core.yaml")
-	coreYamlFile, err := os.Create(coreYamlFilePath)
+	// the builder of hlf-easy releases the connections of the service registry
+	executable, err := os.Executable()
 	if err != nil {
 		return err
 	}
-	defer coreYamlFile.Close()
-	err = tmpl.Execute(coreYamlFile, struct {
-		FileSystemPath string
-	}{
-		FileSystemPath: filepath.Join(peerDir, "data"),
-	})
+	err = chaincode.WriteBuilder(w, peerDir, executable)
 	if err != nil {
 		return err
 	}
-	peerInitOptsBytes, err := json.Marshal(peerInitOpts)
+	// the custom builders of the host are tried after the one of hlf-easy
+	externalBuilders, err := chaincode.ListExternalBuilders()
 	if err != nil {
 		return err
 	}
-	initJsonPath := filepath.Join(peerDir, "init.json")
-	err = os.WriteFile(initJsonPath, peerInitOptsBytes, 0644)
+	gossipBootstrap := defaultGossipBootstrap
+	if len(bootstrap) > 0 {
+		gossipBootstrap = strings.Join(bootstrap, " ")
+	}
+	operationsCertFile, operationsKeyFile := GetOperationsTLSFiles(peerDir, peerInitOpts)
+	var coreYaml bytes.Buffer
+	err = tmpl.Execute(&coreYaml, struct {
+		FileSystemPath   string
+		GossipBootstrap  string
+		ExternalEndpoint string
+		GossipState      config.GossipStateOptions
+		Gateway          config.GatewayOptions
+		VM               config.VMOptions
+		OrdererOverrides []config.OrdererOverride
+		BuilderName      string
+		BuilderPath      string
+		ExternalBuilders []chaincode.ExternalBuilder
+		DevMode          bool
+		Operations       config.OperationsOptions
+		// the operations TLS files, only rendered when its TLS is enabled
+		OperationsCertFile     string
+		OperationsKeyFile      string
+		OperationsClientRootCA string
+	}{
+		FileSystemPath:         GetPeerDataDir(peerDir),
+		GossipBootstrap:        gossipBootstrap,
+		ExternalEndpoint:       peerInitOpts.ExternalEndpoint,
+		GossipState:            gossipStateWithDefaults(peerInitOpts.GossipState),
+		Gateway:                gatewayWithDefaults(peerInitOpts.Gateway),
+		VM:                     peerInitOpts.VM,
+		OrdererOverrides:       peerInitOpts.OrdererOverrides,
+		BuilderName:            chaincode.BuilderName,
+		BuilderPath:            chaincode.GetBuilderDir(peerDir),
+		ExternalBuilders:       externalBuilders,
+		DevMode:                peerInitOpts.DevMode,
+		Operations:             operationsWithDefaults(peerInitOpts.Operations),
+		OperationsCertFile:     operationsCertFile,
+		OperationsKeyFile:      operationsKeyFile,
+		OperationsClientRootCA: filepath.Join(peerDir, "tlscacerts", "cacert.pem"),
+	})
 	if err != nil {
 		return err
 	}
-	return nil
+	return w.WriteFile(filepath.Join(peerDir, "core.yaml"), coreYaml.Bytes(), 0644)
 }
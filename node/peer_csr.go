@@ -0,0 +1,244 @@
+package node
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/certs"
+	"hlf-easy/config"
+	"hlf-easy/plan"
+	"hlf-easy/resources"
+	"hlf-easy/utils"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// PeerCSRs contains the PEM encoded certificate signing requests of a peer
+type PeerCSRs struct {
+	TLSCSR  []byte
+	SignCSR []byte
+}
+
+// GeneratePeerCSRs generates the TLS and sign keys of a peer and the CSRs to be
+// signed by an external CA, the keys are kept in the csr directory of the peer
+// until the signed certificates are imported with ImportPeerCertificates.
+// It refuses to overwrite an existing peer or pending CSRs unless force is set
+func GeneratePeerCSRs(peerInitOpts config.PeerInitOptions, force bool) (*PeerCSRs, error) {
+	var csrs *PeerCSRs
+	err := withLock("peer", peerInitOpts.ID, "peer.csr.generate", func() error {
+		var err error
+		csrs, err = generatePeerCSRs(peerInitOpts, force)
+		return err
+	})
+	return csrs, err
+}
+
+func generatePeerCSRs(peerInitOpts config.PeerInitOptions, force bool) (*PeerCSRs, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	peerDir := filepath.Join(home, fmt.Sprintf("hlf-easy/peers/%s", peerInitOpts.ID))
+	csrDir := filepath.Join(peerDir, "csr")
+	if !force {
+		if _, err := os.Stat(filepath.Join(peerDir, "init.json")); err == nil {
+			return nil, errors.Errorf("peer %s is already initialized, use --force to overwrite it", peerInitOpts.ID)
+		}
+		if _, err := os.Stat(csrDir); err == nil {
+			return nil, errors.Errorf("peer %s has pending CSRs, use --force to overwrite them", peerInitOpts.ID)
+		}
+	}
+	err = resources.CheckReservation("peer", peerInitOpts.ID, peerInitOpts.Resources, peerInitOpts.Limits)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(csrDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	var dnsNames []string
+	for _, host := range certificateHosts(peerInitOpts.Hosts, peerInitOpts.ExternalEndpoint) {
+		// check if it's ip address
+		ip := net.ParseIP(host)
+		if ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = append(dnsNames, host)
+		}
+	}
+	// same subject as the certificates issued by a local CA in EnrollPeerCertificates
+	tlsCSROpts := certs.GenerateCertificateOptions{
+		CommonName:       "peer",
+		OrganizationUnit: []string{"peer"},
+		IPAddresses:      ips,
+		DNSNames:         dnsNames,
+		Curve:            tlsCurve(peerInitOpts.TLSPolicy),
+	}
+	err = certs.ApplyCertificatePolicySANs(&tlsCSROpts, peerInitOpts.CertPolicy)
+	if err != nil {
+		return nil, err
+	}
+	tlsCSR, tlsKey, err := certs.GenerateCSR(tlsCSROpts)
+	if err != nil {
+		return nil, err
+	}
+	signCSR, signKey, err := certs.GenerateCSR(certs.GenerateCertificateOptions{
+		CommonName:       "peer",
+		OrganizationUnit: []string{"peer"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	tlsKeyBytes, err := utils.EncodePrivateKey(tlsKey)
+	if err != nil {
+		return nil, err
+	}
+	signKeyBytes, err := utils.EncodePrivateKey(signKey)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{
+		"tls.key":  tlsKeyBytes,
+		"tls.csr":  tlsCSR,
+		"sign.key": signKeyBytes,
+		"sign.csr": signCSR,
+	}
+	for name, contents := range files {
+		err = os.WriteFile(filepath.Join(csrDir, name), contents, 0600)
+		if err != nil {
+			return nil, err
+		}
+	}
+	peerInitOpts.ExternalCA = true
+	peerInitOptsBytes, err := json.Marshal(peerInitOpts)
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(filepath.Join(peerDir, "init.json"), peerInitOptsBytes, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &PeerCSRs{
+		TLSCSR:  tlsCSR,
+		SignCSR: signCSR,
+	}, nil
+}
+
+// ImportPeerCertificatesOptions contains the certificates signed by the external CA
+type ImportPeerCertificatesOptions struct {
+	ID string
+	// PEM encoded certificates
+	TLSCert  []byte
+	SignCert []byte
+	// PEM encoded CA chains, the root CA must be the last certificate
+	CAChain    []byte
+	TLSCAChain []byte
+}
+
+// ImportPeerCertificates ingests the certificates signed by an external CA for the
+// CSRs generated by GeneratePeerCSRs and lays out the MSP of the peer
+func ImportPeerCertificates(opts ImportPeerCertificatesOptions) error {
+	return withLock("peer", opts.ID, "peer.csr.import", func() error {
+		return importPeerCertificates(opts)
+	})
+}
+
+func importPeerCertificates(opts ImportPeerCertificatesOptions) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	peerDir := filepath.Join(home, fmt.Sprintf("hlf-easy/peers/%s", opts.ID))
+	csrDir := filepath.Join(peerDir, "csr")
+	initJsonBytes, err := os.ReadFile(filepath.Join(peerDir, "init.json"))
+	if err != nil {
+		return errors.Wrapf(err, "failed to read init options of peer %s, were the CSRs generated?", opts.ID)
+	}
+	peerInitOpts := config.PeerInitOptions{}
+	err = json.Unmarshal(initJsonBytes, &peerInitOpts)
+	if err != nil {
+		return err
+	}
+	tlsKeyBytes, err := os.ReadFile(filepath.Join(csrDir, "tls.key"))
+	if err != nil {
+		return err
+	}
+	signKeyBytes, err := os.ReadFile(filepath.Join(csrDir, "sign.key"))
+	if err != nil {
+		return err
+	}
+	tlsCert, tlsChain, err := verifyImportedCertificate(opts.TLSCert, opts.TLSCAChain, tlsKeyBytes)
+	if err != nil {
+		return errors.Wrap(err, "invalid tls certificate")
+	}
+	signCert, signChain, err := verifyImportedCertificate(opts.SignCert, opts.CAChain, signKeyBytes)
+	if err != nil {
+		return errors.Wrap(err, "invalid sign certificate")
+	}
+	err = writePeerMaterial(plan.Disk, peerDir, peerInitOpts, peerMaterial{
+		TLSCert:              tlsCert,
+		TLSKey:               tlsKeyBytes,
+		SignCert:             signCert,
+		SignKey:              signKeyBytes,
+		CACert:               signChain[len(signChain)-1],
+		TLSCACert:            tlsChain[len(tlsChain)-1],
+		IntermediateCerts:    signChain[:len(signChain)-1],
+		TLSIntermediateCerts: tlsChain[:len(tlsChain)-1],
+	})
+	if err != nil {
+		return err
+	}
+	// the keys now live in the MSP, the CSRs are no longer needed
+	return os.RemoveAll(csrDir)
+}
+
+// verifyImportedCertificate checks that the certificate matches the private key
+// generated for the CSR and that it chains up to the root of the CA chain
+func verifyImportedCertificate(certPem []byte, chainPem []byte, keyPem []byte) (*x509.Certificate, []*x509.Certificate, error) {
+	crt, err := utils.ParseX509Certificate(certPem)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := utils.ParseECDSAPrivateKey(keyPem)
+	if err != nil {
+		return nil, nil, err
+	}
+	pub, ok := crt.PublicKey.(*ecdsa.PublicKey)
+	if !ok || !pub.Equal(key.Public()) {
+		return nil, nil, errors.New("certificate doesn't match the private key generated for the CSR")
+	}
+	chain, err := utils.ParseX509CertificateChain(chainPem)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the first certificate of the chain must be the issuer of the certificate
+	err = crt.CheckSignatureFrom(chain[0])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "certificate is not issued by the first certificate of the CA chain")
+	}
+	// the root must be the last certificate of the chain
+	root := chain[len(chain)-1]
+	if !bytes.Equal(root.RawIssuer, root.RawSubject) {
+		return nil, nil, errors.New("last certificate of the CA chain is not a root CA")
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	intermediates := x509.NewCertPool()
+	for _, intermediate := range chain[:len(chain)-1] {
+		intermediates.AddCert(intermediate)
+	}
+	_, err = crt.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return crt, chain, nil
+}
@@ -0,0 +1,82 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"hlf-easy/certs"
+	"hlf-easy/internal/testca"
+	"hlf-easy/utils"
+	"testing"
+)
+
+func encodeTestKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	keyPem, err := utils.EncodePrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyPem
+}
+
+func TestVerifyImportedCertificate(t *testing.T) {
+	root, rootKey := testca.NewCA(t, "root")
+	intermediate, intermediateKey := testca.Issue(t, testca.Cert{CommonName: "intermediate", CA: true}, root, rootKey)
+	opts := certs.GenerateCertificateOptions{CommonName: "peer", OrganizationUnit: []string{"peer"}}
+
+	t.Run("matching key", func(t *testing.T) {
+		crt, key, err := certs.GenerateCertificate(opts, root, rootKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, chain, err := verifyImportedCertificate(utils.EncodeX509Certificate(crt), utils.EncodeX509Certificate(root), encodeTestKey(t, key))
+		if err != nil {
+			t.Fatalf("expected certificate to be accepted: %v", err)
+		}
+		if len(chain) != 1 {
+			t.Fatalf("expected a chain of 1 certificate, got %d", len(chain))
+		}
+	})
+
+	t.Run("mismatched key", func(t *testing.T) {
+		crt, _, err := certs.GenerateCertificate(opts, root, rootKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = verifyImportedCertificate(utils.EncodeX509Certificate(crt), utils.EncodeX509Certificate(root), encodeTestKey(t, otherKey))
+		if err == nil {
+			t.Fatal("expected certificate with a different key to be rejected")
+		}
+	})
+
+	t.Run("chain with intermediate", func(t *testing.T) {
+		crt, key, err := certs.GenerateCertificate(opts, intermediate, intermediateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chainPem := encodeX509Certificates([]*x509.Certificate{intermediate, root})
+		_, chain, err := verifyImportedCertificate(utils.EncodeX509Certificate(crt), chainPem, encodeTestKey(t, key))
+		if err != nil {
+			t.Fatalf("expected certificate to be accepted: %v", err)
+		}
+		if len(chain) != 2 || !chain[0].Equal(intermediate) {
+			t.Fatal("expected the issuing intermediate to be the first certificate of the chain")
+		}
+
+		// root first is not a valid chain order
+		_, _, err = verifyImportedCertificate(utils.EncodeX509Certificate(crt), encodeX509Certificates([]*x509.Certificate{root, intermediate}), encodeTestKey(t, key))
+		if err == nil {
+			t.Fatal("expected a chain with the root first to be rejected")
+		}
+		// the intermediate is required to reach the root
+		_, _, err = verifyImportedCertificate(utils.EncodeX509Certificate(crt), utils.EncodeX509Certificate(root), encodeTestKey(t, key))
+		if err == nil {
+			t.Fatal("expected a chain without the intermediate to be rejected")
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/plan"
+	"hlf-easy/resources"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+)
+
+// ImportPeerMSPOptions locates the MSP and TLS material created by cryptogen or
+// fabric-ca-client for a peer
+type ImportPeerMSPOptions struct {
+	// InitOptions are written to the init.json of the peer, the hosts default
+	// to the SANs of the TLS certificate
+	InitOptions config.PeerInitOptions
+	// MSPDir has the signcerts, keystore, cacerts and optionally the
+	// intermediatecerts of the peer
+	MSPDir string
+	// TLSDir is either the tls directory of cryptogen, with server.crt,
+	// server.key and ca.crt, or the MSP enrolled with the tls profile of
+	// fabric-ca-client. It defaults to the tls directory next to MSPDir
+	TLSDir string
+	// Force overwrites an existing peer
+	Force bool
+}
+
+// ImportPeerMSP validates the MSP of a peer created outside of hlf-easy and
+// lays out the peer from it, the peer is managed by hlf-easy afterwards
+func ImportPeerMSP(opts ImportPeerMSPOptions) error {
+	return withLock("peer", opts.InitOptions.ID, "peer.import", func() error {
+		return importPeerMSP(opts)
+	})
+}
+
+func importPeerMSP(opts ImportPeerMSPOptions) error {
+	peerInitOpts := opts.InitOptions
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	peerDir := filepath.Join(home, fmt.Sprintf("hlf-easy/peers/%s", peerInitOpts.ID))
+	if !opts.Force {
+		if _, err := os.Stat(filepath.Join(peerDir, "init.json")); err == nil {
+			return errors.Errorf("peer %s is already initialized, use --force to overwrite it", peerInitOpts.ID)
+		}
+	}
+	tlsDir := opts.TLSDir
+	if tlsDir == "" {
+		tlsDir = filepath.Join(filepath.Dir(filepath.Clean(opts.MSPDir)), "tls")
+	}
+	signCert, signKey, signChain, err := readImportedIdentity(opts.MSPDir, "signcerts", "keystore", "cacerts", "intermediatecerts", "")
+	if err != nil {
+		return errors.Wrapf(err, "invalid MSP %s", opts.MSPDir)
+	}
+	// the NodeOUs of the MSP written by hlf-easy classify the peer by its OU
+	if !utils.Contains(signCert.Subject.OrganizationalUnit, "peer") {
+		return errors.Errorf("the sign certificate of %s has the OUs %v without peer, generate it with EnableNodeOUs (cryptogen) or the peer type (fabric-ca-client)", opts.MSPDir, signCert.Subject.OrganizationalUnit)
+	}
+	var tlsCert *x509.Certificate
+	var tlsKey []byte
+	var tlsChain []*x509.Certificate
+	_, statErr := os.Stat(filepath.Join(tlsDir, "server.crt"))
+	if statErr == nil {
+		tlsCert, tlsKey, tlsChain, err = readImportedIdentity(tlsDir, "server.crt", "server.key", "ca.crt", "", filepath.Join(opts.MSPDir, "tlscacerts"))
+	} else {
+		tlsCert, tlsKey, tlsChain, err = readImportedIdentity(tlsDir, "signcerts", "keystore", "tlscacerts", "tlsintermediatecerts", filepath.Join(opts.MSPDir, "tlscacerts"))
+	}
+	if err != nil {
+		return errors.Wrapf(err, "invalid TLS material %s", tlsDir)
+	}
+	if len(peerInitOpts.Hosts) == 0 {
+		peerInitOpts.Hosts = append(peerInitOpts.Hosts, tlsCert.DNSNames...)
+		for _, ip := range tlsCert.IPAddresses {
+			peerInitOpts.Hosts = append(peerInitOpts.Hosts, ip.String())
+		}
+	}
+	for _, host := range peerInitOpts.Hosts {
+		if tlsCert.VerifyHostname(host) != nil {
+			return errors.Errorf("the TLS certificate isn't valid for the host %s", host)
+		}
+	}
+	// the imported key can't be issued again on the curve of the policy
+	if err := checkTLSCurve(tlsCert, peerInitOpts.TLSPolicy); err != nil {
+		return err
+	}
+	// the certificates can't be renewed by a CA of hlf-easy
+	peerInitOpts.ExternalCA = true
+	err = resources.CheckReservation("peer", peerInitOpts.ID, peerInitOpts.Resources, peerInitOpts.Limits)
+	if err != nil {
+		return err
+	}
+	if opts.Force {
+		// the material of the previous peer mustn't mix with the imported one
+		for _, dir := range []string{"intermediatecerts", "tlsintermediatecerts", "keystore", "csr"} {
+			err = os.RemoveAll(filepath.Join(peerDir, dir))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = os.MkdirAll(peerDir, 0755)
+	if err != nil {
+		return err
+	}
+	return writePeerMaterial(plan.Disk, peerDir, peerInitOpts, peerMaterial{
+		TLSCert:              tlsCert,
+		TLSKey:               tlsKey,
+		SignCert:             signCert,
+		SignKey:              signKey,
+		CACert:               signChain[len(signChain)-1],
+		TLSCACert:            tlsChain[len(tlsChain)-1],
+		IntermediateCerts:    signChain[:len(signChain)-1],
+		TLSIntermediateCerts: tlsChain[:len(tlsChain)-1],
+	})
+}
+
+// readImportedIdentity reads the certificate of a directory, the key of the
+// keystore that matches it and verifies its chain to the CAs. The paths are
+// files or directories of PEM files relative to dir, the fallback CAs are used
+// when the directory has none. It returns the PKCS#8 key and the chain of
+// CAs, the issuing CA first and the root last
+func readImportedIdentity(dir string, certPath string, keyPath string, caPath string, intermediatesPath string, fallbackCAPath string) (*x509.Certificate, []byte, []*x509.Certificate, error) {
+	crts, err := readPEMPath(filepath.Join(dir, certPath))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(crts) == 0 {
+		return nil, nil, nil, errors.Errorf("no certificate in %s", filepath.Join(dir, certPath))
+	}
+	crt := crts[0]
+	key, err := findMatchingKey(filepath.Join(dir, keyPath), crt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	roots, err := readPEMPath(filepath.Join(dir, caPath))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(roots) == 0 && fallbackCAPath != "" {
+		roots, err = readPEMPath(fallbackCAPath)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if len(roots) == 0 {
+		return nil, nil, nil, errors.Errorf("no CA certificate in %s", filepath.Join(dir, caPath))
+	}
+	intermediates := crts[1:]
+	if intermediatesPath != "" {
+		moreIntermediates, err := readPEMPath(filepath.Join(dir, intermediatesPath))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		intermediates = append(intermediates, moreIntermediates...)
+	}
+	rootPool := x509.NewCertPool()
+	for _, root := range roots {
+		rootPool.AddCert(root)
+	}
+	intermediatePool := x509.NewCertPool()
+	for _, intermediate := range intermediates {
+		intermediatePool.AddCert(intermediate)
+	}
+	chains, err := crt.Verify(x509.VerifyOptions{
+		Roots:         rootPool,
+		Intermediates: intermediatePool,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "%s doesn't chain to the CAs", crt.Subject.CommonName)
+	}
+	keyBytes, err := utils.EncodePrivateKey(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return crt, keyBytes, chains[0][1:], nil
+}
+
+// readPEMPath reads the certificates of a PEM file or of the PEM files of a
+// directory, a missing path has no certificates
+func readPEMPath(path string) ([]*x509.Certificate, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	var crts []*x509.Certificate
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		chain, err := utils.ParseX509CertificateChain(contents)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", file)
+		}
+		crts = append(crts, chain...)
+	}
+	return crts, nil
+}
+
+// findMatchingKey returns the key of the file, or of the files of a keystore,
+// that matches the certificate. cryptogen and fabric-ca-client name the keys
+// after their SKI so the name isn't known
+func findMatchingKey(path string, crt *x509.Certificate) (*ecdsa.PrivateKey, error) {
+	pub, ok := crt.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("the key of %s is not of ECDSA type", crt.Subject.CommonName)
+	}
+	files := []string{path}
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(contents)
+		if block == nil {
+			continue
+		}
+		var key *ecdsa.PrivateKey
+		if block.Type == "EC PRIVATE KEY" {
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		} else {
+			key, err = utils.ParseECDSAPrivateKey(contents)
+		}
+		if err != nil {
+			continue
+		}
+		if pub.Equal(key.Public()) {
+			return key, nil
+		}
+	}
+	return nil, errors.Errorf("no key in %s matches the certificate of %s", path, crt.Subject.CommonName)
+}
@@ -0,0 +1,106 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path string, contents []byte) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, contents, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeCryptogenPeer writes the msp and tls directories of a peer the way
+// cryptogen lays them out, it returns the msp directory
+func writeCryptogenPeer(t *testing.T, dir string, signCert *x509.Certificate, signKey *ecdsa.PrivateKey, ca *x509.Certificate, tlsCert *x509.Certificate, tlsKey *ecdsa.PrivateKey, tlsCA *x509.Certificate) string {
+	t.Helper()
+	mspDir := filepath.Join(dir, "msp")
+	writeTestFile(t, filepath.Join(mspDir, "signcerts/peer0.org1.example.com-cert.pem"), utils.EncodeX509Certificate(signCert))
+	writeTestFile(t, filepath.Join(mspDir, "keystore/priv_sk"), encodeTestKey(t, signKey))
+	writeTestFile(t, filepath.Join(mspDir, "cacerts/ca.org1.example.com-cert.pem"), utils.EncodeX509Certificate(ca))
+	writeTestFile(t, filepath.Join(mspDir, "tlscacerts/tlsca.org1.example.com-cert.pem"), utils.EncodeX509Certificate(tlsCA))
+	writeTestFile(t, filepath.Join(dir, "tls/server.crt"), utils.EncodeX509Certificate(tlsCert))
+	writeTestFile(t, filepath.Join(dir, "tls/server.key"), encodeTestKey(t, tlsKey))
+	writeTestFile(t, filepath.Join(dir, "tls/ca.crt"), utils.EncodeX509Certificate(tlsCA))
+	return mspDir
+}
+
+func TestImportPeerMSP(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	ca, caKey := testca.NewCA(t, "ca.org1.example.com")
+	tlsCA, tlsCAKey := testca.NewCA(t, "tlsca.org1.example.com")
+	signCert, signKey := testca.Issue(t, testca.Cert{CommonName: "peer0.org1.example.com", OUs: []string{"peer"}}, ca, caKey)
+	tlsCert, tlsKey := testca.Issue(t, testca.Cert{CommonName: "peer0.org1.example.com", DNSNames: []string{"peer0.org1.example.com", "peer0"}}, tlsCA, tlsCAKey)
+	cryptoDir := filepath.Join(t.TempDir(), "peers/peer0.org1.example.com")
+	mspDir := writeCryptogenPeer(t, cryptoDir, signCert, signKey, ca, tlsCert, tlsKey, tlsCA)
+
+	opts := ImportPeerMSPOptions{
+		InitOptions: config.PeerInitOptions{ID: "peer0", MSPID: "Org1MSP"},
+		MSPDir:      mspDir,
+	}
+	err := ImportPeerMSP(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers/peer0")
+	initBytes, err := os.ReadFile(filepath.Join(peerDir, "init.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerInitOpts := config.PeerInitOptions{}
+	err = json.Unmarshal(initBytes, &peerInitOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !peerInitOpts.ExternalCA {
+		t.Error("expected the imported peer to be enrolled by an external CA")
+	}
+	if expected := []string{"peer0.org1.example.com", "peer0"}; !reflect.DeepEqual(peerInitOpts.Hosts, expected) {
+		t.Errorf("expected the hosts %v of the TLS certificate, got %v", expected, peerInitOpts.Hosts)
+	}
+	checks, err := ValidatePeer("peer0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range checks {
+		if check.Status == CheckFail {
+			t.Errorf("expected %s of the imported peer to pass, got %s", check.Name, check.Message)
+		}
+	}
+
+	if err := ImportPeerMSP(opts); err == nil {
+		t.Fatal("expected an error importing over an existing peer without force")
+	}
+	opts.Force = true
+	opts.InitOptions.Hosts = []string{"peer1.org1.example.com"}
+	if err := ImportPeerMSP(opts); err == nil {
+		t.Fatal("expected an error for a host that isn't in the TLS certificate")
+	}
+
+	// without NodeOUs cryptogen issues the certificates without the peer OU
+	clientCert, clientKey := testca.Issue(t, testca.Cert{CommonName: "peer0.org1.example.com"}, ca, caKey)
+	noOUDir := filepath.Join(t.TempDir(), "peers/peer0.org1.example.com")
+	opts = ImportPeerMSPOptions{
+		InitOptions: config.PeerInitOptions{ID: "peer1"},
+		MSPDir:      writeCryptogenPeer(t, noOUDir, clientCert, clientKey, ca, tlsCert, tlsKey, tlsCA),
+	}
+	if err := ImportPeerMSP(opts); err == nil {
+		t.Fatal("expected an error for a certificate without the peer OU")
+	}
+}
@@ -0,0 +1,121 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"hlf-easy/certs"
+	"hlf-easy/config"
+	"hlf-easy/plan"
+	"hlf-easy/utils"
+	"math/big"
+	"net"
+	"time"
+)
+
+// issueCertificate issues a certificate with a local CA. A dry run plans the
+// update of the serial file of a sequential policy without reserving the
+// serial number
+func issueCertificate(
+	w plan.Writer,
+	o certs.GenerateCertificateOptions,
+	caCert *x509.Certificate,
+	caKey *ecdsa.PrivateKey,
+) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	if p, ok := w.(*plan.Plan); ok {
+		serialFilePath, err := certs.PlanSerialNumber(&o)
+		if err != nil {
+			return nil, nil, err
+		}
+		if serialFilePath != "" {
+			err = p.WriteFile(serialFilePath, []byte(o.SerialNumber.String()), 0644)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return certs.GenerateCertificate(o, caCert, caKey)
+}
+
+// placeholderCA is a throwaway CA standing for a Fabric CA in a dry run,
+// enrolling would use up the enrollments of the identity
+func placeholderCA(caName string, caURL string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName:         caName,
+			OrganizationalUnit: []string{fmt.Sprintf("enrolled from %s", caURL)},
+		},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	crtBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	crt, err := x509.ParseCertificate(crtBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return crt, key, nil
+}
+
+// planEnrolledPeerMaterial plans the material of a peer enrolled with a Fabric
+// CA, its certificates are issued by placeholder CAs named after the CAs of
+// the enrollment
+func planEnrolledPeerMaterial(p *plan.Plan, peerDir string, peerInitOpts config.PeerInitOptions, tlsHosts []string) error {
+	tlsCAName := peerInitOpts.TLSCAName
+	if tlsCAName == "" {
+		tlsCAName = peerInitOpts.CAName
+	}
+	tlsCACert, tlsCAKey, err := placeholderCA(tlsCAName, peerInitOpts.CAUrl)
+	if err != nil {
+		return err
+	}
+	caCert, caKey, err := placeholderCA(peerInitOpts.CAName, peerInitOpts.CAUrl)
+	if err != nil {
+		return err
+	}
+	tlsCertOpts := certs.GenerateCertificateOptions{CommonName: peerInitOpts.EnrollID, Curve: tlsCurve(peerInitOpts.TLSPolicy)}
+	for _, host := range tlsHosts {
+		if ip := net.ParseIP(host); ip != nil {
+			tlsCertOpts.IPAddresses = append(tlsCertOpts.IPAddresses, ip)
+		} else {
+			tlsCertOpts.DNSNames = append(tlsCertOpts.DNSNames, host)
+		}
+	}
+	tlsCert, tlsKey, err := certs.GenerateCertificate(tlsCertOpts, tlsCACert, tlsCAKey)
+	if err != nil {
+		return err
+	}
+	signCert, signKey, err := certs.GenerateCertificate(certs.GenerateCertificateOptions{CommonName: peerInitOpts.EnrollID}, caCert, caKey)
+	if err != nil {
+		return err
+	}
+	tlsKeyBytes, err := utils.EncodePrivateKey(tlsKey)
+	if err != nil {
+		return err
+	}
+	signKeyBytes, err := utils.EncodePrivateKey(signKey)
+	if err != nil {
+		return err
+	}
+	return writePeerMaterial(p, peerDir, peerInitOpts, peerMaterial{
+		TLSCert:   tlsCert,
+		TLSKey:    tlsKeyBytes,
+		SignCert:  signCert,
+		SignKey:   signKeyBytes,
+		CACert:    caCert,
+		TLSCACert: tlsCACert,
+	})
+}
@@ -0,0 +1,163 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"hlf-easy/plan"
+	"hlf-easy/utils"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// listFiles returns the files of the directory, sorted
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func plannedFiles(p *plan.Plan) []string {
+	var files []string
+	for _, c := range p.Changes {
+		if c.Kind != plan.KindDir && c.Action == plan.Create {
+			files = append(files, c.Path)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestPlanPeerInit(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	caCert, caKey := testca.NewCA(t, "ca")
+	tlsCACert, tlsCAKey := testca.NewCA(t, "tlsca")
+	caConfigBytes, err := json.Marshal(config.CAConfig{
+		CaCert:     utils.EncodeX509Certificate(caCert),
+		CaKey:      encodeTestKey(t, caKey),
+		CaName:     "org1-ca",
+		TlsCACert:  utils.EncodeX509Certificate(tlsCACert),
+		TlsCAKey:   encodeTestKey(t, tlsCAKey),
+		CertPolicy: config.CertificatePolicy{SerialNumberPolicy: "sequential"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caDir := filepath.Join(home, "hlf-easy/cas/org1-ca")
+	if err := os.MkdirAll(caDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(caDir, "config.json"), caConfigBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := config.PeerInitOptions{ID: "peer0", Local: true, CAName: "org1-ca", Hosts: []string{"node1", "10.0.0.2"}, MSPID: "Org1MSP"}
+
+	before := listFiles(t, home)
+	p := plan.New()
+	err = PlanPeerInit(p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after := listFiles(t, home); !reflect.DeepEqual(before, after) {
+		t.Fatalf("the dry run wrote %v", after)
+	}
+	for _, c := range p.Changes {
+		if strings.HasSuffix(c.Path, "tls.crt") && !strings.Contains(c.Detail, "SANs node1,10.0.0.2") {
+			t.Errorf("expected the SANs of the TLS certificate, got %q", c.Detail)
+		}
+	}
+
+	// the real run writes exactly the planned files
+	err = EnrollPeerCertificates(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written []string
+	for _, file := range listFiles(t, home) {
+		if !contains(before, file) {
+			written = append(written, file)
+		}
+	}
+	if planned := plannedFiles(p); !reflect.DeepEqual(planned, written) {
+		t.Fatalf("expected the files\n%s\ngot\n%s", strings.Join(planned, "\n"), strings.Join(written, "\n"))
+	}
+
+	// planning it again only re-issues the certificates, the serial files
+	// advance
+	p = plan.New()
+	err = PlanPeerInit(p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Count(plan.Create) != 0 {
+		t.Errorf("expected nothing to create, got %+v", p.Changes)
+	}
+	for _, c := range p.Changes {
+		if c.Action == plan.Unchanged && (c.Kind == plan.KindCert || c.Kind == plan.KindKey) && !strings.Contains(c.Path, "cacert") {
+			t.Errorf("expected %s to be re-issued", c.Path)
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPlanPeerInitFabricCA(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	p := plan.New()
+	// the CA isn't contacted, its TLS certificate isn't even read
+	err := PlanPeerInit(p, config.PeerInitOptions{
+		ID:           "peer0",
+		CAUrl:        "https://ca.example.com:7054",
+		CAName:       "ca",
+		CACert:       filepath.Join(home, "missing.pem"),
+		EnrollID:     "peer0",
+		EnrollSecret: "pw",
+		Hosts:        []string{"node1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := listFiles(t, home); len(files) != 0 {
+		t.Fatalf("the dry run wrote %v", files)
+	}
+	found := false
+	for _, c := range p.Changes {
+		if strings.HasSuffix(c.Path, "signcerts/cert.pem") {
+			found = true
+			if !strings.Contains(c.Detail, "enrolled from https://ca.example.com:7054") {
+				t.Errorf("expected the certificate to be enrolled from the CA, got %q", c.Detail)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected the sign certificate to be planned, got %+v", p.Changes)
+	}
+}
@@ -0,0 +1,191 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	psnet "github.com/shirou/gopsutil/net"
+	"github.com/shirou/gopsutil/process"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ListenAddress is an address a node listens on, Name tells which one
+type ListenAddress struct {
+	Name    string
+	Address string
+}
+
+// PeerListenAddresses returns the addresses a peer started with the options
+// listens on
+func PeerListenAddresses(opts config.PeerStartOptions) []ListenAddress {
+	return []ListenAddress{
+		{Name: "listen address", Address: opts.ListenAddress},
+		{Name: "chaincode address", Address: opts.ChaincodeAddress},
+		{Name: "events address", Address: opts.EventsAddress},
+		{Name: "operations address", Address: opts.OperationsListenAddress},
+	}
+}
+
+// OrdererListenAddresses returns the addresses an orderer started with the
+// options listens on
+func OrdererListenAddresses(opts config.OrdererStartOptions) []ListenAddress {
+	return []ListenAddress{
+		{Name: "listen address", Address: opts.ListenAddress},
+		{Name: "admin address", Address: opts.AdminListenAddress},
+		{Name: "operations address", Address: opts.OperationsListenAddress},
+	}
+}
+
+// claim is a listen address of a running node of the host
+type claim struct {
+	ListenAddress
+	kind string
+	id   string
+}
+
+// runningClaims returns the listen addresses of the nodes of the host that
+// have a run.json, but the node being started
+func runningClaims(kind string, id string) ([]claim, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	var claims []claim
+	for _, nodeKind := range []string{KindPeer, KindOrderer} {
+		runFiles, err := filepath.Glob(filepath.Join(home, fmt.Sprintf("hlf-easy/%ss/*/run.json", nodeKind)))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(runFiles)
+		for _, runFile := range runFiles {
+			nodeID := filepath.Base(filepath.Dir(runFile))
+			if nodeKind == kind && nodeID == id {
+				continue
+			}
+			runBytes, err := os.ReadFile(runFile)
+			if err != nil {
+				continue
+			}
+			var addresses []ListenAddress
+			if nodeKind == KindPeer {
+				runConfig := config.PeerRunConfig{}
+				if json.Unmarshal(runBytes, &runConfig) != nil {
+					continue
+				}
+				addresses = PeerListenAddresses(runConfig.Options)
+			} else {
+				runConfig := config.OrdererRunConfig{}
+				if json.Unmarshal(runBytes, &runConfig) != nil {
+					continue
+				}
+				addresses = OrdererListenAddresses(runConfig.Options)
+			}
+			for _, address := range addresses {
+				if address.Address != "" {
+					claims = append(claims, claim{ListenAddress: address, kind: nodeKind, id: nodeID})
+				}
+			}
+		}
+	}
+	return claims, nil
+}
+
+// overlap tells whether two listen addresses can't be bound together: same
+// port on the same host, or on any host for an unspecified one
+func overlap(a string, b string) bool {
+	hostA, portA, errA := net.SplitHostPort(a)
+	hostB, portB, errB := net.SplitHostPort(b)
+	if errA != nil || errB != nil || portA != portB {
+		return false
+	}
+	normalize := func(host string) string {
+		if host == "localhost" {
+			return "127.0.0.1"
+		}
+		return host
+	}
+	hostA, hostB = normalize(hostA), normalize(hostB)
+	unspecified := func(host string) bool {
+		return host == "" || net.ParseIP(host).IsUnspecified()
+	}
+	return hostA == hostB || unspecified(hostA) || unspecified(hostB)
+}
+
+// portOwner names the process listening on the port of an address, it's
+// "another process" when it can't be found, e.g. one of another user
+func portOwner(address string) string {
+	_, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return "another process"
+	}
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		return "another process"
+	}
+	conns, err := psnet.Connections("tcp")
+	if err != nil {
+		return "another process"
+	}
+	for _, conn := range conns {
+		if conn.Status != "LISTEN" || int(conn.Laddr.Port) != port || conn.Pid == 0 {
+			continue
+		}
+		if p, err := process.NewProcess(conn.Pid); err == nil {
+			if name, err := p.Name(); err == nil && name != "" {
+				return fmt.Sprintf("process %d (%s)", conn.Pid, name)
+			}
+		}
+		return fmt.Sprintf("process %d", conn.Pid)
+	}
+	return "another process"
+}
+
+// CheckPorts checks that the addresses of a node are free before its process
+// is started: each one is used once, isn't claimed by another running node
+// of the host and can be bound. The error names the node or the process
+// holding each conflicting port
+func CheckPorts(kind string, id string, addresses []ListenAddress) error {
+	claims, err := runningClaims(kind, id)
+	if err != nil {
+		return err
+	}
+	var conflicts []string
+	for i, address := range addresses {
+		if address.Address == "" {
+			continue
+		}
+		conflict := ""
+		for _, other := range addresses[:i] {
+			if other.Address != "" && overlap(address.Address, other.Address) {
+				conflict = fmt.Sprintf("the %s %s of %s %s", other.Name, other.Address, kind, id)
+				break
+			}
+		}
+		for _, c := range claims {
+			if conflict == "" && overlap(address.Address, c.Address) {
+				conflict = fmt.Sprintf("the %s %s of the running %s %s", c.Name, c.Address, c.kind, c.id)
+			}
+		}
+		if conflict == "" {
+			l, err := net.Listen("tcp", address.Address)
+			if err != nil {
+				conflict = fmt.Sprintf("%s: %v", portOwner(address.Address), err)
+			} else if err := l.Close(); err != nil {
+				return err
+			}
+		}
+		if conflict != "" {
+			conflicts = append(conflicts, fmt.Sprintf("the %s %s is in use by %s", address.Name, address.Address, conflict))
+		}
+	}
+	if len(conflicts) > 0 {
+		return errdefs.Errorf(errdefs.ErrPortInUse, "%s %s can't start, %s", kind, id, strings.Join(conflicts, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOverlap(t *testing.T) {
+	cases := []struct {
+		a, b    string
+		overlap bool
+	}{
+		{"0.0.0.0:7051", "127.0.0.1:7051", true},
+		{"localhost:7051", "127.0.0.1:7051", true},
+		{":7051", "10.0.0.1:7051", true},
+		{"10.0.0.1:7051", "10.0.0.2:7051", false},
+		{"0.0.0.0:7051", "0.0.0.0:7052", false},
+	}
+	for _, c := range cases {
+		if overlap(c.a, c.b) != c.overlap {
+			t.Errorf("expected the overlap of %s and %s to be %v", c.a, c.b, c.overlap)
+		}
+	}
+}
+
+func TestCheckPorts(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	// peer1 is running with its listen address on 7061
+	peerDir := filepath.Join(home, "hlf-easy/peers/peer1")
+	if err := os.MkdirAll(peerDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	runBytes, _ := json.Marshal(config.PeerRunConfig{PeerID: "peer1", Options: config.PeerStartOptions{ListenAddress: "0.0.0.0:7061"}})
+	if err := os.WriteFile(filepath.Join(peerDir, "run.json"), runBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	err = CheckPorts(KindPeer, "peer0", []ListenAddress{
+		{Name: "listen address", Address: "127.0.0.1:7061"},
+		{Name: "chaincode address", Address: l.Addr().String()},
+	})
+	if !errors.Is(err, errdefs.ErrPortInUse) {
+		t.Fatalf("expected a port in use error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "running peer peer1") || !strings.Contains(err.Error(), "chaincode address "+l.Addr().String()) {
+		t.Errorf("expected the error to name the conflicts, got %v", err)
+	}
+
+	// the ports claimed by the node itself are free on restart
+	err = CheckPorts(KindPeer, "peer1", []ListenAddress{{Name: "listen address", Address: "127.0.0.1:0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = CheckPorts(KindPeer, "peer1", []ListenAddress{
+		{Name: "listen address", Address: "0.0.0.0:7071"},
+		{Name: "operations address", Address: "127.0.0.1:7071"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "listen address 0.0.0.0:7071 of peer peer1") {
+		t.Errorf("expected the ports used twice to conflict, got %v", err)
+	}
+}
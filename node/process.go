@@ -0,0 +1,185 @@
+package node
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/process"
+	"hlf-easy/errdefs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ProcessRecord is the process of a node persisted in its directory, a
+// restarted hlf-easy re-attaches to the process instead of starting another
+// one
+type ProcessRecord struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	PID  int    `json:"pid"`
+	// CreateTime of the process in milliseconds, it tells a PID reused by
+	// another process
+	CreateTime int64 `json:"createTime"`
+	// ManagerPID is the hlf-easy process managing the node
+	ManagerPID        int       `json:"managerPID"`
+	ManagerCreateTime int64     `json:"managerCreateTime"`
+	StartedAt         time.Time `json:"startedAt"`
+}
+
+// processWatchInterval is how often the exit of an attached process is
+// checked, it isn't a child of hlf-easy so it can't be waited
+const processWatchInterval = time.Second
+
+// GetProcessRecordPath returns the process.json of a node
+func GetProcessRecordPath(kind string, id string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy", kind+"s", id, "run", "process.json"), nil
+}
+
+// LoadProcessRecord reads the process.json of a node, it's nil when the node
+// has no process
+func LoadProcessRecord(kind string, id string) (*ProcessRecord, error) {
+	recordPath, err := GetProcessRecordPath(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	recordBytes, err := os.ReadFile(recordPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &ProcessRecord{}
+	err = json.Unmarshal(recordBytes, r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the process.json of %s %s", kind, id)
+	}
+	return r, nil
+}
+
+// saveProcessRecord records the process of a node managed by the current
+// hlf-easy process
+func saveProcessRecord(kind string, id string, pid int) error {
+	createTime, err := processCreateTime(pid)
+	if err != nil {
+		return err
+	}
+	managerCreateTime, err := processCreateTime(os.Getpid())
+	if err != nil {
+		return err
+	}
+	r := ProcessRecord{
+		Kind:              kind,
+		ID:                id,
+		PID:               pid,
+		CreateTime:        createTime,
+		ManagerPID:        os.Getpid(),
+		ManagerCreateTime: managerCreateTime,
+		StartedAt:         time.Now(),
+	}
+	recordPath, err := GetProcessRecordPath(kind, id)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(recordPath), 0755)
+	if err != nil {
+		return err
+	}
+	recordBytes, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(recordPath, recordBytes, 0644)
+}
+
+// removeProcessRecord removes the process.json of a node whose process exited
+func removeProcessRecord(kind string, id string) error {
+	recordPath, err := GetProcessRecordPath(kind, id)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(recordPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func processCreateTime(pid int) (int64, error) {
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return 0, err
+	}
+	return p.CreateTime()
+}
+
+// findProcess returns the process of a PID when it's the one created at
+// createTime, it's nil when the process exited or the PID was reused
+func findProcess(pid int, createTime int64) *process.Process {
+	if pid <= 0 || pid == os.Getpid() {
+		return nil
+	}
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return nil
+	}
+	pCreateTime, err := p.CreateTime()
+	if err != nil || pCreateTime != createTime {
+		return nil
+	}
+	return p
+}
+
+// CheckManager fails when the node is managed by another hlf-easy process
+// that is running
+func CheckManager(kind string, id string) error {
+	r, err := LoadProcessRecord(kind, id)
+	if err != nil || r == nil {
+		return err
+	}
+	if r.ManagerPID == os.Getpid() || findProcess(r.ManagerPID, r.ManagerCreateTime) == nil {
+		return nil
+	}
+	if findProcess(r.PID, r.CreateTime) == nil {
+		return nil
+	}
+	return errdefs.Errorf(errdefs.ErrNodeAlreadyRunning, "%s %s is managed by the hlf-easy process %d", kind, id, r.ManagerPID)
+}
+
+// findRecordedProcess returns the process of a node left running by an
+// hlf-easy process that exited, the record of a process that exited is
+// removed
+func findRecordedProcess(kind string, id string) (*process.Process, error) {
+	err := CheckManager(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	r, err := LoadProcessRecord(kind, id)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	p := findProcess(r.PID, r.CreateTime)
+	if p == nil {
+		return nil, removeProcessRecord(kind, id)
+	}
+	return p, nil
+}
+
+// ProcessRunning tells whether the process of a PID is the one created at
+// createTime
+func ProcessRunning(pid int, createTime int64) bool {
+	return findProcess(pid, createTime) != nil
+}
+
+// WaitProcess waits for a process that isn't a child of hlf-easy to exit, it's
+// looked up again every time so it's not shared with the status queries
+func WaitProcess(pid int, createTime int64) error {
+	for findProcess(pid, createTime) != nil {
+		time.Sleep(processWatchInterval)
+	}
+	return errors.Errorf("process %d exited", pid)
+}
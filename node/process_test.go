@@ -0,0 +1,225 @@
+package node
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/errdefs"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPeerNodeCrash(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	n := NewPeerNode("peer0", "Org1MSP", config.NodeLimits{}, func() (*exec.Cmd, error) {
+		return exec.Command("sh", "-c", "exit 3"), nil
+	})
+	err := n.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-n.exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the peer process to exit")
+	}
+	n.mu.Lock()
+	crashed := n.cmd == nil
+	n.mu.Unlock()
+	if !crashed || n.State() != StateFailed {
+		t.Fatalf("expected the crashed peer to be failed, got %s", n.State())
+	}
+	// a crashed peer can be started again
+	err = n.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeerNodeStop(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	n := NewPeerNode("peer0", "Org1MSP", config.NodeLimits{}, func() (*exec.Cmd, error) {
+		return exec.Command("sleep", "30"), nil
+	})
+	err := n.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Restart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Stop(); err == nil {
+		t.Fatal("expected a stopped peer not to be stopped again")
+	}
+	if n.State() != StateStopped {
+		t.Errorf("expected the peer to be stopped, got %s", n.State())
+	}
+}
+
+func TestPeerNodeConcurrent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	n := NewPeerNode("peer0", "Org1MSP", config.NodeLimits{}, func() (*exec.Cmd, error) {
+		return exec.Command("sleep", "30"), nil
+	})
+	// only one of the concurrent starts starts the peer
+	var wg sync.WaitGroup
+	var started int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := n.Start(); err == nil {
+				atomic.AddInt32(&started, 1)
+			} else if !errors.Is(err, errdefs.ErrNodeAlreadyRunning) {
+				t.Errorf("expected the peer to be already running, got %v", err)
+			}
+			if _, err := n.Status(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if started != 1 || n.State() != StateRunning {
+		t.Fatalf("expected the peer to be started once, got %d starts and %s", started, n.State())
+	}
+	status, err := n.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != StateRunning || status.PID == 0 {
+		t.Errorf("unexpected status %+v", status)
+	}
+	err = n.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeerNodeAttach(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	n := NewPeerNode("peer0", "Org1MSP", config.NodeLimits{}, func() (*exec.Cmd, error) {
+		return nil, errors.New("the peer is attached")
+	})
+	attached, err := n.Attach()
+	if err != nil || attached {
+		t.Fatalf("expected no process to attach, got %t: %v", attached, err)
+	}
+	// the peer process left by an hlf-easy process that exited
+	cmd := exec.Command("sleep", "30")
+	err = cmd.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = cmd.Wait() }()
+	err = saveProcessRecord(KindPeer, "peer0", cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attached, err = n.Attach()
+	if err != nil || !attached {
+		t.Fatalf("expected the process to be attached, got %t: %v", attached, err)
+	}
+	status, err := n.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.PID != cmd.Process.Pid || status.State != StateRunning {
+		t.Errorf("unexpected status %+v", status)
+	}
+	err = n.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := LoadProcessRecord(KindPeer, "peer0"); err != nil || r != nil {
+		t.Errorf("expected the record to be removed, got %+v: %v", r, err)
+	}
+}
+
+func TestCheckManager(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	// the node and the hlf-easy process managing it are running
+	var pids []int
+	for i := 0; i < 2; i++ {
+		cmd := exec.Command("sleep", "30")
+		err := cmd.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		})
+		pids = append(pids, cmd.Process.Pid)
+	}
+	err := saveProcessRecord(KindOrderer, "orderer0", pids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckManager(KindOrderer, "orderer0"); err != nil {
+		t.Fatalf("expected the node to be managed by the test, got %v", err)
+	}
+	r, err := LoadProcessRecord(KindOrderer, "orderer0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.ManagerPID = pids[1]
+	r.ManagerCreateTime, err = processCreateTime(pids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordPath, err := GetProcessRecordPath(KindOrderer, "orderer0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordBytes, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(recordPath, recordBytes, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckManager(KindOrderer, "orderer0"); !errors.Is(err, errdefs.ErrNodeAlreadyRunning) {
+		t.Fatalf("expected the node to be managed by another process, got %v", err)
+	}
+	n := NewOrdererNode("orderer0", "OrdererMSP", config.NodeLimits{}, nil)
+	if _, err := n.Attach(); err == nil {
+		t.Fatal("expected the orderer not to be attached")
+	}
+}
+
+func TestPeerNodePreStartHook(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	started := false
+	n := NewPeerNode("peer0", "Org1MSP", config.NodeLimits{}, func() (*exec.Cmd, error) {
+		started = true
+		return exec.Command("sleep", "30"), nil
+	})
+	n.SetHooks(config.NodeHooks{{Event: "pre-start", Command: "false"}})
+	if err := n.Start(); err == nil {
+		t.Fatal("expected the failed pre-start hook to keep the peer from starting")
+	}
+	if started || n.State() != StateFailed {
+		t.Fatalf("expected the peer not to be started, got %s", n.State())
+	}
+	n.SetHooks(config.NodeHooks{{Event: "pre-start", Command: "true"}, {Event: "pre-stop", Command: "false"}})
+	if err := n.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// a failed pre-stop hook doesn't keep the peer running
+	if err := n.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if n.State() != StateStopped {
+		t.Errorf("expected the peer to be stopped, got %s", n.State())
+	}
+}
@@ -0,0 +1,136 @@
+package node
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultReadyTimeout is how long a started peer has to become ready
+const DefaultReadyTimeout = 2 * time.Minute
+
+// readyInterval is the time between two polls of a starting node
+var readyInterval = time.Second
+
+// ReadyCheck tells when a started node is ready: its operations service
+// answers /healthz with 200 and its gRPC listener accepts connections
+type ReadyCheck struct {
+	Operations OperationsEndpoint
+	// Address is the listen address of the gRPC service of the node
+	Address string
+}
+
+// loopbackAddress returns the address to reach a listen address of the host,
+// an unspecified host is reached on 127.0.0.1
+func loopbackAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	if host == "" || net.ParseIP(host).IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// check polls the node once
+func (c ReadyCheck) check(ctx context.Context) error {
+	operations := c.Operations
+	operations.Address = loopbackAddress(operations.Address)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, operations.URL("/healthz"), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := operations.Client().Do(req)
+	if err != nil {
+		return errors.Wrap(err, "the operations service isn't reachable")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("the operations service answers /healthz with status %d", resp.StatusCode)
+	}
+	dialer := net.Dialer{Timeout: operationsHTTP.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", loopbackAddress(c.Address))
+	if err != nil {
+		return errors.Wrap(err, "the gRPC port isn't listening")
+	}
+	return conn.Close()
+}
+
+// Wait polls the node until it's ready or the context is done, the error of
+// the context is returned with the one of the last poll
+func (c ReadyCheck) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(readyInterval)
+	defer ticker.Stop()
+	var last error
+	for {
+		err := c.check(ctx)
+		if err == nil {
+			return nil
+		}
+		// the poll cut by the context doesn't tell why the node isn't ready
+		if last == nil || ctx.Err() == nil {
+			last = err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "%v", last)
+		case <-ticker.C:
+		}
+	}
+}
+
+// PeerReadyCheck returns the readiness check of a peer of the host started
+// with the options
+func PeerReadyCheck(peerDir string, opts config.PeerStartOptions) (ReadyCheck, error) {
+	operations, err := GetPeerOperationsEndpoint(peerDir, opts.OperationsListenAddress)
+	if err != nil {
+		return ReadyCheck{}, err
+	}
+	return ReadyCheck{Operations: operations, Address: opts.ListenAddress}, nil
+}
+
+// SetReadyCheck sets the check a started peer must pass within the timeout,
+// Start and Restart fail and stop the peer when it doesn't. It's set before
+// the peer starts
+func (n *PeerNode) SetReadyCheck(check ReadyCheck, timeout time.Duration) {
+	n.readyCheck = &check
+	n.readyTimeout = timeout
+}
+
+// waitReady waits for the started peer to be ready, a peer that isn't ready
+// within the timeout is stopped
+func (n *PeerNode) waitReady() error {
+	if n.readyCheck == nil || n.readyTimeout <= 0 {
+		return nil
+	}
+	n.mu.Lock()
+	exited := n.exited
+	n.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), n.readyTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-exited:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	err := n.readyCheck.Wait(ctx)
+	if err == nil {
+		return nil
+	}
+	select {
+	case <-exited:
+		return errors.Wrap(err, "peer node exited before it was ready")
+	default:
+	}
+	if stopErr := n.stop(); stopErr != nil {
+		log.Warnf("Failed to stop the peer node that isn't ready: %v", stopErr)
+	}
+	return errors.Wrapf(err, "peer node isn't ready after %s", n.readyTimeout)
+}
@@ -0,0 +1,90 @@
+package node
+
+import (
+	"context"
+	"hlf-easy/config"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadyCheck(t *testing.T) {
+	readyInterval = 10 * time.Millisecond
+	var healthy int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" || atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	grpc, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer grpc.Close()
+	_, port, _ := net.SplitHostPort(grpc.Addr().String())
+	check := ReadyCheck{
+		Operations: OperationsEndpoint{Address: strings.TrimPrefix(srv.URL, "http://")},
+		// the listen address of the peer is reached on 127.0.0.1
+		Address: net.JoinHostPort("0.0.0.0", port),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = check.Wait(ctx)
+	if err == nil || !strings.Contains(err.Error(), "status 503") {
+		t.Fatalf("expected the unhealthy peer not to be ready, got %v", err)
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	err = check.Wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grpc.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = check.Wait(ctx)
+	if err == nil || !strings.Contains(err.Error(), "gRPC port") {
+		t.Fatalf("expected the peer without its gRPC port not to be ready, got %v", err)
+	}
+}
+
+func TestPeerNodeNotReady(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	readyInterval = 10 * time.Millisecond
+	n := NewPeerNode("peer0", "Org1MSP", config.NodeLimits{}, func() (*exec.Cmd, error) {
+		return exec.Command("sleep", "30"), nil
+	})
+	// nothing listens on the ports of the check
+	n.SetReadyCheck(ReadyCheck{Operations: OperationsEndpoint{Address: "127.0.0.1:1"}, Address: "127.0.0.1:1"}, 200*time.Millisecond)
+	err := n.Start()
+	if err == nil {
+		t.Fatal("expected the start to fail when the peer isn't ready")
+	}
+	if n.State() != StateStopped {
+		t.Fatalf("expected the peer that isn't ready to be stopped, got %s", n.State())
+	}
+
+	// a peer that exits while it boots fails its start right away
+	n = NewPeerNode("peer0", "Org1MSP", config.NodeLimits{}, func() (*exec.Cmd, error) {
+		return exec.Command("sh", "-c", "exit 3"), nil
+	})
+	n.SetReadyCheck(ReadyCheck{Operations: OperationsEndpoint{Address: "127.0.0.1:1"}, Address: "127.0.0.1:1"}, time.Minute)
+	started := time.Now()
+	err = n.Start()
+	if err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Fatalf("expected the start to fail when the peer exits, got %v", err)
+	}
+	if time.Since(started) > 10*time.Second {
+		t.Fatal("expected the start not to wait for the timeout once the peer exited")
+	}
+}
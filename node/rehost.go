@@ -0,0 +1,161 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/plan"
+	"hlf-easy/utils"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// RehostPeerOptions are the new hosts of a peer moved to another hostname or
+// IP
+type RehostPeerOptions struct {
+	// Hosts replace the hosts of the peer, the first one is the host of its
+	// external endpoint
+	Hosts []string
+	// ExternalEndpoint is built with the first host and the port of the
+	// current endpoint when empty
+	ExternalEndpoint string
+}
+
+// RehostPeer moves a stopped peer to new hosts: its TLS certificate is issued
+// again with their SANs, and its external endpoint is updated in its
+// init.json, its core.yaml and the gossip bootstrap of the peers of its org.
+// The sign certificate is kept so the identity of the peer doesn't change. It
+// returns the previous and the new external endpoint
+func RehostPeer(ctx context.Context, peerDir string, opts RehostPeerOptions) (string, string, error) {
+	if len(opts.Hosts) == 0 {
+		return "", "", errors.New("at least one host is required")
+	}
+	if err := ValidateEndpoints(opts.Hosts, opts.ExternalEndpoint); err != nil {
+		return "", "", err
+	}
+	peerInitOpts, err := readPeerInitOptions(peerDir)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to read the init options of %s", peerDir)
+	}
+	if peerInitOpts.ExternalCA {
+		return "", "", errors.Errorf("peer %s is enrolled by an external CA, issue its TLS certificate for the new hosts and import it again", peerInitOpts.ID)
+	}
+	previous := peerExternalEndpoint(peerInitOpts)
+	// the port of the external endpoint is kept
+	if _, port, err := net.SplitHostPort(previous); err == nil {
+		if p, err := strconv.Atoi(port); err == nil {
+			peerInitOpts.ExternalPort = p
+		}
+	}
+	peerInitOpts.Hosts = opts.Hosts
+	peerInitOpts.ExternalEndpoint = opts.ExternalEndpoint
+
+	m := peerMaterial{}
+	if peerInitOpts.Local {
+		caConfig, err := utils.GetCAConfig(peerInitOpts.CAName)
+		if err != nil {
+			return "", "", err
+		}
+		err = issueLocalPeerTLS(plan.Disk, caConfig, peerInitOpts, &m)
+		if err != nil {
+			return "", "", err
+		}
+	} else {
+		tlsHosts, err := peerTLSHosts(peerInitOpts)
+		if err != nil {
+			return "", "", err
+		}
+		caTLSCert, err := os.ReadFile(peerInitOpts.CACert)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "failed to read CA TLS certificate %s", peerInitOpts.CACert)
+		}
+		m.TLSCert, m.TLSKey, _, err = enrollPeerTLS(ctx, peerInitOpts, caTLSCert, tlsHosts)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	err = writePeerTLS(peerDir, m)
+	if err != nil {
+		return "", "", err
+	}
+
+	peerInitOpts.ExternalEndpoint = peerExternalEndpoint(peerInitOpts)
+	peerInitOptsBytes, err := json.Marshal(peerInitOpts)
+	if err != nil {
+		return "", "", err
+	}
+	err = os.WriteFile(filepath.Join(peerDir, "init.json"), peerInitOptsBytes, 0644)
+	if err != nil {
+		return "", "", err
+	}
+	if peerInitOpts.MSPID != "" {
+		// the other peers of the org bootstrap from the new endpoint
+		err = wireOrgGossip(plan.Disk, peerInitOpts.MSPID, &peerInitOpts)
+	} else {
+		err = renderPeerCoreYaml(plan.Disk, peerDir, peerInitOpts, gossipBootstrap(peerInitOpts, nil))
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return previous, peerInitOpts.ExternalEndpoint, nil
+}
+
+// writePeerTLS replaces the TLS certificate and key of a peer, in its
+// config.json too, and the ones of its operations endpoint when they're set
+func writePeerTLS(peerDir string, m peerMaterial) error {
+	peerConfigPath := filepath.Join(peerDir, "config.json")
+	peerConfigBytes, err := os.ReadFile(peerConfigPath)
+	if err != nil {
+		return err
+	}
+	peerConfig := config.PeerConfig{}
+	err = json.Unmarshal(peerConfigBytes, &peerConfig)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s", peerConfigPath)
+	}
+	peerConfig.TLSCert = utils.EncodeX509Certificate(m.TLSCert)
+	peerConfig.TLSKey = m.TLSKey
+	peerConfigBytes, err = json.MarshalIndent(peerConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		peerConfigPath:                    peerConfigBytes,
+		filepath.Join(peerDir, "tls.key"): m.TLSKey,
+		filepath.Join(peerDir, "tls.crt"): utils.EncodeX509Certificate(m.TLSCert),
+	}
+	if m.OperationsTLSCert != nil {
+		files[filepath.Join(peerDir, "operations", "tls.key")] = m.OperationsTLSKey
+		files[filepath.Join(peerDir, "operations", "tls.crt")] = utils.EncodeX509Certificate(m.OperationsTLSCert)
+		err = os.MkdirAll(filepath.Join(peerDir, "operations"), 0755)
+		if err != nil {
+			return err
+		}
+	}
+	defer utils.InvalidateConfig(peerConfigPath)
+	for path, content := range files {
+		err = os.WriteFile(path, content, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPeerEndpoints returns the external endpoint and the gossip bootstrap of
+// the init.json of a peer, they're read on every start of the peer so a
+// rehost applies when it restarts
+func GetPeerEndpoints(peerDir string) (string, []string, error) {
+	peerInitOpts, err := readPeerInitOptions(peerDir)
+	if err != nil {
+		return "", nil, err
+	}
+	bootstrap, err := GetPeerGossipBootstrap(peerInitOpts)
+	if err != nil {
+		return "", nil, err
+	}
+	return peerInitOpts.ExternalEndpoint, bootstrap, nil
+}
@@ -0,0 +1,79 @@
+package node
+
+import (
+	"context"
+	"hlf-easy/config"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRehostPeer(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer0", Hosts: []string{"node1"}, ExternalPort: 7061, MSPID: "Org1MSP"})
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer1", Hosts: []string{"node2"}, MSPID: "Org1MSP"})
+	peer0Dir := filepath.Join(home, "hlf-easy/peers/peer0")
+	signCert, err := os.ReadFile(filepath.Join(peer0Dir, "signcerts/cert.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous, endpoint, err := RehostPeer(context.Background(), peer0Dir, RehostPeerOptions{Hosts: []string{"new.example.com", "10.0.0.3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != "node1:7061" || endpoint != "new.example.com:7061" {
+		t.Fatalf("expected to move from node1:7061 to new.example.com:7061, got %s to %s", previous, endpoint)
+	}
+	tlsCertBytes, err := os.ReadFile(filepath.Join(peer0Dir, "tls.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsCert, err := utils.ParseX509Certificate(tlsCertBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tlsCert.DNSNames, []string{"new.example.com"}) || len(tlsCert.IPAddresses) != 1 || tlsCert.IPAddresses[0].String() != "10.0.0.3" {
+		t.Fatalf("expected the SANs of the new hosts, got %v %v", tlsCert.DNSNames, tlsCert.IPAddresses)
+	}
+	// the identity of the peer doesn't change
+	newSignCert, err := os.ReadFile(filepath.Join(peer0Dir, "signcerts/cert.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(newSignCert) != string(signCert) {
+		t.Fatal("expected the sign certificate to be kept")
+	}
+
+	externalEndpoint, bootstrap, err := GetPeerEndpoints(peer0Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if externalEndpoint != "new.example.com:7061" || !reflect.DeepEqual(bootstrap, []string{"node2:7051"}) {
+		t.Fatalf("unexpected endpoints %s %v", externalEndpoint, bootstrap)
+	}
+	coreYaml, err := os.ReadFile(filepath.Join(peer0Dir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(coreYaml), "externalEndpoint: new.example.com:7061\n") {
+		t.Fatal("expected the new external endpoint in core.yaml")
+	}
+	// the other peers of the org bootstrap from the new endpoint
+	coreYaml, err = os.ReadFile(filepath.Join(home, "hlf-easy/peers/peer1/core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(coreYaml), "bootstrap: new.example.com:7061\n") {
+		t.Fatal("expected peer1 to bootstrap from the new endpoint of peer0")
+	}
+
+	_, _, err = RehostPeer(context.Background(), peer0Dir, RehostPeerOptions{})
+	if err == nil {
+		t.Fatal("expected an error without hosts")
+	}
+}
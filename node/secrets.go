@@ -0,0 +1,181 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/profile"
+	"hlf-easy/secrets"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+)
+
+// getNodeSecrets returns the URL of the secrets manager holding the signing
+// key of a node, it's empty when the key is in its keystore
+func getNodeSecrets(nodeDir string) (string, error) {
+	nodeConfigPath := filepath.Join(nodeDir, "config.json")
+	nodeConfigBytes, err := os.ReadFile(nodeConfigPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	nodeConfig := config.PeerConfig{}
+	err = json.Unmarshal(nodeConfigBytes, &nodeConfig)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse %s", nodeConfigPath)
+	}
+	return nodeConfig.Secrets, nil
+}
+
+// findNodeDir returns the directory of a peer or an orderer
+func findNodeDir(kind string, id string) (string, error) {
+	if kind != "peer" && kind != "orderer" {
+		return "", errors.Errorf("invalid kind %q, expected peer or orderer", kind)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, "hlf-easy", kind+"s", id)
+	if _, err := os.Stat(filepath.Join(dir, "config.json")); err != nil {
+		return "", errors.Errorf("%s %s does not exist", kind, id)
+	}
+	return dir, nil
+}
+
+// MoveSignKey moves the signing key of a peer or an orderer to a secrets
+// manager, it's removed from its keystore and its config.json. An empty to
+// moves the key back to the keystore and removes it from the secrets manager
+func MoveSignKey(ctx context.Context, kind string, id string, to string) error {
+	dir, err := findNodeDir(kind, id)
+	if err != nil {
+		return err
+	}
+	nodeConfigPath := filepath.Join(dir, "config.json")
+	nodeConfigBytes, err := os.ReadFile(nodeConfigPath)
+	if err != nil {
+		return err
+	}
+	nodeConfig := config.PeerConfig{}
+	err = json.Unmarshal(nodeConfigBytes, &nodeConfig)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s", nodeConfigPath)
+	}
+	from := nodeConfig.Secrets
+	if from == to {
+		if to == "" {
+			return errors.Errorf("the signing key of %s %s is already in its keystore", kind, id)
+		}
+		return errors.Errorf("the signing key of %s %s is already in %s", kind, id, to)
+	}
+	name := secrets.KeyName(kind, id, secrets.SignKey)
+	keyPath := filepath.Join(dir, "keystore", "key.pem")
+	signKey := nodeConfig.SignKey
+	if from != "" {
+		signKey, err = secrets.Fetch(from, name)
+		if err != nil {
+			return err
+		}
+	}
+	if to != "" {
+		err = storeSignKey(ctx, to, kind, id, signKey)
+		if err != nil {
+			return err
+		}
+		nodeConfig.SignKey = nil
+	} else {
+		err = os.WriteFile(keyPath, signKey, 0600)
+		if err != nil {
+			return err
+		}
+		nodeConfig.SignKey = signKey
+	}
+	nodeConfig.Secrets = to
+	nodeConfigBytes, err = json.MarshalIndent(nodeConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(nodeConfigPath, nodeConfigBytes, 0644)
+	utils.InvalidateConfig(nodeConfigPath)
+	if err != nil {
+		return err
+	}
+	if to != "" {
+		err = os.Remove(keyPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if from == "" {
+		return nil
+	}
+	// the key is only removed from the previous store once the node uses the
+	// new one
+	store, err := secrets.Open(from)
+	if err != nil {
+		return err
+	}
+	err = store.Delete(ctx, name)
+	if err != nil && !errors.Is(err, secrets.ErrNotFound) {
+		return errors.Wrapf(err, "failed to remove the signing key of %s %s from %s", kind, id, from)
+	}
+	return nil
+}
+
+// storeSignKey writes the signing key of a peer or an orderer to a secrets
+// manager
+func storeSignKey(ctx context.Context, secretsURL string, kind string, id string, signKey []byte) error {
+	store, err := secrets.Open(secretsURL)
+	if err != nil {
+		return err
+	}
+	err = store.Put(ctx, secrets.KeyName(kind, id, secrets.SignKey), signKey)
+	return errors.Wrapf(err, "failed to store the signing key of %s %s", kind, id)
+}
+
+// FetchSignKey writes the signing key of a peer or an orderer kept in a
+// secrets manager to a keystore of the runtime directory of the user, for
+// the process of the node. It returns the keystore and a function removing
+// it once the process exits, the keystore is empty when the key is in the
+// directory of the node
+func FetchSignKey(kind string, id string) (string, func(), error) {
+	dir, err := findNodeDir(kind, id)
+	if err != nil {
+		return "", func() {}, err
+	}
+	secretsURL, err := getNodeSecrets(dir)
+	if err != nil || secretsURL == "" {
+		return "", func() {}, err
+	}
+	signKey, err := secrets.Fetch(secretsURL, secrets.KeyName(kind, id, secrets.SignKey))
+	if err != nil {
+		return "", func() {}, errors.Wrapf(err, "failed to fetch the signing key of %s %s", kind, id)
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = os.TempDir()
+	}
+	// the nodes of two profiles can have the same ID
+	keystoreDir := filepath.Join(runtimeDir, "hlf-easy", profile.Current(), kind+"s", id, "keystore")
+	err = os.MkdirAll(keystoreDir, 0700)
+	if err != nil {
+		return "", func() {}, err
+	}
+	// a directory left by another user isn't trusted with the key
+	err = os.Chmod(keystoreDir, 0700)
+	if err != nil {
+		return "", func() {}, err
+	}
+	err = os.WriteFile(filepath.Join(keystoreDir, "key.pem"), signKey, 0600)
+	if err != nil {
+		return "", func() {}, err
+	}
+	remove := func() {
+		_ = os.RemoveAll(keystoreDir)
+	}
+	return keystoreDir, remove, nil
+}
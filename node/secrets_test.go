@@ -0,0 +1,108 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"hlf-easy/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestVault serves the KV version 2 engine of Vault mounted on secret
+func newTestVault(t *testing.T) map[string]json.RawMessage {
+	t.Helper()
+	var mu sync.Mutex
+	kv := map[string]json.RawMessage{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		kind, name, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/secret/"), "/")
+		switch {
+		case r.Method == http.MethodPost && kind == "data":
+			body := struct {
+				Data json.RawMessage `json:"data"`
+			}{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			kv[name] = body.Data
+		case r.Method == http.MethodGet && kind == "data" && kv[name] != nil:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": kv[name]}})
+		case r.Method == http.MethodDelete && kind == "metadata":
+			delete(kv, name)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "token")
+	return kv
+}
+
+func TestMoveSignKey(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	kv := newTestVault(t)
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer0", Hosts: []string{"localhost"}, MSPID: "Org1MSP"})
+	peerDir := filepath.Join(home, "hlf-easy/peers/peer0")
+	keyPath := filepath.Join(peerDir, "keystore/key.pem")
+	signKey, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := MoveSignKey(ctx, "peer", "peer0", "vault://secret/hlf-easy"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(keyPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the keystore to be emptied, got %v", err)
+	}
+	peerConfigBytes, err := os.ReadFile(filepath.Join(peerDir, "config.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerConfig := config.PeerConfig{}
+	if err := json.Unmarshal(peerConfigBytes, &peerConfig); err != nil {
+		t.Fatal(err)
+	}
+	if peerConfig.SignKey != nil || peerConfig.Secrets != "vault://secret/hlf-easy" || len(kv) != 1 {
+		t.Fatalf("expected the key in Vault only, got %s", peerConfigBytes)
+	}
+	if err := MoveSignKey(ctx, "peer", "peer0", "vault://secret/hlf-easy"); err == nil {
+		t.Fatal("expected a move to the same secrets manager to fail")
+	}
+
+	// the peer is started with the key in a keystore of the runtime directory
+	keystoreDir, remove, err := FetchSignKey("peer", "peer0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched, err := os.ReadFile(filepath.Join(keystoreDir, "key.pem"))
+	if err != nil || !bytes.Equal(fetched, signKey) {
+		t.Fatalf("expected the signing key in %s, got %v", keystoreDir, err)
+	}
+	remove()
+	if _, err := os.Stat(keystoreDir); !os.IsNotExist(err) {
+		t.Fatalf("expected the keystore to be removed, got %v", err)
+	}
+
+	if err := MoveSignKey(ctx, "peer", "peer0", ""); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := os.ReadFile(keyPath)
+	if err != nil || !bytes.Equal(restored, signKey) || len(kv) != 0 {
+		t.Fatalf("expected the key back in the keystore only, got %v, %d secrets", err, len(kv))
+	}
+	keystoreDir, _, err = FetchSignKey("peer", "peer0")
+	if err != nil || keystoreDir != "" {
+		t.Fatalf("expected no keystore for a key on disk, got %q, %v", keystoreDir, err)
+	}
+}
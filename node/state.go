@@ -0,0 +1,41 @@
+package node
+
+import (
+	"github.com/pkg/errors"
+)
+
+// State is the lifecycle state of the process of a node managed by hlf-easy
+type State string
+
+const (
+	StateStopped  State = "Stopped"
+	StateStarting State = "Starting"
+	StateRunning  State = "Running"
+	StateStopping State = "Stopping"
+	// StateFailed is a process that failed to start or exited without being
+	// stopped, it can be started again
+	StateFailed State = "Failed"
+)
+
+// transitions are the states a node can move to from each state
+var transitions = map[State][]State{
+	StateStopped: {StateStarting},
+	// a failed node waiting to be restarted is stopped to cancel it
+	StateFailed:   {StateStarting, StateStopped},
+	StateStarting: {StateRunning, StateFailed},
+	StateRunning:  {StateStopping, StateFailed},
+	// a process that can't be signaled keeps running
+	StateStopping: {StateStopped, StateRunning},
+}
+
+// Transition moves a state to another one, the state is left as is when the
+// move isn't allowed
+func Transition(state *State, to State) error {
+	for _, next := range transitions[*state] {
+		if next == to {
+			*state = to
+			return nil
+		}
+	}
+	return errors.Errorf("invalid node state transition from %s to %s", *state, to)
+}
@@ -0,0 +1,77 @@
+package node
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+)
+
+// TLS versions of the TLS policies, the peers and orderers of Fabric pin
+// their gRPC listeners to TLS 1.2
+const (
+	TLSVersion12 = "1.2"
+	TLSVersion13 = "1.3"
+)
+
+// TLS curves of the TLS policies, the curves of the ECDSA keys Fabric
+// supports
+const (
+	CurveP256 = "P-256"
+	CurveP384 = "P-384"
+)
+
+// ValidateTLSPolicy checks the TLS policy of a node against what the Fabric
+// listeners support
+func ValidateTLSPolicy(opts config.TLSPolicyOptions) error {
+	switch opts.MinVersion {
+	case "", TLSVersion12:
+	case TLSVersion13:
+		return errors.New("the peers and orderers of Fabric only negotiate TLS 1.2 on their listeners, a minimum TLS version of 1.3 can't be met")
+	case "1.0", "1.1":
+		return errors.Errorf("the peers and orderers of Fabric require TLS 1.2, a minimum TLS version of %s isn't supported", opts.MinVersion)
+	default:
+		return errors.Errorf("invalid minimum TLS version %q, expected %s", opts.MinVersion, TLSVersion12)
+	}
+	switch opts.Curve {
+	case "", CurveP256, CurveP384:
+	default:
+		return errors.Errorf("invalid TLS curve %q, Fabric supports %s and %s", opts.Curve, CurveP256, CurveP384)
+	}
+	return nil
+}
+
+// tlsCurve returns the curve of the keys of the TLS certificates of a TLS
+// policy, nil for the default of the certificates
+func tlsCurve(opts config.TLSPolicyOptions) elliptic.Curve {
+	if opts.Curve == CurveP384 {
+		return elliptic.P384()
+	}
+	return nil
+}
+
+// tlsKeySize returns the size of the keys of the TLS certificates a Fabric
+// CA enrolls for a TLS policy, 0 for the default of the Fabric CA client
+func tlsKeySize(opts config.TLSPolicyOptions) int {
+	if opts.Curve == CurveP384 {
+		return 384
+	}
+	return 0
+}
+
+// checkTLSCurve checks the key of a TLS certificate of a node is on the curve
+// of its TLS policy
+func checkTLSCurve(crt *x509.Certificate, opts config.TLSPolicyOptions) error {
+	if opts.Curve == "" {
+		return nil
+	}
+	key, ok := crt.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.Errorf("the key of the TLS certificate %s isn't an ECDSA key, the TLS policy requires %s", crt.Subject.CommonName, opts.Curve)
+	}
+	if key.Curve.Params().Name != opts.Curve {
+		return errors.Errorf("the key of the TLS certificate %s is on %s, the TLS policy requires %s", crt.Subject.CommonName, key.Curve.Params().Name, opts.Curve)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package node
+
+import (
+	"crypto/elliptic"
+	"hlf-easy/certs"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"testing"
+)
+
+func TestValidateTLSPolicy(t *testing.T) {
+	for _, opts := range []config.TLSPolicyOptions{{}, {MinVersion: "1.2"}, {Curve: "P-384"}} {
+		if err := ValidateTLSPolicy(opts); err != nil {
+			t.Errorf("unexpected error for %+v: %v", opts, err)
+		}
+	}
+	for _, opts := range []config.TLSPolicyOptions{{MinVersion: "1.3"}, {MinVersion: "1.1"}, {MinVersion: "tls12"}, {Curve: "P-521"}, {Curve: "X25519"}} {
+		if err := ValidateTLSPolicy(opts); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
+
+func TestTLSCurve(t *testing.T) {
+	caCert, caKey := testca.NewCA(t, "tlsca")
+	policy := config.TLSPolicyOptions{Curve: CurveP384}
+	crt, key, err := certs.GenerateCertificate(certs.GenerateCertificateOptions{CommonName: "peer", Curve: tlsCurve(policy)}, caCert, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Curve != elliptic.P384() {
+		t.Fatalf("expected a P-384 key, got %s", key.Curve.Params().Name)
+	}
+	if err := checkTLSCurve(crt, policy); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTLSCurve(crt, config.TLSPolicyOptions{Curve: CurveP256}); err == nil {
+		t.Fatal("expected an error for a key on another curve")
+	}
+	if err := checkTLSCurve(crt, config.TLSPolicyOptions{}); err != nil {
+		t.Fatalf("expected any curve without a policy: %v", err)
+	}
+	if tlsCurve(config.TLSPolicyOptions{}) != nil || tlsKeySize(policy) != 384 {
+		t.Fatal("expected the default curve without a policy and 384 bits keys with P-384")
+	}
+}
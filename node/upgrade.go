@@ -0,0 +1,82 @@
+package node
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/fabric"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+func readPeerInitOptions(peerDir string) (config.PeerInitOptions, error) {
+	peerInitOpts := config.PeerInitOptions{}
+	initBytes, err := os.ReadFile(filepath.Join(peerDir, "init.json"))
+	if err != nil {
+		return peerInitOpts, err
+	}
+	err = json.Unmarshal(initBytes, &peerInitOpts)
+	return peerInitOpts, err
+}
+
+// GetPeerBinary returns the path of the binary the peer is started with, the
+// one of its fabric version or the peer in the PATH
+func GetPeerBinary(peerDir string) (string, error) {
+	peerInitOpts, err := readPeerInitOptions(peerDir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return fabric.Binary("peer", peerInitOpts.FabricVersion)
+}
+
+// GetPeerFabricVersion returns the fabric version the peer runs, the version
+// of the peer in the PATH when it isn't managed by hlf-easy
+func GetPeerFabricVersion(peerDir string) (fabric.Version, error) {
+	peerInitOpts, err := readPeerInitOptions(peerDir)
+	if err != nil {
+		return fabric.Version{}, err
+	}
+	if peerInitOpts.FabricVersion != "" {
+		return fabric.ParseVersion(peerInitOpts.FabricVersion)
+	}
+	return fabric.BinaryVersion("peer")
+}
+
+// SetPeerFabricVersion records in the init.json of a peer the fabric version
+// it's started with, and returns the previous one
+func SetPeerFabricVersion(peerDir string, version string) (string, error) {
+	peerInitOpts, err := readPeerInitOptions(peerDir)
+	if err != nil {
+		return "", err
+	}
+	previous := peerInitOpts.FabricVersion
+	peerInitOpts.FabricVersion = version
+	initBytes, err := json.Marshal(peerInitOpts)
+	if err != nil {
+		return "", err
+	}
+	return previous, os.WriteFile(filepath.Join(peerDir, "init.json"), initBytes, 0644)
+}
+
+// UpgradePeerDBs drops the state and history databases of a stopped peer so
+// they're rebuilt by a new major version of the binary
+func UpgradePeerDBs(peerDir string, binary string) error {
+	return runPeerNodeCommand(peerDir, binary, "upgrade-dbs")
+}
+
+// runPeerNodeCommand runs a peer node command on the ledger of a stopped
+// peer with the binary
+func runPeerNodeCommand(peerDir string, binary string, args ...string) error {
+	cmd := exec.Command(binary, append([]string{"node"}, args...)...)
+	cmd.Env = []string{
+		fmt.Sprintf("FABRIC_CFG_PATH=%s", peerDir),
+		fmt.Sprintf("CORE_PEER_MSPCONFIGPATH=%s", peerDir),
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "failed to run peer node %s: %s", args[0], output)
+	}
+	return nil
+}
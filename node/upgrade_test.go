@@ -0,0 +1,42 @@
+package node
+
+import (
+	"hlf-easy/config"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetPeerFabricVersion(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	peerDir := writeTestPeer(t, home, config.PeerInitOptions{ID: "peer0", MSPID: "Org1MSP"})
+	binary, err := GetPeerBinary(peerDir)
+	if err != nil || binary != "peer" {
+		t.Fatalf("expected the peer in the PATH, got %s: %v", binary, err)
+	}
+
+	previous, err := SetPeerFabricVersion(peerDir, "2.5.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous != "" {
+		t.Errorf("expected no previous version, got %s", previous)
+	}
+	binary, err = GetPeerBinary(peerDir)
+	if err != nil || binary != filepath.Join(home, "hlf-easy/bin/fabric-2.5.4/peer") {
+		t.Fatalf("expected the managed binary, got %s: %v", binary, err)
+	}
+	version, err := GetPeerFabricVersion(peerDir)
+	if err != nil || version.String() != "2.5.4" {
+		t.Fatalf("expected version 2.5.4, got %s: %v", version, err)
+	}
+	peerInitOpts, err := readPeerInitOptions(peerDir)
+	if err != nil || peerInitOpts.MSPID != "Org1MSP" {
+		t.Fatalf("expected the init options to be kept, got %+v: %v", peerInitOpts, err)
+	}
+
+	previous, err = SetPeerFabricVersion(peerDir, "")
+	if err != nil || previous != "2.5.4" {
+		t.Fatalf("expected the previous version 2.5.4, got %s: %v", previous, err)
+	}
+}
@@ -0,0 +1,325 @@
+package node
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/errdefs"
+	"hlf-easy/secrets"
+	"hlf-easy/utils"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// Check is the result of a check of the directory of a node
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// peerLayout are the files the peer needs to start
+var peerLayout = []string{
+	"keystore/key.pem",
+	"signcerts/cert.pem",
+	"cacerts/cacert.pem",
+	"tlscacerts/cacert.pem",
+	"config.yaml",
+	"tls.key",
+	"tls.crt",
+	"core.yaml",
+}
+
+// mspConfig is the NodeOUs part of the config.yaml of an MSP
+type mspConfig struct {
+	NodeOUs struct {
+		Enable              bool          `yaml:"Enable"`
+		ClientOUIdentifier  *ouIdentifier `yaml:"ClientOUIdentifier"`
+		PeerOUIdentifier    *ouIdentifier `yaml:"PeerOUIdentifier"`
+		AdminOUIdentifier   *ouIdentifier `yaml:"AdminOUIdentifier"`
+		OrdererOUIdentifier *ouIdentifier `yaml:"OrdererOUIdentifier"`
+	} `yaml:"NodeOUs"`
+}
+
+type ouIdentifier struct {
+	Certificate                  string `yaml:"Certificate"`
+	OrganizationalUnitIdentifier string `yaml:"OrganizationalUnitIdentifier"`
+}
+
+// ValidatePeer checks the directory of a peer before it's started: the MSP
+// layout, that the certificates match their keys and chain to the CAs of the
+// MSP, the NodeOUs, core.yaml and that the addresses are free
+func ValidatePeer(peerID string, addresses []string) ([]Check, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	peerDir := filepath.Join(home, "hlf-easy/peers", peerID)
+	if _, err := os.Stat(peerDir); err != nil {
+		return nil, errdefs.Errorf(errdefs.ErrNodeNotFound, "peer %s does not exist, initialize it with hlf-easy peer init --id=%s", peerID, peerID)
+	}
+	now := time.Now()
+	layout := peerLayout
+	signCertPath := filepath.Join(peerDir, "signcerts/cert.pem")
+	var signCert *x509.Certificate
+	var signCheck Check
+	// the signing key kept in a secrets manager is fetched to be checked
+	secretsURL, err := getNodeSecrets(peerDir)
+	if err != nil {
+		return nil, err
+	}
+	if secretsURL != "" {
+		// the keystore is empty then
+		layout = peerLayout[1:]
+		signKeyName := secrets.KeyName("peer", peerID, secrets.SignKey)
+		signKey, err := secrets.Fetch(secretsURL, signKeyName)
+		signCert, signCheck = matchKeyPair("msp-keypair", signCertPath, signKey, err, signKeyName)
+	} else {
+		signCert, signCheck = checkKeyPair("msp-keypair", signCertPath, filepath.Join(peerDir, "keystore/key.pem"))
+	}
+	checks := []Check{checkLayout(peerDir, layout)}
+	tlsCert, tlsCheck := checkKeyPair("tls-keypair", filepath.Join(peerDir, "tls.crt"), filepath.Join(peerDir, "tls.key"))
+	checks = append(checks, signCheck, tlsCheck)
+	checks = append(checks,
+		checkChain("msp-chain", signCert, filepath.Join(peerDir, "cacerts"), filepath.Join(peerDir, "intermediatecerts"), "", now),
+		checkChain("tls-chain", tlsCert, filepath.Join(peerDir, "tlscacerts"), filepath.Join(peerDir, "tlsintermediatecerts"), filepath.Join(peerDir, "tls.crt"), now),
+		checkNodeOUs(peerDir, signCert),
+		checkCoreYaml(peerDir),
+	)
+	checks = append(checks, checkAddresses(peerDir, addresses)...)
+	return checks, nil
+}
+
+func checkLayout(peerDir string, layout []string) Check {
+	var missing []string
+	for _, file := range layout {
+		if _, err := os.Stat(filepath.Join(peerDir, file)); err != nil {
+			missing = append(missing, file)
+		}
+	}
+	if len(missing) > 0 {
+		return Check{Name: "layout", Status: CheckFail, Message: fmt.Sprintf("missing %s, initialize the peer again or restore it from a backup", strings.Join(missing, ", "))}
+	}
+	return Check{Name: "layout", Status: CheckPass, Message: "the MSP and TLS files are present"}
+}
+
+// checkKeyPair checks that the first certificate of the file matches the key
+func checkKeyPair(name string, certPath string, keyPath string) (*x509.Certificate, Check) {
+	keyPem, err := os.ReadFile(keyPath)
+	return matchKeyPair(name, certPath, keyPem, err, keyPath)
+}
+
+// matchKeyPair checks that the first certificate of the file matches a key
+// read from keyPath, a file or a secret, or the error reading it
+func matchKeyPair(name string, certPath string, keyPem []byte, keyErr error, keyPath string) (*x509.Certificate, Check) {
+	certPem, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, Check{Name: name, Status: CheckFail, Message: err.Error()}
+	}
+	// the chain parser doesn't panic on files that aren't PEM
+	chain, err := utils.ParseX509CertificateChain(certPem)
+	if err != nil {
+		return nil, Check{Name: name, Status: CheckFail, Message: fmt.Sprintf("failed to parse %s: %v", certPath, err)}
+	}
+	crt := chain[0]
+	if keyErr != nil {
+		return crt, Check{Name: name, Status: CheckFail, Message: keyErr.Error()}
+	}
+	if block, _ := pem.Decode(keyPem); block == nil {
+		return crt, Check{Name: name, Status: CheckFail, Message: fmt.Sprintf("%s is not a PEM key", keyPath)}
+	}
+	key, err := utils.ParseECDSAPrivateKey(keyPem)
+	if err != nil {
+		return crt, Check{Name: name, Status: CheckFail, Message: fmt.Sprintf("failed to parse %s: %v", keyPath, err)}
+	}
+	pub, ok := crt.PublicKey.(*ecdsa.PublicKey)
+	if !ok || !pub.Equal(key.Public()) {
+		return crt, Check{Name: name, Status: CheckFail, Message: fmt.Sprintf("%s doesn't match the key %s, re-enroll the peer", certPath, keyPath)}
+	}
+	return crt, Check{Name: name, Status: CheckPass, Message: fmt.Sprintf("%s matches its key", filepath.Base(certPath))}
+}
+
+// checkChain checks that the certificate chains to the CAs of the directory
+// through the intermediates, the extra intermediates are the ones appended to
+// the certificate file
+func checkChain(name string, crt *x509.Certificate, caDir string, intermediatesDir string, chainPath string, now time.Time) Check {
+	if crt == nil {
+		return Check{Name: name, Status: CheckFail, Message: "no certificate to verify"}
+	}
+	roots, err := readCertsDir(caDir)
+	if err != nil {
+		return Check{Name: name, Status: CheckFail, Message: err.Error()}
+	}
+	if len(roots) == 0 {
+		return Check{Name: name, Status: CheckFail, Message: fmt.Sprintf("no CA certificate in %s", caDir)}
+	}
+	intermediates, err := readCertsDir(intermediatesDir)
+	if err != nil {
+		return Check{Name: name, Status: CheckFail, Message: err.Error()}
+	}
+	if chainPath != "" {
+		chainPem, err := os.ReadFile(chainPath)
+		if err != nil {
+			return Check{Name: name, Status: CheckFail, Message: err.Error()}
+		}
+		chain, err := utils.ParseX509CertificateChain(chainPem)
+		if err != nil {
+			return Check{Name: name, Status: CheckFail, Message: err.Error()}
+		}
+		intermediates = append(intermediates, chain[1:]...)
+	}
+	rootPool := x509.NewCertPool()
+	for _, root := range roots {
+		rootPool.AddCert(root)
+	}
+	intermediatePool := x509.NewCertPool()
+	for _, intermediate := range intermediates {
+		intermediatePool.AddCert(intermediate)
+	}
+	_, err = crt.Verify(x509.VerifyOptions{
+		Roots:         rootPool,
+		Intermediates: intermediatePool,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return Check{Name: name, Status: CheckFail, Message: fmt.Sprintf("%s doesn't chain to %s: %v", crt.Subject.CommonName, caDir, err)}
+	}
+	if crt.NotAfter.Sub(now) < 30*24*time.Hour {
+		return Check{Name: name, Status: CheckWarn, Message: fmt.Sprintf("%s expires on %s, renew it", crt.Subject.CommonName, crt.NotAfter.Format(time.RFC3339))}
+	}
+	return Check{Name: name, Status: CheckPass, Message: fmt.Sprintf("%s chains to %s", crt.Subject.CommonName, filepath.Base(caDir))}
+}
+
+// readCertsDir parses the PEM files of a directory, a missing directory has
+// no certificates
+func readCertsDir(dir string) ([]*x509.Certificate, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	var crts []*x509.Certificate
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		chain, err := utils.ParseX509CertificateChain(contents)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", file)
+		}
+		crts = append(crts, chain...)
+	}
+	return crts, nil
+}
+
+// checkNodeOUs checks that the NodeOUs are enabled, point to a CA of the MSP
+// that issued the certificate of the peer, and that it has the peer OU
+func checkNodeOUs(peerDir string, signCert *x509.Certificate) Check {
+	contents, err := os.ReadFile(filepath.Join(peerDir, "config.yaml"))
+	if err != nil {
+		return Check{Name: "node-ous", Status: CheckFail, Message: err.Error()}
+	}
+	c := mspConfig{}
+	err = yaml.Unmarshal(contents, &c)
+	if err != nil {
+		return Check{Name: "node-ous", Status: CheckFail, Message: fmt.Sprintf("failed to parse config.yaml: %v", err)}
+	}
+	if !c.NodeOUs.Enable {
+		return Check{Name: "node-ous", Status: CheckFail, Message: "the NodeOUs of config.yaml aren't enabled, the peer can't be told apart from the clients of the org"}
+	}
+	identifiers := map[string]*ouIdentifier{
+		"ClientOUIdentifier":  c.NodeOUs.ClientOUIdentifier,
+		"PeerOUIdentifier":    c.NodeOUs.PeerOUIdentifier,
+		"AdminOUIdentifier":   c.NodeOUs.AdminOUIdentifier,
+		"OrdererOUIdentifier": c.NodeOUs.OrdererOUIdentifier,
+	}
+	for _, name := range []string{"ClientOUIdentifier", "PeerOUIdentifier", "AdminOUIdentifier", "OrdererOUIdentifier"} {
+		identifier := identifiers[name]
+		if identifier == nil || identifier.OrganizationalUnitIdentifier == "" {
+			return Check{Name: "node-ous", Status: CheckFail, Message: fmt.Sprintf("%s of config.yaml is missing", name)}
+		}
+		if identifier.Certificate == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(peerDir, identifier.Certificate)); err != nil {
+			return Check{Name: "node-ous", Status: CheckFail, Message: fmt.Sprintf("the certificate %s of the %s doesn't exist", identifier.Certificate, name)}
+		}
+	}
+	if signCert == nil {
+		return Check{Name: "node-ous", Status: CheckFail, Message: "no certificate to check the peer OU of"}
+	}
+	peerOU := c.NodeOUs.PeerOUIdentifier
+	if !utils.Contains(signCert.Subject.OrganizationalUnit, peerOU.OrganizationalUnitIdentifier) {
+		return Check{Name: "node-ous", Status: CheckFail, Message: fmt.Sprintf("the certificate of the peer has the OUs %v, not %s", signCert.Subject.OrganizationalUnit, peerOU.OrganizationalUnitIdentifier)}
+	}
+	if peerOU.Certificate != "" {
+		ouCertPem, err := os.ReadFile(filepath.Join(peerDir, peerOU.Certificate))
+		if err != nil {
+			return Check{Name: "node-ous", Status: CheckFail, Message: err.Error()}
+		}
+		ouCerts, err := utils.ParseX509CertificateChain(ouCertPem)
+		if err != nil {
+			return Check{Name: "node-ous", Status: CheckFail, Message: fmt.Sprintf("failed to parse %s: %v", peerOU.Certificate, err)}
+		}
+		ouCert := ouCerts[0]
+		if !bytes.Equal(signCert.RawIssuer, ouCert.RawSubject) || signCert.CheckSignatureFrom(ouCert) != nil {
+			return Check{Name: "node-ous", Status: CheckFail, Message: fmt.Sprintf("the NodeOUs point to %s which didn't issue the certificate of the peer", peerOU.Certificate)}
+		}
+	}
+	return Check{Name: "node-ous", Status: CheckPass, Message: fmt.Sprintf("the certificate of the peer has the %s OU", peerOU.OrganizationalUnitIdentifier)}
+}
+
+func checkCoreYaml(peerDir string) Check {
+	contents, err := os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		return Check{Name: "core-yaml", Status: CheckFail, Message: err.Error()}
+	}
+	coreYaml := map[string]interface{}{}
+	err = yaml.Unmarshal(contents, &coreYaml)
+	if err != nil {
+		return Check{Name: "core-yaml", Status: CheckFail, Message: fmt.Sprintf("failed to parse core.yaml: %v", err)}
+	}
+	for _, section := range []string{"peer", "chaincode", "ledger"} {
+		if _, ok := coreYaml[section]; !ok {
+			return Check{Name: "core-yaml", Status: CheckFail, Message: fmt.Sprintf("core.yaml has no %s section, upgrade the peer to write it again", section)}
+		}
+	}
+	return Check{Name: "core-yaml", Status: CheckPass, Message: "core.yaml parses"}
+}
+
+// checkAddresses checks that the addresses the peer listens on are free, they
+// are in use by the peer itself while it runs
+func checkAddresses(peerDir string, addresses []string) []Check {
+	if _, err := os.Stat(filepath.Join(peerDir, "run.json")); err == nil {
+		return []Check{{Name: "ports", Status: CheckWarn, Message: "the peer is running, its addresses weren't checked"}}
+	}
+	var checks []Check
+	for _, address := range addresses {
+		name := fmt.Sprintf("port %s", address)
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			checks = append(checks, Check{Name: name, Status: CheckFail, Message: fmt.Sprintf("%s is in use or can't be bound: %v", address, err)})
+			continue
+		}
+		err = l.Close()
+		if err != nil {
+			return append(checks, Check{Name: name, Status: CheckFail, Message: err.Error()})
+		}
+		checks = append(checks, Check{Name: name, Status: CheckPass, Message: fmt.Sprintf("%s is free", address)})
+	}
+	return checks
+}
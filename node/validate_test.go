@@ -0,0 +1,103 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"hlf-easy/utils"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func checkStatuses(checks []Check) map[string]string {
+	statuses := map[string]string{}
+	for _, check := range checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+// initTestPeer enrolls a peer with the local CA org1-ca, created the first time
+func initTestPeer(t *testing.T, home string, peerInitOpts config.PeerInitOptions) {
+	t.Helper()
+	caDir := filepath.Join(home, "hlf-easy/cas/org1-ca")
+	if _, err := os.Stat(caDir); os.IsNotExist(err) {
+		caCert, caKey := testca.NewCA(t, "ca")
+		tlsCACert, tlsCAKey := testca.NewCA(t, "tlsca")
+		caConfigBytes, err := json.Marshal(config.CAConfig{
+			CaCert:    utils.EncodeX509Certificate(caCert),
+			CaKey:     encodeTestKey(t, caKey),
+			CaName:    "org1-ca",
+			TlsCACert: utils.EncodeX509Certificate(tlsCACert),
+			TlsCAKey:  encodeTestKey(t, tlsCAKey),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(caDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(caDir, "config.json"), caConfigBytes, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	peerInitOpts.Local = true
+	peerInitOpts.CAName = "org1-ca"
+	err := EnrollPeerCertificates(context.Background(), peerInitOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidatePeer(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	initTestPeer(t, home, config.PeerInitOptions{ID: "peer0", Hosts: []string{"localhost"}, MSPID: "Org1MSP"})
+
+	checks, err := ValidatePeer("peer0", []string{"127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range checks {
+		// the test CAs expire within the hour, the chains only warn
+		if check.Status == CheckFail {
+			t.Errorf("expected %s to pass, got %s: %s", check.Name, check.Status, check.Message)
+		}
+	}
+
+	// a port in use, a TLS key of another certificate and a core.yaml that
+	// doesn't parse fail
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, otherKey := testca.NewCA(t, "other")
+	peerDir := filepath.Join(home, "hlf-easy/peers/peer0")
+	if err := os.WriteFile(filepath.Join(peerDir, "tls.key"), encodeTestKey(t, otherKey), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(peerDir, "core.yaml"), []byte("peer: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	checks, err = ValidatePeer("peer0", []string{l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := checkStatuses(checks)
+	for _, name := range []string{"tls-keypair", "core-yaml", "port " + l.Addr().String()} {
+		if statuses[name] != CheckFail {
+			t.Errorf("expected %s to fail, got %v", name, statuses)
+		}
+	}
+	if statuses["msp-keypair"] != CheckPass || statuses["node-ous"] != CheckPass {
+		t.Errorf("expected the MSP checks to pass, got %v", statuses)
+	}
+
+	if _, err := ValidatePeer("peer1", nil); err == nil {
+		t.Fatal("expected an error for a peer that doesn't exist")
+	}
+}
@@ -0,0 +1,221 @@
+package node
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/fabric"
+	"hlf-easy/plan"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultVMCheckTimeout bounds the check of the docker daemon of a peer on
+// peer init
+const DefaultVMCheckTimeout = 10 * time.Second
+
+// vmTLSFile is a TLS file of the docker daemon of a peer, copied to its
+// docker directory where its core.yaml reads it from
+type vmTLSFile struct {
+	name        string
+	description string
+	src         string
+}
+
+// getVMTLSFiles returns the TLS files of the docker daemon of a peer
+func getVMTLSFiles(opts config.VMOptions) []vmTLSFile {
+	return []vmTLSFile{
+		{name: "ca.crt", description: "CA certificate", src: opts.TLSCACert},
+		{name: "tls.crt", description: "client certificate", src: opts.TLSCert},
+		{name: "tls.key", description: "client key", src: opts.TLSKey},
+	}
+}
+
+// ValidateVM checks the docker settings of a peer
+func ValidateVM(opts config.VMOptions) error {
+	if opts.Endpoint == "" {
+		if opts.TLS || opts.AttachStdout {
+			return errors.New("the docker TLS and attach stdout settings require the docker endpoint")
+		}
+		return nil
+	}
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return errors.Wrapf(err, "invalid docker endpoint %q", opts.Endpoint)
+	}
+	switch u.Scheme {
+	case "unix":
+		if u.Path == "" {
+			return errors.Errorf("invalid docker endpoint %q, expected unix:///path/to/docker.sock", opts.Endpoint)
+		}
+		if opts.TLS {
+			return errors.New("the docker TLS requires a tcp:// or https:// endpoint")
+		}
+	case "tcp", "http", "https":
+		if u.Host == "" {
+			return errors.Errorf("invalid docker endpoint %q, expected %s://<host>:<port>", opts.Endpoint, u.Scheme)
+		}
+	default:
+		return errors.Errorf("invalid docker endpoint %q, expected a unix://, tcp://, http:// or https:// URL", opts.Endpoint)
+	}
+	if !opts.TLS {
+		if opts.TLSCACert != "" || opts.TLSCert != "" || opts.TLSKey != "" {
+			return errors.New("the docker TLS files require the docker TLS")
+		}
+		return nil
+	}
+	for _, f := range getVMTLSFiles(opts) {
+		if f.src == "" {
+			return errors.Errorf("the docker TLS requires its %s", f.description)
+		}
+	}
+	return nil
+}
+
+// writeVMTLS copies the TLS files of the docker daemon of a peer to its
+// docker directory, the relative paths of its core.yaml
+func writeVMTLS(w plan.Writer, peerDir string, opts config.VMOptions) error {
+	if !opts.TLS {
+		return nil
+	}
+	dockerDir := filepath.Join(peerDir, "docker")
+	err := w.MkdirAll(dockerDir, 0755)
+	if err != nil {
+		return err
+	}
+	for _, f := range getVMTLSFiles(opts) {
+		content, err := os.ReadFile(f.src)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read the docker %s", f.description)
+		}
+		err = w.WriteFile(filepath.Join(dockerDir, f.name), content, 0600)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dockerHTTPClient returns a client of the API of a docker daemon and its
+// base URL
+func dockerHTTPClient(opts config.VMOptions) (*http.Client, string, error) {
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return nil, "", err
+	}
+	transport := &http.Transport{}
+	if u.Scheme == "unix" {
+		socket := u.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+		return &http.Client{Transport: transport}, "http://docker", nil
+	}
+	scheme := "http"
+	if opts.TLS || u.Scheme == "https" {
+		scheme = "https"
+	}
+	if opts.TLS {
+		caCert, err := os.ReadFile(opts.TLSCACert)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "failed to read the docker CA certificate")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, "", errors.Errorf("no certificate in the docker CA certificate %s", opts.TLSCACert)
+		}
+		clientCert, err := tls.LoadX509KeyPair(opts.TLSCert, opts.TLSKey)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "failed to load the docker client certificate")
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:      pool,
+			Certificates: []tls.Certificate{clientCert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+	return &http.Client{Transport: transport}, scheme + "://" + u.Host, nil
+}
+
+// CheckDocker checks the docker daemon of a peer answers, the peer builds
+// and runs its chaincodes with it
+func CheckDocker(ctx context.Context, opts config.VMOptions) error {
+	client, baseURL, err := dockerHTTPClient(opts)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/_ping", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to the docker daemon %s, the peer builds its chaincodes with it", opts.Endpoint)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("the docker daemon %s answered %s: %s", opts.Endpoint, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// DockerArch returns the architecture of the docker daemon of a peer, the
+// one of the images of the chaincodes it builds and runs
+func DockerArch(ctx context.Context, opts config.VMOptions) (string, error) {
+	client, baseURL, err := dockerHTTPClient(opts)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/version", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to connect to the docker daemon %s", opts.Endpoint)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("the docker daemon %s answered %s to its version", opts.Endpoint, resp.Status)
+	}
+	version := struct {
+		Arch string `json:"Arch"`
+	}{}
+	err = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&version)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid version of the docker daemon %s", opts.Endpoint)
+	}
+	return fabric.NormalizeArch(version.Arch), nil
+}
+
+// CheckDockerArch checks the chaincode images of the fabric version of a
+// peer, fabric-ccenv and fabric-baseos, are published for the architecture
+// of its docker daemon. The check is skipped when the version is unknown
+func CheckDockerArch(ctx context.Context, opts config.PeerInitOptions) error {
+	var version fabric.Version
+	var err error
+	if opts.FabricVersion != "" {
+		version, err = fabric.ParseVersion(opts.FabricVersion)
+	} else {
+		version, err = fabric.BinaryVersion("peer")
+	}
+	if err != nil {
+		return nil
+	}
+	arch, err := DockerArch(ctx, opts.VM)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(fabric.CheckImageArch(version, arch), "the docker daemon %s is %s", opts.VM.Endpoint, arch)
+}
@@ -0,0 +1,139 @@
+package node
+
+import (
+	"context"
+	"hlf-easy/config"
+	"hlf-easy/plan"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateVM(t *testing.T) {
+	valid := []config.VMOptions{
+		{},
+		{Endpoint: "unix:///var/run/docker.sock", AttachStdout: true},
+		{Endpoint: "tcp://docker.example.com:2376", TLS: true, TLSCACert: "ca.crt", TLSCert: "tls.crt", TLSKey: "tls.key"},
+		{Endpoint: "http://localhost:2375"},
+	}
+	for _, opts := range valid {
+		if err := ValidateVM(opts); err != nil {
+			t.Errorf("expected %+v to be valid: %v", opts, err)
+		}
+	}
+	invalid := []config.VMOptions{
+		{AttachStdout: true},
+		{Endpoint: "/var/run/docker.sock"},
+		{Endpoint: "unix://"},
+		{Endpoint: "tcp://"},
+		{Endpoint: "unix:///var/run/docker.sock", TLS: true, TLSCACert: "ca.crt", TLSCert: "tls.crt", TLSKey: "tls.key"},
+		{Endpoint: "tcp://docker.example.com:2376", TLS: true, TLSCACert: "ca.crt"},
+		{Endpoint: "tcp://docker.example.com:2376", TLSCACert: "ca.crt"},
+	}
+	for _, opts := range invalid {
+		if err := ValidateVM(opts); err == nil {
+			t.Errorf("expected an error for %+v", opts)
+		}
+	}
+}
+
+func TestRenderVM(t *testing.T) {
+	peerDir := t.TempDir()
+	err := renderPeerCoreYaml(plan.Disk, peerDir, config.PeerInitOptions{ID: "peer0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreYaml, err := os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(coreYaml), "endpoint: \"\"\n") || !strings.Contains(string(coreYaml), "attachStdout: false\n") {
+		t.Fatal("expected the docker builds to be disabled by default")
+	}
+
+	srcDir := t.TempDir()
+	vm := config.VMOptions{
+		Endpoint:     "tcp://docker.example.com:2376",
+		TLS:          true,
+		TLSCACert:    filepath.Join(srcDir, "ca.pem"),
+		TLSCert:      filepath.Join(srcDir, "cert.pem"),
+		TLSKey:       filepath.Join(srcDir, "key.pem"),
+		AttachStdout: true,
+	}
+	for _, f := range []string{vm.TLSCACert, vm.TLSCert, vm.TLSKey} {
+		if err := os.WriteFile(f, []byte(filepath.Base(f)), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writeVMTLS(plan.Disk, peerDir, vm); err != nil {
+		t.Fatal(err)
+	}
+	if key, err := os.ReadFile(filepath.Join(peerDir, "docker/tls.key")); err != nil || string(key) != "key.pem" {
+		t.Fatalf("expected the client key in the docker directory of the peer, got %v", err)
+	}
+	err = renderPeerCoreYaml(plan.Disk, peerDir, config.PeerInitOptions{ID: "peer0", VM: vm}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coreYaml, err = os.ReadFile(filepath.Join(peerDir, "core.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"endpoint: \"tcp://docker.example.com:2376\"\n", "tls:\n      enabled: true\n", "file: docker/tls.key\n", "attachStdout: true\n"} {
+		if !strings.Contains(string(coreYaml), expected) {
+			t.Fatalf("expected the docker setting %q", expected)
+		}
+	}
+}
+
+func TestCheckDocker(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/_ping":
+			_, _ = w.Write([]byte("OK"))
+		case "/version":
+			_, _ = w.Write([]byte(`{"Version":"24.0.7","Os":"linux","Arch":"aarch64"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	ctx := context.Background()
+	if err := CheckDocker(ctx, config.VMOptions{Endpoint: "tcp://" + srv.Listener.Addr().String()}); err != nil {
+		t.Fatal(err)
+	}
+	// the arm64 chaincode images are published from fabric 2.5
+	vm := config.VMOptions{Endpoint: "tcp://" + srv.Listener.Addr().String()}
+	if arch, err := DockerArch(ctx, vm); err != nil || arch != "arm64" {
+		t.Fatalf("expected an arm64 docker daemon, got %s: %v", arch, err)
+	}
+	if err := CheckDockerArch(ctx, config.PeerInitOptions{FabricVersion: "2.5.4", VM: vm}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDockerArch(ctx, config.PeerInitOptions{FabricVersion: "2.4.9", VM: vm}); err == nil {
+		t.Fatal("expected the fabric 2.4 images to be refused on arm64")
+	}
+
+	// the local daemon is reached on its socket
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+	unixSrv := &httptest.Server{Listener: l, Config: &http.Server{Handler: handler}}
+	unixSrv.Start()
+	defer unixSrv.Close()
+	if err := CheckDocker(ctx, config.VMOptions{Endpoint: "unix://" + socket}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Close()
+	if err := CheckDocker(ctx, config.VMOptions{Endpoint: "tcp://" + srv.Listener.Addr().String()}); err == nil {
+		t.Fatal("expected an unreachable docker daemon to fail the check")
+	}
+}
@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/log"
+	"hlf-easy/utils"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+)
+
+// Events of the nodes of the host
+const (
+	EventNodeStarted   = "node_started"
+	EventNodeCrashed   = "node_crashed"
+	EventNodeRestarted = "node_restarted"
+	EventCertExpiring  = "cert_expiring"
+	EventChannelJoined = "channel_joined"
+	EventLogAnomaly    = "log_anomaly"
+	EventTaskFailed    = "task_failed"
+	// EventAlertFired is only sent to the webhooks of the alert rules
+	EventAlertFired = "alert_fired"
+)
+
+// Events are all the events that can be notified
+var Events = []string{
+	EventNodeStarted,
+	EventNodeCrashed,
+	EventNodeRestarted,
+	EventCertExpiring,
+	EventChannelJoined,
+	EventLogAnomaly,
+	EventTaskFailed,
+}
+
+// Formats of the payload posted to the webhooks
+const (
+	FormatJSON  = "json"
+	FormatSlack = "slack"
+)
+
+// Event is posted as JSON to the webhooks
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Host string    `json:"host"`
+	// Kind and ID of the node, e.g. peer and peer1
+	Kind    string            `json:"kind,omitempty"`
+	ID      string            `json:"id,omitempty"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// NewEvent creates an event of a node of the host
+func NewEvent(eventType string, kind string, id string, message string) Event {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return Event{
+		Type:    eventType,
+		Time:    time.Now().UTC(),
+		Host:    host,
+		Kind:    kind,
+		ID:      id,
+		Message: message,
+	}
+}
+
+// slackPayload is the payload of the Slack incoming webhooks
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// ValidateWebhook checks the URL, format and events of a webhook
+func ValidateWebhook(webhook config.WebhookConfig) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil {
+		return errors.Wrapf(err, "invalid webhook URL %s", webhook.URL)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("webhook URL %s must be http or https", webhook.URL)
+	}
+	if webhook.Format != "" && webhook.Format != FormatJSON && webhook.Format != FormatSlack {
+		return errors.Errorf("invalid webhook format %s, expected %s or %s", webhook.Format, FormatJSON, FormatSlack)
+	}
+	for _, event := range webhook.Events {
+		if !utils.Contains(Events, event) {
+			return errors.Errorf("unknown event %s, expected one of %v", event, Events)
+		}
+	}
+	return nil
+}
+
+// payload returns the body posted to a webhook for an event
+func payload(webhook config.WebhookConfig, event Event) ([]byte, error) {
+	if webhook.Format == FormatSlack {
+		text := fmt.Sprintf("[%s] %s", event.Host, event.Message)
+		keys := make([]string, 0, len(event.Details))
+		for key := range event.Details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			text += fmt.Sprintf("\n%s: %s", key, event.Details[key])
+		}
+		return json.Marshal(slackPayload{Text: text})
+	}
+	return json.Marshal(event)
+}
+
+// Send posts the event to the webhooks subscribed to it
+func Send(notifyConfig config.NotifyConfig, event Event) error {
+	var errs []string
+	for _, webhook := range notifyConfig.Webhooks {
+		if len(webhook.Events) > 0 && !utils.Contains(webhook.Events, event.Type) {
+			continue
+		}
+		err := post(webhook, event)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("failed to notify %s: %v", event.Type, errs)
+	}
+	return nil
+}
+
+func post(webhook config.WebhookConfig, event Event) error {
+	body, err := payload(webhook, event)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(webhook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// the error may contain the URL, which usually embeds a secret
+		return errors.Errorf("webhook %s failed", redact(webhook.URL))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("webhook %s returned %s", redact(webhook.URL), resp.Status)
+	}
+	return nil
+}
+
+// redact removes the path and query of a webhook URL
+func redact(webhookURL string) string {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return "<invalid URL>"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// Notify posts the event to the webhooks of the host, a failure is logged
+// since notifications must not break the operation that triggered them
+func Notify(event Event) {
+	notifyConfig, err := utils.GetNotifyConfig()
+	if err != nil {
+		log.Warnf("Failed to read the notify config: %v", err)
+		return
+	}
+	err = Send(*notifyConfig, event)
+	if err != nil {
+		log.Warnf("%v", err)
+	}
+}
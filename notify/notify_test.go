@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidateWebhook(t *testing.T) {
+	valid := []config.WebhookConfig{
+		{URL: "https://hooks.example.com/services/T0/B0/X"},
+		{URL: "http://localhost:8080/events", Format: FormatSlack, Events: []string{EventNodeCrashed}},
+	}
+	for _, webhook := range valid {
+		if err := ValidateWebhook(webhook); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", webhook, err)
+		}
+	}
+	invalid := []config.WebhookConfig{
+		{URL: "ftp://example.com"},
+		{URL: "https://example.com", Format: "xml"},
+		{URL: "https://example.com", Events: []string{"node_exploded"}},
+	}
+	for _, webhook := range invalid {
+		if err := ValidateWebhook(webhook); err == nil {
+			t.Errorf("expected %+v to be invalid", webhook)
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	bodies := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies[r.URL.Path] = string(body)
+	}))
+	defer srv.Close()
+	notifyConfig := config.NotifyConfig{
+		Webhooks: []config.WebhookConfig{
+			{URL: srv.URL + "/json"},
+			{URL: srv.URL + "/slack", Format: FormatSlack},
+			{URL: srv.URL + "/certs", Events: []string{EventCertExpiring}},
+		},
+	}
+	event := NewEvent(EventNodeCrashed, "peer", "peer1", "Peer peer1 crashed")
+	event.Details = map[string]string{"exit": "exit status 2"}
+	err := Send(notifyConfig, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := bodies["/certs"]; ok {
+		t.Fatal("expected the webhook not subscribed to the event not to be posted")
+	}
+	posted := Event{}
+	err = json.Unmarshal([]byte(bodies["/json"]), &posted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posted.Type != EventNodeCrashed || posted.ID != "peer1" || posted.Details["exit"] != "exit status 2" {
+		t.Fatalf("unexpected event %+v", posted)
+	}
+	slack := slackPayload{}
+	err = json.Unmarshal([]byte(bodies["/slack"]), &slack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(slack.Text, "Peer peer1 crashed") || !strings.Contains(slack.Text, "exit: exit status 2") {
+		t.Fatalf("unexpected slack text %q", slack.Text)
+	}
+}
+
+func TestSendFailureRedactsURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	notifyConfig := config.NotifyConfig{
+		Webhooks: []config.WebhookConfig{{URL: srv.URL + "/services/secret-token"}},
+	}
+	err := Send(notifyConfig, NewEvent(EventNodeStarted, "peer", "peer1", "Peer peer1 started"))
+	if err == nil {
+		t.Fatal("expected the failed webhook to be reported")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Fatalf("expected the webhook URL to be redacted, got %v", err)
+	}
+}
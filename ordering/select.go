@@ -0,0 +1,160 @@
+package ordering
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint is an orderer endpoint with the TLS root certificates it's
+// verified with
+type Endpoint struct {
+	// Address is the host:port of the orderer
+	Address    string
+	TLSCACerts []string
+}
+
+// Probe is the result of the health check of an orderer endpoint
+type Probe struct {
+	Address string `json:"address"`
+	Healthy bool   `json:"healthy"`
+	// RTT is the fastest TLS handshake with the orderer
+	RTT   time.Duration `json:"rtt"`
+	Error string        `json:"error,omitempty"`
+}
+
+// Defaults of the probes of the orderers
+const (
+	DefaultAttempts = 3
+	DefaultTimeout  = 5 * time.Second
+)
+
+// EndpointAddress returns the host:port of an orderer URL, e.g.
+// grpcs://orderer0.example.com:7050
+func EndpointAddress(url string) string {
+	for _, scheme := range []string{"grpcs://", "grpc://"} {
+		url = strings.TrimPrefix(url, scheme)
+	}
+	return url
+}
+
+// probe checks that the orderer completes a TLS handshake with a certificate
+// of its TLS root certificates, its RTT is the fastest of the attempts
+func probe(ctx context.Context, endpoint Endpoint, attempts int, timeout time.Duration) Probe {
+	p := Probe{Address: endpoint.Address}
+	host, _, err := net.SplitHostPort(endpoint.Address)
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	rootCAs := x509.NewCertPool()
+	for _, pem := range endpoint.TLSCACerts {
+		rootCAs.AppendCertsFromPEM([]byte(pem))
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		Config: &tls.Config{
+			RootCAs:    rootCAs,
+			ServerName: host,
+			NextProtos: []string{"h2"},
+			MinVersion: tls.VersionTLS12,
+		},
+	}
+	for i := 0; i < attempts; i++ {
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", endpoint.Address)
+		if err != nil {
+			p.Error = err.Error()
+			continue
+		}
+		rtt := time.Since(start)
+		conn.Close()
+		if !p.Healthy || rtt < p.RTT {
+			p.RTT = rtt
+		}
+		p.Healthy = true
+	}
+	if p.Healthy {
+		p.Error = ""
+	}
+	return p
+}
+
+// ProbeEndpoints checks the orderer endpoints concurrently
+func ProbeEndpoints(ctx context.Context, endpoints []Endpoint, attempts int, timeout time.Duration) []Probe {
+	probes := make([]Probe, len(endpoints))
+	wg := sync.WaitGroup{}
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint Endpoint) {
+			defer wg.Done()
+			probes[i] = probe(ctx, endpoint, attempts, timeout)
+		}(i, endpoint)
+	}
+	wg.Wait()
+	return probes
+}
+
+// Rank orders the probes in the failover order: the healthy orderers, the
+// closest first, and then the unhealthy ones in their original order
+func Rank(probes []Probe) []Probe {
+	ranked := append([]Probe(nil), probes...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Healthy != ranked[j].Healthy {
+			return ranked[i].Healthy
+		}
+		return ranked[i].Healthy && ranked[i].RTT < ranked[j].RTT
+	})
+	return ranked
+}
+
+// Select probes the orderer endpoints and returns them in the failover order,
+// it fails when no orderer is healthy
+func Select(ctx context.Context, endpoints []Endpoint) ([]Probe, error) {
+	ranked := Rank(ProbeEndpoints(ctx, endpoints, DefaultAttempts, DefaultTimeout))
+	if len(ranked) == 0 || !ranked[0].Healthy {
+		messages := []string{}
+		for _, p := range ranked {
+			messages = append(messages, p.Address+": "+p.Error)
+		}
+		return ranked, errors.Errorf("no orderer is reachable: %s", strings.Join(messages, "; "))
+	}
+	return ranked, nil
+}
+
+// MergeOverrides updates the deliveryclient overrides of a peer with the
+// probes of the orderers of a channel: the unhealthy orderers are replaced
+// with the closest healthy one and the overrides of the healthy orderers are
+// removed
+func MergeOverrides(existing []config.OrdererOverride, ranked []Probe, caCertsFile string) []config.OrdererOverride {
+	probed := map[string]bool{}
+	for _, p := range ranked {
+		probed[p.Address] = true
+	}
+	overrides := []config.OrdererOverride{}
+	for _, override := range existing {
+		if !probed[override.From] {
+			overrides = append(overrides, override)
+		}
+	}
+	if len(ranked) == 0 || !ranked[0].Healthy {
+		return overrides
+	}
+	for _, p := range ranked {
+		if !p.Healthy {
+			overrides = append(overrides, config.OrdererOverride{
+				From:        p.Address,
+				To:          ranked[0].Address,
+				CACertsFile: caCertsFile,
+			})
+		}
+	}
+	return overrides
+}
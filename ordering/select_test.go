@@ -0,0 +1,111 @@
+package ordering
+
+import (
+	"context"
+	"encoding/pem"
+	"hlf-easy/config"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEndpointAddress(t *testing.T) {
+	for url, expected := range map[string]string{
+		"grpcs://orderer0.example.com:7050": "orderer0.example.com:7050",
+		"grpc://127.0.0.1:7050":             "127.0.0.1:7050",
+		"orderer1.example.com:7050":         "orderer1.example.com:7050",
+	} {
+		if address := EndpointAddress(url); address != expected {
+			t.Errorf("expected %s for %s, got %s", expected, url, address)
+		}
+	}
+}
+
+func unusedAddress(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+	return address
+}
+
+func TestSelect(t *testing.T) {
+	// the orderers negotiate h2 like gRPC servers
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+	healthy := strings.TrimPrefix(srv.URL, "https://")
+	unreachable := unusedAddress(t)
+
+	ranked, err := Select(context.Background(), []Endpoint{
+		{Address: unreachable, TLSCACerts: []string{caCert}},
+		{Address: healthy, TLSCACerts: []string{caCert}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranked[0].Address != healthy || !ranked[0].Healthy || ranked[0].RTT <= 0 {
+		t.Fatalf("expected %s to be the healthy orderer, got %+v", healthy, ranked[0])
+	}
+	if ranked[1].Address != unreachable || ranked[1].Healthy || ranked[1].Error == "" {
+		t.Fatalf("expected %s to be unreachable, got %+v", unreachable, ranked[1])
+	}
+
+	// the certificate isn't issued by the TLS root certificates
+	_, err = Select(context.Background(), []Endpoint{{Address: healthy}})
+	if err == nil {
+		t.Fatal("expected an error without a trusted orderer")
+	}
+}
+
+func TestRank(t *testing.T) {
+	ranked := Rank([]Probe{
+		{Address: "a:7050", Error: "refused"},
+		{Address: "b:7050", Healthy: true, RTT: 30 * time.Millisecond},
+		{Address: "c:7050", Error: "timeout"},
+		{Address: "d:7050", Healthy: true, RTT: 10 * time.Millisecond},
+	})
+	addresses := []string{}
+	for _, p := range ranked {
+		addresses = append(addresses, p.Address)
+	}
+	expected := "d:7050,b:7050,a:7050,c:7050"
+	if strings.Join(addresses, ",") != expected {
+		t.Fatalf("expected %s, got %v", expected, addresses)
+	}
+}
+
+func TestMergeOverrides(t *testing.T) {
+	existing := []config.OrdererOverride{
+		{From: "other:7050", To: "other2:7050"},
+		{From: "b:7050", To: "a:7050"},
+	}
+	ranked := []Probe{
+		{Address: "b:7050", Healthy: true, RTT: time.Millisecond},
+		{Address: "a:7050", Error: "refused"},
+	}
+	overrides := MergeOverrides(existing, ranked, "/ca.pem")
+	if len(overrides) != 2 {
+		t.Fatalf("expected 2 overrides, got %v", overrides)
+	}
+	if overrides[0].From != "other:7050" {
+		t.Errorf("expected the override of another channel to be kept, got %v", overrides[0])
+	}
+	if overrides[1] != (config.OrdererOverride{From: "a:7050", To: "b:7050", CACertsFile: "/ca.pem"}) {
+		t.Errorf("expected a:7050 to be replaced with b:7050, got %v", overrides[1])
+	}
+
+	// without a healthy orderer the overrides of the channel are dropped
+	overrides = MergeOverrides(existing, []Probe{{Address: "b:7050"}}, "/ca.pem")
+	if len(overrides) != 1 || overrides[0].From != "other:7050" {
+		t.Fatalf("expected only the other override, got %v", overrides)
+	}
+}
@@ -0,0 +1,92 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+	"io"
+	"text/tabwriter"
+)
+
+// Formats of the output of the status, list and config commands
+const (
+	Table = "table"
+	JSON  = "json"
+	YAML  = "yaml"
+)
+
+// Format is the output format set with the global --output flag
+var Format = Table
+
+// AddFlag adds the --output flag, the commands that write files keep their
+// own --output flag with the path of the file
+func AddFlag(f *pflag.FlagSet) {
+	f.StringVarP(&Format, "output", "o", Table, "Output format of the status, list and config commands: table, json or yaml")
+}
+
+// Validate checks that a format is known
+func Validate(format string) error {
+	switch format {
+	case Table, JSON, YAML:
+		return nil
+	}
+	return errors.Errorf("invalid output %s, expected table, json or yaml", format)
+}
+
+// blockStyle clears the JSON styles of the nodes so they're written as
+// block YAML
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// Write writes v in a format, the table format is written by table
+func Write(out io.Writer, format string, v interface{}, table func(w io.Writer) error) error {
+	switch format {
+	case JSON:
+		vBytes, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(vBytes))
+		return err
+	case YAML:
+		// the values are written with their JSON names and in the order of
+		// their fields, YAML being a superset of JSON
+		vBytes, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		node := &yaml.Node{}
+		err = yaml.Unmarshal(vBytes, node)
+		if err != nil {
+			return err
+		}
+		blockStyle(node)
+		encoder := yaml.NewEncoder(out)
+		encoder.SetIndent(2)
+		err = encoder.Encode(node)
+		if err != nil {
+			return err
+		}
+		return encoder.Close()
+	case Table:
+		return table(out)
+	}
+	return Validate(format)
+}
+
+// Print writes v in the format of the --output flag
+func Print(out io.Writer, v interface{}, table func(w io.Writer) error) error {
+	return Write(out, Format, v, table)
+}
+
+// NewTabWriter returns the writer of the tables of the commands, it must be
+// flushed
+func NewTabWriter(out io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+}
@@ -0,0 +1,101 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+type task struct {
+	Name     string            `json:"name"`
+	Schedule string            `json:"schedule"`
+	Params   map[string]string `json:"params,omitempty"`
+	Disabled bool              `json:"disabled,omitempty"`
+}
+
+var tasks = []task{
+	{Name: "snapshot", Schedule: "0 2 * * *", Params: map[string]string{"channel": "demo"}},
+	{Name: "prune", Schedule: "@daily", Disabled: true},
+}
+
+func printTasks(w io.Writer) error {
+	for _, t := range tasks {
+		fmt.Fprintf(w, "%s\t%s\n", t.Name, t.Schedule)
+	}
+	return nil
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: JSON,
+			expected: `[
+  {
+    "name": "snapshot",
+    "schedule": "0 2 * * *",
+    "params": {
+      "channel": "demo"
+    }
+  },
+  {
+    "name": "prune",
+    "schedule": "@daily",
+    "disabled": true
+  }
+]
+`,
+		},
+		{
+			// the fields keep their JSON names and order in block style
+			format: YAML,
+			expected: `- name: snapshot
+  schedule: 0 2 * * *
+  params:
+    channel: demo
+- name: prune
+  schedule: '@daily'
+  disabled: true
+`,
+		},
+		{
+			format:   Table,
+			expected: "snapshot\t0 2 * * *\nprune\t@daily\n",
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := Write(&buf, tt.format, tasks, printTasks)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.format, tt.expected, buf.String())
+		}
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, JSON, []task{}, printTasks)
+	if err != nil || buf.String() != "[]\n" {
+		t.Errorf("expected an empty JSON list, got %q: %v", buf.String(), err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, format := range []string{Table, JSON, YAML} {
+		if err := Validate(format); err != nil {
+			t.Errorf("expected %s to be valid: %v", format, err)
+		}
+	}
+	if err := Validate("xml"); err == nil {
+		t.Error("expected xml to be invalid")
+	}
+	if err := Write(io.Discard, "xml", tasks, printTasks); err == nil {
+		t.Error("expected writing xml to fail")
+	}
+}
@@ -0,0 +1,111 @@
+// Package hlfeasy is the Go API of hlf-easy for the programs provisioning a
+// network from their own tools and tests. It writes the same state as the
+// CLI in $HOME/hlf-easy, so the nodes it initializes are started by
+// hlf-easy peer start, orderer start or the daemon. The functions return
+// errors instead of exiting and check their context before each step, their
+// logs go to the logger set with SetLogger
+package hlfeasy
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"hlf-easy/certs"
+	"hlf-easy/channel"
+	"hlf-easy/config"
+	"hlf-easy/log"
+	"hlf-easy/node"
+)
+
+// The options are the ones of the CLI commands, the aliases keep their names
+// stable for the programs using this package
+type (
+	// CAOptions are the options of ca init
+	CAOptions = certs.InitCAOptions
+	// EnrollOptions are the options of ca enroll
+	EnrollOptions = certs.EnrollOptions
+	// PeerOptions are the options of peer init
+	PeerOptions = config.PeerInitOptions
+	// OrdererOptions are the options of orderer init
+	OrdererOptions = config.OrdererInitOptions
+	// CertificatePolicy is the policy of the certificates issued by a CA
+	CertificatePolicy = config.CertificatePolicy
+	// ChannelOptions are the options of the genesis block of a channel
+	ChannelOptions = channel.CreateOptions
+	// Logger receives the logs of the provisioning, logrus by default
+	Logger = log.Logger
+)
+
+// SetLogger plugs the logger of the program, nil restores logrus
+func SetLogger(l Logger) {
+	log.SetLogger(l)
+}
+
+// Identity is a certificate with its private key, PEM encoded
+type Identity struct {
+	Certificate []byte
+	PrivateKey  []byte
+}
+
+// InitCA creates a local CA with its signing and TLS CAs
+func InitCA(ctx context.Context, opts CAOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	_, err := certs.InitCA(opts)
+	return err
+}
+
+// Enroll issues an identity with a local CA
+func Enroll(ctx context.Context, opts EnrollOptions) (*Identity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	cert, key, err := certs.Enroll(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{Certificate: cert, PrivateKey: key}, nil
+}
+
+// InitPeer enrolls the certificates of a peer and writes its configuration
+func InitPeer(ctx context.Context, opts PeerOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := node.ValidatePeerInitOptions(opts); err != nil {
+		return err
+	}
+	return node.EnrollPeerCertificates(ctx, opts)
+}
+
+// InitOrderer enrolls the certificates of an orderer and writes its
+// configuration
+func InitOrderer(ctx context.Context, opts OrdererOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := node.ValidateOrdererInitOptions(opts); err != nil {
+		return err
+	}
+	return node.EnrollOrdererCertificates(ctx, opts)
+}
+
+// GenesisBlock returns the protobuf encoded genesis block of an application
+// channel, it's joined by the orderers through their channel participation
+// API
+func GenesisBlock(ctx context.Context, opts ChannelOptions) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	block, err := channel.NewGenesisBlock(opts)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(block)
+}
@@ -0,0 +1,62 @@
+package hlfeasy
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"hlf-easy/errdefs"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProvisioning(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	ctx := context.Background()
+	err := InitCA(ctx, CAOptions{Name: "org1-ca", Organization: "Org1MSP", Hosts: []string{"localhost"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := Enroll(ctx, EnrollOptions{CAName: "org1-ca", Type: "admin", CommonName: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	crt, err := utils.ParseX509Certificate(identity.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crt.Subject.CommonName != "admin" || len(identity.PrivateKey) == 0 {
+		t.Errorf("expected the admin identity, got %s", crt.Subject)
+	}
+
+	err = InitPeer(ctx, PeerOptions{ID: "peer0", Local: true, CAName: "org1-ca", MSPID: "Org1MSP", Hosts: []string{"localhost"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitOrderer(ctx, OrdererOptions{ID: "orderer0", Local: true, CAName: "org1-ca", Hosts: []string{"localhost"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"peers/peer0/core.yaml", "peers/peer0/signcerts/cert.pem", "orderers/orderer0/tls.crt"} {
+		if _, err := os.Stat(filepath.Join(home, "hlf-easy", path)); err != nil {
+			t.Errorf("expected %s to be written: %v", path, err)
+		}
+	}
+}
+
+func TestProvisioningErrors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := InitCA(ctx, CAOptions{Name: "org1-ca", Hosts: []string{"localhost"}}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the canceled context to stop the CA init, got %v", err)
+	}
+	_, err := Enroll(context.Background(), EnrollOptions{CAName: "org2-ca", Type: "client", CommonName: "user1"})
+	if !errors.Is(err, errdefs.ErrCANotInitialized) {
+		t.Errorf("expected the CA not to be initialized, got %v", err)
+	}
+	if err := InitPeer(context.Background(), PeerOptions{ID: "peer0", Local: true}); err == nil {
+		t.Error("expected the CA name to be required")
+	}
+}
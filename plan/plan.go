@@ -0,0 +1,209 @@
+package plan
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Writer writes the directories and files of a command, a Plan records them
+// instead so a dry run and a real run share the same list of changes
+type Writer interface {
+	MkdirAll(path string, perm os.FileMode) error
+	WriteFile(name string, data []byte, perm os.FileMode) error
+}
+
+type disk struct{}
+
+func (disk) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (disk) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+// Disk writes to the filesystem
+var Disk Writer = disk{}
+
+const (
+	Create    = "create"
+	Update    = "update"
+	Unchanged = "unchanged"
+
+	KindDir  = "dir"
+	KindFile = "file"
+	KindCert = "cert"
+	KindKey  = "key"
+)
+
+// Change is a directory or file a command would write
+type Change struct {
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	Path   string `json:"path"`
+	// Detail describes certificates, the subject, SANs and issuer
+	Detail string `json:"detail,omitempty"`
+}
+
+// Plan records the changes of a command without touching the filesystem,
+// every change is compared with what the filesystem has
+type Plan struct {
+	Changes []Change
+	index   map[string]int
+}
+
+func New() *Plan {
+	return &Plan{Changes: []Change{}, index: map[string]int{}}
+}
+
+func (p *Plan) record(c Change) {
+	if i, ok := p.index[c.Path]; ok {
+		// a file written twice is still compared with the filesystem, a
+		// create stays a create
+		action := p.Changes[i].Action
+		if action == Unchanged {
+			action = c.Action
+		}
+		c.Action = action
+		p.Changes[i] = c
+		return
+	}
+	p.index[c.Path] = len(p.Changes)
+	p.Changes = append(p.Changes, c)
+}
+
+// MkdirAll records the directories of the path that don't exist, parents first
+func (p *Plan) MkdirAll(path string, perm os.FileMode) error {
+	path = filepath.Clean(path)
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, planned := p.index[dir]; planned {
+			break
+		}
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return errors.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		p.record(Change{Action: Create, Kind: KindDir, Path: missing[i]})
+	}
+	return nil
+}
+
+// WriteFile records a file, it's an update if its content differs from the
+// one of the filesystem
+func (p *Plan) WriteFile(name string, data []byte, perm os.FileMode) error {
+	name = filepath.Clean(name)
+	c := Change{Action: Create, Kind: KindFile, Path: name}
+	existing, err := os.ReadFile(name)
+	if err == nil {
+		c.Action = Update
+		if bytes.Equal(existing, data) {
+			c.Action = Unchanged
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	c.Kind, c.Detail = describe(data)
+	p.record(c)
+	return nil
+}
+
+// describe tells whether the data is a certificate or a key, certificates
+// are described by their subject, SANs, issuer and expiry
+func describe(data []byte) (string, string) {
+	block, rest := pem.Decode(data)
+	if block == nil {
+		return KindFile, ""
+	}
+	if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+		return KindKey, ""
+	}
+	if block.Type != "CERTIFICATE" {
+		return KindFile, ""
+	}
+	crt, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return KindCert, ""
+	}
+	parts := []string{crt.Subject.String()}
+	var sans []string
+	sans = append(sans, crt.DNSNames...)
+	for _, ip := range crt.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	if len(sans) > 0 {
+		parts = append(parts, "SANs "+strings.Join(sans, ","))
+	}
+	if crt.IsCA && crt.Subject.String() == crt.Issuer.String() {
+		parts = append(parts, "self-signed")
+	} else {
+		parts = append(parts, "issued by "+crt.Issuer.String())
+	}
+	parts = append(parts, "expires "+crt.NotAfter.UTC().Format("2006-01-02"))
+	chain := 1
+	for {
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		chain++
+	}
+	if chain > 1 {
+		parts = append(parts, fmt.Sprintf("chain of %d", chain))
+	}
+	return KindCert, strings.Join(parts, ", ")
+}
+
+// Count returns the number of changes with the action
+func (p *Plan) Count(action string) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+var symbols = map[string]string{Create: "+", Update: "~"}
+
+// Print prints the changes terraform-plan style, the unchanged files are
+// only counted
+func (p *Plan) Print(out io.Writer) error {
+	for _, c := range p.Changes {
+		symbol, ok := symbols[c.Action]
+		if !ok {
+			continue
+		}
+		line := fmt.Sprintf("  %s %-4s %s", symbol, c.Kind, c.Path)
+		if c.Detail != "" {
+			line += "\n           " + c.Detail
+		}
+		fmt.Fprintln(out, line)
+	}
+	if p.Count(Create)+p.Count(Update) == 0 {
+		_, err := fmt.Fprintln(out, "No changes.")
+		return err
+	}
+	_, err := fmt.Fprintf(out, "\nPlan: %d to create, %d to update, %d unchanged.\n", p.Count(Create), p.Count(Update), p.Count(Unchanged))
+	return err
+}
@@ -0,0 +1,79 @@
+package proc
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// NewProcessGroup starts a command in its own process group so it can be
+// interrupted without interrupting hlf-easy, on Windows it doesn't get the
+// Ctrl+C of the console anymore
+func NewProcessGroup(cmd *exec.Cmd) {
+	newProcessGroup(cmd)
+}
+
+// Interrupt asks a child process started in its own process group to exit
+// gracefully: SIGINT on Unix and CTRL_BREAK on Windows, both handled as an
+// interrupt by the Fabric and hlf-easy processes
+func Interrupt(p *os.Process) error {
+	return interrupt(p)
+}
+
+// Running returns whether a process is alive
+func Running(pid int) bool {
+	return running(pid)
+}
+
+// Terminate asks a process that isn't a child of the caller to exit, it's
+// SIGTERM on Unix. On Windows the process is interrupted when it shares the
+// console of the caller and killed with its children by taskkill otherwise
+func Terminate(pid int) error {
+	return terminate(pid)
+}
+
+// WaitExited waits for a process to exit
+func WaitExited(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !running(pid) {
+			return true
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return !running(pid)
+}
+
+// Env adds to the environment of a child process the variables of hlf-easy
+// it needs on the platform, the Fabric processes get an environment of their
+// own and Windows processes can't open sockets without SYSTEMROOT
+func Env(env []string) []string {
+	for _, name := range platformEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// NotifyContext returns a context done when hlf-easy is interrupted or
+// terminated, or when its Windows service is stopped. stop restores the
+// default behavior of the signals
+func NotifyContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return notifyContext(parent)
+}
+
+// TimeoutContext is NotifyContext with a deadline after timeout, the commands
+// bound their long operations with it. A timeout of 0 sets no deadline
+func TimeoutContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := NotifyContext(parent)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
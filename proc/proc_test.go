@@ -0,0 +1,28 @@
+package proc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTimeoutContext(t *testing.T) {
+	ctx, cancel := TimeoutContext(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the context to time out")
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("expected the deadline to be exceeded, got %v", ctx.Err())
+	}
+	ctx, cancel = TimeoutContext(context.Background(), 0)
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("expected no deadline")
+	}
+	cancel()
+	if ctx.Err() != context.Canceled {
+		t.Errorf("expected the context to be canceled, got %v", ctx.Err())
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+
+package proc
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+var platformEnv []string
+
+func newProcessGroup(cmd *exec.Cmd) {
+	// the children stay in the group of hlf-easy so they get the Ctrl+C of
+	// the terminal, they're signaled individually
+}
+
+func interrupt(p *os.Process) error {
+	return p.Signal(os.Interrupt)
+}
+
+func running(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+func terminate(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(syscall.SIGTERM)
+}
+
+func notifyContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(parent, syscall.SIGINT, syscall.SIGTERM)
+}
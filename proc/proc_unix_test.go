@@ -0,0 +1,61 @@
+//go:build !windows
+
+package proc
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestInterrupt(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	NewProcessGroup(cmd)
+	err := cmd.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	if !Running(cmd.Process.Pid) {
+		t.Fatal("expected the process to be running")
+	}
+	err = Interrupt(cmd.Process)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the process to exit on the interrupt")
+	}
+	if Running(cmd.Process.Pid) {
+		t.Error("expected the process to have exited")
+	}
+}
+
+func TestTerminate(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	err := cmd.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the process is reaped so it doesn't stay a zombie
+	go cmd.Wait()
+	err = Terminate(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !WaitExited(cmd.Process.Pid, 5*time.Second) {
+		t.Error("expected the process to exit on SIGTERM")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	env := Env([]string{"FABRIC_CFG_PATH=/peer"})
+	if len(env) != 1 || env[0] != "FABRIC_CFG_PATH=/peer" {
+		t.Errorf("expected no variable to be added, got %v", env)
+	}
+}
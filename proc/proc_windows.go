@@ -0,0 +1,103 @@
+//go:build windows
+
+package proc
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"hlf-easy/log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// stillActive is the exit code of a process that hasn't exited, STILL_ACTIVE
+const stillActive = 259
+
+// platformEnv are the variables the Windows processes need whatever their
+// environment
+var platformEnv = []string{"SYSTEMROOT", "WINDIR", "COMSPEC", "TEMP", "TMP", "USERPROFILE"}
+
+func newProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+func interrupt(p *os.Process) error {
+	// the ID of the process group is the PID of its first process
+	return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(p.Pid))
+}
+
+func running(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	err = windows.GetExitCodeProcess(h, &code)
+	return err == nil && code == stillActive
+}
+
+func terminate(pid int) error {
+	// a process of another console can't be interrupted
+	err := windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(pid))
+	if err == nil {
+		return nil
+	}
+	log.Debugf("Failed to interrupt process %d, killing it: %v", pid, err)
+	output, err := exec.Command("taskkill", "/PID", strconv.Itoa(pid), "/T", "/F").CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "taskkill failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// serviceHandler cancels the context of hlf-easy when its service is stopped
+type serviceHandler struct {
+	cancel context.CancelFunc
+}
+
+func (h *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for r := range requests {
+		switch r.Cmd {
+		case svc.Interrogate:
+			status <- r.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending}
+			h.cancel()
+			return false, 0
+		}
+	}
+	return false, 0
+}
+
+func notifyContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		log.Warnf("Failed to check if hlf-easy runs as a service: %v", err)
+	}
+	if !isService {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		// the name is ignored for the services running in their own process
+		if err := svc.Run("", &serviceHandler{cancel: cancel}); err != nil {
+			log.Errorf("Failed to run the service: %v", err)
+		}
+	}()
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
@@ -0,0 +1,47 @@
+package proc
+
+import (
+	"github.com/pkg/errors"
+	"regexp"
+)
+
+var serviceNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ServiceOptions are the options of a Windows service running an hlf-easy
+// command, like the start of a node
+type ServiceOptions struct {
+	Name        string
+	Description string
+	// Args of the hlf-easy command
+	Args []string
+	// Account the service runs as, LocalSystem when empty. The nodes are
+	// in the hlf-easy directory of the profile of the account
+	Account  string
+	Password string
+}
+
+// Validate checks the name and the command of the service
+func (o ServiceOptions) Validate() error {
+	if !serviceNameRegexp.MatchString(o.Name) {
+		return errors.Errorf("invalid service name %q", o.Name)
+	}
+	if len(o.Args) == 0 {
+		return errors.New("the hlf-easy command of the service is required")
+	}
+	return nil
+}
+
+// InstallService registers a service starting an hlf-easy command with the
+// host, it's started on boot and restarted when it fails
+func InstallService(opts ServiceOptions) error {
+	err := opts.Validate()
+	if err != nil {
+		return err
+	}
+	return installService(opts)
+}
+
+// RemoveService stops and removes a service
+func RemoveService(name string) error {
+	return removeService(name)
+}
@@ -0,0 +1,22 @@
+package proc
+
+import (
+	"testing"
+)
+
+func TestServiceOptionsValidate(t *testing.T) {
+	valid := ServiceOptions{Name: "hlf-easy-peer0", Args: []string{"peer", "start", "--id", "peer0"}}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	invalid := map[string]ServiceOptions{
+		"no name":      {Args: valid.Args},
+		"invalid name": {Name: "hlf easy", Args: valid.Args},
+		"no command":   {Name: valid.Name},
+	}
+	for name, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+}
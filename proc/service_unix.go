@@ -0,0 +1,17 @@
+//go:build !windows
+
+package proc
+
+import (
+	"github.com/pkg/errors"
+)
+
+var errServices = errors.New("services are only managed on Windows, run the commands with the service manager of the host")
+
+func installService(opts ServiceOptions) error {
+	return errServices
+}
+
+func removeService(name string) error {
+	return errServices
+}
@@ -0,0 +1,75 @@
+//go:build windows
+
+package proc
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+	"os"
+	"time"
+)
+
+// restartDelay is how long the service manager waits to restart a failed
+// service
+const restartDelay = 10 * time.Second
+
+func installService(opts ServiceOptions) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the service manager, run as an administrator")
+	}
+	defer m.Disconnect()
+	if s, err := m.OpenService(opts.Name); err == nil {
+		s.Close()
+		return errors.Errorf("service %s already exists", opts.Name)
+	}
+	s, err := m.CreateService(opts.Name, executable, mgr.Config{
+		DisplayName:      opts.Name,
+		Description:      opts.Description,
+		StartType:        mgr.StartAutomatic,
+		ServiceStartName: opts.Account,
+		Password:         opts.Password,
+	}, opts.Args...)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create service %s", opts.Name)
+	}
+	defer s.Close()
+	err = s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: restartDelay},
+		{Type: mgr.ServiceRestart, Delay: restartDelay},
+		{Type: mgr.ServiceRestart, Delay: restartDelay},
+	}, uint32((24 * time.Hour).Seconds()))
+	if err != nil {
+		return errors.Wrapf(err, "failed to set the recovery actions of service %s", opts.Name)
+	}
+	return nil
+}
+
+func removeService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the service manager, run as an administrator")
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(name)
+	if err != nil {
+		return errors.Errorf("service %s not found", name)
+	}
+	defer s.Close()
+	status, err := s.Query()
+	if err != nil {
+		return err
+	}
+	if status.State != svc.Stopped {
+		_, err = s.Control(svc.Stop)
+		if err != nil {
+			return errors.Wrapf(err, "failed to stop service %s", name)
+		}
+	}
+	return s.Delete()
+}
@@ -0,0 +1,151 @@
+// Package profile isolates the organizations managed from one host: every
+// profile has its own home directory, so its own hlf-easy directory with its
+// nodes, CAs, API tokens and daemon
+package profile
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+)
+
+const (
+	// EnvProfile selects the profile of the commands without --profile, it's
+	// inherited by the processes hlf-easy starts
+	EnvProfile = "HLF_EASY_PROFILE"
+	// EnvUserHome is the home directory of the user while a profile is
+	// active, the home directory of the process being the one of the profile
+	EnvUserHome = "HLF_EASY_USER_HOME"
+)
+
+// profilesDir is the directory of the profiles in the home of the user
+const profilesDir = "hlf-easy-profiles"
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Validate checks the name of a profile, it's also the name of its directory
+func Validate(name string) error {
+	if !validName.MatchString(name) {
+		return errors.Errorf("invalid profile %q, expected lowercase letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// HomeEnv returns the variable the home directory is read from
+func HomeEnv() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "USERPROFILE"
+	case "plan9":
+		return "home"
+	}
+	return "HOME"
+}
+
+// Current returns the active profile, empty for the default one
+func Current() string {
+	return os.Getenv(EnvProfile)
+}
+
+// UserHome returns the home directory of the user, the one of the default
+// profile, whichever profile is active
+func UserHome() (string, error) {
+	if home := os.Getenv(EnvUserHome); home != "" {
+		return home, nil
+	}
+	return os.UserHomeDir()
+}
+
+// Dir returns the home directory of a profile, the home directory of the
+// user for the default one
+func Dir(name string) (string, error) {
+	home, err := UserHome()
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return home, nil
+	}
+	return filepath.Join(home, profilesDir, name), nil
+}
+
+// Activate makes a profile the home directory of the process and of the
+// processes it starts, the default profile when the name is empty. The
+// directory of the profile is created on its first use
+func Activate(name string) error {
+	if name != "" {
+		if err := Validate(name); err != nil {
+			return err
+		}
+	}
+	home, err := UserHome()
+	if err != nil {
+		return err
+	}
+	dir, err := Dir(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := os.Setenv(EnvUserHome, home); err != nil {
+		return err
+	}
+	if err := os.Setenv(EnvProfile, name); err != nil {
+		return err
+	}
+	return os.Setenv(HomeEnv(), dir)
+}
+
+// List returns the profiles of the user, without the default one
+func List() ([]string, error) {
+	home, err := UserHome()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(home, profilesDir))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	profiles := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() && Validate(entry.Name()) == nil {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// Remove removes a profile with its directory, its nodes must be stopped
+func Remove(name string) error {
+	if err := Validate(name); err != nil {
+		return err
+	}
+	if name == Current() {
+		return errors.Errorf("profile %s is active, switch to another profile to remove it", name)
+	}
+	dir, err := Dir(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return errors.Errorf("profile %s does not exist", name)
+	}
+	// a running node has a run.json in its directory
+	running, err := filepath.Glob(filepath.Join(dir, "hlf-easy", "*", "*", "run.json"))
+	if err != nil {
+		return err
+	}
+	if len(running) > 0 {
+		return errors.Errorf("profile %s has running nodes, stop them before removing it", name)
+	}
+	return os.RemoveAll(dir)
+}
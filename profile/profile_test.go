@@ -0,0 +1,60 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestActivate(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvUserHome, "")
+	if err := Activate("org1"); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := os.UserHomeDir()
+	if err != nil || dir != filepath.Join(home, "hlf-easy-profiles/org1") || Current() != "org1" {
+		t.Fatalf("expected the home of org1, got %s, %v", dir, err)
+	}
+	// the profiles are switched from the home of the user, not nested
+	if err := Activate("org2"); err != nil {
+		t.Fatal(err)
+	}
+	if dir, _ := os.UserHomeDir(); dir != filepath.Join(home, "hlf-easy-profiles/org2") {
+		t.Fatalf("expected the home of org2, got %s", dir)
+	}
+	profiles, err := List()
+	if err != nil || !reflect.DeepEqual(profiles, []string{"org1", "org2"}) {
+		t.Fatalf("expected org1 and org2, got %v, %v", profiles, err)
+	}
+	if err := Remove("org2"); err == nil {
+		t.Fatal("expected the active profile not to be removed")
+	}
+	if err := Activate(""); err != nil {
+		t.Fatal(err)
+	}
+	if dir, _ := os.UserHomeDir(); dir != home || Current() != "" {
+		t.Fatalf("expected the home of the user, got %s", dir)
+	}
+	runDir := filepath.Join(home, "hlf-easy-profiles/org1/hlf-easy/peers/peer0")
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, "run.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove("org1"); err == nil {
+		t.Fatal("expected a profile with a running node not to be removed")
+	}
+	if err := Remove("org2"); err != nil {
+		t.Fatal(err)
+	}
+	for _, invalid := range []string{"../org1", "Org1", "org 1"} {
+		if err := Activate(invalid); err == nil {
+			t.Errorf("expected %q to be refused", invalid)
+		}
+	}
+}
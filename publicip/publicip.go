@@ -0,0 +1,216 @@
+package publicip
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultSource is the HTTP echo service the public IP of the host is asked
+// to, it answers with the IP as plain text
+const DefaultSource = "https://checkip.amazonaws.com"
+
+// DefaultTimeout is how long the source has to answer
+const DefaultTimeout = 10 * time.Second
+
+// stunPrefix marks a STUN server as the source, stun:host:port
+const stunPrefix = "stun:"
+
+// stunMagicCookie is the fixed value of the header of the STUN messages,
+// also the key the addresses of XOR-MAPPED-ADDRESS are xored with
+const stunMagicCookie = 0x2112a442
+
+// types of the STUN messages and attributes of a binding
+const (
+	stunBindingRequest  = 0x0001
+	stunBindingResponse = 0x0101
+	stunMappedAddress   = 0x0001
+	stunXORMappedAddr   = 0x0020
+)
+
+var client = &http.Client{Timeout: DefaultTimeout}
+
+// ValidateSource checks the source of the public IP, an http(s) URL of an echo
+// service or a STUN server as stun:host:port
+func ValidateSource(source string) error {
+	if strings.HasPrefix(source, stunPrefix) {
+		if strings.TrimPrefix(source, stunPrefix) == "" {
+			return errors.Errorf("invalid STUN server %q, expected stun:host:port", source)
+		}
+		return nil
+	}
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return nil
+	}
+	return errors.Errorf("invalid public IP source %q, expected an http(s) URL or stun:host:port", source)
+}
+
+// Detect returns the public IP of the host, the address its requests to the
+// source come from, as seen through the NAT of a cloud VM
+func Detect(ctx context.Context, source string) (net.IP, error) {
+	if err := ValidateSource(source); err != nil {
+		return nil, err
+	}
+	var ip net.IP
+	var err error
+	if strings.HasPrefix(source, stunPrefix) {
+		ip, err = detectSTUN(ctx, strings.TrimPrefix(source, stunPrefix))
+	} else {
+		ip, err = detectHTTP(ctx, source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return nil, errors.Errorf("%s answered %s, which isn't a public IP", source, ip)
+	}
+	return ip, nil
+}
+
+// detectHTTP asks an HTTP echo service for the IP it sees the request from
+func detectHTTP(ctx context.Context, url string) (net.IP, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to reach %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s answered %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the answer of %s", url)
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return nil, errors.Errorf("%s didn't answer with an IP", url)
+	}
+	return ip, nil
+}
+
+// detectSTUN sends a binding request to a STUN server, RFC 5389, it answers
+// with the address and port it received the request from
+func detectSTUN(ctx context.Context, server string) (net.IP, error) {
+	address := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		address = net.JoinHostPort(server, "3478")
+	}
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to reach STUN server %s", server)
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	request := make([]byte, 20)
+	binary.BigEndian.PutUint16(request[0:2], stunBindingRequest)
+	binary.BigEndian.PutUint32(request[4:8], stunMagicCookie)
+	if _, err := rand.Read(request[8:20]); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(request); err != nil {
+		return nil, errors.Wrapf(err, "failed to query STUN server %s", server)
+	}
+	response := make([]byte, 1500)
+	n, err := conn.Read(response)
+	if err != nil {
+		return nil, errors.Wrapf(err, "STUN server %s didn't answer", server)
+	}
+	ip, err := parseBindingResponse(response[:n], request[8:20])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid answer of STUN server %s", server)
+	}
+	return ip, nil
+}
+
+// parseBindingResponse returns the mapped address of a binding response to
+// the transaction, XOR-MAPPED-ADDRESS or the MAPPED-ADDRESS of older servers
+func parseBindingResponse(msg []byte, transactionID []byte) (net.IP, error) {
+	if len(msg) < 20 || binary.BigEndian.Uint16(msg[0:2]) != stunBindingResponse {
+		return nil, errors.New("not a binding response")
+	}
+	if binary.BigEndian.Uint32(msg[4:8]) != stunMagicCookie || !bytes.Equal(msg[8:20], transactionID) {
+		return nil, errors.New("the answer isn't for the request")
+	}
+	length := int(binary.BigEndian.Uint16(msg[2:4]))
+	if 20+length > len(msg) {
+		return nil, errors.New("truncated message")
+	}
+	var mapped net.IP
+	attrs := msg[20 : 20+length]
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:2])
+		attrLen := int(binary.BigEndian.Uint16(attrs[2:4]))
+		if 4+attrLen > len(attrs) {
+			return nil, errors.New("truncated attribute")
+		}
+		value := attrs[4 : 4+attrLen]
+		switch attrType {
+		case stunXORMappedAddr:
+			// the address is xored with the magic cookie and the transaction ID
+			key := msg[4:20]
+			ip, err := parseAddress(value)
+			if err != nil {
+				return nil, err
+			}
+			for i := range ip {
+				ip[i] ^= key[i]
+			}
+			return ip, nil
+		case stunMappedAddress:
+			ip, err := parseAddress(value)
+			if err != nil {
+				return nil, err
+			}
+			mapped = ip
+		}
+		// the attributes are padded to 4 bytes
+		next := 4 + (attrLen+3)&^3
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+	if mapped == nil {
+		return nil, errors.New("no mapped address")
+	}
+	return mapped, nil
+}
+
+// parseAddress decodes the address of a MAPPED-ADDRESS attribute, a family
+// of 1 for IPv4 and 2 for IPv6
+func parseAddress(value []byte) (net.IP, error) {
+	if len(value) < 4 {
+		return nil, errors.New("truncated address")
+	}
+	size := 0
+	switch value[1] {
+	case 1:
+		size = net.IPv4len
+	case 2:
+		size = net.IPv6len
+	default:
+		return nil, errors.Errorf("unknown address family %d", value[1])
+	}
+	if len(value) < 4+size {
+		return nil, errors.New("truncated address")
+	}
+	return append(net.IP{}, value[4:4+size]...), nil
+}
@@ -0,0 +1,103 @@
+package publicip
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// serveSTUN answers the binding requests with ip as the XOR-MAPPED-ADDRESS
+func serveSTUN(t *testing.T, ip net.IP) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		request := make([]byte, 20)
+		for {
+			_, addr, err := conn.ReadFrom(request)
+			if err != nil {
+				return
+			}
+			response := make([]byte, 32)
+			binary.BigEndian.PutUint16(response[0:2], stunBindingResponse)
+			binary.BigEndian.PutUint16(response[2:4], 12)
+			copy(response[4:20], request[4:20])
+			binary.BigEndian.PutUint16(response[20:22], stunXORMappedAddr)
+			binary.BigEndian.PutUint16(response[22:24], 8)
+			response[25] = 1
+			binary.BigEndian.PutUint16(response[26:28], 7051^(stunMagicCookie>>16))
+			for i, b := range ip.To4() {
+				response[28+i] = b ^ response[4+i]
+			}
+			conn.WriteTo(response, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDetect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ip, err := Detect(ctx, "stun:"+serveSTUN(t, net.ParseIP("203.0.113.7")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip.String() != "203.0.113.7" {
+		t.Fatalf("expected 203.0.113.7 from STUN, got %s", ip)
+	}
+
+	answer := "198.51.100.20\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, answer)
+	}))
+	defer srv.Close()
+	ip, err = Detect(ctx, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip.String() != "198.51.100.20" {
+		t.Fatalf("expected 198.51.100.20 from HTTP, got %s", ip)
+	}
+	for _, answer = range []string{"10.0.0.4", "not an ip"} {
+		if _, err := Detect(ctx, srv.URL); err == nil {
+			t.Fatalf("expected an error for the answer %q", answer)
+		}
+	}
+	if _, err := Detect(ctx, "udp://stun.example.com"); err == nil {
+		t.Fatal("expected an error for an unknown source")
+	}
+}
+
+func TestParseBindingResponse(t *testing.T) {
+	transactionID := []byte("0123456789ab")
+	msg := make([]byte, 32)
+	binary.BigEndian.PutUint16(msg[0:2], stunBindingResponse)
+	binary.BigEndian.PutUint16(msg[2:4], 12)
+	binary.BigEndian.PutUint32(msg[4:8], stunMagicCookie)
+	copy(msg[8:20], transactionID)
+	binary.BigEndian.PutUint16(msg[20:22], stunMappedAddress)
+	binary.BigEndian.PutUint16(msg[22:24], 8)
+	msg[25] = 1
+	copy(msg[28:32], net.ParseIP("192.0.2.1").To4())
+	ip, err := parseBindingResponse(msg, transactionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip.String() != "192.0.2.1" {
+		t.Fatalf("expected the MAPPED-ADDRESS of an older server, got %s", ip)
+	}
+	if _, err := parseBindingResponse(msg, []byte("another-txid")); err == nil {
+		t.Fatal("expected an error for the answer to another request")
+	}
+	if _, err := parseBindingResponse(msg[:28], transactionID); err == nil {
+		t.Fatal("expected an error for a truncated message")
+	}
+}
@@ -0,0 +1,128 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/utils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	FormatJSON = "json"
+	FormatPDF  = "pdf"
+)
+
+// File is a report file ready to be delivered
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// ValidateConfig checks the report config
+func ValidateConfig(reportConfig config.ReportConfig) error {
+	interval, err := time.ParseDuration(reportConfig.Interval)
+	if err != nil {
+		return errors.Wrapf(err, "invalid interval %s", reportConfig.Interval)
+	}
+	if interval <= 0 {
+		return errors.New("interval must be greater than 0")
+	}
+	if len(reportConfig.Formats) == 0 {
+		return errors.New("at least one format is required")
+	}
+	for _, format := range reportConfig.Formats {
+		if format != FormatJSON && format != FormatPDF {
+			return errors.Errorf("unknown format %s, valid formats are %s and %s", format, FormatJSON, FormatPDF)
+		}
+	}
+	if reportConfig.Destination == "" {
+		return errors.New("destination is required")
+	}
+	if reportConfig.Identity == "" {
+		return errors.New("identity is required to sign the reports")
+	}
+	if reportConfig.ExpiryWarningDays < 0 {
+		return errors.New("expiry warning days can't be negative")
+	}
+	return nil
+}
+
+// Files generates the signed files of the report in the configured formats,
+// the signature of a PDF is written in a file next to it
+func Files(r *Report, reportConfig config.ReportConfig) ([]File, error) {
+	crt, key, err := utils.ReadIdentity(reportConfig.Identity)
+	if err != nil {
+		return nil, err
+	}
+	baseName := fmt.Sprintf("compliance-%s-%s", r.Host, r.GeneratedAt.Format("20060102T150405Z"))
+	var files []File
+	for _, format := range reportConfig.Formats {
+		switch format {
+		case FormatJSON:
+			data, err := EncodeSignedJSON(r, crt, key)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, File{Name: baseName + ".json", ContentType: "application/json", Data: data})
+		case FormatPDF:
+			data := r.RenderPDF()
+			signature, err := Sign(data, crt, key)
+			if err != nil {
+				return nil, err
+			}
+			signatureBytes, err := json.MarshalIndent(signature, "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			files = append(files,
+				File{Name: baseName + ".pdf", ContentType: "application/pdf", Data: data},
+				File{Name: baseName + ".pdf.sig", ContentType: "application/json", Data: signatureBytes},
+			)
+		}
+	}
+	return files, nil
+}
+
+// Deliver writes the files to the destination directory or posts them to the
+// destination URL, one request per file with its name in the Content-Disposition header
+func Deliver(destination string, files []File) error {
+	if strings.HasPrefix(destination, "http://") || strings.HasPrefix(destination, "https://") {
+		client := &http.Client{Timeout: 30 * time.Second}
+		for _, file := range files {
+			req, err := http.NewRequest(http.MethodPost, destination, bytes.NewReader(file.Data))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", file.ContentType)
+			req.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Name))
+			resp, err := client.Do(req)
+			if err != nil {
+				return errors.Wrapf(err, "failed to post %s", file.Name)
+			}
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				return errors.Errorf("failed to post %s, got status %d", file.Name, resp.StatusCode)
+			}
+		}
+		return nil
+	}
+	err := os.MkdirAll(destination, 0755)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		err = os.WriteFile(filepath.Join(destination, file.Name), file.Data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package report
+
+import (
+	"fmt"
+	"hlf-easy/notify"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExpiringCertificateEvents returns an event for every expiring or expired
+// certificate of the report
+func ExpiringCertificateEvents(r *Report) []notify.Event {
+	var events []notify.Event
+	for _, crt := range r.Certificates {
+		if crt.Status != StatusExpiring && crt.Status != StatusExpired {
+			continue
+		}
+		kind, id, _ := strings.Cut(crt.Owner, "/")
+		message := fmt.Sprintf("The %s certificate of %s expires in %d days", crt.Usage, crt.Owner, crt.DaysLeft)
+		if crt.Status == StatusExpired {
+			message = fmt.Sprintf("The %s certificate of %s has expired", crt.Usage, crt.Owner)
+		}
+		event := notify.NewEvent(notify.EventCertExpiring, kind, id, message)
+		event.Time = r.GeneratedAt
+		event.Details = map[string]string{
+			"subject":      crt.Subject,
+			"serialNumber": crt.SerialNumber,
+			"notAfter":     crt.NotAfter.UTC().Format(time.RFC3339),
+			"daysLeft":     strconv.Itoa(crt.DaysLeft),
+			"status":       crt.Status,
+		}
+		events = append(events, event)
+	}
+	return events
+}
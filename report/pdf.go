@@ -0,0 +1,116 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pdfLinesPerPage = 70
+	pdfFontSize     = 8
+	pdfLineHeight   = 10
+	pdfPageWidth    = 842
+	pdfPageHeight   = 595
+	pdfMargin       = 36
+)
+
+// lines renders the report as plain text lines, in columns so the PDF can use
+// a monospaced font
+func (r *Report) lines() []string {
+	lines := []string{
+		"Compliance report",
+		fmt.Sprintf("Host: %s", r.Host),
+		fmt.Sprintf("Generated at: %s", r.GeneratedAt.Format("2006-01-02 15:04:05 MST")),
+		fmt.Sprintf("Certificates: %d, expiring soon: %d, expired: %d", len(r.Certificates), r.ExpiringSoon, r.Expired),
+		"",
+		"Certificates",
+		fmt.Sprintf("%-24s %-6s %-9s %-11s %-6s %-16s %s", "OWNER", "USAGE", "STATUS", "NOT AFTER", "DAYS", "KEY", "SUBJECT"),
+	}
+	for _, c := range r.Certificates {
+		lines = append(lines, fmt.Sprintf("%-24s %-6s %-9s %-11s %-6d %-16s %s",
+			c.Owner, c.Usage, c.Status, c.NotAfter.Format("2006-01-02"), c.DaysLeft, c.KeyAlgorithm, c.Subject))
+	}
+	lines = append(lines,
+		"",
+		"Nodes",
+		fmt.Sprintf("%-8s %-16s %-12s %-10s %-8s %-4s %-11s %s", "KIND", "ID", "MSP ID", "VERSION", "RUNNING", "TLS", "CLIENT AUTH", "TLS HOSTS"),
+	)
+	for _, n := range r.Nodes {
+		lines = append(lines, fmt.Sprintf("%-8s %-16s %-12s %-10s %-8t %-4t %-11t %s",
+			n.Kind, n.ID, n.MSPID, n.Version, n.Running, n.TLSEnabled, n.ClientAuthRequired, strings.Join(n.TLSHosts, ", ")))
+	}
+	lines = append(lines,
+		"",
+		"Certificate policies",
+		fmt.Sprintf("%-24s %-10s %-10s %-30s %s", "OWNER", "VALIDITY", "SERIAL", "KEY USAGES", "EXT KEY USAGES"),
+	)
+	for _, p := range r.Policies {
+		validity := p.CertPolicy.Validity
+		if validity == "" {
+			validity = "default"
+		}
+		serial := p.CertPolicy.SerialNumberPolicy
+		if serial == "" {
+			serial = "fixed"
+		}
+		lines = append(lines, fmt.Sprintf("%-24s %-10s %-10s %-30s %s",
+			p.Owner, validity, serial, strings.Join(p.CertPolicy.KeyUsages, ","), strings.Join(p.CertPolicy.ExtKeyUsages, ",")))
+	}
+	return lines
+}
+
+func escapePDFText(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`)
+	return replacer.Replace(s)
+}
+
+// RenderPDF renders the report as a landscape A4 PDF
+func (r *Report) RenderPDF() []byte {
+	lines := r.lines()
+	var pages [][]string
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// objects 1 and 2 are the catalog and the pages, 3 the font, then a
+	// page and its content for every page
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	var kids []string
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 4+i*2))
+	}
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>")
+	for i, page := range pages {
+		content := &bytes.Buffer{}
+		fmt.Fprintf(content, "BT /F1 %d Tf %d TL %d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range page {
+			fmt.Fprintf(content, "(%s) '\n", escapePDFText(line))
+		}
+		content.WriteString("ET")
+		objects = append(objects, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+i*2,
+		))
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	pdf := &bytes.Buffer{}
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(pdf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return pdf.Bytes()
+}
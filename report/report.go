@@ -0,0 +1,286 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/utils"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report is the compliance report of the CAs and nodes of a host
+type Report struct {
+	GeneratedAt  time.Time     `json:"generatedAt"`
+	Host         string        `json:"host"`
+	Certificates []Certificate `json:"certificates"`
+	Nodes        []Node        `json:"nodes"`
+	Policies     []Policy      `json:"policies"`
+	// ExpiringSoon is the number of certificates expiring within the warning days
+	ExpiringSoon int `json:"expiringSoon"`
+	Expired      int `json:"expired"`
+}
+
+// Certificate is an entry of the certificate inventory
+type Certificate struct {
+	// Owner is the CA or node the certificate belongs to, e.g. peer/peer1
+	Owner              string    `json:"owner"`
+	Usage              string    `json:"usage"`
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serialNumber"`
+	NotBefore          time.Time `json:"notBefore"`
+	NotAfter           time.Time `json:"notAfter"`
+	DaysLeft           int       `json:"daysLeft"`
+	KeyAlgorithm       string    `json:"keyAlgorithm"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	Status             string    `json:"status"`
+}
+
+// Node is the TLS settings and version of a node
+type Node struct {
+	Kind               string   `json:"kind"`
+	ID                 string   `json:"id"`
+	MSPID              string   `json:"mspID,omitempty"`
+	Version            string   `json:"version"`
+	Running            bool     `json:"running"`
+	TLSEnabled         bool     `json:"tlsEnabled"`
+	ClientAuthRequired bool     `json:"clientAuthRequired"`
+	TLSHosts           []string `json:"tlsHosts"`
+}
+
+// Policy is the certificate policy of a CA or node
+type Policy struct {
+	Owner      string                   `json:"owner"`
+	CertPolicy config.CertificatePolicy `json:"certPolicy"`
+}
+
+const (
+	StatusValid    = "valid"
+	StatusExpiring = "expiring"
+	StatusExpired  = "expired"
+)
+
+// nodeVersion returns the version reported by the binary of a node
+func nodeVersion(binary string) string {
+	output, err := exec.Command(binary, "version").Output()
+	if err != nil {
+		return "unknown"
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "Version:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Version:"))
+		}
+	}
+	return "unknown"
+}
+
+func keyAlgorithm(crt *x509.Certificate) string {
+	switch pub := crt.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", pub.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", pub.N.BitLen())
+	default:
+		return crt.PublicKeyAlgorithm.String()
+	}
+}
+
+type builder struct {
+	report      *Report
+	now         time.Time
+	warningDays int
+}
+
+func (b *builder) addCertificate(owner string, usage string, crt *x509.Certificate) {
+	daysLeft := int(crt.NotAfter.Sub(b.now).Hours() / 24)
+	status := StatusValid
+	switch {
+	case b.now.After(crt.NotAfter):
+		status = StatusExpired
+		b.report.Expired++
+	case daysLeft < b.warningDays:
+		status = StatusExpiring
+		b.report.ExpiringSoon++
+	}
+	b.report.Certificates = append(b.report.Certificates, Certificate{
+		Owner:              owner,
+		Usage:              usage,
+		Subject:            crt.Subject.String(),
+		Issuer:             crt.Issuer.String(),
+		SerialNumber:       crt.SerialNumber.String(),
+		NotBefore:          crt.NotBefore.UTC(),
+		NotAfter:           crt.NotAfter.UTC(),
+		DaysLeft:           daysLeft,
+		KeyAlgorithm:       keyAlgorithm(crt),
+		SignatureAlgorithm: crt.SignatureAlgorithm.String(),
+		Status:             status,
+	})
+}
+
+// nodeConfig holds the certificates of a node, the same for peers and orderers
+type nodeConfig struct {
+	TLSCert   []byte `json:"tlsCert"`
+	SignCert  []byte `json:"signCert"`
+	TlsCACert []byte `json:"tlsCACert"`
+	CaCert    []byte `json:"caCert"`
+}
+
+// nodeInitOptions are the init options shared by peers and orderers
+type nodeInitOptions struct {
+	MSPID      string                   `json:"mspID"`
+	CertPolicy config.CertificatePolicy `json:"certPolicy"`
+	// FabricVersion is set on the peers upgraded by hlf-easy
+	FabricVersion string `json:"fabricVersion"`
+}
+
+func (b *builder) addNode(kind string, nodeDir string, version string) error {
+	id := filepath.Base(nodeDir)
+	owner := fmt.Sprintf("%s/%s", kind, id)
+	nodeConfigBytes, err := os.ReadFile(filepath.Join(nodeDir, "config.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// not enrolled yet, e.g. waiting for the CSR to be signed
+			return nil
+		}
+		return err
+	}
+	nc := nodeConfig{}
+	err = json.Unmarshal(nodeConfigBytes, &nc)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse the config of %s", owner)
+	}
+	node := Node{
+		Kind:    kind,
+		ID:      id,
+		Version: version,
+		// peers and orderers are always started with TLS and without client authentication
+		TLSEnabled:         true,
+		ClientAuthRequired: false,
+	}
+	certificates := []struct {
+		usage string
+		pem   []byte
+	}{
+		{"tls", nc.TLSCert},
+		{"sign", nc.SignCert},
+		{"tlsca", nc.TlsCACert},
+		{"ca", nc.CaCert},
+	}
+	for _, c := range certificates {
+		if len(c.pem) == 0 {
+			continue
+		}
+		crt, err := utils.ParseX509Certificate(c.pem)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse the %s certificate of %s", c.usage, owner)
+		}
+		b.addCertificate(owner, c.usage, crt)
+		if c.usage == "tls" {
+			node.TLSHosts = append(node.TLSHosts, crt.DNSNames...)
+			for _, ip := range crt.IPAddresses {
+				node.TLSHosts = append(node.TLSHosts, ip.String())
+			}
+		}
+	}
+	initOpts := nodeInitOptions{}
+	initBytes, err := os.ReadFile(filepath.Join(nodeDir, "init.json"))
+	if err == nil {
+		err = json.Unmarshal(initBytes, &initOpts)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse the init options of %s", owner)
+		}
+		node.MSPID = initOpts.MSPID
+		if initOpts.FabricVersion != "" {
+			node.Version = initOpts.FabricVersion
+		}
+		b.report.Policies = append(b.report.Policies, Policy{Owner: owner, CertPolicy: initOpts.CertPolicy})
+	}
+	if _, err := os.Stat(filepath.Join(nodeDir, "run.json")); err == nil {
+		node.Running = true
+	}
+	b.report.Nodes = append(b.report.Nodes, node)
+	return nil
+}
+
+func (b *builder) addCA(caDir string) error {
+	name := filepath.Base(caDir)
+	caConfig, err := utils.GetCAConfig(name)
+	if err != nil {
+		return err
+	}
+	owner := fmt.Sprintf("ca/%s", name)
+	b.addCertificate(owner, "ca", caConfig.CACert)
+	b.addCertificate(owner, "tlsca", caConfig.TLSCACert)
+	b.report.Policies = append(b.report.Policies, Policy{Owner: owner, CertPolicy: caConfig.CertPolicy})
+	return nil
+}
+
+// Generate builds the compliance report of the CAs, peers and orderers of the host
+func Generate(now time.Time, warningDays int) (*Report, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	b := &builder{
+		report: &Report{
+			GeneratedAt:  now.UTC(),
+			Host:         hostname,
+			Certificates: []Certificate{},
+			Nodes:        []Node{},
+			Policies:     []Policy{},
+		},
+		now:         now,
+		warningDays: warningDays,
+	}
+	caDirs, err := filepath.Glob(filepath.Join(home, "hlf-easy/cas/*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, caDir := range caDirs {
+		if _, err := os.Stat(filepath.Join(caDir, "config.json")); err != nil {
+			continue
+		}
+		err = b.addCA(caDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	versions := map[string]string{}
+	for _, kind := range []string{"peer", "orderer"} {
+		nodeDirs, err := filepath.Glob(filepath.Join(home, fmt.Sprintf("hlf-easy/%ss/*", kind)))
+		if err != nil {
+			return nil, err
+		}
+		for _, nodeDir := range nodeDirs {
+			if _, ok := versions[kind]; !ok {
+				versions[kind] = nodeVersion(kind)
+			}
+			err = b.addNode(kind, nodeDir, versions[kind])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	// the certificates closer to expire first
+	sort.SliceStable(b.report.Certificates, func(i, j int) bool {
+		return b.report.Certificates[i].NotAfter.Before(b.report.Certificates[j].NotAfter)
+	})
+	return b.report, nil
+}
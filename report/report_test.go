@@ -0,0 +1,243 @@
+package report
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"hlf-easy/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCert(t *testing.T, cn string, notAfter time.Time) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	return testca.Issue(t, testca.Cert{CommonName: cn, DNSNames: []string{cn + ".example.com"}, NotAfter: notAfter}, nil, nil)
+}
+
+func writeJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func encodeKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	keyBytes, err := utils.EncodePrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyBytes
+}
+
+// setupHost lays out a CA and a peer whose TLS certificate expires in 10 days
+func setupHost(t *testing.T, now time.Time) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	caCert, caKey := newTestCert(t, "ca", now.Add(365*24*time.Hour))
+	tlsCACert, tlsCAKey := newTestCert(t, "tlsca", now.Add(365*24*time.Hour))
+	writeJSON(t, filepath.Join(home, "hlf-easy/cas/ca-1/config.json"), config.CAConfig{
+		CaCert:     utils.EncodeX509Certificate(caCert),
+		CaKey:      encodeKey(t, caKey),
+		CaName:     "ca-1",
+		TlsCACert:  utils.EncodeX509Certificate(tlsCACert),
+		TlsCAKey:   encodeKey(t, tlsCAKey),
+		CertPolicy: config.CertificatePolicy{Validity: "720h"},
+	})
+	tlsCert, _ := newTestCert(t, "peer1", now.Add(10*24*time.Hour))
+	signCert, _ := newTestCert(t, "peer1-sign", now.Add(200*24*time.Hour))
+	peerDir := filepath.Join(home, "hlf-easy/peers/peer1")
+	writeJSON(t, filepath.Join(peerDir, "config.json"), config.PeerConfig{
+		TLSCert:   utils.EncodeX509Certificate(tlsCert),
+		SignCert:  utils.EncodeX509Certificate(signCert),
+		TlsCACert: utils.EncodeX509Certificate(tlsCACert),
+		CaCert:    utils.EncodeX509Certificate(caCert),
+	})
+	writeJSON(t, filepath.Join(peerDir, "init.json"), config.PeerInitOptions{ID: "peer1", MSPID: "Org1MSP"})
+	// a peer waiting for its CSR to be signed has no config.json
+	err := os.MkdirAll(filepath.Join(home, "hlf-easy/peers/peer2"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+func TestGenerate(t *testing.T) {
+	now := time.Now()
+	setupHost(t, now)
+	r, err := Generate(now, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Certificates) != 6 {
+		t.Fatalf("expected 6 certificates, got %d", len(r.Certificates))
+	}
+	first := r.Certificates[0]
+	if first.Owner != "peer/peer1" || first.Usage != "tls" || first.Status != StatusExpiring {
+		t.Fatalf("expected the expiring TLS certificate of peer1 first, got %+v", first)
+	}
+	if r.ExpiringSoon != 1 || r.Expired != 0 {
+		t.Fatalf("expected 1 expiring and 0 expired certificates, got %d and %d", r.ExpiringSoon, r.Expired)
+	}
+	if len(r.Nodes) != 1 || r.Nodes[0].MSPID != "Org1MSP" || r.Nodes[0].TLSHosts[0] != "peer1.example.com" {
+		t.Fatalf("unexpected nodes %+v", r.Nodes)
+	}
+	if len(r.Policies) != 2 || r.Policies[0].Owner != "ca/ca-1" || r.Policies[0].CertPolicy.Validity != "720h" {
+		t.Fatalf("unexpected policies %+v", r.Policies)
+	}
+}
+
+func TestSignedJSON(t *testing.T) {
+	now := time.Now()
+	setupHost(t, now)
+	r, err := Generate(now, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signerCert, signerKey := newTestCert(t, "admin", now.Add(time.Hour))
+	data, err := EncodeSignedJSON(r, signerCert, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, crt, err := VerifySignedJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crt.Subject.CommonName != "admin" || len(verified.Certificates) != len(r.Certificates) {
+		t.Fatal("unexpected verified report")
+	}
+	tampered := bytes.Replace(data, []byte(`"expiringSoon": 1`), []byte(`"expiringSoon": 0`), 1)
+	if bytes.Equal(tampered, data) {
+		t.Fatal("expected the report to be tampered")
+	}
+	if _, _, err := VerifySignedJSON(tampered); err == nil {
+		t.Fatal("expected a tampered report to be rejected")
+	}
+}
+
+func TestVerifyDetached(t *testing.T) {
+	now := time.Now()
+	signerCert, signerKey := newTestCert(t, "admin", now.Add(time.Hour))
+	otherCert, _ := newTestCert(t, "other", now.Add(time.Hour))
+	data := (&Report{GeneratedAt: now, Host: "host1"}).RenderPDF()
+	signature, err := Sign(data, signerCert, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatureBytes, err := json.Marshal(signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crt, err := VerifyDetached(data, signatureBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crt.Subject.CommonName != "admin" {
+		t.Fatalf("unexpected signer %s", crt.Subject.CommonName)
+	}
+	if _, err := VerifyDetached(append(data, ' '), signatureBytes); err == nil {
+		t.Fatal("expected a tampered PDF to be rejected")
+	}
+	if err := VerifySigner(crt, []*x509.Certificate{signerCert}, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySigner(crt, []*x509.Certificate{otherCert}, now); err == nil {
+		t.Fatal("expected a signer of another CA to be rejected")
+	}
+	if err := VerifySigner(crt, []*x509.Certificate{signerCert}, now.Add(2*time.Hour)); err == nil {
+		t.Fatal("expected a signer expired when the report was generated to be rejected")
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	r := &Report{GeneratedAt: time.Now(), Host: "host (1)"}
+	for i := 0; i < 100; i++ {
+		r.Certificates = append(r.Certificates, Certificate{Owner: "peer/peer1", Usage: "tls", Status: StatusValid})
+	}
+	pdf := r.RenderPDF()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("expected a PDF document")
+	}
+	if !bytes.Contains(pdf, []byte("/Count 2")) {
+		t.Fatal("expected the report to span two pages")
+	}
+	if !bytes.Contains(pdf, []byte(`Host: host \(1\)`)) {
+		t.Fatal("expected the text to be escaped")
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	files := []File{{Name: "report.json", ContentType: "application/json", Data: []byte("{}")}}
+
+	dir := filepath.Join(t.TempDir(), "reports")
+	err := Deliver(dir, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "report.json")); err != nil || string(data) != "{}" {
+		t.Fatalf("expected the report in the destination directory: %v", err)
+	}
+
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.Header.Get("Content-Disposition")+" "+string(body))
+	}))
+	defer srv.Close()
+	err = Deliver(srv.URL, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 || !strings.Contains(received[0], `filename="report.json"`) {
+		t.Fatalf("unexpected requests %v", received)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := Deliver(failing.URL, files); err == nil {
+		t.Fatal("expected a failed delivery to return an error")
+	}
+}
+
+func TestExpiringCertificateEvents(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &Report{
+		GeneratedAt: now,
+		Certificates: []Certificate{
+			{Owner: "peer/peer1", Usage: "tls", Status: StatusExpiring, DaysLeft: 10, NotAfter: now.AddDate(0, 0, 10)},
+			{Owner: "ca/ca1", Usage: "ca", Status: StatusExpired, NotAfter: now.AddDate(0, 0, -1)},
+			{Owner: "peer/peer2", Usage: "tls", Status: StatusValid, DaysLeft: 300},
+		},
+	}
+	events := ExpiringCertificateEvents(r)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Kind != "peer" || events[0].ID != "peer1" || events[0].Details["daysLeft"] != "10" {
+		t.Fatalf("unexpected event %+v", events[0])
+	}
+	if !strings.Contains(events[1].Message, "has expired") {
+		t.Fatalf("expected the expired certificate message, got %q", events[1].Message)
+	}
+}
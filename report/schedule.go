@@ -0,0 +1,57 @@
+package report
+
+import (
+	"context"
+	"hlf-easy/config"
+	"hlf-easy/log"
+	"hlf-easy/notify"
+	"time"
+)
+
+// GenerateAndDeliver generates the report of the host and delivers it to the
+// configured destination, the expiring certificates are notified to the webhooks
+func GenerateAndDeliver(reportConfig config.ReportConfig, now time.Time) ([]File, error) {
+	r, err := Generate(now, reportConfig.ExpiryWarningDays)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range ExpiringCertificateEvents(r) {
+		notify.Notify(event)
+	}
+	files, err := Files(r, reportConfig)
+	if err != nil {
+		return nil, err
+	}
+	err = Deliver(reportConfig.Destination, files)
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Schedule generates and delivers a report every interval until the context
+// is done, the first one right away. A failed report is logged and retried at
+// the next interval
+func Schedule(ctx context.Context, reportConfig config.ReportConfig) error {
+	interval, err := time.ParseDuration(reportConfig.Interval)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		files, err := GenerateAndDeliver(reportConfig, time.Now())
+		if err != nil {
+			log.Errorf("Failed to deliver the compliance report: %v", err)
+		} else {
+			for _, file := range files {
+				log.Infof("Delivered %s to %s", file.Name, reportConfig.Destination)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
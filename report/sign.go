@@ -0,0 +1,134 @@
+package report
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/utils"
+	"time"
+)
+
+const signatureAlgorithm = "ECDSA-SHA256"
+
+// Signature is the signature of a report, embedded in the JSON reports and
+// written next to the PDF reports
+type Signature struct {
+	Algorithm  string `json:"algorithm"`
+	SignerCert string `json:"signerCert"`
+	Value      []byte `json:"value"`
+}
+
+// SignedReport is the JSON report with its signature, the signature is over
+// the compact JSON encoding of the report
+type SignedReport struct {
+	Report    json.RawMessage `json:"report"`
+	Signature Signature       `json:"signature"`
+}
+
+// Sign signs the data with the key of the identity
+func Sign(data []byte, crt *x509.Certificate, key *ecdsa.PrivateKey) (*Signature, error) {
+	digest := sha256.Sum256(data)
+	value, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{
+		Algorithm:  signatureAlgorithm,
+		SignerCert: string(utils.EncodeX509Certificate(crt)),
+		Value:      value,
+	}, nil
+}
+
+// Verify checks the signature of the data and returns the certificate of the signer
+func Verify(data []byte, signature Signature) (*x509.Certificate, error) {
+	if signature.Algorithm != signatureAlgorithm {
+		return nil, errors.Errorf("unsupported signature algorithm %s", signature.Algorithm)
+	}
+	crt, err := utils.ParseX509Certificate([]byte(signature.SignerCert))
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := crt.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("signer certificate doesn't have an ECDSA key")
+	}
+	digest := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(pub, digest[:], signature.Value) {
+		return nil, errors.New("report signature is not valid")
+	}
+	return crt, nil
+}
+
+// EncodeSignedJSON encodes the report as JSON with its signature
+func EncodeSignedJSON(r *Report, crt *x509.Certificate, key *ecdsa.PrivateKey) ([]byte, error) {
+	reportBytes, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := Sign(reportBytes, crt, key)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(SignedReport{
+		Report:    reportBytes,
+		Signature: *signature,
+	}, "", "  ")
+}
+
+// VerifySignedJSON checks the signature of a JSON report and returns the report
+func VerifySignedJSON(data []byte) (*Report, *x509.Certificate, error) {
+	signed := &SignedReport{}
+	err := json.Unmarshal(data, signed)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the report is indented with the rest of the document
+	compact := &bytes.Buffer{}
+	err = json.Compact(compact, signed.Report)
+	if err != nil {
+		return nil, nil, err
+	}
+	crt, err := Verify(compact.Bytes(), signed.Signature)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := &Report{}
+	err = json.Unmarshal(signed.Report, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, crt, nil
+}
+
+// VerifyDetached checks the signature of a PDF report, read from its .pdf.sig
+// file, and returns the certificate of the signer
+func VerifyDetached(data []byte, signatureBytes []byte) (*x509.Certificate, error) {
+	signature := Signature{}
+	err := json.Unmarshal(signatureBytes, &signature)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid signature file")
+	}
+	return Verify(data, signature)
+}
+
+// VerifySigner checks that the certificate of the signer chains to one of the
+// CA certificates and was valid when the report was generated
+func VerifySigner(crt *x509.Certificate, caCerts []*x509.Certificate, generatedAt time.Time) error {
+	roots := x509.NewCertPool()
+	for _, caCert := range caCerts {
+		roots.AddCert(caCert)
+	}
+	_, err := crt.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: generatedAt,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "signer %q isn't trusted", crt.Subject.CommonName)
+	}
+	return nil
+}
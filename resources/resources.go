@@ -0,0 +1,247 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+	"hlf-easy/config"
+	"hlf-easy/log"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+)
+
+const (
+	OvercommitPolicyWarn   = "warn"
+	OvercommitPolicyRefuse = "refuse"
+)
+
+// Capacity is the CPU and memory of the host
+type Capacity struct {
+	CPUs     int   `json:"cpus"`
+	MemoryMB int64 `json:"memoryMB"`
+}
+
+// NodeReservation is the resources declared by a node of the host
+type NodeReservation struct {
+	Kind      string               `json:"kind"`
+	ID        string               `json:"id"`
+	Resources config.NodeResources `json:"resources"`
+}
+
+// Utilization is the share of the host capacity reserved by its nodes
+type Utilization struct {
+	Capacity              Capacity             `json:"capacity"`
+	Reserved              config.NodeResources `json:"reserved"`
+	ReservedCPUPercent    float64              `json:"reservedCPUPercent"`
+	ReservedMemoryPercent float64              `json:"reservedMemoryPercent"`
+	UsedMemoryPercent     float64              `json:"usedMemoryPercent"`
+	Nodes                 []NodeReservation    `json:"nodes"`
+	Config                config.HostConfig    `json:"config"`
+}
+
+// GetCapacity returns the logical CPUs and total memory of the host
+func GetCapacity() (*Capacity, error) {
+	cpus, err := cpu.Counts(true)
+	if err != nil {
+		return nil, err
+	}
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return nil, err
+	}
+	return &Capacity{
+		CPUs:     cpus,
+		MemoryMB: int64(vm.Total / 1024 / 1024),
+	}, nil
+}
+
+// GetReservations returns the resources declared in the init options of all
+// the peers and orderers of the host, counting the limits of the resources
+// that aren't reserved
+func GetReservations() ([]NodeReservation, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	var reservations []NodeReservation
+	for _, kind := range []string{"peer", "orderer"} {
+		initFiles, err := filepath.Glob(filepath.Join(home, fmt.Sprintf("hlf-easy/%ss/*/init.json", kind)))
+		if err != nil {
+			return nil, err
+		}
+		for _, initFile := range initFiles {
+			initBytes, err := os.ReadFile(initFile)
+			if err != nil {
+				return nil, err
+			}
+			initOpts := struct {
+				Resources config.NodeResources `json:"resources"`
+				Limits    config.NodeLimits    `json:"limits"`
+			}{}
+			err = json.Unmarshal(initBytes, &initOpts)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse %s", initFile)
+			}
+			reservations = append(reservations, NodeReservation{
+				Kind:      kind,
+				ID:        filepath.Base(filepath.Dir(initFile)),
+				Resources: Reserved(initOpts.Resources, initOpts.Limits),
+			})
+		}
+	}
+	return reservations, nil
+}
+
+func sumReservations(reservations []NodeReservation) config.NodeResources {
+	total := config.NodeResources{}
+	for _, reservation := range reservations {
+		total.CPUs += reservation.Resources.CPUs
+		total.MemoryMB += reservation.Resources.MemoryMB
+	}
+	return total
+}
+
+func percent(value float64, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return value / total * 100
+}
+
+// GetUtilization returns the capacity of the host and how much of it is reserved
+func GetUtilization() (*Utilization, error) {
+	capacity, err := GetCapacity()
+	if err != nil {
+		return nil, err
+	}
+	reservations, err := GetReservations()
+	if err != nil {
+		return nil, err
+	}
+	hostConfig, err := utils.GetHostConfig()
+	if err != nil {
+		return nil, err
+	}
+	vm, err := mem.VirtualMemory()
+	if err != nil {
+		return nil, err
+	}
+	reserved := sumReservations(reservations)
+	return &Utilization{
+		Capacity:              *capacity,
+		Reserved:              reserved,
+		ReservedCPUPercent:    percent(reserved.CPUs, float64(capacity.CPUs)),
+		ReservedMemoryPercent: percent(float64(reserved.MemoryMB), float64(capacity.MemoryMB)),
+		UsedMemoryPercent:     vm.UsedPercent,
+		Nodes:                 reservations,
+		Config:                *hostConfig,
+	}, nil
+}
+
+// checkOvercommit returns an error if reserving the requested resources on
+// top of the existing reservations exceeds the threshold of the capacity
+func checkOvercommit(capacity Capacity, reserved config.NodeResources, requested config.NodeResources, threshold float64) error {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	cpus := reserved.CPUs + requested.CPUs
+	if requested.CPUs > 0 && cpus > float64(capacity.CPUs)*threshold {
+		return errors.Errorf(
+			"reserving %.2f CPUs would reserve %.2f of the %d CPUs of the host, above the threshold of %.0f%%",
+			requested.CPUs, cpus, capacity.CPUs, threshold*100,
+		)
+	}
+	memoryMB := reserved.MemoryMB + requested.MemoryMB
+	if requested.MemoryMB > 0 && float64(memoryMB) > float64(capacity.MemoryMB)*threshold {
+		return errors.Errorf(
+			"reserving %dMB of memory would reserve %dMB of the %dMB of the host, above the threshold of %.0f%%",
+			requested.MemoryMB, memoryMB, capacity.MemoryMB, threshold*100,
+		)
+	}
+	return nil
+}
+
+// Reserved returns the resources a node takes from the host, a resource that
+// is limited but not reserved counts with its limit as the process can use
+// up to it
+func Reserved(requested config.NodeResources, nodeLimits config.NodeLimits) config.NodeResources {
+	if requested.CPUs == 0 {
+		requested.CPUs = nodeLimits.CPUs
+	}
+	if requested.MemoryMB == 0 {
+		requested.MemoryMB = nodeLimits.MemoryMB
+	}
+	return requested
+}
+
+// CheckLimits checks that the limits of a node don't prevent it from using
+// the resources reserved for it
+func CheckLimits(requested config.NodeResources, nodeLimits config.NodeLimits) error {
+	if nodeLimits.CPUs > 0 && nodeLimits.CPUs < requested.CPUs {
+		return errors.Errorf("CPU limit %.2f is below the %.2f CPUs reserved for the node", nodeLimits.CPUs, requested.CPUs)
+	}
+	if nodeLimits.MemoryMB > 0 && nodeLimits.MemoryMB < requested.MemoryMB {
+		return errors.Errorf("memory limit %dMB is below the %dMB reserved for the node", nodeLimits.MemoryMB, requested.MemoryMB)
+	}
+	return nil
+}
+
+// CheckReservation checks that the resources of a node fit in the host, the
+// previous reservation of the same node is replaced. The limits must not be
+// below the reservation, and the resources that are limited but not reserved
+// are checked with their limit. Depending on the host config an overcommit is
+// either logged or refused
+func CheckReservation(kind string, id string, requested config.NodeResources, nodeLimits config.NodeLimits) error {
+	err := CheckLimits(requested, nodeLimits)
+	if err != nil {
+		return errors.Wrapf(err, "invalid limits of %s %s", kind, id)
+	}
+	requested = Reserved(requested, nodeLimits)
+	if requested.CPUs == 0 && requested.MemoryMB == 0 {
+		return nil
+	}
+	capacity, err := GetCapacity()
+	if err != nil {
+		return err
+	}
+	reservations, err := GetReservations()
+	if err != nil {
+		return err
+	}
+	var others []NodeReservation
+	for _, reservation := range reservations {
+		if reservation.Kind == kind && reservation.ID == id {
+			continue
+		}
+		others = append(others, reservation)
+	}
+	hostConfig, err := utils.GetHostConfig()
+	if err != nil {
+		return err
+	}
+	err = checkOvercommit(*capacity, sumReservations(others), requested, hostConfig.OvercommitThreshold)
+	if err == nil {
+		return nil
+	}
+	if hostConfig.OvercommitPolicy == OvercommitPolicyRefuse {
+		return errors.Wrapf(err, "%s %s would overcommit the host", kind, id)
+	}
+	log.Warnf("%s %s overcommits the host: %v", kind, id, err)
+	return nil
+}
+
+// ValidateHostConfig checks the values of the host config
+func ValidateHostConfig(hostConfig config.HostConfig) error {
+	if hostConfig.OvercommitThreshold < 0 {
+		return errors.Errorf("overcommit threshold can't be negative")
+	}
+	switch hostConfig.OvercommitPolicy {
+	case "", OvercommitPolicyWarn, OvercommitPolicyRefuse:
+	default:
+		return errors.Errorf("unknown overcommit policy %q", hostConfig.OvercommitPolicy)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package resources
+
+import (
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckOvercommit(t *testing.T) {
+	capacity := Capacity{CPUs: 4, MemoryMB: 8192}
+	reserved := config.NodeResources{CPUs: 2, MemoryMB: 4096}
+
+	if err := checkOvercommit(capacity, reserved, config.NodeResources{CPUs: 2, MemoryMB: 4096}, 0); err != nil {
+		t.Fatalf("expected the host to fit the node: %v", err)
+	}
+	if err := checkOvercommit(capacity, reserved, config.NodeResources{CPUs: 2.5}, 0); err == nil {
+		t.Fatal("expected the CPUs to be overcommitted")
+	}
+	if err := checkOvercommit(capacity, reserved, config.NodeResources{MemoryMB: 5000}, 0); err == nil {
+		t.Fatal("expected the memory to be overcommitted")
+	}
+	if err := checkOvercommit(capacity, reserved, config.NodeResources{CPUs: 1}, 0.5); err == nil {
+		t.Fatal("expected the threshold to be applied")
+	}
+	if err := checkOvercommit(capacity, reserved, config.NodeResources{CPUs: 4}, 1.5); err != nil {
+		t.Fatalf("expected a threshold above 1 to allow overcommit: %v", err)
+	}
+	// nodes that don't declare a resource aren't checked against it
+	if err := checkOvercommit(capacity, config.NodeResources{CPUs: 8}, config.NodeResources{MemoryMB: 1024}, 0); err != nil {
+		t.Fatalf("expected only the requested resources to be checked: %v", err)
+	}
+}
+
+func TestGetReservations(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeInit := func(kind string, id string, contents string) {
+		dir := filepath.Join(home, "hlf-easy", kind+"s", id)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "init.json"), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeInit("peer", "peer1", `{"id":"peer1","resources":{"cpus":1.5,"memoryMB":2048}}`)
+	writeInit("peer", "peer2", `{"id":"peer2"}`)
+	writeInit("orderer", "orderer1", `{"id":"orderer1","resources":{"cpus":1,"memoryMB":1024}}`)
+	writeInit("orderer", "orderer2", `{"id":"orderer2","limits":{"memoryMB":512}}`)
+
+	reservations, err := GetReservations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 4 {
+		t.Fatalf("expected 4 reservations, got %d", len(reservations))
+	}
+	total := sumReservations(reservations)
+	if total.CPUs != 2.5 || total.MemoryMB != 3584 {
+		t.Fatalf("unexpected total reservation %+v", total)
+	}
+}
+
+func TestReservedWithLimits(t *testing.T) {
+	requested := config.NodeResources{CPUs: 1}
+	nodeLimits := config.NodeLimits{CPUs: 2, MemoryMB: 1024}
+	if err := CheckLimits(requested, nodeLimits); err != nil {
+		t.Fatalf("expected limits above the reservation to be valid: %v", err)
+	}
+	// the memory isn't reserved, the node can use up to its limit
+	reserved := Reserved(requested, nodeLimits)
+	if reserved.CPUs != 1 || reserved.MemoryMB != 1024 {
+		t.Fatalf("unexpected reserved resources %+v", reserved)
+	}
+	if err := CheckLimits(config.NodeResources{CPUs: 2}, config.NodeLimits{CPUs: 1}); err == nil {
+		t.Fatal("expected a CPU limit below the reservation to be refused")
+	}
+	if err := CheckLimits(config.NodeResources{MemoryMB: 2048}, config.NodeLimits{MemoryMB: 1024}); err == nil {
+		t.Fatal("expected a memory limit below the reservation to be refused")
+	}
+}
@@ -0,0 +1,475 @@
+package rollover
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/certs"
+	"hlf-easy/utils"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Decisions of a rehearsal
+const (
+	DecisionGo   = "go"
+	DecisionNoGo = "no-go"
+)
+
+// Status of the checks of a node
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// Phases of a TLS CA rollover, a running node is restarted in each of them
+const (
+	// PhaseTrust adds the new TLS CA to the TLS root certificates of the nodes
+	PhaseTrust = "trust"
+	// PhaseSwap replaces the TLS certificates of the nodes with the ones of
+	// the new TLS CA
+	PhaseSwap = "swap"
+)
+
+// phases are the phases of a rollover in their order
+var phases = []string{PhaseTrust, PhaseSwap}
+
+// DefaultRestartDuration is the estimated time a node takes to restart
+const DefaultRestartDuration = 30 * time.Second
+
+// Options of a rehearsal
+type Options struct {
+	Now time.Time
+	// RestartDuration is the estimated time a node takes to restart
+	RestartDuration time.Duration
+	// StagingDir receives the certificates of the staging CAs and the
+	// reissued TLS certificates, nothing is written when it's empty
+	StagingDir string
+}
+
+// Check is the result of a check of a node
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// Node is the rehearsal of the rollover of a node
+type Node struct {
+	Kind    string `json:"kind"`
+	ID      string `json:"id"`
+	MSPID   string `json:"mspID,omitempty"`
+	Running bool   `json:"running"`
+	// TLSCA is the fingerprint of the TLS CA of the node
+	TLSCA    string  `json:"tlsCA"`
+	Channels int     `json:"channels"`
+	Restarts int     `json:"restarts"`
+	Downtime float64 `json:"downtimeSeconds"`
+	Checks   []Check `json:"checks"`
+}
+
+// CA is a TLS CA of the inventory and the staging CA that replaces it
+type CA struct {
+	Subject            string   `json:"subject"`
+	Fingerprint        string   `json:"fingerprint"`
+	StagingFingerprint string   `json:"stagingFingerprint"`
+	Nodes              []string `json:"nodes"`
+}
+
+// Report is the go/no-go report of a rehearsal
+type Report struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	Decision    string    `json:"decision"`
+	// Reasons are the failed checks of a no-go and the warnings of a go
+	Reasons []string `json:"reasons"`
+	CAs     []CA     `json:"cas"`
+	Nodes   []Node   `json:"nodes"`
+	// Downtime is the time of the rolling restarts of the running nodes,
+	// one node at a time
+	Downtime float64 `json:"downtimeSeconds"`
+}
+
+// inventoryNode is a node of the host with its TLS material
+type inventoryNode struct {
+	Node
+	dir      string
+	tlsChain []*x509.Certificate
+	tlsCA    *x509.Certificate
+	hosts    []string
+	err      error
+}
+
+type initOptions struct {
+	MSPID            string   `json:"mspID"`
+	Hosts            []string `json:"hosts"`
+	ExternalEndpoint string   `json:"externalEndpoint"`
+}
+
+type stagingCA struct {
+	current *x509.Certificate
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	nodes   []*inventoryNode
+}
+
+// Fingerprint is the SHA-256 of a certificate
+func Fingerprint(crt *x509.Certificate) string {
+	sum := sha256.Sum256(crt.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func channelsDir(kind string, nodeDir string) string {
+	if kind == "peer" {
+		return filepath.Join(nodeDir, "data/ledgersData/chains/chains")
+	}
+	return filepath.Join(nodeDir, "data/chains")
+}
+
+func countChannels(kind string, nodeDir string) int {
+	entries, err := os.ReadDir(channelsDir(kind, nodeDir))
+	if err != nil {
+		return 0
+	}
+	channels := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			channels++
+		}
+	}
+	return channels
+}
+
+// readNode reads the TLS material of a node, the nodes that can't be read
+// fail the rehearsal
+func readNode(kind string, nodeDir string) *inventoryNode {
+	n := &inventoryNode{
+		Node: Node{Kind: kind, ID: filepath.Base(nodeDir), Checks: []Check{}},
+		dir:  nodeDir,
+	}
+	if _, err := os.Stat(filepath.Join(nodeDir, "run.json")); err == nil {
+		n.Running = true
+	}
+	n.Channels = countChannels(kind, nodeDir)
+	initBytes, err := os.ReadFile(filepath.Join(nodeDir, "init.json"))
+	if err == nil {
+		initOpts := initOptions{}
+		if err := json.Unmarshal(initBytes, &initOpts); err == nil {
+			n.MSPID = initOpts.MSPID
+			n.hosts = initOpts.Hosts
+			if host, _, err := net.SplitHostPort(initOpts.ExternalEndpoint); err == nil && !utils.Contains(n.hosts, host) {
+				n.hosts = append(n.hosts, host)
+			}
+		}
+	}
+	tlsBytes, err := os.ReadFile(filepath.Join(nodeDir, "tls.crt"))
+	if err != nil {
+		n.err = errors.Wrap(err, "failed to read the TLS certificate")
+		return n
+	}
+	n.tlsChain, err = utils.ParseX509CertificateChain(tlsBytes)
+	if err != nil || len(n.tlsChain) == 0 {
+		n.err = errors.New("failed to parse the TLS certificate")
+		return n
+	}
+	caBytes, err := os.ReadFile(filepath.Join(nodeDir, "tlscacerts/cacert.pem"))
+	if err != nil {
+		n.err = errors.Wrap(err, "failed to read the TLS CA certificate")
+		return n
+	}
+	n.tlsCA, err = utils.ParseX509Certificate(caBytes)
+	if err != nil {
+		n.err = errors.Wrap(err, "failed to parse the TLS CA certificate")
+		return n
+	}
+	n.TLSCA = Fingerprint(n.tlsCA)
+	return n
+}
+
+// readInventory reads the peers and orderers of the host
+func readInventory() ([]*inventoryNode, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*inventoryNode{}
+	for _, kind := range []string{"peer", "orderer"} {
+		nodeDirs, err := filepath.Glob(filepath.Join(home, fmt.Sprintf("hlf-easy/%ss/*", kind)))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(nodeDirs)
+		for _, nodeDir := range nodeDirs {
+			if _, err := os.Stat(filepath.Join(nodeDir, "config.json")); err != nil {
+				// not enrolled yet, e.g. waiting for the CSR to be signed
+				continue
+			}
+			nodes = append(nodes, readNode(kind, nodeDir))
+		}
+	}
+	return nodes, nil
+}
+
+// newStagingCA creates a CA with the subject of a TLS CA, it only lives in
+// memory unless the rehearsal has a staging directory
+func newStagingCA(current *x509.Certificate, now time.Time) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	subject := current.Subject
+	subject.CommonName = strings.TrimSpace(subject.CommonName + " staging")
+	raw := elliptic.Marshal(key.Curve, key.PublicKey.X, key.PublicKey.Y)
+	ski := sha256.Sum256(raw)
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name(subject),
+		NotBefore:             now.AddDate(0, 0, -1),
+		NotAfter:              now.AddDate(10, 0, 0),
+		IsCA:                  true,
+		SubjectKeyId:          ski[:],
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	crt, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return crt, key, nil
+}
+
+// reissue issues the TLS certificate of a node from the staging CA with the
+// names and usages of its current certificate
+func reissue(current *x509.Certificate, ca *stagingCA) (*x509.Certificate, error) {
+	crt, _, err := certs.GenerateCertificate(certs.GenerateCertificateOptions{
+		CommonName:       current.Subject.CommonName,
+		OrganizationUnit: current.Subject.OrganizationalUnit,
+		IPAddresses:      current.IPAddresses,
+		DNSNames:         current.DNSNames,
+		Validity:         current.NotAfter.Sub(current.NotBefore),
+		KeyUsage:         current.KeyUsage,
+		ExtKeyUsage:      current.ExtKeyUsage,
+	}, ca.cert, ca.key)
+	return crt, err
+}
+
+func newPool(crts ...*x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, crt := range crts {
+		pool.AddCert(crt)
+	}
+	return pool
+}
+
+func verify(crt *x509.Certificate, roots *x509.CertPool, intermediates []*x509.Certificate, now time.Time) error {
+	_, err := crt.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: newPool(intermediates...),
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+func (n *inventoryNode) check(name string, err error, message string) {
+	if err != nil {
+		n.Checks = append(n.Checks, Check{Name: name, Status: StatusFail, Message: err.Error()})
+		return
+	}
+	n.Checks = append(n.Checks, Check{Name: name, Status: StatusPass, Message: message})
+}
+
+func (n *inventoryNode) warn(name string, message string) {
+	n.Checks = append(n.Checks, Check{Name: name, Status: StatusWarn, Message: message})
+}
+
+// rehearseNode checks that the node is trusted in both phases of the
+// rollover with its current and reissued certificates
+func rehearseNode(n *inventoryNode, ca *stagingCA, now time.Time, stagingDir string) {
+	leaf := n.tlsChain[0]
+	intermediates := n.tlsChain[1:]
+	n.check("current-chain", verify(leaf, newPool(ca.current), intermediates, now), "the current TLS certificate chains to the TLS CA")
+	if len(intermediates) > 0 {
+		n.warn("intermediates", "the TLS certificate is issued by an intermediate CA, it must be rolled over too")
+	}
+	reissued, err := reissue(leaf, ca)
+	if err != nil {
+		n.check("reissue", errors.Wrap(err, "failed to reissue the TLS certificate"), "")
+		return
+	}
+	n.check("reissue", nil, fmt.Sprintf("reissued by the staging CA until %s", reissued.NotAfter.Format(time.RFC3339)))
+	// during the trust phase the nodes still present their current
+	// certificates and trust both CAs
+	transition := newPool(ca.current, ca.cert)
+	err = verify(leaf, transition, intermediates, now)
+	if err == nil {
+		err = verify(reissued, transition, nil, now)
+	}
+	n.check("trust-phase", err, "the current and reissued certificates are trusted with both CAs")
+	n.check("swap-phase", verify(reissued, newPool(ca.cert), nil, now), "the reissued certificate is trusted with the staging CA only")
+	var hostErr error
+	for _, host := range n.hosts {
+		if err := reissued.VerifyHostname(host); err != nil {
+			hostErr = errors.Errorf("the reissued certificate isn't valid for %s", host)
+			break
+		}
+	}
+	n.check("hosts", hostErr, fmt.Sprintf("the reissued certificate is valid for %s", strings.Join(n.hosts, ", ")))
+	if stagingDir != "" {
+		err = os.WriteFile(filepath.Join(stagingDir, fmt.Sprintf("%s-%s-tls.crt", n.Kind, n.ID)), utils.EncodeX509Certificate(reissued), 0644)
+		n.check("staging", err, "the reissued certificate is written to the staging directory")
+	}
+}
+
+// Rehearse simulates a TLS CA rollover of the peers and orderers of the host:
+// every TLS CA is replaced by a staging CA, the TLS certificates are
+// reissued by them and checked against the trust of the nodes during and
+// after the rollover. The material of the nodes isn't changed
+func Rehearse(opts Options) (*Report, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.RestartDuration == 0 {
+		opts.RestartDuration = DefaultRestartDuration
+	}
+	if opts.StagingDir != "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		stagingDir, err := filepath.Abs(opts.StagingDir)
+		if err != nil {
+			return nil, err
+		}
+		if rel, err := filepath.Rel(filepath.Join(home, "hlf-easy"), stagingDir); err == nil && !strings.HasPrefix(rel, "..") {
+			return nil, errors.Errorf("the staging directory %s can't be in the directory of the nodes", opts.StagingDir)
+		}
+		err = os.MkdirAll(stagingDir, 0755)
+		if err != nil {
+			return nil, err
+		}
+		opts.StagingDir = stagingDir
+	}
+	inventory, err := readInventory()
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{
+		GeneratedAt: opts.Now.UTC(),
+		Reasons:     []string{},
+		CAs:         []CA{},
+		Nodes:       []Node{},
+	}
+	cas := map[string]*stagingCA{}
+	fingerprints := []string{}
+	for _, n := range inventory {
+		if n.err != nil {
+			n.check("tls-material", n.err, "")
+			continue
+		}
+		ca, ok := cas[n.TLSCA]
+		if !ok {
+			crt, key, err := newStagingCA(n.tlsCA, opts.Now)
+			if err != nil {
+				return nil, err
+			}
+			ca = &stagingCA{current: n.tlsCA, cert: crt, key: key}
+			cas[n.TLSCA] = ca
+			fingerprints = append(fingerprints, n.TLSCA)
+		}
+		ca.nodes = append(ca.nodes, n)
+		rehearseNode(n, ca, opts.Now, opts.StagingDir)
+	}
+	for _, fingerprint := range fingerprints {
+		ca := cas[fingerprint]
+		names := []string{}
+		for _, n := range ca.nodes {
+			names = append(names, n.Kind+"/"+n.ID)
+		}
+		report.CAs = append(report.CAs, CA{
+			Subject:            ca.current.Subject.String(),
+			Fingerprint:        fingerprint,
+			StagingFingerprint: Fingerprint(ca.cert),
+			Nodes:              names,
+		})
+		if opts.StagingDir != "" {
+			err = os.WriteFile(filepath.Join(opts.StagingDir, fmt.Sprintf("staging-ca-%s.crt", fingerprint[:16])), utils.EncodeX509Certificate(ca.cert), 0644)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(cas) > 1 {
+		report.Reasons = append(report.Reasons, fmt.Sprintf("%d TLS CAs are rolled over, the staging CAs must be added to the channel configs before the swap phase", len(cas)))
+	}
+	estimateRestarts(report, inventory, opts.RestartDuration)
+	report.Decision = DecisionGo
+	if len(inventory) == 0 {
+		report.Decision = DecisionNoGo
+		report.Reasons = append(report.Reasons, "no peer or orderer in the inventory")
+	}
+	for _, n := range inventory {
+		for _, c := range n.Checks {
+			if c.Status == StatusFail {
+				report.Decision = DecisionNoGo
+				report.Reasons = append(report.Reasons, fmt.Sprintf("%s/%s %s: %s", n.Kind, n.ID, c.Name, c.Message))
+			}
+		}
+		report.Nodes = append(report.Nodes, n.Node)
+	}
+	return report, nil
+}
+
+// estimateRestarts estimates the downtime of the running nodes, they're
+// restarted in both phases, one at a time. The orgs with a single running
+// peer can't endorse during its restarts
+func estimateRestarts(report *Report, inventory []*inventoryNode, restartDuration time.Duration) {
+	peersByOrg := map[string]int{}
+	for _, n := range inventory {
+		if n.Running && n.Kind == "peer" && n.MSPID != "" {
+			peersByOrg[n.MSPID]++
+		}
+	}
+	for _, n := range inventory {
+		if !n.Running {
+			continue
+		}
+		n.Restarts = len(phases)
+		n.Downtime = (time.Duration(n.Restarts) * restartDuration).Seconds()
+		report.Downtime += n.Downtime
+		if n.Kind == "peer" && peersByOrg[n.MSPID] == 1 {
+			n.warn("availability", fmt.Sprintf("%s has no other running peer on the host, it can't endorse during the restarts", n.MSPID))
+		}
+	}
+	orgs := []string{}
+	for mspID, peers := range peersByOrg {
+		if peers == 1 {
+			orgs = append(orgs, mspID)
+		}
+	}
+	sort.Strings(orgs)
+	for _, mspID := range orgs {
+		report.Reasons = append(report.Reasons, fmt.Sprintf("%s has a single running peer on the host, its endorsements are unavailable during its restarts", mspID))
+	}
+}
@@ -0,0 +1,121 @@
+package rollover
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"hlf-easy/certs"
+	"hlf-easy/internal/testca"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testNode struct {
+	kind, id, mspID, host string
+	running               bool
+	issuer                *x509.Certificate
+	issuerKey             *ecdsa.PrivateKey
+	tlsCA                 *x509.Certificate
+}
+
+func writeTestNode(t *testing.T, home string, n testNode) string {
+	t.Helper()
+	nodeDir := filepath.Join(home, "hlf-easy", n.kind+"s", n.id)
+	err := os.MkdirAll(filepath.Join(nodeDir, "tlscacerts"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crt, _, err := certs.GenerateCertificate(certs.GenerateCertificateOptions{CommonName: n.id, DNSNames: []string{n.host}}, n.issuer, n.issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initBytes, _ := json.Marshal(map[string]interface{}{"mspID": n.mspID, "hosts": []string{n.host}, "externalEndpoint": n.host + ":7051"})
+	files := map[string][]byte{
+		"config.json":           []byte("{}"),
+		"init.json":             initBytes,
+		"tls.crt":               utils.EncodeX509Certificate(crt),
+		"tlscacerts/cacert.pem": utils.EncodeX509Certificate(n.tlsCA),
+	}
+	if n.running {
+		files["run.json"] = []byte("{}")
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(nodeDir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return nodeDir
+}
+
+func TestRehearse(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	tlsCA, tlsCAKey := testca.Issue(t, testca.Cert{CommonName: "tlsca", NotAfter: time.Now().AddDate(5, 0, 0)}, nil, nil)
+	otherCA, otherCAKey := testca.Issue(t, testca.Cert{CommonName: "other", NotAfter: time.Now().AddDate(5, 0, 0)}, nil, nil)
+	writeTestNode(t, home, testNode{kind: "peer", id: "peer0", mspID: "Org1MSP", host: "peer0.example.com", running: true, issuer: tlsCA, issuerKey: tlsCAKey, tlsCA: tlsCA})
+	writeTestNode(t, home, testNode{kind: "peer", id: "peer1", mspID: "Org1MSP", host: "peer1.example.com", issuer: tlsCA, issuerKey: tlsCAKey, tlsCA: tlsCA})
+	// the TLS certificate of the orderer isn't issued by its TLS CA
+	ordererDir := writeTestNode(t, home, testNode{kind: "orderer", id: "orderer0", host: "orderer0.example.com", running: true, issuer: otherCA, issuerKey: otherCAKey, tlsCA: tlsCA})
+
+	report, err := Rehearse(Options{RestartDuration: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Decision != DecisionNoGo {
+		t.Fatalf("expected a no-go, got %+v", report)
+	}
+	if len(report.CAs) != 1 || len(report.CAs[0].Nodes) != 3 {
+		t.Fatalf("expected the nodes to share a TLS CA, got %+v", report.CAs)
+	}
+	reasons := strings.Join(report.Reasons, "\n")
+	if !strings.Contains(reasons, "orderer/orderer0 current-chain") {
+		t.Errorf("expected the orderer chain to fail, got %s", reasons)
+	}
+	if !strings.Contains(reasons, "Org1MSP has a single running peer") {
+		t.Errorf("expected the availability of Org1MSP to be reported, got %s", reasons)
+	}
+	if report.Downtime != 40 {
+		t.Errorf("expected 40s of downtime for 2 running nodes, got %v", report.Downtime)
+	}
+
+	err = os.WriteFile(filepath.Join(ordererDir, "tlscacerts/cacert.pem"), utils.EncodeX509Certificate(otherCA), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stagingDir := filepath.Join(t.TempDir(), "staging")
+	report, err = Rehearse(Options{StagingDir: stagingDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Decision != DecisionGo {
+		t.Fatalf("expected a go, got %v", report.Reasons)
+	}
+	if len(report.CAs) != 2 {
+		t.Fatalf("expected 2 TLS CAs, got %+v", report.CAs)
+	}
+	for _, n := range report.Nodes {
+		for _, c := range n.Checks {
+			if c.Status == StatusFail {
+				t.Errorf("unexpected failed check %s of %s: %s", c.Name, n.ID, c.Message)
+			}
+		}
+	}
+	staged, err := filepath.Glob(filepath.Join(stagingDir, "*.crt"))
+	if err != nil || len(staged) != 5 {
+		t.Fatalf("expected 2 staging CAs and 3 certificates, got %v", staged)
+	}
+	// the material of the nodes is unchanged
+	tlsBytes, err := os.ReadFile(filepath.Join(ordererDir, "tlscacerts/cacert.pem"))
+	if err != nil || string(tlsBytes) != string(utils.EncodeX509Certificate(otherCA)) {
+		t.Fatal("expected the TLS CA of the orderer to be unchanged")
+	}
+
+	_, err = Rehearse(Options{StagingDir: filepath.Join(home, "hlf-easy/staging")})
+	if err == nil {
+		t.Fatal("expected the staging directory to be refused in the directory of the nodes")
+	}
+}
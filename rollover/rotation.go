@@ -0,0 +1,214 @@
+package rollover
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Steps of the rotation of the root of a local CA, the rotation is in a step
+// once it's done
+const (
+	// StepIssued creates the new root, the nodes trust the new and the
+	// previous root
+	StepIssued = "issued"
+	// StepPropagated adds the new root to the MSP of the org in its channels
+	StepPropagated = "propagated"
+	// StepReissued issues the certificates of the nodes by the new root
+	StepReissued = "reissued"
+	// StepRetired removes the previous root from the MSP of the org in its
+	// channels and from the nodes
+	StepRetired = "retired"
+)
+
+// steps are the steps of a rotation in their order
+var steps = []string{StepIssued, StepPropagated, StepReissued, StepRetired}
+
+// RotationNode is a node of the org whose certificate is issued again by the
+// new root
+type RotationNode struct {
+	Kind     string `json:"kind"`
+	ID       string `json:"id"`
+	Reissued bool   `json:"reissued"`
+}
+
+// RotationChannel is a channel of the org and the peer its config is read
+// from, Step is the last step applied to its config
+type RotationChannel struct {
+	Name string `json:"name"`
+	Peer string `json:"peer"`
+	Step string `json:"step,omitempty"`
+}
+
+// Rotation is the state of the rotation of the root of a local CA, kept in
+// the directory of the CA across the steps
+type Rotation struct {
+	CA        string    `json:"ca"`
+	Kind      string    `json:"kind"`
+	MSPID     string    `json:"mspID"`
+	Step      string    `json:"step"`
+	StartedAt time.Time `json:"startedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// PreviousRoots are the roots in PEM replaced by the new one, removed
+	// from the channels when the rotation is retired
+	PreviousRoots [][]byte          `json:"previousRoots"`
+	Nodes         []RotationNode    `json:"nodes"`
+	Channels      []RotationChannel `json:"channels"`
+}
+
+func rotationPath(caName string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fmt.Sprintf("hlf-easy/cas/%s/rotation.json", caName)), nil
+}
+
+// GetRotation reads the rotation of the root of a local CA, the last one when
+// it's retired
+func GetRotation(caName string) (*Rotation, error) {
+	path, err := rotationPath(caName)
+	if err != nil {
+		return nil, err
+	}
+	rotationBytes, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Errorf("no rotation of %s was started, start one with hlf-easy ca rotation start", caName)
+		}
+		return nil, err
+	}
+	r := &Rotation{}
+	err = json.Unmarshal(rotationBytes, r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+	return r, nil
+}
+
+// NewRotation returns the state of a new rotation of the root of a local CA,
+// it fails while another rotation of the CA is in progress
+func NewRotation(caName string, kind string, mspID string, now time.Time) (*Rotation, error) {
+	if existing, err := GetRotation(caName); err == nil && existing.Step != StepRetired {
+		return nil, errors.Errorf("the rotation of the %s root of %s is %s, continue it with hlf-easy ca rotation %s", existing.Kind, caName, existing.Step, existing.Next())
+	}
+	channels, err := OrgChannels(mspID)
+	if err != nil {
+		return nil, err
+	}
+	return &Rotation{
+		CA:        caName,
+		Kind:      kind,
+		MSPID:     mspID,
+		StartedAt: now.UTC(),
+		UpdatedAt: now.UTC(),
+		Nodes:     []RotationNode{},
+		Channels:  channels,
+	}, nil
+}
+
+// Save writes the state of the rotation
+func (r *Rotation) Save() error {
+	path, err := rotationPath(r.CA)
+	if err != nil {
+		return err
+	}
+	rotationBytes, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, rotationBytes, 0644)
+}
+
+// Next returns the step that follows the one of the rotation, empty once the
+// rotation is retired
+func (r *Rotation) Next() string {
+	for i, step := range steps {
+		if step == r.Step && i+1 < len(steps) {
+			return steps[i+1]
+		}
+	}
+	if r.Step == "" {
+		return steps[0]
+	}
+	return ""
+}
+
+// Check fails when a step isn't the next one of the rotation
+func (r *Rotation) Check(step string) error {
+	next := r.Next()
+	if next == "" {
+		return errors.Errorf("the rotation of the %s root of %s is already retired", r.Kind, r.CA)
+	}
+	if step != next {
+		return errors.Errorf("the rotation of the %s root of %s is %s, the next step is %s", r.Kind, r.CA, r.Step, next)
+	}
+	return nil
+}
+
+// Advance moves the rotation to a step, which must be the next one
+func (r *Rotation) Advance(step string, now time.Time) error {
+	if err := r.Check(step); err != nil {
+		return err
+	}
+	r.Step = step
+	r.UpdatedAt = now.UTC()
+	return nil
+}
+
+// PendingChannels returns the channels a step isn't applied to yet
+func (r *Rotation) PendingChannels(step string) []*RotationChannel {
+	pending := []*RotationChannel{}
+	for i := range r.Channels {
+		if r.Channels[i].Step != step {
+			pending = append(pending, &r.Channels[i])
+		}
+	}
+	return pending
+}
+
+// OrgChannels returns the channels joined by the peers of an org on the host,
+// each with the first peer that joined it
+func OrgChannels(mspID string) ([]RotationChannel, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	peerDirs, err := filepath.Glob(filepath.Join(home, "hlf-easy/peers/*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(peerDirs)
+	peers := map[string]string{}
+	for _, peerDir := range peerDirs {
+		initBytes, err := os.ReadFile(filepath.Join(peerDir, "init.json"))
+		if err != nil {
+			continue
+		}
+		initOpts := initOptions{}
+		if err := json.Unmarshal(initBytes, &initOpts); err != nil || initOpts.MSPID != mspID {
+			continue
+		}
+		entries, err := os.ReadDir(channelsDir("peer", peerDir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if _, ok := peers[entry.Name()]; entry.IsDir() && !ok {
+				peers[entry.Name()] = filepath.Base(peerDir)
+			}
+		}
+	}
+	channels := []RotationChannel{}
+	for name, peer := range peers {
+		channels = append(channels, RotationChannel{Name: name, Peer: peer})
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].Name < channels[j].Name
+	})
+	return channels, nil
+}
@@ -0,0 +1,80 @@
+package rollover
+
+import (
+	"hlf-easy/internal/testca"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotation(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	tlsCA, tlsCAKey := testca.Issue(t, testca.Cert{CommonName: "tlsca", NotAfter: time.Now().AddDate(5, 0, 0)}, nil, nil)
+	peer0 := writeTestNode(t, home, testNode{kind: "peer", id: "peer0", mspID: "Org1MSP", host: "peer0.example.com", issuer: tlsCA, issuerKey: tlsCAKey, tlsCA: tlsCA})
+	peer1 := writeTestNode(t, home, testNode{kind: "peer", id: "peer1", mspID: "Org1MSP", host: "peer1.example.com", issuer: tlsCA, issuerKey: tlsCAKey, tlsCA: tlsCA})
+	other := writeTestNode(t, home, testNode{kind: "peer", id: "peer2", mspID: "Org2MSP", host: "peer2.example.com", issuer: tlsCA, issuerKey: tlsCAKey, tlsCA: tlsCA})
+	for nodeDir, channels := range map[string][]string{peer0: {"demo"}, peer1: {"demo", "other"}, other: {"org2"}} {
+		for _, channel := range channels {
+			if err := os.MkdirAll(filepath.Join(nodeDir, "data/ledgersData/chains/chains", channel), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(home, "hlf-easy/cas/org1-ca"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GetRotation("org1-ca"); err == nil {
+		t.Fatal("expected an error when no rotation was started")
+	}
+	now := time.Now()
+	r, err := NewRotation("org1-ca", "sign", "Org1MSP", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []RotationChannel{{Name: "demo", Peer: "peer0"}, {Name: "other", Peer: "peer1"}}
+	if len(r.Channels) != len(expected) || r.Channels[0] != expected[0] || r.Channels[1] != expected[1] {
+		t.Fatalf("expected the channels %v, got %v", expected, r.Channels)
+	}
+	if err := r.Advance(StepPropagated, now); err == nil {
+		t.Fatal("expected an error when a step is skipped")
+	}
+	if err := r.Advance(StepIssued, now); err != nil {
+		t.Fatal(err)
+	}
+	r.Channels[0].Step = StepPropagated
+	if pending := r.PendingChannels(StepPropagated); len(pending) != 1 || pending[0].Name != "other" {
+		t.Fatalf("expected the channel other to be pending, got %v", pending)
+	}
+	if err := r.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRotation("org1-ca", "tls", "Org1MSP", now); err == nil {
+		t.Fatal("expected an error while a rotation is in progress")
+	}
+
+	saved, err := GetRotation("org1-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Step != StepIssued || saved.Next() != StepPropagated || saved.Channels[0].Step != StepPropagated {
+		t.Fatalf("expected the saved rotation to be issued, got %+v", saved)
+	}
+	for _, step := range []string{StepPropagated, StepReissued, StepRetired} {
+		if err := saved.Advance(step, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if saved.Next() != "" {
+		t.Fatalf("expected no step after %s, got %s", saved.Step, saved.Next())
+	}
+	if err := saved.Save(); err != nil {
+		t.Fatal(err)
+	}
+	// a new rotation can start once the previous one is retired
+	if _, err := NewRotation("org1-ca", "tls", "Org1MSP", now); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,77 @@
+// Package rpc is the gRPC management API of the daemon, hlfeasy.proto defines
+// it and the Go server and client are generated from it
+package rpc
+
+//go:generate protoc --proto_path=.. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative rpc/hlfeasy.proto
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"hlf-easy/audit"
+	"hlf-easy/auth"
+	"hlf-easy/config"
+	"hlf-easy/daemon"
+	"path/filepath"
+)
+
+// methodPermissions are the permissions of the methods, the methods that
+// only read aren't recorded in the audit log
+var methodPermissions = map[string]auth.Permission{
+	HLFEasy_ListNodes_FullMethodName:         {Resource: auth.ResourceNodes, Action: auth.ActionRead},
+	HLFEasy_AddNode_FullMethodName:           {Resource: auth.ResourceNodes, Action: auth.ActionWrite},
+	HLFEasy_RemoveNode_FullMethodName:        {Resource: auth.ResourceNodes, Action: auth.ActionWrite},
+	HLFEasy_StartNode_FullMethodName:         {Resource: auth.ResourceNodes, Action: auth.ActionWrite},
+	HLFEasy_StopNode_FullMethodName:          {Resource: auth.ResourceNodes, Action: auth.ActionWrite},
+	HLFEasy_RestartNode_FullMethodName:       {Resource: auth.ResourceNodes, Action: auth.ActionWrite},
+	HLFEasy_Enroll_FullMethodName:            {Resource: auth.ResourceIdentities, Action: auth.ActionWrite},
+	HLFEasy_CreateChannel_FullMethodName:     {Resource: auth.ResourceChannels, Action: auth.ActionWrite},
+	HLFEasy_JoinChannel_FullMethodName:       {Resource: auth.ResourceChannels, Action: auth.ActionWrite},
+	HLFEasy_ListChaincodes_FullMethodName:    {Resource: auth.ResourceChaincodes, Action: auth.ActionRead},
+	HLFEasy_RegisterChaincode_FullMethodName: {Resource: auth.ResourceChaincodes, Action: auth.ActionWrite},
+	HLFEasy_InvokeChaincode_FullMethodName:   {Resource: auth.ResourceChaincodes, Action: auth.ActionWrite},
+	HLFEasy_QueryChaincode_FullMethodName:    {Resource: auth.ResourceChaincodes, Action: auth.ActionRead},
+}
+
+// Permission returns the permission of a method
+func Permission(fullMethod string) (auth.Permission, bool) {
+	p, ok := methodPermissions[fullMethod]
+	return p, ok
+}
+
+// ReadOnly tells whether a method only reads the state of the host
+func ReadOnly(fullMethod string) bool {
+	return methodPermissions[fullMethod].Action == auth.ActionRead
+}
+
+// DefaultSocket is the Unix socket the daemon serves the gRPC API on,
+// daemon/grpc.sock in $HOME/hlf-easy
+func DefaultSocket() (string, error) {
+	dir, err := daemon.GetDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "grpc.sock"), nil
+}
+
+// NewGRPCServer returns the gRPC server of the API, the calls on the Unix
+// socket of the daemon are authorized by the permissions of the socket and
+// the ones on TCP by the auth options
+func NewGRPCServer(s *Server, authOpts config.APIAuthOptions, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	authenticator, err := auth.NewAuthenticator(authOpts, nil)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(
+		audit.UnaryInterceptor("daemon", ReadOnly),
+		authenticator.UnaryInterceptor(Permission),
+	))
+	srv := grpc.NewServer(opts...)
+	RegisterHLFEasyServer(srv, s)
+	return srv, nil
+}
+
+// DialSocket connects to the gRPC API of a daemon on its Unix socket, the
+// client is NewHLFEasyClient(conn)
+func DialSocket(socket string) (*grpc.ClientConn, error) {
+	return grpc.Dial("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
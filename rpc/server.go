@@ -0,0 +1,299 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/chaincode"
+	"hlf-easy/contract"
+	"hlf-easy/daemon"
+	"hlf-easy/errdefs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Server implements the gRPC API with the daemon, the enrollments, channels
+// and chaincode registrations are run by the hlf-easy commands
+type Server struct {
+	UnimplementedHLFEasyServer
+	daemon *daemon.Daemon
+	// execute runs an hlf-easy command and returns its output, the tests
+	// replace it
+	execute func(ctx context.Context, args []string) ([]byte, error)
+}
+
+// NewServer returns the gRPC API of a daemon
+func NewServer(d *daemon.Daemon) *Server {
+	return &Server{daemon: d, execute: execute}
+}
+
+// execute runs hlf-easy with args, a failure has the kind of the exit code
+// and the error printed by the command
+func execute(ctx context.Context, args []string) ([]byte, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, executable, args...)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	exitErr := &exec.ExitError{}
+	if !errors.As(err, &exitErr) {
+		return out, err
+	}
+	msg := strings.TrimSpace(stderr.String())
+	for _, line := range strings.Split(msg, "\n") {
+		if strings.HasPrefix(line, "Error: ") {
+			msg = strings.TrimPrefix(line, "Error: ")
+		}
+	}
+	return nil, errdefs.FromExitCode(exitErr.ExitCode(), msg)
+}
+
+// statusError returns the status of an error with the gRPC code of its kind
+func statusError(err error) error {
+	return status.Error(errdefs.GRPCCode(err), err.Error())
+}
+
+// required returns an invalid argument status for the first empty field
+func required(fields ...[2]string) error {
+	for _, field := range fields {
+		if field[1] == "" {
+			return status.Errorf(codes.InvalidArgument, "%s is required", field[0])
+		}
+	}
+	return nil
+}
+
+func toNode(n daemon.NodeStatus) *Node {
+	node := &Node{
+		Kind:     n.Kind,
+		Id:       n.ID,
+		Args:     n.Args,
+		State:    string(n.State),
+		Restarts: int32(n.Restarts),
+	}
+	if n.Process != nil {
+		node.Pid = int32(n.Process.PID)
+		node.Uptime = n.Process.Uptime
+	}
+	return node
+}
+
+func (s *Server) ListNodes(ctx context.Context, req *ListNodesRequest) (*ListNodesResponse, error) {
+	resp := &ListNodesResponse{}
+	for _, n := range s.daemon.Nodes() {
+		resp.Nodes = append(resp.Nodes, toNode(n))
+	}
+	return resp, nil
+}
+
+// node returns the response of the calls on a node
+func (s *Server) node(kind string, id string) (*NodeResponse, error) {
+	n, err := s.daemon.Node(kind, id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &NodeResponse{Node: toNode(n)}, nil
+}
+
+func (s *Server) AddNode(ctx context.Context, req *AddNodeRequest) (*NodeResponse, error) {
+	err := s.daemon.Add(daemon.NodeSpec{Kind: req.Kind, ID: req.Id, Args: req.Args})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return s.node(req.Kind, req.Id)
+}
+
+func (s *Server) RemoveNode(ctx context.Context, req *NodeRequest) (*RemoveNodeResponse, error) {
+	err := s.daemon.Remove(req.Kind, req.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &RemoveNodeResponse{}, nil
+}
+
+// action starts, stops or restarts a node and returns it
+func (s *Server) action(req *NodeRequest, action string) (*NodeResponse, error) {
+	err := s.daemon.Action(req.Kind, req.Id, action)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return s.node(req.Kind, req.Id)
+}
+
+func (s *Server) StartNode(ctx context.Context, req *NodeRequest) (*NodeResponse, error) {
+	return s.action(req, "start")
+}
+
+func (s *Server) StopNode(ctx context.Context, req *NodeRequest) (*NodeResponse, error) {
+	return s.action(req, "stop")
+}
+
+func (s *Server) RestartNode(ctx context.Context, req *NodeRequest) (*NodeResponse, error) {
+	return s.action(req, "restart")
+}
+
+func (s *Server) Enroll(ctx context.Context, req *EnrollRequest) (*EnrollResponse, error) {
+	if err := required([2]string{"ca_name", req.CaName}, [2]string{"common_name", req.CommonName}); err != nil {
+		return nil, err
+	}
+	args := []string{"ca", "enroll", "--name", req.CaName, "--type", req.Type, "--common-name", req.CommonName}
+	if len(req.Hosts) > 0 {
+		args = append(args, "--hosts", strings.Join(req.Hosts, ","))
+	}
+	if req.Tls {
+		args = append(args, "--tls")
+	}
+	out, err := s.execute(ctx, args)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	identity := struct {
+		Key struct {
+			PEM string `yaml:"pem"`
+		} `yaml:"key"`
+		Cert struct {
+			PEM string `yaml:"pem"`
+		} `yaml:"cert"`
+	}{}
+	err = yaml.Unmarshal(out, &identity)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid identity returned by ca enroll: %v", err)
+	}
+	return &EnrollResponse{Certificate: identity.Cert.PEM, PrivateKey: identity.Key.PEM}, nil
+}
+
+func (s *Server) CreateChannel(ctx context.Context, req *CreateChannelRequest) (*CreateChannelResponse, error) {
+	dir, err := os.MkdirTemp("", "hlf-easy-channel")
+	if err != nil {
+		return nil, statusError(err)
+	}
+	defer os.RemoveAll(dir)
+	blockPath := filepath.Join(dir, "genesis.block")
+	args := []string{"channel", "create", "--channel", req.Channel, "--msp-id", req.MspId, "--ca-name", req.CaName, "--orderer-bundle", req.OrdererBundle, "-o", blockPath}
+	for _, anchorPeer := range req.AnchorPeers {
+		args = append(args, "--anchor-peers", anchorPeer)
+	}
+	if req.Submit {
+		args = append(args, "--submit", "--admin-tls-cert", req.AdminTlsCert, "--admin-tls-key", req.AdminTlsKey)
+	}
+	out, err := s.execute(ctx, args)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	block, err := os.ReadFile(blockPath)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &CreateChannelResponse{GenesisBlock: block, Output: string(out)}, nil
+}
+
+func (s *Server) JoinChannel(ctx context.Context, req *JoinChannelRequest) (*CommandResponse, error) {
+	args := []string{"peer", "join", "--id", req.PeerId, "--channel", req.Channel}
+	for _, ordererURL := range req.OrdererUrls {
+		args = append(args, "--orderer-url", ordererURL)
+	}
+	for _, flag := range [][2]string{
+		{"--orderer-tls-cert", req.OrdererTlsCert},
+		{"--orderer-bundle", req.OrdererBundle},
+		{"--identity", req.Identity},
+	} {
+		if flag[1] != "" {
+			args = append(args, flag[0], flag[1])
+		}
+	}
+	out, err := s.execute(ctx, args)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &CommandResponse{Output: string(out)}, nil
+}
+
+func (s *Server) ListChaincodes(ctx context.Context, req *ListChaincodesRequest) (*ListChaincodesResponse, error) {
+	definitions, err := chaincode.List()
+	if err != nil {
+		return nil, statusError(err)
+	}
+	resp := &ListChaincodesResponse{}
+	for _, d := range definitions {
+		resp.Chaincodes = append(resp.Chaincodes, &Chaincode{
+			Name:    d.Name,
+			Version: d.Version,
+			Type:    d.Type,
+			Address: d.Address,
+			Image:   d.Image,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) RegisterChaincode(ctx context.Context, req *RegisterChaincodeRequest) (*CommandResponse, error) {
+	args := []string{"chaincode", "register", "--name", req.Name}
+	// the defaults of the command are kept for the empty fields
+	for _, flag := range [][2]string{
+		{"--version", req.Version},
+		{"--type", req.Type},
+		{"--address", req.Address},
+		{"--image", req.Image},
+		{"--execute-timeout", req.ExecuteTimeout},
+		{"--dial-timeout", req.DialTimeout},
+	} {
+		if flag[1] != "" {
+			args = append(args, flag[0], flag[1])
+		}
+	}
+	out, err := s.execute(ctx, args)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &CommandResponse{Output: string(out)}, nil
+}
+
+// transaction returns the options of the transaction of a chaincode request
+func transaction(req *ChaincodeRequest) (contract.Options, error) {
+	err := required(
+		[2]string{"peer_id", req.PeerId},
+		[2]string{"channel", req.Channel},
+		[2]string{"name", req.Name},
+		[2]string{"function", req.Function},
+	)
+	return contract.Options{
+		PeerID:    req.PeerId,
+		Identity:  req.Identity,
+		Channel:   req.Channel,
+		Chaincode: req.Name,
+		Function:  req.Function,
+		Args:      req.Args,
+	}, err
+}
+
+func (s *Server) InvokeChaincode(ctx context.Context, req *ChaincodeRequest) (*ChaincodeResponse, error) {
+	opts, err := transaction(req)
+	if err != nil {
+		return nil, err
+	}
+	result, err := contract.Invoke(ctx, opts)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &ChaincodeResponse{Result: result}, nil
+}
+
+func (s *Server) QueryChaincode(ctx context.Context, req *ChaincodeRequest) (*ChaincodeResponse, error) {
+	opts, err := transaction(req)
+	if err != nil {
+		return nil, err
+	}
+	result, err := contract.Query(ctx, opts)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &ChaincodeResponse{Result: result}, nil
+}
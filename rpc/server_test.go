@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"hlf-easy/audit"
+	"hlf-easy/auth"
+	"hlf-easy/config"
+	"hlf-easy/daemon"
+	"hlf-easy/errdefs"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// serve serves the API of a daemon without nodes on a Unix socket and on TCP
+// with token authentication
+func serve(t *testing.T, execute func(ctx context.Context, args []string) ([]byte, error)) (HLFEasyClient, HLFEasyClient) {
+	t.Helper()
+	d, err := daemon.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(d)
+	s.execute = execute
+	srv, err := NewGRPCServer(s, config.APIAuthOptions{Mode: auth.ModeToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(t.TempDir(), "grpc.sock")
+	socketListener, err := auth.ListenSocket(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = srv.Serve(socketListener)
+	}()
+	go func() {
+		_ = srv.Serve(tcpListener)
+	}()
+	t.Cleanup(srv.Stop)
+	socketConn, err := DialSocket(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { socketConn.Close() })
+	tcpConn, err := grpc.Dial(tcpListener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tcpConn.Close() })
+	return NewHLFEasyClient(socketConn), NewHLFEasyClient(tcpConn)
+}
+
+func TestServer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	executed := make(chan []string, 2)
+	c, _ := serve(t, func(ctx context.Context, args []string) ([]byte, error) {
+		executed <- args
+		if args[3] == "ca1" {
+			return nil, errdefs.Errorf(errdefs.ErrCANotInitialized, "ca ca1 is not initialized")
+		}
+		return []byte("cert:\n  pem: CERT\nkey:\n  pem: KEY\n"), nil
+	})
+	ctx := context.Background()
+
+	resp, err := c.Enroll(ctx, &EnrollRequest{CaName: "ca0", Type: "client", CommonName: "user1", Hosts: []string{"localhost", "127.0.0.1"}, Tls: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Certificate != "CERT" || resp.PrivateKey != "KEY" {
+		t.Errorf("expected the identity issued by ca enroll, got %+v", resp)
+	}
+	expected := []string{"ca", "enroll", "--name", "ca0", "--type", "client", "--common-name", "user1", "--hosts", "localhost,127.0.0.1", "--tls"}
+	if args := <-executed; !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+	_, err = c.Enroll(ctx, &EnrollRequest{CaName: "ca1", CommonName: "user1"})
+	if status.Code(err) != codes.FailedPrecondition || status.Convert(err).Message() != "ca ca1 is not initialized" {
+		t.Errorf("expected the CA not to be initialized, got %v", err)
+	}
+	if _, err := c.Enroll(ctx, &EnrollRequest{CommonName: "user1"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected the CA name to be required, got %v", err)
+	}
+
+	nodes, err := c.ListNodes(ctx, &ListNodesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes.Nodes) != 0 {
+		t.Errorf("expected no nodes, got %+v", nodes.Nodes)
+	}
+	if _, err := c.AddNode(ctx, &AddNodeRequest{Kind: "peer", Id: "peer0"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected the peer not to be found, got %v", err)
+	}
+	if _, err := c.RestartNode(ctx, &NodeRequest{Kind: "peer", Id: "peer0"}); status.Code(err) != codes.NotFound {
+		t.Errorf("expected the peer not to be supervised, got %v", err)
+	}
+	if _, err := c.InvokeChaincode(ctx, &ChaincodeRequest{PeerId: "peer0", Channel: "mychannel"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected the chaincode name to be required, got %v", err)
+	}
+}
+
+func TestServerAuthentication(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, c := serve(t, func(ctx context.Context, args []string) ([]byte, error) {
+		t.Errorf("expected the unauthenticated call not to run %v", args)
+		return nil, nil
+	})
+	_, err := c.RegisterChaincode(context.Background(), &RegisterChaincodeRequest{Name: "basic", Address: "localhost:9999"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected the call without a token to be rejected, got %v", err)
+	}
+	entries, err := audit.Query(audit.Filter{Operation: "daemon.RegisterChaincode"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Result != audit.ResultDenied || entries[0].Params["name"] != "basic" {
+		t.Errorf("expected the denied call to be recorded, got %+v", entries)
+	}
+}
@@ -0,0 +1,168 @@
+package samples
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"strconv"
+)
+
+// Asset is an asset of the basic sample, the asset-transfer-basic of the
+// Fabric samples
+type Asset struct {
+	AppraisedValue int    `json:"AppraisedValue"`
+	Color          string `json:"Color"`
+	ID             string `json:"ID"`
+	Owner          string `json:"Owner"`
+	Size           int    `json:"Size"`
+}
+
+// Basic is the asset-transfer-basic chaincode: CreateAsset, ReadAsset,
+// UpdateAsset, DeleteAsset, AssetExists, TransferAsset, GetAllAssets and
+// InitLedger
+type Basic struct{}
+
+// Invoke runs a function of the chaincode
+func (b Basic) Invoke(stub Stub, function string, args []string) ([]byte, error) {
+	arity := map[string]int{
+		"InitLedger":    0,
+		"CreateAsset":   5,
+		"ReadAsset":     1,
+		"UpdateAsset":   5,
+		"DeleteAsset":   1,
+		"AssetExists":   1,
+		"TransferAsset": 2,
+		"GetAllAssets":  0,
+	}
+	n, ok := arity[function]
+	if !ok {
+		return nil, errors.Errorf("unknown function %s", function)
+	}
+	if len(args) != n {
+		return nil, errors.Errorf("%s expects %d arguments, got %d", function, n, len(args))
+	}
+	switch function {
+	case "InitLedger":
+		return nil, b.initLedger(stub)
+	case "CreateAsset", "UpdateAsset":
+		asset, err := parseAsset(args)
+		if err != nil {
+			return nil, err
+		}
+		exists, err := assetExists(stub, asset.ID)
+		if err != nil {
+			return nil, err
+		}
+		if function == "CreateAsset" && exists {
+			return nil, errors.Errorf("the asset %s already exists", asset.ID)
+		}
+		if function == "UpdateAsset" && !exists {
+			return nil, errors.Errorf("the asset %s does not exist", asset.ID)
+		}
+		return nil, putAsset(stub, asset)
+	case "ReadAsset":
+		return stateOf(stub, args[0])
+	case "DeleteAsset":
+		if _, err := stateOf(stub, args[0]); err != nil {
+			return nil, err
+		}
+		return nil, stub.DelState(args[0])
+	case "AssetExists":
+		exists, err := assetExists(stub, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.FormatBool(exists)), nil
+	case "TransferAsset":
+		asset, err := readAsset(stub, args[0])
+		if err != nil {
+			return nil, err
+		}
+		oldOwner := asset.Owner
+		asset.Owner = args[1]
+		return []byte(oldOwner), putAsset(stub, asset)
+	default:
+		kvs, err := stub.GetStateByRange("", "")
+		if err != nil {
+			return nil, err
+		}
+		assets := []Asset{}
+		for _, kv := range kvs {
+			asset := Asset{}
+			if err := json.Unmarshal(kv.Value, &asset); err != nil {
+				return nil, errors.Wrapf(err, "invalid asset %s", kv.Key)
+			}
+			assets = append(assets, asset)
+		}
+		return json.Marshal(assets)
+	}
+}
+
+// initLedger writes the assets of the Fabric samples
+func (b Basic) initLedger(stub Stub) error {
+	assets := []Asset{
+		{ID: "asset1", Color: "blue", Size: 5, Owner: "Tomoko", AppraisedValue: 300},
+		{ID: "asset2", Color: "red", Size: 5, Owner: "Brad", AppraisedValue: 400},
+		{ID: "asset3", Color: "green", Size: 10, Owner: "Jin Soo", AppraisedValue: 500},
+		{ID: "asset4", Color: "yellow", Size: 10, Owner: "Max", AppraisedValue: 600},
+		{ID: "asset5", Color: "black", Size: 15, Owner: "Adriana", AppraisedValue: 700},
+		{ID: "asset6", Color: "white", Size: 15, Owner: "Michel", AppraisedValue: 800},
+	}
+	for _, asset := range assets {
+		if err := putAsset(stub, asset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseAsset parses the id, color, size, owner and appraised value of an
+// asset
+func parseAsset(args []string) (Asset, error) {
+	size, err := strconv.Atoi(args[2])
+	if err != nil {
+		return Asset{}, errors.Errorf("invalid size %q", args[2])
+	}
+	appraisedValue, err := strconv.Atoi(args[4])
+	if err != nil {
+		return Asset{}, errors.Errorf("invalid appraised value %q", args[4])
+	}
+	return Asset{ID: args[0], Color: args[1], Size: size, Owner: args[3], AppraisedValue: appraisedValue}, nil
+}
+
+func putAsset(stub Stub, asset Asset) error {
+	assetBytes, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(asset.ID, assetBytes)
+}
+
+// stateOf returns the state of an asset, an error when it doesn't exist
+func stateOf(stub Stub, id string) ([]byte, error) {
+	assetBytes, err := stub.GetState(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(assetBytes) == 0 {
+		return nil, errors.Errorf("the asset %s does not exist", id)
+	}
+	return assetBytes, nil
+}
+
+func readAsset(stub Stub, id string) (Asset, error) {
+	asset := Asset{}
+	assetBytes, err := stateOf(stub, id)
+	if err != nil {
+		return asset, err
+	}
+	err = json.Unmarshal(assetBytes, &asset)
+	return asset, err
+}
+
+func assetExists(stub Stub, id string) (bool, error) {
+	assetBytes, err := stub.GetState(id)
+	if err != nil {
+		return false, err
+	}
+	return len(assetBytes) > 0, nil
+}
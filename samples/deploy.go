@@ -0,0 +1,194 @@
+package samples
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"hlf-easy/chaincode"
+	"hlf-easy/contract"
+	"hlf-easy/node"
+	"hlf-easy/utils"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultVersion is the version the samples are deployed with
+const DefaultVersion = "1.0"
+
+// DefaultDeployTimeout is how long the install, approvals and commit of a
+// sample have to complete
+const DefaultDeployTimeout = 5 * time.Minute
+
+// DeployOptions select the sample, the channel and the peers of the host it's
+// deployed to
+type DeployOptions struct {
+	Sample string
+	// Name of the chaincode on the channel and in the registry, the name of
+	// the sample when empty
+	Name    string
+	Version string
+	Channel string
+	// PeerIDs are the peers of the host the chaincode is installed in, it's
+	// approved for the org of every one and the orgs are the members of the
+	// collections of the sample
+	PeerIDs []string
+	// OrdererBundle is the orderer bundle file of the ordering service of the
+	// channel
+	OrdererBundle string
+	// Address the chaincode server listens on, host:port
+	Address string
+}
+
+// Validate checks the options
+func (o DeployOptions) Validate() error {
+	if _, err := Get(o.Sample); err != nil {
+		return err
+	}
+	if o.Channel == "" {
+		return errors.New("the channel is required")
+	}
+	if len(o.PeerIDs) == 0 {
+		return errors.New("at least one peer is required")
+	}
+	if o.OrdererBundle == "" {
+		return errors.New("the orderer bundle is required")
+	}
+	if _, _, err := net.SplitHostPort(o.Address); err != nil {
+		return errors.Wrapf(err, "invalid address %s", o.Address)
+	}
+	return nil
+}
+
+// Deployment is a sample deployed on a channel, its server runs in the
+// background
+type Deployment struct {
+	Definition chaincode.Definition `json:"definition"`
+	PackageID  string               `json:"packageID"`
+	// PID of the hlf-easy process serving the chaincode
+	PID     int    `json:"pid"`
+	LogPath string `json:"logPath"`
+}
+
+// GetLogPath returns the log file of the server of a deployed sample
+func GetLogPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, "hlf-easy/samples")
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".log"), nil
+}
+
+// Deploy registers the sample as a chaincode-as-a-service, starts its server
+// in the background, installs it in the peers, approves it for their orgs and
+// commits it on the channel. Nothing is downloaded, the server is hlf-easy
+// itself. The deployment is returned once the server is started, even when a
+// later step fails, so the caller can stop it. The lifecycle operations are
+// canceled when the context is done
+func Deploy(ctx context.Context, opts DeployOptions) (*Deployment, error) {
+	err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+	s, err := Get(opts.Sample)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Name == "" {
+		opts.Name = s.Name
+	}
+	if opts.Version == "" {
+		opts.Version = DefaultVersion
+	}
+	// the first peer of every org approves the definition for it
+	var mspIDs []string
+	approvers := map[string]string{}
+	for _, peerID := range opts.PeerIDs {
+		mspID, err := node.GetPeerMSPID(peerID)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := approvers[mspID]; !ok {
+			approvers[mspID] = peerID
+			mspIDs = append(mspIDs, mspID)
+		}
+	}
+	d := chaincode.Definition{
+		Name:    opts.Name,
+		Version: opts.Version,
+		Type:    chaincode.TypeCCaaS,
+		Address: opts.Address,
+	}
+	if s.Collections != nil {
+		d.Collections = s.Collections(mspIDs)
+	}
+	err = chaincode.Save(d)
+	if err != nil {
+		return nil, err
+	}
+	err = chaincode.SaveService(chaincode.Service{Name: d.Name, Address: d.Address, UpdatedAt: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	// the peers find the address of the server in their connection.json
+	_, err = chaincode.SyncPeers()
+	if err != nil {
+		return nil, err
+	}
+	pkg, packageID, err := chaincode.Package(d)
+	if err != nil {
+		return nil, err
+	}
+	logPath, err := GetLogPath(d.Name)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := utils.Spawn([]string{"chaincode", "serve-sample",
+		"--sample", s.Name,
+		"--address", d.Address,
+		"--package-id", packageID,
+	}, logPath)
+	if err != nil {
+		return nil, err
+	}
+	deployment := &Deployment{Definition: d, PackageID: packageID, PID: pid, LogPath: logPath}
+	for _, peerID := range opts.PeerIDs {
+		installedID, err := contract.Install(ctx, peerID, d.Label(), pkg)
+		if err != nil {
+			return deployment, err
+		}
+		if installedID != packageID {
+			return deployment, errors.Errorf("peer %s installed the chaincode as %s, expected %s", peerID, installedID, packageID)
+		}
+	}
+	definition := contract.Definition{
+		Name:        d.Name,
+		Version:     d.Version,
+		Sequence:    1,
+		PackageID:   packageID,
+		Collections: d.Collections,
+	}
+	var approverIDs []string
+	for _, mspID := range mspIDs {
+		approverIDs = append(approverIDs, approvers[mspID])
+		err = contract.Approve(ctx, contract.LifecycleOptions{
+			Channel:       opts.Channel,
+			PeerIDs:       []string{approvers[mspID]},
+			OrdererBundle: opts.OrdererBundle,
+		}, definition)
+		if err != nil {
+			return deployment, err
+		}
+	}
+	err = contract.Commit(ctx, contract.LifecycleOptions{
+		Channel:       opts.Channel,
+		PeerIDs:       approverIDs,
+		OrdererBundle: opts.OrdererBundle,
+	}, definition)
+	return deployment, err
+}
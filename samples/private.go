@@ -0,0 +1,89 @@
+package samples
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+)
+
+// PrivateCollection is the collection of the orgs of the channel where the
+// private sample keeps its assets
+const PrivateCollection = "assetCollection"
+
+// TransientAssetKey is the key of the transient data with the asset to
+// create, the asset isn't written to the transaction
+const TransientAssetKey = "asset_properties"
+
+// Private is a private data demo: its assets are only stored in the private
+// data collection of the orgs, the peers of other orgs only see their hashes.
+// CreateAsset takes the asset from the transient data, ReadAsset, DeleteAsset
+// and GetAllAssets read the collection
+type Private struct{}
+
+// Invoke runs a function of the chaincode
+func (p Private) Invoke(stub Stub, function string, args []string) ([]byte, error) {
+	arity := map[string]int{
+		"CreateAsset":  0,
+		"ReadAsset":    1,
+		"DeleteAsset":  1,
+		"GetAllAssets": 0,
+	}
+	n, ok := arity[function]
+	if !ok {
+		return nil, errors.Errorf("unknown function %s", function)
+	}
+	if len(args) != n {
+		return nil, errors.Errorf("%s expects %d arguments, got %d", function, n, len(args))
+	}
+	switch function {
+	case "CreateAsset":
+		assetBytes, ok := stub.GetTransient()[TransientAssetKey]
+		if !ok {
+			return nil, errors.Errorf("the asset must be passed in the transient data as %s", TransientAssetKey)
+		}
+		asset := Asset{}
+		if err := json.Unmarshal(assetBytes, &asset); err != nil {
+			return nil, errors.Wrap(err, "invalid asset")
+		}
+		if asset.ID == "" {
+			return nil, errors.New("the asset has no ID")
+		}
+		existing, err := stub.GetPrivateData(PrivateCollection, asset.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 {
+			return nil, errors.Errorf("the asset %s already exists", asset.ID)
+		}
+		assetBytes, err = json.Marshal(asset)
+		if err != nil {
+			return nil, err
+		}
+		return nil, stub.PutPrivateData(PrivateCollection, asset.ID, assetBytes)
+	case "ReadAsset", "DeleteAsset":
+		assetBytes, err := stub.GetPrivateData(PrivateCollection, args[0])
+		if err != nil {
+			return nil, err
+		}
+		if len(assetBytes) == 0 {
+			return nil, errors.Errorf("the asset %s does not exist", args[0])
+		}
+		if function == "ReadAsset" {
+			return assetBytes, nil
+		}
+		return nil, stub.DelPrivateData(PrivateCollection, args[0])
+	default:
+		kvs, err := stub.GetPrivateDataByRange(PrivateCollection, "", "")
+		if err != nil {
+			return nil, err
+		}
+		assets := []Asset{}
+		for _, kv := range kvs {
+			asset := Asset{}
+			if err := json.Unmarshal(kv.Value, &asset); err != nil {
+				return nil, errors.Wrapf(err, "invalid asset %s", kv.Key)
+			}
+			assets = append(assets, asset)
+		}
+		return json.Marshal(assets)
+	}
+}
@@ -0,0 +1,88 @@
+package samples
+
+import (
+	"github.com/pkg/errors"
+	"hlf-easy/chaincode"
+	"sort"
+	"strings"
+)
+
+// Sample is a chaincode bundled in hlf-easy, it's served by hlf-easy itself
+// so it's deployed without downloading or building anything
+type Sample struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Functions of the chaincode with their arguments
+	Functions []string  `json:"functions"`
+	Chaincode Chaincode `json:"-"`
+	// Collections returns the private data collections of the chaincode for
+	// the orgs of the channel, nil when it has none
+	Collections func(mspIDs []string) []chaincode.Collection `json:"-"`
+}
+
+var samples = map[string]Sample{
+	"basic": {
+		Name:        "basic",
+		Description: "asset-transfer-basic of the Fabric samples, the assets are in the public state",
+		Functions: []string{
+			"InitLedger",
+			"CreateAsset ID Color Size Owner AppraisedValue",
+			"ReadAsset ID",
+			"UpdateAsset ID Color Size Owner AppraisedValue",
+			"DeleteAsset ID",
+			"AssetExists ID",
+			"TransferAsset ID NewOwner",
+			"GetAllAssets",
+		},
+		Chaincode: Basic{},
+	},
+	"private": {
+		Name:        "private",
+		Description: "private data demo, the assets are in the " + PrivateCollection + " collection of the orgs of the channel",
+		Functions: []string{
+			"CreateAsset, with the asset JSON in the transient data " + TransientAssetKey,
+			"ReadAsset ID",
+			"DeleteAsset ID",
+			"GetAllAssets",
+		},
+		Chaincode: Private{},
+		Collections: func(mspIDs []string) []chaincode.Collection {
+			return []chaincode.Collection{{
+				Name:              PrivateCollection,
+				Policy:            chaincode.MemberPolicy(mspIDs),
+				RequiredPeerCount: 0,
+				MaxPeerCount:      int32(len(mspIDs)),
+				MemberOnlyRead:    true,
+				MemberOnlyWrite:   true,
+			}}
+		},
+	},
+}
+
+// List returns the samples sorted by name
+func List() []Sample {
+	list := []Sample{}
+	for _, s := range samples {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Names returns the names of the samples
+func Names() []string {
+	names := []string{}
+	for _, s := range List() {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// Get returns a sample by name
+func Get(name string) (*Sample, error) {
+	s, ok := samples[name]
+	if !ok {
+		return nil, errors.Errorf("unknown sample %q, the samples are %s", name, strings.Join(Names(), ", "))
+	}
+	return &s, nil
+}
@@ -0,0 +1,284 @@
+package samples
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/grpc"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memStub is the state of a channel in memory, the public state is the one
+// of the empty collection
+type memStub struct {
+	collections map[string]map[string][]byte
+	transient   map[string][]byte
+}
+
+func newMemStub() *memStub {
+	return &memStub{collections: map[string]map[string][]byte{}}
+}
+
+func (s *memStub) GetState(key string) ([]byte, error) {
+	return s.GetPrivateData("", key)
+}
+
+func (s *memStub) PutState(key string, value []byte) error {
+	return s.PutPrivateData("", key, value)
+}
+
+func (s *memStub) DelState(key string) error {
+	return s.DelPrivateData("", key)
+}
+
+func (s *memStub) GetStateByRange(startKey string, endKey string) ([]KV, error) {
+	return s.GetPrivateDataByRange("", startKey, endKey)
+}
+
+func (s *memStub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.collections[collection][key], nil
+}
+
+func (s *memStub) PutPrivateData(collection string, key string, value []byte) error {
+	if s.collections[collection] == nil {
+		s.collections[collection] = map[string][]byte{}
+	}
+	s.collections[collection][key] = value
+	return nil
+}
+
+func (s *memStub) DelPrivateData(collection string, key string) error {
+	delete(s.collections[collection], key)
+	return nil
+}
+
+func (s *memStub) GetPrivateDataByRange(collection string, startKey string, endKey string) ([]KV, error) {
+	kvs := []KV{}
+	for key, value := range s.collections[collection] {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			kvs = append(kvs, KV{Key: key, Value: value})
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs, nil
+}
+
+func (s *memStub) GetTransient() map[string][]byte {
+	return s.transient
+}
+
+func TestBasic(t *testing.T) {
+	stub := newMemStub()
+	b := Basic{}
+	if _, err := b.Invoke(stub, "InitLedger", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Invoke(stub, "CreateAsset", []string{"asset7", "purple", "20", "Ana", "900"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Invoke(stub, "CreateAsset", []string{"asset7", "purple", "20", "Ana", "900"}); err == nil {
+		t.Fatal("expected an error creating an existing asset")
+	}
+	oldOwner, err := b.Invoke(stub, "TransferAsset", []string{"asset7", "Luis"})
+	if err != nil || string(oldOwner) != "Ana" {
+		t.Fatalf("expected the old owner Ana, got %s %v", oldOwner, err)
+	}
+	assetBytes, err := b.Invoke(stub, "ReadAsset", []string{"asset7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset := Asset{}
+	if err := json.Unmarshal(assetBytes, &asset); err != nil || asset.Owner != "Luis" || asset.Size != 20 {
+		t.Fatalf("unexpected asset %s %v", assetBytes, err)
+	}
+	if _, err := b.Invoke(stub, "DeleteAsset", []string{"asset1"}); err != nil {
+		t.Fatal(err)
+	}
+	exists, err := b.Invoke(stub, "AssetExists", []string{"asset1"})
+	if err != nil || string(exists) != "false" {
+		t.Fatalf("expected the deleted asset not to exist, got %s %v", exists, err)
+	}
+	allBytes, err := b.Invoke(stub, "GetAllAssets", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets := []Asset{}
+	if err := json.Unmarshal(allBytes, &assets); err != nil || len(assets) != 6 {
+		t.Fatalf("expected 6 assets, got %s %v", allBytes, err)
+	}
+	for _, args := range [][]string{{"asset1"}, {}} {
+		if _, err := b.Invoke(stub, "UpdateAsset", args); err == nil {
+			t.Fatalf("expected an error updating with %v", args)
+		}
+	}
+	if _, err := b.Invoke(stub, "Mint", nil); err == nil {
+		t.Fatal("expected an error calling an unknown function")
+	}
+}
+
+func TestPrivate(t *testing.T) {
+	stub := newMemStub()
+	p := Private{}
+	if _, err := p.Invoke(stub, "CreateAsset", nil); err == nil {
+		t.Fatal("expected an error creating an asset without transient data")
+	}
+	stub.transient = map[string][]byte{TransientAssetKey: []byte(`{"ID":"asset1","Color":"blue","Size":5,"AppraisedValue":300}`)}
+	if _, err := p.Invoke(stub, "CreateAsset", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Invoke(stub, "CreateAsset", nil); err == nil {
+		t.Fatal("expected an error creating an existing asset")
+	}
+	if len(stub.collections[""]) != 0 || len(stub.collections[PrivateCollection]) != 1 {
+		t.Fatalf("expected the asset only in the collection, got %v", stub.collections)
+	}
+	assetBytes, err := p.Invoke(stub, "ReadAsset", []string{"asset1"})
+	if err != nil || !strings.Contains(string(assetBytes), `"AppraisedValue":300`) {
+		t.Fatalf("unexpected asset %s %v", assetBytes, err)
+	}
+	allBytes, err := p.Invoke(stub, "GetAllAssets", nil)
+	if err != nil || !strings.Contains(string(allBytes), "asset1") {
+		t.Fatalf("unexpected assets %s %v", allBytes, err)
+	}
+	if _, err := p.Invoke(stub, "DeleteAsset", []string{"asset1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Invoke(stub, "ReadAsset", []string{"asset1"}); err == nil {
+		t.Fatal("expected an error reading a deleted asset")
+	}
+}
+
+func TestGet(t *testing.T) {
+	if !reflect.DeepEqual(Names(), []string{"basic", "private"}) {
+		t.Fatalf("unexpected samples %v", Names())
+	}
+	s, err := Get("private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collections := s.Collections([]string{"Org1MSP", "Org2MSP"})
+	if len(collections) != 1 || collections[0].Policy != "OR('Org1MSP.member','Org2MSP.member')" {
+		t.Errorf("unexpected collections %+v", collections)
+	}
+	if _, err := Get("marbles"); err == nil || !strings.Contains(err.Error(), "basic, private") {
+		t.Errorf("expected an error listing the samples, got %v", err)
+	}
+}
+
+// fakeStream is the stream of a peer connected to the chaincode server
+type fakeStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	recv chan *pb.ChaincodeMessage
+	sent chan *pb.ChaincodeMessage
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeStream) Send(msg *pb.ChaincodeMessage) error {
+	s.sent <- msg
+	return nil
+}
+
+func (s *fakeStream) Recv() (*pb.ChaincodeMessage, error) {
+	select {
+	case msg := <-s.recv:
+		return msg, nil
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+func (s *fakeStream) next(t *testing.T) *pb.ChaincodeMessage {
+	t.Helper()
+	select {
+	case msg := <-s.sent:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a message from the chaincode")
+		return nil
+	}
+}
+
+func TestServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &fakeStream{ctx: ctx, recv: make(chan *pb.ChaincodeMessage, 10), sent: make(chan *pb.ChaincodeMessage, 10)}
+	go func() {
+		_ = NewServer("basic_1.0:abc", Basic{}).Connect(stream)
+	}()
+
+	register := stream.next(t)
+	chaincodeID := &pb.ChaincodeID{}
+	if err := proto.Unmarshal(register.Payload, chaincodeID); err != nil || register.Type != pb.ChaincodeMessage_REGISTER || chaincodeID.Name != "basic_1.0:abc" {
+		t.Fatalf("expected the chaincode to register with its package ID, got %v", register)
+	}
+	stream.recv <- &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_REGISTERED}
+	stream.recv <- &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_READY}
+
+	// the peer answers the requests of the transaction from its state
+	state := map[string][]byte{"asset1": []byte(`{"ID":"asset1","Owner":"Tomoko"}`)}
+	invoke := func(txID string, args ...string) *pb.Response {
+		input := &pb.ChaincodeInput{}
+		for _, arg := range args {
+			input.Args = append(input.Args, []byte(arg))
+		}
+		inputBytes, _ := proto.Marshal(input)
+		stream.recv <- &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_TRANSACTION, Payload: inputBytes, Txid: txID, ChannelId: "sandbox"}
+		for {
+			msg := stream.next(t)
+			if msg.Txid != txID || msg.ChannelId != "sandbox" {
+				t.Fatalf("unexpected message %v", msg)
+			}
+			reply := &pb.ChaincodeMessage{Type: pb.ChaincodeMessage_RESPONSE, Txid: txID, ChannelId: "sandbox"}
+			switch msg.Type {
+			case pb.ChaincodeMessage_COMPLETED:
+				resp := &pb.Response{}
+				if err := proto.Unmarshal(msg.Payload, resp); err != nil {
+					t.Fatal(err)
+				}
+				return resp
+			case pb.ChaincodeMessage_GET_STATE:
+				getState := &pb.GetState{}
+				_ = proto.Unmarshal(msg.Payload, getState)
+				reply.Payload = state[getState.Key]
+			case pb.ChaincodeMessage_PUT_STATE:
+				putState := &pb.PutState{}
+				_ = proto.Unmarshal(msg.Payload, putState)
+				state[putState.Key] = putState.Value
+			case pb.ChaincodeMessage_GET_STATE_BY_RANGE:
+				resp := &pb.QueryResponse{Id: "range"}
+				kvBytes, _ := proto.Marshal(&queryresult.KV{Key: "asset1", Value: state["asset1"]})
+				resp.Results = append(resp.Results, &pb.QueryResultBytes{ResultBytes: kvBytes})
+				reply.Payload, _ = proto.Marshal(resp)
+			default:
+				t.Fatalf("unexpected message %v", msg)
+			}
+			stream.recv <- reply
+		}
+	}
+
+	resp := invoke("tx1", "TransferAsset", "asset1", "Brad")
+	if resp.Status != StatusOK || string(resp.Payload) != "Tomoko" {
+		t.Fatalf("unexpected response %v", resp)
+	}
+	if !strings.Contains(string(state["asset1"]), `"Owner":"Brad"`) {
+		t.Fatalf("expected the asset to be transferred, got %s", state["asset1"])
+	}
+	resp = invoke("tx2", "GetAllAssets")
+	if resp.Status != StatusOK || !strings.Contains(string(resp.Payload), "Brad") {
+		t.Fatalf("unexpected response %v", resp)
+	}
+	resp = invoke("tx3", "ReadAsset", "asset2")
+	if resp.Status != StatusError || !strings.Contains(resp.Message, "does not exist") {
+		t.Fatalf("expected an error response, got %v", resp)
+	}
+}
@@ -0,0 +1,335 @@
+package samples
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"hlf-easy/log"
+	"io"
+	"net"
+	"sync"
+)
+
+// Status codes of the responses of the chaincodes, the ones of the shim
+const (
+	StatusOK    = 200
+	StatusError = 500
+)
+
+// emptyKeySubstitute replaces the empty start key of a range query, the
+// peer refuses empty keys
+const emptyKeySubstitute = "\x01"
+
+// KV is a key of the state with its value
+type KV struct {
+	Key   string
+	Value []byte
+}
+
+// Stub reads and writes the state of the channel of a transaction
+type Stub interface {
+	GetState(key string) ([]byte, error)
+	PutState(key string, value []byte) error
+	DelState(key string) error
+	// GetStateByRange returns the keys from startKey to endKey excluded, all
+	// the keys when both are empty
+	GetStateByRange(startKey string, endKey string) ([]KV, error)
+	GetPrivateData(collection string, key string) ([]byte, error)
+	PutPrivateData(collection string, key string, value []byte) error
+	DelPrivateData(collection string, key string) error
+	GetPrivateDataByRange(collection string, startKey string, endKey string) ([]KV, error)
+	// GetTransient returns the transient data of the proposal, they're passed
+	// to the chaincode without being written to the ledger
+	GetTransient() map[string][]byte
+}
+
+// Chaincode is a sample chaincode served by hlf-easy
+type Chaincode interface {
+	// Invoke runs a function of the chaincode, the returned bytes are the
+	// payload of its response
+	Invoke(stub Stub, function string, args []string) ([]byte, error)
+}
+
+// Server serves a chaincode to the peers as a chaincode-as-a-service server,
+// the peers connect to it with the connection.json of its package
+type Server struct {
+	pb.UnimplementedChaincodeServer
+	packageID string
+	chaincode Chaincode
+}
+
+// NewServer returns the server of a chaincode installed with the package ID
+func NewServer(packageID string, chaincode Chaincode) *Server {
+	return &Server{packageID: packageID, chaincode: chaincode}
+}
+
+// Serve serves the chaincode on the address until the context is done
+func Serve(ctx context.Context, address string, packageID string, chaincode Chaincode) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	s := grpc.NewServer()
+	pb.RegisterChaincodeServer(s, NewServer(packageID, chaincode))
+	go func() {
+		<-ctx.Done()
+		// the streams of the peers are never closed by them
+		s.Stop()
+	}()
+	log.Infof("Serving chaincode %s on %s", packageID, address)
+	return s.Serve(lis)
+}
+
+// Connect handles the stream of a peer, the chaincode registers with its
+// package ID and runs the transactions sent by the peer
+func (s *Server) Connect(stream pb.Chaincode_ConnectServer) error {
+	h := &handler{
+		stream:    stream,
+		chaincode: s.chaincode,
+		responses: map[string]chan *pb.ChaincodeMessage{},
+	}
+	return h.run(s.packageID)
+}
+
+type handler struct {
+	stream    pb.Chaincode_ConnectServer
+	chaincode Chaincode
+	sendMu    sync.Mutex
+	mu        sync.Mutex
+	// responses are the pending requests of the transactions to the peer,
+	// keyed by channel and transaction ID
+	responses map[string]chan *pb.ChaincodeMessage
+}
+
+func (h *handler) send(msg *pb.ChaincodeMessage) error {
+	h.sendMu.Lock()
+	defer h.sendMu.Unlock()
+	return h.stream.Send(msg)
+}
+
+func (h *handler) run(packageID string) error {
+	chaincodeID, err := proto.Marshal(&pb.ChaincodeID{Name: packageID})
+	if err != nil {
+		return err
+	}
+	err = h.send(&pb.ChaincodeMessage{Type: pb.ChaincodeMessage_REGISTER, Payload: chaincodeID})
+	if err != nil {
+		return errors.Wrap(err, "failed to register the chaincode")
+	}
+	registered := false
+	for {
+		msg, err := h.stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch msg.Type {
+		case pb.ChaincodeMessage_REGISTERED:
+			registered = true
+		case pb.ChaincodeMessage_READY:
+		case pb.ChaincodeMessage_KEEPALIVE:
+			if err := h.send(msg); err != nil {
+				return err
+			}
+		case pb.ChaincodeMessage_INIT, pb.ChaincodeMessage_TRANSACTION:
+			go h.handleTransaction(msg)
+		case pb.ChaincodeMessage_RESPONSE, pb.ChaincodeMessage_ERROR:
+			if !registered {
+				return errors.Errorf("the peer refused the registration of chaincode %s: %s", packageID, msg.Payload)
+			}
+			h.deliver(msg)
+		default:
+			log.Warnf("Unexpected %s message from the peer", msg.Type)
+		}
+	}
+}
+
+func responseKey(channelID string, txID string) string {
+	return channelID + "/" + txID
+}
+
+// deliver passes the response of the peer to the transaction waiting for it
+func (h *handler) deliver(msg *pb.ChaincodeMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch, ok := h.responses[responseKey(msg.ChannelId, msg.Txid)]
+	if !ok {
+		log.Warnf("Unexpected %s message of transaction %s", msg.Type, msg.Txid)
+		return
+	}
+	ch <- msg
+}
+
+func (h *handler) handleTransaction(msg *pb.ChaincodeMessage) {
+	resp := &pb.Response{Status: StatusOK}
+	input := &pb.ChaincodeInput{}
+	err := proto.Unmarshal(msg.Payload, input)
+	if err == nil && len(input.Args) == 0 {
+		err = errors.New("no function in the arguments")
+	}
+	var transient map[string][]byte
+	if err == nil {
+		transient, err = transientMap(msg.Proposal)
+	}
+	if err == nil {
+		args := []string{}
+		for _, arg := range input.Args[1:] {
+			args = append(args, string(arg))
+		}
+		stub := &stub{h: h, channelID: msg.ChannelId, txID: msg.Txid, transient: transient}
+		resp.Payload, err = h.chaincode.Invoke(stub, string(input.Args[0]), args)
+	}
+	if err != nil {
+		resp = &pb.Response{Status: StatusError, Message: err.Error()}
+	}
+	respBytes, err := proto.Marshal(resp)
+	if err != nil {
+		log.Errorf("Failed to marshal the response of transaction %s: %v", msg.Txid, err)
+		return
+	}
+	err = h.send(&pb.ChaincodeMessage{
+		Type:      pb.ChaincodeMessage_COMPLETED,
+		Payload:   respBytes,
+		Txid:      msg.Txid,
+		ChannelId: msg.ChannelId,
+	})
+	if err != nil {
+		log.Errorf("Failed to send the response of transaction %s: %v", msg.Txid, err)
+	}
+}
+
+// transientMap returns the transient data of the proposal of a transaction
+func transientMap(signedProposal *pb.SignedProposal) (map[string][]byte, error) {
+	if signedProposal == nil {
+		return nil, nil
+	}
+	proposal := &pb.Proposal{}
+	err := proto.Unmarshal(signedProposal.ProposalBytes, proposal)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid proposal")
+	}
+	payload := &pb.ChaincodeProposalPayload{}
+	err = proto.Unmarshal(proposal.Payload, payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid proposal payload")
+	}
+	return payload.TransientMap, nil
+}
+
+type stub struct {
+	h         *handler
+	channelID string
+	txID      string
+	transient map[string][]byte
+}
+
+// call sends a request of the transaction to the peer and waits for its
+// response
+func (s *stub) call(msgType pb.ChaincodeMessage_Type, request proto.Message) ([]byte, error) {
+	payload, err := proto.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	key := responseKey(s.channelID, s.txID)
+	ch := make(chan *pb.ChaincodeMessage, 1)
+	s.h.mu.Lock()
+	s.h.responses[key] = ch
+	s.h.mu.Unlock()
+	defer func() {
+		s.h.mu.Lock()
+		delete(s.h.responses, key)
+		s.h.mu.Unlock()
+	}()
+	err = s.h.send(&pb.ChaincodeMessage{
+		Type:      msgType,
+		Payload:   payload,
+		Txid:      s.txID,
+		ChannelId: s.channelID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Type == pb.ChaincodeMessage_ERROR {
+			return nil, errors.Errorf("%s failed: %s", msgType, resp.Payload)
+		}
+		return resp.Payload, nil
+	case <-s.h.stream.Context().Done():
+		return nil, errors.Errorf("the peer disconnected during %s", msgType)
+	}
+}
+
+// GetState reads the public state, the one of the empty collection
+func (s *stub) GetState(key string) ([]byte, error) {
+	return s.GetPrivateData("", key)
+}
+
+func (s *stub) PutState(key string, value []byte) error {
+	return s.PutPrivateData("", key, value)
+}
+
+func (s *stub) DelState(key string) error {
+	return s.DelPrivateData("", key)
+}
+
+func (s *stub) GetStateByRange(startKey string, endKey string) ([]KV, error) {
+	return s.GetPrivateDataByRange("", startKey, endKey)
+}
+
+func (s *stub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.call(pb.ChaincodeMessage_GET_STATE, &pb.GetState{Key: key, Collection: collection})
+}
+
+func (s *stub) PutPrivateData(collection string, key string, value []byte) error {
+	_, err := s.call(pb.ChaincodeMessage_PUT_STATE, &pb.PutState{Key: key, Value: value, Collection: collection})
+	return err
+}
+
+func (s *stub) DelPrivateData(collection string, key string) error {
+	_, err := s.call(pb.ChaincodeMessage_DEL_STATE, &pb.DelState{Key: key, Collection: collection})
+	return err
+}
+
+func (s *stub) GetPrivateDataByRange(collection string, startKey string, endKey string) ([]KV, error) {
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	payload, err := s.call(pb.ChaincodeMessage_GET_STATE_BY_RANGE, &pb.GetStateByRange{StartKey: startKey, EndKey: endKey, Collection: collection})
+	if err != nil {
+		return nil, err
+	}
+	kvs := []KV{}
+	for {
+		resp := &pb.QueryResponse{}
+		err = proto.Unmarshal(payload, resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range resp.Results {
+			kv := &queryresult.KV{}
+			err = proto.Unmarshal(result.ResultBytes, kv)
+			if err != nil {
+				return nil, err
+			}
+			kvs = append(kvs, KV{Key: kv.Key, Value: kv.Value})
+		}
+		// the peer closes the iterator once it's exhausted
+		if !resp.HasMore {
+			return kvs, nil
+		}
+		payload, err = s.call(pb.ChaincodeMessage_QUERY_STATE_NEXT, &pb.QueryStateNext{Id: resp.Id})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *stub) GetTransient() map[string][]byte {
+	return s.transient
+}
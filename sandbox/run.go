@@ -0,0 +1,330 @@
+package sandbox
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/hyperledger/fabric-config/configtx"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/chaincode"
+	"hlf-easy/channel"
+	"hlf-easy/cluster"
+	"hlf-easy/dashboard"
+	"hlf-easy/errdefs"
+	"hlf-easy/log"
+	"hlf-easy/node"
+	"hlf-easy/proc"
+	"hlf-easy/samples"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stopTimeout is how long a process of the sandbox has to exit once it's
+// signaled
+const stopTimeout = 30 * time.Second
+
+// startNode starts the hlf-easy process of a node and waits for its
+// operations service to be healthy
+func (n *Network) startNode(ctx context.Context, kind string, id string, args []string, operationsPort int, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	nodeDir := filepath.Join(home, "hlf-easy", kind+"s", id)
+	if _, err := os.Stat(filepath.Join(nodeDir, "run.json")); err == nil {
+		return errdefs.Errorf(errdefs.ErrNodeAlreadyRunning, "%s %s is already running", kind, id)
+	}
+	logPath := filepath.Join(nodeDir, "hlf-easy.log")
+	pid, err := utils.Spawn(args, logPath)
+	if err != nil {
+		return err
+	}
+	n.Processes = append(n.Processes, Process{Kind: kind, ID: id, PID: pid})
+	healthyCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err = cluster.WaitHealthy(healthyCtx, operationsPort)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s isn't healthy, see %s", kind, id, logPath)
+	}
+	return nil
+}
+
+// StartOrderer starts the orderer of the sandbox
+func (n *Network) StartOrderer(ctx context.Context, timeout time.Duration) error {
+	for _, m := range n.Cluster.Members {
+		err := n.startNode(ctx, KindOrderer, m.ID, cluster.StartArgs(n.Cluster, m), m.OperationsPort, timeout)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartPeers starts the peers of all the orgs
+func (n *Network) StartPeers(ctx context.Context, timeout time.Duration) error {
+	for _, org := range n.Orgs {
+		for _, p := range org.Peers {
+			err := n.startNode(ctx, KindPeer, p.ID, PeerStartArgs(org, p), p.OperationsPort, timeout)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CreateChannel writes the orderer bundle of the sandbox and creates the
+// channel with all the orgs, the first peer of every org is its anchor peer.
+// The orderers are joined with the TLS certificate of the first one, it's
+// issued by the TLS CA their admin endpoint trusts
+func (n *Network) CreateChannel(ctx context.Context) error {
+	bundle, err := cluster.Bundle(n.Cluster)
+	if err != nil {
+		return err
+	}
+	bundleBytes, err := yaml.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+	bundlePath, err := OrdererBundlePath()
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(bundlePath, bundleBytes, 0644)
+	if err != nil {
+		return err
+	}
+	var orgs []channel.OrgOptions
+	for _, org := range n.Orgs {
+		caConfig, err := utils.GetCAConfig(org.CAName)
+		if err != nil {
+			return err
+		}
+		orgs = append(orgs, channel.OrgOptions{
+			MSPID:       org.MSPID,
+			CACert:      caConfig.CACert,
+			TLSCACert:   caConfig.TLSCACert,
+			AnchorPeers: []configtx.Address{{Host: Host, Port: org.Peers[0].Port}},
+		})
+	}
+	block, err := channel.NewGenesisBlock(channel.CreateOptions{
+		ChannelID: n.Spec.Channel,
+		Org:       orgs[0],
+		Orgs:      orgs[1:],
+		Bundle:    bundle,
+	})
+	if err != nil {
+		return err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	ordererDir := filepath.Join(home, "hlf-easy/orderers", n.Cluster.Members[0].ID)
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(ordererDir, "tls.crt"), filepath.Join(ordererDir, "tls.key"))
+	if err != nil {
+		return err
+	}
+	tlsCACerts, err := utils.ParsePEMCertificates(bundle.TLSCACerts)
+	if err != nil {
+		return err
+	}
+	rootCAs := x509.NewCertPool()
+	for _, tlsCACert := range tlsCACerts {
+		rootCAs.AddCert(tlsCACert)
+	}
+	results := channel.JoinOrderers(ctx, bundle.Orderers, block, &tls.Config{
+		RootCAs:      rootCAs,
+		Certificates: []tls.Certificate{clientCert},
+	})
+	for _, r := range results {
+		if r.Result != channel.JoinResultJoined {
+			return errors.Errorf("orderer %s failed to join channel %s: %s", r.Orderer, n.Spec.Channel, r.Error)
+		}
+	}
+	return nil
+}
+
+// JoinCommands are the hlf-easy commands that join the peers to the channel
+// with the admin identity managed for each one
+func (n *Network) JoinCommands() ([][]string, error) {
+	bundlePath, err := OrdererBundlePath()
+	if err != nil {
+		return nil, err
+	}
+	var commands [][]string
+	for _, p := range n.Peers() {
+		identity, err := node.ManagedAdminIdentity(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, []string{"peer", "join",
+			"--id", p.ID,
+			"--channel", n.Spec.Channel,
+			"--identity", identity,
+			"--orderer-bundle", bundlePath,
+		})
+	}
+	return commands, nil
+}
+
+// DeployChaincode deploys the sample on the channel, it's installed in all
+// the peers and approved for every org
+func (n *Network) DeployChaincode(ctx context.Context) error {
+	bundlePath, err := OrdererBundlePath()
+	if err != nil {
+		return err
+	}
+	var peerIDs []string
+	for _, p := range n.Peers() {
+		peerIDs = append(peerIDs, p.ID)
+	}
+	deployment, err := samples.Deploy(ctx, samples.DeployOptions{
+		Sample:        n.Spec.Sample,
+		Name:          n.Chaincode.Name,
+		Version:       n.Chaincode.Version,
+		Channel:       n.Spec.Channel,
+		PeerIDs:       peerIDs,
+		OrdererBundle: bundlePath,
+		Address:       n.Chaincode.Address,
+	})
+	if deployment != nil {
+		n.Chaincode = deployment.Definition
+		n.PackageID = deployment.PackageID
+		n.Processes = append(n.Processes, Process{Kind: KindChaincode, ID: n.Chaincode.Name, PID: deployment.PID})
+	}
+	return err
+}
+
+// waitExited waits for the run.json of a node to be removed by its hlf-easy
+// process on exit. A process killed without removing it, as taskkill does on
+// Windows, leaves a stale one that is removed
+func waitExited(kind string, id string, pid int) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	runPath := filepath.Join(home, "hlf-easy", kind+"s", id, "run.json")
+	deadline := time.Now().Add(stopTimeout)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(runPath); os.IsNotExist(err) {
+			return nil
+		}
+		if !proc.Running(pid) {
+			err = os.Remove(runPath)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return errors.Errorf("%s %s didn't stop within %s", kind, id, stopTimeout)
+}
+
+// Stop stops the processes of the sandbox, the peers and the orderer are
+// stopped through their management API first so their Fabric process is
+// stopped gracefully even when the hlf-easy one is killed
+func (n *Network) Stop() error {
+	var failed []string
+	// the chaincode server and the peers are stopped before the orderer
+	for i := len(n.Processes) - 1; i >= 0; i-- {
+		p := n.Processes[i]
+		if !proc.Running(p.PID) {
+			continue
+		}
+		if p.Kind != KindChaincode {
+			if err := dashboard.RunAction(p.Kind, p.ID, "stop", ""); err != nil {
+				log.Warnf("Failed to stop %s %s through its management API: %v", p.Kind, p.ID, err)
+			}
+		}
+		err := proc.Terminate(p.PID)
+		if err == nil && p.Kind != KindChaincode {
+			err = waitExited(p.Kind, p.ID, p.PID)
+		}
+		if err != nil {
+			log.Warnf("Failed to stop %s %s: %v", p.Kind, p.ID, err)
+			failed = append(failed, p.ID)
+		}
+	}
+	n.Processes = []Process{}
+	if len(failed) > 0 {
+		return errors.Errorf("failed to stop %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Remove deletes the nodes, the CAs, the cluster and the chaincode of a
+// stopped sandbox, and its directory
+func (n *Network) Remove() error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	for _, p := range n.Peers() {
+		// the peers that failed to init have no directory
+		if _, err := os.Stat(filepath.Join(home, "hlf-easy/peers", p.ID)); os.IsNotExist(err) {
+			continue
+		}
+		err = node.RemovePeer(p.ID)
+		if err != nil {
+			return err
+		}
+	}
+	dirs := []string{filepath.Join(home, "hlf-easy/cas", OrdererCAName)}
+	for _, m := range n.Cluster.Members {
+		dirs = append(dirs, filepath.Join(home, "hlf-easy/orderers", m.ID))
+	}
+	for _, org := range n.Orgs {
+		dirs = append(dirs, filepath.Join(home, "hlf-easy/cas", org.CAName))
+	}
+	for _, dir := range dirs {
+		err = os.RemoveAll(dir)
+		if err != nil {
+			return err
+		}
+	}
+	err = os.Remove(filepath.Join(home, "hlf-easy/clusters", n.Cluster.Name+".json"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	services, err := chaincode.ListServices()
+	if err != nil {
+		return err
+	}
+	for _, s := range services {
+		if s.Name != n.Chaincode.Name {
+			continue
+		}
+		err = chaincode.RemoveService(s.Name)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := chaincode.Get(n.Chaincode.Name); err == nil {
+		err = chaincode.Remove(n.Chaincode.Name)
+		if err != nil {
+			return err
+		}
+	}
+	logPath, err := samples.GetLogPath(n.Chaincode.Name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(logPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	dir, err := GetDir()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
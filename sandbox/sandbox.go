@@ -0,0 +1,346 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/chaincode"
+	"hlf-easy/cluster"
+	"hlf-easy/samples"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Defaults of a sandbox
+const (
+	DefaultOrgs           = 2
+	DefaultPeersPerOrg    = 2
+	DefaultChannel        = "sandbox"
+	DefaultBasePort       = 7050
+	DefaultOperationsPort = 9443
+	DefaultSample         = "basic"
+	// DefaultStartTimeout is how long a node has to become healthy
+	DefaultStartTimeout = 60 * time.Second
+)
+
+// Limits of the size of a sandbox, it runs on a single host
+const (
+	MaxOrgs        = 10
+	MaxPeersPerOrg = 10
+)
+
+// Host is the host of all the nodes of a sandbox, their certificates are
+// issued for it
+const Host = "localhost"
+
+// Names of the nodes and CAs of a sandbox, they are prefixed so they don't
+// collide with the other nodes of the host
+const (
+	OrdererID     = "sandbox-orderer0"
+	OrdererCAName = "sandbox-orderer-ca"
+	OrdererMSPID  = "SandboxOrdererMSP"
+	ClusterName   = "sandbox"
+)
+
+// Kinds of the processes of a sandbox
+const (
+	KindOrderer   = "orderer"
+	KindPeer      = "peer"
+	KindChaincode = "chaincode"
+)
+
+var channelRegexp = regexp.MustCompile(`^[a-z][a-z0-9.-]*$`)
+
+// Spec is the size of a sandbox and the ports it uses
+type Spec struct {
+	Orgs        int    `json:"orgs"`
+	PeersPerOrg int    `json:"peersPerOrg"`
+	Channel     string `json:"channel"`
+	// Sample is the sample chaincode deployed on the channel
+	Sample string `json:"sample"`
+	// BasePort is the first port of the orderer, the peers and the chaincode
+	// server, they take consecutive ports
+	BasePort int `json:"basePort"`
+	// OperationsPort is the first port of the operations services of the
+	// nodes on 127.0.0.1
+	OperationsPort int `json:"operationsPort"`
+}
+
+// Validate checks the size of the sandbox and that its ports are valid
+func (s Spec) Validate() error {
+	if s.Orgs < 1 || s.Orgs > MaxOrgs {
+		return errors.Errorf("the sandbox needs from 1 to %d orgs, got %d", MaxOrgs, s.Orgs)
+	}
+	if s.PeersPerOrg < 1 || s.PeersPerOrg > MaxPeersPerOrg {
+		return errors.Errorf("the sandbox needs from 1 to %d peers per org, got %d", MaxPeersPerOrg, s.PeersPerOrg)
+	}
+	if !channelRegexp.MatchString(s.Channel) {
+		return errors.Errorf("invalid channel name %q", s.Channel)
+	}
+	if _, err := samples.Get(s.Sample); err != nil {
+		return err
+	}
+	peers := s.Orgs * s.PeersPerOrg
+	// the orderer takes 2 ports, every peer 3 and the chaincode server 1
+	lastPort := s.BasePort + 2 + 3*peers
+	lastOperationsPort := s.OperationsPort + peers
+	if s.BasePort <= 0 || lastPort > 65535 {
+		return errors.Errorf("the ports from %d to %d aren't valid", s.BasePort, lastPort)
+	}
+	if s.OperationsPort <= 0 || lastOperationsPort > 65535 {
+		return errors.Errorf("the operations ports from %d to %d aren't valid", s.OperationsPort, lastOperationsPort)
+	}
+	if s.BasePort <= lastOperationsPort && s.OperationsPort <= lastPort {
+		return errors.Errorf("the ports from %d to %d overlap the operations ports from %d to %d", s.BasePort, lastPort, s.OperationsPort, lastOperationsPort)
+	}
+	return nil
+}
+
+// Peer is a peer of an org of the sandbox
+type Peer struct {
+	ID             string `json:"id"`
+	Port           int    `json:"port"`
+	ChaincodePort  int    `json:"chaincodePort"`
+	EventsPort     int    `json:"eventsPort"`
+	OperationsPort int    `json:"operationsPort"`
+}
+
+// Endpoint returns the external endpoint of the peer
+func (p Peer) Endpoint() string {
+	return net.JoinHostPort(Host, strconv.Itoa(p.Port))
+}
+
+// Org is an application organization of the sandbox with its own local CA
+type Org struct {
+	Name   string `json:"name"`
+	MSPID  string `json:"mspID"`
+	CAName string `json:"caName"`
+	Peers  []Peer `json:"peers"`
+}
+
+// Process is a process of the sandbox running in the background
+type Process struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	PID  int    `json:"pid"`
+}
+
+// Network is a sandbox, stored in $HOME/hlf-easy/sandbox/network.json
+type Network struct {
+	Spec    Spec         `json:"spec"`
+	Orgs    []Org        `json:"orgs"`
+	Cluster cluster.Spec `json:"cluster"`
+	// Chaincode is the sample chaincode served by the sandbox, its name is
+	// the one of the sample prefixed with sandbox
+	Chaincode chaincode.Definition `json:"chaincode"`
+	// PackageID of the chaincode installed in the peers
+	PackageID string    `json:"packageID,omitempty"`
+	Processes []Process `json:"processes"`
+}
+
+// New lays out the nodes of a sandbox, every node listens on its own ports
+// starting from the base port so they all run on localhost
+func New(spec Spec) (*Network, error) {
+	err := spec.Validate()
+	if err != nil {
+		return nil, err
+	}
+	port := spec.BasePort
+	next := func() int {
+		port++
+		return port - 1
+	}
+	operationsPort := spec.OperationsPort
+	nextOperations := func() int {
+		operationsPort++
+		return operationsPort - 1
+	}
+	n := &Network{
+		Spec: spec,
+		Cluster: cluster.Spec{
+			Name:   ClusterName,
+			MSPID:  OrdererMSPID,
+			CAName: OrdererCAName,
+			Members: []cluster.Member{{
+				ID:             OrdererID,
+				Host:           Host,
+				Port:           next(),
+				AdminPort:      next(),
+				OperationsPort: nextOperations(),
+			}},
+		},
+		Processes: []Process{},
+	}
+	for i := 1; i <= spec.Orgs; i++ {
+		org := Org{
+			Name:   fmt.Sprintf("org%d", i),
+			MSPID:  fmt.Sprintf("SandboxOrg%dMSP", i),
+			CAName: fmt.Sprintf("sandbox-org%d-ca", i),
+		}
+		for j := 0; j < spec.PeersPerOrg; j++ {
+			org.Peers = append(org.Peers, Peer{
+				ID:             fmt.Sprintf("sandbox-%s-peer%d", org.Name, j),
+				Port:           next(),
+				ChaincodePort:  next(),
+				EventsPort:     next(),
+				OperationsPort: nextOperations(),
+			})
+		}
+		n.Orgs = append(n.Orgs, org)
+	}
+	n.Chaincode = chaincode.Definition{
+		Name:    "sandbox-" + spec.Sample,
+		Version: samples.DefaultVersion,
+		Type:    chaincode.TypeCCaaS,
+		Address: net.JoinHostPort("127.0.0.1", strconv.Itoa(next())),
+	}
+	return n, nil
+}
+
+// Peers returns the peers of all the orgs
+func (n *Network) Peers() []Peer {
+	var peers []Peer
+	for _, org := range n.Orgs {
+		peers = append(peers, org.Peers...)
+	}
+	return peers
+}
+
+// Ports returns the ports the sandbox listens on, each one with the node
+// listening on it
+func (n *Network) Ports() map[int]string {
+	ports := map[int]string{}
+	for _, m := range n.Cluster.Members {
+		ports[m.Port] = m.ID
+		ports[m.AdminPort] = m.ID
+		ports[m.OperationsPort] = m.ID
+	}
+	for _, p := range n.Peers() {
+		for _, port := range []int{p.Port, p.ChaincodePort, p.EventsPort, p.OperationsPort} {
+			ports[port] = p.ID
+		}
+	}
+	_, ccPort, _ := net.SplitHostPort(n.Chaincode.Address)
+	port, _ := strconv.Atoi(ccPort)
+	ports[port] = n.Chaincode.Name
+	return ports
+}
+
+// CheckPorts checks that the ports of the sandbox are free on the host
+func (n *Network) CheckPorts() error {
+	for port, id := range n.Ports() {
+		l, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(port)))
+		if err != nil {
+			return errors.Errorf("the port %d of %s is in use, choose other ports with --base-port", port, id)
+		}
+		err = l.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InitCommands are the hlf-easy commands that create the CAs of the orgs and
+// the orderer, and enroll the orderer and the peers
+func (n *Network) InitCommands() [][]string {
+	commands := [][]string{
+		{"ca", "init", "--name", OrdererCAName, "--organization", OrdererMSPID, "--hosts", Host},
+	}
+	for _, org := range n.Orgs {
+		commands = append(commands, []string{"ca", "init", "--name", org.CAName, "--organization", org.MSPID, "--hosts", Host})
+	}
+	clusterInit := []string{"orderer", "cluster", "init", "--name", n.Cluster.Name, "--msp-id", n.Cluster.MSPID, "--ca-name", n.Cluster.CAName}
+	for _, m := range n.Cluster.Members {
+		clusterInit = append(clusterInit, "--orderer", fmt.Sprintf("%s=%s,admin=%d,operations=%d", m.ID, m.Endpoint(), m.AdminPort, m.OperationsPort))
+	}
+	commands = append(commands, clusterInit)
+	for _, org := range n.Orgs {
+		for _, p := range org.Peers {
+			commands = append(commands, []string{"peer", "init",
+				"--local",
+				"--ca-name", org.CAName,
+				"--id", p.ID,
+				"--hosts", Host,
+				"--msp-id", org.MSPID,
+				"--external-port", strconv.Itoa(p.Port),
+				"--operations-listen-address", net.JoinHostPort("127.0.0.1", strconv.Itoa(p.OperationsPort)),
+			})
+		}
+	}
+	return commands
+}
+
+// PeerStartArgs are the arguments of the hlf-easy command that starts a peer
+// of an org
+func PeerStartArgs(org Org, p Peer) []string {
+	return []string{"peer", "start",
+		"--id", p.ID,
+		"--msp-id", org.MSPID,
+		"--listen-address", net.JoinHostPort("0.0.0.0", strconv.Itoa(p.Port)),
+		"--chaincode-address", net.JoinHostPort("0.0.0.0", strconv.Itoa(p.ChaincodePort)),
+		"--events-address", net.JoinHostPort("0.0.0.0", strconv.Itoa(p.EventsPort)),
+		"--external-endpoint", p.Endpoint(),
+	}
+}
+
+// GetDir returns the directory of the sandbox with its network.json and the
+// orderer bundle of its channel
+func GetDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/sandbox"), nil
+}
+
+// OrdererBundlePath returns the orderer bundle written for the channel
+func OrdererBundlePath() (string, error) {
+	dir, err := GetDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "orderer-bundle.yaml"), nil
+}
+
+// Save writes the network.json of the sandbox
+func Save(n *Network) error {
+	dir, err := GetDir()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	networkBytes, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "network.json"), networkBytes, 0644)
+}
+
+// Load reads the network.json of the sandbox
+func Load() (*Network, error) {
+	dir, err := GetDir()
+	if err != nil {
+		return nil, err
+	}
+	networkBytes, err := os.ReadFile(filepath.Join(dir, "network.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("there is no sandbox on the host, create it with sandbox up")
+		}
+		return nil, err
+	}
+	n := &Network{}
+	err = json.Unmarshal(networkBytes, n)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse the network.json of the sandbox")
+	}
+	return n, nil
+}
@@ -0,0 +1,97 @@
+package sandbox
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testSpec() Spec {
+	return Spec{
+		Orgs:           DefaultOrgs,
+		PeersPerOrg:    DefaultPeersPerOrg,
+		Channel:        DefaultChannel,
+		Sample:         DefaultSample,
+		BasePort:       DefaultBasePort,
+		OperationsPort: DefaultOperationsPort,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := testSpec().Validate(); err != nil {
+		t.Fatal(err)
+	}
+	invalid := map[string]func(s *Spec){
+		"no orgs":          func(s *Spec) { s.Orgs = 0 },
+		"too many peers":   func(s *Spec) { s.PeersPerOrg = MaxPeersPerOrg + 1 },
+		"invalid channel":  func(s *Spec) { s.Channel = "Sandbox" },
+		"unknown sample":   func(s *Spec) { s.Sample = "marbles" },
+		"ports overflow":   func(s *Spec) { s.BasePort = 65530 },
+		"ports overlapped": func(s *Spec) { s.OperationsPort = DefaultBasePort + 4 },
+	}
+	for name, f := range invalid {
+		spec := testSpec()
+		f(&spec)
+		if err := spec.Validate(); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	n, err := New(testSpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Orgs) != 2 || len(n.Peers()) != 4 {
+		t.Fatalf("expected 2 orgs of 2 peers, got %+v", n.Orgs)
+	}
+	if n.Orgs[1].MSPID != "SandboxOrg2MSP" || n.Orgs[1].Peers[1].ID != "sandbox-org2-peer1" {
+		t.Errorf("unexpected org %+v", n.Orgs[1])
+	}
+	if err := n.Cluster.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// the orderer takes 2 ports, every peer 3 and the chaincode server 1,
+	// the orderer and every peer an operations port
+	ports := n.Ports()
+	if len(ports) != 2+3*4+1+5 {
+		t.Fatalf("expected 20 unique ports, got %d: %v", len(ports), ports)
+	}
+	for port := DefaultBasePort; port <= DefaultBasePort+14; port++ {
+		if _, ok := ports[port]; !ok {
+			t.Errorf("expected the port %d to be used", port)
+		}
+	}
+	if n.Chaincode.Name != "sandbox-basic" || n.Chaincode.Address != "127.0.0.1:7064" {
+		t.Errorf("unexpected chaincode %+v", n.Chaincode)
+	}
+}
+
+func TestInitCommands(t *testing.T) {
+	n, err := New(testSpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands := n.InitCommands()
+	// the orderer CA, a CA per org, the cluster and the peers
+	if len(commands) != 1+2+1+4 {
+		t.Fatalf("expected 8 commands, got %d", len(commands))
+	}
+	clusterInit := strings.Join(commands[3], " ")
+	if !strings.Contains(clusterInit, "--orderer sandbox-orderer0=localhost:7050,admin=7051,operations=9443") {
+		t.Errorf("unexpected cluster init %s", clusterInit)
+	}
+	expected := []string{"peer", "init",
+		"--local",
+		"--ca-name", "sandbox-org2-ca",
+		"--id", "sandbox-org2-peer1",
+		"--hosts", "localhost",
+		"--msp-id", "SandboxOrg2MSP",
+		"--external-port", "7061",
+		"--operations-listen-address", "127.0.0.1:9447",
+	}
+	if !reflect.DeepEqual(commands[7], expected) {
+		t.Errorf("expected %v, got %v", expected, commands[7])
+	}
+}
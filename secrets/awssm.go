@@ -0,0 +1,123 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/sigv4"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// awsStore keeps the secrets in AWS Secrets Manager, its credentials are
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+type awsStore struct {
+	endpoint string
+	prefix   string
+	signer   sigv4.Signer
+}
+
+func newAWSStore(region string, prefix string) (*awsStore, error) {
+	signer := sigv4.Signer{
+		Region:       region,
+		Service:      "secretsmanager",
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if signer.AccessKey == "" || signer.SecretKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required by AWS Secrets Manager")
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &awsStore{
+		endpoint: fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region),
+		prefix:   prefix,
+		signer:   signer,
+	}, nil
+}
+
+// call calls an action of the API of Secrets Manager, the type of the
+// exceptions is returned with their error
+func (s *awsStore) call(ctx context.Context, action string, input interface{}, output interface{}) (string, error) {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager."+action)
+	s.signer.Sign(req, sigv4.PayloadHash(body))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		exception := struct {
+			Type    string `json:"__type"`
+			Message string
+		}{}
+		_ = json.Unmarshal(respBody, &exception)
+		// the type can be prefixed with its namespace
+		exceptionType := exception.Type[strings.LastIndex(exception.Type, "#")+1:]
+		return exceptionType, errors.Errorf("secretsmanager %s returned %s: %s %s", action, resp.Status, exceptionType, exception.Message)
+	}
+	if output == nil {
+		return "", nil
+	}
+	return "", json.Unmarshal(respBody, output)
+}
+
+func (s *awsStore) Put(ctx context.Context, name string, value []byte) error {
+	exception, err := s.call(ctx, "PutSecretValue", map[string]interface{}{
+		"SecretId":     s.prefix + name,
+		"SecretBinary": value,
+	}, nil)
+	if exception == "ResourceNotFoundException" {
+		_, err = s.call(ctx, "CreateSecret", map[string]interface{}{
+			"Name":         s.prefix + name,
+			"SecretBinary": value,
+		}, nil)
+	}
+	return errors.Wrapf(err, "failed to write %s to AWS Secrets Manager", name)
+}
+
+func (s *awsStore) Get(ctx context.Context, name string) ([]byte, error) {
+	secret := struct {
+		SecretBinary []byte
+		SecretString string
+	}{}
+	exception, err := s.call(ctx, "GetSecretValue", map[string]string{"SecretId": s.prefix + name}, &secret)
+	if exception == "ResourceNotFoundException" {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.SecretBinary == nil {
+		return []byte(secret.SecretString), nil
+	}
+	return secret.SecretBinary, nil
+}
+
+// Delete schedules the removal of a secret, it can be recovered for 7 days
+func (s *awsStore) Delete(ctx context.Context, name string) error {
+	_, err := s.call(ctx, "DeleteSecret", map[string]interface{}{
+		"SecretId":             s.prefix + name,
+		"RecoveryWindowInDays": 7,
+	}, nil)
+	return errors.Wrapf(err, "failed to remove %s from AWS Secrets Manager", name)
+}
@@ -0,0 +1,158 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// azureAPIVersion is the version of the API of Key Vault
+const azureAPIVersion = "7.4"
+
+// invalidAzureName are the characters a name of a secret of Key Vault can't
+// have
+var invalidAzureName = regexp.MustCompile(`[^0-9A-Za-z-]`)
+
+// azureStore keeps the secrets in Azure Key Vault, with the token of a
+// service principal read from AZURE_TENANT_ID, AZURE_CLIENT_ID and
+// AZURE_CLIENT_SECRET
+type azureStore struct {
+	vaultURL     string
+	authorityURL string
+	tenantID     string
+	clientID     string
+	clientSecret string
+	prefix       string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newAzureStore(vault string, prefix string) (*azureStore, error) {
+	s := &azureStore{
+		vaultURL:     fmt.Sprintf("https://%s.vault.azure.net", vault),
+		authorityURL: "https://login.microsoftonline.com",
+		tenantID:     os.Getenv("AZURE_TENANT_ID"),
+		clientID:     os.Getenv("AZURE_CLIENT_ID"),
+		clientSecret: os.Getenv("AZURE_CLIENT_SECRET"),
+		prefix:       prefix,
+	}
+	if s.tenantID == "" || s.clientID == "" || s.clientSecret == "" {
+		return nil, errors.New("AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET are required by Azure Key Vault")
+	}
+	return s, nil
+}
+
+// secretURL returns the URL of a secret, the names of Key Vault only have
+// letters, digits and dashes
+func (s *azureStore) secretURL(name string) string {
+	if s.prefix != "" {
+		name = s.prefix + "-" + name
+	}
+	return fmt.Sprintf("%s/secrets/%s?api-version=%s", s.vaultURL, invalidAzureName.ReplaceAllString(name, "-"), azureAPIVersion)
+}
+
+// getToken returns the access token of the service principal, it's requested
+// again a minute before it expires
+func (s *azureStore) getToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {s.clientID},
+		"client_secret": {s.clientSecret},
+		"scope":         {"https://vault.azure.net/.default"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s/oauth2/v2.0/token", s.authorityURL, s.tenantID), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := do(req, http.StatusOK)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get a token of Azure AD")
+	}
+	token := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", errors.New("invalid token of Azure AD")
+	}
+	s.token = token.AccessToken
+	s.expires = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
+
+func (s *azureStore) request(ctx context.Context, method string, name string, body []byte) (*http.Request, error) {
+	token, err := s.getToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.secretURL(name), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func (s *azureStore) Put(ctx context.Context, name string, value []byte) error {
+	body, err := json.Marshal(map[string]string{
+		"value":       base64.StdEncoding.EncodeToString(value),
+		"contentType": "application/octet-stream;base64",
+	})
+	if err != nil {
+		return err
+	}
+	req, err := s.request(ctx, http.MethodPut, name, body)
+	if err != nil {
+		return err
+	}
+	_, err = do(req, http.StatusOK)
+	return errors.Wrapf(err, "failed to write %s to Azure Key Vault", name)
+}
+
+func (s *azureStore) Get(ctx context.Context, name string) ([]byte, error) {
+	req, err := s.request(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	secret := struct {
+		Value string `json:"value"`
+	}{}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, errors.Wrapf(err, "invalid secret %s of Azure Key Vault", name)
+	}
+	return base64.StdEncoding.DecodeString(secret.Value)
+}
+
+// Delete removes a secret, it's kept as a deleted secret when soft-delete is
+// enabled on the vault
+func (s *azureStore) Delete(ctx context.Context, name string) error {
+	req, err := s.request(ctx, http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+	_, err = do(req, http.StatusOK)
+	return errors.Wrapf(err, "failed to remove %s from Azure Key Vault", name)
+}
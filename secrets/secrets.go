@@ -0,0 +1,102 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds the requests to a secrets manager of the callers
+// without a context of their own
+const DefaultTimeout = 30 * time.Second
+
+// ErrNotFound is returned by Get when the store has no secret of the name
+var ErrNotFound = errors.New("secret not found")
+
+// httpClient sends the requests of the stores
+var httpClient = &http.Client{Timeout: DefaultTimeout}
+
+// Store is a secrets manager keeping the private keys of the CAs and of the
+// nodes out of ~/hlf-easy
+type Store interface {
+	Put(ctx context.Context, name string, value []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// Open returns the store of a URL: the KV version 2 engine of HashiCorp
+// Vault, vault://<mount>/<prefix>, AWS Secrets Manager,
+// awssm://<region>/<prefix>, or Azure Key Vault, azurekv://<vault>/<prefix>.
+// Their credentials are read from the environment
+func Open(rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, errors.Errorf("invalid secrets manager %q, expected vault://<mount>/<prefix>, awssm://<region>/<prefix> or azurekv://<vault>/<prefix>", rawURL)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "vault":
+		return newVaultStore(u.Host, prefix)
+	case "awssm":
+		return newAWSStore(u.Host, prefix)
+	case "azurekv":
+		return newAzureStore(u.Host, prefix)
+	}
+	return nil, errors.Errorf("unsupported secrets manager %q, expected vault://, awssm:// or azurekv://", rawURL)
+}
+
+// names of the private keys in a store
+const (
+	CAKey    = "ca-key"
+	TLSCAKey = "tlsca-key"
+	SignKey  = "sign-key"
+)
+
+// KeyName returns the name of a private key of a CA or of a node in a store,
+// e.g. peer-peer1-sign-key
+func KeyName(kind string, id string, key string) string {
+	return fmt.Sprintf("%s-%s-%s", kind, id, key)
+}
+
+// Fetch reads a secret of the store of a URL within DefaultTimeout
+func Fetch(rawURL string, name string) ([]byte, error) {
+	store, err := Open(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	value, err := store.Get(ctx, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch %s from %s", name, rawURL)
+	}
+	return value, nil
+}
+
+// do sends a request and returns the body of its response, the statuses
+// other than expected are errors and 404 is ErrNotFound
+func do(req *http.Request, expected ...int) ([]byte, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return body, nil
+		}
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	return nil, errors.Errorf("%s %s returned %s: %s", req.Method, req.URL.Host, resp.Status, strings.TrimSpace(string(body)))
+}
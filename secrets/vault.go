@@ -0,0 +1,101 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// vaultStore keeps the secrets in the KV version 2 engine of HashiCorp Vault,
+// configured with VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE like the
+// vault-transit sealer
+type vaultStore struct {
+	address   string
+	token     string
+	namespace string
+	mount     string
+	prefix    string
+}
+
+func newVaultStore(mount string, prefix string) (*vaultStore, error) {
+	s := &vaultStore{
+		address:   strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"),
+		token:     os.Getenv("VAULT_TOKEN"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		mount:     mount,
+		prefix:    prefix,
+	}
+	if s.address == "" || s.token == "" {
+		return nil, errors.New("VAULT_ADDR and VAULT_TOKEN are required by the Vault secrets manager")
+	}
+	return s, nil
+}
+
+// request returns a request of the API of the KV engine, kind is data or
+// metadata
+func (s *vaultStore) request(ctx context.Context, method string, kind string, name string, body []byte) (*http.Request, error) {
+	url := s.address + "/v1/" + path.Join(s.mount, kind, s.prefix, name)
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func (s *vaultStore) Put(ctx context.Context, name string, value []byte) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"data": map[string]string{"value": base64.StdEncoding.EncodeToString(value)},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := s.request(ctx, http.MethodPost, "data", name, body)
+	if err != nil {
+		return err
+	}
+	_, err = do(req, http.StatusOK, http.StatusNoContent)
+	return errors.Wrapf(err, "failed to write %s to Vault", name)
+}
+
+func (s *vaultStore) Get(ctx context.Context, name string) ([]byte, error) {
+	req, err := s.request(ctx, http.MethodGet, "data", name, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	secret := struct {
+		Data struct {
+			Data struct {
+				Value string `json:"value"`
+			} `json:"data"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, errors.Wrapf(err, "invalid secret %s of Vault", name)
+	}
+	return base64.StdEncoding.DecodeString(secret.Data.Data.Value)
+}
+
+// Delete removes all the versions of a secret
+func (s *vaultStore) Delete(ctx context.Context, name string) error {
+	req, err := s.request(ctx, http.MethodDelete, "metadata", name, nil)
+	if err != nil {
+		return err
+	}
+	_, err = do(req, http.StatusOK, http.StatusNoContent)
+	return errors.Wrapf(err, "failed to remove %s from Vault", name)
+}
@@ -0,0 +1,104 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/backup"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// defaultBackupKeep is the number of backups of a node kept by default
+const defaultBackupKeep = 7
+
+// backupCredentials returns the credentials of the target of a backup task
+func backupCredentials(params map[string]string) backup.Credentials {
+	return backup.Credentials{
+		Region:    params["region"],
+		AccessKey: params["access-key"],
+		SecretKey: params["secret-key"],
+		SSHKey:    params["ssh-key"],
+	}
+}
+
+// runBackup archives the crypto material and the config of the node, and the
+// last snapshots of the channels of a peer with ledger=true, to dir and to
+// target: a directory, a bucket or an SFTP server. The keep newest backups
+// (7) are kept, and the ones older than max-age are removed
+func runBackup(ctx context.Context, n Node, params map[string]string) (string, error) {
+	now := time.Now()
+	keep := defaultBackupKeep
+	if value, ok := params["keep"]; ok {
+		keep, _ = strconv.Atoi(value)
+	}
+	var maxAge time.Duration
+	if value, ok := params["max-age"]; ok {
+		maxAge, _ = time.ParseDuration(value)
+	}
+	storages := []backup.Storage{}
+	for _, target := range []string{params["dir"], params["target"]} {
+		if target == "" {
+			continue
+		}
+		storage, err := backup.OpenStorage(target, backupCredentials(params))
+		if err != nil {
+			return "", err
+		}
+		storages = append(storages, storage)
+	}
+	// the archive is written in dir, or in a temporary file uploaded to the
+	// target
+	dir := params["dir"]
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	name := backup.Name(n.Kind, n.ID, now)
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	files, err := backup.Write(f, n.Dir, params["ledger"] == "true")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// a partial archive can't be restored
+		_ = os.Remove(path)
+		return "", errors.Wrap(err, "failed to write the backup")
+	}
+	if params["dir"] == "" {
+		defer os.Remove(path)
+	}
+	locations := ""
+	for _, storage := range storages {
+		if err := storage.Put(ctx, name, path); err != nil {
+			return "", err
+		}
+		if locations != "" {
+			locations += " and "
+		}
+		locations += storage.Location(name)
+	}
+	output := fmt.Sprintf("Backed up %d files to %s", files, locations)
+	removed := 0
+	for _, storage := range storages {
+		names, err := storage.List(ctx, backup.Prefix(n.Kind, n.ID))
+		if err != nil {
+			return output, err
+		}
+		for _, old := range backup.Expired(n.Kind, n.ID, names, keep, maxAge, now) {
+			if err := storage.Delete(ctx, old); err != nil {
+				return output, err
+			}
+			removed++
+		}
+	}
+	return output + fmt.Sprintf(", removed %d old backups", removed), nil
+}
@@ -0,0 +1,159 @@
+package tasks
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testNodeDir writes the directory of a peer with its material, a ledger and
+// a snapshot
+func testNodeDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.json":          "{}",
+		"msp/keystore/priv_sk": "key",
+		"data/ledgersData/chains/chains/ch1/blockfile": "blocks",
+		"run/stdout.log": "output",
+		"data/snapshots/completed/ch1/10/txids.data": "old",
+		"data/snapshots/completed/ch1/25/txids.data": "new",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readArchive(t *testing.T, r io.Reader) map[string]string {
+	t.Helper()
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[header.Name] = string(content)
+		}
+	}
+}
+
+func TestBackupDir(t *testing.T) {
+	n := Node{Kind: "peer", ID: "peer1", Dir: testNodeDir(t)}
+	backupDir := t.TempDir()
+	old := filepath.Join(backupDir, "peer-peer1-20200101T000000Z.tar.gz")
+	if err := os.WriteFile(old, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	output, err := runBackup(context.Background(), n, map[string]string{"dir": backupDir, "ledger": "true", "keep": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, "Backed up 3 files") || !strings.Contains(output, "removed 1 old backups") {
+		t.Fatalf("unexpected output %s", output)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatal("expected the old backup to be removed")
+	}
+	entries, err := os.ReadDir(backupDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected a single backup, got %v, %v", entries, err)
+	}
+	f, err := os.Open(filepath.Join(backupDir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	files := readArchive(t, f)
+	if files["node/msp/keystore/priv_sk"] != "key" || files["node/config.json"] != "{}" || files["snapshots/ch1/25/txids.data"] != "new" || len(files) != 3 {
+		t.Fatalf("expected the material of the node and its last snapshot, got %v", files)
+	}
+	_, err = runBackup(context.Background(), Node{Kind: "peer", ID: "peer2", Dir: t.TempDir()}, map[string]string{"dir": backupDir, "ledger": "true"})
+	if err == nil {
+		t.Fatal("expected a peer without snapshots to fail")
+	}
+}
+
+func TestBackupS3(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{
+		"backups/org1/peer-peer1-20200101T000000Z.tar.gz":       []byte("old"),
+		"backups/org1/orderer-orderer1-20200101T000000Z.tar.gz": []byte("orderer"),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch {
+		case r.Method == http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[key] = body
+		case r.Method == http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && r.URL.Path == "/bucket":
+			fmt.Fprint(w, "<ListBucketResult>")
+			for name := range objects {
+				if strings.HasPrefix(name, r.URL.Query().Get("prefix")) {
+					fmt.Fprintf(w, "<Contents><Key>%s</Key></Contents>", name)
+				}
+			}
+			fmt.Fprint(w, "<IsTruncated>false</IsTruncated></ListBucketResult>")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	n := Node{Kind: "peer", ID: "peer1", Dir: testNodeDir(t)}
+	output, err := runBackup(context.Background(), n, map[string]string{
+		"target":     srv.URL + "/bucket/backups/org1",
+		"access-key": "access",
+		"secret-key": "secret",
+		"keep":       "1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output, srv.URL+"/bucket/backups/org1/peer-peer1-") || !strings.Contains(output, "removed 1 old backups") {
+		t.Fatalf("unexpected output %s", output)
+	}
+	if len(objects) != 2 || objects["backups/org1/orderer-orderer1-20200101T000000Z.tar.gz"] == nil {
+		t.Fatalf("expected the new backup and the backup of the orderer, got %d objects", len(objects))
+	}
+	_, err = runBackup(context.Background(), n, map[string]string{"target": srv.URL + "/bucket", "access-key": "other", "secret-key": "secret"})
+	if err == nil {
+		t.Fatal("expected a refused upload to fail")
+	}
+}
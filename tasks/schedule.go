@@ -0,0 +1,163 @@
+package tasks
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the next time a task runs after a time
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// everySchedule runs a task every interval
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// cronSchedule runs a task at the minutes matching all its fields, the day of
+// the month and the day of the week are or-ed when both are restricted, as in
+// cron
+type cronSchedule struct {
+	minutes  []bool
+	hours    []bool
+	days     []bool
+	months   []bool
+	weekdays []bool
+	// anyDay and anyWeekday tell if the fields are *
+	anyDay     bool
+	anyWeekday bool
+}
+
+func (s cronSchedule) matchDay(t time.Time) bool {
+	day := s.days[t.Day()]
+	weekday := s.weekdays[int(t.Weekday())]
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+func (s cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// a schedule that never matches, e.g. on February 30th, stops after 5 years
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !s.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// parseField parses a field of a cron expression: *, a value, a range a-b, a
+// step */n or a-b/n, or a list of them separated by commas
+func parseField(field string, min int, max int) ([]bool, error) {
+	values := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return nil, errors.Errorf("invalid step %q", part)
+			}
+		}
+		start, end := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			start, err = strconv.Atoi(from)
+			if err != nil {
+				return nil, errors.Errorf("invalid value %q", part)
+			}
+			end = start
+			if isRange {
+				end, err = strconv.Atoi(to)
+				if err != nil {
+					return nil, errors.Errorf("invalid range %q", part)
+				}
+			} else if hasStep {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, errors.Errorf("%q is out of the range %d-%d", part, min, max)
+		}
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// ParseSchedule parses a cron expression with 5 fields (minute, hour, day of
+// the month, month and day of the week) or @every <duration>, @hourly, @daily
+// or @weekly
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	}
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid schedule %q", spec)
+		}
+		if interval < time.Minute {
+			return nil, errors.Errorf("invalid schedule %q, the interval must be at least 1m", spec)
+		}
+		return everySchedule{interval: interval}, nil
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("invalid schedule %q, expected 5 fields or @every <duration>", spec)
+	}
+	bounds := [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	parsed := make([][]bool, len(fields))
+	for i, field := range fields {
+		values, err := parseField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid schedule %q", spec)
+		}
+		parsed[i] = values
+	}
+	// 7 is also sunday
+	weekdays := parsed[4]
+	weekdays[0] = weekdays[0] || weekdays[7]
+	return cronSchedule{
+		minutes:    parsed[0],
+		hours:      parsed[1],
+		days:       parsed[2],
+		months:     parsed[3],
+		weekdays:   weekdays,
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
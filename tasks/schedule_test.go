@@ -0,0 +1,49 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// a wednesday
+	now := time.Date(2024, 1, 3, 10, 30, 15, 0, time.UTC)
+	for spec, expected := range map[string]time.Time{
+		"* * * * *":       time.Date(2024, 1, 3, 10, 31, 0, 0, time.UTC),
+		"0 3 * * *":       time.Date(2024, 1, 4, 3, 0, 0, 0, time.UTC),
+		"*/15 * * * *":    time.Date(2024, 1, 3, 10, 45, 0, 0, time.UTC),
+		"0,40 10 * * *":   time.Date(2024, 1, 3, 10, 40, 0, 0, time.UTC),
+		"0 9-17/4 * * *":  time.Date(2024, 1, 3, 13, 0, 0, 0, time.UTC),
+		"0 0 * * 0":       time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":       time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		"0 0 1 * *":       time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":      time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		"0 0 15 * 5":      time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		"30 4 1 1 *":      time.Date(2025, 1, 1, 4, 30, 0, 0, time.UTC),
+		"@hourly":         time.Date(2024, 1, 3, 11, 0, 0, 0, time.UTC),
+		"@daily":          time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+		"@weekly":         time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		"@every 90m":      now.Add(90 * time.Minute),
+		" 0  3  *  *  * ": time.Date(2024, 1, 4, 3, 0, 0, 0, time.UTC),
+	} {
+		schedule, err := ParseSchedule(spec)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", spec, err)
+		}
+		if next := schedule.Next(now); !next.Equal(expected) {
+			t.Errorf("expected %q to run at %s, got %s", spec, expected, next)
+		}
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@every 10s", "@every soon", "@monthly"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("expected %q to be invalid", spec)
+		}
+	}
+	schedule, err := ParseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := schedule.Next(now); !next.IsZero() {
+		t.Errorf("expected a schedule that never matches not to run, got %s", next)
+	}
+}
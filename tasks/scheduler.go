@@ -0,0 +1,306 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/log"
+	"hlf-easy/notify"
+	"hlf-easy/utils"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Statuses of the runs
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// Triggers of the runs
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// MaxHistory is the number of runs kept in the history of a node
+const MaxHistory = 200
+
+// maxOutput is the size of the end of the output of a run that's kept
+const maxOutput = 4096
+
+// Run is a run of a task, the runs of a node are stored in the
+// tasks-history.json of its directory
+type Run struct {
+	Task      string    `json:"task"`
+	Type      string    `json:"type"`
+	Trigger   string    `json:"trigger"`
+	StartedAt time.Time `json:"startedAt"`
+	// Duration in seconds
+	Duration float64 `json:"duration"`
+	Status   string  `json:"status"`
+	Output   string  `json:"output,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// TaskStatus is a task with its next run
+type TaskStatus struct {
+	config.TaskConfig
+	NextRun *time.Time `json:"nextRun,omitempty"`
+	Running bool       `json:"running"`
+	// Error is set when the task is invalid, it doesn't run
+	Error string `json:"error,omitempty"`
+}
+
+// Scheduler runs the tasks of a node on their schedule, the tasks are read
+// again on every check so they can be changed while the node runs
+type Scheduler struct {
+	node Node
+	mu   sync.Mutex
+	// next runs of the tasks, computed with the schedule of schedules
+	next      map[string]time.Time
+	schedules map[string]string
+	running   map[string]bool
+	// invalid are the invalid tasks already logged
+	invalid map[string]string
+}
+
+// historyMu serializes the updates of the histories of the process
+var historyMu sync.Mutex
+
+// NewScheduler returns the scheduler of the tasks of a node
+func NewScheduler(n Node) *Scheduler {
+	return &Scheduler{
+		node:      n,
+		next:      map[string]time.Time{},
+		schedules: map[string]string{},
+		running:   map[string]bool{},
+		invalid:   map[string]string{},
+	}
+}
+
+// Tasks returns the tasks of the node with their next run
+func (s *Scheduler) Tasks() ([]TaskStatus, error) {
+	nodeTasks, err := utils.GetNodeTasks(s.node.Kind, s.node.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := []TaskStatus{}
+	for _, task := range nodeTasks.Tasks {
+		status := TaskStatus{TaskConfig: task, Running: s.running[task.Name]}
+		if err := ValidateTask(s.node.Kind, task); err != nil {
+			status.Error = err.Error()
+		} else if next, ok := s.next[task.Name]; ok && !task.Disabled && s.schedules[task.Name] == task.Schedule {
+			status.NextRun = &next
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// check runs the tasks that are due, the next run of a new or changed task
+// is computed from now
+func (s *Scheduler) check(ctx context.Context, now time.Time) {
+	nodeTasks, err := utils.GetNodeTasks(s.node.Kind, s.node.ID)
+	if err != nil {
+		log.Warnf("Failed to read the tasks of %s %s: %v", s.node.Kind, s.node.ID, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, task := range nodeTasks.Tasks {
+		if task.Disabled {
+			delete(s.next, task.Name)
+			continue
+		}
+		if err := ValidateTask(s.node.Kind, task); err != nil {
+			if s.invalid[task.Name] != err.Error() {
+				log.Warnf("Task %s of %s %s is invalid, it won't run: %v", task.Name, s.node.Kind, s.node.ID, err)
+				s.invalid[task.Name] = err.Error()
+			}
+			delete(s.next, task.Name)
+			continue
+		}
+		delete(s.invalid, task.Name)
+		schedule, _ := ParseSchedule(task.Schedule)
+		next, ok := s.next[task.Name]
+		if !ok || s.schedules[task.Name] != task.Schedule {
+			s.next[task.Name] = schedule.Next(now)
+			s.schedules[task.Name] = task.Schedule
+			continue
+		}
+		if now.Before(next) {
+			continue
+		}
+		s.next[task.Name] = schedule.Next(now)
+		if s.running[task.Name] {
+			log.Warnf("Task %s of %s %s is still running, skipping its run", task.Name, s.node.Kind, s.node.ID)
+			continue
+		}
+		s.running[task.Name] = true
+		go func(task config.TaskConfig) {
+			s.run(ctx, task, TriggerSchedule)
+			s.mu.Lock()
+			delete(s.running, task.Name)
+			s.mu.Unlock()
+		}(task)
+	}
+}
+
+// RunTask runs a task of the node now
+func (s *Scheduler) RunTask(ctx context.Context, name string) (*Run, error) {
+	nodeTasks, err := utils.GetNodeTasks(s.node.Kind, s.node.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range nodeTasks.Tasks {
+		if task.Name != name {
+			continue
+		}
+		if err := ValidateTask(s.node.Kind, task); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		if s.running[name] {
+			s.mu.Unlock()
+			return nil, errors.Errorf("task %s is already running", name)
+		}
+		s.running[name] = true
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, name)
+			s.mu.Unlock()
+		}()
+		return s.run(ctx, task, TriggerManual), nil
+	}
+	return nil, errors.Errorf("task %s not found", name)
+}
+
+// run runs a task, records it in the history and notifies its failure
+func (s *Scheduler) run(ctx context.Context, task config.TaskConfig, trigger string) *Run {
+	run := RunTask(ctx, s.node, task, trigger)
+	if run.Status == StatusFailed {
+		log.Errorf("Task %s of %s %s failed: %s", task.Name, s.node.Kind, s.node.ID, run.Error)
+		event := notify.NewEvent(notify.EventTaskFailed, s.node.Kind, s.node.ID, fmt.Sprintf("Task %s of %s %s failed: %s", task.Name, s.node.Kind, s.node.ID, run.Error))
+		event.Details = map[string]string{
+			"task": task.Name,
+			"type": task.Type,
+		}
+		notify.Notify(event)
+	} else {
+		log.Infof("Task %s of %s %s: %s", task.Name, s.node.Kind, s.node.ID, run.Output)
+	}
+	if err := AddRun(s.node.Kind, s.node.ID, *run); err != nil {
+		log.Warnf("Failed to record the run of task %s: %v", task.Name, err)
+	}
+	return run
+}
+
+// Run checks the tasks every interval until the context is done
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.check(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DefaultCheckInterval is how often the scheduler looks for the due tasks
+const DefaultCheckInterval = 15 * time.Second
+
+// RunTask runs a valid task on a node
+func RunTask(ctx context.Context, n Node, task config.TaskConfig, trigger string) *Run {
+	run := &Run{
+		Task:      task.Name,
+		Type:      task.Type,
+		Trigger:   trigger,
+		StartedAt: time.Now().UTC(),
+		Status:    StatusOK,
+	}
+	output, err := types[task.Type].run(ctx, n, task.Params)
+	run.Duration = time.Since(run.StartedAt).Seconds()
+	if len(output) > maxOutput {
+		output = "..." + output[len(output)-maxOutput:]
+	}
+	run.Output = output
+	if err != nil {
+		run.Status = StatusFailed
+		run.Error = err.Error()
+	}
+	return run
+}
+
+func getHistoryPath(kind string, id string) (string, error) {
+	nodeDir, err := utils.GetNodeDir(kind, id)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(nodeDir, "tasks-history.json"), nil
+}
+
+// GetHistory returns the runs of the tasks of a node, the last first. The
+// runs of all the tasks are returned if task is empty
+func GetHistory(kind string, id string, task string) ([]Run, error) {
+	historyPath, err := getHistoryPath(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	runs := []Run{}
+	historyBytes, err := os.ReadFile(historyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return runs, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(historyBytes, &runs)
+	if err != nil {
+		return nil, err
+	}
+	if task == "" {
+		return runs, nil
+	}
+	filtered := []Run{}
+	for _, run := range runs {
+		if run.Task == task {
+			filtered = append(filtered, run)
+		}
+	}
+	return filtered, nil
+}
+
+// AddRun adds a run to the history of a node, only the last MaxHistory runs
+// are kept
+func AddRun(kind string, id string, run Run) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	runs, err := GetHistory(kind, id, "")
+	if err != nil {
+		return err
+	}
+	runs = append([]Run{run}, runs...)
+	if len(runs) > MaxHistory {
+		runs = runs[:MaxHistory]
+	}
+	historyBytes, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return err
+	}
+	historyPath, err := getHistoryPath(kind, id)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(historyPath, historyBytes, 0644)
+}
@@ -0,0 +1,431 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"hlf-easy/notify"
+	"hlf-easy/report"
+	"hlf-easy/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of the tasks
+const (
+	TypeSnapshot       = "snapshot"
+	TypeCouchDBCompact = "couchdb-compact"
+	TypeLogPrune       = "log-prune"
+	TypeCertCheck      = "cert-check"
+	TypeBackup         = "backup"
+)
+
+// Node is the node the tasks run on
+type Node struct {
+	Kind  string
+	ID    string
+	Dir   string
+	MSPID string
+	// Address is the endpoint of the peer used to request the snapshots
+	Address string
+	// Logs are the outputs of the node saved by its hlf-easy process
+	Logs []*config.SaveOutputWriter
+}
+
+// runner runs a task and returns its output
+type runner func(ctx context.Context, n Node, params map[string]string) (string, error)
+
+type taskType struct {
+	// kinds are the kinds of nodes the task can run on
+	kinds  []string
+	params []string
+	// required are the params that must be set
+	required []string
+	run      runner
+}
+
+var types = map[string]taskType{
+	TypeSnapshot: {
+		kinds:    []string{"peer"},
+		params:   []string{"channels", "msp-dir"},
+		required: []string{"msp-dir"},
+		run:      runSnapshot,
+	},
+	TypeCouchDBCompact: {
+		kinds:    []string{"peer"},
+		params:   []string{"url", "databases"},
+		required: []string{"url"},
+		run:      runCouchDBCompact,
+	},
+	TypeLogPrune: {
+		kinds:  []string{"peer", "orderer"},
+		params: []string{"max-size-mb", "dir", "max-age"},
+		run:    runLogPrune,
+	},
+	TypeCertCheck: {
+		kinds:  []string{"peer", "orderer"},
+		params: []string{"warning-days"},
+		run:    runCertCheck,
+	},
+	TypeBackup: {
+		kinds:  []string{"peer", "orderer"},
+		params: []string{"dir", "target", "region", "access-key", "secret-key", "ssh-key", "ledger", "keep", "max-age"},
+		run:    runBackup,
+	},
+}
+
+// Types are the names of the types of tasks
+func Types() []string {
+	names := []string{}
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateTask checks the name, type, schedule and params of a task of a kind
+// of node
+func ValidateTask(kind string, task config.TaskConfig) error {
+	if task.Name == "" {
+		return errors.New("the task has no name")
+	}
+	t, ok := types[task.Type]
+	if !ok {
+		return errors.Errorf("unknown type %q of task %s, expected one of %s", task.Type, task.Name, strings.Join(Types(), ", "))
+	}
+	if !containsString(t.kinds, kind) {
+		return errors.Errorf("%s tasks can't run on a %s", task.Type, kind)
+	}
+	_, err := ParseSchedule(task.Schedule)
+	if err != nil {
+		return err
+	}
+	for name := range task.Params {
+		if !containsString(t.params, name) {
+			return errors.Errorf("unknown param %s of %s tasks, expected one of %s", name, task.Type, strings.Join(t.params, ", "))
+		}
+	}
+	for _, name := range t.required {
+		if task.Params[name] == "" {
+			return errors.Errorf("%s tasks need the %s param", task.Type, name)
+		}
+	}
+	for _, name := range []string{"max-size-mb", "warning-days", "keep"} {
+		if value, ok := task.Params[name]; ok {
+			if n, err := strconv.Atoi(value); err != nil || n < 0 {
+				return errors.Errorf("invalid %s %q", name, value)
+			}
+		}
+	}
+	if maxAge, ok := task.Params["max-age"]; ok {
+		if _, err := time.ParseDuration(maxAge); err != nil {
+			return errors.Wrapf(err, "invalid max-age %q", maxAge)
+		}
+	}
+	if rawURL, ok := task.Params["url"]; ok {
+		if u, err := url.Parse(rawURL); err != nil || u.Host == "" {
+			return errors.Errorf("invalid url of task %s", task.Name)
+		}
+	}
+	if task.Type == TypeBackup {
+		return validateBackup(kind, task)
+	}
+	return nil
+}
+
+// validateBackup checks the destinations and the ledger param of a backup
+// task, the credentials of a bucket can come from the environment
+func validateBackup(kind string, task config.TaskConfig) error {
+	if task.Params["dir"] == "" && task.Params["target"] == "" {
+		return errors.New("backup tasks need the dir or the target param")
+	}
+	if target, ok := task.Params["target"]; ok {
+		u, err := url.Parse(target)
+		if err != nil {
+			return errors.Errorf("invalid target of task %s", task.Name)
+		}
+		switch u.Scheme {
+		case "", "file", "s3", "gs", "http", "https", "sftp":
+		default:
+			return errors.Errorf("unsupported target %q of task %s, expected a directory or an s3://, gs://, http(s):// or sftp:// URL", target, task.Name)
+		}
+		if u.Scheme != "" && u.Scheme != "file" && u.Host == "" {
+			return errors.Errorf("invalid target %q of task %s, it has no bucket or host", target, task.Name)
+		}
+		if (u.Scheme == "http" || u.Scheme == "https") && strings.Trim(u.Path, "/") == "" {
+			return errors.Errorf("invalid target %q of task %s, expected http(s)://<endpoint>/<bucket>/<prefix>", target, task.Name)
+		}
+	}
+	if ledger, ok := task.Params["ledger"]; ok {
+		if ledger != "true" && ledger != "false" {
+			return errors.Errorf("invalid ledger %q, expected true or false", ledger)
+		}
+		if ledger == "true" && kind != "peer" {
+			return errors.New("only the snapshots of the ledgers of the peers are backed up")
+		}
+	}
+	return nil
+}
+
+func containsString(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma separated param
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+var execCommand = exec.CommandContext
+
+// runSnapshot requests a snapshot of the channels of the peer at their
+// current height, with an identity of an admin of the MSP of the peer
+func runSnapshot(ctx context.Context, n Node, params map[string]string) (string, error) {
+	channels := splitList(params["channels"])
+	if len(channels) == 0 {
+		entries, err := os.ReadDir(filepath.Join(n.Dir, "data/ledgersData/chains/chains"))
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				channels = append(channels, entry.Name())
+			}
+		}
+	}
+	if len(channels) == 0 {
+		return "The peer has joined no channel", nil
+	}
+	output := ""
+	for _, channel := range channels {
+		cmd := execCommand(ctx, "peer", "snapshot", "submitrequest",
+			"--channelID", channel,
+			"--blockNumber", "0",
+			"--peerAddress", n.Address,
+			"--tlsRootCertFile", filepath.Join(n.Dir, "tlscacerts/cacert.pem"),
+		)
+		cmd.Env = append(os.Environ(),
+			fmt.Sprintf("FABRIC_CFG_PATH=%s", n.Dir),
+			fmt.Sprintf("CORE_PEER_MSPCONFIGPATH=%s", params["msp-dir"]),
+			fmt.Sprintf("CORE_PEER_LOCALMSPID=%s", n.MSPID),
+			"CORE_PEER_TLS_ENABLED=true",
+		)
+		out, err := cmd.CombinedOutput()
+		output += string(out)
+		if err != nil {
+			return output, errors.Wrapf(err, "failed to request a snapshot of %s", channel)
+		}
+	}
+	return output, nil
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// runCouchDBCompact starts the compaction of the databases of CouchDB, all
+// the databases except the system ones by default
+func runCouchDBCompact(ctx context.Context, n Node, params map[string]string) (string, error) {
+	baseURL := strings.TrimSuffix(params["url"], "/")
+	databases := splitList(params["databases"])
+	if len(databases) == 0 {
+		body, err := couchDBRequest(ctx, http.MethodGet, baseURL+"/_all_dbs", http.StatusOK)
+		if err != nil {
+			return "", err
+		}
+		all := []string{}
+		err = json.Unmarshal(body, &all)
+		if err != nil {
+			return "", err
+		}
+		for _, db := range all {
+			if !strings.HasPrefix(db, "_") {
+				databases = append(databases, db)
+			}
+		}
+	}
+	for _, db := range databases {
+		_, err := couchDBRequest(ctx, http.MethodPost, baseURL+"/"+url.PathEscape(db)+"/_compact", http.StatusAccepted)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to compact %s", db)
+		}
+	}
+	return fmt.Sprintf("Compaction of %d databases started: %s", len(databases), strings.Join(databases, ", ")), nil
+}
+
+func couchDBRequest(ctx context.Context, method string, rawURL string, expected int) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// the error has the URL, with the credentials of CouchDB
+		return nil, errors.Errorf("request to CouchDB failed: %s", redactURL(err.Error(), rawURL))
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != expected {
+		return nil, errors.Errorf("CouchDB returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// redactURL removes the password of a URL from a message
+func redactURL(message string, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil {
+		return message
+	}
+	if password, ok := u.User.Password(); ok {
+		message = strings.ReplaceAll(message, password, "xxxxx")
+	}
+	return message
+}
+
+// runLogPrune keeps the last max-size-mb (50 by default) of the logs saved by
+// the process of the node, and removes the files older than max-age of dir
+func runLogPrune(ctx context.Context, n Node, params map[string]string) (string, error) {
+	maxSizeMB := 50
+	if value, ok := params["max-size-mb"]; ok {
+		maxSizeMB, _ = strconv.Atoi(value)
+	}
+	pruned := 0
+	for _, logs := range n.Logs {
+		pruned += logs.Prune(maxSizeMB * 1024 * 1024)
+	}
+	output := fmt.Sprintf("Pruned %d bytes of logs", pruned)
+	if dir := params["dir"]; dir != "" {
+		maxAge := 7 * 24 * time.Hour
+		if value, ok := params["max-age"]; ok {
+			maxAge, _ = time.ParseDuration(value)
+		}
+		removed, err := removeOldFiles(dir, time.Now().Add(-maxAge))
+		if err != nil {
+			return output, err
+		}
+		output += fmt.Sprintf(", removed %d files of %s", removed, dir)
+	}
+	return output, nil
+}
+
+func removeOldFiles(dir string, before time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return removed, err
+		}
+		if info.ModTime().Before(before) {
+			err = os.Remove(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// runCertCheck fails when a certificate of the node expires within
+// warning-days (30 by default), the expiring certificates are notified
+func runCertCheck(ctx context.Context, n Node, params map[string]string) (string, error) {
+	warningDays := 30
+	if value, ok := params["warning-days"]; ok {
+		warningDays, _ = strconv.Atoi(value)
+	}
+	r, err := report.Generate(time.Now(), warningDays)
+	if err != nil {
+		return "", err
+	}
+	owner := n.Kind + "/" + n.ID
+	certificates := []report.Certificate{}
+	for _, crt := range r.Certificates {
+		if crt.Owner == owner {
+			certificates = append(certificates, crt)
+		}
+	}
+	r.Certificates = certificates
+	events := report.ExpiringCertificateEvents(r)
+	for _, event := range events {
+		notify.Notify(event)
+	}
+	if len(events) > 0 {
+		messages := []string{}
+		for _, event := range events {
+			messages = append(messages, event.Message)
+		}
+		return "", errors.New(strings.Join(messages, "; "))
+	}
+	return fmt.Sprintf("%d certificates valid for more than %d days", len(certificates), warningDays), nil
+}
+
+// SaveTask adds a task to a node, or replaces the task with its name
+func SaveTask(kind string, id string, task config.TaskConfig) error {
+	err := ValidateTask(kind, task)
+	if err != nil {
+		return err
+	}
+	nodeTasks, err := utils.GetNodeTasks(kind, id)
+	if err != nil {
+		return err
+	}
+	saved := []config.TaskConfig{}
+	for _, t := range nodeTasks.Tasks {
+		if t.Name != task.Name {
+			saved = append(saved, t)
+		}
+	}
+	nodeTasks.Tasks = append(saved, task)
+	return utils.SaveNodeTasks(kind, id, nodeTasks)
+}
+
+// RemoveTask removes a task of a node
+func RemoveTask(kind string, id string, name string) error {
+	nodeTasks, err := utils.GetNodeTasks(kind, id)
+	if err != nil {
+		return err
+	}
+	saved := []config.TaskConfig{}
+	for _, t := range nodeTasks.Tasks {
+		if t.Name != name {
+			saved = append(saved, t)
+		}
+	}
+	if len(saved) == len(nodeTasks.Tasks) {
+		return errors.Errorf("task %s not found", name)
+	}
+	nodeTasks.Tasks = saved
+	return utils.SaveNodeTasks(kind, id, nodeTasks)
+}
@@ -0,0 +1,251 @@
+package tasks
+
+import (
+	"context"
+	"hlf-easy/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateTask(t *testing.T) {
+	valid := []struct {
+		kind string
+		task config.TaskConfig
+	}{
+		{"peer", config.TaskConfig{Name: "snap", Type: TypeSnapshot, Schedule: "@daily", Params: map[string]string{"msp-dir": "/admin/msp"}}},
+		{"peer", config.TaskConfig{Name: "compact", Type: TypeCouchDBCompact, Schedule: "0 3 * * 0", Params: map[string]string{"url": "http://admin:pw@127.0.0.1:5984"}}},
+		{"orderer", config.TaskConfig{Name: "prune", Type: TypeLogPrune, Schedule: "@hourly", Params: map[string]string{"max-size-mb": "10", "max-age": "72h", "dir": "/var/log/hlf"}}},
+		{"orderer", config.TaskConfig{Name: "certs", Type: TypeCertCheck, Schedule: "@daily"}},
+		{"peer", config.TaskConfig{Name: "backup", Type: TypeBackup, Schedule: "0 4 * * *", Params: map[string]string{"dir": "/backups", "ledger": "true", "keep": "14"}}},
+		{"orderer", config.TaskConfig{Name: "backup", Type: TypeBackup, Schedule: "@daily", Params: map[string]string{"target": "s3://backups/org1", "region": "eu-west-1", "max-age": "720h"}}},
+		{"peer", config.TaskConfig{Name: "backup", Type: TypeBackup, Schedule: "@daily", Params: map[string]string{"dir": "/backups", "target": "sftp://backup@vault.example.com:2222/srv/backups", "ssh-key": "/root/.ssh/backup"}}},
+	}
+	for _, tc := range valid {
+		if err := ValidateTask(tc.kind, tc.task); err != nil {
+			t.Errorf("expected %s to be valid: %v", tc.task.Name, err)
+		}
+	}
+	invalid := []struct {
+		kind string
+		task config.TaskConfig
+	}{
+		{"peer", config.TaskConfig{Type: TypeCertCheck, Schedule: "@daily"}},
+		{"peer", config.TaskConfig{Name: "x", Type: "defrag", Schedule: "@daily"}},
+		{"peer", config.TaskConfig{Name: "x", Type: TypeBackup, Schedule: "@daily"}},
+		{"peer", config.TaskConfig{Name: "x", Type: TypeBackup, Schedule: "@daily", Params: map[string]string{"target": "https://s3.amazonaws.com"}}},
+		{"peer", config.TaskConfig{Name: "x", Type: TypeBackup, Schedule: "@daily", Params: map[string]string{"target": "ftp://backups.example.com/org1"}}},
+		{"peer", config.TaskConfig{Name: "x", Type: TypeBackup, Schedule: "@daily", Params: map[string]string{"target": "gs:///org1"}}},
+		{"peer", config.TaskConfig{Name: "x", Type: TypeBackup, Schedule: "@daily", Params: map[string]string{"dir": "/backups", "keep": "-1"}}},
+		{"orderer", config.TaskConfig{Name: "x", Type: TypeBackup, Schedule: "@daily", Params: map[string]string{"dir": "/backups", "ledger": "true"}}},
+		{"orderer", config.TaskConfig{Name: "x", Type: TypeSnapshot, Schedule: "@daily", Params: map[string]string{"msp-dir": "/admin/msp"}}},
+		{"peer", config.TaskConfig{Name: "x", Type: TypeSnapshot, Schedule: "@daily"}},
+		{"peer", config.TaskConfig{Name: "x", Type: TypeCertCheck, Schedule: "daily"}},
+		{"peer", config.TaskConfig{Name: "x", Type: TypeCertCheck, Schedule: "@daily", Params: map[string]string{"days": "3"}}},
+		{"peer", config.TaskConfig{Name: "x", Type: TypeCertCheck, Schedule: "@daily", Params: map[string]string{"warning-days": "-1"}}},
+		{"peer", config.TaskConfig{Name: "x", Type: TypeLogPrune, Schedule: "@daily", Params: map[string]string{"max-age": "1 week"}}},
+		{"peer", config.TaskConfig{Name: "x", Type: TypeCouchDBCompact, Schedule: "@daily", Params: map[string]string{"url": "couchdb"}}},
+	}
+	for _, tc := range invalid {
+		if err := ValidateTask(tc.kind, tc.task); err == nil {
+			t.Errorf("expected %+v to be invalid on a %s", tc.task, tc.kind)
+		}
+	}
+}
+
+func TestCouchDBCompact(t *testing.T) {
+	var compacted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "admin" || password != "secretpw" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/_all_dbs" {
+			w.Write([]byte(`["_replicator","_users","mychannel_","mychannel_asset"]`))
+			return
+		}
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/_compact") {
+			compacted = append(compacted, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/_compact"))
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"ok":true}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	couchURL := strings.Replace(srv.URL, "http://", "http://admin:secretpw@", 1)
+
+	output, err := runCouchDBCompact(context.Background(), Node{}, map[string]string{"url": couchURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(compacted, ",") != "mychannel_,mychannel_asset" {
+		t.Fatalf("expected the databases of the channels to be compacted, got %v", compacted)
+	}
+	if !strings.Contains(output, "2 databases") {
+		t.Fatalf("unexpected output %q", output)
+	}
+	_, err = runCouchDBCompact(context.Background(), Node{}, map[string]string{"url": srv.URL, "databases": "mychannel_"})
+	if err == nil {
+		t.Fatal("expected the compaction to fail without the credentials")
+	}
+	srv.Close()
+	_, err = runCouchDBCompact(context.Background(), Node{}, map[string]string{"url": couchURL, "databases": "mychannel_"})
+	if err == nil || strings.Contains(err.Error(), "secretpw") {
+		t.Fatalf("expected an error without the password of CouchDB, got %v", err)
+	}
+}
+
+func TestLogPrune(t *testing.T) {
+	logs := &config.SaveOutputWriter{}
+	for i := 0; i < 3; i++ {
+		logs.Write([]byte(strings.Repeat("x", 1023) + "\n"))
+	}
+	dir := t.TempDir()
+	old := filepath.Join(dir, "peer.log.1")
+	recent := filepath.Join(dir, "peer.log")
+	for _, path := range []string{old, recent} {
+		if err := os.WriteFile(path, []byte("log"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	twoDaysAgo := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(old, twoDaysAgo, twoDaysAgo); err != nil {
+		t.Fatal(err)
+	}
+	output, err := runLogPrune(context.Background(), Node{Logs: []*config.SaveOutputWriter{logs}}, map[string]string{
+		"max-size-mb": "0",
+		"dir":         dir,
+		"max-age":     "24h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs.GetSavedOutput()) != 0 || !strings.Contains(output, "3072 bytes") {
+		t.Fatalf("expected the saved logs to be pruned, got %q", output)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatal("expected the old log file to be removed")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Fatal("expected the recent log file to be kept")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	for _, channel := range []string{"ch1", "ch2"} {
+		if err := os.MkdirAll(filepath.Join(dir, "data/ledgersData/chains/chains", channel), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var commands [][]string
+	execCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		commands = append(commands, append([]string{name}, args...))
+		return exec.CommandContext(ctx, "true")
+	}
+	defer func() { execCommand = exec.CommandContext }()
+	n := Node{Kind: "peer", ID: "peer1", Dir: dir, MSPID: "Org1MSP", Address: "peer1.example.com:7051"}
+	_, err := runSnapshot(context.Background(), n, map[string]string{"msp-dir": "/admin/msp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 2 {
+		t.Fatalf("expected a snapshot request per channel, got %v", commands)
+	}
+	command := strings.Join(commands[0], " ")
+	if !strings.Contains(command, "snapshot submitrequest --channelID ch1") || !strings.Contains(command, "--peerAddress peer1.example.com:7051") {
+		t.Fatalf("unexpected command %s", command)
+	}
+	commands = nil
+	_, err = runSnapshot(context.Background(), n, map[string]string{"msp-dir": "/admin/msp", "channels": "ch2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commands) != 1 || commands[0][4] != "ch2" {
+		t.Fatalf("expected only the snapshot of ch2, got %v", commands)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	nodeDir := filepath.Join(home, "hlf-easy/peers/peer1")
+	logDir := filepath.Join(home, "logs")
+	for _, dir := range []string{nodeDir, logDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := SaveTask("peer", "peer1", config.TaskConfig{Name: "prune", Type: TypeLogPrune, Schedule: "@every 1h", Params: map[string]string{"dir": logDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SaveTask("peer", "peer1", config.TaskConfig{Name: "broken", Type: TypeLogPrune, Schedule: "@every 1h", Params: map[string]string{"dir": filepath.Join(home, "missing")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewScheduler(Node{Kind: "peer", ID: "peer1", Dir: nodeDir})
+	now := time.Now()
+	// the first check schedules the tasks
+	s.check(context.Background(), now)
+	statuses, err := s.Tasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0].NextRun == nil || !statuses[0].NextRun.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected the tasks to run in 1h, got %+v", statuses)
+	}
+	s.check(context.Background(), now.Add(time.Hour))
+	deadline := time.Now().Add(5 * time.Second)
+	var runs []Run
+	for time.Now().Before(deadline) {
+		runs, err = GetHistory("peer", "peer1", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected the 2 due tasks to run, got %+v", runs)
+	}
+	broken, err := GetHistory("peer", "peer1", "broken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(broken) != 1 || broken[0].Status != StatusFailed || broken[0].Trigger != TriggerSchedule || broken[0].Error == "" {
+		t.Fatalf("expected the failure of the broken task to be recorded, got %+v", broken)
+	}
+
+	run, err := s.RunTask(context.Background(), "prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != StatusOK || run.Trigger != TriggerManual {
+		t.Fatalf("unexpected run %+v", run)
+	}
+	runs, err = GetHistory("peer", "peer1", "prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].Trigger != TriggerManual {
+		t.Fatalf("expected the manual run first in the history, got %+v", runs)
+	}
+	if _, err := s.RunTask(context.Background(), "missing"); err == nil {
+		t.Fatal("expected an unknown task not to run")
+	}
+	if err := RemoveTask("peer", "peer1", "broken"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveTask("peer", "peer1", "broken"); err == nil {
+		t.Fatal("expected removing an unknown task to fail")
+	}
+}
@@ -3,7 +3,7 @@ package ui
 import (
 	"embed"
 	"fmt"
-	log "github.com/sirupsen/logrus"
+	"hlf-easy/log"
 	"net/http"
 	"path"
 )
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+)
+
+func getAlertsConfigFilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/alerts.json"), nil
+}
+
+// GetAlertsConfig reads the alert rules of the host, it's empty when no rule
+// is configured
+func GetAlertsConfig() (*config.AlertsConfig, error) {
+	alertsConfigFilePath, err := getAlertsConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+	alertsConfig := &config.AlertsConfig{}
+	alertsConfigBytes, err := os.ReadFile(alertsConfigFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return alertsConfig, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(alertsConfigBytes, alertsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return alertsConfig, nil
+}
+
+// SaveAlertsConfig writes the alert rules of the host
+func SaveAlertsConfig(alertsConfig *config.AlertsConfig) error {
+	alertsConfigFilePath, err := getAlertsConfigFilePath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(alertsConfigFilePath), 0755)
+	if err != nil {
+		return err
+	}
+	alertsConfigBytes, err := json.MarshalIndent(alertsConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(alertsConfigFilePath, alertsConfigBytes, 0644)
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+)
+
+func getAnomalyConfigFilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/anomaly.json"), nil
+}
+
+// GetAnomalyConfig reads the anomaly rules of the host, it's empty when no
+// rule is configured
+func GetAnomalyConfig() (*config.AnomalyConfig, error) {
+	anomalyConfigFilePath, err := getAnomalyConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+	anomalyConfig := &config.AnomalyConfig{}
+	anomalyConfigBytes, err := os.ReadFile(anomalyConfigFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return anomalyConfig, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(anomalyConfigBytes, anomalyConfig)
+	if err != nil {
+		return nil, err
+	}
+	return anomalyConfig, nil
+}
+
+// SaveAnomalyConfig writes the anomaly rules of the host
+func SaveAnomalyConfig(anomalyConfig *config.AnomalyConfig) error {
+	anomalyConfigFilePath, err := getAnomalyConfigFilePath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(anomalyConfigFilePath), 0755)
+	if err != nil {
+		return err
+	}
+	anomalyConfigBytes, err := json.MarshalIndent(anomalyConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(anomalyConfigFilePath, anomalyConfigBytes, 0644)
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+)
+
+func getAPITokensFilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/apitokens.json"), nil
+}
+
+// GetAPITokens reads the API tokens of the host
+func GetAPITokens() (*config.APITokens, error) {
+	apiTokensFilePath, err := getAPITokensFilePath()
+	if err != nil {
+		return nil, err
+	}
+	apiTokens := &config.APITokens{}
+	apiTokensBytes, err := os.ReadFile(apiTokensFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return apiTokens, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(apiTokensBytes, apiTokens)
+	if err != nil {
+		return nil, err
+	}
+	return apiTokens, nil
+}
+
+// SaveAPITokens writes the API tokens of the host, only readable by the user
+func SaveAPITokens(apiTokens *config.APITokens) error {
+	apiTokensFilePath, err := getAPITokensFilePath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(apiTokensFilePath), 0755)
+	if err != nil {
+		return err
+	}
+	apiTokensBytes, err := json.MarshalIndent(apiTokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(apiTokensFilePath, apiTokensBytes, 0600)
+}
+
+func getAPIPoliciesFilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/apipolicies.json"), nil
+}
+
+// GetAPIPolicies reads the roles of the management APIs of the host, it's
+// empty when only the default roles are used
+func GetAPIPolicies() (*config.APIPolicies, error) {
+	apiPoliciesFilePath, err := getAPIPoliciesFilePath()
+	if err != nil {
+		return nil, err
+	}
+	apiPolicies := &config.APIPolicies{}
+	apiPoliciesBytes, err := os.ReadFile(apiPoliciesFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return apiPolicies, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(apiPoliciesBytes, apiPolicies)
+	if err != nil {
+		return nil, err
+	}
+	return apiPolicies, nil
+}
+
+// SaveAPIPolicies writes the roles of the management APIs of the host
+func SaveAPIPolicies(apiPolicies *config.APIPolicies) error {
+	apiPoliciesFilePath, err := getAPIPoliciesFilePath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(apiPoliciesFilePath), 0755)
+	if err != nil {
+		return err
+	}
+	apiPoliciesBytes, err := json.MarshalIndent(apiPolicies, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(apiPoliciesFilePath, apiPoliciesBytes, 0644)
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"crypto/x509"
+	"fmt"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/config"
+	"os"
+	"strings"
+)
+
+// ReadOrdererBundle reads and validates the orderer bundle in the file
+func ReadOrdererBundle(bundlePath string) (*config.OrdererBundle, error) {
+	bundleBytes, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &config.OrdererBundle{}
+	err = yaml.Unmarshal(bundleBytes, bundle)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse orderer bundle %s", bundlePath)
+	}
+	err = ValidateOrdererBundle(bundle)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid orderer bundle %s", bundlePath)
+	}
+	return bundle, nil
+}
+
+// ValidateOrdererBundle checks that the bundle has everything needed to
+// create and join channels on the ordering service
+func ValidateOrdererBundle(bundle *config.OrdererBundle) error {
+	if bundle.MSPID == "" {
+		return errors.New("mspID is required")
+	}
+	if _, err := ParsePEMCertificates(bundle.CACerts); err != nil {
+		return errors.Wrap(err, "invalid caCerts")
+	}
+	if _, err := ParsePEMCertificates(bundle.TLSCACerts); err != nil {
+		return errors.Wrap(err, "invalid tlsCACerts")
+	}
+	if len(bundle.Orderers) == 0 {
+		return errors.New("at least one orderer is required")
+	}
+	bft := bundle.ConsensusType == "BFT"
+	if bundle.ConsensusType != "" && bundle.ConsensusType != "etcdraft" && !bft {
+		return errors.Errorf("unknown consensusType %s, expected etcdraft or BFT", bundle.ConsensusType)
+	}
+	consenterIDs := map[uint32]bool{}
+	for i, orderer := range bundle.Orderers {
+		if orderer.Host == "" || orderer.Port == 0 {
+			return errors.Errorf("orderer %d must have a host and a port", i)
+		}
+		if _, err := ParseX509Certificate([]byte(orderer.TLSCert)); err != nil {
+			return errors.Wrapf(err, "invalid tlsCert of orderer %s:%d", orderer.Host, orderer.Port)
+		}
+		if !bft {
+			continue
+		}
+		// the consenters of a BFT ordering service are mapped to their identity
+		if _, err := ParseX509CertificateChain([]byte(orderer.Identity)); err != nil {
+			return errors.Wrapf(err, "invalid identity of orderer %s:%d, it's required by BFT", orderer.Host, orderer.Port)
+		}
+		id := orderer.ConsenterID(i)
+		if consenterIDs[id] {
+			return errors.Errorf("the consenter ID %d of orderer %s:%d is used by another orderer", id, orderer.Host, orderer.Port)
+		}
+		consenterIDs[id] = true
+	}
+	return nil
+}
+
+// ParsePEMCertificates parses a list of PEM bundles, at least one certificate is required
+func ParsePEMCertificates(pems []string) ([]*x509.Certificate, error) {
+	chain, err := ParseX509CertificateChain([]byte(strings.Join(pems, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	return chain, nil
+}
+
+// OrdererBundleURL returns the grpcs URL of an orderer of the bundle
+func OrdererBundleURL(orderer config.BundleOrderer) string {
+	return fmt.Sprintf("grpcs://%s:%d", orderer.Host, orderer.Port)
+}
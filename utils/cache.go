@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// cachedConfig is a config parsed from a config.json, valid while the file
+// keeps the modification time and the size it had when it was parsed
+type cachedConfig struct {
+	modTime time.Time
+	size    int64
+	value   interface{}
+}
+
+var (
+	configCacheMu sync.Mutex
+	// configCache are the parsed configs of the CAs and the nodes by the path
+	// of their config.json, the API polls them and their keys can be in a
+	// secrets manager
+	configCache = map[string]cachedConfig{}
+)
+
+// getCachedConfig returns the config parsed from a file, unless the file
+// changed since it was parsed
+func getCachedConfig(path string, info os.FileInfo) (interface{}, bool) {
+	configCacheMu.Lock()
+	defer configCacheMu.Unlock()
+	c, ok := configCache[path]
+	if !ok || !c.modTime.Equal(info.ModTime()) || c.size != info.Size() {
+		return nil, false
+	}
+	return c.value, true
+}
+
+// putCachedConfig caches the config parsed from a file, info is the one of
+// the file before it was read so a write during the parsing invalidates it
+func putCachedConfig(path string, info os.FileInfo, value interface{}) {
+	configCacheMu.Lock()
+	defer configCacheMu.Unlock()
+	configCache[path] = cachedConfig{modTime: info.ModTime(), size: info.Size(), value: value}
+}
+
+// InvalidateConfig drops the cached config of a config.json, its writers call
+// it since a rewrite of the same size within the resolution of the
+// modification times would go unnoticed
+func InvalidateConfig(path string) {
+	configCacheMu.Lock()
+	defer configCacheMu.Unlock()
+	delete(configCache, path)
+}
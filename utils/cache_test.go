@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"hlf-easy/internal/testca"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetCAConfigCache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	caConfigPath := filepath.Join(home, "hlf-easy/cas/ca1/config.json")
+	if err := os.MkdirAll(filepath.Dir(caConfigPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeCA := func(commonName string, modTime time.Time) {
+		caCert, caKey := testca.NewCA(t, commonName)
+		caKeyBytes, err := EncodePrivateKey(caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		caConfigBytes, err := json.Marshal(config.CAConfig{
+			CaCert:    EncodeX509Certificate(caCert),
+			CaKey:     caKeyBytes,
+			TlsCACert: EncodeX509Certificate(caCert),
+			TlsCAKey:  caKeyBytes,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(caConfigPath, caConfigBytes, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(caConfigPath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeCA("ca.1", modTime)
+
+	first, err := GetCAConfig("ca1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.PreviousCACerts = append(first.PreviousCACerts, first.CACert)
+	second, err := GetCAConfig("ca1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.CACert != first.CACert || len(second.PreviousCACerts) != 0 {
+		t.Fatal("expected the cached config, unchanged by its callers")
+	}
+
+	// a renewal is parsed again once it's invalidated, even with the same
+	// modification time
+	writeCA("ca.2", modTime)
+	InvalidateConfig(caConfigPath)
+	renewed, err := GetCAConfig("ca1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.CACert.Subject.CommonName != "ca.2" {
+		t.Fatalf("expected the renewed CA, got %s", renewed.CACert.Subject.CommonName)
+	}
+	// a write by another process is noticed by its modification time
+	writeCA("ca.3", modTime.Add(time.Second))
+	renewed, err = GetCAConfig("ca1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.CACert.Subject.CommonName != "ca.3" {
+		t.Fatalf("expected the CA written by another process, got %s", renewed.CACert.Subject.CommonName)
+	}
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+)
+
+func getHostConfigFilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/host.json"), nil
+}
+
+// GetHostConfig reads the host config, the defaults are returned if it doesn't exist
+func GetHostConfig() (*config.HostConfig, error) {
+	hostConfigFilePath, err := getHostConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+	hostConfig := &config.HostConfig{}
+	hostConfigBytes, err := os.ReadFile(hostConfigFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return hostConfig, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(hostConfigBytes, hostConfig)
+	if err != nil {
+		return nil, err
+	}
+	return hostConfig, nil
+}
+
+// SaveHostConfig writes the host config
+func SaveHostConfig(hostConfig *config.HostConfig) error {
+	hostConfigFilePath, err := getHostConfigFilePath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(hostConfigFilePath), 0755)
+	if err != nil {
+		return err
+	}
+	hostConfigBytes, err := json.MarshalIndent(hostConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(hostConfigFilePath, hostConfigBytes, 0644)
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"hlf-easy/errdefs"
+	"os"
+	"time"
+)
+
+type identityPem struct {
+	Pem string `yaml:"pem"`
+}
+
+type identity struct {
+	Cert identityPem `yaml:"cert"`
+	Key  identityPem `yaml:"key"`
+}
+
+// ReadIdentity reads an identity file with the cert and key PEMs, the same
+// format used to join channels and set the anchor peers
+func ReadIdentity(identityPath string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	identityBytes, err := os.ReadFile(identityPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	id := &identity{}
+	err = yaml.Unmarshal(identityBytes, id)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to parse identity %s", identityPath)
+	}
+	crt, err := ParseX509Certificate([]byte(id.Cert.Pem))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to parse the certificate of identity %s", identityPath)
+	}
+	key, err := ParseECDSAPrivateKey([]byte(id.Key.Pem))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to parse the key of identity %s", identityPath)
+	}
+	pub, ok := crt.PublicKey.(*ecdsa.PublicKey)
+	if !ok || !key.PublicKey.Equal(pub) {
+		return nil, nil, errors.Errorf("key of identity %s doesn't match its certificate", identityPath)
+	}
+	return crt, key, nil
+}
+
+// VerifyMSPAdmin checks that an identity is an admin of the MSP of an org,
+// its certificate must be valid, chain to the CA of the MSP and have the admin
+// OU. The identities issued by an intermediate CA chain to the CA through the
+// intermediatecerts of the MSP. Fabric refuses the operations of other
+// identities with ACCESS_DENIED, which doesn't tell which identity or MSP is wrong
+func VerifyMSPAdmin(crt *x509.Certificate, mspID string, caCert *x509.Certificate, intermediates []*x509.Certificate, now time.Time) error {
+	err := VerifyMSPMember(crt, mspID, caCert, intermediates, now)
+	if err != nil {
+		return err
+	}
+	if !Contains(crt.Subject.OrganizationalUnit, "admin") {
+		return errors.Errorf(
+			"identity %q is not an admin of MSP %s, its OUs are %v",
+			crt.Subject.CommonName, mspID, crt.Subject.OrganizationalUnit,
+		)
+	}
+	return nil
+}
+
+// VerifyMSPMember checks that an identity belongs to the MSP of an org, its
+// certificate must be valid and chain to the CA of the MSP
+func VerifyMSPMember(crt *x509.Certificate, mspID string, caCert *x509.Certificate, intermediates []*x509.Certificate, now time.Time) error {
+	if now.After(crt.NotAfter) {
+		return errdefs.Errorf(errdefs.ErrCertExpired, "identity %q expired on %s", crt.Subject.CommonName, crt.NotAfter.Format(time.RFC3339))
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	intermediatePool := x509.NewCertPool()
+	for _, intermediate := range intermediates {
+		intermediatePool.AddCert(intermediate)
+	}
+	_, err := crt.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediatePool,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return errors.Errorf(
+			"identity %q doesn't belong to MSP %s, it's issued by %q instead of the CA of the MSP %q",
+			crt.Subject.CommonName, mspID, crt.Issuer.CommonName, caCert.Subject.CommonName,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/x509"
+	"hlf-easy/internal/testca"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyMSPAdmin(t *testing.T) {
+	org1CA, org1Key := testca.NewCA(t, "ca.org1")
+	org2CA, org2Key := testca.NewCA(t, "ca.org2")
+	admin, _ := testca.Issue(t, testca.Cert{CommonName: "admin1", OUs: []string{"admin"}}, org1CA, org1Key)
+	client, _ := testca.Issue(t, testca.Cert{CommonName: "client1", OUs: []string{"client"}}, org1CA, org1Key)
+	otherAdmin, _ := testca.Issue(t, testca.Cert{CommonName: "admin2", OUs: []string{"admin"}}, org2CA, org2Key)
+	now := time.Now()
+
+	if err := VerifyMSPAdmin(admin, "Org1MSP", org1CA, nil, now); err != nil {
+		t.Fatal(err)
+	}
+	err := VerifyMSPAdmin(otherAdmin, "Org1MSP", org1CA, nil, now)
+	if err == nil || !strings.Contains(err.Error(), "doesn't belong to MSP Org1MSP") || !strings.Contains(err.Error(), "ca.org2") {
+		t.Fatalf("expected the identity of another org to be refused, got %v", err)
+	}
+	err = VerifyMSPAdmin(client, "Org1MSP", org1CA, nil, now)
+	if err == nil || !strings.Contains(err.Error(), "not an admin") {
+		t.Fatalf("expected a client identity to be refused, got %v", err)
+	}
+	err = VerifyMSPAdmin(admin, "Org1MSP", org1CA, nil, now.Add(2*time.Hour))
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("expected an expired identity to be refused, got %v", err)
+	}
+}
+
+func TestVerifyMSPAdminIntermediateCA(t *testing.T) {
+	rootCA, rootKey := testca.NewCA(t, "ca.org1")
+	intermediateCA, intermediateKey := testca.Issue(t, testca.Cert{CommonName: "ica.org1", CA: true}, rootCA, rootKey)
+	admin, _ := testca.Issue(t, testca.Cert{CommonName: "admin1", OUs: []string{"admin"}}, intermediateCA, intermediateKey)
+	now := time.Now()
+
+	err := VerifyMSPAdmin(admin, "Org1MSP", rootCA, nil, now)
+	if err == nil || !strings.Contains(err.Error(), "doesn't belong to MSP Org1MSP") {
+		t.Fatalf("expected the identity to be refused without the intermediate CA, got %v", err)
+	}
+	if err := VerifyMSPAdmin(admin, "Org1MSP", rootCA, []*x509.Certificate{intermediateCA}, now); err != nil {
+		t.Fatal(err)
+	}
+}
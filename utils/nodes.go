@@ -7,16 +7,26 @@ import (
 	"fmt"
 	"github.com/pkg/errors"
 	"hlf-easy/config"
+	"hlf-easy/errdefs"
 	"os"
 	"path/filepath"
 )
 
 type CAConfig struct {
+	Name string
+
 	CACert *x509.Certificate
 	CAKey  *ecdsa.PrivateKey
 
 	TLSCACert *x509.Certificate
 	TLSCAKey  *ecdsa.PrivateKey
+
+	// PreviousCACerts and PreviousTLSCACerts are the roots of a rotation in
+	// progress, still trusted by the nodes
+	PreviousCACerts    []*x509.Certificate
+	PreviousTLSCACerts []*x509.Certificate
+
+	CertPolicy config.CertificatePolicy
 }
 
 func GetCAConfig(name string) (*CAConfig, error) {
@@ -29,8 +39,15 @@ func GetCAConfig(name string) (*CAConfig, error) {
 		fmt.Sprintf("hlf-easy/cas/%s/config.json", name),
 	)
 	// check if file exists
-	if _, err := os.Stat(caConfigFilePath); os.IsNotExist(err) {
-		return nil, errors.Errorf("ca config file does not exist: %v", caConfigFilePath)
+	info, err := os.Stat(caConfigFilePath)
+	if os.IsNotExist(err) {
+		return nil, errdefs.Errorf(errdefs.ErrCANotInitialized, "ca config file does not exist: %v", caConfigFilePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cached, ok := getCachedConfig(caConfigFilePath, info); ok {
+		return cached.(*CAConfig).clone(), nil
 	}
 	// read config ca file
 	caConfigBytes, err := os.ReadFile(caConfigFilePath)
@@ -42,6 +59,10 @@ func GetCAConfig(name string) (*CAConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = LoadCAKeys(name, &caConfig)
+	if err != nil {
+		return nil, err
+	}
 	// parse CA Cert and Key
 	caCert, err := ParseX509Certificate(caConfig.CaCert)
 	if err != nil {
@@ -60,12 +81,47 @@ func GetCAConfig(name string) (*CAConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &CAConfig{
-		CACert:    caCert,
-		CAKey:     caKey,
-		TLSCACert: tlsCACert,
-		TLSCAKey:  tlsCAKey,
-	}, nil
+	previousCACerts, err := parseCertificates(caConfig.PreviousCaCerts)
+	if err != nil {
+		return nil, err
+	}
+	previousTLSCACerts, err := parseCertificates(caConfig.PreviousTlsCACerts)
+	if err != nil {
+		return nil, err
+	}
+	parsed := &CAConfig{
+		Name:               name,
+		CACert:             caCert,
+		CAKey:              caKey,
+		TLSCACert:          tlsCACert,
+		TLSCAKey:           tlsCAKey,
+		PreviousCACerts:    previousCACerts,
+		PreviousTLSCACerts: previousTLSCACerts,
+		CertPolicy:         caConfig.CertPolicy,
+	}
+	putCachedConfig(caConfigFilePath, info, parsed)
+	return parsed.clone(), nil
+}
+
+// clone returns a copy of the config the callers can change without changing
+// the cached one
+func (c *CAConfig) clone() *CAConfig {
+	clone := *c
+	clone.PreviousCACerts = append([]*x509.Certificate{}, c.PreviousCACerts...)
+	clone.PreviousTLSCACerts = append([]*x509.Certificate{}, c.PreviousTLSCACerts...)
+	return &clone
+}
+
+func parseCertificates(pems [][]byte) ([]*x509.Certificate, error) {
+	crts := make([]*x509.Certificate, 0, len(pems))
+	for _, pem := range pems {
+		crt, err := ParseX509Certificate(pem)
+		if err != nil {
+			return nil, err
+		}
+		crts = append(crts, crt)
+	}
+	return crts, nil
 }
 
 type PeerConfig struct {
@@ -76,6 +132,30 @@ type PeerConfig struct {
 
 	TLSCACert *x509.Certificate
 	CaCert    *x509.Certificate
+	// IntermediateCerts are the intermediate CAs of the MSP of the peer
+	IntermediateCerts []*x509.Certificate
+}
+
+// readIntermediateCerts parses the certificates of the intermediatecerts
+// directory of an MSP, one per file
+func readIntermediateCerts(mspDir string) ([]*x509.Certificate, error) {
+	files, err := filepath.Glob(filepath.Join(mspDir, "intermediatecerts", "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	var intermediates []*x509.Certificate
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		chain, err := ParseX509CertificateChain(contents)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", file)
+		}
+		intermediates = append(intermediates, chain...)
+	}
+	return intermediates, nil
 }
 
 func GetPeerConfig(name string) (*PeerConfig, error) {
@@ -88,8 +168,15 @@ func GetPeerConfig(name string) (*PeerConfig, error) {
 		fmt.Sprintf("hlf-easy/peers/%s/config.json", name),
 	)
 	// check if file exists
-	if _, err := os.Stat(caConfigFilePath); os.IsNotExist(err) {
-		return nil, errors.Errorf("peer config file does not exist: %v", caConfigFilePath)
+	info, err := os.Stat(caConfigFilePath)
+	if os.IsNotExist(err) {
+		return nil, errdefs.Errorf(errdefs.ErrNodeNotFound, "peer config file does not exist: %v", caConfigFilePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cached, ok := getCachedConfig(caConfigFilePath, info); ok {
+		return cached.(*PeerConfig).clone(), nil
 	}
 	// read config ca file
 	caConfigBytes, err := os.ReadFile(caConfigFilePath)
@@ -101,6 +188,10 @@ func GetPeerConfig(name string) (*PeerConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = LoadSignKey("peer", name, &caConfig)
+	if err != nil {
+		return nil, err
+	}
 	// parse CA Cert and Key
 	signCert, err := ParseX509Certificate(caConfig.SignCert)
 	if err != nil {
@@ -127,14 +218,29 @@ func GetPeerConfig(name string) (*PeerConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &PeerConfig{
-		TLSKey:    tlsKey,
-		TLSCert:   tlsCert,
-		SignKey:   signKey,
-		SignCert:  signCert,
-		TLSCACert: tlsCACert,
-		CaCert:    caCert,
-	}, nil
+	intermediateCerts, err := readIntermediateCerts(filepath.Dir(caConfigFilePath))
+	if err != nil {
+		return nil, err
+	}
+	parsed := &PeerConfig{
+		TLSKey:            tlsKey,
+		TLSCert:           tlsCert,
+		SignKey:           signKey,
+		SignCert:          signCert,
+		TLSCACert:         tlsCACert,
+		CaCert:            caCert,
+		IntermediateCerts: intermediateCerts,
+	}
+	putCachedConfig(caConfigFilePath, info, parsed)
+	return parsed.clone(), nil
+}
+
+// clone returns a copy of the config the callers can change without changing
+// the cached one
+func (c *PeerConfig) clone() *PeerConfig {
+	clone := *c
+	clone.IntermediateCerts = append([]*x509.Certificate{}, c.IntermediateCerts...)
+	return &clone
 }
 
 func GetPeerRunConfig(name string) (*config.PeerRunConfig, error) {
@@ -148,7 +254,7 @@ func GetPeerRunConfig(name string) (*config.PeerRunConfig, error) {
 	)
 	// check if file exists
 	if _, err := os.Stat(caConfigFilePath); os.IsNotExist(err) {
-		return nil, errors.Errorf("peer run config file does not exist: %v", caConfigFilePath)
+		return nil, errdefs.Errorf(errdefs.ErrNodeNotRunning, "peer run config file does not exist: %v", caConfigFilePath)
 	}
 	// read config ca file
 	caConfigBytes, err := os.ReadFile(caConfigFilePath)
@@ -176,8 +282,16 @@ type OrdererConfig struct {
 func GetOrdererConfig(ordererConfigFilePath string) (*OrdererConfig, error) {
 
 	// check if file exists
-	if _, err := os.Stat(ordererConfigFilePath); os.IsNotExist(err) {
-		return nil, errors.Errorf("peer config file does not exist: %v", ordererConfigFilePath)
+	info, err := os.Stat(ordererConfigFilePath)
+	if os.IsNotExist(err) {
+		return nil, errdefs.Errorf(errdefs.ErrNodeNotFound, "orderer config file does not exist: %v", ordererConfigFilePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cached, ok := getCachedConfig(ordererConfigFilePath, info); ok {
+		clone := *cached.(*OrdererConfig)
+		return &clone, nil
 	}
 	// read config ca file
 	caConfigBytes, err := os.ReadFile(ordererConfigFilePath)
@@ -189,6 +303,10 @@ func GetOrdererConfig(ordererConfigFilePath string) (*OrdererConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = LoadSignKey("orderer", filepath.Base(filepath.Dir(ordererConfigFilePath)), &caConfig)
+	if err != nil {
+		return nil, err
+	}
 	// parse CA Cert and Key
 	signCert, err := ParseX509Certificate(caConfig.SignCert)
 	if err != nil {
@@ -215,12 +333,15 @@ func GetOrdererConfig(ordererConfigFilePath string) (*OrdererConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &OrdererConfig{
+	parsed := &OrdererConfig{
 		TLSKey:    tlsKey,
 		TLSCert:   tlsCert,
 		SignKey:   signKey,
 		SignCert:  signCert,
 		TLSCACert: tlsCACert,
 		CaCert:    caCert,
-	}, nil
+	}
+	putCachedConfig(ordererConfigFilePath, info, parsed)
+	clone := *parsed
+	return &clone, nil
 }
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"encoding/json"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+)
+
+func getNotifyConfigFilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/notify.json"), nil
+}
+
+// GetNotifyConfig reads the notify config of the host, it's empty when no
+// webhook is configured
+func GetNotifyConfig() (*config.NotifyConfig, error) {
+	notifyConfigFilePath, err := getNotifyConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+	notifyConfig := &config.NotifyConfig{}
+	notifyConfigBytes, err := os.ReadFile(notifyConfigFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return notifyConfig, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(notifyConfigBytes, notifyConfig)
+	if err != nil {
+		return nil, err
+	}
+	return notifyConfig, nil
+}
+
+// SaveNotifyConfig writes the notify config of the host, it's only readable
+// by the user since webhook URLs usually embed a secret
+func SaveNotifyConfig(notifyConfig *config.NotifyConfig) error {
+	notifyConfigFilePath, err := getNotifyConfigFilePath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(notifyConfigFilePath), 0755)
+	if err != nil {
+		return err
+	}
+	notifyConfigBytes, err := json.MarshalIndent(notifyConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(notifyConfigFilePath, notifyConfigBytes, 0600)
+}
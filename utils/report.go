@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+)
+
+func getReportConfigFilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "hlf-easy/report.json"), nil
+}
+
+// GetReportConfig reads the report config of the host
+func GetReportConfig() (*config.ReportConfig, error) {
+	reportConfigFilePath, err := getReportConfigFilePath()
+	if err != nil {
+		return nil, err
+	}
+	reportConfigBytes, err := os.ReadFile(reportConfigFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("reports are not configured, run report config first")
+		}
+		return nil, err
+	}
+	reportConfig := &config.ReportConfig{}
+	err = json.Unmarshal(reportConfigBytes, reportConfig)
+	if err != nil {
+		return nil, err
+	}
+	return reportConfig, nil
+}
+
+// SaveReportConfig writes the report config of the host
+func SaveReportConfig(reportConfig *config.ReportConfig) error {
+	reportConfigFilePath, err := getReportConfigFilePath()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(reportConfigFilePath), 0755)
+	if err != nil {
+		return err
+	}
+	reportConfigBytes, err := json.MarshalIndent(reportConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(reportConfigFilePath, reportConfigBytes, 0644)
+}
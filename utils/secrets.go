@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"context"
+	"hlf-easy/config"
+	"hlf-easy/secrets"
+)
+
+// LoadCAKeys fetches the keys of a local CA from its secrets manager, they're
+// not in its config.json then
+func LoadCAKeys(name string, caConfig *config.CAConfig) error {
+	if caConfig.Secrets == "" {
+		return nil
+	}
+	caKey, err := secrets.Fetch(caConfig.Secrets, secrets.KeyName("ca", name, secrets.CAKey))
+	if err != nil {
+		return err
+	}
+	tlsCAKey, err := secrets.Fetch(caConfig.Secrets, secrets.KeyName("ca", name, secrets.TLSCAKey))
+	if err != nil {
+		return err
+	}
+	caConfig.CaKey = caKey
+	caConfig.TlsCAKey = tlsCAKey
+	return nil
+}
+
+// StoreCAKeys writes the keys of a local CA to its secrets manager, and
+// returns its config without them for its config.json
+func StoreCAKeys(ctx context.Context, name string, caConfig *config.CAConfig) (*config.CAConfig, error) {
+	if caConfig.Secrets == "" {
+		return caConfig, nil
+	}
+	store, err := secrets.Open(caConfig.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Put(ctx, secrets.KeyName("ca", name, secrets.CAKey), caConfig.CaKey); err != nil {
+		return nil, err
+	}
+	if err := store.Put(ctx, secrets.KeyName("ca", name, secrets.TLSCAKey), caConfig.TlsCAKey); err != nil {
+		return nil, err
+	}
+	stripped := *caConfig
+	stripped.CaKey = nil
+	stripped.TlsCAKey = nil
+	return &stripped, nil
+}
+
+// LoadSignKey fetches the signing key of a peer or an orderer from its
+// secrets manager, it's not in its config.json then
+func LoadSignKey(kind string, id string, nodeConfig *config.PeerConfig) error {
+	if nodeConfig.Secrets == "" {
+		return nil
+	}
+	signKey, err := secrets.Fetch(nodeConfig.Secrets, secrets.KeyName(kind, id, secrets.SignKey))
+	if err != nil {
+		return err
+	}
+	nodeConfig.SignKey = signKey
+	return nil
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"hlf-easy/proc"
+	"os"
+	"os/exec"
+)
+
+// Spawn starts an hlf-easy command in the background, its output is appended
+// to the log file. It returns the PID of the process, which outlives the
+// caller
+func Spawn(args []string, logPath string) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer logFile.Close()
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	// the process is terminated on its own, not with the group of the caller
+	proc.NewProcessGroup(cmd)
+	err = cmd.Start()
+	if err != nil {
+		return 0, err
+	}
+	pid := cmd.Process.Pid
+	return pid, cmd.Process.Release()
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"hlf-easy/config"
+	"os"
+	"path/filepath"
+)
+
+// GetNodeDir returns the directory of a peer or orderer of the host
+func GetNodeDir(kind string, id string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fmt.Sprintf("hlf-easy/%ss", kind), id), nil
+}
+
+// GetNodeTasks reads the tasks of a node, it's empty when no task is
+// configured
+func GetNodeTasks(kind string, id string) (*config.NodeTasks, error) {
+	nodeDir, err := GetNodeDir(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	nodeTasks := &config.NodeTasks{}
+	nodeTasksBytes, err := os.ReadFile(filepath.Join(nodeDir, "tasks.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nodeTasks, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(nodeTasksBytes, nodeTasks)
+	if err != nil {
+		return nil, err
+	}
+	return nodeTasks, nil
+}
+
+// SaveNodeTasks writes the tasks of a node
+func SaveNodeTasks(kind string, id string, nodeTasks *config.NodeTasks) error {
+	nodeDir, err := GetNodeDir(kind, id)
+	if err != nil {
+		return err
+	}
+	nodeTasksBytes, err := json.MarshalIndent(nodeTasks, "", "  ")
+	if err != nil {
+		return err
+	}
+	// the params can have credentials, e.g. the URL of CouchDB
+	return os.WriteFile(filepath.Join(nodeDir, "tasks.json"), nodeTasksBytes, 0600)
+}
@@ -6,7 +6,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
-	log "github.com/sirupsen/logrus"
+	"hlf-easy/log"
 	"net"
 	"strconv"
 	"strings"
@@ -197,3 +197,27 @@ func GetFreeNodeports(host string, n int) ([]int, error) {
 	}
 	return []int{}, errors.New("no ports are free")
 }
+
+// ParseX509CertificateChain parses all the certificates contained in a PEM bundle
+func ParseX509CertificateChain(contents []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, contents = pem.Decode(contents)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		crt, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, crt)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no certificates found in PEM bundle")
+	}
+	return chain, nil
+}
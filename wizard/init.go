@@ -0,0 +1,273 @@
+package wizard
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"hlf-easy/config"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	nameRegexp     = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	orgRegexp      = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _.-]*$`)
+	hostnameRegexp = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+)
+
+// newCA is the choice of the CA that creates a new one
+const newCA = "create a new CA"
+
+// Host is what the wizard must not overwrite on the host
+type Host struct {
+	CAs   []string
+	Peers []string
+	// Ports are the external ports of the peers by peer ID
+	Ports map[string]int
+}
+
+// LoadHost reads the CAs and peers of the host
+func LoadHost() (*Host, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	h := &Host{Ports: map[string]int{}}
+	for _, kind := range []string{"cas", "peers"} {
+		entries, err := os.ReadDir(filepath.Join(home, "hlf-easy", kind))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			if kind == "cas" {
+				h.CAs = append(h.CAs, entry.Name())
+				continue
+			}
+			h.Peers = append(h.Peers, entry.Name())
+			initBytes, err := os.ReadFile(filepath.Join(home, "hlf-easy/peers", entry.Name(), "init.json"))
+			if err != nil {
+				continue
+			}
+			peerInitOpts := config.PeerInitOptions{}
+			if json.Unmarshal(initBytes, &peerInitOpts) == nil && peerInitOpts.ExternalPort != 0 {
+				h.Ports[entry.Name()] = peerInitOpts.ExternalPort
+			}
+		}
+	}
+	return h, nil
+}
+
+// Answers are the org, CA and first peer set up by the wizard
+type Answers struct {
+	Org    string
+	MSPID  string
+	Hosts  []string
+	CAName string
+	// NewCA creates the CA, an existing CA of the host is used otherwise
+	NewCA  bool
+	PeerID string
+	Port   int
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func validateOrg(org string) error {
+	if !orgRegexp.MatchString(org) {
+		return errors.Errorf("invalid organization name %q", org)
+	}
+	return nil
+}
+
+func validateMSPID(mspID string) error {
+	if !nameRegexp.MatchString(mspID) {
+		return errors.Errorf("invalid MSP ID %q, use letters, digits, '.', '_' and '-'", mspID)
+	}
+	return nil
+}
+
+// parseHosts parses the comma separated hostnames and IPs
+func parseHosts(answer string) ([]string, error) {
+	var hosts []string
+	for _, host := range strings.Split(answer, ",") {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+		if net.ParseIP(host) == nil && (len(host) > 253 || !hostnameRegexp.MatchString(host)) {
+			return nil, errors.Errorf("invalid host %q, expected a hostname or an IP", host)
+		}
+		hosts = append(hosts, host)
+	}
+	if len(hosts) == 0 {
+		return nil, errors.New("at least one host is required")
+	}
+	return hosts, nil
+}
+
+func (h *Host) validateNewCA(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return errors.Errorf("invalid CA name %q", name)
+	}
+	if contains(h.CAs, name) {
+		return errors.Errorf("CA %s already exists", name)
+	}
+	return nil
+}
+
+func (h *Host) validatePeerID(id string) error {
+	if !nameRegexp.MatchString(id) {
+		return errors.Errorf("invalid peer ID %q", id)
+	}
+	if contains(h.Peers, id) {
+		return errors.Errorf("peer %s already exists", id)
+	}
+	return nil
+}
+
+func (h *Host) validatePort(port int) error {
+	if port < 1 || port > 65535 {
+		return errors.Errorf("invalid port %d", port)
+	}
+	for id, used := range h.Ports {
+		if used == port {
+			return errors.Errorf("port %d is used by peer %s", port, id)
+		}
+	}
+	return nil
+}
+
+// Validate checks all the answers against the host before anything is
+// written
+func (a Answers) Validate(h *Host) error {
+	if err := validateOrg(a.Org); err != nil {
+		return err
+	}
+	if err := validateMSPID(a.MSPID); err != nil {
+		return err
+	}
+	if _, err := parseHosts(strings.Join(a.Hosts, ",")); err != nil {
+		return err
+	}
+	if a.NewCA {
+		if err := h.validateNewCA(a.CAName); err != nil {
+			return err
+		}
+	} else if !contains(h.CAs, a.CAName) {
+		return errors.Errorf("CA %s doesn't exist", a.CAName)
+	}
+	if err := h.validatePeerID(a.PeerID); err != nil {
+		return err
+	}
+	return h.validatePort(a.Port)
+}
+
+// defaultMSPID derives the MSP ID from the name of the organization
+func defaultMSPID(org string) string {
+	var b strings.Builder
+	for _, r := range org {
+		if r < 128 && nameRegexp.MatchString(string(r)) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String() + "MSP"
+}
+
+// defaultPort is the first port from 7051 not used by a peer of the host, the
+// ports of a peer are spaced by 1000
+func (h *Host) defaultPort() int {
+	port := 7051
+	for h.validatePort(port) != nil {
+		port += 1000
+	}
+	return port
+}
+
+// Ask asks the questions of the wizard, each answer is validated before the
+// next question
+func Ask(p *Prompter, h *Host, defaultHost string) (*Answers, error) {
+	a := &Answers{}
+	var err error
+	a.Org, err = p.Ask("Organization name", "", validateOrg)
+	if err != nil {
+		return nil, err
+	}
+	a.MSPID, err = p.Ask("MSP ID", defaultMSPID(a.Org), validateMSPID)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := p.Ask("Hosts of the nodes, comma separated", defaultHost, func(answer string) error {
+		_, err := parseHosts(answer)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.Hosts, _ = parseHosts(hosts)
+	ca := newCA
+	if len(h.CAs) > 0 {
+		ca, err = p.Choose("CA of the organization", append(append([]string{}, h.CAs...), newCA), "1")
+		if err != nil {
+			return nil, err
+		}
+	}
+	a.NewCA = ca == newCA
+	a.CAName = ca
+	if a.NewCA {
+		a.CAName, err = p.Ask("Name of the new CA", strings.ToLower(strings.TrimSuffix(a.MSPID, "MSP"))+"-ca", h.validateNewCA)
+		if err != nil {
+			return nil, err
+		}
+	}
+	a.PeerID, err = p.Ask("ID of the peer", "peer0", h.validatePeerID)
+	if err != nil {
+		return nil, err
+	}
+	port, err := p.Ask("External port of the peer", strconv.Itoa(h.defaultPort()), func(answer string) error {
+		port, err := strconv.Atoi(answer)
+		if err != nil {
+			return errors.Errorf("invalid port %q", answer)
+		}
+		return h.validatePort(port)
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.Port, _ = strconv.Atoi(port)
+	return a, a.Validate(h)
+}
+
+// Commands are the hlf-easy commands that set up the answers
+func (a Answers) Commands() [][]string {
+	hosts := strings.Join(a.Hosts, ",")
+	var commands [][]string
+	if a.NewCA {
+		commands = append(commands, []string{
+			"ca", "init",
+			"--name=" + a.CAName,
+			"--organization=" + a.Org,
+			"--hosts=" + hosts,
+		})
+	}
+	return append(commands, []string{
+		"peer", "init", "--local",
+		"--ca-name=" + a.CAName,
+		"--id=" + a.PeerID,
+		"--hosts=" + hosts,
+		"--msp-id=" + a.MSPID,
+		fmt.Sprintf("--external-port=%d", a.Port),
+	})
+}
@@ -0,0 +1,124 @@
+package wizard
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAsk(t *testing.T) {
+	h := &Host{CAs: []string{"org1-ca"}, Peers: []string{"peer0"}, Ports: map[string]int{"peer0": 7051}}
+	answers := []string{
+		"Org 2",
+		"",                // MSP ID derived from the organization
+		"node1,bad host",  // refused
+		"node1, 10.0.0.2", // hosts
+		"5",               // refused
+		"2",               // new CA
+		"org1-ca",         // refused, it exists
+		"",                // org2-ca
+		"",                // peer0 is refused
+		"peer1",
+		"7051", // refused, used by peer0
+		"",     // 8051
+	}
+	var out bytes.Buffer
+	p := NewPrompter(strings.NewReader(strings.Join(answers, "\n")+"\n"), &out)
+	a, err := Ask(p, h, "node1")
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	expected := &Answers{
+		Org:    "Org 2",
+		MSPID:  "Org2MSP",
+		Hosts:  []string{"node1", "10.0.0.2"},
+		CAName: "org2-ca",
+		NewCA:  true,
+		PeerID: "peer1",
+		Port:   8051,
+	}
+	if !reflect.DeepEqual(a, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, a)
+	}
+	for _, refused := range []string{
+		`invalid host "bad host"`,
+		"choose a number between 1 and 2",
+		"CA org1-ca already exists",
+		"peer peer0 already exists",
+		"port 7051 is used by peer peer0",
+	} {
+		if !strings.Contains(out.String(), refused) {
+			t.Errorf("expected %q to be printed, got\n%s", refused, out.String())
+		}
+	}
+	commands := a.Commands()
+	if len(commands) != 2 || commands[0][0] != "ca" || commands[1][1] != "init" {
+		t.Fatalf("expected ca init and peer init, got %v", commands)
+	}
+	expectedPeerInit := "peer init --local --ca-name=org2-ca --id=peer1 --hosts=node1,10.0.0.2 --msp-id=Org2MSP --external-port=8051"
+	if strings.Join(commands[1], " ") != expectedPeerInit {
+		t.Errorf("expected %s, got %s", expectedPeerInit, strings.Join(commands[1], " "))
+	}
+}
+
+func TestAskAborted(t *testing.T) {
+	p := NewPrompter(strings.NewReader("Org1\n"), &bytes.Buffer{})
+	if _, err := Ask(p, &Host{}, "node1"); err == nil {
+		t.Fatal("expected the wizard to abort at the end of the input")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	h := &Host{CAs: []string{"org1-ca"}, Peers: []string{"peer0"}, Ports: map[string]int{"peer0": 7051}}
+	valid := Answers{Org: "Org1", MSPID: "Org1MSP", Hosts: []string{"node1"}, CAName: "org1-ca", PeerID: "peer1", Port: 8051}
+	if err := valid.Validate(h); err != nil {
+		t.Fatal(err)
+	}
+	if commands := valid.Commands(); len(commands) != 1 {
+		t.Errorf("expected only peer init with an existing CA, got %v", commands)
+	}
+	invalid := []func(a *Answers){
+		func(a *Answers) { a.MSPID = "Org1 MSP" },
+		func(a *Answers) { a.Hosts = nil },
+		func(a *Answers) { a.CAName = "org2-ca" },
+		func(a *Answers) { a.NewCA = true },
+		func(a *Answers) { a.PeerID = "peer0" },
+		func(a *Answers) { a.PeerID = "../peer1" },
+		func(a *Answers) { a.Port = 70000 },
+	}
+	for i, change := range invalid {
+		a := valid
+		change(&a)
+		if err := a.Validate(h); err == nil {
+			t.Errorf("expected the answers %d to be invalid: %+v", i, a)
+		}
+	}
+}
+
+func TestLoadHost(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, dir := range []string{"cas/org1-ca", "peers/peer0", "peers/peer1"} {
+		if err := os.MkdirAll(filepath.Join(home, "hlf-easy", dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.WriteFile(filepath.Join(home, "hlf-easy/peers/peer0/init.json"), []byte(`{"id":"peer0","externalPort":7051}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := LoadHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Host{CAs: []string{"org1-ca"}, Peers: []string{"peer0", "peer1"}, Ports: map[string]int{"peer0": 7051}}
+	if !reflect.DeepEqual(h, expected) {
+		t.Errorf("expected %+v, got %+v", expected, h)
+	}
+	if h.defaultPort() != 8051 {
+		t.Errorf("expected the first free port to be 8051, got %d", h.defaultPort())
+	}
+}
@@ -0,0 +1,102 @@
+package wizard
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Prompter asks the questions of the wizard, an invalid answer is asked
+// again with the reason it was refused
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPrompter returns a prompter reading the answers from in
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+func (p *Prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err == io.EOF {
+		return "", errors.New("aborted, no more input")
+	}
+	return strings.TrimSpace(line), err
+}
+
+// Ask asks a question until its answer is valid, the default is used for an
+// empty answer
+func (p *Prompter) Ask(label string, def string, validate func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", label, def)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", label)
+		}
+		answer, err := p.readLine()
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			answer = def
+		}
+		if err := validate(answer); err != nil {
+			fmt.Fprintf(p.out, "  %s\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// Choose asks to pick one of the options by its number or its value
+func (p *Prompter) Choose(label string, options []string, def string) (string, error) {
+	fmt.Fprintf(p.out, "%s:\n", label)
+	for i, option := range options {
+		fmt.Fprintf(p.out, "  %d) %s\n", i+1, option)
+	}
+	answer, err := p.Ask("Choice", def, func(answer string) error {
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return nil
+		}
+		for _, option := range options {
+			if option == answer {
+				return nil
+			}
+		}
+		return errors.Errorf("choose a number between 1 and %d", len(options))
+	})
+	if err != nil {
+		return "", err
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+		return options[n-1], nil
+	}
+	return answer, nil
+}
+
+// Confirm asks a yes or no question
+func (p *Prompter) Confirm(label string, def bool) (bool, error) {
+	defAnswer := "n"
+	if def {
+		defAnswer = "y"
+	}
+	answer, err := p.Ask(label+" (y/n)", defAnswer, func(answer string) error {
+		switch strings.ToLower(answer) {
+		case "y", "yes", "n", "no":
+			return nil
+		}
+		return errors.New("answer y or n")
+	})
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}